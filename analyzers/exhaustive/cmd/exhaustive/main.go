@@ -0,0 +1,11 @@
+// Command exhaustive runs the exhaustive analyzer, standalone or as a vet
+// tool (go vet -vettool=$(which exhaustive) ./...).
+package main
+
+import (
+	"golang.org/x/tools/go/analysis/singlechecker"
+
+	"patterns/analyzers/exhaustive"
+)
+
+func main() { singlechecker.Main(exhaustive.Analyzer) }
@@ -0,0 +1,169 @@
+// Package exhaustive defines an analyzer that reports type switches over a
+// sealed interface (one with an unexported method) that do not list every
+// implementation declared in the interface's package.
+//
+// A variant with value methods is covered by case T, since that is what a
+// value stored in the interface matches; case *T only matches *T and
+// covers a variant only when T implements the interface by pointer alone.
+// A default clause does not count as coverage; put //exhaustive:ignore on
+// the line above the switch to opt out.
+package exhaustive
+
+import (
+	"go/ast"
+	"go/types"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+var Analyzer = &analysis.Analyzer{
+	Name:     "exhaustive",
+	Doc:      "check that type switches over sealed interfaces handle every variant",
+	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Run:      run,
+}
+
+const ignoreDirective = "//exhaustive:ignore"
+
+func run(pass *analysis.Pass) (any, error) {
+	insp := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+
+	ignored := ignoredLines(pass)
+	insp.Preorder([]ast.Node{(*ast.TypeSwitchStmt)(nil)}, func(n ast.Node) {
+		sw := n.(*ast.TypeSwitchStmt)
+		pos := pass.Fset.Position(sw.Pos())
+		if ignored[pos.Filename][pos.Line-1] {
+			return
+		}
+
+		iface, named := switchedInterface(pass, sw)
+		if iface == nil || !sealed(iface) {
+			return
+		}
+
+		// keyed by type identity: types.NewPointer in caseType is a new
+		// *types.Pointer each time, never the one seen in a case
+		var covered typeutil.Map
+		for _, stmt := range sw.Body.List {
+			for _, expr := range stmt.(*ast.CaseClause).List {
+				if t := pass.TypesInfo.TypeOf(expr); t != nil {
+					covered.Set(t, true)
+				}
+			}
+		}
+
+		var missing []string
+		for _, v := range variants(named, iface) {
+			if t := caseType(v, iface); covered.At(t) == nil && !coveredByInterface(&covered, t) {
+				missing = append(missing, v.Obj().Name())
+			}
+		}
+		if len(missing) > 0 {
+			sort.Strings(missing)
+			pass.Reportf(sw.Pos(), "missing cases in type switch over %s: %s",
+				named.Obj().Name(), strings.Join(missing, ", "))
+		}
+	})
+
+	return nil, nil
+}
+
+// switchedInterface returns the interface type of x in switch x.(type).
+func switchedInterface(pass *analysis.Pass, sw *ast.TypeSwitchStmt) (*types.Interface, *types.Named) {
+	var x ast.Expr
+	//exhaustive:ignore
+	switch s := sw.Assign.(type) {
+	case *ast.ExprStmt:
+		x = s.X.(*ast.TypeAssertExpr).X
+	case *ast.AssignStmt:
+		x = s.Rhs[0].(*ast.TypeAssertExpr).X
+	default:
+		return nil, nil
+	}
+
+	named, ok := pass.TypesInfo.TypeOf(x).(*types.Named)
+	if !ok {
+		return nil, nil
+	}
+	iface, ok := named.Underlying().(*types.Interface)
+	if !ok {
+		return nil, nil
+	}
+
+	return iface, named
+}
+
+func sealed(iface *types.Interface) bool {
+	for i := range iface.NumMethods() {
+		if !iface.Method(i).Exported() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// variants lists the concrete named types in the interface's package that
+// implement it, by value or by pointer.
+func variants(named *types.Named, iface *types.Interface) []*types.Named {
+	scope := named.Obj().Pkg().Scope()
+	var out []*types.Named
+	for _, name := range scope.Names() {
+		tn, ok := scope.Lookup(name).(*types.TypeName)
+		if !ok || tn.IsAlias() {
+			continue
+		}
+		t, ok := tn.Type().(*types.Named)
+		if !ok || types.IsInterface(t) {
+			continue
+		}
+		if types.Implements(t, iface) || types.Implements(types.NewPointer(t), iface) {
+			out = append(out, t)
+		}
+	}
+
+	return out
+}
+
+// caseType is the type a case must name to match variant v held in iface:
+// v itself if v implements iface, else *v.
+func caseType(v *types.Named, iface *types.Interface) types.Type {
+	if types.Implements(v, iface) {
+		return v
+	}
+	return types.NewPointer(v)
+}
+
+func coveredByInterface(covered *typeutil.Map, v types.Type) bool {
+	for _, t := range covered.Keys() {
+		if i, ok := t.Underlying().(*types.Interface); ok && types.Implements(v, i) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func ignoredLines(pass *analysis.Pass) map[string]map[int]bool {
+	out := map[string]map[int]bool{}
+	for _, f := range pass.Files {
+		for _, cg := range f.Comments {
+			for _, c := range cg.List {
+				if strings.HasPrefix(c.Text, ignoreDirective) {
+					pos := pass.Fset.Position(c.Pos())
+					if out[pos.Filename] == nil {
+						out[pos.Filename] = map[int]bool{}
+					}
+					out[pos.Filename][pos.Line] = true
+				}
+			}
+		}
+	}
+
+	return out
+}
@@ -0,0 +1,111 @@
+// Package shapes is a sealed interface and switches over it, for
+// exhaustive to check.
+package shapes
+
+type Shape interface{ sealed() }
+
+type Circle struct{ R float64 }
+
+type Square struct{ Side float64 }
+
+// Triangle implements Shape by pointer only.
+type Triangle struct{ A, B, C float64 }
+
+func (Circle) sealed()    {}
+func (Square) sealed()    {}
+func (*Triangle) sealed() {}
+
+func Values(s Shape) {
+	switch s.(type) { // want `missing cases in type switch over Shape: Square, Triangle`
+	case Circle:
+	}
+}
+
+func Complete(s Shape) {
+	switch s.(type) {
+	case Circle, Square, *Triangle:
+	}
+}
+
+// Pointers misses the Circle and Square values: a Shape holding Circle{}
+// does not match case *Circle.
+func Pointers(s Shape) {
+	switch s.(type) { // want `missing cases in type switch over Shape: Circle, Square`
+	case *Circle:
+	case *Square:
+	case *Triangle:
+	}
+}
+
+func MissingPointer(s Shape) {
+	switch s.(type) { // want `missing cases in type switch over Shape: Triangle`
+	case Circle, Square:
+	}
+}
+
+// Both lists the pointers as well, which is fine.
+func Both(s Shape) {
+	switch s.(type) {
+	case Circle, *Circle:
+	case Square, *Square:
+	case *Triangle:
+	}
+}
+
+// Pointed is every Shape with a pointer-receiver area method, which only
+// *Triangle has.
+type Pointed interface {
+	Shape
+	area() float64
+}
+
+func (t *Triangle) area() float64 { return 0 }
+
+// PointerInterface covers Triangle through Pointed, but Pointed says
+// nothing about Circle.
+func PointerInterface(s Shape) {
+	switch s.(type) { // want `missing cases in type switch over Shape: Circle`
+	case Pointed:
+	case Square:
+	}
+}
+
+func DefaultDoesNotCount(s Shape) {
+	switch v := s.(type) { // want `missing cases in type switch over Shape: Triangle`
+	case Circle:
+		_ = v
+	case Square:
+	default:
+	}
+}
+
+// Round is every Shape that is a Circle.
+type Round interface {
+	Shape
+	radius() float64
+}
+
+func (c Circle) radius() float64 { return c.R }
+
+func ByInterface(s Shape) {
+	switch s.(type) {
+	case Round:
+	case Square, *Triangle:
+	}
+}
+
+func Ignored(s Shape) {
+	//exhaustive:ignore
+	switch s.(type) {
+	case Circle:
+	}
+}
+
+// Open has no unexported method, so nothing but its package limits it.
+type Open interface{ Area() float64 }
+
+func NotSealed(o Open) {
+	switch o.(type) {
+	case nil:
+	}
+}
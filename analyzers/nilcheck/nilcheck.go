@@ -0,0 +1,378 @@
+// Package nilcheck defines an analyzer that reports pointers dereferenced
+// where a nil check in the same function says they may be nil:
+//
+//   - before the check: *port < 0 is evaluated, then port == nil is
+//     tested, too late;
+//   - after a check that does not guard: if cfg.Port == nil { ... }
+//     neither returns nor assigns cfg.Port, so *cfg.Port below it still
+//     panics.
+//
+// This is the bug the first procedural and config struct variants of the
+// options comparison had; testdata/src/poor keeps them as they were:
+//
+//	go build -o patterncheck patterns/cmd/patterncheck
+//	go vet -vettool=./patterncheck ./analyzers/nilcheck/testdata/src/poor
+//
+// A pointer never compared with nil is not reported: without a check there
+// is no evidence the author expected nil. Paths are variables and chains
+// of field selections (cfg.Port); the analysis is per function and follows
+// source order, not control flow. Put //nilcheck:ignore on the line above
+// a dereference to opt out.
+package nilcheck
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+)
+
+var Analyzer = &analysis.Analyzer{
+	Name:     "nilcheck",
+	Doc:      "check that pointers are not dereferenced before, or unguarded after, their nil check",
+	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Run:      run,
+}
+
+const ignoreDirective = "//nilcheck:ignore"
+
+func run(pass *analysis.Pass) (any, error) {
+	insp := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+
+	ignored := ignoredLines(pass)
+	insp.Preorder([]ast.Node{(*ast.FuncDecl)(nil), (*ast.FuncLit)(nil)}, func(n ast.Node) {
+		var body *ast.BlockStmt
+		switch fn := n.(type) {
+		case *ast.FuncDecl:
+			body = fn.Body
+		case *ast.FuncLit:
+			body = fn.Body
+		}
+		if body == nil {
+			return
+		}
+
+		f := collect(pass, body)
+		for _, d := range f.derefs {
+			pos := pass.Fset.Position(d.pos)
+			if ignored[pos.Filename][pos.Line-1] {
+				continue
+			}
+			if msg := f.diagnose(pass, d); msg != "" {
+				pass.Reportf(d.pos, "%s", msg)
+			}
+		}
+	})
+
+	return nil, nil
+}
+
+// use is one occurrence of a path.
+type use struct {
+	path string // stable key of the path
+	name string // the path as written
+	pos  token.Pos
+}
+
+// unguarded is an if p == nil { ... } without else whose body neither
+// terminates nor assigns p; end is where the if statement ends.
+type unguarded struct {
+	use
+	end token.Pos
+}
+
+type span struct {
+	path     string
+	from, to token.Pos
+}
+
+// facts is what collect finds in one function body.
+type facts struct {
+	derefs    []use
+	checks    []use
+	unguarded []unguarded
+	assigns   []use
+	guards    []span
+}
+
+func collect(pass *analysis.Pass, body *ast.BlockStmt) *facts {
+	f := &facts{}
+	var stack []ast.Node
+	ast.Inspect(body, func(n ast.Node) bool {
+		if n == nil {
+			stack = stack[:len(stack)-1]
+			return true
+		}
+		if _, ok := n.(*ast.FuncLit); ok {
+			// analysed on its own
+			return false
+		}
+		var parent ast.Node
+		if len(stack) > 0 {
+			parent = stack[len(stack)-1]
+		}
+		stack = append(stack, n)
+
+		switch n := n.(type) {
+		case *ast.StarExpr:
+			if tv, ok := pass.TypesInfo.Types[n]; ok && tv.IsValue() {
+				if u, ok := pathOf(pass, n.X, n.Pos()); ok {
+					f.derefs = append(f.derefs, u)
+				}
+			}
+		case *ast.SelectorExpr:
+			// x.f on a pointer x reads through it
+			if sel, ok := pass.TypesInfo.Selections[n]; ok && sel.Kind() == types.FieldVal && isPointer(pass, n.X) {
+				if u, ok := pathOf(pass, n.X, n.Pos()); ok {
+					f.derefs = append(f.derefs, u)
+				}
+			}
+		case *ast.AssignStmt:
+			for _, lhs := range n.Lhs {
+				if u, ok := pathOf(pass, lhs, n.End()); ok {
+					f.assigns = append(f.assigns, u)
+				}
+			}
+		case *ast.UnaryExpr:
+			// &p lets anything assign p
+			if n.Op == token.AND {
+				if u, ok := pathOf(pass, n.X, n.Pos()); ok {
+					f.assigns = append(f.assigns, u)
+				}
+			}
+		case *ast.BinaryExpr:
+			if u, _, ok := nilComparison(pass, n); ok {
+				f.checks = append(f.checks, u)
+			}
+			// p != nil && q and p == nil || q: q only runs when p is set
+			if u, op, ok := nilComparison(pass, ast.Unparen(n.X)); ok &&
+				(n.Op == token.LAND && op == token.NEQ || n.Op == token.LOR && op == token.EQL) {
+				f.guards = append(f.guards, span{u.path, n.Y.Pos(), n.Y.End()})
+			}
+		case *ast.IfStmt:
+			f.ifGuards(pass, n, parent)
+		}
+		return true
+	})
+
+	return f
+}
+
+// ifGuards records what an if statement comparing a path with nil
+// guards: the body for p != nil, the else branch for p == nil, and the
+// rest of the enclosing block when the p == nil body terminates.
+func (f *facts) ifGuards(pass *analysis.Pass, n *ast.IfStmt, parent ast.Node) {
+	for _, cond := range conjuncts(n.Cond) {
+		if u, op, ok := nilComparison(pass, cond); ok && op == token.NEQ {
+			f.guards = append(f.guards, span{u.path, n.Body.Pos(), n.Body.End()})
+		}
+	}
+
+	u, op, ok := nilComparison(pass, ast.Unparen(n.Cond))
+	if !ok || op != token.EQL {
+		return
+	}
+	if n.Else != nil {
+		f.guards = append(f.guards, span{u.path, n.Else.Pos(), n.Else.End()})
+	}
+	if terminates(n.Body) {
+		if block, ok := parent.(*ast.BlockStmt); ok {
+			f.guards = append(f.guards, span{u.path, n.End(), block.End()})
+		} else if clause, ok := parent.(*ast.CaseClause); ok {
+			f.guards = append(f.guards, span{u.path, n.End(), clause.End()})
+		}
+		return
+	}
+	if n.Else == nil && !assigns(pass, n.Body, u.path) {
+		f.unguarded = append(f.unguarded, unguarded{u, n.End()})
+	}
+}
+
+// diagnose returns the message for d, or "" if d is fine.
+func (f *facts) diagnose(pass *analysis.Pass, d use) string {
+	if f.guarded(d) {
+		return ""
+	}
+	var first *use
+	for i := range f.checks {
+		if c := &f.checks[i]; c.path == d.path && (first == nil || c.pos < first.pos) {
+			first = c
+		}
+	}
+	if first == nil {
+		return ""
+	}
+
+	if d.pos < first.pos {
+		if f.assignedBetween(d.path, token.NoPos, d.pos) {
+			return ""
+		}
+		return d.name + " is dereferenced before its nil check on line " + line(pass, first.pos)
+	}
+
+	// the latest unguarded check before d, with no assignment since
+	var last *unguarded
+	for i := range f.unguarded {
+		c := &f.unguarded[i]
+		if c.path == d.path && c.end <= d.pos && (last == nil || c.pos > last.pos) {
+			last = c
+		}
+	}
+	if last == nil || f.assignedBetween(d.path, last.pos, d.pos) {
+		return ""
+	}
+	return d.name + " may be nil here: the nil check on line " + line(pass, last.pos) + " neither returns nor assigns it"
+}
+
+func (f *facts) guarded(d use) bool {
+	for _, g := range f.guards {
+		if g.path == d.path && g.from <= d.pos && d.pos < g.to {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *facts) assignedBetween(path string, from, to token.Pos) bool {
+	for _, a := range f.assigns {
+		if a.path == path && a.pos >= from && a.pos <= to {
+			return true
+		}
+	}
+	return false
+}
+
+// pathOf returns the path of a variable or a chain of field selections
+// rooted at one, like cfg.Port.
+func pathOf(pass *analysis.Pass, e ast.Expr, pos token.Pos) (use, bool) {
+	e = ast.Unparen(e)
+	var root *ast.Ident
+	for x := e; root == nil; {
+		//exhaustive:ignore
+		switch v := x.(type) {
+		case *ast.Ident:
+			root = v
+		case *ast.SelectorExpr:
+			if sel, ok := pass.TypesInfo.Selections[v]; !ok || sel.Kind() != types.FieldVal {
+				return use{}, false
+			}
+			x = ast.Unparen(v.X)
+		default:
+			return use{}, false
+		}
+	}
+	obj, ok := pass.TypesInfo.ObjectOf(root).(*types.Var)
+	if !ok {
+		return use{}, false
+	}
+	name := types.ExprString(e)
+	// the root's declaration tells shadowed variables apart
+	key := pass.Fset.Position(obj.Pos()).String() + ":" + name
+	return use{path: key, name: name, pos: pos}, true
+}
+
+// nilComparison matches p == nil and p != nil (either way round) for a
+// pointer path p.
+func nilComparison(pass *analysis.Pass, e ast.Expr) (use, token.Token, bool) {
+	b, ok := e.(*ast.BinaryExpr)
+	if !ok || b.Op != token.EQL && b.Op != token.NEQ {
+		return use{}, 0, false
+	}
+	x := b.X
+	if isNil(pass, x) {
+		x = b.Y
+	} else if !isNil(pass, b.Y) {
+		return use{}, 0, false
+	}
+	if !isPointer(pass, x) {
+		return use{}, 0, false
+	}
+	u, ok := pathOf(pass, x, b.Pos())
+	return u, b.Op, ok
+}
+
+func isNil(pass *analysis.Pass, e ast.Expr) bool {
+	tv, ok := pass.TypesInfo.Types[e]
+	return ok && tv.IsNil()
+}
+
+func isPointer(pass *analysis.Pass, e ast.Expr) bool {
+	t := pass.TypesInfo.TypeOf(e)
+	if t == nil {
+		return false
+	}
+	_, ok := t.Underlying().(*types.Pointer)
+	return ok
+}
+
+func conjuncts(e ast.Expr) []ast.Expr {
+	e = ast.Unparen(e)
+	if b, ok := e.(*ast.BinaryExpr); ok && b.Op == token.LAND {
+		return append(conjuncts(b.X), conjuncts(b.Y)...)
+	}
+	return []ast.Expr{e}
+}
+
+// terminates reports whether a block ends by leaving the enclosing flow:
+// return, panic, or a branch statement.
+func terminates(b *ast.BlockStmt) bool {
+	if len(b.List) == 0 {
+		return false
+	}
+	//exhaustive:ignore
+	switch s := b.List[len(b.List)-1].(type) {
+	case *ast.ReturnStmt, *ast.BranchStmt:
+		return true
+	case *ast.ExprStmt:
+		call, ok := s.X.(*ast.CallExpr)
+		if !ok {
+			return false
+		}
+		id, ok := ast.Unparen(call.Fun).(*ast.Ident)
+		return ok && id.Name == "panic"
+	}
+	return false
+}
+
+func assigns(pass *analysis.Pass, b *ast.BlockStmt, path string) bool {
+	found := false
+	ast.Inspect(b, func(n ast.Node) bool {
+		if as, ok := n.(*ast.AssignStmt); ok {
+			for _, lhs := range as.Lhs {
+				if u, ok := pathOf(pass, lhs, lhs.Pos()); ok && u.path == path {
+					found = true
+				}
+			}
+		}
+		return !found
+	})
+	return found
+}
+
+func line(pass *analysis.Pass, pos token.Pos) string {
+	return strconv.Itoa(pass.Fset.Position(pos).Line)
+}
+
+func ignoredLines(pass *analysis.Pass) map[string]map[int]bool {
+	out := map[string]map[int]bool{}
+	for _, f := range pass.Files {
+		for _, cg := range f.Comments {
+			for _, c := range cg.List {
+				if strings.HasPrefix(c.Text, ignoreDirective) {
+					pos := pass.Fset.Position(c.Pos())
+					if out[pos.Filename] == nil {
+						out[pos.Filename] = map[int]bool{}
+					}
+					out[pos.Filename][pos.Line] = true
+				}
+			}
+		}
+	}
+
+	return out
+}
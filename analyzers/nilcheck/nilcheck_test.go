@@ -0,0 +1,13 @@
+package nilcheck_test
+
+import (
+	"testing"
+
+	"golang.org/x/tools/go/analysis/analysistest"
+
+	"patterns/analyzers/nilcheck"
+)
+
+func TestAnalyzer(t *testing.T) {
+	analysistest.Run(t, analysistest.TestData(), nilcheck.Analyzer, "poor", "cases")
+}
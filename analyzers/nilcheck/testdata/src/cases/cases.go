@@ -0,0 +1,131 @@
+// Package cases exercises what nilcheck treats as a guard, and what it
+// does not, beyond the two functions in package poor.
+package cases
+
+type T struct{ P *int }
+
+// Never is not compared with nil, so nothing says it may be.
+func Never(p *int) int {
+	return *p
+}
+
+func Returns(p *int) int {
+	if p == nil {
+		return 0
+	}
+	return *p
+}
+
+func Panics(p *int) int {
+	if p == nil {
+		panic("nil p")
+	}
+	return *p
+}
+
+func Assigns(p *int) int {
+	if p == nil {
+		p = new(int)
+	}
+	return *p
+}
+
+func Else(p *int) int {
+	if p == nil {
+		println("nil")
+	} else {
+		return *p
+	}
+	return 0
+}
+
+func NotNil(t *T) int {
+	if t.P != nil && *t.P > 0 {
+		return *t.P
+	}
+	return 0
+}
+
+func Or(p *int) bool {
+	return p == nil || *p == 0
+}
+
+func AssignedLater(p *int) int {
+	if p == nil {
+		println("nil")
+	}
+	p = new(int)
+	return *p
+}
+
+func Address(p *int) int {
+	if p == nil {
+		println("nil")
+	}
+	reset(&p)
+	return *p
+}
+
+func reset(p **int) { *p = new(int) }
+
+func Ignored(p *int) int {
+	if p == nil {
+		println("nil")
+	}
+	//nilcheck:ignore
+	return *p
+}
+
+func Loop(ps []*int) (n int) {
+	for _, p := range ps {
+		if p == nil {
+			continue
+		}
+		n += *p
+	}
+	return n
+}
+
+func Switch(p *int, k int) int {
+	switch k {
+	case 0:
+		if p == nil {
+			return 0
+		}
+		return *p
+	}
+	return 1
+}
+
+func Field(t *T) int {
+	if t == nil {
+		println("nil")
+	}
+	return *t.P // want `t may be nil here`
+}
+
+// Shadowed compares the outer p, and dereferences an unrelated inner p.
+func Shadowed(p *int) int {
+	if p == nil {
+		println("nil")
+	}
+	{
+		p := new(int)
+		return *p
+	}
+}
+
+// Closure is analysed on its own: the check outside it does not carry
+// into the literal, and the literal's own check does.
+func Closure(p *int) func() int {
+	if p == nil {
+		println("nil")
+	}
+	return func() int {
+		q := p
+		if q == nil {
+			println("nil")
+		}
+		return *p + *q // want `q may be nil here`
+	}
+}
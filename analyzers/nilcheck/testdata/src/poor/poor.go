@@ -0,0 +1,68 @@
+// Package poor keeps the procedural and config struct NewServer as they
+// were before the port spec was implemented, for nilcheck to fire on:
+//
+//	go build -o patterncheck patterns/cmd/patterncheck
+//	go vet -vettool=./patterncheck ./analyzers/nilcheck/testdata/src/poor
+//
+// The want comments are the diagnostics the analyzer test expects.
+// Both take pointers so nil can mean "default", check for nil, and
+// dereference anyway.
+package poor
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+func ProceduralNewServer(addr string, port *int) (*http.Server, error) {
+	if *port < 0 { // want `port is dereferenced before its nil check on line 22`
+		return nil, errors.New("port cannot be negative")
+	}
+	if port == nil {
+		// use default port
+	}
+	if *port == 0 { // want `port may be nil here: the nil check on line 22 neither returns nor assigns it`
+		// use random port
+	}
+
+	return &http.Server{
+		Addr: addr + ":" + strconv.Itoa(*port), // want `port may be nil here`
+	}, nil
+}
+
+type Config struct {
+	Port *int
+}
+
+func ConfigStructNewServer(addr string, cfg *Config) (*http.Server, error) {
+	if cfg.Port == nil {
+		// use random port
+	}
+	if *cfg.Port < 0 { // want `cfg.Port may be nil here: the nil check on line 39 neither returns nor assigns it`
+		return nil, errors.New("port cannot be negative")
+	}
+	if *cfg.Port == 0 { // want `cfg.Port may be nil here`
+		// use default port
+	}
+
+	return &http.Server{
+		Addr: addr + ":" + strconv.Itoa(*cfg.Port), // want `cfg.Port may be nil here`
+	}, nil
+}
+
+// Fixed is the same function written correctly, which nilcheck accepts.
+func Fixed(addr string, port *int) (*http.Server, error) {
+	p := 8080
+	if port != nil {
+		if *port < 0 {
+			return nil, errors.New("port cannot be negative")
+		}
+		p = *port
+	}
+	if cfg := (&Config{Port: port}); cfg.Port != nil && *cfg.Port == 0 {
+		// use random port
+	}
+
+	return &http.Server{Addr: addr + ":" + strconv.Itoa(p)}, nil
+}
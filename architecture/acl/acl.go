@@ -0,0 +1,62 @@
+// Package acl is an anti-corruption layer: the one place that knows both
+// the ShipFast carrier's API (package carrier) and this application's
+// tracking model (package tracking), and translates the first into the
+// second, so the carrier's model never gets past it:
+//
+//	var t tracking.Tracker = acl.New(&carrier.Client{BaseURL: url})
+//	s, err := t.Track(ctx, "SF1001") // a tracking.Shipment
+//
+// The translation (translate.go) is a pure function from carrier.Shipment
+// to tracking.Shipment and carries every decision about the carrier's
+// quirks: which status codes mean what, which of "", "N/A" and a missing
+// field mean "unknown", how to read its dates, weights and places. What
+// it cannot make sense of is an error naming the carrier field, rather
+// than a guess that surfaces later as a wrong answer in domain code.
+//
+// A second carrier is a second translation to the same tracking model;
+// a change in ShipFast's API is a change here and nowhere else. The tests
+// track every recorded response into testdata/fixtures:
+//
+//	go test patterns/architecture/acl [-update]
+package acl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"patterns/architecture/acl/carrier"
+	"patterns/architecture/acl/tracking"
+)
+
+// ErrUntranslatable wraps the validate.Errors of a carrier response the
+// translation rejects.
+var ErrUntranslatable = errors.New("acl: untranslatable carrier response")
+
+// anti-corruption layer
+// Level: Good
+// pros: the domain model stays in the application's own terms, however
+// the external one looks and changes; the translation is pure, and
+// tested against recorded responses.
+// cons: a second model and a mapping to keep for every external system;
+// whatever the domain model cannot express is dropped at the boundary.
+type Tracker struct {
+	client *carrier.Client
+}
+
+var _ tracking.Tracker = (*Tracker)(nil)
+
+func New(client *carrier.Client) *Tracker { return &Tracker{client: client} }
+
+// Track fetches a shipment from the carrier and translates it; carrier
+// errors are translated too, so callers test for tracking.ErrNotFound.
+func (t *Tracker) Track(ctx context.Context, number string) (tracking.Shipment, error) {
+	s, err := t.client.Shipment(ctx, number)
+	if errors.Is(err, carrier.ErrNoSuchShipment) {
+		return tracking.Shipment{}, fmt.Errorf("%w: %s", tracking.ErrNotFound, number)
+	}
+	if err != nil {
+		return tracking.Shipment{}, err
+	}
+	return Translate(*s)
+}
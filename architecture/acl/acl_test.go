@@ -0,0 +1,103 @@
+package acl_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"patterns/architecture/acl"
+	"patterns/architecture/acl/carrier"
+	"patterns/architecture/acl/tracking"
+	"patterns/testing/golden"
+	"patterns/validate"
+)
+
+func tracker(t *testing.T, h http.Handler) tracking.Tracker {
+	t.Helper()
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+	return acl.New(&carrier.Client{BaseURL: srv.URL, HTTP: srv.Client()})
+}
+
+// render writes what the application sees of a shipment.
+func render(s tracking.Shipment) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: %s, %d g", s.Number, s.Status, s.WeightGrams)
+	if !s.ETA.IsZero() {
+		fmt.Fprintf(&b, ", expected %s", s.ETA.Format(time.DateOnly))
+	}
+	if at, ok := s.Delivered(); ok {
+		fmt.Fprintf(&b, ", delivered %s", at.Format(timeFormat))
+	}
+	b.WriteByte('\n')
+	for _, e := range s.Events {
+		fmt.Fprintf(&b, "%s  %-16s %-20q %-3q %q\n", e.At.Format(timeFormat), e.Status, e.Location.City, e.Location.Country, e.Note)
+	}
+	return b.String()
+}
+
+// timeFormat is RFC 3339 to the minute, the carrier's resolution.
+const timeFormat = "2006-01-02T15:04Z07:00"
+
+// TestTrackFixtures tracks every recorded ShipFast response through the
+// client and the translation, and compares what comes out with
+// testdata/fixtures/<number>.golden; SF1005 is the one the translation
+// must refuse, field by field.
+func TestTrackFixtures(t *testing.T) {
+	tr := tracker(t, carrier.Fixtures())
+	for _, n := range carrier.FixtureNumbers() {
+		t.Run(n, func(t *testing.T) {
+			s, err := tr.Track(context.Background(), n)
+			var out string
+			if err != nil {
+				if !errors.Is(err, acl.ErrUntranslatable) {
+					t.Fatalf("Track(%s) = %v, want a translation error", n, err)
+				}
+				var fields validate.Errors
+				if !errors.As(err, &fields) {
+					t.Fatalf("Track(%s) = %v, want validate.Errors inside", n, err)
+				}
+				for _, f := range fields {
+					out += f.Error() + "\n"
+				}
+			} else {
+				out = render(s)
+			}
+			golden.Assert(t, "fixtures/"+n, []byte(out))
+		})
+	}
+}
+
+func TestTrackNotFound(t *testing.T) {
+	_, err := tracker(t, carrier.Fixtures()).Track(context.Background(), "SF9999")
+	if !errors.Is(err, tracking.ErrNotFound) {
+		t.Errorf("Track(SF9999) = %v, want tracking.ErrNotFound", err)
+	}
+}
+
+func TestTrackCarrierErrors(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		h    http.HandlerFunc
+	}{
+		{"status", func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "down", http.StatusServiceUnavailable)
+		}},
+		{"other err", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"err":"RATE_LIMITED"}`))
+		}},
+		{"bad json", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"TrkNo":`))
+		}},
+	} {
+		_, err := tracker(t, c.h).Track(context.Background(), "SF1001")
+		if err == nil || errors.Is(err, tracking.ErrNotFound) || errors.Is(err, acl.ErrUntranslatable) {
+			t.Errorf("%s: Track = %v, want a carrier error", c.name, err)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+// Package tracking is the domain model of shipment tracking, written in
+// the language of this application and nothing else: it knows no carrier,
+// and no carrier's names, codes or formats leak into it.
+package tracking
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+//go:generate go run patterns/cmd/enumgen -type=Status -trimprefix=Status
+
+type Status int
+
+const (
+	StatusPending Status = iota
+	StatusInTransit
+	StatusOutForDelivery
+	StatusDelivered
+	// StatusException: lost, damaged, refused or otherwise stuck.
+	StatusException
+)
+
+var ErrNotFound = errors.New("tracking: shipment not found")
+
+type Shipment struct {
+	Number string
+	Status Status
+	// WeightGrams is 0 when the carrier did not weigh the parcel yet.
+	WeightGrams int
+	// ETA is the zero time when there is no estimate.
+	ETA time.Time
+	// Events are oldest first.
+	Events []Event
+}
+
+type Event struct {
+	At       time.Time
+	Status   Status
+	Location Location
+	// Note is free text for people, never for decisions.
+	Note string
+}
+
+type Location struct {
+	City string
+	// Country is an ISO 3166-1 alpha-2 code, or empty when unknown.
+	Country string
+}
+
+// Delivered reports whether the shipment has arrived, and when.
+func (s Shipment) Delivered() (time.Time, bool) {
+	for i := len(s.Events) - 1; i >= 0; i-- {
+		if s.Events[i].Status == StatusDelivered {
+			return s.Events[i].At, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// Tracker is the port the application tracks shipments through.
+type Tracker interface {
+	Track(ctx context.Context, number string) (Shipment, error)
+}
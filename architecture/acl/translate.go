@@ -0,0 +1,144 @@
+package acl
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"patterns/architecture/acl/carrier"
+	"patterns/architecture/acl/tracking"
+	"patterns/validate"
+)
+
+// statuses maps ShipFast's codes to the domain's: a pick-up and a hold at
+// a hub are both just in transit to us, and every exception is one.
+var statuses = map[string]tracking.Status{
+	"NEW":     tracking.StatusPending,
+	"PU":      tracking.StatusInTransit,
+	"IT":      tracking.StatusInTransit,
+	"HOLD":    tracking.StatusInTransit,
+	"OFD":     tracking.StatusOutForDelivery,
+	"DLVRD":   tracking.StatusDelivered,
+	"EXC_DMG": tracking.StatusException,
+	"EXC_RTS": tracking.StatusException,
+	"EXC_LST": tracking.StatusException,
+}
+
+// Translate turns a ShipFast shipment into a domain one. Unknown status
+// codes, unreadable dates and weights fail, each reported under its
+// carrier field name; missing weights, ETAs, time zones and places are
+// normal and become the domain's zero values.
+func Translate(s carrier.Shipment) (tracking.Shipment, error) {
+	var v validate.Validator
+	out := tracking.Shipment{Number: strings.TrimSpace(s.TrkNo)}
+	validate.Field(&v, "TrkNo", out.Number, validate.NonEmpty[string]())
+	out.Status = status(&v, "StatCd", s.StatCd)
+	out.WeightGrams = weight(&v, "wgt", s.Wgt)
+	out.ETA = eta(&v, "ETA", s.ETA)
+	for i, e := range s.Hist {
+		ev := v.Index("Hist", i)
+		out.Events = append(out.Events, tracking.Event{
+			At:       eventTime(ev, e),
+			Status:   status(ev, "code", e.Code),
+			Location: location(e.Loc),
+			Note:     note(e.Desc),
+		})
+	}
+	if err := v.Err(); err != nil {
+		return tracking.Shipment{}, fmt.Errorf("%w %s: %w", ErrUntranslatable, s.TrkNo, err)
+	}
+	// the carrier usually, but not always, lists events in order
+	slices.SortStableFunc(out.Events, func(a, b tracking.Event) int { return a.At.Compare(b.At) })
+	return out, nil
+}
+
+func status(v *validate.Validator, field, code string) tracking.Status {
+	st, ok := statuses[strings.ToUpper(strings.TrimSpace(code))]
+	v.Check(field, ok, fmt.Sprintf("unknown status code %q", code))
+	return st
+}
+
+// weight reads "12.5 LBS", "2.3KG" and the like into grams.
+func weight(v *validate.Validator, field, s string) int {
+	s = strings.ToUpper(strings.ReplaceAll(s, " ", ""))
+	if s == "" {
+		return 0
+	}
+	var perUnit float64
+	switch {
+	case strings.HasSuffix(s, "LBS"):
+		s, perUnit = strings.TrimSuffix(s, "LBS"), 453.59237
+	case strings.HasSuffix(s, "KG"):
+		s, perUnit = strings.TrimSuffix(s, "KG"), 1000
+	}
+	n, err := strconv.ParseFloat(s, 64)
+	if perUnit == 0 || err != nil || n < 0 {
+		v.Fail(field, "weight is not a number of LBS or KG")
+		return 0
+	}
+	return int(math.Round(n * perUnit))
+}
+
+func eta(v *validate.Validator, field, s string) time.Time {
+	s = strings.TrimSpace(s)
+	if s == "" || strings.EqualFold(s, "N/A") {
+		return time.Time{}
+	}
+	t, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		v.Fail(field, "date is not YYYY-MM-DD")
+	}
+	return t
+}
+
+func eventTime(v *validate.Validator, e carrier.Event) time.Time {
+	tz := e.Tz
+	if tz == "" {
+		tz = "+0000"
+	}
+	t, err := time.Parse("20060102 1504 -0700", e.Dt+" "+e.Tm+" "+tz)
+	if err != nil {
+		v.Fail("dt", "date and time are not YYYYMMDD, HHMM and ±HHMM")
+	}
+	return t.UTC()
+}
+
+// location reads "CITY, CC"; a place it cannot split is all city.
+func location(s string) tracking.Location {
+	s = strings.TrimSpace(s)
+	if s == "" || strings.EqualFold(s, "UNKNOWN") {
+		return tracking.Location{}
+	}
+	city, country, ok := strings.Cut(s, ",")
+	country = strings.TrimSpace(country)
+	if !ok || len(country) != 2 {
+		return tracking.Location{City: title(s)}
+	}
+	return tracking.Location{City: title(city), Country: strings.ToUpper(country)}
+}
+
+// note turns the carrier's shouting into a sentence.
+func note(s string) string {
+	return capitalize(strings.ToLower(strings.TrimSpace(s)))
+}
+
+func title(s string) string {
+	words := strings.Fields(strings.ToLower(s))
+	for i, w := range words {
+		words[i] = capitalize(w)
+	}
+	return strings.Join(words, " ")
+}
+
+func capitalize(s string) string {
+	r, n := utf8.DecodeRuneInString(s)
+	if n == 0 {
+		return s
+	}
+	return string(unicode.ToUpper(r)) + s[n:]
+}
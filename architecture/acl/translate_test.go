@@ -0,0 +1,116 @@
+package acl_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"patterns/architecture/acl"
+	"patterns/architecture/acl/carrier"
+	"patterns/architecture/acl/tracking"
+	"patterns/validate"
+)
+
+func TestTranslateWeight(t *testing.T) {
+	for _, c := range []struct {
+		wgt  string
+		want int
+	}{
+		{"", 0},
+		{"12.5 LBS", 5670},
+		{"0.8 lbs", 363},
+		{"2.3KG", 2300},
+		{" 1 kg ", 1000},
+		{"0KG", 0},
+	} {
+		s, err := acl.Translate(carrier.Shipment{TrkNo: "SF1", StatCd: "NEW", Wgt: c.wgt})
+		if err != nil || s.WeightGrams != c.want {
+			t.Errorf("wgt %q: %d g, %v; want %d g", c.wgt, s.WeightGrams, err, c.want)
+		}
+	}
+}
+
+func TestTranslateETA(t *testing.T) {
+	for _, c := range []struct {
+		eta  string
+		want time.Time
+	}{
+		{"", time.Time{}},
+		{"N/A", time.Time{}},
+		{"n/a", time.Time{}},
+		{"2024-01-09", time.Date(2024, 1, 9, 0, 0, 0, 0, time.UTC)},
+	} {
+		s, err := acl.Translate(carrier.Shipment{TrkNo: "SF1", StatCd: "NEW", ETA: c.eta})
+		if err != nil || !s.ETA.Equal(c.want) {
+			t.Errorf("ETA %q: %v, %v; want %v", c.eta, s.ETA, err, c.want)
+		}
+	}
+}
+
+func TestTranslateEvents(t *testing.T) {
+	s, err := acl.Translate(carrier.Shipment{
+		TrkNo:  " SF1 ",
+		StatCd: " dlvrd",
+		Hist: []carrier.Event{
+			{Dt: "20240106", Tm: "1432", Tz: "+0100", Loc: "munich ,de", Code: "DLVRD", Desc: " DELIVERED "},
+			// listed out of order, and without a time zone: UTC
+			{Dt: "20240105", Tm: "0915", Loc: "NEW  YORK", Code: "hold", Desc: ""},
+			{Dt: "20240105", Tm: "2300", Loc: "UNKNOWN", Code: "IT"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := tracking.Shipment{
+		Number: "SF1",
+		Status: tracking.StatusDelivered,
+		Events: []tracking.Event{
+			{At: time.Date(2024, 1, 5, 9, 15, 0, 0, time.UTC), Status: tracking.StatusInTransit, Location: tracking.Location{City: "New York"}},
+			{At: time.Date(2024, 1, 5, 23, 0, 0, 0, time.UTC), Status: tracking.StatusInTransit},
+			{At: time.Date(2024, 1, 6, 13, 32, 0, 0, time.UTC), Status: tracking.StatusDelivered,
+				Location: tracking.Location{City: "Munich", Country: "DE"}, Note: "Delivered"},
+		},
+	}
+	if s.Number != want.Number || s.Status != want.Status || len(s.Events) != len(want.Events) {
+		t.Fatalf("Translate = %+v, want %+v", s, want)
+	}
+	for i := range want.Events {
+		if g, w := s.Events[i], want.Events[i]; !g.At.Equal(w.At) || g.Status != w.Status || g.Location != w.Location || g.Note != w.Note {
+			t.Errorf("event %d = %+v, want %+v", i, g, w)
+		}
+	}
+	if at, ok := s.Delivered(); !ok || !at.Equal(want.Events[2].At) {
+		t.Errorf("Delivered() = %v, %v", at, ok)
+	}
+}
+
+// TestTranslateRefuses checks that each field the translation cannot
+// read is reported under its carrier name, all of them at once.
+func TestTranslateRefuses(t *testing.T) {
+	_, err := acl.Translate(carrier.Shipment{
+		StatCd: "LOST_IN_SPACE",
+		Wgt:    "12 stone",
+		ETA:    "tomorrow",
+		Hist:   []carrier.Event{{Dt: "20240105", Tm: "0915", Code: "PU"}, {Dt: "20240105", Tm: "9915", Code: "XX"}},
+	})
+	if !errors.Is(err, acl.ErrUntranslatable) {
+		t.Fatalf("Translate = %v, want ErrUntranslatable", err)
+	}
+	var fields validate.Errors
+	if !errors.As(err, &fields) {
+		t.Fatalf("Translate = %v, want validate.Errors", err)
+	}
+	var paths []string
+	for _, f := range fields {
+		paths = append(paths, f.Path)
+	}
+	want := []string{"TrkNo", "StatCd", "wgt", "ETA", "Hist[1].dt", "Hist[1].code"}
+	if len(paths) != len(want) {
+		t.Fatalf("failed fields %v, want %v", paths, want)
+	}
+	for i := range want {
+		if paths[i] != want[i] {
+			t.Fatalf("failed fields %v, want %v", paths, want)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+// Package di wires a small notes app, repository → service → handler,
+// without a framework, and contrasts the ways a piece of code can get
+// what it depends on:
+//
+//   - constructor injection: NewNotes takes the store, the clock and the
+//     id source as parameters, so a caller sees everything it needs and
+//     a test passes fakes
+//   - a composition root: Wire is the one function that knows the
+//     concrete types, builds them in order and hands the result to main
+//   - interfaces declared where they are used: Notes asks for a NoteStore
+//     of two methods, not the five of repository.Repository, so a fake
+//     is two methods long and the service cannot grow a use of Delete
+//     unnoticed
+//   - a package-level default and a service locator, both looking their
+//     dependencies up at call time, as the poor alternatives
+//
+// The tests drive the handler over a fake store, in plain Go.
+// examples/crud wires the same shape with a reflective container; here
+// the compiler checks the wiring instead, and a missing dependency is a
+// build error rather than a Resolve failing at startup.
+package di
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"patterns/clock"
+)
+
+var ErrEmpty = errors.New("di: empty note")
+
+// Note is a piece of text with the time it was written.
+type Note struct {
+	ID      string    `json:"id"`
+	Text    string    `json:"text"`
+	Created time.Time `json:"created"`
+}
+
+// interface at consumption site
+// Level: Good
+// pros: the consumer states the least it needs, so fakes are small, any
+// store with the two methods fits without knowing of this package, and a
+// wider use shows up as a change to the interface.
+// cons: the same small interface may be declared in several consumers;
+// that duplication is the point, but it looks like repetition.
+//
+// NoteStore is what Notes needs of storage; repository.Memory and
+// repository.File satisfy it without naming it.
+type NoteStore interface {
+	Get(ctx context.Context, id string) (Note, error)
+	// Create fails with repository.ErrExists if id is taken.
+	Create(ctx context.Context, id string, n Note) error
+}
+
+// constructor injection
+// Level: Good
+// pros: every dependency is a parameter, so the signature is the list of
+// what the type needs; nothing is looked up later, and two instances with
+// different stores coexist in one process, as parallel tests need.
+// cons: constructors of types deep in the graph take long parameter
+// lists, and someone, the composition root, has to build all of it.
+//
+// Notes is the service: it checks and stamps notes and stores them.
+type Notes struct {
+	store NoteStore
+	clock clock.Clock
+	newID func() string
+}
+
+// NewNotes returns a service storing in store, stamping with c and naming
+// notes with newID.
+func NewNotes(store NoteStore, c clock.Clock, newID func() string) *Notes {
+	return &Notes{store: store, clock: c, newID: newID}
+}
+
+// Create stores text, trimmed, as a new note.
+func (s *Notes) Create(ctx context.Context, text string) (Note, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return Note{}, ErrEmpty
+	}
+	n := Note{ID: s.newID(), Text: text, Created: s.clock.Now()}
+	if err := s.store.Create(ctx, n.ID, n); err != nil {
+		return Note{}, fmt.Errorf("di: create note: %w", err)
+	}
+	return n, nil
+}
+
+// Get returns the note with id; the error wraps repository.ErrNotFound if
+// there is none.
+func (s *Notes) Get(ctx context.Context, id string) (Note, error) {
+	n, err := s.store.Get(ctx, id)
+	if err != nil {
+		return Note{}, fmt.Errorf("di: get note %q: %w", id, err)
+	}
+	return n, nil
+}
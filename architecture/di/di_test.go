@@ -0,0 +1,179 @@
+package di_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"patterns/architecture/di"
+	"patterns/clock"
+	"patterns/persistence/repository"
+)
+
+var epoch = time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+
+// fakeStore is the whole of a NoteStore fake: two methods, because the
+// service asks for no more. It records what it was asked and fails on
+// demand.
+type fakeStore struct {
+	notes   map[string]di.Note
+	calls   []string
+	failing error
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{notes: map[string]di.Note{}}
+}
+
+func (f *fakeStore) Get(ctx context.Context, id string) (di.Note, error) {
+	f.calls = append(f.calls, "Get "+id)
+	if f.failing != nil {
+		return di.Note{}, f.failing
+	}
+	n, ok := f.notes[id]
+	if !ok {
+		return di.Note{}, repository.ErrNotFound
+	}
+	return n, nil
+}
+
+func (f *fakeStore) Create(ctx context.Context, id string, n di.Note) error {
+	f.calls = append(f.calls, "Create "+id)
+	if f.failing != nil {
+		return f.failing
+	}
+	f.notes[id] = n
+	return nil
+}
+
+// server is the app under test, wired as Wire would but from fakes.
+type server struct {
+	store *fakeStore
+	url   string
+}
+
+func newServer(t *testing.T) *server {
+	t.Helper()
+	store := newFakeStore()
+	ids := 0
+	notes := di.NewNotes(store, clock.NewFake(epoch), func() string {
+		ids++
+		return fmt.Sprintf("id%d", ids)
+	})
+	srv := httptest.NewServer(di.Handler(notes))
+	t.Cleanup(srv.Close)
+	return &server{store: store, url: srv.URL}
+}
+
+// do sends a request and returns the status and body.
+func (s *server) do(t *testing.T, method, path, body string) (int, string) {
+	t.Helper()
+	req, err := http.NewRequest(method, s.url+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, path, err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("%s %s: reading body: %v", method, path, err)
+	}
+	return resp.StatusCode, string(b)
+}
+
+func expect[V comparable](t *testing.T, what string, got, want V) {
+	t.Helper()
+	if got != want {
+		t.Fatalf("%s = %v, want %v", what, got, want)
+	}
+}
+
+var cases = []struct {
+	name string
+	test func(t *testing.T)
+}{
+	{"create then get", func(t *testing.T) {
+		s := newServer(t)
+		status, body := s.do(t, "POST", "/notes", "  buy milk \n")
+		expect(t, "POST status", status, http.StatusCreated)
+		var n di.Note
+		if err := json.Unmarshal([]byte(body), &n); err != nil {
+			t.Fatalf("POST body %q: %v", body, err)
+		}
+		want := di.Note{ID: "id1", Text: "buy milk", Created: epoch}
+		expect(t, "created note", n, want)
+		expect(t, "stored note", s.store.notes["id1"], want)
+
+		status, body = s.do(t, "GET", "/notes/id1", "")
+		expect(t, "GET status", status, http.StatusOK)
+		var got di.Note
+		json.Unmarshal([]byte(body), &got)
+		expect(t, "fetched note", got, want)
+	}},
+	{"missing note", func(t *testing.T) {
+		s := newServer(t)
+		status, _ := s.do(t, "GET", "/notes/nope", "")
+		expect(t, "GET status", status, http.StatusNotFound)
+		expect(t, "store calls", strings.Join(s.store.calls, ", "), "Get nope")
+	}},
+	{"empty note never reaches the store", func(t *testing.T) {
+		s := newServer(t)
+		status, _ := s.do(t, "POST", "/notes", " \t")
+		expect(t, "POST status", status, http.StatusBadRequest)
+		expect(t, "store calls", len(s.store.calls), 0)
+	}},
+	{"store failure stays internal", func(t *testing.T) {
+		s := newServer(t)
+		s.store.failing = errors.New("disk on fire")
+		status, body := s.do(t, "POST", "/notes", "x")
+		expect(t, "POST status", status, http.StatusInternalServerError)
+		expect(t, "body mentions the cause", strings.Contains(body, "fire"), false)
+	}},
+	{"two apps side by side", func(t *testing.T) {
+		a, b := newServer(t), newServer(t)
+		a.do(t, "POST", "/notes", "only in a")
+		status, _ := b.do(t, "GET", "/notes/id1", "")
+		expect(t, "GET from the other app", status, http.StatusNotFound)
+	}},
+	{"package default must be swapped and restored", func(t *testing.T) {
+		old := di.DefaultStore
+		t.Cleanup(func() { di.DefaultStore = old })
+		store := newFakeStore()
+		di.DefaultStore = store
+		n, err := di.CreateNote(context.Background(), "global")
+		if err != nil {
+			t.Fatalf("CreateNote: %v", err)
+		}
+		expect(t, "stored in the swapped default", store.notes[n.ID].Text, "global")
+	}},
+	{"locator fails at the first call", func(t *testing.T) {
+		notes := di.LocatedNotes{Services: di.NewLocator()}
+		_, err := notes.Get(context.Background(), "id1")
+		expect(t, "Get without a registration fails", err != nil, true)
+		notes.Services.Register("notes", "not a store")
+		_, err = notes.Get(context.Background(), "id1")
+		expect(t, "Get with a mistyped registration fails", err != nil, true)
+		notes.Services.Register("notes", di.NoteStore(newFakeStore()))
+		_, err = notes.Get(context.Background(), "id1")
+		expect(t, "Get with a store registered wraps ErrNotFound", errors.Is(err, repository.ErrNotFound), true)
+	}},
+}
+
+// TestNotes checks the notes app the way a test would, with no framework:
+// each case builds Notes over a fake NoteStore, by hand, and drives
+// Handler through httptest.
+func TestNotes(t *testing.T) {
+	for _, c := range cases {
+		t.Run(c.name, c.test)
+	}
+}
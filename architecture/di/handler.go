@@ -0,0 +1,65 @@
+package di
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"patterns/persistence/repository"
+)
+
+// NoteService is what Handler needs of the service: the handler is a
+// consumer too, and declares its own interface rather than take *Notes.
+type NoteService interface {
+	Create(ctx context.Context, text string) (Note, error)
+	Get(ctx context.Context, id string) (Note, error)
+}
+
+// Handler serves notes over HTTP:
+//
+//	POST /notes       the body is the text; 201 with the note as JSON
+//	GET  /notes/{id}  200 with the note, 404 if there is none
+func Handler(svc NoteService) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /notes", func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(io.LimitReader(r.Body, 1<<16))
+		if err != nil {
+			http.Error(w, "reading body", http.StatusBadRequest)
+			return
+		}
+		n, err := svc.Create(r.Context(), string(body))
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, n)
+	})
+	mux.HandleFunc("GET /notes/{id}", func(w http.ResponseWriter, r *http.Request) {
+		n, err := svc.Get(r.Context(), r.PathValue("id"))
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, n)
+	})
+	return mux
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrEmpty):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, repository.ErrNotFound):
+		http.Error(w, "no such note", http.StatusNotFound)
+	default:
+		http.Error(w, "internal error", http.StatusInternalServerError)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
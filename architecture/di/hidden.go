@@ -0,0 +1,111 @@
+package di
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"patterns/persistence/repository"
+)
+
+// package-level dependency
+// Level: Poor
+// pros: no wiring at all: callers just call CreateNote.
+// cons: the dependency is invisible in every signature; a test swaps it
+// by assigning the variable, so tests cannot run in parallel and one that
+// forgets to restore it breaks the next; two stores in one process are
+// impossible.
+//
+// DefaultStore is the store CreateNote and GetNote use.
+var DefaultStore NoteStore = repository.NewMemory[string, Note]()
+
+var lastID struct {
+	sync.Mutex
+	n int
+}
+
+// CreateNote stores text as a new note in DefaultStore, stamped with the
+// wall clock, which no test can control.
+func CreateNote(ctx context.Context, text string) (Note, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return Note{}, ErrEmpty
+	}
+	lastID.Lock()
+	lastID.n++
+	id := fmt.Sprintf("n%d", lastID.n)
+	lastID.Unlock()
+	n := Note{ID: id, Text: text, Created: time.Now()}
+	if err := DefaultStore.Create(ctx, id, n); err != nil {
+		return Note{}, fmt.Errorf("di: create note: %w", err)
+	}
+	return n, nil
+}
+
+// GetNote returns the note with id from DefaultStore.
+func GetNote(ctx context.Context, id string) (Note, error) {
+	n, err := DefaultStore.Get(ctx, id)
+	if err != nil {
+		return Note{}, fmt.Errorf("di: get note %q: %w", id, err)
+	}
+	return n, nil
+}
+
+// service locator
+// Level: Poor
+// pros: dependencies can be registered late and replaced at run time, and
+// constructors take nothing.
+// cons: what a type needs is only found by reading its methods; a missing
+// or mistyped registration is a runtime failure on the first call that
+// needs it, not at startup, and a test must know the registry's keys.
+//
+// Locator maps names to services.
+type Locator struct {
+	mu       sync.RWMutex
+	services map[string]any
+}
+
+func NewLocator() *Locator {
+	return &Locator{services: map[string]any{}}
+}
+
+func (l *Locator) Register(name string, svc any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.services[name] = svc
+}
+
+// Lookup returns the service registered as name, if it is a T.
+func Lookup[T any](l *Locator, name string) (T, error) {
+	l.mu.RLock()
+	svc, ok := l.services[name]
+	l.mu.RUnlock()
+	t, isT := svc.(T)
+	switch {
+	case !ok:
+		return t, fmt.Errorf("di: no service %q", name)
+	case !isT:
+		return t, fmt.Errorf("di: service %q is a %T, not a %T", name, svc, t)
+	}
+	return t, nil
+}
+
+// LocatedNotes is Notes fetching its store from a Locator on every call.
+type LocatedNotes struct {
+	Services *Locator
+}
+
+// Get returns the note with id from the store registered as "notes".
+func (s LocatedNotes) Get(ctx context.Context, id string) (Note, error) {
+	store, err := Lookup[NoteStore](s.Services, "notes")
+	if err != nil {
+		return Note{}, err
+	}
+	n, err := store.Get(ctx, id)
+	if err != nil {
+		return Note{}, fmt.Errorf("di: get note %q: %w", id, err)
+	}
+	return n, nil
+}
@@ -0,0 +1,53 @@
+package di
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+
+	"patterns/clock"
+	"patterns/persistence/repository"
+)
+
+// Config is what the app needs from the outside world.
+type Config struct {
+	// File is where notes are kept; empty keeps them in memory.
+	File string
+	// Clock stamps notes; nil means the real one.
+	Clock clock.Clock
+}
+
+// App is the wired application; main serves Handler.
+type App struct {
+	Notes   *Notes
+	Handler http.Handler
+}
+
+// composition root
+// Level: Good
+// pros: the whole object graph is built in one function, in order, with
+// plain calls the compiler checks; the rest of the code never sees a
+// concrete dependency, and swapping one means editing one place.
+// cons: it grows with the app and must be kept by hand; nothing builds
+// the graph lazily or per request unless written to.
+//
+// Wire builds the store, the service and the handler from cfg. It is the
+// only code that names repository.File or clock.Real.
+func Wire(cfg Config) (*App, error) {
+	var store NoteStore = repository.NewMemory[string, Note]()
+	if cfg.File != "" {
+		f, err := repository.OpenFile[string, Note](cfg.File)
+		if err != nil {
+			return nil, err
+		}
+		store = f
+	}
+	notes := NewNotes(store, clock.Or(cfg.Clock), randomID)
+	return &App{Notes: notes, Handler: Handler(notes)}, nil
+}
+
+func randomID() string {
+	var b [8]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
@@ -0,0 +1,102 @@
+package eventsourcing
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrNoAccount    = errors.New("eventsourcing: no such account")
+	ErrOpen         = errors.New("eventsourcing: account already open")
+	ErrInsufficient = errors.New("eventsourcing: insufficient funds")
+	ErrAmount       = errors.New("eventsourcing: amount must be positive")
+)
+
+// The events of an account stream, by type.
+type (
+	Opened    struct{ Owner string }
+	Deposited struct{ Amount int64 }
+	Withdrawn struct{ Amount int64 }
+)
+
+// account is the state Accounts decides on, folded from a stream.
+type account struct {
+	open    bool
+	balance int64
+	version int
+}
+
+func fold(events []Event) (account, error) {
+	var a account
+	for _, e := range events {
+		switch e.Type {
+		case "opened":
+			a.open = true
+		case "deposited":
+			var d Deposited
+			if err := e.Decode(&d); err != nil {
+				return a, err
+			}
+			a.balance += d.Amount
+		case "withdrawn":
+			var w Withdrawn
+			if err := e.Decode(&w); err != nil {
+				return a, err
+			}
+			a.balance -= w.Amount
+		}
+		a.version = e.Version
+	}
+	return a, nil
+}
+
+// Accounts handles the commands on accounts, one stream per account.
+type Accounts struct {
+	store *Store
+}
+
+func NewAccounts(s *Store) *Accounts {
+	return &Accounts{store: s}
+}
+
+func (as *Accounts) Open(id, owner string) error {
+	_, err := as.store.Append(id, 0, Record{"opened", Opened{owner}})
+	if errors.Is(err, ErrConflict) {
+		return fmt.Errorf("%s: %w", id, ErrOpen)
+	}
+	return err
+}
+
+func (as *Accounts) Deposit(id string, amount int64) error {
+	return as.decide(id, amount, func(account) error { return nil }, Record{"deposited", Deposited{amount}})
+}
+
+func (as *Accounts) Withdraw(id string, amount int64) error {
+	return as.decide(id, amount, func(a account) error {
+		if a.balance < amount {
+			return fmt.Errorf("%s: %w", id, ErrInsufficient)
+		}
+		return nil
+	}, Record{"withdrawn", Withdrawn{amount}})
+}
+
+// decide appends e to account id if check passes on its current state;
+// a conflict means another command got there first, and the caller may
+// retry.
+func (as *Accounts) decide(id string, amount int64, check func(account) error, e Record) error {
+	if amount <= 0 {
+		return ErrAmount
+	}
+	a, err := fold(as.store.Load(id))
+	if err != nil {
+		return err
+	}
+	if !a.open {
+		return fmt.Errorf("%s: %w", id, ErrNoAccount)
+	}
+	if err := check(a); err != nil {
+		return err
+	}
+	_, err = as.store.Append(id, a.version, e)
+	return err
+}
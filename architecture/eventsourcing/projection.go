@@ -0,0 +1,131 @@
+package eventsourcing
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"patterns/concurrency/structured"
+)
+
+// Projection is a read model built by applying the store's events in
+// order. Apply is called with every event, of every stream, once.
+type Projection interface {
+	// Name identifies the projection's checkpoint.
+	Name() string
+	Apply(e Event) error
+	// Reset empties the projection, for a rebuild.
+	Reset()
+}
+
+// Checkpoints remember how far each projection has applied the store.
+// A projection stored outside the process must save its checkpoint in
+// the same transaction as its state, or else be idempotent by Seq: a
+// crash between the two would otherwise apply events twice.
+type Checkpoints interface {
+	Load(name string) uint64
+	Save(name string, seq uint64)
+}
+
+// MemoryCheckpoints is Checkpoints in a map, safe for concurrent use.
+type MemoryCheckpoints struct {
+	mu  sync.Mutex
+	seq map[string]uint64
+}
+
+func NewMemoryCheckpoints() *MemoryCheckpoints {
+	return &MemoryCheckpoints{seq: map[string]uint64{}}
+}
+
+func (c *MemoryCheckpoints) Load(name string) uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.seq[name]
+}
+
+func (c *MemoryCheckpoints) Save(name string, seq uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.seq[name] = seq
+}
+
+// rebuildable projection
+// Level: Good
+// pros: a read model is a cache of the log, so a bug in one is fixed by
+// fixing the code and replaying, and a new one starts with all of
+// history; checkpoints make a long replay resumable.
+// cons: a rebuild reads the whole log, which grows forever; until it
+// catches up the projection serves stale or partial answers.
+//
+// Projector catches projections up with Store, saving each one's
+// checkpoint every Every events and when it stops.
+type Projector struct {
+	Store       *Store
+	Checkpoints Checkpoints
+	// Every is how many events apart checkpoints are saved; 0 saves
+	// only when a catch-up stops.
+	Every int
+	// OnProgress, if set, is called after each checkpoint with the
+	// projection's name, its position and the store's last event at
+	// the start of the catch-up. Projections caught up together call
+	// it from their own goroutines.
+	OnProgress func(name string, seq, last uint64)
+}
+
+// CatchUp applies to p the events after its checkpoint, up to the
+// latest, and returns how many it applied. It stops early, checkpointed,
+// when ctx is done or Apply fails.
+func (pr *Projector) CatchUp(ctx context.Context, p Projection) (int, error) {
+	name := p.Name()
+	from := pr.Checkpoints.Load(name)
+	last := pr.Store.Last()
+	seq, applied := from, 0
+	save := func() {
+		pr.Checkpoints.Save(name, seq)
+		if pr.OnProgress != nil {
+			pr.OnProgress(name, seq, last)
+		}
+	}
+	for e := range pr.Store.Read(from) {
+		if e.Seq > last {
+			break
+		}
+		if err := ctx.Err(); err != nil {
+			save()
+			return applied, err
+		}
+		if err := p.Apply(e); err != nil {
+			save()
+			return applied, fmt.Errorf("eventsourcing: projection %s at event %d: %w", name, e.Seq, err)
+		}
+		seq = e.Seq
+		applied++
+		if pr.Every > 0 && applied%pr.Every == 0 {
+			save()
+		}
+	}
+	if pr.Every == 0 || applied%pr.Every != 0 {
+		save()
+	}
+	return applied, nil
+}
+
+// Rebuild empties p and applies the whole store to it.
+func (pr *Projector) Rebuild(ctx context.Context, p Projection) (int, error) {
+	p.Reset()
+	pr.Checkpoints.Save(p.Name(), 0)
+	return pr.CatchUp(ctx, p)
+}
+
+// CatchUpAll catches ps up concurrently, each from its own checkpoint;
+// the first failure cancels the others, checkpointed where they were.
+func (pr *Projector) CatchUpAll(ctx context.Context, ps ...Projection) error {
+	g, ctx := structured.WithContext(ctx)
+	for _, p := range ps {
+		g.Go(func() error {
+			_, err := pr.CatchUp(ctx, p)
+			return err
+		})
+	}
+	return g.Wait()
+}
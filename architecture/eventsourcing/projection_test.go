@@ -0,0 +1,171 @@
+package eventsourcing_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"math/rand/v2"
+	"testing"
+
+	es "patterns/architecture/eventsourcing"
+)
+
+// views is one set of the projections, compared by their states.
+type views struct {
+	balances *es.Balances
+	totals   *es.OwnerTotals
+}
+
+func newViews() views {
+	return views{es.NewBalances(), es.NewOwnerTotals()}
+}
+
+func (v views) all() []es.Projection { return []es.Projection{v.balances, v.totals} }
+
+func (v views) equal(w views) bool {
+	return maps.Equal(v.balances.State(), w.balances.State()) && maps.Equal(v.totals.State(), w.totals.State())
+}
+
+// workload is a store after a seeded random run of account commands,
+// with one set of projections kept up to date after every command.
+type workload struct {
+	store       *es.Store
+	live        views
+	incremental *es.Projector
+	ids         []string
+}
+
+func newWorkload(t *testing.T, commands int, seed uint64) *workload {
+	t.Helper()
+	ctx := context.Background()
+	w := &workload{store: es.NewStore(), live: newViews()}
+	w.incremental = &es.Projector{Store: w.store, Checkpoints: es.NewMemoryCheckpoints()}
+	accounts := es.NewAccounts(w.store)
+
+	// commands that fail, such as overdrafts, append nothing, as they
+	// would in production
+	r := rand.New(rand.NewPCG(seed, 0))
+	owners := []string{"ann", "bo", "cy"}
+	rejected := 0
+	for i := range commands {
+		var err error
+		switch n := r.IntN(10); {
+		case n == 0 || len(w.ids) == 0:
+			id := fmt.Sprintf("acct-%d", len(w.ids)+1)
+			w.ids = append(w.ids, id)
+			err = accounts.Open(id, owners[r.IntN(len(owners))])
+		case n < 6:
+			err = accounts.Deposit(w.ids[r.IntN(len(w.ids))], int64(r.IntN(100)+1))
+		default:
+			err = accounts.Withdraw(w.ids[r.IntN(len(w.ids))], int64(r.IntN(150)+1))
+		}
+		if errors.Is(err, es.ErrInsufficient) {
+			rejected++
+		} else if err != nil {
+			t.Fatalf("command %d: %v", i, err)
+		}
+		if err := w.incremental.CatchUpAll(ctx, w.live.all()...); err != nil {
+			t.Fatalf("incremental catch-up: %v", err)
+		}
+	}
+	if rejected == 0 || rejected == commands {
+		t.Fatalf("%d of %d commands rejected; the workload exercises too little", rejected, commands)
+	}
+	t.Logf("%d commands, %d rejected, %d events in %d accounts", commands, rejected, w.store.Last(), len(w.ids))
+	return w
+}
+
+// TestRebuildFromScratch rebuilds a fresh set concurrently,
+// checkpointing every 100 events, and compares it with the set kept up
+// to date.
+func TestRebuildFromScratch(t *testing.T) {
+	w := newWorkload(t, 2000, 1)
+	rebuilt := newViews()
+	var progress []uint64
+	cps := es.NewMemoryCheckpoints()
+	pr := &es.Projector{Store: w.store, Checkpoints: cps, Every: 100, OnProgress: func(name string, seq, last uint64) {
+		if name == "balances" {
+			progress = append(progress, seq)
+		}
+	}}
+	if err := pr.CatchUpAll(context.Background(), rebuilt.all()...); err != nil {
+		t.Fatalf("CatchUpAll: %v", err)
+	}
+	if !rebuilt.equal(w.live) {
+		t.Errorf("the rebuilt projections differ from the incremental ones")
+	}
+	if len(progress) == 0 || progress[len(progress)-1] != w.store.Last() || !increasing(progress) {
+		t.Errorf("balances checkpointed at %v, last event %d", progress, w.store.Last())
+	}
+	if got := cps.Load("balances"); got != w.store.Last() {
+		t.Errorf("checkpoint %d, want %d", got, w.store.Last())
+	}
+}
+
+// TestInterruptedRebuildResumes stops a rebuild part way and resumes it
+// from its checkpoint: each event is applied once.
+func TestInterruptedRebuildResumes(t *testing.T) {
+	w := newWorkload(t, 2000, 1)
+	half := newViews()
+	cps := es.NewMemoryCheckpoints()
+	stopAt := w.store.Last() / 2
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	pr := &es.Projector{Store: w.store, Checkpoints: cps, Every: 50, OnProgress: func(name string, seq, _ uint64) {
+		if name == "balances" && seq >= stopAt {
+			cancel()
+		}
+	}}
+	err := pr.CatchUpAll(ctx, half.all()...)
+	stopped := cps.Load("balances")
+	if !errors.Is(err, context.Canceled) || stopped >= w.store.Last() {
+		t.Fatalf("interrupted run: %v, stopped at %d of %d", err, stopped, w.store.Last())
+	}
+	pr.OnProgress = nil
+	if err := pr.CatchUpAll(context.Background(), half.all()...); err != nil {
+		t.Fatalf("resumed run: %v", err)
+	}
+	if !half.equal(w.live) {
+		t.Errorf("stopped at %d and resumed to %d, the projections differ", stopped, cps.Load("balances"))
+	}
+}
+
+// TestRebuildInPlace rebuilds the live projections over themselves.
+func TestRebuildInPlace(t *testing.T) {
+	w := newWorkload(t, 2000, 1)
+	before := maps.Clone(w.live.balances.State())
+	n, err := w.incremental.Rebuild(context.Background(), w.live.balances)
+	if err != nil {
+		t.Fatalf("Rebuild: %v", err)
+	}
+	if n != int(w.store.Last()) || !maps.Equal(before, w.live.balances.State()) {
+		t.Errorf("replayed %d of %d events; balances equal: %v", n, w.store.Last(), maps.Equal(before, w.live.balances.State()))
+	}
+}
+
+// TestDivergedProjectionIsDetected checks the comparison the other tests
+// rely on tells a wrong projection apart.
+func TestDivergedProjectionIsDetected(t *testing.T) {
+	w := newWorkload(t, 200, 1)
+	wrong := newViews()
+	if err := (&es.Projector{Store: w.store, Checkpoints: es.NewMemoryCheckpoints()}).CatchUpAll(context.Background(), wrong.all()...); err != nil {
+		t.Fatalf("CatchUpAll: %v", err)
+	}
+	if !wrong.equal(w.live) {
+		t.Fatalf("the projections differ before the extra event")
+	}
+	wrong.balances.Apply(es.Event{Stream: w.ids[0], Type: "deposited", Data: []byte(`{"Amount":1}`)})
+	if wrong.equal(w.live) {
+		t.Errorf("one extra deposit went unnoticed")
+	}
+}
+
+func increasing(xs []uint64) bool {
+	for i := 1; i < len(xs); i++ {
+		if xs[i] <= xs[i-1] {
+			return false
+		}
+	}
+	return true
+}
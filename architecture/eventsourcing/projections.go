@@ -0,0 +1,108 @@
+package eventsourcing
+
+import (
+	"maps"
+	"sync"
+)
+
+// Balances is the balance of every account; safe for concurrent use.
+type Balances struct {
+	mu       sync.Mutex
+	balances map[string]int64
+}
+
+func NewBalances() *Balances {
+	return &Balances{balances: map[string]int64{}}
+}
+
+func (b *Balances) Name() string { return "balances" }
+
+func (b *Balances) Apply(e Event) error {
+	var delta int64
+	switch e.Type {
+	case "opened":
+	case "deposited":
+		var d Deposited
+		if err := e.Decode(&d); err != nil {
+			return err
+		}
+		delta = d.Amount
+	case "withdrawn":
+		var w Withdrawn
+		if err := e.Decode(&w); err != nil {
+			return err
+		}
+		delta = -w.Amount
+	default:
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.balances[e.Stream] += delta
+	return nil
+}
+
+func (b *Balances) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	clear(b.balances)
+}
+
+// State returns a copy of the balances by account.
+func (b *Balances) State() map[string]int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return maps.Clone(b.balances)
+}
+
+// OwnerTotals is how much each owner has deposited over all their
+// accounts; it needs the owner of each account, from its opened event,
+// so it only works applied from the start of the store.
+type OwnerTotals struct {
+	mu     sync.Mutex
+	owners map[string]string // account to owner
+	totals map[string]int64
+}
+
+func NewOwnerTotals() *OwnerTotals {
+	return &OwnerTotals{owners: map[string]string{}, totals: map[string]int64{}}
+}
+
+func (o *OwnerTotals) Name() string { return "owner-totals" }
+
+func (o *OwnerTotals) Apply(e Event) error {
+	switch e.Type {
+	case "opened":
+		var op Opened
+		if err := e.Decode(&op); err != nil {
+			return err
+		}
+		o.mu.Lock()
+		defer o.mu.Unlock()
+		o.owners[e.Stream] = op.Owner
+		o.totals[op.Owner] += 0
+	case "deposited":
+		var d Deposited
+		if err := e.Decode(&d); err != nil {
+			return err
+		}
+		o.mu.Lock()
+		defer o.mu.Unlock()
+		o.totals[o.owners[e.Stream]] += d.Amount
+	}
+	return nil
+}
+
+func (o *OwnerTotals) Reset() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	clear(o.owners)
+	clear(o.totals)
+}
+
+// State returns a copy of the totals by owner.
+func (o *OwnerTotals) State() map[string]int64 {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return maps.Clone(o.totals)
+}
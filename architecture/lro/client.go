@@ -0,0 +1,137 @@
+package lro
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Client starts and polls operations served by Handler.
+type Client struct {
+	// BaseURL is where Handler is served, such as http://localhost:8080.
+	BaseURL string
+	// HTTP is the client to send requests with; nil is http.DefaultClient.
+	HTTP *http.Client
+}
+
+// Handle is one operation, on the client.
+type Handle struct {
+	c  *Client
+	ID string
+}
+
+// Start starts an operation of kind with input, marshalled as JSON, and
+// returns its Handle. An unknown kind is ErrUnknownKind.
+func (c *Client) Start(ctx context.Context, kind string, input any) (*Handle, error) {
+	body, err := json.Marshal(input)
+	if err != nil {
+		return nil, err
+	}
+	var op Operation
+	status, err := c.do(ctx, http.MethodPost, "/operations/"+url.PathEscape(kind), body, &op)
+	if err != nil {
+		if status == http.StatusNotFound {
+			err = fmt.Errorf("%w: %q", ErrUnknownKind, kind)
+		}
+		return nil, err
+	}
+	return c.Handle(op.ID), nil
+}
+
+// Handle returns the Handle of the operation with id, started elsewhere.
+func (c *Client) Handle(id string) *Handle { return &Handle{c: c, ID: id} }
+
+// Poll returns the operation as it is now.
+func (h *Handle) Poll(ctx context.Context) (Operation, error) {
+	var op Operation
+	_, err := h.c.do(ctx, http.MethodGet, h.path(""), nil, &op)
+	return op, err
+}
+
+// Wait polls the operation every so often until it is done, and returns
+// it; it stops with ctx, but the operation goes on without it.
+func (h *Handle) Wait(ctx context.Context, every time.Duration) (Operation, error) {
+	t := time.NewTicker(every)
+	defer t.Stop()
+	for {
+		op, err := h.Poll(ctx)
+		if err != nil || op.Done {
+			return op, err
+		}
+		select {
+		case <-ctx.Done():
+			return op, ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// Result unmarshals the result of the succeeded operation into v. It
+// fails with ErrNotDone if the operation is not done, and with an
+// *OperationError if it failed or was canceled.
+func (h *Handle) Result(ctx context.Context, v any) error {
+	_, err := h.c.do(ctx, http.MethodGet, h.path("/result"), nil, v)
+	return err
+}
+
+// Cancel cancels the operation and returns it: canceled if it was
+// pending, still running with CancelRequested if it was running. It
+// fails with ErrDone if the operation is done.
+func (h *Handle) Cancel(ctx context.Context) (Operation, error) {
+	var op Operation
+	_, err := h.c.do(ctx, http.MethodPost, h.path("/cancel"), nil, &op)
+	return op, err
+}
+
+func (h *Handle) path(suffix string) string {
+	return "/operations/" + url.PathEscape(h.ID) + suffix
+}
+
+// do sends a request and decodes a 2xx answer into v; other answers are
+// turned back into the errors of Manager.
+func (c *Client) do(ctx context.Context, method, path string, body []byte, v any) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	httpc := c.HTTP
+	if httpc == nil {
+		httpc = http.DefaultClient
+	}
+	resp, err := httpc.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return resp.StatusCode, err
+	}
+	if resp.StatusCode/100 == 2 {
+		return resp.StatusCode, json.Unmarshal(raw, v)
+	}
+
+	var eb errorBody
+	if err := json.Unmarshal(raw, &eb); err != nil || eb.Error == "" {
+		return resp.StatusCode, fmt.Errorf("lro: %s %s: %s", method, path, resp.Status)
+	}
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return resp.StatusCode, fmt.Errorf("%w: %s", ErrNotFound, path)
+	case resp.StatusCode == http.StatusConflict && eb.Operation != nil && !eb.Operation.Done:
+		return resp.StatusCode, fmt.Errorf("%w: %s is %s", ErrNotDone, eb.Operation.ID, eb.Operation.State)
+	case resp.StatusCode == http.StatusConflict && eb.Operation != nil && method == http.MethodPost:
+		return resp.StatusCode, fmt.Errorf("%w: %s is %s", ErrDone, eb.Operation.ID, eb.Operation.State)
+	case resp.StatusCode == http.StatusConflict && eb.Operation != nil:
+		return resp.StatusCode, &OperationError{Op: *eb.Operation}
+	}
+	return resp.StatusCode, fmt.Errorf("lro: %s %s: %s: %s", method, path, resp.Status, eb.Error)
+}
@@ -0,0 +1,334 @@
+package lro_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"patterns/architecture/lro"
+	"patterns/examples/jobqueue"
+)
+
+const (
+	poll    = 5 * time.Millisecond
+	timeout = 5 * time.Second
+)
+
+type doubleIn struct {
+	N int `json:"n"`
+}
+
+type doubleOut struct {
+	Doubled int `json:"doubled"`
+}
+
+// env is one store, its runner and its server.
+type env struct {
+	path    string
+	store   *jobqueue.Store
+	manager *lro.Manager
+	client  *lro.Client
+	url     string
+
+	// ran counts the calls of every Func; started is sent to when a
+	// block operation runs, and blockErr what its context ended with
+	ran      atomic.Int32
+	started  chan struct{}
+	blockErr chan error
+	flaky    atomic.Int32
+
+	run func()
+}
+
+// newEnv sets up a case; the runner starts with env.run, or at once if
+// running is set.
+func newEnv(t *testing.T, running bool) *env {
+	t.Helper()
+	e := &env{path: filepath.Join(t.TempDir(), "store.json"), started: make(chan struct{}, 1), blockErr: make(chan error, 1)}
+	e.open(t)
+	if running {
+		e.run()
+	}
+	return e
+}
+
+// open opens the store at e.path with a manager, a runner not yet
+// started and a server, which the cleanup stops.
+func (e *env) open(t *testing.T) {
+	t.Helper()
+	store, err := jobqueue.OpenStore(e.path)
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	e.store = store
+	e.manager = lro.NewManager(store, e.funcs(), nil)
+	runner, err := jobqueue.NewRunner(store, e.manager.Handlers(),
+		jobqueue.WithWorkers(2), jobqueue.WithRetry(3, poll, 2*poll),
+		jobqueue.WithPollInterval(poll), jobqueue.WithLease(timeout))
+	if err != nil {
+		t.Fatalf("runner: %v", err)
+	}
+	srv := httptest.NewServer(lro.Handler(e.manager, time.Second))
+	e.url = srv.URL
+	e.client = &lro.Client{BaseURL: srv.URL, HTTP: srv.Client()}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	close(stopped)
+	e.run = func() {
+		stopped = make(chan struct{})
+		go func() {
+			defer close(stopped)
+			_ = runner.Run(ctx)
+		}()
+	}
+	t.Cleanup(func() {
+		cancel()
+		<-stopped
+		srv.Close()
+	})
+}
+
+func (e *env) funcs() map[string]lro.Func {
+	count := func(f lro.Func) lro.Func {
+		return func(ctx context.Context, input json.RawMessage) (any, error) {
+			e.ran.Add(1)
+			return f(ctx, input)
+		}
+	}
+	return map[string]lro.Func{
+		"double": count(func(_ context.Context, input json.RawMessage) (any, error) {
+			var in doubleIn
+			if err := json.Unmarshal(input, &in); err != nil {
+				return nil, jobqueue.Permanent(err)
+			}
+			if in.N < 0 {
+				return nil, jobqueue.Permanent(fmt.Errorf("n is %d, must not be negative", in.N))
+			}
+			return doubleOut{Doubled: 2 * in.N}, nil
+		}),
+		// flaky fails its first two calls
+		"flaky": count(func(context.Context, json.RawMessage) (any, error) {
+			if e.flaky.Add(1) <= 2 {
+				return nil, errors.New("upstream unavailable")
+			}
+			return doubleOut{Doubled: 0}, nil
+		}),
+		"down": count(func(context.Context, json.RawMessage) (any, error) {
+			return nil, errors.New("upstream unavailable")
+		}),
+		// block runs until it is cancelled
+		"block": count(func(ctx context.Context, _ json.RawMessage) (any, error) {
+			e.started <- struct{}{}
+			<-ctx.Done()
+			e.blockErr <- context.Cause(ctx)
+			return nil, ctx.Err()
+		}),
+	}
+}
+
+func background() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), timeout)
+}
+
+// finish waits for h to be done and checks what it ended as.
+func finish(t *testing.T, h *lro.Handle, want lro.State) lro.Operation {
+	t.Helper()
+	ctx, cancel := background()
+	defer cancel()
+	op, err := h.Wait(ctx, poll)
+	if err != nil {
+		t.Fatalf("wait for %s: %v", h.ID, err)
+	}
+	if op.State != want || !op.Done {
+		t.Fatalf("%s is %s (done %v), want %s: %+v", h.ID, op.State, op.Done, want, op)
+	}
+	return op
+}
+
+func start(t *testing.T, e *env, kind string, input any) *lro.Handle {
+	t.Helper()
+	ctx, cancel := background()
+	defer cancel()
+	h, err := e.client.Start(ctx, kind, input)
+	if err != nil {
+		t.Fatalf("start %s: %v", kind, err)
+	}
+	return h
+}
+
+func waitStarted(t *testing.T, e *env) {
+	t.Helper()
+	select {
+	case <-e.started:
+	case <-time.After(timeout):
+		t.Fatalf("the operation did not start")
+	}
+}
+
+var cases = []struct {
+	name string
+	run  func(t *testing.T)
+}{
+	{"succeeded: polled until done, then its result", func(t *testing.T) {
+		e := newEnv(t, true)
+		h := start(t, e, "double", doubleIn{N: 21})
+		finish(t, h, lro.Succeeded)
+		var out doubleOut
+		if err := h.Result(context.Background(), &out); err != nil || out.Doubled != 42 {
+			t.Fatalf("result %+v, %v", out, err)
+		}
+	}},
+	{"succeeded after transient errors, retried by the queue", func(t *testing.T) {
+		e := newEnv(t, true)
+		h := start(t, e, "flaky", nil)
+		op := finish(t, h, lro.Succeeded)
+		if n := e.ran.Load(); n != 3 || op.Error != "" {
+			t.Fatalf("%d calls and error %q, want 3 and none", n, op.Error)
+		}
+	}},
+	{"failed: a permanent error, at once", func(t *testing.T) {
+		e := newEnv(t, true)
+		h := start(t, e, "double", doubleIn{N: -1})
+		op := finish(t, h, lro.Failed)
+		if !strings.Contains(op.Error, "must not be negative") || e.ran.Load() != 1 {
+			t.Fatalf("error %q after %d calls", op.Error, e.ran.Load())
+		}
+		var oe *lro.OperationError
+		if err := h.Result(context.Background(), new(doubleOut)); !errors.As(err, &oe) || oe.Op.State != lro.Failed {
+			t.Fatalf("result: %v", err)
+		}
+		if ds := e.store.DeadLetters(); len(ds) != 1 || ds[0].Reason != jobqueue.ReasonPermanent {
+			t.Fatalf("dead letters %+v", ds)
+		}
+	}},
+	{"failed: retries exhausted, reported from the dead letter", func(t *testing.T) {
+		e := newEnv(t, true)
+		h := start(t, e, "down", nil)
+		op := finish(t, h, lro.Failed)
+		if want := jobqueue.ReasonExhausted + ": upstream unavailable"; op.Error != want || e.ran.Load() != 3 {
+			t.Fatalf("error %q after %d calls, want %q after 3", op.Error, e.ran.Load(), want)
+		}
+	}},
+	{"canceled while pending: the Func never runs", func(t *testing.T) {
+		e := newEnv(t, false)
+		h := start(t, e, "double", doubleIn{N: 1})
+		op, err := h.Cancel(context.Background())
+		if err != nil || op.State != lro.Canceled {
+			t.Fatalf("cancel: %+v, %v", op, err)
+		}
+		e.run()
+		// the job is still claimed, and done without calling the Func
+		for deadline := time.Now().Add(timeout); e.store.Jobs()[0].State != jobqueue.Done; time.Sleep(poll) {
+			if time.Now().After(deadline) {
+				t.Fatalf("the job is %s", e.store.Jobs()[0].State)
+			}
+		}
+		finish(t, h, lro.Canceled)
+		if n := e.ran.Load(); n != 0 {
+			t.Fatalf("the Func ran %d times", n)
+		}
+	}},
+	{"canceled while running: the Func's context is cancelled", func(t *testing.T) {
+		e := newEnv(t, true)
+		h := start(t, e, "block", nil)
+		waitStarted(t, e)
+		op, err := h.Cancel(context.Background())
+		if err != nil || op.State != lro.Running || !op.CancelRequested {
+			t.Fatalf("cancel: %+v, %v", op, err)
+		}
+		finish(t, h, lro.Canceled)
+		if err := <-e.blockErr; !errors.Is(err, context.Canceled) {
+			t.Fatalf("the Func's context ended with %v", err)
+		}
+		if err := h.Result(context.Background(), new(any)); err == nil {
+			t.Fatalf("a canceled operation has a result")
+		}
+	}},
+	{"not done: the result is 409 with Retry-After", func(t *testing.T) {
+		e := newEnv(t, true)
+		h := start(t, e, "block", nil)
+		waitStarted(t, e)
+		if err := h.Result(context.Background(), new(any)); !errors.Is(err, lro.ErrNotDone) {
+			t.Fatalf("result of a running operation: %v", err)
+		}
+		resp, err := http.Get(e.url + "/operations/" + h.ID + "/result")
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusConflict || resp.Header.Get("Retry-After") != "1" {
+			t.Fatalf("%s, Retry-After %q", resp.Status, resp.Header.Get("Retry-After"))
+		}
+		op, err := h.Poll(context.Background())
+		if err != nil || op.State != lro.Running {
+			t.Fatalf("poll: %+v, %v", op, err)
+		}
+		h.Cancel(context.Background())
+		finish(t, h, lro.Canceled)
+	}},
+	{"starting is 202 with Location", func(t *testing.T) {
+		e := newEnv(t, false)
+		resp, err := http.Post(e.url+"/operations/double", "application/json", strings.NewReader(`{"n":2}`))
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		defer resp.Body.Close()
+		var op lro.Operation
+		if err := json.NewDecoder(resp.Body).Decode(&op); err != nil {
+			t.Fatalf("%v", err)
+		}
+		if resp.StatusCode != http.StatusAccepted || resp.Header.Get("Location") != "/operations/"+op.ID || op.State != lro.Pending {
+			t.Fatalf("%s, Location %q, %+v", resp.Status, resp.Header.Get("Location"), op)
+		}
+	}},
+	{"cancelling a done operation is ErrDone", func(t *testing.T) {
+		e := newEnv(t, true)
+		h := start(t, e, "double", doubleIn{N: 1})
+		finish(t, h, lro.Succeeded)
+		if _, err := h.Cancel(context.Background()); !errors.Is(err, lro.ErrDone) {
+			t.Fatalf("cancel: %v", err)
+		}
+		finish(t, h, lro.Succeeded)
+	}},
+	{"unknown operations and kinds are 404", func(t *testing.T) {
+		e := newEnv(t, false)
+		if _, err := e.client.Handle("op-99").Poll(context.Background()); !errors.Is(err, lro.ErrNotFound) {
+			t.Fatalf("poll: %v", err)
+		}
+		if _, err := e.client.Start(context.Background(), "resize", nil); !errors.Is(err, lro.ErrUnknownKind) {
+			t.Fatalf("start: %v", err)
+		}
+	}},
+	{"an operation outlives the process that started it", func(t *testing.T) {
+		e := newEnv(t, false)
+		h := start(t, e, "double", doubleIn{N: 5})
+		// a new process on the same store
+		e.open(t)
+		e.run()
+		h = e.client.Handle(h.ID)
+		finish(t, h, lro.Succeeded)
+		var out doubleOut
+		if err := h.Result(context.Background(), &out); err != nil || out.Doubled != 10 {
+			t.Fatalf("result %+v, %v", out, err)
+		}
+	}},
+}
+
+// TestEndToEnd runs operations into each of their terminal states: a
+// store in a temporary directory, a jobqueue.Runner working it,
+// lro.Handler served by httptest, and lro.Client starting, polling,
+// fetching and cancelling. Each case has its own store.
+func TestEndToEnd(t *testing.T) {
+	for _, c := range cases {
+		t.Run(c.name, c.run)
+	}
+}
@@ -0,0 +1,112 @@
+package lro
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"patterns/web/handler"
+)
+
+// errorBody is the body of every failed request; Operation is set when
+// the failure is the operation's state.
+type errorBody struct {
+	Error     string     `json:"error"`
+	Operation *Operation `json:"operation,omitempty"`
+}
+
+// Handler serves the operations of m:
+//
+//	POST /operations/{kind}        start one, the body its input: 202 and the operation
+//	GET  /operations/{id}          the operation
+//	GET  /operations/{id}/result   its result once succeeded, 409 until then
+//	POST /operations/{id}/cancel   cancel it: the operation, 409 if done
+//
+// An unknown kind or ID is 404. The answers about an operation not done
+// have a Retry-After of retryAfter, rounded up to seconds, telling clients
+// how often to poll.
+func Handler(m *Manager, retryAfter time.Duration) http.Handler {
+	wait := strconv.Itoa(int((retryAfter + time.Second - 1) / time.Second))
+	writeOp := func(w http.ResponseWriter, status int, op Operation) {
+		if !op.Done {
+			w.Header().Set("Retry-After", wait)
+		}
+		handler.WriteJSON(w, status, op)
+	}
+	fail := func(w http.ResponseWriter, err error, op *Operation) {
+		status := http.StatusInternalServerError
+		var oe *OperationError
+		switch {
+		case errors.Is(err, ErrNotFound), errors.Is(err, ErrUnknownKind):
+			status = http.StatusNotFound
+		case errors.Is(err, ErrNotDone):
+			status = http.StatusConflict
+			w.Header().Set("Retry-After", wait)
+		case errors.Is(err, ErrDone), errors.As(err, &oe):
+			status = http.StatusConflict
+		}
+		handler.WriteJSON(w, status, errorBody{Error: err.Error(), Operation: op})
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /operations/{kind}", func(w http.ResponseWriter, r *http.Request) {
+		input, err := io.ReadAll(http.MaxBytesReader(w, r.Body, 1<<20))
+		if err == nil && len(input) > 0 && !json.Valid(input) {
+			err = errors.New("input is not JSON")
+		}
+		if err != nil {
+			handler.WriteJSON(w, http.StatusBadRequest, errorBody{Error: err.Error()})
+			return
+		}
+		var raw json.RawMessage // null without a body
+		if len(input) > 0 {
+			raw = input
+		}
+		op, err := m.Start(r.PathValue("kind"), raw)
+		if err != nil {
+			fail(w, err, nil)
+			return
+		}
+		w.Header().Set("Location", "/operations/"+op.ID)
+		writeOp(w, http.StatusAccepted, op)
+	})
+	mux.HandleFunc("GET /operations/{id}", func(w http.ResponseWriter, r *http.Request) {
+		op, err := m.Get(r.PathValue("id"))
+		if err != nil {
+			fail(w, err, nil)
+			return
+		}
+		writeOp(w, http.StatusOK, op)
+	})
+	mux.HandleFunc("GET /operations/{id}/result", func(w http.ResponseWriter, r *http.Request) {
+		op, err := m.Result(r.PathValue("id"))
+		switch {
+		case errors.Is(err, ErrNotFound):
+			fail(w, err, nil)
+		case err != nil:
+			fail(w, err, &op)
+		default:
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write(op.Result)
+		}
+	})
+	mux.HandleFunc("POST /operations/{id}/cancel", func(w http.ResponseWriter, r *http.Request) {
+		op, err := m.Cancel(r.PathValue("id"))
+		if errors.Is(err, ErrDone) {
+			// answer with what it ended as
+			if op, gerr := m.Get(r.PathValue("id")); gerr == nil {
+				fail(w, err, &op)
+				return
+			}
+		}
+		if err != nil {
+			fail(w, err, nil)
+			return
+		}
+		writeOp(w, http.StatusOK, op)
+	})
+	return mux
+}
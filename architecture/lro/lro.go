@@ -0,0 +1,349 @@
+// Package lro turns slow work into long-running operations: a request
+// starts the work and gets an Operation at once, 202 Accepted with its
+// ID, and the client polls the operation until it is done, then fetches
+// the result, or cancels it on the way.
+//
+//	pending -> running -> succeeded | failed
+//	    \          \
+//	     +----------+--> canceled
+//
+// The work runs on examples/jobqueue: Start writes the operation and its
+// job in one Update, so neither exists without the other, and the job's
+// handler, from Manager.Handlers, moves the operation through its states.
+// The queue's guarantees carry over: a worker that dies leaves the job to
+// be run again once its lease expires, so an operation's Func must be
+// idempotent; a transient error is retried with backoff while the
+// operation stays running; and a job the queue gives up on, retries
+// exhausted, is reported as a failed operation the next time someone
+// asks for it.
+//
+// Cancel ends a pending operation at once; a running one has its Func's
+// context cancelled and is canceled when the Func returns. Handler serves
+// the operations over HTTP and Client polls them through a Handle:
+//
+//	h, err := client.Start(ctx, "resize", Resize{ID: 7, Width: 640})
+//	op, err := h.Wait(ctx, time.Second)
+//	err = h.Result(ctx, &thumb)
+//
+// The tests run each terminal state end to end, through HTTP and a real
+// queue:
+//
+//	go test -race -v patterns/architecture/lro
+package lro
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"patterns/clock"
+	"patterns/examples/jobqueue"
+)
+
+// State is where an operation is in its life.
+type State string
+
+const (
+	Pending   State = "pending"
+	Running   State = "running"
+	Succeeded State = "succeeded"
+	Failed    State = "failed"
+	Canceled  State = "canceled"
+)
+
+// Done reports whether s is terminal: the operation will not change
+// again.
+func (s State) Done() bool { return s == Succeeded || s == Failed || s == Canceled }
+
+var (
+	ErrNotFound    = errors.New("lro: no such operation")
+	ErrUnknownKind = errors.New("lro: unknown kind of operation")
+	// ErrDone is returned by Cancel for an operation already done.
+	ErrDone = errors.New("lro: operation is done")
+	// ErrNotDone is returned by Result for an operation still pending or
+	// running.
+	ErrNotDone = errors.New("lro: operation is not done")
+)
+
+// OperationError is returned by Result for an operation that failed or
+// was canceled, so it has no result.
+type OperationError struct {
+	Op Operation
+}
+
+func (e *OperationError) Error() string {
+	s := fmt.Sprintf("lro: operation %s %s", e.Op.ID, e.Op.State)
+	if e.Op.Error != "" {
+		s += ": " + e.Op.Error
+	}
+	return s
+}
+
+// Operation is the state of one piece of work, as the client sees it.
+type Operation struct {
+	ID    string `json:"id"`
+	Kind  string `json:"kind"`
+	State State  `json:"state"`
+	Done  bool   `json:"done"`
+	// Result is the Func's result, once succeeded.
+	Result json.RawMessage `json:"result,omitempty"`
+	// Error is why it failed, or the last transient error of a running
+	// one, which will be retried.
+	Error   string    `json:"error,omitempty"`
+	Created time.Time `json:"created"`
+	Updated time.Time `json:"updated"`
+
+	Job             int64 `json:"job"`
+	CancelRequested bool  `json:"cancel_requested,omitempty"`
+}
+
+// Func does the work of one kind of operation, given its input, and
+// returns its result, marshalled as JSON. It must be idempotent: a job
+// can run more than once. Errors are retried by the queue unless marked
+// with jobqueue.Permanent, which fails the operation at once.
+type Func func(ctx context.Context, input json.RawMessage) (any, error)
+
+// long-running operation
+// Level: Good
+// pros: the request that starts slow work returns at once, so no client,
+// proxy or load balancer timeout cuts it short; the operation outlives
+// the connection, the process and a crashed worker; its state is one GET
+// away, and it can be cancelled.
+// cons: clients must poll, and choose how often; finished operations have
+// to be expired eventually, and a Func must be idempotent.
+//
+// Manager starts, tracks and cancels operations kept in a jobqueue.Store.
+type Manager struct {
+	store *jobqueue.Store
+	funcs map[string]Func
+	clock clock.Clock
+
+	mu sync.Mutex
+	// cancels holds the cancel funcs of the operations running in this
+	// process, by ID
+	cancels map[string]context.CancelFunc
+}
+
+// NewManager returns a Manager for the operations of funcs, by kind, in
+// store; a nil clock is the real one.
+func NewManager(store *jobqueue.Store, funcs map[string]Func, c clock.Clock) *Manager {
+	return &Manager{store: store, funcs: funcs, clock: clock.Or(c), cancels: map[string]context.CancelFunc{}}
+}
+
+// jobKind is the queue's kind for the jobs of operations of kind, so each
+// kind has its own circuit breaker.
+func jobKind(kind string) string { return "lro." + kind }
+
+func recordKey(id string) string { return "lro:" + id }
+
+// Handlers returns the queue handlers running the operations, to give to
+// jobqueue.NewRunner along with any others.
+func (m *Manager) Handlers() map[string]jobqueue.Handler {
+	hs := make(map[string]jobqueue.Handler, len(m.funcs))
+	for kind, f := range m.funcs {
+		hs[jobKind(kind)] = func(ctx context.Context, job jobqueue.Job) error { return m.run(ctx, job, f) }
+	}
+	return hs
+}
+
+// Start creates a pending operation of kind with input, marshalled as
+// JSON, and enqueues its job.
+func (m *Manager) Start(kind string, input any) (Operation, error) {
+	if _, ok := m.funcs[kind]; !ok {
+		return Operation{}, fmt.Errorf("%w: %q", ErrUnknownKind, kind)
+	}
+	now := m.clock.Now()
+	var op Operation
+	err := m.store.Update(func(tx *jobqueue.Tx) error {
+		// the job's ID is unique in the store, so it names the operation
+		job, err := tx.Enqueue(jobKind(kind), input, 0)
+		if err != nil {
+			return err
+		}
+		op = Operation{ID: "op-" + strconv.FormatInt(job, 10), Kind: kind, State: Pending, Created: now, Updated: now, Job: job}
+		return put(tx, op)
+	})
+	return op, err
+}
+
+// Get returns the operation with id.
+func (m *Manager) Get(id string) (Operation, error) {
+	var op Operation
+	var err error
+	m.store.View(func(tx *jobqueue.Tx) { op, err = get(tx, id) })
+	if err != nil || op.State.Done() {
+		return op, err
+	}
+	// a job the queue gave up on will not run again: its operation failed
+	for _, d := range m.store.DeadLetters() {
+		if d.Job.ID == op.Job {
+			return m.update(id, func(op *Operation) error {
+				if !op.State.Done() {
+					op.State, op.Error = Failed, d.Reason+": "+d.Job.LastError
+				}
+				return nil
+			})
+		}
+	}
+	return op, nil
+}
+
+// Result returns the operation with id if it succeeded; its result is in
+// Result. It fails with ErrNotDone if the operation is not done, and with
+// an *OperationError if it failed or was canceled.
+func (m *Manager) Result(id string) (Operation, error) {
+	op, err := m.Get(id)
+	switch {
+	case err != nil:
+		return op, err
+	case !op.Done:
+		return op, fmt.Errorf("%w: %s is %s", ErrNotDone, id, op.State)
+	case op.State != Succeeded:
+		return op, &OperationError{Op: op}
+	}
+	return op, nil
+}
+
+// Cancel cancels the operation with id: a pending one is canceled at
+// once, a running one once its Func returns after its context is done.
+// It fails with ErrDone if the operation is already done.
+func (m *Manager) Cancel(id string) (Operation, error) {
+	op, err := m.update(id, func(op *Operation) error {
+		switch op.State {
+		case Pending:
+			op.State = Canceled
+		case Running:
+			op.CancelRequested = true
+		default:
+			return fmt.Errorf("%w: %s is %s", ErrDone, id, op.State)
+		}
+		return nil
+	})
+	if err != nil {
+		return op, err
+	}
+	m.mu.Lock()
+	cancel := m.cancels[id]
+	m.mu.Unlock()
+	if cancel != nil {
+		cancel()
+	}
+	return op, nil
+}
+
+// run is the job of an operation.
+func (m *Manager) run(ctx context.Context, job jobqueue.Job, f Func) error {
+	id := "op-" + strconv.FormatInt(job.ID, 10)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	// registered before the operation is running, so a Cancel that sees
+	// it running finds the func
+	m.mu.Lock()
+	m.cancels[id] = cancel
+	m.mu.Unlock()
+	defer func() {
+		m.mu.Lock()
+		delete(m.cancels, id)
+		m.mu.Unlock()
+	}()
+
+	op, err := m.update(id, func(op *Operation) error {
+		switch {
+		case op.State.Done():
+			// canceled while pending, or a rerun of a finished job
+		case op.CancelRequested:
+			op.State = Canceled
+		default:
+			op.State = Running
+		}
+		return nil
+	})
+	if err != nil {
+		return jobqueue.Permanent(err)
+	}
+	if op.State != Running {
+		return nil
+	}
+
+	result, ferr := f(ctx, job.Payload)
+	var raw json.RawMessage
+	if ferr == nil {
+		if raw, ferr = json.Marshal(result); ferr != nil {
+			ferr = jobqueue.Permanent(fmt.Errorf("result: %w", ferr))
+		}
+	}
+	op, err = m.update(id, func(op *Operation) error {
+		switch {
+		case op.State.Done():
+		case op.CancelRequested:
+			// cancelling wins, even over a Func that finished regardless
+			op.State, op.Error = Canceled, ""
+		case ferr == nil:
+			op.State, op.Result, op.Error = Succeeded, raw, ""
+		case jobqueue.IsPermanent(ferr):
+			op.State, op.Error = Failed, ferr.Error()
+		default:
+			// still running: the queue retries the job
+			op.Error = ferr.Error()
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if op.State == Succeeded || op.State == Canceled {
+		return nil
+	}
+	// the queue retries a transient error and dead-letters a permanent one
+	return ferr
+}
+
+// update applies fn to the operation with id and saves it, unless fn
+// fails.
+func (m *Manager) update(id string, fn func(op *Operation) error) (Operation, error) {
+	var op Operation
+	err := m.store.Update(func(tx *jobqueue.Tx) error {
+		var err error
+		if op, err = get(tx, id); err != nil {
+			return err
+		}
+		before := op
+		if err := fn(&op); err != nil {
+			return err
+		}
+		if op.State != before.State || op.Error != before.Error || op.CancelRequested != before.CancelRequested {
+			op.Updated = m.clock.Now()
+		}
+		op.Done = op.State.Done()
+		return put(tx, op)
+	})
+	if err != nil {
+		return Operation{}, err
+	}
+	return op, nil
+}
+
+func get(tx *jobqueue.Tx, id string) (Operation, error) {
+	s, ok := tx.Get(recordKey(id))
+	if !ok {
+		return Operation{}, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	var op Operation
+	if err := json.Unmarshal([]byte(s), &op); err != nil {
+		return Operation{}, fmt.Errorf("lro: operation %s: %w", id, err)
+	}
+	return op, nil
+}
+
+func put(tx *jobqueue.Tx, op Operation) error {
+	b, err := json.Marshal(op)
+	if err != nil {
+		return err
+	}
+	tx.Set(recordKey(op.ID), string(b))
+	return nil
+}
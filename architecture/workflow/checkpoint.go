@@ -0,0 +1,56 @@
+package workflow
+
+import (
+	"encoding/json"
+	"time"
+
+	"patterns/persistence/repository"
+)
+
+//go:generate go run patterns/cmd/enumgen -type=Status -trimprefix=Status
+
+// Status is where a run stands.
+type Status int
+
+const (
+	StatusRunning Status = iota
+	StatusFailed
+	StatusDone
+)
+
+// Checkpoint is a run as persisted after every step: enough to resume it
+// in another process.
+type Checkpoint struct {
+	Workflow string `json:"workflow"`
+	Run      string `json:"run"`
+	Status   Status `json:"status"`
+	// State is the workflow state after the last completed step.
+	State json.RawMessage `json:"state"`
+	// Completed lists the steps done, in the order they were.
+	Completed []string `json:"completed,omitempty"`
+	// Attempts counts the runs of each step, successful or not.
+	Attempts map[string]int `json:"attempts,omitempty"`
+	// Failure is the last step failure, kept until the step succeeds.
+	Failure *Failure  `json:"failure,omitempty"`
+	Updated time.Time `json:"updated"`
+}
+
+// Failure records a failed step.
+type Failure struct {
+	Step  string    `json:"step"`
+	Error string    `json:"error"`
+	At    time.Time `json:"at"`
+}
+
+// Store keeps checkpoints by run ID; repository.NewMemory and
+// repository.OpenFile are both Stores.
+type Store = repository.Repository[string, Checkpoint]
+
+func (c *Checkpoint) done(step string) bool {
+	for _, s := range c.Completed {
+		if s == step {
+			return true
+		}
+	}
+	return false
+}
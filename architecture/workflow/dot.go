@@ -0,0 +1,43 @@
+package workflow
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Dot renders the workflow as a Graphviz digraph, an edge from each need
+// to the step needing it. Given a checkpoint, it also shows the run's
+// progress: completed steps filled green, the failed one red with its
+// error, attempts on steps tried more than once.
+//
+//	go run ... | dot -Tsvg > run.svg
+func (w *Workflow[S]) Dot(cp *Checkpoint) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "digraph %s {\n\trankdir=LR;\n\tnode [shape=box, style=rounded];\n", strconv.Quote(w.name))
+	for _, i := range w.order {
+		s := w.steps[i]
+		label, attrs := s.Name, ""
+		if cp != nil {
+			if n := cp.Attempts[s.Name]; n > 1 {
+				label += fmt.Sprintf("\n(%d attempts)", n)
+			}
+			switch {
+			case cp.done(s.Name):
+				attrs = `, style="rounded,filled", fillcolor=palegreen`
+			case cp.Failure != nil && cp.Failure.Step == s.Name:
+				label += "\n" + cp.Failure.Error
+				attrs = `, style="rounded,filled", fillcolor=salmon`
+			}
+		}
+		fmt.Fprintf(&b, "\t%s [label=%s%s];\n", strconv.Quote(s.Name), strconv.Quote(label), attrs)
+	}
+	for _, i := range w.order {
+		s := w.steps[i]
+		for _, n := range s.Needs {
+			fmt.Fprintf(&b, "\t%s -> %s;\n", strconv.Quote(n), strconv.Quote(s.Name))
+		}
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
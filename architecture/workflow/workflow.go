@@ -0,0 +1,293 @@
+// Package workflow runs workflows, graphs of steps over a shared state,
+// so that a run interrupted anywhere, by a failing step or a crashed
+// process, resumes where it stopped instead of starting over: the
+// step-function pattern.
+//
+// Before and after every step the state, the steps completed so far and
+// the attempts made are written to a Store as a Checkpoint. Run with the ID of an unfinished run loads
+// its checkpoint and carries on with the first step not completed; a
+// finished run is returned as it is. A step runs on a copy of the state,
+// so a failing step leaves no trace in it.
+//
+// A step can still run twice: when the process dies after the step's
+// effects but before its checkpoint is written. Steps must therefore be
+// idempotent, typically by deriving an idempotency key from the run ID
+// and the step name, which Run passes in StepContext.
+//
+// Dot renders the graph, and a checkpoint's progress, for Graphviz.
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+
+	"patterns/clock"
+	"patterns/construct"
+	"patterns/funcopts"
+	"patterns/persistence/repository"
+)
+
+// Step is one node of a workflow.
+type Step[S any] struct {
+	Name string
+	// Needs names the steps that must complete before this one.
+	Needs []string
+	Run   func(ctx context.Context, sc StepContext, state *S) error
+}
+
+// StepContext identifies a step execution, for idempotency keys.
+type StepContext struct {
+	Run, Step string
+	// Attempt is 1 on the first execution of the step in this run.
+	Attempt int
+}
+
+// Key is an idempotency key for the step's effects, the same on every
+// attempt.
+func (sc StepContext) Key() string { return sc.Run + "/" + sc.Step }
+
+var (
+	ErrInvalid = errors.New("workflow: invalid graph")
+	// ErrIncompatible is returned when a checkpoint belongs to another
+	// workflow, or completed steps this one does not have.
+	ErrIncompatible = errors.New("workflow: checkpoint does not match the workflow")
+)
+
+// StepError is returned by Run when a step fails; the run is resumable.
+type StepError struct {
+	Step string
+	Err  error
+}
+
+func (e *StepError) Error() string { return fmt.Sprintf("workflow: step %s: %v", e.Step, e.Err) }
+func (e *StepError) Unwrap() error { return e.Err }
+
+type options struct {
+	clock clock.Clock
+}
+
+type Option = funcopts.Option[options]
+
+// WithClock sets the clock for checkpoint and failure times.
+func WithClock(c clock.Clock) Option {
+	return func(options *options) error {
+		if c == nil {
+			return errors.New("clock cannot be nil")
+		}
+		options.clock = c
+		return nil
+	}
+}
+
+func (o *options) SetDefaults() { o.clock = clock.Real }
+
+// step function
+// Level: Good
+// pros: a crash or a failing step costs one step, not the whole run; the
+// checkpoints say exactly where every run is and why it stopped.
+// cons: steps must be idempotent and the state serializable, and every
+// step costs two checkpoint writes.
+type Workflow[S any] struct {
+	name  string
+	steps []Step[S]
+	// order is steps in an order honouring Needs.
+	order   []int
+	store   Store
+	options options
+}
+
+// New checks that step names are unique, that every need names a step
+// and that they form no cycle; the steps then run in declaration order
+// as far as their needs allow.
+func New[S any](name string, store Store, steps []Step[S], opts ...Option) (*Workflow[S], error) {
+	options, err := construct.New(opts...)
+	if err != nil {
+		return nil, err
+	}
+	order, err := sortSteps(steps)
+	if err != nil {
+		return nil, err
+	}
+	return &Workflow[S]{name: name, steps: steps, order: order, store: store, options: *options}, nil
+}
+
+// Sequence makes each step need the one before it.
+func Sequence[S any](steps ...Step[S]) []Step[S] {
+	out := slices.Clone(steps)
+	for i := 1; i < len(out); i++ {
+		out[i].Needs = append(slices.Clone(out[i].Needs), out[i-1].Name)
+	}
+	return out
+}
+
+// sortSteps returns a topological order of steps, stable in declaration
+// order.
+func sortSteps[S any](steps []Step[S]) ([]int, error) {
+	index := make(map[string]int, len(steps))
+	for i, s := range steps {
+		if s.Name == "" || s.Run == nil {
+			return nil, fmt.Errorf("%w: step %d needs a name and a Run", ErrInvalid, i)
+		}
+		if _, dup := index[s.Name]; dup {
+			return nil, fmt.Errorf("%w: duplicate step %q", ErrInvalid, s.Name)
+		}
+		index[s.Name] = i
+	}
+	for _, s := range steps {
+		for _, n := range s.Needs {
+			if _, ok := index[n]; !ok {
+				return nil, fmt.Errorf("%w: %q needs unknown step %q", ErrInvalid, s.Name, n)
+			}
+		}
+	}
+	var order []int
+	placed := make([]bool, len(steps))
+	ready := func(i int) bool {
+		return !placed[i] && !slices.ContainsFunc(steps[i].Needs, func(n string) bool { return !placed[index[n]] })
+	}
+	for len(order) < len(steps) {
+		// the first step, in declaration order, whose needs are all placed
+		i := slices.IndexFunc(steps, func(s Step[S]) bool { return ready(index[s.Name]) })
+		if i < 0 {
+			return nil, fmt.Errorf("%w: steps form a cycle", ErrInvalid)
+		}
+		placed[i] = true
+		order = append(order, i)
+	}
+	return order, nil
+}
+
+func (w *Workflow[S]) Name() string { return w.name }
+
+// Run executes the run called id from initial, or resumes it from its
+// checkpoint, ignoring initial, and returns the final state. On a step
+// failure it returns the state after the last completed step and a
+// *StepError; calling Run again with the same id retries from that step.
+func (w *Workflow[S]) Run(ctx context.Context, id string, initial S) (S, error) {
+	cp, state, err := w.load(ctx, id, initial)
+	if err != nil {
+		return state, err
+	}
+	for _, i := range w.order {
+		step := w.steps[i]
+		if cp.done(step.Name) {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return state, err
+		}
+		// the attempt is recorded before the step runs, so one cut short
+		// by a crash still counts
+		cp.Attempts[step.Name]++
+		if err := w.save(ctx, &cp, state); err != nil {
+			return state, err
+		}
+		sc := StepContext{Run: id, Step: step.Name, Attempt: cp.Attempts[step.Name]}
+		next, err := clone(state)
+		if err != nil {
+			return state, err
+		}
+		if err := call(ctx, step, sc, &next); err != nil {
+			cp.Status = StatusFailed
+			cp.Failure = &Failure{Step: step.Name, Error: err.Error(), At: w.options.clock.Now()}
+			if serr := w.save(ctx, &cp, state); serr != nil {
+				return state, errors.Join(&StepError{Step: step.Name, Err: err}, serr)
+			}
+			return state, &StepError{Step: step.Name, Err: err}
+		}
+		state = next
+		cp.Status, cp.Failure = StatusRunning, nil
+		cp.Completed = append(cp.Completed, step.Name)
+		if err := w.save(ctx, &cp, state); err != nil {
+			return state, err
+		}
+	}
+	if cp.Status != StatusDone {
+		cp.Status = StatusDone
+		if err := w.save(ctx, &cp, state); err != nil {
+			return state, err
+		}
+	}
+	return state, nil
+}
+
+// Checkpoint returns the checkpoint of the run id.
+func (w *Workflow[S]) Checkpoint(ctx context.Context, id string) (Checkpoint, error) {
+	return w.store.Get(ctx, id)
+}
+
+// load returns the checkpoint of id and its state, creating both from
+// initial for a new run.
+func (w *Workflow[S]) load(ctx context.Context, id string, initial S) (Checkpoint, S, error) {
+	cp, err := w.store.Get(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		cp = Checkpoint{Workflow: w.name, Run: id, Attempts: map[string]int{}}
+		if err := w.save(ctx, &cp, initial); err != nil {
+			return cp, initial, err
+		}
+		return cp, initial, nil
+	}
+	if err != nil {
+		return cp, initial, err
+	}
+	if cp.Workflow != w.name {
+		return cp, initial, fmt.Errorf("%w: run %s is of workflow %q", ErrIncompatible, id, cp.Workflow)
+	}
+	for _, name := range cp.Completed {
+		if !slices.ContainsFunc(w.steps, func(s Step[S]) bool { return s.Name == name }) {
+			return cp, initial, fmt.Errorf("%w: run %s completed unknown step %q", ErrIncompatible, id, name)
+		}
+	}
+	if cp.Attempts == nil {
+		cp.Attempts = map[string]int{}
+	}
+	var state S
+	if err := json.Unmarshal(cp.State, &state); err != nil {
+		return cp, initial, err
+	}
+	return cp, state, nil
+}
+
+// save writes cp with state; a new run is created, an existing one
+// updated.
+func (w *Workflow[S]) save(ctx context.Context, cp *Checkpoint, state S) error {
+	raw, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	creating := cp.State == nil
+	cp.State, cp.Updated = raw, w.options.clock.Now()
+	// the stored checkpoint must not share the maps and slices Run keeps
+	// changing, as an in-memory store would
+	stored := *cp
+	stored.Completed = slices.Clone(cp.Completed)
+	stored.Attempts = maps.Clone(cp.Attempts)
+	if creating {
+		return w.store.Create(ctx, cp.Run, stored)
+	}
+	return w.store.Update(ctx, cp.Run, stored)
+}
+
+// call runs the step, turning a panic into an error.
+func call[S any](ctx context.Context, step Step[S], sc StepContext, state *S) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("panic: %v", p)
+		}
+	}()
+	return step.Run(ctx, sc, state)
+}
+
+func clone[S any](s S) (S, error) {
+	var c S
+	b, err := json.Marshal(s)
+	if err != nil {
+		return c, err
+	}
+	err = json.Unmarshal(b, &c)
+	return c, err
+}
@@ -0,0 +1,409 @@
+package workflow_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"patterns/architecture/workflow"
+	"patterns/clock"
+	"patterns/persistence/repository"
+	"patterns/testing/golden"
+)
+
+// order is the state of the fulfilment workflow.
+type order struct {
+	ID       string `json:"id"`
+	Reserved bool   `json:"reserved"`
+	Charge   string `json:"charge,omitempty"`
+	Parcel   string `json:"parcel,omitempty"`
+	Tracking string `json:"tracking,omitempty"`
+	Notified bool   `json:"notified"`
+	Closed   bool   `json:"closed"`
+}
+
+// steps in the order Run takes them
+var steps = []string{"reserve", "charge", "pack", "notify", "ship", "close"}
+
+// fulfil is an order fulfilment workflow whose steps count their
+// executions and fail on their first fail[step] attempts, after changing
+// the state.
+type fulfil struct {
+	runs map[string]int
+	fail map[string]int
+	// charges stands in for a payment provider deduplicating by
+	// idempotency key
+	charges *repository.Memory[string, string]
+}
+
+func newFulfil() *fulfil {
+	return &fulfil{runs: map[string]int{}, fail: map[string]int{}, charges: repository.NewMemory[string, string]()}
+}
+
+func (f *fulfil) steps() []workflow.Step[order] {
+	step := func(name string, needs []string, do func(ctx context.Context, sc workflow.StepContext, o *order) error) workflow.Step[order] {
+		return workflow.Step[order]{Name: name, Needs: needs, Run: func(ctx context.Context, sc workflow.StepContext, o *order) error {
+			f.runs[name]++
+			if err := do(ctx, sc, o); err != nil {
+				return err
+			}
+			if sc.Attempt <= f.fail[name] {
+				return fmt.Errorf("injected failure, attempt %d", sc.Attempt)
+			}
+			return nil
+		}}
+	}
+	return []workflow.Step[order]{
+		step("reserve", nil, func(_ context.Context, _ workflow.StepContext, o *order) error {
+			o.Reserved = true
+			return nil
+		}),
+		step("charge", []string{"reserve"}, func(ctx context.Context, sc workflow.StepContext, o *order) error {
+			id := "ch_" + sc.Key()
+			if err := f.charges.Create(ctx, sc.Key(), id); err != nil && !errors.Is(err, repository.ErrExists) {
+				return err
+			}
+			o.Charge = id
+			return nil
+		}),
+		step("pack", []string{"charge"}, func(_ context.Context, _ workflow.StepContext, o *order) error {
+			o.Parcel = "parcel-" + o.ID
+			return nil
+		}),
+		step("notify", []string{"charge"}, func(_ context.Context, _ workflow.StepContext, o *order) error {
+			o.Notified = true
+			return nil
+		}),
+		step("ship", []string{"pack"}, func(_ context.Context, _ workflow.StepContext, o *order) error {
+			o.Tracking = "TRK" + o.ID
+			return nil
+		}),
+		step("close", []string{"ship", "notify"}, func(_ context.Context, _ workflow.StepContext, o *order) error {
+			o.Closed = true
+			return nil
+		}),
+	}
+}
+
+func (f *fulfil) workflow(t *testing.T, store workflow.Store) *workflow.Workflow[order] {
+	t.Helper()
+	wf, err := workflow.New("fulfil", store, f.steps(), workflow.WithClock(clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return wf
+}
+
+// after returns the order once the first n steps have run.
+func after(n int) order {
+	want := order{ID: "order-1"}
+	for _, s := range steps[:n] {
+		switch s {
+		case "reserve":
+			want.Reserved = true
+		case "charge":
+			want.Charge = "ch_order-1/charge"
+		case "pack":
+			want.Parcel = "parcel-order-1"
+		case "notify":
+			want.Notified = true
+		case "ship":
+			want.Tracking = "TRKorder-1"
+		case "close":
+			want.Closed = true
+		}
+	}
+	return want
+}
+
+func TestRun(t *testing.T) {
+	ctx := context.Background()
+	f := newFulfil()
+	wf := f.workflow(t, repository.NewMemory[string, workflow.Checkpoint]())
+	got, err := wf.Run(ctx, "order-1", order{ID: "order-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := after(len(steps)); got != want {
+		t.Errorf("Run = %+v, want %+v", got, want)
+	}
+	cp, err := wf.Checkpoint(ctx, "order-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cp.Status != workflow.StatusDone || !slices.Equal(cp.Completed, steps) || cp.Failure != nil {
+		t.Errorf("checkpoint = %s %v %v, want Done %v and no failure", cp.Status, cp.Completed, cp.Failure, steps)
+	}
+	// a finished run is returned as it is
+	got, err = wf.Run(ctx, "order-1", order{ID: "ignored"})
+	if err != nil || got != after(len(steps)) {
+		t.Errorf("Run of a done run = %+v, %v, want %+v", got, err, after(len(steps)))
+	}
+	for _, s := range steps {
+		if f.runs[s] != 1 {
+			t.Errorf("%s ran %d times, want 1", s, f.runs[s])
+		}
+	}
+}
+
+// TestFailEachStep fails every step in turn on its first attempt and
+// resumes the run.
+func TestFailEachStep(t *testing.T) {
+	ctx := context.Background()
+	for i, failing := range steps {
+		store := repository.NewMemory[string, workflow.Checkpoint]()
+		f := newFulfil()
+		f.fail[failing] = 1
+		wf := f.workflow(t, store)
+
+		got, err := wf.Run(ctx, "order-1", order{ID: "order-1"})
+		var serr *workflow.StepError
+		if !errors.As(err, &serr) || serr.Step != failing {
+			t.Fatalf("%s: Run error = %v, want a StepError of %s", failing, err, failing)
+		}
+		// the failing step changed its copy of the state only
+		if want := after(i); got != want {
+			t.Errorf("%s: failed Run = %+v, want %+v", failing, got, want)
+		}
+		cp, err := wf.Checkpoint(ctx, "order-1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cp.Status != workflow.StatusFailed || !slices.Equal(cp.Completed, steps[:i]) {
+			t.Errorf("%s: checkpoint = %s %v, want Failed %v", failing, cp.Status, cp.Completed, steps[:i])
+		}
+		if cp.Failure == nil || cp.Failure.Step != failing || cp.Failure.Error != "injected failure, attempt 1" {
+			t.Errorf("%s: checkpoint failure = %+v", failing, cp.Failure)
+		}
+
+		// resuming, in a workflow over the same store as another process
+		// would, reruns the failed step alone
+		got, err = f.workflow(t, store).Run(ctx, "order-1", order{ID: "ignored"})
+		if err != nil {
+			t.Fatalf("%s: resumed Run: %v", failing, err)
+		}
+		if want := after(len(steps)); got != want {
+			t.Errorf("%s: resumed Run = %+v, want %+v", failing, got, want)
+		}
+		for _, s := range steps {
+			want := 1
+			if s == failing {
+				want = 2
+			}
+			if f.runs[s] != want {
+				t.Errorf("%s: %s ran %d times, want %d", failing, s, f.runs[s], want)
+			}
+		}
+		cp, err = wf.Checkpoint(ctx, "order-1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cp.Status != workflow.StatusDone || cp.Failure != nil || cp.Attempts[failing] != 2 {
+			t.Errorf("%s: checkpoint = %s, failure %v, attempts %v; want Done, none, 2", failing, cp.Status, cp.Failure, cp.Attempts)
+		}
+	}
+}
+
+var errCrash = errors.New("crash")
+
+// crashStore fails the write recording step as completed, once, as a
+// process dying between a step's effects and its checkpoint would.
+type crashStore struct {
+	workflow.Store
+	step    string
+	crashed bool
+}
+
+func (s *crashStore) Update(ctx context.Context, id string, cp workflow.Checkpoint) error {
+	if !s.crashed && slices.Contains(cp.Completed, s.step) {
+		s.crashed = true
+		return errCrash
+	}
+	return s.Store.Update(ctx, id, cp)
+}
+
+// TestCrashEachStep crashes after the effects of every step in turn: the
+// resumed run repeats the step with the same idempotency key, so the
+// card is charged once.
+func TestCrashEachStep(t *testing.T) {
+	ctx := context.Background()
+	for i, crashing := range steps {
+		store := &crashStore{Store: repository.NewMemory[string, workflow.Checkpoint](), step: crashing}
+		f := newFulfil()
+		if _, err := f.workflow(t, store).Run(ctx, "order-1", order{ID: "order-1"}); !errors.Is(err, errCrash) {
+			t.Fatalf("%s: Run error = %v, want the crash", crashing, err)
+		}
+		cp, err := store.Get(ctx, "order-1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cp.Status != workflow.StatusRunning || !slices.Equal(cp.Completed, steps[:i]) || cp.Attempts[crashing] != 1 {
+			t.Errorf("%s: checkpoint = %s %v %v, want Running %v, one attempt", crashing, cp.Status, cp.Completed, cp.Attempts, steps[:i])
+		}
+
+		// the resumed process keeps f's counters and payment provider
+		got, err := f.workflow(t, store).Run(ctx, "order-1", order{ID: "ignored"})
+		if err != nil {
+			t.Fatalf("%s: resumed Run: %v", crashing, err)
+		}
+		if want := after(len(steps)); got != want {
+			t.Errorf("%s: resumed Run = %+v, want %+v", crashing, got, want)
+		}
+		if f.runs[crashing] != 2 {
+			t.Errorf("%s: ran %d times, want 2", crashing, f.runs[crashing])
+		}
+		charges, err := f.charges.List(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(charges, []string{"ch_order-1/charge"}) {
+			t.Errorf("%s: charges = %v, want one", crashing, charges)
+		}
+		cp, err = store.Get(ctx, "order-1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cp.Attempts[crashing] != 2 {
+			t.Errorf("%s: attempts = %v, want 2", crashing, cp.Attempts)
+		}
+	}
+}
+
+// TestRetries fails a step until its third attempt, showing the attempts
+// and the failure in the graph.
+func TestRetries(t *testing.T) {
+	ctx := context.Background()
+	f := newFulfil()
+	f.fail["pack"] = 2
+	wf := f.workflow(t, repository.NewMemory[string, workflow.Checkpoint]())
+	for attempt := 1; attempt <= 2; attempt++ {
+		var serr *workflow.StepError
+		if _, err := wf.Run(ctx, "order-1", order{ID: "order-1"}); !errors.As(err, &serr) || serr.Step != "pack" {
+			t.Fatalf("attempt %d: Run error = %v, want a StepError of pack", attempt, err)
+		}
+	}
+	cp, err := wf.Checkpoint(ctx, "order-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	golden.Assert(t, "failed.dot", []byte(wf.Dot(&cp)))
+	if _, err := wf.Run(ctx, "order-1", order{ID: "order-1"}); err != nil {
+		t.Fatal(err)
+	}
+	if cp, _ = wf.Checkpoint(ctx, "order-1"); cp.Attempts["pack"] != 3 || cp.Attempts["charge"] != 1 {
+		t.Errorf("attempts = %v, want pack 3, the others 1", cp.Attempts)
+	}
+	golden.Assert(t, "done.dot", []byte(wf.Dot(&cp)))
+}
+
+func TestStepContext(t *testing.T) {
+	ctx := context.Background()
+	var got []workflow.StepContext
+	wf, err := workflow.New("ctx", repository.NewMemory[string, workflow.Checkpoint](), []workflow.Step[int]{{
+		Name: "flaky",
+		Run: func(_ context.Context, sc workflow.StepContext, _ *int) error {
+			got = append(got, sc)
+			if sc.Attempt == 1 {
+				return errors.New("flaky")
+			}
+			return nil
+		},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wf.Run(ctx, "r", 0); err == nil {
+		t.Fatal("first attempt succeeded")
+	}
+	if _, err := wf.Run(ctx, "r", 0); err != nil {
+		t.Fatal(err)
+	}
+	want := []workflow.StepContext{{Run: "r", Step: "flaky", Attempt: 1}, {Run: "r", Step: "flaky", Attempt: 2}}
+	if !slices.Equal(got, want) || got[0].Key() != got[1].Key() {
+		t.Errorf("step contexts = %v, want %v with one key", got, want)
+	}
+}
+
+func TestPanic(t *testing.T) {
+	wf, err := workflow.New("panic", repository.NewMemory[string, workflow.Checkpoint](), []workflow.Step[int]{{
+		Name: "boom",
+		Run:  func(context.Context, workflow.StepContext, *int) error { panic("boom") },
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var serr *workflow.StepError
+	if _, err := wf.Run(context.Background(), "r", 0); !errors.As(err, &serr) || serr.Err.Error() != "panic: boom" {
+		t.Errorf("Run error = %v, want the panic as a StepError", err)
+	}
+}
+
+func TestIncompatible(t *testing.T) {
+	ctx := context.Background()
+	store := repository.NewMemory[string, workflow.Checkpoint]()
+	if _, err := newFulfil().workflow(t, store).Run(ctx, "order-1", order{ID: "order-1"}); err != nil {
+		t.Fatal(err)
+	}
+	noop := func(context.Context, workflow.StepContext, *order) error { return nil }
+	for _, c := range []struct {
+		name  string
+		steps []workflow.Step[order]
+	}{
+		{"other", []workflow.Step[order]{{Name: "reserve", Run: noop}}},
+		// fulfil without the steps the run completed
+		{"fulfil", []workflow.Step[order]{{Name: "reserve", Run: noop}}},
+	} {
+		wf, err := workflow.New(c.name, store, c.steps)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := wf.Run(ctx, "order-1", order{}); !errors.Is(err, workflow.ErrIncompatible) {
+			t.Errorf("%s: Run error = %v, want ErrIncompatible", c.name, err)
+		}
+	}
+}
+
+func TestNewInvalid(t *testing.T) {
+	noop := func(context.Context, workflow.StepContext, *int) error { return nil }
+	for _, c := range []struct {
+		name  string
+		steps []workflow.Step[int]
+	}{
+		{"no name", []workflow.Step[int]{{Run: noop}}},
+		{"no run", []workflow.Step[int]{{Name: "a"}}},
+		{"duplicate", []workflow.Step[int]{{Name: "a", Run: noop}, {Name: "a", Run: noop}}},
+		{"unknown need", []workflow.Step[int]{{Name: "a", Needs: []string{"b"}, Run: noop}}},
+		{"cycle", []workflow.Step[int]{{Name: "a", Needs: []string{"b"}, Run: noop}, {Name: "b", Needs: []string{"a"}, Run: noop}}},
+	} {
+		if _, err := workflow.New("w", nil, c.steps); !errors.Is(err, workflow.ErrInvalid) {
+			t.Errorf("%s: New error = %v, want ErrInvalid", c.name, err)
+		}
+	}
+}
+
+// TestOrder checks that steps run in declaration order as far as their
+// needs allow.
+func TestOrder(t *testing.T) {
+	var ran []string
+	step := func(name string, needs ...string) workflow.Step[int] {
+		return workflow.Step[int]{Name: name, Needs: needs, Run: func(context.Context, workflow.StepContext, *int) error {
+			ran = append(ran, name)
+			return nil
+		}}
+	}
+	wf, err := workflow.New("order", repository.NewMemory[string, workflow.Checkpoint](), []workflow.Step[int]{
+		step("c", "b"), step("a"), step("b", "a"), step("d"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wf.Run(context.Background(), "r", 0); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"a", "b", "c", "d"}; !slices.Equal(ran, want) {
+		t.Errorf("ran %v, want %v", ran, want)
+	}
+}
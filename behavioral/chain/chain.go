@@ -0,0 +1,97 @@
+// Package chain passes a request along a chain of links, each of which
+// handles its part and then either passes the request on or stops it:
+//
+//	validate → enrich → review → place
+//
+// Validation stops an invalid order with an error, review stops a large
+// order from an unverified customer by holding it, without an error, and
+// only orders that get through both are placed. No link knows which links
+// come before or after it, so steps are added, removed and reordered by
+// editing the chain alone.
+//
+// It comes in two shapes over the same steps (steps.go):
+//
+//   - Chain, a slice of Link functions run in order by one loop, which is
+//     also the only place that decides whether to go on
+//   - Handler, the classic linked form: each link holds the next and
+//     calls it to pass the request along, or returns to stop
+package chain
+
+import "context"
+
+// Order is the request travelling along the chain, filled in as it goes.
+type Order struct {
+	Customer string
+	Items    []Item
+	Coupon   string
+
+	// set by enrich
+	Tier       string
+	Discount   int // percent
+	TotalCents int
+
+	// set by the link that stops the order, or by place
+	Status Status
+	Reason string
+}
+
+type Item struct {
+	SKU        string
+	Quantity   int
+	PriceCents int
+}
+
+type Status string
+
+const (
+	Rejected Status = "rejected"
+	Held     Status = "held"
+	Placed   Status = "placed"
+)
+
+// Link handles its part of o and reports whether the chain goes on. It
+// stops the chain by returning false, having settled o, or an error.
+type Link func(ctx context.Context, o *Order) (next bool, err error)
+
+// slice chain
+// Level: Good
+// pros: the chain is a value: links are plain functions, listed in order
+// in one place, and the loop alone decides whether to go on, so no link
+// can forget to pass the request along.
+// cons: every link sees the request only on its way in; a link that must
+// act after the rest, such as a timer or a transaction, needs the linked
+// form or middleware.
+type Chain []Link
+
+// Handle runs o through the links in order until one stops it. A link
+// error is returned and marks o rejected.
+func (c Chain) Handle(ctx context.Context, o *Order) error {
+	for _, link := range c {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		next, err := link(ctx, o)
+		if err != nil {
+			reject(o, err)
+			return err
+		}
+		if !next {
+			return nil
+		}
+	}
+	return nil
+}
+
+// Orders returns the chain of this package's steps, in order.
+func Orders(customers map[string]string, coupons map[string]int, reviewAboveCents int, book *Book) Chain {
+	return Chain{
+		func(_ context.Context, o *Order) (bool, error) { return true, check(o) },
+		func(_ context.Context, o *Order) (bool, error) { return true, enrich(o, customers, coupons) },
+		func(_ context.Context, o *Order) (bool, error) { return !review(o, reviewAboveCents), nil },
+		func(ctx context.Context, o *Order) (bool, error) { return false, book.place(ctx, o) },
+	}
+}
+
+func reject(o *Order, err error) {
+	o.Status, o.Reason = Rejected, err.Error()
+}
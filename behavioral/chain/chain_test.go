@@ -0,0 +1,181 @@
+package chain_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"patterns/behavioral/chain"
+)
+
+var (
+	customers = map[string]string{"ann": "gold"}
+	coupons   = map[string]int{"TEN": 10}
+)
+
+func item(cents int) []chain.Item { return []chain.Item{{SKU: "sku", Quantity: 1, PriceCents: cents}} }
+
+// shapes builds both forms of the order chain over a new book each.
+var shapes = []struct {
+	name  string
+	build func(book *chain.Book) func(context.Context, *chain.Order) error
+}{
+	{"slice", func(book *chain.Book) func(context.Context, *chain.Order) error {
+		return chain.Orders(customers, coupons, 10_000, book).Handle
+	}},
+	{"linked", func(book *chain.Book) func(context.Context, *chain.Order) error {
+		return chain.Linked(customers, coupons, 10_000, book).Handle
+	}},
+}
+
+// TestOrders runs the same orders through both shapes: each stops at the
+// same link, with the same outcome.
+func TestOrders(t *testing.T) {
+	for _, c := range []struct {
+		name   string
+		order  chain.Order
+		status chain.Status
+		reason string
+		err    bool
+		total  int
+	}{
+		{"placed", chain.Order{Customer: "bob", Items: item(5000)}, chain.Placed, "", false, 5000},
+		{"discounted", chain.Order{Customer: "bob", Items: item(5000), Coupon: "TEN"}, chain.Placed, "", false, 4500},
+		{"invalid", chain.Order{Customer: "bob", Items: []chain.Item{{SKU: "sku"}}}, chain.Rejected, "items[0].quantity: must be at least 1", true, 0},
+		{"no items", chain.Order{Customer: "bob"}, chain.Rejected, "items: cannot be empty", true, 0},
+		{"unknown coupon", chain.Order{Customer: "bob", Items: item(5000), Coupon: "FREE"}, chain.Rejected, `chain: unknown coupon "FREE"`, true, 0},
+		{"held", chain.Order{Customer: "bob", Items: item(20_000)}, chain.Held, "new customer ordering above 10000 cents", false, 20_000},
+		// the discount brings it under the limit
+		{"held no longer", chain.Order{Customer: "bob", Items: item(11_000), Coupon: "TEN"}, chain.Placed, "", false, 9900},
+		{"gold above the limit", chain.Order{Customer: "ann", Items: item(20_000)}, chain.Placed, "", false, 20_000},
+	} {
+		for _, s := range shapes {
+			book := &chain.Book{}
+			o := c.order
+			err := s.build(book)(context.Background(), &o)
+			if (err != nil) != c.err || o.Status != c.status || o.Reason != c.reason || o.TotalCents != c.total {
+				t.Errorf("%s %s: %v, %s %q total %d; want %s %q total %d", s.name, c.name, err, o.Status, o.Reason, o.TotalCents, c.status, c.reason, c.total)
+			}
+			if placed := len(book.Orders()); placed != 0 != (c.status == chain.Placed) {
+				t.Errorf("%s %s: %d orders in the book", s.name, c.name, placed)
+			}
+		}
+	}
+	err := chain.Orders(customers, coupons, 0, &chain.Book{}).Handle(context.Background(), &chain.Order{Customer: "bob", Items: item(1), Coupon: "X"})
+	if !errors.Is(err, chain.ErrUnknownCoupon) {
+		t.Errorf("unknown coupon = %v, want ErrUnknownCoupon", err)
+	}
+}
+
+// TestCanceled checks that neither shape places an order once its context
+// is done.
+func TestCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for _, s := range shapes {
+		book := &chain.Book{}
+		o := chain.Order{Customer: "ann", Items: item(1)}
+		if err := s.build(book)(ctx, &o); !errors.Is(err, context.Canceled) || len(book.Orders()) != 0 || o.Status == chain.Placed {
+			t.Errorf("%s: %v, status %s, %d placed", s.name, err, o.Status, len(book.Orders()))
+		}
+	}
+}
+
+// trace returns a link that records its name and then returns next and
+// err.
+func trace(ran *[]string, name string, next bool, err error) chain.Link {
+	return func(context.Context, *chain.Order) (bool, error) {
+		*ran = append(*ran, name)
+		return next, err
+	}
+}
+
+// TestChainStops checks that the links after the one that stops the chain
+// never run, whichever way it stops.
+func TestChainStops(t *testing.T) {
+	boom := errors.New("boom")
+	for _, c := range []struct {
+		name   string
+		build  func(ran *[]string) chain.Chain
+		want   []string
+		err    error
+		status chain.Status
+	}{
+		{"all pass", func(ran *[]string) chain.Chain {
+			return chain.Chain{trace(ran, "a", true, nil), trace(ran, "b", true, nil)}
+		}, []string{"a", "b"}, nil, ""},
+		{"stop", func(ran *[]string) chain.Chain {
+			return chain.Chain{trace(ran, "a", true, nil), trace(ran, "b", false, nil), trace(ran, "c", true, nil)}
+		}, []string{"a", "b"}, nil, ""},
+		// an error stops the chain even if the link asks to go on
+		{"error", func(ran *[]string) chain.Chain {
+			return chain.Chain{trace(ran, "a", true, boom), trace(ran, "b", true, nil)}
+		}, []string{"a"}, boom, chain.Rejected},
+		{"empty", func(*[]string) chain.Chain { return nil }, nil, nil, ""},
+	} {
+		var ran []string
+		var o chain.Order
+		err := c.build(&ran).Handle(context.Background(), &o)
+		if !slices.Equal(ran, c.want) || err != c.err || o.Status != c.status {
+			t.Errorf("%s: ran %q, %v, status %q; want %q, %v, %q", c.name, ran, err, o.Status, c.want, c.err, c.status)
+		}
+	}
+
+	// a link canceling the context stops the chain before the next
+	var ran []string
+	ctx, cancel := context.WithCancel(context.Background())
+	err := chain.Chain{
+		func(context.Context, *chain.Order) (bool, error) { cancel(); return true, nil },
+		trace(&ran, "b", true, nil),
+	}.Handle(ctx, &chain.Order{})
+	if !errors.Is(err, context.Canceled) || len(ran) != 0 {
+		t.Errorf("after cancel: ran %q, %v", ran, err)
+	}
+}
+
+// around is a linked link that records itself on the way in and, after
+// the rest of the chain, on the way out, unless it stops.
+type around struct {
+	chain.Base
+	name string
+	stop bool
+	ran  *[]string
+}
+
+func (a *around) Handle(ctx context.Context, o *chain.Order) error {
+	*a.ran = append(*a.ran, a.name)
+	if a.stop {
+		return nil
+	}
+	err := a.Next(ctx, o)
+	*a.ran = append(*a.ran, a.name+" done")
+	return err
+}
+
+// TestLinkedStops checks the linked form: a link that returns without
+// calling Next ends the chain there, and the links before it finish
+// around it.
+func TestLinkedStops(t *testing.T) {
+	var ran []string
+	a := &around{name: "a", ran: &ran}
+	a.SetNext(&around{name: "b", ran: &ran}).
+		SetNext(&around{name: "c", stop: true, ran: &ran}).
+		SetNext(&around{name: "d", ran: &ran})
+	if err := a.Handle(context.Background(), &chain.Order{}); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"a", "b", "c", "b done", "a done"}; !slices.Equal(ran, want) {
+		t.Errorf("ran %q, want %q", ran, want)
+	}
+
+	// a placer at the head of the chain places an order unchecked: the
+	// order of the links is the chain's whole logic
+	book := &chain.Book{}
+	p := &chain.Placer{Book: book}
+	p.SetNext(&chain.Validator{})
+	o := chain.Order{}
+	if err := p.Handle(context.Background(), &o); err == nil || len(book.Orders()) != 1 {
+		t.Errorf("reordered chain: %v, %d placed", err, len(book.Orders()))
+	}
+}
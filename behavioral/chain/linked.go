@@ -0,0 +1,106 @@
+package chain
+
+import "context"
+
+// Handler is a link of the linked chain: it handles its part of o and
+// calls the next handler to pass o along, or returns to stop.
+type Handler interface {
+	Handle(ctx context.Context, o *Order) error
+}
+
+// linked chain
+// Level: Average
+// pros: a link runs around the rest of the chain, so it can act on the
+// way back too, like middleware; each link decides alone whether to go
+// on.
+// cons: the wiring lives in the links, each of which must remember to
+// call Next; a chain of mutable links cannot be shared between two
+// orders of steps, and is built back to front or with SetNext calls.
+type Base struct {
+	next Handler
+}
+
+// SetNext makes h the link after this one and returns h, so a chain
+// reads front to back: a.SetNext(b).SetNext(c).
+func (b *Base) SetNext(h Linker) Linker {
+	b.next = h
+	return h
+}
+
+// Next passes o to the next link; at the end of the chain it does
+// nothing.
+func (b *Base) Next(ctx context.Context, o *Order) error {
+	if b.next == nil {
+		return nil
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return b.next.Handle(ctx, o)
+}
+
+// Linker is a Handler that can be given a next link, as every type
+// embedding Base can.
+type Linker interface {
+	Handler
+	SetNext(h Linker) Linker
+}
+
+type Validator struct{ Base }
+
+func (v *Validator) Handle(ctx context.Context, o *Order) error {
+	if err := check(o); err != nil {
+		reject(o, err)
+		return err
+	}
+	return v.Next(ctx, o)
+}
+
+type Enricher struct {
+	Base
+	Customers map[string]string
+	Coupons   map[string]int
+}
+
+func (e *Enricher) Handle(ctx context.Context, o *Order) error {
+	if err := enrich(o, e.Customers, e.Coupons); err != nil {
+		reject(o, err)
+		return err
+	}
+	return e.Next(ctx, o)
+}
+
+type Reviewer struct {
+	Base
+	AboveCents int
+}
+
+func (r *Reviewer) Handle(ctx context.Context, o *Order) error {
+	if review(o, r.AboveCents) {
+		return nil
+	}
+	return r.Next(ctx, o)
+}
+
+type Placer struct {
+	Base
+	Book *Book
+}
+
+func (p *Placer) Handle(ctx context.Context, o *Order) error {
+	if err := p.Book.place(ctx, o); err != nil {
+		reject(o, err)
+		return err
+	}
+	return p.Next(ctx, o)
+}
+
+// Linked returns the linked chain of this package's steps, the
+// counterpart of Orders.
+func Linked(customers map[string]string, coupons map[string]int, reviewAboveCents int, book *Book) Handler {
+	head := &Validator{}
+	head.SetNext(&Enricher{Customers: customers, Coupons: coupons}).
+		SetNext(&Reviewer{AboveCents: reviewAboveCents}).
+		SetNext(&Placer{Book: book})
+	return head
+}
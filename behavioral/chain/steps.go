@@ -0,0 +1,83 @@
+package chain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"patterns/validate"
+)
+
+// check rejects an order that cannot be priced.
+func check(o *Order) error {
+	var v validate.Validator
+	validate.Field(&v, "customer", o.Customer, validate.NonEmpty[string]())
+	v.Check("items", len(o.Items) > 0, "cannot be empty")
+	for i, it := range o.Items {
+		iv := v.Index("items", i)
+		validate.Field(iv, "sku", it.SKU, validate.NonEmpty[string]())
+		validate.Field(iv, "quantity", it.Quantity, validate.Min(1))
+		validate.Field(iv, "price", it.PriceCents, validate.Min(0))
+	}
+	return v.Err()
+}
+
+var ErrUnknownCoupon = errors.New("chain: unknown coupon")
+
+// enrich looks up the customer's tier and the coupon's discount, and
+// prices the order with them. Unknown customers are "new".
+func enrich(o *Order, customers map[string]string, coupons map[string]int) error {
+	o.Tier = customers[o.Customer]
+	if o.Tier == "" {
+		o.Tier = "new"
+	}
+	if o.Coupon != "" {
+		d, ok := coupons[o.Coupon]
+		if !ok {
+			return fmt.Errorf("%w %q", ErrUnknownCoupon, o.Coupon)
+		}
+		o.Discount = d
+	}
+	total := 0
+	for _, it := range o.Items {
+		total += it.Quantity * it.PriceCents
+	}
+	o.TotalCents = total * (100 - o.Discount) / 100
+	return nil
+}
+
+// review holds an order above limit from a new customer for a person to
+// look at, and reports whether it did.
+func review(o *Order, limitCents int) bool {
+	if o.Tier != "new" || o.TotalCents <= limitCents {
+		return false
+	}
+	o.Status = Held
+	o.Reason = fmt.Sprintf("new customer ordering above %d cents", limitCents)
+	return true
+}
+
+// Book records the orders that make it to the end of the chain.
+type Book struct {
+	mu     sync.Mutex
+	orders []Order
+}
+
+func (b *Book) place(ctx context.Context, o *Order) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	o.Status = Placed
+	b.orders = append(b.orders, *o)
+	return nil
+}
+
+// Orders returns the placed orders, in the order they were placed.
+func (b *Book) Orders() []Order {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]Order(nil), b.orders...)
+}
@@ -0,0 +1,150 @@
+// Package command turns each change to an object into a value that knows
+// how to apply and revert itself, so a History can keep what was done and
+// walk back and forth through it:
+//
+//	doc := &command.Document{}
+//	h, _ := command.NewHistory(doc)
+//	h.Execute(command.Insert{At: 0, Text: "hello world"})
+//	h.Execute(&command.Delete{At: 5, N: 6})
+//	h.Undo() // "hello world"
+//	h.Redo() // "hello"
+//
+// The History is the invoker: it runs commands against its receiver,
+// here a Document, without knowing what any of them does. A command
+// records in itself whatever its undo needs, such as the text a Delete
+// removed, so undo costs as much as the change and not a copy of the
+// document. Macro groups commands into one undoable step.
+package command
+
+import (
+	"errors"
+
+	"patterns/construct"
+	"patterns/funcopts"
+)
+
+var (
+	ErrNothingToUndo = errors.New("command: nothing to undo")
+	ErrNothingToRedo = errors.New("command: nothing to redo")
+)
+
+// Command changes a T, and reverts the change. Undo is only called right
+// after Execute or Redo of the same command, on the T they left, so it can
+// rely on state Execute recorded.
+type Command[T any] interface {
+	Execute(target T) error
+	Undo(target T) error
+}
+
+type options struct {
+	limit int
+}
+
+type Option = funcopts.Option[options]
+
+// WithLimit sets how many commands History can undo, forgetting the
+// oldest beyond it; the default, 0, keeps all of them.
+func WithLimit(n int) Option {
+	return func(options *options) error {
+		if n < 0 {
+			return errors.New("limit cannot be negative")
+		}
+		options.limit = n
+		return nil
+	}
+}
+
+func (o *options) SetDefaults() {}
+
+// command with undo
+// Level: Good
+// pros: undo and redo of any change, at the cost of the change, behind
+// two stacks that know nothing about the changes; commands are values,
+// so they can also be logged, queued or replayed.
+// cons: every change must come with a correct inverse, and go through the
+// History: a change made directly to the target makes the recorded
+// commands lie.
+type History[T any] struct {
+	target  T
+	options options
+	undo    []Command[T]
+	redo    []Command[T]
+}
+
+func NewHistory[T any](target T, opts ...Option) (*History[T], error) {
+	options, err := construct.New(opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &History[T]{target: target, options: *options}, nil
+}
+
+// Execute runs c and records it for Undo, discarding what could be
+// redone. A command that fails is not recorded, and must leave the target
+// as it found it.
+func (h *History[T]) Execute(c Command[T]) error {
+	if err := c.Execute(h.target); err != nil {
+		return err
+	}
+	h.undo = append(h.undo, c)
+	if h.options.limit > 0 && len(h.undo) > h.options.limit {
+		h.undo = append(h.undo[:0], h.undo[1:]...)
+	}
+	clear(h.redo)
+	h.redo = h.redo[:0]
+	return nil
+}
+
+// Undo reverts the last command executed or redone.
+func (h *History[T]) Undo() error {
+	if len(h.undo) == 0 {
+		return ErrNothingToUndo
+	}
+	c := h.undo[len(h.undo)-1]
+	if err := c.Undo(h.target); err != nil {
+		return err
+	}
+	h.undo = h.undo[:len(h.undo)-1]
+	h.redo = append(h.redo, c)
+	return nil
+}
+
+// Redo executes again the last command undone.
+func (h *History[T]) Redo() error {
+	if len(h.redo) == 0 {
+		return ErrNothingToRedo
+	}
+	c := h.redo[len(h.redo)-1]
+	if err := c.Execute(h.target); err != nil {
+		return err
+	}
+	h.redo = h.redo[:len(h.redo)-1]
+	h.undo = append(h.undo, c)
+	return nil
+}
+
+// CanUndo and CanRedo report how many steps each can go.
+func (h *History[T]) CanUndo() int { return len(h.undo) }
+func (h *History[T]) CanRedo() int { return len(h.redo) }
+
+// Macro is a sequence of commands executed, and undone, as one. If one
+// fails, those before it are undone, so the macro fails as a whole.
+type Macro[T any] []Command[T]
+
+func (m Macro[T]) Execute(target T) error {
+	for i, c := range m {
+		if err := c.Execute(target); err != nil {
+			return errors.Join(err, m[:i].Undo(target))
+		}
+	}
+	return nil
+}
+
+func (m Macro[T]) Undo(target T) error {
+	for i := len(m) - 1; i >= 0; i-- {
+		if err := m[i].Undo(target); err != nil {
+			return err
+		}
+	}
+	return nil
+}
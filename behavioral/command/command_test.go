@@ -0,0 +1,250 @@
+package command_test
+
+import (
+	"errors"
+	"testing"
+
+	"patterns/behavioral/command"
+	"patterns/randsource"
+)
+
+func newHistory(t *testing.T, text string, opts ...command.Option) (*command.Document, *command.History[*command.Document]) {
+	t.Helper()
+	doc := &command.Document{}
+	h, err := command.NewHistory(doc, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text != "" {
+		if err := (command.Insert{Text: text}).Execute(doc); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return doc, h
+}
+
+// TestSequence walks one editing session back and forth, checking the
+// text and how far each way the history can go after every step.
+func TestSequence(t *testing.T) {
+	doc, h := newHistory(t, "")
+	for i, s := range []struct {
+		do         func() error
+		want       string
+		undo, redo int
+	}{
+		{func() error { return h.Execute(command.Insert{At: 0, Text: "hello world"}) }, "hello world", 1, 0},
+		{func() error { return h.Execute(&command.Delete{At: 5, N: 6}) }, "hello", 2, 0},
+		{func() error { return h.Execute(command.Insert{At: 0, Text: "¡"}) }, "¡hello", 3, 0},
+		{h.Undo, "hello", 2, 1},
+		{h.Undo, "hello world", 1, 2},
+		{h.Redo, "hello", 2, 1},
+		{h.Undo, "hello world", 1, 2},
+		{h.Undo, "", 0, 3},
+		{h.Redo, "hello world", 1, 2},
+		{h.Redo, "hello", 2, 1},
+		{h.Redo, "¡hello", 3, 0},
+		{h.Undo, "hello", 2, 1},
+		// a new command drops what could be redone
+		{func() error { return h.Execute(&command.Replace{Old: "l", New: "L"}) }, "heLLo", 3, 0},
+		{h.Undo, "hello", 2, 1},
+		{h.Redo, "heLLo", 3, 0},
+	} {
+		if err := s.do(); err != nil {
+			t.Fatalf("step %d: %v", i, err)
+		}
+		if doc.String() != s.want || h.CanUndo() != s.undo || h.CanRedo() != s.redo {
+			t.Fatalf("step %d: %q, can undo %d, redo %d; want %q, %d, %d", i, doc, h.CanUndo(), h.CanRedo(), s.want, s.undo, s.redo)
+		}
+	}
+	if err := h.Redo(); !errors.Is(err, command.ErrNothingToRedo) {
+		t.Errorf("Redo at the end = %v", err)
+	}
+	for h.CanUndo() > 0 {
+		h.Undo()
+	}
+	if err := h.Undo(); !errors.Is(err, command.ErrNothingToUndo) || doc.String() != "" {
+		t.Errorf("Undo at the start = %v, text %q", err, doc)
+	}
+}
+
+// TestRandom runs random sequences of edits, undos and redos against a
+// model that keeps the text each command left: every undo must restore
+// the text of the command before, and every redo the text it reverted.
+func TestRandom(t *testing.T) {
+	for seed := range uint64(100) {
+		r := randsource.New(seed)
+		doc, h := newHistory(t, "")
+		var done, undone []string // the text each command left
+		for step := range 200 {
+			switch n := r.IntN(10); {
+			case n < 2:
+				err := h.Undo()
+				if len(done) == 0 {
+					if !errors.Is(err, command.ErrNothingToUndo) {
+						t.Fatalf("seed %d step %d: Undo = %v", seed, step, err)
+					}
+					continue
+				}
+				undone = append(undone, done[len(done)-1])
+				done = done[:len(done)-1]
+			case n < 4:
+				err := h.Redo()
+				if len(undone) == 0 {
+					if !errors.Is(err, command.ErrNothingToRedo) {
+						t.Fatalf("seed %d step %d: Redo = %v", seed, step, err)
+					}
+					continue
+				}
+				done = append(done, undone[len(undone)-1])
+				undone = undone[:len(undone)-1]
+			default:
+				if err := h.Execute(edit(r, doc.Len())); err != nil {
+					t.Fatalf("seed %d step %d: %v", seed, step, err)
+				}
+				done, undone = append(done, doc.String()), nil
+			}
+			want := ""
+			if len(done) > 0 {
+				want = done[len(done)-1]
+			}
+			if doc.String() != want || h.CanUndo() != len(done) || h.CanRedo() != len(undone) {
+				t.Fatalf("seed %d step %d: %q, want %q", seed, step, doc, want)
+			}
+		}
+	}
+}
+
+// edit returns a random valid edit of a document n runes long.
+func edit(r randsource.Rand, n int) command.Command[*command.Document] {
+	words := []string{"a", "bé", "ab", "€€", ""}
+	switch r.IntN(3) {
+	case 0:
+		return command.Insert{At: r.IntN(n + 1), Text: words[r.IntN(len(words))]}
+	case 1:
+		at := r.IntN(n + 1)
+		return &command.Delete{At: at, N: r.IntN(n - at + 1)}
+	}
+	return &command.Replace{Old: words[r.IntN(len(words)-1)], New: words[r.IntN(len(words))]}
+}
+
+// TestFailed checks that a command that fails leaves the document and
+// the history as they were.
+func TestFailed(t *testing.T) {
+	for _, c := range []struct {
+		cmd  command.Command[*command.Document]
+		want string
+	}{
+		{command.Insert{At: 6, Text: "x"}, "command: insert at 6 is outside 0..5"},
+		{command.Insert{At: -1, Text: "x"}, "command: insert at -1 is outside 0..5"},
+		{&command.Delete{At: 3, N: 3}, "command: delete of 3 at 3 is outside 0..5"},
+		{&command.Delete{At: 0, N: -1}, "command: delete of -1 at 0 is outside 0..5"},
+		{&command.Replace{Old: ""}, "command: replace of an empty string"},
+		// the macro undoes the insert before the failing delete
+		{command.Macro[*command.Document]{command.Insert{At: 5, Text: "!"}, &command.Delete{At: 0, N: 9}},
+			"command: delete of 9 at 0 is outside 0..6"},
+	} {
+		doc, h := newHistory(t, "hello")
+		h.Execute(command.Insert{At: 0, Text: ">"})
+		h.Undo()
+		if err := h.Execute(c.cmd); err == nil || err.Error() != c.want {
+			t.Errorf("Execute(%v) = %v, want %q", c.cmd, err, c.want)
+		}
+		if doc.String() != "hello" || h.CanUndo() != 0 || h.CanRedo() != 1 {
+			t.Errorf("after %v: %q, can undo %d, redo %d", c.cmd, doc, h.CanUndo(), h.CanRedo())
+		}
+	}
+}
+
+func TestReplace(t *testing.T) {
+	for _, c := range []struct {
+		text, old, new, want string
+	}{
+		{"a-b-c", "-", "--", "a--b--c"},
+		{"a--b--c", "--", "", "abc"},
+		{"aaaa", "aa", "a", "aa"},
+		{"日本日本", "本", "本語", "日本語日本語"},
+		{"none", "x", "y", "none"},
+		{"aaa", "a", "aa", "aaaaaa"},
+	} {
+		doc, h := newHistory(t, c.text)
+		if err := h.Execute(&command.Replace{Old: c.old, New: c.new}); err != nil || doc.String() != c.want {
+			t.Errorf("replace %q with %q in %q = %q, %v; want %q", c.old, c.new, c.text, doc, err, c.want)
+		}
+		if h.Undo(); doc.String() != c.text {
+			t.Errorf("undo of replace %q with %q in %q = %q", c.old, c.new, c.text, doc)
+		}
+		if h.Redo(); doc.String() != c.want {
+			t.Errorf("redo of replace %q with %q in %q = %q", c.old, c.new, c.text, doc)
+		}
+	}
+}
+
+func TestMacro(t *testing.T) {
+	doc, h := newHistory(t, "draft")
+	m := command.Macro[*command.Document]{
+		&command.Delete{At: 0, N: 5},
+		command.Insert{At: 0, Text: "final"},
+		command.Insert{At: 5, Text: "!"},
+	}
+	if err := h.Execute(m); err != nil || doc.String() != "final!" || h.CanUndo() != 1 {
+		t.Fatalf("Execute = %v, %q", err, doc)
+	}
+	if h.Undo(); doc.String() != "draft" {
+		t.Errorf("one undo of the macro left %q", doc)
+	}
+}
+
+// failing undoes once it is allowed to.
+type failing struct{ allow bool }
+
+func (*failing) Execute(*command.Document) error { return nil }
+func (f *failing) Undo(*command.Document) error {
+	if !f.allow {
+		return errors.New("locked")
+	}
+	return nil
+}
+
+// TestUndoFails checks that a command whose undo fails stays where it
+// was, to be undone again.
+func TestUndoFails(t *testing.T) {
+	_, h := newHistory(t, "")
+	f := &failing{}
+	h.Execute(f)
+	if err := h.Undo(); err == nil || h.CanUndo() != 1 || h.CanRedo() != 0 {
+		t.Fatalf("Undo = %v, can undo %d, redo %d", err, h.CanUndo(), h.CanRedo())
+	}
+	f.allow = true
+	if err := h.Undo(); err != nil || h.CanUndo() != 0 || h.CanRedo() != 1 {
+		t.Errorf("second Undo = %v", err)
+	}
+}
+
+func TestLimit(t *testing.T) {
+	doc, h := newHistory(t, "", command.WithLimit(2))
+	for _, s := range []string{"a", "b", "c"} {
+		h.Execute(command.Insert{At: doc.Len(), Text: s})
+	}
+	h.Undo()
+	h.Undo()
+	if err := h.Undo(); !errors.Is(err, command.ErrNothingToUndo) || doc.String() != "a" {
+		t.Errorf("third Undo = %v, text %q; want the oldest forgotten", err, doc)
+	}
+	for h.CanRedo() > 0 {
+		h.Redo()
+	}
+	if doc.String() != "abc" {
+		t.Errorf("after redoing all: %q", doc)
+	}
+
+	if _, err := command.NewHistory(doc, command.WithLimit(-1)); err == nil || err.Error() != "limit cannot be negative" {
+		t.Errorf("WithLimit(-1) = %v", err)
+	}
+	_, h = newHistory(t, "", command.WithLimit(0))
+	for range 100 {
+		h.Execute(command.Insert{Text: "x"})
+	}
+	if h.CanUndo() != 100 {
+		t.Errorf("no limit kept %d commands", h.CanUndo())
+	}
+}
@@ -0,0 +1,87 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// Document is the receiver the editing commands act on: text addressed by
+// rune offset.
+type Document struct {
+	text []rune
+}
+
+func (d *Document) String() string { return string(d.text) }
+
+func (d *Document) Len() int { return len(d.text) }
+
+// Insert puts Text at rune offset At.
+type Insert struct {
+	At   int
+	Text string
+}
+
+func (c Insert) Execute(d *Document) error {
+	if c.At < 0 || c.At > len(d.text) {
+		return fmt.Errorf("command: insert at %d is outside 0..%d", c.At, len(d.text))
+	}
+	d.text = slices.Insert(d.text, c.At, []rune(c.Text)...)
+	return nil
+}
+
+func (c Insert) Undo(d *Document) error {
+	d.text = slices.Delete(d.text, c.At, c.At+len([]rune(c.Text)))
+	return nil
+}
+
+// Delete removes N runes from rune offset At. It keeps what it removed
+// for Undo, so it is used by pointer.
+type Delete struct {
+	At, N   int
+	deleted []rune
+}
+
+func (c *Delete) Execute(d *Document) error {
+	if c.At < 0 || c.N < 0 || c.At+c.N > len(d.text) {
+		return fmt.Errorf("command: delete of %d at %d is outside 0..%d", c.N, c.At, len(d.text))
+	}
+	c.deleted = slices.Clone(d.text[c.At : c.At+c.N])
+	d.text = slices.Delete(d.text, c.At, c.At+c.N)
+	return nil
+}
+
+func (c *Delete) Undo(d *Document) error {
+	d.text = slices.Insert(d.text, c.At, c.deleted...)
+	return nil
+}
+
+// Replace swaps every occurrence of Old for New: a Macro of deletes and
+// inserts, built against the document as it is when executed.
+type Replace struct {
+	Old, New string
+	macro    Macro[*Document]
+}
+
+func (c *Replace) Execute(d *Document) error {
+	if c.Old == "" {
+		return errors.New("command: replace of an empty string")
+	}
+	old, repl := []rune(c.Old), []rune(c.New)
+	c.macro = nil
+	// offsets shift by the growth of each replacement made before them
+	shift := 0
+	for i := 0; i+len(old) <= len(d.text); {
+		if !slices.Equal(d.text[i:i+len(old)], old) {
+			i++
+			continue
+		}
+		at := i + shift
+		c.macro = append(c.macro, &Delete{At: at, N: len(old)}, Insert{At: at, Text: c.New})
+		shift += len(repl) - len(old)
+		i += len(old)
+	}
+	return c.macro.Execute(d)
+}
+
+func (c *Replace) Undo(d *Document) error { return c.macro.Undo(d) }
@@ -0,0 +1,86 @@
+package iterator
+
+import (
+	"context"
+	"iter"
+	"math/rand/v2"
+	"testing"
+)
+
+const treeSize = 10_000
+
+var benchTree = func() *Tree[int] {
+	r := rand.New(rand.NewPCG(1, 2))
+	t := &Tree[int]{}
+	for t.Len() < treeSize {
+		t.Insert(r.IntN(1 << 30))
+	}
+	return t
+}()
+
+var sink int
+
+// sumRecursive is the walk with the sum written in place: the baseline
+// with no iterator at all.
+func sumRecursive(n *node[int]) int {
+	if n == nil {
+		return 0
+	}
+	return sumRecursive(n.left) + n.v + sumRecursive(n.right)
+}
+
+func benchSum(sum func(*Tree[int]) int) func(b *testing.B) {
+	return func(b *testing.B) {
+		for range b.N {
+			sink = sum(benchTree)
+		}
+	}
+}
+
+// BenchmarkSum sums a tree of 10,000 values through each kind of iterator
+// and through a plain recursion.
+func BenchmarkSum(b *testing.B) {
+	b.Run("recursive", benchSum(func(t *Tree[int]) int {
+		t.mu.RLock()
+		defer t.mu.RUnlock()
+		return sumRecursive(t.root)
+	}))
+	b.Run("seq", benchSum(func(t *Tree[int]) int {
+		s := 0
+		for v := range t.All() {
+			s += v
+		}
+		return s
+	}))
+	b.Run("seq2", benchSum(func(t *Tree[int]) int {
+		s := 0
+		for _, v := range t.Ranked() {
+			s += v
+		}
+		return s
+	}))
+	b.Run("next", benchSum(func(t *Tree[int]) int {
+		s := 0
+		it := t.Iter()
+		for it.Next() {
+			s += it.Value()
+		}
+		return s
+	}))
+	b.Run("pull", benchSum(func(t *Tree[int]) int {
+		next, stop := iter.Pull(t.All())
+		defer stop()
+		s := 0
+		for v, ok := next(); ok; v, ok = next() {
+			s += v
+		}
+		return s
+	}))
+	b.Run("chan", benchSum(func(t *Tree[int]) int {
+		s := 0
+		for v := range t.Chan(context.Background()) {
+			s += v
+		}
+		return s
+	}))
+}
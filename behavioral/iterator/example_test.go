@@ -0,0 +1,114 @@
+package iterator_test
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"patterns/behavioral/iterator"
+)
+
+func tree(values ...int) *iterator.Tree[int] {
+	t := &iterator.Tree[int]{}
+	for _, v := range values {
+		t.Insert(v)
+	}
+	return t
+}
+
+// Breaking out of the loop returns false from yield: the walk unwinds,
+// its deferred RUnlock runs, and the Insert after the loop does not
+// block.
+func ExampleTree_All() {
+	t := tree(5, 2, 8, 1, 9, 3)
+	for v := range t.All() {
+		if v > 3 {
+			break
+		}
+		fmt.Println(v)
+	}
+	fmt.Println("inserted 4:", t.Insert(4))
+	// Output:
+	// 1
+	// 2
+	// 3
+	// inserted 4: true
+}
+
+// Returning from inside the loop stops the walk the same way as break.
+func ExampleTree_Ranked() {
+	t := tree(5, 2, 8, 1, 9, 3)
+	rank := func(want int) int {
+		for i, v := range t.Ranked() {
+			if v == want {
+				return i
+			}
+		}
+		return -1
+	}
+	fmt.Println(rank(5), rank(7))
+	fmt.Println("inserted 7:", t.Insert(7))
+	// Output:
+	// 3 -1
+	// inserted 7: true
+}
+
+// A pull iterator abandoned early holds the read lock until Stop; the
+// deferred Stop is what lets the Insert through.
+func ExampleTree_Iter() {
+	t := tree(5, 2, 8, 1, 9, 3)
+	first := func(n int) []int {
+		it := t.Iter()
+		defer it.Stop()
+		var out []int
+		for len(out) < n && it.Next() {
+			out = append(out, it.Value())
+		}
+		return out
+	}
+	fmt.Println(first(2))
+	fmt.Println("inserted 4:", t.Insert(4))
+	// Output:
+	// [1 2]
+	// inserted 4: true
+}
+
+// A channel consumer that stops early must cancel: the producer is
+// blocked on its next send, holding the read lock, until it sees ctx
+// done. Insert then waits only for the producer to return.
+func ExampleTree_Chan() {
+	t := tree(5, 2, 8, 1, 9, 3)
+	ctx, cancel := context.WithCancel(context.Background())
+	for v := range t.Chan(ctx) {
+		if v > 2 {
+			break
+		}
+		fmt.Println(v)
+	}
+	cancel()
+	fmt.Println("inserted 4:", t.Insert(4))
+	// Output:
+	// 1
+	// 2
+	// inserted 4: true
+}
+
+// Breaking out of a Merge runs its deferred stops, which end both pulled
+// walks and release both trees.
+func ExampleMerge() {
+	a, b := tree(1, 4, 7, 10), tree(2, 4, 6, 8)
+	var got []int
+	for v := range iterator.Merge(a.All(), b.All()) {
+		if v > 6 {
+			break
+		}
+		got = append(got, v)
+	}
+	fmt.Println(got)
+	fmt.Println("inserted:", a.Insert(5), b.Insert(5))
+	fmt.Println(slices.Collect(iterator.Merge(a.All(), b.All())))
+	// Output:
+	// [1 2 4 6]
+	// inserted: true true
+	// [1 2 4 5 6 7 8 10]
+}
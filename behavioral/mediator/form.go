@@ -0,0 +1,135 @@
+package mediator
+
+import "strings"
+
+// Mediator is all a widget knows of the form it is on.
+type Mediator interface {
+	// Changed is called by a widget after the user changed it.
+	Changed(w Widget)
+}
+
+// Widget is a control on a form.
+type Widget interface {
+	Enabled() bool
+}
+
+// widget is the state every control shares.
+type widget struct {
+	form     Mediator
+	disabled bool
+}
+
+func (w *widget) Enabled() bool { return !w.disabled }
+
+// TextField is a single-line text input.
+type TextField struct {
+	widget
+	text string
+}
+
+func (t *TextField) Text() string { return t.text }
+
+// Type replaces the text, as the user would; a disabled field ignores it.
+func (t *TextField) Type(s string) {
+	if t.disabled {
+		return
+	}
+	t.text = s
+	t.form.Changed(t)
+}
+
+// Checkbox is an on/off control.
+type Checkbox struct {
+	widget
+	checked bool
+}
+
+func (c *Checkbox) Checked() bool { return c.checked }
+
+// Toggle flips the box, as a click would.
+func (c *Checkbox) Toggle() {
+	if c.disabled {
+		return
+	}
+	c.checked = !c.checked
+	c.form.Changed(c)
+}
+
+// Button reports clicks while enabled.
+type Button struct {
+	widget
+}
+
+func (b *Button) Click() {
+	if b.disabled {
+		return
+	}
+	b.form.Changed(b)
+}
+
+// Order is what a submitted Checkout produces.
+type Order struct {
+	Billing, Shipping string
+}
+
+// form mediator
+// Level: Good
+// pros: widgets are reusable, knowing nothing of the form; every rule of
+// the form is in Changed, read in one place.
+// cons: Changed is a switch over every widget and can become the form's
+// god method.
+//
+// Checkout is a form with a billing address, a "ship to the billing
+// address" box, a shipping address and a submit button; the rules:
+//
+//   - while the box is checked, the shipping address is the billing
+//     address and cannot be edited
+//   - submit is enabled only with both addresses filled in
+//
+// Checkout, like its widgets, is driven by one goroutine, as UI
+// toolkits are.
+type Checkout struct {
+	Billing       *TextField
+	SameAsBilling *Checkbox
+	Shipping      *TextField
+	Submit        *Button
+
+	submitted func(Order)
+}
+
+// NewCheckout returns an empty form calling submitted when submitted.
+func NewCheckout(submitted func(Order)) *Checkout {
+	c := &Checkout{submitted: submitted}
+	c.Billing = &TextField{widget: widget{form: c}}
+	c.SameAsBilling = &Checkbox{widget: widget{form: c}}
+	c.Shipping = &TextField{widget: widget{form: c}}
+	c.Submit = &Button{widget: widget{form: c}}
+	c.update()
+	return c
+}
+
+// Changed applies the form's rules after w changed. It sets the other
+// widgets' state directly rather than through their user-facing methods,
+// so they do not report back and loop.
+func (c *Checkout) Changed(w Widget) {
+	switch w {
+	case c.Submit:
+		c.submitted(Order{Billing: c.Billing.text, Shipping: c.Shipping.text})
+		return
+	case c.SameAsBilling:
+		if !c.SameAsBilling.checked {
+			c.Shipping.text = ""
+		}
+	}
+	c.update()
+}
+
+func (c *Checkout) update() {
+	same := c.SameAsBilling.checked
+	c.Shipping.disabled = same
+	if same {
+		c.Shipping.text = c.Billing.text
+	}
+	filled := strings.TrimSpace(c.Billing.text) != "" && strings.TrimSpace(c.Shipping.text) != ""
+	c.Submit.disabled = !filled
+}
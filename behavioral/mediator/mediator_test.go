@@ -0,0 +1,282 @@
+package mediator_test
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"sync"
+	"testing"
+
+	"patterns/behavioral/mediator"
+)
+
+// inbox is a Participant keeping what it receives.
+type inbox struct {
+	mu   sync.Mutex
+	msgs []mediator.Message
+}
+
+func (in *inbox) Receive(m mediator.Message) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	in.msgs = append(in.msgs, m)
+}
+
+// take returns the messages received since the last take.
+func (in *inbox) take() []mediator.Message {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	out := in.msgs
+	in.msgs = nil
+	return out
+}
+
+func join(t *testing.T, r *mediator.Room, name string) (*mediator.Seat, *inbox) {
+	t.Helper()
+	in := &inbox{}
+	s, err := r.Join(name, in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, in
+}
+
+func expect(t *testing.T, who string, in *inbox, want ...mediator.Message) {
+	t.Helper()
+	if got := in.take(); !slices.Equal(got, want) {
+		t.Errorf("%s received %v, want %v", who, got, want)
+	}
+}
+
+func TestRoom(t *testing.T) {
+	var r mediator.Room
+	ann, annIn := join(t, &r, "ann")
+	bob, bobIn := join(t, &r, "bob")
+	_, carlIn := join(t, &r, "carl")
+	expect(t, "ann", annIn, mediator.Message{Text: "bob joined"}, mediator.Message{Text: "carl joined"})
+	expect(t, "bob", bobIn, mediator.Message{Text: "carl joined"})
+	expect(t, "carl", carlIn)
+
+	ann.Send("bob", "hi")
+	expect(t, "bob", bobIn, mediator.Message{From: "ann", To: "bob", Text: "hi"})
+	expect(t, "carl", carlIn)
+
+	bob.Broadcast("hello all")
+	expect(t, "ann", annIn, mediator.Message{From: "bob", Text: "hello all"})
+	expect(t, "carl", carlIn, mediator.Message{From: "bob", Text: "hello all"})
+	expect(t, "bob", bobIn)
+
+	// a muted member's messages are dropped, without an error
+	r.Mute("bob", true)
+	if err := bob.Send("ann", "psst"); err != nil {
+		t.Errorf("muted Send = %v", err)
+	}
+	bob.Broadcast("anyone?")
+	expect(t, "ann", annIn)
+	// and it still hears the others
+	ann.Send("bob", "you are muted")
+	expect(t, "bob", bobIn, mediator.Message{From: "ann", To: "bob", Text: "you are muted"})
+	r.Mute("bob", false)
+	bob.Send("ann", "back")
+	expect(t, "ann", annIn, mediator.Message{From: "bob", To: "ann", Text: "back"})
+
+	if err := bob.Leave(); err != nil {
+		t.Fatal(err)
+	}
+	expect(t, "ann", annIn, mediator.Message{Text: "bob left"})
+	expect(t, "carl", carlIn, mediator.Message{Text: "bob left"})
+	if got := r.Members(); !slices.Equal(got, []string{"ann", "carl"}) {
+		t.Errorf("Members = %v", got)
+	}
+}
+
+func TestRoomErrors(t *testing.T) {
+	var r mediator.Room
+	ann, _ := join(t, &r, "ann")
+	if _, err := r.Join("ann", &inbox{}); !errors.Is(err, mediator.ErrNameTaken) || err.Error() != `mediator: name taken: "ann"` {
+		t.Errorf("Join of a taken name = %v", err)
+	}
+	if err := ann.Send("zed", "hi"); !errors.Is(err, mediator.ErrNoSuchMember) {
+		t.Errorf("Send to nobody = %v", err)
+	}
+	if err := r.Mute("zed", true); !errors.Is(err, mediator.ErrNoSuchMember) {
+		t.Errorf("Mute of nobody = %v", err)
+	}
+
+	// a seat outlives its member: after Leave it does nothing, even once
+	// someone else has joined under the same name
+	ann.Leave()
+	again, _ := join(t, &r, "ann")
+	_, bobIn := join(t, &r, "bob")
+	for name, err := range map[string]error{
+		"Send": ann.Send("bob", "ghost"), "Broadcast": ann.Broadcast("ghost"), "Leave": ann.Leave(),
+	} {
+		if !errors.Is(err, mediator.ErrLeft) {
+			t.Errorf("%s after Leave = %v", name, err)
+		}
+	}
+	expect(t, "bob", bobIn)
+	if again.Name() != "ann" || !slices.Equal(r.Members(), []string{"ann", "bob"}) {
+		t.Errorf("the new ann was removed by the old one's seat: %v", r.Members())
+	}
+}
+
+// echo answers every direct message from inside Receive.
+type echo struct {
+	seat *mediator.Seat
+	inbox
+}
+
+func (e *echo) Receive(m mediator.Message) {
+	e.inbox.Receive(m)
+	if m.To != "" {
+		e.seat.Send(m.From, "echo: "+m.Text)
+	}
+}
+
+// TestReceiveSends checks that a participant may talk while receiving:
+// the room does not hold its lock over Receive.
+func TestReceiveSends(t *testing.T) {
+	var r mediator.Room
+	e := &echo{}
+	e.seat, _ = r.Join("echo", e)
+	ann, annIn := join(t, &r, "ann")
+	ann.Send("echo", "ping")
+	expect(t, "ann", annIn, mediator.Message{From: "echo", To: "ann", Text: "echo: ping"})
+}
+
+// TestConcurrent has members join, talk and leave at once: each receiver
+// gets any one sender's messages in the order sent. Run with -race.
+func TestConcurrent(t *testing.T) {
+	var r mediator.Room
+	listener, in := join(t, &r, "listener")
+	defer listener.Leave()
+	const senders, n = 8, 100
+	var wg sync.WaitGroup
+	for i := range senders {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s, err := r.Join(fmt.Sprintf("s%d", i), &inbox{})
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			for j := range n {
+				if j%2 == 0 {
+					s.Send("listener", fmt.Sprint(j))
+				} else {
+					s.Broadcast(fmt.Sprint(j))
+				}
+			}
+			r.Members()
+			s.Leave()
+		}()
+	}
+	wg.Wait()
+	next := map[string]int{}
+	for _, m := range in.take() {
+		if m.From == "" {
+			continue
+		}
+		if m.Text != fmt.Sprint(next[m.From]) {
+			t.Fatalf("from %s: %q, want %d", m.From, m.Text, next[m.From])
+		}
+		next[m.From]++
+	}
+	for i := range senders {
+		if s := fmt.Sprintf("s%d", i); next[s] != n {
+			t.Errorf("%d messages from %s, want %d", next[s], s, n)
+		}
+	}
+	if got := r.Members(); !slices.Equal(got, []string{"listener"}) {
+		t.Errorf("Members = %v", got)
+	}
+}
+
+// TestNoDirectReferences checks that no component holds a field of a
+// peer's type: widgets reach the form only as a Mediator, and seats the
+// room, never a participant.
+func TestNoDirectReferences(t *testing.T) {
+	participant := reflect.TypeFor[mediator.Participant]()
+	widgets := []reflect.Type{
+		reflect.TypeFor[mediator.TextField](), reflect.TypeFor[mediator.Checkbox](),
+		reflect.TypeFor[mediator.Button](), reflect.TypeFor[mediator.Checkout](),
+	}
+	for _, c := range []struct {
+		component reflect.Type
+		peers     []reflect.Type
+		iface     reflect.Type // the one interface it may hold, if any
+	}{
+		{reflect.TypeFor[mediator.Seat](), []reflect.Type{participant, reflect.TypeFor[mediator.Seat]()}, nil},
+		{widgets[0], widgets, reflect.TypeFor[mediator.Mediator]()},
+		{widgets[1], widgets, reflect.TypeFor[mediator.Mediator]()},
+		{widgets[2], widgets, reflect.TypeFor[mediator.Mediator]()},
+	} {
+		for _, f := range fields(c.component) {
+			typ := f.Type
+			for typ.Kind() == reflect.Pointer || typ.Kind() == reflect.Slice || typ.Kind() == reflect.Map {
+				typ = typ.Elem()
+			}
+			if slices.Contains(c.peers, typ) || typ.Kind() == reflect.Interface && typ != c.iface {
+				t.Errorf("%s.%s is a %s", c.component.Name(), f.Name, f.Type)
+			}
+		}
+	}
+}
+
+// fields returns the fields of struct type typ, those of embedded
+// structs included.
+func fields(typ reflect.Type) []reflect.StructField {
+	var out []reflect.StructField
+	for i := range typ.NumField() {
+		f := typ.Field(i)
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			out = append(out, fields(f.Type)...)
+			continue
+		}
+		out = append(out, f)
+	}
+	return out
+}
+
+func TestCheckout(t *testing.T) {
+	var orders []mediator.Order
+	c := mediator.NewCheckout(func(o mediator.Order) { orders = append(orders, o) })
+	state := func(step string, shipping string, shippingEnabled, submitEnabled bool) {
+		t.Helper()
+		if c.Shipping.Text() != shipping || c.Shipping.Enabled() != shippingEnabled || c.Submit.Enabled() != submitEnabled {
+			t.Errorf("%s: shipping %q enabled %v, submit enabled %v; want %q, %v, %v", step, c.Shipping.Text(), c.Shipping.Enabled(),
+				c.Submit.Enabled(), shipping, shippingEnabled, submitEnabled)
+		}
+	}
+	state("new", "", true, false)
+	c.Submit.Click()
+	if len(orders) != 0 {
+		t.Error("submitted while disabled")
+	}
+	c.Billing.Type("1 Main St")
+	state("billing typed", "", true, false)
+	c.SameAsBilling.Toggle()
+	state("same as billing", "1 Main St", false, true)
+	c.Billing.Type("2 Side St")
+	state("billing changed", "2 Side St", false, true)
+	c.Shipping.Type("ignored")
+	state("shipping typed while disabled", "2 Side St", false, true)
+	c.Submit.Click()
+	c.SameAsBilling.Toggle()
+	state("unchecked", "", true, false)
+	c.Shipping.Type("   ")
+	state("blank shipping", "   ", true, false)
+	c.Shipping.Type("3 Dock Rd")
+	state("shipping typed", "3 Dock Rd", true, true)
+	c.Submit.Click()
+	want := []mediator.Order{{Billing: "2 Side St", Shipping: "2 Side St"}, {Billing: "2 Side St", Shipping: "3 Dock Rd"}}
+	if !slices.Equal(orders, want) {
+		t.Errorf("orders %v, want %v", orders, want)
+	}
+	if !c.Billing.Enabled() || !c.SameAsBilling.Enabled() || c.SameAsBilling.Checked() {
+		t.Error("billing and the box are always enabled, and the box is unchecked")
+	}
+}
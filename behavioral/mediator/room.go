@@ -0,0 +1,187 @@
+// Package mediator keeps components that must cooperate from knowing
+// each other: each talks only to a mediator, which knows them all and
+// holds the rules of how they interact. Two forms:
+//
+//   - Room, a chat room: participants address each other by name through
+//     the seat they were given on joining, and the room routes, announces
+//     joins and leaves, and mutes; a participant added or removed changes
+//     no other participant
+//   - Checkout, a form: its widgets report their changes to the dialog,
+//     which enables, disables and fills in the others; the rules live in
+//     one place instead of every widget holding pointers to its
+//     neighbours
+//
+// Neither kind of component has a field of another's type: a Participant
+// has a *Seat, a widget a Mediator interface.
+package mediator
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+var (
+	ErrNameTaken    = errors.New("mediator: name taken")
+	ErrNoSuchMember = errors.New("mediator: no such member")
+	// ErrLeft is returned by a Seat whose participant has left.
+	ErrLeft = errors.New("mediator: left the room")
+)
+
+// Message is what the room delivers; From is empty for the room's own
+// announcements, To is empty for a message to everyone.
+type Message struct {
+	From, To string
+	Text     string
+}
+
+// Participant receives the room's messages. Receive is called without
+// the room's lock held, so it may send; messages from one sender arrive
+// in the order sent, those of different senders in no fixed order.
+type Participant interface {
+	Receive(m Message)
+}
+
+// mediator
+// Level: Good
+// pros: participants depend on the room alone, so they come and go, and
+// the routing rules change, without touching any other participant.
+// cons: the mediator knows everyone and every rule, and grows with them;
+// a message goes through one more hop than a direct call.
+//
+// Room routes messages between participants; it is safe for concurrent
+// use. The zero Room is ready to use.
+type Room struct {
+	mu      sync.RWMutex
+	members map[string]*member
+}
+
+type member struct {
+	p     Participant
+	muted bool
+}
+
+// Seat is a participant's handle on the room: all it needs to talk to the
+// others, naming them, with no reference to them.
+type Seat struct {
+	room *Room
+	name string
+	// self tells this seat from one given to a later member of the same name
+	self *member
+}
+
+// Join adds p as name, announces it to the others, and returns p's seat.
+func (r *Room) Join(name string, p Participant) (*Seat, error) {
+	r.mu.Lock()
+	if _, ok := r.members[name]; ok {
+		r.mu.Unlock()
+		return nil, fmt.Errorf("%w: %q", ErrNameTaken, name)
+	}
+	if r.members == nil {
+		r.members = map[string]*member{}
+	}
+	self := &member{p: p}
+	r.members[name] = self
+	others := r.recipients(name)
+	r.mu.Unlock()
+	deliver(others, Message{Text: name + " joined"})
+	return &Seat{room: r, name: name, self: self}, nil
+}
+
+// Members returns the names in the room, sorted.
+func (r *Room) Members() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.members))
+	for n := range r.members {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Mute stops or resumes delivery of name's messages to the others.
+func (r *Room) Mute(name string, muted bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	m, ok := r.members[name]
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrNoSuchMember, name)
+	}
+	m.muted = muted
+	return nil
+}
+
+// recipients returns everyone but except; r.mu is held.
+func (r *Room) recipients(except string) []Participant {
+	ps := make([]Participant, 0, len(r.members))
+	for n, m := range r.members {
+		if n != except {
+			ps = append(ps, m.p)
+		}
+	}
+	return ps
+}
+
+func deliver(ps []Participant, m Message) {
+	for _, p := range ps {
+		p.Receive(m)
+	}
+}
+
+func (s *Seat) Name() string { return s.name }
+
+// Send delivers text to the member called to. A muted sender's message is
+// dropped without an error, as a room would.
+func (s *Seat) Send(to, text string) error {
+	r := s.room
+	r.mu.RLock()
+	if r.members[s.name] != s.self {
+		r.mu.RUnlock()
+		return ErrLeft
+	}
+	dst, ok := r.members[to]
+	muted := s.self.muted
+	r.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrNoSuchMember, to)
+	}
+	if !muted {
+		dst.p.Receive(Message{From: s.name, To: to, Text: text})
+	}
+	return nil
+}
+
+// Broadcast delivers text to every other member.
+func (s *Seat) Broadcast(text string) error {
+	r := s.room
+	r.mu.RLock()
+	if r.members[s.name] != s.self {
+		r.mu.RUnlock()
+		return ErrLeft
+	}
+	var others []Participant
+	if !s.self.muted {
+		others = r.recipients(s.name)
+	}
+	r.mu.RUnlock()
+	deliver(others, Message{From: s.name, Text: text})
+	return nil
+}
+
+// Leave removes the participant and announces it; the seat is unusable
+// afterwards.
+func (s *Seat) Leave() error {
+	r := s.room
+	r.mu.Lock()
+	if r.members[s.name] != s.self {
+		r.mu.Unlock()
+		return ErrLeft
+	}
+	delete(r.members, s.name)
+	others := r.recipients(s.name)
+	r.mu.Unlock()
+	deliver(others, Message{Text: s.name + " left"})
+	return nil
+}
@@ -0,0 +1,295 @@
+// Package observer delivers values published on a Subject to every
+// subscribed Observer, each from its own goroutine and buffer, so a slow
+// observer delays nobody but itself (and, under OverflowBlock, the
+// publisher):
+//
+//	prices, _ := observer.NewSubject[Quote](observer.WithBuffer(64))
+//	stop := prices.Subscribe(ctx, observer.Func[Quote](func(ctx context.Context, q Quote) {
+//		ticker.Update(q)
+//	}))
+//	defer stop()
+//	prices.Publish(ctx, Quote{Symbol: "GO", Price: 42})
+//
+// An observer sees values in the order one goroutine published them, and
+// never two Notify calls at once. A subscription ends when its context is
+// done, when the returned func is called, or when the subject is closed;
+// Close delivers what is already buffered before it returns.
+//
+// examples/chat and resilience/discovery carry small hand-written
+// versions; this is the general one.
+package observer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"patterns/construct"
+	"patterns/funcopts"
+)
+
+// Observer receives values from a Subject. ctx is the subscription's
+// context, done once the subscription ends.
+type Observer[T any] interface {
+	Notify(ctx context.Context, v T)
+}
+
+// Func adapts a function to Observer.
+type Func[T any] func(ctx context.Context, v T)
+
+func (f Func[T]) Notify(ctx context.Context, v T) { f(ctx, v) }
+
+//go:generate go run patterns/cmd/enumgen -type=Overflow -trimprefix=Overflow
+
+// Overflow decides what Publish does when an observer's buffer is full.
+type Overflow int
+
+const (
+	// OverflowBlock makes Publish wait for room, or for its context: no
+	// value is lost, and the slowest observer sets the pace.
+	OverflowBlock Overflow = iota
+	// OverflowDropNewest discards the value being published for that
+	// observer.
+	OverflowDropNewest
+	// OverflowDropOldest discards the oldest buffered value to make room,
+	// for observers that only care about the latest state.
+	OverflowDropOldest
+)
+
+var ErrClosed = errors.New("observer: subject is closed")
+
+type options struct {
+	buffer   int
+	overflow Overflow
+}
+
+type Option = funcopts.Option[options]
+
+// WithBuffer sets how many values each observer may fall behind by; the
+// default is 16.
+func WithBuffer(n int) Option {
+	return func(options *options) error {
+		if n <= 0 {
+			return errors.New("buffer must be positive")
+		}
+		options.buffer = n
+		return nil
+	}
+}
+
+// WithOverflow sets the policy for a full buffer; the default is
+// OverflowBlock.
+func WithOverflow(o Overflow) Option {
+	return func(options *options) error {
+		if _, ok := _OverflowNames[o]; !ok {
+			return fmt.Errorf("invalid overflow policy %d", o)
+		}
+		options.overflow = o
+		return nil
+	}
+}
+
+func (o *options) SetDefaults() {
+	o.buffer = 16
+	o.overflow = OverflowBlock
+}
+
+// observer pattern
+// Level: Good
+// pros: publishers and observers know nothing of each other; per-observer
+// goroutines and buffers isolate a slow or stuck observer, and contexts
+// end subscriptions without bookkeeping.
+// cons: delivery is asynchronous, so Publish returning does not mean
+// anyone has seen the value; with many observers every value is copied
+// into every buffer.
+type Subject[T any] struct {
+	options options
+
+	mu       sync.RWMutex
+	subs     map[*subscription[T]]struct{}
+	closed   bool
+	inflight sync.WaitGroup // Publish calls past the closed check
+	dropped  atomic.Int64
+}
+
+type subscription[T any] struct {
+	ch       chan T
+	ctx      context.Context
+	cancel   context.CancelFunc
+	closing  chan struct{} // closed by Subject.Close: drain, then stop
+	finished chan struct{}
+	// mu serializes DropOldest's make-room-and-send
+	mu sync.Mutex
+}
+
+func NewSubject[T any](opts ...Option) (*Subject[T], error) {
+	options, err := construct.New(opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &Subject[T]{options: *options, subs: map[*subscription[T]]struct{}{}}, nil
+}
+
+// Subscribe starts delivering published values to o until ctx is done or
+// the returned func is called. A Notify call already running when the
+// subscription ends is not interrupted; buffered values are discarded.
+// On a closed subject Subscribe returns a no-op func and o is never
+// called.
+func (s *Subject[T]) Subscribe(ctx context.Context, o Observer[T]) (unsubscribe func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	sub := &subscription[T]{
+		ch:       make(chan T, s.options.buffer),
+		ctx:      ctx,
+		cancel:   cancel,
+		closing:  make(chan struct{}),
+		finished: make(chan struct{}),
+	}
+
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		cancel()
+		return func() {}
+	}
+	s.subs[sub] = struct{}{}
+	s.mu.Unlock()
+
+	go s.deliver(sub, o)
+	return cancel
+}
+
+func (s *Subject[T]) deliver(sub *subscription[T], o Observer[T]) {
+	defer close(sub.finished)
+	defer s.remove(sub)
+	for {
+		select {
+		case <-sub.ctx.Done():
+			return
+		case v := <-sub.ch:
+			// select picks at random when both are ready
+			if sub.ctx.Err() != nil {
+				return
+			}
+			o.Notify(sub.ctx, v)
+		case <-sub.closing:
+			for {
+				select {
+				case v := <-sub.ch:
+					if sub.ctx.Err() != nil {
+						return
+					}
+					o.Notify(sub.ctx, v)
+				default:
+					sub.cancel()
+					return
+				}
+			}
+		}
+	}
+}
+
+func (s *Subject[T]) remove(sub *subscription[T]) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.subs, sub)
+}
+
+// Publish hands v to every current observer. Under OverflowBlock it waits
+// while a buffer is full and returns ctx.Err() if ctx ends first, with v
+// delivered to some observers only; the drop policies never wait.
+func (s *Subject[T]) Publish(ctx context.Context, v T) error {
+	s.mu.RLock()
+	if s.closed {
+		s.mu.RUnlock()
+		return ErrClosed
+	}
+	s.inflight.Add(1)
+	defer s.inflight.Done()
+	subs := make([]*subscription[T], 0, len(s.subs))
+	for sub := range s.subs {
+		subs = append(subs, sub)
+	}
+	s.mu.RUnlock()
+
+	for _, sub := range subs {
+		if err := s.send(ctx, sub, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Subject[T]) send(ctx context.Context, sub *subscription[T], v T) error {
+	switch s.options.overflow {
+	case OverflowBlock:
+		select {
+		case sub.ch <- v:
+		case <-sub.ctx.Done():
+			// the observer left; nothing to wait for
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	case OverflowDropNewest:
+		select {
+		case sub.ch <- v:
+		default:
+			s.dropped.Add(1)
+		}
+	case OverflowDropOldest:
+		sub.mu.Lock()
+		defer sub.mu.Unlock()
+		for {
+			select {
+			case sub.ch <- v:
+				return nil
+			default:
+			}
+			select {
+			case <-sub.ch:
+				s.dropped.Add(1)
+			default:
+			}
+		}
+	}
+	return nil
+}
+
+// Dropped counts values discarded by the drop policies.
+func (s *Subject[T]) Dropped() int64 { return s.dropped.Load() }
+
+// Observers reports how many subscriptions are active.
+func (s *Subject[T]) Observers() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.subs)
+}
+
+// Close rejects later Publish and Subscribe calls, waits for the Publish
+// calls in progress, then lets every observer finish its buffer and
+// waits for that. Close must not be called from Notify.
+func (s *Subject[T]) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return ErrClosed
+	}
+	s.closed = true
+	s.mu.Unlock()
+
+	s.inflight.Wait()
+	s.mu.RLock()
+	subs := make([]*subscription[T], 0, len(s.subs))
+	for sub := range s.subs {
+		subs = append(subs, sub)
+	}
+	s.mu.RUnlock()
+	for _, sub := range subs {
+		close(sub.closing)
+	}
+	for _, sub := range subs {
+		<-sub.finished
+	}
+	return nil
+}
@@ -0,0 +1,316 @@
+package observer_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"patterns/behavioral/observer"
+)
+
+type msg struct{ pub, seq int }
+
+// recorder keeps what it was notified of and fails the test if two
+// Notify calls ever overlap.
+type recorder struct {
+	t      *testing.T
+	active atomic.Int32
+	mu     sync.Mutex
+	got    []msg
+}
+
+func (r *recorder) Notify(_ context.Context, m msg) {
+	if r.active.Add(1) != 1 {
+		r.t.Error("two Notify calls at once")
+	}
+	r.mu.Lock()
+	r.got = append(r.got, m)
+	r.mu.Unlock()
+	r.active.Add(-1)
+}
+
+// inOrder checks that r saw seqs 0 to n-1 of every publisher in pubs,
+// each once and in order.
+func (r *recorder) inOrder(pubs, n int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	next := make([]int, pubs)
+	for _, m := range r.got {
+		if m.seq != next[m.pub] {
+			return errors.New("out of order or repeated")
+		}
+		next[m.pub]++
+	}
+	for _, c := range next {
+		if c != n {
+			return errors.New("missing values")
+		}
+	}
+	return nil
+}
+
+func eventually(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); !cond(); {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// TestConcurrentPublish checks that with several publishers at once every
+// observer still sees each value once, in each publisher's order, and
+// one Notify at a time.
+func TestConcurrentPublish(t *testing.T) {
+	const pubs, n = 8, 500
+	s, _ := observer.NewSubject[msg](observer.WithBuffer(4))
+	obs := make([]*recorder, 5)
+	for i := range obs {
+		obs[i] = &recorder{t: t}
+		s.Subscribe(context.Background(), obs[i])
+	}
+
+	var wg sync.WaitGroup
+	for p := range pubs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range n {
+				if err := s.Publish(context.Background(), msg{p, i}); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	s.Close()
+	for i, r := range obs {
+		if err := r.inOrder(pubs, n); err != nil {
+			t.Errorf("observer %d: %v", i, err)
+		}
+	}
+}
+
+// TestChurn subscribes and unsubscribes while values are published: the
+// subscription held throughout sees everything, and no subscription
+// outlives Close.
+func TestChurn(t *testing.T) {
+	const pubs, n = 4, 500
+	s, _ := observer.NewSubject[msg](observer.WithBuffer(2))
+	steady := &recorder{t: t}
+	s.Subscribe(context.Background(), steady)
+
+	var churn sync.WaitGroup
+	for range 4 {
+		churn.Add(1)
+		go func() {
+			defer churn.Done()
+			for i := range 200 {
+				ctx, cancel := context.WithCancel(context.Background())
+				unsubscribe := s.Subscribe(ctx, observer.Func[msg](func(context.Context, msg) {}))
+				// end half the subscriptions each way
+				if i%2 == 0 {
+					unsubscribe()
+				}
+				cancel()
+			}
+		}()
+	}
+
+	var wg sync.WaitGroup
+	for p := range pubs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range n {
+				s.Publish(context.Background(), msg{p, i})
+			}
+		}()
+	}
+	wg.Wait()
+	churn.Wait()
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := steady.inOrder(pubs, n); err != nil {
+		t.Errorf("steady observer: %v", err)
+	}
+	if got := s.Observers(); got != 0 {
+		t.Errorf("%d observers after Close", got)
+	}
+}
+
+// TestCloseWhilePublishing checks that every Publish that returned nil
+// before Close was delivered by the time Close returns, and every later
+// one returned ErrClosed.
+func TestCloseWhilePublishing(t *testing.T) {
+	const pubs = 4
+	s, _ := observer.NewSubject[msg](observer.WithBuffer(1))
+	r := &recorder{t: t}
+	s.Subscribe(context.Background(), r)
+
+	sent := make([]int, pubs)
+	var wg sync.WaitGroup
+	for p := range pubs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; ; i++ {
+				if err := s.Publish(context.Background(), msg{p, i}); err != nil {
+					if !errors.Is(err, observer.ErrClosed) {
+						t.Error(err)
+					}
+					return
+				}
+				sent[p] = i + 1
+			}
+		}()
+	}
+	eventually(t, "values to flow", func() bool {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		return len(r.got) > 100
+	})
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	r.mu.Lock()
+	got := slices.Clone(r.got)
+	r.mu.Unlock()
+	wg.Wait()
+
+	seen := make([]int, pubs)
+	for _, m := range got {
+		seen[m.pub] = max(seen[m.pub], m.seq+1)
+	}
+	if !slices.Equal(seen, sent) {
+		t.Errorf("delivered up to %v, published %v", seen, sent)
+	}
+	if err := s.Close(); !errors.Is(err, observer.ErrClosed) {
+		t.Errorf("second Close = %v", err)
+	}
+	called := false
+	s.Subscribe(context.Background(), observer.Func[msg](func(context.Context, msg) { called = true }))()
+	if err := s.Publish(context.Background(), msg{}); !errors.Is(err, observer.ErrClosed) || called {
+		t.Errorf("Publish after Close = %v, observer called: %v", err, called)
+	}
+}
+
+// stuck takes its first value and holds it until released, so the
+// values after it queue in the buffer.
+type stuck struct {
+	first   chan int
+	release chan struct{}
+	mu      sync.Mutex
+	got     []int
+}
+
+func newStuck() *stuck { return &stuck{first: make(chan int, 1), release: make(chan struct{})} }
+
+func (s *stuck) Notify(ctx context.Context, v int) {
+	s.mu.Lock()
+	s.got = append(s.got, v)
+	n := len(s.got)
+	s.mu.Unlock()
+	if n == 1 {
+		s.first <- v
+		select {
+		case <-s.release:
+		case <-ctx.Done():
+		}
+	}
+}
+
+func TestOverflow(t *testing.T) {
+	for _, c := range []struct {
+		overflow observer.Overflow
+		want     []int
+		dropped  int64
+	}{
+		{observer.OverflowDropNewest, []int{1, 2, 3}, 3},
+		{observer.OverflowDropOldest, []int{1, 5, 6}, 3},
+	} {
+		s, _ := observer.NewSubject[int](observer.WithBuffer(2), observer.WithOverflow(c.overflow))
+		o := newStuck()
+		s.Subscribe(context.Background(), o)
+		s.Publish(context.Background(), 1)
+		<-o.first
+		for v := 2; v <= 6; v++ {
+			if err := s.Publish(context.Background(), v); err != nil {
+				t.Errorf("%s: Publish(%d) = %v", c.overflow, v, err)
+			}
+		}
+		close(o.release)
+		s.Close()
+		if !slices.Equal(o.got, c.want) || s.Dropped() != c.dropped {
+			t.Errorf("%s: got %v, dropped %d; want %v, %d", c.overflow, o.got, s.Dropped(), c.want, c.dropped)
+		}
+	}
+}
+
+// TestBlock checks that a full buffer holds Publish until its context
+// ends, or until the slow observer leaves.
+func TestBlock(t *testing.T) {
+	s, _ := observer.NewSubject[int](observer.WithBuffer(1))
+	o := newStuck()
+	unsubscribe := s.Subscribe(context.Background(), o)
+	s.Publish(context.Background(), 1)
+	<-o.first
+	s.Publish(context.Background(), 2) // fills the buffer
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := s.Publish(ctx, 3); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Publish to a full buffer = %v, want the context's error", err)
+	}
+
+	done := make(chan error)
+	go func() { done <- s.Publish(context.Background(), 4) }()
+	select {
+	case err := <-done:
+		t.Fatalf("Publish returned %v with the buffer full", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+	unsubscribe()
+	if err := <-done; err != nil {
+		t.Errorf("Publish after the observer left = %v", err)
+	}
+	eventually(t, "the observer to be removed", func() bool { return s.Observers() == 0 })
+	if slices.Contains(o.got, 2) {
+		t.Errorf("got %v: buffered values are discarded on unsubscribe", o.got)
+	}
+}
+
+// TestSubscriptionContext checks that a subscription ends with its
+// context and that Notify sees that context.
+func TestSubscriptionContext(t *testing.T) {
+	s, _ := observer.NewSubject[int]()
+	ctx, cancel := context.WithCancel(context.Background())
+	seen := make(chan context.Context, 1)
+	s.Subscribe(ctx, observer.Func[int](func(ctx context.Context, _ int) { seen <- ctx }))
+	if n := s.Observers(); n != 1 {
+		t.Fatalf("Observers = %d, want 1", n)
+	}
+	s.Publish(context.Background(), 1)
+	got := <-seen
+	cancel()
+	eventually(t, "the subscription to end", func() bool { return s.Observers() == 0 })
+	if got.Err() == nil {
+		t.Error("Notify's context is not done after the subscription ended")
+	}
+}
+
+func TestOptions(t *testing.T) {
+	if _, err := observer.NewSubject[int](observer.WithBuffer(0)); err == nil || err.Error() != "buffer must be positive" {
+		t.Errorf("WithBuffer(0) = %v", err)
+	}
+	if _, err := observer.NewSubject[int](observer.WithOverflow(7)); err == nil || err.Error() != "invalid overflow policy 7" {
+		t.Errorf("WithOverflow(7) = %v", err)
+	}
+}
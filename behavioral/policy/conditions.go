@@ -0,0 +1,62 @@
+package policy
+
+import "slices"
+
+// Always holds for every request: the condition of a catch-all rule.
+func Always() Condition { return func(Request) bool { return true } }
+
+// All holds when every condition does.
+func All(cs ...Condition) Condition {
+	return func(r Request) bool {
+		for _, c := range cs {
+			if !c(r) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Any holds when at least one condition does.
+func Any(cs ...Condition) Condition {
+	return func(r Request) bool {
+		for _, c := range cs {
+			if c(r) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func Not(c Condition) Condition { return func(r Request) bool { return !c(r) } }
+
+// HasRole holds when the subject has any of roles.
+func HasRole(roles ...string) Condition {
+	return func(r Request) bool {
+		return slices.ContainsFunc(r.Subject.Roles, func(role string) bool { return slices.Contains(roles, role) })
+	}
+}
+
+// ActionIs holds when the action is any of actions.
+func ActionIs(actions ...string) Condition {
+	return func(r Request) bool { return slices.Contains(actions, r.Action) }
+}
+
+// ResourceIs holds when the resource is of any of types.
+func ResourceIs(types ...string) Condition {
+	return func(r Request) bool { return slices.Contains(types, r.Resource.Type) }
+}
+
+// IsOwner holds when the subject owns the resource.
+func IsOwner() Condition {
+	return func(r Request) bool { return r.Resource.Owner != "" && r.Resource.Owner == r.Subject.ID }
+}
+
+// AttrIs holds when the attribute key has the value value.
+func AttrIs(key, value string) Condition {
+	return func(r Request) bool {
+		v, ok := r.Attrs[key]
+		return ok && v == value
+	}
+}
@@ -0,0 +1,47 @@
+package policy
+
+import (
+	"context"
+	"net/http"
+
+	"patterns/web/middleware"
+)
+
+// RequestFunc describes an HTTP request to the policy; an error means
+// the caller could not be identified.
+type RequestFunc func(r *http.Request) (Request, error)
+
+type ctxKey struct{}
+
+// FromContext returns the decision Enforce made for the request, for
+// handlers fulfilling its obligations.
+func FromContext(ctx context.Context) (Decision, bool) {
+	d, ok := ctx.Value(ctxKey{}).(Decision)
+	return d, ok
+}
+
+// Enforce returns middleware evaluating p for every request. It answers
+// 401 if describe fails and 403 if p denies, without saying which rule
+// denied, which would describe the policy to whoever probes it; otherwise
+// the handler runs with the decision in its context. observe, if not nil,
+// sees every decision, for audit logs and traces.
+func Enforce(p *Policy, describe RequestFunc, observe func(*http.Request, Decision)) middleware.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			req, err := describe(r)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+			d := p.Evaluate(req)
+			if observe != nil {
+				observe(r, d)
+			}
+			if !d.Allowed() {
+				http.Error(w, "forbidden", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), ctxKey{}, d)))
+		})
+	}
+}
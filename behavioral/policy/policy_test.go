@@ -0,0 +1,255 @@
+package policy_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"patterns/behavioral/policy"
+)
+
+// documents is the package doc's policy.
+func documents(t *testing.T) *policy.Policy {
+	t.Helper()
+	p, err := policy.New(
+		policy.Rule{Name: "suspended", When: policy.AttrIs("suspended", "true"), Effect: policy.EffectDeny},
+		policy.Rule{Name: "admins", When: policy.HasRole("admin"), Effect: policy.EffectAllow,
+			Obligations: []policy.Obligation{{Name: "audit", Params: map[string]string{"level": "high"}}}},
+		policy.Rule{Name: "owners", When: policy.All(policy.ActionIs("read", "update"), policy.IsOwner()), Effect: policy.EffectAllow},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func request(id string, roles []string, action, owner string, attrs map[string]string) policy.Request {
+	return policy.Request{
+		Subject:  policy.Subject{ID: id, Roles: roles},
+		Action:   action,
+		Resource: policy.Resource{Type: "doc", ID: "d1", Owner: owner},
+		Attrs:    attrs,
+	}
+}
+
+// TestPrecedence checks that the first matching rule decides, and that
+// the trace stops at it.
+func TestPrecedence(t *testing.T) {
+	p := documents(t)
+	suspended := map[string]string{"suspended": "true"}
+	for _, c := range []struct {
+		name  string
+		req   policy.Request
+		allow bool
+		rule  string
+		trace []bool
+	}{
+		{"admin", request("a", []string{"admin"}, "delete", "o", nil), true, "admins", []bool{false, true}},
+		// the exception above wins over the rule it excepts from
+		{"suspended admin", request("a", []string{"admin"}, "read", "o", suspended), false, "suspended", []bool{true}},
+		{"owner reads", request("o", nil, "read", "o", nil), true, "owners", []bool{false, false, true}},
+		{"suspended owner", request("o", nil, "read", "o", suspended), false, "suspended", []bool{true}},
+		{"owner deletes", request("o", nil, "delete", "o", nil), false, "", []bool{false, false, false}},
+		{"stranger reads", request("s", []string{"viewer"}, "read", "o", nil), false, "", []bool{false, false, false}},
+		// an empty owner is nobody's
+		{"unowned", request("", nil, "read", "", nil), false, "", []bool{false, false, false}},
+		{"not suspended", request("o", nil, "update", "o", map[string]string{"suspended": "false"}), true, "owners", []bool{false, false, true}},
+	} {
+		d := p.Evaluate(c.req)
+		var trace []bool
+		for _, s := range d.Trace {
+			trace = append(trace, s.Matched)
+		}
+		if d.Allowed() != c.allow || d.Rule != c.rule || !slices.Equal(trace, c.trace) {
+			t.Errorf("%s: %v, trace %v; want allowed %v by %q, trace %v", c.name, d, trace, c.allow, c.rule, c.trace)
+		}
+	}
+}
+
+// TestOrder evaluates the same rules in both orders: the outcome is the
+// first match's, whichever effect that has.
+func TestOrder(t *testing.T) {
+	deny := policy.Rule{Name: "no deletes", When: policy.ActionIs("delete"), Effect: policy.EffectDeny}
+	allow := policy.Rule{Name: "editors", When: policy.HasRole("editor"), Effect: policy.EffectAllow}
+	req := request("e", []string{"editor"}, "delete", "", nil)
+	denyFirst, _ := policy.New(deny, allow)
+	allowFirst, _ := policy.New(allow, deny)
+	if d := denyFirst.Evaluate(req); d.Allowed() || d.Rule != "no deletes" {
+		t.Errorf("deny first: %v", d)
+	}
+	if d := allowFirst.Evaluate(req); !d.Allowed() || d.Rule != "editors" {
+		t.Errorf("allow first: %v", d)
+	}
+}
+
+func TestDefaultDeny(t *testing.T) {
+	empty, err := policy.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []*policy.Policy{empty, documents(t)} {
+		d := p.Evaluate(policy.Request{})
+		if d.Allowed() || d.Effect != policy.EffectDeny || d.Rule != "" || d.Obligations != nil || d.String() != "deny (no rule matched)" {
+			t.Errorf("no match: %v, obligations %v", d, d.Obligations)
+		}
+	}
+	if got, want := policy.Explain(documents(t).Evaluate(policy.Request{})), "   suspended\n   admins\n   owners\ndeny (no rule matched)\n"; got != want {
+		t.Errorf("Explain:\n%s\nwant:\n%s", got, want)
+	}
+	// a catch-all allow at the end turns the default around, explicitly
+	open, _ := policy.New(policy.Rule{Name: "everyone", When: policy.Always(), Effect: policy.EffectAllow})
+	if d := open.Evaluate(policy.Request{}); !d.Allowed() {
+		t.Errorf("catch-all: %v", d)
+	}
+}
+
+func TestObligations(t *testing.T) {
+	p := documents(t)
+	d := p.Evaluate(request("a", []string{"admin"}, "read", "", nil))
+	o, ok := d.Obligation("audit")
+	if !ok || o.Params["level"] != "high" {
+		t.Fatalf("Obligation(audit) = %v, %v", o, ok)
+	}
+	if _, ok := d.Obligation("redact"); ok {
+		t.Error("found an obligation the rule does not have")
+	}
+	if got, want := policy.Explain(d), "   suspended\n=> admins\nallow (admins)\n"; got != want {
+		t.Errorf("Explain:\n%s\nwant:\n%s", got, want)
+	}
+	// a decision's obligations are its own
+	d.Obligations[0].Name = "changed"
+	if again := p.Evaluate(request("a", []string{"admin"}, "read", "", nil)); again.Obligations[0].Name != "audit" {
+		t.Errorf("policy changed through a decision: %v", again.Obligations)
+	}
+	if d := p.Evaluate(request("o", nil, "read", "o", nil)); len(d.Obligations) != 0 {
+		t.Errorf("owners rule carries %v", d.Obligations)
+	}
+}
+
+func TestConditions(t *testing.T) {
+	req := policy.Request{
+		Subject:  policy.Subject{ID: "u", Roles: []string{"viewer", "billing"}},
+		Action:   "read",
+		Resource: policy.Resource{Type: "invoice", Owner: "u"},
+		Attrs:    map[string]string{"plan": "pro", "empty": ""},
+	}
+	yes, no := policy.Always(), policy.Not(policy.Always())
+	for _, c := range []struct {
+		name string
+		when policy.Condition
+		want bool
+	}{
+		{"always", yes, true},
+		{"not", no, false},
+		{"all", policy.All(yes, yes), true},
+		{"all with one false", policy.All(yes, no), false},
+		{"all of none", policy.All(), true},
+		{"any", policy.Any(no, yes), true},
+		{"any of none", policy.Any(), false},
+		{"role", policy.HasRole("admin", "billing"), true},
+		{"no role", policy.HasRole("admin"), false},
+		{"action", policy.ActionIs("list", "read"), true},
+		{"other action", policy.ActionIs("write"), false},
+		{"resource", policy.ResourceIs("invoice"), true},
+		{"other resource", policy.ResourceIs("doc"), false},
+		{"owner", policy.IsOwner(), true},
+		{"attr", policy.AttrIs("plan", "pro"), true},
+		{"other attr value", policy.AttrIs("plan", "free"), false},
+		{"empty attr", policy.AttrIs("empty", ""), true},
+		{"missing attr", policy.AttrIs("tenant", ""), false},
+	} {
+		if got := c.when(req); got != c.want {
+			t.Errorf("%s = %v, want %v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestNew(t *testing.T) {
+	_, err := policy.New(
+		policy.Rule{When: policy.Always()},
+		policy.Rule{Name: "a", When: policy.Always()},
+		policy.Rule{Name: "a", When: policy.Always()},
+		policy.Rule{Name: "b"},
+	)
+	want := "policy: invalid rule: rule 0 has no name\npolicy: invalid rule: duplicate rule \"a\"\npolicy: invalid rule: rule \"b\" has no condition"
+	if !errors.Is(err, policy.ErrInvalidRule) || err.Error() != want {
+		t.Errorf("New = %v, want:\n%s", err, want)
+	}
+
+	// the policy keeps its own copy of the rules
+	rules := []policy.Rule{{Name: "all", When: policy.Always(), Effect: policy.EffectAllow}}
+	p, _ := policy.New(rules...)
+	rules[0].Effect = policy.EffectDeny
+	if !p.Evaluate(policy.Request{}).Allowed() {
+		t.Error("policy changed through the slice given to New")
+	}
+}
+
+func TestEnforce(t *testing.T) {
+	p := documents(t)
+	describe := func(r *http.Request) (policy.Request, error) {
+		user := r.Header.Get("X-User")
+		if user == "" {
+			return policy.Request{}, errors.New("who are you?")
+		}
+		return request(user, strings.Split(r.Header.Get("X-Roles"), ","), r.URL.Query().Get("action"), "owner", nil), nil
+	}
+	var observed []string
+	var obligations []string
+	h := policy.Enforce(p, describe, func(_ *http.Request, d policy.Decision) { observed = append(observed, d.String()) })(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			d, ok := policy.FromContext(r.Context())
+			if !ok {
+				t.Error("no decision in the handler's context")
+			}
+			for _, o := range d.Obligations {
+				obligations = append(obligations, o.Name)
+			}
+			w.Write([]byte("ok"))
+		}))
+
+	for _, c := range []struct {
+		user, roles, action string
+		code                int
+		body                string
+	}{
+		{"", "", "read", http.StatusUnauthorized, "who are you?\n"},
+		{"owner", "", "read", http.StatusOK, "ok"},
+		// the refusal does not name the rule
+		{"stranger", "", "read", http.StatusForbidden, "forbidden\n"},
+		{"root", "admin", "delete", http.StatusOK, "ok"},
+	} {
+		req := httptest.NewRequest("GET", "/doc?action="+c.action, nil)
+		if c.user != "" {
+			req.Header.Set("X-User", c.user)
+			req.Header.Set("X-Roles", c.roles)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != c.code || rec.Body.String() != c.body {
+			t.Errorf("%s %s: %d %q, want %d %q", c.user, c.action, rec.Code, rec.Body, c.code, c.body)
+		}
+	}
+	if want := []string{"allow (owners)", "deny (no rule matched)", "allow (admins)"}; !slices.Equal(observed, want) {
+		t.Errorf("observed %q, want %q", observed, want)
+	}
+	if !slices.Equal(obligations, []string{"audit"}) {
+		t.Errorf("handler saw obligations %q", obligations)
+	}
+	if _, ok := policy.FromContext(httptest.NewRequest("GET", "/", nil).Context()); ok {
+		t.Error("FromContext found a decision outside Enforce")
+	}
+
+	// observe is optional
+	h = policy.Enforce(p, describe, nil)(http.NotFoundHandler())
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/?action=read", nil)
+	req.Header.Set("X-User", "owner")
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("without observe: %d", rec.Code)
+	}
+}
@@ -0,0 +1,162 @@
+// Package state models the lifecycle of an order,
+//
+//	created → paid → shipped → delivered, and created or paid → cancelled
+//
+// twice:
+//
+//   - Order delegates every event to a State value, one type per state,
+//     which performs the transitions allowed from it and rejects the
+//     rest at run time; the order swaps its State as it moves along
+//   - the typestate types (typestate.go) make the state part of the
+//     order's type, so an invalid transition, such as shipping an order
+//     that is not paid, is a method that does not exist and does not
+//     compile
+//
+// Both keep the rules next to the state they belong to, rather than in a
+// switch on a status field repeated in every operation.
+package state
+
+import (
+	"errors"
+	"fmt"
+)
+
+//go:generate go run patterns/cmd/enumgen -type=Status -trimprefix=Status
+
+// Status names a state of the order lifecycle, for storage and display.
+type Status int
+
+const (
+	StatusCreated Status = iota
+	StatusPaid
+	StatusShipped
+	StatusDelivered
+	StatusCancelled
+)
+
+var ErrInvalidTransition = errors.New("state: invalid transition")
+
+// State is the behaviour of an order in one state: each event either
+// moves the order on, by setting its state, or fails.
+type State interface {
+	Status() Status
+	Pay(o *Order, amountCents int) error
+	Ship(o *Order, tracking string) error
+	Deliver(o *Order) error
+	Cancel(o *Order) error
+}
+
+// state interface
+// Level: Good
+// pros: each state's rules are in its own type, and adding a state is
+// one new type; the current state is a value, so it can be loaded from
+// storage and chosen at run time.
+// cons: an invalid transition is found only when it is attempted, and
+// every state must answer every event, even those it only rejects.
+type Order struct {
+	ID          string
+	AmountCents int
+	Tracking    string
+	// Refunded is set when a paid order is cancelled.
+	Refunded bool
+
+	state State
+}
+
+func NewOrder(id string) *Order { return &Order{ID: id, state: created{}} }
+
+func (o *Order) Status() Status { return o.state.Status() }
+
+func (o *Order) Pay(amountCents int) error  { return o.state.Pay(o, amountCents) }
+func (o *Order) Ship(tracking string) error { return o.state.Ship(o, tracking) }
+func (o *Order) Deliver() error             { return o.state.Deliver(o) }
+func (o *Order) Cancel() error              { return o.state.Cancel(o) }
+
+func (o *Order) set(s State) { o.state = s }
+
+func (o *Order) invalid(event string) error {
+	return fmt.Errorf("%w: cannot %s a %s order", ErrInvalidTransition, event, o.Status())
+}
+
+// rejectAll answers every event with ErrInvalidTransition; each state
+// embeds it and overrides the events it accepts.
+type rejectAll struct{}
+
+func (rejectAll) Pay(o *Order, _ int) error     { return o.invalid("pay") }
+func (rejectAll) Ship(o *Order, _ string) error { return o.invalid("ship") }
+func (rejectAll) Deliver(o *Order) error        { return o.invalid("deliver") }
+func (rejectAll) Cancel(o *Order) error         { return o.invalid("cancel") }
+
+type created struct{ rejectAll }
+
+func (created) Status() Status { return StatusCreated }
+
+func (created) Pay(o *Order, amountCents int) error {
+	if amountCents <= 0 {
+		return errors.New("state: amount must be positive")
+	}
+	o.AmountCents = amountCents
+	o.set(paid{})
+	return nil
+}
+
+func (created) Cancel(o *Order) error {
+	o.set(cancelled{})
+	return nil
+}
+
+type paid struct{ rejectAll }
+
+func (paid) Status() Status { return StatusPaid }
+
+func (paid) Ship(o *Order, tracking string) error {
+	if tracking == "" {
+		return errors.New("state: tracking number cannot be empty")
+	}
+	o.Tracking = tracking
+	o.set(shipped{})
+	return nil
+}
+
+func (paid) Cancel(o *Order) error {
+	o.Refunded = true
+	o.set(cancelled{})
+	return nil
+}
+
+type shipped struct{ rejectAll }
+
+func (shipped) Status() Status { return StatusShipped }
+
+func (shipped) Deliver(o *Order) error {
+	o.set(delivered{})
+	return nil
+}
+
+type delivered struct{ rejectAll }
+
+func (delivered) Status() Status { return StatusDelivered }
+
+type cancelled struct{ rejectAll }
+
+func (cancelled) Status() Status { return StatusCancelled }
+
+// Restore returns the order stored with status s.
+func Restore(id string, s Status, amountCents int, tracking string, refunded bool) (*Order, error) {
+	o := &Order{ID: id, AmountCents: amountCents, Tracking: tracking, Refunded: refunded}
+	switch s {
+	case StatusCreated:
+		o.state = created{}
+	case StatusPaid:
+		o.state = paid{}
+	case StatusShipped:
+		o.state = shipped{}
+	case StatusDelivered:
+		o.state = delivered{}
+	case StatusCancelled:
+		o.state = cancelled{}
+	default:
+		return nil, fmt.Errorf("state: unknown status %v", s)
+	}
+	return o, nil
+}
@@ -0,0 +1,171 @@
+package state_test
+
+import (
+	"errors"
+	"maps"
+	"reflect"
+	"slices"
+	"testing"
+
+	"patterns/behavioral/state"
+)
+
+type event struct {
+	name  string
+	apply func(o *state.Order) error
+}
+
+var events = []event{
+	{"pay", func(o *state.Order) error { return o.Pay(100) }},
+	{"ship", func(o *state.Order) error { return o.Ship("TRK") }},
+	{"deliver", (*state.Order).Deliver},
+	{"cancel", (*state.Order).Cancel},
+}
+
+// rules is the lifecycle: the state each event leads to from each state,
+// and nothing else.
+var rules = map[state.Status]map[string]state.Status{
+	state.StatusCreated: {"pay": state.StatusPaid, "cancel": state.StatusCancelled},
+	state.StatusPaid:    {"ship": state.StatusShipped, "cancel": state.StatusCancelled},
+	state.StatusShipped: {"deliver": state.StatusDelivered},
+}
+
+// TestTransitions applies every event in every state: the allowed ones
+// move the order as the rules say, the rest fail and leave it alone.
+func TestTransitions(t *testing.T) {
+	for _, from := range state.StatusValues() {
+		for _, e := range events {
+			o, err := state.Restore("o", from, 0, "", false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = e.apply(o)
+			to, ok := rules[from][e.name]
+			if !ok {
+				want := "state: invalid transition: cannot " + e.name + " a " + from.String() + " order"
+				if !errors.Is(err, state.ErrInvalidTransition) || err.Error() != want || o.Status() != from {
+					t.Errorf("%s from %s: %v, now %s; want %q", e.name, from, err, o.Status(), want)
+				}
+				continue
+			}
+			if err != nil || o.Status() != to {
+				t.Errorf("%s from %s: %v, now %s; want %s", e.name, from, err, o.Status(), to)
+			}
+		}
+	}
+}
+
+func TestLifecycle(t *testing.T) {
+	o := state.NewOrder("o-1")
+	if o.Status() != state.StatusCreated {
+		t.Fatalf("new order is %s", o.Status())
+	}
+	if err := o.Pay(2500); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.Ship("TRK-1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.Deliver(); err != nil {
+		t.Fatal(err)
+	}
+	if o.Status() != state.StatusDelivered || o.AmountCents != 2500 || o.Tracking != "TRK-1" || o.Refunded {
+		t.Errorf("delivered order %+v", o)
+	}
+
+	unpaid, paid := state.NewOrder("o-2"), state.NewOrder("o-3")
+	paid.Pay(100)
+	unpaid.Cancel()
+	paid.Cancel()
+	if unpaid.Refunded || !paid.Refunded {
+		t.Errorf("refunded: unpaid %v, paid %v; want false, true", unpaid.Refunded, paid.Refunded)
+	}
+}
+
+// TestBadData checks that an allowed event with bad data fails without
+// being an invalid transition, and leaves the order as it was.
+func TestBadData(t *testing.T) {
+	o := state.NewOrder("o")
+	if err := o.Pay(0); err == nil || errors.Is(err, state.ErrInvalidTransition) || o.Status() != state.StatusCreated || o.AmountCents != 0 {
+		t.Errorf("Pay(0) = %v, now %s", err, o.Status())
+	}
+	o.Pay(100)
+	if err := o.Ship(""); err == nil || errors.Is(err, state.ErrInvalidTransition) || o.Status() != state.StatusPaid {
+		t.Errorf(`Ship("") = %v, now %s`, err, o.Status())
+	}
+}
+
+func TestRestore(t *testing.T) {
+	o, err := state.Restore("o", state.StatusShipped, 300, "TRK", false)
+	if err != nil || o.Status() != state.StatusShipped || o.AmountCents != 300 || o.Tracking != "TRK" {
+		t.Fatalf("Restore = %+v, %v", o, err)
+	}
+	if o, err := state.Restore("o", state.Status(9), 0, "", false); o != nil || err == nil || err.Error() != "state: unknown status Status(9)" {
+		t.Errorf("Restore of an unknown status = %v, %v", o, err)
+	}
+}
+
+// TestTypestate checks that each typestate type has the methods of the
+// transitions the rules allow from it, and no others: the compiler then
+// rejects the rest.
+func TestTypestate(t *testing.T) {
+	methods := map[string]string{"pay": "Pay", "ship": "Ship", "deliver": "Deliver", "cancel": "Cancel"}
+	for _, c := range []struct {
+		v    state.Lifecycle
+		want state.Status
+	}{
+		{state.Created{}, state.StatusCreated},
+		{state.Paid{}, state.StatusPaid},
+		{state.Shipped{}, state.StatusShipped},
+		{state.Delivered{}, state.StatusDelivered},
+		{state.Cancelled{}, state.StatusCancelled},
+	} {
+		typ := reflect.TypeOf(c.v)
+		if c.v.Status() != c.want {
+			t.Errorf("%s.Status() = %s, want %s", typ.Name(), c.v.Status(), c.want)
+		}
+		var got []string
+		for _, e := range events {
+			if _, ok := typ.MethodByName(methods[e.name]); ok {
+				got = append(got, e.name)
+			}
+		}
+		want := slices.Sorted(maps.Keys(rules[c.want]))
+		slices.Sort(got)
+		if !slices.Equal(got, want) {
+			t.Errorf("%s has %v, want %v", typ.Name(), got, want)
+		}
+		// each transition returns the type of the state it leads to
+		for name, to := range rules[c.want] {
+			m, _ := typ.MethodByName(methods[name])
+			if out := m.Type.Out(0); reflect.Zero(out).Interface().(state.Lifecycle).Status() != to {
+				t.Errorf("%s.%s returns %s, want a %s", typ.Name(), m.Name, out.Name(), to)
+			}
+		}
+	}
+}
+
+func TestTypestateLifecycle(t *testing.T) {
+	c := state.Create("o")
+	if _, err := c.Pay(0); err == nil {
+		t.Error("Pay(0) succeeded")
+	}
+	p, err := c.Pay(2500)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Ship(""); err == nil {
+		t.Error(`Ship("") succeeded`)
+	}
+	s, err := p.Ship("TRK")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := s.Deliver()
+	if d != (state.Delivered{ID: "o", AmountCents: 2500, Tracking: "TRK"}) {
+		t.Errorf("delivered %+v", d)
+	}
+	if c.Cancel().Refunded || !p.Cancel().Refunded {
+		t.Error("a cancelled order is refunded only if it was paid")
+	}
+}
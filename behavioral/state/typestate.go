@@ -0,0 +1,82 @@
+package state
+
+import "errors"
+
+// typestate
+// Level: Average
+// pros: invalid transitions do not compile: a Created has no Ship
+// method, and a function that needs a paid order says so by taking a
+// Paid; no state checks at run time.
+// cons: Go cannot use up a value, so an old state stays usable: a Created
+// can be paid twice. An order whose state is only known at run time, as
+// when loaded, is back to a type switch over Lifecycle.
+type Created struct {
+	ID string
+}
+
+type Paid struct {
+	ID          string
+	AmountCents int
+}
+
+// Shipped and Delivered repeat the fields of Paid rather than embed it:
+// embedding would promote Paid's Ship and Cancel to them.
+type Shipped struct {
+	ID          string
+	AmountCents int
+	Tracking    string
+}
+
+type Delivered struct {
+	ID          string
+	AmountCents int
+	Tracking    string
+}
+
+type Cancelled struct {
+	ID       string
+	Refunded bool
+}
+
+// Lifecycle is any state of a typestate order; it is sealed, so a type
+// switch over the five states is complete.
+type Lifecycle interface {
+	Status() Status
+	lifecycle()
+}
+
+func (Created) Status() Status   { return StatusCreated }
+func (Paid) Status() Status      { return StatusPaid }
+func (Shipped) Status() Status   { return StatusShipped }
+func (Delivered) Status() Status { return StatusDelivered }
+func (Cancelled) Status() Status { return StatusCancelled }
+
+func (Created) lifecycle()   {}
+func (Paid) lifecycle()      {}
+func (Shipped) lifecycle()   {}
+func (Delivered) lifecycle() {}
+func (Cancelled) lifecycle() {}
+
+func Create(id string) Created { return Created{ID: id} }
+
+// Pay can still fail, on the amount: typestate rules out invalid
+// transitions, not invalid data.
+func (c Created) Pay(amountCents int) (Paid, error) {
+	if amountCents <= 0 {
+		return Paid{}, errors.New("state: amount must be positive")
+	}
+	return Paid{ID: c.ID, AmountCents: amountCents}, nil
+}
+
+func (c Created) Cancel() Cancelled { return Cancelled{ID: c.ID} }
+
+func (p Paid) Ship(tracking string) (Shipped, error) {
+	if tracking == "" {
+		return Shipped{}, errors.New("state: tracking number cannot be empty")
+	}
+	return Shipped{ID: p.ID, AmountCents: p.AmountCents, Tracking: tracking}, nil
+}
+
+func (p Paid) Cancel() Cancelled { return Cancelled{ID: p.ID, Refunded: true} }
+
+func (s Shipped) Deliver() Delivered { return Delivered(s) }
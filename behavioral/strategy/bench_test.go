@@ -0,0 +1,95 @@
+package strategy
+
+import (
+	"bytes"
+	"io"
+	"math/rand/v2"
+	"strconv"
+	"testing"
+)
+
+const people = 1000
+
+var benchPeople = func() []Person {
+	r := rand.New(rand.NewPCG(1, 2))
+	ps := make([]Person, people)
+	for i := range ps {
+		ps[i] = Person{Name: "person-" + strconv.Itoa(r.IntN(people)), Age: r.IntN(100)}
+	}
+	return ps
+}()
+
+// sortByAge is sortWith with ByAge written in place: the baseline with no
+// strategy at all.
+func sortByAge(s []Person) {
+	less := func(a, b Person) bool { return a.Age < b.Age || a.Age == b.Age && a.Name < b.Name }
+	for len(s) > 12 {
+		last := len(s) - 1
+		s[len(s)/2], s[last] = s[last], s[len(s)/2]
+		p := 0
+		for j := range last {
+			if less(s[j], s[last]) {
+				s[p], s[j] = s[j], s[p]
+				p++
+			}
+		}
+		s[p], s[last] = s[last], s[p]
+		if p < len(s)-p {
+			sortByAge(s[:p])
+			s = s[p+1:]
+		} else {
+			sortByAge(s[p+1:])
+			s = s[:p]
+		}
+	}
+	for i := 1; i < len(s); i++ {
+		for j := i; j > 0 && less(s[j], s[j-1]); j-- {
+			s[j], s[j-1] = s[j-1], s[j]
+		}
+	}
+}
+
+func benchSort(sort func([]Person)) func(b *testing.B) {
+	return func(b *testing.B) {
+		s := make([]Person, people)
+		for range b.N {
+			copy(s, benchPeople)
+			sort(s)
+		}
+	}
+}
+
+var sinkCompressor Compressor
+
+// BenchmarkSort sorts 1000 people by age with the comparison inline and
+// through each strategy shape; the copy into the slice is in every one.
+func BenchmarkSort(b *testing.B) {
+	b.Run("inline", benchSort(sortByAge))
+	b.Run("interface", benchSort(func(s []Person) { SortInterface[Person](s, ByAge{}) }))
+	b.Run("func", benchSort(func(s []Person) { SortFunc(s, ByAge{}.Compare) }))
+	b.Run("generic", benchSort(func(s []Person) { Sort(s, ByAge{}) }))
+}
+
+// BenchmarkCompress sets the cost of selecting a compressor against
+// compressing 64KiB.
+func BenchmarkCompress(b *testing.B) {
+	b.Run("lookup", func(b *testing.B) {
+		for range b.N {
+			sinkCompressor, _ = Lookup("gzip")
+		}
+	})
+	b.Run("gzip-64k", func(b *testing.B) {
+		src := bytes.Repeat([]byte("the quick brown fox jumps over the lazy dog "), 64<<10/44)
+		c, err := Lookup("gzip")
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.SetBytes(int64(len(src)))
+		b.ResetTimer()
+		for range b.N {
+			if err := c.Compress(io.Discard, src); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
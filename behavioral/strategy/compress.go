@@ -0,0 +1,73 @@
+package strategy
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+)
+
+// Compressor is a compression strategy, selected by name with Lookup.
+type Compressor interface {
+	// Name is the Content-Encoding the strategy produces.
+	Name() string
+	Compress(dst io.Writer, src []byte) error
+}
+
+type Gzip struct{ Level int }
+
+func (Gzip) Name() string { return "gzip" }
+
+func (g Gzip) Compress(dst io.Writer, src []byte) error {
+	w, err := gzip.NewWriterLevel(dst, g.Level)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(src); err != nil {
+		return err
+	}
+	return w.Close()
+}
+
+type Deflate struct{ Level int }
+
+func (Deflate) Name() string { return "deflate" }
+
+func (d Deflate) Compress(dst io.Writer, src []byte) error {
+	w, err := flate.NewWriter(dst, d.Level)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(src); err != nil {
+		return err
+	}
+	return w.Close()
+}
+
+// Identity stores data as it is, for payloads that are already
+// compressed.
+type Identity struct{}
+
+func (Identity) Name() string { return "identity" }
+
+func (Identity) Compress(dst io.Writer, src []byte) error {
+	_, err := dst.Write(src)
+	return err
+}
+
+var compressors = map[string]Compressor{
+	"gzip":     Gzip{Level: gzip.DefaultCompression},
+	"deflate":  Deflate{Level: flate.DefaultCompression},
+	"identity": Identity{},
+}
+
+// Lookup returns the compressor called name, as configured at run time.
+func Lookup(name string) (Compressor, error) {
+	c, ok := compressors[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown compressor %q (want one of %v)", name, slices.Sorted(maps.Keys(compressors)))
+	}
+	return c, nil
+}
@@ -0,0 +1,56 @@
+package template
+
+import "io"
+
+// hook functions
+// Level: Good
+// pros: a format is a literal at the call site, with no type to declare;
+// every step is optional and visibly so.
+// cons: a format carrying state shares it through closures, and hooks are
+// harder to reuse than a named type's methods.
+//
+// Hooks is a Format built from functions; a nil Header or Footer writes
+// nothing and a nil Row writes the row as Lines does.
+type Hooks struct {
+	Header func(w io.Writer, columns []string) error
+	Row    func(w io.Writer, row []string) error
+	Footer func(w io.Writer, rows int) error
+}
+
+// Format returns h as a Format with exactly the optional steps h has, so
+// Export skips the ones it has not.
+func (h Hooks) Format() Format {
+	row := hookRow(h.Row)
+	if row == nil {
+		row = Lines{}.Row
+	}
+	switch {
+	case h.Header != nil && h.Footer != nil:
+		return struct {
+			hookRow
+			hookHeader
+			hookFooter
+		}{row, h.Header, h.Footer}
+	case h.Header != nil:
+		return struct {
+			hookRow
+			hookHeader
+		}{row, h.Header}
+	case h.Footer != nil:
+		return struct {
+			hookRow
+			hookFooter
+		}{row, h.Footer}
+	}
+	return row
+}
+
+type (
+	hookRow    func(w io.Writer, row []string) error
+	hookHeader func(w io.Writer, columns []string) error
+	hookFooter func(w io.Writer, rows int) error
+)
+
+func (f hookRow) Row(w io.Writer, row []string) error           { return f(w, row) }
+func (f hookHeader) Header(w io.Writer, columns []string) error { return f(w, columns) }
+func (f hookFooter) Footer(w io.Writer, rows int) error         { return f(w, rows) }
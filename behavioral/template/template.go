@@ -0,0 +1,124 @@
+// Package template exports a table in several formats with the template
+// method pattern: one algorithm fixing the order of the steps, header,
+// rows, footer, and formats supplying the steps that differ.
+//
+// The classic version puts the algorithm in a base class and the steps in
+// overridable methods. Go has no such thing, and embedding.go shows what
+// happens when it is written as if it had: embedding promotes methods but
+// does not make them virtual, so BaseExporter.Export always calls the
+// base's own steps, and CSVExporter's Row is silently never run.
+//
+// The Go version turns the relationship around: the algorithm is a plain
+// function, Export, and the steps are what it is given:
+//
+//   - a Format, the one step every format has, Row, plus optional small
+//     interfaces, Header and Footer, discovered by type assertion the way
+//     io.Copy discovers io.WriterTo
+//   - Hooks, a struct of functions for one-off formats, any of them nil
+//     for the default
+package template
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Table is what gets exported.
+type Table struct {
+	Columns []string
+	Rows    [][]string
+}
+
+// template method
+// Level: Good
+// pros: the order of the steps is written once, in a function that can be
+// read top to bottom; a format implements only the steps it needs.
+// cons: optional steps are found by type assertion, so a misspelled
+// method is not an error, merely a step that never runs.
+//
+// Format writes the rows of one export format.
+type Format interface {
+	Row(w io.Writer, row []string) error
+}
+
+// Header is implemented by formats writing something before the rows.
+type Header interface {
+	Header(w io.Writer, columns []string) error
+}
+
+// Footer is implemented by formats writing something after the rows.
+type Footer interface {
+	Footer(w io.Writer, rows int) error
+}
+
+// Export writes t in format f: its header if f has one, every row, then
+// its footer if f has one.
+func Export(w io.Writer, t Table, f Format) error {
+	if h, ok := f.(Header); ok {
+		if err := h.Header(w, t.Columns); err != nil {
+			return fmt.Errorf("header: %w", err)
+		}
+	}
+	for i, row := range t.Rows {
+		if err := f.Row(w, row); err != nil {
+			return fmt.Errorf("row %d: %w", i, err)
+		}
+	}
+	if ft, ok := f.(Footer); ok {
+		if err := ft.Footer(w, len(t.Rows)); err != nil {
+			return fmt.Errorf("footer: %w", err)
+		}
+	}
+	return nil
+}
+
+// CSV writes RFC 4180 records, the columns first.
+type CSV struct{}
+
+func (CSV) Header(w io.Writer, columns []string) error { return CSV{}.Row(w, columns) }
+
+func (CSV) Row(w io.Writer, row []string) error {
+	cw := csv.NewWriter(w)
+	cw.Write(row)
+	cw.Flush()
+	return cw.Error()
+}
+
+// Markdown writes a GitHub-flavoured table and a count of its rows.
+type Markdown struct{}
+
+func (Markdown) Header(w io.Writer, columns []string) error {
+	if err := (Markdown{}).Row(w, columns); err != nil {
+		return err
+	}
+	rule := make([]string, len(columns))
+	for i := range rule {
+		rule[i] = "---"
+	}
+	return Markdown{}.Row(w, rule)
+}
+
+func (Markdown) Row(w io.Writer, row []string) error {
+	cells := make([]string, len(row))
+	for i, c := range row {
+		cells[i] = strings.ReplaceAll(c, "|", `\|`)
+	}
+	_, err := fmt.Fprintf(w, "| %s |\n", strings.Join(cells, " | "))
+	return err
+}
+
+func (Markdown) Footer(w io.Writer, rows int) error {
+	_, err := fmt.Fprintf(w, "\n%d rows\n", rows)
+	return err
+}
+
+// Lines writes each row on its own line, tab separated, and nothing else:
+// a Format with only the required step.
+type Lines struct{}
+
+func (Lines) Row(w io.Writer, row []string) error {
+	_, err := fmt.Fprintln(w, strings.Join(row, "\t"))
+	return err
+}
@@ -0,0 +1,149 @@
+package template_test
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"patterns/behavioral/template"
+)
+
+var table = template.Table{
+	Columns: []string{"name", "note"},
+	Rows:    [][]string{{"ann", "a|b"}, {"bob", `say "hi", twice`}},
+}
+
+func export(t *testing.T, f template.Format) string {
+	t.Helper()
+	var b strings.Builder
+	if err := template.Export(&b, table, f); err != nil {
+		t.Fatal(err)
+	}
+	return b.String()
+}
+
+const (
+	csvOut   = "name,note\nann,a|b\nbob,\"say \"\"hi\"\", twice\"\n"
+	linesOut = "ann\ta|b\nbob\tsay \"hi\", twice\n"
+)
+
+func TestFormats(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		f    template.Format
+		want string
+	}{
+		{"csv", template.CSV{}, csvOut},
+		{"markdown", template.Markdown{}, "| name | note |\n| --- | --- |\n| ann | a\\|b |\n| bob | say \"hi\", twice |\n\n2 rows\n"},
+		{"lines", template.Lines{}, linesOut},
+	} {
+		if got := export(t, c.f); got != c.want {
+			t.Errorf("%s:\n%s\nwant:\n%s", c.name, got, c.want)
+		}
+	}
+}
+
+// TestEmbeddingPitfall shows that the embedded base's Export never calls
+// the embedding type's steps, while Export, handed the same value, does.
+func TestEmbeddingPitfall(t *testing.T) {
+	var b strings.Builder
+	c := &template.CSVExporter{}
+	if err := c.Export(&b, table); err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != linesOut {
+		t.Errorf("CSVExporter.Export wrote:\n%s\nwant the base's steps:\n%s", &b, linesOut)
+	}
+	// the overrides are there, called directly
+	b.Reset()
+	c.Row(&b, []string{"a,b"})
+	if b.String() != "\"a,b\"\n" {
+		t.Errorf("CSVExporter.Row wrote %q", &b)
+	}
+	if got := export(t, c); got != csvOut {
+		t.Errorf("Export(CSVExporter) wrote:\n%s\nwant:\n%s", got, csvOut)
+	}
+}
+
+// TestHooks checks each combination of hooks: the steps set run, the rest
+// are skipped or default, and the Format has only the optional steps set.
+func TestHooks(t *testing.T) {
+	header := func(w io.Writer, cols []string) error {
+		_, err := io.WriteString(w, "H:"+strings.Join(cols, ",")+"\n")
+		return err
+	}
+	row := func(w io.Writer, r []string) error {
+		_, err := io.WriteString(w, "R:"+r[0]+"\n")
+		return err
+	}
+	footer := func(w io.Writer, n int) error {
+		_, err := io.WriteString(w, strings.Repeat("F", n)+"\n")
+		return err
+	}
+	for _, c := range []struct {
+		name  string
+		hooks template.Hooks
+		want  string
+	}{
+		{"none", template.Hooks{}, linesOut},
+		{"row", template.Hooks{Row: row}, "R:ann\nR:bob\n"},
+		{"header", template.Hooks{Header: header}, "H:name,note\n" + linesOut},
+		{"footer", template.Hooks{Row: row, Footer: footer}, "R:ann\nR:bob\nFF\n"},
+		{"all", template.Hooks{Header: header, Row: row, Footer: footer}, "H:name,note\nR:ann\nR:bob\nFF\n"},
+	} {
+		f := c.hooks.Format()
+		if got := export(t, f); got != c.want {
+			t.Errorf("%s: wrote:\n%s\nwant:\n%s", c.name, got, c.want)
+		}
+		_, hasHeader := f.(template.Header)
+		_, hasFooter := f.(template.Footer)
+		if hasHeader != (c.hooks.Header != nil) || hasFooter != (c.hooks.Footer != nil) {
+			t.Errorf("%s: Format has header %v, footer %v", c.name, hasHeader, hasFooter)
+		}
+	}
+}
+
+// failAfter fails every write after its first n.
+type failAfter struct{ n int }
+
+var errFull = errors.New("disk full")
+
+func (f *failAfter) Write(p []byte) (int, error) {
+	if f.n == 0 {
+		return 0, errFull
+	}
+	f.n--
+	return len(p), nil
+}
+
+// TestErrors checks that a failing step stops the export and is named in
+// its error.
+func TestErrors(t *testing.T) {
+	for _, c := range []struct {
+		f      template.Format
+		writes int
+		want   string
+	}{
+		{template.Markdown{}, 0, "header: disk full"},
+		{template.Markdown{}, 1, "header: disk full"},
+		{template.Markdown{}, 3, "row 1: disk full"},
+		{template.Markdown{}, 4, "footer: disk full"},
+		{template.Lines{}, 0, "row 0: disk full"},
+		{template.CSV{}, 2, "row 1: disk full"},
+	} {
+		err := template.Export(&failAfter{c.writes}, table, c.f)
+		if !errors.Is(err, errFull) || err.Error() != c.want {
+			t.Errorf("%T after %d writes: %v, want %q", c.f, c.writes, err, c.want)
+		}
+	}
+
+	calls := 0
+	f := template.Hooks{Row: func(io.Writer, []string) error {
+		calls++
+		return errFull
+	}}.Format()
+	if err := template.Export(io.Discard, table, f); !errors.Is(err, errFull) || calls != 1 {
+		t.Errorf("failing hook: %v after %d calls", err, calls)
+	}
+}
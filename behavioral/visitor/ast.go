@@ -0,0 +1,76 @@
+// Package visitor runs operations over a small arithmetic AST in two ways,
+// to compare how each takes change:
+//
+//   - a Visitor, with one method per node type, which each node's Accept
+//     calls back (double dispatch): Evaluator and Printer
+//   - type switches over the sealed Expr: Eval and Format
+//
+// A new operation is a new Visitor type, or one new function with a
+// switch: neither touches the nodes. A new node is where they differ: the
+// Visitor interface gains a method and every visitor stops compiling until
+// it handles the node, while a switch compiles on and misses the node at
+// run time, unless an analyzer such as analyzers/exhaustive, which knows
+// Expr is sealed, reports it. In Go the switch is the lighter of the two,
+// and the usual choice for ASTs, go/ast included; the visitor pays off
+// when the operations are many, written outside the package, or must be
+// complete by construction.
+package visitor
+
+// Expr is a node of the AST. It is sealed: the node types below are all
+// there are.
+type Expr interface {
+	// Accept calls the method of v for this node's type.
+	Accept(v Visitor)
+	expr()
+}
+
+type (
+	Num struct{ Value float64 }
+	Var struct{ Name string }
+	// Neg is unary minus.
+	Neg struct{ X Expr }
+	// Binary is L Op R, with Op one of + - * / ^.
+	Binary struct {
+		Op   byte
+		L, R Expr
+	}
+)
+
+func (n *Num) Accept(v Visitor)    { v.VisitNum(n) }
+func (n *Var) Accept(v Visitor)    { v.VisitVar(n) }
+func (n *Neg) Accept(v Visitor)    { v.VisitNeg(n) }
+func (n *Binary) Accept(v Visitor) { v.VisitBinary(n) }
+
+func (*Num) expr()    {}
+func (*Var) expr()    {}
+func (*Neg) expr()    {}
+func (*Binary) expr() {}
+
+// Constructors, so an expression reads close to its formula:
+// Add(N(1), Mul(V("x"), N(2))) is 1 + x * 2.
+func N(v float64) Expr   { return &Num{v} }
+func V(name string) Expr { return &Var{name} }
+func Minus(x Expr) Expr  { return &Neg{x} }
+func Add(l, r Expr) Expr { return &Binary{'+', l, r} }
+func Sub(l, r Expr) Expr { return &Binary{'-', l, r} }
+func Mul(l, r Expr) Expr { return &Binary{'*', l, r} }
+func Div(l, r Expr) Expr { return &Binary{'/', l, r} }
+func Pow(l, r Expr) Expr { return &Binary{'^', l, r} }
+
+// precedence and right-associativity decide where Printer and Format
+// need parentheses.
+func precedence(op byte) int {
+	switch op {
+	case '+', '-':
+		return 1
+	case '*', '/':
+		return 2
+	case '^':
+		return 4
+	}
+	return 0
+}
+
+const negPrecedence = 3
+
+func rightAssoc(op byte) bool { return op == '^' }
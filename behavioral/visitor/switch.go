@@ -0,0 +1,63 @@
+package visitor
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// type switch
+// Level: Good
+// pros: an operation is one plain recursive function with real return
+// values; nodes need no Accept, and new operations need no new types.
+// cons: a new node compiles without its cases and fails at run time;
+// only an analyzer over the sealed Expr, not the compiler, catches it.
+func Eval(e Expr, env map[string]float64) (float64, error) {
+	switch e := e.(type) {
+	case *Num:
+		return e.Value, nil
+	case *Var:
+		v, ok := env[e.Name]
+		if !ok {
+			return 0, fmt.Errorf("visitor: undefined variable %s", e.Name)
+		}
+		return v, nil
+	case *Neg:
+		v, err := Eval(e.X, env)
+		return -v, err
+	case *Binary:
+		l, err := Eval(e.L, env)
+		if err != nil {
+			return 0, err
+		}
+		r, err := Eval(e.R, env)
+		if err != nil {
+			return 0, err
+		}
+		return apply(e.Op, l, r)
+	}
+	panic(fmt.Sprintf("visitor: unknown node %T", e))
+}
+
+// Format is Printer.Print as a type switch.
+func Format(e Expr) string { return format(e, 0, false) }
+
+func format(e Expr, outer int, strict bool) string {
+	paren := func(prec int, s string) string {
+		if prec < outer || (prec == outer && strict) {
+			return "(" + s + ")"
+		}
+		return s
+	}
+	switch e := e.(type) {
+	case *Num:
+		return strconv.FormatFloat(e.Value, 'g', -1, 64)
+	case *Var:
+		return e.Name
+	case *Neg:
+		return paren(negPrecedence, "-"+format(e.X, negPrecedence, true))
+	case *Binary:
+		prec := precedence(e.Op)
+		return paren(prec, format(e.L, prec, rightAssoc(e.Op))+" "+string(e.Op)+" "+format(e.R, prec, !rightAssoc(e.Op)))
+	}
+	panic(fmt.Sprintf("visitor: unknown node %T", e))
+}
@@ -0,0 +1,148 @@
+package visitor
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Visitor has one method per node type. Methods return nothing, since Go
+// methods cannot be generic over a result type: a visitor keeps its
+// result, and whatever it needs to build it, in its own fields.
+type Visitor interface {
+	VisitNum(n *Num)
+	VisitVar(n *Var)
+	VisitNeg(n *Neg)
+	VisitBinary(n *Binary)
+}
+
+var ErrDivideByZero = errors.New("visitor: division by zero")
+
+// visitor
+// Level: Average
+// pros: a new node type cannot be forgotten: every visitor fails to
+// compile until it handles it; operations live in their own types and
+// can carry state through the walk.
+// cons: results pass through fields rather than return values, so each
+// visitor is a small state machine; a new node changes the Visitor
+// interface, and with it every visitor, in every package.
+type Evaluator struct {
+	Env map[string]float64
+
+	result float64
+	err    error
+}
+
+// Eval evaluates e with the variables in Env.
+func (ev *Evaluator) Eval(e Expr) (float64, error) {
+	ev.err = nil
+	e.Accept(ev)
+	if ev.err != nil {
+		return 0, ev.err
+	}
+	return ev.result, nil
+}
+
+func (ev *Evaluator) VisitNum(n *Num) { ev.result = n.Value }
+
+func (ev *Evaluator) VisitVar(n *Var) {
+	v, ok := ev.Env[n.Name]
+	if !ok && ev.err == nil {
+		ev.err = fmt.Errorf("visitor: undefined variable %s", n.Name)
+	}
+	ev.result = v
+}
+
+func (ev *Evaluator) VisitNeg(n *Neg) {
+	n.X.Accept(ev)
+	ev.result = -ev.result
+}
+
+func (ev *Evaluator) VisitBinary(n *Binary) {
+	n.L.Accept(ev)
+	l := ev.result
+	n.R.Accept(ev)
+	r := ev.result
+	v, err := apply(n.Op, l, r)
+	if err != nil && ev.err == nil {
+		ev.err = err
+	}
+	ev.result = v
+}
+
+func apply(op byte, l, r float64) (float64, error) {
+	switch op {
+	case '+':
+		return l + r, nil
+	case '-':
+		return l - r, nil
+	case '*':
+		return l * r, nil
+	case '/':
+		if r == 0 {
+			return 0, ErrDivideByZero
+		}
+		return l / r, nil
+	case '^':
+		return math.Pow(l, r), nil
+	}
+	return 0, fmt.Errorf("visitor: unknown operator %q", op)
+}
+
+// Printer formats an expression with as few parentheses as precedence
+// allows.
+type Printer struct {
+	b strings.Builder
+	// prec is the precedence of the operator around the current node, and
+	// right whether the node is its right operand.
+	prec  int
+	right bool
+}
+
+func (p *Printer) Print(e Expr) string {
+	p.b.Reset()
+	p.prec, p.right = 0, false
+	e.Accept(p)
+	return p.b.String()
+}
+
+func (p *Printer) VisitNum(n *Num) { p.b.WriteString(strconv.FormatFloat(n.Value, 'g', -1, 64)) }
+func (p *Printer) VisitVar(n *Var) { p.b.WriteString(n.Name) }
+
+func (p *Printer) VisitNeg(n *Neg) {
+	p.wrap(negPrecedence, func() {
+		p.b.WriteByte('-')
+		p.operand(n.X, negPrecedence, true)
+	})
+}
+
+func (p *Printer) VisitBinary(n *Binary) {
+	prec := precedence(n.Op)
+	p.wrap(prec, func() {
+		p.operand(n.L, prec, rightAssoc(n.Op))
+		p.b.WriteString(" " + string(n.Op) + " ")
+		p.operand(n.R, prec, !rightAssoc(n.Op))
+	})
+}
+
+// operand prints e as an operand of an operator of precedence prec;
+// strict means an operand of equal precedence needs parentheses too.
+func (p *Printer) operand(e Expr, prec int, strict bool) {
+	outer, right := p.prec, p.right
+	p.prec, p.right = prec, strict
+	e.Accept(p)
+	p.prec, p.right = outer, right
+}
+
+func (p *Printer) wrap(prec int, body func()) {
+	paren := prec < p.prec || (prec == p.prec && p.right)
+	if paren {
+		p.b.WriteByte('(')
+	}
+	body()
+	if paren {
+		p.b.WriteByte(')')
+	}
+}
@@ -0,0 +1,32 @@
+package dispatch
+
+import (
+	"strconv"
+	"testing"
+)
+
+var sizes = []int{10, 1_000, 100_000}
+
+var sink float64
+
+// benchEval evaluates a tree of each size with eval.
+func benchEval(b *testing.B, eval func(Node) float64) {
+	for _, size := range sizes {
+		b.Run("size="+strconv.Itoa(size), func(b *testing.B) {
+			tree := Build(size)
+			b.ResetTimer()
+			for range b.N {
+				sink = eval(tree)
+			}
+		})
+	}
+}
+
+// BenchmarkVisitor evaluates the tree with the double-dispatch visitor.
+func BenchmarkVisitor(b *testing.B) { benchEval(b, EvalVisitor) }
+
+// BenchmarkSwitch evaluates the tree with the type switch.
+func BenchmarkSwitch(b *testing.B) { benchEval(b, EvalSwitch) }
+
+// BenchmarkRegistry evaluates the tree with the registry of handlers.
+func BenchmarkRegistry(b *testing.B) { benchEval(b, NewRegistry().Eval) }
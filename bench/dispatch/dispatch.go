@@ -0,0 +1,65 @@
+package dispatch
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// visitor dispatch
+
+type evalVisitor struct{}
+
+func (e evalVisitor) VisitNum(n Num) float64 { return n.V }
+func (e evalVisitor) VisitAdd(n Add) float64 { return n.L.Accept(e) + n.R.Accept(e) }
+func (e evalVisitor) VisitMul(n Mul) float64 { return n.L.Accept(e) * n.R.Accept(e) }
+func (e evalVisitor) VisitNeg(n Neg) float64 { return -n.X.Accept(e) }
+
+func EvalVisitor(n Node) float64 { return n.Accept(evalVisitor{}) }
+
+// type switch dispatch
+
+func EvalSwitch(n Node) float64 {
+	switch n := n.(type) {
+	case Num:
+		return n.V
+	case Add:
+		return EvalSwitch(n.L) + EvalSwitch(n.R)
+	case Mul:
+		return EvalSwitch(n.L) * EvalSwitch(n.R)
+	case Neg:
+		return -EvalSwitch(n.X)
+	default:
+		panic(fmt.Sprintf("dispatch: unknown node %T", n))
+	}
+}
+
+// handler map dispatch
+
+type handler func(r *Registry, n Node) float64
+
+// Registry maps node types to handlers; new node types can be registered
+// without touching existing code.
+type Registry struct {
+	handlers map[reflect.Type]handler
+}
+
+func NewRegistry() *Registry {
+	r := &Registry{handlers: map[reflect.Type]handler{}}
+	r.Register(Num{}, func(_ *Registry, n Node) float64 { return n.(Num).V })
+	r.Register(Add{}, func(r *Registry, n Node) float64 { a := n.(Add); return r.Eval(a.L) + r.Eval(a.R) })
+	r.Register(Mul{}, func(r *Registry, n Node) float64 { m := n.(Mul); return r.Eval(m.L) * r.Eval(m.R) })
+	r.Register(Neg{}, func(r *Registry, n Node) float64 { return -r.Eval(n.(Neg).X) })
+	return r
+}
+
+func (r *Registry) Register(sample Node, h handler) {
+	r.handlers[reflect.TypeOf(sample)] = h
+}
+
+func (r *Registry) Eval(n Node) float64 {
+	h, ok := r.handlers[reflect.TypeOf(n)]
+	if !ok {
+		panic(fmt.Sprintf("dispatch: no handler for %T", n))
+	}
+	return h(r, n)
+}
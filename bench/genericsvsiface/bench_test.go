@@ -0,0 +1,71 @@
+package genericsvsiface
+
+import "testing"
+
+var sink int
+
+// ints is the input of every benchmark: 0..999.
+var ints = func() []int {
+	xs := make([]int, 1000)
+	for i := range xs {
+		xs[i] = i
+	}
+	return xs
+}()
+
+// combiner keeps the compiler from seeing which Combiner FoldIface gets,
+// as it cannot when the strategy is chosen at run time.
+var combiner Combiner = Adder{}
+
+var add = func(a, b int) int { return a + b }
+
+func fold(f func([]int) int) func(b *testing.B) {
+	return func(b *testing.B) {
+		for range b.N {
+			sink = f(ints)
+		}
+	}
+}
+
+// BenchmarkFold folds 1,000 ints with each kind of strategy.
+func BenchmarkFold(b *testing.B) {
+	b.Run("loop", fold(Loop))
+	b.Run("generic-sum", fold(Sum[int]))
+	b.Run("iface", fold(func(xs []int) int { return FoldIface(xs, combiner) }))
+	b.Run("generic-method", fold(func(xs []int) int { return FoldGeneric(xs, Adder{}) }))
+	b.Run("func", fold(func(xs []int) int { return FoldFunc(xs, add) }))
+}
+
+// BenchmarkStack pushes 1,000 ints through each stack and pops them.
+func BenchmarkStack(b *testing.B) {
+	b.Run("any", func(b *testing.B) {
+		var s AnyStack
+		for range b.N {
+			for _, x := range ints {
+				s.Push(x)
+			}
+			for {
+				v, ok := s.Pop()
+				if !ok {
+					break
+				}
+				sink = v.(int)
+			}
+		}
+	})
+	b.Run("generic", func(b *testing.B) {
+		var s Stack[int]
+		for range b.N {
+			for _, x := range ints {
+				s.Push(x)
+			}
+			for {
+				v, ok := s.Pop()
+				if !ok {
+					break
+				}
+				sink = v
+			}
+		}
+	})
+}
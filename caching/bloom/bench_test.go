@@ -0,0 +1,120 @@
+package bloom
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+// benchKeys is how many keys the benchmark filters hold.
+const benchKeys = 100_000
+
+func benchFilter(b *testing.B) *Filter[string] {
+	f, err := Strings[string](benchKeys, 0.01)
+	if err != nil {
+		b.Fatal(err)
+	}
+	for i := range benchKeys {
+		f.Add("key-" + strconv.Itoa(i))
+	}
+	return f
+}
+
+// statLoader stands in for a store lookup: one stat(2) in a directory
+// holding the keys that exist.
+func statLoader(dir string) func(ctx context.Context, key string) (string, error) {
+	return func(ctx context.Context, key string) (string, error) {
+		if _, err := os.Stat(filepath.Join(dir, key)); err != nil {
+			return "", err
+		}
+		return key, nil
+	}
+}
+
+func benchMisses(b *testing.B, guarded bool) {
+	f := benchFilter(b)
+	load := statLoader(b.TempDir())
+	if guarded {
+		load = Guard(f, load)
+	}
+	keys := make([]string, 4096)
+	for i := range keys {
+		keys[i] = "absent-" + strconv.Itoa(i)
+	}
+	ctx := context.Background()
+	falsePositives := 0
+	b.ResetTimer()
+	for i := range b.N {
+		key := keys[i%len(keys)]
+		if _, err := load(ctx, key); err == nil {
+			b.Fatal("absent key found")
+		}
+		if guarded && f.MayContain(key) {
+			falsePositives++
+		}
+	}
+	if guarded {
+		b.ReportMetric(float64(falsePositives)/float64(b.N), "fp/op")
+	}
+}
+
+// BenchmarkAdd measures adding a key to the filter.
+func BenchmarkAdd(b *testing.B) {
+	f, _ := Strings[string](b.N+1, 0.01)
+	key := "key-0"
+	b.ResetTimer()
+	for range b.N {
+		f.Add(key)
+	}
+}
+
+// BenchmarkMayContain measures a lookup of a key the filter holds.
+func BenchmarkMayContain(b *testing.B) {
+	f := benchFilter(b)
+	keys := make([]string, 4096)
+	for i := range keys {
+		keys[i] = "key-" + strconv.Itoa(i*(benchKeys/len(keys)))
+	}
+	b.ResetTimer()
+	for i := range b.N {
+		sinkBool = f.MayContain(keys[i%len(keys)])
+	}
+}
+
+// BenchmarkMiss measures misses through a loader with and without the
+// guard.
+func BenchmarkMiss(b *testing.B) {
+	b.Run("unguarded", func(b *testing.B) { benchMisses(b, false) })
+	b.Run("guarded", func(b *testing.B) { benchMisses(b, true) })
+}
+
+// BenchmarkHit measures hits through a loader with and without the
+// guard.
+func BenchmarkHit(b *testing.B) {
+	b.Run("unguarded", func(b *testing.B) { benchHits(b, false) })
+	b.Run("guarded", func(b *testing.B) { benchHits(b, true) })
+}
+
+var sinkBool bool
+
+func benchHits(b *testing.B, guarded bool) {
+	dir := b.TempDir()
+	f, _ := Strings[string](benchKeys, 0.01)
+	if err := os.WriteFile(filepath.Join(dir, "present"), nil, 0o644); err != nil {
+		b.Fatal(err)
+	}
+	f.Add("present")
+	load := statLoader(dir)
+	if guarded {
+		load = Guard(f, load)
+	}
+	ctx := context.Background()
+	b.ResetTimer()
+	for range b.N {
+		if _, err := load(ctx, "present"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
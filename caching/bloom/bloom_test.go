@@ -0,0 +1,152 @@
+package bloom
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"strconv"
+	"sync"
+	"testing"
+	"testing/quick"
+
+	"patterns/persistence/repository"
+)
+
+// TestNoFalseNegatives checks the one promise a filter makes, over random
+// key sets, sizes and rates, including filters filled far past their
+// expected count.
+func TestNoFalseNegatives(t *testing.T) {
+	prop := func(keys []string, expected uint8, rate uint8) bool {
+		fpRate := (float64(rate) + 1) / 257 // in (0, 1)
+		f, err := Strings[string](int(expected)+1, fpRate)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, k := range keys {
+			f.Add(k)
+		}
+		for _, k := range keys {
+			if !f.MayContain(k) {
+				t.Logf("added %q, MayContain = false (m=%d, k=%d)", k, f.Bits(), f.Probes())
+				return false
+			}
+		}
+		return true
+	}
+	if err := quick.Check(prop, &quick.Config{MaxCount: 500}); err != nil {
+		t.Error(err)
+	}
+}
+
+// TestNoFalseNegativesWeakHash uses the identity as the hash, so the
+// probes of nearby keys overlap: overlap must only cost false positives.
+func TestNoFalseNegativesWeakHash(t *testing.T) {
+	f, err := New(100, 0.01, func(k uint64) uint64 { return k })
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k := range uint64(5000) {
+		f.Add(k * 3)
+	}
+	for k := range uint64(5000) {
+		if !f.MayContain(k * 3) {
+			t.Fatalf("added %d, MayContain = false", k*3)
+		}
+	}
+}
+
+func TestConcurrentAdd(t *testing.T) {
+	f, err := Strings[string](10_000, 0.01)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	for g := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 1000 {
+				key := strconv.Itoa(g) + "-" + strconv.Itoa(i)
+				f.Add(key)
+				if !f.MayContain(key) {
+					t.Errorf("added %q, MayContain = false", key)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	for g := range 8 {
+		for i := range 1000 {
+			if key := strconv.Itoa(g) + "-" + strconv.Itoa(i); !f.MayContain(key) {
+				t.Fatalf("added %q, MayContain = false", key)
+			}
+		}
+	}
+}
+
+func TestFalsePositiveRate(t *testing.T) {
+	const n = 20_000
+	for _, fpRate := range []float64{0.1, 0.01, 0.001} {
+		f, err := Strings[string](n, fpRate)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := range n {
+			f.Add("in-" + strconv.Itoa(i))
+		}
+		r := rand.New(rand.NewPCG(1, 2))
+		const trials = 200_000
+		hits := 0
+		for range trials {
+			if f.MayContain("out-" + strconv.FormatUint(r.Uint64(), 36)) {
+				hits++
+			}
+		}
+		// generous bounds: the estimate, not the filter, is what is tested
+		if got := float64(hits) / trials; got > 2*fpRate || got < fpRate/2 {
+			t.Errorf("rate %g: measured %g", fpRate, got)
+		}
+		if est := f.FalsePositiveRate(); est > 1.5*fpRate || est < fpRate/1.5 {
+			t.Errorf("rate %g: FalsePositiveRate() = %g", fpRate, est)
+		}
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	hash := func(k int) uint64 { return uint64(k) }
+	for _, c := range []struct {
+		expected int
+		fpRate   float64
+		hash     func(int) uint64
+	}{
+		{0, 0.01, hash},
+		{10, 0, hash},
+		{10, 1, hash},
+		{10, 0.01, nil},
+	} {
+		if _, err := New(c.expected, c.fpRate, c.hash); err == nil {
+			t.Errorf("New(%d, %g, hash nil %v) succeeded", c.expected, c.fpRate, c.hash == nil)
+		}
+	}
+}
+
+func TestGuard(t *testing.T) {
+	f, err := Strings[string](10, 0.01)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Add("present")
+	var calls int
+	load := Guard(f, func(ctx context.Context, key string) (string, error) {
+		calls++
+		return "value of " + key, nil
+	})
+
+	if v, err := load(context.Background(), "present"); err != nil || v != "value of present" || calls != 1 {
+		t.Errorf("load(present) = %q, %v after %d calls", v, err, calls)
+	}
+	if _, err := load(context.Background(), "absent"); !errors.Is(err, repository.ErrNotFound) || calls != 1 {
+		t.Errorf("load(absent) = %v after %d calls, want ErrNotFound without a lookup", err, calls)
+	}
+}
@@ -0,0 +1,48 @@
+// Package cacheaside implements the cache-aside (lazy loading) pattern:
+// reads look in the cache first and fall back to the source of truth,
+// storing what they loaded; writers invalidate.
+//
+// pros: the cache can be dropped at any time, only hot keys are cached
+// cons: first read of every key is a miss, writers must remember to
+// invalidate or readers see stale values
+package cacheaside
+
+import "context"
+
+// Cache is the subset of a cache the pattern needs; *lru.Cache satisfies it.
+type Cache[K comparable, V any] interface {
+	Get(key K) (V, bool)
+	Add(key K, value V)
+	Remove(key K)
+}
+
+// Loader reads key from the source of truth.
+type Loader[K comparable, V any] func(ctx context.Context, key K) (V, error)
+
+type Aside[K comparable, V any] struct {
+	cache Cache[K, V]
+	load  Loader[K, V]
+}
+
+func New[K comparable, V any](cache Cache[K, V], load Loader[K, V]) *Aside[K, V] {
+	return &Aside[K, V]{cache: cache, load: load}
+}
+
+// Get returns the cached value or loads and caches it. Errors are not
+// cached.
+func (a *Aside[K, V]) Get(ctx context.Context, key K) (V, error) {
+	if v, ok := a.cache.Get(key); ok {
+		return v, nil
+	}
+	v, err := a.load(ctx, key)
+	if err != nil {
+		return v, err
+	}
+	a.cache.Add(key, v)
+	return v, nil
+}
+
+// Invalidate drops key so the next Get reloads it.
+func (a *Aside[K, V]) Invalidate(key K) {
+	a.cache.Remove(key)
+}
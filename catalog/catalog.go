@@ -0,0 +1,175 @@
+// Package catalog is the machine-readable index of the patterns in this
+// repository: for each, its category, level, trade-offs, relations, and
+// the package and runnable example that implement it. Tools
+// (cmd/patterndoc, cmd/patterns, cmd/patterns-tui) read it instead of
+// guessing from the directory layout.
+//
+// Export is the form for tools outside Go and the website: a versioned
+// Document, described by the JSON Schema in schema.json, that lists the
+// categories, the patterns with their import paths and run commands, and
+// the relations as edges. Import reads one back and rejects anything
+// Validate would, so a hand-edited or generated catalog is checked
+// before it is used. The tests check the two against each other and the
+// schema:
+//
+//	go run patterns/cmd/patterns export > catalog.json
+//	go run patterns/cmd/patterns import catalog.json
+package catalog
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"patterns/idioms/enum"
+)
+
+// Category is the top level of the taxonomy.
+type Category string
+
+const (
+	Creational   Category = "creational"
+	Structural   Category = "structural"
+	Behavioral   Category = "behavioral"
+	Concurrency  Category = "concurrency"
+	Resilience   Category = "resilience"
+	Architecture Category = "architecture"
+)
+
+var categoryOrder = []Category{Creational, Structural, Behavioral, Concurrency, Resilience, Architecture}
+
+// RelationKind labels an edge between two patterns.
+type RelationKind string
+
+const (
+	// AlternativeTo: solves the same problem differently.
+	AlternativeTo RelationKind = "alternative-to"
+	// ComposesWith: commonly used together.
+	ComposesWith RelationKind = "composes-with"
+	// Refines: a more specific or improved form of the target.
+	Refines RelationKind = "refines"
+)
+
+// Relation is an edge from the owning pattern to Target.
+type Relation struct {
+	Kind   RelationKind `json:"kind"`
+	Target string       `json:"target"`
+}
+
+// Pattern describes one pattern implementation.
+type Pattern struct {
+	// Name is a unique kebab-case identifier.
+	Name     string   `json:"name"`
+	Category Category `json:"category"`
+	// Level grades the implementation as the options examples do; zero
+	// means not graded.
+	Level   enum.Level `json:"level,omitempty"`
+	Summary string     `json:"summary"`
+	// Path is the repository-relative package directory.
+	Path string `json:"path"`
+	// Example is the repository-relative directory of a main package that
+	// demonstrates or checks the pattern, empty if it has none.
+	Example   string     `json:"example,omitempty"`
+	Pros      []string   `json:"pros,omitempty"`
+	Cons      []string   `json:"cons,omitempty"`
+	Relations []Relation `json:"relations,omitempty"`
+}
+
+// All returns every pattern, sorted by category then name.
+func All() []Pattern {
+	out := slices.Clone(patterns)
+	slices.SortStableFunc(out, func(a, b Pattern) int {
+		return cmp.Or(
+			cmp.Compare(slices.Index(categoryOrder, a.Category), slices.Index(categoryOrder, b.Category)),
+			strings.Compare(a.Name, b.Name),
+		)
+	})
+	return out
+}
+
+// Lookup finds a pattern by name.
+func Lookup(name string) (Pattern, bool) {
+	for _, p := range patterns {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return Pattern{}, false
+}
+
+// InCategory returns the patterns of c, sorted by name.
+func InCategory(c Category) []Pattern {
+	var out []Pattern
+	for _, p := range All() {
+		if p.Category == c {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// ParseCategory returns the Category named s.
+func ParseCategory(s string) (Category, error) {
+	if c := Category(s); slices.Contains(categoryOrder, c) {
+		return c, nil
+	}
+	return "", fmt.Errorf("unknown category %q", s)
+}
+
+// Categories returns the categories that have at least one pattern, in
+// taxonomy order.
+func Categories() []Category {
+	var out []Category
+	for _, c := range categoryOrder {
+		if slices.ContainsFunc(patterns, func(p Pattern) bool { return p.Category == c }) {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// Validate checks the integrity of the catalog: unique kebab-case names,
+// known categories, examples inside the repository, and relations that point at
+// existing patterns.
+func Validate() error {
+	return validate(patterns)
+}
+
+var namePattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+func validate(ps []Pattern) error {
+	var errs []error
+	names := map[string]bool{}
+	for _, p := range ps {
+		if names[p.Name] {
+			errs = append(errs, fmt.Errorf("duplicate pattern %q", p.Name))
+		}
+		names[p.Name] = true
+		if !namePattern.MatchString(p.Name) {
+			errs = append(errs, fmt.Errorf("name %q is not kebab-case", p.Name))
+		}
+		if !slices.Contains(categoryOrder, p.Category) {
+			errs = append(errs, fmt.Errorf("%s: unknown category %q", p.Name, p.Category))
+		}
+		if p.Example != "" && !filepath.IsLocal(p.Example) {
+			errs = append(errs, fmt.Errorf("%s: example %q is not a relative path inside the repository", p.Name, p.Example))
+		}
+	}
+	for _, p := range ps {
+		for _, r := range p.Relations {
+			switch {
+			case r.Kind != AlternativeTo && r.Kind != ComposesWith && r.Kind != Refines:
+				errs = append(errs, fmt.Errorf("%s: unknown relation kind %q", p.Name, r.Kind))
+			case r.Target == p.Name:
+				errs = append(errs, fmt.Errorf("%s: relation to itself", p.Name))
+			case !names[r.Target]:
+				errs = append(errs, fmt.Errorf("%s: %s dangling reference %q", p.Name, r.Kind, r.Target))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
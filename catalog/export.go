@@ -0,0 +1,198 @@
+package catalog
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+
+	"patterns/idioms/enum"
+)
+
+// SchemaVersion is the version of the export format. Adding an optional
+// field keeps it; renaming, removing or changing the meaning of one
+// bumps it.
+const SchemaVersion = 1
+
+// Module is the module path that package paths are relative to.
+const Module = "patterns"
+
+// Schema is the JSON Schema of a Document, for consumers outside Go.
+//
+//go:embed schema.json
+var Schema []byte
+
+// Document is the catalog as exported: the stable form external tools and
+// the website read. It is kept apart from Pattern so the Go type can
+// change without breaking them.
+type Document struct {
+	Version    int        `json:"version"`
+	Categories []Category `json:"categories"`
+	Patterns   []Entry    `json:"patterns"`
+	Relations  []Edge     `json:"relations"`
+}
+
+// Entry is one pattern with its code entry points.
+type Entry struct {
+	Name     string   `json:"name"`
+	Category Category `json:"category"`
+	// Level is good, average or poor; empty if not graded.
+	Level   string `json:"level,omitempty"`
+	Summary string `json:"summary"`
+	// Path is the package directory, and Package its import path.
+	Path    string `json:"path"`
+	Package string `json:"package"`
+	// Example is the directory of the runnable example, and Run the
+	// command that runs it; both empty if there is none.
+	Example string   `json:"example,omitempty"`
+	Run     string   `json:"run,omitempty"`
+	Pros    []string `json:"pros"`
+	Cons    []string `json:"cons"`
+}
+
+// Edge is one relation, From the pattern that declares it.
+type Edge struct {
+	From string       `json:"from"`
+	Kind RelationKind `json:"kind"`
+	To   string       `json:"to"`
+}
+
+// Export returns the catalog as a Document: categories in taxonomy order,
+// patterns as All sorts them, and relations in the order of their
+// patterns, so the same catalog always exports to the same bytes.
+func Export() Document {
+	d := Document{Version: SchemaVersion, Categories: Categories(), Patterns: []Entry{}, Relations: []Edge{}}
+	for _, p := range All() {
+		e := Entry{
+			Name: p.Name, Category: p.Category, Summary: p.Summary,
+			Path: p.Path, Package: Module + "/" + p.Path, Example: p.Example,
+			Pros: orEmpty(p.Pros), Cons: orEmpty(p.Cons),
+		}
+		if p.Level != 0 {
+			e.Level = p.Level.String()
+		}
+		if p.Example != "" {
+			e.Run = "go run " + Module + "/" + p.Example
+		}
+		d.Patterns = append(d.Patterns, e)
+		for _, r := range p.Relations {
+			d.Relations = append(d.Relations, Edge{From: p.Name, Kind: r.Kind, To: r.Target})
+		}
+	}
+	return d
+}
+
+// orEmpty returns s, or an empty slice for nil, so consumers see [] and
+// not null.
+func orEmpty(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
+
+// Encode writes d as indented JSON.
+func (d Document) Encode(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(d)
+}
+
+// Import reads a Document and validates it: malformed JSON, an unknown
+// field or another version is an error, and so is anything Validate
+// finds.
+func Import(r io.Reader) (Document, error) {
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	var d Document
+	if err := dec.Decode(&d); err != nil {
+		return Document{}, fmt.Errorf("catalog: decode: %w", err)
+	}
+	if dec.More() {
+		return Document{}, errors.New("catalog: decode: data after the document")
+	}
+	if err := d.Validate(); err != nil {
+		return Document{}, err
+	}
+	return d, nil
+}
+
+// Validate checks d as Validate checks the catalog, and that its version
+// is SchemaVersion, its categories are known and listed once, each
+// pattern's category is listed, levels parse, the entry points agree with
+// the paths, and every relation is declared by a pattern.
+func (d Document) Validate() error {
+	if d.Version != SchemaVersion {
+		return fmt.Errorf("catalog: schema version %d, want %d", d.Version, SchemaVersion)
+	}
+	var errs []error
+	for i, c := range d.Categories {
+		if !slices.Contains(categoryOrder, c) {
+			errs = append(errs, fmt.Errorf("unknown category %q listed", c))
+		}
+		if slices.Index(d.Categories, c) != i {
+			errs = append(errs, fmt.Errorf("category %q listed twice", c))
+		}
+	}
+	names := map[string]bool{}
+	for _, e := range d.Patterns {
+		names[e.Name] = true
+		if !slices.Contains(d.Categories, e.Category) && slices.Contains(categoryOrder, e.Category) {
+			errs = append(errs, fmt.Errorf("%s: category %q is not listed", e.Name, e.Category))
+		}
+		if e.Level != "" {
+			if _, err := enum.ParseLevel(e.Level); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", e.Name, err))
+			}
+		}
+		if want := Module + "/" + e.Path; e.Package != want {
+			errs = append(errs, fmt.Errorf("%s: package %q, want %q", e.Name, e.Package, want))
+		}
+		if want := "go run " + Module + "/" + e.Example; (e.Example == "") != (e.Run == "") || (e.Example != "" && e.Run != want) {
+			errs = append(errs, fmt.Errorf("%s: run %q does not match example %q", e.Name, e.Run, e.Example))
+		}
+	}
+	for _, r := range d.Relations {
+		if !names[r.From] {
+			errs = append(errs, fmt.Errorf("relation from unknown pattern %q", r.From))
+		}
+	}
+	if err := validate(d.patterns()); err != nil {
+		errs = append(errs, err)
+	}
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("catalog: invalid document:\n%w", err)
+	}
+	return nil
+}
+
+// Catalog returns the patterns of d, with their relations, as the
+// catalog holds them; d must be valid.
+func (d Document) Catalog() []Pattern { return d.patterns() }
+
+// patterns converts d back; levels that do not parse are left zero, for
+// Validate to report.
+func (d Document) patterns() []Pattern {
+	ps := make([]Pattern, 0, len(d.Patterns))
+	index := map[string]int{}
+	for _, e := range d.Patterns {
+		p := Pattern{Name: e.Name, Category: e.Category, Summary: e.Summary, Path: e.Path, Example: e.Example}
+		if len(e.Pros) > 0 {
+			p.Pros = e.Pros
+		}
+		if len(e.Cons) > 0 {
+			p.Cons = e.Cons
+		}
+		p.Level, _ = enum.ParseLevel(e.Level)
+		index[e.Name] = len(ps)
+		ps = append(ps, p)
+	}
+	for _, r := range d.Relations {
+		if i, ok := index[r.From]; ok {
+			ps[i].Relations = append(ps[i].Relations, Relation{Kind: r.Kind, Target: r.To})
+		}
+	}
+	return ps
+}
@@ -0,0 +1,232 @@
+package catalog_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"patterns/catalog"
+	"patterns/idioms/enum"
+)
+
+// fresh returns a deep copy of the export to break.
+func fresh(t *testing.T) catalog.Document {
+	var b bytes.Buffer
+	if err := catalog.Export().Encode(&b); err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	var d catalog.Document
+	if err := json.Unmarshal(b.Bytes(), &d); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	return d
+}
+
+func encode(t *testing.T, d catalog.Document) string {
+	var b bytes.Buffer
+	if err := d.Encode(&b); err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	return b.String()
+}
+
+// entry returns the entry named name in d.
+func entry(d *catalog.Document, name string) *catalog.Entry {
+	for i := range d.Patterns {
+		if d.Patterns[i].Name == name {
+			return &d.Patterns[i]
+		}
+	}
+	panic("no entry " + name)
+}
+
+// invalid are broken documents, each made from the export by mutate, and
+// what Import must say about them.
+var invalid = []struct {
+	name   string
+	mutate func(d *catalog.Document, raw string) string
+	want   []string
+}{
+	{"another version", func(d *catalog.Document, _ string) string { d.Version = 2; return "" },
+		[]string{"schema version 2, want 1"}},
+	{"malformed JSON", func(_ *catalog.Document, raw string) string { return raw[:len(raw)/2] },
+		[]string{"decode: unexpected EOF"}},
+	{"an unknown field", func(_ *catalog.Document, raw string) string {
+		return strings.Replace(raw, `"name": "arena",`, `"name": "arena", "color": "red",`, 1)
+	}, []string{`unknown field "color"`}},
+	{"data after the document", func(_ *catalog.Document, raw string) string { return raw + "{}" },
+		[]string{"data after the document"}},
+	{"a duplicate name", func(d *catalog.Document, _ string) string { d.Patterns = append(d.Patterns, d.Patterns[0]); return "" },
+		[]string{`duplicate pattern "arena"`}},
+	{"a name not in kebab-case", func(d *catalog.Document, _ string) string {
+		e := *entry(d, "arena")
+		e.Name = "Arena_Alloc"
+		d.Patterns = append(d.Patterns, e)
+		return ""
+	},
+		[]string{`name "Arena_Alloc" is not kebab-case`}},
+	{"an unknown category", func(d *catalog.Document, _ string) string { entry(d, "arena").Category = "idioms"; return "" },
+		[]string{`arena: unknown category "idioms"`}},
+	{"a category not listed", func(d *catalog.Document, _ string) string { d.Categories = d.Categories[1:]; return "" },
+		[]string{`arena: category "creational" is not listed`, `buffer-pool: category "creational" is not listed`}},
+	{"a category listed twice or unknown", func(d *catalog.Document, _ string) string {
+		d.Categories = append(d.Categories, "structural", "idioms")
+		return ""
+	}, []string{`category "structural" listed twice`, `unknown category "idioms" listed`}},
+	{"a level that does not parse", func(d *catalog.Document, _ string) string { entry(d, "builder").Level = "great"; return "" },
+		[]string{`builder: invalid Level "great"`}},
+	{"a package not matching the path", func(d *catalog.Document, _ string) string { entry(d, "arena").Package = "perf/arena"; return "" },
+		[]string{`arena: package "perf/arena", want "patterns/perf/arena"`}},
+	{"a run command without example", func(d *catalog.Document, _ string) string { entry(d, "arena").Run = "go run ."; return "" },
+		[]string{`arena: run "go run ." does not match example ""`}},
+	{"an example outside the repository", func(d *catalog.Document, _ string) string {
+		e := entry(d, "lazy-field")
+		e.Example, e.Run = "../lazy", "go run patterns/../lazy"
+		return ""
+	}, []string{`lazy-field: example "../lazy" is not a relative path inside the repository`}},
+	{"relations that are broken", func(d *catalog.Document, _ string) string {
+		d.Relations = append(d.Relations,
+			catalog.Edge{From: "arena", Kind: catalog.ComposesWith, To: "slab"},
+			catalog.Edge{From: "slab", Kind: catalog.ComposesWith, To: "arena"},
+			catalog.Edge{From: "arena", Kind: catalog.Refines, To: "arena"},
+			catalog.Edge{From: "arena", Kind: "extends", To: "buffer-pool"})
+		return ""
+	}, []string{
+		`arena: composes-with dangling reference "slab"`,
+		`relation from unknown pattern "slab"`,
+		`arena: relation to itself`,
+		`arena: unknown relation kind "extends"`,
+	}},
+}
+
+func TestValidate(t *testing.T) {
+	if err := catalog.Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestExportImportRoundTrip(t *testing.T) {
+	raw := encode(t, catalog.Export())
+	d, err := catalog.Import(strings.NewReader(raw))
+	if err != nil {
+		t.Fatalf("import: %v", err)
+	}
+	if got, want := d.Catalog(), catalog.All(); !reflect.DeepEqual(got, want) {
+		for i := range min(len(got), len(want)) {
+			if !reflect.DeepEqual(got[i], want[i]) {
+				t.Fatalf("pattern %d:\ngot  %+v\nwant %+v", i, got[i], want[i])
+			}
+		}
+		t.Fatalf("%d patterns, want %d", len(got), len(want))
+	}
+	if again := encode(t, d); again != raw {
+		t.Fatalf("the imported document exports differently")
+	}
+}
+
+func TestExportIsDeterministic(t *testing.T) {
+	first := encode(t, catalog.Export())
+	for range 5 {
+		if encode(t, catalog.Export()) != first {
+			t.Fatalf("exports differ")
+		}
+	}
+}
+
+func TestImportRejects(t *testing.T) {
+	for _, c := range invalid {
+		t.Run(c.name, func(t *testing.T) {
+			d := fresh(t)
+			raw := c.mutate(&d, encode(t, d))
+			if raw == "" {
+				raw = encode(t, d)
+			}
+			_, err := catalog.Import(strings.NewReader(raw))
+			if err == nil {
+				t.Fatalf("imported")
+			}
+			for _, w := range c.want {
+				if !strings.Contains(err.Error(), w) {
+					t.Errorf("error does not say %q:\n%v", w, err)
+				}
+			}
+			// one fault is reported once, however many rules look at it
+			if n := strings.Count(err.Error(), "\n"); len(c.want) == 1 && n > 1 {
+				t.Errorf("%d lines for one fault:\n%v", n, err)
+			}
+		})
+	}
+}
+
+// schemaObject is the part of a JSON Schema object the check reads.
+type schemaObject struct {
+	Required   []string                   `json:"required"`
+	Properties map[string]json.RawMessage `json:"properties"`
+	Enum       []string                   `json:"enum"`
+}
+
+func TestSchemaDescribesTheTypes(t *testing.T) {
+	var root struct {
+		schemaObject
+		Defs map[string]schemaObject `json:"$defs"`
+	}
+	if err := json.Unmarshal(catalog.Schema, &root); err != nil {
+		t.Fatalf("schema.json: %v", err)
+	}
+	for _, c := range []struct {
+		def string
+		obj schemaObject
+		typ reflect.Type
+	}{
+		{"document", root.schemaObject, reflect.TypeFor[catalog.Document]()},
+		{"entry", root.Defs["entry"], reflect.TypeFor[catalog.Entry]()},
+		{"edge", root.Defs["edge"], reflect.TypeFor[catalog.Edge]()},
+	} {
+		var fields, required []string
+		for i := range c.typ.NumField() {
+			name, opts, _ := strings.Cut(c.typ.Field(i).Tag.Get("json"), ",")
+			fields = append(fields, name)
+			if opts != "omitempty" {
+				required = append(required, name)
+			}
+		}
+		var props []string
+		for p := range c.obj.Properties {
+			props = append(props, p)
+		}
+		if !sameSet(props, fields) || !sameSet(c.obj.Required, required) {
+			t.Fatalf("%s: schema properties %v, required %v; Go fields %v, required %v", c.def, props, c.obj.Required, fields, required)
+		}
+	}
+
+	var levels []string
+	for _, l := range enum.LevelValues() {
+		levels = append(levels, l.String())
+	}
+	var categories []string
+	for _, c := range catalog.Categories() {
+		categories = append(categories, string(c))
+	}
+	var entryLevel, edgeKind schemaObject
+	json.Unmarshal(root.Defs["entry"].Properties["level"], &entryLevel)
+	json.Unmarshal(root.Defs["edge"].Properties["kind"], &edgeKind)
+	kinds := []string{string(catalog.AlternativeTo), string(catalog.ComposesWith), string(catalog.Refines)}
+	switch {
+	case !sameSet(root.Defs["category"].Enum, categories):
+		t.Fatalf("schema categories %v, Go %v", root.Defs["category"].Enum, categories)
+	case !sameSet(entryLevel.Enum, levels):
+		t.Fatalf("schema levels %v, Go %v", entryLevel.Enum, levels)
+	case !sameSet(edgeKind.Enum, kinds):
+		t.Fatalf("schema relation kinds %v, Go %v", edgeKind.Enum, kinds)
+	}
+}
+
+func sameSet(a, b []string) bool {
+	a, b = slices.Clone(a), slices.Clone(b)
+	slices.Sort(a)
+	slices.Sort(b)
+	return slices.Equal(a, b)
+}
@@ -0,0 +1,62 @@
+package catalog
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Mermaid writes the relationship graph as a mermaid flowchart, grouped
+// by category.
+func Mermaid(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+	for _, c := range Categories() {
+		fmt.Fprintf(&b, "  subgraph %s\n", c)
+		for _, p := range All() {
+			if p.Category == c {
+				fmt.Fprintf(&b, "    %s[%q]\n", nodeID(p.Name), p.Name)
+			}
+		}
+		b.WriteString("  end\n")
+	}
+	for _, p := range All() {
+		for _, r := range p.Relations {
+			fmt.Fprintf(&b, "  %s -->|%s| %s\n", nodeID(p.Name), r.Kind, nodeID(r.Target))
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// DOT writes the relationship graph in Graphviz format.
+func DOT(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("digraph patterns {\n  rankdir=LR;\n")
+	for _, c := range Categories() {
+		fmt.Fprintf(&b, "  subgraph \"cluster_%s\" {\n    label=%q;\n", c, c)
+		for _, p := range All() {
+			if p.Category == c {
+				fmt.Fprintf(&b, "    %q;\n", p.Name)
+			}
+		}
+		b.WriteString("  }\n")
+	}
+	for _, p := range All() {
+		for _, r := range p.Relations {
+			style := ""
+			if r.Kind == AlternativeTo {
+				style = ", style=dashed"
+			}
+			fmt.Fprintf(&b, "  %q -> %q [label=%q%s];\n", p.Name, r.Target, r.Kind, style)
+		}
+	}
+	b.WriteString("}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// nodeID makes a mermaid-safe identifier.
+func nodeID(name string) string {
+	return strings.ReplaceAll(name, "-", "_")
+}
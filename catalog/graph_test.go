@@ -0,0 +1,213 @@
+package catalog_test
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"patterns/catalog"
+)
+
+// graph is an exported graph read back: the category each node was
+// declared under, and the edges as "from kind to".
+type graph struct {
+	nodes map[string]string
+	edges []string
+}
+
+// edges returns the catalog's relations as "from kind to", named by
+// id.
+func edges(id func(string) string) []string {
+	var out []string
+	for _, p := range catalog.All() {
+		for _, r := range p.Relations {
+			out = append(out, fmt.Sprintf("%s %s %s", id(p.Name), r.Kind, id(r.Target)))
+		}
+	}
+	slices.Sort(out)
+	return out
+}
+
+// check compares g with the catalog: every pattern a node once, under
+// its category, every relation an edge, and no edge between nodes that
+// were not declared.
+func (g graph) check(t *testing.T, format string, id func(string) string) {
+	t.Helper()
+	ps := catalog.All()
+	if len(g.nodes) != len(ps) {
+		t.Errorf("%s: %d nodes, want %d", format, len(g.nodes), len(ps))
+	}
+	for _, p := range ps {
+		if c, ok := g.nodes[id(p.Name)]; !ok || c != string(p.Category) {
+			t.Errorf("%s: %s declared in %q, want %q", format, p.Name, c, p.Category)
+		}
+	}
+	slices.Sort(g.edges)
+	if want := edges(id); len(want) == 0 || !slices.Equal(g.edges, want) {
+		t.Errorf("%s: %d edges, want the %d relations", format, len(g.edges), len(want))
+	}
+	for _, e := range g.edges {
+		f := strings.Fields(e)
+		for _, end := range []string{f[0], f[2]} {
+			if _, ok := g.nodes[end]; !ok {
+				t.Errorf("%s: edge %s to undeclared node %s", format, e, end)
+			}
+		}
+	}
+}
+
+var (
+	mermaidSubgraph = regexp.MustCompile(`^  subgraph (\S+)$`)
+	mermaidNode     = regexp.MustCompile(`^    ([A-Za-z0-9_]+)\["([^"]+)"\]$`)
+	mermaidEdge     = regexp.MustCompile(`^  ([A-Za-z0-9_]+) -->\|([a-z-]+)\| ([A-Za-z0-9_]+)$`)
+)
+
+// mermaidID is the node id Mermaid gives a pattern: kebab-case names have
+// no underscores, so the ids of two patterns cannot collide.
+func mermaidID(name string) string { return strings.ReplaceAll(name, "-", "_") }
+
+func TestMermaid(t *testing.T) {
+	var b bytes.Buffer
+	if err := catalog.Mermaid(&b); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n")
+	if lines[0] != "flowchart LR" {
+		t.Fatalf("first line %q", lines[0])
+	}
+	g := graph{nodes: map[string]string{}}
+	var subgraphs []string
+	category := ""
+	for i, line := range lines[1:] {
+		switch m := mermaidSubgraph.FindStringSubmatch(line); {
+		case m != nil && category == "":
+			category = m[1]
+			subgraphs = append(subgraphs, category)
+		case line == "  end" && category != "":
+			category = ""
+		case category != "":
+			m := mermaidNode.FindStringSubmatch(line)
+			if m == nil || m[1] != mermaidID(m[2]) {
+				t.Fatalf("line %d: %q is not a node", i+2, line)
+			}
+			if _, ok := g.nodes[m[1]]; ok {
+				t.Errorf("%s declared twice", m[2])
+			}
+			g.nodes[m[1]] = category
+		default:
+			m := mermaidEdge.FindStringSubmatch(line)
+			if m == nil {
+				t.Fatalf("line %d: %q is not an edge", i+2, line)
+			}
+			g.edges = append(g.edges, strings.Join(m[1:], " "))
+		}
+	}
+	if category != "" {
+		t.Errorf("subgraph %s not ended", category)
+	}
+	g.check(t, "mermaid", mermaidID)
+	if want := categories(); !slices.Equal(subgraphs, want) {
+		t.Errorf("subgraphs %v, want %v", subgraphs, want)
+	}
+}
+
+var (
+	dotCluster = regexp.MustCompile(`^  subgraph "cluster_(\S+)" \{$`)
+	dotNode    = regexp.MustCompile(`^    "([^"]+)";$`)
+	dotEdge    = regexp.MustCompile(`^  "([^"]+)" -> "([^"]+)" \[label="([a-z-]+)"(, style=dashed)?\];$`)
+)
+
+func TestDOT(t *testing.T) {
+	var b bytes.Buffer
+	if err := catalog.DOT(&b); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n")
+	if lines[0] != "digraph patterns {" || lines[1] != "  rankdir=LR;" || lines[len(lines)-1] != "}" {
+		t.Fatalf("graph starts %q, %q and ends %q", lines[0], lines[1], lines[len(lines)-1])
+	}
+	g := graph{nodes: map[string]string{}}
+	var clusters []string
+	category := ""
+	for i := 2; i < len(lines)-1; i++ {
+		line := lines[i]
+		switch m := dotCluster.FindStringSubmatch(line); {
+		case m != nil && category == "":
+			category = m[1]
+			clusters = append(clusters, category)
+			if label := fmt.Sprintf("    label=%q;", category); lines[i+1] != label {
+				t.Errorf("cluster %s labelled %q", category, lines[i+1])
+			}
+			i++
+		case line == "  }" && category != "":
+			category = ""
+		case category != "":
+			m := dotNode.FindStringSubmatch(line)
+			if m == nil {
+				t.Fatalf("line %d: %q is not a node", i+1, line)
+			}
+			if _, ok := g.nodes[m[1]]; ok {
+				t.Errorf("%s declared twice", m[1])
+			}
+			g.nodes[m[1]] = category
+		default:
+			m := dotEdge.FindStringSubmatch(line)
+			if m == nil {
+				t.Fatalf("line %d: %q is not an edge", i+1, line)
+			}
+			// only alternatives are dashed
+			if dashed := m[4] != ""; dashed != (catalog.RelationKind(m[3]) == catalog.AlternativeTo) {
+				t.Errorf("%s %s %s dashed: %v", m[1], m[3], m[2], dashed)
+			}
+			g.edges = append(g.edges, strings.Join([]string{m[1], m[3], m[2]}, " "))
+		}
+	}
+	g.check(t, "dot", func(name string) string { return name })
+	if want := categories(); !slices.Equal(clusters, want) {
+		t.Errorf("clusters %v, want %v", clusters, want)
+	}
+}
+
+func categories() []string {
+	var out []string
+	for _, c := range catalog.Categories() {
+		out = append(out, string(c))
+	}
+	return out
+}
+
+// TestGraphIsDeterministic renders each graph twice: the doc generator
+// commits them, so the output must not depend on map order.
+func TestGraphIsDeterministic(t *testing.T) {
+	for _, render := range []func(*bytes.Buffer) error{
+		func(b *bytes.Buffer) error { return catalog.Mermaid(b) },
+		func(b *bytes.Buffer) error { return catalog.DOT(b) },
+	} {
+		var first, again bytes.Buffer
+		if err := render(&first); err != nil {
+			t.Fatal(err)
+		}
+		for range 5 {
+			again.Reset()
+			if err := render(&again); err != nil || again.String() != first.String() {
+				t.Fatalf("renders differ: %v", err)
+			}
+		}
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) { return 0, fmt.Errorf("disk full") }
+
+func TestGraphWriteError(t *testing.T) {
+	if err := catalog.Mermaid(failingWriter{}); err == nil || err.Error() != "disk full" {
+		t.Errorf("Mermaid = %v", err)
+	}
+	if err := catalog.DOT(failingWriter{}); err == nil || err.Error() != "disk full" {
+		t.Errorf("DOT = %v", err)
+	}
+}
@@ -0,0 +1,1578 @@
+package catalog
+
+import "patterns/idioms/enum"
+
+var patterns = []Pattern{
+	{
+		Name:     "procedural",
+		Category: Creational,
+		Level:    enum.LevelPoor,
+		Summary:  "Configuration passed as positional (pointer) parameters.",
+		Path:     "options/procedural",
+		Cons:     []string{"nil vs zero needs a pointer", "every new setting breaks callers"},
+		Relations: []Relation{
+			{AlternativeTo, "functional-options"},
+		},
+	},
+	{
+		Name:     "config-struct",
+		Category: Creational,
+		Level:    enum.LevelAverage,
+		Summary:  "Configuration passed as a struct of field.Field settings that can be left out.",
+		Path:     "options/configstruct",
+		Pros:     []string{"adding fields is compatible"},
+		Cons:     []string{"a wrapper type to tell unset from zero"},
+		Relations: []Relation{
+			{AlternativeTo, "functional-options"},
+			{Refines, "procedural"},
+		},
+	},
+	{
+		Name:     "builder",
+		Category: Creational,
+		Level:    enum.LevelGood,
+		Summary:  "Method-chained builder producing a config.",
+		Path:     "options/builder",
+		Pros:     []string{"readable method chain"},
+		Cons:     []string{"delayed validation", "setters cannot return errors", "empty config for defaults"},
+		Relations: []Relation{
+			{AlternativeTo, "functional-options"},
+		},
+	},
+	{
+		Name:     "functional-options",
+		Category: Creational,
+		Level:    enum.LevelGood,
+		Summary:  "Variadic With* options validated as they are applied.",
+		Path:     "options/functional",
+		Example:  "options/functional/cmd/server",
+		Pros:     []string{"immediate validation", "lightweight writing", "readable", "encapsulation"},
+	},
+	{
+		Name:     "client-options",
+		Category: Creational,
+		Summary:  "Functional options for an HTTP client with retry and timeouts.",
+		Path:     "options/clientexample",
+		Relations: []Relation{
+			{Refines, "functional-options"},
+		},
+	},
+	{
+		Name:     "call-options",
+		Category: Creational,
+		Summary:  "Constructor defaults overridden by per-call options.",
+		Path:     "options/calloptions",
+		Relations: []Relation{
+			{Refines, "functional-options"},
+		},
+	},
+	{
+		Name:     "funcopts",
+		Category: Creational,
+		Summary:  "Generic functional options helper with duplicate, conflict, deprecation, introspection and reuse guards.",
+		Path:     "funcopts",
+		Relations: []Relation{
+			{Refines, "functional-options"},
+		},
+	},
+	{
+		Name:     "config-dump",
+		Category: Architecture,
+		Summary:  "Render resolved configuration as JSON/YAML with secret redaction.",
+		Path:     "configdump",
+		Relations: []Relation{
+			{ComposesWith, "funcopts"},
+		},
+	},
+	{
+		Name:     "enum",
+		Category: Structural,
+		Summary:  "Typed constants with generated String/Parse/Values/marshaling.",
+		Path:     "idioms/enum",
+	},
+	{
+		Name:     "phantom-types",
+		Category: Structural,
+		Summary:  "Type parameters used only as compile-time state markers.",
+		Path:     "idioms/phantom",
+		Pros:     []string{"invalid state transitions do not compile"},
+		Cons:     []string{"explicit conversion still bypasses the check"},
+	},
+	{
+		Name:     "newtype",
+		Category: Structural,
+		Summary:  "Defined types for identifiers to prevent argument transposition.",
+		Path:     "idioms/newtype",
+		Relations: []Relation{
+			{ComposesWith, "value-object"},
+		},
+	},
+	{
+		Name:     "units",
+		Category: Structural,
+		Summary:  "Units of measure as defined types (bytes, temperature, money).",
+		Path:     "idioms/units",
+		Relations: []Relation{
+			{Refines, "newtype"},
+			{ComposesWith, "phantom-types"},
+		},
+	},
+	{
+		Name:     "value-object",
+		Category: Structural,
+		Summary:  "Comparable, normalized value objects and Equal for non-comparable ones.",
+		Path:     "idioms/valueobject",
+		Relations: []Relation{
+			{Refines, "newtype"},
+		},
+	},
+	{
+		Name:     "marker-interface",
+		Category: Structural,
+		Summary:  "Marker methods vs struct tags vs type lists for capability marking.",
+		Path:     "idioms/markeriface",
+	},
+	{
+		Name:     "sealed-interface",
+		Category: Structural,
+		Summary:  "Interfaces closed to outside implementations via an unexported method.",
+		Path:     "idioms/sealed",
+		Relations: []Relation{
+			{AlternativeTo, "marker-interface"},
+		},
+	},
+	{
+		Name:     "sum-type",
+		Category: Structural,
+		Summary:  "Sealed interface plus type switch, checked by analyzers/exhaustive.",
+		Path:     "idioms/sumtype",
+		Relations: []Relation{
+			{ComposesWith, "sealed-interface"},
+		},
+	},
+	{
+		Name:     "typed-error-union",
+		Category: Architecture,
+		Summary:  "Closed sets of error kinds matched exhaustively.",
+		Path:     "errors/union",
+		Relations: []Relation{
+			{Refines, "sum-type"},
+			{ComposesWith, "handler-adapter"},
+		},
+	},
+	{
+		Name:     "handler-adapter",
+		Category: Structural,
+		Summary:  "Typed func(ctx, Req) (Resp, error) adapted to http.Handler.",
+		Path:     "web/handler",
+	},
+	{
+		Name:     "request-builder",
+		Category: Creational,
+		Summary:  "Fluent *http.Request builder with error accumulation.",
+		Path:     "web/requestbuilder",
+		Relations: []Relation{
+			{Refines, "builder"},
+		},
+	},
+	{
+		Name:     "response-recorder",
+		Category: Structural,
+		Summary:  "ResponseWriter decorator preserving Flusher/Hijacker.",
+		Path:     "web/responserecorder",
+	},
+	{
+		Name:     "middleware",
+		Category: Structural,
+		Summary:  "Middleware chain with named entries, ordering constraints and predicates.",
+		Path:     "web/middleware",
+		Relations: []Relation{
+			{ComposesWith, "response-recorder"},
+			{ComposesWith, "handler-adapter"},
+		},
+	},
+	{
+		Name:     "ephemeral-port",
+		Category: Concurrency,
+		Summary:  "Bind port 0 and keep the listener to avoid rebind races.",
+		Path:     "netutil/freeport",
+	},
+	{
+		Name:     "dispatch-benchmark",
+		Category: Behavioral,
+		Summary:  "Visitor vs type switch vs handler registry dispatch costs.",
+		Path:     "bench/dispatch",
+		Relations: []Relation{
+			{AlternativeTo, "sum-type"},
+		},
+	},
+	{
+		Name:     "repository",
+		Category: Architecture,
+		Summary:  "Collection-like storage interface with in-memory and JSON file implementations, and an entity-shaped UserRepository over memory and database/sql.",
+		Path:     "persistence/repository",
+	},
+	{
+		Name:     "lru",
+		Category: Structural,
+		Summary:  "Fixed-capacity least-recently-used cache.",
+		Path:     "caching/lru",
+	},
+	{
+		Name:     "cache-aside",
+		Category: Resilience,
+		Summary:  "Read through the cache, load from the source on a miss, invalidate on write.",
+		Path:     "caching/cacheaside",
+		Relations: []Relation{
+			{ComposesWith, "lru"},
+			{ComposesWith, "repository"},
+		},
+	},
+	{
+		Name:     "token-bucket",
+		Category: Resilience,
+		Summary:  "Token bucket rate limiter with per-key limiters: bursts up to a saved allowance, then the rate.",
+		Path:     "resilience/ratelimit",
+		Relations: []Relation{
+			{ComposesWith, "middleware"},
+		},
+	},
+	{
+		Name:     "graceful-shutdown",
+		Category: Concurrency,
+		Summary:  "Serve until the context or a signal is done, then drain in-flight requests within a deadline and close what is left.",
+		Path:     "lifecycle/shutdown",
+	},
+	{
+		Name:     "url-shortener",
+		Category: Architecture,
+		Summary:  "Runnable service composing options, repository, cache-aside, rate limiting, middleware and graceful shutdown.",
+		Path:     "examples/urlshortener",
+		Example:  "examples/urlshortener",
+		Relations: []Relation{
+			{ComposesWith, "funcopts"},
+			{ComposesWith, "repository"},
+			{ComposesWith, "cache-aside"},
+			{ComposesWith, "token-bucket"},
+			{ComposesWith, "middleware"},
+			{ComposesWith, "handler-adapter"},
+			{ComposesWith, "graceful-shutdown"},
+		},
+	},
+	{
+		Name:     "kvstore",
+		Category: Architecture,
+		Summary:  "Embedded key-value store: commands in a write-ahead log, memento snapshots, iterator scans.",
+		Path:     "examples/kvstore",
+		Example:  "examples/kvstore/cmd/kvctl",
+		Relations: []Relation{
+			{ComposesWith, "funcopts"},
+		},
+	},
+	{
+		Name:     "job-queue",
+		Category: Architecture,
+		Summary:  "Persistent job runner: outbox, priority dispatch, worker pool, retry with backoff, per-kind circuit breakers and a dead-letter store.",
+		Path:     "examples/jobqueue",
+		Example:  "examples/jobqueue/cmd/jobqueue",
+		Relations: []Relation{
+			{ComposesWith, "funcopts"},
+		},
+	},
+	{
+		Name:     "chat",
+		Category: Concurrency,
+		Summary:  "Long-poll chat server: rooms as actors, presence as observer, fan-out through a typed bus.",
+		Path:     "examples/chat",
+		Example:  "examples/chat",
+		Relations: []Relation{
+			{ComposesWith, "handler-adapter"},
+			{ComposesWith, "graceful-shutdown"},
+		},
+	},
+	{
+		Name:     "crud",
+		Category: Architecture,
+		Summary:  "REST CRUD service: typed handlers, validation, error union mapping, repository backends and DI wiring.",
+		Path:     "examples/crud",
+		Example:  "examples/crud",
+		Relations: []Relation{
+			{ComposesWith, "handler-adapter"},
+			{ComposesWith, "typed-error-union"},
+			{ComposesWith, "repository"},
+		},
+	},
+	{
+		Name:     "cli-commands",
+		Category: Behavioral,
+		Summary:  "Subcommands as Command values with per-command FlagSets and testable Run(args, stdout, stderr) entry points.",
+		Path:     "cli",
+	},
+	{
+		Name:     "api-evolution",
+		Category: Architecture,
+		Summary:  "Growing an API compatibly: options from v1, optional interface upgrades, vN packages; verified with a go/types API diff.",
+		Path:     "idioms/apievolution",
+		Relations: []Relation{
+			{ComposesWith, "functional-options"},
+		},
+	},
+	{
+		Name:     "zero-value",
+		Category: Creational,
+		Summary:  "Types usable without a constructor: lazy allocation, nil-safe reads, defaulted fields, nil receivers.",
+		Path:     "idioms/zerovalue",
+		Relations: []Relation{
+			{AlternativeTo, "functional-options"},
+		},
+	},
+	{
+		Name:     "construct",
+		Category: Creational,
+		Summary:  "Shared constructor sequence: defaults, options, then cross-field validation.",
+		Path:     "construct",
+		Relations: []Relation{
+			{ComposesWith, "funcopts"},
+			{Refines, "functional-options"},
+		},
+	},
+	{
+		Name:     "must",
+		Category: Creational,
+		Summary:  "Must[T] for panic-on-error construction where errors are programming mistakes, with call-site panic values.",
+		Path:     "idioms/must",
+		Relations: []Relation{
+			{ComposesWith, "functional-options"},
+		},
+	},
+	{
+		Name:     "validate",
+		Category: Creational,
+		Summary:  "Composable field rules collecting every constructor invariant violation with its field path.",
+		Path:     "validate",
+		Relations: []Relation{
+			{ComposesWith, "construct"},
+			{ComposesWith, "value-object"},
+		},
+	},
+	{
+		Name:     "panic-policy",
+		Category: Resilience,
+		Summary:  "Errors for expected failures, panics for misuse, and one recover at the request or job boundary.",
+		Path:     "idioms/panicpolicy",
+		Relations: []Relation{
+			{ComposesWith, "must"},
+		},
+	},
+	{
+		Name:     "multicloser",
+		Category: Resilience,
+		Summary:  "Cleanup stack closing resources in reverse order on failed construction or shutdown, with joined errors and per-close timeouts.",
+		Path:     "lifecycle/multicloser",
+		Relations: []Relation{
+			{ComposesWith, "graceful-shutdown"},
+		},
+	},
+	{
+		Name:     "connpool",
+		Category: Architecture,
+		Summary:  "Connection pool dialed up front that rolls back already opened connections when a dial fails.",
+		Path:     "examples/connpool",
+		Example:  "examples/connpool",
+		Relations: []Relation{
+			{ComposesWith, "multicloser"},
+			{ComposesWith, "construct"},
+			{ComposesWith, "resource-handle"},
+		},
+	},
+	{
+		Name:     "tx-defer",
+		Category: Resilience,
+		Summary:  "Deferred commit/rollback wrapped in a Tx that ends once on every path, with the hand-written pitfalls.",
+		Path:     "idioms/txdefer",
+		Relations: []Relation{
+			{ComposesWith, "multicloser"},
+			{ComposesWith, "panic-policy"},
+		},
+	},
+	{
+		Name:     "resource-handle",
+		Category: Resilience,
+		Summary:  "Handles that track open resources, record acquisition stacks in debug mode and report leaks at test teardown.",
+		Path:     "lifecycle/handle",
+		Relations: []Relation{
+			{ComposesWith, "multicloser"},
+		},
+	},
+	{
+		Name:     "arena",
+		Category: Creational,
+		Summary:  "Chunked slab allocation with index references and bulk Reset, trading per-value frees for no GC pressure.",
+		Path:     "perf/arena",
+	},
+	{
+		Name:     "zero-alloc-options",
+		Category: Creational,
+		Summary:  "Closure, interface, tagged struct and config literal options benchmarked for allocations per construction.",
+		Path:     "options/zeroalloc",
+		Relations: []Relation{
+			{AlternativeTo, "functional-options"},
+			{ComposesWith, "call-options"},
+		},
+	},
+	{
+		Name:     "buffer-pool",
+		Category: Creational,
+		Summary:  "Pooled bytes.Buffers with a capacity cap so one large render does not pin memory in the pool.",
+		Path:     "perf/bufpool",
+		Relations: []Relation{
+			{AlternativeTo, "arena"},
+		},
+	},
+	{
+		Name:     "io-decorators",
+		Category: Structural,
+		Summary:  "Counting, rate-limited, progress and tee decorators composing over any io.Reader or io.Writer.",
+		Path:     "structural/iodecorators",
+		Relations: []Relation{
+			{ComposesWith, "token-bucket"},
+		},
+	},
+	{
+		Name:     "freezing-builder",
+		Category: Creational,
+		Summary:  "Builder whose Build returns a deep-copied immutable config, safe to share while the builder is reused.",
+		Path:     "options/builder",
+		Relations: []Relation{
+			{Refines, "builder"},
+		},
+	},
+	{
+		Name:     "test-options",
+		Category: Creational,
+		Summary:  "Functional options for test helpers that fail the test and register their own cleanup.",
+		Path:     "testing/testoptions",
+		Relations: []Relation{
+			{Refines, "functional-options"},
+		},
+	},
+	{
+		Name:     "clock",
+		Category: Concurrency,
+		Summary:  "Injectable Clock with a fake that fires timers, tickers and sleepers only on Advance.",
+		Path:     "clock",
+		Relations: []Relation{
+			{ComposesWith, "token-bucket"},
+			{ComposesWith, "test-options"},
+		},
+	},
+	{
+		Name:     "injectable-rand",
+		Category: Behavioral,
+		Summary:  "Randomness behind an interface with seeded, logged sources so jittered and sampled behavior replays in tests.",
+		Path:     "randsource",
+		Relations: []Relation{
+			{ComposesWith, "clock"},
+		},
+	},
+	{
+		Name:     "load-balancing",
+		Category: Resilience,
+		Summary:  "Random, round-robin, weighted, least-connections and power-of-two-choices pickers behind one Picker interface.",
+		Path:     "resilience/loadbalance",
+		Relations: []Relation{
+			{ComposesWith, "injectable-rand"},
+		},
+	},
+	{
+		Name:     "ab-bucketing",
+		Category: Architecture,
+		Summary:  "Experiment assignment by hashing a user id, or a seeded random draw pinned by cookie for anonymous visitors.",
+		Path:     "web/abtest",
+		Relations: []Relation{
+			{ComposesWith, "middleware"},
+			{ComposesWith, "injectable-rand"},
+		},
+	},
+	{
+		Name:     "staged-builder",
+		Category: Creational,
+		Level:    enum.LevelGood,
+		Summary:  "Typestate builder whose stage types make missing required settings and negative ports compile errors.",
+		Path:     "options/staged",
+		Pros:     []string{"required settings checked by the compiler", "no run-time validation errors"},
+		Cons:     []string{"one type per stage", "int ports need a caller-side range check"},
+		Relations: []Relation{
+			{Refines, "builder"},
+			{AlternativeTo, "functional-options"},
+		},
+	},
+	{
+		Name:     "service-discovery",
+		Category: Resilience,
+		Summary:  "Static, file-watched and DNS resolvers feeding a polled, diffed endpoint pool with health status and change notifications.",
+		Path:     "resilience/discovery",
+		Relations: []Relation{
+			{ComposesWith, "load-balancing"},
+			{ComposesWith, "clock"},
+		},
+	},
+	{
+		Name:     "connection-draining",
+		Category: Concurrency,
+		Summary:  "Signal long-poll, SSE and hijacked streams to finish on shutdown and wait for them before returning.",
+		Path:     "web/draining",
+		Relations: []Relation{
+			{Refines, "graceful-shutdown"},
+		},
+	},
+	{
+		Name:     "interface-options",
+		Category: Creational,
+		Level:    enum.LevelGood,
+		Summary:  "Options as comparable values behind an interface with Name and Priority, applied in a defined order.",
+		Path:     "options/ifaceopts",
+		Pros:     []string{"options can be logged, compared and looked up", "application order independent of call order"},
+		Cons:     []string{"a type and several methods per option"},
+		Relations: []Relation{
+			{AlternativeTo, "functional-options"},
+			{Refines, "sealed-interface"},
+		},
+	},
+	{
+		Name:     "request-scope",
+		Category: Architecture,
+		Summary:  "Per-request dependencies (transaction, tagged logger, principal) built by middleware and read through typed context keys.",
+		Path:     "web/requestscope",
+		Relations: []Relation{
+			{ComposesWith, "middleware"},
+			{ComposesWith, "crud"},
+		},
+	},
+	{
+		Name:     "http-cache",
+		Category: Structural,
+		Summary:  "Caching handler decorator with ETag revalidation, max-age freshness, Vary variants and pluggable cache keys.",
+		Path:     "web/httpcache",
+		Relations: []Relation{
+			{ComposesWith, "lru"},
+			{ComposesWith, "middleware"},
+			{AlternativeTo, "cache-aside"},
+		},
+	},
+	{
+		Name:     "content-negotiation",
+		Category: Behavioral,
+		Summary:  "Server-driven negotiation that picks a response encoder from the Accept header by quality and specificity.",
+		Path:     "web/negotiate",
+		Relations: []Relation{
+			{ComposesWith, "handler-adapter"},
+		},
+	},
+	{
+		Name:     "option-presets",
+		Category: Creational,
+		Summary:  "Composite functional options (WithDefaults, WithProductionProfile) built with an Options combinator and overridable by later options.",
+		Path:     "options/functional",
+		Example:  "options/functional/cmd/server",
+		Level:    enum.LevelGood,
+		Pros:     []string{"one name for a vetted group of settings", "overridable like any option"},
+		Cons:     []string{"expansion is invisible at the call site"},
+		Relations: []Relation{
+			{Refines, "functional-options"},
+		},
+	},
+	{
+		Name:     "streaming-response",
+		Category: Behavioral,
+		Summary:  "Chunked and server-sent event responses fed by iter.Seq or channel producers, with heartbeats, resumable event IDs and an httptest-based stream reader.",
+		Path:     "web/streaming",
+		Relations: []Relation{
+			{ComposesWith, "connection-draining"},
+		},
+	},
+	{
+		Name:     "generated-options",
+		Category: Creational,
+		Summary:  "Functional options generated by cmd/optgen from an annotated config struct, with per-field rules and hand-written check hooks.",
+		Path:     "options/generated",
+		Level:    enum.LevelGood,
+		Pros:     []string{"no option boilerplate", "field rules stay next to the field"},
+		Cons:     []string{"a generate step to keep in sync"},
+		Relations: []Relation{
+			{Refines, "functional-options"},
+		},
+	},
+	{
+		Name:     "polling",
+		Category: Resilience,
+		Summary:  "Interval and long polling with conditional requests, jittered backoff on failures and Retry-After support.",
+		Path:     "clientpatterns/polling",
+		Level:    enum.LevelGood,
+		Relations: []Relation{
+			{AlternativeTo, "streaming-response"},
+			{ComposesWith, "http-cache"},
+			{ComposesWith, "clock"},
+		},
+	},
+	{
+		Name:     "singleton",
+		Category: Creational,
+		Summary:  "Eager, mutex, sync.Once, sync.OnceValue and atomic singletons next to broken double-checked locking, with benchmarks and a race demo.",
+		Path:     "creational/singleton",
+		Example:  "creational/singleton/cmd/racedemo",
+		Level:    enum.LevelGood,
+		Pros:     []string{"sync.OnceValue is race-free and as fast as the broken version"},
+		Cons:     []string{"global state hides dependencies and resists tests"},
+		Relations: []Relation{
+			{AlternativeTo, "construct"},
+		},
+	},
+	{
+		Name:     "write-ahead-log",
+		Category: Architecture,
+		Summary:  "Segmented append-only log with CRC-checked records, fsync policies, replay from an index and truncation at the first bad record.",
+		Path:     "storage/wal",
+		Level:    enum.LevelGood,
+		Pros:     []string{"sequential writes; recovery needs nothing but the files"},
+		Cons:     []string{"grows until a snapshot lets the front be dropped"},
+		Relations: []Relation{
+			{ComposesWith, "kvstore"},
+			{ComposesWith, "clock"},
+		},
+	},
+	{
+		Name:     "factory",
+		Category: Creational,
+		Summary:  "Simple factory, factory method and abstract factory building memory and file storage backends, with a name registry.",
+		Path:     "creational/factory",
+		Level:    enum.LevelGood,
+		Pros:     []string{"callers swap backends without changing"},
+		Cons:     []string{"an abstract factory grows a method per product"},
+		Relations: []Relation{
+			{ComposesWith, "repository"},
+		},
+	},
+	{
+		Name:     "snapshot-compaction",
+		Category: Architecture,
+		Summary:  "State machine over a write-ahead log with periodic CRC-checked snapshots, log compaction and snapshot-then-replay recovery.",
+		Path:     "storage/snapshot",
+		Level:    enum.LevelGood,
+		Pros:     []string{"recovery and disk use bounded by state size, not history"},
+		Cons:     []string{"writes wait while the whole state is serialized"},
+		Relations: []Relation{
+			{Refines, "write-ahead-log"},
+		},
+	},
+	{
+		Name:     "bloom-filter",
+		Category: Structural,
+		Summary:  "Generic lock-free Bloom filter sized by false-positive rate, guarding a loader against lookups of keys that do not exist.",
+		Path:     "caching/bloom",
+		Level:    enum.LevelGood,
+		Pros:     []string{"a miss costs a few hashes instead of a store lookup"},
+		Cons:     []string{"no deletion; sized up front"},
+		Relations: []Relation{
+			{ComposesWith, "cache-aside"},
+			{ComposesWith, "repository"},
+		},
+	},
+	{
+		Name:     "prototype",
+		Category: Creational,
+		Summary:  "Prototype registry over Clone methods, shallow versus deep copy pitfalls and a reflection-based DeepCopy.",
+		Path:     "creational/prototype",
+		Level:    enum.LevelGood,
+		Pros:     []string{"new values from configured templates without a constructor per variant"},
+		Cons:     []string{"only as safe as each type's Clone"},
+		Relations: []Relation{
+			{AlternativeTo, "factory"},
+		},
+	},
+	{
+		Name:     "consistent-hashing",
+		Category: Resilience,
+		Summary:  "Hash ring with virtual nodes: order-independent placement, minimal key movement on membership change, replica sets.",
+		Path:     "distribution/consistenthash",
+		Level:    enum.LevelGood,
+		Pros:     []string{"a membership change moves about 1/n of the keys"},
+		Cons:     []string{"balances key counts, not load"},
+		Relations: []Relation{
+			{AlternativeTo, "load-balancing"},
+			{ComposesWith, "service-discovery"},
+		},
+	},
+	{
+		Name:     "object-pool",
+		Category: Creational,
+		Summary:  "sync.Pool for transient buffers next to a bounded channel pool for connections, with GC-cycle benchmarks.",
+		Path:     "creational/pool",
+		Level:    enum.LevelGood,
+		Pros:     []string{"each pool fits one kind of value: scratch memory or limited resources"},
+		Cons:     []string{"a bounded pool holds idle resources; sync.Pool drops them at any GC"},
+		Relations: []Relation{
+			{Refines, "buffer-pool"},
+			{AlternativeTo, "connpool"},
+		},
+	},
+	{
+		Name:     "observer",
+		Category: Behavioral,
+		Summary:  "A generic Subject with per-observer buffers, overflow policies and context-scoped subscriptions.",
+		Path:     "behavioral/observer",
+		Level:    enum.LevelGood,
+		Pros:     []string{"a slow observer holds up only itself; subscriptions end with their context"},
+		Cons:     []string{"delivery is asynchronous; every value is copied into every buffer"},
+		Relations: []Relation{
+			{ComposesWith, "chat"},
+			{ComposesWith, "service-discovery"},
+		},
+	},
+	{
+		Name:     "sharding",
+		Category: Concurrency,
+		Summary:  "Keys routed to shard goroutines that own their partition of state, benchmarked against one global lock.",
+		Path:     "distribution/sharding",
+		Level:    enum.LevelGood,
+		Pros:     []string{"no locks on the state; operations on one key run in order"},
+		Cons:     []string{"a goroutine handoff per operation; a hot key stalls its shard"},
+		Relations: []Relation{
+			{ComposesWith, "consistent-hashing"},
+		},
+	},
+	{
+		Name:     "leader-election",
+		Category: Resilience,
+		Summary:  "Lease-based leader election with fencing terms, gain and loss callbacks and standby followers.",
+		Path:     "distribution/election",
+		Level:    enum.LevelGood,
+		Pros:     []string{"one leader at a time with no coordinator; a crashed leader is replaced after a TTL"},
+		Cons:     []string{"nobody leads during failover; needs an atomic store and clocks that do not drift"},
+		Relations: []Relation{
+			{ComposesWith, "repository"},
+			{ComposesWith, "clock"},
+		},
+	},
+	{
+		Name:     "strategy",
+		Category: Behavioral,
+		Summary:  "Interface, function-value and type-parameter strategies for sorting and compression, with dispatch benchmarks.",
+		Path:     "behavioral/strategy",
+		Level:    enum.LevelGood,
+		Pros:     []string{"the algorithm changes without touching its callers"},
+		Cons:     []string{"an indirect call per use; only the type-parameter form is checked at compile time"},
+		Relations: []Relation{
+			{ComposesWith, "dispatch-benchmark"},
+			{ComposesWith, "factory"},
+		},
+	},
+	{
+		Name:     "decorator",
+		Category: Structural,
+		Summary:  "Logging, auth and recovery as http.Handler decorators, nested and through a Chain helper.",
+		Path:     "structural/decorator",
+		Example:  "structural/decorator/cmd/server",
+		Level:    enum.LevelGood,
+		Pros:     []string{"cross-cutting behaviour added without touching handlers"},
+		Cons:     []string{"the order of decorators is behaviour, and easy to get wrong"},
+		Relations: []Relation{
+			{Refines, "middleware"},
+			{ComposesWith, "functional-options"},
+			{ComposesWith, "io-decorators"},
+		},
+	},
+	{
+		Name:     "logical-clocks",
+		Category: Resilience,
+		Summary:  "Lamport and vector clocks, and a sibling-keeping replica that detects concurrent writes.",
+		Path:     "distribution/logicalclock",
+		Level:    enum.LevelGood,
+		Pros:     []string{"causal order without synchronized wall clocks; vector clocks detect conflicts exactly"},
+		Cons:     []string{"vector clocks grow with the writers; siblings push resolution onto the application"},
+		Relations: []Relation{
+			{ComposesWith, "consistent-hashing"},
+		},
+	},
+	{
+		Name:     "crdt",
+		Category: Resilience,
+		Summary:  "State-based G-Counter, PN-Counter and add-wins OR-Set with JSON state exchange.",
+		Path:     "distribution/crdt",
+		Level:    enum.LevelGood,
+		Pros:     []string{"replicas update without coordination and converge in any merge order"},
+		Cons:     []string{"only operations with a convergent merge fit; state grows with the replicas"},
+		Relations: []Relation{
+			{AlternativeTo, "logical-clocks"},
+		},
+	},
+	{
+		Name:     "adapter",
+		Category: Structural,
+		Summary:  "A third-party-style logger adapted to slog.Handler and back, and io.Reader adapted to a line iterator and back.",
+		Path:     "structural/adapter",
+		Level:    enum.LevelGood,
+		Pros:     []string{"neither side changes; the translation lives in one place"},
+		Cons:     []string{"what one side cannot express is lost in translation"},
+		Relations: []Relation{
+			{ComposesWith, "handler-adapter"},
+			{ComposesWith, "io-decorators"},
+		},
+	},
+	{
+		Name:     "message-envelope",
+		Category: Architecture,
+		Summary:  "Typed, versioned message envelopes with JSON and gob codecs, correlation IDs and an upcaster chain.",
+		Path:     "messaging/envelope",
+		Level:    enum.LevelGood,
+		Pros:     []string{"self-describing payloads; old versions keep decoding into the current type"},
+		Cons:     []string{"a type and an upcaster per schema change, kept while old messages exist"},
+		Relations: []Relation{
+			{ComposesWith, "job-queue"},
+			{ComposesWith, "write-ahead-log"},
+		},
+	},
+	{
+		Name:     "proxy",
+		Category: Structural,
+		Summary:  "Caching and rate-limiting http.RoundTrippers that stand in for the transport of an http.Client.",
+		Path:     "structural/proxy",
+		Level:    enum.LevelGood,
+		Pros:     []string{"transparent to callers: the client and its requests stay as they are"},
+		Cons:     []string{"a fixed-lifetime cache serves stale data; waiting for tokens holds the caller"},
+		Relations: []Relation{
+			{AlternativeTo, "http-cache"},
+			{ComposesWith, "token-bucket"},
+			{ComposesWith, "decorator"},
+		},
+	},
+	{
+		Name:     "chain-of-responsibility",
+		Category: Behavioral,
+		Summary:  "Order processing (validate, enrich, review, place) as a slice of links and as linked handlers, either able to stop the request.",
+		Path:     "behavioral/chain",
+		Level:    enum.LevelGood,
+		Pros:     []string{"steps added, removed and reordered in one place; no link knows its neighbours"},
+		Cons:     []string{"which link stopped a request, and why, must be recorded on the request to be known"},
+		Relations: []Relation{
+			{AlternativeTo, "middleware"},
+			{ComposesWith, "validate"},
+		},
+	},
+	{
+		Name:     "ordered-consumers",
+		Category: Concurrency,
+		Summary:  "Competing consumers over one stream that keep each key's messages in order through key-hashed sub-queues.",
+		Path:     "messaging/orderedconsumers",
+		Level:    enum.LevelGood,
+		Pros:     []string{"parallel across keys, serial and in order within one"},
+		Cons:     []string{"a hot or slow key stalls every key hashed with it"},
+		Relations: []Relation{
+			{ComposesWith, "sharding"},
+			{ComposesWith, "message-envelope"},
+		},
+	},
+	{
+		Name:     "command",
+		Category: Behavioral,
+		Summary:  "Text editor commands with undo, redo and macros behind a generic History invoker.",
+		Path:     "behavioral/command",
+		Level:    enum.LevelGood,
+		Pros:     []string{"undo costs the size of the change; the invoker knows nothing about the commands"},
+		Cons:     []string{"every command needs a correct inverse, and every change must go through the history"},
+		Relations: []Relation{
+			{ComposesWith, "chain-of-responsibility"},
+		},
+	},
+	{
+		Name:     "inbox",
+		Category: Architecture,
+		Summary:  "Consumer-side deduplication by message ID, recorded after handling or atomically with the consumer's state.",
+		Path:     "messaging/inbox",
+		Level:    enum.LevelGood,
+		Pros:     []string{"redeliveries are skipped; the transactional form never applies a message twice"},
+		Cons:     []string{"IDs pile up until pruned; the plain form still repeats a message after an ill-timed crash"},
+		Relations: []Relation{
+			{ComposesWith, "job-queue"},
+			{ComposesWith, "message-envelope"},
+			{ComposesWith, "repository"},
+		},
+	},
+	{
+		Name:     "state",
+		Category: Behavioral,
+		Summary:  "An order lifecycle as one type per state behind an interface, and as typestate structs where invalid transitions do not compile.",
+		Path:     "behavioral/state",
+		Level:    enum.LevelGood,
+		Pros:     []string{"each state's rules live in its own type instead of switches on a status field"},
+		Cons:     []string{"state objects answer every event; typestate cannot stop an old state from being reused"},
+		Relations: []Relation{
+			{ComposesWith, "sealed-interface"},
+			{ComposesWith, "enum"},
+			{ComposesWith, "phantom-types"},
+		},
+	},
+	{
+		Name:     "anti-corruption-layer",
+		Category: Architecture,
+		Summary:  "A pure translation from a messy carrier API's models to the application's tracking domain, behind a Tracker port.",
+		Path:     "architecture/acl",
+		Level:    enum.LevelGood,
+		Pros:     []string{"external names, codes and quirks stop at one boundary; the mapping is pure and testable"},
+		Cons:     []string{"a second model and a mapping per external system, kept in step with its API"},
+		Relations: []Relation{
+			{Refines, "adapter"},
+			{ComposesWith, "validate"},
+		},
+	},
+	{
+		Name:     "visitor",
+		Category: Behavioral,
+		Summary:  "An evaluator and a pretty-printer over an arithmetic AST, as Visitors with double dispatch and as type switches over a sealed interface.",
+		Path:     "behavioral/visitor",
+		Level:    enum.LevelAverage,
+		Pros:     []string{"a new node breaks every visitor at compile time instead of at run time"},
+		Cons:     []string{"results travel through fields; in Go a type switch is usually lighter"},
+		Relations: []Relation{
+			{AlternativeTo, "sum-type"},
+			{ComposesWith, "sealed-interface"},
+		},
+	},
+	{
+		Name:     "iterator",
+		Category: Behavioral,
+		Summary:  "In-order tree traversal as iter.Seq/Seq2 push iterators, a Next/Value pull iterator, iter.Pull and a channel, with early-break semantics and benchmarks.",
+		Path:     "behavioral/iterator",
+		Level:    enum.LevelGood,
+		Pros:     []string{"push iterators are plain recursion, and breaking out runs their cleanup"},
+		Cons:     []string{"pull and channel iterators must be stopped explicitly, or they hold what they hold"},
+		Relations: []Relation{
+			{ComposesWith, "adapter"},
+		},
+	},
+	{
+		Name:     "contract-tests",
+		Category: Architecture,
+		Summary:  "Behaviour suites for Repository, UserStore, BlobStore, Locker and Limiter ports, run by the tests of every adapter so each is a drop-in for the others.",
+		Path:     "testing/contracts",
+		Level:    enum.LevelGood,
+		Pros:     []string{"a new adapter is done when it passes the suite the others pass"},
+		Cons:     []string{"a contract only covers what it states; adapter-specific failures still need their own tests"},
+		Relations: []Relation{
+			{ComposesWith, "repository"},
+			{ComposesWith, "factory"},
+			{ComposesWith, "leader-election"},
+		},
+	},
+	{
+		Name:     "template-method",
+		Category: Behavioral,
+		Summary:  "A table exporter whose step order lives in one function taking a Format with optional Header/Footer interfaces or hook funcs, next to the embedding version that silently ignores overrides.",
+		Path:     "behavioral/template",
+		Level:    enum.LevelGood,
+		Pros:     []string{"the algorithm is a plain function; formats implement only the steps they need"},
+		Cons:     []string{"optional steps are found by type assertion, so a misnamed method just never runs"},
+		Relations: []Relation{
+			{AlternativeTo, "strategy"},
+			{ComposesWith, "handler-adapter"},
+		},
+	},
+	{
+		Name:     "delayed-message",
+		Category: Architecture,
+		Summary:  "A durable scheduler publishing messages after a duration or at a time, catching up on restart, with a job-queue sink that enqueues each message once.",
+		Path:     "messaging/delayed",
+		Level:    enum.LevelGood,
+		Pros:     []string{"producers schedule and forget; pending messages survive restarts"},
+		Cons:     []string{"delivery is at least once; one file holds every pending message"},
+		Relations: []Relation{
+			{ComposesWith, "job-queue"},
+			{ComposesWith, "clock"},
+			{ComposesWith, "inbox"},
+		},
+	},
+	{
+		Name:     "mediator",
+		Category: Behavioral,
+		Summary:  "A concurrency-safe chat room routing messages between participants by name, and a checkout form whose widgets report to the dialog that holds every rule.",
+		Path:     "behavioral/mediator",
+		Level:    enum.LevelGood,
+		Pros:     []string{"components depend on the mediator alone and come and go without touching each other"},
+		Cons:     []string{"the mediator knows every component and rule and can grow into a god object"},
+		Relations: []Relation{
+			{AlternativeTo, "observer"},
+			{ComposesWith, "chat"},
+		},
+	},
+	{
+		Name:     "workflow",
+		Category: Architecture,
+		Summary:  "Workflows as graphs of idempotent steps checkpointed in a repository, resuming a crashed or failed run at the step that stopped it, with Graphviz export of a run's progress.",
+		Path:     "architecture/workflow",
+		Level:    enum.LevelGood,
+		Pros:     []string{"an interruption costs one step, and the checkpoint says where every run is and why"},
+		Cons:     []string{"steps must be idempotent and the state serializable; two writes per step"},
+		Relations: []Relation{
+			{ComposesWith, "repository"},
+			{ComposesWith, "job-queue"},
+			{ComposesWith, "write-ahead-log"},
+		},
+	},
+	{
+		Name:     "null-object",
+		Category: Structural,
+		Summary:  "NopLogger and NopMetrics as defaults instead of nil checks, the typed-nil-in-interface trap and its fixes, and a benchmark of nil checks against null-object calls.",
+		Path:     "structural/nullobject",
+		Level:    enum.LevelGood,
+		Pros:     []string{"code using an optional collaborator has no branches to forget"},
+		Cons:     []string{"arguments are still built and boxed for a call that discards them"},
+		Relations: []Relation{
+			{ComposesWith, "functional-options"},
+			{ComposesWith, "zero-value"},
+		},
+	},
+	{
+		Name:     "policy",
+		Category: Behavioral,
+		Summary:  "Ordered condition-to-effect rules with first-match precedence, default deny, obligations and a decision trace, enforced on handlers as middleware.",
+		Path:     "behavioral/policy",
+		Level:    enum.LevelGood,
+		Pros:     []string{"every access decision is in one ordered list, testable without the handlers"},
+		Cons:     []string{"a rule's meaning depends on the rules above it"},
+		Relations: []Relation{
+			{ComposesWith, "middleware"},
+			{AlternativeTo, "chain-of-responsibility"},
+			{ComposesWith, "strategy"},
+		},
+	},
+	{
+		Name:     "worker-pool",
+		Category: Concurrency,
+		Summary:  "A generic Pool[In, Out] with bounded workers and queue, graceful drain on Close, immediate stop on cancel, an ordered Map, and benchmarks against a goroutine per input.",
+		Path:     "concurrency/workerpool",
+		Level:    enum.LevelGood,
+		Pros:     []string{"concurrency and the memory it holds are bounded; a full queue pushes back on the producer"},
+		Cons:     []string{"results must be read until closed; two channel handoffs per input"},
+		Relations: []Relation{
+			{AlternativeTo, "ordered-consumers"},
+			{ComposesWith, "graceful-shutdown"},
+			{ComposesWith, "job-queue"},
+		},
+	},
+	{
+		Name:     "rbac",
+		Category: Architecture,
+		Summary:  "Roles granting permissions and inheriting other roles, from static or repository-backed providers, enforced deny-by-default on HTTP handlers and command-bus commands with a typed ForbiddenError.",
+		Path:     "security/rbac",
+		Level:    enum.LevelGood,
+		Pros:     []string{"code asks for permissions, not roles; unknown subjects, roles and undeclared commands are refused"},
+		Cons:     []string{"roles cannot express conditions on the resource, such as ownership"},
+		Relations: []Relation{
+			{AlternativeTo, "policy"},
+			{ComposesWith, "middleware"},
+			{ComposesWith, "marker-interface"},
+			{ComposesWith, "repository"},
+		},
+	},
+	{
+		Name:     "pipeline",
+		Category: Concurrency,
+		Summary:  "Generic channel stages composed with Then, fanned out over workers and merged back, where each stage owns and closes its output and cancellation or the first error stops every goroutine.",
+		Path:     "concurrency/pipeline",
+		Level:    enum.LevelGood,
+		Pros:     []string{"stages are small and concurrent; Wait returning means no goroutine is left"},
+		Cons:     []string{"a channel handoff per value per stage; Parallel loses input order"},
+		Relations: []Relation{
+			{ComposesWith, "worker-pool"},
+			{ComposesWith, "iterator"},
+		},
+	},
+	{
+		Name:     "secret",
+		Category: Architecture,
+		Summary:  "A Secret[T] wrapper that formats, marshals, logs and dumps as [REDACTED] under every fmt verb and encoder, with the value reachable only through an explicit Reveal at the use site.",
+		Path:     "security/secret",
+		Level:    enum.LevelGood,
+		Pros:     []string{"redaction is a property of the type, not of each log line; Reveal calls are easy to audit"},
+		Cons:     []string{"a revealed value is a plain T again; memory is not wiped"},
+		Relations: []Relation{
+			{ComposesWith, "config-dump"},
+			{ComposesWith, "funcopts"},
+			{Refines, "newtype"},
+		},
+	},
+	{
+		Name:     "request-validation",
+		Category: Architecture,
+		Summary:  "Typed handler requests sanitized and validated from struct tags and Validate methods before the endpoint runs, nested structs and slices included, answered 422 with every field path at once.",
+		Path:     "web/validation",
+		Level:    enum.LevelGood,
+		Pros:     []string{"rules sit beside their fields; tag typos fail at startup; endpoints only see valid input"},
+		Cons:     []string{"string tags and per-request reflection; zero values pass unless required"},
+		Relations: []Relation{
+			{ComposesWith, "handler-adapter"},
+			{ComposesWith, "validate"},
+			{ComposesWith, "middleware"},
+		},
+	},
+	{
+		Name:     "structured-concurrency",
+		Category: Concurrency,
+		Summary:  "A home-grown errgroup, built next to the naive WaitGroup fan-out it replaces: goroutines scoped to Wait, the first error kept and cancelling the siblings, SetLimit bounding how many run, panics raised again in the owner.",
+		Path:     "concurrency/structured",
+		Level:    enum.LevelGood,
+		Pros:     []string{"nothing outlives Wait; one failure stops the rest; bounded without a pool"},
+		Cons:     []string{"only the first error survives; cancellation relies on functions watching the context"},
+		Relations: []Relation{
+			{AlternativeTo, "worker-pool"},
+			{ComposesWith, "pipeline"},
+		},
+	},
+	{
+		Name:     "retry",
+		Category: Resilience,
+		Summary:  "Do re-runs an operation on retryable errors with pluggable backoff and jitter, Permanent and classifier-based give-up, on an injectable clock and random source.",
+		Path:     "resilience/retry",
+		Level:    enum.LevelGood,
+		Pros:     []string{"transient failures absorbed in one place; jitter spreads retry waves; deterministic under clock.Fake"},
+		Cons:     []string{"adds load to a failing dependency; safe only for idempotent operations"},
+		Relations: []Relation{
+			{ComposesWith, "funcopts"},
+			{ComposesWith, "clock"},
+			{ComposesWith, "injectable-rand"},
+			{ComposesWith, "client-options"},
+		},
+	},
+	{
+		Name:     "token-refresh",
+		Category: Resilience,
+		Summary:  "A single-writer credential cache that refreshes ahead of expiry in the background, shares one fetch among all waiting callers, backs off failed refreshes with jitter and invalidates a refused token only once.",
+		Path:     "security/tokenrefresh",
+		Level:    enum.LevelGood,
+		Pros:     []string{"callers never wait while the token is valid; no stampede on the issuer; deterministic under clock.Fake"},
+		Cons:     []string{"tokens are refreshed slightly early; an expired token with a failing issuer fails callers until the backoff passes"},
+		Relations: []Relation{
+			{ComposesWith, "secret"},
+			{ComposesWith, "retry"},
+			{ComposesWith, "clock"},
+			{AlternativeTo, "cache-aside"},
+		},
+	},
+	{
+		Name:     "circuit-breaker",
+		Category: Resilience,
+		Summary:  "A closed/open/half-open breaker with a consecutive-failure threshold, cooldown and probe count, two-phase Allow/done calls whose results only count in the generation they were let through, on an injectable clock.",
+		Path:     "resilience/circuitbreaker",
+		Level:    enum.LevelGood,
+		Pros:     []string{"fail fast against a dead dependency; one trial call at a time while it recovers; stale results cannot flip the state"},
+		Cons:     []string{"healthy calls are refused for a cooldown after a burst of failures; thresholds need tuning per dependency"},
+		Relations: []Relation{
+			{ComposesWith, "retry"},
+			{ComposesWith, "job-queue"},
+			{ComposesWith, "clock"},
+		},
+	},
+	{
+		Name:     "tri-state-field",
+		Category: Structural,
+		Summary:  "A Field[T] that tells unset from explicitly null from set (zero included), decoding JSON absent/null apart, merging RFC 7396 patches and storing as a nullable SQL column.",
+		Path:     "types/field",
+		Level:    enum.LevelGood,
+		Pros:     []string{"zero, absent and cleared are separate values; no pointers to locals in config literals"},
+		Cons:     []string{"every use unwraps; encoding/json before Go 1.24 writes unset fields as null"},
+		Relations: []Relation{
+			{AlternativeTo, "config-struct"},
+			{Refines, "zero-value"},
+		},
+	},
+	{
+		Name:     "leaky-bucket",
+		Category: Resilience,
+		Summary:  "A limiter that lets events out one per interval, refusing or queueing (with a wait) anything sooner, so no burst passes.",
+		Path:     "resilience/ratelimit",
+		Level:    enum.LevelGood,
+		Pros:     []string{"perfectly smooth output; Take turns refusal into a bounded wait"},
+		Cons:     []string{"no burst allowance, even after a long idle spell"},
+		Relations: []Relation{
+			{AlternativeTo, "token-bucket"},
+		},
+	},
+	{
+		Name:     "sliding-window",
+		Category: Resilience,
+		Summary:  "Window rate limits without the fixed window's boundary burst: an exact log of recent admissions, or two counters weighted by overlap.",
+		Path:     "resilience/ratelimit",
+		Level:    enum.LevelGood,
+		Pros:     []string{"the log is exact per window; the counter is constant memory at any limit"},
+		Cons:     []string{"the log's memory grows with the limit; the counter can admit up to twice the limit when traffic bunches at a window's end"},
+		Relations: []Relation{
+			{AlternativeTo, "token-bucket"},
+			{AlternativeTo, "leaky-bucket"},
+		},
+	},
+	{
+		Name:     "resilience-policy",
+		Category: Resilience,
+		Summary:  "Timeout, retry, circuit breaker and fallback declared outermost first as one Policy[T], with the order validated when built and the semantics of each order documented.",
+		Path:     "resilience/policy",
+		Level:    enum.LevelGood,
+		Pros:     []string{"the whole stack is one checked value; orders that cannot work are refused"},
+		Cons:     []string{"timeouts are cooperative: an operation that ignores its context is not cut"},
+		Relations: []Relation{
+			{ComposesWith, "retry"},
+			{ComposesWith, "circuit-breaker"},
+			{Refines, "decorator"},
+		},
+	},
+	{
+		Name:     "semaphore",
+		Category: Concurrency,
+		Summary:  "Bounded concurrency with a buffered-channel semaphore, a FIFO weighted semaphore, and ForEachLimit, which acquires before starting each goroutine so at most limit exist.",
+		Path:     "concurrency/semaphore",
+		Level:    enum.LevelGood,
+		Pros:     []string{"bounds work without keeping goroutines around; weights bound quantities, not counts"},
+		Cons:     []string{"acquired inside the goroutine it bounds the work but not the goroutines"},
+		Relations: []Relation{
+			{AlternativeTo, "worker-pool"},
+			{ComposesWith, "structured-concurrency"},
+		},
+	},
+	{
+		Name:     "fault-injection",
+		Category: Resilience,
+		Summary:  "Seeded latency, error, short read/write, truncated body and clock-skew faults wrapped around repositories, readers, writers, transports and clocks, with scenarios replayed twice to prove the seed reproduces them.",
+		Path:     "testing/chaos",
+		Level:    enum.LevelGood,
+		Pros:     []string{"failure paths run on every check; a seed replays the same faults; the code under test is unchanged"},
+		Cons:     []string{"faults are drawn in call order, so concurrent callers make a run unreproducible"},
+		Relations: []Relation{
+			{ComposesWith, "retry"},
+			{ComposesWith, "circuit-breaker"},
+			{ComposesWith, "injectable-rand"},
+			{ComposesWith, "decorator"},
+		},
+	},
+	{
+		Name:     "actor",
+		Category: Concurrency,
+		Summary:  "State confined to one goroutine that handles typed request messages with reply channels, beside the same accounts behind a mutex, benchmarked alone and under contention.",
+		Path:     "concurrency/actor",
+		Level:    enum.LevelGood,
+		Pros:     []string{"one owner: no locks to take or order, each message atomic, the loop may block on I/O"},
+		Cons:     []string{"~20x a mutex per call; a goroutine to start and stop; a slow message delays all callers"},
+		Relations: []Relation{
+			{ComposesWith, "command"},
+			{ComposesWith, "sum-type"},
+		},
+	},
+	{
+		Name:     "deterministic-scheduler",
+		Category: Concurrency,
+		Summary:  "Tasks run one at a time, switching only at explicit Yield and Wait points, under a seeded, replayed or exhaustive choice of who runs next, so ordering bugs are found by Stress or Exhaust and replayed from their schedule.",
+		Path:     "testing/sched",
+		Level:    enum.LevelGood,
+		Pros:     []string{"an interleaving is a reproducible value; yield points are no-ops in production"},
+		Cons:     []string{"explores only the marked points; code under test must block through Wait, not mutexes or channels"},
+		Relations: []Relation{
+			{ComposesWith, "injectable-rand"},
+			{ComposesWith, "fault-injection"},
+		},
+	},
+	{
+		Name:     "pub-sub",
+		Category: Behavioral,
+		Summary:  "An in-memory topic bus whose subscriptions pull from buffered channels, each with its own block, drop-newest or drop-oldest policy, and close behind their buffer on Unsubscribe.",
+		Path:     "messaging/pubsub",
+		Level:    enum.LevelGood,
+		Pros:     []string{"per-subscriber choice between slowing the source and missing values; consumers select and range over channels"},
+		Cons:     []string{"in memory only; under blocking one stuck subscriber stalls its topic"},
+		Relations: []Relation{
+			{Refines, "observer"},
+			{ComposesWith, "message-envelope"},
+		},
+	},
+	{
+		Name:     "run-group",
+		Category: Concurrency,
+		Summary:  "Components such as a server and its worker pool run together and stop together, in reverse order and under one deadline, when a signal arrives or any one fails.",
+		Path:     "lifecycle/shutdown",
+		Level:    enum.LevelGood,
+		Pros:     []string{"one place decides when the process stops and the order its parts drain", "a failing component stops the rest"},
+		Cons:     []string{"every component must stop when asked; the order of Add is the dependency order, unchecked"},
+		Relations: []Relation{
+			{Refines, "graceful-shutdown"},
+			{ComposesWith, "worker-pool"},
+			{AlternativeTo, "structured-concurrency"},
+		},
+	},
+	{
+		Name:     "cache-line-padding",
+		Category: Concurrency,
+		Summary:  "Counters written from many cores padded to a cache line each against packed ones that falsely share a line, with a struct layout report and field ordering that halves a record's size.",
+		Path:     "perf/falsesharing",
+		Level:    enum.LevelGood,
+		Pros:     []string{"writers on different cores stop contending for one line", "reordering fields by alignment removes padding for free"},
+		Cons:     []string{"eight times the memory for each padded counter", "the cache line size differs between processors"},
+		Relations: []Relation{
+			{ComposesWith, "sharding"},
+		},
+	},
+	{
+		Name:     "context-usage",
+		Category: Concurrency,
+		Summary:  "Typed context keys against colliding string keys, request facts in the context and dependencies as parameters, cancellation passed down every layer, and background work detached with WithoutCancel.",
+		Path:     "idioms/ctxpatterns",
+		Level:    enum.LevelGood,
+		Pros:     []string{"no package can overwrite another's values", "a caller giving up stops every layer below it"},
+		Cons:     []string{"a key type and accessors per value; every blocking call must take and watch a ctx"},
+		Relations: []Relation{
+			{ComposesWith, "request-scope"},
+			{ComposesWith, "graceful-shutdown"},
+		},
+	},
+	{
+		Name:     "generics-vs-interfaces",
+		Category: Behavioral,
+		Summary:  "The same fold and stack with interface values and with type parameters: generics remove boxing and stand in for basic types, but a method on a type argument is as indirect as an interface call.",
+		Path:     "bench/genericsvsiface",
+		Level:    enum.LevelGood,
+		Pros:     []string{"use type parameters for containers and for operators on basic types: no boxing, inlined like hand-written code"},
+		Cons:     []string{"for choosing behaviour a type parameter costs what an interface does, GC shapes share one body through a dictionary; prefer the interface there"},
+		Relations: []Relation{
+			{ComposesWith, "strategy"},
+		},
+	},
+	{
+		Name:     "error-taxonomy",
+		Category: Behavioral,
+		Summary:  "Sentinels with errors.Is, error types with errors.As and wrapping with %w against == and %v, and a NotFound/Invalid/Internal Error kind mapped to HTTP by a small service.",
+		Path:     "errors/errs",
+		Level:    enum.LevelGood,
+		Pros:     []string{"callers branch on a few kinds; the cause stays in the chain for logs and errors.As", "internal causes never reach the client"},
+		Cons:     []string{"every layer must classify what it returns; errors.Is still sees a kind an outer layer reclassified"},
+		Relations: []Relation{
+			{AlternativeTo, "typed-error-union"},
+		},
+	},
+	{
+		Name:     "result-type",
+		Category: Behavioral,
+		Summary:  "Generic Result[T] and Optional[T] with Map/AndThen/OrElse beside the same task written with (T, error) and comma-ok, for judging chains against Go's error handling.",
+		Path:     "functional/result",
+		Level:    enum.LevelPoor,
+		Pros:     []string{"outcomes become values that slices and channels can hold", "no if err != nil in a chain"},
+		Cons:     []string{"no generic methods, so chains read inside out", "errors gain no context unless each step wraps them", "every Go API boundary converts"},
+		Relations: []Relation{
+			{AlternativeTo, "error-taxonomy"},
+			{ComposesWith, "worker-pool"},
+		},
+	},
+	{
+		Name:     "canonical-text-form",
+		Category: Behavioral,
+		Summary:  "One domain type implementing Stringer, TextMarshaler, json.Marshaler and slog.LogValuer from a single canonical form, so fmt, JSON, flags and logs agree and round-trip.",
+		Path:     "idioms/formatting",
+		Level:    enum.LevelGood,
+		Pros:     []string{"whatever the value prints as parses back", "value receivers make every copy a Stringer"},
+		Cons:     []string{"five small methods per type; the form becomes API once stored"},
+		Relations: []Relation{
+			{ComposesWith, "secret"},
+		},
+	},
+	{
+		Name:     "constructor-injection",
+		Category: Architecture,
+		Summary:  "Dependencies as constructor parameters wired by hand in one composition root, with interfaces declared by their consumers, beside a package-level default and a service locator.",
+		Path:     "architecture/di",
+		Level:    enum.LevelGood,
+		Pros:     []string{"signatures list what a type needs", "the compiler checks the wiring", "fakes are as small as the consumer's interface"},
+		Cons:     []string{"the composition root grows with the app and is kept by hand"},
+		Relations: []Relation{
+			{ComposesWith, "repository"},
+			{ComposesWith, "clock"},
+			{AlternativeTo, "crud"},
+		},
+	},
+	{
+		Name:     "progressive-disclosure",
+		Category: Structural,
+		Summary:  "A one-call Do(ctx, url) built on New(opts...).Do(req) as one implementation, so the simple path is the configurable one with its defaults, beside a drifting parallel copy.",
+		Path:     "idioms/progressive",
+		Level:    enum.LevelGood,
+		Pros:     []string{"the common case is one call", "outgrowing it means adding one option, not switching implementations"},
+		Cons:     []string{"the simple layer's defaults become API for every caller who never chose them"},
+		Relations: []Relation{
+			{ComposesWith, "functional-options"},
+			{Refines, "client-options"},
+		},
+	},
+	{
+		Name:     "transaction-scoped-repository",
+		Category: Architecture,
+		Summary:  "WithTx(ctx, fn) hands fn a repository bound to one transaction, committed if fn returns nil and rolled back on error or panic, in memory and over database/sql.",
+		Path:     "persistence/repository",
+		Level:    enum.LevelGood,
+		Pros:     []string{"business code never sees a *sql.Tx", "the same unit of work runs on the in-memory store in tests"},
+		Cons:     []string{"calls on the outer repository inside fn escape the transaction"},
+		Relations: []Relation{
+			{Refines, "repository"},
+			{ComposesWith, "contract-tests"},
+		},
+	},
+	{
+		Name:     "tolerant-reader",
+		Category: Behavioral,
+		Summary:  "Version-tolerant JSON: a custom UnmarshalJSON accepting a renamed field, unknown fields kept as json.RawMessage and written back, and a strict mode via DisallowUnknownFields on a plain wire struct.",
+		Path:     "interop/jsonevolution",
+		Level:    enum.LevelGood,
+		Pros:     []string{"old payloads decode after a rename", "fields from newer writers survive read-modify-write"},
+		Cons:     []string{"custom marshalling per evolving type", "DisallowUnknownFields does not reach a custom UnmarshalJSON"},
+		Relations: []Relation{
+			{ComposesWith, "canonical-text-form"},
+			{ComposesWith, "api-evolution"},
+		},
+	},
+	{
+		Name:     "test-doubles",
+		Category: Behavioral,
+		Summary:  "One Mailer replaced by a hand-written fake, a function-field stub, a recording spy and an expectation mock, each checking the same service, with the mock alone breaking when the sends are reordered.",
+		Path:     "testing/doubles",
+		Level:    enum.LevelGood,
+		Pros:     []string{"no framework or generated code", "fakes verify outcomes and survive refactoring"},
+		Cons:     []string{"fakes can drift from the real implementation", "mocks pin the conversation, not the result"},
+		Relations: []Relation{
+			{ComposesWith, "contract-tests"},
+			{ComposesWith, "test-options"},
+			{ComposesWith, "constructor-injection"},
+		},
+	},
+	{
+		Name:     "event-time-windows",
+		Category: Concurrency,
+		Summary:  "Tumbling, sliding and session windows over an event channel with a bounded-lateness watermark, late-event side output, idle advancement on the clock and mergeable per-window aggregates.",
+		Path:     "streaming/windows",
+		Level:    enum.LevelGood,
+		Pros:     []string{"out-of-order events counted up to the allowed lateness", "deterministic under clock.Fake"},
+		Cons:     []string{"lateness delays every window by as much", "session aggregates must be mergeable"},
+		Relations: []Relation{
+			{ComposesWith, "clock"},
+			{ComposesWith, "pipeline"},
+		},
+	},
+	{
+		Name:     "event-sourcing",
+		Category: Architecture,
+		Summary:  "Accounts kept as event streams with optimistic appends, and read-model projections caught up concurrently from checkpoints and rebuilt from scratch by replaying the store.",
+		Path:     "architecture/eventsourcing",
+		Level:    enum.LevelGood,
+		Pros:     []string{"projections are disposable: fix one and replay", "a resumed rebuild applies each event once"},
+		Cons:     []string{"rebuilds read the whole log", "projections lag the log until caught up"},
+		Relations: []Relation{
+			{ComposesWith, "write-ahead-log"},
+			{ComposesWith, "structured-concurrency"},
+		},
+	},
+	{
+		Name:     "lazy-field",
+		Category: Creational,
+		Summary:  "A generic Lazy[T] and a retrying Retry[T] next to eager, mutex-guarded and sync.OnceValue fields, benchmarked under concurrent first access.",
+		Path:     "creational/lazy",
+		Level:    enum.LevelGood,
+		Pros:     []string{"a value never used is never built; the fast path is one atomic load"},
+		Cons:     []string{"the first caller pays the build, and a failing init needs Retry"},
+		Relations: []Relation{
+			{Refines, "singleton"},
+		},
+	},
+	{
+		Name:     "optional-interfaces",
+		Category: Structural,
+		Summary:  "A core io.Writer with capability interfaces detected by type assertion, and a counting decorator that keeps exactly the capabilities it wraps.",
+		Path:     "idioms/optionaliface",
+		Level:    enum.LevelGood,
+		Pros:     []string{"an interface grows without breaking implementations, and callers keep their fast paths"},
+		Cons:     []string{"every decorator must preserve every capability, 2^n types for n of them"},
+		Relations: []Relation{
+			{ComposesWith, "io-decorators"},
+			{Refines, "decorator"},
+		},
+	},
+	{
+		Name:     "layered-config",
+		Category: Creational,
+		Summary:  "Config loaded from defaults, a JSON file, environment variables, flags and overrides, each layer turned into the same functional options, with the source of every setting.",
+		Path:     "configpatterns",
+		Level:    enum.LevelGood,
+		Pros:     []string{"one parser and check per setting; precedence holds setting by setting; errors name their layer"},
+		Cons:     []string{"every setting is listed in a keys table besides the struct"},
+		Relations: []Relation{
+			{ComposesWith, "functional-options"},
+			{ComposesWith, "construct"},
+			{ComposesWith, "config-dump"},
+		},
+	},
+	{
+		Name:     "flyweight",
+		Category: Structural,
+		Summary:  "Immutable metric label sets interned in a concurrency-safe table, next to per-string interning with unique and plain copies, with heap per series measured.",
+		Path:     "structural/flyweight",
+		Level:    enum.LevelGood,
+		Pros:     []string{"a series costs a pointer; a hit allocates nothing"},
+		Cons:     []string{"the table never forgets, so unbounded label values grow it forever"},
+		Relations: []Relation{
+			{AlternativeTo, "object-pool"},
+		},
+	},
+	{
+		Name:     "error-hints",
+		Category: Behavioral,
+		Summary:  "Errors with registered, unique codes and remediation hints, rendered alike by the cli package and by an HTTP error mapper for web/handler.",
+		Path:     "errors/hints",
+		Level:    enum.LevelGood,
+		Pros:     []string{"codes to match on without parsing messages; the hint travels with the error to every surface"},
+		Cons:     []string{"every failure a person may see needs a definition, and hints go stale like comments"},
+		Relations: []Relation{
+			{ComposesWith, "error-taxonomy"},
+			{ComposesWith, "handler-adapter"},
+		},
+	},
+	{
+		Name:     "long-running-operation",
+		Category: Architecture,
+		Summary:  "Slow work starts with 202 Accepted and an operation ID; clients poll its state, fetch its result or cancel it, while the job queue runs and retries it.",
+		Path:     "architecture/lro",
+		Level:    enum.LevelGood,
+		Pros:     []string{"no request waits for the work; the operation outlives the connection and the process, and can be cancelled"},
+		Cons:     []string{"clients poll, operations need expiring, and the work must be idempotent since a job can run twice"},
+		Relations: []Relation{
+			{ComposesWith, "job-queue"},
+			{ComposesWith, "polling"},
+		},
+	},
+	{
+		Name:     "fuzz-friendly-parser",
+		Category: Behavioral,
+		Summary:  "A strict, pure, bounded parser and encoder pair with engine-neutral properties, native fuzz targets, a seed corpus, and a blind mutator and record generator that catch and shrink a naive parser's bugs.",
+		Path:     "testing/fuzzpatterns",
+		Level:    enum.LevelGood,
+		Pros:     []string{"a canonical format makes decode-then-encode checkable on any input", "properties run under go test -fuzz and anywhere else"},
+		Cons:     []string{"strictness rejects input a lenient reader would accept", "a blind mutator only finds shallow bugs"},
+		Relations: []Relation{
+			{ComposesWith, "injectable-rand"},
+			{ComposesWith, "contract-tests"},
+		},
+	},
+	{
+		Name:     "golden-file",
+		Category: Behavioral,
+		Summary:  "golden.Assert compares output with a checked-in testdata file and rewrites it under -update, shown on a table of configurations dumped in every format, with the diff, missing-file and line-ending behaviour checked.",
+		Path:     "testing/golden",
+		Level:    enum.LevelGood,
+		Pros:     []string{"the whole output is reviewed, and a change shows as a diff in the commit", "a new case is a table row and a run with -update"},
+		Cons:     []string{"-update accepts whatever the code prints; the diff review is the test", "output must be deterministic first"},
+		Relations: []Relation{
+			{ComposesWith, "config-dump"},
+			{ComposesWith, "test-options"},
+		},
+	},
+}
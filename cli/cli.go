@@ -0,0 +1,202 @@
+// Package cli dispatches subcommands with the standard library only.
+//
+// Each subcommand is a Command value (command pattern) with its own
+// flag.FlagSet. Entry points take args and writers instead of touching
+// os.Args and os.Stdout, so a whole invocation can be run and checked in
+// process:
+//
+//	code := app.Run([]string{"check", "-v"}, &stdout, &stderr)
+//
+// Global flags are accepted both before and after the subcommand name. A
+// local flag with the same name as a global shadows it for that
+// subcommand.
+//
+// A command that fails has its error printed, then the error's hint if it
+// has one, and exits 1 or the code the error chooses, as errors/hints
+// errors do.
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Env is what a command sees of the outside world.
+type Env struct {
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// Command is one subcommand.
+type Command struct {
+	Name string
+	// Args is the synopsis of the positional arguments, e.g. "[exercise]".
+	Args  string
+	Short string
+	// Flags registers local flags.
+	Flags func(fs *flag.FlagSet)
+	Run   func(env Env, args []string) error
+}
+
+// ErrUsage makes Run print the command usage and exit with 2.
+var ErrUsage = errors.New("usage")
+
+// ExitError carries a specific exit code without printing anything.
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string { return fmt.Sprintf("exit status %d", e.Code) }
+
+// App is a program made of subcommands.
+type App struct {
+	Name     string
+	Short    string
+	Globals  func(fs *flag.FlagSet)
+	Commands []*Command
+}
+
+// Main runs app with the process arguments and exits.
+func Main(run func(args []string, stdout, stderr io.Writer) int) {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// Run parses args and executes the selected command, returning the exit
+// code: 0 on success, 1 on error, 2 on bad usage.
+func (a *App) Run(args []string, stdout, stderr io.Writer) int {
+	env := Env{Stdout: stdout, Stderr: stderr}
+	global := flag.NewFlagSet(a.Name, flag.ContinueOnError)
+	global.SetOutput(stderr)
+	if a.Globals != nil {
+		a.Globals(global)
+	}
+	global.Usage = func() { a.usage(global) }
+	if err := global.Parse(args); err != nil {
+		return parseCode(err)
+	}
+
+	if global.NArg() == 0 {
+		a.usage(global)
+		return 2
+	}
+	name := global.Arg(0)
+	for _, c := range a.Commands {
+		if c.Name == name {
+			return execute(env, a.Name+" "+c.Name, c, global, global.Args()[1:])
+		}
+	}
+	fmt.Fprintf(stderr, "%s: unknown command %q\n", a.Name, name)
+	a.usage(global)
+	return 2
+}
+
+// Main executes c as a program of its own, without subcommand dispatch.
+func (c *Command) Main(args []string, stdout, stderr io.Writer) int {
+	return execute(Env{Stdout: stdout, Stderr: stderr}, c.Name, c, nil, args)
+}
+
+func execute(env Env, prog string, c *Command, global *flag.FlagSet, args []string) int {
+	fs := flag.NewFlagSet(prog, flag.ContinueOnError)
+	fs.SetOutput(env.Stderr)
+	if c.Flags != nil {
+		c.Flags(fs)
+	}
+	if global != nil {
+		// globals after the subcommand share the same Value; locals win
+		global.VisitAll(func(f *flag.Flag) {
+			if fs.Lookup(f.Name) == nil {
+				fs.Var(f.Value, f.Name, f.Usage)
+			}
+		})
+	}
+	fs.Usage = func() { commandUsage(fs, prog, c) }
+	if err := fs.Parse(args); err != nil {
+		return parseCode(err)
+	}
+
+	err := c.Run(env, fs.Args())
+	var exit *ExitError
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, ErrUsage):
+		fs.Usage()
+		return 2
+	case errors.As(err, &exit):
+		return exit.Code
+	}
+	fmt.Fprintf(env.Stderr, "%s: %v\n", prog, err)
+	var h hinter
+	if errors.As(err, &h) && h.Hint() != "" {
+		fmt.Fprintf(env.Stderr, "hint: %s\n", h.Hint())
+	}
+	var ec exitCoder
+	if errors.As(err, &ec) {
+		return ec.ExitCode()
+	}
+	return 1
+}
+
+// An error in the chain of a failed command with a Hint method has the
+// hint printed on the line after the error, and one with an ExitCode
+// method chooses the exit status; errors/hints has both.
+type (
+	hinter    interface{ Hint() string }
+	exitCoder interface{ ExitCode() int }
+)
+
+func parseCode(err error) int {
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	return 2
+}
+
+func (a *App) usage(global *flag.FlagSet) {
+	w := global.Output()
+	fmt.Fprintf(w, "usage: %s [flags] <command> [args]\n", a.Name)
+	if a.Short != "" {
+		fmt.Fprintf(w, "\n%s\n", a.Short)
+	}
+	fmt.Fprintln(w, "\ncommands:")
+	width := 0
+	for _, c := range a.Commands {
+		width = max(width, len(c.Name))
+	}
+	for _, c := range a.Commands {
+		fmt.Fprintf(w, "  %-*s  %s\n", width, c.Name, c.Short)
+	}
+	if hasFlags(global) {
+		fmt.Fprintln(w, "\nflags:")
+		global.PrintDefaults()
+	}
+}
+
+func commandUsage(fs *flag.FlagSet, prog string, c *Command) {
+	w := fs.Output()
+	fmt.Fprintf(w, "usage: %s", prog)
+	if hasFlags(fs) {
+		fmt.Fprint(w, " [flags]")
+	}
+	if c.Args != "" {
+		fmt.Fprint(w, " "+c.Args)
+	}
+	fmt.Fprintln(w)
+	if c.Short != "" {
+		fmt.Fprintf(w, "\n%s\n", strings.TrimSpace(c.Short))
+	}
+	if hasFlags(fs) {
+		fmt.Fprintln(w, "\nflags:")
+		fs.PrintDefaults()
+	}
+}
+
+func hasFlags(fs *flag.FlagSet) bool {
+	n := 0
+	fs.VisitAll(func(*flag.Flag) { n++ })
+	return n > 0
+}
@@ -0,0 +1,141 @@
+package cli_test
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"patterns/cli"
+	"patterns/testing/golden"
+)
+
+// hinted is an error with a hint and its own exit code, like the errors
+// of errors/hints.
+type hinted struct{ error }
+
+func (hinted) Hint() string  { return "try again later" }
+func (hinted) ExitCode() int { return 75 }
+
+// newApp returns a fresh app; flags bind to variables, so each run needs
+// its own.
+func newApp() *cli.App {
+	var verbose, upper bool
+	var region, local string
+	return &cli.App{
+		Name:  "svc",
+		Short: "A test program.",
+		Globals: func(fs *flag.FlagSet) {
+			fs.BoolVar(&verbose, "v", false, "verbose output")
+			fs.StringVar(&region, "region", "eu", "region to use")
+		},
+		Commands: []*cli.Command{
+			{
+				Name:  "echo",
+				Args:  "[words]",
+				Short: "print the words",
+				Flags: func(fs *flag.FlagSet) {
+					fs.BoolVar(&upper, "upper", false, "uppercase the words")
+				},
+				Run: func(env cli.Env, args []string) error {
+					if verbose {
+						fmt.Fprintf(env.Stderr, "echo: %d words in %s\n", len(args), region)
+					}
+					out := strings.Join(args, " ")
+					if upper {
+						out = strings.ToUpper(out)
+					}
+					fmt.Fprintln(env.Stdout, out)
+					return nil
+				},
+			},
+			{
+				Name:  "where",
+				Short: "print the region, with a local -region",
+				Flags: func(fs *flag.FlagSet) {
+					fs.StringVar(&local, "region", "local", "shadows the global")
+				},
+				Run: func(env cli.Env, args []string) error {
+					fmt.Fprintf(env.Stdout, "global %s, local %s\n", region, local)
+					return nil
+				},
+			},
+			{Name: "usage", Args: "<arg>", Short: "always misused", Run: func(cli.Env, []string) error { return cli.ErrUsage }},
+			{Name: "exit", Short: "exit 3 silently", Run: func(cli.Env, []string) error { return &cli.ExitError{Code: 3} }},
+			{Name: "fail", Short: "fail plainly", Run: func(cli.Env, []string) error { return errors.New("it broke") }},
+			{Name: "busy", Short: "fail with a hint", Run: func(cli.Env, []string) error {
+				return fmt.Errorf("connect: %w", hinted{errors.New("server busy")})
+			}},
+		},
+	}
+}
+
+func invoke(run func([]string, io.Writer, io.Writer) int, args ...string) []byte {
+	var stdout, stderr bytes.Buffer
+	code := run(args, &stdout, &stderr)
+	var b bytes.Buffer
+	b.Write(stdout.Bytes())
+	fmt.Fprintf(&b, "--- stderr\n%s--- exit %d\n", stderr.Bytes(), code)
+	return b.Bytes()
+}
+
+func TestAppRun(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		args []string
+	}{
+		{"no-command", nil},
+		{"help", []string{"-h"}},
+		{"unknown", []string{"nope"}},
+		{"bad-global", []string{"-nope", "echo"}},
+		{"echo", []string{"echo", "a", "b"}},
+		{"global-before", []string{"-v", "-region", "us", "echo", "a"}},
+		{"global-after", []string{"echo", "-v", "-region", "us", "a"}},
+		{"local-flag", []string{"echo", "-upper", "a"}},
+		{"local-shadows-global", []string{"-region", "us", "where", "-region", "x"}},
+		{"command-help", []string{"echo", "-h"}},
+		{"bad-local", []string{"echo", "-nope"}},
+		{"err-usage", []string{"usage"}},
+		{"exit-error", []string{"exit"}},
+		{"error", []string{"fail"}},
+		{"error-hint", []string{"busy"}},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			golden.Assert(t, "app/"+c.name, invoke(newApp().Run, c.args...))
+		})
+	}
+}
+
+func TestCommandMain(t *testing.T) {
+	newCmd := func() *cli.Command {
+		var n int
+		return &cli.Command{
+			Name:  "count",
+			Args:  "[words]",
+			Short: "count the words",
+			Flags: func(fs *flag.FlagSet) { fs.IntVar(&n, "n", 0, "added to the count") },
+			Run: func(env cli.Env, args []string) error {
+				if len(args) == 0 {
+					return cli.ErrUsage
+				}
+				fmt.Fprintln(env.Stdout, len(args)+n)
+				return nil
+			},
+		}
+	}
+	for _, c := range []struct {
+		name string
+		args []string
+	}{
+		{"words", []string{"-n", "10", "a", "b"}},
+		{"usage", nil},
+		{"help", []string{"-h"}},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			golden.Assert(t, "command/"+c.name, invoke(newCmd().Main, c.args...))
+		})
+	}
+}
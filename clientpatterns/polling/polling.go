@@ -0,0 +1,350 @@
+// Package polling keeps a client in step with a resource the server
+// cannot push: interval polling asks every Interval, long polling sends
+// the next request as soon as the last one returns and lets the server
+// hold it until something changes.
+//
+//	p, err := polling.New("https://config.internal/flags",
+//		polling.WithInterval(30*time.Second))
+//	err = p.Run(ctx, func(r polling.Response) error {
+//		return flags.Load(r.Body)
+//	})
+//
+// Requests are conditional either way: the last ETag and Last-Modified go
+// back as If-None-Match and If-Modified-Since, so an unchanged resource
+// costs a bodiless 304 and fn only sees changes. Transport errors, 5xx
+// and 429 back off exponentially with full jitter, never sooner than a
+// Retry-After the server sent, and the backoff resets on the next
+// success. Other statuses are the caller's mistake and stop Run.
+package polling
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"patterns/clock"
+	"patterns/construct"
+	"patterns/funcopts"
+	"patterns/randsource"
+)
+
+// Response is a changed representation of the resource.
+type Response struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+// StatusError is a response status that is neither a change, nor "not
+// modified", nor a retryable failure.
+type StatusError struct {
+	Code int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected status %d %s", e.Code, http.StatusText(e.Code))
+}
+
+var ErrTooLarge = errors.New("response body exceeds the limit")
+
+type options struct {
+	client     *http.Client
+	interval   time.Duration
+	longPoll   time.Duration
+	minBackoff time.Duration
+	maxBackoff time.Duration
+	maxBody    int64
+	onError    func(err error, wait time.Duration)
+	clock      clock.Clock
+	rand       randsource.Rand
+}
+
+type Option = funcopts.Option[options]
+
+func WithClient(c *http.Client) Option {
+	return func(options *options) error {
+		if c == nil {
+			return errors.New("client cannot be nil")
+		}
+		options.client = c
+		return nil
+	}
+}
+
+// WithInterval sets the pause between polls that did not fail; the
+// default is 10 seconds.
+func WithInterval(d time.Duration) Option {
+	return func(options *options) error {
+		if d <= 0 {
+			return errors.New("interval must be positive")
+		}
+		options.interval = d
+		return nil
+	}
+}
+
+// WithLongPoll switches to long polling: every request asks the server,
+// with Prefer: wait (RFC 7240), to hold it up to wait seconds, and the
+// next one is sent as soon as it returns. A "not modified" answer is
+// followed by the next request no sooner than Interval after it started,
+// so a server that ignores the preference gets interval polling rather
+// than a busy loop. A server that
+// times out answers 304 or 204. The request itself times out a little
+// after wait, so a server cannot hang the poller either.
+func WithLongPoll(wait time.Duration) Option {
+	return func(options *options) error {
+		if wait < time.Second {
+			return errors.New("long poll wait must be at least a second")
+		}
+		options.longPoll = wait
+		return nil
+	}
+}
+
+// WithBackoff sets the first and the largest wait after a failure; the
+// defaults are one second and five minutes.
+func WithBackoff(min, max time.Duration) Option {
+	return func(options *options) error {
+		if min <= 0 || max < min {
+			return errors.New("backoff must satisfy 0 < min <= max")
+		}
+		options.minBackoff = min
+		options.maxBackoff = max
+		return nil
+	}
+}
+
+// WithMaxBody sets the largest body read, in bytes; a larger one stops
+// Run with ErrTooLarge. The default is 10 MiB.
+func WithMaxBody(n int64) Option {
+	return func(options *options) error {
+		if n <= 0 {
+			return errors.New("max body must be positive")
+		}
+		options.maxBody = n
+		return nil
+	}
+}
+
+// WithOnError reports every retryable failure with the wait before the
+// next attempt, for logs and metrics.
+func WithOnError(fn func(err error, wait time.Duration)) Option {
+	return func(options *options) error {
+		if fn == nil {
+			return errors.New("error callback cannot be nil")
+		}
+		options.onError = fn
+		return nil
+	}
+}
+
+func WithClock(c clock.Clock) Option {
+	return func(options *options) error {
+		if c == nil {
+			return errors.New("clock cannot be nil")
+		}
+		options.clock = c
+		return nil
+	}
+}
+
+func WithRand(r randsource.Rand) Option {
+	return func(options *options) error {
+		if r == nil {
+			return errors.New("rand cannot be nil")
+		}
+		options.rand = r
+		return nil
+	}
+}
+
+func (o *options) SetDefaults() {
+	o.client = http.DefaultClient
+	o.interval = 10 * time.Second
+	o.minBackoff = time.Second
+	o.maxBackoff = 5 * time.Minute
+	o.maxBody = 10 << 20
+	o.onError = func(error, time.Duration) {}
+	o.clock = clock.Real
+	o.rand = randsource.Global
+}
+
+// longPollSlack is how much longer than the requested wait a long poll
+// may take before it counts as failed.
+const longPollSlack = 5 * time.Second
+
+// polling pattern
+// Level: Good
+// pros: works through every proxy and needs nothing from the server but
+// validators; conditional requests make the idle case nearly free, and
+// jittered backoff keeps a fleet of clients from hammering a recovering
+// server in lockstep.
+// cons: interval polling trades latency for load, and long polling ties
+// up a server connection per client; neither is a substitute for a push
+// channel (see web/streaming) when the server can offer one.
+type Poller struct {
+	url     string
+	options options
+
+	etag         string
+	lastModified string
+	failures     int
+}
+
+func New(rawURL string, opts ...Option) (*Poller, error) {
+	if _, err := url.ParseRequestURI(rawURL); err != nil {
+		return nil, err
+	}
+	options, err := construct.New(opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &Poller{url: rawURL, options: *options}, nil
+}
+
+// Run polls until ctx is done, calling fn with every changed response.
+// It returns ctx.Err(), or the first error from fn, a StatusError for a
+// non-retryable status, or ErrTooLarge. Run must not be called
+// concurrently.
+func (p *Poller) Run(ctx context.Context, fn func(Response) error) error {
+	for {
+		wait, err := p.poll(ctx, fn)
+		if err != nil {
+			return err
+		}
+		if err := p.sleep(ctx, wait); err != nil {
+			return err
+		}
+	}
+}
+
+// poll makes one request and returns how long to wait before the next.
+func (p *Poller) poll(ctx context.Context, fn func(Response) error) (time.Duration, error) {
+	start := p.options.clock.Now()
+	resp, err := p.fetch(ctx)
+	if ctx.Err() != nil {
+		return 0, ctx.Err()
+	}
+	if errors.Is(err, ErrTooLarge) {
+		return 0, err
+	}
+	if err != nil {
+		return p.fail(err, 0), nil
+	}
+
+	switch code := resp.Status; {
+	case code == http.StatusNotModified || code == http.StatusNoContent:
+		p.failures = 0
+		return p.idle(start, false), nil
+	case code >= 200 && code < 300:
+		p.failures = 0
+		if etag := resp.Header.Get("ETag"); etag != "" {
+			p.etag = etag
+		}
+		if lm := resp.Header.Get("Last-Modified"); lm != "" {
+			p.lastModified = lm
+		}
+		if err := fn(resp); err != nil {
+			return 0, err
+		}
+		return p.idle(start, true), nil
+	case code == http.StatusTooManyRequests || code >= 500:
+		return p.fail(&StatusError{Code: code}, retryAfter(resp.Header, p.options.clock.Now())), nil
+	default:
+		return 0, &StatusError{Code: code}
+	}
+}
+
+func (p *Poller) fetch(ctx context.Context) (Response, error) {
+	if p.options.longPoll > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.options.longPoll+longPollSlack)
+		defer cancel()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.url, nil)
+	if err != nil {
+		return Response{}, err
+	}
+	if p.etag != "" {
+		req.Header.Set("If-None-Match", p.etag)
+	}
+	if p.lastModified != "" {
+		req.Header.Set("If-Modified-Since", p.lastModified)
+	}
+	if p.options.longPoll > 0 {
+		req.Header.Set("Prefer", "wait="+strconv.Itoa(int(p.options.longPoll/time.Second)))
+	}
+
+	resp, err := p.options.client.Do(req)
+	if err != nil {
+		return Response{}, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, p.options.maxBody+1))
+	if err != nil {
+		return Response{}, err
+	}
+	if int64(len(body)) > p.options.maxBody {
+		return Response{}, ErrTooLarge
+	}
+	return Response{Status: resp.StatusCode, Header: resp.Header, Body: body}, nil
+}
+
+// idle is the wait after a poll started at start that did not fail.
+func (p *Poller) idle(start time.Time, changed bool) time.Duration {
+	if p.options.longPoll > 0 {
+		if changed {
+			return 0
+		}
+		return p.options.interval - p.options.clock.Since(start)
+	}
+	return p.options.interval
+}
+
+// fail records a failure and returns the backoff: full jitter over an
+// exponentially growing window, but never less than the server's hint.
+func (p *Poller) fail(err error, hint time.Duration) time.Duration {
+	p.failures++
+	window := p.options.minBackoff << min(p.failures-1, 30)
+	if window <= 0 || window > p.options.maxBackoff {
+		window = p.options.maxBackoff
+	}
+	wait := time.Duration(p.options.rand.Int64N(int64(window)) + 1)
+	wait = max(wait, hint)
+	p.options.onError(err, wait)
+	return wait
+}
+
+func (p *Poller) sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := p.options.clock.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C():
+		return nil
+	}
+}
+
+// retryAfter parses a Retry-After header, in seconds or as an HTTP date.
+func retryAfter(h http.Header, now time.Time) time.Duration {
+	v := h.Get("Retry-After")
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return max(t.Sub(now), 0)
+	}
+	return 0
+}
@@ -0,0 +1,347 @@
+package polling_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"patterns/clientpatterns/polling"
+	"patterns/clock"
+	"patterns/randsource"
+)
+
+var epoch = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// step is one scripted answer.
+type step struct {
+	status int
+	header map[string]string
+	body   string
+	// retryIn sends Retry-After as the HTTP date this far ahead.
+	retryIn time.Duration
+	// hold advances the clock before answering, as a long poll would.
+	hold time.Duration
+	// drop closes the connection without answering.
+	drop bool
+}
+
+// script answers requests with its steps in order and holds any request
+// past the last until the client gives up on it.
+type script struct {
+	steps []step
+	clk   *clock.Fake
+
+	mu   sync.Mutex
+	reqs []http.Header
+}
+
+func (s *script) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	i := len(s.reqs)
+	s.reqs = append(s.reqs, r.Header.Clone())
+	s.mu.Unlock()
+	if i >= len(s.steps) {
+		<-r.Context().Done()
+		return
+	}
+	st := s.steps[i]
+	if st.drop {
+		c, _, _ := http.NewResponseController(w).Hijack()
+		c.Close()
+		return
+	}
+	s.clk.Advance(st.hold)
+	for k, v := range st.header {
+		w.Header().Set(k, v)
+	}
+	if st.retryIn > 0 {
+		w.Header().Set("Retry-After", s.clk.Now().Add(st.retryIn).Format(http.TimeFormat))
+	}
+	w.WriteHeader(st.status)
+	fmt.Fprint(w, st.body)
+}
+
+// waits reports every timer the poller starts, so a test sees each wait
+// and then ends it.
+type waits struct {
+	*clock.Fake
+	c chan time.Duration
+}
+
+func (w waits) NewTimer(d time.Duration) clock.Timer {
+	t := w.Fake.NewTimer(d)
+	w.c <- d
+	return t
+}
+
+type outcome struct {
+	err    error
+	bodies []string
+	waits  []time.Duration
+	errs   []error
+	reqs   []http.Header
+}
+
+// poll runs a poller against steps until Run returns, ending every wait
+// but the nth, which it cancels instead; n 0 leaves the end to Run.
+func poll(t *testing.T, steps []step, n int, opts ...polling.Option) outcome {
+	t.Helper()
+	s := &script{steps: steps, clk: clock.NewFake(epoch)}
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+
+	var o outcome
+	w := waits{s.clk, make(chan time.Duration)}
+	p, err := polling.New(srv.URL, append([]polling.Option{
+		polling.WithClient(&http.Client{Transport: &http.Transport{DisableKeepAlives: true}}),
+		polling.WithClock(w),
+		polling.WithOnError(func(err error, _ time.Duration) { o.errs = append(o.errs, err) }),
+	}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		done <- p.Run(ctx, func(r polling.Response) error {
+			if string(r.Body) == "fail" {
+				return errors.New("rejected")
+			}
+			o.bodies = append(o.bodies, string(r.Body))
+			return nil
+		})
+	}()
+	for o.err == nil {
+		select {
+		case d := <-w.c:
+			o.waits = append(o.waits, d)
+			if len(o.waits) == n {
+				cancel()
+			} else {
+				s.clk.Advance(d)
+			}
+		case o.err = <-done:
+		}
+	}
+	s.mu.Lock()
+	o.reqs = s.reqs
+	s.mu.Unlock()
+	return o
+}
+
+// TestConditional checks that the validators of the last change go back
+// on every request and that only changes reach fn.
+func TestConditional(t *testing.T) {
+	lm := epoch.Format(http.TimeFormat)
+	o := poll(t, []step{
+		{status: 200, header: map[string]string{"ETag": `"v1"`, "Last-Modified": lm}, body: "a"},
+		{status: 304},
+		{status: 200, header: map[string]string{"ETag": `"v2"`}, body: "b"},
+		{status: 204},
+	}, 4, polling.WithInterval(time.Minute))
+
+	if !errors.Is(o.err, context.Canceled) {
+		t.Errorf("Run = %v, want context.Canceled", o.err)
+	}
+	if !slices.Equal(o.bodies, []string{"a", "b"}) {
+		t.Errorf("fn saw %q, want the two changes", o.bodies)
+	}
+	if want := slices.Repeat([]time.Duration{time.Minute}, 4); !slices.Equal(o.waits, want) {
+		t.Errorf("waits %v, want %v", o.waits, want)
+	}
+	for i, want := range [][2]string{{"", ""}, {`"v1"`, lm}, {`"v1"`, lm}, {`"v2"`, lm}} {
+		h := o.reqs[i]
+		if got := [2]string{h.Get("If-None-Match"), h.Get("If-Modified-Since")}; got != want {
+			t.Errorf("request %d: validators %q, want %q", i, got, want)
+		}
+		if h.Get("Prefer") != "" {
+			t.Errorf("request %d: Prefer %q on an interval poll", i, h.Get("Prefer"))
+		}
+	}
+}
+
+// top always draws the largest value, so every backoff is its whole
+// window.
+type top struct{ randsource.Rand }
+
+func (top) Int64N(n int64) int64 { return n - 1 }
+
+// TestBackoff checks that retryable failures back off exponentially up
+// to the maximum, never sooner than Retry-After, and that a success
+// resets the backoff.
+func TestBackoff(t *testing.T) {
+	o := poll(t, []step{
+		{status: 500},
+		{status: 503, header: map[string]string{"Retry-After": "120"}},
+		{status: 429, retryIn: 90 * time.Second},
+		{drop: true},
+		{status: 502, header: map[string]string{"Retry-After": "soon"}},
+		{status: 200, body: "ok"},
+		{status: 500},
+	}, 7, polling.WithInterval(30*time.Second), polling.WithBackoff(time.Second, 10*time.Second), polling.WithRand(top{}))
+
+	want := []time.Duration{time.Second, 120 * time.Second, 90 * time.Second, 8 * time.Second, 10 * time.Second, 30 * time.Second, time.Second}
+	if !slices.Equal(o.waits, want) {
+		t.Errorf("waits %v, want %v", o.waits, want)
+	}
+	var codes []int
+	for _, err := range o.errs {
+		var se *polling.StatusError
+		if errors.As(err, &se) {
+			codes = append(codes, se.Code)
+		} else {
+			codes = append(codes, 0)
+		}
+	}
+	if !slices.Equal(codes, []int{500, 503, 429, 0, 502, 500}) {
+		t.Errorf("reported %v, want the five failures and the one after the success (0: transport)", o.errs)
+	}
+	if !slices.Equal(o.bodies, []string{"ok"}) {
+		t.Errorf("fn saw %q", o.bodies)
+	}
+}
+
+// TestJitter checks that each backoff is drawn from (0, window], not
+// always the window itself.
+func TestJitter(t *testing.T) {
+	steps := slices.Repeat([]step{{status: 500}}, 12)
+	o := poll(t, steps, len(steps), polling.WithBackoff(time.Second, time.Minute), polling.WithRand(randsource.New(1)))
+	if len(o.waits) != len(steps) {
+		t.Fatalf("waits %v, want %d", o.waits, len(steps))
+	}
+	under := 0
+	for i, d := range o.waits {
+		window := min(time.Second<<i, time.Minute)
+		if d <= 0 || d > window {
+			t.Errorf("wait %d = %v, want in (0, %v]", i, d, window)
+		}
+		if d < window/2 {
+			under++
+		}
+	}
+	if under == 0 {
+		t.Errorf("waits %v: none under half the window", o.waits)
+	}
+}
+
+// TestLongPoll checks that a change is followed by the next request at
+// once and "not modified" by one no sooner than Interval after the last
+// started, however long the server held it.
+func TestLongPoll(t *testing.T) {
+	o := poll(t, []step{
+		{status: 200, header: map[string]string{"ETag": `"v1"`}, body: "a"},
+		{status: 304},
+		{status: 304, hold: 25 * time.Second},
+		{status: 200, body: "b", hold: 30 * time.Second},
+		{status: 204, hold: 4 * time.Second},
+	}, 2, polling.WithLongPoll(30*time.Second), polling.WithInterval(10*time.Second))
+
+	if want := []time.Duration{10 * time.Second, 6 * time.Second}; !slices.Equal(o.waits, want) {
+		t.Errorf("waits %v, want %v", o.waits, want)
+	}
+	if !slices.Equal(o.bodies, []string{"a", "b"}) {
+		t.Errorf("fn saw %q", o.bodies)
+	}
+	if len(o.reqs) != 5 {
+		t.Fatalf("%d requests, want 5", len(o.reqs))
+	}
+	for i, h := range o.reqs {
+		if h.Get("Prefer") != "wait=30" {
+			t.Errorf("request %d: Prefer %q, want wait=30", i, h.Get("Prefer"))
+		}
+	}
+	if o.reqs[1].Get("If-None-Match") != `"v1"` {
+		t.Errorf("long poll sent If-None-Match %q", o.reqs[1].Get("If-None-Match"))
+	}
+}
+
+// TestStop checks what ends Run without a cancel.
+func TestStop(t *testing.T) {
+	for _, c := range []struct {
+		name  string
+		steps []step
+		opts  []polling.Option
+		want  func(error) bool
+	}{
+		{"client error", []step{{status: 200, body: "a"}, {status: 404}},
+			nil, func(err error) bool {
+				var se *polling.StatusError
+				return errors.As(err, &se) && se.Code == 404 && err.Error() == "unexpected status 404 Not Found"
+			}},
+		{"fn error", []step{{status: 200, body: "fail"}}, nil,
+			func(err error) bool { return err != nil && err.Error() == "rejected" }},
+		{"too large", []step{{status: 200, body: "hello"}}, []polling.Option{polling.WithMaxBody(4)},
+			func(err error) bool { return errors.Is(err, polling.ErrTooLarge) }},
+		{"at the limit", []step{{status: 200, body: "hell"}, {status: 410}}, []polling.Option{polling.WithMaxBody(4)},
+			func(err error) bool { var se *polling.StatusError; return errors.As(err, &se) && se.Code == 410 }},
+	} {
+		o := poll(t, c.steps, 0, c.opts...)
+		if !c.want(o.err) {
+			t.Errorf("%s: Run = %v", c.name, o.err)
+		}
+		if len(o.errs) > 0 {
+			t.Errorf("%s: reported %v as retryable", c.name, o.errs)
+		}
+	}
+}
+
+// TestCancelHeld checks that a cancel ends a long poll the server is
+// holding, without reporting it as a failure.
+func TestCancelHeld(t *testing.T) {
+	s := &script{clk: clock.NewFake(epoch)}
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+	failed := false
+	p, err := polling.New(srv.URL, polling.WithLongPoll(time.Minute), polling.WithClock(s.clk),
+		polling.WithOnError(func(error, time.Duration) { failed = true }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- p.Run(ctx, func(polling.Response) error { return nil }) }()
+	for {
+		s.mu.Lock()
+		held := len(s.reqs)
+		s.mu.Unlock()
+		if held > 0 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) || failed {
+		t.Errorf("Run = %v, reported a failure: %v", err, failed)
+	}
+}
+
+func TestNew(t *testing.T) {
+	for _, c := range []struct {
+		url  string
+		opts []polling.Option
+		want string
+	}{
+		{"not a url", nil, `parse "not a url": invalid URI for request`},
+		{"http://x", []polling.Option{polling.WithInterval(0)}, "interval must be positive"},
+		{"http://x", []polling.Option{polling.WithLongPoll(time.Millisecond)}, "long poll wait must be at least a second"},
+		{"http://x", []polling.Option{polling.WithBackoff(time.Minute, time.Second)}, "backoff must satisfy 0 < min <= max"},
+		{"http://x", []polling.Option{polling.WithBackoff(0, time.Second)}, "backoff must satisfy 0 < min <= max"},
+		{"http://x", []polling.Option{polling.WithMaxBody(0)}, "max body must be positive"},
+		{"http://x", []polling.Option{polling.WithClient(nil)}, "client cannot be nil"},
+		{"http://x", []polling.Option{polling.WithOnError(nil)}, "error callback cannot be nil"},
+		{"http://x", []polling.Option{polling.WithClock(nil)}, "clock cannot be nil"},
+		{"http://x", []polling.Option{polling.WithRand(nil)}, "rand cannot be nil"},
+	} {
+		if p, err := polling.New(c.url, c.opts...); p != nil || err == nil || err.Error() != c.want {
+			t.Errorf("New(%q) = %v, %v; want %q", c.url, p, err, c.want)
+		}
+	}
+}
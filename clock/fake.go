@@ -0,0 +1,202 @@
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Fake is a Clock that only moves on Advance. Timers, tickers and
+// sleepers registered with it fire, in deadline order, as Advance passes
+// their deadlines. Safe for concurrent use.
+type Fake struct {
+	mu      sync.Mutex
+	changed *sync.Cond
+	now     time.Time
+	waiters []*fakeWaiter
+}
+
+// NewFake returns a fake clock reading start.
+func NewFake(start time.Time) *Fake {
+	f := &Fake{now: start}
+	f.changed = sync.NewCond(&f.mu)
+	return f
+}
+
+type fakeWaiter struct {
+	f      *Fake
+	when   time.Time
+	period time.Duration // zero for timers and sleepers
+	ch     chan time.Time
+}
+
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+func (f *Fake) Since(t time.Time) time.Duration { return f.Now().Sub(t) }
+
+// Sleep blocks until Advance moves the clock d past the current time.
+func (f *Fake) Sleep(d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	<-f.NewTimer(d).C()
+}
+
+func (f *Fake) NewTimer(d time.Duration) Timer {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	w := &fakeWaiter{f: f, ch: make(chan time.Time, 1)}
+	f.schedule(w, d)
+	return (*fakeTimer)(w)
+}
+
+func (f *Fake) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("clock: non-positive interval for NewTicker")
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	w := &fakeWaiter{f: f, period: d, ch: make(chan time.Time, 1)}
+	f.schedule(w, d)
+	return (*fakeTicker)(w)
+}
+
+// schedule arms w to fire d from now; a non-positive d fires at once.
+// f.mu must be held.
+func (f *Fake) schedule(w *fakeWaiter, d time.Duration) {
+	w.when = f.now.Add(d)
+	if d <= 0 {
+		w.fire(f.now)
+		if w.period == 0 {
+			return
+		}
+		w.when = f.now.Add(w.period)
+	}
+	f.waiters = append(f.waiters, w)
+	f.changed.Broadcast()
+}
+
+// unschedule stops w for Stop and Reset and reports whether it was
+// pending. As with time since Go 1.23, a fire nobody has received yet is
+// discarded and counts as pending, so C holds no stale value afterwards.
+// f.mu must be held.
+func (f *Fake) unschedule(w *fakeWaiter) bool {
+	pending := f.remove(w)
+	select {
+	case <-w.ch:
+		pending = true
+	default:
+	}
+	return pending
+}
+
+// remove drops w from the pending waiters and reports whether it was
+// there. f.mu must be held.
+func (f *Fake) remove(w *fakeWaiter) bool {
+	for i, x := range f.waiters {
+		if x == w {
+			f.waiters = append(f.waiters[:i], f.waiters[i+1:]...)
+			f.changed.Broadcast()
+			return true
+		}
+	}
+	return false
+}
+
+// fire delivers t without blocking; like time.Timer, a tick nobody has
+// received yet is dropped rather than queued.
+func (w *fakeWaiter) fire(t time.Time) {
+	select {
+	case w.ch <- t:
+	default:
+	}
+}
+
+// Advance moves the clock forward by d, firing every deadline passed on
+// the way at its own time, so a ticker advanced by three periods ticks
+// three times (receivers see at most one buffered tick, as with time).
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	target := f.now.Add(d)
+	for {
+		var next *fakeWaiter
+		for _, w := range f.waiters {
+			if !w.when.After(target) && (next == nil || w.when.Before(next.when)) {
+				next = w
+			}
+		}
+		if next == nil {
+			break
+		}
+		f.now = next.when
+		next.fire(f.now)
+		if next.period > 0 {
+			next.when = next.when.Add(next.period)
+		} else {
+			f.remove(next)
+		}
+	}
+	f.now = target
+	f.changed.Broadcast()
+}
+
+// Waiters reports how many timers, tickers and sleepers are pending.
+func (f *Fake) Waiters() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.waiters)
+}
+
+// BlockUntil waits until at least n timers, tickers or sleepers are
+// pending, i.e. until the code under test has reached its wait. Advance
+// after BlockUntil is then guaranteed to wake it.
+func (f *Fake) BlockUntil(n int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for len(f.waiters) < n {
+		f.changed.Wait()
+	}
+}
+
+type fakeTimer fakeWaiter
+
+func (t *fakeTimer) C() <-chan time.Time { return t.ch }
+
+func (t *fakeTimer) Stop() bool {
+	t.f.mu.Lock()
+	defer t.f.mu.Unlock()
+	return t.f.unschedule((*fakeWaiter)(t))
+}
+
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	t.f.mu.Lock()
+	defer t.f.mu.Unlock()
+	active := t.f.unschedule((*fakeWaiter)(t))
+	t.f.schedule((*fakeWaiter)(t), d)
+	return active
+}
+
+type fakeTicker fakeWaiter
+
+func (t *fakeTicker) C() <-chan time.Time { return t.ch }
+
+func (t *fakeTicker) Stop() {
+	t.f.mu.Lock()
+	defer t.f.mu.Unlock()
+	t.f.unschedule((*fakeWaiter)(t))
+}
+
+func (t *fakeTicker) Reset(d time.Duration) {
+	if d <= 0 {
+		panic("clock: non-positive interval for Ticker.Reset")
+	}
+	t.f.mu.Lock()
+	defer t.f.mu.Unlock()
+	t.f.unschedule((*fakeWaiter)(t))
+	t.period = d
+	t.f.schedule((*fakeWaiter)(t), d)
+}
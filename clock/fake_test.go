@@ -0,0 +1,272 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+var start = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// received returns the value waiting in c, if any, without blocking.
+func received(c <-chan time.Time) (time.Time, bool) {
+	select {
+	case t := <-c:
+		return t, true
+	default:
+		return time.Time{}, false
+	}
+}
+
+func TestFakeNow(t *testing.T) {
+	f := NewFake(start)
+	if !f.Now().Equal(start) {
+		t.Fatalf("Now = %v, want %v", f.Now(), start)
+	}
+	f.Advance(time.Minute)
+	if got := f.Since(start); got != time.Minute {
+		t.Errorf("Since = %v, want 1m", got)
+	}
+}
+
+func TestFakeTimer(t *testing.T) {
+	f := NewFake(start)
+	tm := f.NewTimer(time.Second)
+	f.Advance(999 * time.Millisecond)
+	if _, ok := received(tm.C()); ok {
+		t.Fatal("fired before its deadline")
+	}
+	f.Advance(time.Hour)
+	got, ok := received(tm.C())
+	if !ok || !got.Equal(start.Add(time.Second)) {
+		t.Fatalf("fired %v, %t; want at the deadline %v", got, ok, start.Add(time.Second))
+	}
+	if f.Waiters() != 0 {
+		t.Errorf("Waiters = %d after the timer fired", f.Waiters())
+	}
+	if tm.Stop() {
+		t.Error("Stop of a fired and received timer = true")
+	}
+}
+
+func TestFakeTimerZero(t *testing.T) {
+	f := NewFake(start)
+	tm := f.NewTimer(0)
+	if _, ok := received(tm.C()); !ok {
+		t.Error("NewTimer(0) did not fire at once")
+	}
+	if f.Waiters() != 0 {
+		t.Errorf("Waiters = %d", f.Waiters())
+	}
+}
+
+func TestFakeTimerStop(t *testing.T) {
+	f := NewFake(start)
+	tm := f.NewTimer(time.Second)
+	if !tm.Stop() {
+		t.Error("Stop of a pending timer = false")
+	}
+	f.Advance(time.Hour)
+	if _, ok := received(tm.C()); ok {
+		t.Error("a stopped timer fired")
+	}
+}
+
+// TestTimerNoStaleValue is the Go 1.23 guarantee: after Stop or Reset,
+// C does not hold a value from before. It runs on the real clock too, so
+// the fake is held to what time itself does.
+func TestTimerNoStaleValue(t *testing.T) {
+	for _, c := range []struct {
+		name    string
+		clock   Clock
+		advance func(time.Duration)
+	}{
+		{"real", Real, func(d time.Duration) { time.Sleep(d + 50*time.Millisecond) }},
+		{"fake", NewFake(start), nil},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			advance := c.advance
+			if advance == nil {
+				advance = c.clock.(*Fake).Advance
+			}
+
+			tm := c.clock.NewTimer(time.Millisecond)
+			advance(time.Millisecond)
+			// fired, not received: Stop still prevents the delivery
+			if !tm.Stop() {
+				t.Error("Stop of a fired, unreceived timer = false")
+			}
+			if _, ok := received(tm.C()); ok {
+				t.Error("C holds a value after Stop")
+			}
+
+			tm = c.clock.NewTimer(time.Millisecond)
+			advance(time.Millisecond)
+			if !tm.Reset(time.Hour) {
+				t.Error("Reset of a fired, unreceived timer = false")
+			}
+			if _, ok := received(tm.C()); ok {
+				t.Error("C holds a value after Reset")
+			}
+			tm.Stop()
+		})
+	}
+}
+
+func TestFakeTimerReset(t *testing.T) {
+	f := NewFake(start)
+	tm := f.NewTimer(time.Second)
+	f.Advance(500 * time.Millisecond)
+	if !tm.Reset(time.Second) {
+		t.Error("Reset of a pending timer = false")
+	}
+	f.Advance(999 * time.Millisecond)
+	if _, ok := received(tm.C()); ok {
+		t.Fatal("fired at the old deadline")
+	}
+	f.Advance(time.Millisecond)
+	if got, ok := received(tm.C()); !ok || !got.Equal(start.Add(1500*time.Millisecond)) {
+		t.Fatalf("fired %v, %t; want at the new deadline", got, ok)
+	}
+	// a fired timer can be reused
+	if tm.Reset(time.Second) {
+		t.Error("Reset of a received timer = true")
+	}
+	f.Advance(time.Second)
+	if _, ok := received(tm.C()); !ok {
+		t.Error("reused timer did not fire")
+	}
+}
+
+func TestFakeTicker(t *testing.T) {
+	f := NewFake(start)
+	tk := f.NewTicker(time.Second)
+	f.Advance(time.Second)
+	if got, ok := received(tk.C()); !ok || !got.Equal(start.Add(time.Second)) {
+		t.Fatalf("tick %v, %t", got, ok)
+	}
+
+	// three periods tick three times, but only one tick is buffered
+	f.Advance(3 * time.Second)
+	if got, ok := received(tk.C()); !ok || !got.Equal(start.Add(2*time.Second)) {
+		t.Errorf("buffered tick %v, %t; want the first one missed", got, ok)
+	}
+	if _, ok := received(tk.C()); ok {
+		t.Error("more than one tick buffered")
+	}
+
+	tk.Reset(time.Minute)
+	f.Advance(59 * time.Second)
+	if _, ok := received(tk.C()); ok {
+		t.Error("ticked on the old period after Reset")
+	}
+	f.Advance(time.Second)
+	if _, ok := received(tk.C()); !ok {
+		t.Error("no tick on the new period")
+	}
+
+	f.Advance(time.Minute)
+	tk.Stop()
+	if _, ok := received(tk.C()); ok {
+		t.Error("C holds a tick after Stop")
+	}
+	f.Advance(time.Hour)
+	if _, ok := received(tk.C()); ok || f.Waiters() != 0 {
+		t.Error("a stopped ticker ticked")
+	}
+}
+
+func TestFakeTickerPanics(t *testing.T) {
+	for name, f := range map[string]func(){
+		"NewTicker": func() { NewFake(start).NewTicker(0) },
+		"Reset":     func() { NewFake(start).NewTicker(time.Second).Reset(-1) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s with a non-positive interval did not panic", name)
+				}
+			}()
+			f()
+		}()
+	}
+}
+
+// TestFakeAdvanceOrder checks deadlines fire in order, each at its own
+// time, whatever order they were created in.
+func TestFakeAdvanceOrder(t *testing.T) {
+	f := NewFake(start)
+	late, early := f.NewTimer(3*time.Second), f.NewTimer(time.Second)
+	tk := f.NewTicker(2 * time.Second)
+	f.Advance(3 * time.Second)
+	for _, c := range []struct {
+		name string
+		c    <-chan time.Time
+		want time.Duration
+	}{
+		{"early", early.C(), time.Second},
+		{"ticker", tk.C(), 2 * time.Second},
+		{"late", late.C(), 3 * time.Second},
+	} {
+		if got, ok := received(c.c); !ok || !got.Equal(start.Add(c.want)) {
+			t.Errorf("%s fired %v, %t; want %v", c.name, got, ok, start.Add(c.want))
+		}
+	}
+	if !f.Now().Equal(start.Add(3 * time.Second)) {
+		t.Errorf("Now = %v", f.Now())
+	}
+}
+
+func TestFakeSleep(t *testing.T) {
+	f := NewFake(start)
+	done := make(chan struct{})
+	go func() {
+		f.Sleep(time.Second)
+		close(done)
+	}()
+	f.BlockUntil(1)
+	f.Advance(999 * time.Millisecond)
+	select {
+	case <-done:
+		t.Fatal("Sleep returned early")
+	case <-time.After(10 * time.Millisecond):
+	}
+	f.Advance(time.Millisecond)
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Sleep did not return")
+	}
+
+	f.Sleep(0) // returns at once
+}
+
+func TestFakeBlockUntil(t *testing.T) {
+	f := NewFake(start)
+	reached := make(chan struct{})
+	go func() {
+		f.BlockUntil(2)
+		close(reached)
+	}()
+	f.NewTimer(time.Second)
+	select {
+	case <-reached:
+		t.Fatal("BlockUntil(2) returned with one waiter")
+	case <-time.After(10 * time.Millisecond):
+	}
+	f.NewTicker(time.Second)
+	select {
+	case <-reached:
+	case <-time.After(5 * time.Second):
+		t.Fatal("BlockUntil(2) did not return")
+	}
+}
+
+func TestOr(t *testing.T) {
+	if Or(nil) != Real {
+		t.Error("Or(nil) is not Real")
+	}
+	f := NewFake(start)
+	if Or(f) != f {
+		t.Error("Or(f) is not f")
+	}
+}
@@ -0,0 +1,44 @@
+// Command apidiff reports API changes between two versions of a package,
+// and exits 1 if any would break callers.
+//
+// usage:
+//
+//	go run patterns/cmd/apidiff old/dir new/dir
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"patterns/cli"
+	"patterns/idioms/apievolution/apidiff"
+)
+
+func main() {
+	cli.Main(run)
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	cmd := &cli.Command{
+		Name:  "apidiff",
+		Args:  "old new",
+		Short: "report API changes between two package directories",
+		Run: func(env cli.Env, args []string) error {
+			if len(args) != 2 {
+				return cli.ErrUsage
+			}
+			changes, err := apidiff.DiffDirs(args[0], args[1])
+			if err != nil {
+				return err
+			}
+			for _, c := range changes {
+				fmt.Fprintln(env.Stdout, c)
+			}
+			if len(apidiff.Breaking(changes)) > 0 {
+				return &cli.ExitError{Code: 1}
+			}
+			return nil
+		},
+	}
+	return cmd.Main(args, stdout, stderr)
+}
@@ -0,0 +1,422 @@
+// Command benchgate runs the benchmarks of every package with go test
+// -bench and compares them with a committed baseline, failing when one
+// got slower or allocates more: a regression gate for CI.
+//
+// usage:
+//
+//	go run patterns/cmd/benchgate [-baseline file] [-run regexp] [-count n]
+//		[-benchtime d] [-time frac] [-allocs frac] [-alpha p] [-against file]
+//	go run patterns/cmd/benchgate -update [-baseline file] [-count n] [-benchtime d]
+//
+// The baseline is go test -bench output, every benchmark -count times,
+// so benchstat reads it too. Its header records the -count and -benchtime
+// it was measured with, which a comparison reuses unless told otherwise.
+// A benchmark is named by its import path and go test name without the
+// GOMAXPROCS suffix, patterns/bench/dispatch.BenchmarkSwitch/size=10, so
+// -run matches those names and a baseline taken on a machine with another
+// number of CPUs still lines up.
+//
+// Timings are noisy, so one slower run proves nothing. Like benchstat,
+// benchgate compares medians and asks the Mann-Whitney U test whether the
+// two sets of runs differ at all: ns/op regresses when the median is more
+// than -time slower and p < -alpha. Allocations are nearly deterministic,
+// so allocs/op regresses when its median grows by at least one and by
+// more than -allocs. With the default -count of 5 the smallest p the test
+// can give is 0.008, so the gate never fails on fewer than four runs a
+// side. A benchmark that regresses is measured again before the gate
+// fails, and judged on the second set of runs: on a shared CI machine a
+// neighbour's burst of work can slow one benchmark's every run, and it
+// seldom lasts long enough to slow the next ones too.
+//
+// Refresh the baseline on the machine CI runs on, after a change that is
+// meant to move the numbers:
+//
+//	go run patterns/cmd/benchgate -update
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"patterns/cli"
+)
+
+// packages are the packages whose benchmarks the gate runs.
+const packages = "./..."
+
+func main() {
+	cli.Main(run)
+}
+
+// gate is what counts as a regression.
+type gate struct {
+	time, allocs, alpha float64
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	var (
+		baseline, pattern, against string
+		benchtime                  time.Duration
+		count                      int
+		update                     bool
+		g                          gate
+	)
+	cmd := &cli.Command{
+		Name:  "benchgate",
+		Short: "fail when benchmarks regress against the baseline",
+		Flags: func(fs *flag.FlagSet) {
+			fs.StringVar(&baseline, "baseline", "bench/baseline.txt", "baseline `file`")
+			fs.StringVar(&pattern, "run", "", "only gate benchmarks whose import path and name match `regexp`")
+			fs.StringVar(&against, "against", "", "compare results read from `file` instead of running the benchmarks")
+			fs.IntVar(&count, "count", 0, "run each benchmark `n` times (default: the baseline's, or 5)")
+			fs.DurationVar(&benchtime, "benchtime", 0, "run each benchmark for `d` (default: the baseline's, or 1s)")
+			fs.BoolVar(&update, "update", false, "write the baseline instead of comparing with it")
+			fs.Float64Var(&g.time, "time", 0.2, "ns/op regression threshold, as a `fraction` of the baseline")
+			fs.Float64Var(&g.allocs, "allocs", 0, "allocs/op regression threshold, as a `fraction` of the baseline")
+			fs.Float64Var(&g.alpha, "alpha", 0.05, "significance `level` of a timing change")
+		},
+		Run: func(env cli.Env, args []string) error {
+			if len(args) > 0 {
+				return cli.ErrUsage
+			}
+			var filter *regexp.Regexp
+			if pattern != "" {
+				var err error
+				if filter, err = regexp.Compile(pattern); err != nil {
+					return err
+				}
+			}
+			if update {
+				return write(env, baseline, filter, orDefault(count, 5), orDefault(benchtime, time.Second))
+			}
+			base, err := readResults(baseline)
+			if err != nil {
+				return err
+			}
+			var cur *results
+			if against != "" {
+				if cur, err = readResults(against); err != nil {
+					return err
+				}
+			} else {
+				count = orDefault(count, configInt(base, "count", 5))
+				benchtime = orDefault(benchtime, configDuration(base, "benchtime", time.Second))
+				if cur, err = measure(env.Stderr, filter, []target{{packages, "."}}, count, benchtime); err != nil {
+					return err
+				}
+				if names := suspects(base, cur, filter, g); len(names) > 0 {
+					if err := remeasure(env.Stderr, cur, names, count, benchtime); err != nil {
+						return err
+					}
+				}
+			}
+			return compare(env.Stdout, base, cur, filter, g)
+		},
+	}
+	return cmd.Main(args, stdout, stderr)
+}
+
+// target is a go test -bench run: the package pattern and the -bench
+// regexp.
+type target struct {
+	pkg, bench string
+}
+
+// measure runs the benchmarks of targets matching filter count times.
+func measure(progress io.Writer, filter *regexp.Regexp, targets []target, count int, benchtime time.Duration) (*results, error) {
+	var buf bytes.Buffer
+	if err := rounds(&buf, progress, filter, targets, count, benchtime); err != nil {
+		return nil, err
+	}
+	r, err := parseResults(&buf)
+	if err != nil {
+		return nil, fmt.Errorf("go test output: %w", err)
+	}
+	if len(r.names) == 0 {
+		return nil, errors.New("no benchmarks match")
+	}
+	return r, nil
+}
+
+// rounds runs the benchmarks of targets count times, in rounds, so that
+// drift in the machine's speed spreads over all of them instead of
+// landing on the last few, and writes the results matching filter to w.
+func rounds(w, progress io.Writer, filter *regexp.Regexp, targets []target, count int, benchtime time.Duration) error {
+	for i := range count {
+		fmt.Fprintf(progress, "round %d of %d\n", i+1, count)
+		for _, t := range targets {
+			out, err := goTest(t, benchtime)
+			if err != nil {
+				return err
+			}
+			if _, err := w.Write(benchLines(out, filter)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// goTest runs the benchmarks of t once each, with allocations reported,
+// and returns what go test printed.
+func goTest(t target, benchtime time.Duration) ([]byte, error) {
+	cmd := exec.Command("go", "test", "-run", "^$", "-bench", t.bench, "-benchmem",
+		"-count", "1", "-benchtime", benchtime.String(), t.pkg)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("go test -bench %s %s: %v\n%s%s", t.bench, t.pkg, err, stderr.Bytes(), failures(out))
+	}
+	return out, nil
+}
+
+// configLine splits a "key: value" configuration line of go test output;
+// the lines benchmarks log are indented, so their keys have spaces.
+func configLine(line string) (key, value string, ok bool) {
+	key, value, ok = strings.Cut(strings.TrimSuffix(line, "\n"), ": ")
+	return key, value, ok && key != "" && !strings.ContainsAny(key, " \t")
+}
+
+// failures drops the lines of go test output that report a package
+// passing, having no tests, or its benchmarks, leaving what went wrong.
+func failures(out []byte) []byte {
+	var b bytes.Buffer
+	for _, line := range strings.SplitAfter(string(out), "\n") {
+		if _, _, ok := configLine(line); ok || line == "PASS\n" || strings.HasPrefix(line, "ok  \t") ||
+			strings.HasPrefix(line, "?   \t") || strings.HasPrefix(line, "Benchmark") {
+			continue
+		}
+		b.WriteString(line)
+	}
+	return b.Bytes()
+}
+
+// benchLines keeps the lines of go test output that parseResults reads:
+// the results of the benchmarks matching filter, after the configuration
+// lines of their package. PASS, ok and the indented lines benchmarks log
+// are dropped, and so is the configuration of a package with no results
+// left: only the last line of each key is kept until a result is.
+func benchLines(out []byte, filter *regexp.Regexp) []byte {
+	var b bytes.Buffer
+	var keys []string
+	config := map[string]string{}
+	pkg := ""
+	for _, line := range strings.SplitAfter(string(out), "\n") {
+		if key, value, ok := configLine(line); ok {
+			if key == "pkg" {
+				pkg = value
+			}
+			if _, ok := config[key]; !ok {
+				keys = append(keys, key)
+			}
+			config[key] = line
+			continue
+		}
+		name, _, ok := strings.Cut(line, "\t")
+		if !ok || !strings.HasPrefix(name, "Benchmark") {
+			continue
+		}
+		if filter == nil || filter.MatchString(benchName(pkg, strings.TrimSpace(name))) {
+			for _, k := range keys {
+				b.WriteString(config[k])
+			}
+			keys, config = nil, map[string]string{}
+			b.WriteString(line)
+		}
+	}
+	return b.Bytes()
+}
+
+func write(env cli.Env, path string, filter *regexp.Regexp, count int, benchtime time.Duration) error {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "count: %d\nbenchtime: %s\n", count, benchtime)
+	if err := rounds(&buf, env.Stderr, filter, []target{{packages, "."}}, count, benchtime); err != nil {
+		return err
+	}
+	r, err := parseResults(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		return fmt.Errorf("go test output: %w", err)
+	}
+	if len(r.names) == 0 {
+		return errors.New("no benchmarks match")
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		return err
+	}
+	fmt.Fprintf(env.Stdout, "wrote %d benchmarks × %d to %s\n", len(r.names), count, path)
+	return nil
+}
+
+// suspects returns the benchmarks of base matching filter that regressed
+// in cur.
+func suspects(base, cur *results, filter *regexp.Regexp, g gate) []string {
+	var names []string
+	for _, name := range base.names {
+		if now, ok := cur.byName[name]; ok && (filter == nil || filter.MatchString(name)) {
+			if g.judge(base.byName[name], now).regressed() {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// remeasure runs names again and replaces their results in cur. Each is
+// run on its own, in its package, with a -bench regexp anchoring every
+// level of its name.
+func remeasure(progress io.Writer, cur *results, names []string, count int, benchtime time.Duration) error {
+	targets := make([]target, len(names))
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		pkg, bench := splitName(name)
+		levels := strings.Split(bench, "/")
+		for j, l := range levels {
+			levels[j] = "^" + regexp.QuoteMeta(l) + "$"
+		}
+		targets[i] = target{pkg, strings.Join(levels, "/")}
+		quoted[i] = regexp.QuoteMeta(name)
+	}
+	fmt.Fprintf(progress, "measuring %d suspected regressions again\n", len(names))
+	again, err := measure(progress, regexp.MustCompile("^("+strings.Join(quoted, "|")+")$"), targets, count, benchtime)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		cur.byName[name] = again.byName[name]
+	}
+	return nil
+}
+
+// verdict is the comparison of one benchmark.
+type verdict struct {
+	oldT, newT, delta, p float64
+	oldA, newA           float64
+	slower, allocs       bool
+}
+
+func (v verdict) regressed() bool { return v.slower || v.allocs }
+
+func (g gate) judge(old, now *samples) verdict {
+	v := verdict{
+		oldT: median(old.ns), newT: median(now.ns),
+		p:    mannWhitney(old.ns, now.ns),
+		oldA: median(old.allocs), newA: median(now.allocs),
+	}
+	v.delta = v.newT/v.oldT - 1
+	v.slower = v.delta > g.time && v.p < g.alpha
+	v.allocs = v.newA >= v.oldA+1 && v.newA > v.oldA*(1+g.allocs)
+	return v
+}
+
+// compare prints a benchstat-like table of the benchmarks in both base
+// and cur and fails if any regressed.
+func compare(w io.Writer, base, cur *results, filter *regexp.Regexp, g gate) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "name\told ns/op\tnew ns/op\tdelta\tp\told allocs\tnew allocs\t\t")
+	var regressed, missing []string
+	for _, name := range base.names {
+		if filter != nil && !filter.MatchString(name) {
+			continue
+		}
+		old := base.byName[name]
+		now, ok := cur.byName[name]
+		if !ok {
+			missing = append(missing, name)
+			continue
+		}
+		v := g.judge(old, now)
+		delta := "~"
+		if v.p < g.alpha {
+			delta = fmt.Sprintf("%+.1f%%", 100*v.delta)
+		}
+		var why []string
+		if v.slower {
+			why = append(why, "slower")
+		}
+		if v.allocs {
+			why = append(why, "allocates more")
+		}
+		mark := ""
+		if len(why) > 0 {
+			regressed = append(regressed, name)
+			mark = "REGRESSED: " + strings.Join(why, ", ")
+		}
+		fmt.Fprintf(tw, "%s\t%s ±%.0f%%\t%s ±%.0f%%\t%s\t%.3f\t%s\t%s\t\t%s\n",
+			name, ns(v.oldT), 100*spread(old.ns), ns(v.newT), 100*spread(now.ns), delta, v.p,
+			strconv.FormatFloat(v.oldA, 'f', -1, 64), strconv.FormatFloat(v.newA, 'f', -1, 64), mark)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	for _, name := range missing {
+		fmt.Fprintf(w, "%s: in the baseline but not measured\n", name)
+	}
+	for _, name := range cur.names {
+		if _, ok := base.byName[name]; !ok && (filter == nil || filter.MatchString(name)) {
+			fmt.Fprintf(w, "%s: not in the baseline; run with -update to add it\n", name)
+		}
+	}
+	if len(regressed) > 0 {
+		return fmt.Errorf("%d benchmarks regressed: %v", len(regressed), regressed)
+	}
+	return nil
+}
+
+// ns formats a duration in nanoseconds with three significant digits.
+func ns(v float64) string {
+	switch {
+	case v >= 1e9:
+		return fmt.Sprintf("%.3gs", v/1e9)
+	case v >= 1e6:
+		return fmt.Sprintf("%.3gms", v/1e6)
+	case v >= 1e3:
+		return fmt.Sprintf("%.3gµs", v/1e3)
+	}
+	return fmt.Sprintf("%.3gns", v)
+}
+
+func readResults(path string) (*results, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r, err := parseResults(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return r, nil
+}
+
+func configInt(r *results, key string, def int) int {
+	if n, err := strconv.Atoi(r.config[key]); err == nil && n > 0 {
+		return n
+	}
+	return def
+}
+
+func configDuration(r *results, key string, def time.Duration) time.Duration {
+	if d, err := time.ParseDuration(r.config[key]); err == nil && d > 0 {
+		return d
+	}
+	return def
+}
+
+func orDefault[T comparable](v, def T) T {
+	var zero T
+	if v == zero {
+		return def
+	}
+	return v
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"patterns/testing/golden"
+)
+
+// runs returns n timings of about ns, alternating above and below it, and
+// n of allocs.
+func runs(n int, ns, allocs float64) *samples {
+	s := &samples{}
+	for i := range n {
+		s.ns = append(s.ns, ns+float64(i%2*2-1)*float64(i)/100*ns/10)
+		s.bytes = append(s.bytes, 0)
+		s.allocs = append(s.allocs, allocs)
+	}
+	return s
+}
+
+func TestJudge(t *testing.T) {
+	defaults := gate{time: 0.2, allocs: 0, alpha: 0.05}
+	for _, c := range []struct {
+		name          string
+		g             gate
+		old, now      *samples
+		slower, alloc bool
+	}{
+		{"same", defaults, runs(5, 100, 1), runs(5, 100, 1), false, false},
+		{"slower", defaults, runs(5, 100, 1), runs(5, 130, 1), true, false},
+		{"faster", defaults, runs(5, 130, 1), runs(5, 100, 1), false, false},
+		{"under the threshold", defaults, runs(5, 100, 1), runs(5, 115, 1), false, false},
+		{"looser threshold", gate{time: 0.5, alpha: 0.05}, runs(5, 100, 1), runs(5, 130, 1), false, false},
+		// three runs a side cannot reach p < 0.05, four can
+		{"three runs", defaults, runs(3, 100, 1), runs(3, 200, 1), false, false},
+		{"four runs", defaults, runs(4, 100, 1), runs(4, 200, 1), true, false},
+		{"stricter alpha", gate{time: 0.2, alpha: 0.01}, runs(4, 100, 1), runs(4, 200, 1), false, false},
+		// one more allocation is a regression, from none too
+		{"allocs", defaults, runs(5, 100, 2), runs(5, 100, 3), false, true},
+		{"first alloc", defaults, runs(5, 100, 0), runs(5, 100, 1), false, true},
+		{"fewer allocs", defaults, runs(5, 100, 3), runs(5, 100, 2), false, false},
+		// an amortized half allocation is not
+		{"half alloc", defaults, runs(5, 100, 10), runs(5, 100, 10.5), false, false},
+		{"allocs threshold", gate{time: 0.2, allocs: 0.2, alpha: 0.05}, runs(5, 100, 10), runs(5, 100, 12), false, false},
+		{"past the allocs threshold", gate{time: 0.2, allocs: 0.2, alpha: 0.05}, runs(5, 100, 10), runs(5, 100, 13), false, true},
+		{"both", defaults, runs(5, 100, 1), runs(5, 200, 2), true, true},
+	} {
+		v := c.g.judge(c.old, c.now)
+		if v.slower != c.slower || v.allocs != c.alloc || v.regressed() != (c.slower || c.alloc) {
+			t.Errorf("%s: slower %v, allocs %v (delta %.3f, p %.3f); want %v, %v", c.name, v.slower, v.allocs, v.delta, v.p, c.slower, c.alloc)
+		}
+	}
+}
+
+func TestParseResults(t *testing.T) {
+	r, err := readResults("testdata/current.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"patterns/a.BenchmarkSteady", "patterns/a.BenchmarkSlower", "patterns/a.BenchmarkAllocs", "patterns/a.BenchmarkNew", "patterns/b.BenchmarkNoisy/size=10"}
+	if !slices.Equal(r.names, want) {
+		t.Errorf("names = %v, want %v", r.names, want)
+	}
+	s := r.byName["patterns/a.BenchmarkAllocs"]
+	if !slices.Equal(s.ns, []float64{203, 198, 205, 200, 201}) || s.bytes[0] != 64 || s.allocs[0] != 4 {
+		t.Errorf("BenchmarkAllocs = %+v", s)
+	}
+	if r.config["cpu"] != "AMD EPYC" || r.config["pkg"] != "patterns/b" {
+		t.Errorf("config = %v", r.config)
+	}
+
+	for _, c := range []struct {
+		in, want string
+	}{
+		{"# a comment\n\nBenchmarkX 10 5 ns/op\nBenchmarkX 10\n", "line 4: not a benchmark result: \"BenchmarkX 10\""},
+		{"pkg: p\nBenchmarkX 10 fast ns/op", "line 2: strconv.ParseFloat: parsing \"fast\": invalid syntax"},
+		{"BenchmarkX 10 5 ns/op 3\n", "line 1: not a benchmark result: \"BenchmarkX 10 5 ns/op 3\""},
+	} {
+		if _, err := parseResults(strings.NewReader(c.in)); err == nil || err.Error() != c.want {
+			t.Errorf("parseResults(%q) = %v, want %s", c.in, err, c.want)
+		}
+	}
+	if _, err := readResults("testdata/missing.txt"); err == nil {
+		t.Error("readResults of a missing file succeeded")
+	}
+}
+
+func TestNames(t *testing.T) {
+	for _, c := range []struct {
+		pkg, name, want string
+	}{
+		{"patterns/a", "BenchmarkX-8", "patterns/a.BenchmarkX"},
+		{"patterns/a", "BenchmarkX", "patterns/a.BenchmarkX"},
+		{"patterns/a", "BenchmarkX/size=10-16", "patterns/a.BenchmarkX/size=10"},
+		{"patterns/a", "BenchmarkX/a-b", "patterns/a.BenchmarkX/a-b"},
+		{"", "BenchmarkX-2", "BenchmarkX"},
+	} {
+		got := benchName(c.pkg, c.name)
+		if got != c.want {
+			t.Errorf("benchName(%q, %q) = %q, want %q", c.pkg, c.name, got, c.want)
+		}
+		pkg, bench := splitName(got)
+		if wantPkg := orDefault(c.pkg, packages); pkg != wantPkg || !strings.HasPrefix(c.name, bench) {
+			t.Errorf("splitName(%q) = %q, %q", got, pkg, bench)
+		}
+	}
+}
+
+// output is go test -bench output for two packages, with the lines
+// benchLines and failures drop.
+const output = `goos: linux
+pkg: patterns/a
+cpu: Xeon
+BenchmarkKeep-8   	1000	 5 ns/op	 0 B/op	 0 allocs/op
+    a_test.go:12: logged: by the benchmark
+BenchmarkDrop-8   	1000	 5 ns/op	 0 B/op	 0 allocs/op
+PASS
+ok  	patterns/a	1.0s
+goos: linux
+pkg: patterns/b
+cpu: Xeon
+BenchmarkDrop-8   	1000	 5 ns/op	 0 B/op	 0 allocs/op
+PASS
+ok  	patterns/b	1.0s
+?   	patterns/c	[no test files]
+--- FAIL: BenchmarkBroken
+    b_test.go:3: broken
+FAIL	patterns/d	0.1s
+`
+
+func TestBenchLines(t *testing.T) {
+	for _, c := range []struct {
+		filter *regexp.Regexp
+		want   string
+	}{
+		// patterns/b's configuration goes with its last result
+		{regexp.MustCompile("Keep"), "goos: linux\npkg: patterns/a\ncpu: Xeon\nBenchmarkKeep-8   \t1000\t 5 ns/op\t 0 B/op\t 0 allocs/op\n"},
+		{regexp.MustCompile(`^patterns/b\.`), "goos: linux\npkg: patterns/b\ncpu: Xeon\nBenchmarkDrop-8   \t1000\t 5 ns/op\t 0 B/op\t 0 allocs/op\n"},
+		{regexp.MustCompile("none"), ""},
+	} {
+		if got := string(benchLines([]byte(output), c.filter)); got != c.want {
+			t.Errorf("benchLines(%s) = %q, want %q", c.filter, got, c.want)
+		}
+	}
+	r, err := parseResults(bytes.NewReader(benchLines([]byte(output), nil)))
+	if err != nil || !slices.Equal(r.names, []string{"patterns/a.BenchmarkKeep", "patterns/a.BenchmarkDrop", "patterns/b.BenchmarkDrop"}) {
+		t.Errorf("parsed %v, %v", r, err)
+	}
+
+	want := "--- FAIL: BenchmarkBroken\n    b_test.go:3: broken\nFAIL\tpatterns/d\t0.1s\n"
+	if got := string(failures([]byte(output))); !strings.HasSuffix(got, want) || strings.Contains(got, "ok  \t") {
+		t.Errorf("failures = %q", got)
+	}
+}
+
+func TestSuspects(t *testing.T) {
+	base, err := readResults("testdata/baseline.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cur, err := readResults("testdata/current.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := gate{time: 0.2, alpha: 0.05}
+	for _, c := range []struct {
+		filter *regexp.Regexp
+		want   []string
+	}{
+		{nil, []string{"patterns/a.BenchmarkSlower", "patterns/a.BenchmarkAllocs"}},
+		{regexp.MustCompile("Allocs"), []string{"patterns/a.BenchmarkAllocs"}},
+		{regexp.MustCompile("Steady|Noisy|Gone"), nil},
+	} {
+		if got := suspects(base, cur, c.filter, g); !slices.Equal(got, c.want) {
+			t.Errorf("suspects(%v) = %v, want %v", c.filter, got, c.want)
+		}
+	}
+}
+
+// invoke runs the command with args and returns its stdout, stderr and
+// exit code as the content of a golden file.
+func invoke(args ...string) []byte {
+	var stdout, stderr bytes.Buffer
+	code := run(args, &stdout, &stderr)
+	var b bytes.Buffer
+	b.Write(stdout.Bytes())
+	fmt.Fprintf(&b, "--- stderr\n%s--- exit %d\n", stderr.Bytes(), code)
+	return b.Bytes()
+}
+
+// TestCompare gates testdata/current.txt against testdata/baseline.txt,
+// in which one benchmark got slower, one allocates more, one is only
+// noisier, one is gone and one is new.
+func TestCompare(t *testing.T) {
+	against := []string{"-baseline", "testdata/baseline.txt", "-against", "testdata/current.txt"}
+	for _, c := range []struct {
+		name string
+		args []string
+	}{
+		{"compare", against},
+		{"compare-run", append([]string{"-run", "Steady|Noisy"}, against...)},
+		{"compare-thresholds", append([]string{"-time", "0.6", "-allocs", "0.5"}, against...)},
+		{"compare-self", []string{"-baseline", "testdata/baseline.txt", "-against", "testdata/baseline.txt"}},
+		{"compare-bad-run", append([]string{"-run", "("}, against...)},
+		{"compare-args", append(against, "extra")},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			golden.Assert(t, c.name, invoke(c.args...))
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// samples are the measurements of one benchmark, one per run.
+type samples struct {
+	ns, bytes, allocs []float64
+}
+
+// results are benchmark runs in the go test -bench text format, which
+// benchstat reads too: "key: value" configuration lines, then one line
+// per run, a name, an iteration count and value-unit pairs. A pkg line
+// names the package of the runs after it.
+type results struct {
+	config map[string]string
+	names  []string // in first-seen order
+	byName map[string]*samples
+}
+
+func newResults() *results {
+	return &results{config: map[string]string{}, byName: map[string]*samples{}}
+}
+
+func (r *results) add(name string, ns, bytes, allocs float64) {
+	s := r.byName[name]
+	if s == nil {
+		s = &samples{}
+		r.byName[name] = s
+		r.names = append(r.names, name)
+	}
+	s.ns = append(s.ns, ns)
+	s.bytes = append(s.bytes, bytes)
+	s.allocs = append(s.allocs, allocs)
+}
+
+func parseResults(rd io.Reader) (*results, error) {
+	r := newResults()
+	sc := bufio.NewScanner(rd)
+	for line := 1; sc.Scan(); line++ {
+		text := strings.TrimSpace(sc.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		if key, value, ok := strings.Cut(text, ": "); ok && !strings.ContainsAny(key, " \t") {
+			r.config[key] = value
+			continue
+		}
+		fields := strings.Fields(text)
+		if len(fields) < 4 || len(fields)%2 != 0 {
+			return nil, fmt.Errorf("line %d: not a benchmark result: %q", line, text)
+		}
+		var ns, bytes, allocs float64
+		for i := 2; i < len(fields); i += 2 {
+			v, err := strconv.ParseFloat(fields[i], 64)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", line, err)
+			}
+			switch fields[i+1] {
+			case "ns/op":
+				ns = v
+			case "B/op":
+				bytes = v
+			case "allocs/op":
+				allocs = v
+			}
+		}
+		r.add(benchName(r.config["pkg"], fields[0]), ns, bytes, allocs)
+	}
+	return r, sc.Err()
+}
+
+// benchName is the name of a benchmark go test printed as name in pkg:
+// the import path, a dot and the name, less the -N GOMAXPROCS suffix go
+// test adds when N is not 1.
+func benchName(pkg, name string) string {
+	if i := strings.LastIndexByte(name, '-'); i >= 0 {
+		if _, err := strconv.Atoi(name[i+1:]); err == nil {
+			name = name[:i]
+		}
+	}
+	if pkg == "" {
+		return name
+	}
+	return pkg + "." + name
+}
+
+// splitName is the inverse of benchName: the import path and the go test
+// name.
+func splitName(name string) (pkg, bench string) {
+	if i := strings.Index(name, ".Benchmark"); i >= 0 {
+		return name[:i], name[i+1:]
+	}
+	return packages, name
+}
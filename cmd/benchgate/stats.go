@@ -0,0 +1,134 @@
+package main
+
+import (
+	"math"
+	"slices"
+)
+
+func median(xs []float64) float64 {
+	s := slices.Clone(xs)
+	slices.Sort(s)
+	n := len(s)
+	if n == 0 {
+		return math.NaN()
+	}
+	if n%2 == 1 {
+		return s[n/2]
+	}
+	return (s[n/2-1] + s[n/2]) / 2
+}
+
+// spread is the largest distance of a sample from the median, as a
+// fraction of it: benchstat's "± x%".
+func spread(xs []float64) float64 {
+	m := median(xs)
+	d := 0.0
+	for _, x := range xs {
+		d = max(d, math.Abs(x-m))
+	}
+	return d / m
+}
+
+// mannWhitney returns the two-sided p-value of the Mann-Whitney U test,
+// benchstat's default: the chance that samples as far apart as a and b
+// come from one distribution. It makes no assumption about the shape of
+// the distribution, which for timings has a long right tail. The
+// distribution of U is exact when there are no ties, and the normal
+// approximation with a tie correction when there are.
+func mannWhitney(a, b []float64) float64 {
+	m, n := len(a), len(b)
+	if m == 0 || n == 0 {
+		return 1
+	}
+	type obs struct {
+		v     float64
+		fromA bool
+	}
+	all := make([]obs, 0, m+n)
+	for _, v := range a {
+		all = append(all, obs{v, true})
+	}
+	for _, v := range b {
+		all = append(all, obs{v, false})
+	}
+	slices.SortFunc(all, func(x, y obs) int {
+		switch {
+		case x.v < y.v:
+			return -1
+		case x.v > y.v:
+			return 1
+		}
+		return 0
+	})
+	// midranks, and the tie correction term
+	rankA, tieTerm, ties := 0.0, 0.0, false
+	for i := 0; i < len(all); {
+		j := i
+		for j < len(all) && all[j].v == all[i].v {
+			j++
+		}
+		rank := float64(i+j+1) / 2 // ranks i+1..j averaged
+		for k := i; k < j; k++ {
+			if all[k].fromA {
+				rankA += rank
+			}
+		}
+		if t := float64(j - i); t > 1 {
+			ties = true
+			tieTerm += t*t*t - t
+		}
+		i = j
+	}
+	u := rankA - float64(m*(m+1))/2
+	mean := float64(m*n) / 2
+
+	if !ties {
+		// P(U <= min(u, mn-u)), doubled
+		lo := int(math.Min(u, float64(m*n)-u))
+		counts := uCounts(m, n)
+		total, tail := 0.0, 0.0
+		for k, c := range counts {
+			total += c
+			if k <= lo {
+				tail += c
+			}
+		}
+		return math.Min(1, 2*tail/total)
+	}
+	N := float64(m + n)
+	sd := math.Sqrt(float64(m*n) / 12 * (N + 1 - tieTerm/(N*(N-1))))
+	if sd == 0 {
+		return 1
+	}
+	z := (math.Abs(u-mean) - 0.5) / sd // with continuity correction
+	return math.Min(1, math.Erfc(math.Max(z, 0)/math.Sqrt2))
+}
+
+// uCounts returns, for each k, how many orderings of m values from one
+// sample and n from another give U = k.
+func uCounts(m, n int) []float64 {
+	// f[i][j] is the distribution for i and j values
+	f := make([][][]float64, m+1)
+	for i := range f {
+		f[i] = make([][]float64, n+1)
+		for j := range f[i] {
+			f[i][j] = make([]float64, i*j+1)
+			switch {
+			case i == 0 || j == 0:
+				f[i][j][0] = 1
+			default:
+				// the largest value is from the first sample, beating all
+				// j of the second, or from the second, beating none
+				for k := range f[i][j] {
+					if k >= j && k-j < len(f[i-1][j]) {
+						f[i][j][k] += f[i-1][j][k-j]
+					}
+					if k < len(f[i][j-1]) {
+						f[i][j][k] += f[i][j-1][k]
+					}
+				}
+			}
+		}
+	}
+	return f[m][n]
+}
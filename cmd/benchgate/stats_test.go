@@ -0,0 +1,92 @@
+package main
+
+import (
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestMedian(t *testing.T) {
+	for _, c := range []struct {
+		xs           []float64
+		median, sprd float64
+	}{
+		{[]float64{3}, 3, 0},
+		{[]float64{120, 90, 100}, 100, 0.2},
+		{[]float64{4, 1, 3, 2}, 2.5, 0.6},
+	} {
+		in := slices.Clone(c.xs)
+		if got := median(c.xs); got != c.median {
+			t.Errorf("median(%v) = %v, want %v", c.xs, got, c.median)
+		}
+		if got := spread(c.xs); math.Abs(got-c.sprd) > 1e-12 {
+			t.Errorf("spread(%v) = %v, want %v", c.xs, got, c.sprd)
+		}
+		if !slices.Equal(c.xs, in) {
+			t.Errorf("median sorted its argument: %v", c.xs)
+		}
+	}
+	if !math.IsNaN(median(nil)) {
+		t.Errorf("median(nil) = %v, want NaN", median(nil))
+	}
+}
+
+// TestUCounts checks the exact distribution of U against small cases
+// counted by hand: it is symmetric and sums to m+n choose m.
+func TestUCounts(t *testing.T) {
+	for _, c := range []struct {
+		m, n int
+		want []float64
+	}{
+		{1, 1, []float64{1, 1}},
+		{2, 2, []float64{1, 1, 2, 1, 1}},
+		{3, 3, []float64{1, 1, 2, 3, 3, 3, 3, 2, 1, 1}},
+		{1, 3, []float64{1, 1, 1, 1}},
+		{0, 4, []float64{1}},
+	} {
+		if got := uCounts(c.m, c.n); !slices.Equal(got, c.want) {
+			t.Errorf("uCounts(%d, %d) = %v, want %v", c.m, c.n, got, c.want)
+		}
+	}
+	got := uCounts(5, 5)
+	total := 0.0
+	for _, c := range got {
+		total += c
+	}
+	back := slices.Clone(got)
+	slices.Reverse(back)
+	if total != 252 || !slices.Equal(got, back) {
+		t.Errorf("uCounts(5, 5) = %v", got)
+	}
+}
+
+func TestMannWhitney(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		a, b []float64
+		want float64
+	}{
+		// exact: 2 of the 252 orderings are as far apart
+		{"separated 5", []float64{1, 2, 3, 4, 5}, []float64{6, 7, 8, 9, 10}, 2.0 / 252},
+		// the smallest p four runs a side can give is under 0.05, three
+		// runs' is not
+		{"separated 4", []float64{1, 2, 3, 4}, []float64{5, 6, 7, 8}, 2.0 / 70},
+		{"separated 3", []float64{1, 2, 3}, []float64{4, 5, 6}, 0.1},
+		{"interleaved", []float64{1, 3, 5}, []float64{2, 4, 6}, 0.7},
+		{"same", []float64{1, 2, 3}, []float64{1.5, 2.5}, 1},
+		{"unequal sizes", []float64{1, 2, 3, 4, 5}, []float64{6, 7, 8}, 2.0 / 56},
+		// the normal approximation with ties: |U - 12.5| = 12.5 over
+		// sd 4.729, less the continuity correction
+		{"ties", []float64{1, 1, 2, 2, 3}, []float64{10, 10, 11, 11, 12}, math.Erfc(12 / math.Sqrt(25.0/12*(11-24.0/90)) / math.Sqrt2)},
+		{"all tied", []float64{5, 5, 5}, []float64{5, 5, 5}, 1},
+		{"empty", nil, []float64{1, 2}, 1},
+	} {
+		got := mannWhitney(c.a, c.b)
+		if math.Abs(got-c.want) > 1e-9 {
+			t.Errorf("%s: p = %v, want %v", c.name, got, c.want)
+		}
+		if back := mannWhitney(c.b, c.a); math.Abs(back-got) > 1e-9 {
+			t.Errorf("%s: p = %v one way, %v the other", c.name, got, back)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+// Command enumgen generates String, MarshalText, UnmarshalText, Parse and
+// Values boilerplate for an integer enum declared as a const block.
+//
+// usage:
+//
+//	//go:generate go run patterns/cmd/enumgen -type=Level -trimprefix=Level
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"patterns/cli"
+)
+
+func main() {
+	cli.Main(run)
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	var typeName, trimPrefix, output, dir string
+	var lower bool
+	cmd := &cli.Command{
+		Name:  "enumgen",
+		Short: "generate String, MarshalText, UnmarshalText, Parse and Values for an enum",
+		Flags: func(fs *flag.FlagSet) {
+			fs.StringVar(&typeName, "type", "", "enum type name (required)")
+			fs.StringVar(&trimPrefix, "trimprefix", "", "prefix to trim from constant names")
+			fs.BoolVar(&lower, "lower", true, "lowercase the string form")
+			fs.StringVar(&output, "output", "", "output file (default <type>_enum.go)")
+			fs.StringVar(&dir, "dir", ".", "package directory")
+		},
+		Run: func(env cli.Env, args []string) error {
+			if typeName == "" || len(args) > 0 {
+				return cli.ErrUsage
+			}
+			return generateFile(dir, typeName, output, func(name string) string {
+				s := strings.TrimPrefix(name, trimPrefix)
+				if lower {
+					s = strings.ToLower(s)
+				}
+				return s
+			})
+		},
+	}
+	return cmd.Main(args, stdout, stderr)
+}
+
+// generateFile writes the code for typeName in dir to output, by default
+// <type>_enum.go.
+func generateFile(dir, typeName, output string, str func(string) string) error {
+	pkg, names, err := parseEnum(dir, typeName)
+	if err != nil {
+		return err
+	}
+	src, err := generate(pkg, typeName, names, str)
+	if err != nil {
+		return err
+	}
+
+	if output == "" {
+		output = strings.ToLower(typeName) + "_enum.go"
+	}
+	return os.WriteFile(filepath.Join(dir, output), src, 0o644)
+}
+
+// parseEnum returns the package name and the constant names declared with
+// type typeName, in declaration order.
+// Constants that repeat the previous spec implicitly (iota style) are included.
+func parseEnum(dir, typeName string) (string, []string, error) {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, dir, func(fi os.FileInfo) bool {
+		name := fi.Name()
+		return !strings.HasSuffix(name, "_test.go") && !strings.HasSuffix(name, "_enum.go")
+	}, 0)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(pkgs) != 1 {
+		return "", nil, fmt.Errorf("expected one package in %s, found %d", dir, len(pkgs))
+	}
+
+	var pkgName string
+	var names []string
+	for name, pkg := range pkgs {
+		pkgName = name
+		for _, file := range pkg.Files {
+			for _, decl := range file.Decls {
+				gen, ok := decl.(*ast.GenDecl)
+				if !ok || gen.Tok != token.CONST {
+					continue
+				}
+				names = append(names, constsOfType(gen, typeName)...)
+			}
+		}
+	}
+	if len(names) == 0 {
+		return "", nil, fmt.Errorf("no constants of type %s found", typeName)
+	}
+
+	return pkgName, names, nil
+}
+
+func constsOfType(gen *ast.GenDecl, typeName string) []string {
+	var names []string
+	inType := false
+	for _, spec := range gen.Specs {
+		vs := spec.(*ast.ValueSpec)
+		switch {
+		case vs.Type != nil:
+			ident, ok := vs.Type.(*ast.Ident)
+			inType = ok && ident.Name == typeName
+		case len(vs.Values) > 0:
+			// explicit untyped value ends the implicit repetition
+			inType = false
+		}
+		if !inType {
+			continue
+		}
+		for _, n := range vs.Names {
+			if n.Name != "_" {
+				names = append(names, n.Name)
+			}
+		}
+	}
+
+	return names
+}
+
+func generate(pkg, typeName string, names []string, str func(string) string) ([]byte, error) {
+	if len(names) == 0 {
+		return nil, errors.New("no values")
+	}
+	seen := map[string]string{}
+	for _, n := range names {
+		if prev, ok := seen[str(n)]; ok {
+			return nil, fmt.Errorf("%s and %s both have the string form %q", prev, n, str(n))
+		}
+		seen[str(n)] = n
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// Code generated by enumgen -type=%s; DO NOT EDIT.\n\n", typeName)
+	fmt.Fprintf(&b, "package %s\n\n", pkg)
+	b.WriteString("import (\n\"fmt\"\n\"strconv\"\n)\n\n")
+
+	fmt.Fprintf(&b, "var _%sNames = map[%s]string{\n", typeName, typeName)
+	for _, n := range names {
+		fmt.Fprintf(&b, "%s: %q,\n", n, str(n))
+	}
+	b.WriteString("}\n\n")
+
+	fmt.Fprintf(&b, "func (v %s) String() string {\n", typeName)
+	fmt.Fprintf(&b, "if s, ok := _%sNames[v]; ok {\nreturn s\n}\n", typeName)
+	fmt.Fprintf(&b, "return %q + strconv.FormatInt(int64(v), 10) + \")\"\n}\n\n", typeName+"(")
+
+	fmt.Fprintf(&b, "// %sValues returns every declared %s in declaration order.\n", typeName, typeName)
+	fmt.Fprintf(&b, "func %sValues() []%s {\nreturn []%s{", typeName, typeName, typeName)
+	b.WriteString(strings.Join(names, ", "))
+	b.WriteString("}\n}\n\n")
+
+	fmt.Fprintf(&b, "// Parse%s returns the %s whose string form is s.\n", typeName, typeName)
+	fmt.Fprintf(&b, "func Parse%s(s string) (%s, error) {\n", typeName, typeName)
+	// a switch in declaration order, not a loop over the map: the
+	// generated code reads the same on every run, and so does its result
+	b.WriteString("switch s {\n")
+	for _, n := range names {
+		fmt.Fprintf(&b, "case %q:\nreturn %s, nil\n", str(n), n)
+	}
+	b.WriteString("}\n")
+	fmt.Fprintf(&b, "return 0, fmt.Errorf(\"invalid %s %%q\", s)\n}\n\n", typeName)
+
+	fmt.Fprintf(&b, "func (v %s) MarshalText() ([]byte, error) {\n", typeName)
+	fmt.Fprintf(&b, "if _, ok := _%sNames[v]; !ok {\n", typeName)
+	fmt.Fprintf(&b, "return nil, fmt.Errorf(\"invalid %s %%d\", int64(v))\n}\n", typeName)
+	b.WriteString("return []byte(v.String()), nil\n}\n\n")
+
+	fmt.Fprintf(&b, "func (v *%s) UnmarshalText(text []byte) error {\n", typeName)
+	fmt.Fprintf(&b, "parsed, err := Parse%s(string(text))\nif err != nil {\nreturn err\n}\n", typeName)
+	b.WriteString("*v = parsed\nreturn nil\n}\n")
+
+	return format.Source(b.Bytes())
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"patterns/testing/golden"
+)
+
+func TestGenerate(t *testing.T) {
+	for _, c := range []struct {
+		dir, typeName, trimPrefix string
+		lower                     bool
+	}{
+		{"level", "Level", "Level", true},
+		{"mixed", "Color", "", false},
+	} {
+		t.Run(c.dir, func(t *testing.T) {
+			pkg, names, err := parseEnum(filepath.Join("testdata", c.dir), c.typeName)
+			if err != nil {
+				t.Fatal(err)
+			}
+			src, err := generate(pkg, c.typeName, names, func(name string) string {
+				s := strings.TrimPrefix(name, c.trimPrefix)
+				if c.lower {
+					s = strings.ToLower(s)
+				}
+				return s
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			golden.Assert(t, c.dir+"/"+strings.ToLower(c.typeName)+"_enum.go", src)
+		})
+	}
+}
+
+func TestGenerateRejectsDuplicateStrings(t *testing.T) {
+	_, err := generate("p", "Kind", []string{"KindA", "KindB"}, func(string) string { return "same" })
+	if err == nil || !strings.Contains(err.Error(), `KindA and KindB both have the string form "same"`) {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestParseEnumNoConstants(t *testing.T) {
+	if _, _, err := parseEnum(filepath.Join("testdata", "level"), "Missing"); err == nil {
+		t.Fatal("no error for a type without constants")
+	}
+}
+
+// TestRun generates into a copy of testdata/level, as go:generate would.
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	src, err := os.ReadFile(filepath.Join("testdata", "level", "level.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "level.go"), src, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"-type=Level", "-trimprefix=Level", "-dir", dir}, &stdout, &stderr); code != 0 {
+		t.Fatalf("exit %d, stderr:\n%s", code, &stderr)
+	}
+	if stdout.Len() > 0 || stderr.Len() > 0 {
+		t.Errorf("output %q, %q; want none", &stdout, &stderr)
+	}
+	got, err := os.ReadFile(filepath.Join(dir, "level_enum.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	golden.Assert(t, "level/level_enum.go", got)
+
+	if code := run([]string{"-type=Level", "-dir", dir, "-output", "custom.go"}, &stdout, &stderr); code != 0 {
+		t.Fatalf("-output: exit %d, stderr:\n%s", code, &stderr)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "custom.go")); err != nil {
+		t.Errorf("-output: %v", err)
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	for _, c := range []struct {
+		name   string
+		args   []string
+		code   int
+		stderr string
+	}{
+		{"no type", nil, 2, "usage: enumgen [flags]\n"},
+		{"extra args", []string{"-type=Level", "x"}, 2, "usage: enumgen [flags]\n"},
+		{"bad flag", []string{"-nope"}, 2, "flag provided but not defined: -nope\n"},
+		{"no constants", []string{"-type=Missing", "-dir", filepath.Join("testdata", "level")}, 1, "enumgen: no constants of type Missing found\n"},
+	} {
+		var stdout, stderr bytes.Buffer
+		code := run(c.args, &stdout, &stderr)
+		if code != c.code || !strings.HasPrefix(stderr.String(), c.stderr) {
+			t.Errorf("%s: exit %d, stderr:\n%s\nwant exit %d, stderr starting %q", c.name, code, &stderr, c.code, c.stderr)
+		}
+	}
+}
@@ -0,0 +1,15 @@
+package mixed
+
+type Color int
+
+type Size int
+
+// constants of other types, blanks and explicit values share the block
+const (
+	Red Color = iota + 1
+	_
+	Green
+	Small     Size  = 1
+	Blue      Color = 10
+	Unrelated       = 7
+)
@@ -0,0 +1,471 @@
+// Command optgen generates functional options from an annotated config
+// struct: one WithX function per tagged field, the Option type, and a
+// constructor that applies defaults, the options in order, and validation.
+//
+// usage:
+//
+//	//go:generate go run patterns/cmd/optgen -type=config
+//
+//	type config struct {
+//		port    *int          `opt:"Port,optional,check"`
+//		timeout time.Duration `opt:"Timeout,nonnegative"`
+//		logger  *slog.Logger  `opt:"Logger,nonnil"`
+//		cache   int           // untagged: not an option
+//	}
+//
+// The tag is the option name (WithPort) followed by rules, checked in
+// WithX so a bad value fails when the option is applied:
+//
+//	nonnil       the value cannot be nil
+//	nonzero      the value cannot be the zero value ("" for strings)
+//	positive     the value must be > 0
+//	nonnegative  the value cannot be < 0
+//	optional     the field is a pointer, WithX takes the element type, and
+//	             nil means "not set"
+//	check        WithX calls a hand-written check<Name>(v) error
+//
+// If the package declares setDefaults or validate methods on the type,
+// the constructor calls them before and after the options. -stubs writes
+// the missing check funcs and validate method to <type>_checks.go, to be
+// filled in; it never overwrites that file.
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"patterns/cli"
+)
+
+func main() {
+	cli.Main(run)
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	var typeName, optionType, constructor, output, dir string
+	var stubs bool
+	cmd := &cli.Command{
+		Name:  "optgen",
+		Short: "generate functional options from an annotated config struct",
+		Flags: func(fs *flag.FlagSet) {
+			fs.StringVar(&typeName, "type", "", "config struct type name (required)")
+			fs.StringVar(&optionType, "option", "Option", "name of the generated option type")
+			fs.StringVar(&constructor, "new", "", "name of the generated constructor (default new<Type>)")
+			fs.StringVar(&output, "output", "", "output file (default <type>_options.go)")
+			fs.BoolVar(&stubs, "stubs", false, "write missing check funcs and validate to <type>_checks.go")
+			fs.StringVar(&dir, "dir", ".", "package directory")
+		},
+		Run: func(env cli.Env, args []string) error {
+			if typeName == "" || len(args) > 0 {
+				return cli.ErrUsage
+			}
+			if constructor == "" {
+				constructor = "new" + exported(typeName)
+			}
+			if output == "" {
+				output = strings.ToLower(typeName) + "_options.go"
+			}
+			cfg, err := parseConfig(dir, typeName, output)
+			if err != nil {
+				return err
+			}
+			cfg.option = optionType
+			cfg.constructor = constructor
+			if err := generateFile(cfg, filepath.Join(dir, output)); err != nil {
+				return err
+			}
+			if !stubs {
+				return nil
+			}
+			path := filepath.Join(dir, strings.ToLower(typeName)+"_checks.go")
+			if _, err := os.Stat(path); err == nil {
+				fmt.Fprintf(env.Stderr, "optgen: %s exists, not writing stubs\n", path)
+				return nil
+			}
+			return generateStubsFile(cfg, path)
+		},
+	}
+	return cmd.Main(args, stdout, stderr)
+}
+
+// generateFile writes the options for cfg to path.
+func generateFile(cfg *config, path string) error {
+	src, err := generate(cfg)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, src, 0o644)
+}
+
+// generateStubsFile writes the missing stubs for cfg to path, and nothing
+// if none is missing.
+func generateStubsFile(cfg *config, path string) error {
+	src, err := generateStubs(cfg)
+	if err != nil || src == nil {
+		return err
+	}
+	return os.WriteFile(path, src, 0o644)
+}
+
+// config is what the generator knows about the annotated struct.
+type config struct {
+	pkg         string
+	typeName    string
+	option      string
+	constructor string
+	fields      []field
+	imports     map[string]string // name used in the source -> import path
+	methods     map[string]bool   // methods declared on the type
+	funcs       map[string]bool   // funcs declared in the package
+}
+
+type field struct {
+	name   string // struct field
+	option string // WithX suffix
+	typ    string // field type as written
+	param  string // WithX parameter type
+	doc    []string
+	rules  []string
+	// pkgs are the package names the type refers to, for imports
+	pkgs []string
+}
+
+func (f field) has(rule string) bool { return slices.Contains(f.rules, rule) }
+
+var knownRules = []string{"nonnil", "nonzero", "positive", "nonnegative", "optional", "check"}
+
+// parseConfig finds typeName in dir, skipping test files and the output
+// of a previous run.
+func parseConfig(dir, typeName, output string) (*config, error) {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, dir, func(fi os.FileInfo) bool {
+		name := fi.Name()
+		return !strings.HasSuffix(name, "_test.go") && name != output
+	}, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	if len(pkgs) != 1 {
+		return nil, fmt.Errorf("expected one package in %s, found %d", dir, len(pkgs))
+	}
+
+	cfg := &config{typeName: typeName, imports: map[string]string{}, methods: map[string]bool{}, funcs: map[string]bool{}}
+	var st *ast.StructType
+	var stFile *ast.File
+	for name, pkg := range pkgs {
+		cfg.pkg = name
+		for _, file := range pkg.Files {
+			for _, decl := range file.Decls {
+				//exhaustive:ignore
+				switch d := decl.(type) {
+				case *ast.FuncDecl:
+					if d.Recv == nil {
+						cfg.funcs[d.Name.Name] = true
+					} else if receiverIs(d.Recv, typeName) {
+						cfg.methods[d.Name.Name] = true
+					}
+				case *ast.GenDecl:
+					if s := findStruct(d, typeName); s != nil {
+						st, stFile = s, file
+					}
+				}
+			}
+		}
+	}
+	if st == nil {
+		return nil, fmt.Errorf("struct type %s not found", typeName)
+	}
+
+	fileImports := map[string]string{}
+	for _, spec := range stFile.Imports {
+		path, _ := strconv.Unquote(spec.Path.Value)
+		name := filepath.Base(path)
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		fileImports[name] = path
+	}
+
+	for _, f := range st.Fields.List {
+		if f.Tag == nil {
+			continue
+		}
+		tag, _ := strconv.Unquote(f.Tag.Value)
+		spec, ok := reflect.StructTag(tag).Lookup("opt")
+		if !ok {
+			continue
+		}
+		if len(f.Names) != 1 {
+			return nil, fmt.Errorf("%s: tagged fields must declare exactly one name", fset.Position(f.Pos()))
+		}
+		parsed, err := parseField(f, spec)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", fset.Position(f.Pos()), err)
+		}
+		for _, p := range parsed.pkgs {
+			path, ok := fileImports[p]
+			if !ok {
+				return nil, fmt.Errorf("%s: package %s is not imported", fset.Position(f.Pos()), p)
+			}
+			cfg.imports[p] = path
+		}
+		cfg.fields = append(cfg.fields, parsed)
+	}
+	if len(cfg.fields) == 0 {
+		return nil, fmt.Errorf("struct %s has no opt-tagged fields", typeName)
+	}
+
+	return cfg, nil
+}
+
+func receiverIs(recv *ast.FieldList, typeName string) bool {
+	t := recv.List[0].Type
+	if star, ok := t.(*ast.StarExpr); ok {
+		t = star.X
+	}
+	id, ok := t.(*ast.Ident)
+	return ok && id.Name == typeName
+}
+
+func findStruct(gen *ast.GenDecl, typeName string) *ast.StructType {
+	if gen.Tok != token.TYPE {
+		return nil
+	}
+	for _, spec := range gen.Specs {
+		ts := spec.(*ast.TypeSpec)
+		if ts.Name.Name != typeName {
+			continue
+		}
+		if st, ok := ts.Type.(*ast.StructType); ok {
+			return st
+		}
+	}
+	return nil
+}
+
+func parseField(f *ast.Field, spec string) (field, error) {
+	parts := strings.Split(spec, ",")
+	fd := field{
+		name:   f.Names[0].Name,
+		option: parts[0],
+		typ:    types.ExprString(f.Type),
+		rules:  parts[1:],
+	}
+	if fd.option == "" {
+		fd.option = exported(fd.name)
+	}
+	for _, r := range fd.rules {
+		if !slices.Contains(knownRules, r) {
+			return field{}, fmt.Errorf("unknown rule %q", r)
+		}
+	}
+
+	fd.param = fd.typ
+	if fd.has("optional") {
+		star, ok := f.Type.(*ast.StarExpr)
+		if !ok {
+			return field{}, errors.New("optional field must be a pointer")
+		}
+		fd.param = types.ExprString(star.X)
+	}
+	if f.Doc != nil {
+		for _, c := range f.Doc.List {
+			fd.doc = append(fd.doc, strings.TrimSpace(strings.TrimPrefix(c.Text, "//")))
+		}
+	}
+	ast.Inspect(f.Type, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if id, ok := sel.X.(*ast.Ident); ok && !slices.Contains(fd.pkgs, id.Name) {
+				fd.pkgs = append(fd.pkgs, id.Name)
+			}
+		}
+		return true
+	})
+
+	return fd, nil
+}
+
+func generate(cfg *config) ([]byte, error) {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// Code generated by optgen -type=%s; DO NOT EDIT.\n\n", cfg.typeName)
+	fmt.Fprintf(&b, "package %s\n\n", cfg.pkg)
+
+	imports := map[string]string{}
+	for name, path := range cfg.imports {
+		imports[name] = path
+	}
+	if slices.ContainsFunc(cfg.fields, func(f field) bool { return len(rulesChecked(f)) > 0 }) {
+		imports["errors"] = "errors"
+	}
+	writeImports(&b, imports)
+
+	fmt.Fprintf(&b, "// %s configures a %s.\n", cfg.option, cfg.typeName)
+	fmt.Fprintf(&b, "type %s func(*%s) error\n\n", cfg.option, cfg.typeName)
+
+	for _, f := range cfg.fields {
+		writeOption(&b, cfg, f)
+	}
+
+	fmt.Fprintf(&b, "// %s applies opts in order", cfg.constructor)
+	if cfg.methods["setDefaults"] {
+		b.WriteString(" over the defaults")
+	}
+	if cfg.methods["validate"] {
+		b.WriteString(" and validates the result")
+	}
+	b.WriteString(".\n")
+	fmt.Fprintf(&b, "func %s(opts ...%s) (*%s, error) {\n", cfg.constructor, cfg.option, cfg.typeName)
+	fmt.Fprintf(&b, "c := &%s{}\n", cfg.typeName)
+	if cfg.methods["setDefaults"] {
+		b.WriteString("c.setDefaults()\n")
+	}
+	b.WriteString("for _, opt := range opts {\nif err := opt(c); err != nil {\nreturn nil, err\n}\n}\n")
+	if cfg.methods["validate"] {
+		b.WriteString("if err := c.validate(); err != nil {\nreturn nil, err\n}\n")
+	}
+	b.WriteString("return c, nil\n}\n")
+
+	return format.Source(b.Bytes())
+}
+
+func writeImports(b *bytes.Buffer, imports map[string]string) {
+	if len(imports) == 0 {
+		return
+	}
+	names := make([]string, 0, len(imports))
+	for name := range imports {
+		names = append(names, name)
+	}
+	slices.SortFunc(names, func(a, c string) int { return strings.Compare(imports[a], imports[c]) })
+	b.WriteString("import (\n")
+	for _, name := range names {
+		path := imports[name]
+		if filepath.Base(path) == name {
+			fmt.Fprintf(b, "%q\n", path)
+		} else {
+			fmt.Fprintf(b, "%s %q\n", name, path)
+		}
+	}
+	b.WriteString(")\n\n")
+}
+
+func writeOption(b *bytes.Buffer, cfg *config, f field) {
+	fmt.Fprintf(b, "// With%s sets %s.", f.option, f.name)
+	if len(f.doc) > 0 {
+		b.WriteString("\n//")
+		for _, line := range f.doc {
+			fmt.Fprintf(b, "\n// %s", line)
+		}
+	}
+	b.WriteString("\n")
+	fmt.Fprintf(b, "func With%s(v %s) %s {\n", f.option, f.param, cfg.option)
+	fmt.Fprintf(b, "return func(c *%s) error {\n", cfg.typeName)
+	for _, check := range rulesChecked(f) {
+		fmt.Fprintf(b, "if %s {\nreturn errors.New(%q)\n}\n", check.cond, words(f.option)+" "+check.msg)
+	}
+	if f.has("check") {
+		fmt.Fprintf(b, "if err := check%s(v); err != nil {\nreturn err\n}\n", f.option)
+	}
+	if f.has("optional") {
+		fmt.Fprintf(b, "c.%s = &v\n", f.name)
+	} else {
+		fmt.Fprintf(b, "c.%s = v\n", f.name)
+	}
+	b.WriteString("return nil\n}\n}\n\n")
+}
+
+type ruleCheck struct{ cond, msg string }
+
+func rulesChecked(f field) []ruleCheck {
+	var checks []ruleCheck
+	for _, r := range f.rules {
+		switch r {
+		case "nonnil":
+			checks = append(checks, ruleCheck{"v == nil", "cannot be nil"})
+		case "nonzero":
+			if f.param == "string" {
+				checks = append(checks, ruleCheck{`v == ""`, "cannot be empty"})
+			} else {
+				checks = append(checks, ruleCheck{"v == 0", "cannot be zero"})
+			}
+		case "positive":
+			checks = append(checks, ruleCheck{"v <= 0", "must be positive"})
+		case "nonnegative":
+			checks = append(checks, ruleCheck{"v < 0", "cannot be negative"})
+		}
+	}
+	return checks
+}
+
+// generateStubs returns a file with a TODO body for every check func and
+// the validate method not yet declared, or nil if nothing is missing.
+func generateStubs(cfg *config) ([]byte, error) {
+	var body bytes.Buffer
+	for _, f := range cfg.fields {
+		name := "check" + f.option
+		if !f.has("check") || cfg.funcs[name] {
+			continue
+		}
+		fmt.Fprintf(&body, "// %s validates the value given to With%s.\n", name, f.option)
+		fmt.Fprintf(&body, "func %s(v %s) error {\n// TODO\nreturn nil\n}\n\n", name, f.param)
+	}
+	if !cfg.methods["validate"] {
+		fmt.Fprintf(&body, "// validate checks rules involving more than one field, once every\n// option has been applied.\n")
+		fmt.Fprintf(&body, "func (c *%s) validate() error {\n// TODO\nreturn nil\n}\n", cfg.typeName)
+	}
+	if body.Len() == 0 {
+		return nil, nil
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "package %s\n\n", cfg.pkg)
+	imports := map[string]string{}
+	for _, f := range cfg.fields {
+		if f.has("check") && !cfg.funcs["check"+f.option] {
+			for _, p := range f.pkgs {
+				imports[p] = cfg.imports[p]
+			}
+		}
+	}
+	writeImports(&b, imports)
+	b.Write(body.Bytes())
+	return format.Source(b.Bytes())
+}
+
+func exported(s string) string {
+	if s == "" {
+		return s
+	}
+	r := []rune(s)
+	r[0] = unicode.ToUpper(r[0])
+	return string(r)
+}
+
+// words turns an option name into the subject of an error message:
+// ReadTimeout becomes "read timeout".
+func words(name string) string {
+	var b strings.Builder
+	r := []rune(name)
+	for i, c := range r {
+		if i > 0 && unicode.IsUpper(c) && (unicode.IsLower(r[i-1]) || i+1 < len(r) && unicode.IsLower(r[i+1])) {
+			b.WriteByte(' ')
+		}
+		b.WriteRune(unicode.ToLower(c))
+	}
+	return b.String()
+}
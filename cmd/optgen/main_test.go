@@ -0,0 +1,220 @@
+package main
+
+import (
+	"bytes"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"patterns/testing/golden"
+)
+
+func parse(t *testing.T, dir, typeName, option, constructor string) *config {
+	t.Helper()
+	cfg, err := parseConfig(filepath.Join("testdata", dir), typeName, strings.ToLower(typeName)+"_options.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.option, cfg.constructor = option, constructor
+	return cfg
+}
+
+func TestGenerate(t *testing.T) {
+	for _, c := range []struct {
+		dir, typeName, option, constructor string
+	}{
+		{"all", "settings", "Option", "newSettings"},
+		{"bare", "server", "Setting", "build"},
+	} {
+		t.Run(c.dir, func(t *testing.T) {
+			src, err := generate(parse(t, c.dir, c.typeName, c.option, c.constructor))
+			if err != nil {
+				t.Fatal(err)
+			}
+			golden.Assert(t, c.dir+"/"+c.typeName+"_options.go", src)
+		})
+	}
+}
+
+func TestGenerateStubs(t *testing.T) {
+	src, err := generateStubs(parse(t, "all", "settings", "Option", "newSettings"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	golden.Assert(t, "all/settings_checks.go", src)
+}
+
+// TestCompiles type-checks each testdata package together with what
+// optgen writes for it, stubs included, so generated code that gofmt
+// accepts but the compiler would not fails here.
+func TestCompiles(t *testing.T) {
+	for _, c := range []struct {
+		dir, typeName string
+	}{
+		{"all", "settings"},
+		{"bare", "server"},
+	} {
+		cfg := parse(t, c.dir, c.typeName, "Option", "new"+exported(c.typeName))
+		src, err := generate(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		stubs, err := generateStubs(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		fset := token.NewFileSet()
+		var files []*ast.File
+		add := func(name string, src []byte) {
+			f, err := parser.ParseFile(fset, name, src, 0)
+			if err != nil {
+				t.Fatalf("%s: %v", c.dir, err)
+			}
+			files = append(files, f)
+		}
+		orig, err := os.ReadFile(filepath.Join("testdata", c.dir, c.dir+".go"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		add(c.dir+".go", orig)
+		add("options.go", src)
+		if stubs != nil {
+			add("checks.go", stubs)
+		}
+		conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+		if _, err := conf.Check(c.dir, fset, files, nil); err != nil {
+			t.Errorf("%s: generated code does not compile: %v", c.dir, err)
+		}
+	}
+}
+
+// TestUpToDate checks that the options checked in to options/generated
+// are what optgen generates now, so go generate has been run.
+func TestUpToDate(t *testing.T) {
+	dir := filepath.Join("..", "..", "options", "generated")
+	cfg, err := parseConfig(dir, "config", "config_options.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.option, cfg.constructor = "Option", "newConfig"
+	src, err := generate(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := os.ReadFile(filepath.Join(dir, "config_options.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(src, want) {
+		t.Errorf("options/generated/config_options.go is stale; run go generate patterns/options/generated")
+	}
+}
+
+// TestRun generates into a copy of testdata/all, as go:generate would,
+// then checks that -stubs does not overwrite the stubs it wrote.
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	src, err := os.ReadFile(filepath.Join("testdata", "all", "all.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "all.go"), src, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"-type=settings", "-stubs", "-dir", dir}, &stdout, &stderr); code != 0 {
+		t.Fatalf("exit %d, stderr:\n%s", code, &stderr)
+	}
+	if stdout.Len() > 0 || stderr.Len() > 0 {
+		t.Errorf("output %q, %q; want none", &stdout, &stderr)
+	}
+	for _, name := range []string{"settings_options.go", "settings_checks.go"} {
+		got, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		golden.Assert(t, "all/"+name, got)
+	}
+
+	checks := filepath.Join(dir, "settings_checks.go")
+	if err := os.WriteFile(checks, []byte("package all\n\nfunc checkWindow(v time.Duration) error { return nil }\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	stderr.Reset()
+	if code := run([]string{"-type=settings", "-stubs", "-dir", dir}, &stdout, &stderr); code != 0 {
+		t.Fatalf("second run: exit %d, stderr:\n%s", code, &stderr)
+	}
+	if want := "optgen: " + checks + " exists, not writing stubs\n"; stderr.String() != want {
+		t.Errorf("second run: stderr %q, want %q", &stderr, want)
+	}
+	if got, _ := os.ReadFile(checks); !strings.Contains(string(got), "return nil }") {
+		t.Errorf("-stubs overwrote %s:\n%s", checks, got)
+	}
+
+	if code := run([]string{"-type=settings", "-dir", dir, "-output", "custom.go"}, &stdout, &stderr); code != 0 {
+		t.Fatalf("-output: exit %d, stderr:\n%s", code, &stderr)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "custom.go")); err != nil {
+		t.Errorf("-output: %v", err)
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	dir := t.TempDir()
+	for _, c := range []struct {
+		name   string
+		src    string // written to dir as p.go, if set
+		args   []string
+		code   int
+		stderr string
+	}{
+		{"no type", "", nil, 2, "usage: optgen [flags]\n"},
+		{"extra args", "", []string{"-type=config", "x"}, 2, "usage: optgen [flags]\n"},
+		{"bad flag", "", []string{"-nope"}, 2, "flag provided but not defined: -nope\n"},
+		{"not found", "", []string{"-type=missing", "-dir", filepath.Join("testdata", "bare")}, 1,
+			"optgen: struct type missing not found\n"},
+		{"no options", "package p\n\ntype config struct{ a int }\n", []string{"-type=config"}, 1,
+			"optgen: struct config has no opt-tagged fields\n"},
+		{"unknown rule", "package p\n\ntype config struct{ a int `opt:\"A,even\"` }\n", []string{"-type=config"}, 1,
+			`unknown rule "even"`},
+		{"optional value", "package p\n\ntype config struct{ a int `opt:\"A,optional\"` }\n", []string{"-type=config"}, 1,
+			"optional field must be a pointer"},
+		{"two names", "package p\n\ntype config struct{ a, b int `opt:\"A\"` }\n", []string{"-type=config"}, 1,
+			"tagged fields must declare exactly one name"},
+		{"not imported", "package p\n\ntype config struct{ a time.Duration `opt:\"A\"` }\n", []string{"-type=config"}, 1,
+			"package time is not imported"},
+	} {
+		args := c.args
+		if c.src != "" {
+			if err := os.WriteFile(filepath.Join(dir, "p.go"), []byte(c.src), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			args = append(args, "-dir", dir)
+		}
+		var stdout, stderr bytes.Buffer
+		code := run(args, &stdout, &stderr)
+		if code != c.code || !strings.Contains(stderr.String(), c.stderr) {
+			t.Errorf("%s: exit %d, stderr:\n%s\nwant exit %d, stderr with %q", c.name, code, &stderr, c.code, c.stderr)
+		}
+	}
+}
+
+func TestWords(t *testing.T) {
+	for _, c := range []struct{ in, want string }{
+		{"Port", "port"},
+		{"ReadTimeout", "read timeout"},
+		{"HTTPClient", "http client"},
+	} {
+		if got := words(c.in); got != c.want {
+			t.Errorf("words(%q) = %q, want %q", c.in, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+// Package all has a field for every rule optgen knows.
+package all
+
+import (
+	stdlog "log"
+	"time"
+)
+
+type settings struct {
+	name    string  `opt:"Name,nonzero"`
+	retries int     `opt:"Retries,positive"`
+	weight  float64 `opt:"Weight,nonnegative"`
+	// The option name defaults to the field's.
+	count int `opt:",nonzero"`
+	// Unset means no limit.
+	// Checked by checkLimit below.
+	limit *int `opt:"Limit,optional,check"`
+	// checkWindow is left for -stubs.
+	window time.Duration  `opt:"Window,check"`
+	logger *stdlog.Logger `opt:"Logger,nonnil"`
+	cache  map[string]int
+	id     string `json:"id"`
+}
+
+func (s *settings) setDefaults() {
+	s.retries = 3
+}
+
+func checkLimit(v int) error {
+	return nil
+}
@@ -0,0 +1,6 @@
+// Package bare has one option without rules, defaults or validation.
+package bare
+
+type server struct {
+	addr string `opt:"Addr"`
+}
@@ -0,0 +1,16 @@
+// Command patterncheck runs the repository's analyzers (exhaustive and
+// nilcheck) together, standalone or as a vet tool:
+//
+//	go build -o patterncheck patterns/cmd/patterncheck
+//	go vet -vettool=./patterncheck ./...
+//	./patterncheck ./...
+package main
+
+import (
+	"golang.org/x/tools/go/analysis/multichecker"
+
+	"patterns/analyzers/exhaustive"
+	"patterns/analyzers/nilcheck"
+)
+
+func main() { multichecker.Main(exhaustive.Analyzer, nilcheck.Analyzer) }
@@ -0,0 +1,69 @@
+// Command patterndoc generates a documentation site from the catalog and
+// the doc comments of each pattern's package.
+//
+// usage:
+//
+//	go run patterns/cmd/patterndoc -out site -format md
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"patterns/cli"
+)
+
+func main() {
+	cli.Main(run)
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	var root, out, format string
+	cmd := &cli.Command{
+		Name:  "patterndoc",
+		Short: "generate a documentation site from the catalog",
+		Flags: func(fs *flag.FlagSet) {
+			fs.StringVar(&root, "root", ".", "repository root")
+			fs.StringVar(&out, "out", "site", "output directory")
+			fs.StringVar(&format, "format", "md", "output format: md or html")
+		},
+		Run: func(env cli.Env, args []string) error {
+			if len(args) > 0 {
+				return cli.ErrUsage
+			}
+			return generate(root, out, format)
+		},
+	}
+	return cmd.Main(args, stdout, stderr)
+}
+
+func generate(root, out, format string) error {
+	site, err := buildSite(root)
+	if err != nil {
+		return err
+	}
+
+	var files map[string][]byte
+	switch format {
+	case "md":
+		files = site.markdown()
+	case "html":
+		files = site.html()
+	default:
+		return fmt.Errorf("unknown format %q", format)
+	}
+
+	for name, content := range files {
+		path := filepath.Join(out, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, content, 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
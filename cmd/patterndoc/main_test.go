@@ -0,0 +1,44 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"patterns/testing/golden"
+)
+
+// TestGenerate renders the site from this repository and compares the
+// index and a library and a command page in each format. Every page is
+// rendered by the same code, so a few of them stand for the rest
+// without a catalog change rewriting a golden file per pattern.
+func TestGenerate(t *testing.T) {
+	for _, c := range []struct {
+		format string
+		files  []string
+	}{
+		{"md", []string{"index.md", "functional-options.md", "url-shortener.md"}},
+		{"html", []string{"index.html", "functional-options.html", "url-shortener.html"}},
+	} {
+		t.Run(c.format, func(t *testing.T) {
+			out := t.TempDir()
+			if err := generate("../..", out, c.format); err != nil {
+				t.Fatal(err)
+			}
+			for _, name := range c.files {
+				got, err := os.ReadFile(filepath.Join(out, name))
+				if err != nil {
+					t.Fatal(err)
+				}
+				golden.Assert(t, c.format+"/"+name, got)
+			}
+		})
+	}
+}
+
+func TestGenerateUnknownFormat(t *testing.T) {
+	err := generate("../..", t.TempDir(), "pdf")
+	if err == nil || err.Error() != `unknown format "pdf"` {
+		t.Fatalf("generate(pdf) = %v, want unknown format", err)
+	}
+}
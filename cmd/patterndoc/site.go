@@ -0,0 +1,244 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/doc"
+	"go/doc/comment"
+	"go/parser"
+	"go/token"
+	"html/template"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"patterns/catalog"
+)
+
+// page is one pattern with its parsed package documentation.
+type page struct {
+	catalog.Pattern
+	doc     *comment.Doc
+	isMain  bool
+	exports []string
+}
+
+type site struct {
+	pages []page
+}
+
+func buildSite(root string) (*site, error) {
+	if err := catalog.Validate(); err != nil {
+		return nil, fmt.Errorf("catalog: %w", err)
+	}
+	var s site
+	for _, p := range catalog.All() {
+		pg, err := loadPage(root, p)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", p.Name, err)
+		}
+		s.pages = append(s.pages, pg)
+	}
+	return &s, nil
+}
+
+func loadPage(root string, p catalog.Pattern) (page, error) {
+	fset := token.NewFileSet()
+	dir := filepath.Join(root, p.Path)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return page{}, err
+	}
+	var files []*ast.File
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		if ok, err := build.Default.MatchFile(dir, name); err != nil || !ok {
+			// e.g. the *_invalid.go compile-failure demos
+			continue
+		}
+		f, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, parser.ParseComments)
+		if err != nil {
+			// snapshot-style files may not type-check; the doc comment is
+			// still usable, so only give up on syntax errors
+			return page{}, err
+		}
+		files = append(files, f)
+	}
+
+	pg := page{Pattern: p}
+	if len(files) == 0 {
+		return pg, nil
+	}
+	pkg, err := doc.NewFromFiles(fset, files, "patterns/"+p.Path, doc.AllDecls)
+	if err != nil {
+		return page{}, err
+	}
+	pg.doc = pkg.Parser().Parse(pkg.Doc)
+	pg.isMain = pkg.Name == "main"
+	for _, t := range pkg.Types {
+		if token.IsExported(t.Name) {
+			pg.exports = append(pg.exports, "type "+t.Name)
+		}
+		for _, f := range t.Funcs {
+			if token.IsExported(f.Name) {
+				pg.exports = append(pg.exports, "func "+f.Name)
+			}
+		}
+	}
+	for _, f := range pkg.Funcs {
+		if token.IsExported(f.Name) {
+			pg.exports = append(pg.exports, "func "+f.Name)
+		}
+	}
+	return pg, nil
+}
+
+func (pg page) runCommand() string {
+	if pg.Example != "" {
+		return "go run patterns/" + pg.Example
+	}
+	if pg.isMain {
+		return "go run patterns/" + pg.Path
+	}
+	return "go doc -all patterns/" + pg.Path
+}
+
+func (s *site) markdown() map[string][]byte {
+	files := map[string][]byte{}
+	var index bytes.Buffer
+	index.WriteString("# Patterns\n")
+	for _, cat := range catalog.Categories() {
+		fmt.Fprintf(&index, "\n## %s\n\n", cat)
+		for _, pg := range s.pages {
+			if pg.Category == cat {
+				fmt.Fprintf(&index, "- [%s](%s.md) — %s\n", pg.Name, pg.Name, pg.Summary)
+			}
+		}
+	}
+	index.WriteString("\n## Relationships\n\n```mermaid\n")
+	catalog.Mermaid(&index)
+	index.WriteString("```\n")
+	files["index.md"] = index.Bytes()
+	files["graph.dot"] = graphDOT()
+
+	for _, pg := range s.pages {
+		var b bytes.Buffer
+		fmt.Fprintf(&b, "# %s\n\n", pg.Name)
+		fmt.Fprintf(&b, "Category: %s", pg.Category)
+		if pg.Level != 0 {
+			fmt.Fprintf(&b, " · Level: %s", pg.Level)
+		}
+		fmt.Fprintf(&b, " · Path: `%s`\n\n%s\n", pg.Path, pg.Summary)
+
+		if len(pg.Pros)+len(pg.Cons) > 0 {
+			b.WriteString("\n| Pros | Cons |\n| --- | --- |\n")
+			for i := range max(len(pg.Pros), len(pg.Cons)) {
+				fmt.Fprintf(&b, "| %s | %s |\n", at(pg.Pros, i), at(pg.Cons, i))
+			}
+		}
+		if len(pg.Relations) > 0 {
+			b.WriteString("\n## Related\n\n")
+			for _, r := range pg.Relations {
+				fmt.Fprintf(&b, "- %s [%s](%s.md)\n", r.Kind, r.Target, r.Target)
+			}
+		}
+		if pg.doc != nil {
+			var p comment.Printer
+			b.WriteString("\n## Overview\n\n")
+			b.Write(p.Markdown(pg.doc))
+		}
+		if len(pg.exports) > 0 {
+			b.WriteString("\n## API\n\n")
+			for _, e := range pg.exports {
+				fmt.Fprintf(&b, "- `%s`\n", e)
+			}
+		}
+		fmt.Fprintf(&b, "\n## Try it\n\n```\n%s\n```\n", pg.runCommand())
+		files[pg.Name+".md"] = b.Bytes()
+	}
+	return files
+}
+
+var htmlPage = template.Must(template.New("page").Parse(`<!doctype html>
+<html><head><meta charset="utf-8"><title>{{.Title}}</title></head>
+<body>
+{{.Body}}
+</body></html>
+`))
+
+func (s *site) html() map[string][]byte {
+	files := map[string][]byte{}
+	render := func(title string, body template.HTML) []byte {
+		var b bytes.Buffer
+		htmlPage.Execute(&b, struct {
+			Title string
+			Body  template.HTML
+		}{title, body})
+		return b.Bytes()
+	}
+
+	var index strings.Builder
+	index.WriteString("<h1>Patterns</h1>\n")
+	for _, cat := range catalog.Categories() {
+		fmt.Fprintf(&index, "<h2>%s</h2>\n<ul>\n", template.HTMLEscapeString(string(cat)))
+		for _, pg := range s.pages {
+			if pg.Category == cat {
+				fmt.Fprintf(&index, "<li><a href=\"%s.html\">%s</a> — %s</li>\n",
+					pg.Name, pg.Name, template.HTMLEscapeString(pg.Summary))
+			}
+		}
+		index.WriteString("</ul>\n")
+	}
+	index.WriteString("<h2>Relationships</h2>\n<pre class=\"mermaid\">\n")
+	var graph strings.Builder
+	catalog.Mermaid(&graph)
+	index.WriteString(template.HTMLEscapeString(graph.String()))
+	index.WriteString("</pre>\n")
+	files["index.html"] = render("Patterns", template.HTML(index.String()))
+	files["graph.dot"] = graphDOT()
+
+	for _, pg := range s.pages {
+		var b strings.Builder
+		fmt.Fprintf(&b, "<h1>%s</h1>\n<p>%s</p>\n", pg.Name, template.HTMLEscapeString(pg.Summary))
+		if len(pg.Pros)+len(pg.Cons) > 0 {
+			b.WriteString("<table><tr><th>Pros</th><th>Cons</th></tr>\n")
+			for i := range max(len(pg.Pros), len(pg.Cons)) {
+				fmt.Fprintf(&b, "<tr><td>%s</td><td>%s</td></tr>\n",
+					template.HTMLEscapeString(at(pg.Pros, i)), template.HTMLEscapeString(at(pg.Cons, i)))
+			}
+			b.WriteString("</table>\n")
+		}
+		if len(pg.Relations) > 0 {
+			b.WriteString("<ul>\n")
+			for _, r := range pg.Relations {
+				fmt.Fprintf(&b, "<li>%s <a href=\"%s.html\">%s</a></li>\n", r.Kind, r.Target, r.Target)
+			}
+			b.WriteString("</ul>\n")
+		}
+		if pg.doc != nil {
+			var p comment.Printer
+			b.Write(p.HTML(pg.doc))
+		}
+		fmt.Fprintf(&b, "<pre>%s</pre>\n", template.HTMLEscapeString(pg.runCommand()))
+		files[pg.Name+".html"] = render(pg.Name, template.HTML(b.String()))
+	}
+	return files
+}
+
+func graphDOT() []byte {
+	var b bytes.Buffer
+	catalog.DOT(&b)
+	return b.Bytes()
+}
+
+func at(xs []string, i int) string {
+	if i < len(xs) {
+		return xs[i]
+	}
+	return ""
+}
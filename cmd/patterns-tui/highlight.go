@@ -0,0 +1,69 @@
+package main
+
+import (
+	"go/scanner"
+	"go/token"
+	"strings"
+)
+
+const (
+	ansiReset   = "\x1b[0m"
+	ansiBold    = "\x1b[1m"
+	ansiDim     = "\x1b[2m"
+	ansiKeyword = "\x1b[34m"
+	ansiString  = "\x1b[32m"
+	ansiComment = "\x1b[90m"
+)
+
+func bold(s string) string { return ansiBold + s + ansiReset }
+
+func dim(s string) string { return ansiDim + s + ansiReset }
+
+// highlight colors Go keywords, literals and comments with ANSI escapes.
+// Colors are applied per line so a scrolled window never starts inside
+// an unterminated escape sequence.
+func highlight(src []byte) string {
+	fset := token.NewFileSet()
+	file := fset.AddFile("", fset.Base(), len(src))
+	var s scanner.Scanner
+	s.Init(file, src, nil, scanner.ScanComments)
+
+	var b strings.Builder
+	last := 0
+	for {
+		pos, tok, lit := s.Scan()
+		if tok == token.EOF {
+			break
+		}
+		off := file.Offset(pos)
+		if off < last {
+			// automatically inserted semicolon
+			continue
+		}
+		var color string
+		text := lit
+		switch {
+		case tok.IsKeyword():
+			color, text = ansiKeyword, tok.String()
+		case tok == token.STRING || tok == token.CHAR:
+			color = ansiString
+		case tok == token.COMMENT:
+			color = ansiComment
+		default:
+			continue
+		}
+		if off+len(text) > len(src) || string(src[off:off+len(text)]) != text {
+			continue
+		}
+		b.Write(src[last:off])
+		for i, line := range strings.Split(text, "\n") {
+			if i > 0 {
+				b.WriteString("\n")
+			}
+			b.WriteString(color + line + ansiReset)
+		}
+		last = off + len(text)
+	}
+	b.Write(src[last:])
+	return b.String()
+}
@@ -0,0 +1,182 @@
+// Command patterns-tui is a terminal browser for the pattern catalog:
+// categories, descriptions, highlighted source, and shortcuts to run a
+// pattern or its benchmarks.
+//
+// It only uses the standard library; raw mode is set with stty, and
+// without a terminal it falls back to one command letter per line.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"go/build"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"patterns/cli"
+)
+
+// stdin is where keys are read from; raw mode is only set when it is a
+// file, i.e. a terminal.
+var stdin io.Reader = os.Stdin
+
+func main() {
+	cli.Main(run)
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	var root string
+	var height int
+	cmd := &cli.Command{
+		Name:  "patterns-tui",
+		Short: "browse the pattern catalog in the terminal",
+		Flags: func(fs *flag.FlagSet) {
+			fs.StringVar(&root, "root", ".", "repository root")
+			fs.IntVar(&height, "height", 30, "lines available for source view")
+		},
+		Run: func(env cli.Env, args []string) error {
+			if len(args) > 0 {
+				return cli.ErrUsage
+			}
+			return browse(env, root, height)
+		},
+	}
+	return cmd.Main(args, stdout, stderr)
+}
+
+// browse runs the key loop until quit or the end of input.
+func browse(env cli.Env, root string, height int) error {
+	restore := rawMode()
+	defer restore()
+
+	in := bufio.NewReader(stdin)
+	m := newModel(root, height)
+	for {
+		fmt.Fprint(env.Stdout, "\x1b[H\x1b[2J"+crlf(m.view()))
+		k, err := readKey(in)
+		if err != nil {
+			return ignoreEOF(err)
+		}
+		var act *action
+		m, act = m.update(k)
+		if act == nil {
+			continue
+		}
+		switch act.kind {
+		case "quit":
+			return nil
+		case "run":
+			// commands are run, libraries are shown with go doc
+			if pkg, err := build.ImportDir(filepath.Join(root, act.arg), 0); err == nil && pkg.Name == "main" {
+				runExternal(env, restore, "go", "run", "./"+act.arg)
+			} else {
+				runExternal(env, restore, "go", "doc", "-all", "./"+act.arg)
+			}
+		case "bench":
+			runExternal(env, restore, "go", "test", "-run", "^$", "-bench", ".", "-benchmem", "./"+act.arg)
+		}
+	}
+}
+
+// runExternal leaves raw mode while a child command owns the terminal.
+func runExternal(env cli.Env, restore func(), name string, args ...string) {
+	restore()
+	cmd := exec.Command(name, args...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = stdin, env.Stdout, env.Stderr
+	if err := cmd.Run(); err != nil {
+		fmt.Fprintln(env.Stdout, err)
+	}
+	fmt.Fprint(env.Stdout, "press enter to continue")
+	bufio.NewReader(stdin).ReadString('\n')
+	rawMode()
+}
+
+func rawMode() (restore func()) {
+	if _, ok := stdin.(*os.File); !ok {
+		return func() {}
+	}
+	if err := stty("raw", "-echo"); err != nil {
+		return func() {}
+	}
+	return func() { stty("-raw", "echo") }
+}
+
+func stty(args ...string) error {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = stdin
+	return cmd.Run()
+}
+
+// crlf is needed because raw mode disables output newline translation.
+func crlf(s string) string {
+	var out []byte
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\n' {
+			out = append(out, '\r')
+		}
+		out = append(out, s[i])
+	}
+	return string(out)
+}
+
+func readKey(in *bufio.Reader) (key, error) {
+	c, err := in.ReadByte()
+	if err != nil {
+		return keyOther, err
+	}
+	switch c {
+	case 'k':
+		return keyUp, nil
+	case 'j':
+		return keyDown, nil
+	case '\r', '\n', 'l':
+		return keyEnter, nil
+	case 'h', 127:
+		return keyBack, nil
+	case 'r':
+		return keyRun, nil
+	case 'b':
+		return keyBench, nil
+	case 'q', 3: // 3 is ctrl-c in raw mode
+		return keyQuit, nil
+	case 0x1b:
+		return readEscape(in)
+	}
+	return keyOther, nil
+}
+
+// readEscape decodes arrow keys (ESC [ A/B/C/D); a lone ESC means back.
+func readEscape(in *bufio.Reader) (key, error) {
+	if in.Buffered() == 0 {
+		return keyBack, nil
+	}
+	b, err := in.ReadByte()
+	if err != nil || b != '[' {
+		return keyBack, ignoreEOF(err)
+	}
+	c, err := in.ReadByte()
+	if err != nil {
+		return keyOther, err
+	}
+	switch c {
+	case 'A':
+		return keyUp, nil
+	case 'B':
+		return keyDown, nil
+	case 'C':
+		return keyEnter, nil
+	case 'D':
+		return keyBack, nil
+	}
+	return keyOther, nil
+}
+
+func ignoreEOF(err error) error {
+	if err == io.EOF {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+const clear = "\x1b[H\x1b[2J"
+
+func runWith(t *testing.T, input string, args ...string) (code int, frames []string, stderr string) {
+	t.Helper()
+	defer func(r io.Reader) { stdin = r }(stdin)
+	stdin = strings.NewReader(input)
+	var out, errOut bytes.Buffer
+	code = run(args, &out, &errOut)
+	frames = strings.Split(out.String(), clear)[1:]
+	return code, frames, errOut.String()
+}
+
+func TestRun(t *testing.T) {
+	for _, c := range []struct {
+		name   string
+		input  string
+		frames int
+	}{
+		{"quit", "q", 1},
+		{"move then quit", "jq", 2},
+		{"ctrl-c", "j\x03", 2},
+		{"end of input", "jj", 3},
+	} {
+		code, frames, stderr := runWith(t, c.input, "-height", "5")
+		if code != 0 || stderr != "" {
+			t.Errorf("%s: exit %d, stderr %q", c.name, code, stderr)
+		}
+		if len(frames) != c.frames {
+			t.Errorf("%s: %d frames, want %d", c.name, len(frames), c.frames)
+			continue
+		}
+		for i, f := range frames {
+			if strings.Contains(strings.ReplaceAll(f, "\r\n", ""), "\n") {
+				t.Errorf("%s: frame %d has a bare newline", c.name, i)
+			}
+		}
+	}
+
+	// moving down changes the view
+	_, frames, _ := runWith(t, "jq")
+	if len(frames) == 2 && frames[0] == frames[1] {
+		t.Error("the frame after j is the same as the first")
+	}
+}
+
+func TestRunUsage(t *testing.T) {
+	for _, args := range [][]string{{"extra"}, {"-nope"}} {
+		code, _, stderr := runWith(t, "q", args...)
+		if code != 2 || !strings.Contains(stderr, "usage: patterns-tui [flags]") {
+			t.Errorf("%q: exit %d, stderr %q", args, code, stderr)
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"patterns/catalog"
+)
+
+type screen int
+
+const (
+	screenCategories screen = iota
+	screenPatterns
+	screenDetail
+	screenSource
+)
+
+type key int
+
+const (
+	keyUp key = iota
+	keyDown
+	keyEnter
+	keyBack
+	keyRun
+	keyBench
+	keyQuit
+	keyOther
+)
+
+// action is a side effect requested by the model; main performs it so the
+// model stays pure and easy to drive in tests.
+type action struct {
+	kind string // "run", "bench" or "quit"
+	arg  string
+}
+
+// model is the whole UI state. update never does I/O except reading the
+// source files it is asked to show.
+type model struct {
+	root string
+
+	screen   screen
+	category int
+	pattern  int
+	file     int
+	scroll   int
+	height   int
+
+	files  []string
+	source []string
+	status string
+}
+
+func newModel(root string, height int) model {
+	return model{root: root, height: height}
+}
+
+func (m model) categories() []catalog.Category { return catalog.Categories() }
+
+func (m model) patterns() []catalog.Pattern {
+	cats := m.categories()
+	if len(cats) == 0 {
+		return nil
+	}
+	var out []catalog.Pattern
+	for _, p := range catalog.All() {
+		if p.Category == cats[m.category] {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+func (m model) current() catalog.Pattern {
+	return m.patterns()[m.pattern]
+}
+
+func (m model) update(k key) (model, *action) {
+	m.status = ""
+	switch k {
+	case keyQuit:
+		return m, &action{kind: "quit"}
+	case keyUp:
+		m = m.move(-1)
+	case keyDown:
+		m = m.move(1)
+	case keyEnter:
+		m = m.enter()
+	case keyBack:
+		if m.screen > screenCategories {
+			m.screen--
+		}
+	case keyRun:
+		if m.screen >= screenPatterns {
+			return m, &action{kind: "run", arg: cmp.Or(m.current().Example, m.current().Path)}
+		}
+	case keyBench:
+		if m.screen >= screenPatterns {
+			return m, &action{kind: "bench", arg: m.current().Path}
+		}
+	}
+	return m, nil
+}
+
+func (m model) move(delta int) model {
+	clamp := func(v, n int) int { return max(0, min(v, n-1)) }
+	switch m.screen {
+	case screenCategories:
+		m.category = clamp(m.category+delta, len(m.categories()))
+		m.pattern = 0
+	case screenPatterns:
+		m.pattern = clamp(m.pattern+delta, len(m.patterns()))
+	case screenDetail:
+		m.file = clamp(m.file+delta, len(m.files))
+	case screenSource:
+		m.scroll = clamp(m.scroll+delta, max(1, len(m.source)-m.height+2))
+	}
+	return m
+}
+
+func (m model) enter() model {
+	switch m.screen {
+	case screenCategories:
+		if len(m.categories()) > 0 {
+			m.screen = screenPatterns
+		}
+	case screenPatterns:
+		m.screen = screenDetail
+		m.file = 0
+		m.files = goFiles(filepath.Join(m.root, m.current().Path))
+	case screenDetail:
+		if len(m.files) == 0 {
+			m.status = "no source files"
+			return m
+		}
+		src, err := os.ReadFile(filepath.Join(m.root, m.current().Path, m.files[m.file]))
+		if err != nil {
+			m.status = err.Error()
+			return m
+		}
+		m.screen = screenSource
+		m.scroll = 0
+		m.source = strings.Split(highlight(src), "\n")
+	}
+	return m
+}
+
+func goFiles(dir string) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var out []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), ".go") {
+			out = append(out, e.Name())
+		}
+	}
+	slices.Sort(out)
+	return out
+}
+
+func (m model) view() string {
+	var b strings.Builder
+	list := func(items []string, selected int) {
+		for i, item := range items {
+			cursor := "  "
+			if i == selected {
+				cursor = "> "
+			}
+			b.WriteString(cursor + item + "\n")
+		}
+	}
+
+	switch m.screen {
+	case screenCategories:
+		b.WriteString(bold("Categories") + "\n\n")
+		var names []string
+		for _, c := range m.categories() {
+			names = append(names, string(c))
+		}
+		list(names, m.category)
+	case screenPatterns:
+		b.WriteString(bold(string(m.categories()[m.category])) + "\n\n")
+		var names []string
+		for _, p := range m.patterns() {
+			names = append(names, fmt.Sprintf("%-22s %s", p.Name, p.Summary))
+		}
+		list(names, m.pattern)
+	case screenDetail:
+		p := m.current()
+		b.WriteString(bold(p.Name) + "  " + p.Path + "\n\n" + p.Summary + "\n")
+		if p.Level != 0 {
+			b.WriteString("Level: " + p.Level.String() + "\n")
+		}
+		for _, pro := range p.Pros {
+			b.WriteString("  + " + pro + "\n")
+		}
+		for _, con := range p.Cons {
+			b.WriteString("  - " + con + "\n")
+		}
+		b.WriteString("\nFiles:\n")
+		list(m.files, m.file)
+	case screenSource:
+		b.WriteString(bold(m.current().Path+"/"+m.files[m.file]) + "\n\n")
+		end := min(len(m.source), m.scroll+m.height-2)
+		for _, line := range m.source[m.scroll:end] {
+			b.WriteString(line + "\n")
+		}
+	}
+
+	b.WriteString("\n" + dim("↑/k ↓/j move · enter open · esc/h back · r run · b bench · q quit") + "\n")
+	if m.status != "" {
+		b.WriteString(m.status + "\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bufio"
+	"slices"
+	"strings"
+	"testing"
+
+	"patterns/catalog"
+)
+
+// press feeds keys to m and returns the model and the last action.
+func press(m model, keys ...key) (model, *action) {
+	var act *action
+	for _, k := range keys {
+		m, act = m.update(k)
+	}
+	return m, act
+}
+
+// open walks from the categories screen to the detail screen of the
+// named pattern.
+func open(t *testing.T, m model, name string) model {
+	t.Helper()
+	p, ok := catalog.Lookup(name)
+	if !ok {
+		t.Fatalf("no pattern %q", name)
+	}
+	for range slices.Index(m.categories(), p.Category) {
+		m, _ = m.update(keyDown)
+	}
+	m, _ = m.update(keyEnter)
+	for m.current().Name != name {
+		m, _ = m.update(keyDown)
+	}
+	m, _ = m.update(keyEnter)
+	if m.screen != screenDetail {
+		t.Fatalf("screen = %d, want detail", m.screen)
+	}
+	return m
+}
+
+func TestMoveClamps(t *testing.T) {
+	m := newModel("../..", 10)
+	m, _ = press(m, keyUp, keyUp)
+	if m.category != 0 {
+		t.Errorf("category after up = %d, want 0", m.category)
+	}
+	n := len(m.categories())
+	for range n + 3 {
+		m, _ = m.update(keyDown)
+	}
+	if m.category != n-1 {
+		t.Errorf("category after down past the end = %d, want %d", m.category, n-1)
+	}
+}
+
+func TestChangingCategoryResetsPattern(t *testing.T) {
+	m, _ := press(newModel("../..", 10), keyEnter, keyDown, keyDown, keyBack, keyDown)
+	if m.pattern != 0 {
+		t.Errorf("pattern = %d, want 0 in the new category", m.pattern)
+	}
+}
+
+func TestDetail(t *testing.T) {
+	m := open(t, newModel("../..", 10), "functional-options")
+	if !slices.Contains(m.files, "functional.go") {
+		t.Errorf("files = %v, want functional.go among them", m.files)
+	}
+	if !slices.IsSorted(m.files) {
+		t.Errorf("files = %v, want them sorted", m.files)
+	}
+	view := m.view()
+	for _, want := range []string{"functional-options", "options/functional", "Level: ", "+ readable", "> "} {
+		if !strings.Contains(view, want) {
+			t.Errorf("detail view lacks %q:\n%s", want, view)
+		}
+	}
+}
+
+func TestSourceScrolls(t *testing.T) {
+	m := open(t, newModel("../..", 10), "functional-options")
+	m, _ = m.update(keyEnter)
+	if m.screen != screenSource {
+		t.Fatalf("screen = %d, want source (status %q)", m.screen, m.status)
+	}
+	if got := strings.Count(m.view(), "\n"); got > m.height+4 {
+		t.Errorf("source view is %d lines, want at most height+4 = %d", got, m.height+4)
+	}
+	for range len(m.source) + 5 {
+		m, _ = m.update(keyDown)
+	}
+	if want := len(m.source) - m.height + 1; m.scroll != want {
+		t.Errorf("scroll past the end = %d, want %d", m.scroll, want)
+	}
+	m, _ = press(m, keyBack, keyBack, keyBack)
+	if m.screen != screenCategories {
+		t.Errorf("screen after three backs = %d, want categories", m.screen)
+	}
+	m, _ = m.update(keyBack)
+	if m.screen != screenCategories {
+		t.Errorf("back on categories moved to screen %d", m.screen)
+	}
+}
+
+func TestNoSourceFiles(t *testing.T) {
+	// a root without the packages leaves every pattern without files
+	m := open(t, newModel(t.TempDir(), 10), "functional-options")
+	m, _ = m.update(keyEnter)
+	if m.screen != screenDetail || m.status != "no source files" {
+		t.Errorf("screen %d, status %q, want detail with no source files", m.screen, m.status)
+	}
+	if m, _ = m.update(keyDown); m.status != "" {
+		t.Errorf("status %q survived the next key", m.status)
+	}
+}
+
+func TestActions(t *testing.T) {
+	m := newModel("../..", 10)
+	if _, act := m.update(keyRun); act != nil {
+		t.Errorf("run on categories = %+v, want nothing", act)
+	}
+	if _, act := m.update(keyQuit); act == nil || act.kind != "quit" {
+		t.Errorf("quit = %+v", act)
+	}
+
+	m = open(t, m, "functional-options")
+	p := m.current()
+	if _, act := m.update(keyRun); act == nil || *act != (action{"run", p.Example}) {
+		t.Errorf("run = %+v, want the example %s", act, p.Example)
+	}
+	if _, act := m.update(keyBench); act == nil || *act != (action{"bench", p.Path}) {
+		t.Errorf("bench = %+v, want the package %s", act, p.Path)
+	}
+
+	// without an example, run shows the package itself
+	m = open(t, newModel("../..", 10), "funcopts")
+	if _, act := m.update(keyRun); act == nil || *act != (action{"run", m.current().Path}) {
+		t.Errorf("run = %+v, want the package %s", act, m.current().Path)
+	}
+}
+
+func TestReadKey(t *testing.T) {
+	for _, c := range []struct {
+		in   string
+		want []key
+	}{
+		{"kjlhrbq", []key{keyUp, keyDown, keyEnter, keyBack, keyRun, keyBench, keyQuit}},
+		{"\r\n\x7f\x03x", []key{keyEnter, keyEnter, keyBack, keyQuit, keyOther}},
+		{"\x1b[A\x1b[B\x1b[C\x1b[D\x1b[Z", []key{keyUp, keyDown, keyEnter, keyBack, keyOther}},
+		{"\x1b", []key{keyBack}},
+		{"\x1bx", []key{keyBack}},
+	} {
+		in := bufio.NewReader(strings.NewReader(c.in))
+		var got []key
+		for {
+			k, err := readKey(in)
+			if err != nil {
+				break
+			}
+			got = append(got, k)
+		}
+		if !slices.Equal(got, c.want) {
+			t.Errorf("readKey(%q) = %v, want %v", c.in, got, c.want)
+		}
+	}
+}
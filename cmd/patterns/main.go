@@ -0,0 +1,277 @@
+// Command patterns is the entry point for working with the catalog.
+//
+// usage:
+//
+//	patterns list [-category c] [-level l] [-json]   list patterns
+//	patterns show <pattern>                          describe one pattern
+//	patterns run <pattern> [args...]                 run its example
+//	patterns export [-schema]                        print the catalog as JSON
+//	patterns import <file>                           validate an exported catalog
+//	patterns check [exercise]                        report exercise progress
+//
+// run builds and runs the pattern's example with go run, from -root, the
+// repository root; arguments after the pattern's name go to the example.
+// export writes the stable form of catalog.Export, or with -schema its
+// JSON Schema; import reads one back, - for stdin, and reports every
+// problem it finds.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"patterns/catalog"
+	"patterns/cli"
+	"patterns/errors/hints"
+	"patterns/exercises"
+	"patterns/idioms/enum"
+)
+
+var root = "."
+
+var app = &cli.App{
+	Name:  "patterns",
+	Short: "Work with the pattern catalog and exercises.",
+	Globals: func(fs *flag.FlagSet) {
+		fs.StringVar(&root, "root", root, "repository root")
+	},
+	Commands: []*cli.Command{
+		{Name: "list", Short: "list patterns", Flags: listFlags, Run: runList},
+		{Name: "show", Args: "<pattern>", Short: "describe one pattern", Run: runShow},
+		{Name: "run", Args: "<pattern> [args...]", Short: "run a pattern's example", Run: runExample},
+		{Name: "export", Short: "print the catalog as JSON", Flags: exportFlags, Run: runExport},
+		{Name: "import", Args: "<file>", Short: "validate an exported catalog", Run: runImport},
+		{Name: "check", Args: "[exercise]", Short: "report exercise progress", Run: runCheck},
+	},
+}
+
+var codes hints.Registry
+
+// exercisesAll is what check reports on; tests replace it so that their
+// output does not depend on how far the skeletons have been filled in.
+var exercisesAll = exercises.All
+
+var (
+	errUnknownPattern = codes.Define(hints.Def{
+		Code:    "UNKNOWN_PATTERN",
+		Message: "no pattern named {name}",
+		Hint:    "patterns list shows every name",
+		Exit:    2,
+	})
+	errNoExample = codes.Define(hints.Def{
+		Code:    "NO_EXAMPLE",
+		Message: "{name} has no runnable example",
+		Hint:    "read its documentation with go doc -all patterns/{path}",
+	})
+)
+
+func main() {
+	cli.Main(run)
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	return app.Run(args, stdout, stderr)
+}
+
+var list struct {
+	category, level string
+	json            bool
+}
+
+func listFlags(fs *flag.FlagSet) {
+	fs.StringVar(&list.category, "category", "", "only patterns of this category")
+	fs.StringVar(&list.level, "level", "", "only patterns of this level: good, average or poor")
+	fs.BoolVar(&list.json, "json", false, "print the catalog entries as JSON")
+}
+
+func runList(env cli.Env, args []string) error {
+	if len(args) > 0 {
+		return cli.ErrUsage
+	}
+	ps := catalog.All()
+	if list.category != "" {
+		c, err := catalog.ParseCategory(list.category)
+		if err != nil {
+			return err
+		}
+		ps = catalog.InCategory(c)
+	}
+	if list.level != "" {
+		l, err := enum.ParseLevel(list.level)
+		if err != nil {
+			return err
+		}
+		var graded []catalog.Pattern
+		for _, p := range ps {
+			if p.Level == l {
+				graded = append(graded, p)
+			}
+		}
+		ps = graded
+	}
+
+	if list.json {
+		enc := json.NewEncoder(env.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(ps)
+	}
+	tw := tabwriter.NewWriter(env.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tCATEGORY\tLEVEL\tEXAMPLE")
+	for _, p := range ps {
+		level, example := "-", "-"
+		if p.Level != 0 {
+			level = p.Level.String()
+		}
+		if p.Example != "" {
+			example = p.Example
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", p.Name, p.Category, level, example)
+	}
+	return tw.Flush()
+}
+
+func lookup(name string) (catalog.Pattern, error) {
+	p, ok := catalog.Lookup(name)
+	if !ok {
+		return p, errUnknownPattern.New(nil, "name", name)
+	}
+	return p, nil
+}
+
+func runShow(env cli.Env, args []string) error {
+	if len(args) != 1 {
+		return cli.ErrUsage
+	}
+	p, err := lookup(args[0])
+	if err != nil {
+		return err
+	}
+	w := env.Stdout
+	fmt.Fprintf(w, "%s (%s", p.Name, p.Category)
+	if p.Level != 0 {
+		fmt.Fprintf(w, ", %s", p.Level)
+	}
+	fmt.Fprintf(w, ")\n  %s\n\n", p.Summary)
+	fmt.Fprintf(w, "package  patterns/%s\n", p.Path)
+	if p.Example != "" {
+		fmt.Fprintf(w, "example  patterns run %s   (go run patterns/%s)\n", p.Name, p.Example)
+	}
+	for _, pro := range p.Pros {
+		fmt.Fprintf(w, "pro      %s\n", pro)
+	}
+	for _, con := range p.Cons {
+		fmt.Fprintf(w, "con      %s\n", con)
+	}
+	for _, r := range p.Relations {
+		fmt.Fprintf(w, "%s %s\n", r.Kind, r.Target)
+	}
+	return nil
+}
+
+func runExample(env cli.Env, args []string) error {
+	if len(args) == 0 {
+		return cli.ErrUsage
+	}
+	p, err := lookup(args[0])
+	if err != nil {
+		return err
+	}
+	if p.Example == "" {
+		return errNoExample.New(nil, "name", p.Name, "path", p.Path)
+	}
+	cmd := exec.Command("go", append([]string{"run", "./" + filepath.ToSlash(p.Example)}, args[1:]...)...)
+	cmd.Dir = root
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, env.Stdout, env.Stderr
+	err = cmd.Run()
+	var exit *exec.ExitError
+	if errors.As(err, &exit) {
+		// the example said what went wrong; pass its status on
+		return &cli.ExitError{Code: exit.ExitCode()}
+	}
+	return err
+}
+
+var exportSchema bool
+
+func exportFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&exportSchema, "schema", false, "print the JSON Schema of the export instead")
+}
+
+func runExport(env cli.Env, args []string) error {
+	if len(args) > 0 {
+		return cli.ErrUsage
+	}
+	if exportSchema {
+		_, err := env.Stdout.Write(catalog.Schema)
+		return err
+	}
+	return catalog.Export().Encode(env.Stdout)
+}
+
+func runImport(env cli.Env, args []string) error {
+	if len(args) != 1 {
+		return cli.ErrUsage
+	}
+	var r io.Reader = os.Stdin
+	if args[0] != "-" {
+		f, err := os.Open(args[0])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+	d, err := catalog.Import(r)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(env.Stdout, "valid: %d patterns in %d categories, %d relations\n", len(d.Patterns), len(d.Categories), len(d.Relations))
+	return nil
+}
+
+func runCheck(env cli.Env, args []string) error {
+	if len(args) > 1 {
+		return cli.ErrUsage
+	}
+	total, passed := 0, 0
+	for _, ex := range exercisesAll() {
+		if len(args) > 0 && args[0] != ex.Name {
+			continue
+		}
+		results := ex.Run()
+		ok := 0
+		for _, r := range results {
+			if r.Err == nil {
+				ok++
+			}
+		}
+		fmt.Fprintf(env.Stdout, "%-20s %d/%d  (%s)\n", ex.Name, ok, len(results), ex.Path)
+		for _, r := range results {
+			if r.Err != nil {
+				// errors.Join and testoptions put one error per line;
+				// keep the later lines under the check they belong to
+				msg := strings.ReplaceAll(r.Err.Error(), "\n", "\n        ")
+				fmt.Fprintf(env.Stdout, "    FAIL %s: %s\n", r.Check, msg)
+			}
+		}
+		total += len(results)
+		passed += ok
+	}
+	if total == 0 && len(args) > 0 {
+		return fmt.Errorf("unknown exercise %q", args[0])
+	}
+
+	fmt.Fprintf(env.Stdout, "\n%d/%d checks passing\n", passed, total)
+	if passed < total {
+		return &cli.ExitError{Code: 1}
+	}
+	return nil
+}
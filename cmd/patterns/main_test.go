@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+
+	"patterns/exercises/check"
+	"patterns/testing/golden"
+)
+
+// invoke runs the command with args and returns its stdout, stderr and
+// exit code as the content of a golden file.
+func invoke(args ...string) []byte {
+	var stdout, stderr bytes.Buffer
+	code := run(args, &stdout, &stderr)
+	var b bytes.Buffer
+	b.Write(stdout.Bytes())
+	fmt.Fprintf(&b, "--- stderr\n%s--- exit %d\n", stderr.Bytes(), code)
+	return b.Bytes()
+}
+
+func fixedExercises() []check.Exercise {
+	ok := func() error { return nil }
+	return []check.Exercise{
+		{Name: "done", Path: "exercises/done", Checks: []check.Check{{Name: "works", Run: ok}}},
+		{Name: "partial", Path: "exercises/partial", Checks: []check.Check{
+			{Name: "works", Run: ok},
+			{Name: "two problems", Run: func() error {
+				return errors.Join(errors.New("first"), errors.New("second"))
+			}},
+			{Name: "panics", Run: func() error { panic("not implemented") }},
+		}},
+	}
+}
+
+// TestCheck reports on fixed exercises; partial joins two errors, each
+// on its own line under the check.
+func TestCheck(t *testing.T) {
+	saved := exercisesAll
+	exercisesAll = fixedExercises
+	t.Cleanup(func() { exercisesAll = saved })
+	for _, c := range []struct {
+		name string
+		args []string
+	}{
+		{"check", []string{"check"}},
+		{"check-done", []string{"check", "done"}},
+		{"check-one", []string{"check", "partial"}},
+		{"check-unknown", []string{"check", "nope"}},
+		{"check-args", []string{"check", "a", "b"}},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			golden.Assert(t, c.name, invoke(c.args...))
+		})
+	}
+}
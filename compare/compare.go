@@ -0,0 +1,85 @@
+// Package compare sets hand-rolled patterns from this repository beside
+// the well-known library each one stands in for, behind one interface per
+// pair, and checks that they behave the same:
+//
+//   - retry (resilience/retry) and github.com/cenkalti/backoff/v5
+//   - singleflight (a toy here; security/tokenrefresh has the one-key
+//     form) and golang.org/x/sync/singleflight
+//   - errgroup (concurrency/structured) and golang.org/x/sync/errgroup
+//   - lru (caching/lru) and github.com/hashicorp/golang-lru/v2
+//
+// It is a module of its own so that the repository itself keeps to the
+// standard library; its tests run every check, and -v lists what each
+// library adds:
+//
+//	cd compare && go test -v .
+//
+// Each check is a differential case: one scenario run against both, the
+// toy and the library, and the same observations required of each. What
+// a pair does not check is listed with it: what the library adds, which
+// is why reaching for it is usually right outside a book of patterns, and
+// where the two differ by design.
+package compare
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"patterns/testing/contracts"
+)
+
+// Pair is a hand-rolled pattern and its library counterpart.
+type Pair struct {
+	Name string
+	// Toy is the package in this repository, Library the module it is
+	// compared with.
+	Toy, Library string
+	// Adds is what the library offers that the toy does not.
+	Adds []string
+	// Differs is behaviour that differs on purpose, and is not checked.
+	Differs []string
+	Cases   []contracts.Case
+}
+
+// Same fails t unless the toy and the library observed the same. A
+// trace, an observation of many lines, is reported by its first
+// difference.
+func Same[V any](t testing.TB, what string, toy, library V) {
+	t.Helper()
+	if reflect.DeepEqual(toy, library) {
+		return
+	}
+	if a, ok := any(toy).(string); ok && strings.Contains(a, "\n") {
+		b := any(library).(string)
+		al, bl := strings.Split(a, "\n"), strings.Split(b, "\n")
+		for i := range min(len(al), len(bl)) {
+			if al[i] != bl[i] {
+				t.Fatalf("%s: line %d: toy %q, library %q", what, i+1, al[i], bl[i])
+			}
+		}
+		t.Fatalf("%s: toy has %d lines, library %d", what, len(al), len(bl))
+	}
+	t.Fatalf("%s: toy %v, library %v", what, toy, library)
+}
+
+// Expect fails t unless got is want, for the observations both should
+// make, and already have made the same.
+func Expect[V comparable](t testing.TB, what string, got, want V) {
+	t.Helper()
+	if got != want {
+		t.Fatalf("%s = %v, want %v", what, got, want)
+	}
+}
+
+// Both runs scenario against toy and library and requires the same
+// observation; Case names it.
+func Both[I, O any](name string, toy, library I, scenario func(t testing.TB, impl I) O, check ...func(t testing.TB, o O)) contracts.Case {
+	return contracts.Case{Name: name, Test: func(t testing.TB) {
+		a, b := scenario(t, toy), scenario(t, library)
+		Same(t, "observed", a, b)
+		for _, c := range check {
+			c(t, a)
+		}
+	}}
+}
@@ -0,0 +1,29 @@
+package compare_test
+
+import (
+	"testing"
+
+	"patterns/compare"
+	"patterns/compare/errgroup"
+	"patterns/compare/lru"
+	"patterns/compare/retry"
+	"patterns/compare/singleflight"
+	"patterns/testing/contracts"
+)
+
+// TestPairs runs the differential cases of every pair; -v lists what each
+// library adds to its toy, and where the two differ by design.
+func TestPairs(t *testing.T) {
+	for _, p := range []compare.Pair{retry.Pair, singleflight.Pair, errgroup.Pair, lru.Pair} {
+		t.Run(p.Name, func(t *testing.T) {
+			t.Logf("%s vs %s", p.Toy, p.Library)
+			for _, a := range p.Adds {
+				t.Logf("+ %s", a)
+			}
+			for _, d := range p.Differs {
+				t.Logf("~ %s", d)
+			}
+			contracts.Run(t, p.Cases)
+		})
+	}
+}
@@ -0,0 +1,127 @@
+// Package errgroup compares concurrency/structured.Group with
+// golang.org/x/sync/errgroup.Group.
+package errgroup
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+
+	"patterns/compare"
+	"patterns/concurrency/structured"
+	"patterns/testing/contracts"
+)
+
+// Group is what both provide.
+type Group interface {
+	Go(f func() error)
+	TryGo(f func() error) bool
+	SetLimit(n int)
+	Wait() error
+}
+
+// Impl makes groups of one implementation.
+type Impl struct {
+	WithContext func(ctx context.Context) (Group, context.Context)
+	// Zero returns a zero-value group, without a context.
+	Zero func() Group
+}
+
+var (
+	Toy = Impl{
+		WithContext: func(ctx context.Context) (Group, context.Context) { return structured.WithContext(ctx) },
+		Zero:        func() Group { return new(structured.Group) },
+	}
+	Library = Impl{
+		WithContext: func(ctx context.Context) (Group, context.Context) { return errgroup.WithContext(ctx) },
+		Zero:        func() Group { return new(errgroup.Group) },
+	}
+)
+
+var errFirst = errors.New("first")
+
+var Pair = compare.Pair{
+	Name:    "errgroup",
+	Toy:     "patterns/concurrency/structured",
+	Library: "golang.org/x/sync/errgroup",
+	Adds:    []string{"years of review and production use; the API is the same"},
+	Differs: []string{
+		"a panic is raised again from Wait by the toy, but crashes the process in errgroup, " +
+			"which keeps the panicking goroutine's stack for crash tooling and cannot hang on a Wait never reached",
+	},
+	Cases: []contracts.Case{
+		compare.Both("all succeed", Toy, Library, func(t testing.TB, impl Impl) [2]any {
+			g, _ := impl.WithContext(context.Background())
+			var n atomic.Int32
+			for range 10 {
+				g.Go(func() error { n.Add(1); return nil })
+			}
+			err := g.Wait()
+			return [2]any{err == nil, n.Load()}
+		}, func(t testing.TB, o [2]any) {
+			compare.Expect(t, "Wait returned nil after 10 calls", o, [2]any{true, int32(10)})
+		}),
+		compare.Both("context done after Wait", Toy, Library, func(t testing.TB, impl Impl) bool {
+			g, ctx := impl.WithContext(context.Background())
+			g.Go(func() error { return nil })
+			g.Wait()
+			return ctx.Err() != nil
+		}),
+		compare.Both("first error wins and cancels the rest", Toy, Library, func(t testing.TB, impl Impl) [3]bool {
+			g, ctx := impl.WithContext(context.Background())
+			g.Go(func() error {
+				<-ctx.Done()
+				return errors.New("second")
+			})
+			g.Go(func() error { return errFirst })
+			err := g.Wait()
+			return [3]bool{err == errFirst, errors.Is(ctx.Err(), context.Canceled), context.Cause(ctx) == errFirst}
+		}, func(t testing.TB, o [3]bool) {
+			compare.Expect(t, "first error returned, context cancelled with it as the cause", o, [3]bool{true, true, true})
+		}),
+		compare.Both("limit bounds concurrency", Toy, Library, func(t testing.TB, impl Impl) [2]any {
+			g, _ := impl.WithContext(context.Background())
+			g.SetLimit(3)
+			var running, peak, done atomic.Int32
+			for range 20 {
+				g.Go(func() error {
+					n := running.Add(1)
+					for p := peak.Load(); n > p && !peak.CompareAndSwap(p, n); p = peak.Load() {
+					}
+					time.Sleep(time.Millisecond)
+					running.Add(-1)
+					done.Add(1)
+					return nil
+				})
+			}
+			g.Wait()
+			return [2]any{peak.Load() <= 3, done.Load()}
+		}, func(t testing.TB, o [2]any) {
+			compare.Expect(t, "at most 3 at once, all 20 run", o, [2]any{true, int32(20)})
+		}),
+		compare.Both("TryGo refuses when full", Toy, Library, func(t testing.TB, impl Impl) [3]bool {
+			g, _ := impl.WithContext(context.Background())
+			g.SetLimit(1)
+			release := make(chan struct{})
+			first := g.TryGo(func() error { <-release; return nil })
+			second := g.TryGo(func() error { return nil })
+			close(release)
+			g.Wait()
+			return [3]bool{first, second, g.TryGo(func() error { return nil })}
+		}, func(t testing.TB, o [3]bool) {
+			compare.Expect(t, "TryGo: free, full, free again after Wait", o, [3]bool{true, false, true})
+		}),
+		compare.Both("zero value keeps the first error", Toy, Library, func(t testing.TB, impl Impl) bool {
+			g := impl.Zero()
+			g.Go(func() error { return errFirst })
+			g.Go(func() error { return nil })
+			return g.Wait() == errFirst
+		}, func(t testing.TB, o bool) {
+			compare.Expect(t, "Wait returned the error", o, true)
+		}),
+	},
+}
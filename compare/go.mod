@@ -0,0 +1,13 @@
+module patterns/compare
+
+go 1.23.5
+
+require (
+	github.com/hashicorp/golang-lru/v2 v2.0.7
+	golang.org/x/sync v0.16.0
+	patterns v0.0.0
+)
+
+require github.com/cenkalti/backoff/v5 v5.0.3
+
+replace patterns => ../
@@ -0,0 +1,139 @@
+// Package lru compares caching/lru.Cache with
+// github.com/hashicorp/golang-lru/v2.Cache.
+package lru
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+
+	hashicorp "github.com/hashicorp/golang-lru/v2"
+
+	"patterns/caching/lru"
+	"patterns/compare"
+	"patterns/randsource"
+	"patterns/testing/contracts"
+)
+
+// Cache is what both provide, over string keys and int values.
+type Cache interface {
+	Add(key string, v int)
+	Get(key string) (int, bool)
+	Remove(key string)
+	Len() int
+}
+
+// Impl makes a new, empty cache of capacity entries.
+type Impl func(t testing.TB, capacity int) Cache
+
+var (
+	Toy Impl = func(t testing.TB, capacity int) Cache {
+		c, err := lru.New[string, int](capacity)
+		if err != nil {
+			t.Fatalf("lru.New: %v", err)
+		}
+		return c
+	}
+	Library Impl = func(t testing.TB, capacity int) Cache {
+		c, err := hashicorp.New[string, int](capacity)
+		if err != nil {
+			t.Fatalf("golang-lru New: %v", err)
+		}
+		return library{c}
+	}
+)
+
+// library drops the results Add and Remove report.
+type library struct{ c *hashicorp.Cache[string, int] }
+
+func (l library) Add(key string, v int)      { l.c.Add(key, v) }
+func (l library) Get(key string) (int, bool) { return l.c.Get(key) }
+func (l library) Remove(key string)          { l.c.Remove(key) }
+func (l library) Len() int                   { return l.c.Len() }
+
+var Pair = compare.Pair{
+	Name:    "lru",
+	Toy:     "patterns/caching/lru",
+	Library: "github.com/hashicorp/golang-lru/v2",
+	Adds: []string{
+		"an eviction callback, and Add reporting whether it evicted",
+		"Peek, Contains, Keys, Resize and Purge",
+		"2Q and ARC caches, which resist a scan flushing the hot set, and an expirable LRU",
+	},
+	Cases: []contracts.Case{
+		compare.Both("evicts the least recently added", Toy, Library, func(t testing.TB, impl Impl) string {
+			c := impl(t, 2)
+			c.Add("a", 1)
+			c.Add("b", 2)
+			c.Add("c", 3)
+			return contents(c, "a", "b", "c")
+		}, func(t testing.TB, got string) {
+			compare.Expect(t, "contents", got, "b=2 c=3 len=2")
+		}),
+		compare.Both("Get makes an entry recent", Toy, Library, func(t testing.TB, impl Impl) string {
+			c := impl(t, 2)
+			c.Add("a", 1)
+			c.Add("b", 2)
+			c.Get("a")
+			c.Add("c", 3)
+			return contents(c, "a", "b", "c")
+		}, func(t testing.TB, got string) {
+			compare.Expect(t, "contents", got, "a=1 c=3 len=2")
+		}),
+		compare.Both("Add of a present key replaces and refreshes", Toy, Library, func(t testing.TB, impl Impl) string {
+			c := impl(t, 2)
+			c.Add("a", 1)
+			c.Add("b", 2)
+			c.Add("a", 10)
+			c.Add("c", 3)
+			return contents(c, "a", "b", "c")
+		}, func(t testing.TB, got string) {
+			compare.Expect(t, "contents", got, "a=10 c=3 len=2")
+		}),
+		compare.Both("Remove frees a slot", Toy, Library, func(t testing.TB, impl Impl) string {
+			c := impl(t, 2)
+			c.Add("a", 1)
+			c.Add("b", 2)
+			c.Remove("a")
+			c.Remove("missing")
+			c.Add("c", 3)
+			return contents(c, "a", "b", "c")
+		}, func(t testing.TB, got string) {
+			compare.Expect(t, "contents", got, "b=2 c=3 len=2")
+		}),
+		compare.Both("random operations", Toy, Library, func(t testing.TB, impl Impl) string {
+			// the same seeded sequence of 10,000 operations over 20 keys
+			// in a cache of 8: every result goes into the trace
+			r := randsource.New(1)
+			c := impl(t, 8)
+			var trace strings.Builder
+			for i := range 10000 {
+				key := "k" + strconv.Itoa(r.IntN(20))
+				switch r.IntN(3) {
+				case 0:
+					c.Add(key, i)
+				case 1:
+					v, ok := c.Get(key)
+					fmt.Fprintf(&trace, "%v:%d ", ok, v)
+				case 2:
+					c.Remove(key)
+				}
+				fmt.Fprintf(&trace, "%d\n", c.Len())
+			}
+			return trace.String()
+		}),
+	},
+}
+
+// contents describes the cache through Get, which is the only way both
+// can, so the order of keys matters: it refreshes each one.
+func contents(c Cache, keys ...string) string {
+	var parts []string
+	for _, k := range keys {
+		if v, ok := c.Get(k); ok {
+			parts = append(parts, fmt.Sprintf("%s=%d", k, v))
+		}
+	}
+	return strings.Join(append(parts, fmt.Sprintf("len=%d", c.Len())), " ")
+}
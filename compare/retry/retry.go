@@ -0,0 +1,136 @@
+// Package retry compares resilience/retry with
+// github.com/cenkalti/backoff/v5.
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cenkalti/backoff/v5"
+
+	"patterns/compare"
+	"patterns/resilience/retry"
+	"patterns/testing/contracts"
+)
+
+// Policy is what both are asked for: attempts in all, and exponential
+// waits from base, doubling up to max, without jitter so that the waits
+// can be compared.
+type Policy struct {
+	Attempts  int
+	Base, Max time.Duration
+}
+
+// Impl runs op under p, reporting each wait to onWait. Permanent marks an
+// error not to retry.
+type Impl struct {
+	Do        func(ctx context.Context, p Policy, op func() error, onWait func(time.Duration)) error
+	Permanent func(error) error
+}
+
+var (
+	Toy = Impl{
+		Do: func(ctx context.Context, p Policy, op func() error, onWait func(time.Duration)) error {
+			return retry.Do(ctx, func(context.Context) error { return op() },
+				retry.WithAttempts(p.Attempts),
+				retry.WithBackoff(retry.Exponential(p.Base, p.Max)),
+				retry.WithJitter(retry.None),
+				retry.WithOnRetry(func(_ int, _ error, wait time.Duration) { onWait(wait) }))
+		},
+		Permanent: retry.Permanent,
+	}
+	Library = Impl{
+		Do: func(ctx context.Context, p Policy, op func() error, onWait func(time.Duration)) error {
+			_, err := backoff.Retry(ctx, func() (struct{}, error) { return struct{}{}, op() },
+				backoff.WithMaxTries(uint(p.Attempts)),
+				backoff.WithBackOff(&backoff.ExponentialBackOff{
+					InitialInterval: p.Base,
+					Multiplier:      2,
+					MaxInterval:     p.Max,
+				}),
+				backoff.WithNotify(func(_ error, wait time.Duration) { onWait(wait) }))
+			return err
+		},
+		Permanent: func(err error) error { return backoff.Permanent(err) },
+	}
+)
+
+var (
+	errTransient = errors.New("transient")
+	errFatal     = errors.New("fatal")
+)
+
+var Pair = compare.Pair{
+	Name:    "retry",
+	Toy:     "patterns/resilience/retry",
+	Library: "github.com/cenkalti/backoff/v5",
+	Adds: []string{
+		"RetryAfter errors, for a server that says when to come back",
+		"a total time budget, WithMaxElapsedTime, besides the attempt count",
+		"multiplier and randomisation factor as parameters",
+	},
+	Differs: []string{
+		"running out of attempts returns the last error as it was; the toy wraps it in an ExhaustedError",
+		"a context ending during a wait returns only the context's cause; the toy joins the last error too",
+		"the library gives up by default once 15 minutes have passed, without the wait that would cross them; the toy only counts attempts",
+		"the toy classifies errors with WithRetryIf and never retries context errors; the library retries all but Permanent ones",
+		"jitter: the toy's Full jitter draws from an injectable source; the library's ±factor draws from math/rand",
+	},
+	Cases: []contracts.Case{
+		compare.Both("succeeds after failures", Toy, Library, func(t testing.TB, impl Impl) [3]any {
+			calls, waits := 0, 0
+			err := impl.Do(context.Background(), Policy{5, time.Millisecond, 10 * time.Millisecond}, func() error {
+				if calls++; calls < 3 {
+					return errTransient
+				}
+				return nil
+			}, func(time.Duration) { waits++ })
+			return [3]any{err == nil, calls, waits}
+		}, func(t testing.TB, o [3]any) {
+			compare.Expect(t, "succeeded, calls, waits", o, [3]any{true, 3, 2})
+		}),
+		compare.Both("gives up after the attempts", Toy, Library, func(t testing.TB, impl Impl) [2]any {
+			calls := 0
+			err := impl.Do(context.Background(), Policy{4, time.Millisecond, time.Millisecond}, func() error {
+				calls++
+				return errTransient
+			}, func(time.Duration) {})
+			return [2]any{errors.Is(err, errTransient), calls}
+		}, func(t testing.TB, o [2]any) {
+			compare.Expect(t, "last error, calls", o, [2]any{true, 4})
+		}),
+		compare.Both("a permanent error stops at once, unwrapped", Toy, Library, func(t testing.TB, impl Impl) [2]any {
+			calls := 0
+			err := impl.Do(context.Background(), Policy{5, time.Millisecond, time.Millisecond}, func() error {
+				calls++
+				return impl.Permanent(errFatal)
+			}, func(time.Duration) {})
+			return [2]any{err == errFatal, calls}
+		}, func(t testing.TB, o [2]any) {
+			compare.Expect(t, "error as it was, calls", o, [2]any{true, 1})
+		}),
+		compare.Both("waits double up to the cap", Toy, Library, func(t testing.TB, impl Impl) []time.Duration {
+			var waits []time.Duration
+			impl.Do(context.Background(), Policy{6, time.Millisecond, 4 * time.Millisecond}, func() error {
+				return errTransient
+			}, func(d time.Duration) { waits = append(waits, d) })
+			return waits
+		}, func(t testing.TB, waits []time.Duration) {
+			compare.Same(t, "waits", waits, []time.Duration{1e6, 2e6, 4e6, 4e6, 4e6})
+		}),
+		compare.Both("a context ending during a wait stops", Toy, Library, func(t testing.TB, impl Impl) [2]any {
+			ctx, cancel := context.WithCancel(context.Background())
+			calls := 0
+			err := impl.Do(ctx, Policy{5, time.Minute, time.Minute}, func() error {
+				calls++
+				time.AfterFunc(time.Millisecond, cancel)
+				return errTransient
+			}, func(time.Duration) {})
+			return [2]any{errors.Is(err, context.Canceled), calls}
+		}, func(t testing.TB, o [2]any) {
+			compare.Expect(t, "cancelled, calls", o, [2]any{true, 1})
+		}),
+	},
+}
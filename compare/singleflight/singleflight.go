@@ -0,0 +1,176 @@
+package singleflight
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+
+	"patterns/compare"
+	"patterns/testing/contracts"
+)
+
+// Flight is what both provide, over string keys and int results.
+type Flight interface {
+	Do(key string, fn func() (int, error)) (int, error, bool)
+	Forget(key string)
+}
+
+// Impl makes a new, empty Flight.
+type Impl func() Flight
+
+var (
+	Toy     Impl = func() Flight { return new(Group[string, int]) }
+	Library Impl = func() Flight { return &library{} }
+)
+
+// library adapts singleflight.Group, whose results are untyped.
+type library struct{ g singleflight.Group }
+
+func (l *library) Do(key string, fn func() (int, error)) (int, error, bool) {
+	v, err, shared := l.g.Do(key, func() (any, error) { return fn() })
+	return v.(int), err, shared
+}
+
+func (l *library) Forget(key string) { l.g.Forget(key) }
+
+var Pair = compare.Pair{
+	Name:    "singleflight",
+	Toy:     "patterns/compare/singleflight (security/tokenrefresh for one key)",
+	Library: "golang.org/x/sync/singleflight",
+	Adds: []string{
+		"DoChan, to wait for a result in a select",
+		"a panic or runtime.Goexit in fn reaches every waiting caller instead of leaving them blocked",
+	},
+	Differs: []string{"the toy is generic; the library's results are interface values"},
+	Cases: []contracts.Case{
+		compare.Both("concurrent callers share one call", Toy, Library, func(t testing.TB, impl Impl) [3]any {
+			f := impl()
+			calls, results, shared := joined(f, "a", 10)
+			return [3]any{calls, results, shared}
+		}, func(t testing.TB, o [3]any) {
+			compare.Expect(t, "calls, distinct results, shared by all", o, [3]any{int32(1), 1, true})
+		}),
+		compare.Both("sequential callers each call", Toy, Library, func(t testing.TB, impl Impl) [3]any {
+			f := impl()
+			n := 0
+			v1, _, s1 := f.Do("a", func() (int, error) { n++; return n, nil })
+			v2, _, s2 := f.Do("a", func() (int, error) { n++; return n, nil })
+			return [3]any{[2]int{v1, v2}, s1, s2}
+		}, func(t testing.TB, o [3]any) {
+			compare.Expect(t, "results, shared", o, [3]any{[2]int{1, 2}, false, false})
+		}),
+		compare.Both("keys are independent", Toy, Library, func(t testing.TB, impl Impl) int32 {
+			f := impl()
+			var calls atomic.Int32
+			var wg sync.WaitGroup
+			both := make(chan struct{})
+			var started atomic.Int32
+			for _, key := range []string{"a", "b"} {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					f.Do(key, func() (int, error) {
+						calls.Add(1)
+						if started.Add(1) == 2 {
+							close(both)
+						}
+						<-both // each key's call runs while the other's does
+						return 0, nil
+					})
+				}()
+			}
+			wg.Wait()
+			return calls.Load()
+		}, func(t testing.TB, calls int32) {
+			compare.Expect(t, "calls", calls, int32(2))
+		}),
+		compare.Both("the error is shared", Toy, Library, func(t testing.TB, impl Impl) int {
+			f := impl()
+			boom := errors.New("boom")
+			gate := make(chan struct{})
+			started := make(chan struct{})
+			errs := make(chan error, 2)
+			go func() {
+				_, err, _ := f.Do("a", func() (int, error) { close(started); <-gate; return 0, boom })
+				errs <- err
+			}()
+			<-started
+			go func() {
+				_, err, _ := f.Do("a", func() (int, error) { return 0, nil })
+				errs <- err
+			}()
+			time.Sleep(20 * time.Millisecond) // let the second caller join
+			close(gate)
+			n := 0
+			for range 2 {
+				if <-errs == boom {
+					n++
+				}
+			}
+			return n
+		}, func(t testing.TB, n int) {
+			compare.Expect(t, "callers failing with the call's error", n, 2)
+		}),
+		compare.Both("Forget starts a new call during one", Toy, Library, func(t testing.TB, impl Impl) [2]int {
+			f := impl()
+			gate := make(chan struct{})
+			started := make(chan struct{})
+			first := make(chan int)
+			go func() {
+				v, _, _ := f.Do("a", func() (int, error) { close(started); <-gate; return 1, nil })
+				first <- v
+			}()
+			<-started
+			f.Forget("a")
+			v, _, _ := f.Do("a", func() (int, error) { return 2, nil })
+			close(gate)
+			return [2]int{<-first, v}
+		}, func(t testing.TB, o [2]int) {
+			compare.Expect(t, "results", o, [2]int{1, 2})
+		}),
+	},
+}
+
+// joined runs n callers of key, all joining the first one's call, and
+// returns how many calls ran, how many distinct results the callers got,
+// and whether every caller saw its result as shared.
+func joined(f Flight, key string, n int) (int32, int, bool) {
+	var calls atomic.Int32
+	gate := make(chan struct{})
+	started := make(chan struct{})
+	fn := func() (int, error) {
+		if calls.Add(1) == 1 {
+			close(started)
+		}
+		<-gate
+		return 42, nil
+	}
+	type result struct {
+		v      int
+		shared bool
+	}
+	results := make(chan result, n)
+	do := func() {
+		v, _, shared := f.Do(key, fn)
+		results <- result{v, shared}
+	}
+	go do()
+	<-started
+	for range n - 1 {
+		go do()
+	}
+	time.Sleep(20 * time.Millisecond) // let the others join
+	close(gate)
+	distinct := map[int]bool{}
+	allShared := true
+	for range n {
+		r := <-results
+		distinct[r.v] = true
+		allShared = allShared && r.shared
+	}
+	return calls.Load(), len(distinct), allShared
+}
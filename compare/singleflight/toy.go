@@ -0,0 +1,64 @@
+// Package singleflight compares a toy duplicate-call suppressor with
+// golang.org/x/sync/singleflight.
+package singleflight
+
+import "sync"
+
+// single flight
+// Level: Good
+// pros: a burst of identical requests, a cache miss under load, costs one
+// call to the backend however many callers there are.
+// cons: every caller waits as long as the slowest one's call, and shares
+// its failure.
+//
+// Group runs one call per key at a time and hands its result to every
+// caller that asked meanwhile. The zero Group is ready to use.
+type Group[K comparable, V any] struct {
+	mu    sync.Mutex
+	calls map[K]*call[V]
+}
+
+type call[V any] struct {
+	done chan struct{}
+	v    V
+	err  error
+	dups int
+}
+
+// Do runs fn for key, unless a call for key is already running, in which
+// case it waits for that. shared reports whether the result went to more
+// than one caller.
+func (g *Group[K, V]) Do(key K, fn func() (V, error)) (v V, err error, shared bool) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = map[K]*call[V]{}
+	}
+	if c, ok := g.calls[key]; ok {
+		c.dups++
+		g.mu.Unlock()
+		<-c.done
+		return c.v, c.err, true
+	}
+	c := &call[V]{done: make(chan struct{})}
+	g.calls[key] = c
+	g.mu.Unlock()
+
+	c.v, c.err = fn()
+
+	g.mu.Lock()
+	if g.calls[key] == c {
+		delete(g.calls, key)
+	}
+	shared = c.dups > 0
+	g.mu.Unlock()
+	close(c.done)
+	return c.v, c.err, shared
+}
+
+// Forget makes the next Do for key start a new call, even if one is
+// running.
+func (g *Group[K, V]) Forget(key K) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	delete(g.calls, key)
+}
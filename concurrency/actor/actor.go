@@ -0,0 +1,252 @@
+// Package actor confines state to one goroutine, which alone reads and
+// writes it, and talks to the rest of the program in messages sent over
+// a channel, each carrying a channel for its reply:
+//
+//	a := actor.New()
+//	defer a.Close()
+//	a.Deposit(ctx, "alice", 100)
+//	err := a.Transfer(ctx, "alice", "bob", 30)
+//
+// It is the same bank twice: Actor owns its balances in a loop, Locked
+// guards them with a mutex, and both implement Accounts. With one owner
+// there is nothing to lock and nothing to forget to lock: every message
+// is handled to completion before the next, so a transfer is atomic by
+// construction, and the race detector has no shared memory to watch.
+//
+// The price is a channel round trip per call, and callers that now wait
+// in a queue instead of on a lock. The actor earns it when the state has
+// to do things a mutex should not be held across: call out, wait on a
+// timer, own a connection; or when the operations are many and their
+// interleavings hard to reason about. For a map and a few arithmetic
+// operations, as here, the mutex is the better tool.
+//
+// findings (see bench_test.go; go test -bench . -benchmem
+// patterns/concurrency/actor), transfers between 64 accounts, on a single
+// CPU:
+//
+//   - Locked takes ~75ns a transfer alone and ~95ns contended.
+//   - Actor takes ~1.8µs either way: two channel operations and two
+//     goroutine switches, ~20x, and three allocations, the reply channel
+//     and the message boxed into the inbox's interface. Contention
+//     changes little, because the actor was a queue all along.
+package actor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+var (
+	ErrInsufficient = errors.New("actor: insufficient funds")
+	ErrStopped      = errors.New("actor: stopped")
+)
+
+// Accounts holds balances by account id; accounts start at zero.
+type Accounts interface {
+	Deposit(ctx context.Context, id string, amount int64) error
+	// Transfer moves amount from one account to another, or fails with
+	// ErrInsufficient and moves nothing.
+	Transfer(ctx context.Context, from, to string, amount int64) error
+	Balance(ctx context.Context, id string) (int64, error)
+}
+
+func checkAmount(amount int64) error {
+	if amount <= 0 {
+		return fmt.Errorf("actor: amount %d is not positive", amount)
+	}
+	return nil
+}
+
+// message is a request to the actor's loop. Its reply channel is
+// buffered, so the loop never waits for a caller that gave up.
+type message interface {
+	handle(balances map[string]int64)
+}
+
+type deposit struct {
+	id     string
+	amount int64
+	reply  chan error
+}
+
+func (m deposit) handle(balances map[string]int64) {
+	balances[m.id] += m.amount
+	m.reply <- nil
+}
+
+type transfer struct {
+	from, to string
+	amount   int64
+	reply    chan error
+}
+
+func (m transfer) handle(balances map[string]int64) {
+	if balances[m.from] < m.amount {
+		m.reply <- ErrInsufficient
+		return
+	}
+	balances[m.from] -= m.amount
+	balances[m.to] += m.amount
+	m.reply <- nil
+}
+
+type balance struct {
+	id    string
+	reply chan int64
+}
+
+func (m balance) handle(balances map[string]int64) { m.reply <- balances[m.id] }
+
+// actor
+// Level: Good
+// pros: the state has one owner, so there are no locks to take, forget
+// or order; each message is atomic; the loop may block on I/O or timers
+// without holding anyone else's lock.
+// cons: a channel round trip and a goroutine switch per call; one
+// goroutine to start and stop; a slow message delays every caller behind
+// it.
+//
+// Actor keeps the balances in its own goroutine. It is safe for
+// concurrent use, and must be closed.
+type Actor struct {
+	inbox   chan message
+	stopped chan struct{}
+	once    sync.Once
+	done    chan struct{} // closed when the loop has returned
+}
+
+func New() *Actor {
+	a := &Actor{inbox: make(chan message), stopped: make(chan struct{}), done: make(chan struct{})}
+	go a.loop()
+	return a
+}
+
+func (a *Actor) loop() {
+	defer close(a.done)
+	balances := map[string]int64{} // only this goroutine touches it
+	for {
+		select {
+		case m := <-a.inbox:
+			m.handle(balances)
+		case <-a.stopped:
+			return
+		}
+	}
+}
+
+// send delivers m, or fails when ctx ends or the actor has stopped.
+func (a *Actor) send(ctx context.Context, m message) error {
+	select {
+	case a.inbox <- m:
+		return nil
+	case <-a.stopped:
+		return ErrStopped
+	case <-ctx.Done():
+		return context.Cause(ctx)
+	}
+}
+
+// await returns the reply to a delivered message; a message delivered is
+// always handled, so only ctx can cut the wait short.
+func await[R any](ctx context.Context, reply chan R) (R, error) {
+	select {
+	case r := <-reply:
+		return r, nil
+	case <-ctx.Done():
+		var zero R
+		return zero, context.Cause(ctx)
+	}
+}
+
+// call sends m and returns the error it replies with on reply.
+func (a *Actor) call(ctx context.Context, m message, reply chan error) error {
+	if err := a.send(ctx, m); err != nil {
+		return err
+	}
+	err, cerr := await(ctx, reply)
+	if cerr != nil {
+		return cerr
+	}
+	return err
+}
+
+func (a *Actor) Deposit(ctx context.Context, id string, amount int64) error {
+	if err := checkAmount(amount); err != nil {
+		return err
+	}
+	reply := make(chan error, 1)
+	return a.call(ctx, deposit{id, amount, reply}, reply)
+}
+
+func (a *Actor) Transfer(ctx context.Context, from, to string, amount int64) error {
+	if err := checkAmount(amount); err != nil {
+		return err
+	}
+	reply := make(chan error, 1)
+	return a.call(ctx, transfer{from, to, amount, reply}, reply)
+}
+
+func (a *Actor) Balance(ctx context.Context, id string) (int64, error) {
+	reply := make(chan int64, 1)
+	if err := a.send(ctx, balance{id, reply}); err != nil {
+		return 0, err
+	}
+	return await(ctx, reply)
+}
+
+// Close stops the loop once the message being handled is done; calls
+// after it fail with ErrStopped. It may be called more than once.
+func (a *Actor) Close() error {
+	a.once.Do(func() { close(a.stopped) })
+	<-a.done
+	return nil
+}
+
+// mutex-guarded struct
+// Level: Good
+// pros: a plain method call; an order of magnitude cheaper than a
+// message; nothing to start or stop.
+// cons: every method must take the lock, and nothing slow may happen
+// while holding it; invariants across several structs need a lock order.
+//
+// Locked keeps the balances behind a mutex. It is safe for concurrent
+// use; the zero value is ready.
+type Locked struct {
+	mu       sync.Mutex
+	balances map[string]int64
+}
+
+func (l *Locked) Deposit(ctx context.Context, id string, amount int64) error {
+	if err := checkAmount(amount); err != nil {
+		return err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.balances == nil {
+		l.balances = map[string]int64{}
+	}
+	l.balances[id] += amount
+	return nil
+}
+
+func (l *Locked) Transfer(ctx context.Context, from, to string, amount int64) error {
+	if err := checkAmount(amount); err != nil {
+		return err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.balances[from] < amount {
+		return ErrInsufficient
+	}
+	l.balances[from] -= amount
+	l.balances[to] += amount
+	return nil
+}
+
+func (l *Locked) Balance(ctx context.Context, id string) (int64, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.balances[id], nil
+}
@@ -0,0 +1,147 @@
+package actor
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"sync"
+	"testing"
+)
+
+var implementations = []struct {
+	name string
+	new  func() (Accounts, func())
+}{
+	{"locked", func() (Accounts, func()) { return &Locked{}, func() {} }},
+	{"actor", func() (Accounts, func()) {
+		a := New()
+		return a, func() { a.Close() }
+	}},
+}
+
+// TestConcurrentTransfers runs random transfers between a few accounts
+// from many goroutines, many of them overdrawing: money is neither made
+// nor lost and no balance goes negative. Run it with -race.
+func TestConcurrentTransfers(t *testing.T) {
+	const (
+		accounts   = 5
+		goroutines = 16
+		transfers  = 500
+		opening    = 100
+	)
+	for _, impl := range implementations {
+		t.Run(impl.name, func(t *testing.T) {
+			a, stop := impl.new()
+			defer stop()
+			ctx := context.Background()
+			for _, id := range ids[:accounts] {
+				if err := a.Deposit(ctx, id, opening); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			var wg sync.WaitGroup
+			for g := range goroutines {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					r := rand.New(rand.NewPCG(uint64(g), 0))
+					for range transfers {
+						from, to := ids[r.IntN(accounts)], ids[r.IntN(accounts)]
+						err := a.Transfer(ctx, from, to, 1+r.Int64N(60))
+						if err != nil && !errors.Is(err, ErrInsufficient) {
+							t.Error(err)
+							return
+						}
+						if _, err := a.Balance(ctx, from); err != nil {
+							t.Error(err)
+							return
+						}
+					}
+				}()
+			}
+			wg.Wait()
+
+			var total int64
+			for _, id := range ids[:accounts] {
+				b, err := a.Balance(ctx, id)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if b < 0 {
+					t.Errorf("%s: balance %d", id, b)
+				}
+				total += b
+			}
+			if total != accounts*opening {
+				t.Errorf("total = %d, want %d", total, accounts*opening)
+			}
+		})
+	}
+}
+
+func TestTransfer(t *testing.T) {
+	for _, impl := range implementations {
+		t.Run(impl.name, func(t *testing.T) {
+			a, stop := impl.new()
+			defer stop()
+			ctx := context.Background()
+			a.Deposit(ctx, "alice", 100)
+
+			if err := a.Transfer(ctx, "alice", "bob", 30); err != nil {
+				t.Fatal(err)
+			}
+			if err := a.Transfer(ctx, "alice", "bob", 71); !errors.Is(err, ErrInsufficient) {
+				t.Errorf("overdrawing Transfer = %v, want ErrInsufficient", err)
+			}
+			for id, want := range map[string]int64{"alice": 70, "bob": 30, "carol": 0} {
+				if got, err := a.Balance(ctx, id); err != nil || got != want {
+					t.Errorf("Balance(%s) = %d, %v; want %d", id, got, err, want)
+				}
+			}
+			for _, amount := range []int64{0, -5} {
+				if err := a.Deposit(ctx, "alice", amount); err == nil {
+					t.Errorf("Deposit(%d) succeeded", amount)
+				}
+				if err := a.Transfer(ctx, "alice", "bob", amount); err == nil {
+					t.Errorf("Transfer(%d) succeeded", amount)
+				}
+			}
+		})
+	}
+}
+
+func TestActorStopped(t *testing.T) {
+	a := New()
+	a.Deposit(context.Background(), "alice", 1)
+	a.Close()
+	if err := a.Close(); err != nil {
+		t.Errorf("second Close = %v", err)
+	}
+	ctx := context.Background()
+	if err := a.Deposit(ctx, "alice", 1); !errors.Is(err, ErrStopped) {
+		t.Errorf("Deposit after Close = %v, want ErrStopped", err)
+	}
+	if _, err := a.Balance(ctx, "alice"); !errors.Is(err, ErrStopped) {
+		t.Errorf("Balance after Close = %v, want ErrStopped", err)
+	}
+}
+
+// TestActorCancelled checks that a caller whose context is done gets its
+// cause, and that a cancelled call never leaves the loop stuck.
+func TestActorCancelled(t *testing.T) {
+	a := New()
+	defer a.Close()
+	errGone := errors.New("caller gone")
+	ctx, cancel := context.WithCancelCause(context.Background())
+	cancel(errGone)
+	for range 100 {
+		// the send may still win the race with ctx; either outcome is fine
+		if err := a.Deposit(ctx, "alice", 1); err != nil && !errors.Is(err, errGone) {
+			t.Fatalf("Deposit with a done ctx = %v, want nil or its cause", err)
+		}
+	}
+	if _, err := a.Balance(context.Background(), "alice"); err != nil {
+		t.Errorf("Balance after cancelled calls = %v", err)
+	}
+}
@@ -0,0 +1,75 @@
+package actor
+
+import (
+	"context"
+	"strconv"
+	"sync/atomic"
+	"testing"
+)
+
+const benchAccounts = 64
+
+var ids = func() []string {
+	ids := make([]string, benchAccounts)
+	for i := range ids {
+		ids[i] = "acct-" + strconv.Itoa(i)
+	}
+	return ids
+}()
+
+// funded opens every account with enough that no transfer fails.
+func funded(a Accounts) Accounts {
+	for _, id := range ids {
+		a.Deposit(context.Background(), id, 1<<40)
+	}
+	return a
+}
+
+func benchTransfer(newAccounts func() (Accounts, func())) func(b *testing.B) {
+	return func(b *testing.B) {
+		a, stop := newAccounts()
+		defer stop()
+		ctx := context.Background()
+		for i := range b.N {
+			a.Transfer(ctx, ids[i%benchAccounts], ids[(i+1)%benchAccounts], 1)
+		}
+	}
+}
+
+func benchTransferParallel(newAccounts func() (Accounts, func())) func(b *testing.B) {
+	return func(b *testing.B) {
+		a, stop := newAccounts()
+		defer stop()
+		ctx := context.Background()
+		var next atomic.Int64
+		b.SetParallelism(8)
+		b.RunParallel(func(pb *testing.PB) {
+			i := int(next.Add(1))
+			for pb.Next() {
+				a.Transfer(ctx, ids[i%benchAccounts], ids[(i+7)%benchAccounts], 1)
+				i++
+			}
+		})
+	}
+}
+
+func newActor() (Accounts, func()) {
+	a := New()
+	return funded(a), func() { a.Close() }
+}
+
+func newLocked() (Accounts, func()) { return funded(&Locked{}), func() {} }
+
+// BenchmarkTransfer transfers between 64 accounts on each implementation
+// from one goroutine.
+func BenchmarkTransfer(b *testing.B) {
+	b.Run("locked", benchTransfer(newLocked))
+	b.Run("actor", benchTransfer(newActor))
+}
+
+// BenchmarkTransferParallel transfers between 64 accounts on each
+// implementation from eight goroutines per CPU.
+func BenchmarkTransferParallel(b *testing.B) {
+	b.Run("locked", benchTransferParallel(newLocked))
+	b.Run("actor", benchTransferParallel(newActor))
+}
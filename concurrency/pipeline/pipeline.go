@@ -0,0 +1,139 @@
+// Package pipeline connects stages with channels, each stage running on
+// its own goroutines:
+//
+//	p := pipeline.New(ctx)
+//	parse := pipeline.Map(parseLine)
+//	enrich := pipeline.Parallel(8, lookupCustomer)
+//	orders, err := pipeline.Collect(p, pipeline.Then(parse, enrich)(p, pipeline.From(p, lines)))
+//
+// The rules that keep a pipeline from leaking goroutines or deadlocking:
+//
+//   - a channel is owned by the stage that makes it: that stage alone
+//     sends on it and closes it, when its input is exhausted or the
+//     pipeline is cancelled, so consumers simply range
+//   - every send also selects on the pipeline's context, so a stage whose
+//     consumer went away exits instead of blocking forever
+//   - every goroutine is started through the Pipeline, which waits for
+//     them all: Wait returning means nothing is left running
+//
+// The first stage error cancels the whole pipeline and is what Wait
+// returns; a consumer that has seen enough calls Stop, which cancels
+// without it being an error.
+//
+// Parallel fans a stage out over several workers and merges their
+// outputs back into one channel; the outputs come in completion order.
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"sync"
+)
+
+// Pipeline runs the goroutines of a set of connected stages and cancels
+// them together.
+type Pipeline struct {
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+	wg     sync.WaitGroup
+}
+
+// errStopped is the cause of a pipeline stopped by its consumer.
+var errStopped = errors.New("pipeline: stopped")
+
+// New returns a pipeline cancelled with ctx.
+func New(ctx context.Context) *Pipeline {
+	ctx, cancel := context.WithCancelCause(ctx)
+	return &Pipeline{ctx: ctx, cancel: cancel}
+}
+
+// Context is done when the pipeline is cancelled, by an error, by Stop or
+// by its parent context.
+func (p *Pipeline) Context() context.Context { return p.ctx }
+
+// Go runs f on a goroutine of the pipeline; an error from f cancels the
+// pipeline, and the first such error is what Wait returns.
+func (p *Pipeline) Go(f func(ctx context.Context) error) {
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		if err := f(p.ctx); err != nil {
+			p.cancel(err)
+		}
+	}()
+}
+
+// Stop cancels the pipeline without that being an error, for a consumer
+// that needs no more; it returns at once, and Wait waits for the stages.
+func (p *Pipeline) Stop() { p.cancel(errStopped) }
+
+// Wait waits for every goroutine of the pipeline and returns the first
+// stage error, the parent context's error if it ended the pipeline, or nil.
+func (p *Pipeline) Wait() error {
+	p.wg.Wait()
+	err := context.Cause(p.ctx)
+	p.cancel(nil)
+	if errors.Is(err, errStopped) {
+		return nil
+	}
+	return err
+}
+
+// send sends v on out unless ctx is done first, and reports whether it
+// did.
+func send[T any](ctx context.Context, out chan<- T, v T) bool {
+	select {
+	case out <- v:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// receive receives from in unless ctx is done first; ok is false when in
+// is closed or ctx done.
+func receive[T any](ctx context.Context, in <-chan T) (v T, ok bool) {
+	select {
+	case v, ok = <-in:
+		return v, ok
+	case <-ctx.Done():
+		return v, false
+	}
+}
+
+// From sends items, in order, on a channel it closes after the last.
+func From[T any](p *Pipeline, items []T) <-chan T {
+	return FromSeq(p, func(yield func(T) bool) {
+		for _, v := range items {
+			if !yield(v) {
+				return
+			}
+		}
+	})
+}
+
+// FromSeq sends the values of seq on a channel it closes after the last.
+func FromSeq[T any](p *Pipeline, seq iter.Seq[T]) <-chan T {
+	out := make(chan T)
+	p.Go(func(ctx context.Context) error {
+		defer close(out)
+		for v := range seq {
+			if !send(ctx, out, v) {
+				return nil
+			}
+		}
+		return nil
+	})
+	return out
+}
+
+// Collect drains in, then waits for the pipeline, and returns what it
+// received with the pipeline's error.
+func Collect[T any](p *Pipeline, in <-chan T) ([]T, error) {
+	var out []T
+	for v := range in {
+		out = append(out, v)
+	}
+	return out, p.Wait()
+}
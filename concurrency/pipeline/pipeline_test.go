@@ -0,0 +1,204 @@
+package pipeline_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"patterns/concurrency/pipeline"
+)
+
+// running counts the goroutines started by pipelines that have yet to
+// exit. A goroutine calls Done just before it returns, so the count is
+// polled for a while.
+func running(t *testing.T) int {
+	t.Helper()
+	n := 0
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		buf := make([]byte, 1<<20)
+		buf = buf[:runtime.Stack(buf, true)]
+		n = strings.Count(string(buf), "created by patterns/concurrency/pipeline.(*Pipeline).Go")
+		if n == 0 {
+			break
+		}
+	}
+	return n
+}
+
+// naturals counts up from 0 until the pipeline stops it.
+func naturals(yield func(int) bool) {
+	for i := 0; yield(i); i++ {
+	}
+}
+
+func double(_ context.Context, v int) (int, error) { return 2 * v, nil }
+
+var even = pipeline.Filter(func(v int) bool { return v%2 == 0 })
+
+func TestStages(t *testing.T) {
+	p := pipeline.New(context.Background())
+	got, err := pipeline.Collect(p, pipeline.Then(even, pipeline.Map(double))(p, pipeline.From(p, []int{1, 2, 3, 4, 5, 6})))
+	if err != nil || !slices.Equal(got, []int{4, 8, 12}) {
+		t.Errorf("Collect = %v, %v", got, err)
+	}
+
+	p = pipeline.New(context.Background())
+	got, err = pipeline.Collect(p, pipeline.Parallel(4, double)(p, pipeline.From(p, []int{1, 2, 3, 4, 5, 6, 7})))
+	slices.Sort(got)
+	if err != nil || !slices.Equal(got, []int{2, 4, 6, 8, 10, 12, 14}) {
+		t.Errorf("Parallel = %v, %v", got, err)
+	}
+
+	p = pipeline.New(context.Background())
+	if got, err := pipeline.Collect(p, pipeline.Map(double)(p, pipeline.From[int](p, nil))); err != nil || len(got) != 0 {
+		t.Errorf("no input: %v, %v", got, err)
+	}
+	if n := running(t); n != 0 {
+		t.Errorf("%d goroutines left running", n)
+	}
+}
+
+// TestParallel checks that Parallel's workers run at once: each waits
+// until all of them have a value.
+func TestParallel(t *testing.T) {
+	const n = 4
+	var started sync.WaitGroup
+	started.Add(n)
+	p := pipeline.New(context.Background())
+	outs := pipeline.FanOut(p, pipeline.From(p, []int{0, 1, 2, 3}), n, func(_ context.Context, v int) (int, error) {
+		started.Done()
+		started.Wait()
+		return v, nil
+	})
+	if len(outs) != n {
+		t.Fatalf("FanOut made %d outputs, want %d", len(outs), n)
+	}
+	got, err := pipeline.Collect(p, pipeline.Merge(p, outs...))
+	slices.Sort(got)
+	if err != nil || !slices.Equal(got, []int{0, 1, 2, 3}) {
+		t.Errorf("Collect = %v, %v", got, err)
+	}
+}
+
+// shapes are pipelines of each kind of stage over an endless input.
+var shapes = []struct {
+	name  string
+	stage pipeline.Stage[int, int]
+}{
+	{"map", pipeline.Map(double)},
+	{"filter", even},
+	{"then", pipeline.Then(even, pipeline.Map(double))},
+	{"parallel", pipeline.Parallel(3, double)},
+	{"parallel then filter", pipeline.Then(pipeline.Parallel(3, double), even)},
+}
+
+// TestStop has the consumer stop after a few values of an endless input,
+// and leave the rest unread: every stage exits, Wait returns without an
+// error, and no goroutine is left.
+func TestStop(t *testing.T) {
+	for _, c := range shapes {
+		p := pipeline.New(context.Background())
+		out := c.stage(p, pipeline.FromSeq(p, naturals))
+		for range 3 {
+			<-out
+		}
+		p.Stop()
+		if err := p.Wait(); err != nil {
+			t.Errorf("%s: Wait = %v", c.name, err)
+		}
+		if n := running(t); n != 0 {
+			t.Errorf("%s: %d goroutines left running", c.name, n)
+		}
+	}
+}
+
+// TestParentCancel cancels the parent context of a pipeline whose
+// consumer has stopped reading.
+func TestParentCancel(t *testing.T) {
+	for _, c := range shapes {
+		ctx, cancel := context.WithCancel(context.Background())
+		p := pipeline.New(ctx)
+		<-c.stage(p, pipeline.FromSeq(p, naturals))
+		cancel()
+		if err := p.Wait(); !errors.Is(err, context.Canceled) {
+			t.Errorf("%s: Wait = %v, want Canceled", c.name, err)
+		}
+		if n := running(t); n != 0 {
+			t.Errorf("%s: %d goroutines left running", c.name, n)
+		}
+	}
+}
+
+// TestError fails a stage partway through an endless input: the error
+// cancels the rest, and is what Collect returns.
+func TestError(t *testing.T) {
+	errBad := errors.New("bad value")
+	failAt := func(_ context.Context, v int) (int, error) {
+		if v == 5 {
+			return 0, fmt.Errorf("%d: %w", v, errBad)
+		}
+		return v, nil
+	}
+	for _, c := range []struct {
+		name  string
+		stage pipeline.Stage[int, int]
+	}{
+		{"map", pipeline.Map(failAt)},
+		{"filter then map", pipeline.Then(pipeline.Filter(func(int) bool { return true }), pipeline.Map(failAt))},
+		{"parallel", pipeline.Parallel(3, failAt)},
+		{"map then parallel", pipeline.Then(pipeline.Map(failAt), pipeline.Parallel(3, double))},
+	} {
+		p := pipeline.New(context.Background())
+		got, err := pipeline.Collect(p, c.stage(p, pipeline.FromSeq(p, naturals)))
+		if !errors.Is(err, errBad) || err.Error() != "5: bad value" {
+			t.Errorf("%s: Collect = %v", c.name, err)
+		}
+		if slices.Contains(got, 5) {
+			t.Errorf("%s: the failed value came out", c.name)
+		}
+		if n := running(t); n != 0 {
+			t.Errorf("%s: %d goroutines left running", c.name, n)
+		}
+	}
+}
+
+// TestForeignInput feeds each stage from a channel the pipeline does not
+// own and nobody closes: stopping the pipeline still ends every stage.
+func TestForeignInput(t *testing.T) {
+	for _, c := range shapes {
+		in := make(chan int)
+		p := pipeline.New(context.Background())
+		out := c.stage(p, in)
+		in <- 2
+		<-out
+		p.Stop()
+		done := make(chan error)
+		go func() { done <- p.Wait() }()
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Errorf("%s: Wait = %v", c.name, err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%s: Wait blocked on a stage waiting for its input", c.name)
+		}
+		if _, ok := <-out; ok {
+			t.Errorf("%s: output open after Wait", c.name)
+		}
+	}
+	p := pipeline.New(context.Background())
+	merged := pipeline.Merge(p, make(chan int), make(chan int))
+	p.Stop()
+	if err := p.Wait(); err != nil {
+		t.Errorf("Merge: Wait = %v", err)
+	}
+	if _, ok := <-merged; ok {
+		t.Error("Merge: output open after Wait")
+	}
+}
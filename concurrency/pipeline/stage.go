@@ -0,0 +1,120 @@
+package pipeline
+
+import (
+	"context"
+)
+
+// pipeline
+// Level: Good
+// pros: each stage is a small function with one input and one output;
+// stages run concurrently, and a slow one can be widened with Parallel;
+// cancellation reaches every goroutine, so none leaks.
+// cons: a channel handoff per value per stage, too much for cheap stages,
+// which are better fused into one; Parallel loses the input order.
+//
+// Stage turns a channel of In into a channel of Out it owns: it starts
+// goroutines on p that send on the output, and close it, once in is
+// closed or p is cancelled.
+type Stage[In, Out any] func(p *Pipeline, in <-chan In) <-chan Out
+
+// Then composes two stages, a's output feeding b.
+func Then[A, B, C any](a Stage[A, B], b Stage[B, C]) Stage[A, C] {
+	return func(p *Pipeline, in <-chan A) <-chan C { return b(p, a(p, in)) }
+}
+
+// Map applies fn to each value, one at a time, in order. An error from fn
+// cancels the pipeline.
+func Map[In, Out any](fn func(ctx context.Context, v In) (Out, error)) Stage[In, Out] {
+	return func(p *Pipeline, in <-chan In) <-chan Out {
+		out := make(chan Out)
+		p.Go(func(ctx context.Context) error {
+			defer close(out)
+			return mapInto(ctx, in, out, fn)
+		})
+		return out
+	}
+}
+
+// mapInto sends fn of every value of in on out until in is closed or ctx
+// done.
+func mapInto[In, Out any](ctx context.Context, in <-chan In, out chan<- Out, fn func(context.Context, In) (Out, error)) error {
+	for {
+		v, ok := receive(ctx, in)
+		if !ok {
+			return nil
+		}
+		r, err := fn(ctx, v)
+		if err != nil {
+			return err
+		}
+		if !send(ctx, out, r) {
+			return nil
+		}
+	}
+}
+
+// Filter passes on the values keep accepts.
+func Filter[T any](keep func(v T) bool) Stage[T, T] {
+	return func(p *Pipeline, in <-chan T) <-chan T {
+		out := make(chan T)
+		p.Go(func(ctx context.Context) error {
+			defer close(out)
+			for {
+				v, ok := receive(ctx, in)
+				if !ok || keep(v) && !send(ctx, out, v) {
+					return nil
+				}
+			}
+		})
+		return out
+	}
+}
+
+// FanOut starts n workers applying fn to values taken from in, each with
+// its own output channel; values go to whichever worker is free.
+func FanOut[In, Out any](p *Pipeline, in <-chan In, n int, fn func(ctx context.Context, v In) (Out, error)) []<-chan Out {
+	outs := make([]<-chan Out, n)
+	for i := range outs {
+		out := make(chan Out)
+		outs[i] = out
+		p.Go(func(ctx context.Context) error {
+			defer close(out)
+			return mapInto(ctx, in, out, fn)
+		})
+	}
+	return outs
+}
+
+// Merge sends the values of every channel of ins on one channel, closed
+// once they all are.
+func Merge[T any](p *Pipeline, ins ...<-chan T) <-chan T {
+	out := make(chan T)
+	remaining := make(chan struct{}, len(ins))
+	for _, in := range ins {
+		p.Go(func(ctx context.Context) error {
+			defer func() { remaining <- struct{}{} }()
+			for {
+				v, ok := receive(ctx, in)
+				if !ok || !send(ctx, out, v) {
+					return nil
+				}
+			}
+		})
+	}
+	p.Go(func(context.Context) error {
+		for range ins {
+			<-remaining
+		}
+		close(out)
+		return nil
+	})
+	return out
+}
+
+// Parallel is Map on n workers: FanOut then Merge. Values come out in the
+// order they complete.
+func Parallel[In, Out any](n int, fn func(ctx context.Context, v In) (Out, error)) Stage[In, Out] {
+	return func(p *Pipeline, in <-chan In) <-chan Out {
+		return Merge(p, FanOut(p, in, n, fn)...)
+	}
+}
@@ -0,0 +1,229 @@
+// Package semaphore bounds how much runs at once, three ways:
+//
+//   - Chan is a buffered channel: a send acquires, a receive releases,
+//     and the capacity is the bound. The idiom is a line of Go, and this
+//     type only names it.
+//   - Weighted lets each holder take several units, for bounds on
+//     something other than a count: bytes of memory, connections per
+//     request. Waiters are served in order, so a large request is not
+//     starved by a stream of small ones.
+//   - ForEachLimit runs a function over a slice at most limit at a time.
+//
+// Where the acquire happens matters more than which semaphore it is:
+//
+//	for _, item := range items {
+//		sem.Acquire(ctx) // before go: at most n goroutines exist
+//		go func() { defer sem.Release(); work(item) }()
+//	}
+//
+// Acquiring inside the goroutine bounds the work but not the goroutines:
+// all of them start at once and wait, each with its stack and whatever it
+// captured. ForEachLimit acquires before it starts each one.
+//
+// For work that arrives as a stream rather than a slice, a worker pool
+// (concurrency/workerpool) keeps the goroutines too; for a group of tasks
+// that may fail or panic together, see concurrency/structured.
+package semaphore
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// channel semaphore
+// Level: Good
+// pros: a buffered channel, nothing to learn; Acquire selects on ctx;
+// len and cap show the state.
+// cons: one unit per holder, and no order among waiters.
+//
+// Chan is a semaphore of cap(s) units.
+type Chan chan struct{}
+
+// NewChan returns a semaphore of n units; n must be positive.
+func NewChan(n int) Chan {
+	if n <= 0 {
+		panic("semaphore: size must be positive")
+	}
+	return make(Chan, n)
+}
+
+// Acquire takes a unit, waiting until one is free or ctx is done.
+func (s Chan) Acquire(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return context.Cause(ctx)
+	}
+	select {
+	case s <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return context.Cause(ctx)
+	}
+}
+
+// TryAcquire takes a unit if one is free now and reports whether it did.
+func (s Chan) TryAcquire() bool {
+	select {
+	case s <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// Release returns a unit; releasing one not held panics.
+func (s Chan) Release() {
+	select {
+	case <-s:
+	default:
+		panic("semaphore: Release without Acquire")
+	}
+}
+
+// weighted semaphore
+// Level: Good
+// pros: bounds a quantity, not a count; first come first served, so no
+// request waits forever behind smaller ones.
+// cons: a mutex and a waiter list instead of a channel; one large waiter
+// at the front holds back small ones that would fit.
+//
+// Weighted is a semaphore of a fixed number of units that holders take
+// several at a time. It is safe for concurrent use.
+type Weighted struct {
+	mu      sync.Mutex
+	size    int64
+	held    int64
+	waiters list.List // of *waiter, in arrival order
+}
+
+type waiter struct {
+	n     int64
+	ready chan struct{} // closed once the units are taken for the waiter
+}
+
+// NewWeighted returns a semaphore of n units; n must be positive.
+func NewWeighted(n int64) *Weighted {
+	if n <= 0 {
+		panic("semaphore: size must be positive")
+	}
+	return &Weighted{size: n}
+}
+
+// Acquire takes n units, waiting behind earlier waiters until they are
+// free or ctx is done. Asking for more than the semaphore's size fails at
+// once rather than waiting forever.
+func (s *Weighted) Acquire(ctx context.Context, n int64) error {
+	if n > s.size {
+		return fmt.Errorf("semaphore: acquiring %d of %d units", n, s.size)
+	}
+	s.mu.Lock()
+	if s.size-s.held >= n && s.waiters.Len() == 0 {
+		s.held += n
+		s.mu.Unlock()
+		return nil
+	}
+	if err := ctx.Err(); err != nil {
+		s.mu.Unlock()
+		return context.Cause(ctx)
+	}
+	w := &waiter{n: n, ready: make(chan struct{})}
+	elem := s.waiters.PushBack(w)
+	s.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		select {
+		case <-w.ready:
+			// granted as ctx ended: give the units back
+			s.held -= n
+		default:
+			s.waiters.Remove(elem)
+		}
+		// either way the front may have changed
+		s.grant()
+		return context.Cause(ctx)
+	}
+}
+
+// TryAcquire takes n units if they are free now and nobody is waiting,
+// and reports whether it did.
+func (s *Weighted) TryAcquire(n int64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.size-s.held < n || s.waiters.Len() > 0 {
+		return false
+	}
+	s.held += n
+	return true
+}
+
+// Release returns n units; returning more than are held panics.
+func (s *Weighted) Release(n int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if n > s.held {
+		panic("semaphore: released more than held")
+	}
+	s.held -= n
+	s.grant()
+}
+
+// grant hands free units to waiters from the front, stopping at the
+// first that does not fit so that order is kept.
+func (s *Weighted) grant() {
+	for e := s.waiters.Front(); e != nil; e = s.waiters.Front() {
+		w := e.Value.(*waiter)
+		if s.size-s.held < w.n {
+			return
+		}
+		s.held += w.n
+		s.waiters.Remove(e)
+		close(w.ready)
+	}
+}
+
+// ForEachLimit calls fn for each item, with its index, running at most
+// limit at once; limit must be positive. The first error cancels the
+// context fn gets, starts no more items, and is returned once the running
+// ones have; nil means every item ran and succeeded. A panic in fn is not
+// recovered.
+func ForEachLimit[T any](ctx context.Context, items []T, limit int, fn func(ctx context.Context, i int, item T) error) error {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	sem := NewChan(limit)
+	var wg sync.WaitGroup
+	var failed atomic.Bool
+	started := 0
+	for i, item := range items {
+		if sem.Acquire(ctx) != nil {
+			break
+		}
+		// a failure frees its slot after it cancels ctx, and Acquire may
+		// take the slot rather than see ctx done
+		if ctx.Err() != nil {
+			sem.Release()
+			break
+		}
+		started++
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer sem.Release()
+			if err := fn(ctx, i, item); err != nil {
+				failed.Store(true)
+				cancel(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if failed.Load() || started < len(items) {
+		return context.Cause(ctx)
+	}
+	return nil
+}
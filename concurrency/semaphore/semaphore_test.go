@@ -0,0 +1,344 @@
+package semaphore_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"patterns/concurrency/semaphore"
+)
+
+var errBad = errors.New("bad item")
+
+// ceiling tracks how many hold a semaphore at once, and the most that
+// ever did.
+type ceiling struct {
+	now, most atomic.Int64
+}
+
+func (c *ceiling) enter(n int64) {
+	now := c.now.Add(n)
+	for m := c.most.Load(); now > m && !c.most.CompareAndSwap(m, now); m = c.most.Load() {
+	}
+}
+
+func (c *ceiling) leave(n int64) { c.now.Add(-n) }
+
+// hold is how long each holder keeps its units in the ceiling tests, so
+// that the others pile up behind it.
+const hold = time.Millisecond
+
+func TestChanCeiling(t *testing.T) {
+	for _, size := range []int{1, 3, 8} {
+		sem := semaphore.NewChan(size)
+		var c ceiling
+		var wg sync.WaitGroup
+		for range 10 * size {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if err := sem.Acquire(context.Background()); err != nil {
+					t.Error(err)
+					return
+				}
+				defer sem.Release()
+				c.enter(1)
+				defer c.leave(1)
+				time.Sleep(hold)
+			}()
+		}
+		wg.Wait()
+		if got := c.most.Load(); got != int64(size) {
+			t.Errorf("size %d: %d held at once", size, got)
+		}
+		if len(sem) != 0 {
+			t.Errorf("size %d: %d units held after all released", size, len(sem))
+		}
+	}
+}
+
+func TestChan(t *testing.T) {
+	sem := semaphore.NewChan(2)
+	if !sem.TryAcquire() || !sem.TryAcquire() {
+		t.Fatal("TryAcquire refused with free units")
+	}
+	if sem.TryAcquire() {
+		t.Error("TryAcquire took a unit past the size")
+	}
+
+	// a waiter gives up with the cause of its context's end
+	ctx, cancel := context.WithCancelCause(context.Background())
+	done := make(chan error)
+	go func() { done <- sem.Acquire(ctx) }()
+	cancel(errBad)
+	if err := <-done; err != errBad {
+		t.Errorf("cancelled Acquire = %v, want %v", err, errBad)
+	}
+	// and an ended context takes nothing, even with a unit free
+	sem.Release()
+	if err := sem.Acquire(ctx); err != errBad || len(sem) != 1 {
+		t.Errorf("Acquire on an ended context = %v, %d held", err, len(sem))
+	}
+	if err := sem.Acquire(context.Background()); err != nil || len(sem) != 2 {
+		t.Errorf("Acquire = %v, %d held", err, len(sem))
+	}
+
+	sem.Release()
+	sem.Release()
+	for _, c := range []struct {
+		name string
+		f    func()
+		want string
+	}{
+		{"release", sem.Release, "semaphore: Release without Acquire"},
+		{"size", func() { semaphore.NewChan(0) }, "semaphore: size must be positive"},
+	} {
+		if got := recovered(c.f); got != c.want {
+			t.Errorf("%s: panicked with %q, want %q", c.name, got, c.want)
+		}
+	}
+}
+
+// TestWeightedCeiling has holders of varied weights take the units
+// together: the units held never pass the size.
+func TestWeightedCeiling(t *testing.T) {
+	const size = 10
+	sem := semaphore.NewWeighted(size)
+	var c ceiling
+	var wg sync.WaitGroup
+	for i := range 100 {
+		n := int64(i%size + 1)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := sem.Acquire(context.Background(), n); err != nil {
+				t.Error(err)
+				return
+			}
+			defer sem.Release(n)
+			c.enter(n)
+			defer c.leave(n)
+			time.Sleep(hold)
+		}()
+	}
+	wg.Wait()
+	if got := c.most.Load(); got > size || got < size/2 {
+		t.Errorf("%d units held at once, want at most %d", got, size)
+	}
+	if !sem.TryAcquire(size) {
+		t.Error("units still held after all released")
+	}
+}
+
+// acquire starts Acquire(n) in a goroutine; the channel gets its result.
+// A request that queues is given time to queue before acquire returns.
+func acquire(sem *semaphore.Weighted, ctx context.Context, n int64) <-chan error {
+	done := make(chan error, 1)
+	go func() { done <- sem.Acquire(ctx, n) }()
+	time.Sleep(5 * time.Millisecond)
+	return done
+}
+
+func granted(done <-chan error) bool {
+	select {
+	case err := <-done:
+		return err == nil
+	case <-time.After(10 * time.Millisecond):
+		return false
+	}
+}
+
+// TestWeightedOrder queues a large waiter before a small one: the small
+// one waits its turn though it would fit, and TryAcquire does not jump
+// the queue either.
+func TestWeightedOrder(t *testing.T) {
+	sem := semaphore.NewWeighted(4)
+	if err := sem.Acquire(context.Background(), 3); err != nil {
+		t.Fatal(err)
+	}
+	large := acquire(sem, context.Background(), 4)
+	small := acquire(sem, context.Background(), 1)
+	if sem.TryAcquire(1) || granted(small) {
+		t.Fatal("small request served before the large one queued ahead of it")
+	}
+	sem.Release(3)
+	if !granted(large) || granted(small) {
+		t.Fatal("large request not served first")
+	}
+	sem.Release(4)
+	if !granted(small) {
+		t.Fatal("small request not served once the units were free")
+	}
+
+	// the waiter at the front giving up lets those behind it in
+	ctx, cancel := context.WithCancel(context.Background())
+	large = acquire(sem, ctx, 4)
+	small = acquire(sem, context.Background(), 2)
+	if granted(small) {
+		t.Fatal("small request served before the large one queued ahead of it")
+	}
+	cancel()
+	if err := <-large; err != context.Canceled {
+		t.Errorf("cancelled Acquire = %v", err)
+	}
+	if !granted(small) {
+		t.Error("waiter behind a cancelled one not served")
+	}
+	// held: the 1 and the 2; the cancelled 4 left nothing behind
+	sem.Release(3)
+	if !sem.TryAcquire(4) {
+		t.Error("units leaked by a cancelled waiter")
+	}
+}
+
+func TestWeighted(t *testing.T) {
+	sem := semaphore.NewWeighted(5)
+	if err := sem.Acquire(context.Background(), 6); err == nil || err.Error() != "semaphore: acquiring 6 of 5 units" {
+		t.Errorf("Acquire past the size = %v", err)
+	}
+	// free units are taken even on an ended context; only waiting checks it
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := sem.Acquire(ctx, 5); err != nil {
+		t.Errorf("Acquire of free units = %v", err)
+	}
+	if err := sem.Acquire(ctx, 1); err != context.Canceled {
+		t.Errorf("Acquire on an ended context = %v", err)
+	}
+	sem.Release(5)
+
+	for _, c := range []struct {
+		name string
+		f    func()
+		want string
+	}{
+		{"release", func() { sem.Release(1) }, "semaphore: released more than held"},
+		{"size", func() { semaphore.NewWeighted(0) }, "semaphore: size must be positive"},
+	} {
+		if got := recovered(c.f); got != c.want {
+			t.Errorf("%s: panicked with %q, want %q", c.name, got, c.want)
+		}
+	}
+}
+
+// goroutines counts the goroutines ForEachLimit has started and that are
+// still running, giving those that have finished a moment to exit.
+func goroutines(want int) int {
+	n := 0
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		buf := make([]byte, 1<<20)
+		buf = buf[:runtime.Stack(buf, true)]
+		n = strings.Count(string(buf), "created by patterns/concurrency/semaphore.ForEachLimit")
+		if n == want {
+			break
+		}
+	}
+	return n
+}
+
+// TestForEachLimit runs more items than the limit: as many as the limit
+// run at once and no more, there are no more goroutines than that, and
+// every item runs once.
+func TestForEachLimit(t *testing.T) {
+	for _, limit := range []int{1, 3, 8} {
+		items := make([]int, 10*limit)
+		indexes := make([]int, len(items))
+		for i := range items {
+			items[i] = i * i
+			indexes[i] = i
+		}
+		var c ceiling
+		var mu sync.Mutex
+		var seen []int
+		release := make(chan struct{})
+		var first sync.Once
+		full := make(chan struct{})
+		done := make(chan error)
+		go func() {
+			done <- semaphore.ForEachLimit(context.Background(), items, limit, func(_ context.Context, i, item int) error {
+				if item != i*i {
+					t.Errorf("item %d is %d", i, item)
+				}
+				mu.Lock()
+				seen = append(seen, i)
+				mu.Unlock()
+				c.enter(1)
+				defer c.leave(1)
+				if c.now.Load() == int64(limit) {
+					first.Do(func() { close(full) })
+				}
+				<-release
+				return nil
+			})
+		}()
+		<-full
+		if n := goroutines(limit); n != limit {
+			t.Errorf("limit %d: %d goroutines with the limit reached", limit, n)
+		}
+		close(release)
+		if err := <-done; err != nil {
+			t.Fatal(err)
+		}
+		slices.Sort(seen)
+		if got := c.most.Load(); got != int64(limit) || !slices.Equal(seen, indexes) {
+			t.Errorf("limit %d: %d at once, ran %v", limit, got, seen)
+		}
+	}
+}
+
+// TestForEachLimitError fails one item: its error is returned, the others
+// running see their context cancelled with it as the cause, and no item
+// after them starts.
+func TestForEachLimitError(t *testing.T) {
+	for range 100 {
+		forEachLimitError(t)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var started atomic.Int32
+	err := semaphore.ForEachLimit(ctx, []int{1, 2}, 1, func(context.Context, int, int) error { started.Add(1); return nil })
+	if err != context.Canceled || started.Load() != 0 {
+		t.Errorf("cancelled ForEachLimit = %v after %d", err, started.Load())
+	}
+	if err := semaphore.ForEachLimit(context.Background(), []int(nil), 1, func(context.Context, int, int) error { return errBad }); err != nil {
+		t.Errorf("no items: %v", err)
+	}
+}
+
+func forEachLimitError(t *testing.T) {
+	t.Helper()
+	var started atomic.Int32
+	var cancelled atomic.Int32
+	err := semaphore.ForEachLimit(context.Background(), make([]struct{}, 100), 4, func(ctx context.Context, i int, _ struct{}) error {
+		started.Add(1)
+		if i == 3 {
+			return fmt.Errorf("item %d: %w", i, errBad)
+		}
+		<-ctx.Done()
+		if errors.Is(context.Cause(ctx), errBad) {
+			cancelled.Add(1)
+		}
+		return ctx.Err()
+	})
+	if !errors.Is(err, errBad) || err.Error() != "item 3: bad item" {
+		t.Errorf("ForEachLimit = %v", err)
+	}
+	// 0 to 2 hold three slots until 3 fails, and 3 frees its slot only
+	// once the context is already cancelled
+	if started.Load() != 4 || cancelled.Load() != 3 {
+		t.Fatalf("%d started, %d cancelled by the failure", started.Load(), cancelled.Load())
+	}
+}
+
+func recovered(f func()) (msg string) {
+	defer func() { msg = fmt.Sprint(recover()) }()
+	f()
+	return ""
+}
@@ -0,0 +1,71 @@
+package structured
+
+import (
+	"context"
+	"sync"
+)
+
+// FetchFunc fetches one key; it should give up when ctx is done.
+type FetchFunc[K, V any] func(ctx context.Context, key K) (V, error)
+
+// naive WaitGroup fan-out
+// Level: Poor
+// pros: only the standard library's most familiar primitive.
+// cons: a goroutine per key however many keys; a failure stops nothing,
+// so Wait lasts as long as the slowest fetch; the error is whichever
+// failure took the lock first, kept by hand under a mutex, and forgetting
+// the mutex is a data race.
+//
+// FetchNaive fetches every key at once, the usual WaitGroup way.
+func FetchNaive[K, V any](ctx context.Context, keys []K, fetch FetchFunc[K, V]) ([]V, error) {
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	out := make([]V, len(keys))
+	for i, k := range keys {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := fetch(ctx, k)
+			if err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = err
+				}
+				mu.Unlock()
+				return
+			}
+			out[i] = v
+		}()
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return out, nil
+}
+
+// Fetch fetches every key, at most limit at once, in a Group: the first
+// failure cancels the fetches still running and is the error returned.
+func Fetch[K, V any](ctx context.Context, keys []K, limit int, fetch FetchFunc[K, V]) ([]V, error) {
+	g, ctx := WithContext(ctx)
+	g.SetLimit(limit)
+	out := make([]V, len(keys))
+	for i, k := range keys {
+		g.Go(func() error {
+			if ctx.Err() != nil {
+				// a fetch failed, or the caller gave up: do not start
+				return context.Cause(ctx)
+			}
+			v, err := fetch(ctx, k)
+			out[i] = v
+			return err
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
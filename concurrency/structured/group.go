@@ -0,0 +1,144 @@
+// Package structured builds errgroup-style structured concurrency from
+// scratch, next to the WaitGroup code it replaces, so the pattern reads as
+// the handful of lines it is rather than as a dependency.
+//
+// Structured means goroutines are scoped like a block: a Group starts
+// them, Wait does not return until every one has, and nothing outlives
+// the function that made the group. Within that scope the group adds the
+// three things a bare sync.WaitGroup leaves to every caller:
+//
+//   - the first error is kept and returned by Wait; later ones, usually
+//     just "context canceled" from the siblings it stopped, are dropped
+//   - that first error cancels the group's context, so the siblings stop
+//     early instead of finishing work whose result is thrown away
+//   - SetLimit bounds how many run at once; Go blocks for a free slot
+//     rather than starting a goroutine per item
+//
+// A panic in one of them is recovered and raised again from Wait, on the
+// goroutine that owns the group, instead of crashing the process from a
+// goroutine no one can recover.
+//
+// FetchNaive and Fetch do the same job, one with a WaitGroup, one with a
+// Group; the pattern is in the difference.
+package structured
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sync"
+)
+
+// structured concurrency (errgroup)
+// Level: Good
+// pros: no goroutine outlives Wait; the first failure is the one
+// reported and stops the rest; concurrency is bounded without a worker
+// pool.
+// cons: one error wins and the others are lost; f must watch the context
+// for cancellation to mean anything.
+//
+// Group runs functions on goroutines and waits for them. The zero Group
+// has no context and no limit; WithContext adds one.
+type Group struct {
+	cancel context.CancelCauseFunc
+	wg     sync.WaitGroup
+	sem    chan struct{}
+
+	once  sync.Once
+	err   error
+	panic any
+}
+
+// WithContext returns a group and a context derived from ctx that is
+// cancelled, with the error as its cause, by the first function to fail,
+// or when Wait returns.
+func WithContext(ctx context.Context) (*Group, context.Context) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	return &Group{cancel: cancel}, ctx
+}
+
+// SetLimit allows at most n functions to run at once; n < 0 removes the
+// limit. It must not be called while any are running.
+func (g *Group) SetLimit(n int) {
+	if len(g.sem) != 0 {
+		panic(fmt.Errorf("structured: SetLimit with %d functions running", len(g.sem)))
+	}
+	if n < 0 {
+		g.sem = nil
+		return
+	}
+	g.sem = make(chan struct{}, n)
+}
+
+// Go runs f on a new goroutine, first waiting for a free slot if the
+// group is limited.
+func (g *Group) Go(f func() error) {
+	if g.sem != nil {
+		g.sem <- struct{}{}
+	}
+	g.start(f)
+}
+
+// TryGo runs f on a new goroutine only if a slot is free, and reports
+// whether it did.
+func (g *Group) TryGo(f func() error) bool {
+	if g.sem != nil {
+		select {
+		case g.sem <- struct{}{}:
+		default:
+			return false
+		}
+	}
+	g.start(f)
+	return true
+}
+
+func (g *Group) start(f func() error) {
+	g.wg.Add(1)
+	go func() {
+		defer g.done()
+		defer func() {
+			if p := recover(); p != nil {
+				g.fail(fmt.Errorf("structured: panic: %v", p), fmt.Sprintf("%v\n\n%s", p, debug.Stack()))
+			}
+		}()
+		if err := f(); err != nil {
+			g.fail(err, nil)
+		}
+	}()
+}
+
+func (g *Group) done() {
+	if g.sem != nil {
+		<-g.sem
+	}
+	g.wg.Done()
+}
+
+// fail records the first error, or panic, and cancels the siblings with
+// err as the cause.
+func (g *Group) fail(err error, p any) {
+	g.once.Do(func() {
+		if p != nil {
+			g.panic = p
+		} else {
+			g.err = err
+		}
+		if g.cancel != nil {
+			g.cancel(err)
+		}
+	})
+}
+
+// Wait blocks until every function has returned, then returns the first
+// error, or panics with the first panic, with its goroutine's stack.
+func (g *Group) Wait() error {
+	g.wg.Wait()
+	if g.cancel != nil {
+		g.cancel(context.Canceled)
+	}
+	if g.panic != nil {
+		panic(g.panic)
+	}
+	return g.err
+}
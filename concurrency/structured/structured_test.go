@@ -0,0 +1,290 @@
+package structured_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"patterns/concurrency/structured"
+)
+
+var errBad = errors.New("bad key")
+
+// TestFirstError fails one function while its siblings wait for the
+// group's context: the failure cancels them, with itself as the cause,
+// and is the one Wait returns.
+func TestFirstError(t *testing.T) {
+	g, ctx := structured.WithContext(context.Background())
+	var causes sync.Map
+	for i := range 5 {
+		g.Go(func() error {
+			if i == 2 {
+				return errBad
+			}
+			<-ctx.Done()
+			causes.Store(i, context.Cause(ctx))
+			return fmt.Errorf("sibling %d: %w", i, ctx.Err())
+		})
+	}
+	if err := g.Wait(); err != errBad {
+		t.Errorf("Wait = %v, want %v", err, errBad)
+	}
+	n := 0
+	causes.Range(func(_, cause any) bool {
+		n++
+		if cause != errBad {
+			t.Errorf("sibling cancelled by %v", cause)
+		}
+		return true
+	})
+	if n != 4 {
+		t.Errorf("%d siblings stopped, want 4", n)
+	}
+}
+
+func TestWait(t *testing.T) {
+	g, ctx := structured.WithContext(context.Background())
+	ran := 0
+	var mu sync.Mutex
+	for range 3 {
+		g.Go(func() error {
+			mu.Lock()
+			defer mu.Unlock()
+			ran++
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil || ran != 3 {
+		t.Errorf("Wait = %v after %d", err, ran)
+	}
+	// done once Wait returns, without an error to blame
+	if ctx.Err() == nil || context.Cause(ctx) != context.Canceled {
+		t.Errorf("context after Wait: %v", context.Cause(ctx))
+	}
+
+	// the zero group keeps the first error, with nothing to cancel
+	var zero structured.Group
+	zero.Go(func() error { return errBad })
+	if err := zero.Wait(); err != errBad {
+		t.Errorf("zero Group: %v", err)
+	}
+	if err := new(structured.Group).Wait(); err != nil {
+		t.Errorf("empty Group: %v", err)
+	}
+}
+
+// TestLimit runs many functions in groups of each limit: no more than the
+// limit run at once, and as many as the limit do.
+func TestLimit(t *testing.T) {
+	for _, limit := range []int{1, 3, 8} {
+		var g structured.Group
+		g.SetLimit(limit)
+		var running, most atomic.Int32
+		full := make(chan struct{})
+		var once sync.Once
+		for range 4 * limit {
+			g.Go(func() error {
+				n := running.Add(1)
+				defer running.Add(-1)
+				for m := most.Load(); n > m && !most.CompareAndSwap(m, n); m = most.Load() {
+				}
+				if n == int32(limit) {
+					once.Do(func() { close(full) })
+				}
+				// hold the slot until the group has been full once
+				<-full
+				return nil
+			})
+		}
+		if err := g.Wait(); err != nil {
+			t.Fatal(err)
+		}
+		if got := most.Load(); got != int32(limit) {
+			t.Errorf("limit %d: %d ran at once", limit, got)
+		}
+	}
+}
+
+func TestTryGo(t *testing.T) {
+	var g structured.Group
+	g.SetLimit(1)
+	release := make(chan struct{})
+	if !g.TryGo(func() error { <-release; return nil }) {
+		t.Fatal("TryGo refused with a free slot")
+	}
+	if g.TryGo(func() error { t.Error("ran without a slot"); return nil }) {
+		t.Error("TryGo started a function over the limit")
+	}
+	close(release)
+	g.Wait()
+	if !g.TryGo(func() error { return errBad }) || g.Wait() != errBad {
+		t.Error("TryGo refused once the slot was free again")
+	}
+
+	// unlimited
+	g = structured.Group{}
+	for range 100 {
+		if !g.TryGo(func() error { return nil }) {
+			t.Fatal("TryGo refused without a limit")
+		}
+	}
+	g.Wait()
+}
+
+func TestSetLimit(t *testing.T) {
+	var g structured.Group
+	g.SetLimit(2)
+	release := make(chan struct{})
+	g.Go(func() error { <-release; return nil })
+	func() {
+		defer func() {
+			if r := recover(); r == nil || fmt.Sprint(r) != "structured: SetLimit with 1 functions running" {
+				t.Errorf("SetLimit while running: recovered %v", r)
+			}
+		}()
+		g.SetLimit(5)
+	}()
+	close(release)
+	g.Wait()
+
+	// -1 lifts the limit
+	g.SetLimit(-1)
+	start := make(chan struct{})
+	var started sync.WaitGroup
+	started.Add(10)
+	for range 10 {
+		g.Go(func() error {
+			started.Done()
+			<-start
+			return nil
+		})
+	}
+	started.Wait()
+	close(start)
+	g.Wait()
+}
+
+// TestPanic panics in one function: Wait panics in the caller's goroutine
+// with the value and the stack it came from, after the siblings, told
+// why, have stopped.
+func TestPanic(t *testing.T) {
+	g, ctx := structured.WithContext(context.Background())
+	var cause error
+	g.Go(func() error {
+		<-ctx.Done()
+		cause = context.Cause(ctx)
+		return nil
+	})
+	g.Go(func() error { panic("boom") })
+	defer func() {
+		r := recover()
+		s, _ := r.(string)
+		if !strings.HasPrefix(s, "boom\n\n") || !strings.Contains(s, "structured_test.TestPanic") {
+			t.Errorf("Wait panicked with %q", r)
+		}
+		if cause == nil || cause.Error() != "structured: panic: boom" {
+			t.Errorf("sibling cancelled by %v", cause)
+		}
+	}()
+	g.Wait()
+	t.Error("Wait returned after a panic")
+}
+
+// fetcher fetches key k as "v<k>", failing for bad, and records what it
+// was asked for. Keys after bad wait for cancellation, so the failure is
+// what ends them.
+type fetcher struct {
+	bad     int
+	mu      sync.Mutex
+	fetched []int
+}
+
+func (f *fetcher) fetch(ctx context.Context, k int) (string, error) {
+	f.mu.Lock()
+	f.fetched = append(f.fetched, k)
+	f.mu.Unlock()
+	switch {
+	case k == f.bad:
+		return "", fmt.Errorf("%d: %w", k, errBad)
+	case f.bad >= 0 && k > f.bad:
+		<-ctx.Done()
+		return "", ctx.Err()
+	}
+	return fmt.Sprintf("v%d", k), nil
+}
+
+func keys(n int) []int {
+	out := make([]int, n)
+	for i := range out {
+		out[i] = i
+	}
+	return out
+}
+
+func TestFetch(t *testing.T) {
+	f := &fetcher{bad: -1}
+	got, err := structured.Fetch(context.Background(), keys(6), 2, f.fetch)
+	if err != nil || !slices.Equal(got, []string{"v0", "v1", "v2", "v3", "v4", "v5"}) {
+		t.Errorf("Fetch = %v, %v", got, err)
+	}
+}
+
+// TestFetchPartial fails one key in the middle: Fetch returns its error
+// and no values, cancels the key fetched beside it, and starts none of
+// the keys still waiting for a slot.
+func TestFetchPartial(t *testing.T) {
+	for range 20 {
+		f := &fetcher{bad: 3}
+		got, err := structured.Fetch(context.Background(), keys(20), 2, f.fetch)
+		if !errors.Is(err, errBad) || err.Error() != "3: bad key" || got != nil {
+			t.Fatalf("Fetch = %v, %v", got, err)
+		}
+		// 4 can take 2's slot before 3 fails, but by 5's turn the two slots
+		// are 3's and 4's, freed only after the failure; a key before 3 may
+		// lose the race to it and not start either
+		if !slices.Contains(f.fetched, 3) || slices.Max(f.fetched) > 4 {
+			t.Fatalf("fetched %v", f.fetched)
+		}
+	}
+
+	// a caller that has given up starts nothing
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	f := &fetcher{bad: -1}
+	if _, err := structured.Fetch(ctx, keys(5), 2, f.fetch); !errors.Is(err, context.Canceled) || len(f.fetched) != 0 {
+		t.Errorf("cancelled Fetch = %v, fetched %v", err, f.fetched)
+	}
+}
+
+// TestFetchNaive shows the difference: a failure leaves every other fetch
+// to finish, with a context nothing cancels.
+func TestFetchNaive(t *testing.T) {
+	f := &fetcher{bad: -1}
+	got, err := structured.FetchNaive(context.Background(), keys(4), f.fetch)
+	if err != nil || !slices.Equal(got, []string{"v0", "v1", "v2", "v3"}) {
+		t.Errorf("FetchNaive = %v, %v", got, err)
+	}
+
+	var mu sync.Mutex
+	fetched := 0
+	_, err = structured.FetchNaive(context.Background(), keys(10), func(ctx context.Context, k int) (string, error) {
+		mu.Lock()
+		fetched++
+		mu.Unlock()
+		if k == 3 {
+			return "", errBad
+		}
+		if ctx.Err() != nil {
+			t.Error("FetchNaive cancelled a fetch")
+		}
+		return "", nil
+	})
+	if err != errBad || fetched != 10 {
+		t.Errorf("FetchNaive = %v after %d fetches", err, fetched)
+	}
+}
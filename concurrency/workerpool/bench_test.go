@@ -0,0 +1,84 @@
+package workerpool
+
+import (
+	"context"
+	"crypto/sha256"
+	"runtime"
+	"sync"
+	"testing"
+)
+
+const benchInputs = 10000
+
+var benchData = func() [][]byte {
+	ins := make([][]byte, benchInputs)
+	for i := range ins {
+		ins[i] = make([]byte, 1024)
+		ins[i][0] = byte(i)
+	}
+	return ins
+}()
+
+func hash(_ context.Context, b []byte) ([32]byte, error) { return sha256.Sum256(b), nil }
+
+func benchHash(run func(outs [][32]byte)) func(b *testing.B) {
+	return func(b *testing.B) {
+		outs := make([][32]byte, benchInputs)
+		for range b.N {
+			run(outs)
+		}
+	}
+}
+
+// BenchmarkHash hashes benchInputs inputs sequentially, with a goroutine
+// each, bounded by a semaphore, on a Pool and with Map.
+func BenchmarkHash(b *testing.B) {
+	b.Run("sequential", benchHash(func(outs [][32]byte) {
+		for i, in := range benchData {
+			outs[i], _ = hash(nil, in)
+		}
+	}))
+	b.Run("unbounded", benchHash(func(outs [][32]byte) {
+		var wg sync.WaitGroup
+		for i, in := range benchData {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				outs[i], _ = hash(nil, in)
+			}()
+		}
+		wg.Wait()
+	}))
+	b.Run("semaphore", benchHash(func(outs [][32]byte) {
+		var wg sync.WaitGroup
+		sem := make(chan struct{}, runtime.GOMAXPROCS(0))
+		for i, in := range benchData {
+			sem <- struct{}{}
+			wg.Add(1)
+			go func() {
+				defer func() { <-sem; wg.Done() }()
+				outs[i], _ = hash(nil, in)
+			}()
+		}
+		wg.Wait()
+	}))
+	b.Run("pool", benchHash(func(outs [][32]byte) {
+		ctx := context.Background()
+		p, _ := New(ctx, func(ctx context.Context, i int) ([32]byte, error) {
+			return hash(ctx, benchData[i])
+		})
+		go func() {
+			for i := range benchData {
+				p.Submit(ctx, i)
+			}
+			p.Close()
+		}()
+		for r := range p.Results() {
+			outs[r.In] = r.Out
+		}
+	}))
+	b.Run("map", benchHash(func(outs [][32]byte) {
+		res, _ := Map(context.Background(), benchData, hash)
+		copy(outs, res)
+	}))
+}
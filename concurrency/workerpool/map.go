@@ -0,0 +1,45 @@
+package workerpool
+
+import "context"
+
+type indexed[In any] struct {
+	i  int
+	in In
+}
+
+// Map runs fn over ins on a pool and returns the outputs in input order.
+// The first error cancels the inputs not yet started and is returned,
+// with the outputs of the inputs that did complete.
+func Map[In, Out any](ctx context.Context, ins []In, fn Func[In, Out], opts ...Option) ([]Out, error) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	p, err := New(ctx, func(ctx context.Context, x indexed[In]) (Out, error) {
+		return fn(ctx, x.in)
+	}, opts...)
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		defer p.Close()
+		for i, in := range ins {
+			if p.Submit(ctx, indexed[In]{i, in}) != nil {
+				return
+			}
+		}
+	}()
+	outs := make([]Out, len(ins))
+	done := 0
+	for r := range p.Results() {
+		if r.Err != nil {
+			// the first cause sticks: later results fail with the cancel
+			cancel(r.Err)
+			continue
+		}
+		outs[r.In.i] = r.Out
+		done++
+	}
+	if done < len(ins) {
+		return outs, context.Cause(ctx)
+	}
+	return outs, nil
+}
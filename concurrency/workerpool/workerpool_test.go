@@ -0,0 +1,237 @@
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func double(_ context.Context, n int) (int, error) { return 2 * n, nil }
+
+// TestCloseRacesSubmit has producers submit while another goroutine
+// closes the pool: every accepted input gets exactly one result, every
+// refused one gets ErrClosed, and Results is closed at the end. Run it
+// with -race.
+func TestCloseRacesSubmit(t *testing.T) {
+	for range 20 {
+		p, err := New(context.Background(), double, WithWorkers(4), WithQueue(2))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var accepted atomic.Int64
+		var wg sync.WaitGroup
+		for g := range 8 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := range 100 {
+					switch err := p.Submit(context.Background(), g*100+i); err {
+					case nil:
+						accepted.Add(1)
+					case ErrClosed:
+						return
+					default:
+						t.Errorf("Submit = %v", err)
+						return
+					}
+				}
+			}()
+		}
+		go func() {
+			time.Sleep(time.Millisecond)
+			p.Close()
+		}()
+
+		seen := map[int]bool{}
+		for r := range p.Results() {
+			if r.Err != nil || r.Out != 2*r.In {
+				t.Fatalf("result %+v, want %d", r, 2*r.In)
+			}
+			if seen[r.In] {
+				t.Fatalf("input %d has two results", r.In)
+			}
+			seen[r.In] = true
+		}
+		wg.Wait()
+		if int64(len(seen)) != accepted.Load() {
+			t.Fatalf("%d results for %d accepted inputs", len(seen), accepted.Load())
+		}
+		if err := p.Submit(context.Background(), 0); err != ErrClosed {
+			t.Fatalf("Submit after Close = %v, want ErrClosed", err)
+		}
+		p.Wait()
+	}
+}
+
+func TestWorkerBound(t *testing.T) {
+	var running, peak atomic.Int32
+	p, err := New(context.Background(), func(_ context.Context, n int) (int, error) {
+		now := running.Add(1)
+		for {
+			old := peak.Load()
+			if now <= old || peak.CompareAndSwap(old, now) {
+				break
+			}
+		}
+		time.Sleep(100 * time.Microsecond)
+		running.Add(-1)
+		return n, nil
+	}, WithWorkers(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		defer p.Close()
+		for i := range 200 {
+			p.Submit(context.Background(), i)
+		}
+	}()
+	n := 0
+	for range p.Results() {
+		n++
+	}
+	if n != 200 {
+		t.Errorf("%d results, want 200", n)
+	}
+	if got := peak.Load(); got > 3 {
+		t.Errorf("%d inputs ran at once on 3 workers", got)
+	}
+}
+
+// TestCloseDrains checks that inputs queued before Close still run.
+func TestCloseDrains(t *testing.T) {
+	release := make(chan struct{})
+	p, err := New(context.Background(), func(_ context.Context, n int) (int, error) {
+		<-release
+		return n, nil
+	}, WithWorkers(1), WithQueue(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range 10 {
+		if err := p.Submit(context.Background(), i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	p.Close()
+	close(release)
+	var got []int
+	for r := range p.Results() {
+		if r.Err != nil {
+			t.Errorf("input %d: %v", r.In, r.Err)
+		}
+		got = append(got, r.In)
+	}
+	slices.Sort(got)
+	if !slices.Equal(got, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}) {
+		t.Errorf("results for %v, want all ten inputs", got)
+	}
+}
+
+// TestCancel checks that cancelling the pool's context hands the queued
+// inputs back unrun, with the context's error, and stops Submit.
+func TestCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	started := make(chan struct{})
+	var ran atomic.Int32
+	p, err := New(ctx, func(ctx context.Context, n int) (int, error) {
+		if ran.Add(1) == 1 {
+			close(started)
+		}
+		<-ctx.Done()
+		return n, ctx.Err()
+	}, WithWorkers(1), WithQueue(5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range 6 {
+		if err := p.Submit(context.Background(), i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	<-started
+	cancel()
+
+	n := 0
+	for r := range p.Results() {
+		n++
+		if !errors.Is(r.Err, context.Canceled) {
+			t.Errorf("input %d: err %v, want Canceled", r.In, r.Err)
+		}
+	}
+	if n != 6 {
+		t.Errorf("%d results, want one for each of the 6 inputs", n)
+	}
+	if got := ran.Load(); got != 1 {
+		t.Errorf("fn ran %d times, want only the input already started", got)
+	}
+	// Results is closed only after the cancel closed the pool
+	if err := p.Submit(context.Background(), 7); err != ErrClosed {
+		t.Errorf("Submit after cancel = %v, want ErrClosed", err)
+	}
+}
+
+// TestSubmitBlocks checks that a full queue pushes back on Submit.
+func TestSubmitBlocks(t *testing.T) {
+	release := make(chan struct{})
+	p, err := New(context.Background(), func(_ context.Context, n int) (int, error) {
+		<-release
+		return n, nil
+	}, WithWorkers(1), WithQueue(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Submit(context.Background(), 1) // taken by the worker
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := p.Submit(ctx, 2); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Submit to a busy pool = %v, want DeadlineExceeded", err)
+	}
+	p.Close()
+	close(release)
+	for range p.Results() {
+	}
+}
+
+func TestMap(t *testing.T) {
+	ins := make([]int, 100)
+	for i := range ins {
+		ins[i] = i
+	}
+	outs, err := Map(context.Background(), ins, double, WithWorkers(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, out := range outs {
+		if out != 2*i {
+			t.Fatalf("outs[%d] = %d, want %d", i, out, 2*i)
+		}
+	}
+}
+
+func TestMapFirstError(t *testing.T) {
+	errBad := errors.New("bad input")
+	var ran atomic.Int32
+	ins := make([]int, 1000)
+	for i := range ins {
+		ins[i] = i
+	}
+	_, err := Map(context.Background(), ins, func(ctx context.Context, n int) (int, error) {
+		ran.Add(1)
+		if n == 10 {
+			return 0, errBad
+		}
+		return n, nil
+	}, WithWorkers(2))
+	if !errors.Is(err, errBad) {
+		t.Errorf("Map = %v, want the first error", err)
+	}
+	if got := ran.Load(); got == int32(len(ins)) {
+		t.Errorf("fn ran for all %d inputs after the error", got)
+	}
+}
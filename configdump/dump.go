@@ -0,0 +1,272 @@
+// Package configdump renders a resolved configuration struct as JSON or
+// YAML for --print-config style debugging. Fields tagged `secret:"true"`,
+// and values that say they are secret as security/secret.Secret does, are
+// replaced by "[REDACTED]", so dumps are safe to paste into tickets.
+package configdump
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Format selects the output encoding.
+type Format string
+
+const (
+	JSON Format = "json"
+	YAML Format = "yaml"
+)
+
+// ParseFormat accepts "json", "yaml" or "yml".
+func ParseFormat(s string) (Format, error) {
+	switch strings.ToLower(s) {
+	case "json":
+		return JSON, nil
+	case "yaml", "yml":
+		return YAML, nil
+	}
+	return "", fmt.Errorf("unknown config format %q", s)
+}
+
+// Redacted replaces secret values.
+const Redacted = "[REDACTED]"
+
+// Dump writes v in format. Struct field names come from the json tag when
+// present; durations and TextMarshalers are written in their text form.
+func Dump(w io.Writer, v any, format Format) error {
+	n := toNode(reflect.ValueOf(v))
+	switch format {
+	case JSON:
+		var b strings.Builder
+		writeJSON(&b, n, "")
+		b.WriteString("\n")
+		_, err := io.WriteString(w, b.String())
+		return err
+	case YAML:
+		var b strings.Builder
+		writeYAML(&b, n, 0)
+		_, err := io.WriteString(w, b.String())
+		return err
+	}
+	return fmt.Errorf("unknown config format %q", format)
+}
+
+// node is an ordered, encoding-neutral tree.
+type node struct {
+	scalar any // string, bool, int64, float64 or nil
+	keys   []string
+	fields map[string]node
+	items  []node
+	kind   byte // 's'calar, 'm'ap, 'l'ist
+}
+
+var textMarshaler = reflect.TypeFor[encoding.TextMarshaler]()
+
+// secret is implemented by values that are redacted wherever they appear.
+type secret interface{ IsSecret() bool }
+
+var secretType = reflect.TypeFor[secret]()
+
+func toNode(v reflect.Value) node {
+	if !v.IsValid() {
+		return node{kind: 's'}
+	}
+	if v.Type().Implements(secretType) && v.CanInterface() && v.Interface().(secret).IsSecret() {
+		return node{kind: 's', scalar: Redacted}
+	}
+	if v.Type() == reflect.TypeFor[time.Duration]() {
+		return node{kind: 's', scalar: time.Duration(v.Int()).String()}
+	}
+	if v.Type().Implements(textMarshaler) && (v.Kind() != reflect.Pointer || !v.IsNil()) {
+		if text, err := v.Interface().(encoding.TextMarshaler).MarshalText(); err == nil {
+			return node{kind: 's', scalar: string(text)}
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return node{kind: 's'}
+		}
+		return toNode(v.Elem())
+	case reflect.Struct:
+		n := node{kind: 'm', fields: map[string]node{}}
+		t := v.Type()
+		for i := range t.NumField() {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			name := f.Name
+			if tag, _, _ := strings.Cut(f.Tag.Get("json"), ","); tag == "-" {
+				continue
+			} else if tag != "" {
+				name = tag
+			}
+			n.keys = append(n.keys, name)
+			if f.Tag.Get("secret") == "true" {
+				n.fields[name] = node{kind: 's', scalar: Redacted}
+				continue
+			}
+			n.fields[name] = toNode(v.Field(i))
+		}
+		return n
+	case reflect.Map:
+		n := node{kind: 'm', fields: map[string]node{}}
+		for _, k := range v.MapKeys() {
+			key := fmt.Sprint(k.Interface())
+			n.keys = append(n.keys, key)
+			n.fields[key] = toNode(v.MapIndex(k))
+		}
+		sort.Strings(n.keys)
+		return n
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return node{kind: 'l'}
+		}
+		n := node{kind: 'l'}
+		for i := range v.Len() {
+			n.items = append(n.items, toNode(v.Index(i)))
+		}
+		return n
+	case reflect.String:
+		return node{kind: 's', scalar: v.String()}
+	case reflect.Bool:
+		return node{kind: 's', scalar: v.Bool()}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return node{kind: 's', scalar: v.Int()}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return node{kind: 's', scalar: int64(v.Uint())}
+	case reflect.Float32, reflect.Float64:
+		return node{kind: 's', scalar: v.Float()}
+	}
+	return node{kind: 's', scalar: fmt.Sprint(v.Interface())}
+}
+
+func writeJSON(b *strings.Builder, n node, indent string) {
+	switch n.kind {
+	case 'm':
+		if len(n.keys) == 0 {
+			b.WriteString("{}")
+			return
+		}
+		b.WriteString("{\n")
+		for i, k := range n.keys {
+			key, _ := json.Marshal(k)
+			b.WriteString(indent + "  " + string(key) + ": ")
+			writeJSON(b, n.fields[k], indent+"  ")
+			if i < len(n.keys)-1 {
+				b.WriteString(",")
+			}
+			b.WriteString("\n")
+		}
+		b.WriteString(indent + "}")
+	case 'l':
+		if len(n.items) == 0 {
+			b.WriteString("[]")
+			return
+		}
+		b.WriteString("[\n")
+		for i, item := range n.items {
+			b.WriteString(indent + "  ")
+			writeJSON(b, item, indent+"  ")
+			if i < len(n.items)-1 {
+				b.WriteString(",")
+			}
+			b.WriteString("\n")
+		}
+		b.WriteString(indent + "]")
+	default:
+		out, _ := json.Marshal(n.scalar)
+		b.Write(out)
+	}
+}
+
+func writeYAML(b *strings.Builder, n node, depth int) {
+	pad := strings.Repeat("  ", depth)
+	switch n.kind {
+	case 'm':
+		for _, k := range n.keys {
+			child := n.fields[k]
+			b.WriteString(pad + yamlKey(k) + ":")
+			writeYAMLChild(b, child, depth)
+		}
+	case 'l':
+		for _, item := range n.items {
+			if item.kind == 'm' && len(item.keys) > 0 {
+				// "- key: value" with the remaining keys aligned under it
+				var sub strings.Builder
+				writeYAML(&sub, item, depth+1)
+				b.WriteString(pad + "- " + strings.TrimPrefix(sub.String(), pad+"  "))
+				continue
+			}
+			b.WriteString(pad + "-")
+			writeYAMLChild(b, item, depth)
+		}
+	default:
+		b.WriteString(pad + yamlScalar(n.scalar) + "\n")
+	}
+}
+
+func writeYAMLChild(b *strings.Builder, child node, depth int) {
+	switch {
+	case child.kind == 'm' && len(child.keys) == 0:
+		b.WriteString(" {}\n")
+	case child.kind == 'l' && len(child.items) == 0:
+		b.WriteString(" []\n")
+	case child.kind == 's':
+		b.WriteString(" " + yamlScalar(child.scalar) + "\n")
+	default:
+		b.WriteString("\n")
+		writeYAML(b, child, depth+1)
+	}
+}
+
+func yamlKey(k string) string {
+	if k == "" || strings.ContainsAny(k, ":#{}[],&*?|-<>=!%@`'\" \t") {
+		return strconv.Quote(k)
+	}
+	return k
+}
+
+func yamlScalar(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return strconv.FormatBool(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case string:
+		if needsQuote(v) {
+			return strconv.Quote(v)
+		}
+		return v
+	}
+	return strconv.Quote(fmt.Sprint(v))
+}
+
+// needsQuote is conservative: anything YAML could read as another type or
+// as syntax is quoted.
+func needsQuote(s string) bool {
+	if s == "" || strings.TrimSpace(s) != s {
+		return true
+	}
+	switch strings.ToLower(s) {
+	case "true", "false", "yes", "no", "on", "off", "null", "~":
+		return true
+	}
+	if _, err := strconv.ParseFloat(s, 64); err == nil {
+		return true
+	}
+	return strings.ContainsAny(s, ":#{}[],&*?|<>=!%@`'\"\n") || strings.HasPrefix(s, "-")
+}
@@ -0,0 +1,140 @@
+// Package configpatterns loads a server's configuration from four layers,
+// each overriding the one before it:
+//
+//	defaults < file < environment < flags < overrides
+//
+// Every layer is turned into the same functional options, so a setting is
+// parsed and checked by one function whichever layer it comes from, and
+// the layers are merged by applying the options in order with
+// construct.New: SetDefaults first, then the file, APP_* variables, the
+// flags that were actually given and the caller's overrides, then
+// Validate for the rules across fields. A setting a layer does not
+// mention keeps the value from below; a flag left at its default is not
+// given, so it does not reset what the environment set. Load also returns
+// the Source of every setting, for a --print-config that says where each
+// value came from.
+//
+// NaiveLoad is the common shortcut that gets the order wrong.
+//
+// The tests run a table of layer combinations through Load and check the
+// merged values, their sources and the errors:
+//
+//	go test patterns/configpatterns
+package configpatterns
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"patterns/funcopts"
+)
+
+// Config is the merged configuration.
+type Config struct {
+	Addr            string        `json:"addr"`
+	Port            int           `json:"port"`
+	ReadTimeout     time.Duration `json:"read_timeout"`
+	ShutdownTimeout time.Duration `json:"shutdown_timeout"`
+	LogLevel        string        `json:"log_level"`
+	MaxConns        int           `json:"max_conns"`
+	Debug           bool          `json:"debug"`
+}
+
+// The defaults, the bottom layer.
+const (
+	DefaultAddr            = "localhost"
+	DefaultPort            = 8080
+	DefaultReadTimeout     = 5 * time.Second
+	DefaultShutdownTimeout = 10 * time.Second
+	DefaultLogLevel        = "info"
+	DefaultMaxConns        = 100
+)
+
+// LogLevels are the accepted log levels.
+var LogLevels = []string{"debug", "info", "warn", "error"}
+
+func (c *Config) SetDefaults() {
+	*c = Config{
+		Addr:            DefaultAddr,
+		Port:            DefaultPort,
+		ReadTimeout:     DefaultReadTimeout,
+		ShutdownTimeout: DefaultShutdownTimeout,
+		LogLevel:        DefaultLogLevel,
+		MaxConns:        DefaultMaxConns,
+	}
+}
+
+func (c *Config) Validate() error {
+	if c.ShutdownTimeout < c.ReadTimeout {
+		return fmt.Errorf("shutdown_timeout %v is shorter than read_timeout %v", c.ShutdownTimeout, c.ReadTimeout)
+	}
+	return nil
+}
+
+// Option sets one setting of a Config; every layer is made of them.
+type Option = funcopts.Option[Config]
+
+// set returns an option named key that checks v and stores it.
+func set[V any](key string, v V, check func(V) error, store func(*Config, V)) Option {
+	return funcopts.Describe(funcopts.Info{Name: key, Value: v}, func(c *Config) error {
+		if check != nil {
+			if err := check(v); err != nil {
+				return fmt.Errorf("%s: %w", key, err)
+			}
+		}
+		store(c, v)
+		return nil
+	})
+}
+
+func WithAddr(addr string) Option {
+	return set("addr", addr, nil, func(c *Config, v string) { c.Addr = v })
+}
+
+func WithPort(port int) Option {
+	return set("port", port, func(p int) error {
+		if p < 0 || p > 65535 {
+			return fmt.Errorf("%d out of range 0-65535", p)
+		}
+		return nil
+	}, func(c *Config, v int) { c.Port = v })
+}
+
+func WithReadTimeout(d time.Duration) Option {
+	return set("read_timeout", d, positive, func(c *Config, v time.Duration) { c.ReadTimeout = v })
+}
+
+func WithShutdownTimeout(d time.Duration) Option {
+	return set("shutdown_timeout", d, positive, func(c *Config, v time.Duration) { c.ShutdownTimeout = v })
+}
+
+func WithLogLevel(level string) Option {
+	return set("log_level", level, func(l string) error {
+		if !slices.Contains(LogLevels, l) {
+			return fmt.Errorf("%q is not one of %v", l, LogLevels)
+		}
+		return nil
+	}, func(c *Config, v string) { c.LogLevel = v })
+}
+
+func WithMaxConns(n int) Option {
+	return set("max_conns", n, func(n int) error {
+		if n <= 0 {
+			return errors.New("must be positive")
+		}
+		return nil
+	}, func(c *Config, v int) { c.MaxConns = v })
+}
+
+func WithDebug(debug bool) Option {
+	return set("debug", debug, nil, func(c *Config, v bool) { c.Debug = v })
+}
+
+func positive(d time.Duration) error {
+	if d <= 0 {
+		return fmt.Errorf("%v must be positive", d)
+	}
+	return nil
+}
@@ -0,0 +1,294 @@
+package configpatterns
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"patterns/construct"
+	"patterns/funcopts"
+)
+
+// Layer is where a setting came from, lowest precedence first.
+type Layer int
+
+const (
+	Default Layer = iota
+	File
+	Env
+	Flag
+	Override
+)
+
+func (l Layer) String() string {
+	return [...]string{"default", "file", "env", "flag", "override"}[l]
+}
+
+// Source is the layer that set a setting and the name it had there: the
+// file's path, the variable or the flag.
+type Source struct {
+	Layer Layer
+	Name  string
+}
+
+func (s Source) String() string {
+	if s.Name == "" {
+		return s.Layer.String()
+	}
+	return s.Layer.String() + " " + s.Name
+}
+
+// Sources maps each setting, by key, to its Source.
+type Sources map[string]Source
+
+// key is one setting: its name in the file, from which the variable and
+// the flag are derived, and how to parse it from text.
+type key struct {
+	name  string
+	usage string
+	bool  bool
+	parse func(s string) (Option, error)
+}
+
+var keys = []key{
+	{name: "addr", usage: "host to listen on", parse: func(s string) (Option, error) { return WithAddr(s), nil }},
+	{name: "port", usage: "port to listen on", parse: parseWith(strconv.Atoi, WithPort)},
+	{name: "read_timeout", usage: "request read timeout", parse: parseWith(time.ParseDuration, WithReadTimeout)},
+	{name: "shutdown_timeout", usage: "graceful shutdown timeout", parse: parseWith(time.ParseDuration, WithShutdownTimeout)},
+	{name: "log_level", usage: "one of debug, info, warn, error", parse: func(s string) (Option, error) { return WithLogLevel(s), nil }},
+	{name: "max_conns", usage: "concurrent connections", parse: parseWith(strconv.Atoi, WithMaxConns)},
+	{name: "debug", usage: "debug endpoints", bool: true, parse: parseWith(strconv.ParseBool, WithDebug)},
+}
+
+func parseWith[V any](parse func(string) (V, error), with func(V) Option) func(string) (Option, error) {
+	return func(s string) (Option, error) {
+		v, err := parse(s)
+		if err != nil {
+			return nil, err
+		}
+		return with(v), nil
+	}
+}
+
+func lookupKey(name string) (key, bool) {
+	i := slices.IndexFunc(keys, func(k key) bool { return k.name == name })
+	if i < 0 {
+		return key{}, false
+	}
+	return keys[i], true
+}
+
+func (k key) env(prefix string) string { return prefix + "_" + strings.ToUpper(k.name) }
+
+func (k key) flag() string { return strings.ReplaceAll(k.name, "_", "-") }
+
+// layered config loader
+// Level: Good
+// pros: one parser and one check per setting, whatever the layer; only
+// what a layer mentions overrides the layer below, so precedence holds
+// setting by setting; every error names the variable, flag or file it
+// came from, and they are all reported at once.
+// cons: each setting is listed in the keys table as well as in Config.
+//
+// Loader loads a Config from its layers. The zero value reads no file,
+// the APP_* variables of the process and no arguments.
+type Loader struct {
+	// File is the JSON file to read, if not empty; the -config flag
+	// replaces it.
+	File string
+	// EnvPrefix names the variables, APP if empty: APP_PORT, APP_DEBUG.
+	EnvPrefix string
+	// LookupEnv and ReadFile default to os.LookupEnv and os.ReadFile.
+	LookupEnv func(string) (string, bool)
+	ReadFile  func(string) ([]byte, error)
+	// Output receives the usage for -h; nil discards it.
+	Output io.Writer
+}
+
+// Load parses args as flags and merges the layers, then overrides, into
+// a validated Config. A flag that does not parse stops Load, as it stops
+// flag parsing, and -h returns flag.ErrHelp; otherwise the errors of all
+// layers are joined, so one run reports them all.
+func (l Loader) Load(args []string, overrides ...Option) (*Config, Sources, error) {
+	flagOpts, file, err := l.flags(args)
+	if err != nil {
+		return nil, nil, err
+	}
+	var layers []sourced
+	var errs []error
+	if file != "" {
+		opts, err := l.file(file)
+		layers = append(layers, opts...)
+		errs = append(errs, err)
+	}
+	opts, err := l.env()
+	layers = append(layers, opts...)
+	errs = append(errs, err)
+	layers = append(layers, flagOpts...)
+	for _, o := range overrides {
+		layers = append(layers, sourced{Source{Layer: Override}, o})
+	}
+
+	sources := Sources{}
+	for _, k := range keys {
+		sources[k.name] = Source{Layer: Default}
+	}
+	var current Source
+	apply := make([]Option, len(layers))
+	for i, s := range layers {
+		apply[i] = func(c *Config) error {
+			current = s.source
+			if err := s.opt(c); err != nil {
+				return fmt.Errorf("%v: %w", s.source, err)
+			}
+			return nil
+		}
+	}
+	c, err := construct.NewWith(funcopts.ApplyConfig{
+		CollectErrors: true,
+		OnApply:       func(a funcopts.Applied) { sources[a.Name] = current },
+	}, apply...)
+	if err := errors.Join(append(errs, err)...); err != nil {
+		return nil, nil, err
+	}
+	return c, sources, nil
+}
+
+// sourced is an option and the layer it came from.
+type sourced struct {
+	source Source
+	opt    Option
+}
+
+// file parses the JSON object in path; its values may be strings,
+// numbers or booleans, and an unknown key is an error, a typo that
+// would otherwise be silently ignored.
+func (l Loader) file(path string) ([]sourced, error) {
+	readFile := l.ReadFile
+	if readFile == nil {
+		readFile = os.ReadFile
+	}
+	data, err := readFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("config file: %w", err)
+	}
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("config file %s: %w", path, err)
+	}
+	var out []sourced
+	var errs []error
+	for _, name := range slices.Sorted(maps.Keys(raw)) {
+		src := Source{File, path + ":" + name}
+		k, ok := lookupKey(name)
+		if !ok {
+			errs = append(errs, fmt.Errorf("%v: unknown setting", src))
+			continue
+		}
+		text, err := scalar(raw[name])
+		if err == nil {
+			var opt Option
+			if opt, err = k.parse(text); err == nil {
+				out = append(out, sourced{src, opt})
+				continue
+			}
+		}
+		errs = append(errs, fmt.Errorf("%v: %w", src, err))
+	}
+	return out, errors.Join(errs...)
+}
+
+// scalar returns the text of a JSON string, number or boolean.
+func scalar(v json.RawMessage) (string, error) {
+	var s string
+	if err := json.Unmarshal(v, &s); err == nil {
+		return s, nil
+	}
+	var x any
+	if err := json.Unmarshal(v, &x); err != nil {
+		return "", err
+	}
+	switch x.(type) {
+	case float64, bool:
+		return string(v), nil
+	}
+	return "", fmt.Errorf("%s is not a string, number or boolean", v)
+}
+
+func (l Loader) env() ([]sourced, error) {
+	lookup := l.LookupEnv
+	if lookup == nil {
+		lookup = os.LookupEnv
+	}
+	prefix := l.EnvPrefix
+	if prefix == "" {
+		prefix = "APP"
+	}
+	var out []sourced
+	var errs []error
+	for _, k := range keys {
+		name := k.env(prefix)
+		s, ok := lookup(name)
+		if !ok {
+			continue
+		}
+		src := Source{Env, name}
+		opt, err := k.parse(s)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%v: %w", src, err))
+			continue
+		}
+		out = append(out, sourced{src, opt})
+	}
+	return out, errors.Join(errs...)
+}
+
+// flagValue adds an option to opts for each time its flag is given, so
+// a flag left out sets nothing.
+type flagValue struct {
+	k    key
+	opts *[]sourced
+}
+
+func (v flagValue) String() string   { return "" }
+func (v flagValue) IsBoolFlag() bool { return v.k.bool }
+
+func (v flagValue) Set(s string) error {
+	opt, err := v.k.parse(s)
+	if err != nil {
+		return err
+	}
+	*v.opts = append(*v.opts, sourced{Source{Flag, "-" + v.k.flag()}, opt})
+	return nil
+}
+
+// flags parses args; a flag whose value does not parse is reported by
+// the flag package, a value out of range when it is applied.
+func (l Loader) flags(args []string) ([]sourced, string, error) {
+	fs := flag.NewFlagSet("config", flag.ContinueOnError)
+	out := l.Output
+	if out == nil {
+		out = io.Discard
+	}
+	fs.SetOutput(out)
+	var opts []sourced
+	file := fs.String("config", l.File, "JSON config `file`")
+	for _, k := range keys {
+		fs.Var(flagValue{k, &opts}, k.flag(), k.usage)
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, "", err
+	}
+	if fs.NArg() > 0 {
+		return nil, "", fmt.Errorf("unexpected arguments %q", fs.Args())
+	}
+	return opts, *file, nil
+}
@@ -0,0 +1,143 @@
+package configpatterns_test
+
+import (
+	"io/fs"
+	"strings"
+	"testing"
+	"time"
+
+	cp "patterns/configpatterns"
+)
+
+const fileJSON = `{"addr": "0.0.0.0", "port": 9000, "log_level": "warn", "read_timeout": "2s"}`
+
+// files are the config files the cases can name.
+var files = map[string]string{
+	"app.json":     fileJSON,
+	"other.json":   `{"port": 9100}`,
+	"typo.json":    `{"prot": 9000, "max_conns": 0, "debug": "yes"}`,
+	"nested.json":  `{"addr": {"host": "x"}}`,
+	"invalid.json": `{"port": `,
+}
+
+func readFile(name string) ([]byte, error) {
+	s, ok := files[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return []byte(s), nil
+}
+
+func env(vars map[string]string) func(string) (string, bool) {
+	return func(k string) (string, bool) {
+		v, ok := vars[k]
+		return v, ok
+	}
+}
+
+var cases = []struct {
+	name      string
+	file      string
+	env       map[string]string
+	args      []string
+	overrides []cp.Option
+	// want edits the defaults into the expected Config
+	want func(c *cp.Config)
+	// sources are expected sources by key, the rest must be default
+	sources map[string]string
+	// errs are substrings the error must contain; none expects success
+	errs []string
+}{
+	{name: "defaults only", want: func(c *cp.Config) {}},
+	{name: "file over defaults", file: "app.json",
+		want:    func(c *cp.Config) { c.Addr, c.Port, c.LogLevel, c.ReadTimeout = "0.0.0.0", 9000, "warn", 2*time.Second },
+		sources: map[string]string{"addr": "file app.json:addr", "port": "file app.json:port", "log_level": "file app.json:log_level", "read_timeout": "file app.json:read_timeout"}},
+	{name: "env over file", file: "app.json", env: map[string]string{"APP_PORT": "9001", "APP_DEBUG": "true"},
+		want: func(c *cp.Config) {
+			c.Addr, c.Port, c.LogLevel, c.ReadTimeout, c.Debug = "0.0.0.0", 9001, "warn", 2*time.Second, true
+		},
+		sources: map[string]string{"addr": "file app.json:addr", "port": "env APP_PORT", "debug": "env APP_DEBUG", "log_level": "file app.json:log_level", "read_timeout": "file app.json:read_timeout"}},
+	{name: "flags over env", file: "app.json", env: map[string]string{"APP_PORT": "9001", "APP_LOG_LEVEL": "error"}, args: []string{"-port", "9002"},
+		want: func(c *cp.Config) {
+			c.Addr, c.Port, c.LogLevel, c.ReadTimeout = "0.0.0.0", 9002, "error", 2*time.Second
+		},
+		sources: map[string]string{"addr": "file app.json:addr", "port": "flag -port", "log_level": "env APP_LOG_LEVEL", "read_timeout": "file app.json:read_timeout"}},
+	{name: "overrides over flags", env: map[string]string{"APP_PORT": "9001"}, args: []string{"-port=9002", "-debug"}, overrides: []cp.Option{cp.WithPort(9003)},
+		want:    func(c *cp.Config) { c.Port, c.Debug = 9003, true },
+		sources: map[string]string{"port": "override", "debug": "flag -debug"}},
+	{name: "a flag not given keeps the env value", env: map[string]string{"APP_MAX_CONNS": "7"}, args: []string{"-addr", "example.org"},
+		want:    func(c *cp.Config) { c.MaxConns, c.Addr = 7, "example.org" },
+		sources: map[string]string{"max_conns": "env APP_MAX_CONNS", "addr": "flag -addr"}},
+	{name: "a flag given twice: the last wins", args: []string{"-port", "1", "-port", "2"},
+		want: func(c *cp.Config) { c.Port = 2 }, sources: map[string]string{"port": "flag -port"}},
+	{name: "-debug=false turns off the env's true", env: map[string]string{"APP_DEBUG": "1"}, args: []string{"-debug=false"},
+		want: func(c *cp.Config) {}, sources: map[string]string{"debug": "flag -debug"}},
+	{name: "-config replaces the file", file: "app.json", args: []string{"-config", "other.json"},
+		want: func(c *cp.Config) { c.Port = 9100 }, sources: map[string]string{"port": "file other.json:port"}},
+	{name: "errors of every layer at once", file: "typo.json", env: map[string]string{"APP_PORT": "http", "APP_LOG_LEVEL": "loud"}, overrides: []cp.Option{cp.WithReadTimeout(-time.Second)},
+		errs: []string{"file typo.json:prot: unknown setting", `file typo.json:debug: strconv.ParseBool: parsing "yes"`, "env APP_PORT: strconv.Atoi", "file typo.json:max_conns: max_conns: must be positive", `env APP_LOG_LEVEL: log_level: "loud" is not one of`, "override: read_timeout: -1s must be positive"}},
+	{name: "out of range from a flag names the flag", args: []string{"-port", "70000"},
+		errs: []string{"flag -port: port: 70000 out of range"}},
+	{name: "cross-field rule after merging", env: map[string]string{"APP_READ_TIMEOUT": "30s"},
+		errs: []string{"shutdown_timeout 10s is shorter than read_timeout 30s"}},
+	{name: "a flag that does not parse stops Load", args: []string{"-max-conns", "many"},
+		errs: []string{`invalid value "many" for flag -max-conns`}},
+	{name: "unknown flag", args: []string{"-prot", "1"}, errs: []string{"flag provided but not defined: -prot"}},
+	{name: "nested value in the file", file: "nested.json", errs: []string{`file nested.json:addr: {"host": "x"} is not a string, number or boolean`}},
+	{name: "malformed file", file: "invalid.json", errs: []string{"config file invalid.json: unexpected end of JSON input"}},
+	{name: "missing file", file: "gone.json", errs: []string{"config file: open gone.json: file does not exist"}},
+}
+
+func TestLoad(t *testing.T) {
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			l := cp.Loader{File: c.file, LookupEnv: env(c.env), ReadFile: readFile}
+			got, sources, err := l.Load(c.args, c.overrides...)
+			if len(c.errs) > 0 {
+				if err == nil {
+					t.Fatalf("loaded %+v, want an error", *got)
+				}
+				for _, e := range c.errs {
+					if !strings.Contains(err.Error(), e) {
+						t.Errorf("error %q\ndoes not contain %q", err, e)
+					}
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load: %v", err)
+			}
+			var want cp.Config
+			want.SetDefaults()
+			c.want(&want)
+			if *got != want {
+				t.Fatalf("got  %+v\nwant %+v", *got, want)
+			}
+			for k, s := range sources {
+				w, ok := c.sources[k]
+				if !ok {
+					w = "default"
+				}
+				if s.String() != w {
+					t.Errorf("%s came from %v, want %s", k, s, w)
+				}
+			}
+		})
+	}
+}
+
+// TestNaiveLoad shows the naive loader, given a file and a flag for the
+// same setting, ending up with the file's.
+func TestNaiveLoad(t *testing.T) {
+	naive, err := cp.NaiveLoad([]string{"-config", "other.json", "-port", "9002"}, env(map[string]string{"APP_PORT": "9001"}), readFile)
+	if err != nil {
+		t.Fatalf("NaiveLoad: %v", err)
+	}
+	if naive.Port != 9100 {
+		t.Errorf("-port 9002, APP_PORT=9001 and the file's 9100 give %d, want the file's", naive.Port)
+	}
+	// and, unmarshalling into Config, reads durations as nanoseconds
+	if _, err := cp.NaiveLoad([]string{"-config", "app.json"}, env(nil), readFile); err == nil {
+		t.Errorf(`NaiveLoad accepted "2s"`)
+	}
+}
@@ -0,0 +1,57 @@
+package configpatterns
+
+import (
+	"encoding/json"
+	"flag"
+	"io"
+	"os"
+	"strconv"
+)
+
+// env-defaulted flags
+// Level: Poor
+// pros: a few lines with the flag package, and -h shows the effective
+// defaults.
+// cons: the environment is read into the flag defaults before the file is
+// known, so the file, unmarshalled last, overrides the flags and the
+// environment both; a variable that does not parse silently becomes
+// zero; the file must spell durations in nanoseconds; only the settings
+// someone remembered to wire read a variable at all, and nothing is
+// validated.
+//
+// NaiveLoad loads a Config from flags defaulted from the environment and
+// the file named by -config.
+func NaiveLoad(args []string, lookupEnv func(string) (string, bool), readFile func(string) ([]byte, error)) (*Config, error) {
+	c := &Config{}
+	c.SetDefaults()
+	fs := flag.NewFlagSet("config", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	file := fs.String("config", "", "JSON config file")
+	fs.StringVar(&c.Addr, "addr", envOr(lookupEnv, "APP_ADDR", c.Addr), "host to listen on")
+	port, _ := strconv.Atoi(envOr(lookupEnv, "APP_PORT", strconv.Itoa(c.Port)))
+	fs.IntVar(&c.Port, "port", port, "port to listen on")
+	fs.StringVar(&c.LogLevel, "log-level", c.LogLevel, "log level")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if *file != "" {
+		if readFile == nil {
+			readFile = os.ReadFile
+		}
+		data, err := readFile(*file)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, c); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+func envOr(lookup func(string) (string, bool), name, def string) string {
+	if v, ok := lookup(name); ok {
+		return v
+	}
+	return def
+}
@@ -0,0 +1,89 @@
+// Package construct is the constructor sequence shared by the examples in
+// this repository: defaults, then options, then invariants.
+//
+//	type options struct{ port int }
+//
+//	func (o *options) SetDefaults()    { o.port = 8080 }
+//	func (o *options) Validate() error { ... }
+//
+//	func NewServer(opts ...Option) (*Server, error) {
+//		o, err := construct.New(opts...)
+//		if err != nil {
+//			return nil, err
+//		}
+//		...
+//	}
+//
+// Options validate their own argument as they are applied; Validate is
+// for rules that involve more than one option (write timeout not shorter
+// than read timeout), which no single option can check.
+package construct
+
+import (
+	"errors"
+
+	"patterns/funcopts"
+)
+
+// Defaulter is implemented by option structs whose zero value is not the
+// default configuration.
+type Defaulter interface {
+	SetDefaults()
+}
+
+// Validator is implemented by option structs with cross-field invariants.
+type Validator interface {
+	Validate() error
+}
+
+// New builds a T: SetDefaults if *T has it, every option in order,
+// then Validate if *T has it.
+//
+// O is any func(*T) error type, so both funcopts.Option[T] and a package's
+// own "type Option func(*options) error" work.
+func New[T any, O ~func(*T) error](opts ...O) (*T, error) {
+	return NewWith(funcopts.ApplyConfig{}, opts...)
+}
+
+// NewWith is New with a funcopts.ApplyConfig for duplicate policies,
+// warnings and introspection.
+//
+// With cfg.CollectErrors, Validate runs even when options failed and its
+// error is joined after theirs. The fields of a failed option keep their
+// defaults, so Validate only sees values that could actually be used.
+func NewWith[T any, O ~func(*T) error](cfg funcopts.ApplyConfig, opts ...O) (*T, error) {
+	target := new(T)
+	if d, ok := any(target).(Defaulter); ok {
+		d.SetDefaults()
+	}
+	err := funcopts.ApplyWith(cfg, target, convert[T](opts)...)
+	if err != nil && !cfg.CollectErrors {
+		return nil, err
+	}
+	if v, ok := any(target).(Validator); ok {
+		if verr := v.Validate(); verr != nil {
+			if err == nil {
+				err = verr
+			} else {
+				err = errors.Join(err, verr)
+			}
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return target, nil
+}
+
+// convert reuses the caller's slice when it already has the funcopts
+// type, so a funcopts.Guard sees the same slice identity.
+func convert[T any, O ~func(*T) error](opts []O) []funcopts.Option[T] {
+	if same, ok := any(opts).([]funcopts.Option[T]); ok {
+		return same
+	}
+	out := make([]funcopts.Option[T], len(opts))
+	for i, opt := range opts {
+		out[i] = funcopts.Option[T](opt)
+	}
+	return out
+}
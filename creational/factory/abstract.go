@@ -0,0 +1,173 @@
+package factory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+
+	"patterns/persistence/repository"
+)
+
+// Blobs stores opaque content by name.
+type Blobs interface {
+	Put(ctx context.Context, name string, data []byte) error
+	// Get fails with repository.ErrNotFound for an unknown name.
+	Get(ctx context.Context, name string) ([]byte, error)
+}
+
+// abstract factory
+// Level: Good
+// pros: the products of one backend are made together, so a tenant's
+// items and blobs cannot end up one in memory and the other on disk; a
+// new backend registers itself and no caller changes.
+// cons: every product is in the interface, so adding a product type
+// means adding a method to every backend.
+type Backend interface {
+	ItemsFactory
+	NewBlobs(tenant string) (Blobs, error)
+}
+
+// Memory returns a backend keeping everything in memory, for tests.
+func Memory() Backend { return memoryBackend{} }
+
+type memoryBackend struct{}
+
+func (memoryBackend) NewItems(string) (Items, error) {
+	return repository.NewMemory[string, Item](), nil
+}
+
+func (memoryBackend) NewBlobs(string) (Blobs, error) {
+	return &memoryBlobs{blobs: map[string][]byte{}}, nil
+}
+
+type memoryBlobs struct {
+	mu    sync.RWMutex
+	blobs map[string][]byte
+}
+
+func (m *memoryBlobs) Put(_ context.Context, name string, data []byte) error {
+	if err := checkName(name); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.blobs[name] = slices.Clone(data)
+	return nil
+}
+
+func (m *memoryBlobs) Get(_ context.Context, name string) ([]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	data, ok := m.blobs[name]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	return slices.Clone(data), nil
+}
+
+// Files returns a backend storing each tenant under dir/<tenant>: items
+// in items.json and blobs as files in blobs/.
+func Files(dir string) Backend { return fileBackend{dir: dir} }
+
+type fileBackend struct{ dir string }
+
+func (b fileBackend) NewItems(tenant string) (Items, error) {
+	dir, err := b.tenantDir(tenant)
+	if err != nil {
+		return nil, err
+	}
+	return New(KindFile, dir)
+}
+
+func (b fileBackend) NewBlobs(tenant string) (Blobs, error) {
+	dir, err := b.tenantDir(tenant)
+	if err != nil {
+		return nil, err
+	}
+	dir = filepath.Join(dir, "blobs")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return fileBlobs{dir: dir}, nil
+}
+
+func (b fileBackend) tenantDir(tenant string) (string, error) {
+	if err := checkName(tenant); err != nil {
+		return "", err
+	}
+	dir := filepath.Join(b.dir, tenant)
+	return dir, os.MkdirAll(dir, 0o755)
+}
+
+type fileBlobs struct{ dir string }
+
+func (f fileBlobs) Put(_ context.Context, name string, data []byte) error {
+	if err := checkName(name); err != nil {
+		return err
+	}
+	path := filepath.Join(f.dir, name)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func (f fileBlobs) Get(_ context.Context, name string) ([]byte, error) {
+	if err := checkName(name); err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(filepath.Join(f.dir, name))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, repository.ErrNotFound
+	}
+	return data, err
+}
+
+// Opener makes a backend from a backend-specific source string.
+type Opener func(source string) (Backend, error)
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]Opener{
+		"memory": func(string) (Backend, error) { return Memory(), nil },
+		"file": func(dir string) (Backend, error) {
+			if dir == "" {
+				return nil, errors.New("file backend needs a directory")
+			}
+			return Files(dir), nil
+		},
+	}
+)
+
+// Register makes a backend available to Open under name, the way
+// database/sql drivers register themselves from an init function. It
+// panics if name is taken or open is nil.
+func Register(name string, open Opener) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if open == nil {
+		panic("factory: Register opener is nil")
+	}
+	if _, dup := registry[name]; dup {
+		panic("factory: Register called twice for " + name)
+	}
+	registry[name] = open
+}
+
+// Open returns the backend registered under name, made from source:
+// Open("file", "/var/lib/inventory") or Open("memory", "").
+func Open(name, source string) (Backend, error) {
+	registryMu.RLock()
+	open, ok := registry[name]
+	registryMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown backend %q", name)
+	}
+	return open(source)
+}
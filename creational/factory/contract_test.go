@@ -0,0 +1,26 @@
+package factory_test
+
+import (
+	"testing"
+
+	"patterns/creational/factory"
+	"patterns/testing/contracts"
+)
+
+func blobsContract(t *testing.T, backend func(t testing.TB) factory.Backend) {
+	contracts.RunBlobStoreContract(t, func(t testing.TB) contracts.BlobStore {
+		s, err := backend(t).NewBlobs("tenant")
+		if err != nil {
+			t.Fatalf("NewBlobs: %v", err)
+		}
+		return s
+	})
+}
+
+func TestMemoryContract(t *testing.T) {
+	blobsContract(t, func(testing.TB) factory.Backend { return factory.Memory() })
+}
+
+func TestFilesContract(t *testing.T) {
+	blobsContract(t, func(t testing.TB) factory.Backend { return factory.Files(t.TempDir()) })
+}
@@ -0,0 +1,78 @@
+// Package factory builds the same storage backends, in memory or on
+// disk, three ways, from a switch to a family of products selected by
+// name:
+//
+//   - New: a simple factory, one function switching on a Kind (Level:
+//     Average, closed to new backends)
+//   - ItemsFactory: a factory method; Inventory asks it for a repository
+//     per tenant, on demand, without knowing which kind it gets (Level:
+//     Good)
+//   - Backend: an abstract factory making a family of products that
+//     belong together, items and blobs stored the same way, with Open
+//     selecting one registered by name (Level: Good)
+//
+// Code that takes the factory instead of a concrete backend can be handed
+// the memory one in tests and the file one in production:
+//
+//	for _, b := range []factory.Backend{factory.Memory(), factory.Files(dir)} {
+//		inv, err := factory.NewInventory(b)
+//		...
+//	}
+package factory
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"patterns/persistence/repository"
+)
+
+// Item is the entity every backend stores.
+type Item struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Stock int    `json:"stock"`
+}
+
+// Items is the product the factories make.
+type Items = repository.Repository[string, Item]
+
+//go:generate go run patterns/cmd/enumgen -type=Kind -trimprefix=Kind
+
+// Kind selects a backend for the simple factory.
+type Kind int
+
+const (
+	KindMemory Kind = iota
+	KindFile
+)
+
+// simple factory
+// Level: Average
+// pros: one place decides how a backend is built; callers pass a value
+// they can read from a flag or a config file.
+// cons: the switch is closed: a new backend means editing New and the
+// Kind enum, and every backend's dependencies are linked into every
+// program.
+func New(kind Kind, dir string) (Items, error) {
+	switch kind {
+	case KindMemory:
+		return repository.NewMemory[string, Item](), nil
+	case KindFile:
+		return repository.OpenFile[string, Item](filepath.Join(dir, "items.json"))
+	}
+	return nil, fmt.Errorf("unknown kind %v", kind)
+}
+
+// checkName rejects tenant and blob names that are not a single path
+// element, so the file backend cannot be steered outside its directory.
+func checkName(name string) error {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) || !filepath.IsLocal(name) {
+		return fmt.Errorf("invalid name %q", name)
+	}
+	return nil
+}
+
+var errNilFactory = errors.New("factory cannot be nil")
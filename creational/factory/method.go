@@ -0,0 +1,85 @@
+package factory
+
+import (
+	"context"
+	"sync"
+)
+
+// factory method
+// Level: Good
+// pros: Inventory decides when a repository is made, one per tenant the
+// first time it is used, and the factory decides what kind; adding a
+// backend adds a type, not a case.
+// cons: one more interface to name, for code that often needs only one
+// product once, where passing the product itself is simpler.
+type ItemsFactory interface {
+	NewItems(tenant string) (Items, error)
+}
+
+// FactoryFunc adapts a function to ItemsFactory.
+type FactoryFunc func(tenant string) (Items, error)
+
+func (f FactoryFunc) NewItems(tenant string) (Items, error) { return f(tenant) }
+
+// Inventory keeps each tenant's items apart, in a repository made on
+// first use.
+type Inventory struct {
+	factory ItemsFactory
+
+	mu      sync.Mutex
+	tenants map[string]Items
+}
+
+func NewInventory(f ItemsFactory) (*Inventory, error) {
+	if f == nil {
+		return nil, errNilFactory
+	}
+	return &Inventory{factory: f, tenants: map[string]Items{}}, nil
+}
+
+func (inv *Inventory) items(tenant string) (Items, error) {
+	if err := checkName(tenant); err != nil {
+		return nil, err
+	}
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+	if items, ok := inv.tenants[tenant]; ok {
+		return items, nil
+	}
+	items, err := inv.factory.NewItems(tenant)
+	if err != nil {
+		return nil, err
+	}
+	inv.tenants[tenant] = items
+	return items, nil
+}
+
+func (inv *Inventory) Add(ctx context.Context, tenant string, item Item) error {
+	items, err := inv.items(tenant)
+	if err != nil {
+		return err
+	}
+	return items.Create(ctx, item.ID, item)
+}
+
+func (inv *Inventory) Get(ctx context.Context, tenant, id string) (Item, error) {
+	items, err := inv.items(tenant)
+	if err != nil {
+		return Item{}, err
+	}
+	return items.Get(ctx, id)
+}
+
+// Restock adds n to an item's stock.
+func (inv *Inventory) Restock(ctx context.Context, tenant, id string, n int) error {
+	items, err := inv.items(tenant)
+	if err != nil {
+		return err
+	}
+	item, err := items.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	item.Stock += n
+	return items.Update(ctx, id, item)
+}
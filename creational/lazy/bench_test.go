@@ -0,0 +1,95 @@
+package lazy
+
+import (
+	"sync"
+	"testing"
+)
+
+// readers is how many goroutines search a new document at once in
+// BenchmarkFirst.
+const readers = 8
+
+var (
+	benchText = Text(2000)
+	sinkInts  []int
+	sinkIndex Index
+)
+
+// firstAccess builds a document with newDoc and has readers goroutines,
+// released together, search it once each.
+func firstAccess(newDoc func(string) Document, text string, readers int) {
+	d := newDoc(text)
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for range readers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			d.Search("fox")
+		}()
+	}
+	close(start)
+	wg.Wait()
+}
+
+// BenchmarkFirst measures, for each variant, a new document searched by
+// readers goroutines at once.
+func BenchmarkFirst(b *testing.B) {
+	for _, v := range Variants {
+		b.Run(v.Name, func(b *testing.B) {
+			for range b.N {
+				firstAccess(v.New, benchText, readers)
+			}
+		})
+	}
+}
+
+// BenchmarkUnused measures, for each variant, a new document never
+// searched.
+func BenchmarkUnused(b *testing.B) {
+	for _, v := range Variants {
+		b.Run(v.Name, func(b *testing.B) {
+			var d Document
+			for range b.N {
+				d = v.New(benchText)
+			}
+			_ = d
+		})
+	}
+}
+
+// BenchmarkSearch measures, for each variant, Search once the index is
+// built.
+func BenchmarkSearch(b *testing.B) {
+	for _, v := range Variants {
+		b.Run(v.Name, func(b *testing.B) {
+			d := v.New(benchText)
+			d.Search("fox")
+			b.ResetTimer()
+			for range b.N {
+				sinkInts = d.Search("fox")
+			}
+		})
+	}
+}
+
+// BenchmarkGet measures the fast path of Lazy and Retry.
+func BenchmarkGet(b *testing.B) {
+	b.Run("lazy", func(b *testing.B) {
+		l := New(func() Index { return Index{} })
+		l.Get()
+		b.ResetTimer()
+		for range b.N {
+			sinkIndex = l.Get()
+		}
+	})
+	b.Run("retry", func(b *testing.B) {
+		r := NewRetry(func() (Index, error) { return Index{}, nil })
+		r.Get()
+		b.ResetTimer()
+		for range b.N {
+			sinkIndex, _ = r.Get()
+		}
+	})
+}
@@ -0,0 +1,92 @@
+package lazy
+
+import (
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Index maps each word of a text, lower-cased, to its positions in it.
+type Index map[string][]int
+
+// BuildIndex indexes text; it is the expensive field of every Document.
+func BuildIndex(text string) Index {
+	idx := Index{}
+	words := strings.FieldsFunc(text, func(r rune) bool { return !unicode.IsLetter(r) && r != '\'' })
+	for i, w := range words {
+		w = strings.ToLower(w)
+		idx[w] = append(idx[w], i)
+	}
+	return idx
+}
+
+// Document is a text searched by word, with each variant building its
+// index differently; Search returns the positions of word, in order.
+type Document interface {
+	Search(word string) []int
+}
+
+// eager field
+// Level: Good
+// pros: no synchronization and nothing to get wrong: the field is set
+// before anyone can read it.
+// cons: every document pays for its index, searched or not, and the
+// constructor is as slow as the index.
+type EagerDocument struct {
+	text  string
+	index Index
+}
+
+func NewEagerDocument(text string) *EagerDocument {
+	return &EagerDocument{text: text, index: BuildIndex(text)}
+}
+
+func (d *EagerDocument) Search(word string) []int { return d.index[strings.ToLower(word)] }
+
+// mutex-guarded lazy field
+// Level: Average
+// pros: obviously correct, and easy to extend into a retry or a reset.
+// cons: every Search takes the lock, long after the index stopped
+// changing, so concurrent readers of one document queue up.
+type MutexDocument struct {
+	text  string
+	mu    sync.Mutex
+	index Index
+}
+
+func NewMutexDocument(text string) *MutexDocument { return &MutexDocument{text: text} }
+
+func (d *MutexDocument) Search(word string) []int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.index == nil {
+		d.index = BuildIndex(d.text)
+	}
+	return d.index[strings.ToLower(word)]
+}
+
+// sync.OnceValue field
+// Level: Good
+// pros: the standard library's answer; one atomic load once built.
+// cons: the field is a func, so its type says nothing about laziness and
+// the constructor must wire the closure; forgetting leaves a nil func.
+type OnceDocument struct {
+	index func() Index
+}
+
+func NewOnceDocument(text string) *OnceDocument {
+	return &OnceDocument{index: sync.OnceValue(func() Index { return BuildIndex(text) })}
+}
+
+func (d *OnceDocument) Search(word string) []int { return d.index()[strings.ToLower(word)] }
+
+// LazyDocument holds its index in a Lazy; see Lazy.
+type LazyDocument struct {
+	index *Lazy[Index]
+}
+
+func NewLazyDocument(text string) *LazyDocument {
+	return &LazyDocument{index: New(func() Index { return BuildIndex(text) })}
+}
+
+func (d *LazyDocument) Search(word string) []int { return d.index.Get()[strings.ToLower(word)] }
@@ -0,0 +1,123 @@
+package lazy_test
+
+import (
+	"errors"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"patterns/creational/lazy"
+)
+
+// goroutines reach for each new value at once, its first use; trials is
+// how many new values each test makes.
+const (
+	goroutines = 64
+	trials     = 200
+)
+
+// together runs f from n goroutines released at once and waits for them.
+func together(n int, f func(i int)) {
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			f(i)
+		}()
+	}
+	close(start)
+	wg.Wait()
+}
+
+// recovered runs f and returns what it panicked with, nil if nothing.
+func recovered(f func()) (p any) {
+	defer func() { p = recover() }()
+	f()
+	return nil
+}
+
+// An index built twice would give readers results with different backing
+// arrays.
+func TestDocumentsBuildTheIndexOnce(t *testing.T) {
+	text := lazy.Text(500)
+	want := lazy.BuildIndex(text)["fox"]
+	for _, v := range lazy.Variants {
+		t.Run(v.Name, func(t *testing.T) {
+			for range trials {
+				d := v.New(text)
+				got := make([][]int, goroutines)
+				together(goroutines, func(i int) { got[i] = d.Search("fox") })
+				for i, g := range got {
+					if !slices.Equal(g, want) {
+						t.Fatalf("reader %d got %v, want %v", i, g, want)
+					}
+					if &g[0] != &got[0][0] {
+						t.Fatalf("reader %d got an index of its own", i)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestLazyBuildsOnce(t *testing.T) {
+	for range trials {
+		var n atomic.Int32
+		l := lazy.New(func() int { return int(n.Add(1)) })
+		together(goroutines, func(int) { l.Get() })
+		if n.Load() != 1 {
+			t.Fatalf("%d inits for one value", n.Load())
+		}
+	}
+}
+
+// A panic in init is raised by every Get, not only the first.
+func TestLazyRepanics(t *testing.T) {
+	l := lazy.New(func() int { panic("no config") })
+	first, second := recovered(func() { l.Get() }), recovered(func() { l.Get() })
+	if first != "no config" || second != "no config" {
+		t.Fatalf("first Get: %v, second Get: %v", first, second)
+	}
+}
+
+// Where a bare sync.Once counts a panicking init as done.
+func TestSyncOnceForgetsThePanic(t *testing.T) {
+	var once sync.Once
+	var v int
+	first := recovered(func() { once.Do(func() { panic("no config") }) })
+	once.Do(func() { v = 1 })
+	if first != "no config" || v != 0 {
+		t.Fatalf("first Do: %v, then the value is %d", first, v)
+	}
+}
+
+// The first two attempts fail, whoever makes them; after the third every
+// caller gets its value and init is not called again.
+func TestRetryRetriesUntilItSucceeds(t *testing.T) {
+	errDown := errors.New("down")
+	var attempts atomic.Int32
+	r := lazy.NewRetry(func() (string, error) {
+		if attempts.Add(1) <= 2 {
+			return "", errDown
+		}
+		return "connected", nil
+	})
+	var fails, oks atomic.Int32
+	together(goroutines, func(int) {
+		switch s, err := r.Get(); {
+		case errors.Is(err, errDown):
+			fails.Add(1)
+		case err == nil && s == "connected":
+			oks.Add(1)
+		}
+	})
+	s, err := r.Get()
+	if attempts.Load() != 3 || fails.Load() != 2 || oks.Load() != goroutines-2 || err != nil || s != "connected" {
+		t.Fatalf("%d attempts, %d callers got the error, %d the value; then %q, %v",
+			attempts.Load(), fails.Load(), oks.Load(), s, err)
+	}
+}
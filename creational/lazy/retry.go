@@ -0,0 +1,49 @@
+package lazy
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// retrying lazy value
+// Level: Good
+// pros: a failed init, a dial or a read of a file not there yet, is tried
+// again by the next Get instead of cached for the life of the value; once
+// it succeeds the fast path is one atomic load, as with sync.Once.
+// cons: callers arriving during a failing init wait for it and then run
+// their own attempt, one at a time; nothing backs off between them.
+//
+// Retry is a value of type T built by its init function on the first Get
+// that succeeds. It must not be copied after first use.
+type Retry[T any] struct {
+	done atomic.Bool
+	mu   sync.Mutex
+	init func() (T, error)
+	v    T
+}
+
+// NewRetry returns a Retry whose Gets call init until it succeeds.
+func NewRetry[T any](init func() (T, error)) *Retry[T] {
+	return &Retry[T]{init: init}
+}
+
+// Get returns the value, or the error of this call's attempt to build it.
+func (r *Retry[T]) Get() (T, error) {
+	if r.done.Load() {
+		return r.v, nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.done.Load() {
+		return r.v, nil
+	}
+	v, err := r.init()
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	// the store publishes v to the readers that load done without the lock
+	r.v, r.init = v, nil
+	r.done.Store(true)
+	return r.v, nil
+}
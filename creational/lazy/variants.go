@@ -0,0 +1,27 @@
+package lazy
+
+import "strings"
+
+// Variants are the document variants by name.
+var Variants = []struct {
+	Name string
+	New  func(text string) Document
+}{
+	{"eager", func(text string) Document { return NewEagerDocument(text) }},
+	{"mutex", func(text string) Document { return NewMutexDocument(text) }},
+	{"oncevalue", func(text string) Document { return NewOnceDocument(text) }},
+	{"lazy", func(text string) Document { return NewLazyDocument(text) }},
+}
+
+// Text returns a deterministic text of n words.
+func Text(n int) string {
+	words := strings.Fields("the quick brown fox jumps over a lazy dog while seven wizards quietly pack boxes of liquor jugs")
+	var b strings.Builder
+	for i := range n {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(words[(i*7+i/len(words))%len(words)])
+	}
+	return b.String()
+}
@@ -0,0 +1,153 @@
+package pool
+
+import (
+	"bytes"
+	"context"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+const (
+	burst   = 64
+	bufSize = 4096
+)
+
+func newBuffer() *bytes.Buffer {
+	b := new(bytes.Buffer)
+	b.Grow(bufSize)
+	return b
+}
+
+// conn stands in for a database connection: opening one is counted, and
+// so is closing it.
+type conn struct{ id int64 }
+
+type counters struct{ opened, closed atomic.Int64 }
+
+func (c *counters) open(context.Context) (*conn, error) {
+	return &conn{id: c.opened.Add(1)}, nil
+}
+
+func (c *counters) close(*conn) error {
+	c.closed.Add(1)
+	return nil
+}
+
+// idle stands for a quiet period long enough for two GC cycles.
+func idle() {
+	runtime.GC()
+	runtime.GC()
+}
+
+// heapAfterIdle is the live heap once the pool has been idle.
+func heapAfterIdle() uint64 {
+	idle()
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	return m.HeapAlloc
+}
+
+// rounds is the number of bursts per benchmark op: two forced GCs cost
+// milliseconds, so ops must be long enough for go test -bench to settle
+// on a small b.N.
+const rounds = 16
+
+// bursts runs rounds bursts per op, each calling get burst times and then
+// put for all of them, followed by an idle period. It reports values made
+// per burst and the heap the pool holds while idle; ns/op is mostly the
+// collections.
+func bursts[T any](b *testing.B, get func() T, put func(T), made func() int64) {
+	before := heapAfterIdle()
+	held := make([]T, burst)
+	b.ResetTimer()
+	for range b.N * rounds {
+		for j := range held {
+			held[j] = get()
+		}
+		for j := range held {
+			put(held[j])
+		}
+		clear(held)
+		idle()
+	}
+	b.StopTimer()
+	retained := heapAfterIdle()
+	b.ReportMetric(float64(made())/float64(b.N*rounds), "new/burst")
+	b.ReportMetric(float64(int64(retained)-int64(before)), "idle-heap-B")
+}
+
+// BenchmarkBurst measures bursts with idle periods for buffers in Transient
+// and Bounded and for connections in sync.Pool.
+func BenchmarkBurst(b *testing.B) {
+	b.Run("transient", func(b *testing.B) {
+		var news atomic.Int64
+		p := NewTransient(func() *bytes.Buffer { news.Add(1); return newBuffer() }, (*bytes.Buffer).Reset)
+		bursts(b, p.Get, p.Put, news.Load)
+	})
+	b.Run("bounded", func(b *testing.B) {
+		var news atomic.Int64
+		p, err := NewBounded(func(context.Context) (*bytes.Buffer, error) {
+			news.Add(1)
+			return newBuffer(), nil
+		}, func(*bytes.Buffer) error { return nil }, WithMaxOpen(burst))
+		if err != nil {
+			b.Fatal(err)
+		}
+		defer p.Close()
+		ctx := context.Background()
+		get := func() *bytes.Buffer {
+			v, err := p.Get(ctx)
+			if err != nil {
+				b.Fatal(err)
+			}
+			return v
+		}
+		bursts(b, get, func(v *bytes.Buffer) { p.Put(v) }, news.Load)
+	})
+	b.Run("conns-in-syncpool", func(b *testing.B) {
+		var c counters
+		var p sync.Pool
+		get := func() *conn {
+			if v, ok := p.Get().(*conn); ok {
+				return v
+			}
+			v, _ := c.open(context.Background())
+			return v
+		}
+		bursts(b, get, func(v *conn) { p.Put(v) }, c.opened.Load)
+		b.ReportMetric(float64(c.opened.Load()-c.closed.Load()-burst)/float64(b.N*rounds), "leaked/burst")
+	})
+}
+
+// BenchmarkSteady measures Get and Put without bursts.
+func BenchmarkSteady(b *testing.B) {
+	b.Run("transient", func(b *testing.B) {
+		p := NewTransient(newBuffer, (*bytes.Buffer).Reset)
+		for range b.N {
+			p.Put(p.Get())
+		}
+	})
+	// a slice stored in an interface needs its header on the heap
+	b.Run("transient-slice", func(b *testing.B) {
+		p := NewTransient(func() []byte { return make([]byte, 0, bufSize) }, nil)
+		for range b.N {
+			p.Put(p.Get()[:0])
+		}
+	})
+	b.Run("bounded", func(b *testing.B) {
+		p, _ := NewBounded(func(context.Context) (*bytes.Buffer, error) {
+			return newBuffer(), nil
+		}, func(*bytes.Buffer) error { return nil })
+		defer p.Close()
+		ctx := context.Background()
+		for range b.N {
+			v, err := p.Get(ctx)
+			if err != nil {
+				b.Fatal(err)
+			}
+			p.Put(v)
+		}
+	})
+}
@@ -0,0 +1,188 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"patterns/construct"
+	"patterns/funcopts"
+)
+
+var ErrClosed = errors.New("pool: closed")
+
+type options struct {
+	maxOpen int
+	maxIdle int
+}
+
+type Option = funcopts.Option[options]
+
+// WithMaxOpen limits the resources open at once, idle or in use; the
+// default is 10.
+func WithMaxOpen(n int) Option {
+	return func(options *options) error {
+		if n <= 0 {
+			return errors.New("max open must be positive")
+		}
+		options.maxOpen = n
+		return nil
+	}
+}
+
+// WithMaxIdle limits the idle resources kept open; a resource put back
+// beyond it is closed. The default is MaxOpen.
+func WithMaxIdle(n int) Option {
+	return func(options *options) error {
+		if n < 0 {
+			return errors.New("max idle cannot be negative")
+		}
+		options.maxIdle = n
+		return nil
+	}
+}
+
+func (o *options) SetDefaults() {
+	o.maxOpen = 10
+	o.maxIdle = -1
+}
+
+func (o *options) Validate() error {
+	if o.maxIdle > o.maxOpen {
+		return errors.New("max idle cannot exceed max open")
+	}
+	return nil
+}
+
+// Bounded pool
+// Level: Good
+// pros: the limit protects the server behind the pool; waiting callers
+// are served in turn as resources come back, and give up with their
+// context; idle resources stay open, so a burst after a quiet period pays
+// no reconnects.
+// cons: idle resources hold memory and server-side state until Close;
+// the limit is one more number to tune against the server's own.
+type Bounded[T any] struct {
+	open    func(ctx context.Context) (T, error)
+	close   func(T) error
+	options options
+
+	// tokens holds one token per resource that may still be opened; idle
+	// holds the open resources nobody is using.
+	tokens chan struct{}
+	idle   chan T
+
+	mu     sync.Mutex
+	closed bool
+	done   chan struct{}
+}
+
+// NewBounded returns a pool that opens resources with open, on demand, and
+// closes them with close.
+func NewBounded[T any](open func(ctx context.Context) (T, error), close func(T) error, opts ...Option) (*Bounded[T], error) {
+	if open == nil || close == nil {
+		return nil, errors.New("open and close cannot be nil")
+	}
+	options, err := construct.New(opts...)
+	if err != nil {
+		return nil, err
+	}
+	if options.maxIdle < 0 {
+		options.maxIdle = options.maxOpen
+	}
+	p := &Bounded[T]{
+		open:    open,
+		close:   close,
+		options: *options,
+		tokens:  make(chan struct{}, options.maxOpen),
+		idle:    make(chan T, options.maxOpen),
+		done:    make(chan struct{}),
+	}
+	for range options.maxOpen {
+		p.tokens <- struct{}{}
+	}
+	return p, nil
+}
+
+// Get returns an idle resource, opens a new one if the limit allows, or
+// waits for one to be put back until ctx is done.
+func (p *Bounded[T]) Get(ctx context.Context) (T, error) {
+	var zero T
+	// an idle resource first, without waiting
+	select {
+	case v := <-p.idle:
+		return v, nil
+	case <-p.done:
+		return zero, ErrClosed
+	default:
+	}
+	select {
+	case v := <-p.idle:
+		return v, nil
+	case <-p.tokens:
+		v, err := p.open(ctx)
+		if err != nil {
+			p.tokens <- struct{}{}
+			return zero, err
+		}
+		return v, nil
+	case <-p.done:
+		return zero, ErrClosed
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	}
+}
+
+// Put returns v for reuse, or closes it if the pool is closed or has
+// enough idle resources. v must not be used afterwards.
+func (p *Bounded[T]) Put(v T) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.closed && len(p.idle) < p.options.maxIdle {
+		p.idle <- v
+		return nil
+	}
+	return p.discard(v)
+}
+
+// Discard closes v instead of returning it, for a resource found broken;
+// its slot is free for a new one.
+func (p *Bounded[T]) Discard(v T) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.discard(v)
+}
+
+func (p *Bounded[T]) discard(v T) error {
+	err := p.close(v)
+	p.tokens <- struct{}{}
+	return err
+}
+
+// Stats reports the resources open (idle or in use) and idle.
+func (p *Bounded[T]) Stats() (open, idle int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.options.maxOpen - len(p.tokens), len(p.idle)
+}
+
+// Close closes the idle resources and fails waiting and later Gets;
+// resources in use are closed as they are put back.
+func (p *Bounded[T]) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return ErrClosed
+	}
+	p.closed = true
+	close(p.done)
+	var errs []error
+	for {
+		select {
+		case v := <-p.idle:
+			errs = append(errs, p.discard(v))
+		default:
+			return errors.Join(errs...)
+		}
+	}
+}
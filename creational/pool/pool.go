@@ -0,0 +1,68 @@
+// Package pool contrasts the two object pools Go programs need and the
+// mistake of using one for the other's job:
+//
+//   - Transient wraps sync.Pool for cheap, interchangeable scratch values
+//     such as buffers (Level: Good): it grows with demand and the garbage
+//     collector empties it, so idle memory goes back to the heap.
+//   - Bounded is a channel-backed pool for expensive resources with an
+//     identity such as database connections (Level: Good): it never opens
+//     more than its limit, makes callers wait for one instead, and keeps
+//     idle resources open until Close.
+//   - connections in a sync.Pool (Level: Poor): the collector drops them
+//     without closing them, so every GC cycle leaks sockets.
+//
+// For example:
+//
+//	bufs := pool.NewTransient(func() *bytes.Buffer { return new(bytes.Buffer) }, (*bytes.Buffer).Reset)
+//	conns, _ := pool.NewBounded(dial, closeConn, pool.WithMaxOpen(10))
+//
+// findings (see bench_test.go; go test -bench . patterns/creational/pool),
+// in bursts of 64 4KiB buffers with two GC cycles in between, the idle
+// time of a real service:
+//
+//   - Transient allocates all 64 buffers again after every idle period:
+//     sync.Pool keeps values across one GC cycle (the victim cache) but not
+//     two. In exchange it holds nothing while idle.
+//   - Bounded allocates its 64 buffers once and holds them, ~260KiB,
+//     through every idle period.
+//   - connections in a sync.Pool are opened again after every idle period,
+//     64 per burst, and the ones dropped are never closed: ~63 leaked per
+//     burst.
+//   - without GC in between, Get and Put cost ~45ns for Transient with a
+//     pointer type and ~260ns for Bounded (a mutex and two channel
+//     operations). A Transient of []byte allocates the slice header on
+//     every Put and costs ~200ns: pool pointers.
+package pool
+
+import "sync"
+
+// Transient pool
+// Level: Good
+// pros: no limit to configure and no idle memory to budget for: the pool
+// holds what recent load needed and the collector reclaims the rest; Get
+// and Put scale across cores.
+// cons: values vanish at any GC, so nothing that must be closed, and
+// nothing expensive to rebuild, belongs in it.
+type Transient[T any] struct {
+	pool  sync.Pool
+	reset func(T)
+}
+
+// NewTransient returns a pool making values with newFn and clearing them
+// with reset (which may be nil) when they are put back. T should be a
+// pointer: any other value is copied to the heap on every Put.
+func NewTransient[T any](newFn func() T, reset func(T)) *Transient[T] {
+	t := &Transient[T]{reset: reset}
+	t.pool.New = func() any { return newFn() }
+	return t
+}
+
+func (t *Transient[T]) Get() T { return t.pool.Get().(T) }
+
+// Put resets v and returns it to the pool; v must not be used afterwards.
+func (t *Transient[T]) Put(v T) {
+	if t.reset != nil {
+		t.reset(v)
+	}
+	t.pool.Put(v)
+}
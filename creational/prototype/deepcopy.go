@@ -0,0 +1,119 @@
+package prototype
+
+import "reflect"
+
+// reflective deep copy
+// Level: Average
+// pros: works for any value with no code per type; shared pointers stay
+// shared in the copy and cycles are copied as cycles.
+// cons: reflection on every field, and unexported fields cannot be set
+// through reflection, so they are copied shallowly: a type with
+// unexported references needs a Clone method, which DeepCopy then calls.
+// Channels and funcs are shared, there being nothing to copy.
+//
+// Clone methods of the values inside v are used, but not v's own, so a
+// Clone method may be written as return DeepCopy(x).
+func DeepCopy[T any](v T) T {
+	c := copier{seen: map[pointer]reflect.Value{}}
+	var out T
+	reflect.ValueOf(&out).Elem().Set(c.copyValue(reflect.ValueOf(&v).Elem()))
+	return out
+}
+
+type copier struct {
+	// seen maps a pointer already copied to its copy
+	seen map[pointer]reflect.Value
+}
+
+type pointer struct {
+	addr uintptr
+	typ  reflect.Type
+}
+
+func (c *copier) copy(src reflect.Value) reflect.Value {
+	if clone, ok := cloneMethod(src); ok {
+		return clone
+	}
+	return c.copyValue(src)
+}
+
+func (c *copier) copyValue(src reflect.Value) reflect.Value {
+	switch src.Kind() {
+	case reflect.Pointer:
+		if src.IsNil() {
+			return src
+		}
+		key := pointer{src.Pointer(), src.Type()}
+		if dst, ok := c.seen[key]; ok {
+			return dst
+		}
+		dst := reflect.New(src.Type().Elem())
+		// registered before recursing, so a cycle ends here
+		c.seen[key] = dst
+		dst.Elem().Set(c.copy(src.Elem()))
+		return dst
+	case reflect.Slice:
+		if src.IsNil() {
+			return src
+		}
+		dst := reflect.MakeSlice(src.Type(), src.Len(), src.Len())
+		for i := range src.Len() {
+			dst.Index(i).Set(c.copy(src.Index(i)))
+		}
+		return dst
+	case reflect.Map:
+		if src.IsNil() {
+			return src
+		}
+		dst := reflect.MakeMapWithSize(src.Type(), src.Len())
+		for iter := src.MapRange(); iter.Next(); {
+			// keys are kept: a copied pointer key would be a different key
+			dst.SetMapIndex(iter.Key(), c.copy(iter.Value()))
+		}
+		return dst
+	case reflect.Array:
+		dst := reflect.New(src.Type()).Elem()
+		for i := range src.Len() {
+			dst.Index(i).Set(c.copy(src.Index(i)))
+		}
+		return dst
+	case reflect.Struct:
+		dst := reflect.New(src.Type()).Elem()
+		// unexported fields come along shallowly
+		dst.Set(src)
+		for i := range src.NumField() {
+			if src.Type().Field(i).IsExported() {
+				dst.Field(i).Set(c.copy(src.Field(i)))
+			}
+		}
+		return dst
+	case reflect.Interface:
+		if src.IsNil() {
+			return src
+		}
+		dst := reflect.New(src.Type()).Elem()
+		dst.Set(c.copy(src.Elem()))
+		return dst
+	default:
+		// numbers, strings, bools, channels, funcs, unsafe pointers
+		return src
+	}
+}
+
+// cloneMethod calls src's Clone method if it has one returning its own
+// type.
+func cloneMethod(src reflect.Value) (reflect.Value, bool) {
+	// a pointer's Clone would copy the pointer, not what the copier does
+	if src.Kind() == reflect.Pointer || src.Kind() == reflect.Interface || !src.CanInterface() {
+		return reflect.Value{}, false
+	}
+	m := src.MethodByName("Clone")
+	if !m.IsValid() {
+		return reflect.Value{}, false
+	}
+	t := m.Type()
+	if t.NumIn() != 0 || t.NumOut() != 1 || t.Out(0) != src.Type() {
+		return reflect.Value{}, false
+	}
+	return m.Call(nil)[0], true
+}
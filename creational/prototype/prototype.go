@@ -0,0 +1,128 @@
+// Package prototype makes new values by copying a configured one, and
+// shows why the copy is the hard part:
+//
+//	reg := prototype.NewRegistry[prototype.Document]()
+//	reg.Register("memo", memoTemplate)
+//	doc, err := reg.New("memo") // a deep copy, safe to edit
+//
+// Assigning a struct copies its fields, but a slice, map or pointer field
+// copies only the reference, so the "copy" and the original share what it
+// points to:
+//
+//   - Shallow: d2 := d (Level: Poor): writes through Tags, Meta or Author
+//     show up in both, and append into spare capacity overwrites the other
+//     copy's later appends
+//   - Clone: a Clone method written per type (Level: Good): copies exactly
+//     what the type owns, unexported fields included
+//   - DeepCopy: a reflection-based copy of any value (Level: Average):
+//     no code per type, but slower and blind to unexported fields, which
+//     it copies shallowly unless the type has its own Clone
+//
+// TestAliasing runs each copy through the usual aliasing bugs, along with
+// the common first attempt at Clone that copies the slices and maps of
+// the document but not the pointer and slices inside them:
+//
+//	go test -run Aliasing patterns/creational/prototype
+package prototype
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+)
+
+// Cloner is implemented by types that copy themselves deeply.
+type Cloner[T any] interface {
+	Clone() T
+}
+
+type Person struct {
+	Name  string
+	Email string
+}
+
+type Section struct {
+	Heading string
+	Lines   []string
+}
+
+// Document is the prototype in the examples: a value with every kind of
+// reference field.
+type Document struct {
+	Title    string
+	Tags     []string
+	Meta     map[string]string
+	Author   *Person
+	Sections []Section
+}
+
+// shallow copy
+// Level: Poor
+// cons: only the top level is copied; both documents share the Tags and
+// Sections arrays, the Meta map and the Author.
+func Shallow(d Document) Document { return d }
+
+// Clone pattern
+// Level: Good
+// pros: copies exactly what the type owns, at the cost of plain
+// assignments; the compiler checks it as fields change.
+// cons: one more method to keep in step with the struct: a new reference
+// field that Clone forgets is shared again.
+func (d Document) Clone() Document {
+	c := d
+	c.Tags = slices.Clone(d.Tags)
+	c.Meta = maps.Clone(d.Meta)
+	if d.Author != nil {
+		a := *d.Author
+		c.Author = &a
+	}
+	if d.Sections != nil {
+		c.Sections = make([]Section, len(d.Sections))
+		for i, s := range d.Sections {
+			c.Sections[i] = Section{Heading: s.Heading, Lines: slices.Clone(s.Lines)}
+		}
+	}
+	return c
+}
+
+// prototype registry
+// Level: Good
+// pros: new values start from named, preconfigured prototypes chosen at
+// run time, without a constructor per variant.
+// cons: only as safe as T's Clone.
+type Registry[T Cloner[T]] struct {
+	mu     sync.RWMutex
+	protos map[string]T
+}
+
+func NewRegistry[T Cloner[T]]() *Registry[T] {
+	return &Registry[T]{protos: map[string]T{}}
+}
+
+// Register stores a copy of proto under name, so later changes to proto
+// do not leak into values made from it.
+func (r *Registry[T]) Register(name string, proto T) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.protos[name] = proto.Clone()
+}
+
+// New returns a fresh copy of the prototype registered under name.
+func (r *Registry[T]) New(name string) (T, error) {
+	r.mu.RLock()
+	proto, ok := r.protos[name]
+	r.mu.RUnlock()
+	if !ok {
+		var zero T
+		return zero, fmt.Errorf("unknown prototype %q", name)
+	}
+	return proto.Clone(), nil
+}
+
+// Names lists the registered prototypes in order.
+func (r *Registry[T]) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return slices.Sorted(maps.Keys(r.protos))
+}
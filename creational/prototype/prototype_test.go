@@ -0,0 +1,191 @@
+package prototype_test
+
+import (
+	"maps"
+	"slices"
+	"testing"
+
+	"patterns/creational/prototype"
+)
+
+// draft has Document's fields but not its Clone method, so DeepCopy has
+// to walk it by reflection.
+type draft prototype.Document
+
+var copies = []struct {
+	name string
+	copy func(prototype.Document) prototype.Document
+}{
+	{"shallow", prototype.Shallow},
+	// the usual first attempt at Clone: every field of the document is
+	// copied, but not what those fields point to
+	{"one level", func(d prototype.Document) prototype.Document {
+		d.Tags = slices.Clone(d.Tags)
+		d.Meta = maps.Clone(d.Meta)
+		d.Sections = slices.Clone(d.Sections)
+		return d
+	}},
+	{"clone", prototype.Document.Clone},
+	{"deepcopy", func(d prototype.Document) prototype.Document {
+		return prototype.Document(prototype.DeepCopy(draft(d)))
+	}},
+}
+
+// bugs are edits to a copy; each reports whether the original changed.
+// leaks lists the copies the edit gets through.
+var bugs = []struct {
+	name  string
+	edit  func(orig, cp *prototype.Document) bool
+	leaks []string
+}{
+	{"slice element", func(orig, cp *prototype.Document) bool {
+		cp.Tags[0] = "edited"
+		return orig.Tags[0] != "go"
+	}, []string{"shallow"}},
+	{"append into spare capacity", func(orig, cp *prototype.Document) bool {
+		cp.Tags = append(cp.Tags, "from copy")
+		orig.Tags = append(orig.Tags, "from original")
+		return cp.Tags[len(cp.Tags)-1] != "from copy"
+	}, []string{"shallow"}},
+	{"map entry", func(orig, cp *prototype.Document) bool {
+		cp.Meta["status"] = "edited"
+		return orig.Meta["status"] != "draft"
+	}, []string{"shallow"}},
+	{"pointer field", func(orig, cp *prototype.Document) bool {
+		cp.Author.Name = "edited"
+		return orig.Author.Name != "Ada"
+	}, []string{"shallow", "one level"}},
+	{"nested slice", func(orig, cp *prototype.Document) bool {
+		cp.Sections[0].Lines[0] = "edited"
+		return orig.Sections[0].Lines[0] != "first line"
+	}, []string{"shallow", "one level"}},
+}
+
+func template() prototype.Document {
+	tags := make([]string, 0, 4)
+	return prototype.Document{
+		Title:    "memo",
+		Tags:     append(tags, "go", "patterns"),
+		Meta:     map[string]string{"status": "draft"},
+		Author:   &prototype.Person{Name: "Ada", Email: "ada@example.com"},
+		Sections: []prototype.Section{{Heading: "intro", Lines: []string{"first line"}}},
+	}
+}
+
+// TestAliasing runs every copy through the usual aliasing bugs: each
+// copy that shares memory with the original leaks some edit, Clone and
+// DeepCopy none.
+func TestAliasing(t *testing.T) {
+	for _, b := range bugs {
+		for _, c := range copies {
+			orig := template()
+			cp := c.copy(orig)
+			if leaked, want := b.edit(&orig, &cp), slices.Contains(b.leaks, c.name); leaked != want {
+				t.Errorf("%s through %s: leaked %v, want %v", b.name, c.name, leaked, want)
+			}
+		}
+	}
+}
+
+func TestCloneNil(t *testing.T) {
+	c := prototype.Document{Title: "empty"}.Clone()
+	if c.Tags != nil || c.Meta != nil || c.Author != nil || c.Sections != nil {
+		t.Errorf("Clone of a document without references = %+v, want nil references kept nil", c)
+	}
+}
+
+type node struct {
+	Name string
+	Next *node
+}
+
+func TestDeepCopyPointers(t *testing.T) {
+	// a cycle, and a pointer reached twice
+	a := &node{Name: "a"}
+	b := &node{Name: "b", Next: a}
+	a.Next = b
+	shared := &prototype.Person{Name: "Ada"}
+	in := struct {
+		Ring         *node
+		First, Again *prototype.Person
+	}{a, shared, shared}
+
+	out := prototype.DeepCopy(in)
+	if out.Ring == a || out.Ring.Next == b {
+		t.Error("DeepCopy kept the original nodes")
+	}
+	if out.Ring.Next.Next != out.Ring {
+		t.Error("DeepCopy did not copy the cycle as a cycle")
+	}
+	if out.First == shared || out.First != out.Again {
+		t.Errorf("DeepCopy: shared pointer copied to %p and %p, want one new pointer", out.First, out.Again)
+	}
+}
+
+// counted has an unexported reference DeepCopy cannot reach, and a Clone
+// method that copies it, which DeepCopy must use.
+type counted struct {
+	Name  string
+	count *int
+}
+
+func (c counted) Clone() counted {
+	n := *c.count
+	return counted{Name: c.Name, count: &n}
+}
+
+type unexported struct {
+	Name  string
+	count *int
+}
+
+func TestDeepCopyUnexported(t *testing.T) {
+	n := 1
+	cloned := prototype.DeepCopy([]counted{{"x", &n}})
+	if cloned[0].count == &n {
+		t.Error("DeepCopy did not use the element's Clone method")
+	}
+	plain := prototype.DeepCopy([]unexported{{"x", &n}})
+	if plain[0].count != &n {
+		t.Error("DeepCopy copied an unexported pointer it cannot set")
+	}
+}
+
+func TestDeepCopyContainers(t *testing.T) {
+	in := map[string][]*prototype.Person{"team": {{Name: "Ada"}}}
+	out := prototype.DeepCopy(in)
+	out["team"][0].Name = "edited"
+	out["team"] = append(out["team"], nil)
+	if in["team"][0].Name != "Ada" || len(in["team"]) != 1 {
+		t.Errorf("edits to the copy reached the original: %v", in["team"])
+	}
+	var anyVal any = []string{"a"}
+	if cp := prototype.DeepCopy(anyVal).([]string); &cp[0] == &anyVal.([]string)[0] {
+		t.Error("DeepCopy of an interface shared its slice")
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	r := prototype.NewRegistry[prototype.Document]()
+	proto := template()
+	r.Register("memo", proto)
+	r.Register("letter", prototype.Document{Title: "letter"})
+	// later changes to the prototype passed in do not reach the registry
+	proto.Tags[0] = "edited"
+
+	a, err := r.New("memo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := r.New("memo")
+	a.Author.Name = "edited"
+	if a.Tags[0] != "go" || b.Author.Name != "Ada" {
+		t.Errorf("values from one prototype share memory: %v, %v", a.Tags, b.Author)
+	}
+	if _, err := r.New("invoice"); err == nil {
+		t.Error("New of an unknown prototype succeeded")
+	}
+	if got := r.Names(); !slices.Equal(got, []string{"letter", "memo"}) {
+		t.Errorf("Names = %v", got)
+	}
+}
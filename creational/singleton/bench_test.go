@@ -0,0 +1,34 @@
+package singleton
+
+import "testing"
+
+func loadSettings() *Settings { return &Settings{Name: "default", Limit: 100} }
+
+var sink *Settings
+
+// BenchmarkGet measures Get on an initialized instance of each variant,
+// from one goroutine and from GOMAXPROCS goroutines at once.
+func BenchmarkGet(b *testing.B) {
+	for _, v := range Variants {
+		b.Run(v.Name, func(b *testing.B) {
+			l := v.New(loadSettings)
+			l.Get()
+			b.ResetTimer()
+			for range b.N {
+				sink = l.Get()
+			}
+		})
+		b.Run(v.Name+"/parallel", func(b *testing.B) {
+			l := v.New(loadSettings)
+			l.Get()
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				var s *Settings
+				for pb.Next() {
+					s = l.Get()
+				}
+				_ = s
+			})
+		})
+	}
+}
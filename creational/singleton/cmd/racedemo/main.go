@@ -0,0 +1,59 @@
+// Command racedemo calls each singleton variant from many goroutines at
+// once, the first use included, and reports how often the loader ran.
+// Run it under the race detector to see DoubleChecked rejected:
+//
+//	go run -race patterns/creational/singleton/cmd/racedemo [-variant doublechecked]
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+
+	"patterns/creational/singleton"
+)
+
+func main() {
+	variant := flag.String("variant", "", "only run this variant")
+	goroutines := flag.Int("goroutines", 64, "concurrent callers")
+	flag.Parse()
+
+	ran := false
+	for _, v := range singleton.Variants {
+		if *variant != "" && v.Name != *variant {
+			continue
+		}
+		ran = true
+		var loads atomic.Int32
+		l := v.New(func() *singleton.Settings {
+			loads.Add(1)
+			return &singleton.Settings{Name: "default", Limit: 100}
+		})
+
+		var wg sync.WaitGroup
+		start := make(chan struct{})
+		seen := make([]*singleton.Settings, *goroutines)
+		for i := range seen {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				<-start
+				seen[i] = l.Get()
+			}()
+		}
+		close(start)
+		wg.Wait()
+
+		distinct := map[*singleton.Settings]bool{}
+		for _, s := range seen {
+			distinct[s] = true
+		}
+		fmt.Printf("%-14s loads=%d instances=%d\n", v.Name, loads.Load(), len(distinct))
+	}
+	if !ran {
+		fmt.Fprintf(os.Stderr, "unknown variant %q\n", *variant)
+		os.Exit(2)
+	}
+}
@@ -0,0 +1,5 @@
+//go:build race
+
+package singleton
+
+const raceEnabled = true
@@ -0,0 +1,173 @@
+// Package singleton compares ways to create one shared instance lazily
+// (or not), from the one to use to the one that only looks right:
+//
+//	var config = sync.OnceValue(loadConfig)
+//
+//	func Config() *Settings { return config() }
+//
+// Each variant is a Lazy over the same loader, so cmd/racedemo and the
+// Benchmarks can build fresh ones; in a program the singleton is the
+// package-level variable holding it, as above.
+//
+//   - Eager: built at package init (Level: Good when init is cheap)
+//   - Mutex: lock on every call (Level: Average)
+//   - Once: sync.Once guarding a field (Level: Good)
+//   - OnceValue: sync.OnceValue, the same with less code (Level: Good)
+//   - Atomic: double-checked locking done right with atomic.Pointer
+//     (Level: Average, sync.Once rewritten by hand)
+//   - DoubleChecked: the classic unsynchronized fast path (Level: Poor, a
+//     data race)
+//
+// The race detector rejects DoubleChecked and accepts the others:
+//
+//	go run -race patterns/creational/singleton/cmd/racedemo
+//
+// and go test -race runs the same first-use storm against every other
+// variant, checking one load and one instance.
+//
+// findings (see bench_test.go; go test -bench .
+// patterns/creational/singleton), measured through the Lazy interface on
+// a single-CPU machine:
+//
+//   - once initialized, Eager costs ~2ns per call and Once and Atomic
+//     ~3.5ns: one atomic load on top of the interface call. OnceValue is
+//     ~5.5ns, one more indirect call through the func it returns.
+//   - DoubleChecked is ~5ns, no faster than Once. Skipping the
+//     synchronization buys nothing, because an atomic load on the fast
+//     path is as cheap as a plain one on amd64.
+//   - Mutex is ~19ns, 5x Once, on every call for the life of the program.
+//     With one CPU the parallel runs match the serial ones; on more cores
+//     contention can only widen the gap, since the other variants never
+//     write shared memory once initialized.
+//
+// So there is no speed to win by hand-rolling the check: use
+// sync.OnceValue (or sync.Once) and let the race detector stay quiet.
+package singleton
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Lazy returns the shared instance, creating it on first use.
+type Lazy[T any] interface {
+	Get() T
+}
+
+// eager pattern
+// Level: Good
+// pros: no synchronization at all: the value exists before main runs.
+// cons: paid at startup by every program importing the package, used or
+// not, and a failing load can only panic.
+type eager[T any] struct{ v T }
+
+func Eager[T any](v T) Lazy[T] { return eager[T]{v} }
+
+func (e eager[T]) Get() T { return e.v }
+
+// mutex singleton
+// Level: Average
+// pros: obviously correct.
+// cons: every call takes the lock, long after the value stopped changing,
+// so it serializes all readers.
+type mutex[T any] struct {
+	mu   sync.Mutex
+	load func() T
+	v    T
+	done bool
+}
+
+func Mutex[T any](load func() T) Lazy[T] { return &mutex[T]{load: load} }
+
+func (m *mutex[T]) Get() T {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.done {
+		m.v = m.load()
+		m.done = true
+	}
+	return m.v
+}
+
+// sync.Once singleton
+// Level: Good
+// pros: the fast path is one atomic load; the slow path runs the loader
+// exactly once, and callers arriving meanwhile wait for it to finish.
+// cons: if load panics, Do still counts as done and later calls get the
+// zero value.
+type once[T any] struct {
+	once sync.Once
+	load func() T
+	v    T
+}
+
+func Once[T any](load func() T) Lazy[T] { return &once[T]{load: load} }
+
+func (o *once[T]) Get() T {
+	o.once.Do(func() { o.v = o.load() })
+	return o.v
+}
+
+// sync.OnceValue singleton
+// Level: Good
+// pros: sync.Once with the field and closure written for you; a panic in
+// load is re-raised on every call instead of turning into a zero value.
+// Use sync.OnceValues for loaders that return an error.
+type onceValue[T any] struct{ get func() T }
+
+func OnceValue[T any](load func() T) Lazy[T] { return onceValue[T]{sync.OnceValue(load)} }
+
+func (o onceValue[T]) Get() T { return o.get() }
+
+// atomic double-checked locking
+// Level: Average
+// pros: correct: the atomic load pairs with the atomic store, so a reader
+// that sees the pointer sees the value behind it.
+// cons: it is sync.Once written by hand, with a pointer per instance.
+type atomicDCL[T any] struct {
+	mu   sync.Mutex
+	load func() T
+	p    atomic.Pointer[T]
+}
+
+func Atomic[T any](load func() T) Lazy[T] { return &atomicDCL[T]{load: load} }
+
+func (a *atomicDCL[T]) Get() T {
+	if p := a.p.Load(); p != nil {
+		return *p
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if p := a.p.Load(); p != nil {
+		return *p
+	}
+	v := a.load()
+	a.p.Store(&v)
+	return v
+}
+
+// double-checked locking
+// Level: Poor
+// cons: the first check reads p without synchronization while another
+// goroutine writes it under the lock: a data race. The Go memory model
+// gives a racing reader no guarantee it sees the pointed-to value fully
+// written, and the race detector reports it on the first concurrent use.
+type doubleChecked[T any] struct {
+	mu   sync.Mutex
+	load func() T
+	p    *T
+}
+
+func DoubleChecked[T any](load func() T) Lazy[T] { return &doubleChecked[T]{load: load} }
+
+func (d *doubleChecked[T]) Get() T {
+	if d.p == nil { // racy read
+		d.mu.Lock()
+		if d.p == nil {
+			v := d.load()
+			d.p = &v
+		}
+		d.mu.Unlock()
+	}
+	return *d.p
+}
@@ -0,0 +1,80 @@
+package singleton
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// TestConcurrentFirstUse calls each variant from many goroutines released
+// at once, so the loader runs while other callers are already waiting.
+// Run it with -race: every variant but DoubleChecked must pass there, and
+// DoubleChecked is skipped because the detector rightly fails it.
+func TestConcurrentFirstUse(t *testing.T) {
+	const goroutines = 64
+	for _, v := range Variants {
+		t.Run(v.Name, func(t *testing.T) {
+			if v.Name == "doublechecked" && raceEnabled {
+				t.Skip("racy by design; see go run -race ./cmd/racedemo")
+			}
+			for range 20 {
+				var loads atomic.Int32
+				l := v.New(func() *Settings {
+					loads.Add(1)
+					return &Settings{Name: "default", Limit: 100}
+				})
+
+				var wg sync.WaitGroup
+				start := make(chan struct{})
+				seen := make([]*Settings, goroutines)
+				for i := range seen {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						<-start
+						seen[i] = l.Get()
+					}()
+				}
+				close(start)
+				wg.Wait()
+
+				if n := loads.Load(); n != 1 {
+					t.Fatalf("loads = %d, want 1", n)
+				}
+				for i, s := range seen {
+					if s != seen[0] {
+						t.Fatalf("goroutine %d got %p, goroutine 0 got %p", i, s, seen[0])
+					}
+				}
+				if *seen[0] != (Settings{Name: "default", Limit: 100}) {
+					t.Fatalf("Get() = %+v, want the loaded settings", *seen[0])
+				}
+			}
+		})
+	}
+}
+
+// TestLoadPanics pins down the difference the package doc draws between
+// Once and OnceValue when the loader panics.
+func TestLoadPanics(t *testing.T) {
+	get := func(l Lazy[*Settings]) (s *Settings, panicked bool) {
+		defer func() { panicked = recover() != nil }()
+		return l.Get(), false
+	}
+	load := func() *Settings { panic("load failed") }
+
+	o := Once(load)
+	if _, panicked := get(o); !panicked {
+		t.Error("Once: first Get did not panic")
+	}
+	if s, panicked := get(o); panicked || s != nil {
+		t.Errorf("Once: second Get = %v, panicked %v; want nil, false", s, panicked)
+	}
+
+	ov := OnceValue(load)
+	for i := range 2 {
+		if _, panicked := get(ov); !panicked {
+			t.Errorf("OnceValue: Get %d did not panic", i+1)
+		}
+	}
+}
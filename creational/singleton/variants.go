@@ -0,0 +1,20 @@
+package singleton
+
+// Settings stands in for whatever the singleton holds.
+type Settings struct {
+	Name  string
+	Limit int
+}
+
+// Variants are the lazy variants by name, each freshly built.
+var Variants = []struct {
+	Name string
+	New  func(load func() *Settings) Lazy[*Settings]
+}{
+	{"eager", func(load func() *Settings) Lazy[*Settings] { return Eager(load()) }},
+	{"mutex", Mutex[*Settings]},
+	{"once", Once[*Settings]},
+	{"oncevalue", OnceValue[*Settings]},
+	{"atomic", Atomic[*Settings]},
+	{"doublechecked", DoubleChecked[*Settings]},
+}
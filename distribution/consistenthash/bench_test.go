@@ -0,0 +1,40 @@
+package consistenthash
+
+import (
+	"strconv"
+	"testing"
+)
+
+var sink string
+
+func benchRing(b *testing.B, nodes int) *Ring[string] {
+	r, err := New[string]()
+	if err != nil {
+		b.Fatal(err)
+	}
+	for i := range nodes {
+		name := "node-" + strconv.Itoa(i)
+		if err := r.AddNode(name, name); err != nil {
+			b.Fatal(err)
+		}
+	}
+	return r
+}
+
+// BenchmarkLocate measures Locate on rings of 10 and 100 nodes with the
+// default virtual nodes.
+func BenchmarkLocate(b *testing.B) {
+	for _, nodes := range []int{10, 100} {
+		b.Run(strconv.Itoa(nodes), func(b *testing.B) {
+			r := benchRing(b, nodes)
+			keys := make([]string, 1024)
+			for i := range keys {
+				keys[i] = "user-" + strconv.Itoa(i)
+			}
+			b.ResetTimer()
+			for i := range b.N {
+				sink, _ = r.Locate(keys[i%len(keys)])
+			}
+		})
+	}
+}
@@ -0,0 +1,226 @@
+// Package consistenthash maps keys to nodes on a hash ring, so that a
+// change of membership moves only the keys it has to:
+//
+//	ring, _ := consistenthash.New[*Shard]()
+//	ring.AddNode("cache-a", shardA)
+//	ring.AddNode("cache-b", shardB)
+//	shard, ok := ring.Locate(userID)
+//
+// Every node is hashed onto the ring at many points (virtual nodes) and a
+// key belongs to the first point at or after its own hash. Adding a node
+// takes over the arcs before its points, a share of about 1/n of the
+// keys, all of them from other nodes; removing one hands its arcs to the
+// successors; keys elsewhere on the ring stay put. With hash(key) % n,
+// changing n moves nearly every key.
+//
+// Placement depends only on the node names and the hash, never on the
+// order nodes were added or on the process: every client of a cache
+// fleet computes the same owner for a key.
+//
+// findings (100k keys over 10 nodes; see consistenthash_test.go, go test
+// -v -run 'Remap|Balance' patterns/distribution/consistenthash):
+//
+//   - adding an 11th node moves 9.4% of the keys, close to the 1/11 it
+//     must take, and all of them to the new node; hash % n moves 91.0%.
+//   - removing a node moves 8.3%, exactly the keys it owned and no
+//     others; hash % n moves 90.2%.
+//   - the busiest node holds 1.13x its fair share with 160 virtual nodes,
+//     1.48x with 10 and 1.58x with one; 500 bring it to 1.05x.
+//   - Locate is a binary search over the points, ~135ns for 10 nodes and
+//     ~180ns for 100 (see bench_test.go; go test -bench .
+//     patterns/distribution/consistenthash).
+package consistenthash
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"slices"
+	"sort"
+	"strconv"
+	"sync"
+
+	"patterns/construct"
+	"patterns/funcopts"
+)
+
+var ErrExists = errors.New("consistenthash: node already on the ring")
+
+type options struct {
+	replicas int
+	hash     func(string) uint64
+}
+
+type Option = funcopts.Option[options]
+
+// WithReplicas sets the number of virtual nodes per node; the default is
+// 160. More points spread the keys more evenly, at the cost of memory and
+// a slightly deeper search.
+func WithReplicas(n int) Option {
+	return func(options *options) error {
+		if n <= 0 {
+			return errors.New("replicas must be positive")
+		}
+		options.replicas = n
+		return nil
+	}
+}
+
+// WithHash replaces the hash of keys and virtual node names. It must give
+// the same result in every process sharing the ring, which rules out a
+// randomly seeded hash/maphash.
+func WithHash(hash func(string) uint64) Option {
+	return func(options *options) error {
+		if hash == nil {
+			return errors.New("hash cannot be nil")
+		}
+		options.hash = hash
+		return nil
+	}
+}
+
+func (o *options) SetDefaults() {
+	o.replicas = 160
+	o.hash = Hash
+}
+
+// Hash is the default hash: 64-bit FNV-1a with a SplitMix64 finalizer,
+// whose avalanche spreads similar names like node-1 and node-2 across the
+// ring, which FNV alone does not.
+func Hash(s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	x := h.Sum64()
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+// consistent hashing pattern
+// Level: Good
+// pros: membership changes move the minimum share of keys, and any client
+// computes the owner of a key locally with no coordination.
+// cons: keys are spread by count, not load: a hot key stays on one node;
+// correctness during a change still needs the data moved (or refetched)
+// by someone.
+type Ring[T any] struct {
+	options options
+
+	mu     sync.RWMutex
+	points []point // sorted by hash, then name
+	nodes  map[string]T
+}
+
+type point struct {
+	hash uint64
+	name string
+}
+
+func New[T any](opts ...Option) (*Ring[T], error) {
+	options, err := construct.New(opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &Ring[T]{options: *options, nodes: map[string]T{}}, nil
+}
+
+// AddNode places node on the ring under name.
+func (r *Ring[T]) AddNode(name string, node T) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.nodes[name]; ok {
+		return fmt.Errorf("%w: %q", ErrExists, name)
+	}
+	r.nodes[name] = node
+	for i := range r.options.replicas {
+		r.points = append(r.points, point{hash: r.options.hash(name + "#" + strconv.Itoa(i)), name: name})
+	}
+	slices.SortFunc(r.points, comparePoints)
+	return nil
+}
+
+// comparePoints orders by hash and breaks ties by name, so colliding
+// points land the same way whatever the order nodes were added in.
+func comparePoints(a, b point) int {
+	switch {
+	case a.hash < b.hash:
+		return -1
+	case a.hash > b.hash:
+		return 1
+	case a.name < b.name:
+		return -1
+	case a.name > b.name:
+		return 1
+	}
+	return 0
+}
+
+// RemoveNode takes name off the ring and reports whether it was there.
+func (r *Ring[T]) RemoveNode(name string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.nodes[name]; !ok {
+		return false
+	}
+	delete(r.nodes, name)
+	r.points = slices.DeleteFunc(r.points, func(p point) bool { return p.name == name })
+	return true
+}
+
+// Locate returns the node owning key, or false if the ring is empty.
+func (r *Ring[T]) Locate(key string) (T, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if len(r.points) == 0 {
+		var zero T
+		return zero, false
+	}
+	return r.nodes[r.points[r.search(key)].name], true
+}
+
+// LocateN returns up to n distinct nodes for key, the owner first and
+// then the next nodes clockwise: the replica set of the key.
+func (r *Ring[T]) LocateN(key string, n int) []T {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if len(r.points) == 0 || n <= 0 {
+		return nil
+	}
+	n = min(n, len(r.nodes))
+	out := make([]T, 0, n)
+	seen := make(map[string]bool, n)
+	for i := r.search(key); len(out) < n; i = (i + 1) % len(r.points) {
+		name := r.points[i].name
+		if !seen[name] {
+			seen[name] = true
+			out = append(out, r.nodes[name])
+		}
+	}
+	return out
+}
+
+// search returns the index of the first point at or after key's hash,
+// wrapping to the first point past the top of the ring.
+func (r *Ring[T]) search(key string) int {
+	h := r.options.hash(key)
+	i := sort.Search(len(r.points), func(i int) bool { return r.points[i].hash >= h })
+	if i == len(r.points) {
+		return 0
+	}
+	return i
+}
+
+// Nodes returns the node names in order.
+func (r *Ring[T]) Nodes() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.nodes))
+	for name := range r.nodes {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
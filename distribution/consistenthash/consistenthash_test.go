@@ -0,0 +1,202 @@
+package consistenthash
+
+import (
+	"errors"
+	"slices"
+	"strconv"
+	"testing"
+)
+
+const keyCount = 100_000
+
+func ring(t *testing.T, nodes int, opts ...Option) *Ring[string] {
+	t.Helper()
+	r, err := New[string](opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range nodes {
+		name := "node-" + strconv.Itoa(i)
+		if err := r.AddNode(name, name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return r
+}
+
+// owners maps every test key to its node.
+func owners(r *Ring[string]) []string {
+	out := make([]string, keyCount)
+	for i := range out {
+		out[i], _ = r.Locate("user-" + strconv.Itoa(i))
+	}
+	return out
+}
+
+// modOwners places the same keys with hash % n, for comparison.
+func modOwners(n int) []string {
+	out := make([]string, keyCount)
+	for i := range out {
+		out[i] = "node-" + strconv.FormatUint(Hash("user-"+strconv.Itoa(i))%uint64(n), 10)
+	}
+	return out
+}
+
+func moved(before, after []string) int {
+	n := 0
+	for i := range before {
+		if before[i] != after[i] {
+			n++
+		}
+	}
+	return n
+}
+
+// TestAddNodeRemap checks that an added node takes close to its 1/n
+// share, only from other nodes, against hash % n moving nearly all keys.
+func TestAddNodeRemap(t *testing.T) {
+	r := ring(t, 10)
+	before := owners(r)
+	if err := r.AddNode("node-10", "node-10"); err != nil {
+		t.Fatal(err)
+	}
+	after := owners(r)
+
+	for i := range before {
+		if before[i] != after[i] && after[i] != "node-10" {
+			t.Fatalf("key %d moved from %s to %s, not to the new node", i, before[i], after[i])
+		}
+	}
+	share := float64(moved(before, after)) / keyCount
+	mod := float64(moved(modOwners(10), modOwners(11))) / keyCount
+	t.Logf("adding an 11th node moves %.1f%% of keys; hash %% n moves %.1f%%", 100*share, 100*mod)
+	if share < 0.06 || share > 0.13 {
+		t.Errorf("moved %.3f of keys, want about 1/11", share)
+	}
+	if mod < 0.8 {
+		t.Errorf("hash %% n moved %.3f of keys, want nearly all", mod)
+	}
+}
+
+// TestRemoveNodeRemap checks that exactly the removed node's keys move.
+func TestRemoveNodeRemap(t *testing.T) {
+	r := ring(t, 10)
+	before := owners(r)
+	if !r.RemoveNode("node-3") {
+		t.Fatal("RemoveNode(node-3) = false")
+	}
+	after := owners(r)
+
+	owned := 0
+	for i := range before {
+		if before[i] == "node-3" {
+			owned++
+			if after[i] == "node-3" {
+				t.Fatalf("key %d still on the removed node", i)
+			}
+		} else if after[i] != before[i] {
+			t.Fatalf("key %d moved from %s to %s, though its node stayed", i, before[i], after[i])
+		}
+	}
+	share := float64(moved(before, after)) / keyCount
+	mod := float64(moved(modOwners(10), modOwners(9))) / keyCount
+	t.Logf("removing a node moves %.1f%% of keys; hash %% n moves %.1f%%", 100*share, 100*mod)
+	if moved(before, after) != owned {
+		t.Errorf("moved %d keys, the node owned %d", moved(before, after), owned)
+	}
+	if share < 0.05 || share > 0.15 {
+		t.Errorf("moved %.3f of keys, want about 1/10", share)
+	}
+	if r.RemoveNode("node-3") {
+		t.Error("second RemoveNode(node-3) = true")
+	}
+}
+
+// TestAddRemoveRestores checks that removing an added node puts every
+// key back where it was.
+func TestAddRemoveRestores(t *testing.T) {
+	r := ring(t, 10)
+	before := owners(r)
+	r.AddNode("node-10", "node-10")
+	r.RemoveNode("node-10")
+	if n := moved(before, owners(r)); n != 0 {
+		t.Errorf("%d keys moved after adding and removing a node", n)
+	}
+}
+
+func TestBalance(t *testing.T) {
+	for _, c := range []struct {
+		replicas int
+		max      float64
+	}{
+		{1, 3},
+		{10, 2},
+		{160, 1.3},
+		{500, 1.15},
+	} {
+		r := ring(t, 10, WithReplicas(c.replicas))
+		counts := map[string]int{}
+		for _, o := range owners(r) {
+			counts[o]++
+		}
+		busiest := 0
+		for _, n := range counts {
+			busiest = max(busiest, n)
+		}
+		ratio := float64(busiest) / (keyCount / 10)
+		t.Logf("%d replicas: busiest node holds %.2fx its share", c.replicas, ratio)
+		if ratio > c.max {
+			t.Errorf("%d replicas: busiest node holds %.2fx its share, want at most %gx", c.replicas, ratio, c.max)
+		}
+	}
+}
+
+func TestOrderIndependent(t *testing.T) {
+	a := ring(t, 10)
+	b, _ := New[string]()
+	for i := 9; i >= 0; i-- {
+		name := "node-" + strconv.Itoa(i)
+		b.AddNode(name, name)
+	}
+	if n := moved(owners(a), owners(b)); n != 0 {
+		t.Errorf("%d keys placed differently when nodes were added in reverse", n)
+	}
+}
+
+func TestLocateN(t *testing.T) {
+	r := ring(t, 5)
+	for i := range 1000 {
+		key := "user-" + strconv.Itoa(i)
+		owner, _ := r.Locate(key)
+		got := r.LocateN(key, 3)
+		if len(got) != 3 || got[0] != owner {
+			t.Fatalf("LocateN(%s, 3) = %v, want 3 nodes starting with %s", key, got, owner)
+		}
+		if len(slices.Compact(slices.Sorted(slices.Values(got)))) != 3 {
+			t.Fatalf("LocateN(%s, 3) = %v, want distinct nodes", key, got)
+		}
+	}
+	if got := r.LocateN("user-1", 10); len(got) != 5 {
+		t.Errorf("LocateN(_, 10) on 5 nodes = %v, want all 5", got)
+	}
+}
+
+func TestEmptyAndErrors(t *testing.T) {
+	r, _ := New[string]()
+	if _, ok := r.Locate("k"); ok {
+		t.Error("Locate on an empty ring = true")
+	}
+	if got := r.LocateN("k", 2); got != nil {
+		t.Errorf("LocateN on an empty ring = %v", got)
+	}
+	r.AddNode("a", "a")
+	if err := r.AddNode("a", "b"); !errors.Is(err, ErrExists) {
+		t.Errorf("AddNode twice = %v, want ErrExists", err)
+	}
+	if _, err := New[string](WithReplicas(0)); err == nil {
+		t.Error("New(WithReplicas(0)) succeeded")
+	}
+	if _, err := New[string](WithHash(nil)); err == nil {
+		t.Error("New(WithHash(nil)) succeeded")
+	}
+}
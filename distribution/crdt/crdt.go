@@ -0,0 +1,124 @@
+// Package crdt implements conflict-free replicated data types: values
+// every replica updates locally, without coordination, and that converge
+// once replicas have exchanged their states, in whatever order and however
+// often the exchanges happen.
+//
+// Merge is what makes that work: it is commutative, associative and
+// idempotent, so a.Merge(b) after b.Merge(a) leaves both equal, a state
+// merged twice changes nothing, and states can travel through any gossip
+// path. Each type here is state-based: replicas ship the whole value,
+// encoded with encoding/json, and merge what they receive.
+//
+//	cart := crdt.NewORSet[string]("phone")
+//	cart.Add("book")
+//	state, _ := json.Marshal(cart)
+//	// on the laptop
+//	var remote crdt.ORSet[string]
+//	json.Unmarshal(state, &remote)
+//	laptopCart.Merge(&remote)
+//
+// The values are owned by one goroutine each, like the maps they wrap;
+// distribution/logicalclock keeps conflicting writes for the application
+// to resolve instead.
+package crdt
+
+import (
+	"encoding/json"
+	"maps"
+)
+
+// G-Counter
+// Level: Good
+// pros: increments from every replica add up exactly, with no
+// coordination; merge is an element-wise max.
+// cons: only grows; the state holds one entry per replica that ever
+// counted.
+type GCounter struct {
+	id     string
+	counts map[string]uint64
+}
+
+// NewGCounter returns a counter for the replica id, which must be unique
+// among the replicas.
+func NewGCounter(id string) *GCounter {
+	return &GCounter{id: id, counts: map[string]uint64{}}
+}
+
+// Inc adds n to the replica's own count.
+func (c *GCounter) Inc(n uint64) { c.counts[c.id] += n }
+
+// Value is the sum over every replica.
+func (c *GCounter) Value() uint64 {
+	var sum uint64
+	for _, n := range c.counts {
+		sum += n
+	}
+	return sum
+}
+
+// Merge takes the larger count of every replica from other.
+func (c *GCounter) Merge(other *GCounter) {
+	if c.counts == nil {
+		c.counts = map[string]uint64{}
+	}
+	for id, n := range other.counts {
+		c.counts[id] = max(c.counts[id], n)
+	}
+}
+
+// Equal reports whether c and other hold the same state.
+func (c *GCounter) Equal(other *GCounter) bool { return maps.Equal(c.counts, other.counts) }
+
+// MarshalJSON encodes the state, without the replica id: what a replica
+// receives is someone else's state to merge.
+func (c *GCounter) MarshalJSON() ([]byte, error) { return json.Marshal(c.counts) }
+
+// UnmarshalJSON replaces the state, keeping the replica id.
+func (c *GCounter) UnmarshalJSON(b []byte) error {
+	var counts map[string]uint64
+	if err := json.Unmarshal(b, &counts); err != nil {
+		return err
+	}
+	if counts == nil {
+		counts = map[string]uint64{}
+	}
+	c.counts = counts
+	return nil
+}
+
+// PN-Counter
+// Level: Good
+// pros: increments and decrements converge, as two G-Counters whose
+// difference is the value.
+// cons: twice the state of a G-Counter; the value can go below any bound
+// the application had in mind, since no replica sees the others' updates
+// in time to refuse one.
+type PNCounter struct {
+	P, N *GCounter
+}
+
+func NewPNCounter(id string) *PNCounter {
+	return &PNCounter{P: NewGCounter(id), N: NewGCounter(id)}
+}
+
+func (c *PNCounter) Inc(n uint64) { c.P.Inc(n) }
+
+func (c *PNCounter) Dec(n uint64) { c.N.Inc(n) }
+
+func (c *PNCounter) Value() int64 { return int64(c.P.Value() - c.N.Value()) }
+
+func (c *PNCounter) Merge(other *PNCounter) {
+	c.P.Merge(other.P)
+	c.N.Merge(other.N)
+}
+
+func (c *PNCounter) Equal(other *PNCounter) bool { return c.P.Equal(other.P) && c.N.Equal(other.N) }
+
+// UnmarshalJSON replaces the state, keeping the replica id.
+func (c *PNCounter) UnmarshalJSON(b []byte) error {
+	if c.P == nil {
+		c.P, c.N = NewGCounter(""), NewGCounter("")
+	}
+	state := struct{ P, N *GCounter }{c.P, c.N}
+	return json.Unmarshal(b, &state)
+}
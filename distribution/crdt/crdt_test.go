@@ -0,0 +1,302 @@
+package crdt_test
+
+import (
+	"encoding/json"
+	"maps"
+	"slices"
+	"testing"
+
+	"patterns/distribution/crdt"
+	"patterns/randsource"
+)
+
+type state[T any] interface {
+	Merge(T)
+	Equal(T) bool
+}
+
+// ship is what a replica receives of another: its state after a JSON
+// round trip.
+func ship[T any](t *testing.T, v T, into T) T {
+	t.Helper()
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(b, into); err != nil {
+		t.Fatal(err)
+	}
+	return into
+}
+
+// run applies steps random operations to replicas, interleaved with
+// merges of one replica's shipped state into another, and leaves the
+// replicas in the states they reached. merged, if set, learns of each
+// merge of j into i.
+func run[T state[T]](t *testing.T, r randsource.Rand, replicas []T, empty func() T, op func(i int), merged func(i, j int), steps int) {
+	for range steps {
+		i := r.IntN(len(replicas))
+		if r.IntN(4) > 0 {
+			op(i)
+			continue
+		}
+		if j := r.IntN(len(replicas)); j != i {
+			replicas[i].Merge(ship(t, replicas[j], empty()))
+			if merged != nil {
+				merged(i, j)
+			}
+		}
+	}
+}
+
+// converge has every replica exchange states with every other, in a
+// random order, and checks they end equal.
+func converge[T state[T]](t *testing.T, r randsource.Rand, replicas []T, empty func() T) {
+	t.Helper()
+	n := len(replicas)
+	for range 2 * n * n {
+		if i, j := r.IntN(n), r.IntN(n); i != j {
+			replicas[i].Merge(ship(t, replicas[j], empty()))
+		}
+	}
+	// a second round carries what the first picked up late
+	for i := range replicas {
+		for j := range replicas {
+			replicas[i].Merge(ship(t, replicas[j], empty()))
+		}
+	}
+	for i, v := range replicas[1:] {
+		if !v.Equal(replicas[0]) {
+			t.Fatalf("replica %d did not converge with replica 0", i+1)
+		}
+	}
+}
+
+// laws checks that merging a, b and c, states reached by independent
+// replicas, is commutative, associative and idempotent.
+func laws[T state[T]](t *testing.T, seed uint64, a, b, c T, empty func() T) {
+	t.Helper()
+	join := func(vs ...T) T {
+		out := ship(t, vs[0], empty())
+		for _, v := range vs[1:] {
+			out.Merge(v)
+		}
+		return out
+	}
+	if !join(a, b).Equal(join(b, a)) {
+		t.Errorf("seed %d: a⊔b != b⊔a", seed)
+	}
+	if !join(join(a, b), c).Equal(join(a, join(b, c))) {
+		t.Errorf("seed %d: (a⊔b)⊔c != a⊔(b⊔c)", seed)
+	}
+	if !join(a, a).Equal(a) || !join(join(a, b), b).Equal(join(a, b)) {
+		t.Errorf("seed %d: merging a state twice changed it", seed)
+	}
+}
+
+func TestGCounter(t *testing.T) {
+	for seed := range uint64(50) {
+		r := randsource.New(seed)
+		empty := func() *crdt.GCounter { return &crdt.GCounter{} }
+		replicas := []*crdt.GCounter{crdt.NewGCounter("a"), crdt.NewGCounter("b"), crdt.NewGCounter("c")}
+		var total uint64
+		run(t, r, replicas, empty, func(i int) {
+			n := uint64(r.IntN(5))
+			replicas[i].Inc(n)
+			total += n
+		}, nil, 200)
+		laws(t, seed, replicas[0], replicas[1], replicas[2], empty)
+		converge(t, r, replicas, empty)
+		if got := replicas[0].Value(); got != total {
+			t.Errorf("seed %d: Value %d, want %d", seed, got, total)
+		}
+	}
+}
+
+func TestPNCounter(t *testing.T) {
+	for seed := range uint64(50) {
+		r := randsource.New(seed)
+		empty := func() *crdt.PNCounter { return &crdt.PNCounter{} }
+		replicas := []*crdt.PNCounter{crdt.NewPNCounter("a"), crdt.NewPNCounter("b"), crdt.NewPNCounter("c")}
+		var total int64
+		run(t, r, replicas, empty, func(i int) {
+			n := uint64(r.IntN(5))
+			if r.IntN(2) == 0 {
+				replicas[i].Inc(n)
+				total += int64(n)
+			} else {
+				replicas[i].Dec(n)
+				total -= int64(n)
+			}
+		}, nil, 200)
+		laws(t, seed, replicas[0], replicas[1], replicas[2], empty)
+		converge(t, r, replicas, empty)
+		if got := replicas[0].Value(); got != total {
+			t.Errorf("seed %d: Value %d, want %d", seed, got, total)
+		}
+	}
+}
+
+// orModel is the OR-Set by its definition: a replica has seen some adds
+// and some removes of them, and holds the elements of the adds it has seen
+// that it has not seen removed.
+type orModel struct {
+	seen, removed map[int]bool // add ids
+}
+
+// TestORSet checks the laws, and that converged replicas hold what the
+// model says: every element with an add no replica has removed.
+func TestORSet(t *testing.T) {
+	for seed := range uint64(50) {
+		r := randsource.New(seed)
+		empty := func() *crdt.ORSet[int] { return &crdt.ORSet[int]{} }
+		replicas := []*crdt.ORSet[int]{crdt.NewORSet[int]("a"), crdt.NewORSet[int]("b"), crdt.NewORSet[int]("c")}
+		var added []int // element of each add id
+		models := make([]orModel, len(replicas))
+		for i := range models {
+			models[i] = orModel{map[int]bool{}, map[int]bool{}}
+		}
+		run(t, r, replicas, empty, func(i int) {
+			e, m := r.IntN(8), models[i]
+			if r.IntN(3) == 0 {
+				replicas[i].Remove(e)
+				for id := range m.seen {
+					if added[id] == e {
+						m.removed[id] = true
+					}
+				}
+				return
+			}
+			replicas[i].Add(e)
+			m.seen[len(added)] = true
+			added = append(added, e)
+		}, func(i, j int) {
+			maps.Copy(models[i].seen, models[j].seen)
+			maps.Copy(models[i].removed, models[j].removed)
+		}, 300)
+		laws(t, seed, replicas[0], replicas[1], replicas[2], empty)
+		converge(t, r, replicas, empty)
+
+		var want []int
+		for id, e := range added {
+			if !slices.Contains(want, e) && !slices.ContainsFunc(models, func(m orModel) bool { return m.removed[id] }) {
+				want = append(want, e)
+			}
+		}
+		slices.Sort(want)
+		got := replicas[0].Elements()
+		slices.Sort(got)
+		if !slices.Equal(got, want) || replicas[0].Len() != len(want) {
+			t.Errorf("seed %d: elements %v, want %v", seed, got, want)
+		}
+	}
+}
+
+// TestORSetAddWins checks the OR-Set semantics on the cases that define
+// them.
+func TestORSetAddWins(t *testing.T) {
+	sync := func(a, b *crdt.ORSet[string]) {
+		a.Merge(ship(t, b, &crdt.ORSet[string]{}))
+		b.Merge(ship(t, a, &crdt.ORSet[string]{}))
+	}
+	for _, c := range []struct {
+		name string
+		ops  func(a, b *crdt.ORSet[string])
+		want []string
+	}{
+		{"remove seen add", func(a, b *crdt.ORSet[string]) {
+			a.Add("x")
+			sync(a, b)
+			b.Remove("x")
+		}, nil},
+		{"concurrent add and remove", func(a, b *crdt.ORSet[string]) {
+			a.Add("x")
+			sync(a, b)
+			a.Add("x")
+			b.Remove("x")
+		}, []string{"x"}},
+		{"remove of an unseen add", func(a, b *crdt.ORSet[string]) {
+			a.Add("x")
+			b.Remove("x")
+		}, []string{"x"}},
+		{"add after remove", func(a, b *crdt.ORSet[string]) {
+			a.Add("x")
+			sync(a, b)
+			b.Remove("x")
+			sync(a, b)
+			b.Add("x")
+		}, []string{"x"}},
+		{"both remove", func(a, b *crdt.ORSet[string]) {
+			a.Add("x")
+			b.Add("x")
+			sync(a, b)
+			a.Remove("x")
+			b.Remove("x")
+		}, nil},
+		{"one removes both adds", func(a, b *crdt.ORSet[string]) {
+			a.Add("x")
+			b.Add("x")
+			b.Add("y")
+			sync(a, b)
+			a.Remove("x")
+		}, []string{"y"}},
+	} {
+		a, b := crdt.NewORSet[string]("a"), crdt.NewORSet[string]("b")
+		c.ops(a, b)
+		sync(a, b)
+		for _, s := range []*crdt.ORSet[string]{a, b} {
+			got := s.Elements()
+			slices.Sort(got)
+			if !slices.Equal(got, c.want) {
+				t.Errorf("%s: elements %q, want %q", c.name, got, c.want)
+			}
+		}
+	}
+}
+
+// TestJSON checks that a state survives encoding and that decoding keeps
+// the receiver's replica id.
+func TestJSON(t *testing.T) {
+	g := crdt.NewGCounter("a")
+	g.Inc(3)
+	got := ship(t, g, crdt.NewGCounter("b"))
+	got.Inc(1)
+	if got.Value() != 4 || g.Value() != 3 {
+		t.Errorf("decoded counter %d, original %d; want 4 and 3", got.Value(), g.Value())
+	}
+	g.Merge(got)
+	if g.Value() != 4 {
+		t.Errorf("merged %d, want b's increment counted once", g.Value())
+	}
+
+	pn := crdt.NewPNCounter("a")
+	pn.Inc(5)
+	pn.Dec(7)
+	var decoded crdt.PNCounter
+	if ship(t, pn, &decoded); !decoded.Equal(pn) || decoded.Value() != -2 {
+		t.Errorf("decoded PN-Counter %d, want -2", decoded.Value())
+	}
+
+	// elements of any type encoding/json handles, not only map keys
+	type point struct{ X, Y int }
+	s := crdt.NewORSet[point]("a")
+	s.Add(point{1, 2})
+	s.Add(point{3, 4})
+	s.Remove(point{1, 2})
+	into := crdt.NewORSet[point]("b")
+	if ship(t, s, into); !into.Equal(s) || !into.Contains(point{3, 4}) || into.Contains(point{1, 2}) {
+		t.Errorf("decoded set %v", into.Elements())
+	}
+	into.Add(point{5, 6})
+	s.Merge(into)
+	if s.Len() != 2 {
+		t.Errorf("merge after decoding: %v", s.Elements())
+	}
+
+	if err := json.Unmarshal([]byte(`{"a": -1}`), g); err == nil {
+		t.Error("decoded a negative count")
+	}
+	if b, _ := json.Marshal(crdt.NewGCounter("a")); string(b) != "{}" {
+		t.Errorf("empty counter encodes as %s", b)
+	}
+}
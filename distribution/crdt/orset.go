@@ -0,0 +1,137 @@
+package crdt
+
+import (
+	"encoding/json"
+	"maps"
+	"slices"
+)
+
+// dot identifies one Add: the replica that made it and its sequence
+// number there.
+type dot struct {
+	Replica string `json:"r"`
+	Seq     uint64 `json:"s"`
+}
+
+// OR-Set (observed-remove set)
+// Level: Good
+// pros: elements are added and removed on any replica; a Remove takes out
+// only the adds it has seen, so an Add concurrent with it survives
+// (add wins) instead of being lost.
+// cons: every live element carries the ids of the adds behind it, and
+// the state a version vector of every replica that ever added.
+//
+// Removes need no tombstones: the version vector records which adds a
+// replica has seen, so a dot it has seen but no longer holds was removed.
+type ORSet[E comparable] struct {
+	id      string
+	clock   map[string]uint64 // highest dot seen per replica
+	entries map[E]map[dot]struct{}
+}
+
+// NewORSet returns a set for the replica id, which must be unique among
+// the replicas.
+func NewORSet[E comparable](id string) *ORSet[E] {
+	return &ORSet[E]{id: id, clock: map[string]uint64{}, entries: map[E]map[dot]struct{}{}}
+}
+
+// Add inserts e, superseding the adds of e this replica has seen.
+func (s *ORSet[E]) Add(e E) {
+	s.clock[s.id]++
+	s.entries[e] = map[dot]struct{}{{Replica: s.id, Seq: s.clock[s.id]}: {}}
+}
+
+// Remove deletes e as far as this replica has seen it added.
+func (s *ORSet[E]) Remove(e E) { delete(s.entries, e) }
+
+func (s *ORSet[E]) Contains(e E) bool {
+	_, ok := s.entries[e]
+	return ok
+}
+
+func (s *ORSet[E]) Len() int { return len(s.entries) }
+
+// Elements returns the elements in no particular order.
+func (s *ORSet[E]) Elements() []E {
+	return slices.Collect(maps.Keys(s.entries))
+}
+
+// Merge adds what other knows: a dot survives if both replicas hold it,
+// or if one holds it and the other has never seen it; a dot one replica
+// has seen and dropped was removed there.
+func (s *ORSet[E]) Merge(other *ORSet[E]) {
+	if s.entries == nil {
+		s.clock, s.entries = map[string]uint64{}, map[E]map[dot]struct{}{}
+	}
+	for e := range s.entries {
+		dots := s.entries[e]
+		for d := range dots {
+			_, theirs := other.entries[e][d]
+			if !theirs && d.Seq <= other.clock[d.Replica] {
+				delete(dots, d)
+			}
+		}
+	}
+	for e, theirs := range other.entries {
+		for d := range theirs {
+			if _, ok := s.entries[e][d]; ok || d.Seq <= s.clock[d.Replica] {
+				continue
+			}
+			if s.entries[e] == nil {
+				s.entries[e] = map[dot]struct{}{}
+			}
+			s.entries[e][d] = struct{}{}
+		}
+	}
+	maps.DeleteFunc(s.entries, func(_ E, dots map[dot]struct{}) bool { return len(dots) == 0 })
+	for r, n := range other.clock {
+		s.clock[r] = max(s.clock[r], n)
+	}
+}
+
+// Equal reports whether s and other hold the same state.
+func (s *ORSet[E]) Equal(other *ORSet[E]) bool {
+	return maps.Equal(s.clock, other.clock) &&
+		maps.EqualFunc(s.entries, other.entries, func(a, b map[dot]struct{}) bool { return maps.Equal(a, b) })
+}
+
+type orsetState[E comparable] struct {
+	Clock   map[string]uint64 `json:"clock"`
+	Entries []orsetEntry[E]   `json:"entries"`
+}
+
+type orsetEntry[E comparable] struct {
+	Elem E     `json:"elem"`
+	Dots []dot `json:"dots"`
+}
+
+// MarshalJSON encodes the state, without the replica id. Elements are
+// listed rather than used as object keys, so E may be any type
+// encoding/json handles.
+func (s *ORSet[E]) MarshalJSON() ([]byte, error) {
+	state := orsetState[E]{Clock: s.clock, Entries: []orsetEntry[E]{}}
+	for e, dots := range s.entries {
+		state.Entries = append(state.Entries, orsetEntry[E]{Elem: e, Dots: slices.Collect(maps.Keys(dots))})
+	}
+	return json.Marshal(state)
+}
+
+// UnmarshalJSON replaces the state, keeping the replica id.
+func (s *ORSet[E]) UnmarshalJSON(b []byte) error {
+	var state orsetState[E]
+	if err := json.Unmarshal(b, &state); err != nil {
+		return err
+	}
+	s.clock, s.entries = state.Clock, make(map[E]map[dot]struct{}, len(state.Entries))
+	if s.clock == nil {
+		s.clock = map[string]uint64{}
+	}
+	for _, entry := range state.Entries {
+		dots := make(map[dot]struct{}, len(entry.Dots))
+		for _, d := range entry.Dots {
+			dots[d] = struct{}{}
+		}
+		s.entries[entry.Elem] = dots
+	}
+	return nil
+}
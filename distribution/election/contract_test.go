@@ -0,0 +1,27 @@
+package election_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"patterns/clock"
+	"patterns/distribution/election"
+	"patterns/persistence/repository"
+	"patterns/testing/contracts"
+)
+
+func TestLeasesOnMemoryContract(t *testing.T) {
+	contracts.RunLockerContract(t, func(_ testing.TB, c clock.Clock) election.Store {
+		return election.NewLeases(repository.NewMemory[string, election.Lease](), c)
+	})
+}
+
+func TestLeasesOnFileContract(t *testing.T) {
+	contracts.RunLockerContract(t, func(t testing.TB, c clock.Clock) election.Store {
+		r, err := repository.OpenFile[string, election.Lease](filepath.Join(t.TempDir(), "leases.json"))
+		if err != nil {
+			t.Fatalf("OpenFile: %v", err)
+		}
+		return election.NewLeases(r, c)
+	})
+}
@@ -0,0 +1,299 @@
+// Package election picks one leader among candidates with a lease: the
+// candidate holding an unexpired lease leads, renews it while it can, and
+// steps down as soon as it can no longer prove it still holds it.
+//
+//	leases := election.NewLeases(repository.NewMemory[string, election.Lease](), nil)
+//	e, _ := election.New(leases, "scheduler", hostname,
+//		election.WithOnElected(func(ctx context.Context, term uint64) {
+//			runScheduler(ctx) // until ctx is done: leadership lost
+//		}))
+//	err := e.Run(ctx)
+//
+// A follower stands by: it polls the lease every renewal interval, and
+// wakes at the exact moment the lease expires when that comes sooner, so
+// it takes over as soon as a crashed leader's lease runs out, and at the
+// next poll after a leader that stopped cleanly released it.
+//
+// The leader times its lease from before its Acquire call, not from the
+// store's answer, and gives it up one renewal interval early: a leader
+// whose renewals fail cancels the OnElected context a full interval
+// before a follower can get the lease, time for the work to stop. Clocks
+// must therefore run at about the same rate; leases tolerate them being
+// set apart, but not drifting by an interval within a TTL.
+package election
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"patterns/clock"
+	"patterns/construct"
+	"patterns/funcopts"
+)
+
+type options struct {
+	ttl       time.Duration
+	renew     time.Duration
+	clock     clock.Clock
+	onElected func(ctx context.Context, term uint64)
+	onDemoted func()
+	onLeader  func(holder string)
+}
+
+type Option = funcopts.Option[options]
+
+// WithTTL sets how long a lease lasts without renewal, and so how long a
+// crashed leader holds up its successor; the default is 15s.
+func WithTTL(d time.Duration) Option {
+	return func(options *options) error {
+		if d <= 0 {
+			return errors.New("ttl must be positive")
+		}
+		options.ttl = d
+		return nil
+	}
+}
+
+// WithRenewInterval sets how often the leader renews and a follower
+// polls, and how long before its lease expires a leader that cannot renew
+// steps down; the default is a third of the TTL.
+func WithRenewInterval(d time.Duration) Option {
+	return func(options *options) error {
+		if d <= 0 {
+			return errors.New("renew interval must be positive")
+		}
+		options.renew = d
+		return nil
+	}
+}
+
+func WithClock(c clock.Clock) Option {
+	return func(options *options) error {
+		if c == nil {
+			return errors.New("clock cannot be nil")
+		}
+		options.clock = c
+		return nil
+	}
+}
+
+// WithOnElected sets the work to do while leading. It runs in its own
+// goroutine with a context that is done once leadership is lost or Run
+// returns, and must return promptly then: the elector waits for it before
+// calling OnDemoted.
+func WithOnElected(fn func(ctx context.Context, term uint64)) Option {
+	return func(options *options) error {
+		if fn == nil {
+			return errors.New("on elected cannot be nil")
+		}
+		options.onElected = fn
+		return nil
+	}
+}
+
+// WithOnDemoted sets a func called after leadership is lost and the
+// OnElected work has returned.
+func WithOnDemoted(fn func()) Option {
+	return func(options *options) error {
+		if fn == nil {
+			return errors.New("on demoted cannot be nil")
+		}
+		options.onDemoted = fn
+		return nil
+	}
+}
+
+// WithOnLeader sets a func called whenever the elector sees the lease
+// pass to another holder, this candidate included.
+func WithOnLeader(fn func(holder string)) Option {
+	return func(options *options) error {
+		if fn == nil {
+			return errors.New("on leader cannot be nil")
+		}
+		options.onLeader = fn
+		return nil
+	}
+}
+
+func (o *options) SetDefaults() {
+	o.ttl = 15 * time.Second
+	o.clock = clock.Real
+	o.onElected = func(context.Context, uint64) {}
+	o.onDemoted = func() {}
+	o.onLeader = func(string) {}
+}
+
+func (o *options) Validate() error {
+	if o.renew == 0 {
+		o.renew = o.ttl / 3
+	}
+	// a leader renews every interval and steps down an interval before
+	// expiry: it needs room for one renewal in between
+	if 2*o.renew >= o.ttl {
+		return errors.New("renew interval must be shorter than half the ttl")
+	}
+	return nil
+}
+
+// leader election pattern
+// Level: Good
+// pros: exactly one candidate does the work at a time, and a crashed
+// leader is replaced after one TTL with no coordinator; terms fence off a
+// deposed leader's late writes.
+// cons: failover takes up to a TTL, during which nobody leads; safety
+// rests on the store's atomic Acquire and on clocks that do not drift.
+type Elector struct {
+	store Store
+	name  string
+	id    string
+
+	options options
+
+	mu     sync.Mutex
+	leader string
+	term   uint64 // while leading, else 0
+}
+
+// New returns an elector for candidate id competing for the lease called
+// name in store.
+func New(store Store, name, id string, opts ...Option) (*Elector, error) {
+	if store == nil {
+		return nil, errors.New("store cannot be nil")
+	}
+	if name == "" || id == "" {
+		return nil, errors.New("name and id cannot be empty")
+	}
+	options, err := construct.New(opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &Elector{store: store, name: name, id: id, options: *options}, nil
+}
+
+// Leader returns the holder the elector last saw, "" if none.
+func (e *Elector) Leader() string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.leader
+}
+
+// Term returns the term this candidate leads in, or 0 while following.
+func (e *Elector) Term() uint64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.term
+}
+
+// Run campaigns until ctx is done, leading whenever it holds the lease,
+// and returns ctx.Err(). On the way out a leader steps down and releases
+// the lease, so a follower takes over at once. Store errors are retried.
+func (e *Elector) Run(ctx context.Context) error {
+	var reign *reign
+	defer func() {
+		if reign != nil {
+			e.demote(reign)
+			// ctx is done, but the release should still reach the store;
+			// if it fails the lease expires anyway
+			_ = e.store.Release(context.WithoutCancel(ctx), e.name, e.id)
+		}
+	}()
+
+	opts := &e.options
+	for {
+		start := opts.clock.Now()
+		if reign != nil && !start.Before(reign.deadline) {
+			// the renewals failed until one interval before expiry
+			e.demote(reign)
+			reign = nil
+		}
+		actx, cancel := ctx, context.CancelFunc(func() {})
+		if reign != nil {
+			// a renewal that outlives the lease is worthless
+			actx, cancel = context.WithDeadline(ctx, reign.deadline)
+		}
+		lease, err := e.store.Acquire(actx, e.name, e.id, opts.ttl)
+		cancel()
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		wait := opts.renew
+		switch {
+		case err != nil:
+			// retry; the deadline check above ends a failing reign
+		case lease.Holder == e.id:
+			if reign != nil && reign.term != lease.Term {
+				// the lease lapsed and was granted again: a new term
+				e.demote(reign)
+				reign = nil
+			}
+			if reign == nil {
+				reign = e.elect(ctx, lease.Term)
+			}
+			reign.deadline = start.Add(opts.ttl - opts.renew)
+		default:
+			if reign != nil {
+				e.demote(reign)
+				reign = nil
+			}
+			e.observe(lease.Holder)
+			// stand by until the lease can be taken
+			wait = min(opts.renew, max(lease.Expires.Sub(opts.clock.Now()), opts.renew/10))
+		}
+		if reign != nil {
+			wait = min(wait, max(reign.deadline.Sub(opts.clock.Now()), 0))
+		}
+
+		t := opts.clock.NewTimer(wait)
+		select {
+		case <-t.C():
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+// reign is one term of leadership.
+type reign struct {
+	term     uint64
+	deadline time.Time // step down then, an interval before the lease expires
+	cancel   context.CancelFunc
+	done     chan struct{}
+}
+
+func (e *Elector) elect(ctx context.Context, term uint64) *reign {
+	e.mu.Lock()
+	e.term = term
+	e.mu.Unlock()
+	e.observe(e.id)
+
+	ctx, cancel := context.WithCancel(ctx)
+	r := &reign{term: term, cancel: cancel, done: make(chan struct{})}
+	go func() {
+		defer close(r.done)
+		e.options.onElected(ctx, term)
+	}()
+	return r
+}
+
+func (e *Elector) demote(r *reign) {
+	r.cancel()
+	<-r.done
+	e.mu.Lock()
+	e.term = 0
+	e.mu.Unlock()
+	e.options.onDemoted()
+}
+
+func (e *Elector) observe(holder string) {
+	e.mu.Lock()
+	changed := e.leader != holder
+	e.leader = holder
+	e.mu.Unlock()
+	if changed {
+		e.options.onLeader(holder)
+	}
+}
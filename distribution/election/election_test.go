@@ -0,0 +1,243 @@
+package election_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"patterns/clock"
+	"patterns/distribution/election"
+	"patterns/persistence/repository"
+)
+
+// partitioned cuts one candidate off from the store: while cut, every
+// call fails as a lost connection would.
+type partitioned struct {
+	election.Store
+	cut atomic.Bool
+}
+
+var errCut = errors.New("store unreachable")
+
+func (p *partitioned) Acquire(ctx context.Context, name, holder string, ttl time.Duration) (election.Lease, error) {
+	if p.cut.Load() {
+		return election.Lease{}, errCut
+	}
+	return p.Store.Acquire(ctx, name, holder, ttl)
+}
+
+func (p *partitioned) Release(ctx context.Context, name, holder string) error {
+	if p.cut.Load() {
+		return errCut
+	}
+	return p.Store.Release(ctx, name, holder)
+}
+
+// cluster runs candidates over one lease store on a fake clock and logs
+// what each of them sees, timed from the start.
+type cluster struct {
+	t      *testing.T
+	clk    *clock.Fake
+	start  time.Time
+	leases election.Store
+
+	mu      sync.Mutex
+	log     []string
+	running int
+}
+
+func newCluster(t *testing.T) *cluster {
+	// the clock starts now: renewals are bounded by real-time deadlines
+	clk := clock.NewFake(time.Now())
+	return &cluster{t: t, clk: clk, start: clk.Now(), leases: election.NewLeases(repository.NewMemory[string, election.Lease](), clk)}
+}
+
+func (c *cluster) logf(format string, args ...any) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.log = append(c.log, fmt.Sprintf(format, args...)+fmt.Sprintf(" at %v", c.clk.Since(c.start)))
+}
+
+type candidate struct {
+	*election.Elector
+	store *partitioned
+	stop  func() error
+}
+
+// join starts candidate id, with a 15s lease renewed every 5s, and waits
+// for its first wait.
+func (c *cluster) join(id string) *candidate {
+	cand := &candidate{store: &partitioned{Store: c.leases}}
+	e, err := election.New(cand.store, "scheduler", id,
+		election.WithTTL(15*time.Second),
+		election.WithRenewInterval(5*time.Second),
+		election.WithClock(c.clk),
+		election.WithOnLeader(func(holder string) {
+			if holder == id {
+				c.logf("%s leads term %d", id, cand.Term())
+			} else if holder != "" {
+				c.logf("%s follows %s", id, holder)
+			}
+		}),
+		election.WithOnElected(func(ctx context.Context, _ uint64) {
+			<-ctx.Done()
+			c.logf("%s stops", id)
+		}),
+		election.WithOnDemoted(func() { c.logf("%s demoted", id) }))
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	cand.Elector = e
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- e.Run(ctx) }()
+	cand.stop = func() error {
+		cancel()
+		err := <-done
+		c.running--
+		return err
+	}
+	c.running++
+	c.clk.BlockUntil(c.running)
+	return cand
+}
+
+// until advances the clock to at, in half-second steps, letting every
+// candidate act on each step before the next.
+func (c *cluster) until(at time.Duration) {
+	for c.clk.Since(c.start) < at {
+		c.clk.Advance(500 * time.Millisecond)
+		c.clk.BlockUntil(c.running)
+	}
+}
+
+func (c *cluster) expect(want ...string) {
+	c.t.Helper()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !slices.Equal(c.log, want) {
+		c.t.Errorf("log:\n%q\nwant:\n%q", c.log, want)
+	}
+}
+
+// TestLeaderCrash cuts the leader off right after it is elected. It must
+// stop its work a renewal interval before its lease expires, and the
+// follower must take over at the moment of expiry, not at its next poll.
+func TestLeaderCrash(t *testing.T) {
+	c := newCluster(t)
+	a := c.join("a")
+	a.store.cut.Store(true)
+	c.until(2500 * time.Millisecond)
+	b := c.join("b")
+	c.until(17500 * time.Millisecond)
+	c.expect(
+		"a leads term 1 at 0s",
+		"b follows a at 2.5s",
+		"a stops at 10s",
+		"a demoted at 10s",
+		"b leads term 2 at 15s",
+	)
+
+	// back on the network, a finds it has been replaced
+	a.store.cut.Store(false)
+	c.until(20 * time.Second)
+	if a.Term() != 0 || a.Leader() != "b" || b.Term() != 2 || b.Leader() != "b" {
+		t.Errorf("a: term %d leader %q; b: term %d leader %q", a.Term(), a.Leader(), b.Term(), b.Leader())
+	}
+	a.stop()
+	b.stop()
+}
+
+// TestStepDown checks that a leader that stops cleanly releases its lease,
+// so the follower takes over at its next poll rather than at expiry.
+func TestStepDown(t *testing.T) {
+	c := newCluster(t)
+	a := c.join("a")
+	b := c.join("b")
+	c.until(7 * time.Second)
+	if err := a.stop(); !errors.Is(err, context.Canceled) {
+		t.Errorf("Run = %v, want context.Canceled", err)
+	}
+	c.until(12 * time.Second)
+	c.expect(
+		"a leads term 1 at 0s",
+		"b follows a at 0s",
+		"a stops at 7s",
+		"a demoted at 7s",
+		"b leads term 2 at 10s",
+	)
+	b.stop()
+}
+
+// TestNewTerm checks that a leader whose lease lapsed while it was cut
+// off wins it back in a new term, with its work restarted, not resumed.
+func TestNewTerm(t *testing.T) {
+	c := newCluster(t)
+	a := c.join("a")
+	a.store.cut.Store(true)
+	c.until(17500 * time.Millisecond)
+	a.store.cut.Store(false)
+	c.until(19500 * time.Millisecond)
+	if a.Term() != 0 {
+		t.Errorf("term %d at 19.5s, want 0: the lease is up for grabs at 20s", a.Term())
+	}
+	c.until(20 * time.Second)
+	if a.Term() != 2 {
+		t.Errorf("term %d at 20s, want 2", a.Term())
+	}
+	a.stop()
+	// onLeader is silent: the holder did not change
+	c.expect(
+		"a leads term 1 at 0s",
+		"a stops at 10s",
+		"a demoted at 10s",
+		"a stops at 20s",
+		"a demoted at 20s",
+	)
+}
+
+// TestRenewals checks that a leader whose renewals keep succeeding leads
+// one term however long it runs.
+func TestRenewals(t *testing.T) {
+	c := newCluster(t)
+	a := c.join("a")
+	b := c.join("b")
+	c.until(2 * time.Minute)
+	c.expect("a leads term 1 at 0s", "b follows a at 0s")
+	if a.Term() != 1 || b.Term() != 0 {
+		t.Errorf("terms %d and %d, want 1 and 0", a.Term(), b.Term())
+	}
+	b.stop()
+	a.stop()
+}
+
+func TestNew(t *testing.T) {
+	leases := election.NewLeases(repository.NewMemory[string, election.Lease](), nil)
+	for _, c := range []struct {
+		store    election.Store
+		name, id string
+		opts     []election.Option
+		want     string
+	}{
+		{nil, "n", "a", nil, "store cannot be nil"},
+		{leases, "", "a", nil, "name and id cannot be empty"},
+		{leases, "n", "", nil, "name and id cannot be empty"},
+		{leases, "n", "a", []election.Option{election.WithTTL(0)}, "ttl must be positive"},
+		{leases, "n", "a", []election.Option{election.WithRenewInterval(-time.Second)}, "renew interval must be positive"},
+		{leases, "n", "a", []election.Option{election.WithTTL(10 * time.Second), election.WithRenewInterval(5 * time.Second)},
+			"renew interval must be shorter than half the ttl"},
+		{leases, "n", "a", []election.Option{election.WithClock(nil)}, "clock cannot be nil"},
+		{leases, "n", "a", []election.Option{election.WithOnElected(nil)}, "on elected cannot be nil"},
+		{leases, "n", "a", []election.Option{election.WithOnDemoted(nil)}, "on demoted cannot be nil"},
+		{leases, "n", "a", []election.Option{election.WithOnLeader(nil)}, "on leader cannot be nil"},
+	} {
+		if e, err := election.New(c.store, c.name, c.id, c.opts...); e != nil || err == nil || err.Error() != c.want {
+			t.Errorf("New(%q, %q) = %v, %v; want %q", c.name, c.id, e, err, c.want)
+		}
+	}
+}
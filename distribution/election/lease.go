@@ -0,0 +1,86 @@
+package election
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"patterns/clock"
+	"patterns/persistence/repository"
+)
+
+// Lease is the right to lead, held by Holder until Expires. Term grows by
+// one whenever the lease passes to a holder, so it serves as a fencing
+// token: storage that rejects writes carrying an older term is safe even
+// from a deposed leader that has not noticed yet.
+type Lease struct {
+	Name    string    `json:"name"`
+	Holder  string    `json:"holder,omitempty"`
+	Term    uint64    `json:"term"`
+	Expires time.Time `json:"expires"`
+}
+
+// Store grants leases. Acquire must be atomic: two candidates racing for
+// an expired lease may not both get it.
+type Store interface {
+	// Acquire gives the lease called name to holder for ttl if it is free,
+	// expired or already holder's (a renewal), and returns the lease as it
+	// stands afterwards, whoever holds it.
+	Acquire(ctx context.Context, name, holder string, ttl time.Duration) (Lease, error)
+	// Release frees the lease if holder has it, so a follower need not
+	// wait for it to expire.
+	Release(ctx context.Context, name, holder string) error
+}
+
+// Leases is a Store over a repository: repository.NewMemory for one
+// process, repository.OpenFile to keep terms across restarts. The mutex
+// makes Acquire atomic within the process only; leases shared between
+// processes need storage with a conditional update, such as
+// UPDATE leases SET ... WHERE name = ? AND (holder = ? OR expires < ?).
+type Leases struct {
+	mu    sync.Mutex
+	repo  repository.Repository[string, Lease]
+	clock clock.Clock
+}
+
+// NewLeases returns a Store keeping leases in repo and timing them by c
+// (nil means the system clock).
+func NewLeases(repo repository.Repository[string, Lease], c clock.Clock) *Leases {
+	return &Leases{repo: repo, clock: clock.Or(c)}
+}
+
+func (l *Leases) Acquire(ctx context.Context, name, holder string, ttl time.Duration) (Lease, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.clock.Now()
+	cur, err := l.repo.Get(ctx, name)
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		next := Lease{Name: name, Holder: holder, Term: 1, Expires: now.Add(ttl)}
+		return next, l.repo.Create(ctx, name, next)
+	case err != nil:
+		return Lease{}, err
+	case cur.Holder == holder && now.Before(cur.Expires):
+		cur.Expires = now.Add(ttl)
+	case cur.Holder == "" || !now.Before(cur.Expires):
+		cur.Holder, cur.Term, cur.Expires = holder, cur.Term+1, now.Add(ttl)
+	default:
+		return cur, nil
+	}
+	return cur, l.repo.Update(ctx, name, cur)
+}
+
+func (l *Leases) Release(ctx context.Context, name, holder string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	cur, err := l.repo.Get(ctx, name)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil
+	}
+	if err != nil || cur.Holder != holder {
+		return err
+	}
+	cur.Holder, cur.Expires = "", l.clock.Now()
+	return l.repo.Update(ctx, name, cur)
+}
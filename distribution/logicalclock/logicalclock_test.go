@@ -0,0 +1,178 @@
+package logicalclock
+
+import (
+	"math/rand/v2"
+	"strconv"
+	"testing"
+)
+
+// event is one step of a simulated run, with both clocks' stamps and the
+// ground truth: the ids of every event that happened before it.
+type event struct {
+	node    int
+	vector  Vector
+	lamport uint64
+	past    map[int]bool
+}
+
+type message struct {
+	from    int // id of the send event
+	vector  Vector
+	lamport uint64
+}
+
+// simulate runs nodes through steps random local events, sends and
+// receives, message delivery in random order, and returns every event.
+func simulate(r *rand.Rand, nodes, steps int) []event {
+	var (
+		events   []event
+		vectors  = make([]Vector, nodes)
+		lamports = make([]Lamport, nodes)
+		last     = make([]int, nodes) // last event id per node, -1 for none
+		inbox    = make([][]message, nodes)
+	)
+	for i := range last {
+		last[i] = -1
+	}
+	record := func(n int, cause int) event {
+		e := event{node: n, vector: vectors[n].Clone(), lamport: lamports[n].Now(), past: map[int]bool{}}
+		for _, p := range []int{last[n], cause} {
+			if p < 0 {
+				continue
+			}
+			e.past[p] = true
+			for q := range events[p].past {
+				e.past[q] = true
+			}
+		}
+		events = append(events, e)
+		last[n] = len(events) - 1
+		return e
+	}
+	for range steps {
+		n := r.IntN(nodes)
+		id := "n" + strconv.Itoa(n)
+		switch op := r.IntN(3); {
+		case op == 0 || op == 1 && nodes == 1:
+			vectors[n].Tick(id)
+			lamports[n].Tick()
+			record(n, -1)
+		case op == 1:
+			vectors[n].Tick(id)
+			lamports[n].Tick()
+			e := record(n, -1)
+			to := (n + 1 + r.IntN(nodes-1)) % nodes
+			inbox[to] = append(inbox[to], message{len(events) - 1, e.vector, e.lamport})
+		default:
+			if len(inbox[n]) == 0 {
+				continue
+			}
+			i := r.IntN(len(inbox[n]))
+			m := inbox[n][i]
+			inbox[n] = append(inbox[n][:i], inbox[n][i+1:]...)
+			vectors[n].Merge(m.vector)
+			vectors[n].Tick(id)
+			lamports[n].Observe(m.lamport)
+			record(n, m.from)
+		}
+	}
+	return events
+}
+
+// TestHappenedBefore checks, over random runs, that vector clocks order
+// two events exactly as causality does, and that Lamport timestamps are
+// consistent with it.
+func TestHappenedBefore(t *testing.T) {
+	for seed := range uint64(50) {
+		r := rand.New(rand.NewPCG(seed, 0))
+		events := simulate(r, 1+r.IntN(5), 150)
+		for i, a := range events {
+			for j, b := range events {
+				var want Order
+				switch {
+				case i == j:
+					want = OrderEqual
+				case b.past[i]:
+					want = OrderBefore
+				case a.past[j]:
+					want = OrderAfter
+				default:
+					want = OrderConcurrent
+				}
+				if got := a.vector.Compare(b.vector); got != want {
+					t.Fatalf("seed %d: %v.Compare(%v) = %v, want %v", seed, a.vector, b.vector, got, want)
+				}
+				if want == OrderBefore && a.lamport >= b.lamport {
+					t.Fatalf("seed %d: event %d happened before %d, Lamport %d >= %d", seed, i, j, a.lamport, b.lamport)
+				}
+				if a.vector.HappenedBefore(b.vector) != (want == OrderBefore) || a.vector.Concurrent(b.vector) != (want == OrderConcurrent) {
+					t.Fatalf("seed %d: HappenedBefore or Concurrent disagrees with Compare for %v, %v", seed, a.vector, b.vector)
+				}
+			}
+		}
+	}
+}
+
+// TestLamportHidesConcurrency checks the converse the package doc warns
+// of: Lamport timestamps rank concurrent events anyway.
+func TestLamportHidesConcurrency(t *testing.T) {
+	ranked := 0
+	r := rand.New(rand.NewPCG(1, 1))
+	events := simulate(r, 3, 150)
+	for _, a := range events {
+		for _, b := range events {
+			if a.vector.Concurrent(b.vector) && a.lamport < b.lamport {
+				ranked++
+			}
+		}
+	}
+	if ranked == 0 {
+		t.Error("no concurrent pair got different Lamport timestamps; the run has no concurrency to show")
+	}
+}
+
+// TestMerge checks that Merge is the least upper bound: commutative,
+// idempotent, and after both operands.
+func TestMerge(t *testing.T) {
+	r := rand.New(rand.NewPCG(2, 2))
+	random := func() Vector {
+		v := Vector{}
+		for _, n := range []string{"a", "b", "c"} {
+			if k := r.IntN(4); k > 0 {
+				v[n] = uint64(k)
+			}
+		}
+		return v
+	}
+	for range 500 {
+		a, b := random(), random()
+		ab, ba := a.Clone(), b.Clone()
+		ab.Merge(b)
+		ba.Merge(a)
+		if ab.Compare(ba) != OrderEqual {
+			t.Fatalf("%v merged with %v = %v, the other way %v", a, b, ab, ba)
+		}
+		if o := a.Compare(ab); o != OrderBefore && o != OrderEqual {
+			t.Fatalf("%v is %v its merge with %v, %v", a, o, b, ab)
+		}
+		again := ab.Clone()
+		again.Merge(b)
+		if again.Compare(ab) != OrderEqual {
+			t.Fatalf("merging %v twice changed %v to %v", b, ab, again)
+		}
+	}
+	var zero Vector
+	zero.Merge(nil)
+	if zero != nil {
+		t.Errorf("merging nil into the zero Vector = %v, want nil", zero)
+	}
+}
+
+func TestVectorString(t *testing.T) {
+	if got := (Vector{"b": 1, "a": 2}).String(); got != "{a:2 b:1}" {
+		t.Errorf("String() = %q", got)
+	}
+	if got := Vector(nil).Compare(Vector{"a": 0}); got != OrderEqual {
+		t.Errorf("nil vs explicit zero = %v, want equal", got)
+	}
+}
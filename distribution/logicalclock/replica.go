@@ -0,0 +1,130 @@
+package logicalclock
+
+import (
+	"slices"
+	"sync"
+)
+
+// Version is one value of a replicated register. Its write is the event
+// Dot, and Context is the clock of what the writer had read, without the
+// write itself: a dotted version vector.
+type Version struct {
+	Value   string
+	Dot     Dot
+	Context Vector
+}
+
+// Dot names one write: the Counter-th event at Node.
+type Dot struct {
+	Node    string
+	Counter uint64
+}
+
+// Clock returns the vector clock of v's write, its context ticked at
+// its node. Context and Dot are what decide which versions supersede
+// which: two writes from the same stale context get ordered clocks, {a:2}
+// and {a:3}, but neither context holds the other's dot.
+func (v Version) Clock() Vector {
+	c := v.Context.Clone()
+	c.Merge(Vector{v.Dot.Node: v.Dot.Counter})
+	return c
+}
+
+// Descends reports whether v's writer had seen other, or v is other, so
+// that v supersedes it.
+func (v Version) Descends(other Version) bool {
+	return v.Dot == other.Dot || v.Context[other.Dot.Node] >= other.Dot.Counter
+}
+
+func (v Version) clone() Version {
+	return Version{Value: v.Value, Dot: v.Dot, Context: v.Context.Clone()}
+}
+
+// Replica holds a register replicated without a merge function (no
+// CRDT): writes that supersede others replace them, and concurrent writes
+// are kept as siblings until a write that has seen them all resolves
+// them, as in Dynamo.
+type Replica struct {
+	id string
+
+	mu       sync.Mutex
+	counter  uint64 // this replica's entry in the clocks it stamps
+	versions []Version
+}
+
+func NewReplica(id string) *Replica { return &Replica{id: id} }
+
+// Get returns the current values, more than one after concurrent writes,
+// and the context to pass to the Put that resolves them: the merge of
+// their clocks.
+func (r *Replica) Get() (values []string, context Vector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	context = Vector{}
+	for _, v := range r.versions {
+		values = append(values, v.Value)
+		context.Merge(v.Clock())
+	}
+	return values, context
+}
+
+// Put writes value on behalf of a client that last read context from Get
+// (nil for a blind write). The write supersedes exactly the versions that
+// context covers; the others remain as siblings.
+func (r *Replica) Put(value string, context Vector) Version {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	// two clients writing through this replica with the same context get
+	// distinct dots, and stay siblings
+	r.counter = max(r.counter, context[r.id]) + 1
+	v := Version{Value: value, Dot: Dot{r.id, r.counter}, Context: context.Clone()}
+	r.add(v)
+	return v.clone()
+}
+
+// Receive applies versions replicated from another replica and reports
+// how many of them were concurrent with a version already here, so that
+// both are now kept as siblings.
+func (r *Replica) Receive(versions ...Version) (conflicts int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, v := range versions {
+		r.counter = max(r.counter, v.Clock()[r.id])
+		if r.add(v.clone()) {
+			conflicts++
+		}
+	}
+	return conflicts
+}
+
+// add stores v unless a version here already covers it, drops the
+// versions v covers, and reports whether v joined concurrent siblings.
+func (r *Replica) add(v Version) (concurrent bool) {
+	for _, have := range r.versions {
+		if have.Descends(v) {
+			return false
+		}
+	}
+	r.versions = slices.DeleteFunc(r.versions, v.Descends)
+	concurrent = len(r.versions) > 0
+	r.versions = append(r.versions, v)
+	return concurrent
+}
+
+// Versions returns a copy of what the replica holds, to replicate.
+func (r *Replica) Versions() []Version {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make([]Version, len(r.versions))
+	for i, v := range r.versions {
+		out[i] = v.clone()
+	}
+	return out
+}
+
+// Sync replicates a and b to each other and returns how many versions
+// became siblings, on either side.
+func Sync(a, b *Replica) (conflicts int) {
+	av, bv := a.Versions(), b.Versions()
+	return a.Receive(bv...) + b.Receive(av...)
+}
@@ -0,0 +1,153 @@
+package logicalclock_test
+
+import (
+	"math/rand/v2"
+	"slices"
+	"strconv"
+	"testing"
+
+	"patterns/distribution/logicalclock"
+)
+
+func values(t *testing.T, label string, want []string, rs ...*logicalclock.Replica) {
+	t.Helper()
+	for i, r := range rs {
+		got, _ := r.Get()
+		slices.Sort(got)
+		if !slices.Equal(got, want) {
+			t.Errorf("%s: replica %d holds %q, want %q", label, i, got, want)
+		}
+	}
+}
+
+// TestReplicaWrites runs two replicas through a sequential and a
+// concurrent pair of writes and then a resolving one.
+func TestReplicaWrites(t *testing.T) {
+	a, b := logicalclock.NewReplica("a"), logicalclock.NewReplica("b")
+
+	// sequential: the second write has read the first, so it supersedes it
+	a.Put("cart=[book]", nil)
+	logicalclock.Sync(a, b)
+	_, ctx := b.Get()
+	b.Put("cart=[book pen]", ctx)
+	if n := logicalclock.Sync(a, b); n != 0 {
+		t.Errorf("sequential writes: %d conflicts, want 0", n)
+	}
+	values(t, "sequential", []string{"cart=[book pen]"}, a, b)
+
+	// concurrent: two clients read the same version, then write through
+	// different replicas before they sync
+	_, ctx = a.Get()
+	a.Put("cart=[book pen lamp]", ctx)
+	b.Put("cart=[book]", ctx)
+	if n := logicalclock.Sync(a, b); n != 2 {
+		t.Errorf("concurrent writes: %d conflicts, want one on each side", n)
+	}
+	values(t, "concurrent", []string{"cart=[book pen lamp]", "cart=[book]"}, a, b)
+
+	// the next write that has seen both resolves them
+	_, ctx = a.Get()
+	a.Put("cart=[book lamp]", ctx)
+	if n := logicalclock.Sync(a, b); n != 0 {
+		t.Errorf("resolving write: %d conflicts, want 0", n)
+	}
+	values(t, "resolved", []string{"cart=[book lamp]"}, a, b)
+}
+
+// TestReplicaSameContext checks that two writes through one replica with
+// the same context are siblings, not one silently replacing the other.
+func TestReplicaSameContext(t *testing.T) {
+	r := logicalclock.NewReplica("a")
+	r.Put("v1", nil)
+	_, ctx := r.Get()
+	x, y := r.Put("x", ctx), r.Put("y", ctx)
+	if x.Descends(y) || y.Descends(x) {
+		t.Errorf("versions %+v and %+v, want neither descending from the other", x, y)
+	}
+	values(t, "same context", []string{"x", "y"}, r)
+}
+
+// TestReceiveStale checks that replaying old versions changes nothing.
+func TestReceiveStale(t *testing.T) {
+	a, b := logicalclock.NewReplica("a"), logicalclock.NewReplica("b")
+	old := a.Put("old", nil)
+	_, ctx := a.Get()
+	a.Put("new", ctx)
+	logicalclock.Sync(a, b)
+	if n := b.Receive(old); n != 0 {
+		t.Errorf("stale version: %d conflicts, want 0", n)
+	}
+	values(t, "stale", []string{"new"}, a, b)
+}
+
+// TestLamportLosesWrite stamps a concurrent pair of writes with both
+// clocks: the vectors see the conflict, while last writer wins by
+// Lamport timestamp, ties broken by node id, keeps one write.
+func TestLamportLosesWrite(t *testing.T) {
+	var la, lb logicalclock.Lamport
+	var va, vb logicalclock.Vector
+	va.Tick("a")
+	lb.Observe(la.Tick())
+	vb.Merge(va)
+	vb.Tick("b")
+
+	// a and b now write without hearing from each other
+	ta, tb := la.Tick(), lb.Tick()
+	va.Tick("a")
+	vb.Tick("b")
+	if !va.Concurrent(vb) {
+		t.Fatalf("vectors %v and %v, want concurrent", va, vb)
+	}
+	type stamp struct {
+		t    uint64
+		node string
+	}
+	a, b := stamp{ta, "a"}, stamp{tb, "b"}
+	later := func(x, y stamp) bool { return x.t > y.t || x.t == y.t && x.node > y.node }
+	if later(a, b) == later(b, a) {
+		t.Errorf("Lamport stamps %v and %v, want one strictly later", a, b)
+	}
+}
+
+// TestReplicasConverge runs random reads, writes and pairwise syncs over
+// three replicas: once all have synced they hold the same values, and a
+// write that read all of them leaves one.
+func TestReplicasConverge(t *testing.T) {
+	for seed := range uint64(20) {
+		r := rand.New(rand.NewPCG(seed, 0))
+		rs := []*logicalclock.Replica{
+			logicalclock.NewReplica("a"), logicalclock.NewReplica("b"), logicalclock.NewReplica("c"),
+		}
+		var contexts []logicalclock.Vector
+		for i := range 200 {
+			switch x := rs[r.IntN(3)]; r.IntN(3) {
+			case 0:
+				_, ctx := x.Get()
+				contexts = append(contexts, ctx)
+			case 1:
+				// a client writing from some earlier read, maybe stale
+				var ctx logicalclock.Vector
+				if len(contexts) > 0 {
+					ctx = contexts[r.IntN(len(contexts))]
+				}
+				x.Put("v"+strconv.Itoa(i), ctx)
+			default:
+				logicalclock.Sync(x, rs[r.IntN(3)])
+			}
+		}
+		syncAll := func() {
+			logicalclock.Sync(rs[0], rs[1])
+			logicalclock.Sync(rs[1], rs[2])
+			logicalclock.Sync(rs[0], rs[1])
+		}
+		syncAll()
+		want, _ := rs[0].Get()
+		slices.Sort(want)
+		values(t, "converged", want, rs...)
+
+		_, ctx := rs[2].Get()
+		rs[2].Put("resolved", ctx)
+		syncAll()
+		values(t, "resolved", []string{"resolved"}, rs...)
+	}
+}
@@ -0,0 +1,106 @@
+package logicalclock
+
+import (
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+//go:generate go run patterns/cmd/enumgen -type=Order -trimprefix=Order
+
+// Order is how two vector clocks, and the events they stamp, relate.
+type Order int
+
+const (
+	OrderEqual Order = iota
+	// OrderBefore: the first happened before the second.
+	OrderBefore
+	// OrderAfter: the second happened before the first.
+	OrderAfter
+	// OrderConcurrent: neither knew of the other.
+	OrderConcurrent
+)
+
+// vector clock
+// Level: Good
+// pros: compares exactly: before, after or concurrent, so conflicting
+// updates are detected instead of ordered at random.
+// cons: one counter per node that ever wrote, carried by every value and
+// message; needs pruning when nodes come and go.
+//
+// A Vector maps node ids to counters; a missing node counts as 0. The
+// zero value is the empty clock, ready for Tick. Tick and Merge change
+// the clock in place: Clone before handing one to another owner.
+type Vector map[string]uint64
+
+// Tick records an event on node.
+func (v *Vector) Tick(node string) {
+	if *v == nil {
+		*v = Vector{}
+	}
+	(*v)[node]++
+}
+
+// Merge raises every counter of v to at least other's: v then covers
+// every event either one knew of. A node merges the clock of a message it
+// receives, then ticks.
+func (v *Vector) Merge(other Vector) {
+	if *v == nil && len(other) > 0 {
+		*v = make(Vector, len(other))
+	}
+	for node, n := range other {
+		(*v)[node] = max((*v)[node], n)
+	}
+}
+
+func (v Vector) Clone() Vector { return maps.Clone(v) }
+
+// Compare reports how v relates to other.
+func (v Vector) Compare(other Vector) Order {
+	less, greater := false, false
+	for node, n := range v {
+		switch m := other[node]; {
+		case n < m:
+			less = true
+		case n > m:
+			greater = true
+		}
+	}
+	for node, m := range other {
+		if _, ok := v[node]; !ok && m > 0 {
+			less = true
+		}
+	}
+	switch {
+	case less && greater:
+		return OrderConcurrent
+	case less:
+		return OrderBefore
+	case greater:
+		return OrderAfter
+	}
+	return OrderEqual
+}
+
+// HappenedBefore reports whether v is strictly before other.
+func (v Vector) HappenedBefore(other Vector) bool { return v.Compare(other) == OrderBefore }
+
+// Concurrent reports whether neither of v and other is before the other.
+func (v Vector) Concurrent(other Vector) bool { return v.Compare(other) == OrderConcurrent }
+
+// String formats v with its nodes in order, as {a:2 b:1}.
+func (v Vector) String() string {
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, node := range slices.Sorted(maps.Keys(v)) {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(node)
+		b.WriteByte(':')
+		b.WriteString(strconv.FormatUint(v[node], 10))
+	}
+	b.WriteByte('}')
+	return b.String()
+}
@@ -0,0 +1,92 @@
+package sharding
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+// benchKeys are the counters the benchmarks increment, spread over every
+// shard.
+var benchKeys = func() []string {
+	keys := make([]string, 1024)
+	for i := range keys {
+		keys[i] = "user-" + strconv.Itoa(i)
+	}
+	return keys
+}()
+
+func benchRouter(b *testing.B, shards int) *Router[string, map[string]int] {
+	r, err := New(StringHash[string](), func() map[string]int { return map[string]int{} }, WithShards(shards))
+	if err != nil {
+		b.Fatal(err)
+	}
+	return r
+}
+
+func incr(key string) func(*map[string]int) {
+	return func(m *map[string]int) { (*m)[key]++ }
+}
+
+// BenchmarkIncr measures parallel increments of map counters behind one
+// mutex against the router with 1 and 8 shards, through Send and Do.
+func BenchmarkIncr(b *testing.B) {
+	b.Run("global-lock", func(b *testing.B) {
+		var mu sync.Mutex
+		m := map[string]int{}
+		b.RunParallel(func(pb *testing.PB) {
+			i := 0
+			for pb.Next() {
+				mu.Lock()
+				m[benchKeys[i%len(benchKeys)]]++
+				mu.Unlock()
+				i++
+			}
+		})
+	})
+	for _, shards := range []int{1, 8} {
+		suffix := "/" + strconv.Itoa(shards)
+		// one op per key, made before timing: the closures are not what is
+		// measured
+		ops := make([]func(*map[string]int), len(benchKeys))
+		for i, k := range benchKeys {
+			ops[i] = incr(k)
+		}
+		b.Run("send"+suffix, func(b *testing.B) {
+			r := benchRouter(b, shards)
+			ctx := context.Background()
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				i := 0
+				for pb.Next() {
+					k := i % len(benchKeys)
+					if err := r.Send(ctx, benchKeys[k], ops[k]); err != nil {
+						b.Error(err)
+						return
+					}
+					i++
+				}
+			})
+			// queued increments are part of the work
+			r.Close()
+		})
+		b.Run("do"+suffix, func(b *testing.B) {
+			r := benchRouter(b, shards)
+			defer r.Close()
+			ctx := context.Background()
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				i := 0
+				for pb.Next() {
+					k := i % len(benchKeys)
+					if err := r.Do(ctx, benchKeys[k], ops[k]); err != nil {
+						b.Error(err)
+						return
+					}
+					i++
+				}
+			})
+		})
+	}
+}
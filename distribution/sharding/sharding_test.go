@@ -0,0 +1,167 @@
+package sharding
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// log records, per key, the sequence numbers in the order they ran.
+type log map[string][]int
+
+func newLog() log { return log{} }
+
+// TestPerKeyOrder submits numbered operations for many keys from many
+// goroutines, mixing Send and Do, and checks that every key saw its own
+// operations in submission order. Run it with -race: the state is only
+// ever touched by its shard's goroutine.
+func TestPerKeyOrder(t *testing.T) {
+	const (
+		goroutines   = 16
+		keysEach     = 8
+		opsPerKey    = 200
+		shardsToTest = 4
+	)
+	r, err := New(StringHash[string](), newLog, WithShards(shardsToTest), WithQueue(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	for g := range goroutines {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for seq := range opsPerKey {
+				for k := range keysEach {
+					key := strconv.Itoa(g) + "/" + strconv.Itoa(k)
+					op := func(l *log) { (*l)[key] = append((*l)[key], seq) }
+					var err error
+					if (seq+k)%5 == 0 {
+						err = r.Do(ctx, key, op)
+					} else {
+						err = r.Send(ctx, key, op)
+					}
+					if err != nil {
+						t.Error(err)
+						return
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	seen := 0
+	err = r.Each(ctx, func(shard int, l *log) {
+		for key, seqs := range *l {
+			seen++
+			if got := r.ShardOf(key); got != shard {
+				t.Errorf("key %s ran on shard %d, ShardOf says %d", key, shard, got)
+			}
+			for i, seq := range seqs {
+				if seq != i {
+					t.Errorf("key %s: operation %d ran in position %d", key, seq, i)
+					break
+				}
+			}
+			if len(seqs) != opsPerKey {
+				t.Errorf("key %s: %d operations ran, want %d", key, len(seqs), opsPerKey)
+			}
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if seen != goroutines*keysEach {
+		t.Errorf("%d keys seen, want %d", seen, goroutines*keysEach)
+	}
+}
+
+func TestEach(t *testing.T) {
+	r, err := New(StringHash[string](), newLog, WithShards(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	var order []int
+	if err := r.Each(context.Background(), func(shard int, _ *log) { order = append(order, shard) }); err != nil {
+		t.Fatal(err)
+	}
+	if len(order) != 3 || order[0] != 0 || order[1] != 1 || order[2] != 2 {
+		t.Errorf("Each visited shards %v, want [0 1 2]", order)
+	}
+}
+
+// TestCloseDrains checks that Close runs the operations already queued
+// and that later ones are refused.
+func TestCloseDrains(t *testing.T) {
+	r, err := New(StringHash[string](), newLog, WithShards(1), WithQueue(100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	release := make(chan struct{})
+	r.Send(context.Background(), "k", func(*log) { <-release })
+	ran := 0
+	for range 50 {
+		r.Send(context.Background(), "k", func(*log) { ran++ })
+	}
+	closed := make(chan error)
+	go func() { closed <- r.Close() }()
+	close(release)
+	if err := <-closed; err != nil {
+		t.Fatal(err)
+	}
+	if ran != 50 {
+		t.Errorf("%d queued operations ran before Close returned, want 50", ran)
+	}
+	if err := r.Send(context.Background(), "k", func(*log) {}); !errors.Is(err, ErrClosed) {
+		t.Errorf("Send after Close = %v, want ErrClosed", err)
+	}
+	if err := r.Close(); !errors.Is(err, ErrClosed) {
+		t.Errorf("second Close = %v, want ErrClosed", err)
+	}
+}
+
+// TestFullQueue checks that Send waits for room and gives up with ctx.
+func TestFullQueue(t *testing.T) {
+	r, err := New(StringHash[string](), newLog, WithShards(1), WithQueue(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	release := make(chan struct{})
+	started := make(chan struct{})
+	r.Send(context.Background(), "k", func(*log) { close(started); <-release })
+	<-started
+	r.Send(context.Background(), "k", func(*log) {}) // fills the queue
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := r.Send(ctx, "k", func(*log) {}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Send to a full queue = %v, want DeadlineExceeded", err)
+	}
+	if err := r.Do(ctx, "k", func(*log) {}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Do on a full queue = %v, want DeadlineExceeded", err)
+	}
+	close(release)
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	if _, err := New[string](nil, newLog); err == nil {
+		t.Error("New with a nil hash succeeded")
+	}
+	if _, err := New[string, log](StringHash[string](), nil); err == nil {
+		t.Error("New with a nil newState succeeded")
+	}
+	if _, err := New(StringHash[string](), newLog, WithShards(0)); err == nil {
+		t.Error("New(WithShards(0)) succeeded")
+	}
+}
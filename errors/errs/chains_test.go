@@ -0,0 +1,174 @@
+package errs_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"patterns/errors/errs"
+)
+
+// check is one statement about an error, and whether it holds.
+type check struct {
+	what string
+	ok   bool
+}
+
+type chain struct {
+	name   string
+	err    error
+	checks []check
+}
+
+// links returns err and everything it wraps, depth first; for an
+// errors.Join, every branch.
+func links(err error) []error {
+	if err == nil {
+		return nil
+	}
+	out := []error{err}
+	switch u := err.(type) {
+	case interface{ Unwrap() error }:
+		out = append(out, links(u.Unwrap())...)
+	case interface{ Unwrap() []error }:
+		for _, e := range u.Unwrap() {
+			out = append(out, links(e)...)
+		}
+	}
+	return out
+}
+
+func chains(t *testing.T) []chain {
+	var cs []chain
+
+	wrapped := errs.Wrap("load order 7", errs.ErrNoRows)
+	cs = append(cs, chain{"sentinel wrapped with %w", wrapped, []check{
+		{"== no longer matches", !errs.IsNoRowsEq(wrapped)},
+		{"errors.Is finds the sentinel", errs.IsNoRows(wrapped)},
+		{"errors.Unwrap returns the sentinel", errors.Unwrap(wrapped) == errs.ErrNoRows},
+	}})
+
+	flattened := errs.WrapV("load order 7", errs.ErrNoRows)
+	cs = append(cs, chain{"sentinel wrapped with %v", flattened, []check{
+		{"errors.Is cannot find the sentinel", !errs.IsNoRows(flattened)},
+		{"the chain ends at the wrapper", errors.Unwrap(flattened) == nil},
+		{"only the message still says it", errs.IsNoRowsText(flattened)},
+	}})
+
+	lookalike := fmt.Errorf("name %q is taken", "no rows")
+	cs = append(cs, chain{"message matching", lookalike, []check{
+		{"a user's input fools it", errs.IsNoRowsText(lookalike)},
+		{"errors.Is does not", !errs.IsNoRows(lookalike)},
+	}})
+
+	var users errs.Users
+	_, missing := users.Get(7)
+	var e *errs.Error
+	cs = append(cs, chain{"not found", missing, []check{
+		{"errors.Is finds the kind's sentinel", errors.Is(missing, errs.ErrNotFound)},
+		{"and the storage cause below it", errors.Is(missing, errs.ErrNoRows)},
+		{"but not another kind's", !errors.Is(missing, errs.ErrInvalid)},
+		{"errors.As finds the *Error, naming the operation", errors.As(missing, &e) && e.Op == "users.Get"},
+		{"KindOf is NotFound", errs.KindOf(missing) == errs.KindNotFound},
+		{"the status is 404", errs.Status(missing) == http.StatusNotFound},
+	}})
+
+	invalid := users.Create(errs.User{ID: 1, Name: "Ann", Email: "ann.example.com"})
+	var ve *errs.ValidationError
+	cs = append(cs, chain{"invalid", invalid, []check{
+		{"errors.As finds which field", errors.As(invalid, &ve) && ve.Field == "email"},
+		{"errors.Is finds the kind", errors.Is(invalid, errs.ErrInvalid)},
+		{"the status is 400", errs.Status(invalid) == http.StatusBadRequest},
+		{"the client may see why", errs.Public(invalid) == invalid.Error()},
+	}})
+
+	bare := error(&errs.ValidationError{Field: "name", Reason: "is required"})
+	cs = append(cs, chain{"unclassified validation error", bare, []check{
+		{"its Is method makes it Invalid", errors.Is(bare, errs.ErrInvalid)},
+		{"KindOf agrees without an *Error", errs.KindOf(bare) == errs.KindInvalid},
+	}})
+
+	lost := errors.New("dial tcp db-1:5432: connection refused")
+	users.Fail(lost)
+	_, internal := users.Get(1)
+	users.Fail(nil)
+	cs = append(cs, chain{"internal", internal, []check{
+		{"the cause stays in the chain for the logs", errors.Is(internal, lost)},
+		{"KindOf is Internal", errs.KindOf(internal) == errs.KindInternal},
+		{"the client sees nothing of the cause", !strings.Contains(errs.Public(internal), "db-1")},
+	}})
+
+	unknown := errors.New("something else")
+	cs = append(cs, chain{"unclassified", unknown, []check{
+		{"KindOf calls it Internal", errs.KindOf(unknown) == errs.KindInternal},
+		{"the status is 500", errs.Status(unknown) == http.StatusInternalServerError},
+	}})
+
+	reclassified := errs.E("config.Load", errs.KindInternal, missing)
+	cs = append(cs, chain{"reclassified", reclassified, []check{
+		{"KindOf believes the outermost: Internal", errs.KindOf(reclassified) == errs.KindInternal},
+		{"errors.Is still finds the inner NotFound", errors.Is(reclassified, errs.ErrNotFound)},
+		{"the status is 500", errs.Status(reclassified) == http.StatusInternalServerError},
+	}})
+
+	joined := errors.Join(unknown, invalid)
+	cs = append(cs, chain{"joined", joined, []check{
+		{"errors.As finds the *Error in the second branch", errors.As(joined, &e) && e.Kind == errs.KindInvalid},
+		{"KindOf is its kind", errs.KindOf(joined) == errs.KindInvalid},
+	}})
+
+	cs = append(cs, httpChains(t)...)
+	return cs
+}
+
+// httpChains checks what a client of Handler sees.
+func httpChains(t *testing.T) []chain {
+	var users errs.Users
+	if err := users.Create(errs.User{ID: 1, Name: "Ann", Email: "ann@example.com"}); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	h := errs.Handler(&users)
+	do := func(method, target, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(method, target, strings.NewReader(body)))
+		return rec
+	}
+
+	found, missing, badID := do("GET", "/users/1", ""), do("GET", "/users/7", ""), do("GET", "/users/x", "")
+	invalid := do("POST", "/users", `{"id": 2, "name": "Bo", "email": "bo"}`)
+	users.Fail(errors.New("dial tcp db-1:5432: connection refused"))
+	down := do("GET", "/users/1", "")
+	return []chain{
+		{"http", fmt.Errorf("GET /users/7: %d %s", missing.Code, strings.TrimSpace(missing.Body.String())), []check{
+			{"a user is 200", found.Code == http.StatusOK},
+			{"a missing user is 404 with its message", missing.Code == http.StatusNotFound && strings.Contains(missing.Body.String(), "user 7")},
+			{"a bad id is 400", badID.Code == http.StatusBadRequest},
+			{"a bad email is 400 naming the field", invalid.Code == http.StatusBadRequest && strings.Contains(invalid.Body.String(), "email")},
+			{"a lost database is 500 without its address", down.Code == http.StatusInternalServerError && !strings.Contains(down.Body.String(), "db-1")},
+		}},
+	}
+}
+
+// TestChains builds each error and checks that errors.Is, errors.As,
+// KindOf and the HTTP mapping see in it what the package says they do;
+// a chain with a broken check is logged link by link.
+func TestChains(t *testing.T) {
+	for _, c := range chains(t) {
+		t.Run(c.name, func(t *testing.T) {
+			for _, ch := range c.checks {
+				if !ch.ok {
+					t.Errorf("broken: %s", ch.what)
+				}
+			}
+			if t.Failed() {
+				t.Logf("error: %s", strings.ReplaceAll(c.err.Error(), "\n", "; "))
+				for i, link := range links(c.err) {
+					t.Logf("%d %T", i, link)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package errs
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrNoRows is the storage layer's sentinel for a row that is not there.
+var ErrNoRows = errors.New("no rows")
+
+// comparison with ==
+// Level: Poor
+// pros: none over errors.Is.
+// cons: holds only for the bare sentinel; as soon as a layer adds
+// context with %w, the check is silently false.
+//
+// IsNoRowsEq reports whether err is ErrNoRows, by comparison.
+func IsNoRowsEq(err error) bool { return err == ErrNoRows }
+
+// comparison by message
+// Level: Poor
+// pros: works on errors from code that exports nothing to compare with.
+// cons: matches any message with the words in it, a user's input echoed
+// into one included, and breaks when the wording changes.
+//
+// IsNoRowsText reports whether err's message mentions no rows.
+func IsNoRowsText(err error) bool { return strings.Contains(err.Error(), "no rows") }
+
+// sentinel errors
+// Level: Good
+// pros: a value to compare with, found anywhere in the chain by
+// errors.Is; costs one exported variable.
+// cons: says which failure and nothing more; a sentinel is API and can
+// never be removed.
+//
+// IsNoRows reports whether err is, or wraps, ErrNoRows.
+func IsNoRows(err error) bool { return errors.Is(err, ErrNoRows) }
+
+// wrapping with %v
+// Level: Poor
+// pros: the message reads the same as with %w.
+// cons: the cause becomes text: errors.Is and errors.As cannot reach it,
+// and every caller above must compare messages.
+//
+// WrapV adds op to err's message.
+func WrapV(op string, err error) error { return fmt.Errorf("%s: %v", op, err) }
+
+// wrapping with %w
+// Level: Good
+// pros: adds context and keeps the cause for errors.Is and errors.As.
+// cons: the cause becomes part of the API: callers may come to depend on
+// it, so wrap only what they may rely on.
+//
+// Wrap adds op to err's message and keeps err as its cause.
+func Wrap(op string, err error) error { return fmt.Errorf("%s: %w", op, err) }
+
+// error types
+// Level: Good
+// pros: carries what the caller needs to act, here which field and why,
+// reached with errors.As however deeply it is wrapped.
+// cons: a type per failure with details; callers that only need the
+// kind still have to know it, unless it has an Is method like this one.
+//
+// ValidationError reports input that broke a rule.
+type ValidationError struct {
+	Field  string
+	Reason string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid %s: %s", e.Field, e.Reason)
+}
+
+// Is makes every ValidationError an ErrInvalid, for callers that only
+// need the kind.
+func (e *ValidationError) Is(target error) bool { return target == ErrInvalid }
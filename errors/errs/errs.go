@@ -0,0 +1,141 @@
+// Package errs compares the ways Go code says what went wrong, and
+// builds a small taxonomy on the ones that hold up:
+//
+//   - sentinel errors, values compared with errors.Is: enough when the
+//     caller only needs to know which failure, like io.EOF
+//   - error types, matched with errors.As: for failures that carry
+//     details the caller acts on, such as which field was invalid
+//   - wrapping with %w, which adds context without hiding the cause;
+//     %v flattens the cause into text and errors.Is no longer finds it
+//     (compare.go)
+//
+// A service's callers mostly need one thing: what kind of failure it
+// was, to pick a status code or decide whether to retry. Error carries a
+// Kind (NotFound, Invalid, Internal), the operation that failed and the
+// cause; errors.Is matches it against the kind's sentinel, ErrNotFound
+// and the rest, however deeply it is wrapped, and KindOf finds the kind
+// of any error, calling an error that has none internal. Users is a
+// service written with it, and Status maps kinds to HTTP (service.go).
+//
+// The tests walk the unwrap chains these build and check each behaves
+// as described.
+package errs
+
+import (
+	"errors"
+	"net/http"
+)
+
+//go:generate go run patterns/cmd/enumgen -type=Kind -trimprefix=Kind
+
+// Kind is what the caller can do about a failure.
+type Kind int
+
+const (
+	// KindInternal is a failure of the service itself, and the kind of
+	// any error that has none: the caller can only retry or give up.
+	KindInternal Kind = iota
+	// KindNotFound means the thing asked for does not exist.
+	KindNotFound
+	// KindInvalid means the request was wrong and must be changed.
+	KindInvalid
+)
+
+// The sentinels of the kinds, for errors.Is: errors.Is(err, ErrNotFound)
+// holds for an *Error of KindNotFound anywhere in err's chain.
+var (
+	ErrInternal = errors.New("internal error")
+	ErrNotFound = errors.New("not found")
+	ErrInvalid  = errors.New("invalid")
+)
+
+func (k Kind) sentinel() error {
+	switch k {
+	case KindNotFound:
+		return ErrNotFound
+	case KindInvalid:
+		return ErrInvalid
+	}
+	return ErrInternal
+}
+
+// error taxonomy
+// Level: Good
+// pros: callers branch on a few kinds, not on every error a dependency
+// might return; the cause stays in the chain for logs and errors.As.
+// cons: every layer must classify the errors it returns, and a cause
+// left unclassified reads as internal.
+//
+// Error is a classified failure of an operation.
+type Error struct {
+	Kind Kind
+	// Op names the operation that failed, such as "users.Get".
+	Op string
+	// Err is the cause; nil if there is none beyond the kind.
+	Err error
+}
+
+// E returns an *Error of kind for op, caused by err.
+func E(op string, kind Kind, err error) *Error {
+	return &Error{Kind: kind, Op: op, Err: err}
+}
+
+func (e *Error) Error() string {
+	if e.Err == nil {
+		return e.Op + ": " + e.Kind.sentinel().Error()
+	}
+	return e.Op + ": " + e.Err.Error()
+}
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// Is reports whether target is the sentinel of e's kind, so callers test
+// the kind without asserting the type.
+func (e *Error) Is(target error) bool { return target == e.Kind.sentinel() }
+
+// KindOf returns the kind of the outermost *Error in err's chain. An
+// error with none has the kind of the first sentinel it matches, so a
+// bare ErrNotFound, or a ValidationError, is classified too, and any
+// other is KindInternal: an error nobody classified is the service's own
+// failure. KindOf(nil) is meaningless and panics.
+//
+// The outermost wins: a layer that turns a NotFound into its own
+// Internal, because the thing missing was its configuration and not the
+// caller's fault, is believed. errors.Is(err, ErrNotFound) still finds
+// the inner one, as it looks down the whole chain; branch on KindOf.
+func KindOf(err error) Kind {
+	if err == nil {
+		panic("errs: KindOf(nil)")
+	}
+	var e *Error
+	switch {
+	case errors.As(err, &e):
+		return e.Kind
+	case errors.Is(err, ErrNotFound):
+		return KindNotFound
+	case errors.Is(err, ErrInvalid):
+		return KindInvalid
+	}
+	return KindInternal
+}
+
+// Status returns the HTTP status of err's kind.
+func Status(err error) int {
+	switch KindOf(err) {
+	case KindNotFound:
+		return http.StatusNotFound
+	case KindInvalid:
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// Public returns the message that may be shown to a client: the error
+// itself for the kinds the client can act on, and nothing of the cause
+// of an internal one, which may name hosts, queries or files.
+func Public(err error) string {
+	if KindOf(err) == KindInternal {
+		return ErrInternal.Error()
+	}
+	return err.Error()
+}
@@ -0,0 +1,141 @@
+package errs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// User is what the Users service keeps.
+type User struct {
+	ID    int    `json:"id"`
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+// table is the storage layer: it knows rows, not kinds.
+type table struct {
+	mu   sync.Mutex
+	rows map[int]User
+	fail error
+}
+
+func (t *table) get(id int) (User, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.fail != nil {
+		return User{}, t.fail
+	}
+	u, ok := t.rows[id]
+	if !ok {
+		return User{}, ErrNoRows
+	}
+	return u, nil
+}
+
+func (t *table) insert(u User) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.fail != nil {
+		return t.fail
+	}
+	if t.rows == nil {
+		t.rows = map[int]User{}
+	}
+	t.rows[u.ID] = u
+	return nil
+}
+
+// Users is a service whose every error is an *Error: the storage
+// layer's errors are classified where they leave it, and the callers
+// branch on kinds. The zero value is ready to use.
+type Users struct {
+	t table
+}
+
+// Fail makes every later call fail with err, as if the database were
+// lost; nil restores it.
+func (s *Users) Fail(err error) {
+	s.t.mu.Lock()
+	defer s.t.mu.Unlock()
+	s.t.fail = err
+}
+
+// Get returns the user with id. It fails with KindNotFound, wrapping
+// ErrNoRows, if there is none.
+func (s *Users) Get(id int) (User, error) {
+	const op = "users.Get"
+	u, err := s.t.get(id)
+	switch {
+	case errors.Is(err, ErrNoRows):
+		return User{}, E(op, KindNotFound, fmt.Errorf("user %d: %w", id, err))
+	case err != nil:
+		return User{}, E(op, KindInternal, err)
+	}
+	return u, nil
+}
+
+// Create stores u. It fails with KindInvalid, wrapping a
+// *ValidationError, if u breaks a rule.
+func (s *Users) Create(u User) error {
+	const op = "users.Create"
+	if err := validate(u); err != nil {
+		return E(op, KindInvalid, err)
+	}
+	if err := s.t.insert(u); err != nil {
+		return E(op, KindInternal, err)
+	}
+	return nil
+}
+
+func validate(u User) error {
+	switch {
+	case u.ID <= 0:
+		return &ValidationError{Field: "id", Reason: "must be positive"}
+	case strings.TrimSpace(u.Name) == "":
+		return &ValidationError{Field: "name", Reason: "is required"}
+	case !strings.Contains(u.Email, "@"):
+		return &ValidationError{Field: "email", Reason: "must contain @"}
+	}
+	return nil
+}
+
+// Handler serves GET /users/{id} and POST /users, answering failures
+// with the status of their kind and the message Public allows.
+func Handler(s *Users) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(r.PathValue("id"))
+		if err != nil {
+			fail(w, E("users.Get", KindInvalid, &ValidationError{Field: "id", Reason: "must be a number"}))
+			return
+		}
+		u, err := s.Get(id)
+		if err != nil {
+			fail(w, err)
+			return
+		}
+		json.NewEncoder(w).Encode(u)
+	})
+	mux.HandleFunc("POST /users", func(w http.ResponseWriter, r *http.Request) {
+		var u User
+		if err := json.NewDecoder(r.Body).Decode(&u); err != nil {
+			fail(w, E("users.Create", KindInvalid, err))
+			return
+		}
+		if err := s.Create(u); err != nil {
+			fail(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	})
+	return mux
+}
+
+func fail(w http.ResponseWriter, err error) {
+	http.Error(w, Public(err), Status(err))
+}
@@ -0,0 +1,37 @@
+package hints
+
+import "net/http"
+
+// Codes is the registry of the example service's failures.
+var Codes Registry
+
+// The example service's failures.
+var (
+	ErrConfigMissing = Codes.Define(Def{
+		Code:    "CONFIG_MISSING",
+		Message: "config file {path} not found",
+		Hint:    "create {path}, or point -config at an existing file",
+		Exit:    78, // EX_CONFIG
+	})
+	ErrPortInUse = Codes.Define(Def{
+		Code:    "PORT_IN_USE",
+		Message: "port {port} is already in use",
+		Hint:    "stop the process listening on {port}, or start with -port 0 to pick a free one",
+		Status:  http.StatusServiceUnavailable,
+		Exit:    69, // EX_UNAVAILABLE
+	})
+	ErrTokenExpired = Codes.Define(Def{
+		Code:    "TOKEN_EXPIRED",
+		Message: "the access token expired at {expiry}",
+		Hint:    "sign in again to get a new token",
+		Status:  http.StatusUnauthorized,
+		Exit:    77, // EX_NOPERM
+	})
+	ErrQuotaExceeded = Codes.Define(Def{
+		Code:    "QUOTA_EXCEEDED",
+		Message: "project {project} used its {limit} requests for today",
+		Hint:    "wait until midnight UTC, or ask an owner of {project} to raise the quota",
+		Status:  http.StatusTooManyRequests,
+		Exit:    75, // EX_TEMPFAIL
+	})
+)
@@ -0,0 +1,208 @@
+// Package hints gives errors a stable, machine-readable code and a
+// remediation hint, a sentence telling a person what to do next, and
+// renders both the same way on every surface:
+//
+//	var codes hints.Registry
+//
+//	var ErrNoConfig = codes.Define(hints.Def{
+//		Code:    "CONFIG_MISSING",
+//		Message: "config file {path} not found",
+//		Hint:    "create {path}, or point -config at an existing file",
+//		Exit:    78,
+//	})
+//
+//	return ErrNoConfig.New(err, "path", path)
+//
+// A Def is defined once, in a Registry that refuses a code given twice,
+// so a code names one failure for good and can be searched for in logs
+// and documentation. Each failure is an *Error of its Def, carrying the
+// values its message and hint mention and the cause; errors.Is(err,
+// ErrNoConfig) finds it however it is wrapped.
+//
+// The two surfaces render from Describe, so they agree: on the command
+// line, cli.App prints the error and then its hint, and exits with the
+// Def's code; over HTTP, ErrorMapper, for web/handler, answers the Def's
+// status with a JSON body of the code, the message and the hint. The
+// cause is printed on the command line, for the operator, but not sent
+// over HTTP, where it may name hosts, files or queries; an error with no
+// Def is internal there, as in errors/errs.
+//
+// Codes holds the failures of an example service; the tests run every
+// one through both surfaces and check the output.
+//
+//	go test -v patterns/errors/hints
+package hints
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Def is one kind of failure, defined once.
+type Def struct {
+	// Code is the failure's stable identifier: capital letters, digits
+	// and underscores, such as CONFIG_MISSING.
+	Code string
+	// Message says what went wrong and Hint what to do about it. Both
+	// may mention values, {name}, given to New.
+	Message string
+	Hint    string
+	// Status is the HTTP status, 500 if zero; Exit the exit code of a
+	// command, 1 if zero.
+	Status int
+	Exit   int
+}
+
+// Error returns the message with its placeholders.
+func (d *Def) Error() string { return d.Code + ": " + d.Message }
+
+// New returns a failure of d caused by err, which may be nil, with the
+// values in kv, name and value pairs, filled into the message and hint.
+func (d *Def) New(err error, kv ...string) *Error {
+	return &Error{Def: d, Values: kv, Err: err}
+}
+
+// self-describing error
+// Level: Good
+// pros: a code that scripts, dashboards and support can match on without
+// parsing messages; a hint written where the failure is understood,
+// shown wherever it surfaces; the registry keeps codes unique.
+// cons: every failure a person may see needs a Def, and a hint that
+// stops being true is as misleading as a wrong message.
+//
+// Error is one occurrence of a Def.
+type Error struct {
+	Def *Def
+	// Values are name and value pairs for the placeholders.
+	Values []string
+	// Err is the cause, nil if there is none.
+	Err error
+}
+
+func (e *Error) Error() string {
+	s := e.Def.Code + ": " + e.Message()
+	if e.Err != nil {
+		s += ": " + e.Err.Error()
+	}
+	return s
+}
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// Is reports whether target is e's Def.
+func (e *Error) Is(target error) bool { return target == e.Def }
+
+// Message returns the Def's message with e's values filled in.
+func (e *Error) Message() string { return e.fill(e.Def.Message) }
+
+// Hint returns the Def's hint with e's values filled in; cli.App prints
+// it under the error.
+func (e *Error) Hint() string { return e.fill(e.Def.Hint) }
+
+// ExitCode returns the Def's exit code, for cli.App.
+func (e *Error) ExitCode() int {
+	if e.Def.Exit == 0 {
+		return 1
+	}
+	return e.Def.Exit
+}
+
+// StatusCode returns the Def's HTTP status.
+func (e *Error) StatusCode() int {
+	if e.Def.Status == 0 {
+		return http.StatusInternalServerError
+	}
+	return e.Def.Status
+}
+
+// fill replaces each {name} in s by its value; a placeholder without one
+// is left as it is, so the gap shows.
+func (e *Error) fill(s string) string {
+	pairs := make([]string, 0, len(e.Values))
+	for i := 0; i+1 < len(e.Values); i += 2 {
+		pairs = append(pairs, "{"+e.Values[i]+"}", e.Values[i+1])
+	}
+	return strings.NewReplacer(pairs...).Replace(s)
+}
+
+// Description is what both surfaces show of an error.
+type Description struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Hint    string `json:"hint,omitempty"`
+}
+
+// Internal is the Description of an error without a Def.
+var Internal = Description{Code: "INTERNAL", Message: "internal error"}
+
+// Describe returns the Description and HTTP status of the outermost
+// *Error in err's chain, or Internal and 500 if there is none.
+func Describe(err error) (Description, int) {
+	var e *Error
+	if !errors.As(err, &e) {
+		return Internal, http.StatusInternalServerError
+	}
+	return Description{Code: e.Def.Code, Message: e.Message(), Hint: e.Hint()}, e.StatusCode()
+}
+
+// ErrorMapper is a web/handler.ErrorMapper answering with Describe.
+func ErrorMapper(err error) (int, any) {
+	d, status := Describe(err)
+	return status, d
+}
+
+// Registry holds Defs by code; it is safe for concurrent use.
+type Registry struct {
+	mu   sync.Mutex
+	defs map[string]*Def
+}
+
+var codePattern = regexp.MustCompile(`^[A-Z][A-Z0-9_]*$`)
+
+// Define adds d to r and returns it. It panics, like flag redefining a
+// flag, if the code is malformed or taken, or if d has no message or
+// hint: definitions are package variables, so the mistake shows when
+// the program starts.
+func (r *Registry) Define(d Def) *Def {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	switch {
+	case !codePattern.MatchString(d.Code):
+		panic(fmt.Sprintf("hints: malformed code %q", d.Code))
+	case r.defs[d.Code] != nil:
+		panic(fmt.Sprintf("hints: code %s defined twice", d.Code))
+	case d.Message == "" || d.Hint == "":
+		panic(fmt.Sprintf("hints: %s needs a message and a hint", d.Code))
+	}
+	if r.defs == nil {
+		r.defs = map[string]*Def{}
+	}
+	def := &d
+	r.defs[d.Code] = def
+	return def
+}
+
+// Lookup returns the Def of code.
+func (r *Registry) Lookup(code string) (*Def, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	d, ok := r.defs[code]
+	return d, ok
+}
+
+// Defs returns every Def, by code.
+func (r *Registry) Defs() []*Def {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	defs := make([]*Def, 0, len(r.defs))
+	for _, d := range r.defs {
+		defs = append(defs, d)
+	}
+	slices.SortFunc(defs, func(a, b *Def) int { return strings.Compare(a.Code, b.Code) })
+	return defs
+}
@@ -0,0 +1,183 @@
+package hints_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"patterns/cli"
+	"patterns/errors/hints"
+	"patterns/web/handler"
+)
+
+var cases = []struct {
+	name string
+	err  error
+	// stderr and exit are what the command line must show
+	stderr string
+	exit   int
+	// status and body are what HTTP must answer
+	status int
+	body   hints.Description
+}{
+	{
+		name: "config missing",
+		err: fmt.Errorf("load: %w", hints.ErrConfigMissing.New(
+			&fs.PathError{Op: "open", Path: "app.json", Err: fs.ErrNotExist}, "path", "app.json")),
+		stderr: "svc serve: load: CONFIG_MISSING: config file app.json not found: open app.json: file does not exist\n" +
+			"hint: create app.json, or point -config at an existing file\n",
+		exit:   78,
+		status: 500,
+		body:   hints.Description{Code: "CONFIG_MISSING", Message: "config file app.json not found", Hint: "create app.json, or point -config at an existing file"},
+	},
+	{
+		name: "port in use",
+		err:  hints.ErrPortInUse.New(errors.New("listen tcp :8080: bind: address already in use"), "port", "8080"),
+		stderr: "svc serve: PORT_IN_USE: port 8080 is already in use: listen tcp :8080: bind: address already in use\n" +
+			"hint: stop the process listening on 8080, or start with -port 0 to pick a free one\n",
+		exit:   69,
+		status: 503,
+		body:   hints.Description{Code: "PORT_IN_USE", Message: "port 8080 is already in use", Hint: "stop the process listening on 8080, or start with -port 0 to pick a free one"},
+	},
+	{
+		name: "token expired, no cause",
+		err:  hints.ErrTokenExpired.New(nil, "expiry", "2024-05-01T10:00:00Z"),
+		stderr: "svc serve: TOKEN_EXPIRED: the access token expired at 2024-05-01T10:00:00Z\n" +
+			"hint: sign in again to get a new token\n",
+		exit:   77,
+		status: 401,
+		body:   hints.Description{Code: "TOKEN_EXPIRED", Message: "the access token expired at 2024-05-01T10:00:00Z", Hint: "sign in again to get a new token"},
+	},
+	{
+		name: "quota exceeded, a value used twice",
+		err:  hints.ErrQuotaExceeded.New(nil, "project", "acme", "limit", "10000"),
+		stderr: "svc serve: QUOTA_EXCEEDED: project acme used its 10000 requests for today\n" +
+			"hint: wait until midnight UTC, or ask an owner of acme to raise the quota\n",
+		exit:   75,
+		status: 429,
+		body:   hints.Description{Code: "QUOTA_EXCEEDED", Message: "project acme used its 10000 requests for today", Hint: "wait until midnight UTC, or ask an owner of acme to raise the quota"},
+	},
+	{
+		name:   "a missing value shows as its placeholder",
+		err:    hints.ErrQuotaExceeded.New(nil, "project", "acme"),
+		stderr: "svc serve: QUOTA_EXCEEDED: project acme used its {limit} requests for today\nhint: wait until midnight UTC, or ask an owner of acme to raise the quota\n",
+		exit:   75,
+		status: 429,
+		body:   hints.Description{Code: "QUOTA_EXCEEDED", Message: "project acme used its {limit} requests for today", Hint: "wait until midnight UTC, or ask an owner of acme to raise the quota"},
+	},
+	{
+		name:   "an error without a code",
+		err:    errors.New("dial tcp 10.0.0.7:5432: connection refused"),
+		stderr: "svc serve: dial tcp 10.0.0.7:5432: connection refused\n",
+		exit:   1,
+		status: 500,
+		body:   hints.Internal,
+	},
+}
+
+// cliRun runs a command failing with err and returns its stderr and exit
+// status.
+func cliRun(err error) (string, int) {
+	app := &cli.App{Name: "svc", Commands: []*cli.Command{{
+		Name: "serve",
+		Run:  func(cli.Env, []string) error { return err },
+	}}}
+	var stdout, stderr strings.Builder
+	code := app.Run([]string{"serve"}, &stdout, &stderr)
+	return stderr.String(), code
+}
+
+// httpRun serves an endpoint failing with err and returns the response.
+func httpRun(err error) (int, hints.Description, error) {
+	h := handler.Adapt(func(context.Context, struct{}) (struct{}, error) { return struct{}{}, err },
+		handler.WithErrorMapper(hints.ErrorMapper))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	var d hints.Description
+	derr := json.Unmarshal(w.Body.Bytes(), &d)
+	return w.Code, d, derr
+}
+
+// TestRender runs every case through both surfaces: a cli.App command,
+// whose stderr and exit status it checks, and a web/handler endpoint with
+// hints.ErrorMapper, whose status and JSON body it checks. Both must show
+// the same code, message and hint, and only the command line the cause.
+func TestRender(t *testing.T) {
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			stderr, exit := cliRun(c.err)
+			if stderr != c.stderr || exit != c.exit {
+				t.Errorf("command line: exit %d, stderr\n%swant exit %d, stderr\n%s", exit, stderr, c.exit, c.stderr)
+			}
+			status, body, err := httpRun(c.err)
+			if err != nil {
+				t.Fatalf("HTTP body: %v", err)
+			}
+			if status != c.status || body != c.body {
+				t.Errorf("HTTP: %d %+v, want %d %+v", status, body, c.status, c.body)
+			}
+			// the surfaces agree: the command line shows the message and
+			// hint HTTP sends, and HTTP none of the cause
+			if c.body != hints.Internal && (!strings.Contains(stderr, body.Message) || !strings.Contains(stderr, "hint: "+body.Hint+"\n")) {
+				t.Errorf("the command line does not show the HTTP message and hint")
+			}
+			var he *hints.Error
+			if errors.As(c.err, &he) && he.Err != nil && strings.Contains(fmt.Sprint(body), he.Err.Error()) {
+				t.Errorf("HTTP shows the cause %q", he.Err)
+			}
+		})
+	}
+}
+
+func TestEveryCodeHasACase(t *testing.T) {
+	covered := map[string]bool{}
+	for _, c := range cases {
+		covered[c.body.Code] = true
+	}
+	for _, d := range hints.Codes.Defs() {
+		if !covered[d.Code] {
+			t.Errorf("no case for %s", d.Code)
+		}
+		if got, ok := hints.Codes.Lookup(d.Code); !ok || got != d {
+			t.Errorf("Lookup(%s) = %v, %v", d.Code, got, ok)
+		}
+	}
+}
+
+func TestIsFindsTheDefThroughWrapping(t *testing.T) {
+	err := cases[0].err
+	if !errors.Is(err, hints.ErrConfigMissing) || errors.Is(err, hints.ErrPortInUse) || !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("errors.Is on %v", err)
+	}
+}
+
+func TestRegistryRefusesBadDefinitions(t *testing.T) {
+	var r hints.Registry
+	r.Define(hints.Def{Code: "DISK_FULL", Message: "disk full", Hint: "free some space"})
+	for _, d := range []hints.Def{
+		{Code: "DISK_FULL", Message: "the disk is full", Hint: "delete files"},
+		{Code: "disk-full", Message: "m", Hint: "h"},
+		{Code: "NO_HINT", Message: "m"},
+	} {
+		if p := recovered(func() { r.Define(d) }); p == nil {
+			t.Errorf("Define(%+v) did not panic", d)
+		} else {
+			t.Logf("%v", p)
+		}
+	}
+	if n := len(r.Defs()); n != 1 {
+		t.Fatalf("%d defs, want 1", n)
+	}
+}
+
+func recovered(f func()) (p any) {
+	defer func() { p = recover() }()
+	f()
+	return nil
+}
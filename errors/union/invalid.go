@@ -0,0 +1,10 @@
+//go:build union_invalid
+
+// Build with -tags union_invalid to see a missing kind rejected.
+package union
+
+func unhandled(e *ServiceError) int {
+	// not enough arguments in call to Match3: the conflict and internal
+	// kinds have no handler
+	return Match3(e, func(*ValidationError) int { return 422 })
+}
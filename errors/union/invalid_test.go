@@ -0,0 +1,15 @@
+package union
+
+import (
+	"testing"
+
+	"patterns/testing/buildfail"
+)
+
+func TestInvalid(t *testing.T) {
+	buildfail.Check(t, "union_invalid", ".",
+		"not enough arguments in call to Match3",
+		"have (*ServiceError, func(*ValidationError) int)",
+		"func(A) R, func(B) R, func(C) R)",
+	)
+}
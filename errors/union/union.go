@@ -0,0 +1,140 @@
+// Package union models a function's failure modes as a closed set of error
+// types. Match2/Match3 take one handler per member, so forgetting a kind is
+// a compile error instead of a silently unhandled case.
+package union
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// ValidationError reports bad input.
+type ValidationError struct {
+	Field  string
+	Reason string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid %s: %s", e.Field, e.Reason)
+}
+
+// ConflictError reports a clash with existing state.
+type ConflictError struct {
+	Resource string
+	ID       string
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("%s %q already exists", e.Resource, e.ID)
+}
+
+// InternalError wraps an unexpected failure.
+type InternalError struct {
+	Err error
+}
+
+func (e *InternalError) Error() string { return "internal: " + e.Err.Error() }
+
+func (e *InternalError) Unwrap() error { return e.Err }
+
+// Error2 is an error that is exactly one of A or B.
+type Error2[A, B error] struct {
+	a     A
+	b     B
+	isB   bool
+	valid bool
+}
+
+func Of2A[A, B error](a A) *Error2[A, B] { return &Error2[A, B]{a: a, valid: true} }
+
+func Of2B[A, B error](b B) *Error2[A, B] { return &Error2[A, B]{b: b, isB: true, valid: true} }
+
+func (e *Error2[A, B]) Error() string {
+	return Match2(e, A.Error, B.Error)
+}
+
+func (e *Error2[A, B]) Unwrap() error {
+	if e.isB {
+		return e.b
+	}
+	return e.a
+}
+
+// Match2 calls exactly one of onA or onB.
+func Match2[A, B error, R any](e *Error2[A, B], onA func(A) R, onB func(B) R) R {
+	if !e.valid {
+		panic("union: zero Error2")
+	}
+	if e.isB {
+		return onB(e.b)
+	}
+	return onA(e.a)
+}
+
+// Error3 is an error that is exactly one of A, B or C.
+type Error3[A, B, C error] struct {
+	a     A
+	b     B
+	c     C
+	which int
+}
+
+func Of3A[A, B, C error](a A) *Error3[A, B, C] { return &Error3[A, B, C]{a: a, which: 1} }
+
+func Of3B[A, B, C error](b B) *Error3[A, B, C] { return &Error3[A, B, C]{b: b, which: 2} }
+
+func Of3C[A, B, C error](c C) *Error3[A, B, C] { return &Error3[A, B, C]{c: c, which: 3} }
+
+func (e *Error3[A, B, C]) Error() string {
+	return Match3(e, A.Error, B.Error, C.Error)
+}
+
+func (e *Error3[A, B, C]) Unwrap() error {
+	return Match3(e,
+		func(a A) error { return a },
+		func(b B) error { return b },
+		func(c C) error { return c })
+}
+
+// Match3 calls exactly one of onA, onB or onC.
+func Match3[A, B, C error, R any](e *Error3[A, B, C], onA func(A) R, onB func(B) R, onC func(C) R) R {
+	switch e.which {
+	case 1:
+		return onA(e.a)
+	case 2:
+		return onB(e.b)
+	case 3:
+		return onC(e.c)
+	default:
+		panic("union: zero Error3")
+	}
+}
+
+// ServiceError is the failure set of a typical write endpoint.
+type ServiceError = Error3[*ValidationError, *ConflictError, *InternalError]
+
+// Status maps a ServiceError to an HTTP status; every kind must be handled.
+func Status(e *ServiceError) int {
+	return Match3(e,
+		func(*ValidationError) int { return http.StatusUnprocessableEntity },
+		func(*ConflictError) int { return http.StatusConflict },
+		func(*InternalError) int { return http.StatusInternalServerError },
+	)
+}
+
+// MapError is a handler.ErrorMapper: ServiceErrors keep their kind-specific
+// status and message, anything else is an opaque 500.
+func MapError(err error) (int, any) {
+	var se *ServiceError
+	if !errors.As(err, &se) {
+		return http.StatusInternalServerError, map[string]string{"error": "internal error"}
+	}
+	body := Match3(se,
+		func(v *ValidationError) any { return map[string]string{"error": v.Error(), "field": v.Field} },
+		func(c *ConflictError) any { return map[string]string{"error": c.Error()} },
+		func(*InternalError) any { return map[string]string{"error": "internal error"} },
+	)
+
+	return Status(se), body
+}
@@ -0,0 +1,128 @@
+package union_test
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"patterns/errors/union"
+)
+
+var (
+	validation = &union.ValidationError{Field: "title", Reason: "empty"}
+	conflict   = &union.ConflictError{Resource: "book", ID: "42"}
+	internal   = &union.InternalError{Err: io.ErrUnexpectedEOF}
+)
+
+// kinds is every member of ServiceError, as made by its constructor.
+var kinds = []struct {
+	name   string
+	err    *union.ServiceError
+	member error
+	status int
+	body   map[string]string
+}{
+	{"validation", union.Of3A[*union.ValidationError, *union.ConflictError, *union.InternalError](validation), validation,
+		http.StatusUnprocessableEntity, map[string]string{"error": "invalid title: empty", "field": "title"}},
+	{"conflict", union.Of3B[*union.ValidationError, *union.ConflictError, *union.InternalError](conflict), conflict,
+		http.StatusConflict, map[string]string{"error": `book "42" already exists`}},
+	// the cause of an internal error stays out of the response
+	{"internal", union.Of3C[*union.ValidationError, *union.ConflictError, *union.InternalError](internal), internal,
+		http.StatusInternalServerError, map[string]string{"error": "internal error"}},
+}
+
+func TestMatch3(t *testing.T) {
+	for _, k := range kinds {
+		var called []string
+		got := union.Match3(k.err,
+			func(*union.ValidationError) error { called = append(called, "validation"); return validation },
+			func(*union.ConflictError) error { called = append(called, "conflict"); return conflict },
+			func(*union.InternalError) error { called = append(called, "internal"); return internal },
+		)
+		if len(called) != 1 || called[0] != k.name || got != k.member {
+			t.Errorf("%s: Match3 called %v, returned %v", k.name, called, got)
+		}
+	}
+}
+
+func TestErrorAndUnwrap(t *testing.T) {
+	for _, k := range kinds {
+		if k.err.Error() != k.member.Error() {
+			t.Errorf("%s: Error() = %q, want %q", k.name, k.err.Error(), k.member.Error())
+		}
+		wrapped := fmt.Errorf("create book: %w", k.err)
+		var v *union.ValidationError
+		var c *union.ConflictError
+		var i *union.InternalError
+		got := []bool{errors.As(wrapped, &v), errors.As(wrapped, &c), errors.As(wrapped, &i)}
+		want := []bool{k.name == "validation", k.name == "conflict", k.name == "internal"}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: errors.As to validation, conflict, internal = %v, want %v", k.name, got, want)
+		}
+	}
+	if !errors.Is(kinds[2].err, io.ErrUnexpectedEOF) {
+		t.Error("the cause of an internal error is not in its chain")
+	}
+}
+
+func TestStatus(t *testing.T) {
+	for _, k := range kinds {
+		if got := union.Status(k.err); got != k.status {
+			t.Errorf("%s: Status = %d, want %d", k.name, got, k.status)
+		}
+	}
+}
+
+func TestMapError(t *testing.T) {
+	for _, k := range kinds {
+		status, body := union.MapError(fmt.Errorf("handler: %w", k.err))
+		if status != k.status || !reflect.DeepEqual(body, k.body) {
+			t.Errorf("%s: MapError = %d, %v, want %d, %v", k.name, status, body, k.status, k.body)
+		}
+	}
+	// a bare member, outside a ServiceError, is not trusted
+	for _, err := range []error{validation, errors.New("boom")} {
+		status, body := union.MapError(err)
+		if want := map[string]string{"error": "internal error"}; status != http.StatusInternalServerError || !reflect.DeepEqual(body, want) {
+			t.Errorf("MapError(%v) = %d, %v, want 500", err, status, body)
+		}
+	}
+}
+
+func TestError2(t *testing.T) {
+	a := union.Of2A[*union.ValidationError, *union.ConflictError](validation)
+	b := union.Of2B[*union.ValidationError, *union.ConflictError](conflict)
+	for _, c := range []struct {
+		err  *union.Error2[*union.ValidationError, *union.ConflictError]
+		want string
+	}{{a, "a"}, {b, "b"}} {
+		got := union.Match2(c.err,
+			func(*union.ValidationError) string { return "a" },
+			func(*union.ConflictError) string { return "b" })
+		if got != c.want {
+			t.Errorf("Match2(%v) = %s, want %s", c.err, got, c.want)
+		}
+	}
+	if !errors.Is(a, validation) || !errors.Is(b, conflict) || a.Error() != validation.Error() {
+		t.Errorf("Error2 does not unwrap to its member")
+	}
+}
+
+func TestZeroPanics(t *testing.T) {
+	for name, f := range map[string]func(){
+		"Error2": func() { _ = (&union.Error2[*union.ValidationError, *union.ConflictError]{}).Error() },
+		"Error3": func() { union.Status(&union.ServiceError{}) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("zero %s did not panic", name)
+				}
+			}()
+			f()
+		}()
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import "sync"
+
+// Bus is a typed in-process publish/subscribe topic.
+//
+// Publish never blocks: a subscriber whose buffer is full misses the
+// value. That is acceptable here because pollers re-read the room history
+// and only use the bus as a wake-up signal.
+//
+// The zero Bus is ready to use.
+type Bus[T any] struct {
+	mu   sync.Mutex
+	subs map[chan T]struct{}
+}
+
+// Subscribe returns a channel of published values and a function that
+// cancels the subscription.
+func (b *Bus[T]) Subscribe(buffer int) (<-chan T, func()) {
+	ch := make(chan T, buffer)
+	b.mu.Lock()
+	if b.subs == nil {
+		b.subs = map[chan T]struct{}{}
+	}
+	b.subs[ch] = struct{}{}
+	b.mu.Unlock()
+	return ch, func() {
+		b.mu.Lock()
+		delete(b.subs, ch)
+		b.mu.Unlock()
+	}
+}
+
+func (b *Bus[T]) Publish(v T) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.subs {
+		select {
+		case ch <- v:
+		default:
+		}
+	}
+}
@@ -0,0 +1,53 @@
+// Command chat is a long-poll HTTP chat server composed from patterns:
+//
+//   - each room is an actor: one goroutine owns members and history
+//   - presence is an observer notified of joins and leaves
+//   - new messages fan out to waiting pollers through a typed bus
+//   - endpoints are typed functions behind patterns/web/handler
+//
+// No WebSocket: clients poll GET /rooms/{room}/messages?since=N&wait=20s,
+// which returns as soon as a message newer than N exists.
+//
+// usage:
+//
+//	go run patterns/examples/chat -addr localhost:8080
+//	curl -d '{"user":"ann"}' localhost:8080/rooms/go/join
+//	curl 'localhost:8080/rooms/go/messages?since=0&wait=20s' &
+//	curl -d '{"user":"ann","text":"hi"}' localhost:8080/rooms/go/messages
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"time"
+
+	"patterns/lifecycle/shutdown"
+)
+
+func main() {
+	addr := flag.String("addr", "localhost:8080", "listen address")
+	keep := flag.Int("history", 100, "messages kept per room")
+	flag.Parse()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	presence := new(Presence)
+	h := &hub{ctx: ctx, keep: *keep, observers: []Observer{presence}}
+	srv := &http.Server{Handler: routes(h, presence), ReadHeaderTimeout: 5 * time.Second}
+
+	l, err := net.Listen("tcp", *addr)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("listening on %s", l.Addr())
+	// pollers wait up to maxWait, so give them that long to drain
+	if err := shutdown.Serve(ctx, srv, l, maxWait); err != nil {
+		log.Fatal(err)
+	}
+}
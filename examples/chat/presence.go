@@ -0,0 +1,51 @@
+package main
+
+import (
+	"slices"
+	"sync"
+)
+
+// observer pattern
+// Rooms notify registered observers of membership changes without
+// knowing who they are; Presence is one such observer.
+
+type Observer interface {
+	Joined(room, user string)
+	Left(room, user string)
+}
+
+// Presence tracks who is in which room; the zero value is ready to use.
+type Presence struct {
+	mu    sync.Mutex
+	rooms map[string]map[string]bool
+}
+
+func (p *Presence) Joined(room, user string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.rooms == nil {
+		p.rooms = map[string]map[string]bool{}
+	}
+	if p.rooms[room] == nil {
+		p.rooms[room] = map[string]bool{}
+	}
+	p.rooms[room][user] = true
+}
+
+func (p *Presence) Left(room, user string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.rooms[room], user)
+}
+
+// Online returns the users in room, sorted.
+func (p *Presence) Online(room string) []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	users := []string{}
+	for u := range p.rooms[room] {
+		users = append(users, u)
+	}
+	slices.Sort(users)
+	return users
+}
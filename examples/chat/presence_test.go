@@ -0,0 +1,42 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPresenceZero(t *testing.T) {
+	var p Presence
+	if got := p.Online("go"); got == nil || len(got) != 0 {
+		t.Errorf("Online on a zero Presence = %#v, want []", got)
+	}
+	// leaving before anyone joined is not an error
+	p.Left("go", "ann")
+	p.Joined("go", "bob")
+	p.Joined("go", "ann")
+	p.Joined("rust", "cid")
+	p.Left("go", "bob")
+	if got := p.Online("go"); !reflect.DeepEqual(got, []string{"ann"}) {
+		t.Errorf("Online(go) = %v, want [ann]", got)
+	}
+}
+
+func TestBusZero(t *testing.T) {
+	var b Bus[int]
+	// nobody is subscribed: the value is dropped
+	b.Publish(0)
+	ch, cancel := b.Subscribe(1)
+	b.Publish(1)
+	// the buffer is full: dropped too, Publish does not block
+	b.Publish(2)
+	if v := <-ch; v != 1 {
+		t.Errorf("received %d, want 1", v)
+	}
+	cancel()
+	b.Publish(3)
+	select {
+	case v := <-ch:
+		t.Errorf("received %d after cancel", v)
+	default:
+	}
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// actor pattern
+// A room's state is owned by one goroutine; everything else sends it
+// closures through the inbox, so there is no lock around members or
+// history.
+
+type Message struct {
+	Seq  int64     `json:"seq"`
+	User string    `json:"user"`
+	Text string    `json:"text"`
+	At   time.Time `json:"at"`
+}
+
+var (
+	ErrNotMember = errors.New("user has not joined the room")
+	ErrEmpty     = errors.New("user and text are required")
+)
+
+type roomState struct {
+	members map[string]bool
+	history []Message
+	seq     int64
+}
+
+type room struct {
+	name      string
+	inbox     chan func(*roomState)
+	messages  Bus[Message]
+	observers []Observer
+	keep      int
+}
+
+func newRoom(ctx context.Context, name string, keep int, observers []Observer) *room {
+	r := &room{
+		name:      name,
+		inbox:     make(chan func(*roomState)),
+		observers: observers,
+		keep:      keep,
+	}
+	go r.loop(ctx)
+	return r
+}
+
+func (r *room) loop(ctx context.Context) {
+	state := &roomState{members: map[string]bool{}}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case f := <-r.inbox:
+			f(state)
+		}
+	}
+}
+
+// do runs f inside the actor and waits for it.
+func (r *room) do(ctx context.Context, f func(*roomState)) error {
+	done := make(chan struct{})
+	select {
+	case r.inbox <- func(s *roomState) { f(s); close(done) }:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (r *room) join(ctx context.Context, user string) error {
+	return r.do(ctx, func(s *roomState) {
+		if !s.members[user] {
+			s.members[user] = true
+			for _, o := range r.observers {
+				o.Joined(r.name, user)
+			}
+		}
+	})
+}
+
+func (r *room) leave(ctx context.Context, user string) error {
+	return r.do(ctx, func(s *roomState) {
+		if s.members[user] {
+			delete(s.members, user)
+			for _, o := range r.observers {
+				o.Left(r.name, user)
+			}
+		}
+	})
+}
+
+func (r *room) post(ctx context.Context, user, text string) (Message, error) {
+	var msg Message
+	var err error
+	doErr := r.do(ctx, func(s *roomState) {
+		if !s.members[user] {
+			err = ErrNotMember
+			return
+		}
+		s.seq++
+		msg = Message{Seq: s.seq, User: user, Text: text, At: time.Now().UTC()}
+		s.history = append(s.history, msg)
+		if len(s.history) > r.keep {
+			s.history = s.history[len(s.history)-r.keep:]
+		}
+		r.messages.Publish(msg)
+	})
+	return msg, errors.Join(doErr, err)
+}
+
+func (r *room) since(ctx context.Context, seq int64) ([]Message, error) {
+	var out []Message
+	err := r.do(ctx, func(s *roomState) {
+		for _, m := range s.history {
+			if m.Seq > seq {
+				out = append(out, m)
+			}
+		}
+	})
+	return out, err
+}
+
+// poll long-polls: it returns messages after seq as soon as there are
+// any, or an empty slice after wait.
+func (r *room) poll(ctx context.Context, seq int64, wait time.Duration) ([]Message, error) {
+	// subscribe before reading history so a post in between is not missed
+	wake, cancel := r.messages.Subscribe(1)
+	defer cancel()
+	msgs, err := r.since(ctx, seq)
+	if err != nil || len(msgs) > 0 {
+		return msgs, err
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-wake:
+		return r.since(ctx, seq)
+	case <-timer.C:
+		return []Message{}, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// hub creates rooms on first use.
+type hub struct {
+	ctx       context.Context
+	mu        sync.Mutex
+	rooms     map[string]*room
+	keep      int
+	observers []Observer
+}
+
+func (h *hub) room(name string) *room {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	r, ok := h.rooms[name]
+	if !ok {
+		if h.rooms == nil {
+			h.rooms = map[string]*room{}
+		}
+		r = newRoom(h.ctx, name, h.keep, h.observers)
+		h.rooms[name] = r
+	}
+	return r
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"patterns/web/handler"
+)
+
+type member struct {
+	Room string `path:"room"`
+	User string `json:"user"`
+}
+
+type post struct {
+	Room string `path:"room"`
+	User string `json:"user"`
+	Text string `json:"text"`
+}
+
+type pollRequest struct {
+	Room  string        `path:"room"`
+	Since int64         `query:"since"`
+	Wait  time.Duration `query:"wait"`
+}
+
+type roomRequest struct {
+	Room string `path:"room"`
+}
+
+type ok struct {
+	OK bool `json:"ok"`
+}
+
+type posted struct{ Message }
+
+func (posted) StatusCode() int { return http.StatusCreated }
+
+const maxWait = 30 * time.Second
+
+func routes(h *hub, presence *Presence) http.Handler {
+	mapErr := handler.WithErrorMapper(mapError)
+	mux := http.NewServeMux()
+	mux.Handle("POST /rooms/{room}/join", handler.Adapt(func(ctx context.Context, req member) (ok, error) {
+		if req.User == "" {
+			return ok{}, ErrEmpty
+		}
+		return ok{true}, h.room(req.Room).join(ctx, req.User)
+	}, mapErr))
+	mux.Handle("POST /rooms/{room}/leave", handler.Adapt(func(ctx context.Context, req member) (ok, error) {
+		return ok{true}, h.room(req.Room).leave(ctx, req.User)
+	}, mapErr))
+	mux.Handle("POST /rooms/{room}/messages", handler.Adapt(func(ctx context.Context, req post) (posted, error) {
+		if req.User == "" || req.Text == "" {
+			return posted{}, ErrEmpty
+		}
+		msg, err := h.room(req.Room).post(ctx, req.User, req.Text)
+		return posted{msg}, err
+	}, mapErr))
+	mux.Handle("GET /rooms/{room}/messages", handler.Adapt(func(ctx context.Context, req pollRequest) ([]Message, error) {
+		wait := min(max(req.Wait, 0), maxWait)
+		return h.room(req.Room).poll(ctx, req.Since, wait)
+	}, mapErr))
+	mux.Handle("GET /rooms/{room}/presence", handler.Adapt(func(ctx context.Context, req roomRequest) ([]string, error) {
+		return presence.Online(req.Room), nil
+	}))
+	return mux
+}
+
+func mapError(err error) (int, any) {
+	switch {
+	case errors.Is(err, ErrNotMember):
+		return http.StatusForbidden, map[string]string{"error": err.Error()}
+	case errors.Is(err, ErrEmpty):
+		return http.StatusBadRequest, map[string]string{"error": err.Error()}
+	}
+	return handler.DefaultErrorMapper(err)
+}
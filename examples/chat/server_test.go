@@ -0,0 +1,271 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// chat is a running server and the presence observer behind it.
+type chat struct {
+	t        *testing.T
+	url      string
+	presence *Presence
+}
+
+func newChat(t *testing.T, keep int) *chat {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	presence := new(Presence)
+	h := &hub{ctx: ctx, keep: keep, observers: []Observer{presence}}
+	srv := httptest.NewServer(routes(h, presence))
+	t.Cleanup(func() {
+		srv.Close()
+		cancel()
+	})
+	return &chat{t: t, url: srv.URL, presence: presence}
+}
+
+// call sends body, if any, and decodes the response into out, if not nil;
+// it returns the status.
+func (c *chat) call(method, path, body string, out any) int {
+	c.t.Helper()
+	req, err := http.NewRequest(method, c.url+path, strings.NewReader(body))
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		c.t.Error(err)
+		return 0
+	}
+	defer resp.Body.Close()
+	if out != nil && resp.StatusCode < 300 {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			c.t.Errorf("%s %s: %v", method, path, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func (c *chat) join(room, user string) {
+	c.t.Helper()
+	if status := c.call("POST", "/rooms/"+room+"/join", fmt.Sprintf(`{"user":%q}`, user), nil); status != http.StatusOK {
+		c.t.Errorf("%s joining %s: %d", user, room, status)
+	}
+}
+
+func (c *chat) post(room, user, text string) Message {
+	c.t.Helper()
+	var m Message
+	if status := c.call("POST", "/rooms/"+room+"/messages", fmt.Sprintf(`{"user":%q,"text":%q}`, user, text), &m); status != http.StatusCreated {
+		c.t.Errorf("%s posting to %s: %d", user, room, status)
+	}
+	return m
+}
+
+func (c *chat) poll(room string, since int64, wait time.Duration) []Message {
+	c.t.Helper()
+	var msgs []Message
+	if status := c.call("GET", fmt.Sprintf("/rooms/%s/messages?since=%d&wait=%s", room, since, wait), "", &msgs); status != http.StatusOK {
+		c.t.Errorf("polling %s: %d", room, status)
+	}
+	return msgs
+}
+
+func (c *chat) online(room string) []string {
+	c.t.Helper()
+	var users []string
+	c.call("GET", "/rooms/"+room+"/presence", "", &users)
+	return users
+}
+
+func texts(msgs []Message) []string {
+	out := []string{}
+	for _, m := range msgs {
+		out = append(out, m.User+": "+m.Text)
+	}
+	return out
+}
+
+func TestConversation(t *testing.T) {
+	c := newChat(t, 100)
+	c.join("go", "ann")
+	c.join("go", "bob")
+	c.join("rust", "cid")
+	if got := c.online("go"); !reflect.DeepEqual(got, []string{"ann", "bob"}) {
+		t.Errorf("online in go = %v", got)
+	}
+	first := c.post("go", "ann", "hi")
+	c.post("go", "bob", "hello")
+	c.post("rust", "cid", "elsewhere")
+
+	if got, want := texts(c.poll("go", 0, 0)), []string{"ann: hi", "bob: hello"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("go history = %v, want %v", got, want)
+	}
+	if got, want := texts(c.poll("go", first.Seq, 0)), []string{"bob: hello"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("go since %d = %v, want %v", first.Seq, got, want)
+	}
+
+	c.call("POST", "/rooms/go/leave", `{"user":"ann"}`, nil)
+	if got := c.online("go"); !reflect.DeepEqual(got, []string{"bob"}) {
+		t.Errorf("online in go after ann left = %v", got)
+	}
+	if status := c.call("POST", "/rooms/go/messages", `{"user":"ann","text":"still here?"}`, nil); status != http.StatusForbidden {
+		t.Errorf("post after leaving = %d, want 403", status)
+	}
+}
+
+// TestLongPoll checks that a waiting poller is woken by a post, and that
+// one with nothing to read gets an empty list when its wait is over.
+func TestLongPoll(t *testing.T) {
+	c := newChat(t, 100)
+	c.join("go", "ann")
+	got := make(chan []Message, 1)
+	start := time.Now()
+	go func() { got <- c.poll("go", 0, 20*time.Second) }()
+	time.Sleep(20 * time.Millisecond)
+	c.post("go", "ann", "wake up")
+	select {
+	case msgs := <-got:
+		if want := []string{"ann: wake up"}; !reflect.DeepEqual(texts(msgs), want) {
+			t.Errorf("poll = %v, want %v", texts(msgs), want)
+		}
+		if d := time.Since(start); d > 5*time.Second {
+			t.Errorf("poll returned after %v", d)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("poll was not woken by the post")
+	}
+
+	msgs := c.poll("go", 1, 20*time.Millisecond)
+	if msgs == nil || len(msgs) != 0 {
+		t.Errorf("poll with nothing new = %#v, want []", msgs)
+	}
+}
+
+// TestConcurrentClients has posters and pollers share a room: every
+// poller sees every message once, in one order, with each poster's
+// messages in the order they were sent.
+func TestConcurrentClients(t *testing.T) {
+	const posters, pollers, each = 8, 4, 20
+	c := newChat(t, posters*each)
+	for p := range posters {
+		c.join("go", fmt.Sprint("poster", p))
+	}
+
+	seen := make([][]Message, pollers)
+	var wg sync.WaitGroup
+	for i := range pollers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			deadline := time.Now().Add(10 * time.Second)
+			for len(seen[i]) < posters*each && time.Now().Before(deadline) {
+				var since int64
+				if n := len(seen[i]); n > 0 {
+					since = seen[i][n-1].Seq
+				}
+				seen[i] = append(seen[i], c.poll("go", since, time.Second)...)
+			}
+		}()
+	}
+	for p := range posters {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := range each {
+				c.post("go", fmt.Sprint("poster", p), fmt.Sprint(n))
+			}
+		}()
+	}
+	wg.Wait()
+
+	for i, msgs := range seen {
+		if len(msgs) != posters*each {
+			t.Errorf("poller %d saw %d messages, want %d", i, len(msgs), posters*each)
+			continue
+		}
+		next := map[string]int{}
+		for j, m := range msgs {
+			if m.Seq != int64(j+1) {
+				t.Errorf("poller %d: message %d has seq %d", i, j, m.Seq)
+				break
+			}
+			if m.Text != fmt.Sprint(next[m.User]) {
+				t.Errorf("poller %d: %s's message %q, want %d", i, m.User, m.Text, next[m.User])
+				break
+			}
+			next[m.User]++
+		}
+		if !reflect.DeepEqual(msgs, seen[0]) {
+			t.Errorf("poller %d saw a different history from poller 0", i)
+		}
+	}
+}
+
+// TestConcurrentMembers joins and leaves from many clients at once:
+// presence ends with exactly those who stayed.
+func TestConcurrentMembers(t *testing.T) {
+	c := newChat(t, 10)
+	var wg sync.WaitGroup
+	var want []string
+	for i := range 20 {
+		user := fmt.Sprintf("user%02d", i)
+		if i%2 == 0 {
+			want = append(want, user)
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.join("go", user)
+			c.post("go", user, "hi")
+			if i%2 == 1 {
+				c.call("POST", "/rooms/go/leave", fmt.Sprintf(`{"user":%q}`, user), nil)
+			}
+		}()
+	}
+	wg.Wait()
+	if got := c.online("go"); !reflect.DeepEqual(got, want) {
+		t.Errorf("online = %v, want %v", got, want)
+	}
+}
+
+func TestHistoryLimit(t *testing.T) {
+	c := newChat(t, 3)
+	c.join("go", "ann")
+	for i := range 5 {
+		c.post("go", "ann", fmt.Sprint(i))
+	}
+	want := []string{"ann: 2", "ann: 3", "ann: 4"}
+	if got := texts(c.poll("go", 0, 0)); !reflect.DeepEqual(got, want) {
+		t.Errorf("history = %v, want %v", got, want)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	c := newChat(t, 10)
+	c.join("go", "ann")
+	for _, r := range []struct {
+		method, path, body string
+		status             int
+	}{
+		{"POST", "/rooms/go/join", `{}`, http.StatusBadRequest},
+		{"POST", "/rooms/go/messages", `{"user":"ann"}`, http.StatusBadRequest},
+		{"POST", "/rooms/go/messages", `{"user":"bob","text":"hi"}`, http.StatusForbidden},
+		{"POST", "/rooms/go/messages", `{"user":`, http.StatusBadRequest},
+		{"GET", "/rooms/go/messages?since=last", "", http.StatusBadRequest},
+		{"GET", "/rooms/go/messages?wait=forever", "", http.StatusBadRequest},
+	} {
+		if status := c.call(r.method, r.path, r.body, nil); status != r.status {
+			t.Errorf("%s %s %s = %d, want %d", r.method, r.path, r.body, status, r.status)
+		}
+	}
+}
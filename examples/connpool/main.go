@@ -0,0 +1,133 @@
+// Command connpool shows lifecycle/multicloser rolling back a constructor
+// that fails halfway: a pool dials its connections up front, and when the
+// third dial fails the two already open are closed again. Every
+// connection is a lifecycle/handle, so a pool that is never closed is
+// reported with the stack that opened it.
+//
+// usage:
+//
+//	go run patterns/examples/connpool
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"patterns/lifecycle/handle"
+)
+
+// echoServer echoes lines and counts open connections.
+type echoServer struct {
+	l    net.Listener
+	open atomic.Int64
+	wg   sync.WaitGroup
+}
+
+func startEcho() (*echoServer, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	s := &echoServer{l: l}
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			s.open.Add(1)
+			s.wg.Add(1)
+			go func() {
+				defer s.wg.Done()
+				defer s.open.Add(-1)
+				defer c.Close()
+				sc := bufio.NewScanner(c)
+				for sc.Scan() {
+					fmt.Fprintln(c, sc.Text())
+				}
+			}()
+		}
+	}()
+	return s, nil
+}
+
+// settle waits for the server to notice closed connections.
+func (s *echoServer) settle() int64 {
+	for range 100 {
+		if s.open.Load() == 0 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return s.open.Load()
+}
+
+func main() {
+	srv, err := startEcho()
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer srv.l.Close()
+	ctx := context.Background()
+	var d net.Dialer
+	dial := func(ctx context.Context) (net.Conn, error) {
+		return d.DialContext(ctx, "tcp", srv.l.Addr().String())
+	}
+
+	// partial construction: the third dial fails
+	var dials atomic.Int64
+	flaky := func(ctx context.Context) (net.Conn, error) {
+		if dials.Add(1) == 3 {
+			return nil, errors.New("connection refused")
+		}
+		return dial(ctx)
+	}
+	tracker := &handle.Tracker{Debug: true}
+	_, err = NewPool(ctx, flaky, WithSize(4), WithTracker(tracker))
+	fmt.Println("NewPool:", err)
+	fmt.Println("open after rollback:", srv.settle(), "handles:", tracker.Open())
+
+	// the success path: use the pool, then close it
+	pool, err := NewPool(ctx, dial, WithSize(4), WithTracker(tracker))
+	if err != nil {
+		log.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			h, err := pool.Get(ctx)
+			if err != nil {
+				log.Print(err)
+				return
+			}
+			defer pool.Put(h)
+			c := h.Get()
+			fmt.Fprintf(c, "ping %d\n", i)
+			if _, err := bufio.NewReader(c).ReadString('\n'); err != nil {
+				log.Print(err)
+			}
+		}()
+	}
+	wg.Wait()
+	fmt.Println("open while pooled:", srv.open.Load())
+	fmt.Println("Close:", pool.Close())
+	fmt.Println("open after Close:", srv.settle())
+	fmt.Println("leak check after Close:", tracker.Check())
+
+	// a pool nobody closes
+	leaky, err := NewPool(ctx, dial, WithSize(1), WithTracker(tracker))
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println("leak check without Close:", tracker.Check())
+	leaky.Close()
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"patterns/construct"
+	"patterns/funcopts"
+	"patterns/lifecycle/handle"
+	"patterns/lifecycle/multicloser"
+)
+
+// Dialer opens one connection.
+type Dialer func(ctx context.Context) (net.Conn, error)
+
+type options struct {
+	size         int
+	closeTimeout time.Duration
+	tracker      *handle.Tracker
+}
+
+type Option = funcopts.Option[options]
+
+func WithSize(n int) Option {
+	return func(options *options) error {
+		if n <= 0 {
+			return errors.New("pool size must be positive")
+		}
+		options.size = n
+		return nil
+	}
+}
+
+// WithCloseTimeout bounds how long closing one connection may take.
+func WithCloseTimeout(d time.Duration) Option {
+	return func(options *options) error {
+		if d < 0 {
+			return errors.New("close timeout cannot be negative")
+		}
+		options.closeTimeout = d
+		return nil
+	}
+}
+
+// WithTracker records each connection as a handle in t, so a pool that is
+// never closed shows up in t.Check.
+func WithTracker(t *handle.Tracker) Option {
+	return func(options *options) error {
+		options.tracker = t
+		return nil
+	}
+}
+
+func (o *options) SetDefaults() {
+	o.size = 4
+	o.closeTimeout = time.Second
+}
+
+// Pool is a fixed set of connections dialed up front.
+type Pool struct {
+	idle   chan *handle.Handle[net.Conn]
+	closer *multicloser.Stack
+}
+
+// NewPool dials every connection before returning. If any dial fails, the
+// connections already open are closed again, newest first, and the dial
+// error is returned joined with any close errors.
+func NewPool(ctx context.Context, dial Dialer, opts ...Option) (_ *Pool, err error) {
+	options, err := construct.New(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	mc := multicloser.Stack{Timeout: options.closeTimeout}
+	defer mc.Rollback(&err)
+	idle := make(chan *handle.Handle[net.Conn], options.size)
+	for i := range options.size {
+		c, err := dial(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("dial connection %d of %d: %w", i+1, options.size, err)
+		}
+		name := fmt.Sprintf("conn %d", i+1)
+		h := handle.Acquire(options.tracker, name, c, net.Conn.Close)
+		mc.Add(name, h)
+		idle <- h
+	}
+
+	return &Pool{idle: idle, closer: mc.Release()}, nil
+}
+
+// Get waits for an idle connection.
+func (p *Pool) Get(ctx context.Context) (*handle.Handle[net.Conn], error) {
+	select {
+	case h := <-p.idle:
+		return h, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Put returns a connection taken with Get.
+func (p *Pool) Put(h *handle.Handle[net.Conn]) { p.idle <- h }
+
+// Close closes every connection, including ones still checked out.
+func (p *Pool) Close() error { return p.closer.Close() }
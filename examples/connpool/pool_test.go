@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"patterns/lifecycle/handle"
+)
+
+// newEcho starts an echo server for the test, and a dialer counting its
+// dials that fails the ones listed in fail (1-based).
+func newEcho(t *testing.T, fail ...int64) (*echoServer, Dialer) {
+	t.Helper()
+	srv, err := startEcho()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		srv.l.Close()
+		srv.wg.Wait()
+	})
+	var d net.Dialer
+	var dials atomic.Int64
+	return srv, func(ctx context.Context) (net.Conn, error) {
+		n := dials.Add(1)
+		for _, f := range fail {
+			if n == f {
+				return nil, errors.New("connection refused")
+			}
+		}
+		return d.DialContext(ctx, "tcp", srv.l.Addr().String())
+	}
+}
+
+func echo(t *testing.T, c net.Conn, line string) {
+	t.Helper()
+	fmt.Fprintln(c, line)
+	got, err := bufio.NewReader(c).ReadString('\n')
+	if err != nil || got != line+"\n" {
+		t.Errorf("echo of %q = %q, %v", line, got, err)
+	}
+}
+
+func TestPool(t *testing.T) {
+	tracker := &handle.Tracker{Debug: true}
+	handle.Verify(t, tracker)
+	srv, dial := newEcho(t)
+	pool, err := NewPool(context.Background(), dial, WithSize(3), WithTracker(tracker))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := tracker.Open(); n != 3 {
+		t.Errorf("%d handles open, want 3", n)
+	}
+
+	var wg sync.WaitGroup
+	for i := range 12 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			h, err := pool.Get(context.Background())
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			defer pool.Put(h)
+			echo(t, h.Get(), fmt.Sprint("ping ", i))
+		}()
+	}
+	wg.Wait()
+	if n := srv.open.Load(); n != 3 {
+		t.Errorf("server has %d connections, want the pool's 3", n)
+	}
+	if err := pool.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if n := srv.settle(); n != 0 {
+		t.Errorf("server has %d connections after Close", n)
+	}
+}
+
+// TestRollback fails each dial of a constructor in turn: the connections
+// already open are closed, and no handle is left behind.
+func TestRollback(t *testing.T) {
+	const size = 4
+	for fail := int64(1); fail <= size; fail++ {
+		t.Run(fmt.Sprint("dial ", fail), func(t *testing.T) {
+			tracker := &handle.Tracker{Debug: true}
+			handle.Verify(t, tracker)
+			srv, dial := newEcho(t, fail)
+			pool, err := NewPool(context.Background(), dial, WithSize(size), WithTracker(tracker))
+			want := fmt.Sprintf("dial connection %d of %d: connection refused", fail, size)
+			if pool != nil || err == nil || err.Error() != want {
+				t.Errorf("NewPool = %v, %v; want %q", pool, err, want)
+			}
+			if n := srv.settle(); n != 0 {
+				t.Errorf("server has %d connections after the rollback", n)
+			}
+		})
+	}
+}
+
+// fakeTB stands in for the test Verify is given, so a leak can be
+// reported without failing this one.
+type fakeTB struct {
+	cleanups []func()
+	errors   []string
+}
+
+func (*fakeTB) Helper()             {}
+func (tb *fakeTB) Cleanup(f func()) { tb.cleanups = append(tb.cleanups, f) }
+func (tb *fakeTB) Errorf(format string, args ...any) {
+	tb.errors = append(tb.errors, fmt.Sprintf(format, args...))
+}
+
+// TestLeakReported checks that a pool nobody closes fails the test at
+// teardown, naming every connection and where the pool was made.
+func TestLeakReported(t *testing.T) {
+	tb := &fakeTB{}
+	tracker := &handle.Tracker{Debug: true}
+	handle.Verify(tb, tracker)
+	_, dial := newEcho(t)
+	pool, err := NewPool(context.Background(), dial, WithSize(2), WithTracker(tracker))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+	for _, f := range tb.cleanups {
+		f()
+	}
+	if len(tb.errors) != 1 {
+		t.Fatalf("Verify reported %d errors, want 1", len(tb.errors))
+	}
+	for _, want := range []string{"2 unclosed handle(s)", "conn 1 (open ", "conn 2 (open ", "connpool.NewPool\n", "connpool.TestLeakReported\n"} {
+		if !strings.Contains(tb.errors[0], want) {
+			t.Errorf("report lacks %q:\n%s", want, tb.errors[0])
+		}
+	}
+}
+
+func TestGetWaits(t *testing.T) {
+	tracker := &handle.Tracker{}
+	handle.Verify(t, tracker)
+	_, dial := newEcho(t)
+	pool, err := NewPool(context.Background(), dial, WithSize(1), WithTracker(tracker))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+	h, err := pool.Get(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := pool.Get(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Get with every connection out = %v, want the deadline", err)
+	}
+	pool.Put(h)
+	if got, err := pool.Get(context.Background()); err != nil || got != h {
+		t.Errorf("Get after Put = %v, %v", got, err)
+	}
+}
+
+// TestCloseCheckedOut checks that Close also closes connections still
+// checked out, so a handle taken and never put back is not a leak.
+func TestCloseCheckedOut(t *testing.T) {
+	tracker := &handle.Tracker{}
+	handle.Verify(t, tracker)
+	srv, dial := newEcho(t)
+	pool, err := NewPool(context.Background(), dial, WithSize(2), WithTracker(tracker))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h, _ := pool.Get(context.Background())
+	if err := pool.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !h.Closed() || srv.settle() != 0 {
+		t.Errorf("checked-out handle closed %v, server connections %d", h.Closed(), srv.open.Load())
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"patterns/errors/union"
+	"patterns/persistence/repository"
+	"patterns/web/handler"
+)
+
+type byISBN struct {
+	ISBN string `path:"isbn"`
+}
+
+type update struct {
+	ISBN string `path:"isbn" json:"-"`
+	Book
+}
+
+type created struct{ Book }
+
+func (created) StatusCode() int { return http.StatusCreated }
+
+type deleted struct {
+	Deleted string `json:"deleted"`
+}
+
+type none struct{}
+
+// routes exposes the service; every endpoint is a typed function and
+// every error goes through mapError.
+func routes(s *BookService) http.Handler {
+	mapErr := handler.WithErrorMapper(mapError)
+	mux := http.NewServeMux()
+	mux.Handle("GET /books", handler.Adapt(func(ctx context.Context, _ none) ([]Book, error) {
+		return s.List(ctx)
+	}, mapErr))
+	mux.Handle("POST /books", handler.Adapt(func(ctx context.Context, b Book) (created, error) {
+		b, err := s.Create(ctx, b)
+		return created{b}, err
+	}, mapErr))
+	mux.Handle("GET /books/{isbn}", handler.Adapt(func(ctx context.Context, req byISBN) (Book, error) {
+		return s.Get(ctx, req.ISBN)
+	}, mapErr))
+	mux.Handle("PUT /books/{isbn}", handler.Adapt(func(ctx context.Context, req update) (Book, error) {
+		return s.Update(ctx, req.ISBN, req.Book)
+	}, mapErr))
+	mux.Handle("DELETE /books/{isbn}", handler.Adapt(func(ctx context.Context, req byISBN) (deleted, error) {
+		return deleted{req.ISBN}, s.Delete(ctx, req.ISBN)
+	}, mapErr))
+	return mux
+}
+
+// mapError adds not-found to the service error union's mapping.
+func mapError(err error) (int, any) {
+	if errors.Is(err, repository.ErrNotFound) {
+		return http.StatusNotFound, map[string]string{"error": "book not found"}
+	}
+	return union.MapError(err)
+}
@@ -0,0 +1,287 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"patterns/testing/testoptions"
+)
+
+// testAPI is the environment newTestAPI builds: the wired service over
+// httptest, and the books seeded into it.
+type testAPI struct {
+	cfg   config
+	seed  int
+	url   string
+	books []Book
+	// repo, when set, replaces the configured store
+	repo BookRepository
+}
+
+type testOption = testoptions.Option[testAPI]
+
+// withFileStore stores books in a JSON file removed when the test ends.
+func withFileStore() testOption {
+	return func(t testoptions.T, e *testAPI) {
+		dir, err := os.MkdirTemp("", "crud")
+		if err != nil {
+			t.Fatalf("MkdirTemp: %v", err)
+		}
+		t.Cleanup(func() { os.RemoveAll(dir) })
+		e.cfg.store, e.cfg.file = "file", filepath.Join(dir, "books.json")
+	}
+}
+
+// withRepository stores books in r instead of the configured store.
+func withRepository(r BookRepository) testOption {
+	return func(_ testoptions.T, e *testAPI) {
+		e.repo = r
+	}
+}
+
+// withSeededBooks creates n valid books before the test starts.
+func withSeededBooks(n int) testOption {
+	return func(_ testoptions.T, e *testAPI) {
+		e.seed = n
+	}
+}
+
+// isbn returns a valid ISBN-13 with the given 12-digit prefix.
+func isbn(prefix int) string {
+	s := fmt.Sprintf("%012d", prefix)
+	sum := 0
+	for i, r := range s {
+		d := int(r - '0')
+		if i%2 == 1 {
+			d *= 3
+		}
+		sum += d
+	}
+	return s + fmt.Sprint((10-sum%10)%10)
+}
+
+func newTestAPI(t *testing.T, opts ...testOption) *testAPI {
+	t.Helper()
+	e := &testAPI{cfg: config{store: "memory"}}
+	testoptions.Apply(t, e, opts...)
+
+	c := wire(e.cfg)
+	// swapped like any provider: tests log nowhere
+	Provide(c, func(*Container) (*slog.Logger, error) {
+		return slog.New(slog.NewTextHandler(io.Discard, nil)), nil
+	})
+	if e.repo != nil {
+		Provide(c, func(*Container) (BookRepository, error) { return e.repo, nil })
+	}
+	service, err := Resolve[*BookService](c)
+	if err != nil {
+		t.Fatalf("resolve service: %v", err)
+	}
+	for i := range e.seed {
+		b, err := service.Create(context.Background(), Book{
+			ISBN: isbn(978000000000 + i), Title: fmt.Sprint("Book ", i), Author: "Author", Year: 2000 + i,
+		})
+		if err != nil {
+			t.Fatalf("seed: %v", err)
+		}
+		e.books = append(e.books, b)
+	}
+	srv, err := Resolve[*http.Server](c)
+	if err != nil {
+		t.Fatalf("resolve server: %v", err)
+	}
+	ts := httptest.NewServer(srv.Handler)
+	t.Cleanup(ts.Close)
+	e.url = ts.URL
+	return e
+}
+
+func TestListSeeded(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		opts []testOption
+	}{
+		{"memory", nil},
+		{"file", []testOption{withFileStore()}},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			e := newTestAPI(t, append(c.opts, withSeededBooks(3))...)
+			resp, err := http.Get(e.url + "/books")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			var got []Book
+			if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != http.StatusOK || fmt.Sprint(got) != fmt.Sprint(e.books) {
+				t.Errorf("GET /books = %s %v, want 200 %v", resp.Status, got, e.books)
+			}
+		})
+	}
+}
+
+// send makes a request to the API and returns its status and, if it is
+// JSON, its decoded body.
+func (e *testAPI) send(t *testing.T, method, path, body string) (int, any) {
+	t.Helper()
+	req, err := http.NewRequest(method, e.url+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var got any
+	if resp.Header.Get("Content-Type") == "application/json" {
+		if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+			t.Fatalf("%s %s: %v", method, path, err)
+		}
+	}
+	return resp.StatusCode, got
+}
+
+func bookJSON(isbn, title, author string, year int) string {
+	b, _ := json.Marshal(Book{ISBN: isbn, Title: title, Author: author, Year: year})
+	return string(b)
+}
+
+// TestStatusCodes sends one request per status-code path of the API, each
+// to a fresh service holding one book.
+func TestStatusCodes(t *testing.T) {
+	seeded, fresh := isbn(978000000000), isbn(978000000001)
+	stored := map[string]any{"isbn": seeded, "title": "Book 0", "author": "Author", "year": float64(2000)}
+	invalid := func(field, reason string) map[string]any {
+		return map[string]any{"error": "invalid " + field + ": " + reason, "field": field}
+	}
+	notFound := map[string]any{"error": "book not found"}
+	for _, c := range []struct {
+		name               string
+		method, path, body string
+		status             int
+		// want is the decoded body, if checked
+		want any
+	}{
+		{"list", "GET", "/books", "", 200, []any{stored}},
+		{"get", "GET", "/books/" + seeded, "", 200, stored},
+		{"get missing", "GET", "/books/" + fresh, "", 404, notFound},
+		{"create", "POST", "/books", bookJSON(fresh, "New", "Someone", 2024), 201,
+			map[string]any{"isbn": fresh, "title": "New", "author": "Someone", "year": float64(2024)}},
+		{"create existing", "POST", "/books", bookJSON(seeded, "Again", "Someone", 2024), 409,
+			map[string]any{"error": fmt.Sprintf("book %q already exists", seeded)}},
+		{"create bad isbn", "POST", "/books", bookJSON("9780000000003", "New", "Someone", 2024), 422,
+			invalid("isbn", "must be 13 digits with a valid check digit")},
+		{"create short isbn", "POST", "/books", bookJSON("978", "New", "Someone", 2024), 422,
+			invalid("isbn", "must be 13 digits with a valid check digit")},
+		{"create no title", "POST", "/books", bookJSON(fresh, " ", "Someone", 2024), 422, invalid("title", "is required")},
+		{"create no author", "POST", "/books", bookJSON(fresh, "New", "", 2024), 422, invalid("author", "is required")},
+		{"create too old", "POST", "/books", bookJSON(fresh, "New", "Someone", 1449), 422,
+			invalid("year", "must be between 1450 and 2100")},
+		{"create truncated body", "POST", "/books", `{"isbn":`, 400, nil},
+		{"create wrong type", "POST", "/books", `{"year":"recent"}`, 400, nil},
+		{"update", "PUT", "/books/" + seeded, bookJSON(seeded, "Revised", "Author", 2001), 200,
+			map[string]any{"isbn": seeded, "title": "Revised", "author": "Author", "year": float64(2001)}},
+		{"update key from path", "PUT", "/books/" + seeded, bookJSON("", "Revised", "Author", 2001), 200,
+			map[string]any{"isbn": seeded, "title": "Revised", "author": "Author", "year": float64(2001)}},
+		{"update missing", "PUT", "/books/" + fresh, bookJSON(fresh, "New", "Someone", 2024), 404, notFound},
+		{"update moving key", "PUT", "/books/" + seeded, bookJSON(fresh, "Moved", "Author", 2000), 422,
+			invalid("isbn", "does not match the path")},
+		{"update invalid", "PUT", "/books/" + seeded, bookJSON(seeded, "Book 0", "Author", 2101), 422,
+			invalid("year", "must be between 1450 and 2100")},
+		{"update truncated body", "PUT", "/books/" + seeded, `{`, 400, nil},
+		{"delete", "DELETE", "/books/" + seeded, "", 200, map[string]any{"deleted": seeded}},
+		{"delete missing", "DELETE", "/books/" + fresh, "", 404, notFound},
+		{"method not allowed", "PATCH", "/books/" + seeded, "{}", 405, nil},
+		{"no route", "GET", "/authors", "", 404, nil},
+	} {
+		e := newTestAPI(t, withSeededBooks(1))
+		status, got := e.send(t, c.method, c.path, c.body)
+		if status != c.status || (c.want != nil && !reflect.DeepEqual(got, c.want)) {
+			t.Errorf("%s: %s %s = %d %v, want %d %v", c.name, c.method, c.path, status, got, c.status, c.want)
+		}
+	}
+}
+
+// brokenRepository fails every call, as a store whose disk is gone.
+type brokenRepository struct{}
+
+var errDisk = errors.New("write books.json: no space left on device")
+
+func (brokenRepository) Get(context.Context, string) (Book, error)  { return Book{}, errDisk }
+func (brokenRepository) Create(context.Context, string, Book) error { return errDisk }
+func (brokenRepository) Update(context.Context, string, Book) error { return errDisk }
+func (brokenRepository) Delete(context.Context, string) error       { return errDisk }
+func (brokenRepository) List(context.Context) ([]Book, error)       { return nil, errDisk }
+
+// TestStorageFailure checks that every endpoint reports a failing store
+// as an opaque 500, without the store's error.
+func TestStorageFailure(t *testing.T) {
+	e := newTestAPI(t, withRepository(brokenRepository{}))
+	book := isbn(978000000000)
+	for _, r := range []struct{ method, path, body string }{
+		{"GET", "/books", ""},
+		{"GET", "/books/" + book, ""},
+		{"POST", "/books", bookJSON(book, "New", "Someone", 2024)},
+		{"PUT", "/books/" + book, bookJSON(book, "New", "Someone", 2024)},
+		{"DELETE", "/books/" + book, ""},
+	} {
+		status, got := e.send(t, r.method, r.path, r.body)
+		if want := map[string]any{"error": "internal error"}; status != 500 || !reflect.DeepEqual(got, want) {
+			t.Errorf("%s %s = %d %v, want 500 %v", r.method, r.path, status, got, want)
+		}
+	}
+}
+
+// TestWritesPersist checks that each write is seen by later reads, in
+// either store.
+func TestWritesPersist(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		opts []testOption
+	}{
+		{"memory", nil},
+		{"file", []testOption{withFileStore()}},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			e := newTestAPI(t, c.opts...)
+			book := isbn(978000000000)
+			for _, step := range []struct {
+				method, body string
+				status       int
+				then         int
+				title        string
+			}{
+				{"POST", bookJSON(book, "Draft", "Someone", 2024), 201, 200, "Draft"},
+				{"PUT", bookJSON(book, "Final", "Someone", 2024), 200, 200, "Final"},
+				{"DELETE", "", 200, 404, ""},
+			} {
+				path := "/books/" + book
+				if step.method == "POST" {
+					path = "/books"
+				}
+				if status, _ := e.send(t, step.method, path, step.body); status != step.status {
+					t.Fatalf("%s = %d, want %d", step.method, status, step.status)
+				}
+				status, got := e.send(t, "GET", "/books/"+book, "")
+				title, _ := got.(map[string]any)["title"].(string)
+				if status != step.then || (step.title != "" && title != step.title) {
+					t.Errorf("GET after %s = %d %v, want %d with title %q", step.method, status, got, step.then, step.title)
+				}
+			}
+		})
+	}
+}
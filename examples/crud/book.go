@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+
+	"patterns/errors/union"
+	"patterns/persistence/repository"
+)
+
+// Book is the resource; the ISBN-13 is its key.
+type Book struct {
+	ISBN   string `json:"isbn"`
+	Title  string `json:"title"`
+	Author string `json:"author"`
+	Year   int    `json:"year"`
+}
+
+func (b Book) validate() error {
+	switch {
+	case !validISBN(b.ISBN):
+		return invalid("isbn", "must be 13 digits with a valid check digit")
+	case strings.TrimSpace(b.Title) == "":
+		return invalid("title", "is required")
+	case strings.TrimSpace(b.Author) == "":
+		return invalid("author", "is required")
+	case b.Year < 1450 || b.Year > 2100:
+		return invalid("year", "must be between 1450 and 2100")
+	}
+	return nil
+}
+
+func validISBN(s string) bool {
+	if len(s) != 13 {
+		return false
+	}
+	sum := 0
+	for i, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+		d := int(r - '0')
+		if i%2 == 1 {
+			d *= 3
+		}
+		sum += d
+	}
+	return sum%10 == 0
+}
+
+func invalid(field, reason string) error {
+	return union.Of3A[*union.ValidationError, *union.ConflictError, *union.InternalError](
+		&union.ValidationError{Field: field, Reason: reason})
+}
+
+func conflict(isbn string) error {
+	return union.Of3B[*union.ValidationError, *union.ConflictError, *union.InternalError](
+		&union.ConflictError{Resource: "book", ID: isbn})
+}
+
+func internal(err error) error {
+	return union.Of3C[*union.ValidationError, *union.ConflictError, *union.InternalError](
+		&union.InternalError{Err: err})
+}
+
+type BookRepository = repository.Repository[string, Book]
+
+// BookService holds the business rules; it depends on the repository
+// interface only, and the container decides which one it gets.
+type BookService struct {
+	books BookRepository
+}
+
+func NewBookService(books BookRepository) *BookService {
+	return &BookService{books: books}
+}
+
+func (s *BookService) Create(ctx context.Context, b Book) (Book, error) {
+	if err := b.validate(); err != nil {
+		return Book{}, err
+	}
+	err := s.books.Create(ctx, b.ISBN, b)
+	if errors.Is(err, repository.ErrExists) {
+		return Book{}, conflict(b.ISBN)
+	}
+	if err != nil {
+		return Book{}, internal(err)
+	}
+	return b, nil
+}
+
+// Update replaces the book stored under isbn; the body cannot move it to
+// another key.
+func (s *BookService) Update(ctx context.Context, isbn string, b Book) (Book, error) {
+	if b.ISBN == "" {
+		b.ISBN = isbn
+	}
+	if b.ISBN != isbn {
+		return Book{}, invalid("isbn", "does not match the path")
+	}
+	if err := b.validate(); err != nil {
+		return Book{}, err
+	}
+	if err := s.books.Update(ctx, isbn, b); err != nil {
+		return Book{}, err
+	}
+	return b, nil
+}
+
+func (s *BookService) Get(ctx context.Context, isbn string) (Book, error) {
+	return s.books.Get(ctx, isbn)
+}
+
+func (s *BookService) Delete(ctx context.Context, isbn string) error {
+	return s.books.Delete(ctx, isbn)
+}
+
+// List returns every book ordered by ISBN.
+func (s *BookService) List(ctx context.Context) ([]Book, error) {
+	books, err := s.books.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if books == nil {
+		books = []Book{}
+	}
+	slices.SortFunc(books, func(a, b Book) int { return strings.Compare(a.ISBN, b.ISBN) })
+	return books, nil
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Container is a minimal type-keyed dependency injection container:
+// providers are registered per type and resolved lazily, once, with
+// cycle detection. main is the only place that knows the graph.
+type Container struct {
+	mu        sync.Mutex
+	providers map[reflect.Type]func(*Container) (any, error)
+	instances map[reflect.Type]any
+	resolving []reflect.Type
+}
+
+func NewContainer() *Container {
+	return &Container{
+		providers: map[reflect.Type]func(*Container) (any, error){},
+		instances: map[reflect.Type]any{},
+	}
+}
+
+// Provide registers how to build a T; a later Provide for the same type
+// replaces the earlier one, which is how tests or flags swap
+// implementations.
+func Provide[T any](c *Container, build func(*Container) (T, error)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.providers[reflect.TypeFor[T]()] = func(c *Container) (any, error) { return build(c) }
+}
+
+// Resolve returns the singleton T, building it and its dependencies on
+// first use.
+func Resolve[T any](c *Container) (T, error) {
+	var zero T
+	t := reflect.TypeFor[T]()
+
+	c.mu.Lock()
+	if v, ok := c.instances[t]; ok {
+		c.mu.Unlock()
+		return v.(T), nil
+	}
+	build, ok := c.providers[t]
+	if !ok {
+		c.mu.Unlock()
+		return zero, fmt.Errorf("di: no provider for %s", t)
+	}
+	for _, r := range c.resolving {
+		if r == t {
+			path := append(append([]reflect.Type{}, c.resolving...), t)
+			c.mu.Unlock()
+			return zero, fmt.Errorf("di: cycle %s", formatPath(path))
+		}
+	}
+	c.resolving = append(c.resolving, t)
+	c.mu.Unlock()
+
+	// build without the lock: it resolves its own dependencies
+	v, err := build(c)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.resolving = c.resolving[:len(c.resolving)-1]
+	if err != nil {
+		return zero, fmt.Errorf("di: build %s: %w", t, err)
+	}
+	c.instances[t] = v
+	return v.(T), nil
+}
+
+func formatPath(path []reflect.Type) string {
+	names := make([]string, len(path))
+	for i, t := range path {
+		names[i] = t.String()
+	}
+	return strings.Join(names, " -> ")
+}
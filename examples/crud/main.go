@@ -0,0 +1,101 @@
+// Command crud is a REST CRUD service for books wired from patterns:
+//
+//   - typed endpoints behind patterns/web/handler
+//   - validation and conflicts reported through the patterns/errors/union
+//     ServiceError, mapped to 422/409/500, plus 404 for missing books
+//   - storage behind patterns/persistence/repository, in memory or in a
+//     JSON file (the stand-in for sqlite, which needs a non-stdlib driver)
+//   - a small DI container that is the only place knowing the wiring
+//
+// usage:
+//
+//	go run patterns/examples/crud -store file -file books.json
+//	curl -d '{"isbn":"9780134190440","title":"The Go Programming Language","author":"Donovan, Kernighan","year":2015}' localhost:8080/books
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"time"
+
+	"patterns/lifecycle/shutdown"
+	"patterns/persistence/repository"
+	"patterns/web/middleware"
+)
+
+type config struct {
+	addr  string
+	store string
+	file  string
+}
+
+func main() {
+	var cfg config
+	flag.StringVar(&cfg.addr, "addr", "localhost:8080", "listen address")
+	flag.StringVar(&cfg.store, "store", "memory", "storage backend: memory or file")
+	flag.StringVar(&cfg.file, "file", "books.json", "file for -store file")
+	flag.Parse()
+
+	c := wire(cfg)
+	srv, err := Resolve[*http.Server](c)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	l, err := net.Listen("tcp", cfg.addr)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("listening on %s", l.Addr())
+	if err := shutdown.Serve(ctx, srv, l, 5*time.Second); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// wire registers every provider; nothing is built until resolved.
+func wire(cfg config) *Container {
+	c := NewContainer()
+	Provide(c, func(*Container) (*slog.Logger, error) {
+		return slog.New(slog.NewTextHandler(os.Stderr, nil)), nil
+	})
+	Provide(c, func(*Container) (BookRepository, error) {
+		switch cfg.store {
+		case "memory":
+			return repository.NewMemory[string, Book](), nil
+		case "file":
+			return repository.OpenFile[string, Book](cfg.file)
+		}
+		return nil, fmt.Errorf("unknown store %q", cfg.store)
+	})
+	Provide(c, func(c *Container) (*BookService, error) {
+		books, err := Resolve[BookRepository](c)
+		if err != nil {
+			return nil, err
+		}
+		return NewBookService(books), nil
+	})
+	Provide(c, func(c *Container) (*http.Server, error) {
+		service, err := Resolve[*BookService](c)
+		if err != nil {
+			return nil, err
+		}
+		logger, err := Resolve[*slog.Logger](c)
+		if err != nil {
+			return nil, err
+		}
+		return &http.Server{
+			Handler:           middleware.Chain(routes(service), middleware.Logging(logger)),
+			ReadHeaderTimeout: 5 * time.Second,
+		}, nil
+	})
+	return c
+}
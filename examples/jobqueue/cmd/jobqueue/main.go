@@ -0,0 +1,170 @@
+// Command jobqueue runs the examples/jobqueue runner against injected
+// failures.
+//
+// Orders are written together with their confirmation email job (outbox).
+// Email delivery fails at -fail-rate and is retried with backoff; the
+// "audit" kind always fails, which opens its circuit breaker without
+// slowing emails down. -crash-after kills the process mid-run: start it
+// again with the same -dir and leased jobs are recovered once their lease
+// expires.
+//
+// Jobs that run out of attempts, and the emails of orders without an
+// address, which fail permanently, end up in the dead-letter store without
+// holding up the rest; -requeue puts them back in the queue before running.
+//
+// usage:
+//
+//	go run patterns/examples/jobqueue/cmd/jobqueue -dir /tmp/jobs -orders 20 -fail-rate 0.3
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"time"
+
+	"patterns/examples/jobqueue"
+	"patterns/randsource"
+)
+
+func main() {
+	dir := flag.String("dir", "jobdata", "data directory")
+	orders := flag.Int("orders", 20, "orders to create on a fresh store")
+	failRate := flag.Float64("fail-rate", 0.3, "probability an email attempt fails")
+	crashAfter := flag.Duration("crash-after", 0, "exit abruptly after this long (0: never)")
+	seed := flag.Uint64("seed", 0, "seed for injected failures and jitter (0: random)")
+	requeue := flag.Bool("requeue", false, "move dead-lettered jobs back to the queue first")
+	flag.Parse()
+
+	rng := randsource.Global
+	if *seed != 0 {
+		rng = randsource.New(*seed)
+	}
+
+	if err := os.MkdirAll(*dir, 0o755); err != nil {
+		log.Fatal(err)
+	}
+	store, err := jobqueue.OpenStore(*dir + "/store.json")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if len(store.Jobs()) == 0 {
+		for i := range *orders {
+			err := store.Update(func(tx *jobqueue.Tx) error {
+				id := fmt.Sprintf("order:%d", i)
+				// every seventh order has no address and cannot be emailed
+				tx.Set(id, "placed")
+				if i%7 == 6 {
+					tx.Set(id+":address", "")
+				} else {
+					tx.Set(id+":address", fmt.Sprintf("customer%d@example.com", i))
+				}
+				// every fifth order is a priority customer and is audited
+				priority := i % 5 / 4
+				if _, err := tx.Enqueue("email", id, priority); err != nil {
+					return err
+				}
+				if priority == 0 {
+					return nil
+				}
+				_, err := tx.Enqueue("audit", id, priority)
+				return err
+			})
+			if err != nil {
+				log.Fatal(err)
+			}
+		}
+	}
+
+	if *requeue {
+		for _, d := range store.DeadLetters() {
+			if err := store.Requeue(d.Job.ID); err != nil {
+				log.Fatal(err)
+			}
+		}
+	}
+
+	var attempts, sent atomic.Int64
+	handlers := map[string]jobqueue.Handler{
+		"email": func(ctx context.Context, job jobqueue.Job) error {
+			attempts.Add(1)
+			var order string
+			if err := json.Unmarshal(job.Payload, &order); err != nil {
+				return jobqueue.Permanent(err)
+			}
+			var address string
+			store.View(func(tx *jobqueue.Tx) { address, _ = tx.Get(order + ":address") })
+			if address == "" {
+				return jobqueue.Permanent(fmt.Errorf("%s has no address", order))
+			}
+			if rng.Float64() < *failRate {
+				return errors.New("smtp: temporary failure")
+			}
+			sent.Add(1)
+			return nil
+		},
+		"audit": func(ctx context.Context, job jobqueue.Job) error {
+			return errors.New("audit service unavailable")
+		},
+	}
+	runner, err := jobqueue.NewRunner(store, handlers,
+		jobqueue.WithWorkers(4),
+		jobqueue.WithRetry(6, 10*time.Millisecond, 200*time.Millisecond),
+		jobqueue.WithLease(time.Second),
+		jobqueue.WithRand(rng),
+		jobqueue.WithBreaker(3, 100*time.Millisecond),
+	)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if *crashAfter > 0 {
+		time.AfterFunc(*crashAfter, func() {
+			fmt.Println("crash!")
+			os.Exit(1)
+		})
+	}
+	go func() {
+		// stop once every job has settled
+		for ctx.Err() == nil {
+			time.Sleep(50 * time.Millisecond)
+			if settled(store.Jobs()) {
+				stop()
+			}
+		}
+	}()
+	if err := runner.Run(ctx); err != nil {
+		log.Fatal(err)
+	}
+
+	counts := map[jobqueue.State]int{}
+	for _, j := range store.Jobs() {
+		counts[j.State]++
+	}
+	fmt.Printf("email attempts=%d sent=%d\n", attempts.Load(), sent.Load())
+	dead := store.DeadLetters()
+	fmt.Printf("pending=%d running=%d done=%d dead=%d\n",
+		counts[jobqueue.Pending], counts[jobqueue.Running], counts[jobqueue.Done], len(dead))
+	for _, d := range dead {
+		fmt.Printf("  dead letter %d %s %s: %s after %d attempts: %s\n",
+			d.Job.ID, d.Job.Kind, d.Job.Payload, d.Reason, d.Job.Attempts, d.Job.LastError)
+	}
+}
+
+func settled(jobs []jobqueue.Job) bool {
+	for _, j := range jobs {
+		if j.State == jobqueue.Pending || j.State == jobqueue.Running {
+			return false
+		}
+	}
+	return true
+}
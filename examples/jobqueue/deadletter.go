@@ -0,0 +1,107 @@
+package jobqueue
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Failure is one failed attempt of a job.
+type Failure struct {
+	Attempt int       `json:"attempt"`
+	At      time.Time `json:"at"`
+	Error   string    `json:"error"`
+}
+
+// DeadLetter is a job taken out of the queue after its last attempt
+// failed, kept with why, so an operator can inspect, fix and requeue it.
+type DeadLetter struct {
+	Job    Job       `json:"job"`
+	Reason string    `json:"reason"`
+	At     time.Time `json:"at"`
+}
+
+const (
+	ReasonExhausted = "retries exhausted"
+	ReasonPermanent = "permanent failure"
+)
+
+var ErrNotDeadLettered = errors.New("jobqueue: job is not dead-lettered")
+
+// Permanent marks err as one retrying cannot fix, such as a payload that
+// does not decode: the job is dead-lettered at once, and the failure does
+// not count against the breaker of its kind, since the message is at
+// fault and not whatever the handler calls.
+func Permanent(err error) error { return permanentError{err} }
+
+type permanentError struct{ err error }
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// IsPermanent reports whether err, or an error it wraps, was marked with
+// Permanent.
+func IsPermanent(err error) bool {
+	var p permanentError
+	return errors.As(err, &p)
+}
+
+// deadLetter moves j from the queue to the dead-letter store. Workers only
+// scan the queue, so a poison job costs them nothing once it is here.
+func (tx *Tx) deadLetter(j *Job, reason string) {
+	j.State = Dead
+	tx.state.DeadLetters = append(tx.state.DeadLetters, &DeadLetter{Job: *j, Reason: reason, At: tx.now})
+	for i, q := range tx.state.Jobs {
+		if q == j {
+			tx.state.Jobs = append(tx.state.Jobs[:i], tx.state.Jobs[i+1:]...)
+			break
+		}
+	}
+}
+
+// DeadLetters returns a copy of every dead-lettered job, oldest first.
+func (s *Store) DeadLetters() []DeadLetter {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]DeadLetter, len(s.state.DeadLetters))
+	for i, d := range s.state.DeadLetters {
+		out[i] = *d
+		out[i].Job.Failures = slices.Clone(d.Job.Failures)
+	}
+	return out
+}
+
+// Requeue moves a dead-lettered job back to the queue as a fresh pending
+// job with the same ID, runnable at once and with all its attempts ahead
+// of it. Its failures stay in Failures.
+func (s *Store) Requeue(id int64) error {
+	return s.Update(func(tx *Tx) error {
+		d, err := tx.takeDeadLetter(id)
+		if err != nil {
+			return err
+		}
+		j := d.Job
+		j.State, j.Attempts, j.NextRun = Pending, 0, tx.now
+		tx.state.Jobs = append(tx.state.Jobs, &j)
+		return nil
+	})
+}
+
+// Discard deletes a dead-lettered job for good.
+func (s *Store) Discard(id int64) error {
+	return s.Update(func(tx *Tx) error {
+		_, err := tx.takeDeadLetter(id)
+		return err
+	})
+}
+
+func (tx *Tx) takeDeadLetter(id int64) (*DeadLetter, error) {
+	for i, d := range tx.state.DeadLetters {
+		if d.Job.ID == id {
+			tx.state.DeadLetters = append(tx.state.DeadLetters[:i], tx.state.DeadLetters[i+1:]...)
+			return d, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %d", ErrNotDeadLettered, id)
+}
@@ -0,0 +1,181 @@
+package jobqueue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// TestPoisonDoesNotBlock puts a poison job ahead of the others, one that
+// fails every attempt: the rest keep flowing between its retries, and once
+// it is dead-lettered the workers never see it again.
+func TestPoisonDoesNotBlock(t *testing.T) {
+	poison, ok := 0, 0
+	f := newFixture(t, map[string]Handler{
+		"poison": failing(99, errors.New("cannot parse"), &poison),
+		"ok":     failing(0, nil, &ok),
+	}, WithRetry(3, time.Second, time.Second))
+	id := f.enqueue("poison", 9)
+	for range 6 {
+		f.enqueue("ok", 0)
+	}
+	for range 20 {
+		if _, ran := f.step(); !ran {
+			f.clock.Advance(time.Second)
+		}
+	}
+	if poison != 3 || ok != 6 {
+		t.Fatalf("%d poison calls, %d ok calls; want 3 and 6", poison, ok)
+	}
+	for _, j := range f.store.Jobs() {
+		if j.ID == id || j.State != Done {
+			t.Errorf("job %d %s is %s in the queue", j.ID, j.Kind, j.State)
+		}
+	}
+	dead := f.store.DeadLetters()
+	if len(dead) != 1 || dead[0].Job.ID != id || dead[0].Job.State != Dead || dead[0].Reason != ReasonExhausted {
+		t.Fatalf("dead letters = %+v", dead)
+	}
+	// every attempt is on record, a second of backoff apart
+	d := dead[0]
+	for i, fail := range d.Job.Failures {
+		if fail.Attempt != i+1 || fail.Error != "cannot parse" || i > 0 && fail.At.Sub(d.Job.Failures[i-1].At) != time.Second {
+			t.Errorf("failure %d = %+v", i, fail)
+		}
+	}
+	if len(d.Job.Failures) != 3 || !d.At.Equal(d.Job.Failures[2].At) || d.Job.LastError != "cannot parse" {
+		t.Errorf("dead letter at %v, last error %q, failures %+v", d.At, d.Job.LastError, d.Job.Failures)
+	}
+}
+
+// TestPermanentWrapped checks that a Permanent error still dead-letters
+// at once when the handler wraps it.
+func TestPermanentWrapped(t *testing.T) {
+	calls := 0
+	bad := fmt.Errorf("order 7: %w", Permanent(errors.New("unknown currency")))
+	f := newFixture(t, map[string]Handler{"k": failing(1, bad, &calls)})
+	id := f.enqueue("k", 0)
+	f.step()
+	dead := f.store.DeadLetters()
+	if len(dead) != 1 || dead[0].Job.ID != id || dead[0].Reason != ReasonPermanent || dead[0].Job.LastError != "order 7: unknown currency" {
+		t.Errorf("dead letters = %+v", dead)
+	}
+	if IsPermanent(errors.New("x")) || !IsPermanent(bad) {
+		t.Error("IsPermanent does not follow the chain")
+	}
+}
+
+// TestRequeue dead-letters a job, fixes its handler and requeues it: it
+// runs with all its attempts ahead of it, its history kept. The dead
+// letter and the requeue both survive a restart.
+func TestRequeue(t *testing.T) {
+	calls := 0
+	handlers := map[string]Handler{"k": failing(2, errors.New("down"), &calls)}
+	f := newFixture(t, handlers, WithRetry(2, time.Second, time.Second))
+	id := f.enqueue("k", 0)
+	f.step()
+	f.clock.Advance(time.Second)
+	f.step()
+	f.clock.Advance(time.Hour)
+
+	f.open(handlers, WithRetry(2, time.Second, time.Second))
+	if dead := f.store.DeadLetters(); len(dead) != 1 || len(dead[0].Job.Failures) != 2 {
+		t.Fatalf("dead letters after restart = %+v", dead)
+	}
+	if err := f.store.Requeue(id); err != nil {
+		t.Fatal(err)
+	}
+	f.open(handlers, WithRetry(2, time.Second, time.Second))
+	if len(f.store.DeadLetters()) != 0 {
+		t.Error("requeued job is still dead-lettered")
+	}
+	if j := f.job(id); j.State != Pending || j.Attempts != 0 || !j.NextRun.Equal(f.clock.Now()) || len(j.Failures) != 2 {
+		t.Fatalf("requeued job: %s, %d attempts, next run %v, failures %v", j.State, j.Attempts, j.NextRun, j.Failures)
+	}
+	if j, ok := f.step(); !ok || j.ID != id {
+		t.Fatal("requeued job did not run at once")
+	}
+	if j := f.job(id); j.State != Done || j.LastError != "" || calls != 3 {
+		t.Errorf("requeued job: %s, last error %q, %d calls", j.State, j.LastError, calls)
+	}
+
+	if err := f.store.Requeue(id); !errors.Is(err, ErrNotDeadLettered) {
+		t.Errorf("Requeue of a done job = %v", err)
+	}
+}
+
+func TestDiscard(t *testing.T) {
+	calls := 0
+	f := newFixture(t, map[string]Handler{"k": failing(99, Permanent(errors.New("bad")), &calls)})
+	first, second := f.enqueue("k", 0), f.enqueue("k", 0)
+	f.step()
+	f.step()
+	if err := f.store.Discard(first); err != nil {
+		t.Fatal(err)
+	}
+	f.open(nil)
+	if dead := f.store.DeadLetters(); len(dead) != 1 || dead[0].Job.ID != second {
+		t.Errorf("dead letters after Discard = %+v, want only job %d", dead, second)
+	}
+	if len(f.store.Jobs()) != 0 {
+		t.Errorf("queue holds %v", f.store.Jobs())
+	}
+	for _, err := range []error{f.store.Discard(first), f.store.Requeue(first), f.store.Discard(99)} {
+		if !errors.Is(err, ErrNotDeadLettered) {
+			t.Errorf("error = %v, want ErrNotDeadLettered", err)
+		}
+	}
+	if err := f.store.Discard(first); err == nil || err.Error() != fmt.Sprintf("jobqueue: job is not dead-lettered: %d", first) {
+		t.Errorf("Discard = %v", err)
+	}
+}
+
+// TestDeadLettersCopy checks that callers cannot change the store through
+// what DeadLetters returns.
+func TestDeadLettersCopy(t *testing.T) {
+	calls := 0
+	f := newFixture(t, map[string]Handler{"k": failing(1, Permanent(errors.New("bad")), &calls)})
+	f.enqueue("k", 0)
+	f.step()
+	dead := f.store.DeadLetters()
+	dead[0].Reason = "changed"
+	dead[0].Job.Failures[0].Error = "changed"
+	if again := f.store.DeadLetters(); again[0].Reason != ReasonPermanent || again[0].Job.Failures[0].Error != "bad" {
+		t.Errorf("store changed through a copy: %+v", again[0])
+	}
+}
+
+// TestRunPoison runs the worker pool with poison jobs mixed in: the
+// healthy ones all complete and the poison ones all end dead-lettered.
+func TestRunPoison(t *testing.T) {
+	f := newFixture(t, map[string]Handler{
+		"poison": func(context.Context, Job) error { panic("corrupt") },
+		"ok":     func(context.Context, Job) error { return nil },
+	}, WithWorkers(3), WithRetry(2, time.Second, time.Second), WithPollInterval(time.Second))
+	for i := range 12 {
+		f.enqueue([]string{"poison", "ok", "ok"}[i%3], 3-i%3)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- f.r.Run(ctx) }()
+	for len(f.store.DeadLetters()) < 4 {
+		f.clock.BlockUntil(3)
+		f.clock.Advance(time.Second)
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	for _, j := range f.store.Jobs() {
+		if j.Kind != "ok" || j.State != Done {
+			t.Errorf("job %d %s is %s in the queue", j.ID, j.Kind, j.State)
+		}
+	}
+	for _, d := range f.store.DeadLetters() {
+		if d.Job.Kind != "poison" || d.Reason != ReasonExhausted || d.Job.LastError != "panic: corrupt" {
+			t.Errorf("dead letter %+v", d)
+		}
+	}
+}
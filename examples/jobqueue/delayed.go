@@ -0,0 +1,27 @@
+package jobqueue
+
+import (
+	"context"
+
+	"patterns/messaging/delayed"
+)
+
+// Delayed returns a delayed.Deliver enqueueing each due message as a job
+// of its topic's kind, so work can be scheduled for later: the scheduler
+// holds it until it is due, the runner takes over from there. The job and
+// a record of the message ID are written in one Update, so a message the
+// scheduler delivers again after a crash is enqueued once. The records
+// are kept for good; every scheduler feeding s must use its own file.
+func Delayed(s *Store, priority int) delayed.Deliver {
+	return func(ctx context.Context, m delayed.Message) error {
+		return s.Update(func(tx *Tx) error {
+			key := "delayed:" + m.ID
+			if _, ok := tx.Get(key); ok {
+				return nil
+			}
+			tx.Set(key, "enqueued")
+			_, err := tx.Enqueue(m.Topic, m.Payload, priority)
+			return err
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package jobqueue
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"patterns/messaging/delayed"
+)
+
+// TestDelayed schedules a job for later: it is enqueued when due, once
+// however often the scheduler delivers it, and then runs.
+func TestDelayed(t *testing.T) {
+	calls := 0
+	f := newFixture(t, map[string]Handler{"remind": failing(0, nil, &calls)})
+	s, err := delayed.Open(filepath.Join(t.TempDir(), "delayed.json"), delayed.WithClock(f.clock))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.After("remind", map[string]string{"order": "7"}, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	deliver := Delayed(f.store, 3)
+	// as a crash after delivering and before recording it would
+	once := errors.New("crash")
+	crashed := false
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- s.Run(ctx, func(ctx context.Context, m delayed.Message) error {
+			if err := deliver(ctx, m); err != nil || crashed {
+				return err
+			}
+			crashed = true
+			return once
+		})
+	}()
+	f.clock.BlockUntil(1)
+	if _, ok := f.step(); ok {
+		t.Fatal("a job ran before it was due")
+	}
+	f.clock.Advance(time.Hour)
+	// the first delivery fails after enqueuing, the retry succeeds
+	f.clock.BlockUntil(1)
+	f.clock.Advance(time.Second)
+	for len(s.Pending()) > 0 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	jobs := f.store.Jobs()
+	if len(jobs) != 1 || jobs[0].Kind != "remind" || jobs[0].Priority != 3 || string(jobs[0].Payload) != `{"order":"7"}` {
+		t.Fatalf("jobs = %+v, want the reminder once", jobs)
+	}
+	if _, ok := f.step(); !ok || calls != 1 {
+		t.Errorf("reminder did not run: %d calls", calls)
+	}
+}
@@ -0,0 +1,78 @@
+// Package jobqueue is a persistent background job runner with
+// at-least-once semantics, assembled from patterns:
+//
+//   - outbox: jobs are enqueued in the same atomic write as the business
+//     data that caused them, so neither exists without the other
+//   - priority dispatch: workers claim the most urgent runnable job
+//   - worker pool: a fixed number of goroutines execute handlers
+//   - retry with exponential backoff and jitter
+//   - dead-letter store: jobs that exhaust their retries, or fail with a
+//     Permanent error, leave the queue with their failure history, so
+//     poison messages cannot starve the rest; Requeue puts them back
+//   - circuit breaker per job kind, so a failing dependency stops being
+//     hammered while other kinds keep flowing
+//   - delayed messages: Delayed enqueues the messages of a
+//     messaging/delayed scheduler when they fall due, exactly once
+//
+// Claims are leases: a worker that dies mid-job leaves it running until
+// the lease expires and another worker picks it up. Handlers therefore
+// must be idempotent.
+//
+// The store is a JSON file rewritten atomically; sqlite is the natural
+// production choice but is not available without cgo or a third-party
+// driver.
+package jobqueue
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type State string
+
+const (
+	Pending State = "pending"
+	Running State = "running"
+	Done    State = "done"
+	// Dead jobs have been moved to the dead-letter store.
+	Dead State = "dead"
+)
+
+type Job struct {
+	ID       int64           `json:"id"`
+	Kind     string          `json:"kind"`
+	Payload  json.RawMessage `json:"payload,omitempty"`
+	Priority int             `json:"priority"`
+	State    State           `json:"state"`
+	Attempts int             `json:"attempts"`
+	// NextRun is when a pending job becomes runnable, or when the lease of
+	// a running one expires.
+	NextRun   time.Time `json:"next_run"`
+	LastError string    `json:"last_error,omitempty"`
+	// Failures records every failed attempt, for the dead-letter store.
+	Failures []Failure `json:"failures,omitempty"`
+}
+
+// runnable reports whether j can be claimed at now.
+func (j *Job) runnable(now time.Time) bool {
+	switch j.State {
+	case Pending:
+		return !now.Before(j.NextRun)
+	case Running:
+		// lease expired: the worker died
+		return !now.Before(j.NextRun)
+	}
+	return false
+}
+
+// before orders jobs for dispatch: higher priority first, then the one
+// that has waited longest, then insertion order.
+func (j *Job) before(o *Job) bool {
+	if j.Priority != o.Priority {
+		return j.Priority > o.Priority
+	}
+	if !j.NextRun.Equal(o.NextRun) {
+		return j.NextRun.Before(o.NextRun)
+	}
+	return j.ID < o.ID
+}
@@ -0,0 +1,41 @@
+package jobqueue
+
+import (
+	"sync"
+	"time"
+
+	"patterns/idioms/must"
+	"patterns/randsource"
+	"patterns/resilience/circuitbreaker"
+)
+
+// backoff returns the delay before attempt n (1-based): base doubled per
+// attempt, capped at max, with full jitter so retries of many jobs that
+// failed together spread out.
+func backoff(r randsource.Rand, n int, base, max time.Duration) time.Duration {
+	d := base << min(n-1, 30)
+	if d <= 0 || d > max {
+		d = max
+	}
+	return time.Duration(r.Int64N(int64(d)) + 1)
+}
+
+// breakers keeps one circuit breaker per job kind, so a failing
+// dependency of one kind stops being called without holding up the rest.
+type breakers struct {
+	mu   sync.Mutex
+	opts []circuitbreaker.Option
+	m    map[string]*circuitbreaker.Breaker
+}
+
+func (bs *breakers) get(kind string) *circuitbreaker.Breaker {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	b, ok := bs.m[kind]
+	if !ok {
+		// WithBreaker validated the options: an error is a bug
+		b = must.Must(circuitbreaker.New(bs.opts...))
+		bs.m[kind] = b
+	}
+	return b
+}
@@ -0,0 +1,292 @@
+package jobqueue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"patterns/construct"
+	"patterns/funcopts"
+	"patterns/randsource"
+	"patterns/resilience/circuitbreaker"
+	"patterns/validate"
+)
+
+// Handler executes one job; it must be idempotent since a job can run
+// more than once.
+type Handler func(ctx context.Context, job Job) error
+
+type options struct {
+	workers      int
+	maxAttempts  int
+	baseBackoff  time.Duration
+	maxBackoff   time.Duration
+	lease        time.Duration
+	pollInterval time.Duration
+	threshold    int
+	cooldown     time.Duration
+	rand         randsource.Rand
+}
+
+type Option = funcopts.Option[options]
+
+func WithWorkers(n int) Option {
+	return func(options *options) error {
+		if n <= 0 {
+			return errors.New("workers must be positive")
+		}
+		options.workers = n
+		return nil
+	}
+}
+
+// WithRetry sets how many attempts a job gets and the backoff between them.
+func WithRetry(attempts int, base, max time.Duration) Option {
+	return func(options *options) error {
+		if attempts <= 0 {
+			return errors.New("attempts must be positive")
+		}
+		if base <= 0 || max < base {
+			return errors.New("backoff must be positive and max at least base")
+		}
+		options.maxAttempts, options.baseBackoff, options.maxBackoff = attempts, base, max
+		return nil
+	}
+}
+
+// WithLease sets how long a claimed job stays reserved for its worker.
+func WithLease(d time.Duration) Option {
+	return func(options *options) error {
+		if d <= 0 {
+			return errors.New("lease must be positive")
+		}
+		options.lease = d
+		return nil
+	}
+}
+
+func WithPollInterval(d time.Duration) Option {
+	return func(options *options) error {
+		if d <= 0 {
+			return errors.New("poll interval must be positive")
+		}
+		options.pollInterval = d
+		return nil
+	}
+}
+
+// WithBreaker opens the circuit for a job kind after threshold
+// consecutive failures, for cooldown.
+func WithBreaker(threshold int, cooldown time.Duration) Option {
+	return func(options *options) error {
+		if threshold <= 0 || cooldown <= 0 {
+			return errors.New("breaker threshold and cooldown must be positive")
+		}
+		options.threshold, options.cooldown = threshold, cooldown
+		return nil
+	}
+}
+
+// WithRand sets the source of backoff jitter; a randsource.New(seed)
+// makes retry schedules reproducible.
+func WithRand(r randsource.Rand) Option {
+	return func(options *options) error {
+		if r == nil {
+			return errors.New("rand cannot be nil")
+		}
+		options.rand = r
+		return nil
+	}
+}
+
+type Runner struct {
+	store    *Store
+	handlers map[string]Handler
+	options  options
+	breakers *breakers
+}
+
+func (o *options) SetDefaults() {
+	*o = options{
+		workers:      4,
+		maxAttempts:  5,
+		baseBackoff:  100 * time.Millisecond,
+		maxBackoff:   10 * time.Second,
+		lease:        30 * time.Second,
+		pollInterval: 50 * time.Millisecond,
+		threshold:    5,
+		cooldown:     5 * time.Second,
+		rand:         randsource.Global,
+	}
+}
+
+func (o *options) Validate() error {
+	var v validate.Validator
+	v.Check("pollInterval", o.pollInterval <= o.lease, "cannot exceed the lease")
+	validate.Field(&v, "maxBackoff", o.maxBackoff, validate.Min(o.baseBackoff))
+	return v.Err()
+}
+
+func NewRunner(store *Store, handlers map[string]Handler, opts ...Option) (*Runner, error) {
+	options, err := construct.New(opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &Runner{
+		store:    store,
+		handlers: handlers,
+		options:  *options,
+		breakers: &breakers{opts: []circuitbreaker.Option{
+			circuitbreaker.WithThreshold(options.threshold),
+			circuitbreaker.WithCooldown(options.cooldown),
+			circuitbreaker.WithClock(store.clock),
+		}, m: map[string]*circuitbreaker.Breaker{}},
+	}, nil
+}
+
+// Run starts the worker pool and blocks until ctx is done and every
+// worker has finished its current job.
+func (r *Runner) Run(ctx context.Context) error {
+	var wg sync.WaitGroup
+	errc := make(chan error, r.options.workers)
+	for range r.options.workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := r.work(ctx); err != nil {
+				errc <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errc)
+	return <-errc
+}
+
+func (r *Runner) work(ctx context.Context) error {
+	ticker := r.store.clock.NewTicker(r.options.pollInterval)
+	defer ticker.Stop()
+	for ctx.Err() == nil {
+		job, done, err := r.claim()
+		if err != nil {
+			return err
+		}
+		if done != nil {
+			if err := r.execute(ctx, job, done); err != nil {
+				return err
+			}
+			continue
+		}
+		select {
+		case <-ctx.Done():
+		case <-ticker.C():
+		}
+	}
+	return nil
+}
+
+// claim leases the most urgent runnable job whose breaker allows it, and
+// returns it with the func to report its result to the breaker; a nil
+// func means there was nothing to claim.
+func (r *Runner) claim() (Job, func(error), error) {
+	var (
+		claimed Job
+		done    func(error)
+	)
+	err := r.store.Update(func(tx *Tx) error {
+		var best *Job
+		for _, j := range tx.state.Jobs {
+			if !j.runnable(tx.now) || (best != nil && !j.before(best)) {
+				continue
+			}
+			if _, ok := r.handlers[j.Kind]; !ok || !r.breakers.get(j.Kind).Ready() {
+				continue
+			}
+			best = j
+		}
+		if best == nil {
+			return errNothing
+		}
+		var err error
+		if done, err = r.breakers.get(best.Kind).Allow(); err != nil {
+			// another worker took the half-open trial meanwhile
+			return errNothing
+		}
+		best.State = Running
+		best.Attempts++
+		best.NextRun = tx.now.Add(r.options.lease)
+		claimed = *best
+		return nil
+	})
+	if err != nil {
+		if done != nil {
+			// the claim was not saved: the job will not run
+			done(circuitbreaker.ErrNotRun)
+		}
+		if errors.Is(err, errNothing) {
+			err = nil
+		}
+		return Job{}, nil, err
+	}
+	return claimed, done, nil
+}
+
+var errNothing = errors.New("nothing to claim")
+
+func (r *Runner) execute(ctx context.Context, job Job, done func(error)) error {
+	// the lease bounds the handler so a stuck job is retried elsewhere
+	jobCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), r.options.lease)
+	err := r.call(jobCtx, job)
+	cancel()
+
+	now := r.store.clock.Now()
+	if IsPermanent(err) {
+		done(nil)
+	} else {
+		done(err)
+	}
+	return r.store.Update(func(tx *Tx) error {
+		j := tx.find(job.ID)
+		if j == nil || j.State != Running || j.Attempts != job.Attempts {
+			// lease expired and someone else took over; their result wins
+			return nil
+		}
+		if err == nil {
+			j.State, j.LastError = Done, ""
+			return nil
+		}
+		j.LastError = err.Error()
+		j.Failures = append(j.Failures, Failure{Attempt: j.Attempts, At: now, Error: j.LastError})
+		switch {
+		case IsPermanent(err):
+			tx.deadLetter(j, ReasonPermanent)
+		case j.Attempts >= r.options.maxAttempts:
+			tx.deadLetter(j, ReasonExhausted)
+		default:
+			j.State = Pending
+			j.NextRun = now.Add(backoff(r.options.rand, j.Attempts, r.options.baseBackoff, r.options.maxBackoff))
+		}
+		return nil
+	})
+}
+
+// call runs the handler, turning a panic into an error.
+func (r *Runner) call(ctx context.Context, job Job) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("panic: %v", p)
+		}
+	}()
+	return r.handlers[job.Kind](ctx, job)
+}
+
+func (tx *Tx) find(id int64) *Job {
+	for _, j := range tx.state.Jobs {
+		if j.ID == id {
+			return j
+		}
+	}
+	return nil
+}
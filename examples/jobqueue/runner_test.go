@@ -0,0 +1,465 @@
+package jobqueue
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"patterns/clock"
+	"patterns/randsource"
+)
+
+var epoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// fixture is a store on a fake clock and a runner over it, stepped by
+// hand through claim and execute, so that every failure lands exactly
+// where the test puts it.
+type fixture struct {
+	t     *testing.T
+	path  string
+	clock *clock.Fake
+	store *Store
+	r     *Runner
+}
+
+func newFixture(t *testing.T, handlers map[string]Handler, opts ...Option) *fixture {
+	t.Helper()
+	f := &fixture{t: t, path: filepath.Join(t.TempDir(), "jobs.json"), clock: clock.NewFake(epoch)}
+	f.open(handlers, opts...)
+	return f
+}
+
+// open (re)opens the store from its file, as a restarted process does.
+func (f *fixture) open(handlers map[string]Handler, opts ...Option) {
+	f.t.Helper()
+	store, err := OpenStore(f.path, WithClock(f.clock))
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	opts = append([]Option{
+		WithRetry(5, time.Second, 10*time.Second), WithLease(time.Minute),
+		WithBreaker(3, time.Minute), WithRand(randsource.New(1)),
+	}, opts...)
+	r, err := NewRunner(store, handlers, opts...)
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	f.store, f.r = store, r
+}
+
+func (f *fixture) enqueue(kind string, priority int) int64 {
+	f.t.Helper()
+	var id int64
+	err := f.store.Update(func(tx *Tx) error {
+		var err error
+		id, err = tx.Enqueue(kind, map[string]string{"kind": kind}, priority)
+		return err
+	})
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	return id
+}
+
+// step claims and executes one job and returns it as claimed, or false
+// if nothing was runnable.
+func (f *fixture) step() (Job, bool) {
+	f.t.Helper()
+	job, done, err := f.r.claim()
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	if done == nil {
+		return Job{}, false
+	}
+	if err := f.r.execute(context.Background(), job, done); err != nil {
+		f.t.Fatal(err)
+	}
+	return job, true
+}
+
+func (f *fixture) job(id int64) Job {
+	f.t.Helper()
+	for _, j := range f.store.Jobs() {
+		if j.ID == id {
+			return j
+		}
+	}
+	f.t.Fatalf("job %d is not in the queue", id)
+	return Job{}
+}
+
+// failing returns a handler failing with err on its first n calls and
+// counting them all.
+func failing(n int, err error, calls *int) Handler {
+	return func(context.Context, Job) error {
+		*calls++
+		if *calls <= n {
+			return err
+		}
+		return nil
+	}
+}
+
+// TestOutbox checks that a job and the record enqueuing it are written
+// together or not at all, in memory and on disk.
+func TestOutbox(t *testing.T) {
+	f := newFixture(t, nil)
+	err := f.store.Update(func(tx *Tx) error {
+		tx.Set("order:1", "placed")
+		if _, err := tx.Enqueue("email", "order 1", 0); err != nil {
+			return err
+		}
+		return errors.New("payment declined")
+	})
+	if err == nil {
+		t.Fatal("Update succeeded")
+	}
+	check := func(when string, record bool, jobs int) {
+		t.Helper()
+		var ok bool
+		f.store.View(func(tx *Tx) { _, ok = tx.Get("order:1") })
+		if ok != record || len(f.store.Jobs()) != jobs {
+			t.Errorf("%s: record %v, %d jobs; want %v, %d", when, ok, len(f.store.Jobs()), record, jobs)
+		}
+	}
+	check("after a failed Update", false, 0)
+	f.open(nil)
+	check("reopened after a failed Update", false, 0)
+
+	if err := f.store.Update(func(tx *Tx) error {
+		tx.Set("order:1", "placed")
+		_, err := tx.Enqueue("email", "order 1", 0)
+		return err
+	}); err != nil {
+		t.Fatal(err)
+	}
+	f.open(nil)
+	check("reopened after Update", true, 1)
+}
+
+// TestWriteFailure makes the store's writes fail: the runner reports it,
+// and the queue is left as it was.
+func TestWriteFailure(t *testing.T) {
+	calls := 0
+	f := newFixture(t, map[string]Handler{"k": failing(0, nil, &calls)})
+	id := f.enqueue("k", 0)
+	// the temporary file is a directory, so it cannot be created
+	if err := os.Mkdir(f.path+".tmp", 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if _, done, err := f.r.claim(); err == nil || done != nil {
+		t.Fatalf("claim with a failing store = %v, want the error and no job", err)
+	}
+	if j := f.job(id); j.State != Pending || j.Attempts != 0 || calls != 0 {
+		t.Errorf("job after a failed claim: %s, %d attempts, %d calls", j.State, j.Attempts, calls)
+	}
+	// the breaker was told the claimed job did not run: it stays closed
+	if !f.r.breakers.get("k").Ready() {
+		t.Error("breaker not ready after a failed claim")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := f.r.Run(ctx); err == nil || ctx.Err() != nil {
+		t.Errorf("Run with a failing store = %v, want the store's error", err)
+	}
+
+	os.Remove(f.path + ".tmp")
+	if _, ok := f.step(); !ok || calls != 1 || f.job(id).State != Done {
+		t.Errorf("after the store recovers: %d calls, job %s", calls, f.job(id).State)
+	}
+}
+
+// TestRetry fails a job twice: each retry waits out its backoff, and the
+// failures are kept.
+func TestRetry(t *testing.T) {
+	calls := 0
+	f := newFixture(t, map[string]Handler{"k": failing(2, errors.New("timeout"), &calls)})
+	id := f.enqueue("k", 0)
+	for attempt := 1; attempt <= 2; attempt++ {
+		if _, ok := f.step(); !ok {
+			t.Fatalf("attempt %d: nothing to claim", attempt)
+		}
+		j := f.job(id)
+		wait := j.NextRun.Sub(f.clock.Now())
+		// full jitter under base doubled per attempt
+		if j.State != Pending || wait <= 0 || wait > time.Second<<(attempt-1) {
+			t.Fatalf("attempt %d: job %s, retry in %v", attempt, j.State, wait)
+		}
+		f.clock.Advance(wait - time.Nanosecond)
+		if _, ok := f.step(); ok {
+			t.Fatalf("attempt %d: job ran again before its backoff", attempt)
+		}
+		f.clock.Advance(time.Nanosecond)
+	}
+	if _, ok := f.step(); !ok || calls != 3 {
+		t.Fatalf("third attempt: %d calls", calls)
+	}
+	j := f.job(id)
+	if j.State != Done || j.Attempts != 3 || j.LastError != "" || len(j.Failures) != 2 || j.Failures[1].Attempt != 2 {
+		t.Errorf("job = %s after %d attempts, last error %q, failures %v", j.State, j.Attempts, j.LastError, j.Failures)
+	}
+}
+
+func TestExhausted(t *testing.T) {
+	calls := 0
+	f := newFixture(t, map[string]Handler{"k": failing(99, errors.New("timeout"), &calls)}, WithRetry(3, time.Second, time.Second))
+	id := f.enqueue("k", 0)
+	for range 3 {
+		f.step()
+		f.clock.Advance(time.Second)
+	}
+	if _, ok := f.step(); ok || calls != 3 {
+		t.Errorf("%d calls, want 3 and then nothing to claim", calls)
+	}
+	dead := f.store.DeadLetters()
+	if len(dead) != 1 || dead[0].Job.ID != id || dead[0].Reason != ReasonExhausted || len(dead[0].Job.Failures) != 3 {
+		t.Errorf("dead letters = %+v, want job %d exhausted after 3 failures", dead, id)
+	}
+}
+
+// TestPermanent checks that a poison job is dead-lettered at once and
+// does not open the breaker of its kind.
+func TestPermanent(t *testing.T) {
+	calls := 0
+	f := newFixture(t, map[string]Handler{"k": failing(5, Permanent(errors.New("bad payload")), &calls)})
+	for range 5 {
+		f.enqueue("k", 0)
+	}
+	for range 5 {
+		f.step()
+	}
+	if dead := f.store.DeadLetters(); len(dead) != 5 || dead[0].Reason != ReasonPermanent || len(dead[0].Job.Failures) != 1 {
+		t.Fatalf("dead letters = %+v, want 5 permanent failures", dead)
+	}
+	// five failures, threshold three, and the breaker is still closed
+	id := f.enqueue("k", 0)
+	if _, ok := f.step(); !ok || f.job(id).State != Done {
+		t.Errorf("next job of the kind did not run")
+	}
+}
+
+func TestPanic(t *testing.T) {
+	calls := 0
+	f := newFixture(t, map[string]Handler{"k": func(context.Context, Job) error {
+		calls++
+		if calls == 1 {
+			panic("nil map")
+		}
+		return nil
+	}})
+	id := f.enqueue("k", 0)
+	f.step()
+	if j := f.job(id); j.State != Pending || j.LastError != "panic: nil map" {
+		t.Fatalf("job after a panic: %s, %q", j.State, j.LastError)
+	}
+	f.clock.Advance(time.Second)
+	if f.step(); f.job(id).State != Done {
+		t.Errorf("job after the retry: %s", f.job(id).State)
+	}
+}
+
+// TestWorkerDies claims a job and never finishes it, as a worker killed
+// mid-job: another worker takes it over when the lease expires, and the
+// first one's result, should it come back, is ignored.
+func TestWorkerDies(t *testing.T) {
+	calls := 0
+	f := newFixture(t, map[string]Handler{"k": failing(1, errors.New("late"), &calls)})
+	id := f.enqueue("k", 0)
+	lost, lostDone, err := f.r.claim()
+	if err != nil || lostDone == nil {
+		t.Fatalf("claim = %v", err)
+	}
+	f.clock.Advance(time.Minute - time.Nanosecond)
+	if _, ok := f.step(); ok {
+		t.Fatal("a leased job was claimed again")
+	}
+	f.clock.Advance(time.Nanosecond)
+
+	taken, takenDone, err := f.r.claim()
+	if err != nil || takenDone == nil || taken.ID != id || taken.Attempts != 2 {
+		t.Fatalf("claim after the lease = %+v, %v", taken, err)
+	}
+	// the first worker comes back late, with a failure
+	if err := f.r.execute(context.Background(), lost, lostDone); err != nil {
+		t.Fatal(err)
+	}
+	if j := f.job(id); j.State != Running || j.Attempts != 2 || len(j.Failures) != 0 {
+		t.Errorf("job after the stale result: %s, %d attempts, failures %v", j.State, j.Attempts, j.Failures)
+	}
+	if err := f.r.execute(context.Background(), taken, takenDone); err != nil {
+		t.Fatal(err)
+	}
+	if j := f.job(id); j.State != Done || calls != 2 {
+		t.Errorf("job after the takeover: %s, %d calls", j.State, calls)
+	}
+}
+
+// TestCrashRecovery claims a job and restarts the process from the
+// store file: the job is run again once its lease expires, at least once
+// overall.
+func TestCrashRecovery(t *testing.T) {
+	calls := 0
+	handlers := map[string]Handler{"k": failing(0, nil, &calls)}
+	f := newFixture(t, handlers)
+	ids := []int64{f.enqueue("k", 0), f.enqueue("k", 0)}
+	if _, done, err := f.r.claim(); err != nil || done == nil {
+		t.Fatalf("claim = %v", err)
+	}
+
+	f.open(handlers)
+	if f.job(ids[0]).State != Running {
+		t.Fatalf("claimed job after restart: %s", f.job(ids[0]).State)
+	}
+	// the unclaimed job runs at once, the claimed one after its lease
+	if j, ok := f.step(); !ok || j.ID != ids[1] {
+		t.Fatalf("first job after restart = %v, %v; want %d", j.ID, ok, ids[1])
+	}
+	if _, ok := f.step(); ok {
+		t.Fatal("a leased job ran before its lease expired")
+	}
+	f.clock.Advance(time.Minute)
+	if j, ok := f.step(); !ok || j.ID != ids[0] || j.Attempts != 2 {
+		t.Fatalf("job after the lease = %+v, %v", j, ok)
+	}
+	if f.job(ids[0]).State != Done || f.job(ids[1]).State != Done || calls != 2 {
+		t.Errorf("after recovery: %s, %s, %d calls", f.job(ids[0]).State, f.job(ids[1]).State, calls)
+	}
+}
+
+// TestBreakerPerKind fails one kind until its breaker opens: its jobs
+// wait out the cooldown while another kind keeps flowing.
+func TestBreakerPerKind(t *testing.T) {
+	flaky, healthy := 0, 0
+	f := newFixture(t, map[string]Handler{
+		"flaky":   failing(3, errors.New("dependency down"), &flaky),
+		"healthy": failing(0, nil, &healthy),
+	}, WithRetry(10, time.Second, time.Second))
+	for range 3 {
+		f.enqueue("flaky", 1)
+	}
+	for range 3 {
+		f.step()
+	}
+	if flaky != 3 {
+		t.Fatalf("%d flaky calls, want 3", flaky)
+	}
+	f.clock.Advance(time.Second)
+	id := f.enqueue("healthy", 0)
+	// the flaky jobs have a higher priority, and are skipped
+	if j, ok := f.step(); !ok || j.ID != id {
+		t.Fatalf("claimed %+v, %v with the flaky breaker open; want the healthy job", j, ok)
+	}
+	if _, ok := f.step(); ok || flaky != 3 {
+		t.Fatalf("a flaky job ran with its breaker open")
+	}
+	f.clock.Advance(time.Minute)
+	// half-open: one trial at a time, which succeeds and closes the
+	// breaker
+	trial, done, err := f.r.claim()
+	if err != nil || done == nil || trial.Kind != "flaky" {
+		t.Fatalf("half-open claim = %+v, %v", trial, err)
+	}
+	if _, ok := f.step(); ok {
+		t.Fatal("claimed a second job during the half-open trial")
+	}
+	if err := f.r.execute(context.Background(), trial, done); err != nil {
+		t.Fatal(err)
+	}
+	for range 2 {
+		f.step()
+	}
+	if flaky != 6 {
+		t.Errorf("%d flaky calls after the cooldown, want 6", flaky)
+	}
+	for _, j := range f.store.Jobs() {
+		if j.State != Done {
+			t.Errorf("job %d %s is %s", j.ID, j.Kind, j.State)
+		}
+	}
+}
+
+func TestPriority(t *testing.T) {
+	var ran []int64
+	f := newFixture(t, map[string]Handler{"k": func(_ context.Context, j Job) error {
+		ran = append(ran, j.ID)
+		return nil
+	}})
+	// low has waited longest, but is the least urgent
+	low := f.enqueue("k", 0)
+	f.clock.Advance(time.Second)
+	high1 := f.enqueue("k", 5)
+	high2 := f.enqueue("k", 5)
+	mid := f.enqueue("k", 1)
+	for range 4 {
+		f.step()
+	}
+	if want := []int64{high1, high2, mid, low}; !slices.Equal(ran, want) {
+		t.Errorf("ran %v, want %v", ran, want)
+	}
+}
+
+// TestRun runs the worker pool over jobs that fail at random and checks
+// that every one of them ends up done; run with -race.
+func TestRun(t *testing.T) {
+	store, err := OpenStore(filepath.Join(t.TempDir(), "jobs.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rnd := randsource.New(2)
+	var mu sync.Mutex
+	succeeded := map[int64]int{}
+	var pending atomic.Int64
+	handler := func(_ context.Context, j Job) error {
+		if rnd.IntN(3) == 0 {
+			return errors.New("transient")
+		}
+		mu.Lock()
+		succeeded[j.ID]++
+		mu.Unlock()
+		pending.Add(-1)
+		return nil
+	}
+	r, err := NewRunner(store, map[string]Handler{"a": handler, "b": handler},
+		WithWorkers(4), WithRetry(20, time.Millisecond, 5*time.Millisecond),
+		WithPollInterval(time.Millisecond), WithBreaker(100, time.Millisecond), WithRand(randsource.New(3)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	const n = 40
+	for i := range n {
+		if err := store.Update(func(tx *Tx) error {
+			_, err := tx.Enqueue([]string{"a", "b"}[i%2], i, i%3)
+			return err
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pending.Store(n)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- r.Run(ctx) }()
+	deadline := time.Now().Add(10 * time.Second)
+	for pending.Load() > 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	for _, j := range store.Jobs() {
+		if j.State != Done || succeeded[j.ID] != 1 {
+			t.Errorf("job %d: %s, succeeded %d times", j.ID, j.State, succeeded[j.ID])
+		}
+	}
+	if len(store.Jobs()) != n || len(store.DeadLetters()) != 0 {
+		t.Errorf("%d jobs, %d dead letters; want %d, 0", len(store.Jobs()), len(store.DeadLetters()), n)
+	}
+}
@@ -0,0 +1,175 @@
+package jobqueue
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"patterns/clock"
+	"patterns/construct"
+	"patterns/funcopts"
+)
+
+// Store persists business records and the job outbox in one file, so an
+// Update is atomic across both.
+type Store struct {
+	mu    sync.Mutex
+	path  string
+	state storeState
+	clock clock.Clock
+}
+
+type storeState struct {
+	NextID  int64             `json:"next_id"`
+	Records map[string]string `json:"records"`
+	Jobs    []*Job            `json:"jobs"`
+	// DeadLetters holds the jobs whose last attempt failed, out of the
+	// workers' way.
+	DeadLetters []*DeadLetter `json:"dead_letters,omitempty"`
+}
+
+type storeOptions struct {
+	clock clock.Clock
+}
+
+type StoreOption = funcopts.Option[storeOptions]
+
+// WithClock sets the clock for job timestamps, leases, backoff and the
+// runner's polling; a clock.Fake makes all of them deterministic.
+func WithClock(c clock.Clock) StoreOption {
+	return func(options *storeOptions) error {
+		if c == nil {
+			return errors.New("clock cannot be nil")
+		}
+		options.clock = c
+		return nil
+	}
+}
+
+func (o *storeOptions) SetDefaults() { o.clock = clock.Real }
+
+func OpenStore(path string, opts ...StoreOption) (*Store, error) {
+	options, err := construct.New(opts...)
+	if err != nil {
+		return nil, err
+	}
+	s := &Store{path: path, state: storeState{NextID: 1, Records: map[string]string{}}, clock: options.clock}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &s.state); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Tx is the view of the store inside Update.
+type Tx struct {
+	state *storeState
+	now   time.Time
+}
+
+// Set writes a business record.
+func (tx *Tx) Set(key, value string) { tx.state.Records[key] = value }
+
+func (tx *Tx) Get(key string) (string, bool) {
+	v, ok := tx.state.Records[key]
+	return v, ok
+}
+
+// Enqueue adds a job to the outbox; it becomes visible to workers only if
+// the surrounding Update commits.
+func (tx *Tx) Enqueue(kind string, payload any, priority int) (int64, error) {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return 0, err
+	}
+	id := tx.state.NextID
+	tx.state.NextID++
+	tx.state.Jobs = append(tx.state.Jobs, &Job{
+		ID: id, Kind: kind, Payload: raw, Priority: priority, State: Pending, NextRun: tx.now,
+	})
+	return id, nil
+}
+
+// Update runs fn on a copy of the state and commits it with one atomic
+// file replace; if fn fails nothing is written.
+func (s *Store) Update(fn func(tx *Tx) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	next := s.state.clone()
+	if err := fn(&Tx{state: &next, now: s.clock.Now()}); err != nil {
+		return err
+	}
+	if err := s.write(next); err != nil {
+		return err
+	}
+	s.state = next
+	return nil
+}
+
+// View runs fn on the committed state; fn must not keep tx.
+func (s *Store) View(fn func(tx *Tx)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fn(&Tx{state: &s.state, now: s.clock.Now()})
+}
+
+// Jobs returns a copy of every job in the queue; dead-lettered jobs are
+// in DeadLetters.
+func (s *Store) Jobs() []Job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]Job, len(s.state.Jobs))
+	for i, j := range s.state.Jobs {
+		out[i] = *j
+		out[i].Failures = slices.Clone(j.Failures)
+	}
+	return out
+}
+
+func (s *Store) write(st storeState) error {
+	b, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+func (st storeState) clone() storeState {
+	out := storeState{NextID: st.NextID, Records: make(map[string]string, len(st.Records))}
+	for k, v := range st.Records {
+		out.Records[k] = v
+	}
+	for _, j := range st.Jobs {
+		c := *j
+		c.Failures = slices.Clone(j.Failures)
+		out.Jobs = append(out.Jobs, &c)
+	}
+	// dead letters are only ever added and removed whole, never changed
+	out.DeadLetters = slices.Clone(st.DeadLetters)
+	return out
+}
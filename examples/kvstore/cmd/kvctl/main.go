@@ -0,0 +1,59 @@
+// Command kvctl drives an examples/kvstore directory from the shell; run
+// it repeatedly (or kill it mid-write) to watch the log being replayed.
+//
+// usage:
+//
+//	go run patterns/examples/kvstore/cmd/kvctl -dir /tmp/kv put a 1
+//	go run patterns/examples/kvstore/cmd/kvctl -dir /tmp/kv scan
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"patterns/examples/kvstore"
+)
+
+func main() {
+	dir := flag.String("dir", "kvdata", "store directory")
+	flag.Parse()
+	args := flag.Args()
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: kvctl [-dir d] get k | put k v | del k | scan [start [end]] | snapshot")
+		os.Exit(2)
+	}
+
+	s, err := kvstore.Open(*dir)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer s.Close()
+	fmt.Fprintf(os.Stderr, "replayed %d log entries\n", s.Replayed())
+
+	switch {
+	case args[0] == "get" && len(args) == 2:
+		v, ok := s.Get(args[1])
+		if !ok {
+			log.Fatalf("%s: not found", args[1])
+		}
+		fmt.Println(v)
+	case args[0] == "put" && len(args) == 3:
+		err = s.Put(args[1], args[2])
+	case args[0] == "del" && len(args) == 2:
+		err = s.Delete(args[1])
+	case args[0] == "scan" && len(args) <= 3:
+		args = append(args, "", "")
+		for k, v := range s.Scan(args[1], args[2]) {
+			fmt.Printf("%s=%s\n", k, v)
+		}
+	case args[0] == "snapshot":
+		err = s.Snapshot()
+	default:
+		log.Fatalf("bad command %q", args)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,57 @@
+package kvstore
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// command pattern
+// Every mutation is a value that can be applied, serialized into the log
+// and replayed later; the store never mutates its map any other way.
+
+type command interface {
+	apply(data map[string]string)
+}
+
+type put struct {
+	Key, Value string
+}
+
+func (c put) apply(data map[string]string) { data[c.Key] = c.Value }
+
+type del struct {
+	Key string
+}
+
+func (c del) apply(data map[string]string) { delete(data, c.Key) }
+
+// record is the serialized form of a command.
+type record struct {
+	Op    string `json:"op"`
+	Key   string `json:"key"`
+	Value string `json:"value,omitempty"`
+}
+
+func encode(c command) ([]byte, error) {
+	switch c := c.(type) {
+	case put:
+		return json.Marshal(record{Op: "put", Key: c.Key, Value: c.Value})
+	case del:
+		return json.Marshal(record{Op: "del", Key: c.Key})
+	}
+	return nil, fmt.Errorf("unknown command %T", c)
+}
+
+func decode(b []byte) (command, error) {
+	var r record
+	if err := json.Unmarshal(b, &r); err != nil {
+		return nil, err
+	}
+	switch r.Op {
+	case "put":
+		return put{Key: r.Key, Value: r.Value}, nil
+	case "del":
+		return del{Key: r.Key}, nil
+	}
+	return nil, fmt.Errorf("unknown op %q", r.Op)
+}
@@ -0,0 +1,50 @@
+package kvstore
+
+import (
+	"encoding/json"
+	"maps"
+	"os"
+)
+
+// memento pattern
+// Memento captures the store state without exposing its representation;
+// only the store can read it back.
+
+type Memento struct {
+	data map[string]string
+}
+
+// Len reports how many keys the memento holds.
+func (m Memento) Len() int { return len(m.data) }
+
+func saveSnapshot(path string, m Memento) error {
+	b, err := json.Marshal(m.data)
+	if err != nil {
+		return err
+	}
+	// write then rename so a crash leaves either the old or the new snapshot
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func loadSnapshot(path string) (Memento, error) {
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return Memento{data: map[string]string{}}, nil
+	}
+	if err != nil {
+		return Memento{}, err
+	}
+	data := map[string]string{}
+	if err := json.Unmarshal(b, &data); err != nil {
+		return Memento{}, err
+	}
+	return Memento{data: data}, nil
+}
+
+func (m Memento) clone() Memento {
+	return Memento{data: maps.Clone(m.data)}
+}
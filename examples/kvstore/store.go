@@ -0,0 +1,246 @@
+// Package kvstore is an embedded key-value store assembled from patterns:
+// mutations are commands appended to a write-ahead log (storage/wal),
+// snapshots are mementos, range scans are iterators and the store is
+// configured with functional options.
+//
+// On Open the latest snapshot is loaded and the log replayed on top of
+// it, so a process killed at any point recovers every acknowledged write.
+package kvstore
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"iter"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+
+	"patterns/construct"
+	"patterns/funcopts"
+	"patterns/lifecycle/multicloser"
+	"patterns/storage/wal"
+)
+
+type options struct {
+	sync          bool
+	snapshotEvery int
+}
+
+type Option = funcopts.Option[options]
+
+// WithSync fsyncs the log after every write.
+func WithSync(sync bool) Option {
+	return func(options *options) error {
+		options.sync = sync
+		return nil
+	}
+}
+
+// WithSnapshotEvery snapshots and truncates the log after n writes; 0
+// disables automatic snapshots.
+func WithSnapshotEvery(n int) Option {
+	return func(options *options) error {
+		if n < 0 {
+			return errors.New("snapshot interval cannot be negative")
+		}
+		options.snapshotEvery = n
+		return nil
+	}
+}
+
+type Store struct {
+	mu        sync.RWMutex
+	dir       string
+	options   options
+	data      map[string]string
+	log       *wal.Log
+	closer    *multicloser.Stack
+	sinceSnap int
+	replayed  int
+}
+
+var ErrClosed = errors.New("store is closed")
+
+func (o *options) SetDefaults() {
+	o.sync = true
+	o.snapshotEvery = 1000
+}
+
+func Open(dir string, opts ...Option) (_ *Store, err error) {
+	options, err := construct.New(opts...)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	snap, err := loadSnapshot(filepath.Join(dir, "snapshot.json"))
+	if err != nil {
+		return nil, err
+	}
+	var mc multicloser.Stack
+	defer mc.Rollback(&err)
+	if err := migrateLog(dir); err != nil {
+		return nil, err
+	}
+	sync := wal.SyncNever
+	if options.sync {
+		sync = wal.SyncAlways
+	}
+	log, err := wal.Open(filepath.Join(dir, "wal"), wal.WithSync(sync))
+	if err != nil {
+		return nil, err
+	}
+	mc.Defer("wal", log.Close)
+	n := 0
+	for rec, err := range log.Replay(log.FirstIndex()) {
+		if err != nil {
+			return nil, err
+		}
+		c, err := decode(rec.Data)
+		if err != nil {
+			return nil, fmt.Errorf("log record %d: %w", rec.Index, err)
+		}
+		c.apply(snap.data)
+		n++
+	}
+
+	return &Store{dir: dir, options: *options, data: snap.data, log: log, closer: mc.Release(), sinceSnap: n, replayed: n}, nil
+}
+
+// Replayed reports how many log entries were applied on Open.
+func (s *Store) Replayed() int { return s.replayed }
+
+func (s *Store) Get(key string) (string, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	v, ok := s.data[key]
+	return v, ok
+}
+
+func (s *Store) Put(key, value string) error {
+	return s.execute(put{Key: key, Value: value})
+}
+
+func (s *Store) Delete(key string) error {
+	return s.execute(del{Key: key})
+}
+
+// execute logs c before applying it, so an acknowledged write is durable.
+func (s *Store) execute(c command) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.log == nil {
+		return ErrClosed
+	}
+	payload, err := encode(c)
+	if err != nil {
+		return err
+	}
+	if _, err := s.log.Append(payload); err != nil {
+		return err
+	}
+	c.apply(s.data)
+	s.sinceSnap++
+	if s.options.snapshotEvery > 0 && s.sinceSnap >= s.options.snapshotEvery {
+		return s.snapshotLocked()
+	}
+	return nil
+}
+
+// Snapshot writes the current state and truncates the log.
+func (s *Store) Snapshot() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.log == nil {
+		return ErrClosed
+	}
+	return s.snapshotLocked()
+}
+
+func (s *Store) snapshotLocked() error {
+	if err := saveSnapshot(filepath.Join(s.dir, "snapshot.json"), Memento{data: s.data}); err != nil {
+		return err
+	}
+	s.sinceSnap = 0
+	return s.log.TruncateFront(s.log.LastIndex() + 1)
+}
+
+// migrateLog moves the single-file log of earlier versions into the
+// segment directory, where it becomes the first segment unchanged: the
+// frame format is the same.
+func migrateLog(dir string) error {
+	legacy, segments := filepath.Join(dir, "wal.log"), filepath.Join(dir, "wal")
+	if _, err := os.Stat(legacy); errors.Is(err, fs.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if _, err := os.Stat(segments); err == nil {
+		return fmt.Errorf("both %s and %s exist", legacy, segments)
+	}
+	if err := os.Mkdir(segments, 0o755); err != nil {
+		return err
+	}
+	return os.Rename(legacy, filepath.Join(segments, "00000000000000000001.wal"))
+}
+
+// Save returns a memento of the current state.
+func (s *Store) Save() Memento {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return Memento{data: s.data}.clone()
+}
+
+// Restore replaces the state with m and persists it as a snapshot.
+func (s *Store) Restore(m Memento) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.log == nil {
+		return ErrClosed
+	}
+	s.data = m.clone().data
+	return s.snapshotLocked()
+}
+
+// iterator pattern
+
+// Scan yields keys in [start, end) in order; an empty end means no upper
+// bound. It iterates over a copy of the matching keys, so the store may
+// be written during the scan.
+func (s *Store) Scan(start, end string) iter.Seq2[string, string] {
+	return func(yield func(string, string) bool) {
+		s.mu.RLock()
+		var keys []string
+		for k := range s.data {
+			if k >= start && (end == "" || k < end) {
+				keys = append(keys, k)
+			}
+		}
+		s.mu.RUnlock()
+		slices.Sort(keys)
+
+		for _, k := range keys {
+			v, ok := s.Get(k)
+			if !ok {
+				continue
+			}
+			if !yield(k, v) {
+				return
+			}
+		}
+	}
+}
+
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.log == nil {
+		return ErrClosed
+	}
+	s.log = nil
+	return s.closer.Close()
+}
@@ -0,0 +1,315 @@
+package kvstore
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// crashImage copies dir as a process killed now would leave it: whatever
+// the store has written, without Close.
+func crashImage(t *testing.T, dir string) string {
+	t.Helper()
+	image := filepath.Join(t.TempDir(), "image")
+	if err := os.CopyFS(image, os.DirFS(dir)); err != nil {
+		t.Fatal(err)
+	}
+	return image
+}
+
+func reopen(t *testing.T, dir string, opts ...Option) *Store {
+	t.Helper()
+	s, err := Open(dir, opts...)
+	if err != nil {
+		t.Fatalf("Open after crash: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+// op is one write of a random workload over a few keys, so that keys are
+// overwritten and deleted, and deleted keys written again.
+type op struct {
+	del        bool
+	key, value string
+}
+
+func workload(seed uint64, n int) []op {
+	r := rand.New(rand.NewPCG(seed, seed))
+	ops := make([]op, n)
+	for i := range ops {
+		ops[i] = op{del: r.IntN(4) == 0, key: fmt.Sprint("k", r.IntN(6)), value: fmt.Sprint("v", i)}
+	}
+	return ops
+}
+
+func (o op) do(s *Store) error {
+	if o.del {
+		return s.Delete(o.key)
+	}
+	return s.Put(o.key, o.value)
+}
+
+func (o op) apply(model map[string]string) {
+	if o.del {
+		delete(model, o.key)
+	} else {
+		model[o.key] = o.value
+	}
+}
+
+func contents(s *Store) map[string]string { return maps.Collect(s.Scan("", "")) }
+
+// TestCrashAfterEveryWrite takes a crash image after every acknowledged
+// write, with and without snapshots along the way, and checks that
+// recovery from each image has every write up to it.
+func TestCrashAfterEveryWrite(t *testing.T) {
+	for _, every := range []int{0, 1, 4} {
+		dir := t.TempDir()
+		s, err := Open(dir, WithSnapshotEvery(every))
+		if err != nil {
+			t.Fatal(err)
+		}
+		model := map[string]string{}
+		for i, o := range workload(uint64(every), 30) {
+			if err := o.do(s); err != nil {
+				t.Fatal(err)
+			}
+			o.apply(model)
+			recovered := reopen(t, crashImage(t, dir))
+			if got := contents(recovered); !maps.Equal(got, model) {
+				t.Fatalf("every %d, crash after write %d: recovered %v, want %v", every, i+1, got, model)
+			}
+			// the log holds the writes since the last snapshot only
+			want := i + 1
+			if every > 0 {
+				want = (i + 1) % every
+			}
+			if recovered.Replayed() != want {
+				t.Errorf("every %d, crash after write %d: replayed %d, want %d", every, i+1, recovered.Replayed(), want)
+			}
+		}
+		if err := s.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// TestCrashBetweenSnapshotAndTruncate crashes after the snapshot is
+// written but before the log is truncated: the log replays on top of a
+// snapshot that already has its writes, which commands setting and
+// deleting keys survive.
+func TestCrashBetweenSnapshotAndTruncate(t *testing.T) {
+	dir := t.TempDir()
+	s, err := Open(dir, WithSnapshotEvery(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	model := map[string]string{}
+	for _, o := range workload(7, 20) {
+		if err := o.do(s); err != nil {
+			t.Fatal(err)
+		}
+		o.apply(model)
+	}
+	if err := saveSnapshot(filepath.Join(dir, "snapshot.json"), s.Save()); err != nil {
+		t.Fatal(err)
+	}
+	recovered := reopen(t, crashImage(t, dir))
+	if got := contents(recovered); !maps.Equal(got, model) || recovered.Replayed() != 20 {
+		t.Errorf("recovered %v after replaying %d, want %v after 20", got, recovered.Replayed(), model)
+	}
+}
+
+// TestTornWrite cuts the last log record short, as a crash in the middle
+// of a write can: recovery keeps every write before it.
+func TestTornWrite(t *testing.T) {
+	dir := t.TempDir()
+	s, err := Open(dir, WithSnapshotEvery(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	ops := workload(3, 10)
+	model := map[string]string{}
+	for i, o := range ops {
+		if err := o.do(s); err != nil {
+			t.Fatal(err)
+		}
+		if i < len(ops)-1 {
+			o.apply(model)
+		}
+	}
+	image := crashImage(t, dir)
+	segments, err := filepath.Glob(filepath.Join(image, "wal", "*.wal"))
+	if err != nil || len(segments) == 0 {
+		t.Fatalf("segments = %v, %v", segments, err)
+	}
+	last := segments[len(segments)-1]
+	fi, err := os.Stat(last)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Truncate(last, fi.Size()-3); err != nil {
+		t.Fatal(err)
+	}
+
+	recovered := reopen(t, image)
+	if got := contents(recovered); !maps.Equal(got, model) || recovered.Replayed() != 9 {
+		t.Errorf("recovered %v after replaying %d, want %v after 9", got, recovered.Replayed(), model)
+	}
+	// the torn record is gone for good: writes after recovery follow the
+	// ninth
+	if err := recovered.Put("after", "crash"); err != nil {
+		t.Fatal(err)
+	}
+	model["after"] = "crash"
+	again := reopen(t, crashImage(t, image))
+	if got := contents(again); !maps.Equal(got, model) {
+		t.Errorf("after a second crash: %v, want %v", got, model)
+	}
+}
+
+func TestCorruptSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "snapshot.json"), []byte(`{"k":`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if s, err := Open(dir); err == nil {
+		s.Close()
+		t.Error("Open with a corrupt snapshot succeeded")
+	}
+}
+
+func TestRestore(t *testing.T) {
+	dir := t.TempDir()
+	s, err := Open(dir, WithSnapshotEvery(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	s.Put("a", "1")
+	s.Put("b", "2")
+	m := s.Save()
+	s.Put("a", "changed")
+	s.Delete("b")
+	s.Put("c", "3")
+	if err := s.Restore(m); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"a": "1", "b": "2"}
+	if got := contents(s); !maps.Equal(got, want) {
+		t.Errorf("after Restore: %v, want %v", got, want)
+	}
+	// the memento is not aliased by the store it was restored into
+	s.Put("d", "4")
+	if m.Len() != 2 {
+		t.Errorf("memento has %d keys after a write, want 2", m.Len())
+	}
+	recovered := reopen(t, crashImage(t, dir))
+	want["d"] = "4"
+	if got := contents(recovered); !maps.Equal(got, want) || recovered.Replayed() != 1 {
+		t.Errorf("recovered %v after replaying %d, want %v after 1", got, recovered.Replayed(), want)
+	}
+}
+
+func TestScan(t *testing.T) {
+	s, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	for _, k := range []string{"b", "a", "d", "c", "e"} {
+		s.Put(k, k+k)
+	}
+	for _, c := range []struct {
+		start, end string
+		want       []string
+	}{
+		{"", "", []string{"a", "b", "c", "d", "e"}},
+		{"b", "d", []string{"b", "c"}},
+		{"c", "", []string{"c", "d", "e"}},
+		{"x", "", nil},
+	} {
+		var got []string
+		for k, v := range s.Scan(c.start, c.end) {
+			if v != k+k {
+				t.Errorf("Scan yielded %s=%s", k, v)
+			}
+			got = append(got, k)
+		}
+		if !slices.Equal(got, c.want) {
+			t.Errorf("Scan(%q, %q) = %v, want %v", c.start, c.end, got, c.want)
+		}
+	}
+
+	// writing during a scan: a key deleted before it is reached is
+	// skipped, the scan goes on
+	var got []string
+	for k := range s.Scan("", "") {
+		got = append(got, k)
+		if k == "a" {
+			s.Delete("c")
+		}
+		if k == "d" {
+			break
+		}
+	}
+	if want := []string{"a", "b", "d"}; !slices.Equal(got, want) {
+		t.Errorf("Scan while deleting = %v, want %v", got, want)
+	}
+}
+
+func TestClosed(t *testing.T) {
+	s, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for name, err := range map[string]error{
+		"Put": s.Put("k", "v"), "Delete": s.Delete("k"), "Snapshot": s.Snapshot(),
+		"Restore": s.Restore(Memento{}), "Close": s.Close(),
+	} {
+		if !errors.Is(err, ErrClosed) {
+			t.Errorf("%s after Close = %v, want ErrClosed", name, err)
+		}
+	}
+}
+
+func TestLegacyLog(t *testing.T) {
+	dir := t.TempDir()
+	s, err := Open(dir, WithSnapshotEvery(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Put("k", "v")
+	s.Close()
+	// an earlier version's layout: the one segment as wal.log
+	segments, _ := filepath.Glob(filepath.Join(dir, "wal", "*.wal"))
+	if len(segments) != 1 {
+		t.Fatalf("segments = %v", segments)
+	}
+	if err := os.Rename(segments[0], filepath.Join(dir, "wal.log")); err != nil {
+		t.Fatal(err)
+	}
+	os.Remove(filepath.Join(dir, "wal"))
+
+	migrated := reopen(t, dir)
+	if v, ok := migrated.Get("k"); !ok || v != "v" {
+		t.Errorf("Get after migration = %q, %v", v, ok)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "wal.log"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open(dir); err == nil {
+		t.Error("Open with both log layouts succeeded")
+	}
+}
@@ -0,0 +1,48 @@
+// Command urlshortener is an end-to-end example composing patterns from
+// this repository into one service:
+//
+//   - functional options (patterns/funcopts) configure the server
+//   - a repository (patterns/persistence/repository) stores links
+//   - cache-aside over an LRU (patterns/caching) serves hot redirects
+//   - a middleware chain (patterns/web/middleware) adds logging and
+//     per-client token bucket rate limiting (patterns/resilience/ratelimit)
+//   - typed handlers (patterns/web/handler) keep HTTP out of the service
+//   - graceful shutdown (patterns/lifecycle/shutdown) drains on SIGINT
+//
+// usage:
+//
+//	go run patterns/examples/urlshortener -addr localhost:8080
+//	curl -d '{"url":"https://go.dev"}' localhost:8080/links
+//	curl -i localhost:8080/<code>
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"log/slog"
+	"os"
+	"os/signal"
+)
+
+func main() {
+	addr := flag.String("addr", "localhost:8080", "listen address")
+	rate := flag.Float64("rate", 10, "requests per second per client")
+	burst := flag.Int("burst", 20, "burst size per client")
+	flag.Parse()
+
+	s, err := NewServer(
+		WithAddr(*addr),
+		WithRateLimit(*rate, *burst),
+		WithLogger(slog.New(slog.NewTextHandler(os.Stderr, nil))),
+	)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if err := s.Run(ctx); err != nil {
+		log.Fatal(err)
+	}
+}
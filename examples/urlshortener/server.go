@@ -0,0 +1,223 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"patterns/construct"
+	"patterns/funcopts"
+	"patterns/lifecycle/shutdown"
+	"patterns/persistence/repository"
+	"patterns/resilience/ratelimit"
+	"patterns/web/handler"
+	"patterns/web/middleware"
+)
+
+type options struct {
+	addr            string
+	listener        net.Listener
+	rate            float64
+	burst           int
+	cacheSize       int
+	shutdownTimeout time.Duration
+	logger          *slog.Logger
+}
+
+type Option = funcopts.Option[options]
+
+func WithAddr(addr string) Option {
+	return func(options *options) error {
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			return err
+		}
+		options.addr = addr
+		return nil
+	}
+}
+
+// WithListener serves on an already bound listener; it wins over WithAddr.
+func WithListener(l net.Listener) Option {
+	return func(options *options) error {
+		if l == nil {
+			return errors.New("listener cannot be nil")
+		}
+		options.listener = l
+		return nil
+	}
+}
+
+// WithRateLimit allows rate requests per second per client, with bursts.
+func WithRateLimit(rate float64, burst int) Option {
+	return func(options *options) error {
+		if rate <= 0 || burst <= 0 {
+			return errors.New("rate and burst must be positive")
+		}
+		options.rate, options.burst = rate, burst
+		return nil
+	}
+}
+
+func WithCacheSize(n int) Option {
+	return func(options *options) error {
+		if n <= 0 {
+			return errors.New("cache size must be positive")
+		}
+		options.cacheSize = n
+		return nil
+	}
+}
+
+func WithShutdownTimeout(d time.Duration) Option {
+	return func(options *options) error {
+		if d <= 0 {
+			return errors.New("shutdown timeout must be positive")
+		}
+		options.shutdownTimeout = d
+		return nil
+	}
+}
+
+func WithLogger(logger *slog.Logger) Option {
+	return func(options *options) error {
+		if logger == nil {
+			return errors.New("logger cannot be nil")
+		}
+		options.logger = logger
+		return nil
+	}
+}
+
+// Server wires the service behind the middleware chain.
+type Server struct {
+	options options
+	service *Service
+	srv     *http.Server
+}
+
+func (o *options) SetDefaults() {
+	*o = options{
+		addr:            "localhost:8080",
+		rate:            10,
+		burst:           20,
+		cacheSize:       1024,
+		shutdownTimeout: 5 * time.Second,
+		logger:          slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+}
+
+func NewServer(opts ...Option) (*Server, error) {
+	options, err := construct.New(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	service, err := NewService(repository.NewMemory[string, Link](), options.cacheSize)
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{options: *options, service: service}
+	s.srv = &http.Server{
+		Handler:           s.routes(),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	return s, nil
+}
+
+type shortenRequest struct {
+	URL string `json:"url"`
+}
+
+type created struct{ Link }
+
+func (created) StatusCode() int { return http.StatusCreated }
+
+type deleteRequest struct {
+	Code string `path:"code"`
+}
+
+type deleted struct{}
+
+func (deleted) StatusCode() int { return http.StatusOK }
+
+func (s *Server) routes() http.Handler {
+	mapErr := handler.WithErrorMapper(mapError)
+	mux := http.NewServeMux()
+	mux.Handle("POST /links", handler.Adapt(func(ctx context.Context, req shortenRequest) (created, error) {
+		link, err := s.service.Shorten(ctx, req.URL)
+		return created{link}, err
+	}, mapErr))
+	mux.Handle("DELETE /links/{code}", handler.Adapt(func(ctx context.Context, req deleteRequest) (deleted, error) {
+		return deleted{}, s.service.Delete(ctx, req.Code)
+	}, mapErr))
+	// redirects are not JSON, so this one stays a plain handler
+	mux.HandleFunc("GET /{code}", func(w http.ResponseWriter, r *http.Request) {
+		link, err := s.service.Resolve(r.Context(), r.PathValue("code"))
+		if err != nil {
+			status, body := mapError(err)
+			handler.WriteJSON(w, status, body)
+			return
+		}
+		http.Redirect(w, r, link.URL, http.StatusFound)
+	})
+
+	// a client's bucket is full again once it has been idle for
+	// burst/rate, so forgetting it then is invisible to the client; the
+	// floor keeps a huge rate from rounding it down to zero
+	refill := max(time.Duration(float64(s.options.burst)/s.options.rate*float64(time.Second)), time.Second)
+	// options were validated, so these cannot fail
+	limiters, _ := ratelimit.NewKeyed[string](func() ratelimit.Limiter {
+		b, _ := ratelimit.NewTokenBucket(s.options.rate, s.options.burst)
+		return b
+	}, ratelimit.WithIdle(refill))
+	return middleware.Chain(mux,
+		middleware.Logging(s.options.logger),
+		rateLimit(limiters),
+	)
+}
+
+func mapError(err error) (int, any) {
+	switch {
+	case errors.Is(err, ErrInvalidURL):
+		return http.StatusBadRequest, map[string]string{"error": err.Error()}
+	case errors.Is(err, repository.ErrNotFound):
+		return http.StatusNotFound, map[string]string{"error": "no such link"}
+	}
+	return handler.DefaultErrorMapper(err)
+}
+
+// rateLimit rejects clients that exceed their bucket with 429.
+func rateLimit(limiters *ratelimit.Keyed[string]) middleware.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			client, _, err := net.SplitHostPort(r.RemoteAddr)
+			if err != nil {
+				client = r.RemoteAddr
+			}
+			if !limiters.Allow(client) {
+				w.Header().Set("Retry-After", strconv.Itoa(1))
+				handler.WriteJSON(w, http.StatusTooManyRequests, map[string]string{"error": "rate limit exceeded"})
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Run serves until ctx is done, then drains in-flight requests.
+func (s *Server) Run(ctx context.Context) error {
+	l := s.options.listener
+	if l == nil {
+		var err error
+		if l, err = net.Listen("tcp", s.options.addr); err != nil {
+			return err
+		}
+	}
+	s.options.logger.Info("listening", "addr", l.Addr().String())
+	return shutdown.Serve(ctx, s.srv, l, s.options.shutdownTimeout)
+}
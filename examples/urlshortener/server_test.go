@@ -0,0 +1,315 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"patterns/netutil/freeport/freeporttest"
+	"patterns/testing/testoptions"
+)
+
+// testServer is the environment newTestServer builds: a running Server
+// on a free port, and the links seeded into it.
+type testServer struct {
+	opts  []Option
+	seed  int
+	s     *Server
+	url   string
+	links []Link
+	// client reports redirects instead of following them
+	client *http.Client
+}
+
+type testOption = testoptions.Option[testServer]
+
+func withServerOptions(opts ...Option) testOption {
+	return func(_ testoptions.T, e *testServer) {
+		e.opts = append(e.opts, opts...)
+	}
+}
+
+// withSeededLinks shortens n URLs before the test starts.
+func withSeededLinks(n int) testOption {
+	return func(_ testoptions.T, e *testServer) {
+		e.seed = n
+	}
+}
+
+// newTestServer runs a Server until the test ends.
+func newTestServer(t *testing.T, opts ...testOption) *testServer {
+	t.Helper()
+	e := &testServer{}
+	testoptions.Apply(t, e, opts...)
+	l, _ := freeporttest.Listen(t)
+	s, err := NewServer(append([]Option{WithListener(l)}, e.opts...)...)
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	e.s, e.url = s, "http://"+l.Addr().String()
+	transport := &http.Transport{}
+	e.client = &http.Client{Transport: transport, CheckRedirect: noRedirects}
+
+	for i := range e.seed {
+		link, err := s.service.Shorten(context.Background(), fmt.Sprintf("https://example.com/%d", i))
+		if err != nil {
+			t.Fatalf("seed: %v", err)
+		}
+		e.links = append(e.links, link)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- s.Run(ctx) }()
+	t.Cleanup(func() {
+		// a connection dialed but never used would hold up Shutdown
+		// for as long as it stays new, 5s
+		transport.CloseIdleConnections()
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("Run: %v", err)
+		}
+	})
+	return e
+}
+
+// noRedirects, as CheckRedirect, makes a client report redirects instead of following
+// them.
+func noRedirects(*http.Request, []*http.Request) error {
+	return http.ErrUseLastResponse
+}
+
+func (e *testServer) get(t *testing.T, path string) *http.Response {
+	t.Helper()
+	resp, err := e.client.Get(e.url + path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return resp
+}
+
+func TestSeededRedirects(t *testing.T) {
+	e := newTestServer(t, withSeededLinks(3))
+	for _, link := range e.links {
+		resp := e.get(t, "/"+link.Code)
+		if resp.StatusCode != http.StatusFound || resp.Header.Get("Location") != link.URL {
+			t.Errorf("GET /%s = %s to %q, want 302 to %q", link.Code, resp.Status, resp.Header.Get("Location"), link.URL)
+		}
+	}
+}
+
+func TestRateLimitOption(t *testing.T) {
+	e := newTestServer(t, withServerOptions(WithRateLimit(0.001, 2)), withSeededLinks(1))
+	path := "/" + e.links[0].Code
+	for i, want := range []int{http.StatusFound, http.StatusFound, http.StatusTooManyRequests} {
+		if resp := e.get(t, path); resp.StatusCode != want {
+			t.Errorf("request %d: %s, want %d", i+1, resp.Status, want)
+		}
+	}
+}
+
+// send makes a request without following redirects and returns the
+// response with its JSON body decoded, if any.
+func (e *testServer) send(t *testing.T, method, path, body string) (*http.Response, map[string]string) {
+	t.Helper()
+	req, err := http.NewRequest(method, e.url+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := e.client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var decoded map[string]string
+	if resp.Header.Get("Content-Type") == "application/json" {
+		if err := json.NewDecoder(resp.Body).Decode(&decoded); err != nil {
+			t.Fatalf("%s %s: %v", method, path, err)
+		}
+	}
+	return resp, decoded
+}
+
+// TestLifecycle shortens a URL, follows it and deletes it over HTTP; the
+// redirect must stop with the delete although the link was cached.
+func TestLifecycle(t *testing.T) {
+	e := newTestServer(t)
+	resp, body := e.send(t, http.MethodPost, "/links", `{"url":"https://go.dev/doc"}`)
+	if resp.StatusCode != http.StatusCreated || body["url"] != "https://go.dev/doc" || len(body["code"]) != codeLength {
+		t.Fatalf("POST /links = %s %v", resp.Status, body)
+	}
+	code := body["code"]
+	for range 2 {
+		if resp := e.get(t, "/"+code); resp.StatusCode != http.StatusFound || resp.Header.Get("Location") != "https://go.dev/doc" {
+			t.Errorf("GET /%s = %s to %q", code, resp.Status, resp.Header.Get("Location"))
+		}
+	}
+	if resp, _ := e.send(t, http.MethodDelete, "/links/"+code, ""); resp.StatusCode != http.StatusOK {
+		t.Errorf("DELETE /links/%s = %s", code, resp.Status)
+	}
+	if resp, body := e.send(t, http.MethodGet, "/"+code, ""); resp.StatusCode != http.StatusNotFound || body["error"] != "no such link" {
+		t.Errorf("GET /%s after DELETE = %s %v, want 404", code, resp.Status, body)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	e := newTestServer(t)
+	for _, c := range []struct {
+		method, path, body string
+		status             int
+		error              string
+	}{
+		{http.MethodPost, "/links", `{"url":"go.dev"}`, http.StatusBadRequest, ErrInvalidURL.Error()},
+		{http.MethodPost, "/links", `{"url":"ftp://go.dev"}`, http.StatusBadRequest, ErrInvalidURL.Error()},
+		{http.MethodPost, "/links", `{"url":"https://"}`, http.StatusBadRequest, ErrInvalidURL.Error()},
+		{http.MethodPost, "/links", `{"url":`, http.StatusBadRequest, "decode request: unexpected EOF"},
+		{http.MethodGet, "/nosuch", "", http.StatusNotFound, "no such link"},
+		{http.MethodDelete, "/links/nosuch", "", http.StatusNotFound, "no such link"},
+	} {
+		resp, body := e.send(t, c.method, c.path, c.body)
+		if resp.StatusCode != c.status || body["error"] != c.error {
+			t.Errorf("%s %s %s = %s %v, want %d %q", c.method, c.path, c.body, resp.Status, body, c.status, c.error)
+		}
+	}
+	// the mux answers these before any handler
+	if resp, _ := e.send(t, http.MethodPut, "/links", ""); resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("PUT /links = %s, want 405", resp.Status)
+	}
+}
+
+// TestRateLimitPerClient checks that one client's exhausted bucket does
+// not hold back another: the limiter keys on the remote address, and a
+// client bound to another loopback address is another client.
+func TestRateLimitPerClient(t *testing.T) {
+	e := newTestServer(t, withServerOptions(WithRateLimit(0.001, 1)), withSeededLinks(1))
+	path := "/" + e.links[0].Code
+	e.get(t, path)
+	if resp := e.get(t, path); resp.StatusCode != http.StatusTooManyRequests || resp.Header.Get("Retry-After") != "1" {
+		t.Fatalf("second request = %s, Retry-After %q; want 429", resp.Status, resp.Header.Get("Retry-After"))
+	}
+	other := &http.Client{
+		CheckRedirect: noRedirects,
+		Transport: &http.Transport{DialContext: (&net.Dialer{
+			LocalAddr: &net.TCPAddr{IP: net.IPv4(127, 0, 0, 2)},
+		}).DialContext},
+	}
+	defer other.CloseIdleConnections()
+	resp, err := other.Get(e.url + path)
+	if err != nil {
+		t.Skipf("cannot dial from 127.0.0.2: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Errorf("another client's first request = %s, want 302", resp.Status)
+	}
+}
+
+func TestLogging(t *testing.T) {
+	var mu sync.Mutex
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(lockedWriter{&mu, &buf}, nil))
+	e := newTestServer(t, withServerOptions(WithLogger(logger)), withSeededLinks(1))
+	e.get(t, "/"+e.links[0].Code)
+	mu.Lock()
+	defer mu.Unlock()
+	for _, want := range []string{"msg=listening", "msg=request method=GET path=/" + e.links[0].Code + " status=302"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("log lacks %q:\n%s", want, buf.String())
+		}
+	}
+}
+
+type lockedWriter struct {
+	mu *sync.Mutex
+	w  io.Writer
+}
+
+func (w lockedWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.w.Write(p)
+}
+
+// TestConcurrentClients shortens and follows links from many clients at
+// once; run with -race.
+func TestConcurrentClients(t *testing.T) {
+	e := newTestServer(t, withServerOptions(WithRateLimit(1e6, 1e6), WithCacheSize(4)))
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range 10 {
+				target := fmt.Sprintf("https://example.com/%d/%d", i, j)
+				req, _ := http.NewRequest(http.MethodPost, e.url+"/links", strings.NewReader(`{"url":"`+target+`"}`))
+				resp, err := e.client.Do(req)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				var link Link
+				err = json.NewDecoder(resp.Body).Decode(&link)
+				resp.Body.Close()
+				if err != nil || resp.StatusCode != http.StatusCreated {
+					t.Errorf("POST %s = %s, %v", target, resp.Status, err)
+					return
+				}
+				// a cache of 4 keeps evicting, so most of these load
+				resp, err = e.client.Get(e.url + "/" + link.Code)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				resp.Body.Close()
+				if resp.Header.Get("Location") != target {
+					t.Errorf("GET /%s to %q, want %q", link.Code, resp.Header.Get("Location"), target)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// TestShutdown checks that Run returns once its context is done and the
+// port stops accepting requests.
+func TestShutdown(t *testing.T) {
+	l, _ := freeporttest.Listen(t)
+	s, err := NewServer(WithListener(l), WithShutdownTimeout(time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- s.Run(ctx) }()
+	url := "http://" + l.Addr().String() + "/nosuch"
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Run = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return after cancel")
+	}
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	if resp, err := client.Get(url); err == nil {
+		resp.Body.Close()
+		t.Error("server still answers after shutdown")
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"net/url"
+	"time"
+
+	"patterns/caching/cacheaside"
+	"patterns/caching/lru"
+	"patterns/persistence/repository"
+)
+
+// Link is a shortened URL.
+type Link struct {
+	Code    string    `json:"code"`
+	URL     string    `json:"url"`
+	Created time.Time `json:"created"`
+}
+
+var ErrInvalidURL = errors.New("url must be absolute http or https")
+
+const (
+	codeLength = 7
+	alphabet   = "abcdefghijkmnpqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+)
+
+// Service is the business layer; it knows nothing about HTTP.
+type Service struct {
+	links repository.Repository[string, Link]
+	cache *cacheaside.Aside[string, Link]
+}
+
+func NewService(links repository.Repository[string, Link], cacheSize int) (*Service, error) {
+	cache, err := lru.New[string, Link](cacheSize)
+	if err != nil {
+		return nil, err
+	}
+	return &Service{links: links, cache: cacheaside.New(cache, links.Get)}, nil
+}
+
+// Shorten stores target under a fresh random code.
+func (s *Service) Shorten(ctx context.Context, target string) (Link, error) {
+	u, err := url.Parse(target)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return Link{}, ErrInvalidURL
+	}
+	// collisions are unlikely with 56^7 codes but cheap to retry
+	for range 5 {
+		link := Link{Code: newCode(), URL: u.String(), Created: time.Now().UTC()}
+		err := s.links.Create(ctx, link.Code, link)
+		if errors.Is(err, repository.ErrExists) {
+			continue
+		}
+		if err != nil {
+			return Link{}, err
+		}
+		return link, nil
+	}
+	return Link{}, errors.New("could not allocate a code")
+}
+
+// Resolve returns the link for code, served from the cache when hot.
+func (s *Service) Resolve(ctx context.Context, code string) (Link, error) {
+	return s.cache.Get(ctx, code)
+}
+
+// Delete removes a link and invalidates its cache entry.
+func (s *Service) Delete(ctx context.Context, code string) error {
+	if err := s.links.Delete(ctx, code); err != nil {
+		return err
+	}
+	s.cache.Invalidate(code)
+	return nil
+}
+
+func newCode() string {
+	b := make([]byte, codeLength)
+	rand.Read(b)
+	for i := range b {
+		b[i] = alphabet[int(b[i])%len(alphabet)]
+	}
+	return string(b)
+}
@@ -0,0 +1,66 @@
+// Package check is the tiny harness behind the exercises: each exercise
+// lists checks that fail until the learner's implementation is complete.
+package check
+
+import (
+	"errors"
+	"fmt"
+
+	"patterns/testing/testoptions"
+)
+
+// ErrNotImplemented is what skeletons return before they are filled in.
+var ErrNotImplemented = errors.New("not implemented")
+
+// Check is one expectation on an exercise: either Run, returning an
+// error, or Test, written like a go test function with t.Fatalf and
+// t.Cleanup.
+type Check struct {
+	Name string
+	Run  func() error
+	Test func(t testoptions.T)
+}
+
+// Exercise groups the checks for one pattern.
+type Exercise struct {
+	Name   string
+	Path   string
+	Checks []Check
+}
+
+// Result is the outcome of one check.
+type Result struct {
+	Check string
+	Err   error
+}
+
+// Run executes every check, turning panics (the usual state of an
+// unfinished skeleton) into failures.
+func (e Exercise) Run() []Result {
+	var out []Result
+	for _, c := range e.Checks {
+		run := c.Run
+		if c.Test != nil {
+			run = func() error { return testoptions.Run(c.Test) }
+		}
+		out = append(out, Result{Check: c.Name, Err: safeRun(run)})
+	}
+	return out
+}
+
+func safeRun(f func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return f()
+}
+
+// Expect returns an error describing got vs want when they differ.
+func Expect[T comparable](what string, got, want T) error {
+	if got != want {
+		return fmt.Errorf("%s: got %v, want %v", what, got, want)
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+// Package exercises lists the learner exercises. Each exercise package has
+// a skeleton (built by default) and a reference solution behind the
+// "solution" build tag:
+//
+//	go run patterns/cmd/patterns check              # your progress
+//	go run -tags solution patterns/cmd/patterns check  # reference
+//
+// The same checks run as a go test suite, which fails until every
+// skeleton is filled in; with the solutions it also checks that no
+// skeleton passes a check by accident:
+//
+//	go test -tags exercises patterns/exercises  # your progress
+//	go test -tags solution patterns/exercises   # reference
+package exercises
+
+import (
+	"patterns/exercises/check"
+	"patterns/exercises/funcoptions"
+	"patterns/exercises/middleware"
+	"patterns/exercises/valueobject"
+)
+
+// All returns every exercise in suggested order.
+func All() []check.Exercise {
+	return []check.Exercise{
+		funcoptions.Exercise,
+		valueobject.Exercise,
+		middleware.Exercise,
+	}
+}
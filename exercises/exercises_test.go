@@ -0,0 +1,27 @@
+//go:build exercises || solution
+
+package exercises
+
+import "testing"
+
+// TestExercises runs every check of every exercise as a subtest, so it
+// fails until the skeletons are filled in:
+//
+//	go test -tags exercises patterns/exercises [-run TestExercises/value-object]
+//
+// With -tags solution it runs against the reference solutions instead,
+// which must pass it.
+func TestExercises(t *testing.T) {
+	for _, ex := range All() {
+		t.Run(ex.Name, func(t *testing.T) {
+			if len(ex.Checks) == 0 {
+				t.Fatal("no checks")
+			}
+			for _, r := range ex.Run() {
+				if r.Err != nil {
+					t.Errorf("%s: %v", r.Check, r.Err)
+				}
+			}
+		})
+	}
+}
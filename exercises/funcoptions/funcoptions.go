@@ -0,0 +1,70 @@
+// Package funcoptions is the functional options exercise: implement
+// WithPort, WithHost and NewServer in skeleton.go.
+package funcoptions
+
+import (
+	"errors"
+
+	"patterns/exercises/check"
+	"patterns/testing/testoptions"
+)
+
+type options struct {
+	host string
+	port int
+}
+
+type Option func(o *options) error
+
+type Server struct {
+	Host string
+	Port int
+}
+
+// newServer is the test helper: the learner's own options pass straight
+// through, and a construction error fails the check.
+func newServer(t testoptions.T, opts ...Option) Server {
+	t.Helper()
+	s, err := NewServer(opts...)
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	return s
+}
+
+func expect(t testoptions.T, errs ...error) {
+	t.Helper()
+	if err := errors.Join(errs...); err != nil {
+		t.Fatalf("%v", err)
+	}
+}
+
+var Exercise = check.Exercise{
+	Name: "functional-options",
+	Path: "exercises/funcoptions",
+	Checks: []check.Check{
+		{Name: "defaults", Test: func(t testoptions.T) {
+			s := newServer(t)
+			expect(t, check.Expect("host", s.Host, "localhost"), check.Expect("port", s.Port, 8080))
+		}},
+		{Name: "options override defaults", Test: func(t testoptions.T) {
+			s := newServer(t, WithHost("example.com"), WithPort(9000))
+			expect(t, check.Expect("host", s.Host, "example.com"), check.Expect("port", s.Port, 9000))
+		}},
+		{Name: "zero port is allowed", Test: func(t testoptions.T) {
+			expect(t, check.Expect("port", newServer(t, WithPort(0)).Port, 0))
+		}},
+		{Name: "negative port is rejected", Run: func() error {
+			if _, err := NewServer(WithPort(-1)); err == nil || errors.Is(err, check.ErrNotImplemented) {
+				return errors.New("want a validation error for port -1")
+			}
+			return nil
+		}},
+		{Name: "empty host is rejected", Run: func() error {
+			if _, err := NewServer(WithHost("")); err == nil || errors.Is(err, check.ErrNotImplemented) {
+				return errors.New("want a validation error for empty host")
+			}
+			return nil
+		}},
+	},
+}
@@ -0,0 +1,28 @@
+//go:build !solution
+
+package funcoptions
+
+import "patterns/exercises/check"
+
+// WithPort must reject negative ports and record the port.
+func WithPort(port int) Option {
+	return func(o *options) error {
+		// TODO: validate and set o.port
+		return check.ErrNotImplemented
+	}
+}
+
+// WithHost must reject an empty host and record it.
+func WithHost(host string) Option {
+	return func(o *options) error {
+		// TODO: validate and set o.host
+		return check.ErrNotImplemented
+	}
+}
+
+// NewServer applies opts over the defaults (host "localhost", port 8080)
+// and returns the first option error.
+func NewServer(opts ...Option) (Server, error) {
+	// TODO
+	return Server{}, check.ErrNotImplemented
+}
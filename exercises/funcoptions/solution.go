@@ -0,0 +1,35 @@
+//go:build solution
+
+package funcoptions
+
+import "errors"
+
+func WithPort(port int) Option {
+	return func(o *options) error {
+		if port < 0 {
+			return errors.New("port cannot be negative")
+		}
+		o.port = port
+		return nil
+	}
+}
+
+func WithHost(host string) Option {
+	return func(o *options) error {
+		if host == "" {
+			return errors.New("host cannot be empty")
+		}
+		o.host = host
+		return nil
+	}
+}
+
+func NewServer(opts ...Option) (Server, error) {
+	o := options{host: "localhost", port: 8080}
+	for _, opt := range opts {
+		if err := opt(&o); err != nil {
+			return Server{}, err
+		}
+	}
+	return Server{Host: o.host, Port: o.port}, nil
+}
@@ -0,0 +1,105 @@
+// Package middleware is the decorator exercise: implement Chain in
+// skeleton.go so the first middleware is the outermost.
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+
+	"patterns/exercises/check"
+	"patterns/testing/testoptions"
+)
+
+type Middleware func(http.Handler) http.Handler
+
+func tag(name string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("X-Trace", name)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+var final = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("X-Trace", "handler")
+})
+
+// testServer is the environment newTestServer builds: the learner's Chain
+// over a handler, served by httptest.
+type testServer struct {
+	handler    http.Handler
+	middleware []Middleware
+	url        string
+}
+
+type option = testoptions.Option[testServer]
+
+func withMiddleware(mw ...Middleware) option {
+	return func(t testoptions.T, s *testServer) {
+		s.middleware = append(s.middleware, mw...)
+	}
+}
+
+// withBackend puts Chain in front of a separate backend server, as a
+// proxy would; the backend is closed when the check ends.
+func withBackend(h http.Handler) option {
+	return func(t testoptions.T, s *testServer) {
+		backend := httptest.NewServer(h)
+		t.Cleanup(backend.Close)
+		target, err := url.Parse(backend.URL)
+		if err != nil {
+			t.Fatalf("backend url: %v", err)
+		}
+		s.handler = httputil.NewSingleHostReverseProxy(target)
+	}
+}
+
+func newTestServer(t testoptions.T, opts ...option) *testServer {
+	t.Helper()
+	s := &testServer{handler: final}
+	testoptions.Apply(t, s, opts...)
+	srv := httptest.NewServer(Chain(s.handler, s.middleware...))
+	t.Cleanup(srv.Close)
+	s.url = srv.URL
+	return s
+}
+
+// trace returns the X-Trace values of a GET, in order.
+func (s *testServer) trace(t testoptions.T) string {
+	t.Helper()
+	resp, err := http.Get(s.url)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	resp.Body.Close()
+	return strings.Join(resp.Header.Values("X-Trace"), ",")
+}
+
+func expect(t testoptions.T, what, got, want string) {
+	t.Helper()
+	if err := check.Expect(what, got, want); err != nil {
+		t.Fatalf("%v", err)
+	}
+}
+
+var Exercise = check.Exercise{
+	Name: "middleware-chain",
+	Path: "exercises/middleware",
+	Checks: []check.Check{
+		{Name: "empty chain is the handler", Test: func(t testoptions.T) {
+			expect(t, "trace", newTestServer(t).trace(t), "handler")
+		}},
+		{Name: "first middleware is outermost", Test: func(t testoptions.T) {
+			s := newTestServer(t, withMiddleware(tag("a"), tag("b")))
+			expect(t, "trace", s.trace(t), "a,b,handler")
+		}},
+		{Name: "chain wraps a proxied backend", Test: func(t testoptions.T) {
+			s := newTestServer(t, withBackend(final), withMiddleware(tag("a")))
+			expect(t, "trace", s.trace(t), "a,handler")
+		}},
+	},
+}
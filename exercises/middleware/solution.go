@@ -0,0 +1,12 @@
+//go:build solution
+
+package middleware
+
+import "net/http"
+
+func Chain(h http.Handler, mw ...Middleware) http.Handler {
+	for i := len(mw) - 1; i >= 0; i-- {
+		h = mw[i](h)
+	}
+	return h
+}
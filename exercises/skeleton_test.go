@@ -0,0 +1,32 @@
+//go:build solution
+
+package exercises
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// TestSkeletons checks the exercises themselves, from a build with the
+// solutions: patterns check, built without them, must report every
+// check of every skeleton as failing, so none passes by accident.
+//
+//	go test -tags solution patterns/exercises
+func TestSkeletons(t *testing.T) {
+	gobin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go command not found:", err)
+	}
+	out, err := exec.Command(gobin, "run", "patterns/cmd/patterns", "check").CombinedOutput()
+	if err == nil {
+		t.Fatalf("patterns check passed on the skeletons:\n%s", out)
+	}
+	for _, ex := range All() {
+		want := fmt.Sprintf("%-20s 0/%d  (%s)\n", ex.Name, len(ex.Checks), ex.Path)
+		if !strings.Contains(string(out), want) {
+			t.Errorf("patterns check does not report %q on an untouched skeleton:\n%s", strings.TrimSpace(want), out)
+		}
+	}
+}
@@ -0,0 +1,12 @@
+//go:build !solution
+
+package valueobject
+
+import "patterns/exercises/check"
+
+// NewEmail must trim, lowercase and split addr into local and domain,
+// rejecting anything without exactly one "@" and non-empty parts.
+func NewEmail(addr string) (Email, error) {
+	// TODO
+	return Email{}, check.ErrNotImplemented
+}
@@ -0,0 +1,17 @@
+//go:build solution
+
+package valueobject
+
+import (
+	"fmt"
+	"strings"
+)
+
+func NewEmail(addr string) (Email, error) {
+	addr = strings.ToLower(strings.TrimSpace(addr))
+	local, domain, ok := strings.Cut(addr, "@")
+	if !ok || local == "" || domain == "" || strings.Contains(domain, "@") {
+		return Email{}, fmt.Errorf("invalid email %q", addr)
+	}
+	return Email{local: local, domain: domain}, nil
+}
@@ -0,0 +1,54 @@
+// Package valueobject is the value object exercise: implement NewEmail in
+// skeleton.go so equal addresses produce equal, comparable values.
+package valueobject
+
+import (
+	"errors"
+
+	"patterns/exercises/check"
+)
+
+// Email must stay comparable so it can be a map key.
+type Email struct {
+	local  string
+	domain string
+}
+
+func (e Email) String() string { return e.local + "@" + e.domain }
+
+var Exercise = check.Exercise{
+	Name: "value-object",
+	Path: "exercises/valueobject",
+	Checks: []check.Check{
+		{Name: "normalizes case and spaces", Run: func() error {
+			a, err := NewEmail("  Bob@Example.COM ")
+			if err != nil {
+				return err
+			}
+			return check.Expect("email", a.String(), "bob@example.com")
+		}},
+		{Name: "equal inputs give equal values", Run: func() error {
+			a, err1 := NewEmail("bob@example.com")
+			b, err2 := NewEmail("BOB@example.com")
+			if err := errors.Join(err1, err2); err != nil {
+				return err
+			}
+			return check.Expect("a == b", a == b, true)
+		}},
+		{Name: "usable as map key", Run: func() error {
+			a, err1 := NewEmail("bob@example.com")
+			b, err2 := NewEmail("Bob@Example.com")
+			if err := errors.Join(err1, err2); err != nil {
+				return err
+			}
+			seen := map[Email]int{a: 1}
+			return check.Expect("seen[b]", seen[b], 1)
+		}},
+		{Name: "rejects missing @", Run: func() error {
+			if _, err := NewEmail("bob.example.com"); err == nil || errors.Is(err, check.ErrNotImplemented) {
+				return errors.New("want a validation error")
+			}
+			return nil
+		}},
+	},
+}
@@ -0,0 +1,360 @@
+// Package funcopts is the functional options loop written once:
+//
+//	type options struct{ port int }
+//
+//	func WithPort(port int) funcopts.Option[options] {
+//		return funcopts.Named("port", func(o *options) error {
+//			o.port = port
+//			return nil
+//		})
+//	}
+//
+//	func NewServer(opts ...funcopts.Option[options]) (*Server, error) {
+//		var o options
+//		if err := funcopts.Apply(&o, opts...); err != nil {
+//			return nil, err
+//		}
+//		...
+//	}
+package funcopts
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Option configures a value of type T.
+type Option[T any] func(*T) error
+
+// Apply applies opts to target in order with the default ApplyConfig,
+// stopping at the first error.
+func Apply[T any](target *T, opts ...Option[T]) error {
+	return ApplyWith(ApplyConfig{}, target, opts...)
+}
+
+// DuplicatePolicy decides what happens when an option with the same name
+// is given more than once. Only options wrapped by Named have a name;
+// anonymous options are always applied.
+type DuplicatePolicy int
+
+const (
+	// LastWins applies every option, so later ones overwrite earlier ones.
+	// This is what a plain loop gives you and what most libraries do
+	// (gRPC DialOption, zap.Option, the options in this repo).
+	LastWins DuplicatePolicy = iota
+	// FirstWins skips repeats. Useful when defaults are appended after the
+	// caller's options and must not override them.
+	FirstWins
+	// ErrorOnDuplicate rejects repeats with a *DuplicateError, in the
+	// spirit of the standard flag package refusing to redefine a flag.
+	ErrorOnDuplicate
+)
+
+// ApplyConfig controls ApplyWith. The zero value is the default behavior.
+type ApplyConfig struct {
+	Duplicates DuplicatePolicy
+	// OnWarning receives deprecation warnings, at most once per option per
+	// ApplyWith call. Nil discards them.
+	OnWarning func(Warning)
+	// OnApply receives the name and (redacted) value of every described
+	// option that was applied, in application order.
+	OnApply func(Applied)
+	// Redact, if set, runs on every Applied after the option's own
+	// redaction, e.g. to mask values by name.
+	Redact func(Applied) Applied
+	// CollectErrors applies every option even after one fails and returns
+	// all failures joined with errors.Join, so a configuration can be
+	// fixed in one pass. Options are independent funcs, so a failed one
+	// simply leaves its fields as they were.
+	CollectErrors bool
+}
+
+// Applied records one applied option for introspection.
+type Applied struct {
+	Name  string `json:"name"`
+	Value any    `json:"value"`
+}
+
+// Redacted replaces secret values in Applied records.
+const Redacted = "[REDACTED]"
+
+// RedactAll is an Info.Redact func hiding the whole value.
+func RedactAll(any) any { return Redacted }
+
+// Record returns an OnApply func appending to as.
+func Record(as *[]Applied) func(Applied) {
+	return func(a Applied) { *as = append(*as, a) }
+}
+
+// Effective reduces a record to one entry per name, keeping the last
+// value (LastWins semantics) in the position it was applied.
+func Effective(as []Applied) []Applied {
+	last := map[string]int{}
+	for i, a := range as {
+		last[a.Name] = i
+	}
+	var out []Applied
+	for i, a := range as {
+		if last[a.Name] == i {
+			out = append(out, a)
+		}
+	}
+	return out
+}
+
+// Warning is a non-fatal problem found while applying options.
+type Warning struct {
+	// Option is the name of the deprecated option, if it has one.
+	Option  string
+	Message string
+}
+
+func (w Warning) String() string {
+	if w.Option == "" {
+		return "deprecated option: " + w.Message
+	}
+	return "option " + w.Option + " is deprecated: " + w.Message
+}
+
+// Collect returns an OnWarning func appending to ws, for callers that
+// return warnings alongside the constructed value.
+func Collect(ws *[]Warning) func(Warning) {
+	return func(w Warning) { *ws = append(*ws, w) }
+}
+
+// DuplicateError reports an option given twice under ErrorOnDuplicate.
+type DuplicateError struct {
+	Name string
+}
+
+func (e *DuplicateError) Error() string {
+	return fmt.Sprintf("option %s given more than once", e.Name)
+}
+
+// ConflictError reports options from the same exclusive group, in the
+// order they were given.
+type ConflictError struct {
+	Group   string
+	Options []string
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("options %s are mutually exclusive (group %s)", strings.Join(e.Options, ", "), e.Group)
+}
+
+// ApplyWith applies opts to target according to cfg.
+//
+// Conflicts do not stop application, so the returned *ConflictError names
+// every option of the group; any other error is returned immediately
+// unless cfg.CollectErrors is set, in which case option errors come first
+// in the joined error, followed by conflicts.
+//
+// A target may only be configured by one ApplyWith at a time; a second
+// concurrent call fails with ErrConcurrentApply instead of racing. Targets
+// embedding Guard also reject a slice they were already configured with.
+func ApplyWith[T any](cfg ApplyConfig, target *T, opts ...Option[T]) error {
+	s := &session{
+		cfg:    cfg,
+		seen:   map[string]bool{},
+		groups: map[string]*ConflictError{},
+		warned: map[Warning]bool{},
+	}
+	release, err := guardTarget(target, opts)
+	if err != nil {
+		return err
+	}
+	defer release()
+	if _, busy := sessions.LoadOrStore(target, s); busy {
+		return ErrConcurrentApply
+	}
+	defer sessions.Delete(target)
+
+	var errs []error
+	for _, opt := range opts {
+		if err := opt(target); err != nil {
+			if !cfg.CollectErrors {
+				return err
+			}
+			errs = append(errs, err)
+		}
+	}
+
+	for _, group := range s.groupOrder {
+		if c := s.groups[group]; len(c.Options) > 1 {
+			errs = append(errs, c)
+		}
+	}
+	if len(errs) == 1 {
+		return errs[0]
+	}
+	return errors.Join(errs...)
+}
+
+// Combine returns one option applying opts in order, so a group of options
+// can be named, returned and passed around as a single value. The options
+// inside keep their own names and groups: a later option can still
+// override one of them, and duplicates are detected as if they had been
+// given individually. Under CollectErrors every option of the group is
+// applied and the failures are joined; otherwise the first failure stops it.
+func Combine[T any](opts ...Option[T]) Option[T] {
+	return func(target *T) error {
+		collect := false
+		if v, ok := sessions.Load(target); ok {
+			collect = v.(*session).cfg.CollectErrors
+		}
+		var errs []error
+		for _, opt := range opts {
+			if err := opt(target); err != nil {
+				if !collect {
+					return err
+				}
+				errs = append(errs, err)
+			}
+		}
+		if len(errs) == 1 {
+			return errs[0]
+		}
+		return errors.Join(errs...)
+	}
+}
+
+// session is the bookkeeping of one ApplyWith call. Described options find
+// it through the target pointer, which keeps Option a plain func type.
+type session struct {
+	cfg        ApplyConfig
+	seen       map[string]bool
+	groups     map[string]*ConflictError
+	groupOrder []string
+	warned     map[Warning]bool
+	// deprecation is set by Deprecate for the Describe it wraps
+	deprecation string
+}
+
+func (s *session) warn(w Warning) {
+	if s.warned[w] {
+		return
+	}
+	s.warned[w] = true
+	if s.cfg.OnWarning != nil {
+		s.cfg.OnWarning(w)
+	}
+}
+
+var sessions sync.Map // target pointer -> *session
+
+// Info is the metadata an option can carry.
+type Info struct {
+	// Name identifies the option for duplicate detection and errors.
+	Name string
+	// Group makes options mutually exclusive: at most one option of a
+	// non-empty group may be given (e.g. WithTLS and WithInsecure).
+	Group string
+	// Deprecated, if set, is reported as a Warning whenever the option is
+	// applied, e.g. "use WithTimeouts instead".
+	Deprecated string
+	// Value is what the option sets, for OnApply. Leave nil for options
+	// without a meaningful value.
+	Value any
+	// Redact transforms Value before it leaves the package, e.g.
+	// RedactAll for passwords and keys. A Value with an IsSecret method
+	// returning true, like security/secret.Secret, is redacted anyway.
+	Redact func(any) any
+}
+
+// Named gives opt a name so ApplyWith can recognise repeats.
+func Named[T any](name string, opt Option[T]) Option[T] {
+	return Describe(Info{Name: name}, opt)
+}
+
+// Describe attaches info to opt. Outside of ApplyWith (calling the option
+// directly) the metadata has no effect.
+func Describe[T any](info Info, opt Option[T]) Option[T] {
+	return func(target *T) error {
+		v, ok := sessions.Load(target)
+		if !ok {
+			return opt(target)
+		}
+		s := v.(*session)
+
+		if s.deprecation != "" {
+			s.warn(Warning{Option: info.Name, Message: s.deprecation})
+			s.deprecation = ""
+		}
+		if info.Deprecated != "" {
+			s.warn(Warning{Option: info.Name, Message: info.Deprecated})
+		}
+
+		if s.seen[info.Name] {
+			switch s.cfg.Duplicates {
+			case FirstWins:
+				return nil
+			case ErrorOnDuplicate:
+				return &DuplicateError{Name: info.Name}
+			}
+		}
+		s.seen[info.Name] = true
+
+		if info.Group != "" {
+			c, ok := s.groups[info.Group]
+			if !ok {
+				c = &ConflictError{Group: info.Group}
+				s.groups[info.Group] = c
+				s.groupOrder = append(s.groupOrder, info.Group)
+			}
+			if !slices.Contains(c.Options, info.Name) {
+				c.Options = append(c.Options, info.Name)
+			}
+			if len(c.Options) > 1 {
+				// already an error; do not apply the conflicting option
+				return nil
+			}
+		}
+
+		if err := opt(target); err != nil {
+			return err
+		}
+		s.applied(info)
+		return nil
+	}
+}
+
+func (s *session) applied(info Info) {
+	if s.cfg.OnApply == nil {
+		return
+	}
+	a := Applied{Name: info.Name, Value: info.Value}
+	if info.Redact != nil {
+		a.Value = info.Redact(a.Value)
+	}
+	if s.cfg.Redact != nil {
+		a = s.cfg.Redact(a)
+	}
+	if v, ok := a.Value.(interface{ IsSecret() bool }); ok && v.IsSecret() {
+		a.Value = Redacted
+	}
+	s.cfg.OnApply(a)
+}
+
+// Deprecate marks opt as deprecated; applying it emits msg as a Warning.
+// If opt was built with Named or Describe, the warning carries its name.
+func Deprecate[T any](opt Option[T], msg string) Option[T] {
+	return func(target *T) error {
+		v, ok := sessions.Load(target)
+		if !ok {
+			return opt(target)
+		}
+		s := v.(*session)
+
+		s.deprecation = msg
+		err := opt(target)
+		if s.deprecation != "" {
+			// opt was anonymous, so no Describe consumed the message
+			s.warn(Warning{Message: msg})
+			s.deprecation = ""
+		}
+		return err
+	}
+}
@@ -0,0 +1,436 @@
+package funcopts
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type config struct {
+	port  int
+	hosts []string
+}
+
+func withPort(port int) Option[config] {
+	return Named("port", func(c *config) error {
+		c.port = port
+		return nil
+	})
+}
+
+// withHost is anonymous, so it is never a duplicate.
+func withHost(host string) Option[config] {
+	return func(c *config) error {
+		c.hosts = append(c.hosts, host)
+		return nil
+	}
+}
+
+func TestApply(t *testing.T) {
+	errBad := errors.New("bad host")
+	bad := Option[config](func(*config) error { return errBad })
+	for _, c := range []struct {
+		name      string
+		opts      []Option[config]
+		wantHosts []string
+		wantErr   error
+	}{
+		{"none", nil, nil, nil},
+		{"in order", []Option[config]{withHost("a"), withHost("b"), withHost("c")}, []string{"a", "b", "c"}, nil},
+		{"stops at first error", []Option[config]{withHost("a"), bad, withHost("c"), bad}, []string{"a"}, errBad},
+	} {
+		var got config
+		err := Apply(&got, c.opts...)
+		if err != c.wantErr {
+			t.Errorf("%s: err = %v, want %v unwrapped", c.name, err, c.wantErr)
+		}
+		if !reflect.DeepEqual(got.hosts, c.wantHosts) {
+			t.Errorf("%s: hosts = %q, want %q", c.name, got.hosts, c.wantHosts)
+		}
+	}
+}
+
+func TestApplyNamedLastWins(t *testing.T) {
+	var got config
+	if err := Apply(&got, withPort(1), withPort(2)); err != nil {
+		t.Fatal(err)
+	}
+	if got.port != 2 {
+		t.Errorf("port = %d, want the last one", got.port)
+	}
+}
+
+// TestApplyNested applies options from inside an option to another target,
+// as constructors building sub-configs do.
+func TestApplyNested(t *testing.T) {
+	var inner config
+	outer := Option[transport](func(tr *transport) error {
+		if err := Apply(&inner, withPort(8080)); err != nil {
+			return err
+		}
+		tr.mode = "nested"
+		return nil
+	})
+	var got transport
+	if err := Apply(&got, outer); err != nil {
+		t.Fatal(err)
+	}
+	if got.mode != "nested" || inner.port != 8080 {
+		t.Errorf("got %+v and %+v", got, inner)
+	}
+}
+
+func TestDuplicatePolicy(t *testing.T) {
+	opts := []Option[config]{withPort(1), withHost("a"), withPort(2), withHost("b"), withPort(3)}
+	for _, c := range []struct {
+		policy   DuplicatePolicy
+		wantPort int
+		wantErr  error
+	}{
+		{LastWins, 3, nil},
+		{FirstWins, 1, nil},
+		{ErrorOnDuplicate, 1, &DuplicateError{Name: "port"}},
+	} {
+		var got config
+		err := ApplyWith(ApplyConfig{Duplicates: c.policy}, &got, opts...)
+		if !reflect.DeepEqual(err, c.wantErr) {
+			t.Errorf("policy %d: err = %v, want %v", c.policy, err, c.wantErr)
+		}
+		if got.port != c.wantPort {
+			t.Errorf("policy %d: port = %d, want %d", c.policy, got.port, c.wantPort)
+		}
+		wantHosts := []string{"a", "b"}
+		if c.wantErr != nil {
+			// application stopped at the duplicate
+			wantHosts = wantHosts[:1]
+		}
+		if !reflect.DeepEqual(got.hosts, wantHosts) {
+			t.Errorf("policy %d: hosts = %q, want %q", c.policy, got.hosts, wantHosts)
+		}
+	}
+}
+
+func TestDuplicatePolicyCollectErrors(t *testing.T) {
+	var got config
+	err := ApplyWith(ApplyConfig{Duplicates: ErrorOnDuplicate, CollectErrors: true}, &got,
+		withPort(1), withPort(2), withHost("a"), withPort(3))
+	var dup *DuplicateError
+	if !errors.As(err, &dup) || dup.Name != "port" {
+		t.Fatalf("err = %v, want DuplicateError for port", err)
+	}
+	if n := len(err.(interface{ Unwrap() []error }).Unwrap()); n != 2 {
+		t.Errorf("joined %d errors, want one per repeat", n)
+	}
+	if got.port != 1 || !reflect.DeepEqual(got.hosts, []string{"a"}) {
+		t.Errorf("got %+v, want the first port and every anonymous option", got)
+	}
+}
+
+// TestDuplicatePolicyDirectCall checks that metadata has no effect when an
+// option is called outside ApplyWith.
+func TestDuplicatePolicyDirectCall(t *testing.T) {
+	var got config
+	for _, port := range []int{1, 2} {
+		if err := withPort(port)(&got); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got.port != 2 {
+		t.Errorf("port = %d, want 2", got.port)
+	}
+}
+
+type transport struct {
+	mode    string
+	encoder string
+}
+
+func withMode(name string, group string) Option[transport] {
+	return Describe(Info{Name: name, Group: group}, func(t *transport) error {
+		if group == "encoding" {
+			t.encoder = name
+		} else {
+			t.mode = name
+		}
+		return nil
+	})
+}
+
+func TestConflict(t *testing.T) {
+	var (
+		tls      = withMode("tls", "security")
+		insecure = withMode("insecure", "security")
+		mtls     = withMode("mtls", "security")
+		gob      = withMode("gob", "encoding")
+		json     = withMode("json", "encoding")
+		failing  = Option[transport](func(*transport) error { return errors.New("bad option") })
+	)
+	for _, c := range []struct {
+		name        string
+		cfg         ApplyConfig
+		opts        []Option[transport]
+		want        []*ConflictError
+		wantOther   bool
+		wantApplied transport
+	}{
+		{
+			name:        "one per group",
+			opts:        []Option[transport]{tls, gob},
+			wantApplied: transport{"tls", "gob"},
+		},
+		{
+			name:        "three way",
+			opts:        []Option[transport]{insecure, mtls, tls},
+			want:        []*ConflictError{{"security", []string{"insecure", "mtls", "tls"}}},
+			wantApplied: transport{mode: "insecure"},
+		},
+		{
+			name:        "repeat listed once",
+			opts:        []Option[transport]{tls, insecure, tls, mtls},
+			want:        []*ConflictError{{"security", []string{"tls", "insecure", "mtls"}}},
+			wantApplied: transport{mode: "tls"},
+		},
+		{
+			name: "two groups in order of first use",
+			opts: []Option[transport]{json, tls, gob, insecure},
+			want: []*ConflictError{
+				{"encoding", []string{"json", "gob"}},
+				{"security", []string{"tls", "insecure"}},
+			},
+			wantApplied: transport{"tls", "json"},
+		},
+		{
+			name:        "option error stops first",
+			opts:        []Option[transport]{tls, failing, insecure},
+			wantOther:   true,
+			wantApplied: transport{mode: "tls"},
+		},
+		{
+			name:        "collected option errors before conflicts",
+			cfg:         ApplyConfig{CollectErrors: true},
+			opts:        []Option[transport]{tls, insecure, failing, mtls},
+			want:        []*ConflictError{{"security", []string{"tls", "insecure", "mtls"}}},
+			wantOther:   true,
+			wantApplied: transport{mode: "tls"},
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			var got transport
+			err := ApplyWith(c.cfg, &got, c.opts...)
+
+			var errs []error
+			switch {
+			case err == nil:
+			case c.cfg.CollectErrors || len(c.want) > 1:
+				errs = err.(interface{ Unwrap() []error }).Unwrap()
+			default:
+				errs = []error{err}
+			}
+			var conflicts []*ConflictError
+			other := false
+			for i, err := range errs {
+				if ce, ok := err.(*ConflictError); ok {
+					conflicts = append(conflicts, ce)
+				} else if len(conflicts) > 0 {
+					t.Errorf("error %d (%v) follows a conflict", i, err)
+				} else {
+					other = true
+				}
+			}
+			if !reflect.DeepEqual(conflicts, c.want) {
+				t.Errorf("conflicts = %v, want %v", conflicts, c.want)
+			}
+			if other != c.wantOther {
+				t.Errorf("err = %v, want other errors: %t", err, c.wantOther)
+			}
+			if got != c.wantApplied {
+				t.Errorf("applied %+v, want %+v", got, c.wantApplied)
+			}
+		})
+	}
+}
+
+func TestConflictErrorMessage(t *testing.T) {
+	err := &ConflictError{Group: "security", Options: []string{"tls", "insecure", "mtls"}}
+	want := "options tls, insecure, mtls are mutually exclusive (group security)"
+	if got := err.Error(); got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+}
+
+func TestDeprecateWarnsOncePerApply(t *testing.T) {
+	oldPort := Deprecate(withPort(1), "use WithAddr instead")
+	oldHost := Deprecate(withHost("old"), "hosts are resolved by the dialer")
+	described := Describe(Info{Name: "legacy", Deprecated: "no longer needed"}, func(*config) error { return nil })
+	opts := []Option[config]{oldPort, oldHost, described, oldPort, described, Combine(oldHost, oldPort)}
+
+	var ws []Warning
+	var got config
+	if err := ApplyWith(ApplyConfig{OnWarning: Collect(&ws)}, &got, opts...); err != nil {
+		t.Fatal(err)
+	}
+	want := []Warning{
+		{Option: "port", Message: "use WithAddr instead"},
+		{Message: "hosts are resolved by the dialer"},
+		{Option: "legacy", Message: "no longer needed"},
+	}
+	if !reflect.DeepEqual(ws, want) {
+		t.Errorf("warnings = %v, want %v", ws, want)
+	}
+	// the deprecated options are still applied
+	if got.port != 1 || len(got.hosts) != 2 {
+		t.Errorf("got %+v, want every option applied", got)
+	}
+
+	// each construction warns again
+	ws = nil
+	if err := ApplyWith(ApplyConfig{OnWarning: Collect(&ws)}, &config{}, opts...); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(ws, want) {
+		t.Errorf("second construction warnings = %v, want %v", ws, want)
+	}
+}
+
+func TestDeprecateSkippedDuplicate(t *testing.T) {
+	// under FirstWins the repeat is skipped but was still given, so it
+	// still warns; a warning for the same option is reported once
+	var ws []Warning
+	err := ApplyWith(ApplyConfig{Duplicates: FirstWins, OnWarning: Collect(&ws)}, &config{},
+		withPort(1), Deprecate(withPort(2), "use WithAddr instead"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Warning{{Option: "port", Message: "use WithAddr instead"}}
+	if !reflect.DeepEqual(ws, want) {
+		t.Errorf("warnings = %v, want %v", ws, want)
+	}
+}
+
+func TestWarningString(t *testing.T) {
+	for _, c := range []struct {
+		w    Warning
+		want string
+	}{
+		{Warning{Option: "port", Message: "use WithAddr instead"}, "option port is deprecated: use WithAddr instead"},
+		{Warning{Message: "use WithAddr instead"}, "deprecated option: use WithAddr instead"},
+	} {
+		if got := c.w.String(); got != c.want {
+			t.Errorf("String() = %q, want %q", got, c.want)
+		}
+	}
+}
+
+type password string
+
+func (password) IsSecret() bool { return true }
+
+type credentials struct {
+	user, pass string
+	key        []byte
+}
+
+func withUser(u string) Option[credentials] {
+	return Describe(Info{Name: "user", Value: u}, func(c *credentials) error {
+		c.user = u
+		return nil
+	})
+}
+
+func withPassword(p password) Option[credentials] {
+	// no Redact: IsSecret is enough
+	return Describe(Info{Name: "password", Value: p}, func(c *credentials) error {
+		c.pass = string(p)
+		return nil
+	})
+}
+
+func withKey(k []byte) Option[credentials] {
+	return Describe(Info{Name: "key", Value: k, Redact: RedactAll}, func(c *credentials) error {
+		c.key = k
+		return nil
+	})
+}
+
+func TestRecord(t *testing.T) {
+	var as []Applied
+	cfg := ApplyConfig{OnApply: Record(&as)}
+	failing := Describe(Info{Name: "failing", Value: 1}, func(*credentials) error { return errors.New("bad") })
+	anonymous := func(*credentials) error { return nil }
+
+	var got credentials
+	err := ApplyWith(cfg, &got,
+		withUser("ann"), withPassword("hunter2"), anonymous, withKey([]byte("k1")), withUser("bob"), failing)
+	if err == nil {
+		t.Fatal("want the failing option's error")
+	}
+	want := []Applied{
+		{"user", "ann"},
+		{"password", Redacted},
+		{"key", Redacted},
+		{"user", "bob"},
+	}
+	if !reflect.DeepEqual(as, want) {
+		t.Errorf("record = %v, want %v (application order, secrets redacted, failures and anonymous options left out)", as, want)
+	}
+	// redaction only concerns the record
+	if got.pass != "hunter2" || string(got.key) != "k1" {
+		t.Errorf("credentials = %+v, want the real values applied", got)
+	}
+}
+
+func TestRecordConfigRedact(t *testing.T) {
+	var as []Applied
+	cfg := ApplyConfig{
+		OnApply: Record(&as),
+		Redact: func(a Applied) Applied {
+			if a.Name == "user" {
+				a.Value = "u***"
+			}
+			return a
+		},
+	}
+	if err := ApplyWith(cfg, &credentials{}, withUser("ann"), withKey([]byte("k1"))); err != nil {
+		t.Fatal(err)
+	}
+	want := []Applied{{"user", "u***"}, {"key", Redacted}}
+	if !reflect.DeepEqual(as, want) {
+		t.Errorf("record = %v, want %v", as, want)
+	}
+}
+
+// TestRecordRedactCannotUnmask checks that a config Redact returning the
+// secret value is overridden by IsSecret, which runs last.
+func TestRecordRedactCannotUnmask(t *testing.T) {
+	var as []Applied
+	cfg := ApplyConfig{
+		OnApply: Record(&as),
+		Redact:  func(a Applied) Applied { return a },
+	}
+	if err := ApplyWith(cfg, &credentials{}, withPassword("hunter2")); err != nil {
+		t.Fatal(err)
+	}
+	if want := []Applied{{"password", Redacted}}; !reflect.DeepEqual(as, want) {
+		t.Errorf("record = %v, want %v", as, want)
+	}
+}
+
+func TestEffective(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		in   []Applied
+		want []Applied
+	}{
+		{"empty", nil, nil},
+		{"distinct", []Applied{{"a", 1}, {"b", 2}}, []Applied{{"a", 1}, {"b", 2}}},
+		{
+			"last value at its position",
+			[]Applied{{"a", 1}, {"b", 2}, {"a", 3}, {"c", 4}, {"b", 5}},
+			[]Applied{{"a", 3}, {"c", 4}, {"b", 5}},
+		},
+	} {
+		if got := Effective(c.in); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s: Effective = %v, want %v", c.name, got, c.want)
+		}
+	}
+}
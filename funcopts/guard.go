@@ -0,0 +1,91 @@
+package funcopts
+
+import (
+	"errors"
+	"sync"
+)
+
+var (
+	// ErrReused is returned when the same options slice is applied to the
+	// same guarded target twice.
+	ErrReused = errors.New("options already applied to this target")
+	// ErrConcurrentApply is returned when ApplyWith is called for a
+	// target that another ApplyWith is still configuring.
+	ErrConcurrentApply = errors.New("options are being applied to this target concurrently")
+)
+
+// Guard makes a target reject a second application of the same options
+// slice. Embed it in the options struct; its zero value is ready:
+//
+//	type options struct {
+//		funcopts.Guard
+//		port int
+//	}
+//
+// Re-applying a slice is usually a bug: options that append (middleware,
+// hooks) apply twice, and options capturing a pointer (a listener, a
+// buffer) end up sharing it. Guard detects the case where the very same
+// slice, not just equal options, is applied again.
+//
+// Call Reset to deliberately reconfigure a target with a slice it has
+// already seen, e.g. after restoring the target to its defaults.
+type Guard struct {
+	mu       sync.Mutex
+	applying bool
+	applied  []sliceID
+}
+
+// sliceID identifies an options slice by its first element and length.
+type sliceID struct {
+	first any
+	len   int
+}
+
+type guarded interface {
+	guard() *Guard
+}
+
+func (g *Guard) guard() *Guard { return g }
+
+// Reset forgets every slice applied so far.
+func (g *Guard) Reset() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.applied = nil
+}
+
+// begin records opts and marks the target busy until the returned func
+// is called.
+func (g *Guard) begin(id sliceID) (func(), error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.applying {
+		return nil, ErrConcurrentApply
+	}
+	if id.len > 0 {
+		for _, a := range g.applied {
+			if a == id {
+				return nil, ErrReused
+			}
+		}
+		g.applied = append(g.applied, id)
+	}
+	g.applying = true
+	return func() {
+		g.mu.Lock()
+		g.applying = false
+		g.mu.Unlock()
+	}, nil
+}
+
+func guardTarget[T any](target *T, opts []Option[T]) (func(), error) {
+	g, ok := any(target).(guarded)
+	if !ok {
+		return func() {}, nil
+	}
+	id := sliceID{len: len(opts)}
+	if len(opts) > 0 {
+		id.first = &opts[0]
+	}
+	return g.guard().begin(id)
+}
@@ -0,0 +1,143 @@
+package funcopts
+
+import (
+	"errors"
+	"sync"
+	"testing"
+)
+
+type guardedTarget struct {
+	Guard
+	n int
+}
+
+func inc(t *guardedTarget) error {
+	t.n++
+	return nil
+}
+
+func TestGuardRejectsReuse(t *testing.T) {
+	var target guardedTarget
+	opts := []Option[guardedTarget]{inc, inc}
+	if err := Apply(&target, opts...); err != nil {
+		t.Fatal(err)
+	}
+	if err := Apply(&target, opts...); !errors.Is(err, ErrReused) {
+		t.Fatalf("second Apply of the same slice = %v, want ErrReused", err)
+	}
+	if target.n != 2 {
+		t.Errorf("n = %d, want the rejected slice not applied", target.n)
+	}
+
+	// equal options in another slice, or part of the slice, are not reuse
+	if err := Apply(&target, inc, inc); err != nil {
+		t.Errorf("Apply of an equal slice = %v", err)
+	}
+	if err := Apply(&target, opts[1:]...); err != nil {
+		t.Errorf("Apply of a subslice = %v", err)
+	}
+	// no options is never reuse
+	for range 2 {
+		if err := Apply(&target); err != nil {
+			t.Errorf("Apply() = %v", err)
+		}
+	}
+}
+
+func TestGuardReset(t *testing.T) {
+	var target guardedTarget
+	opts := []Option[guardedTarget]{inc}
+	if err := Apply(&target, opts...); err != nil {
+		t.Fatal(err)
+	}
+	target.Reset()
+	if err := Apply(&target, opts...); err != nil {
+		t.Fatalf("Apply after Reset = %v", err)
+	}
+	if err := Apply(&target, opts...); !errors.Is(err, ErrReused) {
+		t.Fatalf("Apply after Reset and reuse = %v, want ErrReused", err)
+	}
+	if target.n != 2 {
+		t.Errorf("n = %d, want 2", target.n)
+	}
+}
+
+// TestGuardUnguarded shows the default: without Guard, reuse is allowed.
+func TestGuardUnguarded(t *testing.T) {
+	var n int
+	opts := []Option[int]{func(n *int) error { *n++; return nil }}
+	for range 2 {
+		if err := Apply(&n, opts...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n != 2 {
+		t.Errorf("n = %d, want 2", n)
+	}
+}
+
+// TestConcurrentApply holds one application open and starts another on
+// the same target, guarded or not.
+func TestConcurrentApply(t *testing.T) {
+	t.Run("guarded", func(t *testing.T) {
+		var target guardedTarget
+		testConcurrentApply(t, &target, func(*guardedTarget) error { return nil })
+	})
+	t.Run("unguarded", func(t *testing.T) {
+		var target int
+		testConcurrentApply(t, &target, func(*int) error { return nil })
+	})
+}
+
+func testConcurrentApply[T any](t *testing.T, target *T, noop Option[T]) {
+	entered, release := make(chan struct{}), make(chan struct{})
+	block := func(*T) error {
+		close(entered)
+		<-release
+		return nil
+	}
+	first := make(chan error, 1)
+	go func() { first <- Apply(target, block) }()
+	<-entered
+	if err := Apply(target, noop); !errors.Is(err, ErrConcurrentApply) {
+		t.Errorf("concurrent Apply = %v, want ErrConcurrentApply", err)
+	}
+	close(release)
+	if err := <-first; err != nil {
+		t.Errorf("first Apply = %v", err)
+	}
+	if err := Apply(target, noop); err != nil {
+		t.Errorf("Apply once the first finished = %v", err)
+	}
+}
+
+// TestGuardRace applies fresh slices to one target from many goroutines;
+// run with -race. Every call either applies its options or is refused
+// whole, so n counts the successes exactly.
+func TestGuardRace(t *testing.T) {
+	var target guardedTarget
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	applied := 0
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 20 {
+				err := Apply(&target, inc)
+				switch {
+				case err == nil:
+					mu.Lock()
+					applied++
+					mu.Unlock()
+				case !errors.Is(err, ErrConcurrentApply):
+					t.Error(err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if target.n != applied {
+		t.Errorf("n = %d, want %d successful applications", target.n, applied)
+	}
+}
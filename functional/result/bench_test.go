@@ -0,0 +1,59 @@
+package result
+
+import "testing"
+
+var (
+	sinkS  string
+	sinkI  int
+	sinkOK bool
+)
+
+var (
+	good = map[string]string{"HOST": "example.com", "PORT": " 8080 "}
+	bad  = map[string]string{"PORT": "http"}
+	keys = []string{"HOST", "PORT", "USER", "HOME"}
+)
+
+// BenchmarkAddr reads an address from the environment with Result and the Go
+// way, on success and on failure.
+func BenchmarkAddr(b *testing.B) {
+	b.Run("ok/result", func(b *testing.B) {
+		for range b.N {
+			sinkS, _ = AddrResult(good).Get()
+		}
+	})
+	b.Run("ok/idiomatic", func(b *testing.B) {
+		for range b.N {
+			sinkS, _ = Addr(good)
+		}
+	})
+	b.Run("err/result", func(b *testing.B) {
+		for range b.N {
+			sinkOK = AddrResult(bad).IsOk()
+		}
+	})
+	b.Run("err/idiomatic", func(b *testing.B) {
+		for range b.N {
+			_, err := Addr(bad)
+			sinkOK = err == nil
+		}
+	})
+}
+
+// BenchmarkLookup looks keys up with Optional and with comma-ok.
+func BenchmarkLookup(b *testing.B) {
+	b.Run("optional", func(b *testing.B) {
+		for i := range b.N {
+			sinkS = Lookup(good, keys[i%len(keys)]).Or("-")
+		}
+	})
+	b.Run("comma-ok", func(b *testing.B) {
+		for i := range b.N {
+			v, ok := good[keys[i%len(keys)]]
+			if !ok {
+				v = "-"
+			}
+			sinkS = v
+		}
+	})
+}
@@ -0,0 +1,65 @@
+package result
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// ErrUnset is the error of an environment variable that is not set.
+var ErrUnset = errors.New("not set")
+
+// chained Result
+// Level: Poor
+// pros: no if err != nil; a failed step skips the rest.
+// cons: reads inside out; the errors say nothing of which variable, as
+// saying so is the per-step code the chain was to remove; every Go call
+// is wrapped by Try on the way in.
+//
+// AddrResult is Addr written with Result and Optional.
+func AddrResult(env map[string]string) Result[string] {
+	host := Lookup(env, "HOST").Or("localhost")
+	port := AndThen(
+		AndThen(
+			Map(Lookup(env, "PORT").Result(ErrUnset), strings.TrimSpace),
+			Try(strconv.Atoi)),
+		checkPort)
+	return Map(port, func(p int) string { return net.JoinHostPort(host, strconv.Itoa(p)) })
+}
+
+func checkPort(p int) Result[int] {
+	if p < 1 || p > 65535 {
+		return Err[int](fmt.Errorf("%d out of range", p))
+	}
+	return Ok(p)
+}
+
+// (T, error)
+// Level: Good
+// pros: reads top to bottom; each failure is wrapped with what was
+// being done where it happened; what every Go API speaks.
+// cons: three lines per fallible step.
+//
+// Addr reads HOST and PORT from env and returns the address to listen
+// on. HOST defaults to localhost; PORT is required, a number from 1 to
+// 65535.
+func Addr(env map[string]string) (string, error) {
+	host, ok := env["HOST"]
+	if !ok {
+		host = "localhost"
+	}
+	s, ok := env["PORT"]
+	if !ok {
+		return "", fmt.Errorf("PORT: %w", ErrUnset)
+	}
+	p, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil {
+		return "", fmt.Errorf("PORT: %w", err)
+	}
+	if p < 1 || p > 65535 {
+		return "", fmt.Errorf("PORT: %d out of range", p)
+	}
+	return net.JoinHostPort(host, strconv.Itoa(p)), nil
+}
@@ -0,0 +1,86 @@
+package result
+
+import "fmt"
+
+// Optional is a value of T that may be absent. The zero Optional is
+// None.
+type Optional[T any] struct {
+	v  T
+	ok bool
+}
+
+// Some returns an Optional holding v.
+func Some[T any](v T) Optional[T] { return Optional[T]{v: v, ok: true} }
+
+// None returns an absent Optional.
+func None[T any]() Optional[T] { return Optional[T]{} }
+
+// OfOK returns the Optional of a comma-ok lookup: Some(v) if ok.
+func OfOK[T any](v T, ok bool) Optional[T] {
+	if !ok {
+		return Optional[T]{}
+	}
+	return Optional[T]{v: v, ok: true}
+}
+
+// Lookup returns the Optional of m[k].
+func Lookup[K comparable, V any](m map[K]V, k K) Optional[V] {
+	v, ok := m[k]
+	return OfOK(v, ok)
+}
+
+// Get returns the value the comma-ok way.
+func (o Optional[T]) Get() (T, bool) { return o.v, o.ok }
+
+// IsSome reports whether o holds a value.
+func (o Optional[T]) IsSome() bool { return o.ok }
+
+// Or returns o's value, or def if it has none.
+func (o Optional[T]) Or(def T) T {
+	if !o.ok {
+		return def
+	}
+	return o.v
+}
+
+// OrElse returns o if it holds a value, or what f returns.
+func (o Optional[T]) OrElse(f func() Optional[T]) Optional[T] {
+	if !o.ok {
+		return f()
+	}
+	return o
+}
+
+// Result returns o as a Result, failing with err if o is None.
+func (o Optional[T]) Result(err error) Result[T] {
+	if !o.ok {
+		return Err[T](err)
+	}
+	return Ok(o.v)
+}
+
+func (o Optional[T]) String() string {
+	if !o.ok {
+		return "None"
+	}
+	return fmt.Sprintf("Some(%v)", o.v)
+}
+
+// MapOptional applies f to o's value, if it has one. It cannot be called
+// Map, which Result has taken, nor be a method, which could not change
+// the type.
+func MapOptional[T, U any](o Optional[T], f func(T) U) Optional[U] {
+	if !o.ok {
+		return Optional[U]{}
+	}
+	return Optional[U]{v: f(o.v), ok: true}
+}
+
+// AndThenOptional applies f, a step that may find nothing, to o's
+// value, if it has one.
+func AndThenOptional[T, U any](o Optional[T], f func(T) Optional[U]) Optional[U] {
+	if !o.ok {
+		return Optional[U]{}
+	}
+	return f(o.v)
+}
@@ -0,0 +1,132 @@
+// Package result implements Result[T] and Optional[T], the types other
+// languages return instead of Go's (T, error) and (T, bool), with the
+// Map, AndThen and OrElse that chain them, and writes one small task,
+// reading a listen address from the environment, both ways
+// (idiomatic.go), so the two can be judged side by side.
+//
+// What a chain buys is the absence of "if err != nil": a failed step
+// skips the rest. What it costs, in Go:
+//
+//   - methods cannot have type parameters, so Map and AndThen, which
+//     change T, are functions taking the Result first, read inside out,
+//     and the Optional ones need names of their own
+//   - every step is a closure, so an error gains no context on the way
+//     unless each step wraps it, which is the if err != nil it replaced
+//   - every Go API returns (T, error), so each boundary converts: Of in,
+//     Get out
+//   - debuggers and stack traces show the combinators, not the steps
+//
+// Where Result is worth having is as a value rather than as control
+// flow: outcomes kept in a slice, sent on a channel, or collected from
+// goroutines, where (T, error) cannot go (see workerpool.Result). An
+// Optional is worth having as a struct field or map value whose absence
+// must differ from its zero value (see types/field for the three-state
+// version).
+//
+// findings (see bench_test.go; go test -bench . -benchmem
+// patterns/functional/result):
+//
+//   - on success the two cost the same, ~180-210ns, the two allocations
+//     being strconv's and JoinHostPort's: the combinators inline, and
+//     all a Result adds is copying it out of each step.
+//   - on failure the chain is ~5x cheaper, ~170 against ~800ns, and it
+//     is cheaper because it says less: the idiomatic version pays for
+//     the fmt.Errorf that puts "PORT:" in front of its error. Wrap the
+//     chain's errors as well and the difference goes.
+//   - Optional and comma-ok measure the same, ~20ns a lookup.
+package result
+
+import "errors"
+
+// Result is the outcome of a computation: a value of T, or an error.
+// The zero Result is Ok(zero T).
+type Result[T any] struct {
+	v   T
+	err error
+}
+
+// Ok returns a successful Result holding v.
+func Ok[T any](v T) Result[T] { return Result[T]{v: v} }
+
+// Err returns a failed Result; err must not be nil.
+func Err[T any](err error) Result[T] {
+	if err == nil {
+		panic("result: Err(nil)")
+	}
+	return Result[T]{err: err}
+}
+
+// Of returns the Result of a Go call: Ok(v) if err is nil, Err(err)
+// otherwise.
+func Of[T any](v T, err error) Result[T] {
+	if err != nil {
+		return Result[T]{err: err}
+	}
+	return Result[T]{v: v}
+}
+
+// Get returns the value and error the Go way.
+func (r Result[T]) Get() (T, error) { return r.v, r.err }
+
+// IsOk reports whether r succeeded.
+func (r Result[T]) IsOk() bool { return r.err == nil }
+
+// Err returns r's error, nil if it succeeded.
+func (r Result[T]) Err() error { return r.err }
+
+// Or returns r's value, or def if it failed.
+func (r Result[T]) Or(def T) T {
+	if r.err != nil {
+		return def
+	}
+	return r.v
+}
+
+// OrElse returns r if it succeeded, or what f makes of its error: a
+// fallback value, or a different error.
+func (r Result[T]) OrElse(f func(error) Result[T]) Result[T] {
+	if r.err != nil {
+		return f(r.err)
+	}
+	return r
+}
+
+// Map applies f to r's value; a failed r is passed on unchanged.
+func Map[T, U any](r Result[T], f func(T) U) Result[U] {
+	if r.err != nil {
+		return Result[U]{err: r.err}
+	}
+	return Result[U]{v: f(r.v)}
+}
+
+// AndThen applies f, a step that may fail, to r's value; a failed r is
+// passed on unchanged.
+func AndThen[T, U any](r Result[T], f func(T) Result[U]) Result[U] {
+	if r.err != nil {
+		return Result[U]{err: r.err}
+	}
+	return f(r.v)
+}
+
+// Try turns a Go function into a step for AndThen.
+func Try[T, U any](f func(T) (U, error)) func(T) Result[U] {
+	return func(v T) Result[U] { return Of(f(v)) }
+}
+
+// Collect returns the values of rs, or the errors of those that failed,
+// joined.
+func Collect[T any](rs []Result[T]) ([]T, error) {
+	vs := make([]T, 0, len(rs))
+	var errs []error
+	for _, r := range rs {
+		if r.err != nil {
+			errs = append(errs, r.err)
+			continue
+		}
+		vs = append(vs, r.v)
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return vs, nil
+}
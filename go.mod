@@ -1,3 +1,10 @@
 module patterns
 
 go 1.23.5
+
+require golang.org/x/tools v0.36.0
+
+require (
+	golang.org/x/mod v0.27.0 // indirect
+	golang.org/x/sync v0.16.0 // indirect
+)
@@ -0,0 +1,414 @@
+// Package apidiff compares two versions of a package's exported API using
+// go/types and reports the changes that would break callers, in the
+// spirit of golang.org/x/exp/apidiff (much simplified: no generics
+// constraints, no comparability or assignability subtleties).
+package apidiff
+
+import (
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Change is one difference between the old and new API.
+type Change struct {
+	Name       string
+	Compatible bool
+	Message    string
+}
+
+func (c Change) String() string {
+	kind := "breaking"
+	if c.Compatible {
+		kind = "compatible"
+	}
+	return fmt.Sprintf("%s: %s: %s", kind, c.Name, c.Message)
+}
+
+// Breaking filters the incompatible changes.
+func Breaking(changes []Change) []Change {
+	var out []Change
+	for _, c := range changes {
+		if !c.Compatible {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// one importer for every Load, so that both versions see the same
+// context.Context and their signatures can be compared
+var (
+	fset = token.NewFileSet()
+	imp  = importer.ForCompiler(fset, "source", nil)
+)
+
+// Load type-checks the non-test Go files in dir.
+func Load(dir string) (*types.Package, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+	var files []*ast.File
+	for _, p := range paths {
+		if strings.HasSuffix(p, "_test.go") {
+			continue
+		}
+		f, err := parser.ParseFile(fset, p, nil, 0)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no Go files in %s", dir)
+	}
+	conf := types.Config{Importer: imp}
+	return conf.Check(dir, fset, files, nil)
+}
+
+// Diff reports how the exported API changed from old to new.
+func Diff(old, new *types.Package) []Change {
+	d := &differ{old: old, new: new}
+	for _, name := range old.Scope().Names() {
+		o := old.Scope().Lookup(name)
+		if !o.Exported() {
+			continue
+		}
+		n := new.Scope().Lookup(name)
+		if n == nil {
+			d.breaking(name, "removed")
+			continue
+		}
+		d.object(name, o, n)
+	}
+	for _, name := range new.Scope().Names() {
+		if n := new.Scope().Lookup(name); n.Exported() && old.Scope().Lookup(name) == nil {
+			d.compatible(name, "added")
+		}
+	}
+	slices.SortStableFunc(d.changes, func(a, b Change) int { return strings.Compare(a.Name, b.Name) })
+	return d.changes
+}
+
+// DiffDirs loads both directories and diffs them.
+func DiffDirs(oldDir, newDir string) ([]Change, error) {
+	old, err := Load(oldDir)
+	if err != nil {
+		return nil, err
+	}
+	new, err := Load(newDir)
+	if err != nil {
+		return nil, err
+	}
+	return Diff(old, new), nil
+}
+
+type differ struct {
+	old, new *types.Package
+	changes  []Change
+}
+
+// typeString prints types with bare package names.
+func typeString(t types.Type) string {
+	return types.TypeString(t, func(p *types.Package) string { return p.Name() })
+}
+
+func (d *differ) breaking(name, format string, args ...any) {
+	d.changes = append(d.changes, Change{Name: name, Message: fmt.Sprintf(format, args...)})
+}
+
+func (d *differ) compatible(name, format string, args ...any) {
+	d.changes = append(d.changes, Change{Name: name, Compatible: true, Message: fmt.Sprintf(format, args...)})
+}
+
+func (d *differ) object(name string, o, n types.Object) {
+	if fmt.Sprintf("%T", o) != fmt.Sprintf("%T", n) {
+		d.breaking(name, "changed from %s to %s", kind(o), kind(n))
+		return
+	}
+	//exhaustive:ignore
+	switch o := o.(type) {
+	case *types.TypeName:
+		d.typeName(name, o, n.(*types.TypeName))
+	default:
+		if !d.same(o.Type(), n.Type()) {
+			d.breaking(name, "type changed from %s to %s", typeString(o.Type()), typeString(n.Type()))
+		}
+	}
+}
+
+func (d *differ) typeName(name string, o, n *types.TypeName) {
+	ou, nu := o.Type().Underlying(), n.Type().Underlying()
+	switch ou := ou.(type) {
+	case *types.Struct:
+		nu, ok := nu.(*types.Struct)
+		if !ok {
+			d.breaking(name, "no longer a struct")
+			return
+		}
+		d.structFields(name, ou, nu)
+	case *types.Interface:
+		nu, ok := nu.(*types.Interface)
+		if !ok {
+			d.breaking(name, "no longer an interface")
+			return
+		}
+		d.interfaceMethods(name, ou, nu)
+	default:
+		if !d.same(ou, nu) {
+			d.breaking(name, "underlying type changed from %s to %s", typeString(ou), typeString(nu))
+		}
+	}
+	if _, isIface := ou.(*types.Interface); !isIface {
+		d.methods(name, o.Type(), n.Type())
+	}
+}
+
+func (d *differ) structFields(name string, o, n *types.Struct) {
+	fields := map[string]*types.Var{}
+	for i := range n.NumFields() {
+		f := n.Field(i)
+		fields[f.Name()] = f
+	}
+	for i := range o.NumFields() {
+		f := o.Field(i)
+		if !f.Exported() {
+			continue
+		}
+		nf, ok := fields[f.Name()]
+		switch {
+		case !ok:
+			d.breaking(name+"."+f.Name(), "field removed")
+		case !d.same(f.Type(), nf.Type()):
+			d.breaking(name+"."+f.Name(), "field type changed from %s to %s", typeString(f.Type()), typeString(nf.Type()))
+		}
+		delete(fields, f.Name())
+	}
+	for fname, f := range fields {
+		if f.Exported() {
+			d.compatible(name+"."+fname, "field added")
+		}
+	}
+}
+
+// interfaceMethods: removing a method breaks callers, adding one breaks
+// implementations, unless an unexported method already made the interface
+// impossible to implement outside the package.
+func (d *differ) interfaceMethods(name string, o, n *types.Interface) {
+	sealed := false
+	for i := range o.NumMethods() {
+		m := o.Method(i)
+		if !m.Exported() {
+			sealed = true
+		}
+	}
+	for i := range o.NumMethods() {
+		m := o.Method(i)
+		if !m.Exported() {
+			continue
+		}
+		nm := lookup(n, m.Name())
+		switch {
+		case nm == nil:
+			d.breaking(name+"."+m.Name(), "method removed")
+		case !d.same(m.Type(), nm.Type()):
+			d.breaking(name+"."+m.Name(), "method signature changed from %s to %s", typeString(m.Type()), typeString(nm.Type()))
+		}
+	}
+	for i := range n.NumMethods() {
+		m := n.Method(i)
+		if m.Exported() && lookup(o, m.Name()) == nil {
+			if sealed {
+				d.compatible(name+"."+m.Name(), "method added to sealed interface")
+			} else {
+				d.breaking(name+"."+m.Name(), "method added to interface; existing implementations no longer satisfy it")
+			}
+		}
+	}
+}
+
+func lookup(iface *types.Interface, name string) *types.Func {
+	for i := range iface.NumMethods() {
+		m := iface.Method(i)
+		if m.Name() == name {
+			return m
+		}
+	}
+	return nil
+}
+
+// methods compares the method sets of *T, which include those of T.
+func (d *differ) methods(name string, o, n types.Type) {
+	oms := types.NewMethodSet(types.NewPointer(o))
+	nms := types.NewMethodSet(types.NewPointer(n))
+	for i := range oms.Len() {
+		sel := oms.At(i)
+		m := sel.Obj()
+		if !m.Exported() {
+			continue
+		}
+		nsel := nms.Lookup(d.new, m.Name())
+		switch {
+		case nsel == nil:
+			d.breaking(name+"."+m.Name(), "method removed")
+		case !d.same(m.Type(), nsel.Obj().Type()):
+			d.breaking(name+"."+m.Name(), "method signature changed from %s to %s", typeString(m.Type()), typeString(nsel.Obj().Type()))
+		}
+	}
+	for i := range nms.Len() {
+		sel := nms.At(i)
+		m := sel.Obj()
+		if m.Exported() && oms.Lookup(d.old, m.Name()) == nil {
+			d.compatible(name+"."+m.Name(), "method added")
+		}
+	}
+}
+
+// same is types.Identical, except that a named type of the old package
+// corresponds to the same-named type of the new one.
+func (d *differ) same(o, n types.Type) bool {
+	switch o := o.(type) {
+	case *types.Named:
+		n, ok := n.(*types.Named)
+		if !ok {
+			return false
+		}
+		if o.Obj().Pkg() == d.old && n.Obj().Pkg() == d.new {
+			return o.Obj().Name() == n.Obj().Name()
+		}
+		return types.Identical(o, n)
+	case *types.Pointer:
+		n, ok := n.(*types.Pointer)
+		return ok && d.same(o.Elem(), n.Elem())
+	case *types.Slice:
+		n, ok := n.(*types.Slice)
+		return ok && d.same(o.Elem(), n.Elem())
+	case *types.Array:
+		n, ok := n.(*types.Array)
+		return ok && o.Len() == n.Len() && d.same(o.Elem(), n.Elem())
+	case *types.Map:
+		n, ok := n.(*types.Map)
+		return ok && d.same(o.Key(), n.Key()) && d.same(o.Elem(), n.Elem())
+	case *types.Chan:
+		n, ok := n.(*types.Chan)
+		return ok && o.Dir() == n.Dir() && d.same(o.Elem(), n.Elem())
+	case *types.Signature:
+		n, ok := n.(*types.Signature)
+		return ok && o.Variadic() == n.Variadic() &&
+			d.sameTuple(o.Params(), n.Params()) && d.sameTuple(o.Results(), n.Results())
+	case *types.Struct:
+		n, ok := n.(*types.Struct)
+		if !ok || o.NumFields() != n.NumFields() {
+			return false
+		}
+		for i := range o.NumFields() {
+			of, nf := o.Field(i), n.Field(i)
+			if of.Name() != nf.Name() || of.Embedded() != nf.Embedded() || !d.same(of.Type(), nf.Type()) {
+				return false
+			}
+		}
+		return true
+	case *types.Interface:
+		n, ok := n.(*types.Interface)
+		if !ok || o.NumMethods() != n.NumMethods() {
+			return false
+		}
+		for i := range o.NumMethods() {
+			om, nm := o.Method(i), n.Method(i)
+			if om.Name() != nm.Name() || !d.same(om.Type(), nm.Type()) {
+				return false
+			}
+		}
+		return true
+	}
+	return types.Identical(o, n)
+}
+
+func (d *differ) sameTuple(o, n *types.Tuple) bool {
+	if o.Len() != n.Len() {
+		return false
+	}
+	for i := range o.Len() {
+		if !d.same(o.At(i).Type(), n.At(i).Type()) {
+			return false
+		}
+	}
+	return true
+}
+
+func kind(o types.Object) string {
+	//exhaustive:ignore
+	switch o.(type) {
+	case *types.Const:
+		return "const"
+	case *types.Var:
+		return "var"
+	case *types.Func:
+		return "func"
+	case *types.TypeName:
+		return "type"
+	}
+	return "object"
+}
+
+// Case is one testdata example: old/ and new/ versions of a package and
+// the verdict a correct diff must reach.
+type Case struct {
+	Name string
+	Dir  string
+	// WantCompatible is read from the case's want file.
+	WantCompatible bool
+}
+
+// Cases lists the examples under root (one directory per case).
+func Cases(root string) ([]Case, error) {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil, err
+	}
+	var cases []Case
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		dir := filepath.Join(root, e.Name())
+		want, err := os.ReadFile(filepath.Join(dir, "want"))
+		if err != nil {
+			return nil, err
+		}
+		switch strings.TrimSpace(string(want)) {
+		case "compatible":
+			cases = append(cases, Case{Name: e.Name(), Dir: dir, WantCompatible: true})
+		case "breaking":
+			cases = append(cases, Case{Name: e.Name(), Dir: dir})
+		default:
+			return nil, fmt.Errorf("%s: want must be compatible or breaking", e.Name())
+		}
+	}
+	return cases, nil
+}
+
+// Check diffs the case and reports an error if the verdict is not the
+// expected one.
+func (c Case) Check() ([]Change, error) {
+	changes, err := DiffDirs(filepath.Join(c.Dir, "old"), filepath.Join(c.Dir, "new"))
+	if err != nil {
+		return nil, err
+	}
+	breaking := Breaking(changes)
+	switch {
+	case c.WantCompatible && len(breaking) > 0:
+		return changes, fmt.Errorf("%s: want compatible, got %s", c.Name, breaking[0])
+	case !c.WantCompatible && len(breaking) == 0:
+		return changes, fmt.Errorf("%s: want a breaking change, found none", c.Name)
+	}
+	return changes, nil
+}
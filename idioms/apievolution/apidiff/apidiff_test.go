@@ -0,0 +1,30 @@
+package apidiff_test
+
+import (
+	"testing"
+
+	"patterns/idioms/apievolution/apidiff"
+)
+
+// TestExamples checks that each example under apievolution/testdata gets
+// the verdict its want file claims.
+func TestExamples(t *testing.T) {
+	cases, err := apidiff.Cases("../testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cases) == 0 {
+		t.Fatal("no cases")
+	}
+	for _, c := range cases {
+		t.Run(c.Name, func(t *testing.T) {
+			changes, err := c.Check()
+			for _, ch := range changes {
+				t.Log(ch)
+			}
+			if err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
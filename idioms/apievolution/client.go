@@ -0,0 +1,82 @@
+// Package apievolution shows how a library grows without breaking its
+// callers.
+//
+// Three techniques, each with a compatible and a breaking variant under
+// testdata/:
+//
+//   - functional options: ship New(addr, opts ...Option) from the first
+//     release. Adding a variadic parameter later is itself a breaking
+//     change (func values and interface implementations no longer match),
+//     but adding new With* functions never is.
+//   - optional interface upgrade: never add a method to a published
+//     interface; declare a second interface and type-assert for it, with
+//     a fallback for implementations that predate it.
+//   - vN packages: when a break is unavoidable, publish it under a new
+//     import path (./v2) so both majors can coexist in one build.
+//
+// The tests of the apidiff package check that each example under
+// testdata gets the verdict it claims, and cmd/apidiff compares any two
+// versions:
+//
+//	go test -v patterns/idioms/apievolution/apidiff
+//	go run patterns/cmd/apidiff idioms/apievolution/testdata/new-major/old idioms/apievolution/testdata/new-major/new
+package apievolution
+
+import (
+	"errors"
+	"time"
+
+	"patterns/construct"
+)
+
+// Client is the v1 client; v1.0 shipped New and WithRetries, v1.1
+// added WithTimeout without touching either.
+type Client struct {
+	addr    string
+	retries int
+	timeout time.Duration
+}
+
+type options struct {
+	retries int
+	timeout time.Duration
+}
+
+type Option func(options *options) error
+
+func (o *options) SetDefaults() { *o = options{retries: 1, timeout: 10 * time.Second} }
+
+func WithRetries(n int) Option {
+	return func(options *options) error {
+		if n < 0 {
+			return errors.New("retries cannot be negative")
+		}
+		options.retries = n
+		return nil
+	}
+}
+
+// WithTimeout was added in v1.1.
+func WithTimeout(d time.Duration) Option {
+	return func(options *options) error {
+		if d <= 0 {
+			return errors.New("timeout must be positive")
+		}
+		options.timeout = d
+		return nil
+	}
+}
+
+// New cannot report errors without breaking v1 callers, so invalid
+// options panic here; v2.New returns them instead.
+func New(addr string, opts ...Option) *Client {
+	options, err := construct.New(opts...)
+	if err != nil {
+		panic("apievolution: " + err.Error())
+	}
+	return &Client{addr: addr, retries: options.retries, timeout: options.timeout}
+}
+
+func (c *Client) Addr() string           { return c.addr }
+func (c *Client) Retries() int           { return c.retries }
+func (c *Client) Timeout() time.Duration { return c.timeout }
@@ -0,0 +1,31 @@
+package apievolution
+
+import "context"
+
+// Store is published; its method set is frozen.
+type Store interface {
+	Get(ctx context.Context, key string) (string, error)
+}
+
+// BatchGetter is the v1.1 upgrade: stores that can fetch many keys in one
+// round trip implement it in addition to Store.
+type BatchGetter interface {
+	GetMany(ctx context.Context, keys []string) (map[string]string, error)
+}
+
+// GetMany uses the batch method when s has it and falls back to one Get
+// per key for stores written against v1.0.
+func GetMany(ctx context.Context, s Store, keys []string) (map[string]string, error) {
+	if b, ok := s.(BatchGetter); ok {
+		return b.GetMany(ctx, keys)
+	}
+	out := make(map[string]string, len(keys))
+	for _, k := range keys {
+		v, err := s.Get(ctx, k)
+		if err != nil {
+			return nil, err
+		}
+		out[k] = v
+	}
+	return out, nil
+}
@@ -0,0 +1,5 @@
+package client
+
+type Client struct{ addr string }
+
+func (c *Client) Addr() string { return c.addr }
@@ -0,0 +1,7 @@
+package client
+
+type Client struct{ addr string }
+
+func (c *Client) Addr() string { return c.addr }
+
+func (c *Client) Close() error { return nil }
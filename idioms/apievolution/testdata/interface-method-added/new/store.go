@@ -0,0 +1,9 @@
+package store
+
+import "context"
+
+// Every implementation written against the old Store stops compiling.
+type Store interface {
+	Get(ctx context.Context, key string) (string, error)
+	GetMany(ctx context.Context, keys []string) (map[string]string, error)
+}
@@ -0,0 +1,7 @@
+package store
+
+import "context"
+
+type Store interface {
+	Get(ctx context.Context, key string) (string, error)
+}
@@ -0,0 +1,13 @@
+package client
+
+import "errors"
+
+type Client struct{ addr string }
+
+// The result list changed: only acceptable under a new major import path.
+func New(addr string) (*Client, error) {
+	if addr == "" {
+		return nil, errors.New("addr cannot be empty")
+	}
+	return &Client{addr: addr}, nil
+}
@@ -0,0 +1,5 @@
+package client
+
+type Client struct{ addr string }
+
+func New(addr string) *Client { return &Client{addr: addr} }
@@ -0,0 +1,26 @@
+package store
+
+import "context"
+
+type Store interface {
+	Get(ctx context.Context, key string) (string, error)
+}
+
+type BatchGetter interface {
+	GetMany(ctx context.Context, keys []string) (map[string]string, error)
+}
+
+func GetMany(ctx context.Context, s Store, keys []string) (map[string]string, error) {
+	if b, ok := s.(BatchGetter); ok {
+		return b.GetMany(ctx, keys)
+	}
+	out := make(map[string]string, len(keys))
+	for _, k := range keys {
+		v, err := s.Get(ctx, k)
+		if err != nil {
+			return nil, err
+		}
+		out[k] = v
+	}
+	return out, nil
+}
@@ -0,0 +1,15 @@
+package client
+
+type Client struct{ addr string }
+
+type Option func(*Client)
+
+// New gained a variadic parameter: calls still compile, but
+// var f func(string) *Client = New does not.
+func New(addr string, opts ...Option) *Client {
+	c := &Client{addr: addr}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
@@ -0,0 +1,24 @@
+package client
+
+import "time"
+
+type Client struct {
+	addr    string
+	retries int
+	timeout time.Duration
+}
+
+type Option func(*Client)
+
+func WithRetries(n int) Option { return func(c *Client) { c.retries = n } }
+
+// WithTimeout is new; nothing existing changed.
+func WithTimeout(d time.Duration) Option { return func(c *Client) { c.timeout = d } }
+
+func New(addr string, opts ...Option) *Client {
+	c := &Client{addr: addr}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
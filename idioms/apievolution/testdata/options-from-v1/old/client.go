@@ -0,0 +1,18 @@
+package client
+
+type Client struct {
+	addr    string
+	retries int
+}
+
+type Option func(*Client)
+
+func WithRetries(n int) Option { return func(c *Client) { c.retries = n } }
+
+func New(addr string, opts ...Option) *Client {
+	c := &Client{addr: addr}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
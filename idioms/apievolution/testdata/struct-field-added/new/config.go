@@ -0,0 +1,9 @@
+package config
+
+import "time"
+
+type Config struct {
+	Addr    string
+	Timeout time.Duration
+	_       [0]func()
+}
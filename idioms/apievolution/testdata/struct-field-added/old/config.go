@@ -0,0 +1,7 @@
+package config
+
+type Config struct {
+	Addr string
+	// incomparable from the start, so no later field can break == callers
+	_ [0]func()
+}
@@ -0,0 +1,63 @@
+// Package apievolution is major version 2 of patterns/idioms/apievolution.
+//
+// v2 makes the one change v1 could not: New reports invalid options as an
+// error instead of panicking. The new signature breaks every v1 caller,
+// so it lives under a new import path and v1 keeps working unchanged.
+package apievolution
+
+import (
+	"errors"
+	"time"
+
+	"patterns/construct"
+)
+
+type Client struct {
+	addr    string
+	retries int
+	timeout time.Duration
+}
+
+type options struct {
+	retries int
+	timeout time.Duration
+}
+
+type Option func(options *options) error
+
+func (o *options) SetDefaults() { *o = options{retries: 1, timeout: 10 * time.Second} }
+
+func WithRetries(n int) Option {
+	return func(options *options) error {
+		if n < 0 {
+			return errors.New("retries cannot be negative")
+		}
+		options.retries = n
+		return nil
+	}
+}
+
+func WithTimeout(d time.Duration) Option {
+	return func(options *options) error {
+		if d <= 0 {
+			return errors.New("timeout must be positive")
+		}
+		options.timeout = d
+		return nil
+	}
+}
+
+func New(addr string, opts ...Option) (*Client, error) {
+	if addr == "" {
+		return nil, errors.New("addr cannot be empty")
+	}
+	options, err := construct.New(opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{addr: addr, retries: options.retries, timeout: options.timeout}, nil
+}
+
+func (c *Client) Addr() string           { return c.addr }
+func (c *Client) Retries() int           { return c.retries }
+func (c *Client) Timeout() time.Duration { return c.timeout }
@@ -0,0 +1,22 @@
+// Package ctxpatterns shows how context.Context is meant to be used, each
+// next to the mistake it avoids:
+//
+//   - keys are values of unexported types, reached through accessors, so
+//     two packages that both store a "user" cannot overwrite each other
+//     (keys.go)
+//   - a context carries request-scoped facts that cross API boundaries,
+//     such as who is asking and the request id, never the dependencies
+//     or options of a call, which belong in its parameters (params.go)
+//   - cancellation flows down: each layer passes on the ctx it was given,
+//     may narrow its deadline, and gives the reason with a cause; a layer
+//     that starts from context.Background() cuts the chain (layers.go)
+//   - work that outlives the request keeps its values but not its
+//     cancellation, and gets a deadline of its own (detach.go)
+//
+// The tests run each pair and check that the mistake shows.
+package ctxpatterns
+
+import "errors"
+
+// ErrNoUser is returned by code that needs a user the context lacks.
+var ErrNoUser = errors.New("no user in context")
@@ -0,0 +1,157 @@
+package ctxpatterns_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"patterns/idioms/ctxpatterns"
+)
+
+type demo struct {
+	name string
+	run  func() (string, error)
+}
+
+// TestDemos runs each pattern next to its mistake and checks that the
+// mistake shows; -v logs what each one saw.
+func TestDemos(t *testing.T) {
+	demos := []demo{
+		{"string keys collide", stringKeys},
+		{"typed keys do not", typedKeys},
+		{"a dependency in the context fails at run time", dependencyInContext},
+		{"a dependency as a parameter", dependencyAsParameter},
+		{"the service's budget stops the query", budget},
+		{"the caller's deadline stops the query", callerDeadline},
+		{"a layer on context.Background() outlives its caller", severed},
+		{"background work on the request context is cut off", onRequest},
+		{"detached background work finishes", detached},
+	}
+	for _, d := range demos {
+		t.Run(d.name, func(t *testing.T) {
+			saw, err := d.run()
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Log(saw)
+		})
+	}
+}
+
+var alice = ctxpatterns.User{ID: 1, Name: "alice"}
+
+func stringKeys() (string, error) {
+	ctx := ctxpatterns.WithUserName(context.Background(), "alice")
+	ctx = ctxpatterns.WithUserAgent(ctx, "curl/8.5")
+	name, _ := ctxpatterns.UserName(ctx)
+	if name != "curl/8.5" {
+		return "", fmt.Errorf("UserName = %q; the tracing middleware's value should have replaced it", name)
+	}
+	return fmt.Sprintf("the user is now %q", name), nil
+}
+
+func typedKeys() (string, error) {
+	ctx := ctxpatterns.WithUser(context.Background(), alice)
+	ctx = ctxpatterns.WithAgent(ctx, "curl/8.5")
+	u, _ := ctxpatterns.UserFrom(ctx)
+	agent, _ := ctxpatterns.AgentFrom(ctx)
+	if u != alice || agent != "curl/8.5" {
+		return "", fmt.Errorf("UserFrom = %v, AgentFrom = %q", u, agent)
+	}
+	return fmt.Sprintf("user %q, agent %q", u.Name, agent), nil
+}
+
+// gateway records the charges it is asked for.
+type gateway struct{ charged []int }
+
+func (g *gateway) Charge(_ context.Context, userID, cents int) error {
+	g.charged = append(g.charged, cents)
+	return nil
+}
+
+func dependencyInContext() (string, error) {
+	// the caller knew to add the user, not the gateway; it compiles
+	err := ctxpatterns.ChargeFromContext(ctxpatterns.WithUser(context.Background(), alice), 500)
+	if !errors.Is(err, ctxpatterns.ErrNoGateway) {
+		return "", fmt.Errorf("ChargeFromContext: err = %v, want %v", err, ctxpatterns.ErrNoGateway)
+	}
+	return err.Error(), nil
+}
+
+func dependencyAsParameter() (string, error) {
+	g := &gateway{}
+	if err := ctxpatterns.Charge(ctxpatterns.WithUser(context.Background(), alice), g, 500); err != nil {
+		return "", err
+	}
+	if !slices.Equal(g.charged, []int{500}) {
+		return "", fmt.Errorf("charged %v, want [500]", g.charged)
+	}
+	return "charged 500", nil
+}
+
+func budget() (string, error) {
+	s := &ctxpatterns.Service{Store: &ctxpatterns.Store{Latency: time.Second}, Budget: 20 * time.Millisecond}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err := s.Lookup(ctx, 7)
+	if !errors.Is(err, ctxpatterns.ErrLookupBudget) {
+		return "", fmt.Errorf("Lookup: err = %v, want %v", err, ctxpatterns.ErrLookupBudget)
+	}
+	return err.Error(), nil
+}
+
+func callerDeadline() (string, error) {
+	s := &ctxpatterns.Service{Store: &ctxpatterns.Store{Latency: time.Second}, Budget: 5 * time.Second}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := s.Lookup(ctx, 7)
+	if !errors.Is(err, context.DeadlineExceeded) || errors.Is(err, ctxpatterns.ErrLookupBudget) {
+		return "", fmt.Errorf("Lookup: err = %v, want %v", err, context.DeadlineExceeded)
+	}
+	return err.Error(), nil
+}
+
+func severed() (string, error) {
+	store := &ctxpatterns.Store{Latency: 200 * time.Millisecond}
+	s := &ctxpatterns.DetachedService{Store: store}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := s.Lookup(ctx, 7)
+	took := time.Since(start)
+	if err != nil || store.Finished.Load() != 1 || took < store.Latency {
+		return "", fmt.Errorf("Lookup took %v and returned %v; the query should have run to the end", took, err)
+	}
+	return fmt.Sprintf("a 20ms caller waited %v", took.Round(10*time.Millisecond)), nil
+}
+
+// request runs a handler that starts background work, answers, and
+// returns, ending the request's context.
+func request(handler func(ctx context.Context)) {
+	ctx, cancel := context.WithCancel(ctxpatterns.WithUser(context.Background(), alice))
+	defer cancel()
+	handler(ctx)
+}
+
+func onRequest() (string, error) {
+	log := &ctxpatterns.AuditLog{Latency: 20 * time.Millisecond}
+	request(func(ctx context.Context) { log.RecordOnRequest(ctx, "paid") })
+	entries, failures := log.Wait()
+	if len(entries) != 0 || len(failures) != 1 || !errors.Is(failures[0], context.Canceled) {
+		return "", fmt.Errorf("entries %q, failures %v; want the entry cancelled", entries, failures)
+	}
+	return "entry lost: " + failures[0].Error(), nil
+}
+
+func detached() (string, error) {
+	log := &ctxpatterns.AuditLog{Latency: 20 * time.Millisecond}
+	request(func(ctx context.Context) { log.Record(ctx, time.Second, "paid") })
+	entries, failures := log.Wait()
+	if !slices.Equal(entries, []string{"alice paid"}) || len(failures) != 0 {
+		return "", fmt.Errorf("entries %q, failures %v; want the entry written", entries, failures)
+	}
+	return fmt.Sprintf("wrote %q after the request ended", entries[0]), nil
+}
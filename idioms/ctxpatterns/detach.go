@@ -0,0 +1,90 @@
+package ctxpatterns
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// AuditLog records what users did, in the background, after their
+// request has been answered. The zero value is ready to use.
+type AuditLog struct {
+	// Latency is how long writing an entry takes, a remote log's round
+	// trip.
+	Latency time.Duration
+
+	wg       sync.WaitGroup
+	mu       sync.Mutex
+	entries  []string
+	failures []error
+}
+
+func (a *AuditLog) record(ctx context.Context, action string) {
+	defer a.wg.Done()
+	err := a.write(ctx, action)
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if err != nil {
+		a.failures = append(a.failures, err)
+	}
+}
+
+func (a *AuditLog) write(ctx context.Context, action string) error {
+	u, ok := UserFrom(ctx)
+	if !ok {
+		return ErrNoUser
+	}
+	t := time.NewTimer(a.Latency)
+	defer t.Stop()
+	select {
+	case <-t.C:
+	case <-ctx.Done():
+		return context.Cause(ctx)
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.entries = append(a.entries, u.Name+" "+action)
+	return nil
+}
+
+// Wait waits for the entries being written and returns those written
+// and the errors of those that were not.
+func (a *AuditLog) Wait() (entries []string, failures []error) {
+	a.wg.Wait()
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]string(nil), a.entries...), append([]error(nil), a.failures...)
+}
+
+// background work on the request context
+// Level: Poor
+// pros: the goroutine sees the request's values.
+// cons: it also sees the request's cancellation, which comes as soon as
+// the handler returns, so the work is cut off if it was not done by then.
+//
+// RecordOnRequest writes the entry on a goroutine using the request's
+// ctx.
+func (a *AuditLog) RecordOnRequest(ctx context.Context, action string) {
+	a.wg.Add(1)
+	go a.record(ctx, action)
+}
+
+// detached background work
+// Level: Good
+// pros: context.WithoutCancel keeps the values, who the user was and the
+// request id for the logs, and drops the cancellation; the work gets a
+// timeout of its own, so it still cannot run forever.
+// cons: it keeps every value, those that must not outlive the request,
+// such as an open transaction, too; Wait, or something like it, must
+// keep the process up until it is done.
+//
+// Record writes the entry on a goroutine that survives the request,
+// giving up after timeout.
+func (a *AuditLog) Record(ctx context.Context, timeout time.Duration, action string) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), timeout)
+	a.wg.Add(1)
+	go func() {
+		defer cancel()
+		a.record(ctx, action)
+	}()
+}
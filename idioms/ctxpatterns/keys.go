@@ -0,0 +1,71 @@
+package ctxpatterns
+
+import "context"
+
+// string keys
+// Level: Poor
+// pros: nothing to declare.
+// cons: any package may pick the same string and replace the value, or
+// read one of another type; nothing tells the two apart.
+//
+// WithUserName stores the name of the authenticated user under the key
+// "user", the way an auth middleware might.
+func WithUserName(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, "user", name)
+}
+
+// UserName returns the name WithUserName stored, if the key still holds
+// one.
+func UserName(ctx context.Context) (string, bool) {
+	name, ok := ctx.Value("user").(string)
+	return name, ok
+}
+
+// WithUserAgent stores the client's user agent under "user" too, the way
+// a tracing middleware written by someone else might. Below it, UserName
+// returns the agent and every check of who is asking sees it.
+func WithUserAgent(ctx context.Context, agent string) context.Context {
+	return context.WithValue(ctx, "user", agent)
+}
+
+// typed keys
+// Level: Good
+// pros: a key is a value of an unexported type, so no other package can
+// make one equal to it; the accessors fix the value's type.
+// cons: a key type and two functions per value.
+//
+// User is the authenticated caller.
+type User struct {
+	ID   int
+	Name string
+}
+
+// userKey is the key of the User. An empty struct costs nothing to put
+// in an interface, and being unexported, only this package can make one.
+type userKey struct{}
+
+// WithUser returns a copy of ctx carrying u.
+func WithUser(ctx context.Context, u User) context.Context {
+	return context.WithValue(ctx, userKey{}, u)
+}
+
+// UserFrom returns the User of ctx, if it has one.
+func UserFrom(ctx context.Context) (User, bool) {
+	u, ok := ctx.Value(userKey{}).(User)
+	return u, ok
+}
+
+// agentKey is the tracing middleware's key: a type of its own, so it
+// never equals userKey, whatever either is called.
+type agentKey struct{}
+
+// WithAgent stores the client's user agent, beside the User.
+func WithAgent(ctx context.Context, agent string) context.Context {
+	return context.WithValue(ctx, agentKey{}, agent)
+}
+
+// AgentFrom returns the user agent of ctx, if it has one.
+func AgentFrom(ctx context.Context) (string, bool) {
+	a, ok := ctx.Value(agentKey{}).(string)
+	return a, ok
+}
@@ -0,0 +1,75 @@
+package ctxpatterns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// ErrLookupBudget is the cause of a lookup the Service gave up on
+// because its own budget ran out, as opposed to its caller's.
+var ErrLookupBudget = errors.New("lookup budget exhausted")
+
+// Store is the bottom layer: a query that takes Latency unless its
+// context is done first.
+type Store struct {
+	Latency time.Duration
+	// Finished counts the queries that ran to the end.
+	Finished atomic.Int64
+}
+
+// Query returns the value for id after Latency, or ctx's cause if it is
+// done first.
+func (s *Store) Query(ctx context.Context, id int) (string, error) {
+	t := time.NewTimer(s.Latency)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		s.Finished.Add(1)
+		return fmt.Sprintf("item-%d", id), nil
+	case <-ctx.Done():
+		return "", context.Cause(ctx)
+	}
+}
+
+// propagated cancellation
+// Level: Good
+// pros: a caller that gives up stops every layer below it at once; each
+// layer may only shorten the deadline it was given, and says why with a
+// cause.
+// cons: every blocking call must take and watch a ctx.
+//
+// Service is the middle layer, bounding each lookup by Budget.
+type Service struct {
+	Store  *Store
+	Budget time.Duration
+}
+
+// Lookup queries the store with ctx, narrowed to the budget: whichever
+// of the caller's deadline and the budget comes first stops the query,
+// and the error says which.
+func (s *Service) Lookup(ctx context.Context, id int) (string, error) {
+	ctx, cancel := context.WithTimeoutCause(ctx, s.Budget, ErrLookupBudget)
+	defer cancel()
+	return s.Store.Query(ctx, id)
+}
+
+// severed cancellation
+// Level: Poor
+// pros: none; it is the mistake of a layer written before contexts, or
+// to quiet a "ctx unused" complaint.
+// cons: the query runs on after the caller has given up, holding its
+// connection, and the caller's deadline no longer bounds the request.
+//
+// DetachedService starts each lookup from context.Background() instead
+// of the ctx it was given.
+type DetachedService struct {
+	Store *Store
+}
+
+// Lookup ignores ctx, which is the bug.
+func (s *DetachedService) Lookup(ctx context.Context, id int) (string, error) {
+	return s.Store.Query(context.Background(), id)
+}
@@ -0,0 +1,64 @@
+package ctxpatterns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Gateway charges users; a payment provider's client in real code.
+type Gateway interface {
+	Charge(ctx context.Context, userID, cents int) error
+}
+
+// ErrNoGateway is what ChargeFromContext fails with when the dependency
+// it fishes out of the context was never put there.
+var ErrNoGateway = errors.New("no payment gateway in context")
+
+type gatewayKey struct{}
+
+// WithGateway puts g in ctx, for ChargeFromContext.
+func WithGateway(ctx context.Context, g Gateway) context.Context {
+	return context.WithValue(ctx, gatewayKey{}, g)
+}
+
+// dependencies in the context
+// Level: Poor
+// pros: no parameter to thread through the layers in between.
+// cons: the signature lies about what the call needs; a caller that did
+// not know fails at run time instead of compile time, and a test must
+// know which values to plant.
+//
+// ChargeFromContext charges the context's user, through the gateway the
+// context carries.
+func ChargeFromContext(ctx context.Context, cents int) error {
+	g, ok := ctx.Value(gatewayKey{}).(Gateway)
+	if !ok {
+		return ErrNoGateway
+	}
+	u, ok := UserFrom(ctx)
+	if !ok {
+		return ErrNoUser
+	}
+	return g.Charge(ctx, u.ID, cents)
+}
+
+// dependencies as parameters
+// Level: Good
+// pros: the compiler checks that every caller supplies the gateway; the
+// context keeps only what is about the request, who is asking.
+// cons: every layer in between passes g on, or holds it in a field.
+//
+// Charge charges the context's user through g. Who is asking crosses API
+// boundaries with the request, so it stays in ctx; which gateway to use
+// is the caller's decision, so it is a parameter.
+func Charge(ctx context.Context, g Gateway, cents int) error {
+	u, ok := UserFrom(ctx)
+	if !ok {
+		return ErrNoUser
+	}
+	if err := g.Charge(ctx, u.ID, cents); err != nil {
+		return fmt.Errorf("charging user %d: %w", u.ID, err)
+	}
+	return nil
+}
@@ -0,0 +1,14 @@
+// Package enum shows the typed-constant enum idiom. The boilerplate
+// (String, Parse, Values, text/JSON marshaling) is generated by cmd/enumgen.
+package enum
+
+//go:generate go run patterns/cmd/enumgen -type=Level -trimprefix=Level
+
+// Level grades a pattern implementation, as used in the options examples.
+type Level int
+
+const (
+	LevelPoor Level = iota + 1
+	LevelAverage
+	LevelGood
+)
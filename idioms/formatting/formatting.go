@@ -0,0 +1,174 @@
+// Package formatting gives one domain type, a release Version, every
+// textual interface Go code looks for, all from a single canonical form,
+// "v1.4.2-rc.1":
+//
+//   - fmt.Stringer, for %v, %s and Println
+//   - encoding.TextMarshaler and TextUnmarshaler, which encoding/json
+//     also uses for map keys, and flag.TextVar, slog's handlers and
+//     most config libraries use for the value itself
+//   - json.Marshaler and Unmarshaler, a JSON string of the same text
+//   - slog.LogValuer, so a log line shows the text whatever the handler
+//
+// Every one of them calls appendText or Parse, so no two can drift
+// apart: whatever a Version prints as, it parses back from, in a flag,
+// a JSON document or a log line. All the methods have value receivers;
+// LooseVersion shows what a pointer receiver on String does to the
+// values that are not addressable, which is most of them.
+//
+// The tests check the round trips and the log output.
+package formatting
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+)
+
+// canonical form
+// Level: Good
+// pros: one text, formatted by one function and parsed by one, behind
+// every interface; the value prints the same in fmt, JSON, flags and
+// logs, and whatever it prints parses back.
+// cons: five methods for one value, each a line; the form is API once
+// it is in stored documents.
+//
+// Version is a release number: major, minor and patch, and an optional
+// pre-release tag. The zero Version is v0.0.0.
+type Version struct {
+	Major, Minor, Patch int
+	// Pre is the pre-release tag, such as "rc.1"; empty for a release.
+	Pre string
+}
+
+// Parse parses the canonical form: "v", three dot-separated numbers
+// without leading zeros, then optionally "-" and a tag of letters,
+// digits and dots.
+func Parse(s string) (Version, error) {
+	v, err := parse(s)
+	if err != nil {
+		return Version{}, fmt.Errorf("formatting: parse version %q: %w", s, err)
+	}
+	return v, nil
+}
+
+func parse(s string) (Version, error) {
+	rest, ok := strings.CutPrefix(s, "v")
+	if !ok {
+		return Version{}, errors.New(`want a leading "v"`)
+	}
+	nums, pre, hasPre := strings.Cut(rest, "-")
+	parts := strings.Split(nums, ".")
+	if len(parts) != 3 {
+		return Version{}, errors.New("want major.minor.patch")
+	}
+	var n [3]int
+	for i, p := range parts {
+		if p == "" || len(p) > 1 && p[0] == '0' {
+			return Version{}, fmt.Errorf("bad number %q", p)
+		}
+		v, err := strconv.Atoi(p)
+		if err != nil || v < 0 {
+			return Version{}, fmt.Errorf("bad number %q", p)
+		}
+		n[i] = v
+	}
+	if hasPre {
+		if err := checkPre(pre); err != nil {
+			return Version{}, err
+		}
+	}
+	return Version{Major: n[0], Minor: n[1], Patch: n[2], Pre: pre}, nil
+}
+
+func checkPre(pre string) error {
+	if pre == "" {
+		return errors.New("empty pre-release tag")
+	}
+	for _, r := range pre {
+		if !('a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9' || r == '.') {
+			return fmt.Errorf("bad character %q in pre-release tag", r)
+		}
+	}
+	return nil
+}
+
+// appendText appends the canonical form of v to b; everything else
+// formats through it.
+func (v Version) appendText(b []byte) []byte {
+	b = append(b, 'v')
+	b = strconv.AppendInt(b, int64(v.Major), 10)
+	b = append(b, '.')
+	b = strconv.AppendInt(b, int64(v.Minor), 10)
+	b = append(b, '.')
+	b = strconv.AppendInt(b, int64(v.Patch), 10)
+	if v.Pre != "" {
+		b = append(b, '-')
+		b = append(b, v.Pre...)
+	}
+	return b
+}
+
+// valid reports whether v has a canonical form that Parse accepts.
+func (v Version) valid() error {
+	if v.Major < 0 || v.Minor < 0 || v.Patch < 0 {
+		return fmt.Errorf("formatting: negative number in version %d.%d.%d", v.Major, v.Minor, v.Patch)
+	}
+	if v.Pre != "" {
+		if err := checkPre(v.Pre); err != nil {
+			return fmt.Errorf("formatting: version: %w", err)
+		}
+	}
+	return nil
+}
+
+func (v Version) String() string { return string(v.appendText(nil)) }
+
+// MarshalText returns the canonical form. It fails for a Version that
+// could not be parsed back, such as one with a negative number, rather
+// than write what no reader accepts.
+func (v Version) MarshalText() ([]byte, error) {
+	if err := v.valid(); err != nil {
+		return nil, err
+	}
+	return v.appendText(nil), nil
+}
+
+// UnmarshalText parses the canonical form.
+func (v *Version) UnmarshalText(text []byte) error {
+	parsed, err := Parse(string(text))
+	if err != nil {
+		return err
+	}
+	*v = parsed
+	return nil
+}
+
+// MarshalJSON returns the canonical form as a JSON string. encoding/json
+// would do the same through MarshalText; it is written out so that the
+// JSON form is part of the type's declared API, not a consequence.
+func (v Version) MarshalJSON() ([]byte, error) {
+	text, err := v.MarshalText()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(string(text))
+}
+
+// UnmarshalJSON accepts a JSON string in the canonical form, and
+// nothing else: not an object of the fields, which is what encoding/json
+// would otherwise expect of a struct.
+func (v *Version) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("formatting: version must be a JSON string: %w", err)
+	}
+	return v.UnmarshalText([]byte(s))
+}
+
+// LogValue logs the canonical form. slog's TextHandler would find
+// MarshalText and its JSONHandler MarshalJSON; LogValue says so for
+// every handler, those that look for neither included.
+func (v Version) LogValue() slog.Value { return slog.StringValue(v.String()) }
@@ -0,0 +1,24 @@
+package formatting
+
+import "fmt"
+
+// pointer-receiver String
+// Level: Poor
+// pros: none; it is usually written to avoid copying a small struct.
+// cons: only *LooseVersion is a Stringer, so a LooseVersion passed by
+// value, in a slice, a map or a struct field, prints as its fields; and
+// lacking text methods, JSON and logs show the fields too.
+//
+// LooseVersion is Version with only a String method, on the pointer.
+type LooseVersion struct {
+	Major, Minor, Patch int
+	Pre                 string
+}
+
+func (v *LooseVersion) String() string {
+	s := fmt.Sprintf("v%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.Pre != "" {
+		s += "-" + v.Pre
+	}
+	return s
+}
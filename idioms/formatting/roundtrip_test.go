@@ -0,0 +1,134 @@
+package formatting_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"patterns/idioms/formatting"
+)
+
+var (
+	release = formatting.Version{Major: 1, Minor: 4, Patch: 2}
+	rc      = formatting.Version{Major: 2, Minor: 0, Patch: 0, Pre: "rc.1"}
+)
+
+func expect(t *testing.T, what string, got, want any) {
+	t.Helper()
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("%s = %v, want %v", what, got, want)
+	}
+}
+
+func fails(t *testing.T, what string, err error) {
+	t.Helper()
+	if err == nil {
+		t.Errorf("%s succeeded", what)
+	}
+}
+
+func TestRoundTrips(t *testing.T) {
+	for _, v := range []formatting.Version{{}, release, rc, {Major: 10, Minor: 20, Patch: 30, Pre: "beta.2.x"}} {
+		back, err := formatting.Parse(v.String())
+		expect(t, "Parse(String) of "+v.String(), back == v && err == nil, true)
+
+		text, _ := v.MarshalText()
+		var fromText formatting.Version
+		err = fromText.UnmarshalText(text)
+		expect(t, "UnmarshalText(MarshalText) of "+v.String(), fromText == v && err == nil, true)
+	}
+
+	type manifest struct {
+		Name     string                        `json:"name"`
+		Version  formatting.Version            `json:"version"`
+		Previous *formatting.Version           `json:"previous"`
+		History  []formatting.Version          `json:"history"`
+		Notes    map[formatting.Version]string `json:"notes"`
+	}
+	m := manifest{
+		Name: "app", Version: rc, Previous: &release,
+		History: []formatting.Version{release, rc},
+		Notes:   map[formatting.Version]string{release: "stable"},
+	}
+	data, err := json.Marshal(m)
+	expect(t, "json.Marshal error", err, nil)
+	expect(t, "json.Marshal", string(data),
+		`{"name":"app","version":"v2.0.0-rc.1","previous":"v1.4.2","history":["v1.4.2","v2.0.0-rc.1"],"notes":{"v1.4.2":"stable"}}`)
+	var back manifest
+	err = json.Unmarshal(data, &back)
+	expect(t, "json.Unmarshal error", err, nil)
+	expect(t, "json round trip", back.Version == rc && *back.Previous == release &&
+		len(back.History) == 2 && back.History[1] == rc && back.Notes[release] == "stable", true)
+
+	fs := flag.NewFlagSet("app", flag.ContinueOnError)
+	var v formatting.Version
+	fs.TextVar(&v, "version", release, "version to deploy")
+	expect(t, "flag default", fs.Lookup("version").DefValue, "v1.4.2")
+	err = fs.Parse([]string{"-version", "v2.0.0-rc.1"})
+	expect(t, "flag.TextVar", v == rc && err == nil, true)
+}
+
+func TestRejects(t *testing.T) {
+	for _, s := range []string{"1.4.2", "v1.4", "v1.4.2.0", "v01.4.2", "v1.4.2-", "v1.4.2-rc_1", "v1.-4.2"} {
+		_, err := formatting.Parse(s)
+		fails(t, fmt.Sprintf("Parse(%q)", s), err)
+	}
+	var v formatting.Version
+	fails(t, "json.Unmarshal of an object", json.Unmarshal([]byte(`{"Major":1,"Minor":4,"Patch":2}`), &v))
+	_, err := json.Marshal(formatting.Version{Major: -1})
+	fails(t, "json.Marshal of a negative version", err)
+	fs := flag.NewFlagSet("app", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.TextVar(&v, "version", release, "")
+	fails(t, "flag -version 1.4", fs.Parse([]string{"-version", "1.4"}))
+}
+
+func TestPrinting(t *testing.T) {
+	expect(t, "Sprint", fmt.Sprint(rc), "v2.0.0-rc.1")
+	expect(t, "Sprintf %+v", fmt.Sprintf("%+v", rc), "v2.0.0-rc.1")
+	expect(t, "Sprintf %q", fmt.Sprintf("%q", rc), `"v2.0.0-rc.1"`)
+	expect(t, "Sprint of a slice", fmt.Sprint([]formatting.Version{release, rc}), "[v1.4.2 v2.0.0-rc.1]")
+	expect(t, "Sprint of a pointer", fmt.Sprint(&rc), "v2.0.0-rc.1")
+	expect(t, "Sprint of a struct field", fmt.Sprint(struct {
+		Name string
+		V    formatting.Version
+	}{"app", release}), "{app v1.4.2}")
+}
+
+// logLine logs one record with h and returns it without the time.
+func logLine(newHandler func(io.Writer) slog.Handler, v any) string {
+	var buf bytes.Buffer
+	slog.New(newHandler(&buf)).Info("deploy", "version", v)
+	line := strings.TrimSpace(buf.String())
+	if i := strings.Index(line, "level="); i >= 0 {
+		return line[i:]
+	}
+	if i := strings.Index(line, `"level"`); i >= 0 {
+		return "{" + line[i:]
+	}
+	return line
+}
+
+func textHandler(w io.Writer) slog.Handler { return slog.NewTextHandler(w, nil) }
+func jsonHandler(w io.Writer) slog.Handler { return slog.NewJSONHandler(w, nil) }
+
+func TestLogging(t *testing.T) {
+	expect(t, "slog text", logLine(textHandler, rc), "level=INFO msg=deploy version=v2.0.0-rc.1")
+	expect(t, "slog JSON", logLine(jsonHandler, rc), `{"level":"INFO","msg":"deploy","version":"v2.0.0-rc.1"}`)
+	expect(t, "slog text of a pointer", logLine(textHandler, &rc), "level=INFO msg=deploy version=v2.0.0-rc.1")
+}
+
+func TestLooseVersion(t *testing.T) {
+	lv := formatting.LooseVersion{Major: 2, Pre: "rc.1"}
+	expect(t, "loose: Sprint of a pointer", fmt.Sprint(&lv), "v2.0.0-rc.1")
+	expect(t, "loose: Sprint of the value", fmt.Sprint(lv), "{2 0 0 rc.1}")
+	expect(t, "loose: Sprint of a slice", fmt.Sprint([]formatting.LooseVersion{lv}), "[{2 0 0 rc.1}]")
+	data, _ := json.Marshal(lv)
+	expect(t, "loose: json.Marshal", string(data), `{"Major":2,"Minor":0,"Patch":0,"Pre":"rc.1"}`)
+	expect(t, "loose: slog text", logLine(textHandler, lv), "level=INFO msg=deploy version=\"{Major:2 Minor:0 Patch:0 Pre:rc.1}\"")
+}
@@ -0,0 +1,102 @@
+package markeriface
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// Handler handles a command.
+type Handler func(ctx context.Context, cmd any) (any, error)
+
+// Middleware decorates a Handler.
+type Middleware func(Handler) Handler
+
+// Bus dispatches commands to the handler registered for their type.
+type Bus struct {
+	handlers   map[reflect.Type]Handler
+	middleware []Middleware
+}
+
+// NewBus returns a bus applying mw outermost first.
+func NewBus(mw ...Middleware) *Bus {
+	return &Bus{handlers: map[reflect.Type]Handler{}, middleware: mw}
+}
+
+// Handle registers h for commands of type C.
+func Handle[C any](b *Bus, h func(ctx context.Context, cmd C) (any, error)) {
+	b.handlers[reflect.TypeFor[C]()] = func(ctx context.Context, cmd any) (any, error) {
+		return h(ctx, cmd.(C))
+	}
+}
+
+// Dispatch runs the handler for cmd through the middleware.
+func (b *Bus) Dispatch(ctx context.Context, cmd any) (any, error) {
+	h, ok := b.handlers[reflect.TypeOf(cmd)]
+	if !ok {
+		return nil, fmt.Errorf("no handler for %T", cmd)
+	}
+	for i := len(b.middleware) - 1; i >= 0; i-- {
+		h = b.middleware[i](h)
+	}
+
+	return h(ctx, cmd)
+}
+
+// Retry re-runs commands marked retryable when they fail with an error
+// that is itself marked retryable.
+func Retry(attempts int) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, cmd any) (any, error) {
+			if !IsRetryable(cmd) {
+				return next(ctx, cmd)
+			}
+			var res any
+			var err error
+			for range attempts {
+				res, err = next(ctx, cmd)
+				var r Retryable
+				if err == nil || !errors.As(err, &r) || !r.Retryable() || ctx.Err() != nil {
+					break
+				}
+			}
+			return res, err
+		}
+	}
+}
+
+// Cache memoizes successful results of cacheable commands.
+func Cache() Middleware {
+	var mu sync.Mutex
+	results := map[string]any{}
+	return func(next Handler) Handler {
+		return func(ctx context.Context, cmd any) (any, error) {
+			key, ok := IsCacheable(cmd)
+			if !ok {
+				return next(ctx, cmd)
+			}
+			mu.Lock()
+			res, hit := results[key]
+			mu.Unlock()
+			if hit {
+				return res, nil
+			}
+			res, err := next(ctx, cmd)
+			if err == nil {
+				mu.Lock()
+				results[key] = res
+				mu.Unlock()
+			}
+			return res, err
+		}
+	}
+}
+
+// TemporaryError is an error marked retryable via the interface mechanism.
+type TemporaryError struct{ Err error }
+
+func (e *TemporaryError) Error() string   { return "temporary: " + e.Err.Error() }
+func (e *TemporaryError) Unwrap() error   { return e.Err }
+func (e *TemporaryError) Retryable() bool { return true }
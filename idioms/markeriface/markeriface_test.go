@@ -0,0 +1,194 @@
+package markeriface_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"patterns/idioms/markeriface"
+)
+
+// one command type per mechanism
+type (
+	byMethod struct{ ID string }
+	byTag    struct {
+		_  struct{} `bus:"retryable,cacheable"`
+		ID string
+	}
+	// byTagTypo is silently unmarked: the tag says "retry"
+	byTagTypo struct {
+		_ struct{} `bus:"retry"`
+	}
+	byList    struct{ ID string }
+	unmarked  struct{ ID string }
+	optingOut struct{}
+)
+
+func (c byMethod) Retryable() bool  { return true }
+func (c byMethod) CacheKey() string { return "method/" + c.ID }
+func (optingOut) Retryable() bool   { return false }
+
+func init() {
+	markeriface.Mark[byList]("retryable")
+	markeriface.Mark[byList]("cacheable")
+}
+
+func TestMarks(t *testing.T) {
+	for _, c := range []struct {
+		name      string
+		cmd       any
+		retryable bool
+		cacheKey  string
+	}{
+		{"interface", byMethod{ID: "1"}, true, "method/1"},
+		{"tag", byTag{ID: "1"}, true, "markeriface_test.byTag"},
+		{"tag through a pointer", &byTag{}, true, "*markeriface_test.byTag"},
+		{"mistyped tag", byTagTypo{}, false, ""},
+		{"type list", byList{}, true, "markeriface_test.byList"},
+		// Mark registers the type itself, not its pointer
+		{"type list through a pointer", &byList{}, false, ""},
+		{"unmarked", unmarked{}, false, ""},
+		{"Retryable false", optingOut{}, false, ""},
+		{"nil", nil, false, ""},
+		{"not a struct", 42, false, ""},
+	} {
+		if got := markeriface.IsRetryable(c.cmd); got != c.retryable {
+			t.Errorf("%s: IsRetryable = %v, want %v", c.name, got, c.retryable)
+		}
+		key, ok := markeriface.IsCacheable(c.cmd)
+		if key != c.cacheKey || ok != (c.cacheKey != "") {
+			t.Errorf("%s: IsCacheable = %q, %v, want %q", c.name, key, ok, c.cacheKey)
+		}
+	}
+}
+
+// flaky counts the calls of the handlers it is registered as, failing
+// with err on the first failures of them.
+type flaky struct {
+	failures int
+	err      error
+	calls    int
+}
+
+func handle[C any](b *markeriface.Bus, f *flaky) {
+	markeriface.Handle(b, func(_ context.Context, cmd C) (any, error) {
+		f.calls++
+		if f.calls <= f.failures {
+			return nil, f.err
+		}
+		return f.calls, nil
+	})
+}
+
+func TestRetry(t *testing.T) {
+	temporary := &markeriface.TemporaryError{Err: errors.New("timeout")}
+	for _, c := range []struct {
+		name      string
+		cmd       any
+		err       error
+		calls     int
+		succeeded bool
+	}{
+		{"interface", byMethod{}, temporary, 3, true},
+		{"tag", byTag{}, temporary, 3, true},
+		{"type list", byList{}, temporary, 3, true},
+		{"unmarked", unmarked{}, temporary, 1, false},
+		{"Retryable false", optingOut{}, temporary, 1, false},
+		// the command is retryable, the error is not
+		{"permanent error", byMethod{}, errors.New("bad request"), 1, false},
+	} {
+		f := &flaky{failures: 2, err: c.err}
+		b := markeriface.NewBus(markeriface.Retry(3))
+		handle[byMethod](b, f)
+		handle[byTag](b, f)
+		handle[byList](b, f)
+		handle[unmarked](b, f)
+		handle[optingOut](b, f)
+		_, err := b.Dispatch(context.Background(), c.cmd)
+		if f.calls != c.calls || (err == nil) != c.succeeded {
+			t.Errorf("%s: %d calls, error %v; want %d calls, success %v", c.name, f.calls, err, c.calls, c.succeeded)
+		}
+	}
+}
+
+func TestRetryGivesUp(t *testing.T) {
+	f := &flaky{failures: 5, err: &markeriface.TemporaryError{Err: errors.New("timeout")}}
+	b := markeriface.NewBus(markeriface.Retry(3))
+	handle[byMethod](b, f)
+	if _, err := b.Dispatch(context.Background(), byMethod{}); err == nil || f.calls != 3 {
+		t.Errorf("%d calls, error %v; want 3 calls and the error", f.calls, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	f.calls = 0
+	if _, err := b.Dispatch(ctx, byMethod{}); err == nil || f.calls != 1 {
+		t.Errorf("canceled: %d calls, error %v; want 1 call", f.calls, err)
+	}
+}
+
+func TestCache(t *testing.T) {
+	for _, c := range []struct {
+		name  string
+		cmds  []any
+		calls int
+	}{
+		{"interface", []any{byMethod{ID: "1"}, byMethod{ID: "1"}, byMethod{ID: "2"}}, 2},
+		// tag and type-list marks key by type, so IDs do not matter
+		{"tag", []any{byTag{ID: "1"}, byTag{ID: "2"}}, 1},
+		{"type list", []any{byList{ID: "1"}, byList{ID: "2"}}, 1},
+		{"unmarked", []any{unmarked{}, unmarked{}}, 2},
+	} {
+		f := &flaky{}
+		b := markeriface.NewBus(markeriface.Cache())
+		handle[byMethod](b, f)
+		handle[byTag](b, f)
+		handle[byList](b, f)
+		handle[unmarked](b, f)
+		for _, cmd := range c.cmds {
+			if _, err := b.Dispatch(context.Background(), cmd); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if f.calls != c.calls {
+			t.Errorf("%s: %d handler calls, want %d", c.name, f.calls, c.calls)
+		}
+	}
+}
+
+func TestCacheSkipsErrors(t *testing.T) {
+	f := &flaky{failures: 1, err: errors.New("down")}
+	b := markeriface.NewBus(markeriface.Cache())
+	handle[byMethod](b, f)
+	if _, err := b.Dispatch(context.Background(), byMethod{}); err == nil {
+		t.Fatal("first dispatch succeeded")
+	}
+	for range 2 {
+		if res, err := b.Dispatch(context.Background(), byMethod{}); err != nil || res != 2 {
+			t.Errorf("Dispatch = %v, %v, want the second call's result", res, err)
+		}
+	}
+}
+
+func TestMiddlewareOrder(t *testing.T) {
+	var order []string
+	tracing := func(name string) markeriface.Middleware {
+		return func(next markeriface.Handler) markeriface.Handler {
+			return func(ctx context.Context, cmd any) (any, error) {
+				order = append(order, name)
+				return next(ctx, cmd)
+			}
+		}
+	}
+	b := markeriface.NewBus(tracing("outer"), tracing("inner"))
+	handle[unmarked](b, &flaky{})
+	if _, err := b.Dispatch(context.Background(), unmarked{}); err != nil {
+		t.Fatal(err)
+	}
+	if len(order) != 2 || order[0] != "outer" || order[1] != "inner" {
+		t.Errorf("middleware ran %v, want outer then inner", order)
+	}
+	if _, err := b.Dispatch(context.Background(), byTag{}); err == nil {
+		t.Error("Dispatch of a command without a handler succeeded")
+	}
+}
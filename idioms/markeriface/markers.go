@@ -0,0 +1,103 @@
+// Package markeriface compares three ways to mark a type with a capability
+// (e.g. "safe to retry", "result may be cached") and lets a small command
+// bus middleware consume the marks.
+//
+// An empty interface cannot be used as a marker in Go, since every type
+// satisfies it; the marker needs at least one method.
+package markeriface
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// marker interface pattern
+// pros: checked by the compiler, works for errors and commands alike, zero reflection
+// cons: the type must opt in by adding a method
+type Retryable interface {
+	Retryable() bool
+}
+
+type Cacheable interface {
+	CacheKey() string
+}
+
+// struct tag pattern
+// pros: declarative, no methods
+// cons: reflection, typos in tags are silent, only works on structs
+//
+// usage:
+//
+//	type Ping struct {
+//		_ struct{} `bus:"retryable,cacheable"`
+//	}
+const tagName = "bus"
+
+func hasTag(v any, mark string) bool {
+	t := reflect.TypeOf(v)
+	if t == nil {
+		return false
+	}
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+	for i := range t.NumField() {
+		for _, m := range strings.Split(t.Field(i).Tag.Get(tagName), ",") {
+			if m == mark {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// type list pattern
+// pros: marks types you do not own, no change to the type
+// cons: registration is global state and easy to forget
+var (
+	typeListMu sync.RWMutex
+	typeList   = map[string]map[reflect.Type]bool{}
+)
+
+// Mark registers T as having mark.
+func Mark[T any](mark string) {
+	typeListMu.Lock()
+	defer typeListMu.Unlock()
+	if typeList[mark] == nil {
+		typeList[mark] = map[reflect.Type]bool{}
+	}
+	typeList[mark][reflect.TypeFor[T]()] = true
+}
+
+func inTypeList(v any, mark string) bool {
+	typeListMu.RLock()
+	defer typeListMu.RUnlock()
+	return typeList[mark][reflect.TypeOf(v)]
+}
+
+// IsRetryable reports whether v is marked retryable by any mechanism.
+func IsRetryable(v any) bool {
+	if r, ok := v.(Retryable); ok {
+		return r.Retryable()
+	}
+
+	return hasTag(v, "retryable") || inTypeList(v, "retryable")
+}
+
+// IsCacheable reports whether v may be cached, and under which key.
+// Tag and type-list marks use the type name as key.
+func IsCacheable(v any) (string, bool) {
+	if c, ok := v.(Cacheable); ok {
+		return c.CacheKey(), true
+	}
+	if hasTag(v, "cacheable") || inTypeList(v, "cacheable") {
+		return reflect.TypeOf(v).String(), true
+	}
+
+	return "", false
+}
@@ -0,0 +1,72 @@
+// Package must turns (value, error) constructors into panicking ones for
+// the places where an error can only mean a programming mistake:
+//
+//	var validName = must.Must(regexp.Compile(`^[a-z]+$`))
+//
+// When to use it:
+//
+//   - package-level vars and init, where the input is a constant and
+//     there is no caller to return an error to (regexp.MustCompile,
+//     template.Must)
+//   - tests and examples, where a failed setup should stop the test
+//   - main, before anything is running
+//
+// When not to: anywhere the input comes from a user, a file, the network
+// or the environment. Those errors are expected and must be returned.
+//
+// The panic value is a *PanicError wrapping the original error, so a
+// recover can still use errors.Is and errors.As, and the message names
+// the call site.
+package must
+
+import (
+	"fmt"
+	"path/filepath"
+	"runtime"
+)
+
+// PanicError is the value Must panics with.
+type PanicError struct {
+	// Caller is the function that called Must, e.g.
+	// "patterns/options/functional.MustNewServer".
+	Caller string
+	// Location is file:line of the call.
+	Location string
+	Err      error
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("must: %s (%s): %v", e.Caller, e.Location, e.Err)
+}
+
+func (e *PanicError) Unwrap() error { return e.Err }
+
+// Must returns v, or panics with a *PanicError if err is not nil.
+func Must[T any](v T, err error) T {
+	if err != nil {
+		panic(newPanicError(err))
+	}
+	return v
+}
+
+// Do panics with a *PanicError if err is not nil, for calls that only
+// return an error.
+func Do(err error) {
+	if err != nil {
+		panic(newPanicError(err))
+	}
+}
+
+// newPanicError must be called directly by Must or Do.
+func newPanicError(err error) *PanicError {
+	e := &PanicError{Caller: "unknown", Location: "unknown", Err: err}
+	pc, file, line, ok := runtime.Caller(2)
+	if !ok {
+		return e
+	}
+	e.Location = fmt.Sprintf("%s:%d", filepath.Base(file), line)
+	if fn := runtime.FuncForPC(pc); fn != nil {
+		e.Caller = fn.Name()
+	}
+	return e
+}
@@ -0,0 +1,98 @@
+package must_test
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+
+	"patterns/idioms/must"
+	"patterns/options/functional"
+)
+
+// recovered runs f and returns the *must.PanicError it panics with.
+func recovered(t *testing.T, f func()) (pe *must.PanicError) {
+	t.Helper()
+	defer func() {
+		p := recover()
+		var ok bool
+		if pe, ok = p.(*must.PanicError); !ok {
+			t.Fatalf("panic value = %#v, want a *must.PanicError", p)
+		}
+	}()
+	f()
+	t.Fatal("no panic")
+	return nil
+}
+
+// nextLine returns the file:line following its call, as
+// PanicError.Location has it.
+func nextLine() string {
+	_, file, line, _ := runtime.Caller(1)
+	return filepath.Base(file) + ":" + strconv.Itoa(line+1)
+}
+
+func TestMustValue(t *testing.T) {
+	if got := must.Must(strconv.Atoi("42")); got != 42 {
+		t.Errorf("Must = %d, want 42", got)
+	}
+	must.Do(nil)
+}
+
+// TestPanicMessage checks that the panic names the function calling Must
+// and the line of the call, and carries the error whole.
+func TestPanicMessage(t *testing.T) {
+	var wantLine string
+	pe := recovered(t, func() {
+		wantLine = nextLine()
+		must.Must(strconv.Atoi("forty-two"))
+	})
+	if want := "patterns/idioms/must_test.TestPanicMessage.func1"; pe.Caller != want {
+		t.Errorf("Caller = %q, want %q", pe.Caller, want)
+	}
+	if pe.Location != wantLine {
+		t.Errorf("Location = %q, want %q", pe.Location, wantLine)
+	}
+	want := fmt.Sprintf(`must: %s (%s): strconv.Atoi: parsing "forty-two": invalid syntax`, pe.Caller, wantLine)
+	if pe.Error() != want {
+		t.Errorf("message = %q\nwant      %q", pe.Error(), want)
+	}
+	if !errors.Is(pe, strconv.ErrSyntax) {
+		t.Error("errors.Is does not reach the wrapped error")
+	}
+	var ne *strconv.NumError
+	if !errors.As(pe, &ne) || ne.Func != "Atoi" {
+		t.Errorf("errors.As(*strconv.NumError) = %v", ne)
+	}
+}
+
+func TestDo(t *testing.T) {
+	var wantLine string
+	pe := recovered(t, func() {
+		wantLine = nextLine()
+		must.Do(os.Remove(filepath.Join(t.TempDir(), "missing")))
+	})
+	if pe.Location != wantLine || !errors.Is(pe, fs.ErrNotExist) {
+		t.Errorf("Do panicked with %v at %s, want not-exist at %s", pe, pe.Location, wantLine)
+	}
+	if !strings.HasPrefix(pe.Error(), "must: patterns/idioms/must_test.TestDo.func1 (") {
+		t.Errorf("message = %q", pe.Error())
+	}
+}
+
+// TestWrapper checks that a Must* wrapper defined in another package is
+// the one the message names: the caller of Must, not of the wrapper.
+func TestWrapper(t *testing.T) {
+	pe := recovered(t, func() { functional.MustNewServer("localhost", functional.WithPort(-1)) })
+	if want := "patterns/options/functional.MustNewServer"; pe.Caller != want {
+		t.Errorf("Caller = %q, want %q", pe.Caller, want)
+	}
+	if !strings.HasPrefix(pe.Location, "functional.go:") || !strings.Contains(pe.Error(), "port cannot be negative") {
+		t.Errorf("panic = %v", pe)
+	}
+}
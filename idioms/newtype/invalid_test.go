@@ -0,0 +1,14 @@
+package newtype
+
+import (
+	"testing"
+
+	"patterns/testing/buildfail"
+)
+
+func TestTranspositionInvalid(t *testing.T) {
+	buildfail.Check(t, "newtype_invalid", ".",
+		"as UserID value in argument to CancelOrder",
+		"as OrderID value in argument to CancelOrder",
+	)
+}
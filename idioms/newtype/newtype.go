@@ -0,0 +1,118 @@
+// Package newtype wraps primitive identifiers in defined types so that a
+// UserID can never be passed where an OrderID is expected.
+package newtype
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// UserID identifies a user. The zero value is invalid.
+type UserID string
+
+// ParseUserID validates s and returns it as a UserID.
+func ParseUserID(s string) (UserID, error) {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "u_") || len(s) == len("u_") {
+		return "", fmt.Errorf("invalid user id %q: want u_<id>", s)
+	}
+
+	return UserID(s), nil
+}
+
+func (id UserID) String() string { return string(id) }
+
+func (id UserID) MarshalJSON() ([]byte, error) {
+	return json.Marshal(string(id))
+}
+
+func (id *UserID) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	parsed, err := ParseUserID(s)
+	if err != nil {
+		return err
+	}
+	*id = parsed
+	return nil
+}
+
+// Value implements driver.Valuer.
+func (id UserID) Value() (driver.Value, error) {
+	return string(id), nil
+}
+
+// Scan implements sql.Scanner.
+func (id *UserID) Scan(src any) error {
+	var s string
+	switch v := src.(type) {
+	case string:
+		s = v
+	case []byte:
+		s = string(v)
+	default:
+		return fmt.Errorf("cannot scan %T into UserID", src)
+	}
+	parsed, err := ParseUserID(s)
+	if err != nil {
+		return err
+	}
+	*id = parsed
+	return nil
+}
+
+// OrderID identifies an order. Order ids are positive.
+type OrderID int64
+
+// NewOrderID validates n and returns it as an OrderID.
+func NewOrderID(n int64) (OrderID, error) {
+	if n <= 0 {
+		return 0, errors.New("order id must be positive")
+	}
+
+	return OrderID(n), nil
+}
+
+func (id OrderID) String() string { return "order-" + strconv.FormatInt(int64(id), 10) }
+
+func (id OrderID) MarshalJSON() ([]byte, error) {
+	return json.Marshal(int64(id))
+}
+
+func (id *OrderID) UnmarshalJSON(b []byte) error {
+	var n int64
+	if err := json.Unmarshal(b, &n); err != nil {
+		return err
+	}
+	parsed, err := NewOrderID(n)
+	if err != nil {
+		return err
+	}
+	*id = parsed
+	return nil
+}
+
+// Value implements driver.Valuer.
+func (id OrderID) Value() (driver.Value, error) {
+	return int64(id), nil
+}
+
+// Scan implements sql.Scanner.
+func (id *OrderID) Scan(src any) error {
+	n, ok := src.(int64)
+	if !ok {
+		return fmt.Errorf("cannot scan %T into OrderID", src)
+	}
+	parsed, err := NewOrderID(n)
+	if err != nil {
+		return err
+	}
+	*id = parsed
+	return nil
+}
@@ -0,0 +1,17 @@
+package newtype
+
+import "fmt"
+
+// primitive pattern
+// Level: Poor
+// cons: both parameters are strings, so swapping them compiles and fails at runtime (or worse, succeeds).
+func CancelOrderPrimitive(userID, orderID string) string {
+	return fmt.Sprintf("user %s cancelled %s", userID, orderID)
+}
+
+// newtype pattern
+// Level: Good
+// pros: CancelOrder(order, user) does not compile, see transposition_invalid.go
+func CancelOrder(user UserID, order OrderID) string {
+	return fmt.Sprintf("user %s cancelled %s", user, order)
+}
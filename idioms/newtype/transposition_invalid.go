@@ -0,0 +1,15 @@
+//go:build newtype_invalid
+
+// Build with -tags newtype_invalid to see the transposition rejected.
+package newtype
+
+func transposed() {
+	user, _ := ParseUserID("u_42")
+	order, _ := NewOrderID(7)
+
+	// compiles, wrong at runtime
+	_ = CancelOrderPrimitive("7", "u_42")
+
+	// cannot use order (OrderID) as UserID value in argument to CancelOrder
+	_ = CancelOrder(order, user)
+}
@@ -0,0 +1,217 @@
+package optionaliface_test
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	oi "patterns/idioms/optionaliface"
+)
+
+// base records which of its methods were called. The builder is a named
+// field: embedded, its WriteString would be promoted to every base.
+type base struct {
+	buf   strings.Builder
+	calls []string
+}
+
+func (b *base) Write(p []byte) (int, error) {
+	b.calls = append(b.calls, "Write")
+	return b.buf.Write(p)
+}
+
+type (
+	baseReadFrom    struct{ b *base }
+	baseWriteString struct{ b *base }
+	baseFlush       struct{ b *base }
+)
+
+func (r baseReadFrom) ReadFrom(src io.Reader) (int64, error) {
+	r.b.calls = append(r.b.calls, "ReadFrom")
+	return io.Copy(&r.b.buf, src)
+}
+
+func (s baseWriteString) WriteString(str string) (int, error) {
+	s.b.calls = append(s.b.calls, "WriteString")
+	return s.b.buf.WriteString(str)
+}
+
+func (f baseFlush) Flush() error {
+	f.b.calls = append(f.b.calls, "Flush")
+	return nil
+}
+
+// newBase returns a writer with exactly caps and the base behind it.
+func newBase(caps oi.Caps) (io.Writer, *base) {
+	b := &base{}
+	var w io.Writer = b
+	var rf io.ReaderFrom = baseReadFrom{b}
+	var ws io.StringWriter = baseWriteString{b}
+	var fl oi.Flusher = baseFlush{b}
+	switch caps {
+	case oi.CanReadFrom:
+		w = struct {
+			io.Writer
+			io.ReaderFrom
+		}{b, rf}
+	case oi.CanWriteString:
+		w = struct {
+			io.Writer
+			io.StringWriter
+		}{b, ws}
+	case oi.CanFlush:
+		w = struct {
+			io.Writer
+			oi.Flusher
+		}{b, fl}
+	case oi.CanReadFrom | oi.CanWriteString:
+		w = struct {
+			io.Writer
+			io.ReaderFrom
+			io.StringWriter
+		}{b, rf, ws}
+	case oi.CanReadFrom | oi.CanFlush:
+		w = struct {
+			io.Writer
+			io.ReaderFrom
+			oi.Flusher
+		}{b, rf, fl}
+	case oi.CanWriteString | oi.CanFlush:
+		w = struct {
+			io.Writer
+			io.StringWriter
+			oi.Flusher
+		}{b, ws, fl}
+	case oi.AllCaps:
+		w = struct {
+			io.Writer
+			io.ReaderFrom
+			io.StringWriter
+			oi.Flusher
+		}{b, rf, ws, fl}
+	}
+	return w, b
+}
+
+// exercise uses w the way a caller detecting its capabilities would and
+// returns how many bytes it wrote.
+func exercise(w io.Writer) int64 {
+	n := int64(0)
+	m, _ := w.Write([]byte("head "))
+	n += int64(m)
+	// io.Copy uses ReadFrom if w has it; the bare io.Reader hides
+	// strings.Reader's WriterTo, which io.Copy would try first
+	c, _ := io.Copy(w, struct{ io.Reader }{strings.NewReader("body ")})
+	n += c
+	m, _ = io.WriteString(w, "tail")
+	n += int64(m)
+	if f, ok := w.(oi.Flusher); ok {
+		f.Flush()
+	}
+	return n
+}
+
+// want is the calls a base with caps gets from exercise.
+func want(caps oi.Caps) string {
+	calls := []string{"Write"}
+	if caps&oi.CanReadFrom != 0 {
+		calls = append(calls, "ReadFrom")
+	} else {
+		calls = append(calls, "Write")
+	}
+	if caps&oi.CanWriteString != 0 {
+		calls = append(calls, "WriteString")
+	} else {
+		calls = append(calls, "Write")
+	}
+	if caps&oi.CanFlush != 0 {
+		calls = append(calls, "Flush")
+	}
+	return strings.Join(calls, ",")
+}
+
+var cases []struct {
+	name string
+	test func(t *testing.T)
+}
+
+func add(name string, test func(t *testing.T)) {
+	cases = append(cases, struct {
+		name string
+		test func(t *testing.T)
+	}{name, test})
+}
+
+func init() {
+	for caps := range oi.AllCaps + 1 {
+		add("Count keeps "+caps.String(), func(t *testing.T) {
+			w, b := newBase(caps)
+			if got := oi.CapsOf(w); got != caps {
+				t.Fatalf("base has %v", got)
+			}
+			cw, c := oi.Count(w)
+			if got := oi.CapsOf(cw); got != caps {
+				t.Fatalf("wrapped has %v, want %v", got, caps)
+			}
+			n := exercise(cw)
+			if got, w := strings.Join(b.calls, ","), want(caps); got != w {
+				t.Fatalf("base got calls %s, want %s", got, w)
+			}
+			if b.buf.String() != "head body tail" || c.N() != n || n != 14 {
+				t.Fatalf("base has %q, counted %d of %d bytes", b.buf.String(), c.N(), n)
+			}
+		})
+		add("Count twice keeps "+caps.String(), func(t *testing.T) {
+			w, b := newBase(caps)
+			inner, c1 := oi.Count(w)
+			outer, c2 := oi.Count(inner)
+			if got := oi.CapsOf(outer); got != caps {
+				t.Fatalf("wrapped twice has %v, want %v", got, caps)
+			}
+			exercise(outer)
+			if got, w := strings.Join(b.calls, ","), want(caps); got != w {
+				t.Fatalf("base got calls %s, want %s", got, w)
+			}
+			if c1.N() != 14 || c2.N() != 14 {
+				t.Fatalf("counted %d inside and %d outside, want 14", c1.N(), c2.N())
+			}
+		})
+	}
+	add("NaiveCounter hides every capability", func(t *testing.T) {
+		w, b := newBase(oi.AllCaps)
+		nc := &oi.NaiveCounter{Writer: w}
+		if got := oi.CapsOf(nc); got != 0 {
+			t.Fatalf("naive wrapper has %v", got)
+		}
+		exercise(nc)
+		if got := strings.Join(b.calls, ","); got != "Write,Write,Write" {
+			t.Fatalf("base got calls %s", got)
+		}
+		if err := oi.Flush(nc); err != oi.ErrNotSupported {
+			t.Fatalf("Flush through the naive wrapper: %v", err)
+		}
+	})
+	add("Flush finds a Flusher by Unwrap", func(t *testing.T) {
+		w, b := newBase(oi.CanFlush)
+		// a Counter on its own, as a wrapper that dropped Flusher would be
+		_, c := oi.Count(w)
+		if _, ok := io.Writer(c).(oi.Flusher); ok {
+			t.Fatalf("bare Counter is a Flusher")
+		}
+		if err := oi.Flush(c); err != nil || strings.Join(b.calls, ",") != "Flush" {
+			t.Fatalf("Flush: %v, base got calls %v", err, b.calls)
+		}
+		if err := oi.Flush(&strings.Builder{}); err != oi.ErrNotSupported {
+			t.Fatalf("Flush of a writer that cannot: %v", err)
+		}
+	})
+}
+
+// TestCombinations wraps a writer of each combination of the three
+// capabilities with Count, once and twice, and checks the wrapper keeps
+// them, reaches the writer's own methods and counts every byte.
+func TestCombinations(t *testing.T) {
+	for _, c := range cases {
+		t.Run(c.name, c.test)
+	}
+}
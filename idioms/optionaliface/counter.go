@@ -0,0 +1,146 @@
+package optionaliface
+
+import (
+	"errors"
+	"io"
+)
+
+// ErrNotSupported is returned by Flush when no writer in the chain can.
+var ErrNotSupported = errors.New("optionaliface: not supported")
+
+// capability-preserving decorator
+// Level: Good
+// pros: the wrapped writer has exactly the capabilities of the original,
+// so callers that detect them keep their fast paths, and every byte,
+// however it is written, still passes through the decorator.
+// cons: a type per combination, 2^n for n capabilities; a capability
+// added to the list is lost until the switch learns it.
+//
+// Counter counts the bytes written through it. It is not safe for
+// concurrent use.
+type Counter struct {
+	w io.Writer
+	n int64
+}
+
+// Count returns a writer to use in place of w and the Counter behind it.
+// The returned writer implements io.ReaderFrom, io.StringWriter and
+// Flusher if and only if w does, and counts what goes through each.
+func Count(w io.Writer) (io.Writer, *Counter) {
+	c := &Counter{w: w}
+	rf, ws, fl := readerFrom{c}, stringWriter{c}, flusher{c}
+	switch CapsOf(w) {
+	case CanReadFrom:
+		return struct {
+			*Counter
+			io.ReaderFrom
+		}{c, rf}, c
+	case CanWriteString:
+		return struct {
+			*Counter
+			io.StringWriter
+		}{c, ws}, c
+	case CanFlush:
+		return struct {
+			*Counter
+			Flusher
+		}{c, fl}, c
+	case CanReadFrom | CanWriteString:
+		return struct {
+			*Counter
+			io.ReaderFrom
+			io.StringWriter
+		}{c, rf, ws}, c
+	case CanReadFrom | CanFlush:
+		return struct {
+			*Counter
+			io.ReaderFrom
+			Flusher
+		}{c, rf, fl}, c
+	case CanWriteString | CanFlush:
+		return struct {
+			*Counter
+			io.StringWriter
+			Flusher
+		}{c, ws, fl}, c
+	case AllCaps:
+		return struct {
+			*Counter
+			io.ReaderFrom
+			io.StringWriter
+			Flusher
+		}{c, rf, ws, fl}, c
+	default:
+		return c, c
+	}
+}
+
+// N returns the bytes written so far.
+func (c *Counter) N() int64 { return c.n }
+
+func (c *Counter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// Unwrap returns the writer c wraps, for Flush.
+func (c *Counter) Unwrap() io.Writer { return c.w }
+
+// The capabilities, kept off Counter itself so that Count can choose
+// which ones its result has.
+type (
+	readerFrom   struct{ c *Counter }
+	stringWriter struct{ c *Counter }
+	flusher      struct{ c *Counter }
+)
+
+func (r readerFrom) ReadFrom(src io.Reader) (int64, error) {
+	n, err := r.c.w.(io.ReaderFrom).ReadFrom(src)
+	r.c.n += n
+	return n, err
+}
+
+func (s stringWriter) WriteString(str string) (int, error) {
+	n, err := s.c.w.(io.StringWriter).WriteString(str)
+	s.c.n += int64(n)
+	return n, err
+}
+
+func (f flusher) Flush() error { return f.c.w.(Flusher).Flush() }
+
+// capability-hiding decorator
+// Level: Poor
+// pros: three lines.
+// cons: embedding io.Writer gives the wrapper Write and nothing else:
+// io.Copy through it loses ReadFrom and buffers everything itself, and a
+// caller asking for Flusher finds none, so buffered data stays put.
+//
+// NaiveCounter counts the bytes written through Writer.
+type NaiveCounter struct {
+	io.Writer
+	N int64
+}
+
+func (c *NaiveCounter) Write(p []byte) (int, error) {
+	n, err := c.Writer.Write(p)
+	c.N += int64(n)
+	return n, err
+}
+
+// Flush flushes the first writer in the chain starting at w that is a
+// Flusher, following Unwrap() io.Writer through wrappers that are not.
+// It suits capabilities that carry no data: reaching under a wrapper for
+// ReadFrom would write past it, uncounted.
+func Flush(w io.Writer) error {
+	for {
+		if f, ok := w.(Flusher); ok {
+			return f.Flush()
+		}
+		u, ok := w.(interface{ Unwrap() io.Writer })
+		if !ok {
+			return ErrNotSupported
+		}
+		w = u.Unwrap()
+	}
+}
@@ -0,0 +1,78 @@
+// Package optionaliface shows the standard library's way of growing an
+// interface without breaking its implementations: keep the core small,
+// io.Writer here, and offer extra capabilities as separate interfaces
+// that callers detect with a type assertion, as io.Copy does with
+// io.ReaderFrom and an HTTP handler with http.Flusher:
+//
+//	if f, ok := w.(Flusher); ok {
+//		f.Flush()
+//	}
+//
+// The cost shows up in decorators. A wrapper that embeds the core
+// interface has only the core's methods, so wrapping a writer silently
+// removes its capabilities: io.Copy falls back to its buffer loop, and a
+// Flush that used to happen no longer does. NaiveCounter has that bug.
+// Count fixes it by returning a type with exactly the capabilities of the
+// writer it wraps, one small struct per combination. Counter also has
+// Unwrap, which lets the helper Flush, like http.ResponseController, find
+// a capability under wrappers that did not keep theirs.
+// web/responserecorder does the same for http.ResponseWriter.
+//
+// The tests wrap a writer with every combination of the three
+// capabilities and check that each one survives the wrapping, once and
+// twice, and still goes through the counter.
+package optionaliface
+
+import (
+	"io"
+	"strings"
+)
+
+// Flusher is implemented by writers that buffer, to write out what they
+// hold.
+type Flusher interface {
+	Flush() error
+}
+
+// Caps is a set of optional capabilities of an io.Writer.
+type Caps uint8
+
+const (
+	CanReadFrom    Caps = 1 << iota // io.ReaderFrom
+	CanWriteString                  // io.StringWriter
+	CanFlush                        // Flusher
+
+	AllCaps = CanReadFrom | CanWriteString | CanFlush
+)
+
+// CapsOf reports the optional interfaces w implements.
+func CapsOf(w io.Writer) Caps {
+	var c Caps
+	if _, ok := w.(io.ReaderFrom); ok {
+		c |= CanReadFrom
+	}
+	if _, ok := w.(io.StringWriter); ok {
+		c |= CanWriteString
+	}
+	if _, ok := w.(Flusher); ok {
+		c |= CanFlush
+	}
+	return c
+}
+
+// String lists the capabilities, "ReadFrom|WriteString", or "none".
+func (c Caps) String() string {
+	var names []string
+	for _, n := range []struct {
+		c    Caps
+		name string
+	}{{CanReadFrom, "ReadFrom"}, {CanWriteString, "WriteString"}, {CanFlush, "Flush"}} {
+		if c&n.c != 0 {
+			names = append(names, n.name)
+		}
+	}
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, "|")
+}
@@ -0,0 +1,101 @@
+package panicpolicy
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func input(n int) string {
+	var b strings.Builder
+	for y := range n {
+		for x := range n {
+			if x > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(strconv.Itoa(x * y % 10))
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+var sink int
+
+var errSink error
+
+// grids parses the same 100x100 grid with each policy.
+func grids(b *testing.B) (*ErrGrid, *Grid) {
+	src := input(100)
+	eg, err := ParseErrGrid(src)
+	if err != nil {
+		b.Fatal(err)
+	}
+	pg, err := ParseGrid(src)
+	if err != nil {
+		b.Fatal(err)
+	}
+	return eg, pg
+}
+
+// The two policies compare on single lookups, on the Sum hot loop and on
+// the failure path, plus the cost of a Boundary that does not panic.
+
+func BenchmarkAt(b *testing.B) {
+	eg, pg := grids(b)
+	b.Run("errors", func(b *testing.B) {
+		for i := range b.N {
+			v, err := eg.At(i%100, i/100%100)
+			if err != nil {
+				b.Fatal(err)
+			}
+			sink = v
+		}
+	})
+	b.Run("panics", func(b *testing.B) {
+		for i := range b.N {
+			sink = pg.At(i%100, i/100%100)
+		}
+	})
+}
+
+func BenchmarkSum(b *testing.B) {
+	eg, pg := grids(b)
+	b.Run("errors", func(b *testing.B) {
+		for range b.N {
+			v, err := eg.Sum()
+			if err != nil {
+				b.Fatal(err)
+			}
+			sink = v
+		}
+	})
+	b.Run("panics", func(b *testing.B) {
+		for range b.N {
+			sink = pg.Sum()
+		}
+	})
+}
+
+func BenchmarkBoundary(b *testing.B) {
+	b.Run("no-panic", func(b *testing.B) {
+		for range b.N {
+			errSink = Boundary(func() error { return nil })
+		}
+	})
+}
+
+func BenchmarkFailure(b *testing.B) {
+	errFailed := errors.New("failed")
+	b.Run("error", func(b *testing.B) {
+		for range b.N {
+			errSink = func() error { return errFailed }()
+		}
+	})
+	b.Run("panic", func(b *testing.B) {
+		for range b.N {
+			errSink = Boundary(func() error { panic(errFailed) })
+		}
+	})
+}
@@ -0,0 +1,48 @@
+package panicpolicy
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+// PanicError is a recovered panic turned into an error by Boundary.
+type PanicError struct {
+	Value any
+	Stack []byte
+}
+
+func (e *PanicError) Error() string { return fmt.Sprintf("panic: %v", e.Value) }
+
+// Unwrap returns the panic value if it was an error.
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// Boundary runs f and converts a panic into a *PanicError, so one bad
+// request or job fails alone. Use it where work enters the program (an
+// HTTP handler, a worker loop), not around individual calls: recovering
+// deep inside hides the bug from the code that could report it.
+func Boundary(f func() error) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = &PanicError{Value: v, Stack: debug.Stack()}
+		}
+	}()
+	return f()
+}
+
+// Request is what a request handler at the boundary looks like: the input
+// errors come back from ParseGrid, bugs come back as *PanicError.
+func Request(input string, x, y int) (int, error) {
+	var v int
+	err := Boundary(func() error {
+		g, err := ParseGrid(input)
+		if err != nil {
+			return err
+		}
+		v = g.At(x, y)
+		return nil
+	})
+	return v, err
+}
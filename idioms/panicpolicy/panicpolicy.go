@@ -0,0 +1,184 @@
+// Package panicpolicy implements the same small API twice to compare the
+// two error policies:
+//
+//   - ErrGrid returns an error from every call that can fail, including
+//     calls that can only fail through caller bugs.
+//   - Grid returns errors only for failures the caller cannot rule out
+//     (parsing input), and panics on misuse the caller could have avoided
+//     (an index out of range, mismatched sizes), like slice indexing does.
+//
+// Both expose Parse, At, Set and Add. Boundary shows where the panics of
+// the second policy are turned back into errors: once, at the edge of a
+// request or job, not around every call.
+//
+// findings (see bench_test.go; go test -bench . -benchmem
+// patterns/idioms/panicpolicy):
+//   - on the success path the two policies are equal within noise, for
+//     single lookups and for the Sum hot loop: the extra error result and
+//     its nil check are nearly free. The choice is about what the API
+//     tells callers, not speed.
+//   - a deferred recover at a Boundary costs a few ns when nothing
+//     panics, so one per request or job is free.
+//   - an actual panic, recover and stack capture costs about 10us and
+//     allocates, thousands of times a returned error, which is why panics
+//     are for bugs only and never for expected failures.
+package panicpolicy
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrOutOfRange is returned by ErrGrid for an index outside the grid.
+var ErrOutOfRange = errors.New("index out of range")
+
+// ErrSize is returned by ErrGrid.Add for grids of different sizes.
+var ErrSize = errors.New("grid sizes differ")
+
+// parse reads rows of comma-separated integers; shared by both policies
+// since bad input is an expected failure either way.
+func parse(s string) (w, h int, cells []int, err error) {
+	rows := strings.Split(strings.TrimSpace(s), "\n")
+	for y, row := range rows {
+		fields := strings.Split(row, ",")
+		if y == 0 {
+			w = len(fields)
+		} else if len(fields) != w {
+			return 0, 0, nil, fmt.Errorf("row %d: %d cells, want %d", y+1, len(fields), w)
+		}
+		for x, f := range fields {
+			n, err := strconv.Atoi(strings.TrimSpace(f))
+			if err != nil {
+				return 0, 0, nil, fmt.Errorf("row %d, cell %d: %w", y+1, x+1, err)
+			}
+			cells = append(cells, n)
+		}
+	}
+
+	return w, len(rows), cells, nil
+}
+
+// error policy
+// Level: Poor
+// pros:
+//   - nothing panics, so no caller ever needs a recover
+// cons:
+//   - every call site handles an error that only a bug can produce, and
+//     usually just propagates it, so the bug surfaces far from its cause
+//   - the hot path pays for the checks twice, in the callee and the caller
+
+type ErrGrid struct {
+	w, h  int
+	cells []int
+}
+
+func ParseErrGrid(s string) (*ErrGrid, error) {
+	w, h, cells, err := parse(s)
+	if err != nil {
+		return nil, err
+	}
+	return &ErrGrid{w: w, h: h, cells: cells}, nil
+}
+
+func (g *ErrGrid) Size() (w, h int) { return g.w, g.h }
+
+func (g *ErrGrid) At(x, y int) (int, error) {
+	if x < 0 || x >= g.w || y < 0 || y >= g.h {
+		return 0, fmt.Errorf("at (%d, %d): %w", x, y, ErrOutOfRange)
+	}
+	return g.cells[y*g.w+x], nil
+}
+
+func (g *ErrGrid) Set(x, y, v int) error {
+	if x < 0 || x >= g.w || y < 0 || y >= g.h {
+		return fmt.Errorf("set (%d, %d): %w", x, y, ErrOutOfRange)
+	}
+	g.cells[y*g.w+x] = v
+	return nil
+}
+
+func (g *ErrGrid) Add(o *ErrGrid) error {
+	if g.w != o.w || g.h != o.h {
+		return ErrSize
+	}
+	for i := range g.cells {
+		g.cells[i] += o.cells[i]
+	}
+	return nil
+}
+
+// Sum is what a caller of the error policy ends up writing.
+func (g *ErrGrid) Sum() (int, error) {
+	total := 0
+	for y := range g.h {
+		for x := range g.w {
+			v, err := g.At(x, y)
+			if err != nil {
+				return 0, err
+			}
+			total += v
+		}
+	}
+	return total, nil
+}
+
+// panic-on-misuse policy
+// Level: Good
+// pros:
+//   - the API only returns errors a correct caller must handle
+//   - a bug panics at the faulty call with a stack trace
+// cons:
+//   - a bug in one request can take down the process unless a Boundary
+//     recovers it
+
+type Grid struct {
+	w, h  int
+	cells []int
+}
+
+func ParseGrid(s string) (*Grid, error) {
+	w, h, cells, err := parse(s)
+	if err != nil {
+		return nil, err
+	}
+	return &Grid{w: w, h: h, cells: cells}, nil
+}
+
+func (g *Grid) Size() (w, h int) { return g.w, g.h }
+
+func (g *Grid) index(x, y int) int {
+	if x < 0 || x >= g.w || y < 0 || y >= g.h {
+		g.outOfRange(x, y)
+	}
+	return y*g.w + x
+}
+
+// outOfRange is kept out of index so that index and At stay inlinable.
+func (g *Grid) outOfRange(x, y int) {
+	panic(fmt.Sprintf("panicpolicy: index (%d, %d) out of range [%d x %d]", x, y, g.w, g.h))
+}
+
+func (g *Grid) At(x, y int) int { return g.cells[g.index(x, y)] }
+
+func (g *Grid) Set(x, y, v int) { g.cells[g.index(x, y)] = v }
+
+func (g *Grid) Add(o *Grid) {
+	if g.w != o.w || g.h != o.h {
+		panic(fmt.Sprintf("panicpolicy: Add of %dx%d grid to %dx%d grid", o.w, o.h, g.w, g.h))
+	}
+	for i := range g.cells {
+		g.cells[i] += o.cells[i]
+	}
+}
+
+func (g *Grid) Sum() int {
+	total := 0
+	for y := range g.h {
+		for x := range g.w {
+			total += g.At(x, y)
+		}
+	}
+	return total
+}
@@ -0,0 +1,15 @@
+//go:build phantom_invalid
+
+// This file does not compile; build with -tags phantom_invalid to see the
+// errors the phantom types produce.
+package phantom
+
+func invalidUses() {
+	// cannot use NewConfig(...) (Config[Unvalidated]) as Config[Validated]
+	_ = Addr(NewConfig("localhost", 8080))
+
+	cfg, _ := Validate(NewConfig("localhost", 8080))
+	closed := Close(Dial(cfg))
+	// cannot use closed (Conn[Closed]) as Conn[Open]
+	_ = Send(closed, "hello")
+}
@@ -0,0 +1,14 @@
+package phantom
+
+import (
+	"testing"
+
+	"patterns/testing/buildfail"
+)
+
+func TestInvalid(t *testing.T) {
+	buildfail.Check(t, "phantom_invalid", ".",
+		"as Config[Validated] value in argument to Addr",
+		"as Conn[Open] value in argument to Send",
+	)
+}
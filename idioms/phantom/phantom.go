@@ -0,0 +1,83 @@
+// Package phantom encodes state in a type parameter that is never stored
+// (a phantom type), so operations that are only valid in one state are
+// only defined for that state and misuse fails to compile.
+//
+// See invalid.go (build tag phantom_invalid) for code the compiler rejects.
+package phantom
+
+import (
+	"errors"
+	"net"
+	"strconv"
+)
+
+// Unvalidated and Validated are marker types for Config.
+type (
+	Unvalidated struct{}
+	Validated   struct{}
+)
+
+// Config carries its validation state in S.
+// note: Config[Validated](c) is still a legal conversion because both
+// instantiations share an underlying type; the guarantee is against
+// accidents, not against deliberate bypass.
+type Config[S any] struct {
+	Host string
+	Port int
+}
+
+// NewConfig returns an unvalidated config.
+func NewConfig(host string, port int) Config[Unvalidated] {
+	return Config[Unvalidated]{Host: host, Port: port}
+}
+
+// Validate is the only way to obtain a Config[Validated].
+func Validate(c Config[Unvalidated]) (Config[Validated], error) {
+	if c.Host == "" {
+		return Config[Validated]{}, errors.New("host is required")
+	}
+	if c.Port < 0 || c.Port > 65535 {
+		return Config[Validated]{}, errors.New("port out of range")
+	}
+
+	return Config[Validated]{Host: c.Host, Port: c.Port}, nil
+}
+
+// Addr is only meaningful once the config has been validated.
+func Addr(c Config[Validated]) string {
+	return net.JoinHostPort(c.Host, strconv.Itoa(c.Port))
+}
+
+// Open and Closed are marker types for Conn.
+type (
+	Open   struct{}
+	Closed struct{}
+)
+
+// Conn is a connection whose state is tracked in S.
+type Conn[S any] struct {
+	addr string
+	sent []string
+}
+
+// Dial returns an open connection.
+func Dial(c Config[Validated]) Conn[Open] {
+	return Conn[Open]{addr: Addr(c)}
+}
+
+// Send is defined on open connections only.
+func Send(c Conn[Open], msg string) Conn[Open] {
+	c.sent = append(c.sent, msg)
+	return c
+}
+
+// Close consumes an open connection and returns a closed one, which has
+// no Send.
+func Close(c Conn[Open]) Conn[Closed] {
+	return Conn[Closed]{addr: c.addr, sent: c.sent}
+}
+
+// Sent reports the messages sent, in any state.
+func Sent[S any](c Conn[S]) []string {
+	return c.sent
+}
@@ -0,0 +1,232 @@
+package progressive
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"time"
+
+	"patterns/construct"
+	"patterns/funcopts"
+)
+
+// The defaults of New, and so of Do.
+const (
+	DefaultTimeout   = 30 * time.Second
+	DefaultRetries   = 2
+	DefaultBackoff   = 100 * time.Millisecond
+	DefaultMaxBody   = 10 << 20
+	DefaultUserAgent = "patterns-progressive/1"
+)
+
+var ErrTooLarge = errors.New("progressive: response body too large")
+
+// StatusError is the failure of Get on a response that is not 2xx.
+type StatusError struct {
+	Method, URL string
+	Code        int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s %s: %d %s", e.Method, e.URL, e.Code, http.StatusText(e.Code))
+}
+
+type options struct {
+	http    *http.Client
+	timeout time.Duration
+	retries int
+	backoff time.Duration
+	maxBody int64
+	header  http.Header
+}
+
+type Option = funcopts.Option[options]
+
+func (o *options) SetDefaults() {
+	o.http = http.DefaultClient
+	o.timeout = DefaultTimeout
+	o.retries = DefaultRetries
+	o.backoff = DefaultBackoff
+	o.maxBody = DefaultMaxBody
+	o.header = http.Header{"User-Agent": {DefaultUserAgent}}
+}
+
+// WithHTTPClient sends requests with c, for its transport, cookies or
+// redirect policy; its own Timeout still applies.
+func WithHTTPClient(c *http.Client) Option {
+	return func(o *options) error {
+		if c == nil {
+			return errors.New("http client cannot be nil")
+		}
+		o.http = c
+		return nil
+	}
+}
+
+// WithTimeout bounds a whole call, retries included; 0 leaves only the
+// caller's context.
+func WithTimeout(d time.Duration) Option {
+	return func(o *options) error {
+		if d < 0 {
+			return errors.New("timeout cannot be negative")
+		}
+		o.timeout = d
+		return nil
+	}
+}
+
+// WithRetries retries GET and HEAD up to n more times on a transport
+// error or a 502, 503 or 504, waiting backoff, doubled each time, between
+// attempts.
+func WithRetries(n int, backoff time.Duration) Option {
+	return func(o *options) error {
+		if n < 0 || backoff < 0 {
+			return errors.New("retries and backoff cannot be negative")
+		}
+		o.retries = n
+		o.backoff = backoff
+		return nil
+	}
+}
+
+// WithMaxBody fails responses with bodies over n bytes with ErrTooLarge.
+func WithMaxBody(n int64) Option {
+	return func(o *options) error {
+		if n < 0 {
+			return errors.New("max body cannot be negative")
+		}
+		o.maxBody = n
+		return nil
+	}
+}
+
+// WithHeader sends key: value on every request that does not set key
+// itself; an empty value removes a default, such as the User-Agent.
+func WithHeader(key, value string) Option {
+	return func(o *options) error {
+		if value == "" {
+			o.header.Del(key)
+			return nil
+		}
+		o.header.Set(key, value)
+		return nil
+	}
+}
+
+// Response is a whole response, read and closed.
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+// Client is the configurable layer; Do is one with the defaults. A Client
+// is safe for concurrent use.
+type Client struct {
+	http    *http.Client
+	timeout time.Duration
+	retries int
+	backoff time.Duration
+	maxBody int64
+	header  http.Header
+}
+
+// New returns a client with the defaults above, changed by opts.
+func New(opts ...Option) (*Client, error) {
+	o, err := construct.New(opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{
+		http:    o.http,
+		timeout: o.timeout,
+		retries: o.retries,
+		backoff: o.backoff,
+		maxBody: o.maxBody,
+		header:  o.header,
+	}, nil
+}
+
+// Get fetches url and returns the body of a 2xx response; other statuses
+// fail with a *StatusError. It is Do on a GET request plus that check.
+func (c *Client) Get(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		return nil, &StatusError{Method: req.Method, URL: url, Code: resp.StatusCode}
+	}
+	return resp.Body, nil
+}
+
+// Do sends req with the client's headers, timeout and retries and reads
+// the whole body; any status is a Response, not an error.
+func (c *Client) Do(req *http.Request) (*Response, error) {
+	ctx := req.Context()
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+	req = req.Clone(ctx)
+	for key, values := range c.header {
+		if _, ok := req.Header[key]; !ok {
+			req.Header[key] = slices.Clone(values)
+		}
+	}
+
+	attempts := 1
+	if (req.Method == http.MethodGet || req.Method == http.MethodHead) && (req.Body == nil || req.Body == http.NoBody) {
+		attempts += c.retries
+	}
+	wait := c.backoff
+	for attempt := 1; ; attempt++ {
+		resp, err := c.once(req)
+		if attempt == attempts || ctx.Err() != nil || !retryable(resp, err) {
+			return resp, err
+		}
+		t := time.NewTimer(wait)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return resp, err
+		}
+		wait *= 2
+	}
+}
+
+func (c *Client) once(req *http.Request) (*Response, error) {
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, c.maxBody+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > c.maxBody {
+		return nil, fmt.Errorf("%s %s: %w", req.Method, req.URL, ErrTooLarge)
+	}
+	return &Response{StatusCode: resp.StatusCode, Header: resp.Header, Body: body}, nil
+}
+
+func retryable(resp *Response, err error) bool {
+	if err != nil {
+		return !errors.Is(err, ErrTooLarge)
+	}
+	switch resp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
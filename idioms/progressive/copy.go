@@ -0,0 +1,29 @@
+package progressive
+
+import (
+	"context"
+	"io"
+	"net/http"
+)
+
+// parallel convenience function
+// Level: Poor
+// pros: reads as the obvious few lines; no options to learn.
+// cons: a second implementation of the client, so it agrees with it only
+// until one of them changes: this one has no timeout but the caller's,
+// no body cap, no retries and no User-Agent, and returns the body of a
+// 404 as success.
+//
+// DoCopy is Do written out beside the client instead of on it.
+func DoCopy(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return io.ReadAll(resp.Body)
+}
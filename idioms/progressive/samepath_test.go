@@ -0,0 +1,190 @@
+package progressive_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"patterns/idioms/must"
+	"patterns/idioms/progressive"
+)
+
+// server records the requests it sees and fails /flaky once.
+type server struct {
+	mu    sync.Mutex
+	seen  []string
+	flaky int
+}
+
+func (s *server) reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seen, s.flaky = nil, 0
+}
+
+func (s *server) requests() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return strings.Join(s.seen, "; ")
+}
+
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.seen = append(s.seen, fmt.Sprintf("%s %s ua=%q", r.Method, r.URL.Path, r.UserAgent()))
+	s.flaky++
+	first := s.flaky == 1
+	s.mu.Unlock()
+	switch r.URL.Path {
+	case "/ok":
+		fmt.Fprint(w, "hello")
+	case "/missing":
+		http.Error(w, "nope", http.StatusNotFound)
+	case "/flaky":
+		if first {
+			http.Error(w, "busy", http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, "recovered")
+	case "/down":
+		http.Error(w, "down", http.StatusServiceUnavailable)
+	case "/big":
+		w.Write(make([]byte, progressive.DefaultMaxBody+1))
+	case "/slow":
+		select {
+		case <-r.Context().Done():
+		case <-time.After(2 * time.Second):
+		}
+	}
+}
+
+// path is one way of fetching a URL, reduced to what a caller sees.
+type path struct {
+	name  string
+	fetch func(ctx context.Context, url string) string
+}
+
+func outcome(body []byte, err error) string {
+	if err != nil {
+		var se *progressive.StatusError
+		if errors.As(err, &se) {
+			return fmt.Sprintf("status error %d", se.Code)
+		}
+		return "error: " + err.Error()
+	}
+	if len(body) > 32 {
+		return fmt.Sprintf("%d bytes", len(body))
+	}
+	return fmt.Sprintf("body %q", body)
+}
+
+var (
+	client = must.Must(progressive.New())
+	layers = []path{
+		{"Do(ctx, url)", func(ctx context.Context, url string) string {
+			return outcome(progressive.Do(ctx, url))
+		}},
+		{"New().Get", func(ctx context.Context, url string) string {
+			return outcome(client.Get(ctx, url))
+		}},
+		{"New().Do(req)", func(ctx context.Context, url string) string {
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+			if err != nil {
+				return outcome(nil, err)
+			}
+			resp, err := client.Do(req)
+			if err != nil {
+				return outcome(nil, err)
+			}
+			if resp.StatusCode/100 != 2 {
+				return fmt.Sprintf("status error %d", resp.StatusCode)
+			}
+			return outcome(resp.Body, nil)
+		}},
+	}
+	copied = path{"DoCopy", func(ctx context.Context, url string) string {
+		return outcome(progressive.DoCopy(ctx, url))
+	}}
+)
+
+type result struct{ outcome, requests string }
+
+// run fetches url down p, giving up after a second, and /slow after
+// 50ms.
+func run(srv *server, url string, p path) result {
+	srv.reset()
+	timeout := time.Second
+	if strings.HasSuffix(url, "/slow") {
+		timeout = 50 * time.Millisecond
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return result{p.fetch(ctx, url), srv.requests()}
+}
+
+func newServer(t *testing.T) (*server, string) {
+	srv := &server{}
+	hs := httptest.NewServer(srv)
+	t.Cleanup(hs.Close)
+	return srv, hs.URL
+}
+
+// TestEveryLayerIsTheSamePath sends the same requests down each layer and
+// checks every layer returns the same result after the same requests.
+func TestEveryLayerIsTheSamePath(t *testing.T) {
+	srv, base := newServer(t)
+	for _, route := range []string{"/ok", "/missing", "/flaky", "/down", "/big", "/slow"} {
+		t.Run(route, func(t *testing.T) {
+			url := base + route
+			want := run(srv, url, layers[0])
+			t.Logf("every layer: %s after [%s]", want.outcome, want.requests)
+			for _, p := range layers[1:] {
+				if got := run(srv, url, p); got != want {
+					t.Errorf("%s returned %s after [%s]; %s returned %s after [%s]",
+						p.name, got.outcome, got.requests, layers[0].name, want.outcome, want.requests)
+				}
+			}
+		})
+	}
+}
+
+// TestDoCopyDrifted shows where the parallel convenience function
+// differs: the copy has no status check, cap, retry or User-Agent. If it
+// stops differing for these, the test no longer shows the drift.
+func TestDoCopyDrifted(t *testing.T) {
+	srv, base := newServer(t)
+	for _, route := range []string{"/ok", "/missing", "/flaky", "/down", "/big"} {
+		t.Run(route, func(t *testing.T) {
+			url := base + route
+			want, got := run(srv, url, layers[0]), run(srv, url, copied)
+			if got == want {
+				t.Fatalf("DoCopy behaved like the layers: %s after [%s]", got.outcome, got.requests)
+			}
+			t.Logf("layers: %s; DoCopy: %s", want.outcome, got.outcome)
+		})
+	}
+}
+
+// TestOneOptionChangesOneBehaviour checks WithRetries and WithHeader.
+func TestOneOptionChangesOneBehaviour(t *testing.T) {
+	srv, base := newServer(t)
+	noRetry := must.Must(progressive.New(progressive.WithRetries(0, 0)))
+	got := run(srv, base+"/flaky", path{"WithRetries(0)", func(ctx context.Context, url string) string {
+		return outcome(noRetry.Get(ctx, url))
+	}})
+	if got.outcome != "status error 503" || strings.Count(got.requests, ";") != 0 {
+		t.Errorf("WithRetries(0, 0) on /flaky: %s after [%s], want one 503", got.outcome, got.requests)
+	}
+	agent := must.Must(progressive.New(progressive.WithHeader("User-Agent", "custom")))
+	got = run(srv, base+"/ok", path{"WithHeader", func(ctx context.Context, url string) string {
+		return outcome(agent.Get(ctx, url))
+	}})
+	if got.outcome != `body "hello"` || !strings.Contains(got.requests, `ua="custom"`) {
+		t.Errorf("WithHeader on /ok: %s after [%s]", got.outcome, got.requests)
+	}
+}
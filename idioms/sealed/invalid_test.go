@@ -0,0 +1,16 @@
+package sealed
+
+import (
+	"testing"
+
+	"patterns/testing/buildfail"
+)
+
+// TestOutsideInvalid checks that package outside cannot add to either
+// sealed set.
+func TestOutsideInvalid(t *testing.T) {
+	buildfail.Check(t, "sealed_invalid", "./outside",
+		"UserBanned does not implement sealed.Event (unexported method sealedEvent)",
+		"rawOption does not implement sealed.Option (missing method apply)",
+	)
+}
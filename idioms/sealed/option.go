@@ -0,0 +1,62 @@
+package sealed
+
+import "errors"
+
+// Option configures a server. Unlike a plain func type, a sealed Option
+// cannot be implemented by callers, so every option is one of the With*
+// constructors below and the package controls validation.
+type Option interface {
+	apply(*options) error
+}
+
+type options struct {
+	port *int
+	host string
+}
+
+type portOption int
+
+func (p portOption) apply(o *options) error {
+	if p < 0 {
+		return errors.New("port cannot be negative")
+	}
+	port := int(p)
+	o.port = &port
+	return nil
+}
+
+type hostOption string
+
+func (h hostOption) apply(o *options) error {
+	if h == "" {
+		return errors.New("host cannot be empty")
+	}
+	o.host = string(h)
+	return nil
+}
+
+func WithPort(port int) Option { return portOption(port) }
+
+func WithHost(host string) Option { return hostOption(host) }
+
+// Config is the resolved result of applying options.
+type Config struct {
+	Host string
+	Port int
+}
+
+// NewConfig applies opts over the defaults.
+func NewConfig(opts ...Option) (Config, error) {
+	o := options{host: "localhost"}
+	for _, opt := range opts {
+		if err := opt.apply(&o); err != nil {
+			return Config{}, err
+		}
+	}
+
+	cfg := Config{Host: o.host, Port: 8080}
+	if o.port != nil {
+		cfg.Port = *o.port
+	}
+	return cfg, nil
+}
@@ -0,0 +1,25 @@
+//go:build sealed_invalid
+
+// Package outside tries to extend the sealed sets from another package.
+// Build with -tags sealed_invalid to see the compiler reject it.
+package outside
+
+import (
+	"time"
+
+	"patterns/idioms/sealed"
+)
+
+type UserBanned struct{}
+
+func (UserBanned) OccurredAt() time.Time { return time.Time{} }
+
+// sealedEvent here is a different method: unexported names are scoped to
+// their package, so UserBanned still does not implement sealed.Event.
+func (UserBanned) sealedEvent() {}
+
+var _ sealed.Event = UserBanned{}
+
+type rawOption struct{}
+
+var _, _ = sealed.NewConfig(rawOption{})
@@ -0,0 +1,57 @@
+// Package sealed closes an interface to outside implementations by giving
+// it an unexported method: only types in this package can declare it, so
+// the set of implementations is fixed and switches over it can be
+// exhaustive.
+//
+// note: an outside type can still embed one of the implementations and
+// inherit the method. Sealing stops accidents, not determined callers.
+package sealed
+
+import (
+	"fmt"
+	"time"
+)
+
+// Event is a closed set: UserCreated, UserRenamed, UserDeleted.
+type Event interface {
+	OccurredAt() time.Time
+	sealedEvent()
+}
+
+type UserCreated struct {
+	At   time.Time
+	Name string
+}
+
+type UserRenamed struct {
+	At      time.Time
+	OldName string
+	NewName string
+}
+
+type UserDeleted struct {
+	At time.Time
+}
+
+func (e UserCreated) OccurredAt() time.Time { return e.At }
+func (e UserRenamed) OccurredAt() time.Time { return e.At }
+func (e UserDeleted) OccurredAt() time.Time { return e.At }
+
+func (UserCreated) sealedEvent() {}
+func (UserRenamed) sealedEvent() {}
+func (UserDeleted) sealedEvent() {}
+
+// Describe switches over every Event variant. Because the set is closed,
+// the default branch is truly unreachable.
+func Describe(e Event) string {
+	switch e := e.(type) {
+	case UserCreated:
+		return "created " + e.Name
+	case UserRenamed:
+		return "renamed " + e.OldName + " to " + e.NewName
+	case UserDeleted:
+		return "deleted"
+	default:
+		panic(fmt.Sprintf("sealed: unknown event %T", e))
+	}
+}
@@ -0,0 +1,53 @@
+// Package sumtype emulates a sum type with a sealed interface and a type
+// switch. analyzers/exhaustive checks that switches over Shape handle
+// every variant:
+//
+//	go build -o exhaustive patterns/analyzers/exhaustive/cmd/exhaustive
+//	go vet -vettool=./exhaustive ./...
+package sumtype
+
+import "math"
+
+// Shape is Circle | Rect | Triangle.
+type Shape interface {
+	isShape()
+}
+
+type Circle struct{ R float64 }
+
+type Rect struct{ W, H float64 }
+
+type Triangle struct{ A, B, C float64 }
+
+func (Circle) isShape()   {}
+func (Rect) isShape()     {}
+func (Triangle) isShape() {}
+
+// Area handles every variant; adding a new Shape makes the analyzer
+// report this switch until a case is added.
+func Area(s Shape) float64 {
+	switch s := s.(type) {
+	case Circle:
+		return math.Pi * s.R * s.R
+	case Rect:
+		return s.W * s.H
+	case Triangle:
+		// Heron's formula
+		p := (s.A + s.B + s.C) / 2
+		return math.Sqrt(p * (p - s.A) * (p - s.B) * (p - s.C))
+	}
+
+	return 0
+}
+
+// Name handles only some variants and falls back to a default; the
+// analyzer reports it unless the switch is annotated.
+func Name(s Shape) string {
+	//exhaustive:ignore
+	switch s.(type) {
+	case Circle:
+		return "circle"
+	default:
+		return "polygon"
+	}
+}
@@ -0,0 +1,46 @@
+package sumtype
+
+import (
+	"math"
+	"testing"
+)
+
+func TestArea(t *testing.T) {
+	for _, c := range []struct {
+		shape Shape
+		want  float64
+	}{
+		{Circle{R: 1}, math.Pi},
+		{Rect{W: 2, H: 3}, 6},
+		{Triangle{A: 3, B: 4, C: 5}, 6},
+		{nil, 0},
+	} {
+		if got := Area(c.shape); math.Abs(got-c.want) > 1e-9 {
+			t.Errorf("Area(%#v) = %v, want %v", c.shape, got, c.want)
+		}
+	}
+}
+
+// TestAreaPointer shows why exhaustive wants case Circle and not only
+// case *Circle: the variants implement Shape by value, so a *Circle is a
+// Shape too, and matches none of Area's cases.
+func TestAreaPointer(t *testing.T) {
+	if got := Area(&Circle{R: 1}); got != 0 {
+		t.Errorf("Area(&Circle{}) = %v, want 0 from no case matching", got)
+	}
+}
+
+func TestName(t *testing.T) {
+	for _, c := range []struct {
+		shape Shape
+		want  string
+	}{
+		{Circle{}, "circle"},
+		{Rect{}, "polygon"},
+		{Triangle{}, "polygon"},
+	} {
+		if got := Name(c.shape); got != c.want {
+			t.Errorf("Name(%#v) = %q, want %q", c.shape, got, c.want)
+		}
+	}
+}
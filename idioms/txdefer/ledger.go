@@ -0,0 +1,100 @@
+package txdefer
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+var (
+	ErrBusy         = errors.New("a transaction is already open")
+	ErrInsufficient = errors.New("insufficient funds")
+)
+
+// Ledger is a toy store with one transaction at a time, enough to make
+// the pitfalls visible: a transaction that is neither committed nor
+// rolled back leaves the ledger Busy, and partial writes show up in
+// Balance only after a commit.
+type Ledger struct {
+	mu       sync.Mutex
+	balances map[string]int
+	open     bool
+}
+
+func NewLedger(balances map[string]int) *Ledger {
+	b := make(map[string]int, len(balances))
+	for k, v := range balances {
+		b[k] = v
+	}
+	return &Ledger{balances: b}
+}
+
+func (l *Ledger) Balance(account string) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.balances[account]
+}
+
+// Busy reports whether a transaction was leaked.
+func (l *Ledger) Busy() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.open
+}
+
+// Begin starts a transaction; its writes are staged until Commit.
+func (l *Ledger) Begin() (*LedgerTx, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.open {
+		return nil, ErrBusy
+	}
+	l.open = true
+	return &LedgerTx{l: l, staged: map[string]int{}}, nil
+}
+
+type LedgerTx struct {
+	l      *Ledger
+	staged map[string]int
+}
+
+func (t *LedgerTx) balance(account string) int {
+	if v, ok := t.staged[account]; ok {
+		return v
+	}
+	t.l.mu.Lock()
+	defer t.l.mu.Unlock()
+	return t.l.balances[account]
+}
+
+func (t *LedgerTx) Debit(account string, amount int) error {
+	b := t.balance(account)
+	if b < amount {
+		return fmt.Errorf("debit %s: %w", account, ErrInsufficient)
+	}
+	t.staged[account] = b - amount
+	return nil
+}
+
+func (t *LedgerTx) Credit(account string, amount int) error {
+	t.staged[account] = t.balance(account) + amount
+	return nil
+}
+
+func (t *LedgerTx) Commit() error {
+	t.l.mu.Lock()
+	defer t.l.mu.Unlock()
+	for k, v := range t.staged {
+		t.l.balances[k] = v
+	}
+	t.l.open = false
+	return nil
+}
+
+func (t *LedgerTx) Rollback() error {
+	t.l.mu.Lock()
+	defer t.l.mu.Unlock()
+	t.staged = nil
+	t.l.open = false
+	return nil
+}
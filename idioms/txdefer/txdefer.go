@@ -0,0 +1,192 @@
+// Package txdefer shows the deferred commit/rollback idiom:
+//
+//	tx, err := db.Begin()
+//	if err != nil {
+//		return err
+//	}
+//	defer func() {
+//		if err != nil {
+//			tx.Rollback()
+//		}
+//	}()
+//
+// and the ways it goes wrong when written by hand, then wraps it in Tx so
+// that every path ends the transaction exactly once. The Transfer
+// functions move money between two accounts of a Ledger, failing on the
+// credit side when the amount is refused, so each variant can be checked
+// for a leaked transaction (Ledger.Busy) or lost money (Balance).
+package txdefer
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrTxDone is returned by Commit or Rollback after the transaction ended.
+var ErrTxDone = errors.New("transaction already ended")
+
+// Transactional is what Tx wraps; *sql.Tx and *LedgerTx satisfy it.
+type Transactional interface {
+	Commit() error
+	Rollback() error
+}
+
+// Tx ends the wrapped transaction exactly once. Rollback after Commit is
+// a no-op returning ErrTxDone, so a deferred Rollback is always safe.
+type Tx struct {
+	tx   Transactional
+	once sync.Once
+}
+
+func Wrap(tx Transactional) *Tx { return &Tx{tx: tx} }
+
+func (t *Tx) end(f func() error) error {
+	err := ErrTxDone
+	t.once.Do(func() {
+		err = f()
+	})
+	return err
+}
+
+func (t *Tx) Commit() error { return t.end(t.tx.Commit) }
+
+func (t *Tx) Rollback() error { return t.end(t.tx.Rollback) }
+
+// End is the deferred half of the idiom: it rolls back if *err is set or
+// the function is panicking (re-panicking afterwards), and otherwise
+// commits, storing a commit error into *err. err must point at the
+// function's named error result.
+//
+//	func transfer(...) (err error) {
+//		tx := txdefer.Wrap(begin())
+//		defer tx.End(&err)
+//		...
+//	}
+func (t *Tx) End(err *error) {
+	if p := recover(); p != nil {
+		t.Rollback()
+		panic(p)
+	}
+	if *err != nil {
+		if rerr := t.Rollback(); rerr != nil && !errors.Is(rerr, ErrTxDone) {
+			*err = errors.Join(*err, fmt.Errorf("rollback: %w", rerr))
+		}
+		return
+	}
+	if cerr := t.Commit(); cerr != nil && !errors.Is(cerr, ErrTxDone) {
+		*err = fmt.Errorf("commit: %w", cerr)
+	}
+}
+
+// Transfer with Tx
+// Level: Good
+// pros: every return, including a panic, ends the transaction once, and a
+// commit error reaches the caller.
+// cons: relies on the named result; End(&err) with a local err would see
+// nothing, which is why End's doc insists on it.
+func Transfer(l *Ledger, from, to string, amount, limit int) (err error) {
+	ltx, err := l.Begin()
+	if err != nil {
+		return err
+	}
+	tx := Wrap(ltx)
+	defer tx.End(&err)
+
+	if err := ltx.Debit(from, amount); err != nil {
+		// returning assigns the named result, which End reads
+		return err
+	}
+	if amount > limit {
+		panicOrRefuse(amount, limit)
+		return fmt.Errorf("credit %s: amount %d over limit %d", to, amount, limit)
+	}
+	return ltx.Credit(to, amount)
+}
+
+// panicOrRefuse stands in for a bug deeper in the call tree: negative
+// limits are a caller error and panic.
+func panicOrRefuse(amount, limit int) {
+	if limit < 0 {
+		panic(fmt.Sprintf("txdefer: negative limit %d", limit))
+	}
+}
+
+// shadowed err pattern
+// Level: Poor
+// pros: looks like the idiom.
+// cons: the result is unnamed, so the deferred closure checks a local err
+// that the inner := shadows; a refused credit returns an error but the
+// closure sees nil, never rolls back, and the ledger stays Busy.
+func TransferShadowed(l *Ledger, from, to string, amount, limit int) error {
+	tx, err := l.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := tx.Debit(from, amount); err != nil {
+		return err
+	}
+	if amount > limit {
+		return fmt.Errorf("credit %s: amount %d over limit %d", to, amount, limit)
+	}
+	if err := tx.Credit(to, amount); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// deferred commit pattern
+// Level: Poor
+// pros: impossible to forget the commit.
+// cons: commits on the error path too, so a refused credit keeps the debit
+// and money disappears; the commit error is dropped.
+func TransferDeferCommit(l *Ledger, from, to string, amount, limit int) error {
+	tx, err := l.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Commit()
+
+	if err := tx.Debit(from, amount); err != nil {
+		return err
+	}
+	if amount > limit {
+		return fmt.Errorf("credit %s: amount %d over limit %d", to, amount, limit)
+	}
+	return tx.Credit(to, amount)
+}
+
+// error-only rollback pattern
+// Level: Poor
+// pros: correct for every returned error, using the named result.
+// cons: a panic skips the rollback (err is still nil while unwinding), so
+// a recovered panic upstream leaves the transaction open.
+func TransferNoPanic(l *Ledger, from, to string, amount, limit int) (err error) {
+	tx, err := l.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err = tx.Debit(from, amount); err != nil {
+		return err
+	}
+	if amount > limit {
+		panicOrRefuse(amount, limit)
+		return fmt.Errorf("credit %s: amount %d over limit %d", to, amount, limit)
+	}
+	if err = tx.Credit(to, amount); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,174 @@
+package txdefer_test
+
+import (
+	"errors"
+	"testing"
+
+	"patterns/idioms/txdefer"
+)
+
+type transfer func(l *txdefer.Ledger, from, to string, amount, limit int) error
+
+// outcome is the state a transfer leaves behind.
+type outcome struct {
+	err, panicked, busy bool
+	ann, bob            int
+}
+
+func run(f transfer, amount, limit int) (o outcome) {
+	l := txdefer.NewLedger(map[string]int{"ann": 100})
+	defer func() {
+		o.panicked = recover() != nil
+		o.busy, o.ann, o.bob = l.Busy(), l.Balance("ann"), l.Balance("bob")
+	}()
+	o.err = f(l, "ann", "bob", amount, limit) != nil
+	return o
+}
+
+// TestTransfers runs every variant through a transfer that succeeds,
+// one that fails its debit, one refused on the credit side and one that
+// panics, and records who leaks the transaction or loses money.
+func TestTransfers(t *testing.T) {
+	var (
+		ok        = outcome{ann: 70, bob: 30}
+		untouched = outcome{err: true, ann: 100}
+		leaked    = outcome{err: true, busy: true, ann: 100}
+		lost      = outcome{err: true, ann: 40}
+	)
+	for _, c := range []struct {
+		name                          string
+		f                             transfer
+		ok, debit, refused, panicking outcome
+	}{
+		{"Transfer", txdefer.Transfer, ok, untouched, untouched, outcome{panicked: true, ann: 100}},
+		// the inner := hides every error from the deferred check
+		{"TransferShadowed", txdefer.TransferShadowed, ok, leaked, leaked, leaked},
+		// nothing was staged when the debit fails, so its commit is harmless
+		{"TransferDeferCommit", txdefer.TransferDeferCommit, ok, untouched, lost, lost},
+		{"TransferNoPanic", txdefer.TransferNoPanic, ok, untouched, untouched, outcome{panicked: true, busy: true, ann: 100}},
+	} {
+		for _, s := range []struct {
+			scenario      string
+			amount, limit int
+			want          outcome
+		}{
+			{"ok", 30, 50, c.ok},
+			{"insufficient funds", 200, 500, c.debit},
+			{"over limit", 60, 50, c.refused},
+			{"panic", 60, -1, c.panicking},
+		} {
+			if got := run(c.f, s.amount, s.limit); got != s.want {
+				t.Errorf("%s, %s: %+v, want %+v", c.name, s.scenario, got, s.want)
+			}
+		}
+	}
+}
+
+// TestLeakedTransactionBlocks checks what a leak costs: the next
+// transaction cannot begin.
+func TestLeakedTransactionBlocks(t *testing.T) {
+	l := txdefer.NewLedger(map[string]int{"ann": 100})
+	txdefer.TransferShadowed(l, "ann", "bob", 60, 50)
+	if err := txdefer.Transfer(l, "ann", "bob", 10, 50); !errors.Is(err, txdefer.ErrBusy) {
+		t.Errorf("Transfer after a leak = %v, want ErrBusy", err)
+	}
+}
+
+// fakeTx counts how each transaction ended, and fails as told.
+type fakeTx struct {
+	commits, rollbacks     int
+	commitErr, rollbackErr error
+}
+
+func (f *fakeTx) Commit() error   { f.commits++; return f.commitErr }
+func (f *fakeTx) Rollback() error { f.rollbacks++; return f.rollbackErr }
+
+func TestEndsOnce(t *testing.T) {
+	f := &fakeTx{}
+	tx := txdefer.Wrap(f)
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	for name, err := range map[string]error{"Commit": tx.Commit(), "Rollback": tx.Rollback()} {
+		if !errors.Is(err, txdefer.ErrTxDone) {
+			t.Errorf("%s after Commit = %v, want ErrTxDone", name, err)
+		}
+	}
+	if f.commits != 1 || f.rollbacks != 0 {
+		t.Errorf("committed %d, rolled back %d; want 1, 0", f.commits, f.rollbacks)
+	}
+}
+
+func TestEnd(t *testing.T) {
+	errWork := errors.New("work failed")
+	errCommit := errors.New("disk full")
+	errRollback := errors.New("connection lost")
+	for _, c := range []struct {
+		name string
+		tx   *fakeTx
+		work error
+		// early ends the transaction before End
+		early              func(*txdefer.Tx) error
+		commits, rollbacks int
+		want               string
+	}{
+		{"success", &fakeTx{}, nil, nil, 1, 0, ""},
+		{"work fails", &fakeTx{}, errWork, nil, 0, 1, "work failed"},
+		{"commit fails", &fakeTx{commitErr: errCommit}, nil, nil, 1, 0, "commit: disk full"},
+		{"rollback fails", &fakeTx{rollbackErr: errRollback}, errWork, nil, 0, 1, "work failed\nrollback: connection lost"},
+		// committed by hand: End has nothing left to do, and says nothing
+		{"committed early", &fakeTx{}, nil, (*txdefer.Tx).Commit, 1, 0, ""},
+		{"committed early, then fails", &fakeTx{}, errWork, (*txdefer.Tx).Commit, 1, 0, "work failed"},
+	} {
+		err := func() (err error) {
+			tx := txdefer.Wrap(c.tx)
+			defer tx.End(&err)
+			if c.early != nil {
+				if err := c.early(tx); err != nil {
+					return err
+				}
+			}
+			return c.work
+		}()
+		got := ""
+		if err != nil {
+			got = err.Error()
+		}
+		if got != c.want || c.tx.commits != c.commits || c.tx.rollbacks != c.rollbacks {
+			t.Errorf("%s: %q, %d commits, %d rollbacks; want %q, %d, %d",
+				c.name, got, c.tx.commits, c.tx.rollbacks, c.want, c.commits, c.rollbacks)
+		}
+	}
+}
+
+// TestEndPanics checks that End rolls back a panicking function and lets
+// the panic go on.
+func TestEndPanics(t *testing.T) {
+	f := &fakeTx{}
+	defer func() {
+		if p := recover(); p != "boom" || f.commits != 0 || f.rollbacks != 1 {
+			t.Errorf("panic %v, %d commits, %d rollbacks; want boom, 0, 1", p, f.commits, f.rollbacks)
+		}
+	}()
+	func() (err error) {
+		tx := txdefer.Wrap(f)
+		defer tx.End(&err)
+		panic("boom")
+	}()
+}
+
+// TestEndLocalErr is the pitfall End's doc warns about: pointed at a
+// local instead of the named result, End never sees the error returned,
+// and commits.
+func TestEndLocalErr(t *testing.T) {
+	f := &fakeTx{}
+	err := func() error {
+		var err error
+		tx := txdefer.Wrap(f)
+		defer tx.End(&err)
+		return errors.New("work failed")
+	}()
+	if err == nil || f.commits != 1 || f.rollbacks != 0 {
+		t.Errorf("%v, %d commits, %d rollbacks; the pitfall should commit", err, f.commits, f.rollbacks)
+	}
+}
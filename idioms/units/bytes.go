@@ -0,0 +1,66 @@
+// Package units models quantities as defined types, the way time.Duration
+// does, so that mixing units is a compile error rather than a silent bug.
+package units
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Bytes is a size in bytes.
+type Bytes int64
+
+const (
+	Byte Bytes = 1
+	KiB        = 1024 * Byte
+	MiB        = 1024 * KiB
+	GiB        = 1024 * MiB
+)
+
+var byteUnits = []struct {
+	suffix string
+	size   Bytes
+}{
+	{"GiB", GiB},
+	{"MiB", MiB},
+	{"KiB", KiB},
+	{"B", Byte},
+}
+
+// String formats b using the largest unit that divides it exactly, so
+// the result parses back to the same value.
+func (b Bytes) String() string {
+	if b == 0 {
+		return "0B"
+	}
+	for _, u := range byteUnits {
+		if b%u.size == 0 {
+			return strconv.FormatInt(int64(b/u.size), 10) + u.suffix
+		}
+	}
+
+	return strconv.FormatInt(int64(b), 10) + "B"
+}
+
+// ParseBytes parses strings like "512B", "4KiB" or "1GiB".
+func ParseBytes(s string) (Bytes, error) {
+	s = strings.TrimSpace(s)
+	for _, u := range byteUnits {
+		num, ok := strings.CutSuffix(s, u.suffix)
+		if !ok {
+			continue
+		}
+		n, err := strconv.ParseInt(num, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("parse bytes %q: %w", s, err)
+		}
+		if n > math.MaxInt64/int64(u.size) || n < math.MinInt64/int64(u.size) {
+			return 0, fmt.Errorf("parse bytes %q: %w", s, strconv.ErrRange)
+		}
+		return Bytes(n) * u.size, nil
+	}
+
+	return 0, fmt.Errorf("parse bytes %q: missing unit", s)
+}
@@ -0,0 +1,15 @@
+//go:build units_invalid
+
+// Build with -tags units_invalid to see unit mixing rejected.
+package units
+
+func mixed() {
+	// mismatched types Bytes and Celsius
+	_ = KiB + Celsius(20)
+
+	// cannot use Money[USD] as Money[EUR]
+	_ = Money[EUR](100).Add(Money[USD](100))
+
+	// Celsius.Add wants a CelsiusDelta, not another absolute temperature
+	_ = Celsius(20).Add(Celsius(5))
+}
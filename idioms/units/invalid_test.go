@@ -0,0 +1,15 @@
+package units
+
+import (
+	"testing"
+
+	"patterns/testing/buildfail"
+)
+
+func TestInvalid(t *testing.T) {
+	buildfail.Check(t, "units_invalid", ".",
+		"invalid operation: KiB + Celsius(20) (mismatched types Bytes and Celsius)",
+		"as Money[EUR] value in argument to Money[EUR](100).Add",
+		"as CelsiusDelta value in argument to Celsius(20).Add",
+	)
+}
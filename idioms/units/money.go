@@ -0,0 +1,118 @@
+package units
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Currency describes a currency at the type level. Implementations are
+// empty structs used only as type arguments.
+type Currency interface {
+	Code() string
+	// MinorDigits is the number of minor-unit digits (2 for cents).
+	MinorDigits() int
+}
+
+type (
+	EUR struct{}
+	USD struct{}
+	JPY struct{}
+)
+
+func (EUR) Code() string     { return "EUR" }
+func (EUR) MinorDigits() int { return 2 }
+func (USD) Code() string     { return "USD" }
+func (USD) MinorDigits() int { return 2 }
+func (JPY) Code() string     { return "JPY" }
+func (JPY) MinorDigits() int { return 0 }
+
+// Money is an amount in minor units of currency C. Money[EUR] and
+// Money[USD] are distinct types, so they cannot be added together.
+type Money[C Currency] int64
+
+// Add returns m+o.
+func (m Money[C]) Add(o Money[C]) Money[C] { return m + o }
+
+// Mul scales m by an integer factor.
+func (m Money[C]) Mul(n int64) Money[C] { return m * Money[C](n) }
+
+// Split divides m into n parts that sum back to m, spreading the
+// remainder over the first parts instead of losing it to rounding.
+func (m Money[C]) Split(n int) []Money[C] {
+	parts := make([]Money[C], n)
+	q, r := int64(m)/int64(n), int64(m)%int64(n)
+	step := Money[C](1)
+	if r < 0 {
+		r, step = -r, -1
+	}
+	for i := range parts {
+		parts[i] = Money[C](q)
+		if int64(i) < r {
+			parts[i] += step
+		}
+	}
+
+	return parts
+}
+
+func (m Money[C]) String() string {
+	var c C
+	digits := c.MinorDigits()
+	// the magnitude as unsigned, so that the most negative amount has one
+	n := uint64(m)
+	sign := ""
+	if m < 0 {
+		sign, n = "-", -n
+	}
+	if digits == 0 {
+		return sign + strconv.FormatUint(n, 10) + " " + c.Code()
+	}
+	scale := uint64(pow10(digits))
+
+	return fmt.Sprintf("%s%d.%0*d %s", sign, n/scale, digits, n%scale, c.Code())
+}
+
+// ParseMoney parses the String form, e.g. "12.30 EUR".
+func ParseMoney[C Currency](s string) (Money[C], error) {
+	var c C
+	num, ok := strings.CutSuffix(strings.TrimSpace(s), " "+c.Code())
+	if !ok {
+		return 0, fmt.Errorf("parse money %q: want currency %s", s, c.Code())
+	}
+	num, neg := strings.CutPrefix(num, "-")
+
+	whole, frac, dot := strings.Cut(num, ".")
+	if len(frac) != c.MinorDigits() || dot != (c.MinorDigits() > 0) {
+		return 0, fmt.Errorf("parse money %q: want %d minor digits", s, c.MinorDigits())
+	}
+	if whole == "" {
+		return 0, fmt.Errorf("parse money %q: missing whole units", s)
+	}
+	// the digits run together are the amount in minor units; ParseUint
+	// takes no sign, so "--1.00" and "1.-5" are rejected
+	n, err := strconv.ParseUint(whole+frac, 10, 64)
+	limit := uint64(math.MaxInt64)
+	if neg {
+		limit++
+	}
+	if err == nil && n > limit {
+		err = strconv.ErrRange
+	}
+	if err != nil {
+		return 0, fmt.Errorf("parse money %q: %w", s, err)
+	}
+	if neg {
+		n = -n
+	}
+	return Money[C](n), nil
+}
+
+func pow10(n int) int64 {
+	p := int64(1)
+	for range n {
+		p *= 10
+	}
+	return p
+}
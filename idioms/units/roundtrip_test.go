@@ -0,0 +1,162 @@
+package units
+
+import (
+	"math"
+	"math/rand/v2"
+	"testing"
+)
+
+func TestBytesRoundTrip(t *testing.T) {
+	values := []Bytes{0, 1, -1, 512, KiB, 1536, MiB, 3 * GiB, GiB + 1, -4 * KiB, math.MaxInt64, math.MinInt64}
+	r := rand.New(rand.NewPCG(1, 2))
+	for range 1000 {
+		// shifted so that every unit turns up
+		values = append(values, Bytes(r.Int64()>>r.IntN(64)))
+	}
+	for _, b := range values {
+		back, err := ParseBytes(b.String())
+		if err != nil || back != b {
+			t.Errorf("ParseBytes(%q) = %d, %v, want %d", b.String(), back, err, int64(b))
+		}
+	}
+}
+
+func TestBytesString(t *testing.T) {
+	for _, c := range []struct {
+		b    Bytes
+		want string
+	}{
+		{0, "0B"},
+		{512, "512B"},
+		{KiB, "1KiB"},
+		{1536, "1536B"},
+		{1536 * KiB, "1536KiB"},
+		{2 * GiB, "2GiB"},
+		{-MiB, "-1MiB"},
+	} {
+		if got := c.b.String(); got != c.want {
+			t.Errorf("Bytes(%d).String() = %q, want %q", int64(c.b), got, c.want)
+		}
+	}
+}
+
+func TestParseBytes(t *testing.T) {
+	for _, c := range []struct {
+		s    string
+		want Bytes
+	}{
+		{" 4KiB ", 4 * KiB},
+		{"0GiB", 0},
+		{"8589934591GiB", 8589934591 * GiB},
+	} {
+		if got, err := ParseBytes(c.s); err != nil || got != c.want {
+			t.Errorf("ParseBytes(%q) = %d, %v, want %d", c.s, got, err, int64(c.want))
+		}
+	}
+	for _, s := range []string{"", "4", "KiB", "1.5KiB", "4 KiB", "4kib", "8589934592GiB", "-8589934593GiB"} {
+		if got, err := ParseBytes(s); err == nil {
+			t.Errorf("ParseBytes(%q) = %d, want an error", s, int64(got))
+		}
+	}
+}
+
+func TestMoneyRoundTrip(t *testing.T) {
+	values := []int64{0, 1, -1, 5, -5, 99, 100, -100, 1230, math.MaxInt64, math.MinInt64}
+	r := rand.New(rand.NewPCG(3, 4))
+	for range 1000 {
+		values = append(values, r.Int64()>>r.IntN(64))
+	}
+	for _, n := range values {
+		roundTrip(t, Money[EUR](n))
+		roundTrip(t, Money[JPY](n))
+	}
+}
+
+func roundTrip[C Currency](t *testing.T, m Money[C]) {
+	t.Helper()
+	back, err := ParseMoney[C](m.String())
+	if err != nil || back != m {
+		t.Errorf("ParseMoney(%q) = %d, %v, want %d", m.String(), int64(back), err, int64(m))
+	}
+}
+
+func TestMoneyString(t *testing.T) {
+	for _, c := range []struct {
+		got, want string
+	}{
+		{Money[EUR](1230).String(), "12.30 EUR"},
+		{Money[EUR](-5).String(), "-0.05 EUR"},
+		{Money[USD](0).String(), "0.00 USD"},
+		{Money[JPY](1500).String(), "1500 JPY"},
+		{Money[EUR](math.MinInt64).String(), "-92233720368547758.08 EUR"},
+	} {
+		if c.got != c.want {
+			t.Errorf("String() = %q, want %q", c.got, c.want)
+		}
+	}
+}
+
+func TestParseMoneyRejects(t *testing.T) {
+	for _, s := range []string{
+		"12.30", "12.30 USD", "12.3 EUR", "12.300 EUR", "12 EUR", ".30 EUR",
+		"--1.00 EUR", "+1.00 EUR", "1.-5 EUR", "1.+5 EUR", "1,00 EUR",
+		"92233720368547758.08 EUR", "-92233720368547758.09 EUR",
+	} {
+		if got, err := ParseMoney[EUR](s); err == nil {
+			t.Errorf("ParseMoney[EUR](%q) = %d, want an error", s, int64(got))
+		}
+	}
+	for _, s := range []string{"15.00 JPY", "15. JPY"} {
+		if got, err := ParseMoney[JPY](s); err == nil {
+			t.Errorf("ParseMoney[JPY](%q) = %d, want an error", s, int64(got))
+		}
+	}
+}
+
+func TestSplit(t *testing.T) {
+	r := rand.New(rand.NewPCG(5, 6))
+	for range 1000 {
+		m, n := Money[EUR](r.Int64N(1e9)-5e8), 1+r.IntN(12)
+		parts := m.Split(n)
+		var sum Money[EUR]
+		for _, p := range parts {
+			sum = sum.Add(p)
+			// parts differ by at most one minor unit
+			if d := p - parts[0]; d < -1 || d > 1 {
+				t.Fatalf("%d.Split(%d) = %v, parts differ by more than a cent", int64(m), n, parts)
+			}
+		}
+		if len(parts) != n || sum != m {
+			t.Fatalf("%d.Split(%d) = %v, sum %d", int64(m), n, parts, int64(sum))
+		}
+	}
+}
+
+func TestCelsiusRoundTrip(t *testing.T) {
+	values := []Celsius{0, 21.5, -40, -273.15, 0.1, 1e-9, 1e300, math.SmallestNonzeroFloat64, Celsius(math.Inf(1)), Celsius(math.Inf(-1))}
+	r := rand.New(rand.NewPCG(7, 8))
+	for range 1000 {
+		values = append(values, Celsius(r.NormFloat64()*100))
+	}
+	for _, c := range values {
+		back, err := ParseCelsius(c.String())
+		if err != nil || back != c {
+			t.Errorf("ParseCelsius(%q) = %v, %v, want %v", c.String(), back, err, float64(c))
+		}
+	}
+	for _, s := range []string{"21.5", "21.5 °C", "21.5°F", "°C", "warm°C"} {
+		if _, err := ParseCelsius(s); err == nil {
+			t.Errorf("ParseCelsius(%q) succeeded", s)
+		}
+	}
+}
+
+func TestCelsiusArithmetic(t *testing.T) {
+	freezing, boiling := Celsius(0), Celsius(100)
+	if d := boiling.Sub(freezing); d != 100 || freezing.Add(d) != boiling {
+		t.Errorf("Sub/Add: %v - %v = %v", boiling, freezing, d)
+	}
+	if f := Celsius(-40).Fahrenheit(); f != -40 {
+		t.Errorf("-40°C = %v°F, want -40", f)
+	}
+}
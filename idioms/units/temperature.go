@@ -0,0 +1,41 @@
+package units
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Celsius is an absolute temperature. Differences between temperatures are
+// a separate type, so adding two absolute temperatures does not compile.
+type Celsius float64
+
+// CelsiusDelta is a temperature difference.
+type CelsiusDelta float64
+
+// Add shifts t by d.
+func (t Celsius) Add(d CelsiusDelta) Celsius { return t + Celsius(d) }
+
+// Sub returns the difference t-u.
+func (t Celsius) Sub(u Celsius) CelsiusDelta { return CelsiusDelta(t - u) }
+
+// Fahrenheit converts t for display.
+func (t Celsius) Fahrenheit() float64 { return float64(t)*9/5 + 32 }
+
+func (t Celsius) String() string {
+	return strconv.FormatFloat(float64(t), 'f', -1, 64) + "°C"
+}
+
+// ParseCelsius parses the String form, e.g. "21.5°C".
+func ParseCelsius(s string) (Celsius, error) {
+	num, ok := strings.CutSuffix(strings.TrimSpace(s), "°C")
+	if !ok {
+		return 0, fmt.Errorf("parse celsius %q: missing °C", s)
+	}
+	f, err := strconv.ParseFloat(num, 64)
+	if err != nil {
+		return 0, fmt.Errorf("parse celsius %q: %w", s, err)
+	}
+
+	return Celsius(f), nil
+}
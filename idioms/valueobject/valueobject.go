@@ -0,0 +1,89 @@
+// Package valueobject shows value objects: immutable types compared by
+// value, normalized once in their constructor so that equal inputs produce
+// equal values.
+package valueobject
+
+import (
+	"slices"
+	"strings"
+
+	"patterns/validate"
+)
+
+// Email is comparable (only string fields), so == and map keys work
+// directly. Normalization in NewEmail makes " Bob@Example.com" and
+// "bob@example.com" the same key.
+type Email struct {
+	local  string
+	domain string
+}
+
+// NewEmail trims and case-folds addr.
+func NewEmail(addr string) (Email, error) {
+	addr = strings.ToLower(strings.TrimSpace(addr))
+	local, domain, ok := strings.Cut(addr, "@")
+	var v validate.Validator
+	v.Check("email", ok, "missing @")
+	if ok {
+		validate.Field(&v, "email.local", local, validate.NonEmpty[string]())
+		validate.Field(&v, "email.domain", domain, validate.NonEmpty[string](),
+			validate.Custom(func(d string) bool { return !strings.Contains(d, "@") }, "contains @"))
+	}
+	if err := v.Err(); err != nil {
+		return Email{}, err
+	}
+
+	return Email{local: local, domain: domain}, nil
+}
+
+func (e Email) Domain() string { return e.domain }
+
+func (e Email) String() string { return e.local + "@" + e.domain }
+
+// TagSet holds a slice, so it is not comparable; == does not compile and
+// it cannot be a map key. Equal defines value equality instead, and Key
+// gives a canonical comparable form for maps.
+type TagSet struct {
+	tags []string
+}
+
+// NewTagSet lowercases, trims, de-duplicates and sorts tags so that two
+// sets with the same members have identical internal state. Every bad tag
+// is reported, as "tags[i]: ...".
+func NewTagSet(tags ...string) (TagSet, error) {
+	norm := make([]string, 0, len(tags))
+	var v validate.Validator
+	for i, t := range tags {
+		t = strings.ToLower(strings.TrimSpace(t))
+		validate.Field(v.Index("tags", i), "", t, validate.NonEmpty[string](),
+			validate.Custom(func(t string) bool { return !strings.Contains(t, ",") }, "contains a comma"))
+		norm = append(norm, t)
+	}
+	if err := v.Err(); err != nil {
+		return TagSet{}, err
+	}
+	slices.Sort(norm)
+
+	return TagSet{tags: slices.Compact(norm)}, nil
+}
+
+// Equal reports whether s and o have the same members. It is reflexive,
+// symmetric and transitive because it compares the normalized form.
+func (s TagSet) Equal(o TagSet) bool {
+	return slices.Equal(s.tags, o.tags)
+}
+
+// Key returns a comparable representation, usable as a map key.
+func (s TagSet) Key() string {
+	return strings.Join(s.tags, ",")
+}
+
+// Tags returns a copy so callers cannot mutate the value object.
+func (s TagSet) Tags() []string {
+	return slices.Clone(s.tags)
+}
+
+// With returns a new set including tag; s is unchanged.
+func (s TagSet) With(tag string) (TagSet, error) {
+	return NewTagSet(append(s.Tags(), tag)...)
+}
@@ -0,0 +1,167 @@
+package valueobject_test
+
+import (
+	"maps"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"testing"
+
+	"patterns/idioms/valueobject"
+)
+
+// spell writes a tag the way a user might: any case, padded.
+func spell(r *rand.Rand, tag string) string {
+	b := []byte(tag)
+	for i := range b {
+		if r.IntN(2) == 0 {
+			b[i] = strings.ToUpper(string(b[i]))[0]
+		}
+	}
+	return strings.Repeat(" ", r.IntN(2)) + string(b) + strings.Repeat("\t", r.IntN(2))
+}
+
+// randomTags draws a few tags from a small vocabulary, so that sets
+// often share members, spelled differently and repeated.
+func randomTags(r *rand.Rand) (spelled []string, members map[string]bool) {
+	vocabulary := []string{"go", "db", "web", "ops", "a", "b"}
+	members = map[string]bool{}
+	for range r.IntN(5) {
+		tag := vocabulary[r.IntN(len(vocabulary))]
+		members[tag] = true
+		spelled = append(spelled, spell(r, tag))
+	}
+	return spelled, members
+}
+
+func TestTagSetEqualityLaws(t *testing.T) {
+	r := rand.New(rand.NewPCG(1, 2))
+	type drawn struct {
+		set     valueobject.TagSet
+		members map[string]bool
+	}
+	var sets []drawn
+	for range 200 {
+		spelled, members := randomTags(r)
+		s, err := valueobject.NewTagSet(spelled...)
+		if err != nil {
+			t.Fatalf("NewTagSet(%q): %v", spelled, err)
+		}
+		sets = append(sets, drawn{s, members})
+	}
+	for _, a := range sets {
+		if !a.set.Equal(a.set) {
+			t.Errorf("%q is not Equal to itself", a.set.Key())
+		}
+		for _, b := range sets {
+			same := maps.Equal(a.members, b.members)
+			if a.set.Equal(b.set) != same {
+				t.Errorf("%q.Equal(%q) = %v, want %v", a.set.Key(), b.set.Key(), !same, same)
+			}
+			if a.set.Equal(b.set) != b.set.Equal(a.set) {
+				t.Errorf("Equal of %q and %q is not symmetric", a.set.Key(), b.set.Key())
+			}
+			if (a.set.Key() == b.set.Key()) != a.set.Equal(b.set) {
+				t.Errorf("Key of %q and %q disagrees with Equal", a.set.Key(), b.set.Key())
+			}
+			for _, c := range sets[:20] {
+				if a.set.Equal(b.set) && b.set.Equal(c.set) && !a.set.Equal(c.set) {
+					t.Errorf("Equal is not transitive over %q, %q, %q", a.set.Key(), b.set.Key(), c.set.Key())
+				}
+			}
+		}
+	}
+}
+
+func TestTagSetKey(t *testing.T) {
+	byKey := map[string]int{}
+	for _, tags := range [][]string{{"go", "db"}, {" DB", "Go", "go"}, {"db"}, {}} {
+		s, err := valueobject.NewTagSet(tags...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		byKey[s.Key()]++
+	}
+	if want := map[string]int{"db,go": 2, "db": 1, "": 1}; !maps.Equal(byKey, want) {
+		t.Errorf("sets by key = %v, want %v", byKey, want)
+	}
+}
+
+func TestTagSetImmutable(t *testing.T) {
+	s, err := valueobject.NewTagSet("go", "db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tags := s.Tags()
+	tags[0] = "changed"
+	if got := s.Tags(); !slices.Equal(got, []string{"db", "go"}) {
+		t.Errorf("Tags after changing a copy = %v", got)
+	}
+	with, err := s.With("Web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Key() != "db,go" || with.Key() != "db,go,web" {
+		t.Errorf("With: s = %q, result = %q", s.Key(), with.Key())
+	}
+	if _, err := s.With(" "); err == nil {
+		t.Error("With of a blank tag succeeded")
+	}
+}
+
+func TestNewTagSetErrors(t *testing.T) {
+	_, err := valueobject.NewTagSet("go", " ", "a,b")
+	if err == nil {
+		t.Fatal("NewTagSet succeeded")
+	}
+	for _, want := range []string{"tags[1]", "tags[2]", "contains a comma"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %q", err, want)
+		}
+	}
+}
+
+func TestEmailEqualityLaws(t *testing.T) {
+	r := rand.New(rand.NewPCG(3, 4))
+	var emails []valueobject.Email
+	var plain []string
+	for range 200 {
+		addr := []string{"bob", "ann", "x"}[r.IntN(3)] + "@" + []string{"example.com", "go.dev"}[r.IntN(2)]
+		e, err := valueobject.NewEmail(spell(r, addr))
+		if err != nil {
+			t.Fatal(err)
+		}
+		emails, plain = append(emails, e), append(plain, addr)
+	}
+	seen := map[valueobject.Email]string{}
+	for i, a := range emails {
+		if a.String() != plain[i] {
+			t.Errorf("NewEmail of a spelling of %s = %s", plain[i], a)
+		}
+		// the normalized value is the map key: every spelling finds it
+		if prev, ok := seen[a]; ok && prev != plain[i] {
+			t.Errorf("%s and %s are the same key", prev, plain[i])
+		}
+		seen[a] = plain[i]
+		for j, b := range emails {
+			if (a == b) != (plain[i] == plain[j]) || (a == b) != (b == a) {
+				t.Errorf("%s == %s is %v", plain[i], plain[j], a == b)
+			}
+		}
+	}
+	if len(seen) != 6 {
+		t.Errorf("%d distinct emails, want 6", len(seen))
+	}
+}
+
+func TestNewEmailErrors(t *testing.T) {
+	for _, addr := range []string{"", "bob", "@example.com", "bob@", "bob@ex@ample.com"} {
+		if e, err := valueobject.NewEmail(addr); err == nil {
+			t.Errorf("NewEmail(%q) = %s, want an error", addr, e)
+		}
+	}
+	e, err := valueobject.NewEmail(" Bob@Example.COM ")
+	if err != nil || e.Domain() != "example.com" {
+		t.Errorf("NewEmail = %v, %v, want domain example.com", e, err)
+	}
+}
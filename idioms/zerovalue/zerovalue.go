@@ -0,0 +1,195 @@
+// Package zerovalue shows types whose zero value is ready to use, the way
+// bytes.Buffer, sync.Mutex and strings.Builder are:
+//
+//	var c Counter
+//	c.Inc("hits")
+//
+// The techniques: allocate lazily on first write, read through nil maps
+// and slices (which Go allows), fall back to defaults for zero fields, and
+// make methods safe on a nil receiver where that has an obvious meaning.
+package zerovalue
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// constructor-required pattern
+// Level: Poor
+// cons: var c NeedsConstructor compiles, then c.Inc panics on the nil map;
+// every embedding struct needs its own constructor to call NewNeedsConstructor.
+type NeedsConstructor struct {
+	mu sync.Mutex
+	m  map[string]int
+}
+
+func NewNeedsConstructor() *NeedsConstructor {
+	return &NeedsConstructor{m: map[string]int{}}
+}
+
+func (c *NeedsConstructor) Inc(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.m[key]++
+}
+
+// lazy allocation pattern
+// Level: Good
+// pros: usable as a plain field or var, embeds without a constructor, no
+// allocation until the first write.
+type Counter struct {
+	mu sync.Mutex
+	m  map[string]int
+}
+
+func (c *Counter) Inc(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.m == nil {
+		c.m = map[string]int{}
+	}
+	c.m[key]++
+}
+
+// Get reads through a possibly nil map, which returns the zero value.
+func (c *Counter) Get(key string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.m[key]
+}
+
+// Stack needs no allocation logic at all: append and len work on nil.
+type Stack[T any] struct {
+	items []T
+}
+
+func (s *Stack[T]) Push(v T) { s.items = append(s.items, v) }
+
+func (s *Stack[T]) Pop() (T, bool) {
+	var zero T
+	if len(s.items) == 0 {
+		return zero, false
+	}
+	v := s.items[len(s.items)-1]
+	s.items[len(s.items)-1] = zero
+	s.items = s.items[:len(s.items)-1]
+	return v, true
+}
+
+func (s *Stack[T]) Len() int { return len(s.items) }
+
+// defaulted fields pattern
+// Level: Good
+// pros: like http.Client, every field is optional and zero means "the
+// default", so Client{} and &Client{Timeout: time.Second} both work.
+// cons: a zero field cannot mean "really zero"; pick defaults where zero
+// is never a sensible setting.
+type Client struct {
+	// HTTP defaults to http.DefaultClient.
+	HTTP *http.Client
+	// BaseURL defaults to DefaultBaseURL.
+	BaseURL string
+	// Timeout defaults to DefaultTimeout.
+	Timeout time.Duration
+}
+
+const (
+	DefaultBaseURL = "http://localhost:8080"
+	DefaultTimeout = 10 * time.Second
+)
+
+func (c *Client) httpClient() *http.Client {
+	if c.HTTP != nil {
+		return c.HTTP
+	}
+	return http.DefaultClient
+}
+
+func (c *Client) baseURL() string {
+	if c.BaseURL != "" {
+		return c.BaseURL
+	}
+	return DefaultBaseURL
+}
+
+func (c *Client) timeout() time.Duration {
+	if c.Timeout > 0 {
+		return c.Timeout
+	}
+	return DefaultTimeout
+}
+
+// NewRequest builds a request against the effective base URL.
+func (c *Client) NewRequest(method, path string) (*http.Request, error) {
+	return http.NewRequest(method, c.baseURL()+path, nil)
+}
+
+// Do sends req with the effective client and timeout.
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	hc := *c.httpClient()
+	if hc.Timeout == 0 {
+		hc.Timeout = c.timeout()
+	}
+	return hc.Do(req)
+}
+
+// nil receiver pattern
+// Level: Good
+// pros: an empty list is just a nil *List; Len and All need no nil checks
+// at call sites.
+type List[T any] struct {
+	Value T
+	Next  *List[T]
+}
+
+// Prepend returns a new head; works on a nil list.
+func (l *List[T]) Prepend(v T) *List[T] { return &List[T]{Value: v, Next: l} }
+
+func (l *List[T]) Len() int {
+	n := 0
+	for ; l != nil; l = l.Next {
+		n++
+	}
+	return n
+}
+
+// All returns the values in order; nil for an empty list.
+func (l *List[T]) All() []T {
+	var out []T
+	for ; l != nil; l = l.Next {
+		out = append(out, l.Value)
+	}
+	return out
+}
+
+// Once-guarded initialization for state that is expensive or needs more
+// than a make: Registry is usable as a zero value and builds its index the
+// first time it is needed.
+type Registry struct {
+	once  sync.Once
+	mu    sync.RWMutex
+	index map[string]func() any
+}
+
+func (r *Registry) init() {
+	r.once.Do(func() { r.index = map[string]func() any{} })
+}
+
+func (r *Registry) Register(name string, factory func() any) {
+	r.init()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.index[name] = factory
+}
+
+func (r *Registry) New(name string) (any, bool) {
+	r.init()
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	f, ok := r.index[name]
+	if !ok {
+		return nil, false
+	}
+	return f(), true
+}
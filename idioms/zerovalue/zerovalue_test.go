@@ -0,0 +1,152 @@
+package zerovalue_test
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"patterns/idioms/zerovalue"
+)
+
+// TestNeedsConstructor shows the alternative: the zero value compiles,
+// and panics on first use.
+func TestNeedsConstructor(t *testing.T) {
+	zerovalue.NewNeedsConstructor().Inc("hits")
+	defer func() {
+		if recover() == nil {
+			t.Error("Inc on a zero NeedsConstructor did not panic")
+		}
+	}()
+	var c zerovalue.NeedsConstructor
+	c.Inc("hits")
+}
+
+func TestCounter(t *testing.T) {
+	var c zerovalue.Counter
+	if got := c.Get("hits"); got != 0 {
+		t.Errorf("Get on a zero Counter = %d", got)
+	}
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 100 {
+				c.Inc("hits")
+			}
+		}()
+	}
+	wg.Wait()
+	if got := c.Get("hits"); got != 1000 {
+		t.Errorf("Get after 1000 concurrent Inc = %d", got)
+	}
+
+	// as a field, with no constructor for the struct holding it
+	var stats struct {
+		requests zerovalue.Counter
+	}
+	stats.requests.Inc("GET")
+	if got := stats.requests.Get("GET"); got != 1 {
+		t.Errorf("embedded Counter = %d, want 1", got)
+	}
+}
+
+func TestStack(t *testing.T) {
+	var s zerovalue.Stack[string]
+	if v, ok := s.Pop(); ok || v != "" || s.Len() != 0 {
+		t.Errorf("Pop on a zero Stack = %q, %v; Len %d", v, ok, s.Len())
+	}
+	s.Push("a")
+	s.Push("b")
+	var got []string
+	for s.Len() > 0 {
+		v, _ := s.Pop()
+		got = append(got, v)
+	}
+	if !slices.Equal(got, []string{"b", "a"}) {
+		t.Errorf("popped %v, want [b a]", got)
+	}
+}
+
+func TestClientDefaults(t *testing.T) {
+	var c zerovalue.Client
+	req, err := c.NewRequest(http.MethodGet, "/status")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := req.URL.String(); got != zerovalue.DefaultBaseURL+"/status" {
+		t.Errorf("URL of a zero Client = %s", got)
+	}
+}
+
+func TestClientDo(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			time.Sleep(200 * time.Millisecond)
+		}
+	}))
+	defer srv.Close()
+
+	for _, c := range []struct {
+		name    string
+		client  zerovalue.Client
+		path    string
+		timeout bool
+	}{
+		{"default client", zerovalue.Client{BaseURL: srv.URL}, "/", false},
+		{"own timeout", zerovalue.Client{BaseURL: srv.URL, Timeout: 20 * time.Millisecond}, "/slow", true},
+		// the http.Client's own timeout wins over the default
+		{"client timeout", zerovalue.Client{BaseURL: srv.URL, HTTP: &http.Client{Timeout: 20 * time.Millisecond}}, "/slow", true},
+	} {
+		req, err := c.client.NewRequest(http.MethodGet, c.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := c.client.Do(req)
+		if err == nil {
+			resp.Body.Close()
+		}
+		var ne net.Error
+		if timedOut := errors.As(err, &ne) && ne.Timeout(); timedOut != c.timeout || (!c.timeout && err != nil) {
+			t.Errorf("%s: Do = %v, want timeout %v", c.name, err, c.timeout)
+		}
+	}
+}
+
+func TestList(t *testing.T) {
+	var l *zerovalue.List[int]
+	if l.Len() != 0 || l.All() != nil {
+		t.Errorf("nil List: Len %d, All %v", l.Len(), l.All())
+	}
+	l = l.Prepend(3).Prepend(2).Prepend(1)
+	if l.Len() != 3 || !slices.Equal(l.All(), []int{1, 2, 3}) {
+		t.Errorf("List: Len %d, All %v", l.Len(), l.All())
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	var r zerovalue.Registry
+	if _, ok := r.New("csv"); ok {
+		t.Error("New on a zero Registry found a factory")
+	}
+	var wg sync.WaitGroup
+	for _, name := range []string{"csv", "json", "xml"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.Register(name, func() any { return name })
+			r.New(name)
+		}()
+	}
+	wg.Wait()
+	for _, name := range []string{"csv", "json", "xml"} {
+		if v, ok := r.New(name); !ok || v != name {
+			t.Errorf("New(%q) = %v, %v", name, v, ok)
+		}
+	}
+}
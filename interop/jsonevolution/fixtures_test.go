@@ -0,0 +1,129 @@
+package jsonevolution_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+
+	"patterns/interop/jsonevolution"
+)
+
+// fixture is one payload version and what decoding it must give.
+type fixture struct {
+	file    string
+	want    jsonevolution.Profile // without Unknown
+	unknown []string
+	// renamed are the fields written back under another name
+	renamed map[string]string
+	strict  bool // whether Strict accepts it
+}
+
+var fixtures = []fixture{
+	{
+		file:    "v1.json",
+		want:    jsonevolution.Profile{ID: "u1", Name: "Ann Lee", Email: "ann@example.com"},
+		renamed: map[string]string{"mail": "email"},
+	},
+	{
+		file:   "v2.json",
+		want:   jsonevolution.Profile{ID: "u1", Name: "Ann Lee", Email: "ann@example.com", Tags: []string{"admin", "beta"}},
+		strict: true,
+	},
+	{
+		file:    "v3.json",
+		want:    jsonevolution.Profile{ID: "u1", Name: "Ann Lee", Email: "ann@example.com", Tags: []string{"admin"}},
+		unknown: []string{"locale", "prefs"},
+	},
+}
+
+// TestFixtures decodes the v1, v2 and v3 payloads in testdata with each
+// decoder and checks what survives: the fields, the unknown ones, a
+// read-modify-write and strict mode.
+func TestFixtures(t *testing.T) {
+	for _, f := range fixtures {
+		t.Run(f.file, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join("testdata", f.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			p, err := jsonevolution.Decode(data, jsonevolution.Lenient)
+			if err != nil {
+				t.Errorf("Lenient: %v", err)
+			}
+			got := p
+			got.Unknown = nil
+			if !reflect.DeepEqual(got, f.want) {
+				t.Errorf("Lenient decoded %+v, want %+v", got, f.want)
+			}
+			if keys := slices.Sorted(maps.Keys(p.Unknown)); !slices.Equal(keys, f.unknown) {
+				t.Errorf("Unknown fields %v, want %v", keys, f.unknown)
+			}
+
+			// read, edit, write: everything but the edit and the renames
+			// must come back as it was
+			p.Name = "Ann L."
+			out, err := json.Marshal(p)
+			if err != nil {
+				t.Fatalf("Marshal: %v", err)
+			}
+			want := generic(data)
+			want["name"] = "Ann L."
+			for from, to := range f.renamed {
+				want[to] = want[from]
+				delete(want, from)
+			}
+			if written := generic(out); !reflect.DeepEqual(written, want) {
+				t.Errorf("written back as %s, want %v", out, want)
+			}
+
+			if _, err := jsonevolution.Decode(data, jsonevolution.Strict); (err == nil) != f.strict {
+				t.Errorf("Strict: err = %v, want accepted = %v", err, f.strict)
+			}
+
+			// DisallowUnknownFields does not reach a custom UnmarshalJSON
+			dec := json.NewDecoder(bytes.NewReader(data))
+			dec.DisallowUnknownFields()
+			var custom jsonevolution.Profile
+			if err := dec.Decode(&custom); err != nil {
+				t.Errorf("DisallowUnknownFields into Profile: %v; it was expected to be ignored", err)
+			}
+
+			// the naive struct: what a tolerant reader saves
+			var naive jsonevolution.NaiveProfile
+			json.Unmarshal(data, &naive)
+			naiveOut, _ := json.Marshal(naive)
+			lost := lostFields(data, naiveOut)
+			if f.file != "v2.json" && len(lost) == 0 {
+				t.Errorf("NaiveProfile kept every field; it was expected to lose some")
+			}
+			t.Logf("NaiveProfile loses %v", lost)
+		})
+	}
+}
+
+// generic decodes data as a plain map, for comparing documents.
+func generic(data []byte) map[string]any {
+	var m map[string]any
+	json.Unmarshal(data, &m)
+	return m
+}
+
+// lostFields returns the fields of in that out does not have, or has
+// with a different value.
+func lostFields(in, out []byte) []string {
+	a, b := generic(in), generic(out)
+	var lost []string
+	for k, v := range a {
+		if w, ok := b[k]; !ok || !reflect.DeepEqual(v, w) {
+			lost = append(lost, k)
+		}
+	}
+	slices.Sort(lost)
+	return lost
+}
@@ -0,0 +1,155 @@
+// Package jsonevolution decodes a JSON payload whose schema changes
+// between versions, so that readers and writers need not upgrade
+// together:
+//
+//	v1  {"id", "name", "mail"}
+//	v2  {"id", "name", "email", "tags"}     mail renamed to email
+//	v3  v2 plus {"locale", "prefs"}          written by newer code
+//
+// Profile is the v2 reader. Backward compatibility is its UnmarshalJSON
+// accepting the old name of a renamed field. Forward compatibility is
+// keeping what it does not know: the fields of v3 land in Unknown, as
+// json.RawMessage, and MarshalJSON writes them back unchanged, so a v2
+// service that reads, edits and writes a v3 document does not strip what
+// a v3 service put there.
+//
+// Strict decoding is for the other side of the same trade: a config file
+// or an API input where an unknown field is a typo worth failing on.
+// DisallowUnknownFields is the toggle, but it does not reach into a type
+// with its own UnmarshalJSON, which receives the raw bytes; Decode in
+// Strict mode therefore decodes into a plain wire struct instead.
+//
+// The tests decode each version's fixture in testdata every way and
+// check what survives.
+package jsonevolution
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+)
+
+// Mode chooses how Decode treats fields outside the v2 schema.
+type Mode int
+
+const (
+	// Lenient accepts v1's mail and keeps unknown fields.
+	Lenient Mode = iota
+	// Strict accepts exactly v2 and fails on anything else.
+	Strict
+)
+
+// tolerant reader
+// Level: Good
+// pros: old payloads keep decoding after a rename, and fields from newer
+// writers survive a read-modify-write by older code.
+// cons: a custom UnmarshalJSON and MarshalJSON per evolving type, and
+// Unknown must be carried along by whoever copies a Profile.
+//
+// Profile is the v2 payload.
+type Profile struct {
+	ID    string
+	Name  string
+	Email string
+	Tags  []string
+	// Unknown holds the fields this version does not know, by name, as
+	// they were received.
+	Unknown map[string]json.RawMessage
+}
+
+// wire is the v2 schema as a plain struct, what Strict decodes into.
+type wire struct {
+	ID    string   `json:"id"`
+	Name  string   `json:"name"`
+	Email string   `json:"email"`
+	Tags  []string `json:"tags,omitempty"`
+}
+
+// known are the field names UnmarshalJSON takes out of the payload; mail
+// is v1's name for email.
+var known = []string{"id", "name", "email", "mail", "tags"}
+
+func (p *Profile) UnmarshalJSON(data []byte) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	if fields == nil {
+		return errors.New("jsonevolution: profile is null")
+	}
+	if _, ok := fields["email"]; !ok {
+		if mail, ok := fields["mail"]; ok {
+			fields["email"] = mail
+		}
+	}
+	var w wire
+	for name, dst := range map[string]any{"id": &w.ID, "name": &w.Name, "email": &w.Email, "tags": &w.Tags} {
+		if raw, ok := fields[name]; ok {
+			if err := json.Unmarshal(raw, dst); err != nil {
+				return fmt.Errorf("jsonevolution: field %s: %w", name, err)
+			}
+		}
+	}
+	for _, name := range known {
+		delete(fields, name)
+	}
+	*p = Profile{ID: w.ID, Name: w.Name, Email: w.Email, Tags: w.Tags}
+	if len(fields) > 0 {
+		p.Unknown = fields
+	}
+	return nil
+}
+
+// MarshalJSON writes the v2 fields, never mail, and the unknown ones; a
+// known field wins over an unknown of the same name.
+func (p Profile) MarshalJSON() ([]byte, error) {
+	out := maps.Clone(p.Unknown)
+	if out == nil {
+		out = map[string]json.RawMessage{}
+	}
+	w, err := json.Marshal(wire{ID: p.ID, Name: p.Name, Email: p.Email, Tags: p.Tags})
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(w, &fields); err != nil {
+		return nil, err
+	}
+	maps.Copy(out, fields)
+	return json.Marshal(out)
+}
+
+// Decode reads one profile from data in mode.
+func Decode(data []byte, mode Mode) (Profile, error) {
+	if mode == Lenient {
+		var p Profile
+		err := json.Unmarshal(data, &p)
+		return p, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	var w wire
+	if err := dec.Decode(&w); err != nil {
+		return Profile{}, fmt.Errorf("jsonevolution: strict: %w", err)
+	}
+	if dec.More() {
+		return Profile{}, errors.New("jsonevolution: strict: data after the profile")
+	}
+	return Profile{ID: w.ID, Name: w.Name, Email: w.Email, Tags: w.Tags}, nil
+}
+
+// fixed struct decoding
+// Level: Poor
+// pros: no code beyond the struct tags.
+// cons: a v1 payload decodes without error and without its email, and a
+// v3 one loses locale and prefs the first time it is written back.
+//
+// NaiveProfile is the v2 payload as encoding/json decodes it by default.
+type NaiveProfile struct {
+	ID    string   `json:"id"`
+	Name  string   `json:"name"`
+	Email string   `json:"email"`
+	Tags  []string `json:"tags,omitempty"`
+}
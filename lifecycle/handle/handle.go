@@ -0,0 +1,188 @@
+// Package handle wraps acquired resources so that forgetting to close one
+// is reported instead of silently leaking:
+//
+//	var tracker handle.Tracker
+//	h := handle.Acquire(&tracker, "conn", conn, net.Conn.Close)
+//	defer h.Close()
+//	use(h.Get())
+//
+// In a test, handle.Verify(t, &tracker) fails the test at teardown if any
+// handle is still open. With Tracker.Debug set, each leak includes the
+// stack that acquired it.
+package handle
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrClosed is returned by a second Close.
+var ErrClosed = errors.New("handle already closed")
+
+// Tracker records open handles. The zero value is ready to use.
+type Tracker struct {
+	// Debug records the acquiring stack of every handle; it costs a
+	// runtime.Callers per Acquire, so it is meant for tests.
+	Debug bool
+
+	mu   sync.Mutex
+	next uint64
+	open map[uint64]*Leak
+}
+
+// Leak describes a handle that is still open.
+type Leak struct {
+	Name     string
+	Acquired time.Time
+	// Stack is empty unless Tracker.Debug was set at Acquire.
+	Stack string
+}
+
+func (l Leak) String() string {
+	s := fmt.Sprintf("%s (open %v)", l.Name, time.Since(l.Acquired).Round(time.Millisecond))
+	if l.Stack != "" {
+		s += "\n" + l.Stack
+	}
+	return s
+}
+
+func (t *Tracker) add(name string) uint64 {
+	l := &Leak{Name: name, Acquired: time.Now()}
+	if t.Debug {
+		l.Stack = stack(3)
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.open == nil {
+		t.open = make(map[uint64]*Leak)
+	}
+	t.next++
+	t.open[t.next] = l
+	return t.next
+}
+
+func (t *Tracker) remove(id uint64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.open, id)
+}
+
+// Open reports how many handles are open.
+func (t *Tracker) Open() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.open)
+}
+
+// Leaks returns the open handles, oldest first.
+func (t *Tracker) Leaks() []Leak {
+	t.mu.Lock()
+	ids := make([]uint64, 0, len(t.open))
+	for id := range t.open {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	leaks := make([]Leak, len(ids))
+	for i, id := range ids {
+		leaks[i] = *t.open[id]
+	}
+	t.mu.Unlock()
+	return leaks
+}
+
+// Check returns an error listing every open handle, or nil.
+func (t *Tracker) Check() error {
+	leaks := t.Leaks()
+	if len(leaks) == 0 {
+		return nil
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d unclosed handle(s):", len(leaks))
+	for _, l := range leaks {
+		b.WriteString("\n  ")
+		b.WriteString(strings.ReplaceAll(l.String(), "\n", "\n    "))
+	}
+	return errors.New(b.String())
+}
+
+// TB is the part of testing.TB that Verify needs; *testing.T satisfies it.
+type TB interface {
+	Helper()
+	Cleanup(func())
+	Errorf(format string, args ...any)
+}
+
+// Verify fails tb at teardown if t has open handles. Call it at the start
+// of a test, before acquiring anything.
+func Verify(tb TB, t *Tracker) {
+	tb.Helper()
+	tb.Cleanup(func() {
+		if err := t.Check(); err != nil {
+			tb.Errorf("%v", err)
+		}
+	})
+}
+
+func stack(skip int) string {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(skip+1, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	var b strings.Builder
+	for {
+		f, more := frames.Next()
+		if strings.HasPrefix(f.Function, "runtime.") {
+			break
+		}
+		fmt.Fprintf(&b, "%s\n\t%s:%d\n", f.Function, f.File, f.Line)
+		if !more {
+			break
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// Handle owns one resource of type T.
+type Handle[T any] struct {
+	v       T
+	name    string
+	release func(T) error
+	tracker *Tracker
+	id      uint64
+	closed  atomic.Bool
+}
+
+// Acquire wraps v, which release closes. A nil tracker tracks nothing.
+func Acquire[T any](t *Tracker, name string, v T, release func(T) error) *Handle[T] {
+	h := &Handle[T]{v: v, name: name, release: release, tracker: t}
+	if t != nil {
+		h.id = t.add(name)
+	}
+	return h
+}
+
+// Get returns the resource. Using a closed handle is a bug, so Get panics.
+func (h *Handle[T]) Get() T {
+	if h.closed.Load() {
+		panic("handle: " + h.name + " used after Close")
+	}
+	return h.v
+}
+
+func (h *Handle[T]) Closed() bool { return h.closed.Load() }
+
+// Close releases the resource once; later calls return ErrClosed.
+func (h *Handle[T]) Close() error {
+	if !h.closed.CompareAndSwap(false, true) {
+		return fmt.Errorf("%s: %w", h.name, ErrClosed)
+	}
+	if h.tracker != nil {
+		h.tracker.remove(h.id)
+	}
+	return h.release(h.v)
+}
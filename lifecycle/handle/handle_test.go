@@ -0,0 +1,169 @@
+package handle_test
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"patterns/lifecycle/handle"
+)
+
+// resource counts its closes.
+type resource struct{ closes int }
+
+func release(r *resource) error {
+	r.closes++
+	return nil
+}
+
+func TestHandle(t *testing.T) {
+	var tracker handle.Tracker
+	handle.Verify(t, &tracker)
+	r := &resource{}
+	h := handle.Acquire(&tracker, "r", r, release)
+	if h.Get() != r || h.Closed() || tracker.Open() != 1 {
+		t.Fatalf("after Acquire: Get %p, Closed %v, Open %d", h.Get(), h.Closed(), tracker.Open())
+	}
+	if err := h.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Close(); !errors.Is(err, handle.ErrClosed) || err.Error() != "r: handle already closed" {
+		t.Errorf("second Close = %v, want ErrClosed", err)
+	}
+	if r.closes != 1 || !h.Closed() || tracker.Open() != 0 {
+		t.Errorf("after Close: %d closes, Closed %v, Open %d", r.closes, h.Closed(), tracker.Open())
+	}
+	defer func() {
+		if p := recover(); p != "handle: r used after Close" {
+			t.Errorf("Get after Close panicked with %v", p)
+		}
+	}()
+	h.Get()
+}
+
+func TestReleaseError(t *testing.T) {
+	var tracker handle.Tracker
+	errReset := errors.New("connection reset")
+	h := handle.Acquire(&tracker, "conn", 1, func(int) error { return errReset })
+	if err := h.Close(); err != errReset {
+		t.Errorf("Close = %v, want the release error", err)
+	}
+	// a failed release still ends the handle
+	if tracker.Open() != 0 || !errors.Is(h.Close(), handle.ErrClosed) {
+		t.Errorf("after a failed release: Open %d", tracker.Open())
+	}
+}
+
+func TestNilTracker(t *testing.T) {
+	h := handle.Acquire(nil, "untracked", &resource{}, release)
+	if err := h.Close(); err != nil {
+		t.Error(err)
+	}
+}
+
+// acquireHere is a named frame for the Debug stack to show.
+func acquireHere(tracker *handle.Tracker, name string) *handle.Handle[*resource] {
+	return handle.Acquire(tracker, name, &resource{}, release)
+}
+
+func TestLeaks(t *testing.T) {
+	for _, debug := range []bool{false, true} {
+		tracker := &handle.Tracker{Debug: debug}
+		a := acquireHere(tracker, "a")
+		b := acquireHere(tracker, "b")
+		c := acquireHere(tracker, "c")
+		b.Close()
+
+		leaks := tracker.Leaks()
+		if len(leaks) != 2 || leaks[0].Name != "a" || leaks[1].Name != "c" {
+			t.Fatalf("debug %v: leaks = %v, want a and c, oldest first", debug, leaks)
+		}
+		for _, l := range leaks {
+			hasStack := strings.Contains(l.Stack, "handle_test.acquireHere") && strings.Contains(l.Stack, "handle_test.go:")
+			if hasStack != debug {
+				t.Errorf("debug %v: stack of %s = %q", debug, l.Name, l.Stack)
+			}
+		}
+
+		err := tracker.Check()
+		if err == nil || !strings.HasPrefix(err.Error(), "2 unclosed handle(s):\n  a (open ") {
+			t.Errorf("debug %v: Check = %v", debug, err)
+		}
+		// stack lines are indented under their handle
+		if debug && !strings.Contains(err.Error(), "\n    patterns/lifecycle/handle_test.acquireHere\n") {
+			t.Errorf("Check does not indent the stacks:\n%v", err)
+		}
+		a.Close()
+		c.Close()
+		if err := tracker.Check(); err != nil {
+			t.Errorf("debug %v: Check after closing all = %v", debug, err)
+		}
+	}
+}
+
+// fakeTB records what Verify reports, and runs its cleanups on demand.
+type fakeTB struct {
+	cleanups []func()
+	errors   []string
+}
+
+func (*fakeTB) Helper()             {}
+func (tb *fakeTB) Cleanup(f func()) { tb.cleanups = append(tb.cleanups, f) }
+func (tb *fakeTB) Errorf(format string, args ...any) {
+	tb.errors = append(tb.errors, fmt.Sprintf(format, args...))
+}
+func (tb *fakeTB) teardown() {
+	for i := len(tb.cleanups) - 1; i >= 0; i-- {
+		tb.cleanups[i]()
+	}
+}
+
+func TestVerify(t *testing.T) {
+	for _, leak := range []bool{false, true} {
+		tb := &fakeTB{}
+		tracker := &handle.Tracker{Debug: true}
+		handle.Verify(tb, tracker)
+		h := acquireHere(tracker, "conn 1")
+		if !leak {
+			h.Close()
+		}
+		tb.teardown()
+		if reported := len(tb.errors) == 1 && strings.Contains(tb.errors[0], "conn 1 (open "); reported != leak || (!leak && len(tb.errors) != 0) {
+			t.Errorf("leak %v: Verify reported %q", leak, tb.errors)
+		}
+	}
+}
+
+func TestConcurrent(t *testing.T) {
+	var tracker handle.Tracker
+	handle.Verify(t, &tracker)
+	var wg sync.WaitGroup
+	for i := range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			h := handle.Acquire(&tracker, fmt.Sprint("h", i), &resource{}, func(*resource) error { return nil })
+			tracker.Leaks()
+			// racing closes: exactly one wins
+			var closes sync.WaitGroup
+			var ok atomic.Int32
+			for range 3 {
+				closes.Add(1)
+				go func() {
+					defer closes.Done()
+					if h.Close() == nil {
+						ok.Add(1)
+					}
+				}()
+			}
+			closes.Wait()
+			if n := ok.Load(); n != 1 {
+				t.Errorf("%d closes succeeded, want 1", n)
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,155 @@
+// Package multicloser collects cleanups while something is being built
+// and runs them in reverse order, either to roll back a constructor that
+// failed halfway or to shut the finished value down:
+//
+//	func Open(...) (_ *Thing, err error) {
+//		var mc multicloser.Stack
+//		defer mc.Rollback(&err) // closes what was opened if err != nil
+//
+//		db, err := openDB()
+//		if err != nil {
+//			return nil, err
+//		}
+//		mc.Add("db", db)
+//		...
+//		return &Thing{closer: mc.Release()}, nil
+//	}
+//
+//	func (t *Thing) Close() error { return t.closer.Close() }
+package multicloser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+	"time"
+)
+
+// ErrTimeout is joined into Close's error for a cleanup that did not
+// finish within Stack.Timeout.
+var ErrTimeout = errors.New("close timed out")
+
+type entry struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+// Stack is a LIFO list of cleanups. The zero value is ready to use.
+type Stack struct {
+	// Timeout bounds each cleanup; zero means wait as long as it takes.
+	// A cleanup that times out keeps running in the background, and the
+	// stack moves on to the next one.
+	Timeout time.Duration
+
+	mu      sync.Mutex
+	entries []entry
+	closed  bool
+}
+
+// Add registers c to be closed.
+func (s *Stack) Add(name string, c io.Closer) {
+	s.push(name, func(context.Context) error { return c.Close() })
+}
+
+// Defer registers a cleanup func.
+func (s *Stack) Defer(name string, fn func() error) {
+	s.push(name, func(context.Context) error { return fn() })
+}
+
+// DeferContext registers a cleanup that honours the per-close deadline
+// itself, such as http.Server.Shutdown.
+func (s *Stack) DeferContext(name string, fn func(ctx context.Context) error) {
+	s.push(name, fn)
+}
+
+func (s *Stack) push(name string, fn func(ctx context.Context) error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		// registering after Close would silently leak the resource
+		panic("multicloser: " + name + " added after Close")
+	}
+	s.entries = append(s.entries, entry{name: name, fn: fn})
+}
+
+// Len reports how many cleanups are registered.
+func (s *Stack) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.entries)
+}
+
+// Close runs every cleanup, last added first, and joins their errors.
+// It runs all of them even if some fail. Calls after the first return nil.
+func (s *Stack) Close() error {
+	return s.CloseContext(context.Background())
+}
+
+// CloseContext is Close with a deadline for the whole stack on top of the
+// per-close Timeout; once ctx is done the remaining cleanups still run
+// but do not wait.
+func (s *Stack) CloseContext(ctx context.Context) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	entries := s.entries
+	s.entries = nil
+	s.mu.Unlock()
+
+	var errs []error
+	for _, e := range slices.Backward(entries) {
+		if err := s.run(ctx, e); err != nil {
+			errs = append(errs, fmt.Errorf("close %s: %w", e.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (s *Stack) run(ctx context.Context, e entry) error {
+	if s.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.Timeout)
+		defer cancel()
+	}
+	if ctx.Done() == nil {
+		return e.fn(ctx)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- e.fn(ctx) }()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ErrTimeout
+	}
+}
+
+// Rollback closes the stack if *err is non-nil, joining close errors into
+// *err. Defer it at the top of a constructor with a named error result.
+func (s *Stack) Rollback(err *error) {
+	if *err == nil {
+		return
+	}
+	if cerr := s.Close(); cerr != nil {
+		*err = errors.Join(*err, cerr)
+	}
+}
+
+// Release moves every cleanup into a new Stack owned by the caller,
+// leaving s empty so a deferred Rollback does nothing. It is the success
+// path of a constructor: the built value keeps the returned Stack and
+// closes it on shutdown.
+func (s *Stack) Release() *Stack {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := &Stack{Timeout: s.Timeout, entries: s.entries}
+	s.entries = nil
+	return out
+}
@@ -0,0 +1,223 @@
+package multicloser_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"patterns/lifecycle/multicloser"
+)
+
+// world is the resources a test opens and the order they are closed in.
+type world struct {
+	mu     sync.Mutex
+	closed []string
+	// failOpen and failClose name the resources whose open and close fail
+	failOpen, failClose string
+}
+
+type resource struct {
+	w    *world
+	name string
+}
+
+func (r *resource) Close() error {
+	r.w.mu.Lock()
+	defer r.w.mu.Unlock()
+	r.w.closed = append(r.w.closed, r.name)
+	if r.name == r.w.failClose {
+		return fmt.Errorf("%s: broken pipe", r.name)
+	}
+	return nil
+}
+
+func (w *world) open(name string) (*resource, error) {
+	if name == w.failOpen {
+		return nil, fmt.Errorf("open %s: connection refused", name)
+	}
+	return &resource{w: w, name: name}, nil
+}
+
+// service is built from several resources, the way the package comment
+// shows.
+type service struct{ closer *multicloser.Stack }
+
+func (s *service) Close() error { return s.closer.Close() }
+
+func newService(w *world, names ...string) (_ *service, err error) {
+	var mc multicloser.Stack
+	defer mc.Rollback(&err)
+	for _, name := range names {
+		r, err := w.open(name)
+		if err != nil {
+			return nil, err
+		}
+		mc.Add(name, r)
+	}
+	return &service{closer: mc.Release()}, nil
+}
+
+var resources = []string{"db", "cache", "queue", "listener"}
+
+// TestRollback fails each step of a constructor in turn: what was opened
+// before it is closed, newest first, and nothing else.
+func TestRollback(t *testing.T) {
+	for i, fail := range resources {
+		w := &world{failOpen: fail}
+		s, err := newService(w, resources...)
+		if s != nil || err == nil || err.Error() != "open "+fail+": connection refused" {
+			t.Errorf("failing %s: newService = %v, %v", fail, s, err)
+		}
+		want := slices.Clone(resources[:i])
+		slices.Reverse(want)
+		if !slices.Equal(w.closed, want) {
+			t.Errorf("failing %s: closed %v, want %v", fail, w.closed, want)
+		}
+	}
+}
+
+// TestRelease checks that a constructor that succeeds closes nothing,
+// and that the service it built closes everything once.
+func TestRelease(t *testing.T) {
+	w := &world{}
+	s, err := newService(w, resources...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(w.closed) != 0 {
+		t.Fatalf("closed %v during a successful construction", w.closed)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Errorf("second Close = %v, want nil", err)
+	}
+	if want := []string{"listener", "queue", "cache", "db"}; !slices.Equal(w.closed, want) {
+		t.Errorf("closed %v, want %v", w.closed, want)
+	}
+}
+
+// TestRollbackCloseError checks that a rollback whose close fails
+// reports both the construction error and the close error.
+func TestRollbackCloseError(t *testing.T) {
+	w := &world{failOpen: "queue", failClose: "db"}
+	_, err := newService(w, resources...)
+	want := "open queue: connection refused\nclose db: db: broken pipe"
+	if err == nil || err.Error() != want {
+		t.Errorf("newService = %v, want\n%s", err, want)
+	}
+	if !slices.Equal(w.closed, []string{"cache", "db"}) {
+		t.Errorf("closed %v, want [cache db]", w.closed)
+	}
+}
+
+// TestCloseAll checks that Close goes on past failing cleanups and joins
+// their errors in the order they ran.
+func TestCloseAll(t *testing.T) {
+	var s multicloser.Stack
+	var ran []string
+	errA, errC := errors.New("a failed"), errors.New("c failed")
+	s.Defer("a", func() error { ran = append(ran, "a"); return errA })
+	s.Defer("b", func() error { ran = append(ran, "b"); return nil })
+	s.DeferContext("c", func(context.Context) error { ran = append(ran, "c"); return errC })
+	if s.Len() != 3 {
+		t.Errorf("Len = %d, want 3", s.Len())
+	}
+	err := s.Close()
+	if !slices.Equal(ran, []string{"c", "b", "a"}) {
+		t.Errorf("ran %v, want [c b a]", ran)
+	}
+	if !errors.Is(err, errA) || !errors.Is(err, errC) || err.Error() != "close c: c failed\nclose a: a failed" {
+		t.Errorf("Close = %v", err)
+	}
+	if s.Len() != 0 {
+		t.Errorf("Len after Close = %d", s.Len())
+	}
+}
+
+func TestAddAfterClose(t *testing.T) {
+	var s multicloser.Stack
+	s.Close()
+	defer func() {
+		if p := recover(); p == nil || !strings.Contains(fmt.Sprint(p), "late added after Close") {
+			t.Errorf("panic = %v", p)
+		}
+	}()
+	s.Defer("late", func() error { return nil })
+}
+
+// TestTimeout has a cleanup hang: Close gives up on it after the timeout
+// and still runs the ones before it.
+func TestTimeout(t *testing.T) {
+	s := multicloser.Stack{Timeout: 20 * time.Millisecond}
+	hang := make(chan struct{})
+	defer close(hang)
+	var ran []string
+	s.Defer("first", func() error { ran = append(ran, "first"); return nil })
+	s.Defer("hangs", func() error { <-hang; return nil })
+	s.DeferContext("honours", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	// a released stack keeps the timeout
+	released := s.Release()
+	start := time.Now()
+	err := released.Close()
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("Close took %v", d)
+	}
+	if !errors.Is(err, multicloser.ErrTimeout) || !strings.Contains(err.Error(), "close hangs: close timed out") {
+		t.Errorf("Close = %v, want a timeout for hangs", err)
+	}
+	// the cleanup honouring the context may report either
+	if !strings.Contains(err.Error(), "close honours: ") {
+		t.Errorf("Close = %v, want an error for honours", err)
+	}
+	if !slices.Equal(ran, []string{"first"}) {
+		t.Errorf("ran %v, want [first]", ran)
+	}
+}
+
+// TestCloseContext closes with a context already done: every cleanup
+// still runs, none is waited for.
+func TestCloseContext(t *testing.T) {
+	var s multicloser.Stack
+	var mu sync.Mutex
+	var ran []string
+	for _, name := range []string{"a", "b"} {
+		s.DeferContext(name, func(ctx context.Context) error {
+			mu.Lock()
+			ran = append(ran, name)
+			mu.Unlock()
+			return ctx.Err()
+		})
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := s.CloseContext(ctx); err == nil {
+		t.Error("CloseContext with a done context succeeded")
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		mu.Lock()
+		n := len(ran)
+		mu.Unlock()
+		if n == 2 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	slices.Sort(ran)
+	if !slices.Equal(ran, []string{"a", "b"}) {
+		t.Errorf("ran %v, want both", ran)
+	}
+}
@@ -0,0 +1,56 @@
+package shutdown
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+
+	"patterns/concurrency/workerpool"
+)
+
+// HTTPServer runs srv on l. Stopping it closes the listener and waits
+// for in-flight requests; those still running when the group's Timeout
+// passes have their connections closed.
+func HTTPServer(name string, srv *http.Server, l net.Listener) Component {
+	return Component{
+		Name: name,
+		Run: func(context.Context) error {
+			if err := srv.Serve(l); !errors.Is(err, http.ErrServerClosed) {
+				return err
+			}
+			return nil
+		},
+		Stop: func(ctx context.Context) error {
+			// Serve returns as soon as the listener is closed; it is
+			// Shutdown that waits for the requests
+			err := srv.Shutdown(ctx)
+			if errors.Is(err, context.DeadlineExceeded) {
+				srv.Close()
+				return ErrTimeout
+			}
+			return err
+		},
+	}
+}
+
+// WorkerPool passes each of p's results to handle until p has stopped.
+// Stopping it closes p: the inputs already queued still run, and it
+// returns once their results are handled. The group does not wait past
+// its Timeout, but it cannot cancel p's work either; make p with a
+// context of its own to cut running inputs off.
+func WorkerPool[In, Out any](name string, p *workerpool.Pool[In, Out], handle func(workerpool.Result[In, Out])) Component {
+	return Component{
+		Name: name,
+		Run: func(ctx context.Context) error {
+			for r := range p.Results() {
+				handle(r)
+			}
+			return nil
+		},
+		Stop: func(context.Context) error {
+			p.Close()
+			return nil
+		},
+	}
+}
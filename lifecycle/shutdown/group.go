@@ -0,0 +1,130 @@
+package shutdown
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrTimeout is joined into Run's error for each component that was
+// still running when the group's Timeout passed.
+var ErrTimeout = errors.New("shutdown timed out")
+
+// Component is one long-running part of a process.
+type Component struct {
+	Name string
+	// Run does the component's work and returns when it is done: when
+	// Stop has told it to, or when it failed. Its ctx is cancelled only
+	// if a graceful stop takes longer than the group allows.
+	Run func(ctx context.Context) error
+	// Stop asks Run to finish the work in hand and return, before ctx is
+	// done. Nil means the component stops when Run's ctx is cancelled.
+	Stop func(ctx context.Context) error
+}
+
+// run group
+// Level: Good
+// pros: one place decides when the process stops and in which order its
+// parts drain; a component failing stops the others instead of leaving
+// a server up with its workers gone.
+// cons: every component must be written to stop when asked; the order
+// of Add is the dependency order, and nothing checks it.
+//
+// Group runs components until ctx is done or one of them returns, then
+// stops the rest. The zero value is ready to use.
+type Group struct {
+	// Timeout bounds the whole shutdown, every Stop and the Run it ends
+	// taken together; zero means wait as long as it takes.
+	Timeout time.Duration
+
+	components []Component
+}
+
+// Add registers c. Components added later stop first.
+func (g *Group) Add(c Component) {
+	g.components = append(g.components, c)
+}
+
+type running struct {
+	Component
+	cancel context.CancelFunc
+	done   chan struct{}
+	err    error
+}
+
+// Run starts every component and blocks until all have returned. It
+// returns the errors that stopped the group or came up while stopping
+// it, each naming its component; ctx being done is not one of them.
+func (g *Group) Run(ctx context.Context) error {
+	if len(g.components) == 0 {
+		return nil
+	}
+	// a component must keep running while the others drain, after ctx
+	// is done
+	base := context.WithoutCancel(ctx)
+	rs := make([]*running, len(g.components))
+	exited := make(chan *running, len(rs))
+	for i, c := range g.components {
+		runCtx, cancel := context.WithCancel(base)
+		r := &running{Component: c, cancel: cancel, done: make(chan struct{})}
+		rs[i] = r
+		go func() {
+			defer close(r.done)
+			r.err = r.Run(runCtx)
+			exited <- r
+		}()
+	}
+	defer func() {
+		for _, r := range rs {
+			r.cancel()
+		}
+	}()
+
+	var errs []error
+	select {
+	case <-ctx.Done():
+	case r := <-exited:
+		if r.err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", r.Name, r.err))
+		}
+	}
+
+	stopCtx := base
+	if g.Timeout > 0 {
+		var cancel context.CancelFunc
+		stopCtx, cancel = context.WithTimeout(base, g.Timeout)
+		defer cancel()
+	}
+	for i := len(rs) - 1; i >= 0; i-- {
+		errs = append(errs, rs[i].stop(stopCtx)...)
+	}
+	return errors.Join(errs...)
+}
+
+// stop stops r, unless it has returned already, and waits until it
+// returns or ctx is done, cancelling its Run then.
+func (r *running) stop(ctx context.Context) []error {
+	select {
+	case <-r.done:
+		return nil
+	default:
+	}
+	var errs []error
+	if r.Stop == nil {
+		r.cancel()
+	} else if err := r.Stop(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("%s: stop: %w", r.Name, err))
+	}
+	select {
+	case <-r.done:
+		if r.err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", r.Name, r.err))
+		}
+	case <-ctx.Done():
+		// cut it off; it may still return, later, in the background
+		r.cancel()
+		errs = append(errs, fmt.Errorf("%s: %w", r.Name, ErrTimeout))
+	}
+	return errs
+}
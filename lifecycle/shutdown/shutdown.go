@@ -0,0 +1,37 @@
+// Package shutdown stops servers gracefully: stop accepting, let in-flight
+// requests finish within a deadline, then return.
+//
+// A process is seldom one server. Group runs several components, an HTTP
+// server and the worker pool its handlers feed, say, and stops them all
+// when the first signal arrives or any one of them fails:
+//
+//	ctx, stop := shutdown.Signals(context.Background(), os.Interrupt, syscall.SIGTERM)
+//	defer stop()
+//
+//	g := shutdown.Group{Timeout: 10 * time.Second}
+//	g.Add(shutdown.WorkerPool("jobs", pool, record))
+//	g.Add(shutdown.HTTPServer("http", srv, l))
+//	err := g.Run(ctx)
+//
+// Components stop in the reverse of the order they were added, each
+// finishing before the next is asked to: the server stops accepting and
+// waits for its handlers, and only then is the pool closed, so the jobs
+// those last requests submitted still run. The whole shutdown shares one
+// Timeout; when it passes, whatever is still running is cut off.
+package shutdown
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"time"
+)
+
+// Serve runs srv on l until ctx is done, then shuts it down, giving
+// in-flight requests up to timeout to complete; after that the remaining
+// connections are closed and Serve fails with ErrTimeout.
+func Serve(ctx context.Context, srv *http.Server, l net.Listener, timeout time.Duration) error {
+	g := Group{Timeout: timeout}
+	g.Add(HTTPServer("http", srv, l))
+	return g.Run(ctx)
+}
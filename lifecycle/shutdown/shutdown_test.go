@@ -0,0 +1,265 @@
+package shutdown_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"patterns/concurrency/workerpool"
+	"patterns/idioms/must"
+	"patterns/lifecycle/shutdown"
+)
+
+// TestShutdown checks the Group against real servers on httptest
+// listeners: requests in flight when shutdown starts finish, the jobs
+// they queued run, a request that will not finish is cut off at the
+// deadline, a failing component stops the rest, and a signal is
+// reported as the cause.
+func TestShutdown(t *testing.T) {
+	for _, s := range []struct {
+		name string
+		run  func() error
+	}{
+		{"in-flight requests finish", inFlight},
+		{"queued jobs run after the server stops", queuedJobs},
+		{"a hung request is cut off at the deadline", hung},
+		{"a failing component stops the others", failing},
+		{"a signal is the cause", signalled},
+	} {
+		t.Run(s.name, func(t *testing.T) {
+			if err := s.run(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+// server is a handler on an httptest listener, not yet serving: the
+// group under test serves it.
+type server struct {
+	ts  *httptest.Server
+	url string
+}
+
+func newServer(h http.Handler) server {
+	ts := httptest.NewUnstartedServer(h)
+	return server{ts: ts, url: "http://" + ts.Listener.Addr().String()}
+}
+
+func (s server) component() shutdown.Component {
+	return shutdown.HTTPServer("http", s.ts.Config, s.ts.Listener)
+}
+
+// group starts g on its own goroutine; wait returns Run's error.
+func group(ctx context.Context, g *shutdown.Group) (wait func() error) {
+	errc := make(chan error, 1)
+	go func() { errc <- g.Run(ctx) }()
+	return func() error { return <-errc }
+}
+
+// get fetches url n times at once and returns the bodies, or the first
+// error.
+func get(url string, n int) ([]string, error) {
+	bodies := make([]string, n)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := http.Get(url)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			defer resp.Body.Close()
+			b, err := io.ReadAll(resp.Body)
+			bodies[i], errs[i] = string(b), err
+		}()
+	}
+	wg.Wait()
+	return bodies, errors.Join(errs...)
+}
+
+func inFlight() error {
+	const n = 5
+	var started sync.WaitGroup
+	started.Add(n)
+	s := newServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started.Done()
+		time.Sleep(200 * time.Millisecond)
+		io.WriteString(w, "done")
+	}))
+	ctx, cancel := context.WithCancel(context.Background())
+	g := shutdown.Group{Timeout: 5 * time.Second}
+	g.Add(s.component())
+	wait := group(ctx, &g)
+
+	type got struct {
+		bodies []string
+		err    error
+	}
+	res := make(chan got, 1)
+	go func() {
+		bodies, err := get(s.url, n)
+		res <- got{bodies, err}
+	}()
+	started.Wait()
+	cancel()
+	if err := wait(); err != nil {
+		return fmt.Errorf("Run: %v", err)
+	}
+	r := <-res
+	if r.err != nil {
+		return fmt.Errorf("a request in flight failed: %v", r.err)
+	}
+	for _, b := range r.bodies {
+		if b != "done" {
+			return fmt.Errorf("body of a request in flight = %q, want %q", b, "done")
+		}
+	}
+	if _, err := get(s.url, 1); err == nil {
+		return errors.New("the server still answers after Run returned")
+	}
+	return nil
+}
+
+func queuedJobs() error {
+	var ran atomic.Int64
+	pool, err := workerpool.New(context.Background(), func(ctx context.Context, id int) (int, error) {
+		time.Sleep(20 * time.Millisecond)
+		ran.Add(1)
+		return id, nil
+	}, workerpool.WithWorkers(2), workerpool.WithQueue(100))
+	if err != nil {
+		return err
+	}
+	var accepted, handled atomic.Int64
+	var started sync.WaitGroup
+	const n = 10
+	started.Add(n)
+	s := newServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started.Done()
+		// still handling when shutdown starts
+		time.Sleep(100 * time.Millisecond)
+		if err := pool.Submit(r.Context(), 1); err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		accepted.Add(1)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	g := shutdown.Group{Timeout: 5 * time.Second}
+	g.Add(shutdown.WorkerPool("jobs", pool, func(workerpool.Result[int, int]) { handled.Add(1) }))
+	g.Add(s.component())
+	wait := group(ctx, &g)
+	done := make(chan error, 1)
+	go func() {
+		_, err := get(s.url, n)
+		done <- err
+	}()
+	started.Wait()
+	cancel()
+	if err := wait(); err != nil {
+		return fmt.Errorf("Run: %v", err)
+	}
+	if err := <-done; err != nil {
+		return fmt.Errorf("a request in flight failed: %v", err)
+	}
+	if accepted.Load() != n {
+		return fmt.Errorf("%d of %d jobs accepted: the pool closed before the server drained", accepted.Load(), n)
+	}
+	if ran.Load() != n || handled.Load() != n {
+		return fmt.Errorf("%d jobs accepted, %d ran, %d results handled", n, ran.Load(), handled.Load())
+	}
+	return nil
+}
+
+func hung() error {
+	var started sync.WaitGroup
+	started.Add(1)
+	s := newServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started.Done()
+		<-r.Context().Done() // until the connection is closed
+	}))
+	ctx, cancel := context.WithCancel(context.Background())
+	g := shutdown.Group{Timeout: 100 * time.Millisecond}
+	g.Add(s.component())
+	wait := group(ctx, &g)
+	done := make(chan error, 1)
+	go func() {
+		_, err := get(s.url, 1)
+		done <- err
+	}()
+	started.Wait()
+	start := time.Now()
+	cancel()
+	err := wait()
+	if !errors.Is(err, shutdown.ErrTimeout) {
+		return fmt.Errorf("Run: err = %v, want %v", err, shutdown.ErrTimeout)
+	}
+	if d := time.Since(start); d > time.Second {
+		return fmt.Errorf("shutdown took %v with a Timeout of 100ms", d)
+	}
+	select {
+	case err := <-done:
+		if err == nil {
+			return errors.New("the hung request succeeded")
+		}
+	case <-time.After(time.Second):
+		return errors.New("the hung request's connection is still open")
+	}
+	return nil
+}
+
+func failing() error {
+	errLost := errors.New("database lost")
+	s := newServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	var g shutdown.Group
+	g.Add(shutdown.Component{Name: "db", Run: func(ctx context.Context) error {
+		select {
+		case <-time.After(50 * time.Millisecond):
+			return errLost
+		case <-ctx.Done():
+			return nil
+		}
+	}})
+	g.Add(s.component())
+	err := group(context.Background(), &g)()
+	if !errors.Is(err, errLost) {
+		return fmt.Errorf("Run: err = %v, want %v", err, errLost)
+	}
+	if _, err := get(s.url, 1); err == nil {
+		return errors.New("the server still answers after the database failed")
+	}
+	return nil
+}
+
+func signalled() error {
+	ctx, stop := shutdown.Signals(context.Background(), os.Interrupt)
+	defer stop()
+	p := must.Must(os.FindProcess(os.Getpid()))
+	if err := p.Signal(os.Interrupt); err != nil {
+		return fmt.Errorf("cannot signal this process: %v", err)
+	}
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		return errors.New("the context was not cancelled by the signal")
+	}
+	var sig *shutdown.SignalError
+	if cause := context.Cause(ctx); !errors.As(cause, &sig) || sig.Signal != os.Interrupt {
+		return fmt.Errorf("cause = %v, want %v", cause, &shutdown.SignalError{Signal: os.Interrupt})
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package shutdown
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+)
+
+// SignalError is the cause of a context cancelled by Signals.
+type SignalError struct {
+	Signal os.Signal
+}
+
+func (e *SignalError) Error() string {
+	return fmt.Sprintf("received %v", e.Signal)
+}
+
+// Signals returns a copy of ctx cancelled when the first of sigs
+// arrives, with a *SignalError as its cause, so a log can say why the
+// process stopped. After the first, sigs are no longer caught: a second
+// one has its default effect, and an operator whose shutdown hangs can
+// press Ctrl-C again to kill the process. Calling stop releases the
+// signals early.
+func Signals(ctx context.Context, sigs ...os.Signal) (_ context.Context, stop func()) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, sigs...)
+	go func() {
+		select {
+		case sig := <-c:
+			// release the signals first, so a second one kills even a
+			// process stuck in shutdown
+			signal.Stop(c)
+			cancel(&SignalError{Signal: sig})
+		case <-ctx.Done():
+			signal.Stop(c)
+		}
+	}()
+	var once sync.Once
+	return ctx, func() {
+		once.Do(func() {
+			signal.Stop(c)
+			cancel(context.Canceled)
+		})
+	}
+}
@@ -0,0 +1,342 @@
+// Package delayed publishes messages later: after a duration or at a
+// time, to whoever Run hands them to. It decouples the producer from
+// when the message is consumed, as a queue decouples it from who
+// consumes it: a reminder email is scheduled when the order is placed,
+// for three days later, and nothing has to stay running in between
+// but the scheduler.
+//
+// Scheduled messages are kept in a file rewritten atomically, like the
+// job queue's store, so they survive a restart. Messages that fell due
+// while the scheduler was down are delivered as soon as Run starts,
+// oldest first; sqlite would be the production store, but is not
+// available without cgo or a third-party driver.
+//
+// Delivery is at least once: a message is removed only after Deliver
+// returns nil, so a crash in between delivers it again. Deliver is
+// retried with exponential backoff while it fails. examples/jobqueue's
+// Delayed delivers into the job queue, deduplicating by message ID.
+//
+// The scheduler keeps one timer, for the earliest message, and resets it
+// when a message due sooner is scheduled; clock.Fake makes firing exact:
+// a message is delivered in the Advance that reaches its due time.
+package delayed
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"patterns/clock"
+	"patterns/construct"
+	"patterns/funcopts"
+	"patterns/validate"
+)
+
+// Message is a scheduled message.
+type Message struct {
+	// ID is assigned by the scheduler and unique within its file.
+	ID      string          `json:"id"`
+	Topic   string          `json:"topic"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+	// Due is when the message is delivered; after a failed delivery, when
+	// it is retried.
+	Due time.Time `json:"due"`
+	// Attempts counts the failed deliveries.
+	Attempts  int    `json:"attempts,omitempty"`
+	LastError string `json:"last_error,omitempty"`
+}
+
+// Deliver hands a due message on; an error has it retried later.
+type Deliver func(ctx context.Context, m Message) error
+
+// ErrRunning is returned by Run while another Run is delivering.
+var ErrRunning = errors.New("delayed: scheduler already running")
+
+type options struct {
+	clock     clock.Clock
+	baseRetry time.Duration
+	maxRetry  time.Duration
+}
+
+type Option = funcopts.Option[options]
+
+// WithClock sets the clock messages are scheduled and fired by.
+func WithClock(c clock.Clock) Option {
+	return func(options *options) error {
+		if c == nil {
+			return errors.New("clock cannot be nil")
+		}
+		options.clock = c
+		return nil
+	}
+}
+
+// WithRetry sets the delay before retrying a failed delivery, doubling
+// per attempt from base up to max; the default is 1s to 5m.
+func WithRetry(base, max time.Duration) Option {
+	return func(options *options) error {
+		if base <= 0 {
+			return errors.New("base must be positive")
+		}
+		options.baseRetry, options.maxRetry = base, max
+		return nil
+	}
+}
+
+func (o *options) SetDefaults() {
+	*o = options{clock: clock.Real, baseRetry: time.Second, maxRetry: 5 * time.Minute}
+}
+
+func (o *options) Validate() error {
+	var v validate.Validator
+	validate.Field(&v, "maxRetry", o.maxRetry, validate.Min(o.baseRetry))
+	return v.Err()
+}
+
+// delayed message
+// Level: Good
+// pros: the producer schedules and forgets; scheduled messages outlive the
+// process, and ones missed while it was down are caught up on start.
+// cons: at least once, so consumers must be idempotent; one file holds
+// every pending message and is rewritten on each change, fine for
+// thousands, not millions.
+type Scheduler struct {
+	mu      sync.Mutex
+	path    string
+	options options
+	file    schedulerFile
+	running bool
+	// wake tells Run that the earliest message may have changed.
+	wake chan struct{}
+}
+
+type schedulerFile struct {
+	NextID int64 `json:"next_id"`
+	// Messages is kept sorted by Due, then ID.
+	Messages []Message `json:"messages"`
+}
+
+// Open loads the scheduled messages from path, or starts with none if it
+// does not exist.
+func Open(path string, opts ...Option) (*Scheduler, error) {
+	options, err := construct.New(opts...)
+	if err != nil {
+		return nil, err
+	}
+	s := &Scheduler{path: path, options: *options, file: schedulerFile{NextID: 1}, wake: make(chan struct{}, 1)}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &s.file); err != nil {
+		return nil, err
+	}
+	slices.SortFunc(s.file.Messages, compare)
+	return s, nil
+}
+
+// After schedules payload, marshalled as JSON, on topic d from now and
+// returns its ID.
+func (s *Scheduler) After(topic string, payload any, d time.Duration) (string, error) {
+	return s.At(topic, payload, s.options.clock.Now().Add(d))
+}
+
+// At schedules payload on topic for t and returns its ID; a t in the past
+// is delivered at once.
+func (s *Scheduler) At(topic string, payload any, t time.Time) (string, error) {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	next := s.file.clone()
+	m := Message{ID: strconv.FormatInt(next.NextID, 10), Topic: topic, Payload: raw, Due: t}
+	next.NextID++
+	next.insert(m)
+	if err := s.commit(next); err != nil {
+		return "", err
+	}
+	return m.ID, nil
+}
+
+// Cancel unschedules the message id and reports whether it was pending.
+func (s *Scheduler) Cancel(id string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	next := s.file.clone()
+	if !next.remove(id) {
+		return false, nil
+	}
+	return true, s.commit(next)
+}
+
+// Pending returns the scheduled messages, earliest first.
+func (s *Scheduler) Pending() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.file.clone().Messages
+}
+
+// Run delivers each message when it falls due until ctx is done, and
+// returns nil then, or the first error persisting the schedule. Only one
+// Run delivers at a time; Deliver may schedule more messages.
+func (s *Scheduler) Run(ctx context.Context, deliver Deliver) error {
+	s.mu.Lock()
+	if s.running {
+		s.mu.Unlock()
+		return ErrRunning
+	}
+	s.running = true
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		s.running = false
+		s.mu.Unlock()
+	}()
+
+	for ctx.Err() == nil {
+		m, wait, ok := s.earliest()
+		if ok && wait <= 0 {
+			if err := s.settle(m, deliver(ctx, m)); err != nil {
+				return err
+			}
+			continue
+		}
+		var (
+			timer clock.Timer
+			fire  <-chan time.Time
+		)
+		if ok {
+			timer = s.options.clock.NewTimer(wait)
+			fire = timer.C()
+		}
+		select {
+		case <-ctx.Done():
+		case <-s.wake:
+		case <-fire:
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+	return nil
+}
+
+// earliest returns the first message and how long until it is due.
+func (s *Scheduler) earliest() (Message, time.Duration, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.file.Messages) == 0 {
+		return Message{}, 0, false
+	}
+	m := s.file.Messages[0]
+	return m, m.Due.Sub(s.options.clock.Now()), true
+}
+
+// settle removes m after a successful delivery or reschedules it after a
+// failed one. m may have been cancelled meanwhile, and stays cancelled.
+func (s *Scheduler) settle(m Message, err error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	next := s.file.clone()
+	if !next.remove(m.ID) {
+		return nil
+	}
+	if err != nil {
+		m.Attempts++
+		m.LastError = err.Error()
+		m.Due = s.options.clock.Now().Add(s.retryDelay(m.Attempts))
+		next.insert(m)
+	}
+	return s.commit(next)
+}
+
+func (s *Scheduler) retryDelay(attempts int) time.Duration {
+	d := s.options.baseRetry
+	for range attempts - 1 {
+		if d >= s.options.maxRetry/2 {
+			return s.options.maxRetry
+		}
+		d *= 2
+	}
+	return d
+}
+
+// commit writes next and makes it current, then wakes Run; s.mu is held.
+func (s *Scheduler) commit(next schedulerFile) error {
+	if err := s.write(next); err != nil {
+		return err
+	}
+	s.file = next
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// write replaces the file atomically: a crash leaves the old schedule or
+// the new, never a mix.
+func (s *Scheduler) write(file schedulerFile) error {
+	b, err := json.Marshal(file)
+	if err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+func (f schedulerFile) clone() schedulerFile {
+	// messages are replaced whole, never changed in place
+	return schedulerFile{NextID: f.NextID, Messages: slices.Clone(f.Messages)}
+}
+
+func (f *schedulerFile) insert(m Message) {
+	i, _ := slices.BinarySearchFunc(f.Messages, m, compare)
+	f.Messages = slices.Insert(f.Messages, i, m)
+}
+
+func (f *schedulerFile) remove(id string) bool {
+	i := slices.IndexFunc(f.Messages, func(m Message) bool { return m.ID == id })
+	if i < 0 {
+		return false
+	}
+	f.Messages = slices.Delete(f.Messages, i, i+1)
+	return true
+}
+
+func compare(a, b Message) int {
+	if c := a.Due.Compare(b.Due); c != 0 {
+		return c
+	}
+	// IDs are decimal counters: shorter is older
+	if len(a.ID) != len(b.ID) {
+		return len(a.ID) - len(b.ID)
+	}
+	return cmp.Compare(a.ID, b.ID)
+}
@@ -0,0 +1,321 @@
+package delayed_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"patterns/clock"
+	"patterns/messaging/delayed"
+)
+
+var epoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+type delivery struct {
+	topic string
+	at    time.Duration // since epoch
+}
+
+// fixture is a scheduler on a fake clock whose deliveries, and the time
+// each came at, are sent on got.
+type fixture struct {
+	t    *testing.T
+	path string
+	clk  *clock.Fake
+	s    *delayed.Scheduler
+	got  chan delivery
+	fail func(m delayed.Message) error
+}
+
+func newFixture(t *testing.T, opts ...delayed.Option) *fixture {
+	f := &fixture{t: t, path: filepath.Join(t.TempDir(), "delayed.json"), clk: clock.NewFake(epoch), got: make(chan delivery, 16)}
+	f.open(opts...)
+	return f
+}
+
+// open (re)opens the scheduler from its file, as a restarted process does.
+func (f *fixture) open(opts ...delayed.Option) {
+	f.t.Helper()
+	s, err := delayed.Open(f.path, append([]delayed.Option{delayed.WithClock(f.clk)}, opts...)...)
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	f.s = s
+}
+
+// run starts Run; the returned func stops it and returns its error.
+func (f *fixture) run() func() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- f.s.Run(ctx, func(_ context.Context, m delayed.Message) error {
+			if f.fail != nil {
+				if err := f.fail(m); err != nil {
+					return err
+				}
+			}
+			f.got <- delivery{m.Topic, f.clk.Since(epoch)}
+			return nil
+		})
+	}()
+	return func() error {
+		cancel()
+		return <-done
+	}
+}
+
+func (f *fixture) schedule(topic string, d time.Duration) string {
+	f.t.Helper()
+	id, err := f.s.After(topic, topic, d)
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	return id
+}
+
+// advance moves the clock once Run is waiting on its timer.
+func (f *fixture) advance(d time.Duration) {
+	f.clk.BlockUntil(1)
+	f.clk.Advance(d)
+}
+
+// expect checks the next deliveries, then that no other comes while Run
+// goes back to waiting.
+func (f *fixture) expect(want ...delivery) {
+	f.t.Helper()
+	for _, w := range want {
+		select {
+		case got := <-f.got:
+			if got != w {
+				f.t.Errorf("delivered %v, want %v", got, w)
+			}
+		case <-time.After(5 * time.Second):
+			f.t.Fatalf("no delivery, want %v", w)
+		}
+	}
+	if len(f.s.Pending()) > 0 {
+		f.clk.BlockUntil(1)
+	}
+	select {
+	case got := <-f.got:
+		f.t.Errorf("unexpected delivery %v", got)
+	default:
+	}
+}
+
+// TestFiring checks that each message is delivered in the Advance that
+// reaches its due time, not a nanosecond before, and that messages due
+// together come in the order they were scheduled.
+func TestFiring(t *testing.T) {
+	f := newFixture(t)
+	f.schedule("a", 10*time.Second)
+	if _, err := f.s.At("b", "b", epoch.Add(5*time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	f.schedule("c", 10*time.Second)
+	stop := f.run()
+	defer stop()
+
+	f.advance(5*time.Second - time.Nanosecond)
+	f.expect()
+	f.advance(time.Nanosecond)
+	f.expect(delivery{"b", 5 * time.Second})
+	f.advance(5 * time.Second)
+	f.expect(delivery{"a", 10 * time.Second}, delivery{"c", 10 * time.Second})
+	if p := f.s.Pending(); len(p) != 0 {
+		t.Errorf("pending after delivery: %v", p)
+	}
+}
+
+// TestSooner schedules a message due before the one Run is waiting for:
+// Run resets its timer for it.
+func TestSooner(t *testing.T) {
+	f := newFixture(t)
+	f.schedule("later", time.Hour)
+	stop := f.run()
+	defer stop()
+	f.clk.BlockUntil(1)
+	f.schedule("sooner", time.Second)
+	f.schedule("now", 0)
+	f.expect(delivery{"now", 0})
+	f.advance(time.Second)
+	f.expect(delivery{"sooner", time.Second})
+	f.advance(time.Hour - time.Second)
+	f.expect(delivery{"later", time.Hour})
+}
+
+// TestRetry fails a delivery until its third attempt: it is retried with
+// doubling backoff, keeping why it failed.
+func TestRetry(t *testing.T) {
+	f := newFixture(t, delayed.WithRetry(time.Second, 3*time.Second))
+	failures := 0
+	f.fail = func(delayed.Message) error {
+		if failures < 3 {
+			failures++
+			return errors.New("broker down")
+		}
+		return nil
+	}
+	id := f.schedule("m", 0)
+	stop := f.run()
+	defer stop()
+
+	// retries after 1s, 2s, then the 3s cap
+	for _, wait := range []time.Duration{time.Second, 2 * time.Second, 3 * time.Second} {
+		f.clk.BlockUntil(1)
+		p := f.s.Pending()
+		if len(p) != 1 || p[0].ID != id || p[0].Attempts != failures || p[0].LastError != "broker down" || p[0].Due != f.clk.Now().Add(wait) {
+			t.Fatalf("after %d failures: pending %+v, want a retry in %v", failures, p, wait)
+		}
+		f.advance(wait)
+	}
+	f.expect(delivery{"m", 6 * time.Second})
+}
+
+// TestRecovery schedules messages and restarts the process before and
+// after they fall due: the ones due while it was down are delivered as
+// soon as Run starts, oldest first, and the rest on time.
+func TestRecovery(t *testing.T) {
+	f := newFixture(t)
+	f.schedule("second", 2*time.Second)
+	f.schedule("first", time.Second)
+	f.schedule("future", time.Minute)
+
+	// down for 30s
+	f.clk.Advance(30 * time.Second)
+	f.open()
+	stop := f.run()
+	f.expect(delivery{"first", 30 * time.Second}, delivery{"second", 30 * time.Second})
+	stop()
+
+	f.open()
+	stop = f.run()
+	defer stop()
+	f.advance(30 * time.Second)
+	f.expect(delivery{"future", time.Minute})
+}
+
+// TestCrashAfterDeliver makes the write after a delivery fail, as a crash
+// before the scheduler recorded it would: Run stops, and after a restart
+// the message is delivered again.
+func TestCrashAfterDeliver(t *testing.T) {
+	f := newFixture(t)
+	f.schedule("m", time.Second)
+	f.schedule("n", time.Minute)
+	stop := f.run()
+	// the temporary file is a directory, so it cannot be created
+	if err := os.Mkdir(f.path+".tmp", 0o755); err != nil {
+		t.Fatal(err)
+	}
+	f.advance(time.Second)
+	<-f.got
+	if err := stop(); err == nil {
+		t.Error("Run went on after failing to record a delivery")
+	}
+	os.Remove(f.path + ".tmp")
+
+	f.open()
+	stop = f.run()
+	defer stop()
+	f.expect(delivery{"m", time.Second})
+	if p := f.s.Pending(); len(p) != 1 || p[0].Topic != "n" {
+		t.Errorf("pending after recovery: %v", p)
+	}
+}
+
+func TestCancel(t *testing.T) {
+	f := newFixture(t)
+	a := f.schedule("a", time.Second)
+	f.schedule("b", 2*time.Second)
+	if ok, err := f.s.Cancel(a); !ok || err != nil {
+		t.Fatalf("Cancel = %v, %v", ok, err)
+	}
+	if ok, err := f.s.Cancel(a); ok || err != nil {
+		t.Errorf("second Cancel = %v, %v", ok, err)
+	}
+	// cancelling a message while it is delivered keeps it cancelled, even
+	// if the delivery fails
+	attempted := make(chan string, 1)
+	f.fail = func(m delayed.Message) error {
+		f.s.Cancel(m.ID)
+		attempted <- m.Topic
+		return errors.New("failed")
+	}
+	f.open()
+	f.schedule("c", 3*time.Second)
+	stop := f.run()
+	defer stop()
+	f.advance(2 * time.Second)
+	if topic := <-attempted; topic != "b" {
+		t.Errorf("attempted %s, want b: a was cancelled", topic)
+	}
+	// Run is waiting for c: b's failure is settled
+	f.clk.BlockUntil(1)
+	if p := f.s.Pending(); len(p) != 1 || p[0].Topic != "c" {
+		t.Errorf("pending %+v, want only c", p)
+	}
+}
+
+// TestRun checks that only one Run delivers at a time, and that Deliver
+// can schedule more messages.
+func TestRun(t *testing.T) {
+	f := newFixture(t)
+	f.fail = func(m delayed.Message) error {
+		if m.Topic == "ping" {
+			f.s.After("pong", nil, time.Second)
+		}
+		return nil
+	}
+	f.schedule("ping", 0)
+	stop := f.run()
+	defer stop()
+	f.expect(delivery{"ping", 0})
+	if err := f.s.Run(context.Background(), nil); !errors.Is(err, delayed.ErrRunning) {
+		t.Errorf("second Run = %v, want ErrRunning", err)
+	}
+	f.advance(time.Second)
+	f.expect(delivery{"pong", time.Second})
+}
+
+func TestOptions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "delayed.json")
+	for _, c := range []struct {
+		opts []delayed.Option
+		want string
+	}{
+		{[]delayed.Option{delayed.WithClock(nil)}, "clock cannot be nil"},
+		{[]delayed.Option{delayed.WithRetry(0, time.Second)}, "base must be positive"},
+		{[]delayed.Option{delayed.WithRetry(time.Minute, time.Second)}, "maxRetry: must be at least 1m0s"},
+	} {
+		if s, err := delayed.Open(path, c.opts...); s != nil || err == nil || err.Error() != c.want {
+			t.Errorf("Open = %v, %v; want %q", s, err, c.want)
+		}
+	}
+	if err := os.WriteFile(path, []byte("["), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := delayed.Open(path); err == nil {
+		t.Error("opened a corrupt file")
+	}
+	s, _ := delayed.Open(filepath.Join(t.TempDir(), "x.json"))
+	if _, err := s.After("t", func() {}, time.Second); err == nil || len(s.Pending()) != 0 {
+		t.Errorf("After with a payload JSON cannot encode = %v", err)
+	}
+	ids := []string{}
+	for range 12 {
+		id, _ := s.After("t", nil, 0)
+		ids = append(ids, id)
+	}
+	// ten sorts after nine
+	var pending []string
+	for _, m := range s.Pending() {
+		pending = append(pending, m.ID)
+	}
+	if !slices.Equal(pending, ids) {
+		t.Errorf("pending %v, want %v", pending, ids)
+	}
+}
@@ -0,0 +1,272 @@
+// Package envelope wraps messages in a typed, versioned envelope, so a
+// consumer can tell what a payload is, which schema it was written with
+// and what caused it, before decoding a byte of it:
+//
+//	reg, _ := envelope.NewRegistry(envelope.WithCodec(envelope.Gob))
+//	envelope.Register[CartV1](reg, "cart.updated", 1)
+//	envelope.Register[Cart](reg, "cart.updated", 2)
+//	envelope.Upcast(reg, "cart.updated", 1, upgradeCart)
+//
+//	env, _ := reg.Seal(Cart{...}, envelope.Meta{})
+//	cart, _ := envelope.OpenAs[Cart](reg, env) // v1 or v2 in, v2 out
+//
+// A message type's schema changes over time, but envelopes written with
+// the old one stay in queues, logs and event stores. Each registered
+// version keeps its Go type and an upcaster to the next version: Open
+// decodes a payload with the type of its own version, then upcasts it
+// step by step to the current one, so consumers only know the newest.
+// NewOrderRegistry does this for the three versions of order.placed.
+//
+// Correlation and causation IDs trace a conversation: Reply copies the
+// correlation of the message being handled and names it as the cause.
+package envelope
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"patterns/clock"
+	"patterns/construct"
+	"patterns/funcopts"
+)
+
+var ErrUnknownType = errors.New("envelope: unknown message type")
+
+// Envelope carries one encoded message and what a consumer needs to know
+// about it. It is itself plain data, for any transport to encode.
+type Envelope struct {
+	ID      string `json:"id"`
+	Type    string `json:"type"`
+	Version int    `json:"version"`
+	// Codec names the encoding of Payload.
+	Codec string `json:"codec"`
+	// CorrelationID is shared by every message of one conversation;
+	// CausationID is the ID of the message that caused this one.
+	CorrelationID string    `json:"correlation_id,omitempty"`
+	CausationID   string    `json:"causation_id,omitempty"`
+	Time          time.Time `json:"time"`
+	Payload       []byte    `json:"payload"`
+}
+
+// Meta is what the sender supplies when sealing; empty fields are filled
+// in: a new ID, and the ID itself as the correlation of a conversation's
+// first message.
+type Meta struct {
+	ID            string
+	CorrelationID string
+	CausationID   string
+}
+
+// Reply returns the Meta of a message sent while handling cause.
+func Reply(cause Envelope) Meta {
+	return Meta{CorrelationID: cause.CorrelationID, CausationID: cause.ID}
+}
+
+type options struct {
+	codec Codec
+	clock clock.Clock
+}
+
+type Option = funcopts.Option[options]
+
+// WithCodec sets the codec Seal encodes with; the default is JSON. Open
+// decodes with the codec an envelope names, JSON, Gob or this one.
+func WithCodec(c Codec) Option {
+	return func(options *options) error {
+		if c == nil {
+			return errors.New("codec cannot be nil")
+		}
+		options.codec = c
+		return nil
+	}
+}
+
+func WithClock(c clock.Clock) Option {
+	return func(options *options) error {
+		if c == nil {
+			return errors.New("clock cannot be nil")
+		}
+		options.clock = c
+		return nil
+	}
+}
+
+func (o *options) SetDefaults() {
+	o.codec = JSON
+	o.clock = clock.Real
+}
+
+// message envelope with upcasting
+// Level: Good
+// pros: payloads are self-describing, so routing and tracing need no
+// decoding; old versions keep decoding, and consumers only ever see the
+// current one.
+// cons: every schema change is a new type and an upcaster kept for as
+// long as old messages may be read; upcasting costs a decode per version.
+type Registry struct {
+	options options
+	codecs  map[string]Codec
+
+	mu    sync.RWMutex
+	types map[string]*messageType
+	names map[reflect.Type]key
+}
+
+type key struct {
+	name    string
+	version int
+}
+
+type messageType struct {
+	versions map[int]reflect.Type
+	upcast   map[int]func(any) (any, error) // from version n to n+1
+	current  int
+}
+
+func NewRegistry(opts ...Option) (*Registry, error) {
+	options, err := construct.New(opts...)
+	if err != nil {
+		return nil, err
+	}
+	r := &Registry{
+		options: *options,
+		codecs:  map[string]Codec{JSON.Name(): JSON, Gob.Name(): Gob},
+		types:   map[string]*messageType{},
+		names:   map[reflect.Type]key{},
+	}
+	r.codecs[options.codec.Name()] = options.codec
+	return r, nil
+}
+
+// Register makes T version of the message type name. The highest
+// registered version is the current one, which Open returns.
+func Register[T any](r *Registry, name string, version int) error {
+	t := reflect.TypeFor[T]()
+	if name == "" || version <= 0 {
+		return errors.New("envelope: name cannot be empty and version must be positive")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if k, ok := r.names[t]; ok {
+		return fmt.Errorf("envelope: %v already registered as %s v%d", t, k.name, k.version)
+	}
+	mt := r.types[name]
+	if mt == nil {
+		mt = &messageType{versions: map[int]reflect.Type{}, upcast: map[int]func(any) (any, error){}}
+		r.types[name] = mt
+	}
+	if _, ok := mt.versions[version]; ok {
+		return fmt.Errorf("envelope: %s v%d already registered", name, version)
+	}
+	mt.versions[version] = t
+	mt.current = max(mt.current, version)
+	r.names[t] = key{name, version}
+	return nil
+}
+
+// Upcast registers fn to turn version from of name into version from+1,
+// both already registered as From and To.
+func Upcast[From, To any](r *Registry, name string, from int, fn func(From) (To, error)) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	mt := r.types[name]
+	if mt == nil {
+		return fmt.Errorf("%w: %s", ErrUnknownType, name)
+	}
+	if mt.versions[from] != reflect.TypeFor[From]() || mt.versions[from+1] != reflect.TypeFor[To]() {
+		return fmt.Errorf("envelope: %s v%d and v%d are not registered as %v and %v",
+			name, from, from+1, reflect.TypeFor[From](), reflect.TypeFor[To]())
+	}
+	mt.upcast[from] = func(v any) (any, error) { return fn(v.(From)) }
+	return nil
+}
+
+// Seal encodes msg, whose type must be registered, into an envelope.
+func (r *Registry) Seal(msg any, meta Meta) (Envelope, error) {
+	r.mu.RLock()
+	k, ok := r.names[reflect.TypeOf(msg)]
+	r.mu.RUnlock()
+	if !ok {
+		return Envelope{}, fmt.Errorf("%w: %T", ErrUnknownType, msg)
+	}
+	payload, err := r.options.codec.Marshal(msg)
+	if err != nil {
+		return Envelope{}, fmt.Errorf("envelope: encoding %s v%d: %w", k.name, k.version, err)
+	}
+	if meta.ID == "" {
+		meta.ID = newID()
+	}
+	if meta.CorrelationID == "" {
+		meta.CorrelationID = meta.ID
+	}
+	return Envelope{
+		ID:            meta.ID,
+		Type:          k.name,
+		Version:       k.version,
+		Codec:         r.options.codec.Name(),
+		CorrelationID: meta.CorrelationID,
+		CausationID:   meta.CausationID,
+		Time:          r.options.clock.Now(),
+		Payload:       payload,
+	}, nil
+}
+
+// Open decodes the payload of env and upcasts it to the current version
+// of its type.
+func (r *Registry) Open(env Envelope) (any, error) {
+	codec, ok := r.codecs[env.Codec]
+	if !ok {
+		return nil, fmt.Errorf("envelope: unknown codec %q", env.Codec)
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	mt := r.types[env.Type]
+	if mt == nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownType, env.Type)
+	}
+	t, ok := mt.versions[env.Version]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s v%d", ErrUnknownType, env.Type, env.Version)
+	}
+	p := reflect.New(t)
+	if err := codec.Unmarshal(env.Payload, p.Interface()); err != nil {
+		return nil, fmt.Errorf("envelope: decoding %s v%d: %w", env.Type, env.Version, err)
+	}
+	v := p.Elem().Interface()
+	for version := env.Version; version < mt.current; version++ {
+		up, ok := mt.upcast[version]
+		if !ok {
+			return nil, fmt.Errorf("envelope: no upcaster from %s v%d", env.Type, version)
+		}
+		var err error
+		if v, err = up(v); err != nil {
+			return nil, fmt.Errorf("envelope: upcasting %s v%d: %w", env.Type, version, err)
+		}
+	}
+	return v, nil
+}
+
+// OpenAs is Open for a consumer that expects T.
+func OpenAs[T any](r *Registry, env Envelope) (T, error) {
+	v, err := r.Open(env)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	t, ok := v.(T)
+	if !ok {
+		return t, fmt.Errorf("envelope: %s is %T, not %v", env.Type, v, reflect.TypeFor[T]())
+	}
+	return t, nil
+}
+
+func newID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
@@ -0,0 +1,209 @@
+package envelope_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"patterns/clock"
+	"patterns/messaging/envelope"
+	"patterns/testing/golden"
+)
+
+var sealedAt = time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+func orders(t *testing.T, opts ...envelope.Option) *envelope.Registry {
+	t.Helper()
+	r, err := envelope.NewOrderRegistry(append([]envelope.Option{envelope.WithClock(clock.NewFake(sealedAt))}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+// fixtures are order.placed messages as each version of the schema
+// wrote them, checked in under testdata/order.placed: old versions must
+// keep decoding, and sealing must keep writing the same bytes.
+var fixtures = []struct {
+	msg  any
+	want envelope.OrderPlaced
+}{
+	{envelope.OrderPlacedV1{OrderID: "A-1", Total: 19.99}, envelope.OrderPlaced{OrderID: "A-1", TotalCents: 1999, Currency: "USD"}},
+	{envelope.OrderPlacedV2{OrderID: "A-2", TotalCents: 2500}, envelope.OrderPlaced{OrderID: "A-2", TotalCents: 2500, Currency: "USD"}},
+	{envelope.OrderPlaced{OrderID: "A-3", TotalCents: 990, Currency: "EUR"}, envelope.OrderPlaced{OrderID: "A-3", TotalCents: 990, Currency: "EUR"}},
+}
+
+func TestFixtures(t *testing.T) {
+	for _, codec := range []envelope.Codec{envelope.JSON, envelope.Gob} {
+		r := orders(t, envelope.WithCodec(codec))
+		// a registry sealing JSON still opens gob, and the reverse
+		other := orders(t)
+		for i, f := range fixtures {
+			name := fmt.Sprintf("order.placed/v%d.%s", i+1, codec.Name())
+			env, err := r.Seal(f.msg, envelope.Meta{ID: "msg-1", CorrelationID: "conv-1"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := json.MarshalIndent(env, "", "\t")
+			if err != nil {
+				t.Fatal(err)
+			}
+			golden.Assert(t, name, append(b, '\n'))
+
+			b, err = os.ReadFile(filepath.Join("testdata", name+".golden"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var stored envelope.Envelope
+			if err := json.Unmarshal(b, &stored); err != nil {
+				t.Fatal(err)
+			}
+			if stored.Version != i+1 || stored.Codec != codec.Name() || !stored.Time.Equal(sealedAt) {
+				t.Errorf("%s: stored as v%d %s at %v", name, stored.Version, stored.Codec, stored.Time)
+			}
+			for _, reg := range []*envelope.Registry{r, other} {
+				got, err := envelope.OpenAs[envelope.OrderPlaced](reg, stored)
+				if err != nil || got != f.want {
+					t.Errorf("%s: OpenAs = %+v, %v; want %+v", name, got, err, f.want)
+				}
+			}
+		}
+	}
+}
+
+func TestMeta(t *testing.T) {
+	r := orders(t)
+	first, _ := r.Seal(envelope.OrderPlaced{OrderID: "A"}, envelope.Meta{})
+	if len(first.ID) != 32 || first.CorrelationID != first.ID || first.CausationID != "" {
+		t.Errorf("first message: id %q correlation %q causation %q", first.ID, first.CorrelationID, first.CausationID)
+	}
+	reply, _ := r.Seal(envelope.OrderPlaced{OrderID: "B"}, envelope.Reply(first))
+	next, _ := r.Seal(envelope.OrderPlaced{OrderID: "C"}, envelope.Reply(reply))
+	if reply.ID == first.ID || reply.CorrelationID != first.ID || reply.CausationID != first.ID {
+		t.Errorf("reply: id %q correlation %q causation %q", reply.ID, reply.CorrelationID, reply.CausationID)
+	}
+	if next.CorrelationID != first.ID || next.CausationID != reply.ID {
+		t.Errorf("reply to the reply: correlation %q causation %q", next.CorrelationID, next.CausationID)
+	}
+	if !first.Time.Equal(sealedAt) || first.Type != "order.placed" || first.Version != 3 || first.Codec != "json" {
+		t.Errorf("sealed %s v%d %s at %v", first.Type, first.Version, first.Codec, first.Time)
+	}
+}
+
+// marked is a codec of its own: JSON with a marker, so a registry that does
+// not know it cannot mistake it for JSON.
+type marked struct{}
+
+func (marked) Name() string { return "json+marker" }
+func (marked) Marshal(v any) ([]byte, error) {
+	b, err := json.Marshal(v)
+	return append([]byte("M"), b...), err
+}
+func (marked) Unmarshal(data []byte, v any) error {
+	if len(data) == 0 || data[0] != 'M' {
+		return errors.New("no marker")
+	}
+	return json.Unmarshal(data[1:], v)
+}
+
+func TestCustomCodec(t *testing.T) {
+	r := orders(t, envelope.WithCodec(marked{}))
+	env, err := r.Seal(envelope.OrderPlacedV2{OrderID: "A", TotalCents: 5}, envelope.Meta{})
+	if err != nil || env.Codec != "json+marker" || !strings.HasPrefix(string(env.Payload), "M{") {
+		t.Fatalf("Seal = %s %q, %v", env.Codec, env.Payload, err)
+	}
+	if got, err := envelope.OpenAs[envelope.OrderPlaced](r, env); err != nil || got.TotalCents != 5 {
+		t.Errorf("OpenAs = %+v, %v", got, err)
+	}
+	if _, err := orders(t).Open(env); err == nil || err.Error() != `envelope: unknown codec "json+marker"` {
+		t.Errorf("Open without the codec = %v", err)
+	}
+}
+
+func TestOpenErrors(t *testing.T) {
+	r := orders(t)
+	gob := orders(t, envelope.WithCodec(envelope.Gob))
+	seal := func(r *envelope.Registry, msg any) envelope.Envelope {
+		env, err := r.Seal(msg, envelope.Meta{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return env
+	}
+	v3 := seal(r, envelope.OrderPlaced{OrderID: "A"})
+	with := func(f func(*envelope.Envelope)) envelope.Envelope {
+		env := v3
+		f(&env)
+		return env
+	}
+
+	partial, _ := envelope.NewRegistry()
+	envelope.Register[envelope.OrderPlacedV1](partial, "order.placed", 1)
+	envelope.Register[envelope.OrderPlacedV2](partial, "order.placed", 2)
+
+	for _, c := range []struct {
+		name    string
+		r       *envelope.Registry
+		env     envelope.Envelope
+		want    string
+		unknown bool
+	}{
+		{"unknown type", r, with(func(e *envelope.Envelope) { e.Type = "order.shipped" }), "envelope: unknown message type: order.shipped", true},
+		{"future version", r, with(func(e *envelope.Envelope) { e.Version = 4 }), "envelope: unknown message type: order.placed v4", true},
+		{"unknown codec", r, with(func(e *envelope.Envelope) { e.Codec = "xml" }), `envelope: unknown codec "xml"`, false},
+		{"corrupt payload", r, with(func(e *envelope.Envelope) { e.Payload = []byte("{") }),
+			"envelope: decoding order.placed v3: unexpected end of JSON input", false},
+		{"no upcaster", partial, seal(partial, envelope.OrderPlacedV1{OrderID: "A"}), "envelope: no upcaster from order.placed v1", false},
+		{"upcaster fails", r, seal(r, envelope.OrderPlacedV1{Total: 1e18}), "envelope: upcasting order.placed v1: total out of range", false},
+		// NaN has no JSON form: an old gob producer could still send it
+		{"upcaster fails on NaN", gob, seal(gob, envelope.OrderPlacedV1{Total: math.NaN()}), "envelope: upcasting order.placed v1: total out of range", false},
+	} {
+		v, err := c.r.Open(c.env)
+		if v != nil || err == nil || err.Error() != c.want || errors.Is(err, envelope.ErrUnknownType) != c.unknown {
+			t.Errorf("%s: Open = %v, %v; want %q", c.name, v, err, c.want)
+		}
+	}
+
+	if _, err := envelope.OpenAs[envelope.OrderPlacedV2](r, v3); err == nil ||
+		err.Error() != "envelope: order.placed is envelope.OrderPlaced, not envelope.OrderPlacedV2" {
+		t.Errorf("OpenAs of an old version = %v", err)
+	}
+	if _, err := r.Seal(struct{ X int }{1}, envelope.Meta{}); !errors.Is(err, envelope.ErrUnknownType) {
+		t.Errorf("Seal of an unregistered type = %v", err)
+	}
+}
+
+func TestRegisterErrors(t *testing.T) {
+	type first struct{}
+	type second struct{}
+	type third struct{}
+	r, _ := envelope.NewRegistry()
+	if err := envelope.Register[first](r, "m", 1); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		err  error
+		want string
+	}{
+		{envelope.Register[first](r, "n", 1), "envelope: envelope_test.first already registered as m v1"},
+		{envelope.Register[second](r, "m", 1), "envelope: m v1 already registered"},
+		{envelope.Register[second](r, "", 1), "envelope: name cannot be empty and version must be positive"},
+		{envelope.Register[second](r, "m", 0), "envelope: name cannot be empty and version must be positive"},
+		{envelope.Upcast(r, "x", 1, func(first) (second, error) { return second{}, nil }), "envelope: unknown message type: x"},
+		{envelope.Upcast(r, "m", 1, func(first) (second, error) { return second{}, nil }), "envelope: m v1 and v2 are not registered as envelope_test.first and envelope_test.second"},
+		{envelope.Upcast(r, "m", 1, func(third) (second, error) { return second{}, nil }), "envelope: m v1 and v2 are not registered as envelope_test.third and envelope_test.second"},
+	} {
+		if c.err == nil || c.err.Error() != c.want {
+			t.Errorf("error %v, want %q", c.err, c.want)
+		}
+	}
+	if _, err := envelope.NewRegistry(envelope.WithCodec(nil)); err == nil || err.Error() != "codec cannot be nil" {
+		t.Errorf("WithCodec(nil) = %v", err)
+	}
+}
@@ -0,0 +1,51 @@
+package envelope
+
+import (
+	"errors"
+	"math"
+)
+
+// The order.placed message in three versions, as its schema grew.
+
+// OrderPlacedV1 had the total as a float of dollars.
+type OrderPlacedV1 struct {
+	OrderID string
+	Total   float64
+}
+
+// OrderPlacedV2 moved to integer cents.
+type OrderPlacedV2 struct {
+	OrderID    string
+	TotalCents int64
+}
+
+// OrderPlaced, version 3 and current, added the currency.
+type OrderPlaced struct {
+	OrderID    string
+	TotalCents int64
+	Currency   string
+}
+
+// NewOrderRegistry returns a registry knowing every version of
+// order.placed and the upcasters between them.
+func NewOrderRegistry(opts ...Option) (*Registry, error) {
+	r, err := NewRegistry(opts...)
+	if err != nil {
+		return nil, err
+	}
+	return r, errors.Join(
+		Register[OrderPlacedV1](r, "order.placed", 1),
+		Register[OrderPlacedV2](r, "order.placed", 2),
+		Register[OrderPlaced](r, "order.placed", 3),
+		Upcast(r, "order.placed", 1, func(o OrderPlacedV1) (OrderPlacedV2, error) {
+			if math.IsNaN(o.Total) || math.Abs(o.Total) > math.MaxInt64/100 {
+				return OrderPlacedV2{}, errors.New("total out of range")
+			}
+			return OrderPlacedV2{OrderID: o.OrderID, TotalCents: int64(math.Round(o.Total * 100))}, nil
+		}),
+		// every order before version 3 was in dollars
+		Upcast(r, "order.placed", 2, func(o OrderPlacedV2) (OrderPlaced, error) {
+			return OrderPlaced{OrderID: o.OrderID, TotalCents: o.TotalCents, Currency: "USD"}, nil
+		}),
+	)
+}
@@ -0,0 +1,155 @@
+package inbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"maps"
+	"os"
+	"sync"
+	"time"
+
+	"patterns/clock"
+	"patterns/construct"
+)
+
+// transactional inbox
+// Level: Good
+// pros: a message's effects and its record are written in one atomic
+// step, so crashing anywhere neither loses nor repeats a message.
+// cons: only for effects on state this store holds, not for calls to
+// other systems; one file rewritten per message serializes processing,
+// where a database would commit the two in one transaction.
+type Durable[S any] struct {
+	mu    sync.Mutex
+	path  string
+	clock clock.Clock
+	file  durableFile[S]
+}
+
+type durableFile[S any] struct {
+	State     S                    `json:"state"`
+	Processed map[string]time.Time `json:"processed"`
+}
+
+// OpenDurable loads the state and the processed IDs from path, or starts
+// with the zero S if it does not exist. S must survive a JSON round trip.
+func OpenDurable[S any](path string, opts ...Option) (*Durable[S], error) {
+	options, err := construct.New(opts...)
+	if err != nil {
+		return nil, err
+	}
+	d := &Durable[S]{path: path, clock: options.clock, file: durableFile[S]{Processed: map[string]time.Time{}}}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return d, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &d.file); err != nil {
+		return nil, err
+	}
+	if d.file.Processed == nil {
+		d.file.Processed = map[string]time.Time{}
+	}
+	return d, nil
+}
+
+// Process applies fn to a copy of the state unless the message id was
+// processed before, and reports whether it did. The new state and id are
+// then written together; if fn fails, or the write does, neither is kept
+// and the message can be processed again.
+func (d *Durable[S]) Process(ctx context.Context, id string, fn func(state *S) error) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, ok := d.file.Processed[id]; ok {
+		return false, nil
+	}
+	state, err := clone(d.file.State)
+	if err != nil {
+		return false, err
+	}
+	if err := fn(&state); err != nil {
+		return false, err
+	}
+	next := durableFile[S]{State: state, Processed: maps.Clone(d.file.Processed)}
+	next.Processed[id] = d.clock.Now()
+	if err := d.write(next); err != nil {
+		return false, err
+	}
+	d.file = next
+	return true, nil
+}
+
+// View calls fn with the committed state, holding the lock Process takes;
+// fn must not keep or change the state, nor call d.
+func (d *Durable[S]) View(fn func(state S)) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	fn(d.file.State)
+}
+
+// Processed reports whether the message id was processed.
+func (d *Durable[S]) Processed(id string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	_, ok := d.file.Processed[id]
+	return ok
+}
+
+// Prune forgets the messages processed before t and returns how many.
+func (d *Durable[S]) Prune(before time.Time) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	next := durableFile[S]{State: d.file.State, Processed: maps.Clone(d.file.Processed)}
+	maps.DeleteFunc(next.Processed, func(_ string, t time.Time) bool { return t.Before(before) })
+	n := len(d.file.Processed) - len(next.Processed)
+	if n == 0 {
+		return 0, nil
+	}
+	if err := d.write(next); err != nil {
+		return 0, err
+	}
+	d.file = next
+	return n, nil
+}
+
+func clone[S any](s S) (S, error) {
+	var c S
+	b, err := json.Marshal(s)
+	if err != nil {
+		return c, err
+	}
+	err = json.Unmarshal(b, &c)
+	return c, err
+}
+
+// write replaces the file atomically: a crash leaves the old contents or
+// the new, never a mix.
+func (d *Durable[S]) write(file durableFile[S]) error {
+	b, err := json.Marshal(file)
+	if err != nil {
+		return err
+	}
+	tmp := d.path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, d.path)
+}
@@ -0,0 +1,150 @@
+package inbox_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"patterns/clock"
+	"patterns/messaging/inbox"
+)
+
+// balances is the consumer state of the durable tests: an account's
+// balance, moved by the amount of each message.
+type balances map[string]int
+
+func openDurable(t *testing.T, path string, opts ...inbox.Option) *inbox.Durable[balances] {
+	t.Helper()
+	d, err := inbox.OpenDurable[balances](path, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func credit(amount int) func(*balances) error {
+	return func(s *balances) error {
+		if *s == nil {
+			*s = balances{}
+		}
+		(*s)["acct"] += amount
+		return nil
+	}
+}
+
+func balance(d *inbox.Durable[balances]) int {
+	var n int
+	d.View(func(s balances) { n = s["acct"] })
+	return n
+}
+
+// TestDurableDuplicates replays messages with duplicates, restarting the
+// consumer now and then: each message's credit lands once.
+func TestDurableDuplicates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "inbox.json")
+	d := openDurable(t, path)
+	for i, id := range redeliveries(1, 40) {
+		if i%7 == 0 {
+			d = openDurable(t, path)
+		}
+		if _, err := d.Process(context.Background(), id, credit(1)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := balance(openDurable(t, path)); got != 40 {
+		t.Errorf("balance %d, want 40", got)
+	}
+}
+
+// TestDurableCrash makes the write fail, as a crash before the rename
+// would: neither the effect nor the record is kept, so the redelivery
+// applies the message, once.
+func TestDurableCrash(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "inbox.json")
+	d := openDurable(t, path)
+	d.Process(context.Background(), "m1", credit(5))
+
+	// the temporary file is a directory, so it cannot be created
+	if err := os.Mkdir(path+".tmp", 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := d.Process(context.Background(), "m2", credit(7)); ok || err == nil {
+		t.Errorf("Process with a failing write = %v, %v", ok, err)
+	}
+	if balance(d) != 5 || d.Processed("m2") {
+		t.Errorf("after the failed write: balance %d, m2 recorded %v", balance(d), d.Processed("m2"))
+	}
+	os.Remove(path + ".tmp")
+
+	d = openDurable(t, path)
+	for range 2 {
+		d.Process(context.Background(), "m2", credit(7))
+	}
+	if balance(d) != 12 || !d.Processed("m2") {
+		t.Errorf("after redelivery: balance %d", balance(d))
+	}
+}
+
+// TestDurableFailed checks that a handler's changes to the state are
+// thrown away with its error.
+func TestDurableFailed(t *testing.T) {
+	d := openDurable(t, filepath.Join(t.TempDir(), "inbox.json"))
+	d.Process(context.Background(), "m1", credit(1))
+	boom := errors.New("boom")
+	ok, err := d.Process(context.Background(), "m2", func(s *balances) error {
+		(*s)["acct"] = 1000
+		return boom
+	})
+	if ok || !errors.Is(err, boom) || balance(d) != 1 || d.Processed("m2") {
+		t.Errorf("failed Process = %v, %v, balance %d", ok, err, balance(d))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if ok, err := d.Process(ctx, "m3", credit(1)); ok || !errors.Is(err, context.Canceled) || d.Processed("m3") {
+		t.Errorf("Process canceled = %v, %v", ok, err)
+	}
+}
+
+func TestDurablePrune(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "inbox.json")
+	clk := clock.NewFake(epoch)
+	d := openDurable(t, path, inbox.WithClock(clk))
+	d.Process(context.Background(), "old", credit(1))
+	clk.Advance(time.Hour)
+	d.Process(context.Background(), "new", credit(1))
+	if n, err := d.Prune(epoch.Add(time.Hour)); n != 1 || err != nil {
+		t.Errorf("Prune = %d, %v", n, err)
+	}
+	if n, err := d.Prune(epoch.Add(time.Hour)); n != 0 || err != nil {
+		t.Errorf("second Prune = %d, %v", n, err)
+	}
+	d = openDurable(t, path)
+	if d.Processed("old") || !d.Processed("new") || balance(d) != 2 {
+		t.Errorf("after Prune and restart: old %v, new %v, balance %d", d.Processed("old"), d.Processed("new"), balance(d))
+	}
+}
+
+func TestOpenDurable(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "inbox.json")
+	if err := os.WriteFile(path, []byte("{"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := inbox.OpenDurable[balances](path); err == nil {
+		t.Error("opened a corrupt file")
+	}
+	// a file written without processed IDs still records new ones
+	if err := os.WriteFile(path, []byte(`{"state": {"acct": 3}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	d := openDurable(t, path)
+	if ok, err := d.Process(context.Background(), "m", credit(1)); !ok || err != nil || balance(d) != 4 {
+		t.Errorf("Process = %v, %v, balance %d", ok, err, balance(d))
+	}
+	if _, err := inbox.OpenDurable[balances](path, inbox.WithClock(nil)); err == nil {
+		t.Error("WithClock(nil) accepted")
+	}
+}
@@ -0,0 +1,165 @@
+// Package inbox makes a consumer skip messages it has processed already,
+// the receiving half of the outbox that examples/jobqueue sends through:
+// brokers deliver at least once, so a message comes again whenever its
+// ack is lost, and the inbox remembers the IDs it has seen.
+//
+//	in, _ := inbox.New(repository.NewMemory[string, inbox.Record]())
+//	handled, err := in.Handle(ctx, env.ID, func(ctx context.Context) error {
+//		return apply(ctx, env)
+//	})
+//	// ack unless err != nil, whether handled or skipped
+//
+// Where the ID is recorded decides how exactly-once it gets:
+//
+//   - Inbox records it after the handler succeeds, in its own storage.
+//     A crash after the handler and before the ack is harmless: the
+//     redelivery is recognized and skipped. A crash between the handler
+//     and the record is not: the redelivery runs again, so the handler
+//     should still be idempotent.
+//   - Durable keeps the consumer's state and the IDs in one file, written
+//     by one atomic rename, so a message's effects and its record are
+//     stored together or not at all, and no crash processes it twice.
+//
+// IDs are kept until Prune drops them, which must not happen before the
+// broker has stopped redelivering them.
+package inbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"patterns/clock"
+	"patterns/construct"
+	"patterns/funcopts"
+	"patterns/persistence/repository"
+)
+
+// ErrInFlight is returned for a message that is being handled right now,
+// by another delivery of it; the consumer should leave it unacked, to be
+// delivered again once the first one is done.
+var ErrInFlight = errors.New("inbox: message is being processed")
+
+// Record is the entry of one processed message.
+type Record struct {
+	ID        string    `json:"id"`
+	Processed time.Time `json:"processed"`
+}
+
+type options struct {
+	clock clock.Clock
+}
+
+type Option = funcopts.Option[options]
+
+func WithClock(c clock.Clock) Option {
+	return func(options *options) error {
+		if c == nil {
+			return errors.New("clock cannot be nil")
+		}
+		options.clock = c
+		return nil
+	}
+}
+
+func (o *options) SetDefaults() { o.clock = clock.Real }
+
+// inbox
+// Level: Average
+// pros: redeliveries are skipped whatever the handler does and wherever
+// its effects go, for one lookup and one write per message.
+// cons: the record is written after the effects, not with them: a crash
+// in between processes the message twice, so this narrows duplicates
+// rather than ruling them out.
+type Inbox struct {
+	repo    repository.Repository[string, Record]
+	options options
+
+	mu       sync.Mutex
+	inFlight map[string]bool
+}
+
+// New returns an inbox recording IDs in repo: repository.NewMemory for
+// one process's lifetime, repository.OpenFile to survive restarts.
+func New(repo repository.Repository[string, Record], opts ...Option) (*Inbox, error) {
+	if repo == nil {
+		return nil, errors.New("repo cannot be nil")
+	}
+	options, err := construct.New(opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &Inbox{repo: repo, options: *options, inFlight: map[string]bool{}}, nil
+}
+
+// Handle runs fn for the message id unless it was processed before, and
+// reports whether it ran. id is recorded only if fn succeeds, so a failed
+// message runs again when redelivered.
+func (in *Inbox) Handle(ctx context.Context, id string, fn func(ctx context.Context) error) (bool, error) {
+	if !in.claim(id) {
+		return false, fmt.Errorf("%w: %s", ErrInFlight, id)
+	}
+	defer in.release(id)
+
+	if _, err := in.repo.Get(ctx, id); err == nil {
+		return false, nil
+	} else if !errors.Is(err, repository.ErrNotFound) {
+		return false, err
+	}
+	if err := fn(ctx); err != nil {
+		return false, err
+	}
+	err := in.repo.Create(ctx, id, Record{ID: id, Processed: in.options.clock.Now()})
+	if err != nil && !errors.Is(err, repository.ErrExists) {
+		return true, fmt.Errorf("inbox: %s processed but not recorded: %w", id, err)
+	}
+	return true, nil
+}
+
+// Processed reports whether the message id was processed.
+func (in *Inbox) Processed(ctx context.Context, id string) (bool, error) {
+	_, err := in.repo.Get(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Prune forgets the messages processed before t and returns how many.
+func (in *Inbox) Prune(ctx context.Context, before time.Time) (int, error) {
+	records, err := in.repo.List(ctx)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, r := range records {
+		if !r.Processed.Before(before) {
+			continue
+		}
+		if err := in.repo.Delete(ctx, r.ID); err != nil && !errors.Is(err, repository.ErrNotFound) {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// claim keeps two deliveries of one message from running it at once,
+// both having found it unrecorded.
+func (in *Inbox) claim(id string) bool {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	if in.inFlight[id] {
+		return false
+	}
+	in.inFlight[id] = true
+	return true
+}
+
+func (in *Inbox) release(id string) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	delete(in.inFlight, id)
+}
@@ -0,0 +1,259 @@
+package inbox_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"patterns/clock"
+	"patterns/messaging/inbox"
+	"patterns/persistence/repository"
+	"patterns/randsource"
+)
+
+var epoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// redeliveries returns n message IDs, each delivered one to four times, in
+// a random order, as an at-least-once broker whose acks get lost would.
+func redeliveries(seed uint64, n int) []string {
+	r := randsource.New(seed)
+	var out []string
+	for i := range n {
+		for range 1 + r.IntN(4) {
+			out = append(out, fmt.Sprintf("m%d", i))
+		}
+	}
+	for i := len(out) - 1; i > 0; i-- {
+		j := r.IntN(i + 1)
+		out[i], out[j] = out[j], out[i]
+	}
+	return out
+}
+
+func newInbox(t *testing.T, repo repository.Repository[string, inbox.Record], opts ...inbox.Option) *inbox.Inbox {
+	t.Helper()
+	in, err := inbox.New(repo, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return in
+}
+
+// TestDuplicates replays messages with duplicates: each runs once, and
+// Handle reports it ran only that once.
+func TestDuplicates(t *testing.T) {
+	for seed := range uint64(10) {
+		in := newInbox(t, repository.NewMemory[string, inbox.Record]())
+		runs, handled := map[string]int{}, map[string]int{}
+		for _, id := range redeliveries(seed, 50) {
+			ok, err := in.Handle(context.Background(), id, func(context.Context) error {
+				runs[id]++
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ok {
+				handled[id]++
+			}
+		}
+		for i := range 50 {
+			id := fmt.Sprintf("m%d", i)
+			if runs[id] != 1 || handled[id] != 1 {
+				t.Errorf("seed %d: %s ran %d times, reported handled %d", seed, id, runs[id], handled[id])
+			}
+		}
+	}
+}
+
+// TestFailedRunsAgain checks that a message whose handler fails is not
+// recorded, so its redelivery runs it again.
+func TestFailedRunsAgain(t *testing.T) {
+	in := newInbox(t, repository.NewMemory[string, inbox.Record]())
+	boom := errors.New("boom")
+	runs := 0
+	fn := func(context.Context) error {
+		runs++
+		if runs == 1 {
+			return boom
+		}
+		return nil
+	}
+	if ok, err := in.Handle(context.Background(), "m", fn); ok || !errors.Is(err, boom) {
+		t.Errorf("failing Handle = %v, %v", ok, err)
+	}
+	if done, _ := in.Processed(context.Background(), "m"); done {
+		t.Error("failed message recorded")
+	}
+	if ok, err := in.Handle(context.Background(), "m", fn); !ok || err != nil || runs != 2 {
+		t.Errorf("redelivery = %v, %v after %d runs", ok, err, runs)
+	}
+}
+
+// TestCrashBeforeAck processes a message and restarts before it is acked:
+// the redelivery, to a new process over the same file, is skipped.
+func TestCrashBeforeAck(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "inbox.json")
+	open := func() *inbox.Inbox {
+		repo, err := repository.OpenFile[string, inbox.Record](path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return newInbox(t, repo)
+	}
+	runs := 0
+	fn := func(context.Context) error { runs++; return nil }
+	if ok, err := open().Handle(context.Background(), "m", fn); !ok || err != nil {
+		t.Fatalf("Handle = %v, %v", ok, err)
+	}
+	// crash: the ack is never sent, and the message comes again
+	if ok, err := open().Handle(context.Background(), "m", fn); ok || err != nil || runs != 1 {
+		t.Errorf("redelivery after restart = %v, %v, %d runs", ok, err, runs)
+	}
+}
+
+// unrecorded loses every Create, as a crash between the handler and the
+// record would.
+type unrecorded struct {
+	repository.Repository[string, inbox.Record]
+}
+
+var errCrash = errors.New("crashed")
+
+func (unrecorded) Create(context.Context, string, inbox.Record) error { return errCrash }
+
+// TestCrashBeforeRecord is the window Inbox leaves open: the handler ran
+// but its record was lost, so the redelivery runs it again. Handle says
+// so, with an error, for the consumer not to ack.
+func TestCrashBeforeRecord(t *testing.T) {
+	mem := repository.NewMemory[string, inbox.Record]()
+	runs := 0
+	fn := func(context.Context) error { runs++; return nil }
+	ok, err := newInbox(t, unrecorded{mem}).Handle(context.Background(), "m", fn)
+	if !ok || !errors.Is(err, errCrash) || err.Error() != "inbox: m processed but not recorded: crashed" {
+		t.Errorf("Handle = %v, %v", ok, err)
+	}
+	if ok, err := newInbox(t, mem).Handle(context.Background(), "m", fn); !ok || err != nil || runs != 2 {
+		t.Errorf("redelivery = %v, %v, %d runs; want the duplicate documented", ok, err, runs)
+	}
+}
+
+// TestInFlight delivers a message again while it is being handled: the
+// second delivery is refused, to be redelivered, and then skipped.
+func TestInFlight(t *testing.T) {
+	in := newInbox(t, repository.NewMemory[string, inbox.Record]())
+	started, release := make(chan struct{}), make(chan struct{})
+	done := make(chan error, 1)
+	go func() {
+		_, err := in.Handle(context.Background(), "m", func(context.Context) error {
+			close(started)
+			<-release
+			return nil
+		})
+		done <- err
+	}()
+	<-started
+	run := func(context.Context) error { t.Error("ran a message in flight"); return nil }
+	if ok, err := in.Handle(context.Background(), "m", run); ok || !errors.Is(err, inbox.ErrInFlight) {
+		t.Errorf("second delivery = %v, %v; want ErrInFlight", ok, err)
+	}
+	// other messages are not held up
+	if ok, err := in.Handle(context.Background(), "other", func(context.Context) error { return nil }); !ok || err != nil {
+		t.Errorf("other message = %v, %v", ok, err)
+	}
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := in.Handle(context.Background(), "m", run); ok || err != nil {
+		t.Errorf("redelivery = %v, %v", ok, err)
+	}
+}
+
+// TestConcurrent has several consumers take the same redelivered stream,
+// retrying what is in flight; run with -race.
+func TestConcurrent(t *testing.T) {
+	in := newInbox(t, repository.NewMemory[string, inbox.Record]())
+	var mu sync.Mutex
+	runs := map[string]int{}
+	var wg sync.WaitGroup
+	for c := range uint64(4) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for _, id := range redeliveries(c, 30) {
+				for {
+					_, err := in.Handle(context.Background(), id, func(context.Context) error {
+						mu.Lock()
+						runs[id]++
+						mu.Unlock()
+						return nil
+					})
+					if !errors.Is(err, inbox.ErrInFlight) {
+						break
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	for id, n := range runs {
+		if n != 1 {
+			t.Errorf("%s ran %d times", id, n)
+		}
+	}
+	if len(runs) != 30 {
+		t.Errorf("%d messages ran, want 30", len(runs))
+	}
+}
+
+func TestPrune(t *testing.T) {
+	clk := clock.NewFake(epoch)
+	in := newInbox(t, repository.NewMemory[string, inbox.Record](), inbox.WithClock(clk))
+	nop := func(context.Context) error { return nil }
+	in.Handle(context.Background(), "old", nop)
+	clk.Advance(time.Hour)
+	in.Handle(context.Background(), "new", nop)
+	if n, err := in.Prune(context.Background(), epoch.Add(time.Hour)); n != 1 || err != nil {
+		t.Errorf("Prune = %d, %v", n, err)
+	}
+	old, _ := in.Processed(context.Background(), "old")
+	kept, _ := in.Processed(context.Background(), "new")
+	if old || !kept {
+		t.Errorf("after Prune: old %v, new %v", old, kept)
+	}
+	// a pruned message is new again
+	if ok, _ := in.Handle(context.Background(), "old", nop); !ok {
+		t.Error("pruned message skipped")
+	}
+}
+
+// broken fails every read.
+type broken struct {
+	repository.Repository[string, inbox.Record]
+}
+
+func (broken) Get(context.Context, string) (inbox.Record, error) { return inbox.Record{}, errCrash }
+
+func TestNew(t *testing.T) {
+	if in, err := inbox.New(nil); in != nil || err == nil || err.Error() != "repo cannot be nil" {
+		t.Errorf("New(nil) = %v, %v", in, err)
+	}
+	mem := repository.NewMemory[string, inbox.Record]()
+	if _, err := inbox.New(mem, inbox.WithClock(nil)); err == nil || err.Error() != "clock cannot be nil" {
+		t.Errorf("WithClock(nil) = %v", err)
+	}
+	// a lookup that fails decides nothing: the handler does not run
+	in := newInbox(t, broken{mem})
+	ran := false
+	if ok, err := in.Handle(context.Background(), "m", func(context.Context) error { ran = true; return nil }); ok || ran || !errors.Is(err, errCrash) {
+		t.Errorf("Handle with a broken repo = %v, %v, ran %v", ok, err, ran)
+	}
+	if ok, err := in.Processed(context.Background(), "m"); ok || !errors.Is(err, errCrash) {
+		t.Errorf("Processed with a broken repo = %v, %v", ok, err)
+	}
+}
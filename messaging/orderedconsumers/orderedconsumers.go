@@ -0,0 +1,158 @@
+// Package orderedconsumers consumes one stream of messages with several
+// competing workers, while keeping the messages of any one key in order:
+//
+//	g, _ := orderedconsumers.New(sharding.StringHash[string](), handle,
+//		orderedconsumers.WithWorkers(8))
+//	err := g.Run(ctx, stream)
+//
+// Plain competing consumers, each taking the next message off a shared
+// queue, scale but reorder: two updates to one account picked up by two
+// workers can land in either order. Here a dispatcher hashes each key to a
+// sub-queue with exactly one worker behind it, so a key's messages run one
+// at a time in stream order, and different keys run in parallel as far as
+// they hash apart.
+//
+// The price is head-of-line blocking: a slow message holds up the keys
+// behind it in its sub-queue, and once that sub-queue is full the
+// dispatcher too, until it drains.
+package orderedconsumers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+
+	"patterns/construct"
+	"patterns/funcopts"
+)
+
+type Message[K comparable, V any] struct {
+	Key   K
+	Value V
+}
+
+// Handler processes one message. An error stops the whole group, so it
+// is for failures nothing downstream can cope with; retrying, or
+// dead-lettering, a message is the handler's own business.
+type Handler[K comparable, V any] func(ctx context.Context, m Message[K, V]) error
+
+type options struct {
+	workers int
+	queue   int
+}
+
+type Option = funcopts.Option[options]
+
+// WithWorkers sets the number of workers, and so of sub-queues; the
+// default is GOMAXPROCS.
+func WithWorkers(n int) Option {
+	return func(options *options) error {
+		if n <= 0 {
+			return errors.New("workers must be positive")
+		}
+		options.workers = n
+		return nil
+	}
+}
+
+// WithQueue sets how many messages may wait for each worker before the
+// dispatcher blocks; the default is 64.
+func WithQueue(n int) Option {
+	return func(options *options) error {
+		if n < 0 {
+			return errors.New("queue cannot be negative")
+		}
+		options.queue = n
+		return nil
+	}
+}
+
+func (o *options) SetDefaults() {
+	o.workers = runtime.GOMAXPROCS(0)
+	o.queue = 64
+}
+
+// competing consumers with per-key ordering
+// Level: Good
+// pros: in order per key, parallel across keys, with no locks in the
+// handler for state partitioned by the same key.
+// cons: parallelism is capped by the number of distinct keys and by how
+// evenly they hash; one hot or slow key stalls its whole sub-queue.
+type Group[K comparable, V any] struct {
+	hash    func(K) uint64
+	handle  Handler[K, V]
+	options options
+}
+
+// New returns a group routing each message to the worker hash(key) picks;
+// sharding.StringHash suits string keys.
+func New[K comparable, V any](hash func(K) uint64, handle Handler[K, V], opts ...Option) (*Group[K, V], error) {
+	if hash == nil || handle == nil {
+		return nil, errors.New("hash and handler cannot be nil")
+	}
+	options, err := construct.New(opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &Group[K, V]{hash: hash, handle: handle, options: *options}, nil
+}
+
+// Workers reports the number of workers.
+func (g *Group[K, V]) Workers() int { return g.options.workers }
+
+// WorkerOf reports the worker handling key.
+func (g *Group[K, V]) WorkerOf(key K) int { return int(g.hash(key) % uint64(g.options.workers)) }
+
+// Run consumes in until it is closed and every message taken from it is
+// handled, then returns nil. It stops early when ctx is done or a handler
+// fails, and returns why; messages already queued are then dropped
+// unhandled, for the stream's source to deliver again.
+func (g *Group[K, V]) Run(ctx context.Context, in <-chan Message[K, V]) error {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	queues := make([]chan Message[K, V], g.options.workers)
+	var wg sync.WaitGroup
+	for i := range queues {
+		queues[i] = make(chan Message[K, V], g.options.queue)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for m := range queues[i] {
+				if ctx.Err() != nil {
+					continue
+				}
+				if err := g.handle(ctx, m); err != nil {
+					cancel(fmt.Errorf("orderedconsumers: key %v: %w", m.Key, err))
+				}
+			}
+		}()
+	}
+
+	g.dispatch(ctx, in, queues)
+	for _, q := range queues {
+		close(q)
+	}
+	wg.Wait()
+	return context.Cause(ctx)
+}
+
+func (g *Group[K, V]) dispatch(ctx context.Context, in <-chan Message[K, V], queues []chan Message[K, V]) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case m, ok := <-in:
+			if !ok {
+				return
+			}
+			select {
+			case queues[g.WorkerOf(m.Key)] <- m:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
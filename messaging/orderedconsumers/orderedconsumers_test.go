@@ -0,0 +1,238 @@
+package orderedconsumers_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"patterns/distribution/sharding"
+	"patterns/messaging/orderedconsumers"
+	"patterns/randsource"
+)
+
+type msg = orderedconsumers.Message[string, int]
+
+// stream returns a closed channel holding ms.
+func stream(ms ...msg) <-chan msg {
+	in := make(chan msg, len(ms))
+	for _, m := range ms {
+		in <- m
+	}
+	close(in)
+	return in
+}
+
+// keysApart returns n keys that g hands to n different workers.
+func keysApart(g *orderedconsumers.Group[string, int], n int) []string {
+	var keys []string
+	seen := map[int]bool{}
+	for i := 0; len(keys) < n; i++ {
+		k := fmt.Sprintf("key-%d", i)
+		if w := g.WorkerOf(k); !seen[w] {
+			seen[w] = true
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+// TestPerKeyOrder runs a random stream over many keys and checks that each
+// key's messages are handled one at a time, in stream order; run with
+// -race.
+func TestPerKeyOrder(t *testing.T) {
+	r := randsource.New(1)
+	var ms []msg
+	want := map[string][]int{}
+	for i := range 2000 {
+		k := fmt.Sprintf("k%d", r.IntN(50))
+		ms = append(ms, msg{Key: k, Value: i})
+		want[k] = append(want[k], i)
+	}
+
+	var mu sync.Mutex
+	got := map[string][]int{}
+	busy := map[string]bool{}
+	var overlaps atomic.Int64
+	g, err := orderedconsumers.New(sharding.StringHash[string](), func(_ context.Context, m msg) error {
+		mu.Lock()
+		if busy[m.Key] {
+			overlaps.Add(1)
+		}
+		busy[m.Key] = true
+		mu.Unlock()
+		time.Sleep(time.Duration(m.Value%3) * time.Microsecond)
+		mu.Lock()
+		busy[m.Key] = false
+		got[m.Key] = append(got[m.Key], m.Value)
+		mu.Unlock()
+		return nil
+	}, orderedconsumers.WithWorkers(8), orderedconsumers.WithQueue(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Run(context.Background(), stream(ms...)); err != nil {
+		t.Fatal(err)
+	}
+	if n := overlaps.Load(); n != 0 {
+		t.Errorf("%d messages ran alongside another of their key", n)
+	}
+	for k, w := range want {
+		if !slices.Equal(got[k], w) {
+			t.Errorf("key %s handled %v, want %v", k, got[k], w)
+		}
+	}
+}
+
+// TestParallel checks that keys on different workers run at the same
+// time: each handler waits for all of them to have started.
+func TestParallel(t *testing.T) {
+	const n = 4
+	var started sync.WaitGroup
+	started.Add(n)
+	all := make(chan struct{})
+	go func() {
+		started.Wait()
+		close(all)
+	}()
+	g, _ := orderedconsumers.New(sharding.StringHash[string](), func(ctx context.Context, m msg) error {
+		started.Done()
+		select {
+		case <-all:
+			return nil
+		case <-time.After(5 * time.Second):
+			return errors.New("handlers did not run in parallel")
+		}
+	}, orderedconsumers.WithWorkers(n))
+	var ms []msg
+	for _, k := range keysApart(g, n) {
+		ms = append(ms, msg{Key: k})
+	}
+	if err := g.Run(context.Background(), stream(ms...)); err != nil {
+		t.Error(err)
+	}
+}
+
+// TestSharedWorker checks that keys on one worker wait for each other, and
+// that a slow message holds up the keys behind it.
+func TestSharedWorker(t *testing.T) {
+	release := make(chan struct{})
+	var mu sync.Mutex
+	var ran []string
+	g, _ := orderedconsumers.New(sharding.StringHash[string](), func(_ context.Context, m msg) error {
+		if m.Value == 1 {
+			<-release
+		}
+		mu.Lock()
+		ran = append(ran, m.Key)
+		mu.Unlock()
+		return nil
+	}, orderedconsumers.WithWorkers(1))
+	in := make(chan msg)
+	done := make(chan error, 1)
+	go func() { done <- g.Run(context.Background(), in) }()
+	in <- msg{Key: "slow", Value: 1}
+	in <- msg{Key: "fast"}
+	time.Sleep(10 * time.Millisecond)
+	mu.Lock()
+	if len(ran) != 0 {
+		t.Errorf("ran %q behind a message still in its handler", ran)
+	}
+	mu.Unlock()
+	close(release)
+	close(in)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(ran, []string{"slow", "fast"}) {
+		t.Errorf("ran %q", ran)
+	}
+}
+
+// TestHandlerError checks that a failing handler stops the group: Run
+// returns its error, and the messages queued behind it are dropped.
+func TestHandlerError(t *testing.T) {
+	boom := errors.New("boom")
+	var handled atomic.Int64
+	g, _ := orderedconsumers.New(sharding.StringHash[string](), func(_ context.Context, m msg) error {
+		handled.Add(1)
+		if m.Value == 2 {
+			return boom
+		}
+		return nil
+	}, orderedconsumers.WithWorkers(1))
+	var ms []msg
+	for i := range 10 {
+		ms = append(ms, msg{Key: "a", Value: i})
+	}
+	err := g.Run(context.Background(), stream(ms...))
+	if !errors.Is(err, boom) || err.Error() != "orderedconsumers: key a: boom" {
+		t.Errorf("Run = %v", err)
+	}
+	if n := handled.Load(); n != 3 {
+		t.Errorf("handled %d messages, want 3: none after the failure", n)
+	}
+}
+
+// TestCancel checks that Run returns when its context is done, even with
+// the stream still open and a handler running.
+func TestCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	started := make(chan struct{})
+	g, _ := orderedconsumers.New(sharding.StringHash[string](), func(ctx context.Context, _ msg) error {
+		close(started)
+		<-ctx.Done()
+		return nil
+	}, orderedconsumers.WithWorkers(2))
+	in := make(chan msg, 1)
+	in <- msg{Key: "a"}
+	done := make(chan error, 1)
+	go func() { done <- g.Run(ctx, in) }()
+	<-started
+	cancel()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Run = %v, want context.Canceled", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return after cancel")
+	}
+}
+
+func TestNew(t *testing.T) {
+	hash := sharding.StringHash[string]()
+	handle := func(context.Context, msg) error { return nil }
+	for _, c := range []struct {
+		hash   func(string) uint64
+		handle orderedconsumers.Handler[string, int]
+		opts   []orderedconsumers.Option
+		want   string
+	}{
+		{nil, handle, nil, "hash and handler cannot be nil"},
+		{hash, nil, nil, "hash and handler cannot be nil"},
+		{hash, handle, []orderedconsumers.Option{orderedconsumers.WithWorkers(0)}, "workers must be positive"},
+		{hash, handle, []orderedconsumers.Option{orderedconsumers.WithQueue(-1)}, "queue cannot be negative"},
+	} {
+		if g, err := orderedconsumers.New(c.hash, c.handle, c.opts...); g != nil || err == nil || err.Error() != c.want {
+			t.Errorf("New = %v, %v; want %q", g, err, c.want)
+		}
+	}
+	g, err := orderedconsumers.New(hash, handle, orderedconsumers.WithWorkers(3), orderedconsumers.WithQueue(0))
+	if err != nil || g.Workers() != 3 {
+		t.Fatalf("New = %v, %v", g, err)
+	}
+	for _, k := range []string{"a", "b", "c", "d"} {
+		if w := g.WorkerOf(k); w < 0 || w >= 3 || w != g.WorkerOf(k) {
+			t.Errorf("WorkerOf(%q) = %d", k, w)
+		}
+	}
+	// an unbuffered queue still delivers everything
+	if err := g.Run(context.Background(), stream(msg{Key: "a"}, msg{Key: "b"})); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,315 @@
+package pubsub_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"patterns/behavioral/observer"
+	"patterns/messaging/pubsub"
+)
+
+func subscribe(t *testing.T, bus *pubsub.Bus[int], topic string, opts ...pubsub.Option) *pubsub.Subscription[int] {
+	t.Helper()
+	sub, err := bus.Subscribe(topic, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sub
+}
+
+func publish(t *testing.T, bus *pubsub.Bus[int], topic string, vs ...int) (sent []int) {
+	t.Helper()
+	for _, v := range vs {
+		n, err := bus.Publish(context.Background(), topic, v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sent = append(sent, n)
+	}
+	return sent
+}
+
+// drain reads what sub has buffered, without waiting for more.
+func drain(sub *pubsub.Subscription[int]) []int {
+	var vs []int
+	for {
+		select {
+		case m, ok := <-sub.C():
+			if !ok {
+				return vs
+			}
+			vs = append(vs, m.Value)
+		default:
+			return vs
+		}
+	}
+}
+
+// closed reports whether sub's channel yields vs and then closes.
+func closed(sub *pubsub.Subscription[int], vs ...int) bool {
+	var got []int
+	for m := range sub.C() {
+		got = append(got, m.Value)
+	}
+	return slices.Equal(got, vs)
+}
+
+func TestTopics(t *testing.T) {
+	bus := pubsub.New[int]()
+	a := subscribe(t, bus, "orders")
+	b := subscribe(t, bus, "orders")
+	other := subscribe(t, bus, "refunds")
+	if got := publish(t, bus, "orders", 1, 2); !slices.Equal(got, []int{2, 2}) {
+		t.Errorf("Publish sent to %v", got)
+	}
+	if got := publish(t, bus, "nobody", 3); !slices.Equal(got, []int{0}) {
+		t.Errorf("Publish to no subscribers sent to %v", got)
+	}
+	for _, sub := range []*pubsub.Subscription[int]{a, b} {
+		m := <-sub.C()
+		if m.Topic != "orders" || m.Value != 1 || sub.Topic() != "orders" {
+			t.Errorf("got %+v on %s", m, sub.Topic())
+		}
+		if got := drain(sub); !slices.Equal(got, []int{2}) {
+			t.Errorf("then %v", got)
+		}
+	}
+	if got := drain(other); got != nil {
+		t.Errorf("refunds got %v", got)
+	}
+	if bus.Subscribers("orders") != 2 || bus.Subscribers("refunds") != 1 || bus.Subscribers("nobody") != 0 {
+		t.Errorf("Subscribers = %d, %d, %d", bus.Subscribers("orders"), bus.Subscribers("refunds"), bus.Subscribers("nobody"))
+	}
+
+	// a subscription sees only what is published after it starts
+	late := subscribe(t, bus, "orders")
+	publish(t, bus, "orders", 4)
+	if got := drain(late); !slices.Equal(got, []int{4}) {
+		t.Errorf("late subscriber got %v", got)
+	}
+}
+
+// TestDrop publishes past a full buffer under each drop policy: Publish
+// never waits, the subscription keeps the first or the last values, and
+// counts the rest.
+func TestDrop(t *testing.T) {
+	for _, c := range []struct {
+		overflow observer.Overflow
+		sent     []int
+		kept     []int
+	}{
+		{observer.OverflowDropNewest, []int{1, 1, 0, 0, 0}, []int{0, 1}},
+		{observer.OverflowDropOldest, []int{1, 1, 1, 1, 1}, []int{3, 4}},
+	} {
+		bus := pubsub.New[int]()
+		sub := subscribe(t, bus, "prices", pubsub.WithBuffer(2), pubsub.WithOverflow(c.overflow))
+		// a fast subscriber on the topic loses nothing to the slow one
+		fast := subscribe(t, bus, "prices", pubsub.WithBuffer(8), pubsub.WithOverflow(c.overflow))
+		sent := publish(t, bus, "prices", 0, 1, 2, 3, 4)
+		for i := range sent {
+			sent[i]-- // the fast one's
+		}
+		if !slices.Equal(sent, c.sent) {
+			t.Errorf("%s: Publish sent %v, want %v", c.overflow, sent, c.sent)
+		}
+		if got := drain(sub); !slices.Equal(got, c.kept) || sub.Dropped() != 3 {
+			t.Errorf("%s: kept %v, dropped %d; want %v, 3", c.overflow, got, sub.Dropped(), c.kept)
+		}
+		if got := drain(fast); !slices.Equal(got, []int{0, 1, 2, 3, 4}) || fast.Dropped() != 0 {
+			t.Errorf("%s: fast subscriber got %v, dropped %d", c.overflow, got, fast.Dropped())
+		}
+		// room again: nothing more is dropped
+		publish(t, bus, "prices", 5)
+		if got := drain(sub); !slices.Equal(got, []int{5}) || sub.Dropped() != 3 {
+			t.Errorf("%s: then %v, dropped %d", c.overflow, got, sub.Dropped())
+		}
+	}
+}
+
+// publishing starts Publish in a goroutine; the channel gets its result
+// once it returns.
+func publishing(bus *pubsub.Bus[int], ctx context.Context, topic string, v int) <-chan error {
+	done := make(chan error, 1)
+	go func() {
+		_, err := bus.Publish(ctx, topic, v)
+		done <- err
+	}()
+	return done
+}
+
+func returned(done <-chan error) bool {
+	select {
+	case <-done:
+		return true
+	case <-time.After(20 * time.Millisecond):
+		return false
+	}
+}
+
+// TestBlock fills a subscription's buffer under OverflowBlock: Publish
+// waits until the subscriber reads, or until its context ends, and loses
+// nothing.
+func TestBlock(t *testing.T) {
+	bus := pubsub.New[int]()
+	sub := subscribe(t, bus, "jobs", pubsub.WithBuffer(1))
+	publish(t, bus, "jobs", 0)
+	done := publishing(bus, context.Background(), "jobs", 1)
+	if returned(done) {
+		t.Fatal("Publish returned with the buffer full")
+	}
+	if m := <-sub.C(); m.Value != 0 {
+		t.Errorf("got %d, want 0", m.Value)
+	}
+	if !returned(done) {
+		t.Fatal("Publish still blocked after the subscriber read")
+	}
+	if m := <-sub.C(); m.Value != 1 || sub.Dropped() != 0 {
+		t.Errorf("got %d, dropped %d", m.Value, sub.Dropped())
+	}
+
+	// the buffer full again, a context that ends stops the wait
+	publish(t, bus, "jobs", 2)
+	ctx, cancel := context.WithCancelCause(context.Background())
+	errGone := errors.New("caller gone")
+	done = publishing(bus, ctx, "jobs", 3)
+	cancel(errGone)
+	if err := <-done; err != errGone {
+		t.Errorf("cancelled Publish = %v, want %v", err, errGone)
+	}
+	if got := drain(sub); !slices.Equal(got, []int{2}) {
+		t.Errorf("buffered %v, want [2]", got)
+	}
+
+	// the slowest subscriber sets the pace: the fast one waits too
+	fast := subscribe(t, bus, "jobs", pubsub.WithBuffer(8))
+	publish(t, bus, "jobs", 4)
+	done = publishing(bus, context.Background(), "jobs", 5)
+	if returned(done) {
+		t.Fatal("Publish returned with a buffer full")
+	}
+	if got := drain(fast); !slices.Equal(got, []int{4}) && !slices.Equal(got, []int{4, 5}) {
+		t.Errorf("fast subscriber got %v", got)
+	}
+	<-sub.C()
+	<-done
+}
+
+// TestUnsubscribe checks that a subscription ends cleanly: it keeps what
+// was buffered, its channel then closes, it gets nothing published
+// after, and a Publish blocked on it moves on.
+func TestUnsubscribe(t *testing.T) {
+	bus := pubsub.New[int]()
+	sub := subscribe(t, bus, "jobs", pubsub.WithBuffer(2))
+	stay := subscribe(t, bus, "jobs", pubsub.WithBuffer(8))
+	publish(t, bus, "jobs", 0, 1)
+	done := publishing(bus, context.Background(), "jobs", 2)
+	if returned(done) {
+		t.Fatal("Publish returned with the buffer full")
+	}
+	sub.Unsubscribe()
+	if err := <-done; err != nil {
+		t.Errorf("blocked Publish = %v", err)
+	}
+	if got := publish(t, bus, "jobs", 3); !slices.Equal(got, []int{1}) {
+		t.Errorf("Publish after Unsubscribe sent to %v", got)
+	}
+	if !closed(sub, 0, 1) {
+		t.Error("channel did not yield its buffer and close")
+	}
+	if got := drain(stay); !slices.Equal(got, []int{0, 1, 2, 3}) {
+		t.Errorf("other subscriber got %v", got)
+	}
+	sub.Unsubscribe()
+	stay.Unsubscribe()
+	if bus.Subscribers("jobs") != 0 {
+		t.Errorf("Subscribers = %d after both left", bus.Subscribers("jobs"))
+	}
+}
+
+// TestRace publishes under every policy while subscribers come and go:
+// no send lands on a closed channel, and every channel closes.
+func TestRace(t *testing.T) {
+	bus := pubsub.New[int]()
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 200 {
+				if _, err := bus.Publish(context.Background(), "t", i); err != nil {
+					if err != pubsub.ErrClosed {
+						t.Error(err)
+					}
+					return
+				}
+			}
+		}()
+	}
+	for i := range 60 {
+		sub := subscribe(t, bus, "t", pubsub.WithBuffer(1), pubsub.WithOverflow(observer.OverflowValues()[i%3]))
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range sub.C() {
+				if i%2 == 0 {
+					sub.Unsubscribe()
+				}
+			}
+		}()
+		if i%2 == 1 {
+			sub.Unsubscribe()
+		}
+	}
+	// the even subscribers that never got a message leave at Close
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		bus.Close()
+	}()
+	wg.Wait()
+}
+
+func TestClose(t *testing.T) {
+	bus := pubsub.New[int]()
+	a := subscribe(t, bus, "a")
+	b := subscribe(t, bus, "b")
+	publish(t, bus, "a", 1)
+	if err := bus.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !closed(a, 1) || !closed(b) {
+		t.Error("channels did not yield their buffers and close")
+	}
+	if _, err := bus.Publish(context.Background(), "a", 2); err != pubsub.ErrClosed {
+		t.Errorf("Publish after Close = %v", err)
+	}
+	if _, err := bus.Subscribe("a"); err != pubsub.ErrClosed {
+		t.Errorf("Subscribe after Close = %v", err)
+	}
+	if err := bus.Close(); err != pubsub.ErrClosed {
+		t.Errorf("second Close = %v", err)
+	}
+	a.Unsubscribe()
+}
+
+func TestOptions(t *testing.T) {
+	bus := pubsub.New[int]()
+	for _, c := range []struct {
+		opt  pubsub.Option
+		want string
+	}{
+		{pubsub.WithBuffer(0), "buffer must be positive"},
+		{pubsub.WithOverflow(observer.Overflow(9)), "invalid overflow policy 9"},
+	} {
+		if sub, err := bus.Subscribe("a", c.opt); sub != nil || err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Errorf("Subscribe = %v, want %q", err, c.want)
+		}
+	}
+	if bus.Subscribers("a") != 0 {
+		t.Error("a failed Subscribe left a subscription")
+	}
+}
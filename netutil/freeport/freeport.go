@@ -0,0 +1,23 @@
+// Package freeport hands out ephemeral ports without the classic
+// allocate-then-rebind race.
+//
+// The usual trick of listening on :0, reading the port, closing the
+// listener and passing the number on leaves a window in which another
+// process can take the port. ListenEphemeral keeps the bound listener and
+// returns it, so whoever serves uses the very socket the kernel assigned.
+// Tests take one with freeporttest.Listen.
+package freeport
+
+import "net"
+
+// ListenEphemeral binds host:0 and returns the listener together with the
+// port the kernel chose. Serve on the returned listener; do not close it
+// and listen again on the port.
+func ListenEphemeral(host string) (net.Listener, int, error) {
+	l, err := net.Listen("tcp", net.JoinHostPort(host, "0"))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return l, l.Addr().(*net.TCPAddr).Port, nil
+}
@@ -0,0 +1,65 @@
+package freeport
+
+import (
+	"net"
+	"strconv"
+	"testing"
+)
+
+func TestListenEphemeral(t *testing.T) {
+	l, port, err := ListenEphemeral("127.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	if port == 0 {
+		t.Fatal("port = 0, want the port the kernel chose")
+	}
+	if want := "127.0.0.1:" + strconv.Itoa(port); l.Addr().String() != want {
+		t.Errorf("Addr = %s, want %s", l.Addr(), want)
+	}
+
+	// the listener returned is the bound one, ready to accept
+	c, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	s, err := l.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+
+	// and it holds the port
+	if other, err := net.Listen("tcp", l.Addr().String()); err == nil {
+		other.Close()
+		t.Errorf("port %d could be bound again while the listener was open", port)
+	}
+}
+
+func TestListenEphemeralDistinct(t *testing.T) {
+	seen := map[int]bool{}
+	for range 10 {
+		l, port, err := ListenEphemeral("127.0.0.1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer l.Close()
+		if seen[port] {
+			t.Fatalf("port %d handed out twice", port)
+		}
+		seen[port] = true
+	}
+}
+
+func TestListenEphemeralBadHost(t *testing.T) {
+	l, port, err := ListenEphemeral("256.0.0.1")
+	if err == nil {
+		l.Close()
+		t.Fatal("ListenEphemeral(256.0.0.1) succeeded")
+	}
+	if l != nil || port != 0 {
+		t.Errorf("got %v, %d with an error", l, port)
+	}
+}
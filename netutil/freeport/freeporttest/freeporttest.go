@@ -0,0 +1,23 @@
+// Package freeporttest is the test side of freeport, kept apart so that
+// a binary serving on a freeport listener does not link package testing.
+package freeporttest
+
+import (
+	"net"
+	"testing"
+
+	"patterns/netutil/freeport"
+)
+
+// Listen is freeport.ListenEphemeral on the loopback interface; the
+// listener is closed when tb finishes.
+func Listen(tb testing.TB) (net.Listener, int) {
+	tb.Helper()
+	l, port, err := freeport.ListenEphemeral("127.0.0.1")
+	if err != nil {
+		tb.Fatalf("freeport: %v", err)
+	}
+	tb.Cleanup(func() { l.Close() })
+
+	return l, port
+}
@@ -0,0 +1,21 @@
+package freeporttest
+
+import (
+	"errors"
+	"net"
+	"testing"
+)
+
+func TestListenClosedAtCleanup(t *testing.T) {
+	var l net.Listener
+	t.Run("user", func(t *testing.T) {
+		var port int
+		l, port = Listen(t)
+		if port == 0 || l.Addr().(*net.TCPAddr).Port != port {
+			t.Fatalf("Listen = %s, %d", l.Addr(), port)
+		}
+	})
+	if _, err := l.Accept(); !errors.Is(err, net.ErrClosed) {
+		t.Errorf("Accept after the test finished = %v, want net.ErrClosed", err)
+	}
+}
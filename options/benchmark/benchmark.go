@@ -0,0 +1,25 @@
+// Package benchmark measures what each options variant costs to construct
+// the same server: localhost on an explicit port, so no socket is opened
+// and only the configuration path is timed.
+//
+// findings (see benchmark_test.go; go test -bench . -benchmem
+// patterns/options/benchmark):
+//   - procedural, configstruct, builder and staged cost the same, about
+//     0.5µs and 3-4 allocations; that is the *http.Server and the
+//     "host:port" string, not the configuration style.
+//   - ifaceopts is ~3µs and 15 allocations, most of them the default
+//     discard logger built per call, the rest boxing options into
+//     interfaces and cloning the slice to sort it.
+//   - generated is ~2µs and 10 allocations: the same default discard
+//     logger, the option closure and the escaping port; a plain apply
+//     loop with no bookkeeping.
+//   - functional is ~3.5µs and 31 allocations: the funcopts session
+//     (duplicate, group and warning maps), option descriptions for
+//     EffectiveOptions, and the Server wrapper's channel and logger.
+//
+// So the Levels are not about speed: the "Good" variants are the slowest
+// here, by a few microseconds paid once per server. They earn the grade
+// with validation, defaults and introspection, which is exactly what the
+// extra allocations buy. Only per-call options (see calloptions and
+// zeroalloc) are hot enough for this cost to matter.
+package benchmark
@@ -0,0 +1,99 @@
+package benchmark
+
+import (
+	"net/http"
+	"testing"
+
+	"patterns/options/builder"
+	"patterns/options/configstruct"
+	"patterns/options/functional"
+	"patterns/options/generated"
+	"patterns/options/ifaceopts"
+	"patterns/options/procedural"
+	"patterns/options/staged"
+	"patterns/types/field"
+)
+
+var (
+	sink           *http.Server
+	functionalSink *functional.Server
+)
+
+// port varies per iteration so nothing is constant-folded, and stays
+// above 255 where small-int boxing stops being free
+func port(i int) int { return 8000 + i%1000 }
+
+func BenchmarkProcedural(b *testing.B) {
+	for i := range b.N {
+		p := port(i)
+		s, _, err := procedural.NewServer("localhost", &p)
+		if err != nil {
+			b.Fatal(err)
+		}
+		sink = s
+	}
+}
+
+func BenchmarkConfigStruct(b *testing.B) {
+	for i := range b.N {
+		s, _, err := configstruct.NewServer("localhost", &configstruct.Config{Port: field.Of(port(i))})
+		if err != nil {
+			b.Fatal(err)
+		}
+		sink = s
+	}
+}
+
+func BenchmarkBuilder(b *testing.B) {
+	for i := range b.N {
+		cfg, err := new(builder.ConfigBuilder).Port(port(i)).Build()
+		if err != nil {
+			b.Fatal(err)
+		}
+		s, _, err := builder.NewServer("localhost", &cfg)
+		if err != nil {
+			b.Fatal(err)
+		}
+		sink = s
+	}
+}
+
+func BenchmarkFunctionalOptions(b *testing.B) {
+	for i := range b.N {
+		s, err := functional.NewServer("localhost", functional.WithPort(port(i)))
+		if err != nil {
+			b.Fatal(err)
+		}
+		functionalSink = s
+	}
+}
+
+func BenchmarkStaged(b *testing.B) {
+	for i := range b.N {
+		s, _, err := staged.New().Addr("localhost").Port(uint16(port(i))).NewServer()
+		if err != nil {
+			b.Fatal(err)
+		}
+		sink = s
+	}
+}
+
+func BenchmarkInterfaceOptions(b *testing.B) {
+	for i := range b.N {
+		s, _, err := ifaceopts.NewServer("localhost", ifaceopts.WithPort(port(i)))
+		if err != nil {
+			b.Fatal(err)
+		}
+		sink = s
+	}
+}
+
+func BenchmarkGeneratedOptions(b *testing.B) {
+	for i := range b.N {
+		s, _, err := generated.NewServer("localhost", generated.WithPort(port(i)))
+		if err != nil {
+			b.Fatal(err)
+		}
+		sink = s
+	}
+}
@@ -0,0 +1,50 @@
+package builder
+
+import (
+	"net"
+	"net/http"
+
+	"patterns/options/portspec"
+)
+
+// builder pattern
+// Level: Good
+// cons: Delayed validation, port method can not return error
+type Config struct {
+	Port *int
+}
+
+type ConfigBuilder struct {
+	port *int
+}
+
+func (b *ConfigBuilder) Port(port int) *ConfigBuilder {
+	// Can also write port init logic here.
+	b.port = &port
+	return b
+}
+
+// Build validates the configuration. It does not resolve the port:
+// nil stays "default" and 0 stays "random" until NewServer.
+func (b *ConfigBuilder) Build() (Config, error) {
+	if err := portspec.Check(b.port); err != nil {
+		return Config{}, err
+	}
+
+	return Config{Port: b.port}, nil
+}
+
+// NewServer resolves cfg.Port by the port spec; a nil cfg is the zero
+// Config, the default port. s.Addr carries the resolved port, and l is
+// non-nil only for port 0, bound to it.
+func NewServer(addr string, cfg *Config) (s *http.Server, l net.Listener, err error) {
+	if cfg == nil {
+		cfg = &Config{}
+	}
+	hostport, l, err := portspec.Resolve(addr, cfg.Port)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &http.Server{Addr: hostport}, l, nil
+}
@@ -0,0 +1,70 @@
+package builder_test
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"patterns/options/builder"
+)
+
+func ExampleConfigBuilder() {
+	var b builder.ConfigBuilder
+	cfg, err := b.Port(9090).Build()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	s, _, err := builder.NewServer("localhost", &cfg)
+	fmt.Println(s.Addr, err)
+
+	// the zero Config is the default port, and so is none
+	s, _, _ = builder.NewServer("localhost", &builder.Config{})
+	fmt.Println(s.Addr)
+	s, _, _ = builder.NewServer("localhost", nil)
+	fmt.Println(s.Addr)
+
+	_, err = new(builder.ConfigBuilder).Port(-1).Build()
+	fmt.Println(err)
+	// Output:
+	// localhost:9090 <nil>
+	// localhost:8080
+	// localhost:8080
+	// port cannot be negative
+}
+
+func ExampleFreezingBuilder() {
+	b := builder.NewFreezingBuilder().Host("a.example").Header("X-Env", "prod").Limit("/api", 100)
+	cfg, _ := b.Build()
+
+	// using the builder again does not reach the config it built
+	b.Host("b.example").Header("X-Env", "staging")
+	next, _ := b.Port(9090).Timeout(time.Minute).Build()
+
+	fmt.Println(cfg.Port(), cfg.Timeout(), slices.Collect(cfg.Hosts()), cfg.Header("X-Env"))
+	fmt.Println(next.Port(), next.Timeout(), slices.Collect(next.Hosts()), next.Header("X-Env"))
+	fmt.Println(cfg.Limit("/api"))
+	// Output:
+	// 8080 30s [a.example] [prod]
+	// 9090 1m0s [a.example b.example] [prod staging]
+	// 100 true
+}
+
+func ExampleFreezingBuilder_errors() {
+	_, err := builder.NewFreezingBuilder().Port(70000).Host("").Timeout(0).Build()
+	fmt.Println(err)
+	// Output:
+	// port 70000 out of range
+	// host cannot be empty
+	// timeout must be positive
+}
+
+func ExampleSharingBuilder() {
+	b := new(builder.SharingBuilder).Host("a.example").Header("X-Env", "prod")
+	cfg := b.Build()
+
+	// the config shares the builder's header map, so this reaches it
+	b.Header("X-Env", "staging")
+	fmt.Println(cfg.Header("X-Env"))
+	// Output: [prod staging]
+}
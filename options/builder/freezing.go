@@ -0,0 +1,163 @@
+// Package builder is the builder variant of the options comparison:
+//
+//	var b builder.ConfigBuilder
+//	b.Port(8080) // usable method chain
+//	cfg, err := b.Build()
+//	if err != nil {
+//		log.Println(err)
+//	}
+//	s, l, err := builder.NewServer("localhost", &cfg) // l is set for port 0
+//
+// FreezingBuilder builds a ServerConfig that is safe to share: Build
+// deep-copies every slice and map, so the builder can keep being used
+// (for the next config, or a variant of this one) while any number of
+// goroutines read the config it returned.
+package builder
+
+import (
+	"errors"
+	"fmt"
+	"iter"
+	"maps"
+	"slices"
+	"time"
+)
+
+// ServerConfig is immutable: its fields are unexported and the accessors
+// return copies or read-only iterators.
+type ServerConfig struct {
+	port    int
+	hosts   []string
+	headers map[string][]string
+	limits  map[string]int
+	timeout time.Duration
+}
+
+func (c *ServerConfig) Port() int              { return c.port }
+func (c *ServerConfig) Timeout() time.Duration { return c.timeout }
+
+// Hosts yields the allowed hosts in the order they were added.
+func (c *ServerConfig) Hosts() iter.Seq[string] { return slices.Values(c.hosts) }
+
+// Header returns a copy of the default values for key.
+func (c *ServerConfig) Header(key string) []string { return slices.Clone(c.headers[key]) }
+
+// Limit returns the rate limit for route and whether one is set.
+func (c *ServerConfig) Limit(route string) (int, bool) {
+	n, ok := c.limits[route]
+	return n, ok
+}
+
+// sharing builder pattern
+// Level: Poor
+// cons: Build hands out the builder's own slices and maps. Adding a host
+// after Build can write into the config's backing array, and setting a
+// header after Build is a concurrent map write against every reader.
+type SharingBuilder struct {
+	cfg ServerConfig
+}
+
+func (b *SharingBuilder) Host(h string) *SharingBuilder {
+	b.cfg.hosts = append(b.cfg.hosts, h)
+	return b
+}
+
+func (b *SharingBuilder) Header(key, value string) *SharingBuilder {
+	if b.cfg.headers == nil {
+		b.cfg.headers = map[string][]string{}
+	}
+	b.cfg.headers[key] = append(b.cfg.headers[key], value)
+	return b
+}
+
+func (b *SharingBuilder) Build() *ServerConfig {
+	cfg := b.cfg
+	return &cfg
+}
+
+// freezing builder pattern
+// Level: Good
+// pros: the config is a snapshot; mutating or reusing the builder after
+// Build cannot affect it, so it can be shared without locks.
+// cons: Build copies every slice and map, which matters only for very
+// large configs built often.
+type FreezingBuilder struct {
+	cfg  ServerConfig
+	errs []error
+}
+
+// NewFreezingBuilder starts from the defaults: port 8080, 30s timeout.
+func NewFreezingBuilder() *FreezingBuilder {
+	return &FreezingBuilder{cfg: ServerConfig{port: 8080, timeout: 30 * time.Second}}
+}
+
+func (b *FreezingBuilder) Port(port int) *FreezingBuilder {
+	if port < 0 || port > 65535 {
+		b.errs = append(b.errs, fmt.Errorf("port %d out of range", port))
+	}
+	b.cfg.port = port
+	return b
+}
+
+func (b *FreezingBuilder) Timeout(d time.Duration) *FreezingBuilder {
+	if d <= 0 {
+		b.errs = append(b.errs, errors.New("timeout must be positive"))
+	}
+	b.cfg.timeout = d
+	return b
+}
+
+func (b *FreezingBuilder) Host(h string) *FreezingBuilder {
+	if h == "" {
+		b.errs = append(b.errs, errors.New("host cannot be empty"))
+	}
+	b.cfg.hosts = append(b.cfg.hosts, h)
+	return b
+}
+
+func (b *FreezingBuilder) Header(key, value string) *FreezingBuilder {
+	if b.cfg.headers == nil {
+		b.cfg.headers = map[string][]string{}
+	}
+	b.cfg.headers[key] = append(b.cfg.headers[key], value)
+	return b
+}
+
+func (b *FreezingBuilder) Limit(route string, perSecond int) *FreezingBuilder {
+	if perSecond <= 0 {
+		b.errs = append(b.errs, fmt.Errorf("limit for %s must be positive", route))
+	}
+	if b.cfg.limits == nil {
+		b.cfg.limits = map[string]int{}
+	}
+	b.cfg.limits[route] = perSecond
+	return b
+}
+
+// Build returns a deep copy of the configuration so far, or every error
+// recorded along the chain. The builder stays usable.
+func (b *FreezingBuilder) Build() (*ServerConfig, error) {
+	if err := errors.Join(b.errs...); err != nil {
+		return nil, err
+	}
+	return &ServerConfig{
+		port:    b.cfg.port,
+		hosts:   slices.Clone(b.cfg.hosts),
+		headers: cloneHeaders(b.cfg.headers),
+		limits:  maps.Clone(b.cfg.limits),
+		timeout: b.cfg.timeout,
+	}, nil
+}
+
+// cloneHeaders copies the map and each value slice; maps.Clone alone
+// would share the slices.
+func cloneHeaders(h map[string][]string) map[string][]string {
+	if h == nil {
+		return nil
+	}
+	out := make(map[string][]string, len(h))
+	for k, vs := range h {
+		out[k] = slices.Clone(vs)
+	}
+	return out
+}
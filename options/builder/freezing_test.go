@@ -0,0 +1,151 @@
+package builder_test
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"patterns/options/builder"
+)
+
+// snapshot is everything a reader can see of a ServerConfig.
+type snapshot struct {
+	port    int
+	timeout time.Duration
+	hosts   []string
+	env     []string
+	limit   int
+	limited bool
+}
+
+func read(cfg *builder.ServerConfig) snapshot {
+	limit, ok := cfg.Limit("/api")
+	return snapshot{cfg.Port(), cfg.Timeout(), slices.Collect(cfg.Hosts()), cfg.Header("X-Env"), limit, ok}
+}
+
+func (s snapshot) equal(o snapshot) bool {
+	return s.port == o.port && s.timeout == o.timeout && slices.Equal(s.hosts, o.hosts) &&
+		slices.Equal(s.env, o.env) && s.limit == o.limit && s.limited == o.limited
+}
+
+// TestFreezingRace has readers check a built config while the builder
+// goes on changing every field and building more; run with -race, which
+// fails on any memory the config still shares with the builder.
+func TestFreezingRace(t *testing.T) {
+	b := builder.NewFreezingBuilder().Host("a.example").Header("X-Env", "prod").Limit("/api", 100)
+	cfg, err := b.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := read(cfg)
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 500 {
+				if got := read(cfg); !got.equal(want) {
+					t.Errorf("config changed under a reader: %+v, want %+v", got, want)
+					return
+				}
+			}
+		}()
+	}
+	built := make(chan *builder.ServerConfig, 1)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(built)
+		for i := range 500 {
+			b.Port(9000+i).Timeout(time.Duration(i+1)*time.Second).
+				Host(fmt.Sprint("h", i, ".example")).
+				Header("X-Env", fmt.Sprint("v", i)).
+				Limit("/api", i+1).Limit(fmt.Sprint("/r", i), 1)
+			if i%50 == 0 {
+				next, err := b.Build()
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				// each config built is a snapshot in turn: hand one
+				// to a reader while the builder keeps going
+				select {
+				case built <- next:
+				default:
+				}
+			}
+		}
+	}()
+	for next := range built {
+		snap := read(next)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 100 {
+				if got := read(next); !got.equal(snap) {
+					t.Errorf("later config changed under a reader")
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if got := read(cfg); !got.equal(want) {
+		t.Errorf("after the builder was reused: %+v, want %+v", got, want)
+	}
+}
+
+// TestFreezingAliasing changes the builder after Build in every way that
+// could reach a shallow copy: appending into spare capacity, appending
+// to a header's values, overwriting a map key.
+func TestFreezingAliasing(t *testing.T) {
+	b := builder.NewFreezingBuilder()
+	for _, h := range []string{"a", "b", "c"} {
+		// three appends leave spare capacity for the next to write into
+		b.Host(h)
+	}
+	b.Header("X-Env", "prod").Header("X-Env", "eu").Limit("/api", 100)
+	cfg, err := b.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.Host("d").Header("X-Env", "staging").Limit("/api", 1)
+	other, _ := b.Build()
+	other.Header("X-Env")[0] = "mutated by a caller"
+
+	got := read(cfg)
+	if !slices.Equal(got.hosts, []string{"a", "b", "c"}) || !slices.Equal(got.env, []string{"prod", "eu"}) || got.limit != 100 {
+		t.Errorf("config after reusing the builder = %+v", got)
+	}
+	if env := other.Header("X-Env"); !slices.Equal(env, []string{"prod", "eu", "staging"}) {
+		t.Errorf("Header returned the config's own slice: %v", env)
+	}
+}
+
+// TestSharingAliasing shows what TestFreezingAliasing guards against:
+// the sharing builder's config sees every header set after Build.
+func TestSharingAliasing(t *testing.T) {
+	b := new(builder.SharingBuilder).Host("a").Header("X-Env", "prod")
+	cfg := b.Build()
+	b.Header("X-Env", "staging").Header("X-Region", "eu")
+	if got := cfg.Header("X-Env"); !slices.Equal(got, []string{"prod", "staging"}) {
+		t.Errorf("sharing config header = %v, want the builder's later values", got)
+	}
+	if got := cfg.Header("X-Region"); got == nil {
+		t.Error("sharing config does not see a header added after Build")
+	}
+}
+
+func TestFreezingDefaults(t *testing.T) {
+	cfg, err := builder.NewFreezingBuilder().Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := read(cfg)
+	if got.port != 8080 || got.timeout != 30*time.Second || got.hosts != nil || got.env != nil || got.limited {
+		t.Errorf("defaults = %+v", got)
+	}
+}
@@ -0,0 +1,208 @@
+// Package calloptions shows the two-level options of gRPC-style clients:
+// constructor options fix defaults for every call, and call options adjust
+// a single invocation. Precedence falls out of application order: package
+// defaults, then WithDefaultCallOptions, then the options passed to Do.
+//
+// Call options are applied on every call, so the settings they configure
+// are pooled. A pooled value is configured once per call it serves, and
+// funcopts.Guard checks that it was put back to its defaults between two:
+// applying the client's defaults to settings that still hold them fails
+// with funcopts.ErrReused instead of carrying one call's settings into
+// the next.
+package calloptions
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"patterns/construct"
+	"patterns/funcopts"
+)
+
+type callSettings struct {
+	funcopts.Guard
+	timeout  time.Duration
+	header   http.Header
+	attempts int
+}
+
+var settingsPool = sync.Pool{New: func() any { return &callSettings{header: http.Header{}} }}
+
+// reset restores the package defaults, and lets the guard accept the
+// client's default options again.
+func (s *callSettings) reset() {
+	s.Guard.Reset()
+	s.timeout = 10 * time.Second
+	clear(s.header)
+	s.attempts = 1
+}
+
+// CallOption configures one call.
+type CallOption = funcopts.Option[callSettings]
+
+func WithCallTimeout(d time.Duration) CallOption {
+	return func(settings *callSettings) error {
+		if d <= 0 {
+			return errors.New("call timeout must be positive")
+		}
+
+		settings.timeout = d
+		return nil
+	}
+}
+
+// WithHeader sets a request header, replacing a default with the same key.
+func WithHeader(key, value string) CallOption {
+	return func(settings *callSettings) error {
+		if key == "" {
+			return errors.New("header key cannot be empty")
+		}
+
+		settings.header.Set(key, value)
+		return nil
+	}
+}
+
+func WithAttempts(n int) CallOption {
+	return func(settings *callSettings) error {
+		if n < 1 {
+			return errors.New("attempts must be at least 1")
+		}
+
+		settings.attempts = n
+		return nil
+	}
+}
+
+type options struct {
+	httpClient  *http.Client
+	callOptions []CallOption
+}
+
+// Option configures the client.
+type Option = funcopts.Option[options]
+
+func WithHTTPClient(c *http.Client) Option {
+	return func(options *options) error {
+		if c == nil {
+			return errors.New("http client cannot be nil")
+		}
+
+		options.httpClient = c
+		return nil
+	}
+}
+
+// WithDefaultCallOptions applies opts to every call before the call's own
+// options.
+func WithDefaultCallOptions(opts ...CallOption) Option {
+	return func(options *options) error {
+		// validate now, so a bad default fails at construction
+		var probe callSettings
+		probe.header = http.Header{}
+		if err := funcopts.Apply(&probe, opts...); err != nil {
+			return err
+		}
+
+		options.callOptions = append(options.callOptions, opts...)
+		return nil
+	}
+}
+
+type Client struct {
+	http     *http.Client
+	defaults []CallOption
+}
+
+func (o *options) SetDefaults() {
+	o.httpClient = http.DefaultClient
+}
+
+func NewClient(opts ...Option) (*Client, error) {
+	options, err := construct.New(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Client{http: options.httpClient, defaults: options.callOptions}, nil
+}
+
+// settings resolves the effective settings for one call on pooled
+// settings; the caller puts them back once done with them.
+func (c *Client) settings(opts []CallOption) (*callSettings, error) {
+	s := settingsPool.Get().(*callSettings)
+	s.reset()
+	if err := funcopts.Apply(s, c.defaults...); err != nil {
+		settingsPool.Put(s)
+		return nil, err
+	}
+	if err := funcopts.Apply(s, opts...); err != nil {
+		settingsPool.Put(s)
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Do sends req with the effective call settings. Requests with a body are
+// only retried if req.GetBody is set.
+func (c *Client) Do(ctx context.Context, req *http.Request, opts ...CallOption) (*http.Response, error) {
+	s, err := c.settings(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	req = req.Clone(ctx)
+	for k, vs := range s.header {
+		// the pooled header is cleared for the next call
+		req.Header[k] = slices.Clone(vs)
+	}
+	attempts := s.attempts
+	settingsPool.Put(s)
+
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		// the body cannot be replayed
+		attempts = 1
+	}
+
+	var resp *http.Response
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if attempt > 1 && req.GetBody != nil {
+			if req.Body, err = req.GetBody(); err != nil {
+				break
+			}
+		}
+		resp, err = c.http.Do(req)
+		if err == nil && resp.StatusCode < 500 {
+			break
+		}
+		if err == nil && attempt < attempts {
+			resp.Body.Close()
+		}
+	}
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// cancelOnClose keeps the call's context alive until the body is closed.
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
@@ -0,0 +1,226 @@
+package calloptions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"patterns/funcopts"
+)
+
+func TestSettingsNotReset(t *testing.T) {
+	c, err := NewClient(WithDefaultCallOptions(WithAttempts(3)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := c.settings(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := funcopts.Apply(s, c.defaults...); !errors.Is(err, funcopts.ErrReused) {
+		t.Fatalf("defaults applied twice without reset = %v, want ErrReused", err)
+	}
+	s.reset()
+	if err := funcopts.Apply(s, c.defaults...); err != nil {
+		t.Fatalf("defaults applied after reset = %v", err)
+	}
+}
+
+// TestPooledSettings makes concurrent calls that each set their own
+// header; run with -race. A call must never see another call's header or
+// attempts through the pool.
+func TestPooledSettings(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Values("X-Call"); len(got) != 1 || got[0] != r.URL.Query().Get("call") {
+			http.Error(w, fmt.Sprintf("X-Call = %q", got), http.StatusBadRequest)
+			return
+		}
+		if got, want := r.Header.Get("X-Default"), "yes"; got != want {
+			http.Error(w, fmt.Sprintf("X-Default = %q", got), http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	c, err := NewClient(WithHTTPClient(srv.Client()), WithDefaultCallOptions(WithHeader("X-Default", "yes")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	for i := range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range 10 {
+				call := fmt.Sprint(i, "-", j)
+				req, err := http.NewRequest(http.MethodGet, srv.URL+"?call="+call, nil)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				resp, err := c.Do(context.Background(), req, WithHeader("X-Call", call))
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				resp.Body.Close()
+				if resp.StatusCode != http.StatusNoContent {
+					t.Errorf("call %s: status %d", call, resp.StatusCode)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestCallOptionError(t *testing.T) {
+	c, err := NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, err := http.NewRequest(http.MethodGet, "http://example.invalid", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Do(context.Background(), req, WithAttempts(0)); err == nil {
+		t.Fatal("Do with WithAttempts(0) succeeded")
+	}
+	// the failed call's settings went back to the pool reset on next use
+	s, err := c.settings(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer settingsPool.Put(s)
+	if s.attempts != 1 || len(s.header) != 0 {
+		t.Errorf("settings after a failed call: attempts %d, header %v; want package defaults", s.attempts, s.header)
+	}
+}
+
+// TestPrecedence checks that call options override the client's default
+// call options, which override the package defaults.
+func TestPrecedence(t *testing.T) {
+	for _, c := range []struct {
+		name     string
+		defaults []CallOption
+		call     []CallOption
+		timeout  time.Duration
+		attempts int
+		header   http.Header
+	}{
+		{"package defaults", nil, nil, 10 * time.Second, 1, http.Header{}},
+		{"client defaults", []CallOption{WithCallTimeout(time.Second), WithAttempts(3), WithHeader("X-A", "client")}, nil,
+			time.Second, 3, http.Header{"X-A": {"client"}}},
+		{"call options", nil, []CallOption{WithCallTimeout(time.Millisecond), WithAttempts(2), WithHeader("X-A", "call")},
+			time.Millisecond, 2, http.Header{"X-A": {"call"}}},
+		{"call over client", []CallOption{WithCallTimeout(time.Second), WithAttempts(3), WithHeader("X-A", "client"), WithHeader("X-B", "client")},
+			[]CallOption{WithCallTimeout(time.Minute), WithHeader("X-A", "call")},
+			time.Minute, 3, http.Header{"X-A": {"call"}, "X-B": {"client"}}},
+		// within one level, the last option wins
+		{"last wins", []CallOption{WithAttempts(2), WithAttempts(4)}, []CallOption{WithCallTimeout(time.Second), WithCallTimeout(2 * time.Second)},
+			2 * time.Second, 4, http.Header{}},
+	} {
+		client, err := NewClient(WithDefaultCallOptions(c.defaults...))
+		if err != nil {
+			t.Fatal(err)
+		}
+		s, err := client.settings(c.call)
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if s.timeout != c.timeout || s.attempts != c.attempts || !reflect.DeepEqual(s.header, c.header) {
+			t.Errorf("%s: timeout %v, attempts %d, header %v; want %v, %d, %v",
+				c.name, s.timeout, s.attempts, s.header, c.timeout, c.attempts, c.header)
+		}
+		settingsPool.Put(s)
+	}
+}
+
+func TestDefaultCallOptionError(t *testing.T) {
+	if _, err := NewClient(WithDefaultCallOptions(WithCallTimeout(0))); err == nil {
+		t.Error("NewClient with a bad default call option succeeded")
+	}
+}
+
+// TestPrecedenceOverHTTP checks what the server sees: headers set on the
+// request give way to the defaults, which give way to the call's, and the
+// call's attempts and timeout apply to that call only.
+func TestPrecedenceOverHTTP(t *testing.T) {
+	var mu sync.Mutex
+	failures := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		fail := failures > 0
+		if fail {
+			failures--
+		}
+		mu.Unlock()
+		switch {
+		case r.URL.Path == "/slow":
+			select {
+			case <-r.Context().Done():
+			case <-time.After(5 * time.Second):
+			}
+		case fail:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		fmt.Fprintf(w, "%s %s %s", r.Header.Get("X-Req"), r.Header.Get("X-Client"), r.Header.Get("X-Call"))
+	}))
+	defer srv.Close()
+
+	c, err := NewClient(WithHTTPClient(srv.Client()), WithDefaultCallOptions(
+		WithHeader("X-Client", "client"), WithHeader("X-Call", "client"), WithAttempts(2)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	call := func(path string, fails int, opts ...CallOption) (int, string, error) {
+		mu.Lock()
+		failures = fails
+		mu.Unlock()
+		req, err := http.NewRequest(http.MethodGet, srv.URL+path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("X-Req", "req")
+		req.Header.Set("X-Client", "req")
+		resp, err := c.Do(context.Background(), req, opts...)
+		if err != nil {
+			return 0, "", err
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body), err
+	}
+
+	for _, c := range []struct {
+		name   string
+		fails  int
+		opts   []CallOption
+		status int
+		body   string
+	}{
+		{"defaults", 0, nil, 200, "req client client"},
+		{"call header", 0, []CallOption{WithHeader("X-Call", "call")}, 200, "req client call"},
+		// the client's two attempts absorb one failure, not two
+		{"client attempts", 1, nil, 200, "req client client"},
+		{"client attempts exhausted", 2, nil, 503, "req client client"},
+		{"call attempts", 2, []CallOption{WithAttempts(3)}, 200, "req client client"},
+		{"call attempts not kept", 2, nil, 503, "req client client"},
+	} {
+		status, body, err := call("/", c.fails, c.opts...)
+		if err != nil || status != c.status || body != c.body {
+			t.Errorf("%s: %d %q, %v; want %d %q", c.name, status, body, err, c.status, c.body)
+		}
+	}
+
+	if _, _, err := call("/slow", 0, WithCallTimeout(20*time.Millisecond)); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("slow call with a call timeout: %v, want the deadline", err)
+	}
+}
@@ -0,0 +1,308 @@
+// Package clientexample applies the functional options pattern to an HTTP
+// client constructor, showing the pattern is not specific to servers:
+// every resilience knob is an option layered over the transport.
+package clientexample
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"patterns/clock"
+	"patterns/construct"
+	"patterns/funcopts"
+	"patterns/resilience/loadbalance"
+	"patterns/resilience/retry"
+)
+
+type options struct {
+	baseURL   *url.URL
+	timeout   time.Duration
+	transport http.RoundTripper
+	attempts  int
+	backoff   time.Duration
+	clock     clock.Clock
+	picker    loadbalance.Picker
+	backends  []*loadbalance.Backend
+}
+
+type Option = funcopts.Option[options]
+
+func WithBaseURL(raw string) Option {
+	return func(options *options) error {
+		u, err := url.Parse(raw)
+		if err != nil {
+			return fmt.Errorf("parse base url: %w", err)
+		}
+		if u.Scheme == "" || u.Host == "" {
+			return errors.New("base url must be absolute")
+		}
+
+		options.baseURL = u
+		return nil
+	}
+}
+
+// WithTimeout bounds a whole call, retries included.
+func WithTimeout(d time.Duration) Option {
+	return func(options *options) error {
+		if d <= 0 {
+			return errors.New("timeout must be positive")
+		}
+
+		options.timeout = d
+		return nil
+	}
+}
+
+func WithTransport(rt http.RoundTripper) Option {
+	return func(options *options) error {
+		if rt == nil {
+			return errors.New("transport cannot be nil")
+		}
+
+		options.transport = rt
+		return nil
+	}
+}
+
+// WithRetry retries idempotent requests up to attempts times in total,
+// doubling the wait from backoff after each failure.
+func WithRetry(attempts int, backoff time.Duration) Option {
+	return func(options *options) error {
+		if attempts < 1 {
+			return errors.New("attempts must be at least 1")
+		}
+		if backoff < 0 {
+			return errors.New("backoff cannot be negative")
+		}
+
+		options.attempts = attempts
+		options.backoff = backoff
+		return nil
+	}
+}
+
+// WithClock sets the clock retry waits run on. The overall WithTimeout is
+// enforced by net/http and stays on real time.
+func WithClock(c clock.Clock) Option {
+	return func(options *options) error {
+		if c == nil {
+			return errors.New("clock cannot be nil")
+		}
+
+		options.clock = c
+		return nil
+	}
+}
+
+// WithBackends spreads requests over backends with p, replacing the host
+// of the base URL per attempt, so a retry can land on another backend.
+// Load-aware pickers see a request as active until its body is closed.
+func WithBackends(p loadbalance.Picker, backends ...*loadbalance.Backend) Option {
+	return func(options *options) error {
+		if p == nil {
+			return errors.New("picker cannot be nil")
+		}
+		if len(backends) == 0 {
+			return errors.New("at least one backend is required")
+		}
+
+		options.picker = p
+		options.backends = backends
+		return nil
+	}
+}
+
+// Client is an HTTP client bound to a base URL.
+type Client struct {
+	base *url.URL
+	http *http.Client
+}
+
+func (o *options) SetDefaults() {
+	o.timeout = 30 * time.Second
+	o.transport = http.DefaultTransport
+	o.attempts = 1
+	o.clock = clock.Real
+}
+
+func (o *options) Validate() error {
+	if o.baseURL == nil {
+		return errors.New("base url is required")
+	}
+	return nil
+}
+
+func NewClient(opts ...Option) (*Client, error) {
+	options, err := construct.New(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	rt := options.transport
+	if options.picker != nil {
+		rt = &balanceTransport{next: rt, picker: options.picker, backends: options.backends}
+	}
+	if options.attempts > 1 {
+		r, err := retry.New(
+			retry.WithAttempts(options.attempts),
+			retry.WithBackoff(retry.Exponential(options.backoff, 0)),
+			retry.WithJitter(retry.None),
+			retry.WithClock(options.clock),
+			retry.WithRetryIf(func(err error) bool { return transient(nil, err) }),
+		)
+		if err != nil {
+			return nil, err
+		}
+		rt = &retryTransport{next: rt, retrier: r}
+	}
+
+	return &Client{
+		base: options.baseURL,
+		http: &http.Client{Transport: rt, Timeout: options.timeout},
+	}, nil
+}
+
+// Get fetches path relative to the base URL and returns the body of a 2xx
+// response.
+func (c *Client) Get(ctx context.Context, path string) ([]byte, error) {
+	u := c.base.JoinPath(strings.TrimPrefix(path, "/"))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		return nil, fmt.Errorf("GET %s: %s", u, resp.Status)
+	}
+	return body, nil
+}
+
+// retryTransport is a RoundTripper decorator retrying transient failures.
+type retryTransport struct {
+	next    http.RoundTripper
+	retrier *retry.Retrier
+}
+
+// errTransientStatus retries a response worth retrying, like a 503.
+var errTransientStatus = errors.New("transient status")
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !idempotent(req) {
+		return t.next.RoundTrip(req)
+	}
+
+	var resp *http.Response
+	attempt := 0
+	err := t.retrier.Do(req.Context(), func(ctx context.Context) error {
+		attempt++
+		if resp != nil {
+			// drain so the connection can be reused
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+			resp = nil
+		}
+		// a RoundTripper must not modify the request it was given, so
+		// each attempt sends a copy, with a fresh body after the first
+		out := req.Clone(ctx)
+		if attempt > 1 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return retry.Permanent(err)
+			}
+			out.Body = body
+		}
+
+		var err error
+		if resp, err = t.next.RoundTrip(out); err != nil {
+			return err
+		}
+		if transient(resp, nil) {
+			return errTransientStatus
+		}
+		return nil
+	})
+	var exhausted *retry.ExhaustedError
+	if err == nil || errors.As(err, &exhausted) && resp != nil {
+		// out of attempts on a transient status: the caller gets the
+		// last response, as without retries
+		return resp, nil
+	}
+	if resp != nil {
+		resp.Body.Close()
+	}
+	return nil, err
+}
+
+// balanceTransport is a RoundTripper decorator sending each request to a
+// backend chosen by picker.
+type balanceTransport struct {
+	next     http.RoundTripper
+	picker   loadbalance.Picker
+	backends []*loadbalance.Backend
+}
+
+func (t *balanceTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	b, err := t.picker.Pick(t.backends)
+	if err != nil {
+		return nil, err
+	}
+	// a RoundTripper must not modify the request it was given
+	out := req.Clone(req.Context())
+	out.URL.Host = b.Addr
+	out.Host = ""
+
+	done := b.Start()
+	resp, err := t.next.RoundTrip(out)
+	if err != nil {
+		done()
+		return nil, err
+	}
+	resp.Body = &doneBody{ReadCloser: resp.Body, done: done}
+	return resp, nil
+}
+
+// doneBody ends the backend's active request when the body is first
+// closed; closing it again does not count the request twice.
+type doneBody struct {
+	io.ReadCloser
+	once sync.Once
+	done func()
+}
+
+func (b *doneBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.done)
+	return err
+}
+
+func idempotent(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+	}
+	return false
+}
+
+func transient(resp *http.Response, err error) bool {
+	if err != nil {
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+}
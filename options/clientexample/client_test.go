@@ -0,0 +1,58 @@
+package clientexample
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"patterns/resilience/retry"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+func TestRetryKeepsTheCallersRequest(t *testing.T) {
+	var bodies []string
+	next := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		b, _ := io.ReadAll(req.Body)
+		bodies = append(bodies, string(b))
+		status := http.StatusServiceUnavailable
+		if len(bodies) == 3 {
+			status = http.StatusOK
+		}
+		return &http.Response{StatusCode: status, Body: io.NopCloser(strings.NewReader(""))}, nil
+	})
+	r, err := retry.New(retry.WithAttempts(3), retry.WithBackoff(retry.Constant(0)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, err := http.NewRequest(http.MethodPut, "http://example.com/x", bytes.NewReader([]byte("payload")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := req.Body
+
+	resp, err := (&retryTransport{next: next, retrier: r}).RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("RoundTrip = %v, %v; want 200", resp, err)
+	}
+	if want := []string{"payload", "payload", "payload"}; strings.Join(bodies, ",") != strings.Join(want, ",") {
+		t.Errorf("attempts sent %q, want %q", bodies, want)
+	}
+	if req.Body != body {
+		t.Error("RoundTrip replaced the caller's req.Body")
+	}
+}
+
+func TestDoneBodyEndsTheRequestOnce(t *testing.T) {
+	n := 0
+	b := &doneBody{ReadCloser: io.NopCloser(strings.NewReader("")), done: func() { n++ }}
+	b.Close()
+	b.Close()
+	if n != 1 {
+		t.Fatalf("done called %d times, want 1", n)
+	}
+}
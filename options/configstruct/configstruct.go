@@ -0,0 +1,45 @@
+// Package configstruct is the config struct variant of the options
+// comparison: settings travel in a struct whose fields can be left out.
+//
+//	s, l, err := configstruct.NewServer("localhost", &configstruct.Config{Port: field.Of(8080)})
+//	if err != nil {
+//		log.Println(err)
+//	}
+//	if l != nil {
+//		s.Serve(l) // port 0 was resolved to a bound listener
+//	} else {
+//		s.ListenAndServe()
+//	}
+package configstruct
+
+import (
+	"net"
+	"net/http"
+
+	"patterns/options/portspec"
+	"patterns/types/field"
+)
+
+// config struct pattern
+// Level: Average
+type Config struct {
+	// Port is unset or null for portspec.DefaultPort; field.Of(0) picks a
+	// free port.
+	Port field.Field[int]
+}
+
+// NewServer resolves cfg by the port spec; a nil cfg or unset Port means
+// portspec.DefaultPort. s.Addr carries the resolved port, and l is
+// non-nil only for port 0, bound to it.
+func NewServer(addr string, cfg *Config) (s *http.Server, l net.Listener, err error) {
+	var port *int
+	if cfg != nil {
+		port = cfg.Port.Ptr()
+	}
+	hostport, l, err := portspec.Resolve(addr, port)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &http.Server{Addr: hostport}, l, nil
+}
@@ -0,0 +1,42 @@
+package configstruct_test
+
+import (
+	"fmt"
+
+	"patterns/options/configstruct"
+	"patterns/types/field"
+)
+
+func ExampleNewServer() {
+	for _, cfg := range []*configstruct.Config{
+		nil,
+		{},
+		{Port: field.Null[int]()},
+		{Port: field.Of(9090)},
+		{Port: field.Of(-1)},
+	} {
+		s, _, err := configstruct.NewServer("localhost", cfg)
+		if err != nil {
+			fmt.Println(err)
+			continue
+		}
+		fmt.Println(s.Addr)
+	}
+	// Output:
+	// localhost:8080
+	// localhost:8080
+	// localhost:8080
+	// localhost:9090
+	// port cannot be negative
+}
+
+func ExampleNewServer_randomPort() {
+	s, l, err := configstruct.NewServer("127.0.0.1", &configstruct.Config{Port: field.Of(0)})
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer l.Close()
+	fmt.Println(s.Addr == l.Addr().String(), s.Addr != "127.0.0.1:0")
+	// Output: true true
+}
@@ -0,0 +1,17 @@
+// Package options compares seven ways to configure a server constructor,
+// one per sub-package, all implementing the same port spec:
+//
+//   - procedural: positional arguments (Level: Poor)
+//   - configstruct: a config struct of field.Field settings (Level: Average)
+//   - builder: a method-chained builder (Level: Good)
+//   - functional: functional options (Level: Good)
+//   - staged: a typestate builder checked at compile time (Level: Good)
+//   - ifaceopts: options as ordered, comparable interface values (Level: Good)
+//   - generated: functional options generated by cmd/optgen (Level: Good)
+//
+// spec:
+// If port is not set, use default port
+// if port is zero, use random port
+// If port is negative, print error
+// If port is positive, use that port
+package options
@@ -0,0 +1,95 @@
+// Command server runs the functional options variant.
+//
+// usage:
+//
+//	go run patterns/options/functional/cmd/server [-print-config json|yaml] [-check] [-cert file -key file]
+//
+// -check validates the options without starting the server and lists
+// every problem, not only the first. Every failure, from an unknown
+// -print-config format to a server that stops with an error, exits 1.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+
+	"patterns/cli"
+	"patterns/configdump"
+	"patterns/options/functional"
+)
+
+func main() {
+	cli.Main(run)
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	var printConfig, certFile, keyFile string
+	var check bool
+	cmd := &cli.Command{
+		Name:  "server",
+		Short: "run the functional options variant",
+		Flags: func(fs *flag.FlagSet) {
+			fs.StringVar(&printConfig, "print-config", "", "print the resolved config (json or yaml) and exit")
+			fs.BoolVar(&check, "check", false, "validate the options, report every problem and exit")
+			fs.StringVar(&certFile, "cert", "", "TLS certificate file (with -key)")
+			fs.StringVar(&keyFile, "key", "", "TLS key file (with -cert)")
+		},
+		Run: func(env cli.Env, args []string) error {
+			if len(args) > 0 {
+				return cli.ErrUsage
+			}
+			port := 8080
+			sl := slog.New(slog.NewTextHandler(env.Stderr, nil))
+			opts := []functional.Option{
+				functional.WithDefaults(),
+				functional.WithPort(port),
+				functional.WithSlogLogger(sl),
+			}
+			if certFile != "" || keyFile != "" {
+				opts = append(opts, functional.WithTLSCertFiles(certFile, keyFile))
+			}
+			if check {
+				if err := functional.ValidateOptions(opts...); err != nil {
+					return err
+				}
+				fmt.Fprintln(env.Stdout, "options ok")
+				return nil
+			}
+			if printConfig != "" {
+				format, err := configdump.ParseFormat(printConfig)
+				if err != nil {
+					return fmt.Errorf("print config: %w", err)
+				}
+				cfg, err := functional.ResolveConfig("localhost", opts...)
+				if err != nil {
+					return fmt.Errorf("print config: %w", err)
+				}
+				if err := cfg.Dump(env.Stdout, format); err != nil {
+					return fmt.Errorf("print config: %w", err)
+				}
+				return nil
+			}
+
+			// can write default options using like this:
+			// s, err := functional.NewServer("localhost")
+			s, err := functional.NewServer("localhost", opts...)
+			if err != nil {
+				return fmt.Errorf("create server: %w", err)
+			}
+
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+			defer stop()
+			// Run logs "server starting" with the address once it listens
+			if err := s.Run(ctx); err != nil {
+				return fmt.Errorf("run server: %w", err)
+			}
+			return nil
+		},
+	}
+	return cmd.Main(args, stdout, stderr)
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"testing"
+
+	"patterns/testing/golden"
+)
+
+// invoke runs the command with args and returns its stdout, stderr and
+// exit code as the content of a golden file.
+func invoke(args ...string) []byte {
+	var stdout, stderr bytes.Buffer
+	code := run(args, &stdout, &stderr)
+	var b bytes.Buffer
+	b.Write(stdout.Bytes())
+	fmt.Fprintf(&b, "--- stderr\n%s--- exit %d\n", stderr.Bytes(), code)
+	return b.Bytes()
+}
+
+func TestServer(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		args []string
+	}{
+		{"print-config-json", []string{"-print-config", "json"}},
+		{"print-config-yaml", []string{"-print-config", "yaml"}},
+		{"print-config-xml", []string{"-print-config", "xml"}},
+		{"print-config-bad-cert", []string{"-print-config", "json", "-cert", "/nonexist", "-key", "/nonexist"}},
+		{"check", []string{"-check"}},
+		{"check-bad-cert", []string{"-check", "-cert", "/nonexist"}},
+		{"bad-cert", []string{"-cert", "/nonexist", "-key", "/nonexist"}},
+		{"args", []string{"serve"}},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			golden.Assert(t, c.name, invoke(c.args...))
+		})
+	}
+}
+
+// TestRunFails holds the server's port, so that Run fails.
+func TestRunFails(t *testing.T) {
+	if l, err := net.Listen("tcp", "localhost:8080"); err == nil {
+		t.Cleanup(func() { l.Close() })
+	}
+	golden.Assert(t, "run-fails", invoke())
+}
@@ -0,0 +1,88 @@
+package functional_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"patterns/configdump"
+	"patterns/options/functional"
+)
+
+func ExampleNewServer() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s, err := functional.NewServer("127.0.0.1",
+		functional.WithPort(0),
+		functional.WithDefaults(),
+		functional.WithHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, "hello")
+		})),
+	)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	done := make(chan error)
+	go func() { done <- s.Run(ctx) }()
+	<-s.Ready()
+
+	resp, err := http.Get("http://" + s.Addr())
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	fmt.Println(resp.Status, string(body))
+
+	cancel()
+	fmt.Println(<-done)
+	// Output:
+	// 200 OK hello
+	// <nil>
+}
+
+func ExampleValidateOptions() {
+	err := functional.ValidateOptions(
+		functional.WithPort(-1),
+		functional.WithReadTimeout(-time.Second),
+		functional.WithLogger(nil),
+	)
+	fmt.Println(err)
+	// Output:
+	// port cannot be negative
+	// read timeout cannot be negative
+	// logger cannot be nil
+}
+
+func ExampleResolveConfig() {
+	cfg, err := functional.ResolveConfig("localhost",
+		functional.WithDefaults(),
+		functional.WithReadTimeout(20*time.Second), // wins over the preset
+		functional.WithPort(9090),
+	)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	cfg.Dump(os.Stdout, configdump.YAML)
+	// Output:
+	// addr: "localhost:9090"
+	// tls: false
+	// read_timeout: 20s
+	// write_timeout: 30s
+	// idle_timeout: 2m0s
+	// options:
+	//   - name: write-timeout
+	//     value: 30s
+	//   - name: idle-timeout
+	//     value: 2m0s
+	//   - name: read-timeout
+	//     value: 20s
+	//   - name: port
+	//     value: 9090
+}
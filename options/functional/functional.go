@@ -0,0 +1,492 @@
+// Package functional is the functional options variant of the options
+// comparison; cmd/server runs it.
+package functional
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"patterns/configdump"
+	"patterns/construct"
+	"patterns/funcopts"
+	"patterns/idioms/must"
+	"patterns/options/portspec"
+	"patterns/validate"
+	"patterns/web/draining"
+)
+
+// functional options pattern
+// Level: Good
+// pros: immediate validation eval, lightweight writing, readable, Encapsulation
+
+// Logger is the minimal logging surface the server needs; anything from
+// slog to a test recorder can sit behind it.
+type Logger interface {
+	Info(msg string, keysAndValues ...any)
+	Error(msg string, err error, keysAndValues ...any)
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Info(string, ...any)         {}
+func (nopLogger) Error(string, error, ...any) {}
+
+type slogLogger struct {
+	l *slog.Logger
+}
+
+// NewSlogLogger adapts a *slog.Logger to Logger.
+func NewSlogLogger(l *slog.Logger) Logger {
+	return slogLogger{l: l}
+}
+
+func (s slogLogger) Info(msg string, keysAndValues ...any) {
+	s.l.Info(msg, keysAndValues...)
+}
+
+func (s slogLogger) Error(msg string, err error, keysAndValues ...any) {
+	s.l.Error(msg, append(keysAndValues, "err", err)...)
+}
+
+type options struct {
+	port         *int
+	tlsConfig    *tls.Config
+	readTimeout  time.Duration
+	writeTimeout time.Duration
+	idleTimeout  time.Duration
+	listener     net.Listener
+	logger       Logger
+	handler      http.Handler
+	drainer      *draining.Drainer
+}
+
+// Option is a funcopts option, so applying, duplicate and conflict
+// handling, deprecation and introspection come from a shared helper.
+type Option = funcopts.Option[options]
+
+func WithPort(port int) Option {
+	return funcopts.Describe(funcopts.Info{Name: "port", Group: "listen", Value: port}, func(options *options) error {
+		if port < 0 {
+			return errors.New("port cannot be negative")
+		}
+
+		options.port = &port
+		return nil
+	})
+}
+
+// WithTLSCertFiles loads the key pair immediately, so a bad path fails in
+// NewServer rather than at ListenAndServe.
+func WithTLSCertFiles(certFile, keyFile string) Option {
+	return funcopts.Describe(funcopts.Info{Name: "tls", Group: "tls", Value: certFile + "," + keyFile}, func(options *options) error {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return fmt.Errorf("load tls key pair: %w", err)
+		}
+
+		options.tlsConfig = &tls.Config{Certificates: []tls.Certificate{cert}}
+		return nil
+	})
+}
+
+func WithTLSConfig(cfg *tls.Config) Option {
+	return funcopts.Describe(funcopts.Info{Name: "tls-config", Group: "tls", Value: "tls.Config", Redact: funcopts.RedactAll}, func(options *options) error {
+		if cfg == nil {
+			return errors.New("tls config cannot be nil")
+		}
+		if len(cfg.Certificates) == 0 && cfg.GetCertificate == nil {
+			return errors.New("tls config has no certificates")
+		}
+
+		options.tlsConfig = cfg.Clone()
+		return nil
+	})
+}
+
+// WithTimeouts sets the read, write and idle timeouts; zero means no timeout.
+func WithTimeouts(read, write, idle time.Duration) Option {
+	return funcopts.Describe(funcopts.Info{Name: "timeouts", Value: [3]time.Duration{read, write, idle}}, func(options *options) error {
+		if read < 0 || write < 0 || idle < 0 {
+			return errors.New("timeouts cannot be negative")
+		}
+
+		options.readTimeout = read
+		options.writeTimeout = write
+		options.idleTimeout = idle
+		return nil
+	})
+}
+
+// WithReadTimeout bounds reading a whole request, body included; zero
+// means no timeout.
+func WithReadTimeout(d time.Duration) Option {
+	return funcopts.Describe(funcopts.Info{Name: "read-timeout", Value: d}, func(options *options) error {
+		if d < 0 {
+			return errors.New("read timeout cannot be negative")
+		}
+
+		options.readTimeout = d
+		return nil
+	})
+}
+
+// WithWriteTimeout bounds writing a response, from the end of the request
+// headers; zero means no timeout.
+func WithWriteTimeout(d time.Duration) Option {
+	return funcopts.Describe(funcopts.Info{Name: "write-timeout", Value: d}, func(options *options) error {
+		if d < 0 {
+			return errors.New("write timeout cannot be negative")
+		}
+
+		options.writeTimeout = d
+		return nil
+	})
+}
+
+// WithIdleTimeout bounds how long a keep-alive connection waits for the
+// next request; zero means no timeout.
+func WithIdleTimeout(d time.Duration) Option {
+	return funcopts.Describe(funcopts.Info{Name: "idle-timeout", Value: d}, func(options *options) error {
+		if d < 0 {
+			return errors.New("idle timeout cannot be negative")
+		}
+
+		options.idleTimeout = d
+		return nil
+	})
+}
+
+// WithListener serves on an already bound listener, e.g. one on port 0
+// created by a test.
+func WithListener(l net.Listener) Option {
+	return funcopts.Describe(funcopts.Info{Name: "listener", Group: "listen", Value: listenerAddr(l)}, func(options *options) error {
+		if l == nil {
+			return errors.New("listener cannot be nil")
+		}
+
+		options.listener = l
+		return nil
+	})
+}
+
+func WithLogger(logger Logger) Option {
+	return funcopts.Describe(funcopts.Info{Name: "logger", Value: fmt.Sprintf("%T", logger)}, func(options *options) error {
+		if logger == nil {
+			return errors.New("logger cannot be nil")
+		}
+
+		options.logger = logger
+		return nil
+	})
+}
+
+// WithSlogLogger logs through l; it is WithLogger(NewSlogLogger(l)) for
+// the common case.
+func WithSlogLogger(l *slog.Logger) Option {
+	return funcopts.Describe(funcopts.Info{Name: "logger", Value: "*slog.Logger"}, func(options *options) error {
+		if l == nil {
+			return errors.New("logger cannot be nil")
+		}
+
+		options.logger = NewSlogLogger(l)
+		return nil
+	})
+}
+
+func WithHandler(h http.Handler) Option {
+	return funcopts.Describe(funcopts.Info{Name: "handler", Value: fmt.Sprintf("%T", h)}, func(options *options) error {
+		if h == nil {
+			return errors.New("handler cannot be nil")
+		}
+
+		options.handler = h
+		return nil
+	})
+}
+
+// WithDrainer attaches d to the server, so streaming handlers wrapped by
+// d.Track are signalled to finish, and waited for, when Run shuts down.
+func WithDrainer(d *draining.Drainer) Option {
+	return funcopts.Describe(funcopts.Info{Name: "drainer", Value: d != nil}, func(options *options) error {
+		if d == nil {
+			return errors.New("drainer cannot be nil")
+		}
+
+		options.drainer = d
+		return nil
+	})
+}
+
+func listenerAddr(l net.Listener) string {
+	if l == nil {
+		return ""
+	}
+	return l.Addr().String()
+}
+
+// shutdownTimeout bounds how long Run waits for in-flight requests once
+// its context is cancelled.
+const shutdownTimeout = 5 * time.Second
+
+// Server wraps http.Server with a context-driven lifecycle. Port 0 is
+// bound by NewServer, so Addr reports the real port as soon as NewServer
+// returns; Ready tells callers when Run has got as far as it will.
+type Server struct {
+	srv       *http.Server
+	listener  net.Listener
+	logger    Logger
+	drainer   *draining.Drainer
+	effective []funcopts.Applied
+
+	ready     chan struct{}
+	readyOnce sync.Once
+	mu        sync.Mutex
+	addr      string
+	started   bool
+}
+
+// EffectiveOptions reports the options the server was built with, one
+// entry per option in application order, with secrets redacted. Intended
+// for debug endpoints and support bundles.
+func (s *Server) EffectiveOptions() []funcopts.Applied {
+	return append([]funcopts.Applied(nil), s.effective...)
+}
+
+// Ready is closed once Run is serving, or has failed without serving,
+// so go s.Run(ctx); <-s.Ready() never blocks for good. Run's error tells
+// the two apart; it is returned only after Ready is closed.
+func (s *Server) Ready() <-chan struct{} { return s.ready }
+
+func (s *Server) setReady() { s.readyOnce.Do(func() { close(s.ready) }) }
+
+// Addr returns the listening address after Ready, and the configured
+// address before.
+func (s *Server) Addr() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.addr
+}
+
+// Run listens (or uses the injected listener), serves over TLS if a TLS
+// option was given, and shuts down gracefully when ctx is cancelled.
+// It returns nil after a clean shutdown. Run may only be called once.
+func (s *Server) Run(ctx context.Context) error {
+	s.mu.Lock()
+	if s.started {
+		s.mu.Unlock()
+		return errors.New("server already started")
+	}
+	s.started = true
+	s.mu.Unlock()
+	defer s.setReady()
+
+	l := s.listener
+	if l == nil {
+		var err error
+		l, err = net.Listen("tcp", s.Addr())
+		if err != nil {
+			return err
+		}
+	}
+	s.mu.Lock()
+	s.addr = l.Addr().String()
+	s.mu.Unlock()
+	s.logger.Info("server starting", "addr", s.Addr(), "tls", s.srv.TLSConfig != nil)
+	s.setReady()
+
+	errc := make(chan error, 1)
+	go func() {
+		if s.srv.TLSConfig != nil {
+			// certificates come from TLSConfig
+			errc <- s.srv.ServeTLS(l, "", "")
+			return
+		}
+		errc <- s.srv.Serve(l)
+	}()
+
+	select {
+	case err := <-errc:
+		s.logger.Error("server failed", err, "addr", s.Addr())
+		return err
+	case <-ctx.Done():
+	}
+
+	// logged here rather than in RegisterOnShutdown, whose funcs run in
+	// their own goroutine and may outlive Run
+	s.logger.Info("server shutting down", "addr", s.Addr())
+	shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), shutdownTimeout)
+	defer cancel()
+	shutdown := s.srv.Shutdown
+	if s.drainer != nil {
+		shutdown = func(ctx context.Context) error { return s.drainer.Shutdown(ctx, s.srv) }
+	}
+	if err := shutdown(shutdownCtx); err != nil {
+		s.logger.Error("server shutdown", err, "addr", s.Addr())
+		return err
+	}
+	<-errc // http.ErrServerClosed
+	s.logger.Info("server stopped", "addr", s.Addr())
+
+	return nil
+}
+
+func logRequests(logger Logger, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		next.ServeHTTP(w, r)
+		logger.Info("request", "method", r.Method, "path", r.URL.Path, "duration", time.Since(start))
+	})
+}
+
+// resolve applies opts and runs cross-field validation. It has no side
+// effects beyond the options themselves, so ValidateOptions can share it.
+// With collect, every failure is reported instead of the first.
+func resolve(opts []Option, collect bool) (options, []funcopts.Applied, error) {
+	var applied []funcopts.Applied
+	var warnings []funcopts.Warning
+	resolved, err := construct.NewWith(funcopts.ApplyConfig{
+		OnApply:       funcopts.Record(&applied),
+		OnWarning:     funcopts.Collect(&warnings),
+		CollectErrors: collect,
+	}, opts...)
+	if err != nil {
+		return options{}, nil, err
+	}
+	for _, w := range warnings {
+		resolved.logger.Info("deprecated option", "option", w.Option, "message", w.Message)
+	}
+
+	return *resolved, applied, nil
+}
+
+func (o *options) SetDefaults() {
+	o.logger = nopLogger{}
+	o.handler = http.DefaultServeMux
+}
+
+// Validate checks rules that involve more than one option. Every
+// violation is reported, each with the field it concerns.
+func (o *options) Validate() error {
+	var v validate.Validator
+	if o.port != nil {
+		validate.Field(&v, "port", *o.port, validate.Range(0, 65535))
+	}
+	// the write deadline starts when headers are read, so it would
+	// expire while a slow body is still being read
+	v.Check("writeTimeout", o.writeTimeout == 0 || o.writeTimeout >= o.readTimeout,
+		"shorter than read timeout")
+	return v.Err()
+}
+
+// ValidateOptions is a dry run of NewServer: it applies and validates opts
+// without opening sockets (port 0 is not resolved), so deploy tooling can
+// pre-flight a configuration. Unlike NewServer it does not stop at the
+// first invalid option: every failure is returned, joined with
+// errors.Join, so one run lists everything there is to fix:
+//
+//	port cannot be negative
+//	timeouts cannot be negative
+//	logger cannot be nil
+func ValidateOptions(opts ...Option) error {
+	_, _, err := resolve(opts, true)
+	return err
+}
+
+// Config is the resolved server configuration, as printed by
+// --print-config. Option values are already redacted by funcopts.
+type Config struct {
+	Addr         string             `json:"addr"`
+	TLS          bool               `json:"tls"`
+	ReadTimeout  time.Duration      `json:"read_timeout"`
+	WriteTimeout time.Duration      `json:"write_timeout"`
+	IdleTimeout  time.Duration      `json:"idle_timeout"`
+	Options      []funcopts.Applied `json:"options"`
+}
+
+// Dump writes the config as JSON or YAML.
+func (c Config) Dump(w io.Writer, format configdump.Format) error {
+	return configdump.Dump(w, c, format)
+}
+
+// ResolveConfig reports what NewServer would be configured with, without
+// opening sockets.
+func ResolveConfig(addr string, opts ...Option) (Config, error) {
+	options, applied, err := resolve(opts, false)
+	if err != nil {
+		return Config{}, err
+	}
+
+	cfg := Config{
+		TLS:          options.tlsConfig != nil,
+		ReadTimeout:  options.readTimeout,
+		WriteTimeout: options.writeTimeout,
+		IdleTimeout:  options.idleTimeout,
+		Options:      funcopts.Effective(applied),
+	}
+	switch {
+	case options.listener != nil:
+		cfg.Addr = options.listener.Addr().String()
+	case options.port != nil:
+		// 0 stays 0: resolving it would bind a socket
+		cfg.Addr = net.JoinHostPort(addr, strconv.Itoa(*options.port))
+	default:
+		cfg.Addr = net.JoinHostPort(addr, strconv.Itoa(portspec.DefaultPort))
+	}
+
+	return cfg, nil
+}
+
+func NewServer(addr string, opts ...Option) (*Server, error) {
+	options, applied, err := resolve(opts, false)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		srv: &http.Server{
+			Handler:      logRequests(options.logger, options.handler),
+			TLSConfig:    options.tlsConfig,
+			ReadTimeout:  options.readTimeout,
+			WriteTimeout: options.writeTimeout,
+			IdleTimeout:  options.idleTimeout,
+		},
+		listener:  options.listener,
+		logger:    options.logger,
+		drainer:   options.drainer,
+		effective: funcopts.Effective(applied),
+		ready:     make(chan struct{}),
+	}
+	if options.drainer != nil {
+		options.drainer.Attach(s.srv)
+	}
+	if options.listener != nil {
+		s.addr = options.listener.Addr().String()
+		return s, nil
+	}
+
+	// port spec: unset is portspec.DefaultPort; 0 binds a random port
+	// and Run serves on that listener
+	hostport, l, err := portspec.Resolve(addr, options.port)
+	if err != nil {
+		return nil, err
+	}
+	s.listener = l
+	s.addr = hostport
+
+	return s, nil
+}
+
+// MustNewServer is NewServer for options fixed at compile time (tests,
+// examples, main); it panics with a *must.PanicError wrapping the error.
+func MustNewServer(addr string, opts ...Option) *Server {
+	return must.Must(NewServer(addr, opts...))
+}
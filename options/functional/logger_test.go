@@ -0,0 +1,113 @@
+package functional_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"patterns/netutil/freeport/freeporttest"
+	"patterns/options/functional"
+)
+
+// recorder is a Logger keeping each event as "level msg key=value ...",
+// leaving out values that change between runs.
+type recorder struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (r *recorder) Info(msg string, keysAndValues ...any) { r.add("info", msg, nil, keysAndValues) }
+
+func (r *recorder) Error(msg string, err error, keysAndValues ...any) {
+	r.add("error", msg, err, keysAndValues)
+}
+
+func (r *recorder) add(level, msg string, err error, kvs []any) {
+	e := level + " " + msg
+	for i := 0; i+1 < len(kvs); i += 2 {
+		switch kvs[i] {
+		case "addr", "duration":
+			e += fmt.Sprintf(" %v=…", kvs[i])
+		default:
+			e += fmt.Sprintf(" %v=%v", kvs[i], kvs[i+1])
+		}
+	}
+	if err != nil {
+		e += " err=" + err.Error()
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, e)
+}
+
+func (r *recorder) Events() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.events)
+}
+
+func TestLoggerLifecycle(t *testing.T) {
+	var log recorder
+	s := functional.MustNewServer("127.0.0.1",
+		functional.WithPort(0), functional.WithHandler(hello), functional.WithLogger(&log))
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- s.Run(ctx) }()
+	waitReady(t, s)
+
+	get(t, http.DefaultClient, "http://"+s.Addr()+"/a")
+	get(t, http.DefaultClient, "http://"+s.Addr()+"/b")
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"info server starting addr=… tls=false",
+		"info request method=GET path=/a duration=…",
+		"info request method=GET path=/b duration=…",
+		"info server shutting down addr=…",
+		"info server stopped addr=…",
+	}
+	if got := log.Events(); !slices.Equal(got, want) {
+		t.Errorf("events:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+// TestLoggerServeFails hands Run a closed listener, so serving fails
+// after the start was logged.
+func TestLoggerServeFails(t *testing.T) {
+	var log recorder
+	l, _ := freeporttest.Listen(t)
+	s := functional.MustNewServer("127.0.0.1", functional.WithListener(l), functional.WithLogger(&log))
+	l.Close()
+	if err := s.Run(context.Background()); err == nil {
+		t.Fatal("Run on a closed listener succeeded")
+	}
+
+	got := log.Events()
+	if len(got) != 2 || got[0] != "info server starting addr=… tls=false" ||
+		!strings.HasPrefix(got[1], "error server failed addr=… err=") {
+		t.Errorf("events:\n%s", strings.Join(got, "\n"))
+	}
+}
+
+func TestLoggerDefault(t *testing.T) {
+	// without WithLogger nothing is logged, and nothing fails
+	s := functional.MustNewServer("127.0.0.1", functional.WithPort(0), functional.WithHandler(hello))
+	start(t, s)
+	if got := get(t, http.DefaultClient, "http://"+s.Addr()); got != "200 OK hello" {
+		t.Errorf("GET = %q", got)
+	}
+}
+
+func TestWithLoggerNil(t *testing.T) {
+	_, err := functional.NewServer("127.0.0.1", functional.WithPort(0), functional.WithLogger(nil))
+	if err == nil || err.Error() != "logger cannot be nil" {
+		t.Errorf("err = %v, want logger cannot be nil", err)
+	}
+}
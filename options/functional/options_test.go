@@ -0,0 +1,277 @@
+package functional_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"patterns/funcopts"
+	"patterns/netutil/freeport/freeporttest"
+	"patterns/options/functional"
+)
+
+func TestEffectiveOptions(t *testing.T) {
+	l, _ := freeporttest.Listen(t)
+	cfg := &tls.Config{GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) { return nil, nil }}
+	s, err := functional.NewServer("127.0.0.1",
+		functional.WithReadTimeout(time.Second),
+		functional.WithListener(l),
+		functional.WithTLSConfig(cfg),
+		functional.WithReadTimeout(2*time.Second),
+		functional.WithHandler(hello),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []funcopts.Applied{
+		{Name: "listener", Value: l.Addr().String()},
+		{Name: "tls-config", Value: funcopts.Redacted},
+		{Name: "read-timeout", Value: 2 * time.Second},
+		{Name: "handler", Value: "http.HandlerFunc"},
+	}
+	got := s.EffectiveOptions()
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("EffectiveOptions = %v, want %v", got, want)
+	}
+
+	// the result is a copy
+	got[0].Value = "changed"
+	if s.EffectiveOptions()[0] != want[0] {
+		t.Error("changing the result changed the server's record")
+	}
+}
+
+func openFiles(t *testing.T) int {
+	t.Helper()
+	fds, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		t.Skip("cannot count open files:", err)
+	}
+	return len(fds)
+}
+
+// TestValidateOptionsOpensNothing checks the dry run binds no socket,
+// not even for port 0, which NewServer binds.
+func TestValidateOptionsOpensNothing(t *testing.T) {
+	// bind once first, so the runtime's poller is already open
+	l, port := freeporttest.Listen(t)
+	before := openFiles(t)
+	for _, opts := range [][]functional.Option{
+		{functional.WithPort(0)},
+		{functional.WithPort(port)},
+		{functional.WithDefaults(), functional.WithPort(0), functional.WithHandler(hello)},
+	} {
+		if err := functional.ValidateOptions(opts...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if after := openFiles(t); after != before {
+		t.Errorf("open files went from %d to %d", before, after)
+	}
+
+	// an injected listener is left alone: still open, nothing accepted
+	if err := functional.ValidateOptions(functional.WithListener(l)); err != nil {
+		t.Fatal(err)
+	}
+	c, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	accepted, err := l.Accept()
+	if err != nil {
+		t.Fatalf("Accept after ValidateOptions: %v", err)
+	}
+	accepted.Close()
+}
+
+func TestTimeoutOptions(t *testing.T) {
+	for _, c := range []struct {
+		name              string
+		opts              []functional.Option
+		read, write, idle time.Duration
+	}{
+		{"none", nil, 0, 0, 0},
+		{"each", []functional.Option{
+			functional.WithReadTimeout(time.Second),
+			functional.WithWriteTimeout(2 * time.Second),
+			functional.WithIdleTimeout(3 * time.Second),
+		}, time.Second, 2 * time.Second, 3 * time.Second},
+		{"one overrides WithTimeouts", []functional.Option{
+			functional.WithTimeouts(time.Second, 2*time.Second, 3*time.Second),
+			functional.WithWriteTimeout(time.Minute),
+		}, time.Second, time.Minute, 3 * time.Second},
+		{"WithTimeouts overrides each", []functional.Option{
+			functional.WithIdleTimeout(time.Minute),
+			functional.WithTimeouts(time.Second, 0, 0),
+		}, time.Second, 0, 0},
+	} {
+		cfg, err := functional.ResolveConfig("localhost", c.opts...)
+		if err != nil {
+			t.Errorf("%s: %v", c.name, err)
+			continue
+		}
+		if cfg.ReadTimeout != c.read || cfg.WriteTimeout != c.write || cfg.IdleTimeout != c.idle {
+			t.Errorf("%s: timeouts = %v, %v, %v, want %v, %v, %v", c.name,
+				cfg.ReadTimeout, cfg.WriteTimeout, cfg.IdleTimeout, c.read, c.write, c.idle)
+		}
+	}
+}
+
+func TestTimeoutValidation(t *testing.T) {
+	for _, c := range []struct {
+		opt  functional.Option
+		want string
+	}{
+		{functional.WithReadTimeout(-1), "read timeout cannot be negative"},
+		{functional.WithWriteTimeout(-1), "write timeout cannot be negative"},
+		{functional.WithIdleTimeout(-1), "idle timeout cannot be negative"},
+		{functional.WithTimeouts(time.Second, -1, 0), "timeouts cannot be negative"},
+		{functional.WithTimeouts(time.Minute, time.Second, 0), "writeTimeout: shorter than read timeout"},
+		{functional.WithTimeouts(time.Minute, 0, 0), ""},
+		{functional.WithTimeouts(time.Minute, time.Minute, 0), ""},
+	} {
+		err := functional.ValidateOptions(c.opt)
+		if got := fmt.Sprint(err); (c.want == "" && err != nil) || (c.want != "" && got != c.want) {
+			t.Errorf("ValidateOptions = %v, want %q", err, c.want)
+		}
+	}
+	// the cross-field rule sees the result of every option
+	err := functional.ValidateOptions(functional.WithWriteTimeout(time.Second), functional.WithReadTimeout(time.Minute))
+	if err == nil || err.Error() != "writeTimeout: shorter than read timeout" {
+		t.Errorf("write then read = %v", err)
+	}
+}
+
+// TestReadTimeoutSlowHeaders sends half a request and stops; the read
+// timeout must close the connection.
+func TestReadTimeoutSlowHeaders(t *testing.T) {
+	s := functional.MustNewServer("127.0.0.1",
+		functional.WithPort(0), functional.WithReadTimeout(200*time.Millisecond), functional.WithHandler(hello))
+	start(t, s)
+
+	conn, err := net.Dial("tcp", s.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	io.WriteString(conn, "GET / HTTP/1.1\r\nHost: x\r\n")
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	begin := time.Now()
+	_, err = conn.Read(make([]byte, 1))
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Fatal("the server did not close the connection")
+	}
+	if d := time.Since(begin); d > 2*time.Second {
+		t.Errorf("connection closed after %v, want about the 200ms read timeout", d)
+	}
+}
+
+// TestWriteTimeout lets a handler outlive the write timeout; its
+// response never reaches the client.
+func TestWriteTimeout(t *testing.T) {
+	slow := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(300 * time.Millisecond)
+		io.WriteString(w, "late")
+	})
+	s := functional.MustNewServer("127.0.0.1",
+		functional.WithPort(0), functional.WithWriteTimeout(100*time.Millisecond), functional.WithHandler(slow))
+	start(t, s)
+
+	resp, err := http.Get("http://" + s.Addr())
+	if err == nil {
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		t.Errorf("GET = %s %q, want the connection closed", resp.Status, body)
+	}
+}
+
+func TestWithSlogLogger(t *testing.T) {
+	var buf bytes.Buffer
+	var mu sync.Mutex
+	h := slog.NewTextHandler(lockedWriter{&mu, &buf}, &slog.HandlerOptions{
+		ReplaceAttr: func(_ []string, a slog.Attr) slog.Attr {
+			switch a.Key {
+			case slog.TimeKey, "addr", "duration":
+				return slog.Attr{}
+			}
+			return a
+		},
+	})
+	s := functional.MustNewServer("127.0.0.1",
+		functional.WithPort(0), functional.WithHandler(hello), functional.WithSlogLogger(slog.New(h)))
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- s.Run(ctx) }()
+	waitReady(t, s)
+	get(t, http.DefaultClient, "http://"+s.Addr()+"/x")
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	want := `level=INFO msg="server starting" tls=false
+level=INFO msg=request method=GET path=/x
+level=INFO msg="server shutting down"
+level=INFO msg="server stopped"
+`
+	mu.Lock()
+	defer mu.Unlock()
+	if buf.String() != want {
+		t.Errorf("log:\n%s\nwant:\n%s", &buf, want)
+	}
+}
+
+type lockedWriter struct {
+	mu *sync.Mutex
+	w  io.Writer
+}
+
+func (w lockedWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.w.Write(p)
+}
+
+func TestSlogLoggerError(t *testing.T) {
+	var buf bytes.Buffer
+	l := functional.NewSlogLogger(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{
+		ReplaceAttr: func(_ []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	})))
+	l.Error("server failed", errors.New("boom"), "addr", "x")
+	if got, want := buf.String(), "level=ERROR msg=\"server failed\" addr=x err=boom\n"; got != want {
+		t.Errorf("log = %q, want %q", got, want)
+	}
+}
+
+func TestWithSlogLoggerNil(t *testing.T) {
+	err := functional.ValidateOptions(functional.WithSlogLogger(nil))
+	if err == nil || err.Error() != "logger cannot be nil" {
+		t.Errorf("err = %v, want logger cannot be nil", err)
+	}
+	// it is the same option as WithLogger, so the last one wins
+	cfg, err := functional.ResolveConfig("localhost",
+		functional.WithLogger(&recorder{}), functional.WithSlogLogger(slog.Default()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := cfg.Options; len(got) != 1 || got[0].Value != "*slog.Logger" {
+		t.Errorf("options = %v, want one logger entry", got)
+	}
+}
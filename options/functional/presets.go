@@ -0,0 +1,54 @@
+package functional
+
+import (
+	"log/slog"
+	"os"
+	"time"
+
+	"patterns/funcopts"
+)
+
+// option groups
+// Level: Good
+// pros: a preset is an ordinary Option, so it is passed, stored and
+// overridden like any other; callers write one name instead of repeating
+// five settings, and the preset can evolve without touching call sites.
+// cons: what a preset expands to is not visible at the call site, so
+// EffectiveOptions (or --print-config) is the way to find out.
+
+// Options groups opts into a single Option applied in order. The options
+// inside keep their names, so ones given after the group override it:
+//
+//	functional.NewServer("localhost",
+//		functional.WithDefaults(),
+//		functional.WithWriteTimeout(time.Minute), // wins over the preset
+//	)
+//
+// The cross-field rules run on the result, so an override must still fit
+// the rest of the preset: WithReadTimeout(time.Minute) alone would leave
+// the preset's 30s write timeout shorter than the read timeout, and
+// NewServer rejects it.
+func Options(opts ...Option) Option {
+	return funcopts.Combine(opts...)
+}
+
+// WithDefaults sets the timeouts a server facing untrusted clients should
+// have: no timeout at all (the http.Server zero value) lets a slow client
+// hold a connection forever.
+func WithDefaults() Option {
+	return Options(
+		WithReadTimeout(10*time.Second),
+		WithWriteTimeout(30*time.Second),
+		WithIdleTimeout(2*time.Minute),
+	)
+}
+
+// WithProductionProfile is WithDefaults plus structured JSON logs on
+// stderr, for a log collector to pick up. It does not choose a port or
+// TLS, which are deployment specific.
+func WithProductionProfile() Option {
+	return Options(
+		WithDefaults(),
+		WithSlogLogger(slog.New(slog.NewJSONHandler(os.Stderr, nil))),
+	)
+}
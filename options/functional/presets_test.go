@@ -0,0 +1,108 @@
+package functional_test
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"patterns/funcopts"
+	"patterns/options/functional"
+)
+
+func names(as []funcopts.Applied) []string {
+	var out []string
+	for _, a := range as {
+		out = append(out, a.Name)
+	}
+	return out
+}
+
+func TestWithDefaults(t *testing.T) {
+	cfg, err := functional.ResolveConfig("localhost", functional.WithDefaults())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.ReadTimeout != 10*time.Second || cfg.WriteTimeout != 30*time.Second || cfg.IdleTimeout != 2*time.Minute {
+		t.Errorf("timeouts = %v, %v, %v, want 10s, 30s, 2m", cfg.ReadTimeout, cfg.WriteTimeout, cfg.IdleTimeout)
+	}
+	if got, want := names(cfg.Options), []string{"read-timeout", "write-timeout", "idle-timeout"}; !slices.Equal(got, want) {
+		t.Errorf("options = %v, want the preset's own names %v", got, want)
+	}
+}
+
+func TestOptionsOverride(t *testing.T) {
+	for _, c := range []struct {
+		name  string
+		opts  []functional.Option
+		write time.Duration
+	}{
+		{"after the preset wins", []functional.Option{functional.WithDefaults(), functional.WithWriteTimeout(time.Minute)}, time.Minute},
+		{"before the preset loses", []functional.Option{functional.WithWriteTimeout(time.Minute), functional.WithDefaults()}, 30 * time.Second},
+		{"nested groups keep names", []functional.Option{
+			functional.Options(functional.Options(functional.WithDefaults())),
+			functional.WithWriteTimeout(time.Minute),
+		}, time.Minute},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			cfg, err := functional.ResolveConfig("localhost", c.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.WriteTimeout != c.write {
+				t.Errorf("write timeout = %v, want %v", cfg.WriteTimeout, c.write)
+			}
+			if n := strings.Count(strings.Join(names(cfg.Options), " "), "write-timeout"); n != 1 {
+				t.Errorf("options = %v, want write-timeout once", names(cfg.Options))
+			}
+		})
+	}
+}
+
+// TestOverrideStillValidated is the case the Options doc warns about.
+func TestOverrideStillValidated(t *testing.T) {
+	_, err := functional.NewServer("localhost", functional.WithDefaults(), functional.WithReadTimeout(time.Minute))
+	if err == nil || !strings.Contains(err.Error(), "writeTimeout: shorter than read timeout") {
+		t.Errorf("NewServer = %v, want the write timeout rejected", err)
+	}
+}
+
+func TestOptionsStopsAtFirstError(t *testing.T) {
+	_, err := functional.ResolveConfig("localhost", functional.Options(
+		functional.WithReadTimeout(-1),
+		functional.WithWriteTimeout(-1),
+	))
+	if err == nil || err.Error() != "read timeout cannot be negative" {
+		t.Errorf("ResolveConfig = %v, want the first error only", err)
+	}
+	// ValidateOptions collects, inside groups too
+	err = functional.ValidateOptions(functional.Options(
+		functional.WithReadTimeout(-1),
+		functional.WithWriteTimeout(-1),
+	))
+	if want := "read timeout cannot be negative\nwrite timeout cannot be negative"; err == nil || err.Error() != want {
+		t.Errorf("ValidateOptions = %v, want %q", err, want)
+	}
+}
+
+func TestWithProductionProfile(t *testing.T) {
+	cfg, err := functional.ResolveConfig("localhost", functional.WithProductionProfile())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.ReadTimeout != 10*time.Second || cfg.WriteTimeout != 30*time.Second || cfg.IdleTimeout != 2*time.Minute {
+		t.Errorf("timeouts = %v, %v, %v, want the defaults", cfg.ReadTimeout, cfg.WriteTimeout, cfg.IdleTimeout)
+	}
+	want := []funcopts.Applied{
+		{Name: "read-timeout", Value: 10 * time.Second},
+		{Name: "write-timeout", Value: 30 * time.Second},
+		{Name: "idle-timeout", Value: 2 * time.Minute},
+		{Name: "logger", Value: "*slog.Logger"},
+	}
+	if !slices.Equal(cfg.Options, want) {
+		t.Errorf("options = %v, want %v", cfg.Options, want)
+	}
+	if cfg.TLS || cfg.Addr != "localhost:8080" {
+		t.Errorf("addr %s, tls %v: the profile must not choose either", cfg.Addr, cfg.TLS)
+	}
+}
@@ -0,0 +1,154 @@
+package functional_test
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"patterns/netutil/freeport/freeporttest"
+	"patterns/options/functional"
+)
+
+var hello = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	io.WriteString(w, "hello")
+})
+
+// start runs s until the test ends, failing it if Run does not return
+// nil after the cancel.
+func start(t *testing.T, s *functional.Server) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- s.Run(ctx) }()
+	waitReady(t, s)
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("Run: %v", err)
+		}
+	})
+}
+
+func waitReady(t *testing.T, s *functional.Server) {
+	t.Helper()
+	select {
+	case <-s.Ready():
+	case <-time.After(5 * time.Second):
+		t.Fatal("Ready was not closed")
+	}
+}
+
+func get(t *testing.T, c *http.Client, url string) string {
+	t.Helper()
+	resp, err := c.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.Status + " " + string(body)
+}
+
+func TestRunServesOnRandomPort(t *testing.T) {
+	s := functional.MustNewServer("127.0.0.1", functional.WithPort(0), functional.WithHandler(hello))
+	// port 0 is bound by NewServer, so Addr is final before Run
+	before := s.Addr()
+	if _, port, _ := net.SplitHostPort(before); port == "0" || port == "" {
+		t.Fatalf("Addr before Run = %q, want the bound port", before)
+	}
+	start(t, s)
+	if s.Addr() != before {
+		t.Errorf("Addr after Ready = %q, want %q", s.Addr(), before)
+	}
+	if got := get(t, http.DefaultClient, "http://"+s.Addr()); got != "200 OK hello" {
+		t.Errorf("GET = %q", got)
+	}
+}
+
+func TestRunServesOnListener(t *testing.T) {
+	l, port := freeporttest.Listen(t)
+	s := functional.MustNewServer("127.0.0.1", functional.WithListener(l), functional.WithHandler(hello))
+	if want := "127.0.0.1:" + strconv.Itoa(port); s.Addr() != want {
+		t.Errorf("Addr = %q, want %q", s.Addr(), want)
+	}
+	start(t, s)
+	if got := get(t, http.DefaultClient, "http://"+s.Addr()); got != "200 OK hello" {
+		t.Errorf("GET = %q", got)
+	}
+}
+
+// TestRunListenFails holds the configured port, so Run cannot listen:
+// Ready must still be closed, and Run return the error.
+func TestRunListenFails(t *testing.T) {
+	_, port := freeporttest.Listen(t)
+	s := functional.MustNewServer("127.0.0.1", functional.WithPort(port))
+	done := make(chan error, 1)
+	go func() { done <- s.Run(context.Background()) }()
+	waitReady(t, s)
+	select {
+	case err := <-done:
+		if err == nil || !strings.Contains(err.Error(), "address already in use") {
+			t.Errorf("Run = %v, want address already in use", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return")
+	}
+}
+
+func TestRunTwice(t *testing.T) {
+	s := functional.MustNewServer("127.0.0.1", functional.WithPort(0))
+	start(t, s)
+	if err := s.Run(context.Background()); err == nil || err.Error() != "server already started" {
+		t.Errorf("second Run = %v, want server already started", err)
+	}
+}
+
+// TestRunShutsDownGracefully cancels Run while a request is in flight;
+// the request completes and Run returns nil.
+func TestRunShutsDownGracefully(t *testing.T) {
+	entered, release := make(chan struct{}), make(chan struct{})
+	slow := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(entered)
+		<-release
+		io.WriteString(w, "done")
+	})
+	s := functional.MustNewServer("127.0.0.1", functional.WithPort(0), functional.WithHandler(slow))
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- s.Run(ctx) }()
+	waitReady(t, s)
+
+	got := make(chan string, 1)
+	go func() {
+		resp, err := http.Get("http://" + s.Addr())
+		if err != nil {
+			got <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		got <- resp.Status + " " + string(body)
+	}()
+	<-entered
+	cancel()
+	select {
+	case err := <-done:
+		t.Fatalf("Run returned %v with a request in flight", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+	if g := <-got; g != "200 OK done" {
+		t.Errorf("in-flight GET = %q", g)
+	}
+	if err := <-done; err != nil {
+		t.Errorf("Run = %v, want nil", err)
+	}
+}
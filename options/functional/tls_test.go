@@ -0,0 +1,180 @@
+package functional_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"patterns/funcopts"
+	"patterns/netutil/freeport/freeporttest"
+	"patterns/options/functional"
+)
+
+// selfSigned returns a PEM certificate and key for 127.0.0.1, and a
+// client trusting only that certificate.
+func selfSigned(t *testing.T) (certPEM, keyPEM []byte, client *http.Client) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "functional test"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	transport := &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}}
+	t.Cleanup(transport.CloseIdleConnections)
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}),
+		&http.Client{Transport: transport}
+}
+
+func TestTLSCertFiles(t *testing.T) {
+	certPEM, keyPEM, client := selfSigned(t)
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(certFile, certPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, keyPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	l, _ := freeporttest.Listen(t)
+	s := functional.MustNewServer("127.0.0.1",
+		functional.WithListener(l), functional.WithTLSCertFiles(certFile, keyFile), functional.WithHandler(hello))
+	start(t, s)
+
+	if got := get(t, client, "https://"+s.Addr()); got != "200 OK hello" {
+		t.Errorf("GET over TLS = %q", got)
+	}
+	// plain HTTP gets the standard library's answer to a non-TLS client
+	if got := get(t, http.DefaultClient, "http://"+s.Addr()); !strings.HasPrefix(got, "400 ") {
+		t.Errorf("GET over plain HTTP = %q, want 400", got)
+	}
+}
+
+func TestTLSConfig(t *testing.T) {
+	certPEM, keyPEM, client := selfSigned(t)
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &tls.Config{Certificates: []tls.Certificate{cert}}
+
+	l, _ := freeporttest.Listen(t)
+	s := functional.MustNewServer("127.0.0.1",
+		functional.WithListener(l), functional.WithTLSConfig(cfg), functional.WithHandler(hello))
+	// the server keeps its own copy
+	cfg.Certificates = nil
+	start(t, s)
+
+	if got := get(t, client, "https://"+s.Addr()); got != "200 OK hello" {
+		t.Errorf("GET over TLS = %q", got)
+	}
+	// a client that does not trust the certificate fails the handshake
+	if _, err := http.Get("https://" + s.Addr()); err == nil {
+		t.Error("GET with the system roots succeeded")
+	}
+}
+
+func TestTLSOptionErrors(t *testing.T) {
+	certPEM, keyPEM, _ := selfSigned(t)
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+
+	for _, c := range []struct {
+		name string
+		opts []functional.Option
+		want string
+	}{
+		{"missing files", []functional.Option{functional.WithTLSCertFiles(filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem"))}, "load tls key pair: "},
+		{"nil config", []functional.Option{functional.WithTLSConfig(nil)}, "tls config cannot be nil"},
+		{"no certificates", []functional.Option{functional.WithTLSConfig(&tls.Config{})}, "tls config has no certificates"},
+	} {
+		_, err := functional.NewServer("127.0.0.1", append(c.opts, functional.WithPort(0))...)
+		if err == nil || !strings.HasPrefix(err.Error(), c.want) {
+			t.Errorf("%s: err = %v, want %q", c.name, err, c.want)
+		}
+	}
+
+	// the two TLS options are one group
+	cfg := &tls.Config{Certificates: []tls.Certificate{cert}}
+	_, err = functional.NewServer("127.0.0.1", functional.WithPort(0),
+		functional.WithTLSConfig(cfg), functional.WithTLSCertFiles("cert.pem", "key.pem"))
+	var conflict *funcopts.ConflictError
+	if !errors.As(err, &conflict) || conflict.Group != "tls" {
+		t.Errorf("err = %v, want a tls ConflictError", err)
+	}
+}
+
+// TestTLSReadTimeout holds a TLS connection open without sending a
+// request; the read timeout must close it.
+func TestTLSReadTimeout(t *testing.T) {
+	certPEM, keyPEM, client := selfSigned(t)
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, _ := freeporttest.Listen(t)
+	s := functional.MustNewServer("127.0.0.1",
+		functional.WithListener(l),
+		functional.WithTLSConfig(&tls.Config{Certificates: []tls.Certificate{cert}}),
+		functional.WithTimeouts(200*time.Millisecond, time.Second, time.Second),
+		functional.WithHandler(hello),
+	)
+	start(t, s)
+
+	roots := client.Transport.(*http.Transport).TLSClientConfig
+	conn, err := tls.Dial("tcp", s.Addr(), roots)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	begin := time.Now()
+	if _, err := conn.Read(make([]byte, 1)); err == nil {
+		t.Fatal("read a response without sending a request")
+	} else if errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Fatal("the server did not close the idle connection")
+	}
+	if d := time.Since(begin); d > 2*time.Second {
+		t.Errorf("connection closed after %v, want about the 200ms read timeout", d)
+	}
+}
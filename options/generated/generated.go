@@ -0,0 +1,61 @@
+// Package generated is the functional options variant with the options
+// written by a tool: cmd/optgen reads the annotated config struct below
+// and emits config_options.go (the Option type, one WithX per field and
+// the apply loop), so adding a setting is one tagged field.
+//
+//	s, l, err := generated.NewServer("localhost", generated.WithPort(0))
+//
+// Regenerate with go generate after changing config.
+package generated
+
+import (
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"patterns/options/portspec"
+)
+
+//go:generate go run patterns/cmd/optgen -type=config
+
+// generated functional options pattern
+// Level: Good
+// pros: the same API as hand-written functional options without the
+// boilerplate; field rules (nonnil, nonnegative) cannot drift from the
+// options that enforce them.
+// cons: a build step to keep in sync, and anything the tag rules cannot
+// express still goes into hand-written check funcs.
+type config struct {
+	// Unset means portspec.DefaultPort and zero a random port.
+	port *int `opt:"Port,optional,check"`
+	// Bounds reading a whole request, body included; zero means no timeout.
+	readTimeout time.Duration `opt:"ReadTimeout,nonnegative"`
+	logger      *slog.Logger  `opt:"Logger,nonnil"`
+	handler     http.Handler  `opt:"Handler,nonnil"`
+}
+
+func (c *config) setDefaults() {
+	c.logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	c.handler = http.DefaultServeMux
+}
+
+func checkPort(port int) error {
+	return portspec.Check(&port)
+}
+
+func NewServer(addr string, opts ...Option) (*http.Server, net.Listener, error) {
+	c, err := newConfig(opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	hostport, l, err := portspec.Resolve(addr, c.port)
+	if err != nil {
+		return nil, nil, err
+	}
+	c.logger.Info("server configured", "addr", hostport)
+
+	return &http.Server{Addr: hostport, Handler: c.handler, ReadTimeout: c.readTimeout}, l, nil
+}
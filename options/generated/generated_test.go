@@ -0,0 +1,82 @@
+package generated_test
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"patterns/options/generated"
+	"patterns/options/portspec"
+)
+
+// TestDefaults checks that the generated constructor applies setDefaults
+// before any option.
+func TestDefaults(t *testing.T) {
+	s, l, err := generated.NewServer("localhost")
+	if err != nil || l != nil {
+		t.Fatalf("NewServer = %v, %v", l, err)
+	}
+	if want := "localhost:" + strconv.Itoa(portspec.DefaultPort); s.Addr != want || s.Handler != http.DefaultServeMux || s.ReadTimeout != 0 {
+		t.Errorf("NewServer = %+v, want %s on http.DefaultServeMux", s, want)
+	}
+}
+
+func TestOptions(t *testing.T) {
+	var buf bytes.Buffer
+	mux := http.NewServeMux()
+	s, l, err := generated.NewServer("127.0.0.1",
+		generated.WithPort(8081),
+		generated.WithReadTimeout(time.Second),
+		generated.WithLogger(slog.New(slog.NewTextHandler(&buf, nil))),
+		generated.WithHandler(mux),
+		generated.WithPort(8082),
+	)
+	if err != nil || l != nil {
+		t.Fatalf("NewServer = %v, %v", l, err)
+	}
+	if s.Addr != "127.0.0.1:8082" || s.ReadTimeout != time.Second || s.Handler != mux {
+		t.Errorf("NewServer = %+v", s)
+	}
+	if !strings.Contains(buf.String(), "addr=127.0.0.1:8082") {
+		t.Errorf("logged %q, want the configured address on the given logger", &buf)
+	}
+}
+
+// TestRules checks the errors the tag rules and checkPort generate, and
+// that the first failing option stops the others.
+func TestRules(t *testing.T) {
+	for _, c := range []struct {
+		opts []generated.Option
+		want string
+	}{
+		{[]generated.Option{generated.WithReadTimeout(-1)}, "read timeout cannot be negative"},
+		{[]generated.Option{generated.WithLogger(nil)}, "logger cannot be nil"},
+		{[]generated.Option{generated.WithHandler(nil)}, "handler cannot be nil"},
+		{[]generated.Option{generated.WithPort(-1)}, portspec.ErrNegative.Error()},
+		{[]generated.Option{generated.WithHandler(nil), generated.WithLogger(nil)}, "handler cannot be nil"},
+	} {
+		s, l, err := generated.NewServer("localhost", c.opts...)
+		if s != nil || l != nil || err == nil || err.Error() != c.want {
+			t.Errorf("NewServer = %v, %v, %v; want %q", s, l, err, c.want)
+		}
+	}
+	if _, _, err := generated.NewServer("localhost", generated.WithPort(-1)); !errors.Is(err, portspec.ErrNegative) {
+		t.Errorf("WithPort(-1) = %v, want portspec.ErrNegative", err)
+	}
+}
+
+func TestRandomPort(t *testing.T) {
+	s, l, err := generated.NewServer("127.0.0.1", generated.WithPort(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	if s.Addr != l.Addr().String() || s.Addr == "127.0.0.1:0" {
+		t.Errorf("Addr = %s, listener on %s", s.Addr, l.Addr())
+	}
+}
@@ -0,0 +1,174 @@
+// Package ifaceopts is the interface variant of the options comparison:
+// each option is a small comparable value behind an Option interface, the
+// way grpc.DialOption is, instead of an opaque func.
+//
+//	s, l, err := ifaceopts.NewServer("localhost",
+//		ifaceopts.WithPort(0),
+//		ifaceopts.WithLogger(slog.Default()),
+//	)
+//
+// Because options are values, callers can log them (String), compare
+// them (==, e.g. to check whether a default was overridden) and look
+// them up by Name. WithHandler compares only if its handler's type does:
+// a *http.ServeMux does, an http.HandlerFunc panics. Because each carries a Priority, NewServer applies
+// them in a defined order whatever order they were passed in: the logger
+// first, so every later option can log through it.
+package ifaceopts
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"slices"
+	"time"
+
+	"patterns/options/portspec"
+)
+
+// interface options pattern
+// Level: Good
+// pros: options can be printed, compared and ordered; the unexported
+// apply keeps the set closed, so the package controls validation.
+// cons: a type and four methods per option where a closure needs one
+// line; callers cannot write their own options without an escape hatch.
+type Option interface {
+	// Name identifies the option kind; two options with the same Name
+	// set the same thing, and the later one wins.
+	Name() string
+	// Priority orders application: lower first, ties in call order.
+	Priority() Priority
+	fmt.Stringer
+	apply(*options) error
+}
+
+// Priority is an application stage.
+type Priority int
+
+const (
+	// PriorityInfra options provide what other options use.
+	PriorityInfra Priority = iota * 10
+	// PriorityListen options decide where the server listens.
+	PriorityListen
+	// PriorityDefault is for everything else.
+	PriorityDefault
+)
+
+type options struct {
+	logger      *slog.Logger
+	port        *int
+	readTimeout time.Duration
+	handler     http.Handler
+}
+
+type loggerOption struct{ logger *slog.Logger }
+
+func WithLogger(l *slog.Logger) Option { return loggerOption{l} }
+
+func (loggerOption) Name() string       { return "logger" }
+func (loggerOption) Priority() Priority { return PriorityInfra }
+func (loggerOption) String() string     { return "logger" }
+
+func (o loggerOption) apply(opts *options) error {
+	if o.logger == nil {
+		return errors.New("logger cannot be nil")
+	}
+	opts.logger = o.logger
+	return nil
+}
+
+type portOption struct{ port int }
+
+// WithPort sets the port by the port spec; 0 means random.
+func WithPort(port int) Option { return portOption{port} }
+
+func (portOption) Name() string       { return "port" }
+func (portOption) Priority() Priority { return PriorityListen }
+func (o portOption) String() string   { return fmt.Sprintf("port=%d", o.port) }
+
+func (o portOption) apply(opts *options) error {
+	if err := portspec.Check(&o.port); err != nil {
+		return err
+	}
+	opts.port = &o.port
+	opts.logger.Debug("option applied", "option", o.String())
+	return nil
+}
+
+type readTimeoutOption struct{ d time.Duration }
+
+func WithReadTimeout(d time.Duration) Option { return readTimeoutOption{d} }
+
+func (readTimeoutOption) Name() string       { return "read-timeout" }
+func (readTimeoutOption) Priority() Priority { return PriorityDefault }
+func (o readTimeoutOption) String() string   { return "read-timeout=" + o.d.String() }
+
+func (o readTimeoutOption) apply(opts *options) error {
+	if o.d < 0 {
+		return errors.New("read timeout cannot be negative")
+	}
+	opts.readTimeout = o.d
+	opts.logger.Debug("option applied", "option", o.String())
+	return nil
+}
+
+type handlerOption struct{ h http.Handler }
+
+func WithHandler(h http.Handler) Option { return handlerOption{h} }
+
+func (handlerOption) Name() string       { return "handler" }
+func (handlerOption) Priority() Priority { return PriorityDefault }
+func (o handlerOption) String() string   { return fmt.Sprintf("handler=%T", o.h) }
+
+func (o handlerOption) apply(opts *options) error {
+	if o.h == nil {
+		return errors.New("handler cannot be nil")
+	}
+	opts.handler = o.h
+	opts.logger.Debug("option applied", "option", o.String())
+	return nil
+}
+
+// Order returns opts in application order: by Priority, then in the
+// order given. NewServer applies exactly this sequence.
+func Order(opts ...Option) []Option {
+	ordered := slices.Clone(opts)
+	slices.SortStableFunc(ordered, func(a, b Option) int {
+		return cmp.Compare(a.Priority(), b.Priority())
+	})
+	return ordered
+}
+
+// Lookup returns the option named name that would take effect, the last
+// one given.
+func Lookup(name string, opts ...Option) (Option, bool) {
+	for _, opt := range slices.Backward(opts) {
+		if opt.Name() == name {
+			return opt, true
+		}
+	}
+	return nil, false
+}
+
+// NewServer applies opts in Order and resolves the port by the spec.
+// s.Addr carries the resolved port, and l is non-nil only for port 0,
+// bound to it.
+func NewServer(addr string, opts ...Option) (s *http.Server, l net.Listener, err error) {
+	o := options{logger: slog.New(slog.NewTextHandler(io.Discard, nil)), handler: http.DefaultServeMux}
+	for _, opt := range Order(opts...) {
+		if err := opt.apply(&o); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	hostport, l, err := portspec.Resolve(addr, o.port)
+	if err != nil {
+		return nil, nil, err
+	}
+	o.logger.Info("server configured", "addr", hostport)
+
+	return &http.Server{Addr: hostport, Handler: o.handler, ReadTimeout: o.readTimeout}, l, nil
+}
@@ -0,0 +1,195 @@
+package ifaceopts_test
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"patterns/options/ifaceopts"
+	"patterns/randsource"
+)
+
+// applied returns the options NewServer logged applying, in order, and
+// what it logged last.
+func applied(t *testing.T, opts ...ifaceopts.Option) ([]string, string) {
+	t.Helper()
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+		ReplaceAttr: func(_ []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+	if _, _, err := ifaceopts.NewServer("localhost", append(opts, ifaceopts.WithLogger(logger))...); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	for _, line := range lines {
+		if _, opt, ok := strings.Cut(line, "option="); ok {
+			// the text handler quotes values holding '='
+			if u, err := strconv.Unquote(opt); err == nil {
+				opt = u
+			}
+			got = append(got, opt)
+		}
+	}
+	return got, lines[len(lines)-1]
+}
+
+func names(opts []ifaceopts.Option) string {
+	var s []string
+	for _, o := range opts {
+		s = append(s, o.String())
+	}
+	return strings.Join(s, " ")
+}
+
+// a comparable handler; see TestCompareFunc
+var handler = http.NewServeMux()
+
+func TestOrder(t *testing.T) {
+	logger := ifaceopts.WithLogger(slog.Default())
+	for _, c := range []struct {
+		opts []ifaceopts.Option
+		want string
+	}{
+		{nil, ""},
+		{[]ifaceopts.Option{ifaceopts.WithHandler(handler), ifaceopts.WithPort(1), logger},
+			"logger port=1 handler=*http.ServeMux"},
+		// ties keep the order given, so the last of a kind still wins
+		{[]ifaceopts.Option{ifaceopts.WithReadTimeout(time.Second), ifaceopts.WithPort(2), ifaceopts.WithHandler(handler), ifaceopts.WithPort(1)},
+			"port=2 port=1 read-timeout=1s handler=*http.ServeMux"},
+		{[]ifaceopts.Option{ifaceopts.WithHandler(handler), ifaceopts.WithReadTimeout(time.Second), ifaceopts.WithReadTimeout(time.Minute)},
+			"handler=*http.ServeMux read-timeout=1s read-timeout=1m0s"},
+	} {
+		in := slices.Clone(c.opts)
+		if got := names(ifaceopts.Order(c.opts...)); got != c.want {
+			t.Errorf("Order(%s) = %s, want %s", names(in), got, c.want)
+		}
+		if !slices.Equal(in, c.opts) {
+			t.Errorf("Order reordered its argument: %s", names(c.opts))
+		}
+	}
+}
+
+// TestApplyOrder checks what NewServer actually does against Order:
+// the logger passed last is in place for every other option, and
+// shuffled inputs are applied by priority, ties as given.
+func TestApplyOrder(t *testing.T) {
+	got, last := applied(t, ifaceopts.WithHandler(handler), ifaceopts.WithReadTimeout(time.Second), ifaceopts.WithPort(9090))
+	if want := []string{"port=9090", "handler=*http.ServeMux", "read-timeout=1s"}; !slices.Equal(got, want) {
+		t.Errorf("applied %q, want %q", got, want)
+	}
+	if !strings.Contains(last, `msg="server configured" addr=localhost:9090`) {
+		t.Errorf("last log %q, want the configured server", last)
+	}
+
+	r := randsource.New(1)
+	opts := []ifaceopts.Option{
+		ifaceopts.WithPort(1), ifaceopts.WithPort(2),
+		ifaceopts.WithReadTimeout(time.Second), ifaceopts.WithHandler(handler), ifaceopts.WithReadTimeout(time.Minute),
+	}
+	rank := map[string]int{"port": 0, "read-timeout": 1, "handler": 1}
+	for range 50 {
+		for i := len(opts) - 1; i > 0; i-- {
+			j := r.IntN(i + 1)
+			opts[i], opts[j] = opts[j], opts[i]
+		}
+		ordered := ifaceopts.Order(opts...)
+		for i := 1; i < len(ordered); i++ {
+			if a, b := ordered[i-1], ordered[i]; rank[a.Name()] > rank[b.Name()] {
+				t.Fatalf("input %s: %s ordered before %s", names(opts), a, b)
+			}
+		}
+		if got, _ := applied(t, opts...); strings.Join(got, " ") != names(ordered) {
+			t.Fatalf("input %s: applied %q, want %s", names(opts), got, names(ordered))
+		}
+	}
+}
+
+func TestLastWins(t *testing.T) {
+	opts := []ifaceopts.Option{ifaceopts.WithPort(9090), ifaceopts.WithReadTimeout(time.Second), ifaceopts.WithPort(9091)}
+	s, _, err := ifaceopts.NewServer("localhost", opts...)
+	if err != nil || s.Addr != "localhost:9091" || s.ReadTimeout != time.Second {
+		t.Errorf("NewServer = %+v, %v", s, err)
+	}
+	if o, ok := ifaceopts.Lookup("port", opts...); !ok || o != ifaceopts.WithPort(9091) {
+		t.Errorf("Lookup(port) = %v, %v", o, ok)
+	}
+	if o, ok := ifaceopts.Lookup("handler", opts...); ok {
+		t.Errorf("Lookup(handler) = %v, want none", o)
+	}
+}
+
+// TestCompare checks that options are comparable values, so a caller
+// can tell whether a default was overridden.
+func TestCompare(t *testing.T) {
+	for _, c := range []struct {
+		a, b  ifaceopts.Option
+		equal bool
+	}{
+		{ifaceopts.WithPort(1), ifaceopts.WithPort(1), true},
+		{ifaceopts.WithPort(1), ifaceopts.WithPort(2), false},
+		{ifaceopts.WithReadTimeout(time.Second), ifaceopts.WithReadTimeout(time.Second), true},
+		{ifaceopts.WithLogger(slog.Default()), ifaceopts.WithLogger(slog.Default()), true},
+		{ifaceopts.WithHandler(handler), ifaceopts.WithHandler(handler), true},
+		{ifaceopts.WithPort(0), ifaceopts.WithReadTimeout(0), false},
+	} {
+		if got := c.a == c.b; got != c.equal {
+			t.Errorf("%s == %s is %v, want %v", c.a, c.b, got, c.equal)
+		}
+	}
+}
+
+// TestCompareFunc pins the caveat in the package doc: comparing options
+// holding an uncomparable handler panics.
+func TestCompareFunc(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("comparing WithHandler of HandlerFuncs did not panic")
+		}
+	}()
+	f := http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})
+	_ = ifaceopts.WithHandler(f) == ifaceopts.WithHandler(f)
+}
+
+// TestErrors checks that the first failing option in Order is reported,
+// wherever it was passed.
+func TestErrors(t *testing.T) {
+	for _, c := range []struct {
+		opts []ifaceopts.Option
+		want string
+	}{
+		{[]ifaceopts.Option{ifaceopts.WithLogger(nil)}, "logger cannot be nil"},
+		{[]ifaceopts.Option{ifaceopts.WithPort(-1)}, "port cannot be negative"},
+		{[]ifaceopts.Option{ifaceopts.WithReadTimeout(-1)}, "read timeout cannot be negative"},
+		{[]ifaceopts.Option{ifaceopts.WithHandler(nil)}, "handler cannot be nil"},
+		{[]ifaceopts.Option{ifaceopts.WithHandler(nil), ifaceopts.WithPort(-1), ifaceopts.WithLogger(nil)}, "logger cannot be nil"},
+		{[]ifaceopts.Option{ifaceopts.WithHandler(nil), ifaceopts.WithPort(-1)}, "port cannot be negative"},
+	} {
+		s, l, err := ifaceopts.NewServer("localhost", c.opts...)
+		if s != nil || l != nil || err == nil || err.Error() != c.want {
+			t.Errorf("NewServer(%s) = %v, %v, %v; want %q", names(c.opts), s, l, err, c.want)
+		}
+	}
+}
+
+func TestRandomPort(t *testing.T) {
+	s, l, err := ifaceopts.NewServer("127.0.0.1", ifaceopts.WithPort(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	if s.Addr != l.Addr().String() || s.Addr == "127.0.0.1:0" {
+		t.Errorf("Addr = %s, listener on %s", s.Addr, l.Addr())
+	}
+}
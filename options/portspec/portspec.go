@@ -0,0 +1,49 @@
+// Package portspec resolves a port the way every options variant must:
+//
+//   - not set: DefaultPort
+//   - zero: a free port picked by the kernel
+//   - negative: an error
+//   - positive: that port
+//
+// The random port is bound here and the listener returned, so the port
+// cannot be taken between choosing it and serving on it.
+package portspec
+
+import (
+	"errors"
+	"net"
+	"strconv"
+
+	"patterns/netutil/freeport"
+)
+
+// DefaultPort is used when no port is given.
+const DefaultPort = 8080
+
+var ErrNegative = errors.New("port cannot be negative")
+
+// Check validates a port without resolving it.
+func Check(port *int) error {
+	if port != nil && *port < 0 {
+		return ErrNegative
+	}
+	return nil
+}
+
+// Resolve returns host:port with the port resolved. l is non-nil only for
+// port 0: it is bound to the returned address and must be served on.
+func Resolve(host string, port *int) (addr string, l net.Listener, err error) {
+	if err := Check(port); err != nil {
+		return "", nil, err
+	}
+	p := DefaultPort
+	if port != nil {
+		p = *port
+	}
+	if p == 0 {
+		if l, p, err = freeport.ListenEphemeral(host); err != nil {
+			return "", nil, err
+		}
+	}
+	return net.JoinHostPort(host, strconv.Itoa(p)), l, nil
+}
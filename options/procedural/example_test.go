@@ -0,0 +1,37 @@
+package procedural_test
+
+import (
+	"fmt"
+
+	"patterns/options/procedural"
+)
+
+func ExampleNewServer() {
+	s, l, err := procedural.NewServer("localhost", nil)
+	fmt.Println(s.Addr, l, err)
+
+	port := 9090
+	s, _, _ = procedural.NewServer("localhost", &port)
+	fmt.Println(s.Addr)
+
+	port = -1
+	_, _, err = procedural.NewServer("localhost", &port)
+	fmt.Println(err)
+	// Output:
+	// localhost:8080 <nil> <nil>
+	// localhost:9090
+	// port cannot be negative
+}
+
+func ExampleNewServer_randomPort() {
+	port := 0
+	s, l, err := procedural.NewServer("127.0.0.1", &port)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer l.Close()
+	// the listener is already bound to the port s.Addr names
+	fmt.Println(s.Addr == l.Addr().String(), s.Addr != "127.0.0.1:0")
+	// Output: true true
+}
@@ -0,0 +1,35 @@
+// Package procedural is the procedural variant of the options
+// comparison: every setting is a positional argument.
+//
+//	port := 0 // random; nil for the default
+//	s, l, err := procedural.NewServer("localhost", &port)
+//	if err != nil {
+//		log.Println(err)
+//	}
+//	log.Println("listening on", s.Addr)
+//	s.Serve(l)
+package procedural
+
+import (
+	"net"
+	"net/http"
+
+	"patterns/options/portspec"
+)
+
+// procedural pattern
+// Level: Poor
+// cons: nil vs zero needs a pointer; every new setting breaks callers.
+//
+// NewServer resolves port by the spec: nil means portspec.DefaultPort and
+// 0 a random port. s.Addr carries the resolved port. For port 0, l is the
+// listener bound to it; serve on l, since a bare *http.Server cannot
+// carry it. Otherwise l is nil and s.ListenAndServe binds the port.
+func NewServer(addr string, port *int) (s *http.Server, l net.Listener, err error) {
+	hostport, l, err := portspec.Resolve(addr, port)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &http.Server{Addr: hostport}, l, nil
+}
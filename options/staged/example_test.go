@@ -0,0 +1,37 @@
+package staged_test
+
+import (
+	"fmt"
+
+	"patterns/options/staged"
+)
+
+func ExampleNew() {
+	s, l, err := staged.New().Addr("localhost").NewServer()
+	fmt.Println(s.Addr, l, err)
+
+	s, _, _ = staged.New().Addr("localhost").Port(9090).NewServer()
+	fmt.Println(s.Addr)
+
+	// a stage is a value: branching it leaves the original alone
+	base := staged.New().Addr("localhost").Port(9090)
+	_ = base.Port(9091)
+	s, _, _ = base.NewServer()
+	fmt.Println(s.Addr)
+	// Output:
+	// localhost:8080 <nil> <nil>
+	// localhost:9090
+	// localhost:9090
+}
+
+func ExampleOptionsStage_RandomPort() {
+	s, l, err := staged.New().Addr("127.0.0.1").RandomPort().NewServer()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer l.Close()
+	// the listener is already bound to the port s.Addr names
+	fmt.Println(s.Addr == l.Addr().String(), s.Addr != "127.0.0.1:0")
+	// Output: true true
+}
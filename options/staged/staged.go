@@ -0,0 +1,70 @@
+// Package staged is the staged (typestate) builder variant of the options
+// comparison. Each step returns a different type that only has the
+// methods valid next, so an incomplete or invalid configuration does not
+// compile:
+//
+//	s, l, err := staged.New().Addr("localhost").RandomPort().NewServer()
+//	if err != nil {
+//		log.Println(err) // only binding can fail now
+//	}
+//
+//	staged.New().NewServer()                 // compile error: addr is required
+//	staged.New().Addr("localhost").Port(-1)  // compile error: -1 overflows uint16
+//
+// Compare builder, whose Build reports the same mistakes at run time.
+package staged
+
+import (
+	"net"
+	"net/http"
+
+	"patterns/options/portspec"
+)
+
+// staged builder pattern
+// Level: Good
+// pros: required settings and their order are checked by the compiler;
+// Port takes a uint16, so the negative case of the port spec cannot be
+// written, and NewServer only fails for reasons outside the caller's code.
+// cons: one type per stage, growing with every required setting; a port
+// held in an int must be range-checked by the caller before conversion.
+type AddrStage struct{}
+
+// New starts a configuration; Addr is the only way forward.
+func New() AddrStage { return AddrStage{} }
+
+// Addr sets the required listen host and moves to the optional settings.
+func (AddrStage) Addr(addr string) OptionsStage {
+	return OptionsStage{addr: addr}
+}
+
+// OptionsStage holds a complete configuration; its methods return
+// modified copies, so a stage can be branched without aliasing.
+type OptionsStage struct {
+	addr string
+	port *int
+}
+
+// Port sets the port; 0 means random, like RandomPort.
+func (s OptionsStage) Port(port uint16) OptionsStage {
+	p := int(port)
+	s.port = &p
+	return s
+}
+
+// RandomPort asks for a free port picked by the kernel.
+func (s OptionsStage) RandomPort() OptionsStage {
+	return s.Port(0)
+}
+
+// NewServer resolves the port by the spec, portspec.DefaultPort when Port
+// was never called. s.Addr carries the resolved port, and l is non-nil
+// only for port 0, bound to it.
+func (s OptionsStage) NewServer() (srv *http.Server, l net.Listener, err error) {
+	hostport, l, err := portspec.Resolve(s.addr, s.port)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &http.Server{Addr: hostport}, l, nil
+}
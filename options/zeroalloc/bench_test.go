@@ -0,0 +1,62 @@
+package zeroalloc
+
+import (
+	"testing"
+	"time"
+)
+
+var sink Settings
+
+// the values vary per iteration so nothing is constant-folded, and the
+// port stays above 255 where small-int boxing stops being free
+func args(i int) (int, time.Duration, string) {
+	return 8000 + i%1000, time.Duration(i%10+1) * time.Second, names[i%len(names)]
+}
+
+var names = []string{"api", "admin", "metrics"}
+
+// Each benchmark builds Settings from three options with one variant.
+
+func BenchmarkClosure(b *testing.B) {
+	for i := range b.N {
+		p, d, n := args(i)
+		s, err := New(WithPort(p), WithTimeout(d), WithName(n))
+		if err != nil {
+			b.Fatal(err)
+		}
+		sink = s
+	}
+}
+
+func BenchmarkInterface(b *testing.B) {
+	for i := range b.N {
+		p, d, n := args(i)
+		s, err := NewIface(IfacePort(p), IfaceTimeout(d), IfaceName(n))
+		if err != nil {
+			b.Fatal(err)
+		}
+		sink = s
+	}
+}
+
+func BenchmarkTagged(b *testing.B) {
+	for i := range b.N {
+		p, d, n := args(i)
+		s, err := NewTagged(Port(p), Timeout(d), Name(n))
+		if err != nil {
+			b.Fatal(err)
+		}
+		sink = s
+	}
+}
+
+func BenchmarkConfig(b *testing.B) {
+	for i := range b.N {
+		p, d, n := args(i)
+		s, err := NewConfig(Config{Port: p, Timeout: d, Name: n})
+		if err != nil {
+			b.Fatal(err)
+		}
+		sink = s
+	}
+}
@@ -0,0 +1,267 @@
+// Package zeroalloc measures what functional options cost per call and
+// implements variants that avoid it. Each variant configures the same
+// Settings (port, timeout, name) and builds them with the same defaults
+// and validation; only the representation of an option differs.
+//
+// findings (see bench_test.go; go test -bench . -benchmem
+// patterns/options/zeroalloc; go build -gcflags=-m shows the escapes):
+//   - closure options cost one allocation per construction, not one per
+//     option: the With* calls inline at the call site, so their closures
+//     stay on the stack, but the settings struct escapes because it is
+//     passed to an indirect call. A constructor that stores its settings
+//     in the returned value pays that allocation anyway.
+//   - interface options are the worst: the same escaping settings plus
+//     one box per option (ints over 255, strings), four allocations for
+//     three options and about 2.5x the time of closures.
+//   - tagged struct options (one concrete Option struct, applied with a
+//     switch) and a config literal allocate nothing and are 4x and 10x
+//     faster than closures.
+//   - in absolute terms closures cost under 100ns for three options:
+//     noise for a constructor called once at startup. The zero-alloc
+//     forms matter only for options applied per call on a hot path, such
+//     as the call options of an RPC client.
+package zeroalloc
+
+import (
+	"errors"
+	"time"
+)
+
+// Settings is what every variant produces.
+type Settings struct {
+	Port    int
+	Timeout time.Duration
+	Name    string
+}
+
+// The constructors are marked noinline: real ones are too large to be
+// inlined, and inlining would let escape analysis keep the closures of
+// this toy version on the stack, hiding the cost being measured.
+
+func defaults() Settings {
+	return Settings{Port: 8080, Timeout: 30 * time.Second, Name: "server"}
+}
+
+var (
+	errPort    = errors.New("port out of range")
+	errTimeout = errors.New("timeout must be positive")
+	errName    = errors.New("name cannot be empty")
+)
+
+func checkPort(p int) error {
+	if p < 0 || p > 65535 {
+		return errPort
+	}
+	return nil
+}
+
+func checkTimeout(d time.Duration) error {
+	if d <= 0 {
+		return errTimeout
+	}
+	return nil
+}
+
+func checkName(n string) error {
+	if n == "" {
+		return errName
+	}
+	return nil
+}
+
+// closure options pattern
+// Level: Good
+// pros: the usual form; any option can do anything to the settings.
+// cons: the settings escape to the heap through the indirect calls.
+
+type Option func(s *Settings) error
+
+func WithPort(p int) Option {
+	return func(s *Settings) error {
+		if err := checkPort(p); err != nil {
+			return err
+		}
+		s.Port = p
+		return nil
+	}
+}
+
+func WithTimeout(d time.Duration) Option {
+	return func(s *Settings) error {
+		if err := checkTimeout(d); err != nil {
+			return err
+		}
+		s.Timeout = d
+		return nil
+	}
+}
+
+func WithName(n string) Option {
+	return func(s *Settings) error {
+		if err := checkName(n); err != nil {
+			return err
+		}
+		s.Name = n
+		return nil
+	}
+}
+
+//go:noinline
+func New(opts ...Option) (Settings, error) {
+	s := defaults()
+	for _, opt := range opts {
+		if err := opt(&s); err != nil {
+			return Settings{}, err
+		}
+	}
+	return s, nil
+}
+
+// interface options pattern
+// Level: Average
+// pros: options are comparable, printable values, and other packages can
+// still add options if the interface is exported.
+// cons: boxing a value in an interface allocates for most values, and
+// the settings still escape through the interface call.
+
+type IfaceOption interface {
+	apply(s *Settings) error
+}
+
+type portOption int
+
+func (p portOption) apply(s *Settings) error {
+	if err := checkPort(int(p)); err != nil {
+		return err
+	}
+	s.Port = int(p)
+	return nil
+}
+
+type timeoutOption time.Duration
+
+func (d timeoutOption) apply(s *Settings) error {
+	if err := checkTimeout(time.Duration(d)); err != nil {
+		return err
+	}
+	s.Timeout = time.Duration(d)
+	return nil
+}
+
+type nameOption string
+
+func (n nameOption) apply(s *Settings) error {
+	if err := checkName(string(n)); err != nil {
+		return err
+	}
+	s.Name = string(n)
+	return nil
+}
+
+func IfacePort(p int) IfaceOption              { return portOption(p) }
+func IfaceTimeout(d time.Duration) IfaceOption { return timeoutOption(d) }
+func IfaceName(n string) IfaceOption           { return nameOption(n) }
+
+//go:noinline
+func NewIface(opts ...IfaceOption) (Settings, error) {
+	s := defaults()
+	for _, opt := range opts {
+		if err := opt.apply(&s); err != nil {
+			return Settings{}, err
+		}
+	}
+	return s, nil
+}
+
+// tagged options pattern
+// Level: Good
+// pros: same call sites as closure options (Port(8080)), no allocation,
+// comparable and printable.
+// cons: every option lives in one switch, so the set is closed to other
+// packages, and the Option struct carries a field per value type.
+
+type kind uint8
+
+const (
+	kindPort kind = iota + 1
+	kindTimeout
+	kindName
+)
+
+// Tagged is one option; only the field its kind names is set.
+type Tagged struct {
+	kind kind
+	i    int64
+	s    string
+}
+
+func Port(p int) Tagged              { return Tagged{kind: kindPort, i: int64(p)} }
+func Timeout(d time.Duration) Tagged { return Tagged{kind: kindTimeout, i: int64(d)} }
+func Name(n string) Tagged           { return Tagged{kind: kindName, s: n} }
+
+func (o Tagged) apply(s *Settings) error {
+	switch o.kind {
+	case kindPort:
+		if err := checkPort(int(o.i)); err != nil {
+			return err
+		}
+		s.Port = int(o.i)
+	case kindTimeout:
+		if err := checkTimeout(time.Duration(o.i)); err != nil {
+			return err
+		}
+		s.Timeout = time.Duration(o.i)
+	case kindName:
+		if err := checkName(o.s); err != nil {
+			return err
+		}
+		s.Name = o.s
+	default:
+		return errors.New("zero Tagged option")
+	}
+	return nil
+}
+
+//go:noinline
+func NewTagged(opts ...Tagged) (Settings, error) {
+	s := defaults()
+	for _, opt := range opts {
+		if err := opt.apply(&s); err != nil {
+			return Settings{}, err
+		}
+	}
+	return s, nil
+}
+
+// config literal pattern
+// Level: Average
+// pros: no allocation and nothing to apply; one struct copy.
+// cons: zero means "default", so a field cannot be set to its zero value
+// on purpose, and validation moves into the constructor.
+
+type Config struct {
+	Port    int
+	Timeout time.Duration
+	Name    string
+}
+
+//go:noinline
+func NewConfig(c Config) (Settings, error) {
+	s := defaults()
+	if c.Port != 0 {
+		if err := checkPort(c.Port); err != nil {
+			return Settings{}, err
+		}
+		s.Port = c.Port
+	}
+	if c.Timeout != 0 {
+		if err := checkTimeout(c.Timeout); err != nil {
+			return Settings{}, err
+		}
+		s.Timeout = c.Timeout
+	}
+	if c.Name != "" {
+		s.Name = c.Name
+	}
+	return s, nil
+}
@@ -0,0 +1,107 @@
+// Package arena allocates many small values of one type in chunks and
+// hands out index references instead of pointers:
+//
+//	type node struct {
+//		key  int
+//		next arena.Ref // link by Ref, not *node
+//	}
+//
+//	var a arena.Arena[node]
+//	r, n := a.Alloc()
+//	n.next = head
+//	head = r
+//	...
+//	a.Reset() // everything freed at once, chunks reused
+//
+// A chunk of 1024 values is one allocation instead of 1024, and because
+// values link by Ref (a uint32) rather than by pointer, the GC has
+// nothing to trace inside a chunk of pointer-free T.
+//
+// findings (see bench_test.go; go test -bench . -benchmem
+// patterns/perf/arena):
+//   - building a 10k node tree: per-node new allocates 10k times per
+//     build, the reused arena zero times after its first build.
+//   - the pointer trees start a GC cycle every ~30 builds; the arena
+//     starts none.
+//   - wall time is about equal or slightly better for the arena: the
+//     tree walk is dominated by cache misses either way, so the win is
+//     GC pressure, which grows with the live heap of the whole program.
+//   - the cost is lifetime: nothing in an arena can be freed alone, and
+//     a Ref must not outlive the Reset that invalidates it.
+package arena
+
+// Ref refers to a value in an Arena. The zero Ref is nil.
+type Ref uint32
+
+// DefaultChunk is the chunk size of a zero Arena.
+const DefaultChunk = 1024
+
+// Arena stores values of T in fixed-size chunks, so pointers returned by
+// Alloc and Get stay valid until Reset. The zero value is ready to use.
+type Arena[T any] struct {
+	// Chunk is the number of values per chunk, rounded up to a power of
+	// two; zero means DefaultChunk. It is read on the first Alloc.
+	Chunk int
+
+	chunks [][]T
+	n      int
+	shift  uint
+	mask   int
+}
+
+func (a *Arena[T]) init() {
+	size := a.Chunk
+	if size <= 0 {
+		size = DefaultChunk
+	}
+	for 1<<a.shift < size {
+		a.shift++
+	}
+	a.mask = 1<<a.shift - 1
+}
+
+// Alloc returns a zeroed value and its Ref.
+func (a *Arena[T]) Alloc() (Ref, *T) {
+	if a.mask == 0 {
+		a.init()
+	}
+	c, i := a.n>>a.shift, a.n&a.mask
+	if c == len(a.chunks) {
+		a.chunks = append(a.chunks, make([]T, a.mask+1))
+	}
+	a.n++
+	return Ref(a.n), &a.chunks[c][i]
+}
+
+// Get returns the value r refers to, or nil for the zero Ref.
+func (a *Arena[T]) Get(r Ref) *T {
+	if r == 0 {
+		return nil
+	}
+	i := int(r) - 1
+	if i >= a.n {
+		panic("arena: Ref out of range (used after Reset?)")
+	}
+	return &a.chunks[i>>a.shift][i&a.mask]
+}
+
+// Len reports how many values have been allocated since the last Reset.
+func (a *Arena[T]) Len() int { return a.n }
+
+// Reset frees every value at once. Chunks are zeroed and kept, so the
+// next round of Allocs does not allocate; every Ref and pointer from
+// before the Reset is invalid.
+func (a *Arena[T]) Reset() {
+	if a.n > 0 {
+		for c := 0; c <= (a.n-1)>>a.shift; c++ {
+			clear(a.chunks[c])
+		}
+	}
+	a.n = 0
+}
+
+// Release drops the chunks as well, returning the memory to the GC.
+func (a *Arena[T]) Release() {
+	a.chunks = nil
+	a.n = 0
+}
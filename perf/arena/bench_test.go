@@ -0,0 +1,133 @@
+package arena
+
+import (
+	"runtime"
+	"strconv"
+	"testing"
+)
+
+// pointer tree: one allocation per node
+
+type ptrNode struct {
+	key         int
+	left, right *ptrNode
+}
+
+func insertPtr(root *ptrNode, key int) *ptrNode {
+	if root == nil {
+		return &ptrNode{key: key}
+	}
+	n := root
+	for {
+		next := &n.left
+		if key > n.key {
+			next = &n.right
+		}
+		if *next == nil {
+			*next = &ptrNode{key: key}
+			return root
+		}
+		n = *next
+	}
+}
+
+func sumPtr(n *ptrNode) int {
+	if n == nil {
+		return 0
+	}
+	return n.key + sumPtr(n.left) + sumPtr(n.right)
+}
+
+// arena tree: nodes link by Ref
+
+type arenaNode struct {
+	key         int
+	left, right Ref
+}
+
+func insertArena(a *Arena[arenaNode], root Ref, key int) Ref {
+	if root == 0 {
+		r, n := a.Alloc()
+		n.key = key
+		return r
+	}
+	n := a.Get(root)
+	for {
+		next := &n.left
+		if key > n.key {
+			next = &n.right
+		}
+		if *next == 0 {
+			r, child := a.Alloc()
+			child.key = key
+			*next = r
+			return root
+		}
+		n = a.Get(*next)
+	}
+}
+
+func sumArena(a *Arena[arenaNode], r Ref) int {
+	if r == 0 {
+		return 0
+	}
+	n := a.Get(r)
+	return n.key + sumArena(a, n.left) + sumArena(a, n.right)
+}
+
+// keys is a fixed pseudo-random permutation so the trees stay balanced-ish.
+func keys(n int) []int {
+	ks := make([]int, n)
+	x := uint32(1)
+	for i := range ks {
+		x ^= x << 13
+		x ^= x >> 17
+		x ^= x << 5
+		ks[i] = int(x % 1_000_000)
+	}
+	return ks
+}
+
+var sink int
+
+// reportGC adds a gc/op metric: collections started during the benchmark.
+func reportGC(b *testing.B, f func()) {
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	f()
+	runtime.ReadMemStats(&after)
+	b.ReportMetric(float64(after.NumGC-before.NumGC)/float64(b.N), "gc/op")
+}
+
+// BenchmarkTree builds and sums a binary search tree with per-node
+// allocation and with a reused arena.
+func BenchmarkTree(b *testing.B) {
+	for _, size := range []int{1_000, 10_000} {
+		ks := keys(size)
+		suffix := "/nodes=" + strconv.Itoa(size)
+		b.Run("new"+suffix, func(b *testing.B) {
+			reportGC(b, func() {
+				for range b.N {
+					var root *ptrNode
+					for _, k := range ks {
+						root = insertPtr(root, k)
+					}
+					sink = sumPtr(root)
+				}
+			})
+		})
+		b.Run("arena"+suffix, func(b *testing.B) {
+			var a Arena[arenaNode]
+			reportGC(b, func() {
+				for range b.N {
+					a.Reset()
+					var root Ref
+					for _, k := range ks {
+						root = insertArena(&a, root, k)
+					}
+					sink = sumArena(&a, root)
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package bufpool
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"testing"
+)
+
+type item struct {
+	ID    int      `json:"id"`
+	Name  string   `json:"name"`
+	Tags  []string `json:"tags"`
+	Price float64  `json:"price"`
+}
+
+var doc = func() []item {
+	items := make([]item, 20)
+	for i := range items {
+		items[i] = item{ID: i, Name: "item", Tags: []string{"a", "b"}, Price: float64(i) * 1.5}
+	}
+	return items
+}()
+
+// Each benchmark renders the same JSON document one of the three usual
+// ways and writes it to io.Discard, standing in for an
+// http.ResponseWriter.
+
+func BenchmarkMarshal(b *testing.B) {
+	for range b.N {
+		out, err := json.Marshal(doc)
+		if err != nil {
+			b.Fatal(err)
+		}
+		io.Discard.Write(out)
+	}
+}
+
+func BenchmarkNewBuffer(b *testing.B) {
+	for range b.N {
+		var buf bytes.Buffer
+		if err := json.NewEncoder(&buf).Encode(doc); err != nil {
+			b.Fatal(err)
+		}
+		io.Discard.Write(buf.Bytes())
+	}
+}
+
+func BenchmarkPooled(b *testing.B) {
+	var p Pool
+	for range b.N {
+		p.WithBuffer(func(buf *bytes.Buffer) {
+			if err := json.NewEncoder(buf).Encode(doc); err != nil {
+				b.Fatal(err)
+			}
+			io.Discard.Write(buf.Bytes())
+		})
+	}
+}
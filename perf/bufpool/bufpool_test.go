@@ -0,0 +1,82 @@
+package bufpool
+
+import (
+	"bytes"
+	"runtime"
+	"testing"
+)
+
+// TestOversizedNotPooled checks that a buffer grown past MaxCap is
+// dropped and never handed out again, while smaller ones are kept.
+func TestOversizedNotPooled(t *testing.T) {
+	for _, c := range []struct {
+		maxCap, grow int
+		pooled       bool
+	}{
+		{0, DefaultMaxCap / 2, true},
+		{0, DefaultMaxCap + 1, false},
+		{1024, 512, true},
+		{1024, 4096, false},
+	} {
+		p := &Pool{MaxCap: c.maxCap}
+		b := p.Get()
+		b.Grow(c.grow)
+		if got := p.Put(b); got != c.pooled {
+			t.Errorf("MaxCap %d, cap %d: Put = %v, want %v", c.maxCap, b.Cap(), got, c.pooled)
+		}
+		var want int64
+		if !c.pooled {
+			want = 1
+		}
+		if got := p.Stats().Dropped; got != want {
+			t.Errorf("MaxCap %d, cap %d: Dropped = %d, want %d", c.maxCap, b.Cap(), got, want)
+		}
+		if c.pooled {
+			continue
+		}
+		for range 100 {
+			if p.Get() == b {
+				t.Fatalf("MaxCap %d, cap %d: Get returned the dropped buffer", c.maxCap, b.Cap())
+			}
+		}
+	}
+}
+
+// TestReusedEmpty checks that a pooled buffer comes back reset with its
+// capacity. sync.Pool may drop any buffer, and does so at random under
+// the race detector, so the test tries a few times.
+func TestReusedEmpty(t *testing.T) {
+	var p Pool
+	for range 100 {
+		b := p.Get()
+		b.WriteString("left over")
+		capacity := b.Cap()
+		p.Put(b)
+		if got := p.Get(); got == b {
+			if got.Len() != 0 || got.Cap() != capacity {
+				t.Fatalf("reused buffer: len %d, cap %d; want 0, %d", got.Len(), got.Cap(), capacity)
+			}
+			return
+		}
+		runtime.Gosched()
+	}
+	t.Skip("the pool never returned a buffer; nothing to check")
+}
+
+func TestString(t *testing.T) {
+	var p Pool
+	s := p.String(func(b *bytes.Buffer) { b.WriteString("hello") })
+	// the next render reuses the memory; the string must not change
+	p.String(func(b *bytes.Buffer) { b.WriteString("HELLO") })
+	if s != "hello" {
+		t.Errorf("String = %q after another render, want %q", s, "hello")
+	}
+}
+
+func TestWithBufferReturns(t *testing.T) {
+	p := &Pool{MaxCap: 16}
+	p.WithBuffer(func(b *bytes.Buffer) { b.Write(make([]byte, 100)) })
+	if got := p.Stats(); got.New != 1 || got.Dropped != 1 {
+		t.Errorf("Stats = %+v, want one created and one dropped", got)
+	}
+}
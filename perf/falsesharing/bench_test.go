@@ -0,0 +1,77 @@
+package falsesharing
+
+import (
+	"sync/atomic"
+	"testing"
+)
+
+// counter is what each counter benchmark adds to.
+type counter interface {
+	Add(slot int, n int64)
+	Sum() int64
+}
+
+// shared is one counter for every writer: real sharing, for comparison.
+type shared struct{ n atomic.Int64 }
+
+func (c *shared) Add(_ int, n int64) { c.n.Add(n) }
+func (c *shared) Sum() int64         { return c.n.Load() }
+
+// benchCounter has every parallel goroutine add to its own slot of a
+// new counter.
+func benchCounter(newCounter func() counter) func(b *testing.B) {
+	return func(b *testing.B) {
+		c := newCounter()
+		var writers atomic.Int64
+		b.RunParallel(func(pb *testing.PB) {
+			slot := int(writers.Add(1) - 1)
+			for pb.Next() {
+				c.Add(slot, 1)
+			}
+		})
+		if c.Sum() != int64(b.N) {
+			b.Fatalf("Sum = %d, want %d", c.Sum(), b.N)
+		}
+	}
+}
+
+var sink int64
+
+// records is big enough to outgrow the caches: 32MB of Loose.
+const records = 1 << 20
+
+// BenchmarkCounter compares counters that share a cache line with padded
+// ones and one shared counter.
+func BenchmarkCounter(b *testing.B) {
+	b.Run("shared", benchCounter(func() counter { return &shared{} }))
+	b.Run("unpadded", benchCounter(func() counter { return &Unpadded{} }))
+	b.Run("padded", benchCounter(func() counter { return &Padded{} }))
+}
+
+// BenchmarkLayout sums a field over slices of Loose and Tight.
+func BenchmarkLayout(b *testing.B) {
+	b.Run("loose", func(b *testing.B) {
+		rs := make([]Loose, records)
+		b.SetBytes(int64(len(rs)) * int64(Layout[Loose]().Size))
+		b.ResetTimer()
+		for range b.N {
+			var s int64
+			for i := range rs {
+				s += int64(rs[i].Count)
+			}
+			sink = s
+		}
+	})
+	b.Run("tight", func(b *testing.B) {
+		rs := make([]Tight, records)
+		b.SetBytes(int64(len(rs)) * int64(Layout[Tight]().Size))
+		b.ResetTimer()
+		for range b.N {
+			var s int64
+			for i := range rs {
+				s += int64(rs[i].Count)
+			}
+			sink = s
+		}
+	})
+}
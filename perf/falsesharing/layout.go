@@ -0,0 +1,125 @@
+package falsesharing
+
+import (
+	"cmp"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// Loose is a record with its fields in the order they came to mind.
+// Each bool before a wider field is padded out to that field's
+// alignment, and the last to the struct's: 32 bytes for 15 of data.
+type Loose struct {
+	Active  bool  // offset 0, then 7 bytes of padding
+	ID      int64 // 8
+	Deleted bool  // 16, then 3
+	Count   int32 // 20
+	Admin   bool  // 24, then 7
+}
+
+// Tight is Loose with its fields ordered by decreasing alignment, the
+// order that never needs padding between fields: 16 bytes.
+type Tight struct {
+	ID      int64 // offset 0
+	Count   int32 // 8
+	Active  bool  // 12
+	Deleted bool  // 13
+	Admin   bool  // 14, then 1
+}
+
+// Field is where one field of a struct is.
+type Field struct {
+	Name                string
+	Offset, Size, Align uintptr
+	// Padding is the unused bytes between the field and the next, or the
+	// end of the struct.
+	Padding uintptr
+}
+
+// Report is the memory layout of a struct type: what unsafe.Sizeof,
+// unsafe.Alignof and unsafe.Offsetof say about it, field by field,
+// without naming each field in code.
+type Report struct {
+	Type        string
+	Size, Align uintptr
+	Fields      []Field
+}
+
+// Layout reports the layout of T, which must be a struct type.
+func Layout[T any]() Report {
+	t := reflect.TypeFor[T]()
+	if t.Kind() != reflect.Struct {
+		panic("falsesharing: Layout of non-struct type " + t.String())
+	}
+	r := Report{Type: t.String(), Size: t.Size(), Align: uintptr(t.Align())}
+	for i := range t.NumField() {
+		f := t.Field(i)
+		r.Fields = append(r.Fields, Field{
+			Name:   f.Name,
+			Offset: f.Offset,
+			Size:   f.Type.Size(),
+			Align:  uintptr(f.Type.Align()),
+		})
+	}
+	for i := range r.Fields {
+		end := r.Size
+		if i+1 < len(r.Fields) {
+			end = r.Fields[i+1].Offset
+		}
+		r.Fields[i].Padding = end - r.Fields[i].Offset - r.Fields[i].Size
+	}
+	return r
+}
+
+// Padding returns the unused bytes of the struct, between fields and at
+// its end.
+func (r Report) Padding() uintptr {
+	var p uintptr
+	for _, f := range r.Fields {
+		p += f.Padding
+	}
+	if len(r.Fields) == 0 {
+		p = r.Size
+	}
+	return p
+}
+
+// MinSize returns the size of the struct with its fields ordered by
+// decreasing alignment, the smallest any order gives.
+func (r Report) MinSize() uintptr {
+	fs := slices.Clone(r.Fields)
+	slices.SortStableFunc(fs, func(a, b Field) int { return cmp.Compare(b.Align, a.Align) })
+	var off uintptr
+	for _, f := range fs {
+		off = alignUp(off, f.Align) + f.Size
+	}
+	// a zero-size final field is padded so a pointer to it stays inside
+	if n := len(fs); n > 0 && fs[n-1].Size == 0 && off > 0 {
+		off++
+	}
+	return alignUp(off, max(r.Align, 1))
+}
+
+func alignUp(n, align uintptr) uintptr {
+	return (n + align - 1) / align * align
+}
+
+// String formats the report as a table, one line per field and one per
+// run of padding.
+func (r Report) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: size %d, align %d, padding %d", r.Type, r.Size, r.Align, r.Padding())
+	if min := r.MinSize(); min < r.Size {
+		fmt.Fprintf(&b, ", %d if reordered", min)
+	}
+	fmt.Fprintf(&b, "\n%6s %5s %5s  %s\n", "offset", "size", "align", "field")
+	for _, f := range r.Fields {
+		fmt.Fprintf(&b, "%6d %5d %5d  %s\n", f.Offset, f.Size, f.Align, f.Name)
+		if f.Padding > 0 {
+			fmt.Fprintf(&b, "%6d %5d %5s  (padding)\n", f.Offset+f.Size, f.Padding, "")
+		}
+	}
+	return b.String()
+}
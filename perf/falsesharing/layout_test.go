@@ -0,0 +1,88 @@
+package falsesharing
+
+import (
+	"sync"
+	"testing"
+	"unsafe"
+)
+
+// TestLayoutMatchesUnsafe checks Layout against unsafe on every platform.
+func TestLayoutMatchesUnsafe(t *testing.T) {
+	var l Loose
+	r := Layout[Loose]()
+	if r.Size != unsafe.Sizeof(l) || r.Align != unsafe.Alignof(l) {
+		t.Errorf("size %d, align %d; unsafe says %d, %d", r.Size, r.Align, unsafe.Sizeof(l), unsafe.Alignof(l))
+	}
+	for i, want := range []uintptr{
+		unsafe.Offsetof(l.Active), unsafe.Offsetof(l.ID), unsafe.Offsetof(l.Deleted),
+		unsafe.Offsetof(l.Count), unsafe.Offsetof(l.Admin),
+	} {
+		if r.Fields[i].Offset != want {
+			t.Errorf("%s: offset %d, unsafe says %d", r.Fields[i].Name, r.Fields[i].Offset, want)
+		}
+	}
+	// every byte is a field or padding
+	var used uintptr
+	for _, f := range r.Fields {
+		used += f.Size
+	}
+	if used+r.Padding() != r.Size {
+		t.Errorf("fields %d + padding %d != size %d", used, r.Padding(), r.Size)
+	}
+}
+
+// TestMinSize checks MinSize on the orders that need care: Tight is
+// Loose reordered, an empty struct is all padding, and a zero-size final
+// field costs a byte.
+func TestMinSize(t *testing.T) {
+	if got, want := Layout[Loose]().MinSize(), Layout[Tight]().Size; got != want {
+		t.Errorf("Loose.MinSize() = %d, want Tight's size %d", got, want)
+	}
+	type empty struct{}
+	if r := Layout[empty](); r.Size != 0 || r.Padding() != 0 || r.MinSize() != 0 {
+		t.Errorf("empty: size %d, padding %d, min size %d; want 0", r.Size, r.Padding(), r.MinSize())
+	}
+	type zeroLast struct {
+		n int32
+		_ struct{}
+	}
+	if r := Layout[zeroLast](); r.MinSize() != r.Size {
+		t.Errorf("zeroLast: MinSize() = %d, size %d", r.MinSize(), r.Size)
+	}
+	type lockFirst struct {
+		flag bool
+		mu   sync.Mutex
+		ok   bool
+	}
+	if r := Layout[lockFirst](); r.MinSize() >= r.Size {
+		t.Errorf("lockFirst: MinSize() = %d, want less than size %d", r.MinSize(), r.Size)
+	}
+}
+
+func TestLayoutNonStruct(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Layout[int]() did not panic")
+		}
+	}()
+	Layout[int]()
+}
+
+func TestCounters(t *testing.T) {
+	for _, c := range []counter{&Unpadded{}, &Padded{}} {
+		var wg sync.WaitGroup
+		for slot := range 2 * Slots {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for range 1000 {
+					c.Add(slot, 1)
+				}
+			}()
+		}
+		wg.Wait()
+		if got := c.Sum(); got != 2*Slots*1000 {
+			t.Errorf("%T: Sum = %d, want %d", c, got, 2*Slots*1000)
+		}
+	}
+}
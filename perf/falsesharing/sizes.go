@@ -0,0 +1,23 @@
+//go:build amd64 || arm64
+
+package falsesharing
+
+import "unsafe"
+
+// The sizes the comments claim, checked when the package compiles: a
+// size off by any amount makes one of each pair of differences negative,
+// which overflows uintptr and fails the build. They hold where int64 is
+// 8-byte aligned, which is not so on every 32-bit platform.
+const (
+	_ = uintptr(32 - unsafe.Sizeof(Loose{}))
+	_ = uintptr(unsafe.Sizeof(Loose{}) - 32)
+	_ = uintptr(16 - unsafe.Sizeof(Tight{}))
+	_ = uintptr(unsafe.Sizeof(Tight{}) - 16)
+	_ = uintptr(unsafe.Offsetof(Loose{}.Count) - 20)
+	_ = uintptr(20 - unsafe.Offsetof(Loose{}.Count))
+
+	_ = uintptr(CacheLine - unsafe.Sizeof(Unpadded{}))
+	_ = uintptr(unsafe.Sizeof(Unpadded{}) - CacheLine)
+	_ = uintptr(CacheLine - unsafe.Sizeof(paddedSlot{}))
+	_ = uintptr(unsafe.Sizeof(paddedSlot{}) - CacheLine)
+)
@@ -0,0 +1,58 @@
+//go:build amd64 || arm64
+
+package falsesharing
+
+import "testing"
+
+// TestSizes checks the layouts the doc comments describe, field by field.
+func TestSizes(t *testing.T) {
+	for _, c := range []struct {
+		r             Report
+		size, padding uintptr
+		minSize       uintptr
+		offsets       []uintptr
+	}{
+		{Layout[Loose](), 32, 17, 16, []uintptr{0, 8, 16, 20, 24}},
+		{Layout[Tight](), 16, 1, 16, []uintptr{0, 8, 12, 13, 14}},
+		{Layout[Unpadded](), CacheLine, 0, CacheLine, []uintptr{0}},
+		{Layout[Padded](), Slots * CacheLine, 0, Slots * CacheLine, []uintptr{0}},
+		{Layout[paddedSlot](), CacheLine, 0, CacheLine, []uintptr{0, 8}},
+	} {
+		r := c.r
+		if r.Size != c.size || r.Padding() != c.padding || r.MinSize() != c.minSize {
+			t.Errorf("%s: size %d, padding %d, min size %d; want %d, %d, %d",
+				r.Type, r.Size, r.Padding(), r.MinSize(), c.size, c.padding, c.minSize)
+		}
+		var offsets []uintptr
+		for _, f := range r.Fields {
+			offsets = append(offsets, f.Offset)
+		}
+		if len(offsets) != len(c.offsets) {
+			t.Errorf("%s: offsets %v, want %v", r.Type, offsets, c.offsets)
+			continue
+		}
+		for i := range offsets {
+			if offsets[i] != c.offsets[i] {
+				t.Errorf("%s: offsets %v, want %v", r.Type, offsets, c.offsets)
+				break
+			}
+		}
+	}
+}
+
+func TestReportString(t *testing.T) {
+	want := `falsesharing.Loose: size 32, align 8, padding 17, 16 if reordered
+offset  size align  field
+     0     1     1  Active
+     1     7        (padding)
+     8     8     8  ID
+    16     1     1  Deleted
+    17     3        (padding)
+    20     4     4  Count
+    24     1     1  Admin
+    25     7        (padding)
+`
+	if got := Layout[Loose]().String(); got != want {
+		t.Errorf("Layout[Loose]().String() =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,37 @@
+package repository_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"patterns/persistence/repository"
+	"patterns/testing/contracts"
+)
+
+func TestMemoryContract(t *testing.T) {
+	contracts.RunRepositoryContract(t, func(testing.TB) repository.Repository[string, string] {
+		return repository.NewMemory[string, string]()
+	})
+}
+
+func TestFileContract(t *testing.T) {
+	contracts.RunRepositoryContract(t, func(t testing.TB) repository.Repository[string, string] {
+		r, err := repository.OpenFile[string, string](filepath.Join(t.TempDir(), "repo.json"))
+		if err != nil {
+			t.Fatalf("OpenFile: %v", err)
+		}
+		return r
+	})
+}
+
+func TestMemoryUsersContract(t *testing.T) {
+	contracts.RunUsersContract(t, func(testing.TB) repository.UserStore {
+		return repository.NewMemoryUsers()
+	})
+}
+
+func TestSQLUsersContract(t *testing.T) {
+	contracts.RunUsersContract(t, func(t testing.TB) repository.UserStore {
+		return openUsersDB(t)
+	})
+}
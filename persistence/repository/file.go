@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"maps"
+	"os"
+	"sync"
+)
+
+// File is a Repository persisted as one JSON object, rewritten atomically
+// on every change. It stands in for a database where only the standard
+// library is available; K must be a string or integer type so it can be a
+// JSON object key.
+type File[K comparable, V any] struct {
+	mu   sync.Mutex
+	path string
+	mem  *Memory[K, V]
+}
+
+// OpenFile loads path, or starts empty if it does not exist.
+func OpenFile[K comparable, V any](path string) (*File[K, V], error) {
+	f := &File[K, V]{path: path, mem: NewMemory[K, V]()}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return f, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &f.mem.items); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *File[K, V]) Get(ctx context.Context, key K) (V, error) {
+	return f.mem.Get(ctx, key)
+}
+
+func (f *File[K, V]) List(ctx context.Context) ([]V, error) {
+	return f.mem.List(ctx)
+}
+
+func (f *File[K, V]) Create(ctx context.Context, key K, value V) error {
+	return f.write(func() error { return f.mem.Create(ctx, key, value) })
+}
+
+func (f *File[K, V]) Update(ctx context.Context, key K, value V) error {
+	return f.write(func() error { return f.mem.Update(ctx, key, value) })
+}
+
+func (f *File[K, V]) Delete(ctx context.Context, key K) error {
+	return f.write(func() error { return f.mem.Delete(ctx, key) })
+}
+
+// write applies change in memory and persists the result; on a failed
+// save the in-memory state is rolled back.
+func (f *File[K, V]) write(change func() error) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.mem.mu.RLock()
+	before := maps.Clone(f.mem.items)
+	f.mem.mu.RUnlock()
+
+	if err := change(); err != nil {
+		return err
+	}
+	if err := f.save(); err != nil {
+		f.mem.mu.Lock()
+		f.mem.items = before
+		f.mem.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+func (f *File[K, V]) save() error {
+	f.mem.mu.RLock()
+	b, err := json.Marshal(f.mem.items)
+	f.mem.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+	tmp := f.path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, f.path)
+}
@@ -0,0 +1,85 @@
+// Package repository hides storage behind a collection-like interface so
+// that business code does not know whether entities live in memory, a
+// file or a database.
+package repository
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"sync"
+)
+
+var (
+	ErrNotFound = errors.New("not found")
+	ErrExists   = errors.New("already exists")
+)
+
+// Repository stores values of type V under keys of type K.
+type Repository[K comparable, V any] interface {
+	Get(ctx context.Context, key K) (V, error)
+	// Create fails with ErrExists if key is taken.
+	Create(ctx context.Context, key K, value V) error
+	// Update fails with ErrNotFound if key is absent.
+	Update(ctx context.Context, key K, value V) error
+	Delete(ctx context.Context, key K) error
+	// List returns every value in no particular order.
+	List(ctx context.Context) ([]V, error)
+}
+
+// Memory is an in-memory Repository; safe for concurrent use.
+type Memory[K comparable, V any] struct {
+	mu    sync.RWMutex
+	items map[K]V
+}
+
+func NewMemory[K comparable, V any]() *Memory[K, V] {
+	return &Memory[K, V]{items: map[K]V{}}
+}
+
+func (m *Memory[K, V]) Get(ctx context.Context, key K) (V, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	v, ok := m.items[key]
+	if !ok {
+		return v, ErrNotFound
+	}
+	return v, nil
+}
+
+func (m *Memory[K, V]) Create(ctx context.Context, key K, value V) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.items[key]; ok {
+		return ErrExists
+	}
+	m.items[key] = value
+	return nil
+}
+
+func (m *Memory[K, V]) Update(ctx context.Context, key K, value V) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.items[key]; !ok {
+		return ErrNotFound
+	}
+	m.items[key] = value
+	return nil
+}
+
+func (m *Memory[K, V]) List(ctx context.Context) ([]V, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return slices.Collect(maps.Values(m.items)), nil
+}
+
+func (m *Memory[K, V]) Delete(ctx context.Context, key K) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.items[key]; !ok {
+		return ErrNotFound
+	}
+	delete(m.items, key)
+	return nil
+}
@@ -0,0 +1,159 @@
+package repository
+
+import (
+	"context"
+	"maps"
+	"sync"
+)
+
+// User is the entity of UserRepository; Email is unique across users.
+type User struct {
+	ID    string
+	Email string
+	Name  string
+}
+
+// UserRepository is a repository shaped by its entity rather than generic
+// over it: it can look users up by a field and keep a uniqueness rule,
+// which Repository[K, V] cannot express.
+type UserRepository interface {
+	// Get and ByEmail fail with ErrNotFound.
+	Get(ctx context.Context, id string) (User, error)
+	ByEmail(ctx context.Context, email string) (User, error)
+	// Create fails with ErrExists if the id or the email is taken.
+	Create(ctx context.Context, u User) error
+	// Update fails with ErrNotFound for an unknown id and ErrExists if
+	// the new email is another user's.
+	Update(ctx context.Context, u User) error
+	// Delete fails with ErrNotFound.
+	Delete(ctx context.Context, id string) error
+}
+
+// transaction-scoped repository
+// Level: Good
+// pros: business code that must change several things atomically asks
+// for a repository bound to a transaction and uses it like any other; it
+// never sees a *sql.Tx, commit or rollback, and the same code runs on the
+// in-memory store in tests.
+// cons: everything inside fn must use the repository it is given: calls
+// on the outer one run outside the transaction, and on MemoryUsers they
+// deadlock.
+//
+// UserStore is a UserRepository that can also run a unit of work.
+type UserStore interface {
+	UserRepository
+	// WithTx calls fn with a repository whose changes all take effect
+	// if fn returns nil, and none of them if it returns an error or
+	// panics; fn's error is returned as is.
+	WithTx(ctx context.Context, fn func(users UserRepository) error) error
+}
+
+// MemoryUsers is a UserStore in memory, safe for concurrent use. A
+// transaction holds the store for its duration, so transactions are
+// serial, and works on a copy that replaces the store's on success.
+type MemoryUsers struct {
+	mu    sync.Mutex
+	users usersByID
+}
+
+func NewMemoryUsers() *MemoryUsers {
+	return &MemoryUsers{users: usersByID{}}
+}
+
+func (m *MemoryUsers) Get(ctx context.Context, id string) (User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.users.Get(ctx, id)
+}
+
+func (m *MemoryUsers) ByEmail(ctx context.Context, email string) (User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.users.ByEmail(ctx, email)
+}
+
+func (m *MemoryUsers) Create(ctx context.Context, u User) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.users.Create(ctx, u)
+}
+
+func (m *MemoryUsers) Update(ctx context.Context, u User) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.users.Update(ctx, u)
+}
+
+func (m *MemoryUsers) Delete(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.users.Delete(ctx, id)
+}
+
+func (m *MemoryUsers) WithTx(ctx context.Context, fn func(users UserRepository) error) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	staged := maps.Clone(m.users)
+	if err := fn(staged); err != nil {
+		return err
+	}
+	m.users = staged
+	return nil
+}
+
+// usersByID is the unlocked UserRepository MemoryUsers and its
+// transactions share.
+type usersByID map[string]User
+
+func (s usersByID) Get(ctx context.Context, id string) (User, error) {
+	u, ok := s[id]
+	if !ok {
+		return User{}, ErrNotFound
+	}
+	return u, nil
+}
+
+func (s usersByID) ByEmail(ctx context.Context, email string) (User, error) {
+	for _, u := range s {
+		if u.Email == email {
+			return u, nil
+		}
+	}
+	return User{}, ErrNotFound
+}
+
+func (s usersByID) Create(ctx context.Context, u User) error {
+	if _, ok := s[u.ID]; ok {
+		return ErrExists
+	}
+	if s.emailTaken(u) {
+		return ErrExists
+	}
+	s[u.ID] = u
+	return nil
+}
+
+func (s usersByID) Update(ctx context.Context, u User) error {
+	if _, ok := s[u.ID]; !ok {
+		return ErrNotFound
+	}
+	if s.emailTaken(u) {
+		return ErrExists
+	}
+	s[u.ID] = u
+	return nil
+}
+
+func (s usersByID) Delete(ctx context.Context, id string) error {
+	if _, ok := s[id]; !ok {
+		return ErrNotFound
+	}
+	delete(s, id)
+	return nil
+}
+
+// emailTaken reports whether another user than u has u's email.
+func (s usersByID) emailTaken(u User) bool {
+	other, err := s.ByEmail(context.Background(), u.Email)
+	return err == nil && other.ID != u.ID
+}
@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// UsersSchema is the table SQLUsers expects.
+const UsersSchema = `CREATE TABLE users (
+	id    TEXT PRIMARY KEY,
+	email TEXT NOT NULL UNIQUE,
+	name  TEXT NOT NULL
+)`
+
+// SQLUsers is a UserStore over database/sql. Its queries use ? for
+// parameters, as SQLite and MySQL do; drivers report a broken UNIQUE
+// constraint each in their own way, so the caller says how to recognise
+// one. Update reads RowsAffected as rows matched, which MySQL only
+// reports with clientFoundRows set.
+type SQLUsers struct {
+	db *sql.DB
+	sqlUsers
+}
+
+var _ UserStore = (*SQLUsers)(nil)
+
+// NewSQLUsers returns a store on db, whose users table is UsersSchema.
+// isConflict reports whether a driver error is a unique violation, which
+// Create and Update return as ErrExists.
+func NewSQLUsers(db *sql.DB, isConflict func(error) bool) *SQLUsers {
+	return &SQLUsers{db: db, sqlUsers: sqlUsers{q: db, isConflict: isConflict}}
+}
+
+func (s *SQLUsers) WithTx(ctx context.Context, fn func(users UserRepository) error) (err error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+	if err := fn(sqlUsers{q: tx, isConflict: s.isConflict}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// querier is what *sql.DB and *sql.Tx have in common, so the same
+// queries run inside and outside a transaction.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+type sqlUsers struct {
+	q          querier
+	isConflict func(error) bool
+}
+
+func (s sqlUsers) Get(ctx context.Context, id string) (User, error) {
+	return s.one(ctx, `SELECT id, email, name FROM users WHERE id = ?`, id)
+}
+
+func (s sqlUsers) ByEmail(ctx context.Context, email string) (User, error) {
+	return s.one(ctx, `SELECT id, email, name FROM users WHERE email = ?`, email)
+}
+
+func (s sqlUsers) one(ctx context.Context, query string, arg string) (User, error) {
+	var u User
+	err := s.q.QueryRowContext(ctx, query, arg).Scan(&u.ID, &u.Email, &u.Name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return User{}, ErrNotFound
+	}
+	if err != nil {
+		return User{}, fmt.Errorf("users: %w", err)
+	}
+	return u, nil
+}
+
+func (s sqlUsers) Create(ctx context.Context, u User) error {
+	_, err := s.q.ExecContext(ctx, `INSERT INTO users (id, email, name) VALUES (?, ?, ?)`, u.ID, u.Email, u.Name)
+	return s.classify(err)
+}
+
+func (s sqlUsers) Update(ctx context.Context, u User) error {
+	res, err := s.q.ExecContext(ctx, `UPDATE users SET email = ?, name = ? WHERE id = ?`, u.Email, u.Name, u.ID)
+	return s.affected(res, err)
+}
+
+func (s sqlUsers) Delete(ctx context.Context, id string) error {
+	res, err := s.q.ExecContext(ctx, `DELETE FROM users WHERE id = ?`, id)
+	return s.affected(res, err)
+}
+
+// affected returns ErrNotFound if a statement changed no row.
+func (s sqlUsers) affected(res sql.Result, err error) error {
+	if err != nil {
+		return s.classify(err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("users: %w", err)
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s sqlUsers) classify(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case s.isConflict != nil && s.isConflict(err):
+		return ErrExists
+	}
+	return fmt.Errorf("users: %w", err)
+}
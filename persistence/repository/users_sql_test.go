@@ -0,0 +1,189 @@
+package repository_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"sync"
+	"testing"
+
+	"patterns/persistence/repository"
+)
+
+// usersDB is a database/sql driver for the users table and exactly the
+// statements SQLUsers sends, so SQLUsers is tested through database/sql
+// without a real database. Transactions work on a copy of the table that
+// replaces it on commit; that is enough isolation for the contract,
+// which never writes outside a transaction while one is open.
+type usersDB struct {
+	mu    sync.Mutex
+	users map[string]repository.User
+}
+
+// errUnique is the driver's unique violation, for NewSQLUsers'
+// isConflict.
+var errUnique = errors.New("UNIQUE constraint failed")
+
+func openUsersDB(t testing.TB) *repository.SQLUsers {
+	db := sql.OpenDB(&usersDB{})
+	t.Cleanup(func() { db.Close() })
+	if _, err := db.Exec(repository.UsersSchema); err != nil {
+		t.Fatalf("create schema: %v", err)
+	}
+	return repository.NewSQLUsers(db, func(err error) bool { return errors.Is(err, errUnique) })
+}
+
+func (db *usersDB) Connect(context.Context) (driver.Conn, error) { return &usersConn{db: db}, nil }
+func (db *usersDB) Driver() driver.Driver                        { return usersDriver{} }
+
+type usersDriver struct{}
+
+func (usersDriver) Open(string) (driver.Conn, error) {
+	return nil, errors.New("usersDB: open through its Connector")
+}
+
+type usersConn struct {
+	db *usersDB
+	tx map[string]repository.User // nil outside a transaction
+}
+
+func (c *usersConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("usersDB: prepared statements are not supported")
+}
+
+func (c *usersConn) Close() error { return nil }
+
+func (c *usersConn) Begin() (driver.Tx, error) {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	c.tx = maps.Clone(c.db.users)
+	return usersTx{c}, nil
+}
+
+type usersTx struct{ c *usersConn }
+
+func (tx usersTx) Commit() error {
+	tx.c.db.mu.Lock()
+	defer tx.c.db.mu.Unlock()
+	tx.c.db.users, tx.c.tx = tx.c.tx, nil
+	return nil
+}
+
+func (tx usersTx) Rollback() error {
+	tx.c.tx = nil
+	return nil
+}
+
+// table returns the rows statements on c see. c.db.mu must be held.
+func (c *usersConn) table() map[string]repository.User {
+	if c.tx != nil {
+		return c.tx
+	}
+	return c.db.users
+}
+
+func stringArgs(args []driver.NamedValue) []string {
+	s := make([]string, len(args))
+	for i, a := range args {
+		s[i], _ = a.Value.(string)
+	}
+	return s
+}
+
+func (c *usersConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	if query == repository.UsersSchema {
+		c.db.users = map[string]repository.User{}
+		return driver.RowsAffected(0), nil
+	}
+	users, a := c.table(), stringArgs(args)
+	emailTaken := func(email, id string) bool {
+		for _, u := range users {
+			if u.Email == email && u.ID != id {
+				return true
+			}
+		}
+		return false
+	}
+	switch query {
+	case `INSERT INTO users (id, email, name) VALUES (?, ?, ?)`:
+		if _, ok := users[a[0]]; ok || emailTaken(a[1], a[0]) {
+			return nil, errUnique
+		}
+		users[a[0]] = repository.User{ID: a[0], Email: a[1], Name: a[2]}
+		return driver.RowsAffected(1), nil
+	case `UPDATE users SET email = ?, name = ? WHERE id = ?`:
+		if _, ok := users[a[2]]; !ok {
+			return driver.RowsAffected(0), nil
+		}
+		if emailTaken(a[0], a[2]) {
+			return nil, errUnique
+		}
+		users[a[2]] = repository.User{ID: a[2], Email: a[0], Name: a[1]}
+		return driver.RowsAffected(1), nil
+	case `DELETE FROM users WHERE id = ?`:
+		if _, ok := users[a[0]]; !ok {
+			return driver.RowsAffected(0), nil
+		}
+		delete(users, a[0])
+		return driver.RowsAffected(1), nil
+	}
+	return nil, fmt.Errorf("usersDB: unexpected statement %q", query)
+}
+
+func (c *usersConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	users, a := c.table(), stringArgs(args)
+	var match func(repository.User) bool
+	switch query {
+	case `SELECT id, email, name FROM users WHERE id = ?`:
+		match = func(u repository.User) bool { return u.ID == a[0] }
+	case `SELECT id, email, name FROM users WHERE email = ?`:
+		match = func(u repository.User) bool { return u.Email == a[0] }
+	default:
+		return nil, fmt.Errorf("usersDB: unexpected query %q", query)
+	}
+	rows := &userRows{}
+	for _, u := range users {
+		if match(u) {
+			rows.users = append(rows.users, u)
+		}
+	}
+	return rows, nil
+}
+
+type userRows struct{ users []repository.User }
+
+func (r *userRows) Columns() []string { return []string{"id", "email", "name"} }
+func (r *userRows) Close() error      { return nil }
+
+func (r *userRows) Next(dest []driver.Value) error {
+	if len(r.users) == 0 {
+		return io.EOF
+	}
+	u := r.users[0]
+	r.users = r.users[1:]
+	dest[0], dest[1], dest[2] = u.ID, u.Email, u.Name
+	return nil
+}
+
+// TestSQLUsersDriverError checks that an error which is not a conflict
+// is wrapped rather than mistaken for ErrExists or ErrNotFound.
+func TestSQLUsersDriverError(t *testing.T) {
+	db := sql.OpenDB(&usersDB{})
+	db.Close()
+	s := repository.NewSQLUsers(db, func(err error) bool { return errors.Is(err, errUnique) })
+	err := s.Create(context.Background(), repository.User{ID: "1", Email: "ann@example.com"})
+	if err == nil || errors.Is(err, repository.ErrExists) {
+		t.Errorf("Create on a closed database = %v, want a wrapped driver error", err)
+	}
+	if _, err := s.Get(context.Background(), "1"); err == nil || errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("Get on a closed database = %v, want a wrapped driver error", err)
+	}
+}
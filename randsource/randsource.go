@@ -0,0 +1,108 @@
+// Package randsource makes randomness injectable, so jitter, load
+// balancing and experiment bucketing can be replayed exactly:
+//
+//	type Balancer struct{ rand randsource.Rand }
+//
+//	i := b.rand.IntN(len(backends))
+//
+// Global draws from the runtime's random source, like the top-level
+// math/rand/v2 functions. New returns a generator that produces the same
+// sequence for the same seed, and Seeded picks a seed for one test run
+// and logs it, so a failure seen once can be rerun with RANDSEED set.
+//
+// Rand is an interface, not *rand.Rand, for two reasons: a *rand.Rand is
+// not safe for concurrent use, and tests sometimes want a scripted
+// Rand that returns chosen values rather than a seeded one.
+package randsource
+
+import (
+	"math/rand/v2"
+	"os"
+	"strconv"
+	"sync"
+)
+
+// Rand is the subset of *rand.Rand randomized code needs.
+type Rand interface {
+	IntN(n int) int
+	Int64N(n int64) int64
+	Float64() float64
+	Uint64() uint64
+}
+
+// Global draws from the runtime's random source. Safe for concurrent use.
+var Global Rand = global{}
+
+// Or returns r, or Global if r is nil, so a nil Rand field means "really
+// random" and struct types holding one stay zero-value ready.
+func Or(r Rand) Rand {
+	if r == nil {
+		return Global
+	}
+	return r
+}
+
+type global struct{}
+
+func (global) IntN(n int) int       { return rand.IntN(n) }
+func (global) Int64N(n int64) int64 { return rand.Int64N(n) }
+func (global) Float64() float64     { return rand.Float64() }
+func (global) Uint64() uint64       { return rand.Uint64() }
+
+// New returns a generator whose sequence is fixed by seed. Safe for
+// concurrent use; the order concurrent callers draw in is not, so
+// reproducible tests draw from one goroutine.
+func New(seed uint64) Rand {
+	return &locked{r: rand.New(rand.NewPCG(seed, seed^0x9e3779b97f4a7c15))}
+}
+
+type locked struct {
+	mu sync.Mutex
+	r  *rand.Rand
+}
+
+func (l *locked) IntN(n int) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.r.IntN(n)
+}
+
+func (l *locked) Int64N(n int64) int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.r.Int64N(n)
+}
+
+func (l *locked) Float64() float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.r.Float64()
+}
+
+func (l *locked) Uint64() uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.r.Uint64()
+}
+
+// SeedEnv is the environment variable Seeded reads a fixed seed from.
+const SeedEnv = "RANDSEED"
+
+// TB is the subset of testing.TB Seeded uses.
+type TB interface {
+	Helper()
+	Logf(format string, args ...any)
+}
+
+// Seeded returns a generator for one test: seeded from $RANDSEED when set,
+// otherwise from a fresh random seed that is logged, so every run explores
+// a different sequence but any failing one can be replayed.
+func Seeded(t TB) Rand {
+	t.Helper()
+	seed, err := strconv.ParseUint(os.Getenv(SeedEnv), 10, 64)
+	if err != nil {
+		seed = rand.Uint64()
+	}
+	t.Logf("randsource: seed %d (rerun with %s=%d)", seed, SeedEnv, seed)
+	return New(seed)
+}
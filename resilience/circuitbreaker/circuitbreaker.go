@@ -0,0 +1,300 @@
+// Package circuitbreaker stops calling a dependency that keeps failing,
+// so callers fail fast instead of queueing on timeouts, and the
+// dependency gets room to recover:
+//
+//	b, err := circuitbreaker.New(circuitbreaker.WithThreshold(5), circuitbreaker.WithCooldown(30*time.Second))
+//	err = b.Do(ctx, func(ctx context.Context) error {
+//		return inventory.Reserve(ctx, order)
+//	})
+//	if errors.Is(err, circuitbreaker.ErrOpen) {
+//		// fail fast, or fall back
+//	}
+//
+// The breaker has three states:
+//
+//	closed --threshold consecutive failures--> open
+//	open --cooldown passed--> half-open
+//	half-open --probes consecutive successes--> closed
+//	half-open --a failure--> open
+//
+// Closed lets every call through and counts consecutive failures. Open
+// refuses every call with ErrOpen. Half-open lets one trial call through
+// at a time, the others are refused, until enough trials succeed.
+//
+// A call's result counts only in the state it was let through in. Calls
+// take time, and a slow failure that finishes after the breaker has
+// already opened, or a success from before it opened that finishes
+// during a half-open trial, says nothing about the dependency now; each
+// state change starts a new generation, and results from older ones are
+// ignored.
+package circuitbreaker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"patterns/clock"
+	"patterns/construct"
+	"patterns/funcopts"
+)
+
+//go:generate go run patterns/cmd/enumgen -type=State -trimprefix=State
+
+// State is where the breaker stands.
+type State int
+
+const (
+	StateClosed State = iota
+	StateOpen
+	StateHalfOpen
+)
+
+// ErrOpen is returned for a call the breaker refused.
+var ErrOpen = errors.New("circuitbreaker: open")
+
+// ErrNotRun is what to report for a call that was let through but then
+// not made; it counts neither way.
+var ErrNotRun = errors.New("circuitbreaker: call not made")
+
+type options struct {
+	threshold int
+	cooldown  time.Duration
+	probes    int
+	isFailure func(error) bool
+	onChange  func(from, to State)
+	clock     clock.Clock
+}
+
+type Option = funcopts.Option[options]
+
+// WithThreshold opens the breaker after n consecutive failures; the
+// default is 5.
+func WithThreshold(n int) Option {
+	return func(options *options) error {
+		if n < 1 {
+			return errors.New("threshold must be at least 1")
+		}
+		options.threshold = n
+		return nil
+	}
+}
+
+// WithCooldown sets how long the breaker stays open before a trial call;
+// the default is 30 seconds.
+func WithCooldown(d time.Duration) Option {
+	return func(options *options) error {
+		if d <= 0 {
+			return errors.New("cooldown must be positive")
+		}
+		options.cooldown = d
+		return nil
+	}
+}
+
+// WithProbes sets how many trial calls in a row must succeed to close
+// the breaker again; the default is 1.
+func WithProbes(n int) Option {
+	return func(options *options) error {
+		if n < 1 {
+			return errors.New("probes must be at least 1")
+		}
+		options.probes = n
+		return nil
+	}
+}
+
+// WithIsFailure decides which errors count against the dependency. The
+// default counts every error but context.Canceled, the caller giving up;
+// errors that are the caller's fault, like a validation failure, should
+// not count either. Only a nil error counts as a success: other errors
+// that are not failures count neither way.
+func WithIsFailure(fn func(error) bool) Option {
+	return func(options *options) error {
+		if fn == nil {
+			return errors.New("failure classifier cannot be nil")
+		}
+		options.isFailure = fn
+		return nil
+	}
+}
+
+// WithOnStateChange reports every transition, for logs and metrics. fn
+// runs after the breaker's lock is released and may call the breaker.
+func WithOnStateChange(fn func(from, to State)) Option {
+	return func(options *options) error {
+		if fn == nil {
+			return errors.New("state change callback cannot be nil")
+		}
+		options.onChange = fn
+		return nil
+	}
+}
+
+func WithClock(c clock.Clock) Option {
+	return func(options *options) error {
+		if c == nil {
+			return errors.New("clock cannot be nil")
+		}
+		options.clock = c
+		return nil
+	}
+}
+
+func (o *options) SetDefaults() {
+	o.threshold = 5
+	o.cooldown = 30 * time.Second
+	o.probes = 1
+	o.isFailure = func(err error) bool { return err != nil && !errors.Is(err, context.Canceled) }
+	o.onChange = func(State, State) {}
+	o.clock = clock.Real
+}
+
+// circuit breaker
+// Level: Good
+// pros: callers of a dead dependency fail in microseconds instead of a
+// timeout; the dependency sees one trial call instead of full load while
+// it recovers.
+// cons: a healthy dependency is refused for a cooldown after a burst of
+// failures; one breaker per dependency, or per endpoint, to tune.
+//
+// Breaker guards one dependency. It is safe for concurrent use.
+type Breaker struct {
+	options options
+
+	mu        sync.Mutex
+	state     State
+	gen       uint64
+	failures  int // consecutive, while closed
+	successes int // consecutive trials, while half-open
+	trial     bool
+	openUntil time.Time
+	changes   []change // to report once unlocked
+}
+
+type change struct{ from, to State }
+
+func New(opts ...Option) (*Breaker, error) {
+	options, err := construct.New(opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &Breaker{options: *options}, nil
+}
+
+// unlock releases the lock, then reports the transitions made under it.
+func (b *Breaker) unlock() {
+	changes := b.changes
+	b.changes = nil
+	b.mu.Unlock()
+	for _, c := range changes {
+		b.options.onChange(c.from, c.to)
+	}
+}
+
+func (b *Breaker) set(s State) {
+	b.changes = append(b.changes, change{b.state, s})
+	b.state = s
+	b.gen++
+	b.failures, b.successes, b.trial = 0, 0, false
+	if s == StateOpen {
+		b.openUntil = b.options.clock.Now().Add(b.options.cooldown)
+	}
+}
+
+// current moves an open breaker whose cooldown has passed to half-open.
+func (b *Breaker) current() State {
+	if b.state == StateOpen && !b.options.clock.Now().Before(b.openUntil) {
+		b.set(StateHalfOpen)
+	}
+	return b.state
+}
+
+// State returns the breaker's state now.
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.unlock()
+	return b.current()
+}
+
+// Ready reports whether Allow would let a call through now, without
+// taking the half-open trial, for choosing between dependencies.
+func (b *Breaker) Ready() bool {
+	b.mu.Lock()
+	defer b.unlock()
+	switch b.current() {
+	case StateOpen:
+		return false
+	case StateHalfOpen:
+		return !b.trial
+	}
+	return true
+}
+
+// Allow lets a call through, or refuses it with ErrOpen. A call let
+// through must report its result to done, exactly once, or ErrNotRun if
+// it never happened; later calls of done are ignored.
+func (b *Breaker) Allow() (done func(err error), err error) {
+	b.mu.Lock()
+	defer b.unlock()
+	switch b.current() {
+	case StateOpen:
+		return nil, ErrOpen
+	case StateHalfOpen:
+		if b.trial {
+			return nil, ErrOpen
+		}
+		b.trial = true
+	}
+	gen := b.gen
+	var once sync.Once
+	return func(err error) {
+		once.Do(func() { b.record(gen, err) })
+	}, nil
+}
+
+func (b *Breaker) record(gen uint64, err error) {
+	b.mu.Lock()
+	defer b.unlock()
+	if gen != b.gen {
+		// let through in a state that has since changed
+		return
+	}
+	if b.state == StateHalfOpen {
+		b.trial = false
+	}
+	switch {
+	case err == nil:
+		if b.state == StateClosed {
+			b.failures = 0
+		} else if b.successes++; b.successes >= b.options.probes {
+			b.set(StateClosed)
+		}
+	case errors.Is(err, ErrNotRun) || !b.options.isFailure(err):
+		// says nothing about the dependency
+	case b.state == StateClosed:
+		if b.failures++; b.failures >= b.options.threshold {
+			b.set(StateOpen)
+		}
+	default:
+		b.set(StateOpen)
+	}
+}
+
+// Do runs fn if the breaker allows it, and records its result; a panic
+// in fn counts as a failure and is raised again.
+func (b *Breaker) Do(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	done, err := b.Allow()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			done(errors.New("circuitbreaker: panic"))
+			panic(p)
+		}
+		done(err)
+	}()
+	return fn(ctx)
+}
@@ -0,0 +1,229 @@
+package circuitbreaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"patterns/clock"
+)
+
+var errDown = errors.New("down")
+
+type recorder struct{ changes []string }
+
+func (r *recorder) onChange(from, to State) {
+	r.changes = append(r.changes, fmt.Sprintf("%s->%s", from, to))
+}
+
+func newBreaker(t *testing.T, opts ...Option) (*Breaker, *clock.Fake, *recorder) {
+	t.Helper()
+	fake := clock.NewFake(time.Unix(0, 0))
+	r := &recorder{}
+	b, err := New(append([]Option{WithClock(fake), WithOnStateChange(r.onChange)}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b, fake, r
+}
+
+// call lets one call through and reports err for it.
+func call(t *testing.T, b *Breaker, err error) {
+	t.Helper()
+	done, aerr := b.Allow()
+	if aerr != nil {
+		t.Fatalf("Allow in %s: %v", b.State(), aerr)
+	}
+	done(err)
+}
+
+func wantState(t *testing.T, b *Breaker, want State) {
+	t.Helper()
+	if got := b.State(); got != want {
+		t.Fatalf("state = %s, want %s", got, want)
+	}
+}
+
+func TestFullCycle(t *testing.T) {
+	b, fake, r := newBreaker(t, WithThreshold(3), WithCooldown(10*time.Second), WithProbes(2))
+
+	call(t, b, errDown)
+	call(t, b, errDown)
+	call(t, b, nil) // a success resets the streak
+	call(t, b, errDown)
+	call(t, b, errDown)
+	wantState(t, b, StateClosed)
+	call(t, b, errDown)
+	wantState(t, b, StateOpen)
+
+	if _, err := b.Allow(); !errors.Is(err, ErrOpen) {
+		t.Fatalf("Allow while open = %v, want ErrOpen", err)
+	}
+	if b.Ready() {
+		t.Error("Ready while open")
+	}
+	fake.Advance(10*time.Second - time.Nanosecond)
+	wantState(t, b, StateOpen)
+	fake.Advance(time.Nanosecond)
+	wantState(t, b, StateHalfOpen)
+
+	// one trial at a time
+	done, err := b.Allow()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Ready() {
+		t.Error("Ready with the trial taken")
+	}
+	if _, err := b.Allow(); !errors.Is(err, ErrOpen) {
+		t.Fatalf("second Allow during a trial = %v, want ErrOpen", err)
+	}
+	done(nil)
+	wantState(t, b, StateHalfOpen) // one of two probes
+	call(t, b, nil)
+	wantState(t, b, StateClosed)
+
+	want := []string{"closed->open", "open->halfopen", "halfopen->closed"}
+	if !slices.Equal(r.changes, want) {
+		t.Errorf("changes = %v, want %v", r.changes, want)
+	}
+}
+
+func TestTrialFailureReopens(t *testing.T) {
+	b, fake, r := newBreaker(t, WithThreshold(1), WithCooldown(time.Second), WithProbes(3))
+	call(t, b, errDown)
+	fake.Advance(time.Second)
+	call(t, b, nil)
+	call(t, b, errDown) // the second probe fails
+	wantState(t, b, StateOpen)
+
+	// and the cooldown starts again from the failure
+	fake.Advance(time.Second - time.Nanosecond)
+	wantState(t, b, StateOpen)
+	fake.Advance(time.Nanosecond)
+	wantState(t, b, StateHalfOpen)
+
+	want := []string{"closed->open", "open->halfopen", "halfopen->open", "open->halfopen"}
+	if !slices.Equal(r.changes, want) {
+		t.Errorf("changes = %v, want %v", r.changes, want)
+	}
+}
+
+// TestStaleResults lets calls through in one state and reports them in
+// the next: neither counts.
+func TestStaleResults(t *testing.T) {
+	b, fake, _ := newBreaker(t, WithThreshold(2), WithCooldown(time.Second))
+	slowFailure, _ := b.Allow()
+	slowSuccess, _ := b.Allow()
+	call(t, b, errDown)
+	call(t, b, errDown)
+	wantState(t, b, StateOpen)
+
+	slowFailure(errDown)
+	fake.Advance(time.Second)
+	wantState(t, b, StateHalfOpen)
+	// a success from before the breaker opened is not a probe
+	slowSuccess(nil)
+	wantState(t, b, StateHalfOpen)
+	if !b.Ready() {
+		t.Error("stale result took the trial")
+	}
+}
+
+func TestNeutralResults(t *testing.T) {
+	errBadInput := errors.New("bad input")
+	b, _, _ := newBreaker(t, WithThreshold(1), WithIsFailure(func(err error) bool {
+		return !errors.Is(err, errBadInput)
+	}))
+	call(t, b, ErrNotRun)
+	call(t, b, errBadInput)
+	wantState(t, b, StateClosed)
+	call(t, b, errDown)
+	wantState(t, b, StateOpen)
+
+	// the default classifier does not blame the dependency for a caller
+	// that gave up
+	b, _, _ = newBreaker(t, WithThreshold(1))
+	call(t, b, context.Canceled)
+	call(t, b, fmt.Errorf("get: %w", context.Canceled))
+	wantState(t, b, StateClosed)
+	call(t, b, context.DeadlineExceeded)
+	wantState(t, b, StateOpen)
+}
+
+func TestNeutralTrialFreesTheSlot(t *testing.T) {
+	b, fake, _ := newBreaker(t, WithThreshold(1), WithCooldown(time.Second))
+	call(t, b, errDown)
+	fake.Advance(time.Second)
+	call(t, b, ErrNotRun)
+	wantState(t, b, StateHalfOpen)
+	if !b.Ready() {
+		t.Error("trial slot still taken after ErrNotRun")
+	}
+}
+
+func TestDoneOnce(t *testing.T) {
+	b, _, _ := newBreaker(t, WithThreshold(2))
+	done, _ := b.Allow()
+	done(errDown)
+	done(errDown)
+	wantState(t, b, StateClosed)
+}
+
+func TestDo(t *testing.T) {
+	b, _, _ := newBreaker(t, WithThreshold(2))
+	if err := b.Do(context.Background(), func(context.Context) error { return errDown }); err != errDown {
+		t.Fatalf("Do = %v, want fn's error", err)
+	}
+	func() {
+		defer func() {
+			if p := recover(); p != "boom" {
+				t.Errorf("recovered %v, want the panic raised again", p)
+			}
+		}()
+		b.Do(context.Background(), func(context.Context) error { panic("boom") })
+	}()
+	wantState(t, b, StateOpen) // the panic was the second failure
+	ran := false
+	if err := b.Do(context.Background(), func(context.Context) error { ran = true; return nil }); !errors.Is(err, ErrOpen) || ran {
+		t.Errorf("Do while open = %v, ran %v; want ErrOpen without running", err, ran)
+	}
+}
+
+// TestOnStateChangeMayCallBreaker checks the callback runs unlocked.
+func TestOnStateChangeMayCallBreaker(t *testing.T) {
+	fake := clock.NewFake(time.Unix(0, 0))
+	var b *Breaker
+	var seen []State
+	b, err := New(WithClock(fake), WithThreshold(1), WithOnStateChange(func(_, to State) {
+		seen = append(seen, b.State())
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	call(t, b, errDown)
+	if !slices.Equal(seen, []State{StateOpen}) {
+		t.Errorf("states seen from the callback = %v", seen)
+	}
+}
+
+func TestOptionErrors(t *testing.T) {
+	for _, c := range []struct {
+		opt  Option
+		want string
+	}{
+		{WithThreshold(0), "threshold must be at least 1"},
+		{WithCooldown(0), "cooldown must be positive"},
+		{WithProbes(0), "probes must be at least 1"},
+		{WithIsFailure(nil), "failure classifier cannot be nil"},
+		{WithOnStateChange(nil), "state change callback cannot be nil"},
+		{WithClock(nil), "clock cannot be nil"},
+	} {
+		if _, err := New(c.opt); err == nil || err.Error() != c.want {
+			t.Errorf("New = %v, want %q", err, c.want)
+		}
+	}
+}
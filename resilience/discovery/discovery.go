@@ -0,0 +1,100 @@
+// Package discovery keeps a live set of endpoints for a service and feeds
+// it to a loadbalance.Picker.
+//
+// A Resolver answers "where is the service now": Static for fixed lists,
+// File for a hand-edited or config-managed file, DNS for A/AAAA or SRV
+// records. A Pool polls one, diffs each answer against the last, notifies
+// subscribers of the Change, and picks only among Healthy endpoints.
+//
+//	pool, err := discovery.NewPool(discovery.File("backends.txt"),
+//		discovery.WithPicker(&loadbalance.PowerOfTwo{}))
+//	go pool.Run(ctx)
+//	b, err := pool.Pick()
+//
+// Backends keep their identity across refreshes, so in-flight counts used
+// by load-aware pickers survive an endpoint list being re-read.
+package discovery
+
+import (
+	"context"
+	"slices"
+)
+
+//go:generate go run patterns/cmd/enumgen -type=Health
+
+// Health is an endpoint's status as reported by its Resolver.
+type Health int
+
+const (
+	// Healthy endpoints receive traffic. The zero value, so Static lists
+	// need not spell it out.
+	Healthy Health = iota
+	// Draining endpoints are kept, with their in-flight requests, but get
+	// no new ones; use it to take a backend out gracefully.
+	Draining
+	// Down endpoints get no traffic.
+	Down
+)
+
+// Endpoint is one address of a service.
+type Endpoint struct {
+	Addr   string
+	Weight int
+	Health Health
+}
+
+// Resolver returns the current endpoints of a service.
+type Resolver interface {
+	Resolve(ctx context.Context) ([]Endpoint, error)
+}
+
+// ResolverFunc adapts a function to Resolver.
+type ResolverFunc func(ctx context.Context) ([]Endpoint, error)
+
+func (f ResolverFunc) Resolve(ctx context.Context) ([]Endpoint, error) { return f(ctx) }
+
+// Static always resolves to endpoints.
+func Static(endpoints ...Endpoint) Resolver {
+	endpoints = slices.Clone(endpoints)
+	return ResolverFunc(func(context.Context) ([]Endpoint, error) {
+		return slices.Clone(endpoints), nil
+	})
+}
+
+// Change is the difference between two consecutive resolutions. Updated
+// holds endpoints whose weight or health changed, with their new values.
+type Change struct {
+	Added   []Endpoint
+	Removed []Endpoint
+	Updated []Endpoint
+}
+
+func (c Change) Empty() bool {
+	return len(c.Added) == 0 && len(c.Removed) == 0 && len(c.Updated) == 0
+}
+
+// diff compares endpoint sets keyed by Addr; both must be free of
+// duplicate addresses.
+func diff(old, cur []Endpoint) Change {
+	var c Change
+	prev := make(map[string]Endpoint, len(old))
+	for _, e := range old {
+		prev[e.Addr] = e
+	}
+	for _, e := range cur {
+		p, ok := prev[e.Addr]
+		switch {
+		case !ok:
+			c.Added = append(c.Added, e)
+		case p != e:
+			c.Updated = append(c.Updated, e)
+		}
+		delete(prev, e.Addr)
+	}
+	for _, e := range old {
+		if _, gone := prev[e.Addr]; gone {
+			c.Removed = append(c.Removed, e)
+		}
+	}
+	return c
+}
@@ -0,0 +1,468 @@
+package discovery_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"patterns/clock"
+	"patterns/resilience/discovery"
+	"patterns/resilience/loadbalance"
+)
+
+// scripted is a Resolver whose answer the test sets between refreshes.
+type scripted struct {
+	mu        sync.Mutex
+	endpoints []discovery.Endpoint
+	err       error
+}
+
+func (s *scripted) set(err error, endpoints ...discovery.Endpoint) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.endpoints, s.err = endpoints, err
+}
+
+func (s *scripted) Resolve(context.Context) ([]discovery.Endpoint, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.endpoints), s.err
+}
+
+func ep(addr string, weight int, health discovery.Health) discovery.Endpoint {
+	return discovery.Endpoint{Addr: addr, Weight: weight, Health: health}
+}
+
+// addrs formats endpoints compactly, in a stable order.
+func addrs(endpoints []discovery.Endpoint) string {
+	var s []string
+	for _, e := range endpoints {
+		s = append(s, fmt.Sprintf("%s/%d/%s", e.Addr, e.Weight, e.Health))
+	}
+	slices.Sort(s)
+	return strings.Join(s, " ")
+}
+
+func record(p *discovery.Pool) *[]discovery.Change {
+	var changes []discovery.Change
+	p.Subscribe(func(c discovery.Change) { changes = append(changes, c) })
+	return &changes
+}
+
+// picked returns the addresses Pick can return, picking round robin.
+func picked(t *testing.T, p *discovery.Pool, n int) string {
+	t.Helper()
+	var got []string
+	for range n {
+		b, err := p.Pick()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Contains(got, b.Addr) {
+			got = append(got, b.Addr)
+		}
+	}
+	slices.Sort(got)
+	return strings.Join(got, " ")
+}
+
+// TestChurn walks a service through endpoints joining, draining, going
+// down, reweighting and leaving, checking at each step the change
+// reported and where traffic goes.
+func TestChurn(t *testing.T) {
+	ctx := context.Background()
+	r := &scripted{}
+	r.set(nil, ep("a", 1, discovery.Healthy), ep("b", 1, discovery.Healthy))
+	pool, err := discovery.NewPool(ctx, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes := record(pool)
+
+	for _, step := range []struct {
+		name                    string
+		endpoints               []discovery.Endpoint
+		added, removed, updated string
+		picks                   string
+	}{
+		{"join", []discovery.Endpoint{ep("a", 1, discovery.Healthy), ep("b", 1, discovery.Healthy), ep("c", 1, discovery.Healthy)},
+			"c/1/healthy", "", "", "a b c"},
+		{"drain", []discovery.Endpoint{ep("a", 1, discovery.Healthy), ep("b", 1, discovery.Draining), ep("c", 1, discovery.Healthy)},
+			"", "", "b/1/draining", "a c"},
+		{"down and back", []discovery.Endpoint{ep("a", 1, discovery.Down), ep("b", 1, discovery.Healthy), ep("c", 1, discovery.Healthy)},
+			"", "", "a/1/down b/1/healthy", "b c"},
+		{"reweigh", []discovery.Endpoint{ep("a", 1, discovery.Down), ep("b", 5, discovery.Healthy), ep("c", 1, discovery.Healthy)},
+			"", "", "b/5/healthy", "b c"},
+		{"leave and replace", []discovery.Endpoint{ep("c", 1, discovery.Healthy), ep("d", 1, discovery.Healthy)},
+			"d/1/healthy", "a/1/down b/5/healthy", "", "c d"},
+		{"no change", []discovery.Endpoint{ep("d", 1, discovery.Healthy), ep("c", 1, discovery.Healthy)},
+			"", "", "", "c d"},
+	} {
+		*changes = nil
+		r.set(nil, step.endpoints...)
+		if err := pool.Refresh(ctx); err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		if step.added == "" && step.removed == "" && step.updated == "" {
+			if len(*changes) != 0 {
+				t.Errorf("%s: notified %+v for no change", step.name, *changes)
+			}
+		} else if len(*changes) != 1 {
+			t.Errorf("%s: %d notifications, want 1", step.name, len(*changes))
+		} else if c := (*changes)[0]; addrs(c.Added) != step.added || addrs(c.Removed) != step.removed || addrs(c.Updated) != step.updated {
+			t.Errorf("%s: change added [%s] removed [%s] updated [%s], want [%s] [%s] [%s]",
+				step.name, addrs(c.Added), addrs(c.Removed), addrs(c.Updated), step.added, step.removed, step.updated)
+		}
+		if got := addrs(pool.Endpoints()); got != addrs(step.endpoints) {
+			t.Errorf("%s: Endpoints = %s", step.name, got)
+		}
+		if got := picked(t, pool, 20); got != step.picks {
+			t.Errorf("%s: picked %s, want %s", step.name, got, step.picks)
+		}
+	}
+
+	r.set(nil, ep("c", 1, discovery.Down))
+	pool.Refresh(ctx)
+	if b, err := pool.Pick(); !errors.Is(err, loadbalance.ErrNoBackends) {
+		t.Errorf("Pick with nothing healthy = %v, %v", b, err)
+	}
+}
+
+// find picks until it gets addr.
+func find(t *testing.T, p *discovery.Pool, addr string) *loadbalance.Backend {
+	t.Helper()
+	for range 100 {
+		if b, _ := p.Pick(); b != nil && b.Addr == addr {
+			return b
+		}
+	}
+	t.Fatalf("never picked %s", addr)
+	return nil
+}
+
+// TestIdentity checks that a backend keeps its in-flight count across
+// refreshes and health changes, and starts over when it is reweighted or
+// leaves and comes back.
+func TestIdentity(t *testing.T) {
+	ctx := context.Background()
+	r := &scripted{}
+	r.set(nil, ep("a", 1, discovery.Healthy), ep("b", 1, discovery.Healthy))
+	pool, err := discovery.NewPool(ctx, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := find(t, pool, "a")
+	done := a.Start()
+	defer func() { done() }()
+
+	healthy := []discovery.Endpoint{ep("a", 1, discovery.Healthy), ep("b", 1, discovery.Healthy)}
+	for _, step := range []struct {
+		name string
+		via  [][]discovery.Endpoint // resolutions in turn, ending healthy
+		same bool
+	}{
+		{"re-resolved", [][]discovery.Endpoint{{ep("b", 1, discovery.Healthy), ep("a", 1, discovery.Healthy)}}, true},
+		{"drained and back", [][]discovery.Endpoint{{ep("a", 1, discovery.Draining), ep("b", 1, discovery.Healthy)}, healthy}, true},
+		{"reweighted", [][]discovery.Endpoint{{ep("a", 2, discovery.Healthy), ep("b", 1, discovery.Healthy)}}, false},
+		{"left and back", [][]discovery.Endpoint{{ep("b", 1, discovery.Healthy)}, {ep("a", 2, discovery.Healthy), ep("b", 1, discovery.Healthy)}}, false},
+	} {
+		for _, endpoints := range step.via {
+			r.set(nil, endpoints...)
+			if err := pool.Refresh(ctx); err != nil {
+				t.Fatal(err)
+			}
+		}
+		got := find(t, pool, "a")
+		if (got == a) != step.same {
+			t.Errorf("%s: same backend %v, want %v", step.name, got == a, step.same)
+		}
+		want := int64(0)
+		if step.same {
+			want = 1
+		}
+		if got.Active() != want {
+			t.Errorf("%s: Active = %d, want %d", step.name, got.Active(), want)
+		}
+		done()
+		a, done = got, got.Start()
+	}
+}
+
+// TestResolveError checks that a failed or invalid resolution keeps the
+// endpoints in use, and Err clears on the next success.
+func TestResolveError(t *testing.T) {
+	ctx := context.Background()
+	r := &scripted{}
+	r.set(errors.New("no route"))
+	if pool, err := discovery.NewPool(ctx, r); pool != nil || err == nil {
+		t.Errorf("NewPool with a failing resolver = %v, %v", pool, err)
+	}
+
+	r.set(nil, ep("a", 1, discovery.Healthy))
+	pool, err := discovery.NewPool(ctx, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes := record(pool)
+	for _, c := range []struct {
+		name      string
+		err       error
+		endpoints []discovery.Endpoint
+		want      string
+	}{
+		{"resolver fails", errors.New("no route"), nil, "no route"},
+		{"duplicate", nil, []discovery.Endpoint{ep("b", 1, discovery.Healthy), ep("b", 2, discovery.Healthy)}, "discovery: duplicate endpoint b"},
+	} {
+		r.set(c.err, c.endpoints...)
+		if err := pool.Refresh(ctx); err == nil || err.Error() != c.want {
+			t.Errorf("%s: Refresh = %v, want %q", c.name, err, c.want)
+		}
+		if err := pool.Err(); err == nil || err.Error() != c.want {
+			t.Errorf("%s: Err = %v, want %q", c.name, err, c.want)
+		}
+		if got := picked(t, pool, 5); got != "a" {
+			t.Errorf("%s: picked %s, want the last good endpoints", c.name, got)
+		}
+	}
+	if len(*changes) != 0 {
+		t.Errorf("failed refreshes notified %+v", *changes)
+	}
+	r.set(nil, ep("b", 1, discovery.Healthy))
+	if err := pool.Refresh(ctx); err != nil || pool.Err() != nil {
+		t.Errorf("Refresh = %v, Err = %v after recovering", err, pool.Err())
+	}
+	if len(*changes) != 1 {
+		t.Errorf("%d notifications after recovering, want 1", len(*changes))
+	}
+}
+
+func TestSubscribeCancel(t *testing.T) {
+	ctx := context.Background()
+	r := &scripted{}
+	r.set(nil, ep("a", 1, discovery.Healthy))
+	pool, _ := discovery.NewPool(ctx, r)
+	var first, second int
+	cancel := pool.Subscribe(func(discovery.Change) { first++ })
+	pool.Subscribe(func(discovery.Change) { second++ })
+	r.set(nil, ep("b", 1, discovery.Healthy))
+	pool.Refresh(ctx)
+	cancel()
+	r.set(nil, ep("c", 1, discovery.Healthy))
+	pool.Refresh(ctx)
+	if first != 1 || second != 2 {
+		t.Errorf("cancelled subscriber called %d times, other %d; want 1, 2", first, second)
+	}
+}
+
+// TestRun drives Run's polling with a fake clock.
+func TestRun(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	fake := clock.NewFake(time.Unix(0, 0))
+	r := &scripted{}
+	r.set(nil, ep("a", 1, discovery.Healthy))
+	pool, err := discovery.NewPool(ctx, r, discovery.WithClock(fake), discovery.WithInterval(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes := make(chan discovery.Change, 1)
+	pool.Subscribe(func(c discovery.Change) { changes <- c })
+	stopped := make(chan error)
+	go func() { stopped <- pool.Run(ctx) }()
+	fake.BlockUntil(1)
+
+	r.set(nil, ep("a", 1, discovery.Healthy), ep("b", 1, discovery.Healthy))
+	select {
+	case c := <-changes:
+		t.Fatalf("refreshed before the interval: %+v", c)
+	default:
+	}
+	fake.Advance(time.Minute)
+	select {
+	case c := <-changes:
+		if addrs(c.Added) != "b/1/healthy" {
+			t.Errorf("change %+v, want b added", c)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not refresh after the interval")
+	}
+
+	cancel()
+	if err := <-stopped; err != nil {
+		t.Errorf("Run = %v", err)
+	}
+}
+
+func TestOptions(t *testing.T) {
+	ctx := context.Background()
+	r := discovery.Static(ep("a", 1, discovery.Healthy))
+	for _, c := range []struct {
+		opt  discovery.Option
+		want string
+	}{
+		{discovery.WithPicker(nil), "picker cannot be nil"},
+		{discovery.WithInterval(0), "interval must be positive"},
+		{discovery.WithClock(nil), "clock cannot be nil"},
+	} {
+		if _, err := discovery.NewPool(ctx, r, c.opt); err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Errorf("NewPool = %v, want %q", err, c.want)
+		}
+	}
+}
+
+// TestStatic checks that neither the caller's slice nor a returned one
+// reaches the resolver's list.
+func TestStatic(t *testing.T) {
+	in := []discovery.Endpoint{ep("a", 1, discovery.Healthy)}
+	r := discovery.Static(in...)
+	in[0].Addr = "changed"
+	got, _ := r.Resolve(context.Background())
+	got[0].Addr = "changed too"
+	if again, _ := r.Resolve(context.Background()); addrs(again) != "a/1/healthy" {
+		t.Errorf("Static resolved %s", addrs(again))
+	}
+}
+
+// TestConcurrentChurn picks from several goroutines while endpoints
+// come and go; run with -race. Every pick is an endpoint that was
+// healthy at some point.
+func TestConcurrentChurn(t *testing.T) {
+	ctx := context.Background()
+	r := &scripted{}
+	r.set(nil, ep("e0", 1, discovery.Healthy))
+	pool, err := discovery.NewPool(ctx, r, discovery.WithPicker(&loadbalance.LeastConnections{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				b, err := pool.Pick()
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if !strings.HasPrefix(b.Addr, "e") {
+					t.Errorf("picked %s", b.Addr)
+				}
+				b.Start()()
+			}
+		}()
+	}
+	for i := range 200 {
+		// a sliding window of three, the oldest draining
+		r.set(nil, ep(fmt.Sprint("e", i), 1, discovery.Draining),
+			ep(fmt.Sprint("e", i+1), 1+i%3, discovery.Healthy),
+			ep(fmt.Sprint("e", i+2), 1, discovery.Healthy))
+		if err := pool.Refresh(ctx); err != nil {
+			t.Fatal(err)
+		}
+		pool.Endpoints()
+	}
+	close(stop)
+	wg.Wait()
+}
+
+func write(t *testing.T, path, data string, mtime time.Time) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// set the time outright: two writes within the file system's
+	// timestamp granularity would otherwise look unchanged
+	if err := os.Chtimes(path, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestFile edits a backends file under a Pool: changes are picked up,
+// a broken edit keeps the last good list.
+func TestFile(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "backends.txt")
+	start := time.Unix(1700000000, 0)
+	write(t, path, "# backends\n10.0.0.1:80\n10.0.0.2:80 weight=3\n\n10.0.0.3:80 draining # leaving\n", start)
+	pool, err := discovery.NewPool(ctx, discovery.File(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := addrs(pool.Endpoints()); got != "10.0.0.1:80/0/healthy 10.0.0.2:80/3/healthy 10.0.0.3:80/0/draining" {
+		t.Errorf("Endpoints = %s", got)
+	}
+	changes := record(pool)
+
+	for i, c := range []struct {
+		name, data string
+		err        string
+		want       string
+	}{
+		{"edit", "10.0.0.1:80 down\n10.0.0.2:80 weight=3\n10.0.0.4:80\n", "",
+			"10.0.0.1:80/0/down 10.0.0.2:80/3/healthy 10.0.0.4:80/0/healthy"},
+		{"bad weight", "10.0.0.1:80 weight=0\n", "line 1: invalid weight \"0\"", ""},
+		{"bad health", "10.0.0.1:80\n10.0.0.2:80 sleepy\n", "line 2: ", ""},
+		{"duplicate", "10.0.0.1:80\n\n10.0.0.1:80 down\n", "line 3: duplicate endpoint 10.0.0.1:80", ""},
+		{"emptied", "# nothing\n", "", ""},
+	} {
+		write(t, path, c.data, start.Add(time.Duration(i+1)*time.Second))
+		err := pool.Refresh(ctx)
+		if c.err != "" {
+			if err == nil || !strings.HasPrefix(err.Error(), path+": "+c.err) {
+				t.Errorf("%s: Refresh = %v, want %q", c.name, err, c.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if got := addrs(pool.Endpoints()); got != c.want {
+			t.Errorf("%s: Endpoints = %s, want %s", c.name, got, c.want)
+		}
+	}
+	if len(*changes) != 2 {
+		t.Errorf("%d notifications, want the two good edits", len(*changes))
+	}
+
+	os.Remove(path)
+	if err := pool.Refresh(ctx); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Refresh of a removed file = %v", err)
+	}
+}
+
+// TestFileUnchanged checks that an untouched file is not re-read: a
+// rewrite that keeps size and time goes unnoticed.
+func TestFileUnchanged(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "backends.txt")
+	mtime := time.Unix(1700000000, 0)
+	write(t, path, "10.0.0.1:80\n", mtime)
+	r := discovery.File(path)
+	r.Resolve(context.Background())
+	write(t, path, "10.0.0.9:80\n", mtime)
+	if got, _ := r.Resolve(context.Background()); addrs(got) != "10.0.0.1:80/0/healthy" {
+		t.Errorf("Resolve = %s, want the cached answer", addrs(got))
+	}
+}
+
+func TestDNS(t *testing.T) {
+	got, err := discovery.DNS(nil, "localhost", 8080).Resolve(context.Background())
+	if err != nil {
+		t.Skipf("localhost does not resolve here: %v", err)
+	}
+	if !strings.Contains(addrs(got), "127.0.0.1:8080/0/healthy") && !strings.Contains(addrs(got), "[::1]:8080/0/healthy") {
+		t.Errorf("DNS = %s, want a loopback address with the port", addrs(got))
+	}
+}
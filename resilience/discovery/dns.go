@@ -0,0 +1,54 @@
+package discovery
+
+import (
+	"context"
+	"net"
+	"strconv"
+)
+
+// DNS resolves host's A and AAAA records, each with port. r nil means
+// net.DefaultResolver.
+func DNS(r *net.Resolver, host string, port int) Resolver {
+	return ResolverFunc(func(ctx context.Context) ([]Endpoint, error) {
+		addrs, err := resolver(r).LookupHost(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		endpoints := make([]Endpoint, len(addrs))
+		for i, a := range addrs {
+			endpoints[i] = Endpoint{Addr: net.JoinHostPort(a, strconv.Itoa(port))}
+		}
+		return endpoints, nil
+	})
+}
+
+// SRV resolves _service._proto.name SRV records. Only records of the
+// lowest priority are used; higher ones are fallbacks this resolver does
+// not try. Weights carry over.
+func SRV(r *net.Resolver, service, proto, name string) Resolver {
+	return ResolverFunc(func(ctx context.Context) ([]Endpoint, error) {
+		_, records, err := resolver(r).LookupSRV(ctx, service, proto, name)
+		if err != nil {
+			return nil, err
+		}
+		var endpoints []Endpoint
+		for _, rec := range records {
+			// LookupSRV sorts by priority
+			if rec.Priority != records[0].Priority {
+				break
+			}
+			endpoints = append(endpoints, Endpoint{
+				Addr:   net.JoinHostPort(rec.Target, strconv.Itoa(int(rec.Port))),
+				Weight: int(rec.Weight),
+			})
+		}
+		return endpoints, nil
+	})
+}
+
+func resolver(r *net.Resolver) *net.Resolver {
+	if r == nil {
+		return net.DefaultResolver
+	}
+	return r
+}
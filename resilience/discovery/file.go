@@ -0,0 +1,95 @@
+package discovery
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// File resolves from a text file with one endpoint per line:
+//
+//	# addr [weight=N] [healthy|draining|down]
+//	10.0.0.1:8080
+//	10.0.0.2:8080 weight=3
+//	10.0.0.3:8080 draining
+//
+// The file is re-read only when its size or modification time changes,
+// so a Pool can poll it cheaply. A file that fails to parse is an error
+// and the last good answer stays in use.
+func File(path string) Resolver {
+	return &fileResolver{path: path}
+}
+
+type fileResolver struct {
+	path string
+
+	mu        sync.Mutex
+	modTime   time.Time
+	size      int64
+	endpoints []Endpoint
+}
+
+func (f *fileResolver) Resolve(ctx context.Context) ([]Endpoint, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	fi, err := os.Stat(f.path)
+	if err != nil {
+		return nil, err
+	}
+	if f.endpoints != nil && fi.ModTime().Equal(f.modTime) && fi.Size() == f.size {
+		return slices.Clone(f.endpoints), nil
+	}
+
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		return nil, err
+	}
+	endpoints, err := parseEndpoints(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", f.path, err)
+	}
+	f.modTime, f.size, f.endpoints = fi.ModTime(), fi.Size(), endpoints
+	return slices.Clone(endpoints), nil
+}
+
+func parseEndpoints(data []byte) ([]Endpoint, error) {
+	endpoints := []Endpoint{}
+	seen := map[string]bool{}
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; sc.Scan(); n++ {
+		line, _, _ := strings.Cut(sc.Text(), "#")
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		e := Endpoint{Addr: fields[0]}
+		if seen[e.Addr] {
+			return nil, fmt.Errorf("line %d: duplicate endpoint %s", n, e.Addr)
+		}
+		seen[e.Addr] = true
+		for _, f := range fields[1:] {
+			if w, ok := strings.CutPrefix(f, "weight="); ok {
+				weight, err := strconv.Atoi(w)
+				if err != nil || weight < 1 {
+					return nil, fmt.Errorf("line %d: invalid weight %q", n, w)
+				}
+				e.Weight = weight
+				continue
+			}
+			h, err := ParseHealth(f)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", n, err)
+			}
+			e.Health = h
+		}
+		endpoints = append(endpoints, e)
+	}
+	return endpoints, sc.Err()
+}
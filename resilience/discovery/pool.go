@@ -0,0 +1,214 @@
+package discovery
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	"patterns/clock"
+	"patterns/construct"
+	"patterns/funcopts"
+	"patterns/resilience/loadbalance"
+)
+
+type options struct {
+	picker   loadbalance.Picker
+	interval time.Duration
+	clock    clock.Clock
+}
+
+type Option = funcopts.Option[options]
+
+func WithPicker(p loadbalance.Picker) Option {
+	return func(options *options) error {
+		if p == nil {
+			return errors.New("picker cannot be nil")
+		}
+		options.picker = p
+		return nil
+	}
+}
+
+// WithInterval sets how often Run re-resolves.
+func WithInterval(d time.Duration) Option {
+	return func(options *options) error {
+		if d <= 0 {
+			return errors.New("interval must be positive")
+		}
+		options.interval = d
+		return nil
+	}
+}
+
+func WithClock(c clock.Clock) Option {
+	return func(options *options) error {
+		if c == nil {
+			return errors.New("clock cannot be nil")
+		}
+		options.clock = c
+		return nil
+	}
+}
+
+func (o *options) SetDefaults() {
+	o.picker = &loadbalance.RoundRobin{}
+	o.interval = 5 * time.Second
+	o.clock = clock.Real
+}
+
+// Pool is the current endpoint set of one service. Safe for concurrent
+// use.
+type Pool struct {
+	resolver Resolver
+	options  options
+
+	// refresh serializes resolve, apply and notify, so subscribers see
+	// changes in the order they happened
+	refresh sync.Mutex
+
+	mu        sync.RWMutex
+	endpoints []Endpoint
+	backends  map[string]*loadbalance.Backend
+	healthy   []*loadbalance.Backend
+	err       error
+	subs      map[*func(Change)]struct{}
+}
+
+// NewPool resolves once and fails if that fails, so a Pool is never
+// created empty by accident.
+func NewPool(ctx context.Context, r Resolver, opts ...Option) (*Pool, error) {
+	options, err := construct.New(opts...)
+	if err != nil {
+		return nil, err
+	}
+	p := &Pool{
+		resolver: r,
+		options:  *options,
+		backends: map[string]*loadbalance.Backend{},
+		subs:     map[*func(Change)]struct{}{},
+	}
+	if err := p.Refresh(ctx); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Run re-resolves every interval until ctx is done. A failed resolution
+// keeps the previous endpoints; Err reports it until the next success.
+func (p *Pool) Run(ctx context.Context) error {
+	ticker := p.options.clock.NewTicker(p.options.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C():
+			p.Refresh(ctx)
+		}
+	}
+}
+
+// Refresh resolves now and applies the result.
+func (p *Pool) Refresh(ctx context.Context) error {
+	p.refresh.Lock()
+	defer p.refresh.Unlock()
+
+	endpoints, err := p.resolver.Resolve(ctx)
+	if err == nil {
+		err = checkUnique(endpoints)
+	}
+	p.mu.Lock()
+	if err != nil {
+		p.err = err
+		p.mu.Unlock()
+		return err
+	}
+	change := diff(p.endpoints, endpoints)
+	p.apply(endpoints, change)
+	p.err = nil
+	subs := make([]func(Change), 0, len(p.subs))
+	for fn := range p.subs {
+		subs = append(subs, *fn)
+	}
+	p.mu.Unlock()
+
+	if !change.Empty() {
+		for _, fn := range subs {
+			fn(change)
+		}
+	}
+	return nil
+}
+
+func checkUnique(endpoints []Endpoint) error {
+	seen := make(map[string]bool, len(endpoints))
+	for _, e := range endpoints {
+		if seen[e.Addr] {
+			return errors.New("discovery: duplicate endpoint " + e.Addr)
+		}
+		seen[e.Addr] = true
+	}
+	return nil
+}
+
+// apply must be called with mu held.
+func (p *Pool) apply(endpoints []Endpoint, change Change) {
+	for _, e := range change.Removed {
+		delete(p.backends, e.Addr)
+	}
+	for _, e := range endpoints {
+		// Backend.Weight is read by pickers without locking, so a weight
+		// change gets a fresh Backend; requests in flight on the old one
+		// are no longer counted.
+		if b, ok := p.backends[e.Addr]; !ok || b.Weight != e.Weight {
+			p.backends[e.Addr] = &loadbalance.Backend{Addr: e.Addr, Weight: e.Weight}
+		}
+	}
+	p.endpoints = endpoints
+	p.healthy = make([]*loadbalance.Backend, 0, len(endpoints))
+	for _, e := range endpoints {
+		if e.Health == Healthy {
+			p.healthy = append(p.healthy, p.backends[e.Addr])
+		}
+	}
+}
+
+// Pick chooses among the Healthy endpoints with the pool's picker. Callers
+// bracket the request with the Backend's Start for load-aware pickers.
+func (p *Pool) Pick() (*loadbalance.Backend, error) {
+	p.mu.RLock()
+	healthy := p.healthy
+	p.mu.RUnlock()
+	return p.options.picker.Pick(healthy)
+}
+
+// Endpoints returns the endpoints of the last successful resolution.
+func (p *Pool) Endpoints() []Endpoint {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return slices.Clone(p.endpoints)
+}
+
+// Err returns the error of the last resolution, nil if it succeeded.
+func (p *Pool) Err() error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.err
+}
+
+// Subscribe calls fn with every non-empty Change, in order, from the
+// goroutine that ran Refresh; fn must not call Refresh. cancel stops the
+// calls.
+func (p *Pool) Subscribe(fn func(Change)) (cancel func()) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	key := &fn
+	p.subs[key] = struct{}{}
+	return func() {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		delete(p.subs, key)
+	}
+}
@@ -0,0 +1,270 @@
+package loadbalance_test
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"patterns/randsource"
+	"patterns/resilience/loadbalance"
+)
+
+func backends(weights ...int) []*loadbalance.Backend {
+	bs := make([]*loadbalance.Backend, len(weights))
+	for i, w := range weights {
+		bs[i] = &loadbalance.Backend{Addr: fmt.Sprint("b", i), Weight: w}
+	}
+	return bs
+}
+
+// spread picks n times and returns how often each backend was chosen.
+func spread(t *testing.T, p loadbalance.Picker, bs []*loadbalance.Backend, n int) []int {
+	t.Helper()
+	index := map[*loadbalance.Backend]int{}
+	for i, b := range bs {
+		index[b] = i
+	}
+	counts := make([]int, len(bs))
+	for range n {
+		b, err := p.Pick(bs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		counts[index[b]]++
+	}
+	return counts
+}
+
+// chiSquare returns the statistic for counts against the expected
+// shares, which sum to one; a zero share must have no counts.
+func chiSquare(counts []int, shares []float64) float64 {
+	n := 0
+	for _, c := range counts {
+		n += c
+	}
+	x := 0.0
+	for i, c := range counts {
+		want := shares[i] * float64(n)
+		if want == 0 {
+			if c > 0 {
+				return 1e9
+			}
+			continue
+		}
+		d := float64(c) - want
+		x += d * d / want
+	}
+	return x
+}
+
+// chi-square values exceeded with probability 1e-6, by degrees of
+// freedom; the seeded tests pass or fail for good
+var chiCritical = map[int]float64{1: 24.0, 2: 27.6, 3: 30.7, 4: 33.4}
+
+func fair(n int) []float64 {
+	s := make([]float64, n)
+	for i := range s {
+		s[i] = 1 / float64(n)
+	}
+	return s
+}
+
+func TestNoBackends(t *testing.T) {
+	for _, p := range []loadbalance.Picker{
+		&loadbalance.Random{}, &loadbalance.RoundRobin{}, &loadbalance.Weighted{},
+		&loadbalance.LeastConnections{}, &loadbalance.PowerOfTwo{},
+	} {
+		if b, err := p.Pick(nil); b != nil || !errors.Is(err, loadbalance.ErrNoBackends) {
+			t.Errorf("%T.Pick(nil) = %v, %v", p, b, err)
+		}
+	}
+}
+
+// TestDistribution checks each picker's spread over idle backends against
+// the share it promises.
+func TestDistribution(t *testing.T) {
+	const n = 100000
+	for _, c := range []struct {
+		name    string
+		p       loadbalance.Picker
+		weights []int
+		shares  []float64
+	}{
+		{"Random", &loadbalance.Random{Rand: randsource.New(1)}, []int{1, 1, 1, 1}, fair(4)},
+		// weights below one count as one
+		{"Weighted", &loadbalance.Weighted{Rand: randsource.New(2)}, []int{0, 1, 2, 3, 4}, []float64{1.0 / 11, 1.0 / 11, 2.0 / 11, 3.0 / 11, 4.0 / 11}},
+		// every backend is idle, so all are tied
+		{"LeastConnections", &loadbalance.LeastConnections{Rand: randsource.New(3)}, []int{1, 1, 1, 1, 1}, fair(5)},
+		{"PowerOfTwo", &loadbalance.PowerOfTwo{Rand: randsource.New(4)}, []int{1, 1, 1}, fair(3)},
+	} {
+		bs := backends(c.weights...)
+		counts := spread(t, c.p, bs, n)
+		if x := chiSquare(counts, c.shares); x > chiCritical[len(bs)-1] {
+			t.Errorf("%s: counts %v, chi-square %.1f, want shares %.3f", c.name, counts, x, c.shares)
+		}
+	}
+}
+
+// TestRoundRobin checks the exact order, and that concurrent pickers
+// share one cycle.
+func TestRoundRobin(t *testing.T) {
+	bs := backends(1, 1, 1)
+	var rr loadbalance.RoundRobin
+	var got []string
+	for range 7 {
+		b, _ := rr.Pick(bs)
+		got = append(got, b.Addr)
+	}
+	if fmt.Sprint(got) != "[b0 b1 b2 b0 b1 b2 b0]" {
+		t.Errorf("picks %v", got)
+	}
+
+	var mu sync.Mutex
+	counts := map[string]int{}
+	var wg sync.WaitGroup
+	for range 6 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 999 {
+				b, _ := rr.Pick(bs)
+				mu.Lock()
+				counts[b.Addr]++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	// 6*999 picks are whole cycles wherever they start
+	if counts["b0"] != 1998 || counts["b1"] != 1998 || counts["b2"] != 1998 {
+		t.Errorf("concurrent counts %v, want 1998 each", counts)
+	}
+}
+
+// load starts n requests on each backend, and returns the func ending
+// them.
+func load(bs []*loadbalance.Backend, n ...int) func() {
+	var dones []func()
+	for i, b := range bs {
+		for range n[i] {
+			dones = append(dones, b.Start())
+		}
+	}
+	return func() {
+		for _, d := range dones {
+			d()
+		}
+	}
+}
+
+func TestLeastConnections(t *testing.T) {
+	bs := backends(1, 1, 1, 1)
+	end := load(bs, 3, 1, 2, 1)
+	defer end()
+	// b1 and b3 are tied for least: each gets half
+	counts := spread(t, &loadbalance.LeastConnections{Rand: randsource.New(5)}, bs, 20000)
+	if x := chiSquare(counts, []float64{0, 0.5, 0, 0.5}); x > chiCritical[1] {
+		t.Errorf("counts %v, want b1 and b3 only, evenly", counts)
+	}
+}
+
+// TestPowerOfTwo checks that each pair is sampled evenly and the less
+// loaded of it wins: with distinct loads, a backend is chosen in
+// proportion to how many others are busier, and the busiest never is.
+func TestPowerOfTwo(t *testing.T) {
+	bs := backends(1, 1, 1, 1)
+	end := load(bs, 2, 0, 3, 1)
+	defer end()
+	counts := spread(t, &loadbalance.PowerOfTwo{Rand: randsource.New(6)}, bs, 60000)
+	// of the six pairs, b1 wins the 3 it is in, b3 2, b0 1, b2 none
+	if x := chiSquare(counts, []float64{1.0 / 6, 3.0 / 6, 0, 2.0 / 6}); x > chiCritical[2] {
+		t.Errorf("counts %v, want shares 1/6, 3/6, 0, 2/6", counts)
+	}
+
+	one := backends(1)
+	if b, err := (&loadbalance.PowerOfTwo{}).Pick(one); b != one[0] || err != nil {
+		t.Errorf("Pick of one backend = %v, %v", b, err)
+	}
+}
+
+func TestStart(t *testing.T) {
+	b := &loadbalance.Backend{}
+	done := b.Start()
+	b.Start()
+	done()
+	done()
+	if b.Active() != 1 {
+		t.Errorf("Active = %d after two starts and a repeated done, want 1", b.Active())
+	}
+}
+
+// simulate sends two requests per tick to four single-server backends,
+// the last ten times slower than the rest, and returns the slow one's
+// share of requests and the mean latency in ticks. Requests are bracketed
+// by Start and done, as the load-aware pickers need.
+func simulate(t *testing.T, p loadbalance.Picker, requests int) (slowShare, meanLatency float64) {
+	t.Helper()
+	bs := backends(1, 1, 1, 1)
+	service := []int{1, 1, 1, 10}
+	type inflight struct {
+		finish int
+		done   func()
+	}
+	queues := make([][]inflight, len(bs))
+	free := make([]int, len(bs))
+	slow, latency := 0, 0
+	for sent, tick := 0, 0; sent < requests; tick++ {
+		for i, q := range queues {
+			for len(q) > 0 && q[0].finish <= tick {
+				q[0].done()
+				q = q[1:]
+			}
+			queues[i] = q
+		}
+		for range 2 {
+			b, err := p.Pick(bs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			i := int(b.Addr[1] - '0')
+			finish := max(tick, free[i]) + service[i]
+			free[i] = finish
+			queues[i] = append(queues[i], inflight{finish, b.Start()})
+			latency += finish - tick
+			if i == 3 {
+				slow++
+			}
+			sent++
+		}
+	}
+	return float64(slow) / float64(requests), float64(latency) / float64(requests)
+}
+
+// TestSlowBackend checks the package's findings: the load-blind pickers
+// give a slow backend its full share and its queue runs away, the
+// load-aware ones steer around it.
+func TestSlowBackend(t *testing.T) {
+	const n = 100000
+	for _, c := range []struct {
+		name               string
+		p                  loadbalance.Picker
+		minShare, maxShare float64
+		// load-blind pickers queue without bound on the slow backend
+		runaway bool
+	}{
+		{"Random", &loadbalance.Random{Rand: randsource.New(7)}, 0.24, 0.26, true},
+		{"RoundRobin", &loadbalance.RoundRobin{}, 0.25, 0.25, true},
+		{"LeastConnections", &loadbalance.LeastConnections{Rand: randsource.New(8)}, 0, 0.10, false},
+		{"PowerOfTwo", &loadbalance.PowerOfTwo{Rand: randsource.New(9)}, 0, 0.10, false},
+	} {
+		share, latency := simulate(t, c.p, n)
+		t.Logf("%s: slow backend share %.1f%%, mean latency %.1f ticks", c.name, share*100, latency)
+		if share < c.minShare || share > c.maxShare {
+			t.Errorf("%s: slow backend share %.3f, want [%.2f, %.2f]", c.name, share, c.minShare, c.maxShare)
+		}
+		if c.runaway && latency < 1000 || !c.runaway && latency > 3 {
+			t.Errorf("%s: mean latency %.1f ticks, runaway queue %v", c.name, latency, c.runaway)
+		}
+	}
+}
@@ -0,0 +1,251 @@
+// Package policy composes the resilience steps, a timeout, retries, a
+// circuit breaker and a fallback, into one Policy applied to any
+// func(ctx) (T, error):
+//
+//	p, err := policy.New[Quote](
+//		policy.Fallback(cachedQuote),
+//		policy.Timeout(2*time.Second),
+//		policy.Retry(retrier),
+//		policy.Breaker(breaker),
+//		policy.Timeout(300*time.Millisecond),
+//	)
+//	q, err := p.Do(ctx, func(ctx context.Context) (Quote, error) {
+//		return pricing.Quote(ctx, sku)
+//	})
+//
+// Steps are listed outermost first, like middleware.Chain, and the order
+// is the meaning. Each step sees only what the steps inside it return:
+//
+//   - Retry outside Breaker: every attempt counts against the breaker,
+//     and once it opens, ErrOpen ends the retries at once instead of
+//     spending the remaining attempts on refusals.
+//   - Breaker outside Retry: the breaker sees one result per call, all
+//     attempts together, so it opens only after threshold calls have each
+//     exhausted their retries; an open breaker refuses before the first.
+//   - Timeout outside Retry: one deadline for all attempts and the waits
+//     between them. Retry outside Timeout: a deadline per attempt, and a
+//     slow attempt is cut and tried again. Both together, the inner one
+//     shorter, is the usual setup, as above.
+//   - Timeout inside Breaker: a timed-out attempt counts as a failure,
+//     which is how a breaker learns about a dependency that hangs rather
+//     than fails. Outside it, the breaker sees whatever the operation
+//     returns when its context ends, which also counts, by default.
+//   - Fallback outermost turns whatever is left, ErrOpen, ErrTimeout or
+//     the exhausted retries' error, into a value.
+//
+// New refuses orders that cannot mean anything: a fallback that is not
+// outermost, since the steps around it would never see a failure; a
+// second retry or breaker; and a timeout inside a shorter one, which
+// could never fire.
+//
+// Timeouts are cooperative: the step cancels the operation's context and
+// waits for it to return, so an operation that ignores its context is
+// not cut.
+package policy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"patterns/resilience/circuitbreaker"
+	"patterns/resilience/retry"
+)
+
+// ErrTimeout is returned by a Timeout step whose deadline passed while
+// its caller's context was still live. It is not a context error, so a
+// Retry outside the step retries it.
+var ErrTimeout = errors.New("policy: timeout")
+
+type kind int
+
+const (
+	kindTimeout kind = iota
+	kindRetry
+	kindBreaker
+	kindFallback
+)
+
+func (k kind) String() string {
+	return [...]string{"timeout", "retry", "breaker", "fallback"}[k]
+}
+
+// Step is one layer of a Policy.
+type Step struct {
+	kind     kind
+	timeout  time.Duration
+	retrier  *retry.Retrier
+	breaker  *circuitbreaker.Breaker
+	fallback any // func(context.Context, error) (T, error)
+}
+
+func (s Step) String() string {
+	if s.kind == kindTimeout {
+		return fmt.Sprintf("timeout(%v)", s.timeout)
+	}
+	return s.kind.String()
+}
+
+// Timeout gives the steps inside it d to finish.
+func Timeout(d time.Duration) Step { return Step{kind: kindTimeout, timeout: d} }
+
+// Retry runs the steps inside it under r.
+func Retry(r *retry.Retrier) Step { return Step{kind: kindRetry, retrier: r} }
+
+// Breaker runs the steps inside it through b.
+func Breaker(b *circuitbreaker.Breaker) Step { return Step{kind: kindBreaker, breaker: b} }
+
+// Fallback answers for a failed call with f, which gets the error and
+// may return one of its own. It is not called when the caller's context
+// has ended: the caller is not waiting for a value.
+func Fallback[T any](f func(ctx context.Context, err error) (T, error)) Step {
+	return Step{kind: kindFallback, fallback: f}
+}
+
+// Func is an operation a Policy runs.
+type Func[T any] func(ctx context.Context) (T, error)
+
+// resilience policy
+// Level: Good
+// pros: the whole stack is one declared value, checked when built; the
+// order is visible in one place, and the semantics of each order are
+// documented instead of rediscovered.
+// cons: one more layer between the caller and the steps it composes;
+// timeouts rely on the operation honouring its context.
+//
+// Policy is a validated stack of steps. It holds no state of its own, so
+// it is safe for concurrent use as far as its retrier and breaker are.
+type Policy[T any] struct {
+	steps []Step
+	wrap  func(Func[T]) Func[T]
+}
+
+// New validates steps, outermost first, and composes them.
+func New[T any](steps ...Step) (*Policy[T], error) {
+	seen := map[kind]bool{}
+	var outer time.Duration // the innermost timeout so far
+	for i, s := range steps {
+		switch s.kind {
+		case kindTimeout:
+			if s.timeout <= 0 {
+				return nil, errors.New("timeout must be positive")
+			}
+			if outer > 0 && s.timeout >= outer {
+				return nil, fmt.Errorf("timeout %v is inside a timeout of %v and can never fire", s.timeout, outer)
+			}
+			outer = s.timeout
+			continue
+		case kindRetry:
+			if s.retrier == nil {
+				return nil, errors.New("retrier cannot be nil")
+			}
+		case kindBreaker:
+			if s.breaker == nil {
+				return nil, errors.New("breaker cannot be nil")
+			}
+		case kindFallback:
+			if i != 0 {
+				return nil, errors.New("fallback must be the outermost step: the steps outside it would never see a failure")
+			}
+			f, ok := s.fallback.(func(context.Context, error) (T, error))
+			if !ok {
+				var zero T
+				return nil, fmt.Errorf("fallback is a %T, want one returning %T", s.fallback, zero)
+			}
+			if f == nil {
+				return nil, errors.New("fallback cannot be nil")
+			}
+		}
+		if seen[s.kind] {
+			return nil, fmt.Errorf("%v given twice", s.kind)
+		}
+		seen[s.kind] = true
+	}
+
+	retryAt := slices.IndexFunc(steps, func(s Step) bool { return s.kind == kindRetry })
+	p := &Policy[T]{steps: steps}
+	p.wrap = func(fn Func[T]) Func[T] {
+		for i := len(steps) - 1; i >= 0; i-- {
+			fn = layer(steps[i], retryAt >= 0 && retryAt < i, fn)
+		}
+		return fn
+	}
+	return p, nil
+}
+
+// layer is s around next; retried is whether a Retry step encloses s.
+func layer[T any](s Step, retried bool, next Func[T]) Func[T] {
+	switch s.kind {
+	case kindTimeout:
+		return func(ctx context.Context) (T, error) {
+			tctx, cancel := context.WithTimeoutCause(ctx, s.timeout, ErrTimeout)
+			defer cancel()
+			v, err := next(tctx)
+			if err != nil && tctx.Err() != nil && ctx.Err() == nil {
+				var zero T
+				return zero, fmt.Errorf("%w after %v", ErrTimeout, s.timeout)
+			}
+			return v, err
+		}
+	case kindRetry:
+		return func(ctx context.Context) (T, error) {
+			var v T
+			err := s.retrier.Do(ctx, func(ctx context.Context) error {
+				var err error
+				v, err = next(ctx)
+				return err
+			})
+			if err != nil {
+				var zero T
+				return zero, err
+			}
+			return v, nil
+		}
+	case kindBreaker:
+		return func(ctx context.Context) (T, error) {
+			var v T
+			err := s.breaker.Do(ctx, func(ctx context.Context) error {
+				var err error
+				v, err = next(ctx)
+				return err
+			})
+			if err == circuitbreaker.ErrOpen && retried {
+				// refusals will not stop before the cooldown does
+				return v, retry.Permanent(err)
+			}
+			return v, err
+		}
+	default:
+		f := s.fallback.(func(context.Context, error) (T, error))
+		return func(ctx context.Context) (T, error) {
+			v, err := next(ctx)
+			if err == nil || ctx.Err() != nil {
+				return v, err
+			}
+			return f(ctx, err)
+		}
+	}
+}
+
+// Do runs fn through every step.
+func (p *Policy[T]) Do(ctx context.Context, fn func(ctx context.Context) (T, error)) (T, error) {
+	return p.wrap(fn)(ctx)
+}
+
+// Wrap returns fn with the policy applied, to be called later, any number
+// of times.
+func (p *Policy[T]) Wrap(fn func(ctx context.Context) (T, error)) Func[T] {
+	return p.wrap(fn)
+}
+
+// String lists the steps, outermost first.
+func (p *Policy[T]) String() string {
+	names := make([]string, len(p.steps))
+	for i, s := range p.steps {
+		names[i] = s.String()
+	}
+	return strings.Join(names, " → ")
+}
@@ -0,0 +1,304 @@
+package policy_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"patterns/clock"
+	"patterns/resilience/circuitbreaker"
+	"patterns/resilience/policy"
+	"patterns/resilience/retry"
+)
+
+var errDown = errors.New("down")
+
+func retrier(t *testing.T, attempts int) *retry.Retrier {
+	t.Helper()
+	r, err := retry.New(retry.WithAttempts(attempts), retry.WithBackoff(retry.Constant(0)), retry.WithJitter(retry.None))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func breaker(t *testing.T, threshold int) (*circuitbreaker.Breaker, *clock.Fake) {
+	t.Helper()
+	fake := clock.NewFake(time.Unix(0, 0))
+	b, err := circuitbreaker.New(circuitbreaker.WithThreshold(threshold), circuitbreaker.WithCooldown(time.Minute), circuitbreaker.WithClock(fake))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b, fake
+}
+
+func build(t *testing.T, steps ...policy.Step) *policy.Policy[string] {
+	t.Helper()
+	p, err := policy.New[string](steps...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+// op is an operation counting its calls: each fails with errDown, or
+// hangs until its context ends, or answers "ok", as the test says.
+type op struct {
+	calls  atomic.Int32
+	answer func(n int) string // "fail", "hang" or "ok" for call n, from 1
+}
+
+func (o *op) run(ctx context.Context) (string, error) {
+	switch o.answer(int(o.calls.Add(1))) {
+	case "fail":
+		return "", errDown
+	case "hang":
+		<-ctx.Done()
+		return "", ctx.Err()
+	}
+	return "ok", nil
+}
+
+func always(answer string) *op { return &op{answer: func(int) string { return answer }} }
+
+// TestRetryOutsideBreaker checks that the attempts count against the
+// breaker, and that once it opens the retries end at once, with ErrOpen.
+func TestRetryOutsideBreaker(t *testing.T) {
+	b, fake := breaker(t, 2)
+	p := build(t, policy.Retry(retrier(t, 5)), policy.Breaker(b))
+	o := always("fail")
+	_, err := p.Do(context.Background(), o.run)
+	var ex *retry.ExhaustedError
+	if err != circuitbreaker.ErrOpen || errors.As(err, &ex) || o.calls.Load() != 2 {
+		t.Errorf("Do = %v after %d calls, want ErrOpen after 2", err, o.calls.Load())
+	}
+
+	// after the cooldown, the one trial fails and reopens the breaker:
+	// the retry behind it stops again
+	fake.Advance(time.Minute)
+	o.calls.Store(0)
+	if _, err := p.Do(context.Background(), o.run); err != circuitbreaker.ErrOpen || o.calls.Load() != 1 {
+		t.Errorf("half open: %v after %d calls", err, o.calls.Load())
+	}
+
+	fake.Advance(time.Minute)
+	o = &op{answer: func(n int) string { return "ok" }}
+	if v, err := p.Do(context.Background(), o.run); v != "ok" || err != nil || b.State() != circuitbreaker.StateClosed {
+		t.Errorf("recovered: %q, %v, breaker %s", v, err, b.State())
+	}
+}
+
+// TestBreakerOutsideRetry checks that the breaker sees one result per
+// call, all attempts together, and refuses before the first attempt.
+func TestBreakerOutsideRetry(t *testing.T) {
+	b, _ := breaker(t, 2)
+	p := build(t, policy.Breaker(b), policy.Retry(retrier(t, 3)))
+	o := always("fail")
+	for i := 1; i <= 2; i++ {
+		_, err := p.Do(context.Background(), o.run)
+		var ex *retry.ExhaustedError
+		if !errors.As(err, &ex) || ex.Attempts != 3 || o.calls.Load() != int32(3*i) {
+			t.Fatalf("call %d: %v after %d attempts", i, err, o.calls.Load())
+		}
+	}
+	if b.State() != circuitbreaker.StateOpen {
+		t.Fatalf("breaker %s after two exhausted calls", b.State())
+	}
+	if _, err := p.Do(context.Background(), o.run); err != circuitbreaker.ErrOpen || o.calls.Load() != 6 {
+		t.Errorf("open: %v after %d attempts", err, o.calls.Load())
+	}
+
+	// a call that succeeds on a retry is a success to the breaker
+	b, _ = breaker(t, 1)
+	p = build(t, policy.Breaker(b), policy.Retry(retrier(t, 3)))
+	o = &op{answer: func(n int) string { return map[bool]string{true: "ok", false: "fail"}[n == 3] }}
+	if v, err := p.Do(context.Background(), o.run); v != "ok" || err != nil || b.State() != circuitbreaker.StateClosed {
+		t.Errorf("Do = %q, %v; breaker %s", v, err, b.State())
+	}
+}
+
+// TestTimeoutOutsideRetry checks that one deadline covers every attempt:
+// an attempt cut by it is not retried.
+func TestTimeoutOutsideRetry(t *testing.T) {
+	p := build(t, policy.Timeout(20*time.Millisecond), policy.Retry(retrier(t, 5)))
+	o := always("hang")
+	_, err := p.Do(context.Background(), o.run)
+	if !errors.Is(err, policy.ErrTimeout) || err.Error() != "policy: timeout after 20ms" || o.calls.Load() != 1 {
+		t.Errorf("Do = %v after %d attempts", err, o.calls.Load())
+	}
+
+	// nor, though it is not a context error, is the wait between attempts
+	r, _ := retry.New(retry.WithAttempts(5), retry.WithBackoff(retry.Constant(time.Hour)))
+	p = build(t, policy.Timeout(20*time.Millisecond), policy.Retry(r))
+	o = always("fail")
+	start := time.Now()
+	if _, err := p.Do(context.Background(), o.run); !errors.Is(err, policy.ErrTimeout) || o.calls.Load() != 1 || time.Since(start) > time.Second {
+		t.Errorf("Do = %v after %d attempts and %v", err, o.calls.Load(), time.Since(start))
+	}
+}
+
+// TestRetryOutsideTimeout checks that each attempt has its own deadline:
+// a slow one is cut and tried again.
+func TestRetryOutsideTimeout(t *testing.T) {
+	p := build(t, policy.Retry(retrier(t, 3)), policy.Timeout(10*time.Millisecond))
+	o := &op{answer: func(n int) string { return map[bool]string{true: "ok", false: "hang"}[n == 3] }}
+	if v, err := p.Do(context.Background(), o.run); v != "ok" || err != nil || o.calls.Load() != 3 {
+		t.Errorf("Do = %q, %v after %d attempts", v, err, o.calls.Load())
+	}
+	o = always("hang")
+	_, err := p.Do(context.Background(), o.run)
+	var ex *retry.ExhaustedError
+	if !errors.As(err, &ex) || !errors.Is(err, policy.ErrTimeout) || o.calls.Load() != 3 {
+		t.Errorf("Do = %v after %d attempts", err, o.calls.Load())
+	}
+}
+
+// TestBothTimeouts is the usual setup: attempts are cut at the inner
+// timeout, and retried until the outer one.
+func TestBothTimeouts(t *testing.T) {
+	p := build(t, policy.Timeout(100*time.Millisecond), policy.Retry(retrier(t, 1000)), policy.Timeout(10*time.Millisecond))
+	o := always("hang")
+	start := time.Now()
+	_, err := p.Do(context.Background(), o.run)
+	if !errors.Is(err, policy.ErrTimeout) || err.Error() != "policy: timeout after 100ms" {
+		t.Errorf("Do = %v", err)
+	}
+	if n := o.calls.Load(); n < 2 || n > 10 || time.Since(start) > time.Second {
+		t.Errorf("%d attempts in %v", n, time.Since(start))
+	}
+}
+
+// TestTimeoutAndBreaker checks that a dependency that hangs opens the
+// breaker, with the timeout inside it or out.
+func TestTimeoutAndBreaker(t *testing.T) {
+	for _, c := range []struct {
+		name  string
+		steps func(b *circuitbreaker.Breaker) []policy.Step
+	}{
+		{"inside", func(b *circuitbreaker.Breaker) []policy.Step {
+			return []policy.Step{policy.Breaker(b), policy.Timeout(10 * time.Millisecond)}
+		}},
+		// the breaker sees the operation's context error
+		{"outside", func(b *circuitbreaker.Breaker) []policy.Step {
+			return []policy.Step{policy.Timeout(10 * time.Millisecond), policy.Breaker(b)}
+		}},
+	} {
+		b, _ := breaker(t, 2)
+		p := build(t, c.steps(b)...)
+		o := always("hang")
+		for range 2 {
+			if _, err := p.Do(context.Background(), o.run); !errors.Is(err, policy.ErrTimeout) {
+				t.Errorf("%s: Do = %v", c.name, err)
+			}
+		}
+		if _, err := p.Do(context.Background(), o.run); err != circuitbreaker.ErrOpen || o.calls.Load() != 2 {
+			t.Errorf("%s: Do = %v after %d calls, want ErrOpen", c.name, err, o.calls.Load())
+		}
+	}
+
+	// the caller giving up is no timeout, and says nothing about the
+	// dependency
+	b, _ := breaker(t, 1)
+	p := build(t, policy.Breaker(b), policy.Timeout(time.Hour))
+	ctx, cancel := context.WithCancel(context.Background())
+	o := &op{answer: func(int) string { cancel(); return "hang" }}
+	if _, err := p.Do(ctx, o.run); err != context.Canceled || b.State() != circuitbreaker.StateClosed {
+		t.Errorf("cancelled: %v, breaker %s", err, b.State())
+	}
+}
+
+func TestFallback(t *testing.T) {
+	b, _ := breaker(t, 2)
+	var seen []error
+	fallback := policy.Fallback(func(_ context.Context, err error) (string, error) {
+		seen = append(seen, err)
+		if errors.Is(err, circuitbreaker.ErrOpen) {
+			return "", errors.New("no quote")
+		}
+		return "cached", nil
+	})
+	p := build(t, fallback, policy.Retry(retrier(t, 2)), policy.Breaker(b))
+	o := always("fail")
+	if v, err := p.Do(context.Background(), o.run); v != "cached" || err != nil {
+		t.Errorf("Do = %q, %v", v, err)
+	}
+	if _, err := p.Do(context.Background(), o.run); err == nil || err.Error() != "no quote" {
+		t.Errorf("fallback's own error: %v", err)
+	}
+	// the first call exhausts the retries, opening the breaker on the
+	// last; the second is refused
+	var ex *retry.ExhaustedError
+	if len(seen) != 2 || !errors.As(seen[0], &ex) || !errors.Is(seen[0], errDown) || seen[1] != circuitbreaker.ErrOpen || o.calls.Load() != 2 {
+		t.Errorf("fallback saw %v after %d calls", seen, o.calls.Load())
+	}
+
+	// a success, or a caller who left, gets no fallback
+	seen = nil
+	p = build(t, fallback, policy.Timeout(time.Hour))
+	if v, err := p.Do(context.Background(), always("ok").run); v != "ok" || err != nil {
+		t.Errorf("Do = %q, %v", v, err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := p.Do(ctx, always("hang").run); err != context.Canceled || seen != nil {
+		t.Errorf("cancelled: %v, fallback saw %v", err, seen)
+	}
+	// a timeout is a failure like any other
+	p = build(t, fallback, policy.Timeout(10*time.Millisecond))
+	if v, err := p.Do(context.Background(), always("hang").run); v != "cached" || err != nil || !errors.Is(seen[0], policy.ErrTimeout) {
+		t.Errorf("timed out: %q, %v; fallback saw %v", v, err, seen)
+	}
+}
+
+func TestNew(t *testing.T) {
+	b, _ := breaker(t, 1)
+	r := retrier(t, 2)
+	fallback := policy.Fallback(func(context.Context, error) (string, error) { return "", nil })
+	for _, c := range []struct {
+		steps []policy.Step
+		want  string
+	}{
+		{[]policy.Step{policy.Retry(r), fallback}, "fallback must be the outermost step: the steps outside it would never see a failure"},
+		{[]policy.Step{policy.Fallback(func(context.Context, error) (int, error) { return 0, nil })}, "fallback is a func(context.Context, error) (int, error), want one returning string"},
+		{[]policy.Step{policy.Fallback[string](nil)}, "fallback cannot be nil"},
+		{[]policy.Step{policy.Retry(r), policy.Breaker(b), policy.Retry(r)}, "retry given twice"},
+		{[]policy.Step{policy.Breaker(b), policy.Breaker(b)}, "breaker given twice"},
+		{[]policy.Step{policy.Retry(nil)}, "retrier cannot be nil"},
+		{[]policy.Step{policy.Breaker(nil)}, "breaker cannot be nil"},
+		{[]policy.Step{policy.Timeout(0)}, "timeout must be positive"},
+		{[]policy.Step{policy.Timeout(time.Second), policy.Retry(r), policy.Timeout(time.Second)}, "timeout 1s is inside a timeout of 1s and can never fire"},
+		{[]policy.Step{policy.Timeout(time.Second), policy.Timeout(100 * time.Millisecond), policy.Timeout(200 * time.Millisecond)},
+			"timeout 200ms is inside a timeout of 100ms and can never fire"},
+	} {
+		if p, err := policy.New[string](c.steps...); p != nil || err == nil || err.Error() != c.want {
+			t.Errorf("New = %v, %v; want %q", p, err, c.want)
+		}
+	}
+
+	p := build(t, fallback, policy.Timeout(2*time.Second), policy.Retry(r), policy.Breaker(b), policy.Timeout(300*time.Millisecond))
+	if got, want := p.String(), "fallback → timeout(2s) → retry → breaker → timeout(300ms)"; got != want {
+		t.Errorf("String = %q, want %q", got, want)
+	}
+
+	// no steps: the operation as it is
+	o := always("fail")
+	if _, err := build(t).Do(context.Background(), o.run); err != errDown || o.calls.Load() != 1 {
+		t.Errorf("empty policy: %v after %d calls", err, o.calls.Load())
+	}
+}
+
+func TestWrap(t *testing.T) {
+	p := build(t, policy.Retry(retrier(t, 2)))
+	o := &op{answer: func(n int) string { return map[bool]string{true: "ok", false: "fail"}[n%2 == 0] }}
+	f := p.Wrap(o.run)
+	for range 3 {
+		if v, err := f(context.Background()); v != "ok" || err != nil {
+			t.Errorf("wrapped = %q, %v", v, err)
+		}
+	}
+	if o.calls.Load() != 6 {
+		t.Errorf("%d calls, want 6", o.calls.Load())
+	}
+}
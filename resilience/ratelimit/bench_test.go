@@ -0,0 +1,132 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"patterns/clock"
+	"patterns/idioms/must"
+)
+
+const (
+	benchRate  = 10
+	benchBurst = 10
+)
+
+// limiters builds each limiter at the same rate and burst on c.
+var limiters = []struct {
+	name string
+	new  func(rate float64, burst int, c clock.Clock) Limiter
+}{
+	{"tokenbucket", func(rate float64, burst int, c clock.Clock) Limiter {
+		return must.Must(NewTokenBucket(rate, burst, WithClock(c)))
+	}},
+	{"leakybucket", func(rate float64, burst int, c clock.Clock) Limiter {
+		return must.Must(NewLeakyBucket(rate, burst-1, WithClock(c)))
+	}},
+	{"slidinglog", func(rate float64, burst int, c clock.Clock) Limiter {
+		return must.Must(NewSlidingLog(burst, window(rate, burst), WithClock(c)))
+	}},
+	{"slidingwindow", func(rate float64, burst int, c clock.Clock) Limiter {
+		return must.Must(NewSlidingWindow(burst, window(rate, burst), WithClock(c)))
+	}},
+}
+
+// window is the span in which burst events at rate fit.
+func window(rate float64, burst int) time.Duration {
+	return time.Duration(float64(burst) / rate * float64(time.Second))
+}
+
+// replay offers n events at once every step, rounds times, and returns
+// when each admitted one arrived.
+func replay(l Limiter, c *clock.Fake, n, rounds int, step time.Duration) []time.Time {
+	var admitted []time.Time
+	for range rounds {
+		for range n {
+			if l.Allow() {
+				admitted = append(admitted, c.Now())
+			}
+		}
+		c.Advance(step)
+	}
+	return admitted
+}
+
+// most is the largest number of times within any span-long interval.
+func most(times []time.Time, span time.Duration) int {
+	best, lo := 0, 0
+	for hi, t := range times {
+		for t.Sub(times[lo]) >= span {
+			lo++
+		}
+		best = max(best, hi-lo+1)
+	}
+	return best
+}
+
+func benchBurstShape(newLimiter func(float64, int, clock.Clock) Limiter) func(b *testing.B) {
+	return func(b *testing.B) {
+		var first, peak int
+		for range b.N {
+			c := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+			l := newLimiter(benchRate, benchBurst, c)
+			// idle, then bursts from just before a window boundary, where
+			// the sliding window's estimate is at its weakest
+			c.Advance(time.Minute + 900*time.Millisecond)
+			start := c.Now()
+			admitted := replay(l, c, 50, 30, 100*time.Millisecond)
+			first = 0
+			for first < len(admitted) && admitted[first].Equal(start) {
+				first++
+			}
+			peak = most(admitted, time.Second)
+		}
+		b.ReportMetric(float64(first), "admitted-of-50")
+		b.ReportMetric(float64(peak), "max/s")
+	}
+}
+
+func benchAllow(newLimiter func(float64, int, clock.Clock) Limiter) func(b *testing.B) {
+	return func(b *testing.B) {
+		// a rate high enough that the limiter is always deciding, not
+		// refusing on an empty bucket
+		l := newLimiter(1e9, 1000, clock.Real)
+		for range b.N {
+			l.Allow()
+		}
+	}
+}
+
+func benchAllowParallel(newLimiter func(float64, int, clock.Clock) Limiter) func(b *testing.B) {
+	return func(b *testing.B) {
+		l := newLimiter(1e9, 1000, clock.Real)
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				l.Allow()
+			}
+		})
+	}
+}
+
+// BenchmarkBurst offers 50 events every 100ms to each limiter at 10/s
+// with bursts of 10, reporting how many of the first 50 it admits and the
+// most it admits in any one second.
+func BenchmarkBurst(b *testing.B) {
+	for _, l := range limiters {
+		b.Run(l.name, benchBurstShape(l.new))
+	}
+}
+
+// BenchmarkAllow measures Allow alone.
+func BenchmarkAllow(b *testing.B) {
+	for _, l := range limiters {
+		b.Run(l.name, benchAllow(l.new))
+	}
+}
+
+// BenchmarkAllowParallel measures Allow under contention.
+func BenchmarkAllowParallel(b *testing.B) {
+	for _, l := range limiters {
+		b.Run(l.name, benchAllowParallel(l.new))
+	}
+}
@@ -0,0 +1,139 @@
+package ratelimit
+
+import (
+	"math/rand/v2"
+	"testing"
+	"time"
+
+	"patterns/clock"
+)
+
+func newFake() *clock.Fake { return clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)) }
+
+// TestBurstShape pins down the burst findings in the package doc: each
+// limiter at 10/s with bursts of 10 is offered 50 events every 100ms,
+// after a quiet spell ending just before a window boundary.
+func TestBurstShape(t *testing.T) {
+	want := map[string]struct{ first, peak int }{
+		"tokenbucket":   {10, 19},
+		"leakybucket":   {1, 10},
+		"slidinglog":    {10, 10},
+		"slidingwindow": {10, 18},
+	}
+	for _, l := range limiters {
+		c := newFake()
+		lim := l.new(benchRate, benchBurst, c)
+		c.Advance(time.Minute + 900*time.Millisecond)
+		start := c.Now()
+		admitted := replay(lim, c, 50, 30, 100*time.Millisecond)
+		first := 0
+		for first < len(admitted) && admitted[first].Equal(start) {
+			first++
+		}
+		peak := most(admitted, time.Second)
+		if w := want[l.name]; first != w.first || peak != w.peak {
+			t.Errorf("%s: admitted %d of the first 50, at most %d in a second; want %d, %d",
+				l.name, first, peak, w.first, w.peak)
+		}
+		// over 3s of saturation all four settle on the rate
+		if n := len(admitted); n < 30 || n > 30+benchBurst {
+			t.Errorf("%s: admitted %d in 3s, want 30 to %d", l.name, n, 30+benchBurst)
+		}
+	}
+}
+
+// TestLeakyBucketSpacing checks that admitted events are never closer
+// than an interval, however they arrive.
+func TestLeakyBucketSpacing(t *testing.T) {
+	c := newFake()
+	b, err := NewLeakyBucket(benchRate, 0, WithClock(c))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := rand.New(rand.NewPCG(1, 1))
+	var last time.Time
+	for range 2000 {
+		if b.Allow() {
+			if !last.IsZero() && c.Now().Sub(last) < 100*time.Millisecond {
+				t.Fatalf("events %v apart, want at least 100ms", c.Now().Sub(last))
+			}
+			last = c.Now()
+		}
+		c.Advance(time.Duration(r.IntN(60)) * time.Millisecond)
+	}
+}
+
+// TestLeakyBucketTake checks that Take queues a burst into evenly spaced
+// waits instead of admitting it, and refuses beyond the queue.
+func TestLeakyBucketTake(t *testing.T) {
+	c := newFake()
+	b, err := NewLeakyBucket(benchRate, 3, WithClock(c))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range 4 {
+		wait, ok := b.Take()
+		if want := time.Duration(i) * 100 * time.Millisecond; !ok || wait != want {
+			t.Errorf("Take %d = %v, %v; want %v, true", i, wait, ok, want)
+		}
+	}
+	if _, ok := b.Take(); ok {
+		t.Error("Take past the queue succeeded")
+	}
+	if b.Allow() {
+		t.Error("Allow with events queued succeeded")
+	}
+	c.Advance(400 * time.Millisecond)
+	if !b.Allow() {
+		t.Error("Allow after the queue drained failed")
+	}
+}
+
+// TestSlidingLimits offers random traffic to both sliding limiters: the
+// log never admits more than the limit in any window, the counter never
+// more than twice that, and after a full idle window both admit a whole
+// limit at once, unlike the leaky bucket.
+func TestSlidingLimits(t *testing.T) {
+	const limit = 10
+	for _, c := range []struct {
+		name string
+		max  int
+		new  func(c clock.Clock) Limiter
+	}{
+		{"slidinglog", limit, func(c clock.Clock) Limiter {
+			l, _ := NewSlidingLog(limit, time.Second, WithClock(c))
+			return l
+		}},
+		{"slidingwindow", 2 * limit, func(c clock.Clock) Limiter {
+			w, _ := NewSlidingWindow(limit, time.Second, WithClock(c))
+			return w
+		}},
+	} {
+		fake := newFake()
+		l := c.new(fake)
+		r := rand.New(rand.NewPCG(2, 2))
+		var admitted []time.Time
+		for range 5000 {
+			for range r.IntN(4) {
+				if l.Allow() {
+					admitted = append(admitted, fake.Now())
+				}
+			}
+			fake.Advance(time.Duration(r.IntN(40)) * time.Millisecond)
+		}
+		if got := most(admitted, time.Second); got > c.max {
+			t.Errorf("%s: %d admitted in one second, want at most %d", c.name, got, c.max)
+		}
+
+		fake.Advance(2 * time.Second)
+		n := 0
+		for range 3 * limit {
+			if l.Allow() {
+				n++
+			}
+		}
+		if n != limit {
+			t.Errorf("%s: %d of a burst admitted after idling, want %d", c.name, n, limit)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package ratelimit_test
+
+import (
+	"testing"
+	"time"
+
+	"patterns/clock"
+	"patterns/idioms/must"
+	"patterns/resilience/ratelimit"
+	"patterns/testing/contracts"
+)
+
+// window is the span a log or window limiter needs to hold rate with
+// bursts of burst.
+func window(rate float64, burst int) time.Duration {
+	return time.Duration(float64(burst) / rate * float64(time.Second))
+}
+
+func TestLimiterContract(t *testing.T) {
+	for _, impl := range []struct {
+		name string
+		new  contracts.LimiterFactory
+	}{
+		{"TokenBucket", func(_ testing.TB, c clock.Clock, rate float64, burst int) ratelimit.Limiter {
+			return must.Must(ratelimit.NewTokenBucket(rate, burst, ratelimit.WithClock(c)))
+		}},
+		// the queue holds what waits behind the one being let out
+		{"LeakyBucket", func(_ testing.TB, c clock.Clock, rate float64, burst int) ratelimit.Limiter {
+			return must.Must(ratelimit.NewLeakyBucket(rate, burst-1, ratelimit.WithClock(c)))
+		}},
+		{"SlidingLog", func(_ testing.TB, c clock.Clock, rate float64, burst int) ratelimit.Limiter {
+			return must.Must(ratelimit.NewSlidingLog(burst, window(rate, burst), ratelimit.WithClock(c)))
+		}},
+		{"SlidingWindow", func(_ testing.TB, c clock.Clock, rate float64, burst int) ratelimit.Limiter {
+			return must.Must(ratelimit.NewSlidingWindow(burst, window(rate, burst), ratelimit.WithClock(c)))
+		}},
+	} {
+		t.Run(impl.name, func(t *testing.T) { contracts.RunLimiterContract(t, impl.new) })
+	}
+}
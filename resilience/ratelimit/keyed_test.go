@@ -0,0 +1,75 @@
+package ratelimit_test
+
+import (
+	"testing"
+	"time"
+
+	"patterns/clock"
+	"patterns/idioms/must"
+	"patterns/resilience/ratelimit"
+)
+
+// keyed returns a Keyed of token buckets at 1/s with bursts of 1, so a
+// key's second Allow in a row is refused until its limiter is forgotten.
+func keyed(t *testing.T, c clock.Clock, opts ...ratelimit.Option) *ratelimit.Keyed[string] {
+	t.Helper()
+	k, err := ratelimit.NewKeyed[string](func() ratelimit.Limiter {
+		return must.Must(ratelimit.NewTokenBucket(1, 1, ratelimit.WithClock(c)))
+	}, append(opts, ratelimit.WithClock(c))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k
+}
+
+func TestKeyedEvictsIdle(t *testing.T) {
+	c := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	k := keyed(t, c, ratelimit.WithIdle(time.Minute))
+	for _, key := range []string{"a", "b", "c"} {
+		k.Allow(key)
+	}
+	c.Advance(30 * time.Second)
+	k.Allow("a")
+	if n := k.Len(); n != 3 {
+		t.Fatalf("Len after 30s = %d, want 3", n)
+	}
+	c.Advance(30 * time.Second)
+	k.Allow("d")
+	if n := k.Len(); n != 2 {
+		t.Fatalf("Len after b and c idled for a minute = %d, want 2 (a and d)", n)
+	}
+}
+
+func TestKeyedCapsKeys(t *testing.T) {
+	c := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	k := keyed(t, c, ratelimit.WithMaxKeys(2))
+	if !k.Allow("a") || !k.Allow("b") {
+		t.Fatal("first events refused")
+	}
+	if k.Allow("a") {
+		t.Fatal("a allowed twice in a row")
+	}
+	// a was used last, so c evicts b
+	k.Allow("c")
+	if n := k.Len(); n != 2 {
+		t.Fatalf("Len = %d, want 2", n)
+	}
+	if k.Allow("a") {
+		t.Fatal("a was evicted instead of b")
+	}
+	if !k.Allow("b") {
+		t.Fatal("b was kept: its fresh limiter should allow")
+	}
+}
+
+func TestKeyedOptions(t *testing.T) {
+	create := func() ratelimit.Limiter { return must.Must(ratelimit.NewTokenBucket(1, 1)) }
+	for name, opt := range map[string]ratelimit.Option{
+		"idle":     ratelimit.WithIdle(0),
+		"max keys": ratelimit.WithMaxKeys(0),
+	} {
+		if _, err := ratelimit.NewKeyed[string](create, opt); err == nil {
+			t.Errorf("%s: no error for zero", name)
+		}
+	}
+}
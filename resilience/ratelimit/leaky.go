@@ -0,0 +1,75 @@
+package ratelimit
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"patterns/clock"
+	"patterns/construct"
+)
+
+// leaky bucket
+// Level: Good
+// pros: output is perfectly smooth, one event per interval, whatever the
+// input; Take turns refusal into a bounded wait.
+// cons: no burst allowance at all, so a client idle for a minute still
+// sends its next two requests an interval apart.
+//
+// LeakyBucket lets one event through every 1/rate, with up to queue more
+// waiting their turn in Take. Safe for concurrent use.
+type LeakyBucket struct {
+	mu       sync.Mutex
+	interval time.Duration
+	queue    int
+	next     time.Time // when the next event may leave
+	clock    clock.Clock
+}
+
+func NewLeakyBucket(rate float64, queue int, opts ...Option) (*LeakyBucket, error) {
+	if rate <= 0 {
+		return nil, errors.New("rate must be positive")
+	}
+	if queue < 0 {
+		return nil, errors.New("queue cannot be negative")
+	}
+	options, err := construct.New(opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &LeakyBucket{
+		interval: time.Duration(float64(time.Second) / rate),
+		queue:    queue,
+		clock:    options.clock,
+	}, nil
+}
+
+// Allow admits an event only if it can leave now, so admitted events are
+// at least an interval apart.
+func (b *LeakyBucket) Allow() bool {
+	_, ok := b.take(0)
+	return ok
+}
+
+// Take schedules an event behind those already waiting and returns how
+// long the caller must wait before acting on it. It refuses when queue
+// events are waiting already.
+func (b *LeakyBucket) Take() (time.Duration, bool) {
+	return b.take(b.queue)
+}
+
+func (b *LeakyBucket) take(queue int) (time.Duration, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := b.clock.Now()
+	at := b.next
+	if at.Before(now) {
+		at = now
+	}
+	wait := at.Sub(now)
+	if wait > time.Duration(queue)*b.interval {
+		return 0, false
+	}
+	b.next = at.Add(b.interval)
+	return wait, true
+}
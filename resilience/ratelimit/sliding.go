@@ -0,0 +1,118 @@
+package ratelimit
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"patterns/clock"
+	"patterns/construct"
+)
+
+// sliding log
+// Level: Good
+// pros: exact: never more than limit in any window-long interval, which
+// is what a stated limit usually means.
+// cons: memory grows with the limit, a timestamp per admitted event, so
+// it is for limits in the hundreds, not millions.
+//
+// SlidingLog admits up to limit events in any window. Safe for concurrent
+// use.
+type SlidingLog struct {
+	mu     sync.Mutex
+	window time.Duration
+	log    []time.Time // ring of the last len(log) admissions
+	head   int         // index of the oldest
+	n      int
+	clock  clock.Clock
+}
+
+func NewSlidingLog(limit int, window time.Duration, opts ...Option) (*SlidingLog, error) {
+	if limit <= 0 {
+		return nil, errors.New("limit must be positive")
+	}
+	if window <= 0 {
+		return nil, errors.New("window must be positive")
+	}
+	options, err := construct.New(opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &SlidingLog{window: window, log: make([]time.Time, limit), clock: options.clock}, nil
+}
+
+func (l *SlidingLog) Allow() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.clock.Now()
+	if l.n < len(l.log) {
+		l.log[(l.head+l.n)%len(l.log)] = now
+		l.n++
+		return true
+	}
+	// full: the oldest of the last limit admissions must have left the
+	// window, and now takes its slot
+	if now.Sub(l.log[l.head]) < l.window {
+		return false
+	}
+	l.log[l.head] = now
+	l.head = (l.head + 1) % len(l.log)
+	return true
+}
+
+// sliding window counter
+// Level: Good
+// pros: constant memory and work per event, at any limit; no boundary
+// burst like a fixed window's.
+// cons: approximate: it assumes the previous window's events were spread
+// evenly, so traffic bunched at a window's end can get nearly twice the
+// limit into one window-long interval.
+//
+// SlidingWindow admits about limit events per window, counting events in
+// fixed windows and weighting the previous count by how much of the
+// previous window the sliding one still covers. Safe for concurrent use.
+type SlidingWindow struct {
+	mu         sync.Mutex
+	limit      float64
+	window     time.Duration
+	start      time.Time // of the current fixed window
+	prev, curr float64
+	clock      clock.Clock
+}
+
+func NewSlidingWindow(limit int, window time.Duration, opts ...Option) (*SlidingWindow, error) {
+	if limit <= 0 {
+		return nil, errors.New("limit must be positive")
+	}
+	if window <= 0 {
+		return nil, errors.New("window must be positive")
+	}
+	options, err := construct.New(opts...)
+	if err != nil {
+		return nil, err
+	}
+	w := &SlidingWindow{limit: float64(limit), window: window, clock: options.clock}
+	w.start = w.clock.Now()
+	return w, nil
+}
+
+func (w *SlidingWindow) Allow() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	now := w.clock.Now()
+	if elapsed := now.Sub(w.start); elapsed >= w.window {
+		passed := elapsed / w.window
+		w.prev = w.curr
+		if passed > 1 {
+			w.prev = 0
+		}
+		w.curr = 0
+		w.start = w.start.Add(passed * w.window)
+	}
+	behind := 1 - float64(now.Sub(w.start))/float64(w.window)
+	if w.prev*behind+w.curr >= w.limit {
+		return false
+	}
+	w.curr++
+	return true
+}
@@ -0,0 +1,56 @@
+package retry
+
+import (
+	"math"
+	"time"
+
+	"patterns/randsource"
+)
+
+// Backoff returns the wait after the n-th failed attempt, n from 1.
+type Backoff func(n int) time.Duration
+
+// Constant waits d after every failure.
+func Constant(d time.Duration) Backoff {
+	return func(int) time.Duration { return d }
+}
+
+// Exponential waits base after the first failure and doubles the wait
+// after each one after that, up to max; max <= 0 means no cap.
+func Exponential(base, max time.Duration) Backoff {
+	return func(n int) time.Duration {
+		d := base << min(n-1, 30)
+		if d>>min(n-1, 30) != base {
+			d = math.MaxInt64 // overflowed
+		}
+		if max > 0 && d > max {
+			return max
+		}
+		return d
+	}
+}
+
+// Jitter spreads a wait d, drawing from r, so clients that failed
+// together do not retry together.
+type Jitter func(r randsource.Rand, d time.Duration) time.Duration
+
+// None waits exactly d.
+func None(_ randsource.Rand, d time.Duration) time.Duration { return d }
+
+// Full waits anywhere in (0, d]: the most spread, and the AWS
+// architecture blog's recommendation for contended retries.
+func Full(r randsource.Rand, d time.Duration) time.Duration {
+	if d <= 0 {
+		return d
+	}
+	return time.Duration(r.Int64N(int64(d)) + 1)
+}
+
+// Equal waits at least half of d, and anywhere up to d: spread, but never
+// much sooner than the backoff asked for.
+func Equal(r randsource.Rand, d time.Duration) time.Duration {
+	if d <= 1 {
+		return d
+	}
+	return d/2 + Full(r, d-d/2)
+}
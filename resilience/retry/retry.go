@@ -0,0 +1,239 @@
+// Package retry runs an operation again when it fails with an error worth
+// retrying, waiting longer after each failure:
+//
+//	err := retry.Do(ctx, func(ctx context.Context) error {
+//		return client.Send(ctx, msg)
+//	}, retry.WithAttempts(5), retry.WithBackoff(retry.Exponential(100*time.Millisecond, 5*time.Second)))
+//
+// What to retry is the operation's business: an error wrapped with
+// Permanent, a context error, or one WithRetryIf rejects ends Do at once.
+// What is left is when: a Backoff gives the wait after each failure and a
+// Jitter spreads it, Full by default, so a fleet of clients that failed
+// at the same moment does not come back at the same moment too.
+//
+// Waits run on a clock.Clock and jitter draws from a randsource.Rand, so
+// a test can step through every retry with clock.Fake and a seeded Rand
+// instead of sleeping.
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"patterns/clock"
+	"patterns/construct"
+	"patterns/funcopts"
+	"patterns/randsource"
+)
+
+type options struct {
+	attempts int
+	backoff  Backoff
+	jitter   Jitter
+	retryIf  func(error) bool
+	onRetry  func(n int, err error, wait time.Duration)
+	clock    clock.Clock
+	rand     randsource.Rand
+}
+
+type Option = funcopts.Option[options]
+
+// WithAttempts sets how many times the operation runs at most, the first
+// included. The default is 3.
+func WithAttempts(n int) Option {
+	return func(options *options) error {
+		if n < 1 {
+			return errors.New("attempts must be at least 1")
+		}
+		options.attempts = n
+		return nil
+	}
+}
+
+// WithBackoff sets the waits between attempts; the default is
+// Exponential(100ms, 10s).
+func WithBackoff(b Backoff) Option {
+	return func(options *options) error {
+		if b == nil {
+			return errors.New("backoff cannot be nil")
+		}
+		options.backoff = b
+		return nil
+	}
+}
+
+// WithJitter sets how waits are spread; the default is Full.
+func WithJitter(j Jitter) Option {
+	return func(options *options) error {
+		if j == nil {
+			return errors.New("jitter cannot be nil")
+		}
+		options.jitter = j
+		return nil
+	}
+}
+
+// WithRetryIf retries only errors for which retryable returns true.
+// Permanent and context errors are never retried, whatever it says.
+func WithRetryIf(retryable func(error) bool) Option {
+	return func(options *options) error {
+		if retryable == nil {
+			return errors.New("retry classifier cannot be nil")
+		}
+		options.retryIf = retryable
+		return nil
+	}
+}
+
+// WithOnRetry reports every failure that will be retried, with its
+// attempt number and the wait before the next, for logs and metrics.
+func WithOnRetry(fn func(n int, err error, wait time.Duration)) Option {
+	return func(options *options) error {
+		if fn == nil {
+			return errors.New("retry callback cannot be nil")
+		}
+		options.onRetry = fn
+		return nil
+	}
+}
+
+func WithClock(c clock.Clock) Option {
+	return func(options *options) error {
+		if c == nil {
+			return errors.New("clock cannot be nil")
+		}
+		options.clock = c
+		return nil
+	}
+}
+
+func WithRand(r randsource.Rand) Option {
+	return func(options *options) error {
+		if r == nil {
+			return errors.New("rand cannot be nil")
+		}
+		options.rand = r
+		return nil
+	}
+}
+
+func (o *options) SetDefaults() {
+	o.attempts = 3
+	o.backoff = Exponential(100*time.Millisecond, 10*time.Second)
+	o.jitter = Full
+	o.retryIf = func(error) bool { return true }
+	o.onRetry = func(int, error, time.Duration) {}
+	o.clock = clock.Real
+	o.rand = randsource.Global
+}
+
+// permanentError marks an error not worth retrying.
+type permanentError struct{ err error }
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent wraps err so that Do returns it, unwrapped, without retrying:
+// for failures another attempt cannot fix, like a 400 or a bad password.
+// Permanent(nil) is nil.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err}
+}
+
+// ExhaustedError is returned when every attempt failed with a retryable
+// error; Err is the last one.
+type ExhaustedError struct {
+	Attempts int
+	Err      error
+}
+
+func (e *ExhaustedError) Error() string {
+	return fmt.Sprintf("retry: gave up after %d attempts: %v", e.Attempts, e.Err)
+}
+
+func (e *ExhaustedError) Unwrap() error { return e.Err }
+
+// retry with backoff and jitter
+// Level: Good
+// pros: transient failures are absorbed where they happen; jitter keeps
+// retries from arriving in waves; classification keeps it from retrying
+// what cannot succeed.
+// cons: multiplies load on a struggling dependency, and latency by up to
+// the sum of the waits; only safe for idempotent operations.
+//
+// Retrier runs operations under one retry policy. It is safe for
+// concurrent use.
+type Retrier struct {
+	options options
+}
+
+func New(opts ...Option) (*Retrier, error) {
+	options, err := construct.New(opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &Retrier{options: *options}, nil
+}
+
+// Do runs op until it succeeds, fails with an error not worth retrying,
+// which Do returns as it was, or has run the allowed attempts, when Do
+// returns an *ExhaustedError. If ctx ends during a wait, Do returns the
+// context's error together with op's last.
+func (r *Retrier) Do(ctx context.Context, op func(ctx context.Context) error) error {
+	o := &r.options
+	for n := 1; ; n++ {
+		err := op(ctx)
+		if err == nil {
+			return nil
+		}
+		if p := (*permanentError)(nil); errors.As(err, &p) {
+			return p.err
+		}
+		if ctx.Err() != nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) || !o.retryIf(err) {
+			return err
+		}
+		if n == o.attempts {
+			return &ExhaustedError{Attempts: n, Err: err}
+		}
+
+		wait := o.jitter(o.rand, o.backoff(n))
+		o.onRetry(n, err, wait)
+		t := o.clock.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return fmt.Errorf("retry: %w after %d attempts: %w", context.Cause(ctx), n, err)
+		case <-t.C():
+		}
+	}
+}
+
+// Do runs op under a policy built from opts; see Retrier.Do.
+func Do(ctx context.Context, op func(ctx context.Context) error, opts ...Option) error {
+	r, err := New(opts...)
+	if err != nil {
+		return err
+	}
+	return r.Do(ctx, op)
+}
+
+// DoValue is Do for an operation with a result, which is the successful
+// attempt's.
+func DoValue[T any](ctx context.Context, op func(ctx context.Context) (T, error), opts ...Option) (T, error) {
+	var v T
+	err := Do(ctx, func(ctx context.Context) error {
+		var err error
+		v, err = op(ctx)
+		return err
+	}, opts...)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return v, nil
+}
@@ -0,0 +1,315 @@
+package retry_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"patterns/clock"
+	"patterns/randsource"
+	"patterns/resilience/retry"
+)
+
+var errFlaky = errors.New("flaky")
+
+// failing returns an operation failing with err for its first n calls,
+// counted in calls.
+func failing(n int, err error, calls *atomic.Int32) func(context.Context) error {
+	return func(context.Context) error {
+		if int(calls.Add(1)) <= n {
+			return err
+		}
+		return nil
+	}
+}
+
+type retried struct {
+	n    int
+	wait time.Duration
+}
+
+// run starts Do on a fake clock; the returned func waits for its error.
+func run(t *testing.T, ctx context.Context, op func(context.Context) error, opts ...retry.Option) (*clock.Fake, *[]retried, func() error) {
+	t.Helper()
+	fake := clock.NewFake(time.Unix(0, 0))
+	var mu sync.Mutex
+	var log []retried
+	opts = append([]retry.Option{
+		retry.WithClock(fake),
+		retry.WithOnRetry(func(n int, _ error, wait time.Duration) {
+			mu.Lock()
+			defer mu.Unlock()
+			log = append(log, retried{n, wait})
+		}),
+	}, opts...)
+	done := make(chan error, 1)
+	go func() { done <- retry.Do(ctx, op, opts...) }()
+	return fake, &log, func() error {
+		select {
+		case err := <-done:
+			return err
+		case <-time.After(5 * time.Second):
+			t.Fatal("Do did not return")
+			return nil
+		}
+	}
+}
+
+// TestWaits steps through every retry on a fake clock: each attempt comes
+// exactly when its backoff has passed, not a nanosecond earlier.
+func TestWaits(t *testing.T) {
+	var calls atomic.Int32
+	fake, log, wait := run(t, context.Background(), failing(3, errFlaky, &calls),
+		retry.WithAttempts(5), retry.WithBackoff(retry.Exponential(time.Second, 3*time.Second)), retry.WithJitter(retry.None))
+	for i, d := range []time.Duration{time.Second, 2 * time.Second, 3 * time.Second} {
+		fake.BlockUntil(1)
+		if got := calls.Load(); got != int32(i+1) {
+			t.Fatalf("%d attempts before wait %d", got, i+1)
+		}
+		fake.Advance(d - time.Nanosecond)
+		if fake.Waiters() != 1 || calls.Load() != int32(i+1) {
+			t.Fatalf("retried before %v", d)
+		}
+		fake.Advance(time.Nanosecond)
+	}
+	if err := wait(); err != nil || calls.Load() != 4 {
+		t.Errorf("Do = %v after %d attempts", err, calls.Load())
+	}
+	want := []retried{{1, time.Second}, {2, 2 * time.Second}, {3, 3 * time.Second}}
+	if fmt.Sprint(*log) != fmt.Sprint(want) {
+		t.Errorf("retries %v, want %v", *log, want)
+	}
+}
+
+// TestJittered checks that the waits are the backoff's, spread by the
+// jitter drawing from the given Rand: the same seed gives the same waits.
+func TestJittered(t *testing.T) {
+	const seed = 42
+	backoff := retry.Exponential(100*time.Millisecond, 0)
+	for name, j := range map[string]retry.Jitter{"full": retry.Full, "equal": retry.Equal} {
+		var calls atomic.Int32
+		fake, log, wait := run(t, context.Background(), failing(4, errFlaky, &calls),
+			retry.WithAttempts(5), retry.WithBackoff(backoff), retry.WithJitter(j), retry.WithRand(randsource.New(seed)))
+		r := randsource.New(seed)
+		for n := 1; n <= 4; n++ {
+			fake.BlockUntil(1)
+			want := j(r, backoff(n))
+			if got := (*log)[n-1]; got != (retried{n, want}) {
+				t.Fatalf("%s: retry %v, want %v", name, got, retried{n, want})
+			}
+			fake.Advance(want)
+		}
+		if err := wait(); err != nil {
+			t.Errorf("%s: Do = %v", name, err)
+		}
+	}
+}
+
+func TestExhausted(t *testing.T) {
+	var calls atomic.Int32
+	op := func(context.Context) error {
+		return fmt.Errorf("attempt %d: %w", calls.Add(1), errFlaky)
+	}
+	err := retry.Do(context.Background(), op, retry.WithAttempts(3), retry.WithBackoff(retry.Constant(0)), retry.WithClock(clock.NewFake(time.Time{})))
+	var ex *retry.ExhaustedError
+	if !errors.As(err, &ex) || ex.Attempts != 3 || !errors.Is(err, errFlaky) || calls.Load() != 3 ||
+		err.Error() != "retry: gave up after 3 attempts: attempt 3: flaky" {
+		t.Errorf("Do = %v after %d attempts", err, calls.Load())
+	}
+	// one attempt is not retried at all
+	calls.Store(0)
+	err = retry.Do(context.Background(), op, retry.WithAttempts(1))
+	if !errors.As(err, &ex) || ex.Attempts != 1 || calls.Load() != 1 {
+		t.Errorf("one attempt: %v after %d", err, calls.Load())
+	}
+}
+
+// TestNotRetried checks the errors that end Do at once, returned as they
+// were.
+func TestNotRetried(t *testing.T) {
+	errBadRequest := errors.New("400")
+	for _, c := range []struct {
+		name string
+		err  error
+		opts []retry.Option
+		want error
+	}{
+		{"permanent", retry.Permanent(errBadRequest), nil, errBadRequest},
+		{"wrapped permanent", fmt.Errorf("send: %w", retry.Permanent(errBadRequest)), nil, errBadRequest},
+		{"canceled", fmt.Errorf("send: %w", context.Canceled), nil, context.Canceled},
+		{"deadline", context.DeadlineExceeded, nil, context.DeadlineExceeded},
+		{"classified", errBadRequest, []retry.Option{retry.WithRetryIf(func(err error) bool { return err != errBadRequest })}, errBadRequest},
+		// Permanent wins over a classifier that would retry it
+		{"permanent over classifier", retry.Permanent(errFlaky), []retry.Option{retry.WithRetryIf(func(error) bool { return true })}, errFlaky},
+	} {
+		var calls atomic.Int32
+		err := retry.Do(context.Background(), func(context.Context) error {
+			calls.Add(1)
+			return c.err
+		}, append(c.opts, retry.WithAttempts(5))...)
+		var ex *retry.ExhaustedError
+		if !errors.Is(err, c.want) || errors.As(err, &ex) || calls.Load() != 1 {
+			t.Errorf("%s: Do = %v after %d attempts", c.name, err, calls.Load())
+		}
+	}
+	if retry.Permanent(errBadRequest).Error() != "400" || retry.Permanent(nil) != nil {
+		t.Error("Permanent changes what it wraps")
+	}
+	// a classifier keeps retrying what it accepts
+	var calls atomic.Int32
+	err := retry.Do(context.Background(), failing(2, errFlaky, &calls), retry.WithRetryIf(func(err error) bool { return err == errFlaky }),
+		retry.WithBackoff(retry.Constant(0)))
+	if err != nil || calls.Load() != 3 {
+		t.Errorf("classified as retryable: %v after %d", err, calls.Load())
+	}
+}
+
+// TestCancelDuringWait ends the context while Do waits to retry: Do
+// returns at once, with both the context's error and the operation's.
+func TestCancelDuringWait(t *testing.T) {
+	errShutdown := errors.New("shutting down")
+	ctx, cancel := context.WithCancelCause(context.Background())
+	var calls atomic.Int32
+	fake, _, wait := run(t, ctx, failing(5, errFlaky, &calls), retry.WithBackoff(retry.Constant(time.Hour)))
+	fake.BlockUntil(1)
+	cancel(errShutdown)
+	err := wait()
+	if !errors.Is(err, errShutdown) || !errors.Is(err, errFlaky) || err.Error() != "retry: shutting down after 1 attempts: flaky" {
+		t.Errorf("Do = %v", err)
+	}
+	if calls.Load() != 1 || fake.Waiters() != 0 {
+		t.Errorf("%d attempts, %d timers left", calls.Load(), fake.Waiters())
+	}
+
+	// an operation failing because its context ended is not retried
+	ctx, cancelCtx := context.WithCancel(context.Background())
+	calls.Store(0)
+	err = retry.Do(ctx, func(context.Context) error {
+		calls.Add(1)
+		cancelCtx()
+		return errFlaky
+	})
+	if err != errFlaky || calls.Load() != 1 {
+		t.Errorf("context ended during the attempt: %v after %d", err, calls.Load())
+	}
+}
+
+func TestDoValue(t *testing.T) {
+	var calls atomic.Int32
+	v, err := retry.DoValue(context.Background(), func(context.Context) (int, error) {
+		if calls.Add(1) < 3 {
+			return -1, errFlaky
+		}
+		return 42, nil
+	}, retry.WithBackoff(retry.Constant(0)))
+	if v != 42 || err != nil {
+		t.Errorf("DoValue = %v, %v", v, err)
+	}
+	v, err = retry.DoValue(context.Background(), func(context.Context) (int, error) { return 7, retry.Permanent(errFlaky) })
+	if v != 0 || err != errFlaky {
+		t.Errorf("failed DoValue = %v, %v; want the zero value", v, err)
+	}
+}
+
+// TestConcurrent shares one Retrier between goroutines. Run with -race.
+func TestConcurrent(t *testing.T) {
+	r, err := retry.New(retry.WithBackoff(retry.Constant(time.Millisecond)), retry.WithRand(randsource.New(1)), retry.WithAttempts(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var calls atomic.Int32
+			if err := r.Do(context.Background(), failing(2, errFlaky, &calls)); err != nil || calls.Load() != 3 {
+				t.Errorf("Do = %v after %d", err, calls.Load())
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestBackoff(t *testing.T) {
+	huge := time.Duration(1) << 40
+	for _, c := range []struct {
+		name string
+		b    retry.Backoff
+		n    int
+		want time.Duration
+	}{
+		{"constant", retry.Constant(time.Second), 7, time.Second},
+		{"first", retry.Exponential(100*time.Millisecond, time.Second), 1, 100 * time.Millisecond},
+		{"doubled", retry.Exponential(100*time.Millisecond, time.Second), 3, 400 * time.Millisecond},
+		{"capped", retry.Exponential(100*time.Millisecond, time.Second), 5, time.Second},
+		{"uncapped", retry.Exponential(100*time.Millisecond, 0), 11, 100 * time.Millisecond << 10},
+		{"far", retry.Exponential(time.Nanosecond, 0), 1000, time.Nanosecond << 30},
+		{"overflow", retry.Exponential(huge, 0), 40, math.MaxInt64},
+		{"overflow capped", retry.Exponential(huge, time.Hour), 40, time.Hour},
+	} {
+		if got := c.b(c.n); got != c.want {
+			t.Errorf("%s: backoff(%d) = %v, want %v", c.name, c.n, got, c.want)
+		}
+	}
+}
+
+// TestJitter draws many waits from each jitter: they stay within bounds,
+// and cover them.
+func TestJitter(t *testing.T) {
+	r := randsource.Seeded(t)
+	const d = 1000
+	for _, c := range []struct {
+		name     string
+		j        retry.Jitter
+		min, max time.Duration
+	}{
+		{"none", retry.None, d, d},
+		{"full", retry.Full, 1, d},
+		{"equal", retry.Equal, d/2 + 1, d},
+	} {
+		lo, hi := time.Duration(math.MaxInt64), time.Duration(0)
+		for range 10000 {
+			w := c.j(r, d)
+			lo, hi = min(lo, w), max(hi, w)
+		}
+		if lo != c.min || hi != c.max {
+			t.Errorf("%s: waits in [%v, %v], want [%v, %v]", c.name, lo, hi, c.min, c.max)
+		}
+		// too short to spread
+		for _, d := range []time.Duration{0, -1, 1} {
+			if got := c.j(r, d); got != d {
+				t.Errorf("%s(%v) = %v", c.name, d, got)
+			}
+		}
+	}
+}
+
+func TestOptions(t *testing.T) {
+	for _, c := range []struct {
+		opt  retry.Option
+		want string
+	}{
+		{retry.WithAttempts(0), "attempts must be at least 1"},
+		{retry.WithBackoff(nil), "backoff cannot be nil"},
+		{retry.WithJitter(nil), "jitter cannot be nil"},
+		{retry.WithRetryIf(nil), "retry classifier cannot be nil"},
+		{retry.WithOnRetry(nil), "retry callback cannot be nil"},
+		{retry.WithClock(nil), "clock cannot be nil"},
+		{retry.WithRand(nil), "rand cannot be nil"},
+	} {
+		if r, err := retry.New(c.opt); r != nil || err == nil || err.Error() != c.want {
+			t.Errorf("New = %v, %v; want %q", r, err, c.want)
+		}
+		called := false
+		err := retry.Do(context.Background(), func(context.Context) error { called = true; return nil }, c.opt)
+		if err == nil || called {
+			t.Errorf("Do with a bad option: %v, operation run %v", err, called)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"patterns/persistence/repository"
+)
+
+// Static is a Provider of roles fixed at construction.
+type Static struct {
+	roles       map[string]Role
+	assignments map[string][]string
+}
+
+// NewStatic checks that every inherited and assigned role exists; cycles
+// of inheritance are allowed, if pointless.
+func NewStatic(roles []Role, assignments map[string][]string) (*Static, error) {
+	s := &Static{roles: map[string]Role{}, assignments: map[string][]string{}}
+	var errs []error
+	for _, r := range roles {
+		if _, dup := s.roles[r.Name]; dup {
+			errs = append(errs, fmt.Errorf("rbac: duplicate role %q", r.Name))
+		}
+		s.roles[r.Name] = r
+	}
+	for _, r := range roles {
+		for _, in := range r.Inherits {
+			if _, ok := s.roles[in]; !ok {
+				errs = append(errs, fmt.Errorf("%w: %q inherits %q", ErrNoRole, r.Name, in))
+			}
+		}
+	}
+	for subject, names := range assignments {
+		for _, n := range names {
+			if _, ok := s.roles[n]; !ok {
+				errs = append(errs, fmt.Errorf("%w: %q is assigned %q", ErrNoRole, subject, n))
+			}
+		}
+		s.assignments[subject] = slices.Clone(names)
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Static) Role(_ context.Context, name string) (Role, error) {
+	r, ok := s.roles[name]
+	if !ok {
+		return Role{}, fmt.Errorf("%w: %q", ErrNoRole, name)
+	}
+	return r, nil
+}
+
+func (s *Static) Roles(_ context.Context, subject string) ([]string, error) {
+	return slices.Clone(s.assignments[subject]), nil
+}
+
+// Assignment is the roles of one subject.
+type Assignment struct {
+	Subject string   `json:"subject"`
+	Roles   []string `json:"roles"`
+}
+
+// Stored is a Provider reading roles and assignments from repositories,
+// so they change while the program runs; a role deleted while still
+// assigned or inherited grants nothing from then on.
+type Stored struct {
+	roles       repository.Repository[string, Role]
+	assignments repository.Repository[string, Assignment]
+}
+
+func NewStored(roles repository.Repository[string, Role], assignments repository.Repository[string, Assignment]) *Stored {
+	return &Stored{roles: roles, assignments: assignments}
+}
+
+func (s *Stored) Role(ctx context.Context, name string) (Role, error) {
+	r, err := s.roles.Get(ctx, name)
+	if errors.Is(err, repository.ErrNotFound) {
+		return Role{}, fmt.Errorf("%w: %q", ErrNoRole, name)
+	}
+	return r, err
+}
+
+func (s *Stored) Roles(ctx context.Context, subject string) ([]string, error) {
+	a, err := s.assignments.Get(ctx, subject)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, nil
+	}
+	return a.Roles, err
+}
+
+// Assign gives subject the roles names, replacing any it had.
+func (s *Stored) Assign(ctx context.Context, subject string, names ...string) error {
+	for _, n := range names {
+		if _, err := s.Role(ctx, n); err != nil {
+			return err
+		}
+	}
+	a := Assignment{Subject: subject, Roles: slices.Clone(names)}
+	err := s.assignments.Update(ctx, subject, a)
+	if errors.Is(err, repository.ErrNotFound) {
+		err = s.assignments.Create(ctx, subject, a)
+	}
+	return err
+}
+
+// Define adds or replaces a role; the roles it inherits must exist.
+func (s *Stored) Define(ctx context.Context, r Role) error {
+	for _, in := range r.Inherits {
+		if _, err := s.Role(ctx, in); err != nil && in != r.Name {
+			return err
+		}
+	}
+	r.Permissions, r.Inherits = slices.Clone(r.Permissions), slices.Clone(r.Inherits)
+	err := s.roles.Update(ctx, r.Name, r)
+	if errors.Is(err, repository.ErrNotFound) {
+		err = s.roles.Create(ctx, r.Name, r)
+	}
+	return err
+}
@@ -0,0 +1,267 @@
+package rbac_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"patterns/idioms/markeriface"
+	"patterns/persistence/repository"
+	"patterns/security/rbac"
+)
+
+var (
+	roles = []rbac.Role{
+		{Name: "viewer", Permissions: []rbac.Permission{"orders:read", "stock:read"}},
+		{Name: "clerk", Permissions: []rbac.Permission{"orders:create"}, Inherits: []string{"viewer"}},
+		{Name: "manager", Permissions: []rbac.Permission{"orders:*"}, Inherits: []string{"clerk"}},
+		{Name: "admin", Permissions: []rbac.Permission{"*"}},
+		// a cycle, followed once
+		{Name: "ping", Permissions: []rbac.Permission{"ping"}, Inherits: []string{"pong"}},
+		{Name: "pong", Permissions: []rbac.Permission{"pong"}, Inherits: []string{"ping"}},
+	}
+	assignments = map[string][]string{
+		"ann":  {"clerk"},
+		"bob":  {"manager"},
+		"root": {"admin"},
+		"pat":  {"ping"},
+		"vic":  {"viewer", "pong"},
+		"eve":  {},
+	}
+)
+
+func static(t *testing.T) rbac.Provider {
+	t.Helper()
+	p, err := rbac.NewStatic(roles, assignments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func stored(t *testing.T) rbac.Provider {
+	t.Helper()
+	p := rbac.NewStored(repository.NewMemory[string, rbac.Role](), repository.NewMemory[string, rbac.Assignment]())
+	ctx := context.Background()
+	// the cycle cannot be defined in one pass
+	for _, r := range roles {
+		if err := p.Define(ctx, rbac.Role{Name: r.Name}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, r := range roles {
+		if err := p.Define(ctx, r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for subject, names := range assignments {
+		if err := p.Assign(ctx, subject, names...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return p
+}
+
+// TestCan checks each provider the same way: permissions are inherited
+// through any depth of roles, wildcards cover what they name and no more,
+// and whatever is not granted is denied.
+func TestCan(t *testing.T) {
+	for name, provider := range map[string]func(*testing.T) rbac.Provider{"static": static, "stored": stored} {
+		c := rbac.New(provider(t))
+		for _, k := range []struct {
+			subject string
+			p       rbac.Permission
+			want    bool
+		}{
+			{"ann", "orders:create", true},
+			{"ann", "orders:read", true}, // from viewer
+			{"ann", "stock:read", true},
+			{"ann", "orders:delete", false},
+			{"bob", "orders:delete", true},
+			{"bob", "stock:read", true}, // from viewer, through clerk
+			{"bob", "stock:write", false},
+			{"bob", "ordersx:read", false},
+			{"bob", "orders", false},
+			{"root", "anything:at-all", true},
+			{"pat", "pong", true},
+			{"pat", "other", false},
+			{"vic", "ping", true},
+			{"vic", "orders:create", false}, // no inheriting downwards
+			{"eve", "orders:read", false},
+			{"stranger", "orders:read", false},
+			{"", "orders:read", false},
+		} {
+			got, err := c.Can(context.Background(), k.subject, k.p)
+			if err != nil || got != k.want {
+				t.Errorf("%s: Can(%s, %s) = %v, %v; want %v", name, k.subject, k.p, got, err, k.want)
+			}
+		}
+	}
+}
+
+func TestNewStatic(t *testing.T) {
+	_, err := rbac.NewStatic([]rbac.Role{
+		{Name: "a", Inherits: []string{"ghost"}},
+		{Name: "a"},
+	}, map[string][]string{"ann": {"b"}})
+	want := "rbac: duplicate role \"a\"\n" +
+		"rbac: no such role: \"a\" inherits \"ghost\"\n" +
+		"rbac: no such role: \"ann\" is assigned \"b\""
+	if !errors.Is(err, rbac.ErrNoRole) || err.Error() != want {
+		t.Errorf("NewStatic = %v, want:\n%s", err, want)
+	}
+
+	// the provider keeps its own copy of the assignments
+	names := []string{"viewer"}
+	p, _ := rbac.NewStatic(roles, map[string][]string{"ann": names})
+	names[0] = "admin"
+	if ok, _ := rbac.New(p).Can(context.Background(), "ann", "x"); ok {
+		t.Error("provider changed through the assignment given to NewStatic")
+	}
+}
+
+// TestStored changes roles while a checker uses them.
+func TestStored(t *testing.T) {
+	ctx := context.Background()
+	roleRepo := repository.NewMemory[string, rbac.Role]()
+	p := rbac.NewStored(roleRepo, repository.NewMemory[string, rbac.Assignment]())
+	c := rbac.New(p)
+	can := func(step string, subject string, perm rbac.Permission, want bool) {
+		t.Helper()
+		if got, err := c.Can(ctx, subject, perm); err != nil || got != want {
+			t.Errorf("%s: Can(%s, %s) = %v, %v; want %v", step, subject, perm, got, err, want)
+		}
+	}
+
+	if err := p.Define(ctx, rbac.Role{Name: "clerk", Inherits: []string{"viewer"}}); !errors.Is(err, rbac.ErrNoRole) {
+		t.Errorf("Define inheriting a missing role = %v", err)
+	}
+	if err := p.Assign(ctx, "ann", "viewer"); !errors.Is(err, rbac.ErrNoRole) {
+		t.Errorf("Assign of a missing role = %v", err)
+	}
+	p.Define(ctx, rbac.Role{Name: "viewer", Permissions: []rbac.Permission{"orders:read"}})
+	p.Define(ctx, rbac.Role{Name: "clerk", Inherits: []string{"viewer"}})
+	p.Assign(ctx, "ann", "clerk")
+	can("assigned", "ann", "orders:read", true)
+
+	p.Define(ctx, rbac.Role{Name: "viewer", Permissions: []rbac.Permission{"stock:read"}})
+	can("redefined", "ann", "orders:read", false)
+	can("redefined", "ann", "stock:read", true)
+
+	// deleted while still inherited: grants nothing, denies nothing else
+	p.Define(ctx, rbac.Role{Name: "clerk", Permissions: []rbac.Permission{"orders:create"}, Inherits: []string{"viewer"}})
+	roleRepo.Delete(ctx, "viewer")
+	can("inherited role deleted", "ann", "stock:read", false)
+	can("inherited role deleted", "ann", "orders:create", true)
+
+	roleRepo.Delete(ctx, "clerk")
+	can("assigned role deleted", "ann", "orders:create", false)
+
+	p.Define(ctx, rbac.Role{Name: "viewer"})
+	p.Assign(ctx, "ann", "viewer")
+	p.Assign(ctx, "ann")
+	can("unassigned", "ann", "x", false)
+
+	// a role may inherit itself, uselessly
+	if err := p.Define(ctx, rbac.Role{Name: "self", Permissions: []rbac.Permission{"x"}, Inherits: []string{"self"}}); err != nil {
+		t.Errorf("Define of a self-inheriting role = %v", err)
+	}
+	p.Assign(ctx, "ann", "self")
+	can("self-inheriting", "ann", "x", true)
+	can("self-inheriting", "ann", "y", false)
+}
+
+// broken is a Provider whose store is down.
+type broken struct{}
+
+var errDown = errors.New("store down")
+
+func (broken) Role(context.Context, string) (rbac.Role, error) { return rbac.Role{}, errDown }
+func (broken) Roles(context.Context, string) ([]string, error) { return []string{"viewer"}, nil }
+
+func TestRequire(t *testing.T) {
+	c := rbac.New(static(t))
+	ctx := context.Background()
+	if err := rbac.Require(ctx, c, "ann", "orders:read"); err != nil {
+		t.Errorf("Require = %v", err)
+	}
+	err := rbac.Require(ctx, c, "ann", "orders:delete")
+	var fe *rbac.ForbiddenError
+	if !errors.Is(err, rbac.ErrForbidden) || !errors.As(err, &fe) || fe.Subject != "ann" || fe.Permission != "orders:delete" ||
+		err.Error() != "rbac: ann lacks orders:delete" {
+		t.Errorf("Require without the permission = %v", err)
+	}
+	// an unavailable store is not a refusal
+	if err := rbac.Require(ctx, rbac.New(broken{}), "ann", "orders:read"); !errors.Is(err, errDown) || errors.Is(err, rbac.ErrForbidden) {
+		t.Errorf("Require with the store down = %v", err)
+	}
+}
+
+func TestRequireHTTP(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("ok")) })
+	for _, c := range []struct {
+		name    string
+		checker rbac.Checker
+		subject string
+		code    int
+		body    string
+	}{
+		{"allowed", rbac.New(static(t)), "ann", http.StatusOK, "ok"},
+		{"anonymous", rbac.New(static(t)), "", http.StatusUnauthorized, "unauthenticated\n"},
+		{"forbidden", rbac.New(static(t)), "vic", http.StatusForbidden, "forbidden\n"},
+		{"unknown", rbac.New(static(t)), "stranger", http.StatusForbidden, "forbidden\n"},
+		{"store down", rbac.New(broken{}), "ann", http.StatusInternalServerError, "authorization unavailable\n"},
+	} {
+		req := httptest.NewRequest("POST", "/orders", nil)
+		req = req.WithContext(rbac.WithSubject(req.Context(), c.subject))
+		rec := httptest.NewRecorder()
+		rbac.RequireHTTP(c.checker, "orders:create")(ok).ServeHTTP(rec, req)
+		if rec.Code != c.code || rec.Body.String() != c.body {
+			t.Errorf("%s: %d %q, want %d %q", c.name, rec.Code, rec.Body, c.code, c.body)
+		}
+	}
+}
+
+type createOrder struct{}
+
+func (createOrder) Permission() rbac.Permission { return "orders:create" }
+
+type unguarded struct{}
+
+func TestCommands(t *testing.T) {
+	bus := markeriface.NewBus(rbac.Commands(rbac.New(static(t))))
+	handled := 0
+	markeriface.Handle(bus, func(context.Context, createOrder) (any, error) { handled++; return "created", nil })
+	markeriface.Handle(bus, func(context.Context, unguarded) (any, error) { handled++; return nil, nil })
+
+	for _, c := range []struct {
+		name    string
+		subject string
+		cmd     any
+		err     string
+	}{
+		{"allowed", "ann", createOrder{}, ""},
+		{"forbidden", "vic", createOrder{}, "rbac: vic lacks orders:create"},
+		{"anonymous", "", createOrder{}, "rbac: anonymous lacks orders:create"},
+		// even the admin cannot run a command that declares no permission
+		{"undeclared", "root", unguarded{}, "rbac: root: forbidden: no permission declared"},
+		{"undeclared anonymous", "", unguarded{}, "rbac: anonymous: forbidden: no permission declared"},
+	} {
+		before := handled
+		out, err := bus.Dispatch(rbac.WithSubject(context.Background(), c.subject), c.cmd)
+		if c.err == "" {
+			if err != nil || out != "created" || handled != before+1 {
+				t.Errorf("%s: %v, %v", c.name, out, err)
+			}
+			continue
+		}
+		if !errors.Is(err, rbac.ErrForbidden) || err.Error() != c.err || handled != before {
+			t.Errorf("%s: %v after %d handled, want %q", c.name, err, handled-before, c.err)
+		}
+	}
+	if _, ok := rbac.SubjectFrom(context.Background()); ok {
+		t.Error("SubjectFrom found a subject in an empty context")
+	}
+}
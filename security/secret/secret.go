@@ -0,0 +1,125 @@
+// Package secret keeps credentials out of logs, dumps and error messages
+// by type rather than by care: a Secret[T] formats, marshals and logs as
+// "[REDACTED]" however it is printed, and its value is only reachable
+// through Reveal, which is easy to find in review.
+//
+//	type Config struct {
+//		User     string
+//		Password secret.Secret[string] `json:"password"`
+//	}
+//	db.Connect(cfg.User, cfg.Password.Reveal())
+//	log.Printf("%+v", cfg) // {User:app Password:[REDACTED]}
+//
+// Every way out is covered: fmt through Format, whatever the verb;
+// encoding/json and text encoders; log/slog through LogValue;
+// configdump, and funcopts' option reports, through IsSecret. The value
+// is held behind a pointer so that a Secret in an unexported field, which
+// fmt prints by reflection without calling any method, shows only an
+// address.
+//
+// Decoding is the way in, and is not redacted: a Secret unmarshals from
+// JSON or text like the T it holds, so configs and flags can set it. The
+// output is then one-way: marshalling a Secret and reading it back gives
+// "[REDACTED]", never the value.
+package secret
+
+import (
+	"crypto/subtle"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+)
+
+// Redacted is what a Secret shows instead of its value.
+const Redacted = "[REDACTED]"
+
+// redacted type
+// Level: Good
+// pros: leaks need an explicit Reveal, not one forgotten redaction; the
+// type documents which values are sensitive.
+// cons: a revealed value is a plain T again, free to leak; a Secret does
+// not wipe memory, and copies share the value.
+//
+// Secret holds a sensitive T. The zero Secret holds the zero T and is
+// not set.
+type Secret[T any] struct {
+	v *T
+}
+
+// New returns a Secret holding v.
+func New[T any](v T) Secret[T] { return Secret[T]{v: &v} }
+
+// Reveal returns the value. Call it at the use site, passing the result
+// on, never storing it.
+func (s Secret[T]) Reveal() T {
+	if s.v == nil {
+		var zero T
+		return zero
+	}
+	return *s.v
+}
+
+// IsSet reports whether the Secret was given a value, for validation.
+func (s Secret[T]) IsSet() bool { return s.v != nil }
+
+// IsSecret marks Secret for packages that redact by interface, like
+// configdump and funcopts, without importing this one.
+func (Secret[T]) IsSecret() bool { return true }
+
+func (Secret[T]) String() string   { return Redacted }
+func (Secret[T]) GoString() string { return Redacted }
+
+// Format writes Redacted for every verb and flag: %v, %+v, %#v, %s, %q,
+// %x and the rest.
+func (Secret[T]) Format(f fmt.State, verb rune) {
+	if verb == 'q' {
+		fmt.Fprintf(f, "%q", Redacted)
+		return
+	}
+	f.Write([]byte(Redacted))
+}
+
+func (Secret[T]) LogValue() slog.Value { return slog.StringValue(Redacted) }
+
+func (Secret[T]) MarshalJSON() ([]byte, error) { return json.Marshal(Redacted) }
+
+func (Secret[T]) MarshalText() ([]byte, error) { return []byte(Redacted), nil }
+
+// UnmarshalJSON decodes the value as T would be.
+func (s *Secret[T]) UnmarshalJSON(b []byte) error {
+	var v T
+	if err := json.Unmarshal(b, &v); err != nil {
+		// the error may quote the input: say only what failed
+		return fmt.Errorf("secret: cannot decode %T", v)
+	}
+	*s = New(v)
+	return nil
+}
+
+// UnmarshalText decodes the value as T would be: T must be a string, a
+// []byte or implement encoding.TextUnmarshaler, so that flags and
+// environment variables can set it.
+func (s *Secret[T]) UnmarshalText(text []byte) error {
+	var v T
+	switch p := any(&v).(type) {
+	case *string:
+		*p = string(text)
+	case *[]byte:
+		*p = append([]byte(nil), text...)
+	case encoding.TextUnmarshaler:
+		if err := p.UnmarshalText(text); err != nil {
+			return fmt.Errorf("secret: cannot decode %T", v)
+		}
+	default:
+		return fmt.Errorf("secret: %T cannot be decoded from text", v)
+	}
+	*s = New(v)
+	return nil
+}
+
+// Equal reports whether s holds v, in time independent of where they
+// differ, for comparing tokens and passwords without a timing leak.
+func Equal[T ~string | ~[]byte](s Secret[T], v T) bool {
+	return s.IsSet() && subtle.ConstantTimeCompare([]byte(s.Reveal()), []byte(v)) == 1
+}
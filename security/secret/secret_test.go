@@ -0,0 +1,220 @@
+package secret_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"patterns/configdump"
+	"patterns/funcopts"
+	"patterns/security/secret"
+)
+
+const password = "hunter2"
+
+type config struct {
+	User     string                `json:"user"`
+	Password secret.Secret[string] `json:"password"`
+	Key      secret.Secret[[]byte] `json:"key"`
+}
+
+type hidden struct {
+	user string
+	pass secret.Secret[string]
+}
+
+func newConfig() config {
+	return config{User: "app", Password: secret.New(password), Key: secret.New([]byte(password))}
+}
+
+// TestFormat prints secrets every way fmt can, alone and inside other
+// values: the value never shows.
+func TestFormat(t *testing.T) {
+	s, cfg := secret.New(password), newConfig()
+	for _, c := range []struct {
+		format string
+		arg    any
+		want   string // "" for anything without the value
+	}{
+		{"%v", s, "[REDACTED]"},
+		{"%+v", s, "[REDACTED]"},
+		{"%#v", s, "[REDACTED]"},
+		{"%s", s, "[REDACTED]"},
+		{"%q", s, `"[REDACTED]"`},
+		{"%x", s, "[REDACTED]"},
+		{"%d", s, "[REDACTED]"},
+		{"%10s", s, "[REDACTED]"},
+		{"%v", &s, "[REDACTED]"},
+		{"%v", cfg, "{app [REDACTED] [REDACTED]}"},
+		{"%+v", cfg, "{User:app Password:[REDACTED] Key:[REDACTED]}"},
+		{"%+v", &cfg, "&{User:app Password:[REDACTED] Key:[REDACTED]}"},
+		{"%#v", cfg, `secret_test.config{User:"app", Password:[REDACTED], Key:[REDACTED]}`},
+		{"%v", []secret.Secret[string]{s}, "[[REDACTED]]"},
+		{"%v", map[string]secret.Secret[string]{"db": s}, "map[db:[REDACTED]]"},
+		{"%v", secret.New(42), "[REDACTED]"},
+		// fmt prints unexported fields without calling their methods: an
+		// address is all there is to see
+		{"%v", hidden{"app", s}, ""},
+		{"%+v", hidden{"app", s}, ""},
+		{"%#v", hidden{"app", s}, ""},
+	} {
+		got := fmt.Sprintf(c.format, c.arg)
+		if strings.Contains(got, password) || c.want != "" && got != c.want {
+			t.Errorf("Sprintf(%q, %T) = %s, want %s", c.format, c.arg, got, c.want)
+		}
+	}
+	if got := fmt.Sprint(s) + s.String() + s.GoString(); got != strings.Repeat(secret.Redacted, 3) {
+		t.Errorf("Sprint, String and GoString = %s", got)
+	}
+	if err := fmt.Errorf("connect with %v: %w", cfg, errors.New("refused")); strings.Contains(err.Error(), password) {
+		t.Errorf("error leaked: %v", err)
+	}
+}
+
+func TestJSON(t *testing.T) {
+	cfg := newConfig()
+	b, err := json.Marshal(cfg)
+	if want := `{"user":"app","password":"[REDACTED]","key":"[REDACTED]"}`; err != nil || string(b) != want {
+		t.Fatalf("Marshal = %s, %v; want %s", b, err, want)
+	}
+	for _, v := range []any{&cfg, []config{cfg}, map[string]any{"cfg": cfg}} {
+		if b, _ := json.MarshalIndent(v, "", "  "); bytes.Contains(b, []byte(password)) {
+			t.Errorf("Marshal(%T) leaked: %s", v, b)
+		}
+	}
+
+	// decoding sets the value, and marshalling it again does not give it
+	// back
+	var in config
+	if err := json.Unmarshal([]byte(`{"user":"app","password":"hunter2","key":"aHVudGVyMg=="}`), &in); err != nil {
+		t.Fatal(err)
+	}
+	if in.Password.Reveal() != password || string(in.Key.Reveal()) != password {
+		t.Errorf("decoded %q and %q", in.Password.Reveal(), in.Key.Reveal())
+	}
+	b, _ = json.Marshal(in)
+	var again config
+	json.Unmarshal(b, &again)
+	if again.Password.Reveal() != secret.Redacted {
+		t.Errorf("round trip gave %q", again.Password.Reveal())
+	}
+
+	// nor does a decoding error quote it
+	var n secret.Secret[int]
+	if err := json.Unmarshal([]byte(`"hunter2"`), &n); err == nil || strings.Contains(err.Error(), password) || n.IsSet() {
+		t.Errorf("Unmarshal into an int = %v", err)
+	}
+}
+
+// upper is a TextUnmarshaler whose errors quote the text.
+type upper string
+
+func (u *upper) UnmarshalText(b []byte) error {
+	if bytes.ContainsRune(b, '!') {
+		return fmt.Errorf("bad text %q", b)
+	}
+	*u = upper(strings.ToUpper(string(b)))
+	return nil
+}
+
+func TestText(t *testing.T) {
+	if b, err := secret.New(password).MarshalText(); err != nil || string(b) != secret.Redacted {
+		t.Errorf("MarshalText = %s, %v", b, err)
+	}
+
+	var s secret.Secret[string]
+	if err := s.UnmarshalText([]byte(password)); err != nil || s.Reveal() != password {
+		t.Errorf("string: %q, %v", s.Reveal(), err)
+	}
+	text := []byte(password)
+	var b secret.Secret[[]byte]
+	b.UnmarshalText(text)
+	text[0] = 'X'
+	if string(b.Reveal()) != password {
+		t.Errorf("[]byte shares the text it was decoded from: %q", b.Reveal())
+	}
+	var u secret.Secret[upper]
+	if err := u.UnmarshalText([]byte(password)); err != nil || u.Reveal() != "HUNTER2" {
+		t.Errorf("TextUnmarshaler: %q, %v", u.Reveal(), err)
+	}
+	if err := u.UnmarshalText([]byte(password + "!")); err == nil || err.Error() != "secret: cannot decode secret_test.upper" {
+		t.Errorf("failing TextUnmarshaler = %v", err)
+	}
+	var n secret.Secret[int]
+	if err := n.UnmarshalText([]byte("42")); err == nil || n.IsSet() {
+		t.Errorf("int = %v", err)
+	}
+}
+
+func TestSlog(t *testing.T) {
+	var b bytes.Buffer
+	for _, h := range []slog.Handler{slog.NewJSONHandler(&b, nil), slog.NewTextHandler(&b, nil)} {
+		l := slog.New(h)
+		s := secret.New(password)
+		l.Info("connect", "password", s, "config", newConfig(), slog.Any("ptr", &s), slog.Group("db", "password", s))
+		l.Info("connect", "err", fmt.Errorf("with %v", s))
+	}
+	if out := b.String(); strings.Contains(out, password) || !strings.Contains(out, secret.Redacted) {
+		t.Errorf("logged:\n%s", out)
+	}
+}
+
+func TestConfigDump(t *testing.T) {
+	for _, f := range []configdump.Format{configdump.JSON, configdump.YAML} {
+		var b strings.Builder
+		if err := configdump.Dump(&b, newConfig(), f); err != nil {
+			t.Fatal(err)
+		}
+		if out := b.String(); strings.Contains(out, password) || strings.Count(out, secret.Redacted) != 2 {
+			t.Errorf("%s dump:\n%s", f, out)
+		}
+	}
+}
+
+func TestFuncopts(t *testing.T) {
+	var as []funcopts.Applied
+	var got string
+	withPassword := func(p secret.Secret[string]) funcopts.Option[string] {
+		// no Redact: a Secret says it is one
+		return funcopts.Describe(funcopts.Info{Name: "password", Value: p}, func(s *string) error {
+			*s = p.Reveal()
+			return nil
+		})
+	}
+	if err := funcopts.ApplyWith(funcopts.ApplyConfig{OnApply: funcopts.Record(&as)}, &got, withPassword(secret.New(password))); err != nil {
+		t.Fatal(err)
+	}
+	if got != password || len(as) != 1 || as[0].Value != funcopts.Redacted {
+		t.Errorf("applied %q, reported %+v", got, as)
+	}
+}
+
+func TestValue(t *testing.T) {
+	var zero secret.Secret[string]
+	if zero.IsSet() || zero.Reveal() != "" || secret.Equal(zero, "") {
+		t.Error("the zero Secret is set, or holds something")
+	}
+	s := secret.New(password)
+	if !s.IsSet() || s.Reveal() != password || !s.IsSecret() {
+		t.Errorf("Reveal = %q", s.Reveal())
+	}
+	// set, if to the empty value
+	if e := secret.New(""); !e.IsSet() || !secret.Equal(e, "") {
+		t.Error("New of the empty string is not set")
+	}
+	for _, c := range []struct {
+		v    string
+		want bool
+	}{{password, true}, {"hunter3", false}, {"hunter", false}, {"hunter22", false}, {"", false}} {
+		if got := secret.Equal(s, c.v); got != c.want {
+			t.Errorf("Equal(%q) = %v, want %v", c.v, got, c.want)
+		}
+	}
+	if !secret.Equal(secret.New([]byte(password)), []byte(password)) {
+		t.Error("Equal of []byte")
+	}
+}
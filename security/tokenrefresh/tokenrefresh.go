@@ -0,0 +1,282 @@
+// Package tokenrefresh keeps a short-lived credential, an OAuth access
+// token say, fresh for every caller without any of them waiting on, or
+// stampeding, the token endpoint:
+//
+//	r, err := tokenrefresh.New(func(ctx context.Context) (tokenrefresh.Token, error) {
+//		return oauth.ClientCredentials(ctx, id, secret)
+//	})
+//	tok, err := r.Token(ctx)
+//	req.Header.Set("Authorization", "Bearer "+tok.Value.Reveal())
+//
+// One refresher is the single writer of the credential. Callers only read
+// it, and a refresh is one call shared by every caller that needs it, the
+// singleflight idea specialised to one key:
+//
+//   - a token inside its refresh window, shortly before it expires, is
+//     still served at once; the first caller to see it starts a refresh in
+//     the background, and callers never wait for it
+//   - only a missing or expired token makes callers wait, and then all of
+//     them wait for the same fetch; each can give up on its own context
+//     without cancelling the fetch the others share
+//   - a failed refresh is not retried by every caller: the next attempt
+//     waits out a backoff, jittered so a fleet of clients does not retry
+//     in step, and callers meanwhile keep the still-valid token or, once it
+//     has expired, get the failure at once
+//   - Invalidate drops a token the server rejected, for the next caller to
+//     fetch a new one; it names the token, so many requests failing with
+//     the same stale token cause one refresh, not one each
+package tokenrefresh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"patterns/clock"
+	"patterns/construct"
+	"patterns/funcopts"
+	"patterns/randsource"
+	"patterns/resilience/retry"
+	"patterns/security/secret"
+)
+
+// Token is a credential and when it stops working. A zero Expiry never
+// expires.
+type Token struct {
+	Value  secret.Secret[string]
+	Expiry time.Time
+}
+
+// Fetch gets a new token, e.g. from an OAuth token endpoint.
+type Fetch func(ctx context.Context) (Token, error)
+
+type options struct {
+	early   time.Duration
+	timeout time.Duration
+	backoff retry.Backoff
+	clock   clock.Clock
+	rand    randsource.Rand
+}
+
+type Option = funcopts.Option[options]
+
+// WithRefreshBefore starts refreshing d before a token expires, or half
+// way through its life if that is sooner. The default is a minute.
+func WithRefreshBefore(d time.Duration) Option {
+	return func(options *options) error {
+		if d < 0 {
+			return errors.New("refresh window cannot be negative")
+		}
+		options.early = d
+		return nil
+	}
+}
+
+// WithTimeout bounds one fetch, on real time. The default is 30 seconds.
+func WithTimeout(d time.Duration) Option {
+	return func(options *options) error {
+		if d <= 0 {
+			return errors.New("timeout must be positive")
+		}
+		options.timeout = d
+		return nil
+	}
+}
+
+// WithBackoff sets the wait after consecutive failed fetches, before the
+// full jitter; the default is retry.Exponential(time.Second, time.Minute).
+func WithBackoff(b retry.Backoff) Option {
+	return func(options *options) error {
+		if b == nil {
+			return errors.New("backoff cannot be nil")
+		}
+		options.backoff = b
+		return nil
+	}
+}
+
+func WithClock(c clock.Clock) Option {
+	return func(options *options) error {
+		if c == nil {
+			return errors.New("clock cannot be nil")
+		}
+		options.clock = c
+		return nil
+	}
+}
+
+func WithRand(r randsource.Rand) Option {
+	return func(options *options) error {
+		if r == nil {
+			return errors.New("rand cannot be nil")
+		}
+		options.rand = r
+		return nil
+	}
+}
+
+func (o *options) SetDefaults() {
+	o.early = time.Minute
+	o.timeout = 30 * time.Second
+	o.backoff = retry.Exponential(time.Second, time.Minute)
+	o.clock = clock.Real
+	o.rand = randsource.Global
+}
+
+// ErrNoToken is returned by Token while no token has ever been fetched and
+// the last fetch failed; it wraps the failure.
+var ErrNoToken = errors.New("tokenrefresh: no token")
+
+// call is one fetch, shared by every caller waiting for it.
+type call struct {
+	done chan struct{}
+	tok  Token
+	err  error
+}
+
+// single-writer refresher
+// Level: Good
+// pros: callers never wait for a refresh while the token is valid; one
+// fetch per refresh however many callers; failures back off instead of
+// hammering the issuer.
+// cons: a token may be refreshed a little early; an expired token with a
+// failing issuer fails every caller until the backoff passes.
+//
+// Refresher caches one token. It is safe for concurrent use.
+type Refresher struct {
+	fetch   Fetch
+	options options
+
+	mu        sync.Mutex
+	tok       Token
+	have      bool
+	refreshAt time.Time // start of the refresh window
+	inflight  *call
+	failures  int
+	retryAt   time.Time
+	lastErr   error
+}
+
+func New(fetch Fetch, opts ...Option) (*Refresher, error) {
+	if fetch == nil {
+		return nil, errors.New("fetch cannot be nil")
+	}
+	options, err := construct.New(opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &Refresher{fetch: fetch, options: *options}, nil
+}
+
+// Token returns a valid token, fetching one only if there is none.
+func (r *Refresher) Token(ctx context.Context) (Token, error) {
+	r.mu.Lock()
+	now := r.options.clock.Now()
+	if r.have && !expired(r.tok, now) {
+		if !r.refreshAt.IsZero() && !now.Before(r.refreshAt) && !now.Before(r.retryAt) {
+			r.start(ctx) // early: refresh in the background
+		}
+		tok := r.tok
+		r.mu.Unlock()
+		return tok, nil
+	}
+	c := r.inflight
+	if c == nil {
+		if now.Before(r.retryAt) {
+			err := r.failure()
+			r.mu.Unlock()
+			return Token{}, err
+		}
+		c = r.start(ctx)
+	}
+	r.mu.Unlock()
+
+	select {
+	case <-c.done:
+		if c.err != nil {
+			return Token{}, c.err
+		}
+		return c.tok, nil
+	case <-ctx.Done():
+		return Token{}, context.Cause(ctx)
+	}
+}
+
+func expired(t Token, now time.Time) bool {
+	return !t.Expiry.IsZero() && !now.Before(t.Expiry)
+}
+
+// start begins a fetch unless one is running, and returns the one that
+// is. The fetch keeps ctx's values but not its cancellation: other callers
+// may be waiting for it.
+func (r *Refresher) start(ctx context.Context) *call {
+	if r.inflight != nil {
+		return r.inflight
+	}
+	c := &call{done: make(chan struct{})}
+	r.inflight = c
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), r.options.timeout)
+	go func() {
+		defer cancel()
+		tok, err := r.fetch(ctx)
+		r.finish(c, tok, err)
+	}()
+	return c
+}
+
+func (r *Refresher) finish(c *call, tok Token, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := r.options.clock.Now()
+	r.inflight = nil
+	if err == nil && expired(tok, now) {
+		err = fmt.Errorf("token expired at %v, on arrival", tok.Expiry)
+	}
+	if err != nil {
+		r.failures++
+		r.lastErr = err
+		r.retryAt = now.Add(retry.Full(r.options.rand, r.options.backoff(r.failures)))
+		c.err = r.failure()
+	} else {
+		r.tok, r.have = tok, true
+		r.refreshAt = refreshAt(tok, now, r.options.early)
+		r.failures, r.retryAt, r.lastErr = 0, time.Time{}, nil
+		c.tok = tok
+	}
+	close(c.done)
+}
+
+// refreshAt is early before the token expires, but no earlier than half
+// way through its life, so that short-lived tokens are not refreshed
+// continuously; zero, never, for a token that does not expire.
+func refreshAt(t Token, now time.Time, early time.Duration) time.Time {
+	if t.Expiry.IsZero() {
+		return time.Time{}
+	}
+	return t.Expiry.Add(-min(early, t.Expiry.Sub(now)/2))
+}
+
+// failure is the error for callers that find no valid token after the
+// last fetch failed.
+func (r *Refresher) failure() error {
+	if !r.have {
+		return fmt.Errorf("%w: %w", ErrNoToken, r.lastErr)
+	}
+	return fmt.Errorf("tokenrefresh: token expired and refresh failed: %w", r.lastErr)
+}
+
+// Invalidate drops stale, a token the server refused, so that the next
+// Token fetches a new one; if stale is no longer the current token, it
+// was already replaced and nothing happens. The failure backoff is reset:
+// a refused token is a reason to fetch now.
+func (r *Refresher) Invalidate(stale Token) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.have || !stale.Expiry.Equal(r.tok.Expiry) || !secret.Equal(r.tok.Value, stale.Value.Reveal()) {
+		return
+	}
+	r.tok, r.have = Token{}, false
+	r.refreshAt, r.retryAt = time.Time{}, time.Time{}
+}
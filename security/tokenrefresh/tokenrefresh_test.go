@@ -0,0 +1,427 @@
+package tokenrefresh
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"patterns/clock"
+	"patterns/randsource"
+	"patterns/resilience/retry"
+	"patterns/security/secret"
+)
+
+var (
+	epoch      = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	errIssuer  = errors.New("issuer down")
+	errRefused = errors.New("invalid_client")
+)
+
+const seed = 7
+
+type result struct {
+	tok Token
+	err error
+}
+
+// issuer is a token endpoint the test answers by hand: each fetch sends
+// its context on started and returns the next result given.
+type issuer struct {
+	started chan context.Context
+	results chan result
+}
+
+func (i *issuer) fetch(ctx context.Context) (Token, error) {
+	i.started <- ctx
+	select {
+	case res := <-i.results:
+		return res.tok, res.err
+	case <-ctx.Done():
+		return Token{}, ctx.Err()
+	}
+}
+
+type fixture struct {
+	t   *testing.T
+	clk *clock.Fake
+	is  *issuer
+	r   *Refresher
+}
+
+func newFixture(t *testing.T, opts ...Option) *fixture {
+	f := &fixture{
+		t:   t,
+		clk: clock.NewFake(epoch),
+		is:  &issuer{started: make(chan context.Context, 16), results: make(chan result)},
+	}
+	r, err := New(f.is.fetch, append([]Option{WithClock(f.clk), WithRand(randsource.New(seed))}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.r = r
+	return f
+}
+
+func tok(value string, expiry time.Duration) Token {
+	t := Token{Value: secret.New(value)}
+	if expiry != 0 {
+		t.Expiry = epoch.Add(expiry)
+	}
+	return t
+}
+
+// started waits for the next fetch to begin.
+func (f *fixture) started() context.Context {
+	f.t.Helper()
+	select {
+	case ctx := <-f.is.started:
+		return ctx
+	case <-time.After(5 * time.Second):
+		f.t.Fatal("no fetch started")
+		return nil
+	}
+}
+
+// answer ends the running fetch, and waits for the refresher to record it.
+func (f *fixture) answer(t Token, err error) {
+	f.t.Helper()
+	f.is.results <- result{t, err}
+	for {
+		f.r.mu.Lock()
+		idle := f.r.inflight == nil
+		f.r.mu.Unlock()
+		if idle {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// noFetch checks that no fetch has started.
+func (f *fixture) noFetch() {
+	f.t.Helper()
+	select {
+	case <-f.is.started:
+		f.t.Error("a fetch started")
+	default:
+	}
+}
+
+// get calls Token, which must not wait, and checks it returns want.
+func (f *fixture) get(want string) {
+	f.t.Helper()
+	got, err := f.r.Token(context.Background())
+	if err != nil || got.Value.Reveal() != want {
+		f.t.Fatalf("Token = %q, %v; want %q", got.Value.Reveal(), err, want)
+	}
+}
+
+// async calls Token on a goroutine; the returned func waits for it.
+func (f *fixture) async(ctx context.Context) func() (Token, error) {
+	done := make(chan result, 1)
+	go func() {
+		t, err := f.r.Token(ctx)
+		done <- result{t, err}
+	}()
+	return func() (Token, error) {
+		res := <-done
+		return res.tok, res.err
+	}
+}
+
+// TestShared has many callers ask for the first token at once: they all
+// wait for, and get, the one fetch.
+func TestShared(t *testing.T) {
+	f := newFixture(t)
+	var waits []func() (Token, error)
+	for range 10 {
+		waits = append(waits, f.async(context.Background()))
+	}
+	f.started()
+	f.answer(tok("a", time.Hour), nil)
+	for _, wait := range waits {
+		if got, err := wait(); err != nil || got.Value.Reveal() != "a" {
+			t.Errorf("Token = %v, %v", got, err)
+		}
+	}
+	f.noFetch()
+	f.get("a")
+}
+
+// TestEarlyRefresh checks that a token in its refresh window is served at
+// once while one background fetch replaces it.
+func TestEarlyRefresh(t *testing.T) {
+	f := newFixture(t, WithRefreshBefore(5*time.Minute))
+	wait := f.async(context.Background())
+	f.started()
+	f.answer(tok("a", time.Hour), nil)
+	wait()
+
+	f.clk.Advance(55*time.Minute - time.Nanosecond)
+	f.get("a")
+	f.noFetch()
+	f.clk.Advance(time.Nanosecond)
+	f.get("a")
+	f.started()
+	// while it runs, callers still get the old token, and start nothing
+	f.get("a")
+	f.get("a")
+	f.noFetch()
+	f.answer(tok("b", 2*time.Hour), nil)
+	f.get("b")
+	f.noFetch()
+}
+
+// TestShortLived refreshes a token living less than twice the window half
+// way through its life, not at once.
+func TestShortLived(t *testing.T) {
+	f := newFixture(t, WithRefreshBefore(time.Hour))
+	wait := f.async(context.Background())
+	f.started()
+	f.answer(tok("a", 10*time.Minute), nil)
+	wait()
+	f.clk.Advance(5*time.Minute - time.Nanosecond)
+	f.get("a")
+	f.noFetch()
+	f.clk.Advance(time.Nanosecond)
+	f.get("a")
+	f.started()
+	f.answer(tok("b", time.Hour), nil)
+
+	// and one without an expiry is never refreshed
+	f = newFixture(t)
+	wait = f.async(context.Background())
+	f.started()
+	f.answer(tok("forever", 0), nil)
+	wait()
+	f.clk.Advance(24 * 365 * time.Hour)
+	f.get("forever")
+	f.noFetch()
+}
+
+// top draws the top of every range, so that Full jitter waits exactly
+// the backoff.
+type top struct{}
+
+func (top) IntN(n int) int       { return n - 1 }
+func (top) Int64N(n int64) int64 { return n - 1 }
+func (top) Float64() float64     { return 1 }
+func (top) Uint64() uint64       { return 1<<64 - 1 }
+
+// TestBackoff fails refreshes in the window and after expiry: each next
+// attempt waits out a growing backoff, and callers meanwhile keep the
+// valid token, or fail at once without a fetch.
+func TestBackoff(t *testing.T) {
+	f := newFixture(t, WithRefreshBefore(10*time.Minute), WithBackoff(retry.Exponential(time.Minute, 5*time.Minute)), WithRand(top{}))
+	wait := f.async(context.Background())
+	f.started()
+	f.answer(tok("a", time.Hour), nil)
+	wait()
+
+	f.clk.Advance(50 * time.Minute)
+	f.get("a")
+	f.started()
+	f.answer(Token{}, errIssuer)
+	// retried after 1m, 2m and 4m
+	for _, d := range []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute} {
+		f.clk.Advance(d - time.Nanosecond)
+		f.get("a")
+		f.noFetch()
+		f.clk.Advance(time.Nanosecond)
+		f.get("a")
+		f.started()
+		f.answer(Token{}, errIssuer)
+	}
+
+	// at 57m, the next try is capped at 5m, after the token expires: in
+	// between, the failure at once
+	f.clk.Advance(3 * time.Minute)
+	_, err := f.r.Token(context.Background())
+	if !errors.Is(err, errIssuer) || errors.Is(err, ErrNoToken) || err.Error() != "tokenrefresh: token expired and refresh failed: issuer down" {
+		t.Errorf("Token = %v", err)
+	}
+	f.noFetch()
+	// then the next caller fetches, and waits for it
+	f.clk.Advance(2*time.Minute - time.Nanosecond)
+	if _, err := f.r.Token(context.Background()); err == nil {
+		t.Error("Token succeeded during the backoff")
+	}
+	f.noFetch()
+	f.clk.Advance(time.Nanosecond)
+	wait = f.async(context.Background())
+	f.started()
+	f.answer(Token{}, errRefused)
+	if _, err := wait(); !errors.Is(err, errRefused) {
+		t.Errorf("Token after the fetch failed = %v", err)
+	}
+	f.r.mu.Lock()
+	failures, retryAt := f.r.failures, f.r.retryAt
+	f.r.mu.Unlock()
+	if want := f.clk.Now().Add(5 * time.Minute); failures != 5 || !retryAt.Equal(want) {
+		t.Errorf("after %d failures: retry at %v, want 5 failures and %v", failures, retryAt, want)
+	}
+
+	// a success starts the backoff over
+	f.clk.Advance(5 * time.Minute)
+	wait = f.async(context.Background())
+	f.started()
+	f.answer(tok("b", 2*time.Hour), nil)
+	wait()
+	if f.r.failures != 0 || !f.r.retryAt.IsZero() {
+		t.Errorf("after a success: %d failures, retry at %v", f.r.failures, f.r.retryAt)
+	}
+}
+
+func TestNoToken(t *testing.T) {
+	f := newFixture(t)
+	wait := f.async(context.Background())
+	f.started()
+	f.answer(Token{}, errIssuer)
+	want := "tokenrefresh: no token: issuer down"
+	if _, err := wait(); !errors.Is(err, ErrNoToken) || !errors.Is(err, errIssuer) || err.Error() != want {
+		t.Errorf("Token = %v, want %s", err, want)
+	}
+	if _, err := f.r.Token(context.Background()); err == nil || err.Error() != want {
+		t.Errorf("Token during the backoff = %v", err)
+	}
+	f.noFetch()
+	// the default backoff, 1s after the first failure, fully jittered
+	if want := epoch.Add(retry.Full(randsource.New(seed), time.Second)); !f.r.retryAt.Equal(want) {
+		t.Errorf("retry at %v, want %v", f.r.retryAt, want)
+	}
+
+	// a token expired on arrival is a failure too
+	f.clk.Advance(time.Hour)
+	wait = f.async(context.Background())
+	f.started()
+	f.answer(tok("old", time.Minute), nil)
+	if _, err := wait(); !errors.Is(err, ErrNoToken) || !strings.Contains(err.Error(), "on arrival") {
+		t.Errorf("Token = %v", err)
+	}
+}
+
+type key struct{}
+
+// TestCallerGivesUp cancels one waiting caller: it returns its context's
+// cause, while the fetch, which keeps the caller's values but not its
+// cancellation, goes on for the others.
+func TestCallerGivesUp(t *testing.T) {
+	f := newFixture(t)
+	errGone := errors.New("client went away")
+	ctx, cancel := context.WithCancelCause(context.WithValue(context.Background(), key{}, "trace-1"))
+	first := f.async(ctx)
+	fetchCtx := f.started()
+	second := f.async(context.Background())
+	cancel(errGone)
+	if _, err := first(); err != errGone {
+		t.Errorf("cancelled Token = %v", err)
+	}
+	if fetchCtx.Err() != nil || fetchCtx.Value(key{}) != "trace-1" {
+		t.Errorf("fetch context: %v, value %v", fetchCtx.Err(), fetchCtx.Value(key{}))
+	}
+	if _, ok := fetchCtx.Deadline(); !ok {
+		t.Error("fetch has no timeout")
+	}
+	f.answer(tok("a", time.Hour), nil)
+	if got, err := second(); err != nil || got.Value.Reveal() != "a" {
+		t.Errorf("Token = %v, %v", got, err)
+	}
+}
+
+// TestTimeout bounds a fetch that never answers, on real time.
+func TestTimeout(t *testing.T) {
+	f := newFixture(t, WithTimeout(20*time.Millisecond))
+	wait := f.async(context.Background())
+	f.started()
+	if _, err := wait(); !errors.Is(err, context.DeadlineExceeded) || !errors.Is(err, ErrNoToken) {
+		t.Errorf("Token = %v", err)
+	}
+}
+
+// TestInvalidate has many requests fail with the same token: one refresh
+// replaces it, and invalidating it again, or any other token, does
+// nothing.
+func TestInvalidate(t *testing.T) {
+	b := retry.Constant(time.Hour)
+	f := newFixture(t, WithRefreshBefore(time.Minute), WithBackoff(b))
+	wait := f.async(context.Background())
+	f.started()
+	f.answer(tok("a", time.Hour), nil)
+	stale, _ := wait()
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var got []string
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			f.r.Invalidate(stale)
+			tok, err := f.r.Token(context.Background())
+			if err != nil {
+				t.Error(err)
+			}
+			mu.Lock()
+			got = append(got, tok.Value.Reveal())
+			mu.Unlock()
+		}()
+	}
+	f.started()
+	f.answer(tok("b", time.Hour), nil)
+	wg.Wait()
+	for _, v := range got {
+		if v != "b" {
+			t.Errorf("Token after Invalidate = %q", v)
+		}
+	}
+	f.noFetch()
+
+	// neither the replaced token nor a lookalike drops the current one
+	f.r.Invalidate(stale)
+	f.r.Invalidate(tok("b", 2*time.Hour))
+	f.r.Invalidate(tok("c", time.Hour))
+	f.get("b")
+	f.noFetch()
+
+	// a refused token is fetched again at once, backoff or not
+	f.clk.Advance(59 * time.Minute)
+	f.get("b")
+	f.started()
+	f.answer(Token{}, errIssuer)
+	f.get("b")
+	f.noFetch()
+	current, _ := f.r.Token(context.Background())
+	f.r.Invalidate(current)
+	wait = f.async(context.Background())
+	f.started()
+	f.answer(tok("c", 2*time.Hour), nil)
+	if got, err := wait(); err != nil || got.Value.Reveal() != "c" {
+		t.Errorf("Token = %v, %v", got, err)
+	}
+}
+
+func TestOptions(t *testing.T) {
+	fetch := func(context.Context) (Token, error) { return Token{}, nil }
+	for _, c := range []struct {
+		fetch Fetch
+		opt   Option
+		want  string
+	}{
+		{nil, WithTimeout(time.Second), "fetch cannot be nil"},
+		{fetch, WithRefreshBefore(-1), "refresh window cannot be negative"},
+		{fetch, WithTimeout(0), "timeout must be positive"},
+		{fetch, WithBackoff(nil), "backoff cannot be nil"},
+		{fetch, WithClock(nil), "clock cannot be nil"},
+		{fetch, WithRand(nil), "rand cannot be nil"},
+	} {
+		if r, err := New(c.fetch, c.opt); r != nil || err == nil || err.Error() != c.want {
+			t.Errorf("New = %v, %v; want %q", r, err, c.want)
+		}
+	}
+	if _, err := New(fetch, WithRefreshBefore(0)); err != nil {
+		t.Errorf("no refresh window: %v", err)
+	}
+}
@@ -0,0 +1,499 @@
+// Package snapshot keeps a replicated-state-machine style store small:
+// every change goes through a write-ahead log (storage/wal), the state is
+// periodically written out whole, and the log before the snapshot is
+// compacted away.
+//
+//	s, err := snapshot.Open(dir, counters, snapshot.WithSnapshotEvery(10_000))
+//	_, err = s.Apply([]byte(`{"incr":"hits"}`))
+//
+// Recovery loads the newest intact snapshot, then replays the log after
+// it. A snapshot is taken in phases, and a crash in any of them leaves a
+// directory Open recovers from:
+//
+//  1. the log is fsynced, so it reaches at least as far as the snapshot;
+//  2. the state is written to a temporary file with a length and CRC32
+//     trailer and fsynced (a crash leaves a .tmp file, removed on Open);
+//  3. the file is renamed to <last index>.snap and the directory fsynced
+//     (the snapshot now exists, or does not: rename is atomic);
+//  4. snapshots beyond the retained count are deleted;
+//  5. the log is truncated up to the oldest retained snapshot (a crash
+//     before this only leaves records that replay skips).
+//
+// Keeping more than one snapshot, and the log behind the oldest, means a
+// snapshot damaged after it was written costs replay time, not data:
+// Open skips a snapshot whose CRC fails and falls back to the one before.
+package snapshot
+
+import (
+	"bufio"
+	"cmp"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"patterns/clock"
+	"patterns/construct"
+	"patterns/funcopts"
+	"patterns/lifecycle/multicloser"
+	"patterns/storage/wal"
+)
+
+// State is the state machine the log drives. The Store calls its methods
+// one at a time; reads the application makes concurrently with them are
+// the State's to synchronize.
+type State interface {
+	// Apply applies one record. It must be deterministic: a record
+	// applied once must apply the same way on replay.
+	Apply(data []byte) error
+	// Snapshot writes the whole state to w.
+	Snapshot(w io.Writer) error
+	// Restore replaces the whole state with one written by Snapshot.
+	Restore(r io.Reader) error
+}
+
+const snapshotExt = ".snap"
+
+// trailerSize is the payload length (8 bytes) and CRC32 (4 bytes) after
+// the payload.
+const trailerSize = 12
+
+var (
+	ErrClosed = errors.New("snapshot: store is closed")
+	// ErrFailed wraps the log error that left the state ahead of the log.
+	// The store refuses writes from then on; reopen it to recover.
+	ErrFailed = errors.New("snapshot: store failed")
+	errCRC    = errors.New("length or checksum mismatch")
+)
+
+type options struct {
+	every    int
+	interval time.Duration
+	retain   int
+	wal      []wal.Option
+	clock    clock.Clock
+}
+
+type Option = funcopts.Option[options]
+
+// WithSnapshotEvery snapshots after n applied records; 0 disables it.
+// The default is 1000.
+func WithSnapshotEvery(n int) Option {
+	return func(options *options) error {
+		if n < 0 {
+			return errors.New("snapshot interval cannot be negative")
+		}
+		options.every = n
+		return nil
+	}
+}
+
+// WithSnapshotInterval also snapshots every d while records arrive,
+// however few; 0, the default, disables it.
+func WithSnapshotInterval(d time.Duration) Option {
+	return func(options *options) error {
+		if d < 0 {
+			return errors.New("snapshot interval cannot be negative")
+		}
+		options.interval = d
+		return nil
+	}
+}
+
+// WithRetain sets how many snapshots are kept, and so how far back the
+// log reaches; the default is 2.
+func WithRetain(n int) Option {
+	return func(options *options) error {
+		if n < 1 {
+			return errors.New("must retain at least one snapshot")
+		}
+		options.retain = n
+		return nil
+	}
+}
+
+// WithLogOptions configures the write-ahead log.
+func WithLogOptions(opts ...wal.Option) Option {
+	return func(options *options) error {
+		options.wal = append(options.wal, opts...)
+		return nil
+	}
+}
+
+func WithClock(c clock.Clock) Option {
+	return func(options *options) error {
+		if c == nil {
+			return errors.New("clock cannot be nil")
+		}
+		options.clock = c
+		return nil
+	}
+}
+
+func (o *options) SetDefaults() {
+	o.every = 1000
+	o.retain = 2
+	o.clock = clock.Real
+}
+
+// snapshot and compaction pattern
+// Level: Good
+// pros: recovery time is bounded by the state size plus the records since
+// the last snapshot, not by the history; disk use is bounded the same way.
+// cons: Snapshot serializes the whole state while writes wait, so very
+// large states want an incremental or copy-on-write snapshot instead.
+type Store struct {
+	mu      sync.Mutex
+	dir     string
+	options options
+	state   State
+	log     *wal.Log
+	closer  *multicloser.Stack
+	failed  error
+
+	snapIndex uint64 // last index covered by the newest snapshot
+	since     int    // records applied since it
+	replayed  int
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// Open recovers state from dir: it restores the newest intact snapshot
+// and replays the log records after it. state must be empty.
+func Open(dir string, state State, opts ...Option) (_ *Store, err error) {
+	if state == nil {
+		return nil, errors.New("state cannot be nil")
+	}
+	options, err := construct.New(opts...)
+	if err != nil {
+		return nil, err
+	}
+	snapDir := filepath.Join(dir, "snapshots")
+	if err := os.MkdirAll(snapDir, 0o755); err != nil {
+		return nil, err
+	}
+
+	var mc multicloser.Stack
+	defer mc.Rollback(&err)
+	log, err := wal.Open(filepath.Join(dir, "wal"), options.wal...)
+	if err != nil {
+		return nil, err
+	}
+	mc.Defer("wal", log.Close)
+
+	s := &Store{dir: snapDir, options: *options, state: state, log: log}
+	if err := s.recover(); err != nil {
+		return nil, err
+	}
+	s.closer = mc.Release()
+
+	if s.options.interval > 0 {
+		s.stop, s.done = make(chan struct{}), make(chan struct{})
+		go s.snapshotLoop()
+	}
+	return s, nil
+}
+
+func (s *Store) recover() error {
+	snaps, err := s.list()
+	if err != nil {
+		return err
+	}
+	for _, index := range slices.Backward(snaps) {
+		err := s.restore(index)
+		if errors.Is(err, errCRC) {
+			// damaged at rest: the log behind the older one still covers it
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		s.snapIndex = index
+		break
+	}
+
+	first, last := s.log.FirstIndex(), s.log.LastIndex()
+	if s.snapIndex+1 < first {
+		return fmt.Errorf("snapshot: log starts at %d but the newest usable snapshot covers only %d", first, s.snapIndex)
+	}
+	if last < s.snapIndex {
+		return fmt.Errorf("snapshot: log ends at %d, before snapshot %d", last, s.snapIndex)
+	}
+	for rec, err := range s.log.Replay(s.snapIndex + 1) {
+		if err != nil {
+			return err
+		}
+		if err := s.state.Apply(rec.Data); err != nil {
+			return fmt.Errorf("snapshot: replaying record %d: %w", rec.Index, err)
+		}
+		s.replayed++
+	}
+	s.since = s.replayed
+	return nil
+}
+
+// list removes leftover temporary files and returns the snapshot indexes,
+// oldest first.
+func (s *Store) list() ([]uint64, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	var indexes []uint64
+	for _, e := range entries {
+		if strings.HasSuffix(e.Name(), ".tmp") {
+			if err := os.Remove(filepath.Join(s.dir, e.Name())); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		name, ok := strings.CutSuffix(e.Name(), snapshotExt)
+		if !ok {
+			continue
+		}
+		if index, err := strconv.ParseUint(name, 10, 64); err == nil {
+			indexes = append(indexes, index)
+		}
+	}
+	slices.SortFunc(indexes, cmp.Compare)
+	return indexes, nil
+}
+
+func (s *Store) path(index uint64) string {
+	return filepath.Join(s.dir, fmt.Sprintf("%020d%s", index, snapshotExt))
+}
+
+// restore checks a snapshot's trailer before handing it to Restore, so a
+// damaged file never reaches the state.
+func (s *Store) restore(index uint64) error {
+	f, err := os.Open(s.path(index))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	size := fi.Size() - trailerSize
+	if size < 0 {
+		return errCRC
+	}
+	h := crc32.NewIEEE()
+	if _, err := io.Copy(h, io.LimitReader(f, size)); err != nil {
+		return err
+	}
+	var trailer [trailerSize]byte
+	if _, err := io.ReadFull(f, trailer[:]); err != nil {
+		return err
+	}
+	if binary.LittleEndian.Uint64(trailer[:]) != uint64(size) || binary.LittleEndian.Uint32(trailer[8:]) != h.Sum32() {
+		return errCRC
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	return s.state.Restore(bufio.NewReader(io.LimitReader(f, size)))
+}
+
+// Apply applies data to the state and logs it, returning its log index.
+// A record the state rejects is not logged. The write is as durable as
+// the log's sync policy makes it.
+func (s *Store) Apply(data []byte) (uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.usable(); err != nil {
+		return 0, err
+	}
+	if err := s.state.Apply(data); err != nil {
+		return 0, err
+	}
+	index, err := s.log.Append(data)
+	if err != nil {
+		s.failed = fmt.Errorf("%w: %w", ErrFailed, err)
+		return 0, s.failed
+	}
+	s.since++
+	if s.options.every > 0 && s.since >= s.options.every {
+		return index, s.snapshotLocked()
+	}
+	return index, nil
+}
+
+func (s *Store) usable() error {
+	if s.log == nil {
+		return ErrClosed
+	}
+	return s.failed
+}
+
+// Snapshot writes the state now and compacts the log.
+func (s *Store) Snapshot() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.usable(); err != nil {
+		return err
+	}
+	return s.snapshotLocked()
+}
+
+func (s *Store) snapshotLocked() error {
+	index := s.log.LastIndex()
+	if index == s.snapIndex {
+		s.since = 0
+		return nil
+	}
+	// phase 1: the snapshot must not get ahead of the durable log
+	if err := s.log.Sync(); err != nil {
+		return err
+	}
+	// phases 2 and 3
+	if err := s.write(index); err != nil {
+		return err
+	}
+	s.snapIndex, s.since = index, 0
+	// phases 4 and 5
+	return s.compact()
+}
+
+func (s *Store) write(index uint64) error {
+	path := s.path(index)
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp) // no-op after the rename
+
+	h := crc32.NewIEEE()
+	w := &countWriter{w: io.MultiWriter(f, h)}
+	bw := bufio.NewWriter(w)
+	err = s.state.Snapshot(bw)
+	if err == nil {
+		err = bw.Flush()
+	}
+	if err == nil {
+		var trailer [trailerSize]byte
+		binary.LittleEndian.PutUint64(trailer[:], uint64(w.n))
+		binary.LittleEndian.PutUint32(trailer[8:], h.Sum32())
+		_, err = f.Write(trailer[:])
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if err := errors.Join(err, f.Close()); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return err
+	}
+	return syncDir(s.dir)
+}
+
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// compact deletes snapshots beyond the retained count and the log behind
+// the oldest one kept.
+func (s *Store) compact() error {
+	snaps, err := s.list()
+	if err != nil {
+		return err
+	}
+	if extra := len(snaps) - s.options.retain; extra > 0 {
+		for _, index := range snaps[:extra] {
+			if err := os.Remove(s.path(index)); err != nil {
+				return err
+			}
+		}
+		snaps = snaps[extra:]
+		if err := syncDir(s.dir); err != nil {
+			return err
+		}
+	}
+	return s.log.TruncateFront(snaps[0] + 1)
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+func (s *Store) snapshotLoop() {
+	defer close(s.done)
+	t := s.options.clock.NewTicker(s.options.interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-t.C():
+			s.mu.Lock()
+			if s.usable() == nil && s.since > 0 {
+				// a failure is retried on the next tick or record
+				s.snapshotLocked()
+			}
+			s.mu.Unlock()
+		}
+	}
+}
+
+// LastIndex is the index of the last record applied.
+func (s *Store) LastIndex() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.log == nil {
+		return 0
+	}
+	return s.log.LastIndex()
+}
+
+// SnapshotIndex is the last index covered by the newest snapshot, 0 if
+// there is none.
+func (s *Store) SnapshotIndex() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.snapIndex
+}
+
+// Replayed reports how many log records Open applied after the snapshot.
+func (s *Store) Replayed() int { return s.replayed }
+
+// Close stops periodic snapshots and closes the log. It does not take a
+// final snapshot; call Snapshot first to make the next Open faster.
+func (s *Store) Close() error {
+	s.mu.Lock()
+	if s.log == nil {
+		s.mu.Unlock()
+		return ErrClosed
+	}
+	s.log = nil
+	s.mu.Unlock()
+	if s.stop != nil {
+		close(s.stop)
+		<-s.done
+	}
+	return s.closer.Close()
+}
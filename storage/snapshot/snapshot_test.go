@@ -0,0 +1,317 @@
+package snapshot
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"patterns/storage/wal"
+)
+
+// counters counts records by their data.
+type counters map[string]int
+
+func (c counters) Apply(data []byte) error {
+	if len(data) == 0 {
+		return errors.New("empty key")
+	}
+	c[string(data)]++
+	return nil
+}
+
+func (c counters) Snapshot(w io.Writer) error { return json.NewEncoder(w).Encode(c) }
+
+func (c counters) Restore(r io.Reader) error {
+	clear(c)
+	return json.NewDecoder(r).Decode(&c)
+}
+
+// keys cycles through a few keys so counts depend on every record.
+var keys = []string{"a", "b", "c"}
+
+func opts() []Option {
+	return []Option{
+		WithSnapshotEvery(0),
+		WithRetain(2),
+		// a few records per segment, so compaction removes files
+		WithLogOptions(wal.WithSegmentSize(40)),
+	}
+}
+
+func open(t *testing.T, dir string) (*Store, counters) {
+	t.Helper()
+	c := counters{}
+	s, err := Open(dir, c, opts()...)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	return s, c
+}
+
+func apply(t *testing.T, s *Store, from, to int) {
+	t.Helper()
+	for i := from; i <= to; i++ {
+		if _, err := s.Apply([]byte(keys[i%len(keys)])); err != nil {
+			t.Fatalf("Apply %d: %v", i, err)
+		}
+	}
+}
+
+// expect is the state after records 1..n.
+func expect(n int) counters {
+	c := counters{}
+	for i := 1; i <= n; i++ {
+		c[keys[i%len(keys)]]++
+	}
+	return c
+}
+
+// copyDir copies the regular files under src into dst.
+func copyDir(t *testing.T, dst, src string) {
+	t.Helper()
+	err := filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(src, path)
+		if d.IsDir() {
+			return os.MkdirAll(filepath.Join(dst, rel), 0o755)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(filepath.Join(dst, rel), data, 0o644)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func snapshots(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(filepath.Join(dir, "snapshots"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, strings.TrimLeft(e.Name(), "0"))
+	}
+	return names
+}
+
+// TestCrashAtEveryPhase rebuilds the directory a crash would leave in
+// each phase of a snapshot, from copies taken before and after a real
+// one, and checks that Open recovers every record from each.
+func TestCrashAtEveryPhase(t *testing.T) {
+	base := t.TempDir()
+	pre, post := filepath.Join(base, "pre"), filepath.Join(base, "post")
+
+	s, _ := open(t, pre)
+	apply(t, s, 1, 10)
+	if err := s.Snapshot(); err != nil {
+		t.Fatal(err)
+	}
+	apply(t, s, 11, 20)
+	if err := s.Snapshot(); err != nil {
+		t.Fatal(err)
+	}
+	apply(t, s, 21, 30)
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	copyDir(t, post, pre)
+	s, _ = open(t, post)
+	if err := s.Snapshot(); err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+	newSnap, err := os.ReadFile(s.path(30))
+	if err != nil {
+		t.Fatal(err)
+	}
+	newName := filepath.Base(s.path(30))
+
+	for _, c := range []struct {
+		name      string
+		build     func(dir string)
+		snapIndex uint64
+		replayed  int
+	}{
+		{"1 log synced", func(dir string) {
+			copyDir(t, dir, pre)
+		}, 20, 10},
+		{"2 temp file half written", func(dir string) {
+			copyDir(t, dir, pre)
+			tmp := filepath.Join(dir, "snapshots", newName+".tmp")
+			if err := os.WriteFile(tmp, newSnap[:len(newSnap)/2], 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}, 20, 10},
+		{"2 temp file synced", func(dir string) {
+			copyDir(t, dir, pre)
+			tmp := filepath.Join(dir, "snapshots", newName+".tmp")
+			if err := os.WriteFile(tmp, newSnap, 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}, 20, 10},
+		{"3 renamed", func(dir string) {
+			copyDir(t, dir, pre)
+			if err := os.WriteFile(filepath.Join(dir, "snapshots", newName), newSnap, 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}, 30, 0},
+		{"4 old snapshot deleted", func(dir string) {
+			copyDir(t, filepath.Join(dir, "wal"), filepath.Join(pre, "wal"))
+			copyDir(t, filepath.Join(dir, "snapshots"), filepath.Join(post, "snapshots"))
+		}, 30, 0},
+		{"5 log truncated", func(dir string) {
+			copyDir(t, dir, post)
+		}, 30, 0},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			dir := t.TempDir()
+			c.build(dir)
+
+			s, state := open(t, dir)
+			if !maps.Equal(state, expect(30)) {
+				t.Errorf("state = %v, want %v", state, expect(30))
+			}
+			if s.SnapshotIndex() != c.snapIndex || s.Replayed() != c.replayed || s.LastIndex() != 30 {
+				t.Errorf("snapshot %d, replayed %d, last %d; want %d, %d, 30",
+					s.SnapshotIndex(), s.Replayed(), s.LastIndex(), c.snapIndex, c.replayed)
+			}
+			for _, name := range snapshots(t, dir) {
+				if strings.HasSuffix(name, ".tmp") {
+					t.Errorf("temporary file %s left after Open", name)
+				}
+			}
+
+			// the recovered store goes on, and a snapshot puts the
+			// directory back to the retained shape
+			apply(t, s, 31, 35)
+			if err := s.Snapshot(); err != nil {
+				t.Fatal(err)
+			}
+			if got := snapshots(t, dir); len(got) != 2 || got[1] != "35.snap" {
+				t.Errorf("snapshots = %v, want two ending in 35.snap", got)
+			}
+			s.Close()
+
+			s, state = open(t, dir)
+			defer s.Close()
+			if !maps.Equal(state, expect(35)) || s.Replayed() != 0 {
+				t.Errorf("reopened: state %v, replayed %d; want %v, 0", state, s.Replayed(), expect(35))
+			}
+		})
+	}
+}
+
+// TestDamagedSnapshot flips a byte of the newest snapshot at rest: Open
+// falls back to the older one and replays the log behind it.
+func TestDamagedSnapshot(t *testing.T) {
+	for _, c := range []struct {
+		name   string
+		damage func(b []byte) []byte
+	}{
+		{"flipped bit", func(b []byte) []byte { b[0] ^= 1; return b }},
+		{"truncated", func(b []byte) []byte { return b[:len(b)-1] }},
+		{"shorter than trailer", func(b []byte) []byte { return b[:trailerSize-1] }},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			dir := t.TempDir()
+			s, _ := open(t, dir)
+			apply(t, s, 1, 10)
+			s.Snapshot()
+			apply(t, s, 11, 20)
+			s.Snapshot()
+			apply(t, s, 21, 25)
+			path := s.path(20)
+			s.Close()
+
+			b, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(path, c.damage(b), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			s, state := open(t, dir)
+			defer s.Close()
+			if !maps.Equal(state, expect(25)) {
+				t.Errorf("state = %v, want %v", state, expect(25))
+			}
+			if s.SnapshotIndex() != 10 || s.Replayed() != 15 {
+				t.Errorf("snapshot %d, replayed %d; want 10, 15", s.SnapshotIndex(), s.Replayed())
+			}
+		})
+	}
+}
+
+func TestLogBehindSnapshotMissing(t *testing.T) {
+	dir := t.TempDir()
+	s, _ := open(t, dir)
+	apply(t, s, 1, 10)
+	s.Snapshot()
+	apply(t, s, 11, 20)
+	s.Snapshot()
+	s.Close()
+
+	// with both snapshots damaged, the log no longer reaches back far
+	// enough to rebuild the state: Open must refuse, not start empty
+	for _, index := range []uint64{10, 20} {
+		if err := os.WriteFile(s.path(index), []byte("garbage in the snapshot"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := Open(dir, counters{}, opts()...); err == nil {
+		t.Error("Open without a usable snapshot or the log behind it succeeded")
+	}
+}
+
+func TestApplyRejected(t *testing.T) {
+	dir := t.TempDir()
+	s, _ := open(t, dir)
+	apply(t, s, 1, 3)
+	if _, err := s.Apply(nil); err == nil {
+		t.Fatal("Apply of a rejected record succeeded")
+	}
+	if s.LastIndex() != 3 {
+		t.Errorf("LastIndex = %d after a rejected record, want 3", s.LastIndex())
+	}
+	s.Close()
+	if _, err := s.Apply([]byte("a")); err != ErrClosed {
+		t.Errorf("Apply after Close = %v, want ErrClosed", err)
+	}
+}
+
+func TestSnapshotEvery(t *testing.T) {
+	dir := t.TempDir()
+	s, err := Open(dir, counters{}, WithSnapshotEvery(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	apply(t, s, 1, 9)
+	if s.SnapshotIndex() != 8 {
+		t.Errorf("SnapshotIndex = %d, want 8", s.SnapshotIndex())
+	}
+	s.Close()
+
+	state := counters{}
+	s, err = Open(dir, state, WithSnapshotEvery(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if !maps.Equal(state, expect(9)) || s.Replayed() != 1 {
+		t.Errorf("state %v, replayed %d; want %v, 1", state, s.Replayed(), expect(9))
+	}
+}
@@ -0,0 +1,548 @@
+// Package wal is a write-ahead log: records are appended, made durable
+// according to a sync policy, and replayed in order after a restart.
+//
+//	l, err := wal.Open(dir, wal.WithSync(wal.SyncAlways))
+//	idx, err := l.Append(payload)
+//	for rec, err := range l.Replay(l.FirstIndex()) {
+//		...
+//	}
+//	l.TruncateFront(snapshotIndex + 1) // after a snapshot covers the rest
+//
+// The log is a directory of segments, each named after the index of its
+// first record and rotated when it reaches the segment size. A record is
+// framed as a 4-byte length, a 4-byte CRC32 of the length and payload and
+// the payload, with no file header, so a segment is just frames back to
+// back. The length is checksummed so a zeroed tail, which some file
+// systems leave after a crash, does not read as empty records.
+//
+// Recovery: a crash can leave the last frame torn, and a bad disk can
+// flip bits anywhere. Open reads the last segment frame by frame and
+// truncates it at the first record that is short or fails its CRC,
+// keeping every record before it. Damage in an earlier segment cannot be
+// a torn write, so Open refuses it with a *CorruptError instead of
+// silently dropping everything after it.
+package wal
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"iter"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"patterns/clock"
+	"patterns/construct"
+	"patterns/funcopts"
+)
+
+//go:generate go run patterns/cmd/enumgen -type=SyncPolicy -trimprefix=Sync
+
+// SyncPolicy decides when appended records are fsynced.
+type SyncPolicy int
+
+const (
+	// SyncAlways fsyncs before Append returns: an acknowledged record
+	// survives power loss. The slowest.
+	SyncAlways SyncPolicy = iota
+	// SyncPeriodic fsyncs every sync interval in the background: a crash
+	// of the machine loses at most that much, a crash of the process
+	// nothing (the data is already in the page cache).
+	SyncPeriodic
+	// SyncNever leaves flushing to the OS, for logs that can be rebuilt.
+	SyncNever
+)
+
+const frameHeader = 8
+
+const segmentExt = ".wal"
+
+var (
+	ErrClosed = errors.New("wal: log is closed")
+	// ErrTooLarge is returned by Append for a payload larger than the
+	// segment size.
+	ErrTooLarge = errors.New("wal: record exceeds segment size")
+	errTorn     = errors.New("torn or corrupt record")
+)
+
+// CorruptError reports a damaged record outside the last segment.
+type CorruptError struct {
+	Segment string
+	Offset  int64
+}
+
+func (e *CorruptError) Error() string {
+	return fmt.Sprintf("wal: corrupt record in %s at offset %d", e.Segment, e.Offset)
+}
+
+// Record is one replayed entry.
+type Record struct {
+	Index uint64
+	Data  []byte
+}
+
+type options struct {
+	sync         SyncPolicy
+	syncInterval time.Duration
+	segmentSize  int64
+	clock        clock.Clock
+}
+
+type Option = funcopts.Option[options]
+
+func WithSync(p SyncPolicy) Option {
+	return func(options *options) error {
+		if _, ok := _SyncPolicyNames[p]; !ok {
+			return fmt.Errorf("invalid sync policy %d", p)
+		}
+		options.sync = p
+		return nil
+	}
+}
+
+// WithSyncInterval sets how often SyncPeriodic fsyncs; the default is
+// 100ms.
+func WithSyncInterval(d time.Duration) Option {
+	return func(options *options) error {
+		if d <= 0 {
+			return errors.New("sync interval must be positive")
+		}
+		options.syncInterval = d
+		return nil
+	}
+}
+
+// WithSegmentSize sets the size at which a segment is closed and the next
+// one started; the default is 64 MiB.
+func WithSegmentSize(n int64) Option {
+	return func(options *options) error {
+		if n <= frameHeader {
+			return errors.New("segment size too small")
+		}
+		options.segmentSize = n
+		return nil
+	}
+}
+
+func WithClock(c clock.Clock) Option {
+	return func(options *options) error {
+		if c == nil {
+			return errors.New("clock cannot be nil")
+		}
+		options.clock = c
+		return nil
+	}
+}
+
+func (o *options) SetDefaults() {
+	o.sync = SyncAlways
+	o.syncInterval = 100 * time.Millisecond
+	o.segmentSize = 64 << 20
+	o.clock = clock.Real
+}
+
+// write-ahead log pattern
+// Level: Good
+// pros: appends are sequential writes, durability is one fsync per policy
+// tick, and recovery needs no bookkeeping beyond the files themselves.
+// cons: the log grows until a snapshot lets TruncateFront drop it, and
+// replay time grows with it.
+type Log struct {
+	mu      sync.Mutex
+	dir     string
+	options options
+
+	segments []segment // oldest first; the last one is active
+	active   *os.File
+	size     int64 // of the active segment
+	next     uint64
+	dirty    bool // written since the last fsync
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// segment is one file of the log.
+type segment struct {
+	first uint64
+	path  string
+}
+
+// Open opens or creates the log in dir, recovering the last segment.
+func Open(dir string, opts ...Option) (*Log, error) {
+	options, err := construct.New(opts...)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	segments, err := listSegments(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &Log{dir: dir, options: *options, segments: segments, next: 1}
+	if len(segments) == 0 {
+		if err := l.startSegment(1); err != nil {
+			return nil, err
+		}
+	} else {
+		if err := l.recover(); err != nil {
+			return nil, err
+		}
+	}
+
+	if l.options.sync == SyncPeriodic {
+		l.stop, l.done = make(chan struct{}), make(chan struct{})
+		go l.syncLoop()
+	}
+	return l, nil
+}
+
+func listSegments(dir string) ([]segment, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var segments []segment
+	for _, e := range entries {
+		name, ok := strings.CutSuffix(e.Name(), segmentExt)
+		if !ok || e.IsDir() {
+			continue
+		}
+		first, err := strconv.ParseUint(name, 10, 64)
+		if err != nil {
+			continue
+		}
+		segments = append(segments, segment{first: first, path: filepath.Join(dir, e.Name())})
+	}
+	slices.SortFunc(segments, func(a, b segment) int { return cmp.Compare(a.first, b.first) })
+	return segments, nil
+}
+
+func segmentPath(dir string, first uint64) string {
+	return filepath.Join(dir, fmt.Sprintf("%020d%s", first, segmentExt))
+}
+
+// recover checks every segment, truncates a bad tail of the last one and
+// opens it for appending.
+func (l *Log) recover() error {
+	for i, s := range l.segments[:len(l.segments)-1] {
+		n, _, err := scan(s.path, nil)
+		if err != nil {
+			return err
+		}
+		if want := l.segments[i+1].first; s.first+n != want {
+			return fmt.Errorf("wal: segment %s holds %d records, next segment starts at %d", s.path, n, want)
+		}
+	}
+
+	last := l.segments[len(l.segments)-1]
+	n, good, err := scan(last.path, nil)
+	var corrupt *CorruptError
+	if errors.As(err, &corrupt) {
+		// the tail of the active segment is where a crash tears writes
+		err = os.Truncate(last.path, good)
+	}
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(last.path, os.O_RDWR|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	l.active, l.size, l.next = f, good, last.first+n
+	return nil
+}
+
+// scan reads the frames of a segment, calling fn (if set) with each
+// payload. It returns the number of good records and the offset after
+// the last one; a bad frame is reported as a *CorruptError at that
+// offset.
+func scan(path string, fn func(i uint64, data []byte) bool) (n uint64, good int64, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+	r := bufio.NewReader(f)
+	for {
+		data, err := readFrame(r)
+		if err == io.EOF {
+			return n, good, nil
+		}
+		if err != nil {
+			return n, good, &CorruptError{Segment: path, Offset: good}
+		}
+		if fn != nil && !fn(n, data) {
+			return n, good, nil
+		}
+		good += int64(frameHeader + len(data))
+		n++
+	}
+}
+
+func readFrame(r io.Reader) ([]byte, error) {
+	var header [frameHeader]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return nil, errTorn
+		}
+		return nil, err
+	}
+	size := binary.LittleEndian.Uint32(header[:])
+	// a corrupt length must not become a huge allocation up front
+	var buf bytes.Buffer
+	buf.Grow(int(min(size, 1<<20)))
+	if _, err := io.CopyN(&buf, r, int64(size)); err != nil {
+		return nil, errTorn
+	}
+	payload := buf.Bytes()
+	if frameCRC(header[:4], payload) != binary.LittleEndian.Uint32(header[4:]) {
+		return nil, errTorn
+	}
+	return payload, nil
+}
+
+func frameCRC(length, payload []byte) uint32 {
+	return crc32.Update(crc32.ChecksumIEEE(length), crc32.IEEETable, payload)
+}
+
+func (l *Log) startSegment(first uint64) error {
+	path := segmentPath(l.dir, first)
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	if err := syncDir(l.dir); err != nil {
+		f.Close()
+		return err
+	}
+	if len(l.segments) == 0 || l.segments[len(l.segments)-1].first != first {
+		l.segments = append(l.segments, segment{first: first, path: path})
+	}
+	l.active, l.size, l.next = f, 0, first
+	return nil
+}
+
+// syncDir makes a created or removed file name durable.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+// Append writes data as the next record and returns its index. Under
+// SyncAlways the record is on disk when Append returns.
+func (l *Log) Append(data []byte) (uint64, error) {
+	frameSize := int64(frameHeader + len(data))
+	if frameSize > l.options.segmentSize {
+		return 0, ErrTooLarge
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.active == nil {
+		return 0, ErrClosed
+	}
+	if l.size > 0 && l.size+frameSize > l.options.segmentSize {
+		if err := l.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	frame := make([]byte, frameSize)
+	binary.LittleEndian.PutUint32(frame, uint32(len(data)))
+	binary.LittleEndian.PutUint32(frame[4:], frameCRC(frame[:4], data))
+	copy(frame[frameHeader:], data)
+	if _, err := l.active.Write(frame); err != nil {
+		return 0, err
+	}
+	l.size += frameSize
+	l.dirty = true
+	if l.options.sync == SyncAlways {
+		if err := l.syncLocked(); err != nil {
+			return 0, err
+		}
+	}
+
+	idx := l.next
+	l.next++
+	return idx, nil
+}
+
+// rotate seals the active segment and starts the next one.
+func (l *Log) rotate() error {
+	if err := l.syncLocked(); err != nil {
+		return err
+	}
+	if err := l.active.Close(); err != nil {
+		return err
+	}
+	return l.startSegment(l.next)
+}
+
+// Sync fsyncs everything appended so far.
+func (l *Log) Sync() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.active == nil {
+		return ErrClosed
+	}
+	return l.syncLocked()
+}
+
+func (l *Log) syncLocked() error {
+	if !l.dirty {
+		return nil
+	}
+	if err := l.active.Sync(); err != nil {
+		return err
+	}
+	l.dirty = false
+	return nil
+}
+
+func (l *Log) syncLoop() {
+	defer close(l.done)
+	t := l.options.clock.NewTicker(l.options.syncInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-t.C():
+			// an error resurfaces on the next Sync or Close
+			l.Sync()
+		}
+	}
+}
+
+// FirstIndex is the index of the oldest record kept.
+func (l *Log) FirstIndex() uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.segments[0].first
+}
+
+// LastIndex is the index of the newest record, or FirstIndex()-1 if the
+// log is empty.
+func (l *Log) LastIndex() uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.next - 1
+}
+
+// Replay yields the records from index from on, in order, up to the last
+// one appended when Replay was called. A read failure is yielded as the
+// last pair.
+func (l *Log) Replay(from uint64) iter.Seq2[Record, error] {
+	return func(yield func(Record, error) bool) {
+		l.mu.Lock()
+		if l.active == nil {
+			l.mu.Unlock()
+			yield(Record{}, ErrClosed)
+			return
+		}
+		segments := slices.Clone(l.segments)
+		last := l.next - 1
+		l.mu.Unlock()
+
+		for i, s := range segments {
+			if i+1 < len(segments) && segments[i+1].first <= from {
+				continue
+			}
+			if s.first > last {
+				return
+			}
+			stopped := false
+			_, _, err := scan(s.path, func(n uint64, data []byte) bool {
+				idx := s.first + n
+				if idx >= from && !yield(Record{Index: idx, Data: data}, nil) {
+					stopped = true
+					return false
+				}
+				// frames past last may still be half written
+				if idx == last {
+					stopped = true
+					return false
+				}
+				return true
+			})
+			if err != nil {
+				yield(Record{}, err)
+				return
+			}
+			if stopped {
+				return
+			}
+		}
+	}
+}
+
+// TruncateFront drops the records before index, a whole segment at a
+// time, so a few older records may remain in the first one: replay from
+// the index you need, not from FirstIndex. To drop everything, pass
+// LastIndex()+1; the log then continues at that index in a fresh segment.
+func (l *Log) TruncateFront(index uint64) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.active == nil {
+		return ErrClosed
+	}
+	if index > l.next {
+		return fmt.Errorf("wal: truncate at %d beyond next index %d", index, l.next)
+	}
+	if index == l.next && l.size > 0 {
+		if err := l.rotate(); err != nil {
+			return err
+		}
+	}
+
+	keep := len(l.segments) - 1
+	for i := range l.segments[:len(l.segments)-1] {
+		if l.segments[i+1].first > index {
+			keep = i
+			break
+		}
+	}
+	for _, s := range l.segments[:keep] {
+		if err := os.Remove(s.path); err != nil {
+			return err
+		}
+	}
+	l.segments = slices.Delete(l.segments, 0, keep)
+	return syncDir(l.dir)
+}
+
+// Close fsyncs and closes the log.
+func (l *Log) Close() error {
+	l.mu.Lock()
+	if l.active == nil {
+		l.mu.Unlock()
+		return ErrClosed
+	}
+	err := errors.Join(l.syncLocked(), l.active.Close())
+	l.active = nil
+	l.mu.Unlock()
+	// the sync loop sees ErrClosed from here on
+	if l.stop != nil {
+		close(l.stop)
+		<-l.done
+	}
+	return err
+}
@@ -0,0 +1,330 @@
+package wal
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// payload is record i's data, ten bytes so a frame is 18.
+func payload(i int) []byte { return fmt.Appendf(nil, "record-%03d", i) }
+
+const frameSize = frameHeader + 10
+
+func open(t *testing.T, dir string, opts ...Option) *Log {
+	t.Helper()
+	l, err := Open(dir, opts...)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	return l
+}
+
+func appendN(t *testing.T, l *Log, from, to int) {
+	t.Helper()
+	for i := from; i <= to; i++ {
+		idx, err := l.Append(payload(i))
+		if err != nil {
+			t.Fatalf("Append %d: %v", i, err)
+		}
+		if idx != uint64(i) {
+			t.Fatalf("Append %d: index %d", i, idx)
+		}
+	}
+}
+
+// replay returns the data of every record from index from.
+func replay(t *testing.T, l *Log, from uint64) []string {
+	t.Helper()
+	var got []string
+	for rec, err := range l.Replay(from) {
+		if err != nil {
+			t.Fatalf("Replay(%d): %v", from, err)
+		}
+		if want := from + uint64(len(got)); rec.Index != want {
+			t.Fatalf("Replay(%d): index %d, want %d", from, rec.Index, want)
+		}
+		got = append(got, string(rec.Data))
+	}
+	return got
+}
+
+func want(from, to int) []string {
+	var s []string
+	for i := from; i <= to; i++ {
+		s = append(s, string(payload(i)))
+	}
+	return s
+}
+
+func segmentFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	files, err := filepath.Glob(filepath.Join(dir, "*"+segmentExt))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+func TestReplayAfterReopen(t *testing.T) {
+	dir := t.TempDir()
+	l := open(t, dir)
+	appendN(t, l, 1, 5)
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	l = open(t, dir)
+	defer l.Close()
+	if got := replay(t, l, 1); !slices.Equal(got, want(1, 5)) {
+		t.Errorf("Replay(1) = %q, want %q", got, want(1, 5))
+	}
+	if got := replay(t, l, 4); !slices.Equal(got, want(4, 5)) {
+		t.Errorf("Replay(4) = %q, want %q", got, want(4, 5))
+	}
+	appendN(t, l, 6, 6)
+	if l.FirstIndex() != 1 || l.LastIndex() != 6 {
+		t.Errorf("indexes = %d..%d, want 1..6", l.FirstIndex(), l.LastIndex())
+	}
+}
+
+func TestRotation(t *testing.T) {
+	dir := t.TempDir()
+	// two frames per segment
+	l := open(t, dir, WithSegmentSize(2*frameSize))
+	appendN(t, l, 1, 7)
+	if n := len(segmentFiles(t, dir)); n != 4 {
+		t.Errorf("%d segments, want 4", n)
+	}
+	for _, from := range []uint64{1, 2, 3, 6, 7} {
+		if got := replay(t, l, from); !slices.Equal(got, want(int(from), 7)) {
+			t.Errorf("Replay(%d) = %q, want %q", from, got, want(int(from), 7))
+		}
+	}
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	l = open(t, dir, WithSegmentSize(2*frameSize))
+	defer l.Close()
+	appendN(t, l, 8, 9)
+	if got := replay(t, l, 1); !slices.Equal(got, want(1, 9)) {
+		t.Errorf("Replay(1) after reopen = %q, want %q", got, want(1, 9))
+	}
+}
+
+func TestReplayStops(t *testing.T) {
+	l := open(t, t.TempDir(), WithSegmentSize(2*frameSize))
+	defer l.Close()
+	appendN(t, l, 1, 6)
+	var got []uint64
+	for rec, err := range l.Replay(2) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, rec.Index)
+		if rec.Index == 4 {
+			break
+		}
+	}
+	if !slices.Equal(got, []uint64{2, 3, 4}) {
+		t.Errorf("Replay(2) with break = %v, want [2 3 4]", got)
+	}
+}
+
+// TestTornTail damages the end of the last segment the ways a crash or a
+// bad sector can and checks that Open keeps the records before the
+// damage, cuts the rest and appends after them.
+func TestTornTail(t *testing.T) {
+	for _, c := range []struct {
+		name   string
+		damage func(data []byte) []byte
+		keep   int
+	}{
+		{"half header", func(b []byte) []byte { return b[:4*frameSize+frameHeader/2] }, 4},
+		{"half payload", func(b []byte) []byte { return b[:5*frameSize-3] }, 4},
+		{"flipped payload bit", func(b []byte) []byte { b[len(b)-1] ^= 1; return b }, 4},
+		{"flipped crc", func(b []byte) []byte { b[2*frameSize+4] ^= 0x80; return b }, 2},
+		{"huge length", func(b []byte) []byte { b[3*frameSize+3] = 0xff; return b }, 3},
+		{"trailing garbage", func(b []byte) []byte { return append(b, 1, 2, 3) }, 5},
+		{"zeroed tail", func(b []byte) []byte { return append(b, make([]byte, frameSize)...) }, 5},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			dir := t.TempDir()
+			l := open(t, dir)
+			appendN(t, l, 1, 5)
+			l.Close()
+
+			path := segmentPath(dir, 1)
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(path, c.damage(data), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			l = open(t, dir)
+			defer l.Close()
+			if got := replay(t, l, 1); !slices.Equal(got, want(1, c.keep)) {
+				t.Fatalf("Replay(1) = %q, want %q", got, want(1, c.keep))
+			}
+			if fi, err := os.Stat(path); err != nil || fi.Size() != int64(c.keep*frameSize) {
+				t.Errorf("segment size = %v (%v), want %d", fi.Size(), err, c.keep*frameSize)
+			}
+			appendN(t, l, c.keep+1, c.keep+1)
+			if got := replay(t, l, 1); !slices.Equal(got, want(1, c.keep+1)) {
+				t.Errorf("Replay(1) after Append = %q, want %q", got, want(1, c.keep+1))
+			}
+		})
+	}
+}
+
+func TestTornTailAfterRotation(t *testing.T) {
+	dir := t.TempDir()
+	l := open(t, dir, WithSegmentSize(2*frameSize))
+	appendN(t, l, 1, 5)
+	l.Close()
+
+	// the last segment holds record 5 alone
+	path := segmentPath(dir, 5)
+	if err := os.Truncate(path, frameSize-1); err != nil {
+		t.Fatal(err)
+	}
+	l = open(t, dir, WithSegmentSize(2*frameSize))
+	defer l.Close()
+	if got := replay(t, l, 1); !slices.Equal(got, want(1, 4)) {
+		t.Errorf("Replay(1) = %q, want %q", got, want(1, 4))
+	}
+	appendN(t, l, 5, 5)
+}
+
+// TestCorruptSealedSegment checks that damage before the last segment is
+// refused, not truncated away with every record after it.
+func TestCorruptSealedSegment(t *testing.T) {
+	for _, c := range []struct {
+		name   string
+		seg    uint64
+		damage func(path string) error
+		offset int64
+	}{
+		{"flipped bit", 3, func(p string) error {
+			b, err := os.ReadFile(p)
+			if err != nil {
+				return err
+			}
+			b[frameSize+frameHeader] ^= 1
+			return os.WriteFile(p, b, 0o644)
+		}, frameSize},
+		{"truncated", 1, func(p string) error { return os.Truncate(p, frameSize+2) }, frameSize},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			dir := t.TempDir()
+			l := open(t, dir, WithSegmentSize(2*frameSize))
+			appendN(t, l, 1, 7)
+			l.Close()
+
+			path := segmentPath(dir, c.seg)
+			if err := c.damage(path); err != nil {
+				t.Fatal(err)
+			}
+			before := segmentFiles(t, dir)
+
+			_, err := Open(dir, WithSegmentSize(2*frameSize))
+			var corrupt *CorruptError
+			if !errors.As(err, &corrupt) {
+				t.Fatalf("Open = %v, want a *CorruptError", err)
+			}
+			if corrupt.Segment != path || corrupt.Offset != c.offset {
+				t.Errorf("CorruptError = {%s %d}, want {%s %d}", corrupt.Segment, corrupt.Offset, path, c.offset)
+			}
+			if after := segmentFiles(t, dir); !slices.Equal(after, before) {
+				t.Errorf("segments after Open = %v, want %v untouched", after, before)
+			}
+		})
+	}
+}
+
+func TestMissingSegment(t *testing.T) {
+	dir := t.TempDir()
+	l := open(t, dir, WithSegmentSize(2*frameSize))
+	appendN(t, l, 1, 7)
+	l.Close()
+	if err := os.Remove(segmentPath(dir, 3)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open(dir); err == nil {
+		t.Error("Open with a missing middle segment succeeded")
+	}
+}
+
+func TestTruncateFront(t *testing.T) {
+	dir := t.TempDir()
+	l := open(t, dir, WithSegmentSize(2*frameSize))
+	defer l.Close()
+	appendN(t, l, 1, 7)
+
+	if err := l.TruncateFront(4); err != nil {
+		t.Fatal(err)
+	}
+	// record 3 shares a segment with record 4
+	if l.FirstIndex() != 3 {
+		t.Errorf("FirstIndex = %d, want 3", l.FirstIndex())
+	}
+	if got := replay(t, l, 4); !slices.Equal(got, want(4, 7)) {
+		t.Errorf("Replay(4) = %q, want %q", got, want(4, 7))
+	}
+
+	if err := l.TruncateFront(l.LastIndex() + 1); err != nil {
+		t.Fatal(err)
+	}
+	if l.FirstIndex() != 8 || l.LastIndex() != 7 {
+		t.Errorf("indexes after dropping all = %d..%d, want 8..7", l.FirstIndex(), l.LastIndex())
+	}
+	appendN(t, l, 8, 8)
+	if n := len(segmentFiles(t, dir)); n != 1 {
+		t.Errorf("%d segments, want 1", n)
+	}
+	if err := l.TruncateFront(10); err == nil {
+		t.Error("TruncateFront past the next index succeeded")
+	}
+}
+
+func TestErrors(t *testing.T) {
+	l := open(t, t.TempDir(), WithSegmentSize(2*frameSize))
+	if _, err := l.Append(make([]byte, 2*frameSize)); err != ErrTooLarge {
+		t.Errorf("Append oversized = %v, want ErrTooLarge", err)
+	}
+	l.Close()
+	if _, err := l.Append(payload(1)); err != ErrClosed {
+		t.Errorf("Append after Close = %v, want ErrClosed", err)
+	}
+	for _, err := range l.Replay(1) {
+		if err != ErrClosed {
+			t.Errorf("Replay after Close = %v, want ErrClosed", err)
+		}
+	}
+	if err := l.Close(); err != ErrClosed {
+		t.Errorf("second Close = %v, want ErrClosed", err)
+	}
+}
+
+func TestEmptyRecord(t *testing.T) {
+	dir := t.TempDir()
+	l := open(t, dir)
+	for _, data := range [][]byte{nil, payload(2), {}} {
+		if _, err := l.Append(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	l.Close()
+
+	l = open(t, dir)
+	defer l.Close()
+	if got := replay(t, l, 1); !slices.Equal(got, []string{"", "record-002", ""}) {
+		t.Errorf("Replay(1) = %q, want empty records kept", got)
+	}
+}
@@ -0,0 +1,61 @@
+package windows
+
+import "cmp"
+
+// Agg folds the values of a window into an A. Start makes the aggregate
+// of a window's first value, Add adds another, and Merge joins the
+// aggregates of two session windows that an event bridged.
+type Agg[V, A any] struct {
+	Start func(v V) A
+	Add   func(a A, v V) A
+	Merge func(a, b A) A
+}
+
+// Number is what Sum adds.
+type Number interface {
+	~int | ~int64 | ~float64
+}
+
+// Count counts the values; Result.Count has the same number, so Count
+// is for when nothing else is wanted.
+func Count[V any]() Agg[V, int] {
+	return Agg[V, int]{
+		Start: func(V) int { return 1 },
+		Add:   func(a int, _ V) int { return a + 1 },
+		Merge: func(a, b int) int { return a + b },
+	}
+}
+
+func Sum[V Number]() Agg[V, V] {
+	return Agg[V, V]{
+		Start: func(v V) V { return v },
+		Add:   func(a, v V) V { return a + v },
+		Merge: func(a, b V) V { return a + b },
+	}
+}
+
+func Max[V cmp.Ordered]() Agg[V, V] {
+	larger := func(a, b V) V { return max(a, b) }
+	return Agg[V, V]{Start: func(v V) V { return v }, Add: larger, Merge: larger}
+}
+
+func Min[V cmp.Ordered]() Agg[V, V] {
+	smaller := func(a, b V) V { return min(a, b) }
+	return Agg[V, V]{Start: func(v V) V { return v }, Add: smaller, Merge: smaller}
+}
+
+// MeanAcc is the running state of Mean.
+type MeanAcc struct {
+	Sum float64
+	N   int
+}
+
+func (m MeanAcc) Mean() float64 { return m.Sum / float64(m.N) }
+
+func Mean() Agg[float64, MeanAcc] {
+	return Agg[float64, MeanAcc]{
+		Start: func(v float64) MeanAcc { return MeanAcc{v, 1} },
+		Add:   func(a MeanAcc, v float64) MeanAcc { return MeanAcc{a.Sum + v, a.N + 1} },
+		Merge: func(a, b MeanAcc) MeanAcc { return MeanAcc{a.Sum + b.Sum, a.N + b.N} },
+	}
+}
@@ -0,0 +1,155 @@
+package windows_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"patterns/clock"
+	"patterns/streaming/windows"
+)
+
+var epoch = time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+// at returns the event time d after epoch.
+func at(d time.Duration) time.Time { return epoch.Add(d) }
+
+func ev(key string, d time.Duration, v int) windows.Event[int] {
+	return windows.Event[int]{Key: key, Time: at(d), Value: v}
+}
+
+// show writes results relative to epoch: "a [0s,1m0s) n=2 =3".
+func show[A any](rs []windows.Result[A]) string {
+	parts := make([]string, len(rs))
+	for i, r := range rs {
+		parts[i] = fmt.Sprintf("%s [%v,%v) n=%d =%v", r.Key, r.Window.Start.Sub(epoch), r.Window.End.Sub(epoch), r.Count, r.Value)
+	}
+	return strings.Join(parts, "; ")
+}
+
+// step pushes or ticks and checks what fired.
+func step[A any](t *testing.T, what string, fired []windows.Result[A], want string) {
+	t.Helper()
+	got := show(fired)
+	t.Logf("%-28s -> %s", what, got)
+	if got != want {
+		t.Fatalf("%s fired %q, want %q", what, got, want)
+	}
+}
+
+var cases = []struct {
+	name string
+	test func(t *testing.T)
+}{
+	{"tumbling: start inclusive, end exclusive", func(t *testing.T) {
+		o := windows.New(windows.Tumbling(time.Minute), windows.Sum[int](), windows.Config[int]{})
+		step(t, "a@0s", o.Push(ev("a", 0, 1)), "")
+		step(t, "a@59.999s", o.Push(ev("a", time.Minute-time.Millisecond, 2)), "")
+		step(t, "a@60s", o.Push(ev("a", time.Minute, 4)), "a [0s,1m0s) n=2 =3")
+		step(t, "b@61s", o.Push(ev("b", 61*time.Second, 1)), "")
+		step(t, "flush", o.Flush(), "a [1m0s,2m0s) n=1 =4; b [1m0s,2m0s) n=1 =1")
+	}},
+	{"sliding: an event in every window it overlaps", func(t *testing.T) {
+		o := windows.New(windows.Sliding(time.Minute, 30*time.Second), windows.Count[int](), windows.Config[int]{})
+		step(t, "a@45s", o.Push(ev("a", 45*time.Second, 0)), "")
+		step(t, "a@89.999s", o.Push(ev("a", 90*time.Second-time.Millisecond, 0)), "a [0s,1m0s) n=1 =1")
+		step(t, "a@90s", o.Push(ev("a", 90*time.Second, 0)), "a [30s,1m30s) n=2 =2")
+		step(t, "flush", o.Flush(), "a [1m0s,2m0s) n=2 =2; a [1m30s,2m30s) n=1 =1")
+	}},
+	{"session: an event between two merges them", func(t *testing.T) {
+		o := windows.New(windows.Session(30*time.Second), windows.Sum[int](), windows.Config[int]{Lateness: time.Minute})
+		step(t, "a@0s", o.Push(ev("a", 0, 1)), "")
+		step(t, "a@40s", o.Push(ev("a", 40*time.Second, 2)), "")
+		step(t, "b@50s", o.Push(ev("b", 50*time.Second, 8)), "")
+		step(t, "a@25s bridges", o.Push(ev("a", 25*time.Second, 4)), "")
+		step(t, "a@200s", o.Push(ev("a", 200*time.Second, 16)), "a [0s,1m10s) n=3 =7; b [50s,1m20s) n=1 =8")
+		step(t, "flush", o.Flush(), "a [3m20s,3m50s) n=1 =16")
+	}},
+	{"session: a gap of exactly gap opens a new session", func(t *testing.T) {
+		o := windows.New(windows.Session(30*time.Second), windows.Count[int](), windows.Config[int]{})
+		step(t, "a@0s", o.Push(ev("a", 0, 0)), "")
+		step(t, "a@29s", o.Push(ev("a", 29*time.Second, 0)), "")
+		step(t, "a@59s", o.Push(ev("a", 59*time.Second, 0)), "a [0s,59s) n=2 =2")
+		step(t, "a@89s", o.Push(ev("a", 89*time.Second, 0)), "a [59s,1m29s) n=1 =1")
+	}},
+	{"lateness: counted until the watermark passes", func(t *testing.T) {
+		var late []string
+		o := windows.New(windows.Tumbling(time.Minute), windows.Sum[int](), windows.Config[int]{
+			Lateness: 10 * time.Second,
+			OnLate:   func(e windows.Event[int]) { late = append(late, e.Time.Sub(epoch).String()) },
+		})
+		step(t, "a@65s", o.Push(ev("a", 65*time.Second, 1)), "")
+		step(t, "a@55s, late but allowed", o.Push(ev("a", 55*time.Second, 2)), "")
+		step(t, "a@70s", o.Push(ev("a", 70*time.Second, 4)), "a [0s,1m0s) n=1 =2")
+		if got := o.Watermark().Sub(epoch); got != time.Minute {
+			t.Fatalf("watermark = %v, want 1m0s", got)
+		}
+		step(t, "a@59s, too late", o.Push(ev("a", 59*time.Second, 8)), "")
+		step(t, "a@60s", o.Push(ev("a", 60*time.Second, 16)), "")
+		if got := strings.Join(late, ","); got != "59s" {
+			t.Fatalf("late events %q, want 59s", got)
+		}
+		step(t, "flush", o.Flush(), "a [1m0s,2m0s) n=3 =21")
+	}},
+	{"idle: the clock moves a quiet stream on", func(t *testing.T) {
+		c := clock.NewFake(epoch)
+		o := windows.New(windows.Tumbling(time.Minute), windows.Count[int](), windows.Config[int]{Idle: 5 * time.Second, Clock: c})
+		step(t, "a@10s", o.Push(ev("a", 10*time.Second, 0)), "")
+		c.Advance(4 * time.Second)
+		step(t, "tick 4s later, not idle", o.Tick(c.Now()), "")
+		c.Advance(45 * time.Second)
+		step(t, "tick 49s later", o.Tick(c.Now()), "")
+		c.Advance(time.Second)
+		step(t, "tick 50s later", o.Tick(c.Now()), "a [0s,1m0s) n=1 =1")
+	}},
+	{"idle: a session closes without another event", func(t *testing.T) {
+		c := clock.NewFake(epoch)
+		o := windows.New(windows.Session(30*time.Second), windows.Count[int](), windows.Config[int]{Idle: 10 * time.Second, Clock: c})
+		step(t, "a@0s", o.Push(ev("a", 0, 0)), "")
+		c.Advance(29 * time.Second)
+		step(t, "tick 29s later", o.Tick(c.Now()), "")
+		c.Advance(time.Second)
+		step(t, "tick 30s later", o.Tick(c.Now()), "a [0s,30s) n=1 =1")
+	}},
+	{"run: events from a channel, ticks from the fake clock", func(t *testing.T) {
+		c := clock.NewFake(epoch)
+		o := windows.New(windows.Tumbling(time.Minute), windows.Sum[int](), windows.Config[int]{Idle: 5 * time.Second, Clock: c})
+		ctx, cancel := context.WithCancel(context.Background())
+		t.Cleanup(cancel)
+		in := make(chan windows.Event[int])
+		out := windows.Run(ctx, o, in, time.Second)
+		in <- ev("a", 10*time.Second, 3)
+		in <- ev("a", 70*time.Second, 5)
+		step(t, "a@70s", []windows.Result[int]{<-out}, "a [0s,1m0s) n=1 =3")
+		// receiving a@70s does not mean Run has read the clock for it,
+		// so advance a tick at a time until the idle watermark fires
+		var got windows.Result[int]
+		deadline := time.After(5 * time.Second)
+	wait:
+		for {
+			c.Advance(time.Second)
+			select {
+			case got = <-out:
+				break wait
+			case <-time.After(time.Millisecond):
+			case <-deadline:
+				t.Fatalf("no window fired after idling")
+			}
+		}
+		step(t, "idle ticks", []windows.Result[int]{got}, "a [1m0s,2m0s) n=1 =5")
+		close(in)
+		if r, ok := <-out; ok {
+			t.Fatalf("Run sent %s after the stream closed, want nothing", show([]windows.Result[int]{r}))
+		}
+	}},
+}
+
+// TestBoundaries pushes events one at a time through operators and checks,
+// after each, which windows fired; -v logs every step.
+func TestBoundaries(t *testing.T) {
+	for _, c := range cases {
+		t.Run(c.name, c.test)
+	}
+}
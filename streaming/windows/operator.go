@@ -0,0 +1,200 @@
+package windows
+
+import (
+	"cmp"
+	"slices"
+	"time"
+
+	"patterns/clock"
+)
+
+// Config tunes an Operator.
+type Config[V any] struct {
+	// Lateness is how far behind the latest event another may arrive
+	// and still be counted.
+	Lateness time.Duration
+	// Idle, if positive, is how long without events before the
+	// watermark follows the clock.
+	Idle time.Duration
+	// Clock is processing time, for Idle; nil means the real one.
+	Clock clock.Clock
+	// OnLate, if set, receives the events too late to count.
+	OnLate func(e Event[V])
+}
+
+// Operator assigns events to windows, aggregates them per key and fires
+// each window when the watermark passes its end. It is not safe for
+// concurrent use; Run owns one from a single goroutine.
+type Operator[V, A any] struct {
+	windows Windows
+	agg     Agg[V, A]
+	cfg     Config[V]
+	clock   clock.Clock
+
+	panes     map[string][]*pane[A]
+	seen      bool      // whether any event has arrived
+	latest    time.Time // the latest event time seen
+	arrived   time.Time // when, in processing time, the last event arrived
+	watermark time.Time
+}
+
+type pane[A any] struct {
+	w   Window
+	acc A
+	n   int
+}
+
+func New[V, A any](w Windows, agg Agg[V, A], cfg Config[V]) *Operator[V, A] {
+	return &Operator[V, A]{
+		windows: w,
+		agg:     agg,
+		cfg:     cfg,
+		clock:   clock.Or(cfg.Clock),
+		panes:   map[string][]*pane[A]{},
+	}
+}
+
+// Watermark returns the event time up to which windows have fired; the
+// zero time before the first event.
+func (o *Operator[V, A]) Watermark() time.Time { return o.watermark }
+
+// Push adds e to its windows and returns those that fired because e
+// moved the watermark, by end, then start, then key.
+func (o *Operator[V, A]) Push(e Event[V]) []Result[A] {
+	o.arrived = o.clock.Now()
+	var accepted bool
+	if o.windows.session() {
+		accepted = o.addSession(e)
+	} else {
+		for _, w := range o.windows.assign(e.Time) {
+			if !o.fired(w) {
+				o.addFixed(e.Key, w, e.Value)
+				accepted = true
+			}
+		}
+	}
+	if !accepted && o.cfg.OnLate != nil {
+		o.cfg.OnLate(e)
+	}
+	if !o.seen || e.Time.After(o.latest) {
+		o.seen, o.latest = true, e.Time
+	}
+	return o.advance(o.latest.Add(-o.cfg.Lateness))
+}
+
+// Tick moves the watermark on after Idle without events, as if event
+// time had gone on passing with processing time since the last event,
+// and returns the windows that fired. now is processing time.
+func (o *Operator[V, A]) Tick(now time.Time) []Result[A] {
+	idle := now.Sub(o.arrived)
+	if o.cfg.Idle <= 0 || !o.seen || idle < o.cfg.Idle {
+		return nil
+	}
+	return o.advance(o.latest.Add(idle - o.cfg.Lateness))
+}
+
+// Flush fires every open window, for the end of the stream.
+func (o *Operator[V, A]) Flush() []Result[A] {
+	var out []Result[A]
+	for key, ps := range o.panes {
+		for _, p := range ps {
+			out = append(out, Result[A]{key, p.w, p.acc, p.n})
+		}
+	}
+	clear(o.panes)
+	return sortResults(out)
+}
+
+// fired reports whether w ended at or before the watermark.
+func (o *Operator[V, A]) fired(w Window) bool {
+	return o.seen && !w.End.After(o.watermark)
+}
+
+func (o *Operator[V, A]) addFixed(key string, w Window, v V) {
+	for _, p := range o.panes[key] {
+		if p.w == w {
+			p.acc = o.agg.Add(p.acc, v)
+			p.n++
+			return
+		}
+	}
+	o.panes[key] = append(o.panes[key], &pane[A]{w, o.agg.Start(v), 1})
+}
+
+// addSession opens a window of gap at e, merging it with every open
+// window it overlaps, and reports whether e was counted: it is late if it
+// overlaps no open window and its own has already ended.
+func (o *Operator[V, A]) addSession(e Event[V]) bool {
+	p := &pane[A]{Window{e.Time, e.Time.Add(o.windows.gap)}, o.agg.Start(e.Value), 1}
+	ps := o.panes[e.Key]
+	merged := false
+	for i := 0; i < len(ps); {
+		q := ps[i]
+		if !(p.w.Start.Before(q.w.End) && q.w.Start.Before(p.w.End)) {
+			i++
+			continue
+		}
+		if q.w.Start.Before(p.w.Start) {
+			p.acc = o.agg.Merge(q.acc, p.acc)
+		} else {
+			p.acc = o.agg.Merge(p.acc, q.acc)
+		}
+		p.w = Window{minTime(p.w.Start, q.w.Start), maxTime(p.w.End, q.w.End)}
+		p.n += q.n
+		ps = slices.Delete(ps, i, i+1)
+		merged = true
+		i = 0 // the grown window may now reach one passed over
+	}
+	if !merged && o.fired(p.w) {
+		return false
+	}
+	o.panes[e.Key] = append(ps, p)
+	return true
+}
+
+// advance moves the watermark to wm, if that is later, and fires the
+// windows it passes.
+func (o *Operator[V, A]) advance(wm time.Time) []Result[A] {
+	if !wm.After(o.watermark) {
+		return nil
+	}
+	o.watermark = wm
+	var out []Result[A]
+	for key, ps := range o.panes {
+		open := ps[:0]
+		for _, p := range ps {
+			if o.fired(p.w) {
+				out = append(out, Result[A]{key, p.w, p.acc, p.n})
+			} else {
+				open = append(open, p)
+			}
+		}
+		if len(open) == 0 {
+			delete(o.panes, key)
+		} else {
+			o.panes[key] = open
+		}
+	}
+	return sortResults(out)
+}
+
+func sortResults[A any](rs []Result[A]) []Result[A] {
+	slices.SortFunc(rs, func(a, b Result[A]) int {
+		return cmp.Or(a.Window.End.Compare(b.Window.End), a.Window.Start.Compare(b.Window.Start), cmp.Compare(a.Key, b.Key))
+	})
+	return rs
+}
+
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
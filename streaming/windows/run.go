@@ -0,0 +1,49 @@
+package windows
+
+import (
+	"context"
+	"time"
+)
+
+// Run pushes the events of in through o from a goroutine, calls Tick
+// every tick of o's clock, and sends what fires on the returned channel.
+// When in is closed it sends what Flush fires and closes the channel;
+// when ctx is done it closes the channel without flushing. A result not
+// received holds up the events behind it.
+func Run[V, A any](ctx context.Context, o *Operator[V, A], in <-chan Event[V], tick time.Duration) <-chan Result[A] {
+	out := make(chan Result[A])
+	t := o.clock.NewTicker(tick)
+	go func() {
+		defer close(out)
+		defer t.Stop()
+		send := func(rs []Result[A]) bool {
+			for _, r := range rs {
+				select {
+				case out <- r:
+				case <-ctx.Done():
+					return false
+				}
+			}
+			return true
+		}
+		for {
+			var fired []Result[A]
+			select {
+			case e, ok := <-in:
+				if !ok {
+					send(o.Flush())
+					return
+				}
+				fired = o.Push(e)
+			case now := <-t.C():
+				fired = o.Tick(now)
+			case <-ctx.Done():
+				return
+			}
+			if !send(fired) {
+				return
+			}
+		}
+	}()
+	return out
+}
@@ -0,0 +1,123 @@
+// Package windows groups a stream of timestamped events into windows of
+// event time and aggregates each window per key:
+//
+//   - Tumbling(size): fixed, back to back, each event in exactly one
+//   - Sliding(size, every): fixed and overlapping, each event in
+//     size/every of them
+//   - Session(gap): per key, a run of events with no gap longer than gap
+//     between them; a window grows with each event and two merge when an
+//     event falls between them
+//
+// Windows are half open, [Start, End): an event at exactly End belongs
+// to the next one.
+//
+// Events arrive out of order, so a window cannot fire when the first
+// event past its end arrives. The Operator's watermark says how far event
+// time is known to be complete: the latest event time seen, less the
+// Lateness allowed. A window fires once its End is at or before the
+// watermark, and an event that would only land in windows that already
+// fired is late: it is handed to OnLate and not counted. Lateness trades
+// latency for completeness, a window ending Lateness after it could.
+//
+// A stream that goes quiet holds the watermark, and with it the last
+// windows, forever. After Idle without events, the Operator moves the
+// watermark on with its clock, assuming event time passes as processing
+// time does; clock.Fake makes that exact. Run drives an Operator from a
+// channel, with a ticker for the idle check; the tests check each kind
+// of window at its edges, event by event.
+package windows
+
+import (
+	"fmt"
+	"time"
+)
+
+// Event is one value for Key that happened at Time.
+type Event[V any] struct {
+	Key   string
+	Time  time.Time
+	Value V
+}
+
+// Window is the span [Start, End) of event time.
+type Window struct {
+	Start, End time.Time
+}
+
+func (w Window) String() string {
+	return fmt.Sprintf("[%s, %s)", w.Start.Format("15:04:05.000"), w.End.Format("15:04:05.000"))
+}
+
+func (w Window) contains(t time.Time) bool {
+	return !t.Before(w.Start) && t.Before(w.End)
+}
+
+// Result is the aggregate of the Count events of Key in Window.
+type Result[A any] struct {
+	Key    string
+	Window Window
+	Value  A
+	Count  int
+}
+
+// Windows is how events are assigned to windows: Tumbling, Sliding or
+// Session.
+type Windows struct {
+	size, every, gap time.Duration
+}
+
+// tumbling window
+// Level: Good
+// pros: one window per event, so one aggregate to update; boundaries are
+// fixed in advance and the same for every key.
+// cons: an event just past a boundary is counted apart from one just
+// before it, however close.
+//
+// Tumbling returns back-to-back windows of size, aligned to multiples of
+// size since the zero time.
+func Tumbling(size time.Duration) Windows {
+	return Sliding(size, size)
+}
+
+// sliding window
+// Level: Good
+// pros: a fresh view of the last size every time every passes, smoothing
+// the boundary effect of tumbling windows.
+// cons: each event updates size/every aggregates and is emitted in each.
+//
+// Sliding returns windows of size starting every every; size must be a
+// multiple of every.
+func Sliding(size, every time.Duration) Windows {
+	if size <= 0 || every <= 0 || size%every != 0 {
+		panic("windows: size must be a positive multiple of every")
+	}
+	return Windows{size: size, every: every}
+}
+
+// session window
+// Level: Good
+// pros: windows follow activity, one per burst per key, with no
+// boundaries to choose but the gap.
+// cons: a window's end is only known once the gap has passed with no
+// event, and an out-of-order event can merge two windows into one, so
+// aggregates must be mergeable.
+//
+// Session returns per-key windows that close after gap without events.
+func Session(gap time.Duration) Windows {
+	if gap <= 0 {
+		panic("windows: gap must be positive")
+	}
+	return Windows{gap: gap}
+}
+
+func (w Windows) session() bool { return w.gap > 0 }
+
+// assign returns the fixed windows containing t, earliest first.
+func (w Windows) assign(t time.Time) []Window {
+	last := t.Truncate(w.every)
+	var ws []Window
+	for start := last.Add(w.every - w.size); !start.After(last); start = start.Add(w.every) {
+		ws = append(ws, Window{start, start.Add(w.size)})
+	}
+	return ws
+}
@@ -0,0 +1,261 @@
+package adapter_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"patterns/structural/adapter"
+	"patterns/structural/adapter/legacylog"
+)
+
+type entry struct {
+	level  legacylog.Level
+	msg    string
+	fields legacylog.Fields
+}
+
+// recorder is a legacylog.Sink that keeps what it is given.
+type recorder struct{ entries []entry }
+
+func (r *recorder) Log(level legacylog.Level, msg string, fields legacylog.Fields) {
+	r.entries = append(r.entries, entry{level, msg, fields})
+}
+
+// token resolves to its redacted form, as a LogValuer.
+type token string
+
+func (token) LogValue() slog.Value { return slog.StringValue("***") }
+
+func TestHandler(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		log  func(l *slog.Logger)
+		want entry
+	}{
+		{"plain", func(l *slog.Logger) { l.Info("hello", "a", 1) },
+			entry{legacylog.Info, "hello", legacylog.Fields{"a": int64(1)}}},
+		{"with attrs", func(l *slog.Logger) { l.With("svc", "api").Warn("slow", "ms", 900) },
+			entry{legacylog.Warn, "slow", legacylog.Fields{"svc": "api", "ms": int64(900)}}},
+		// attrs added before a group stay outside it
+		{"groups", func(l *slog.Logger) {
+			l.With("a", 1).WithGroup("req").With("id", "x").WithGroup("").WithGroup("hdr").Error("bad", "n", 2)
+		}, entry{legacylog.Error, "bad", legacylog.Fields{"a": int64(1), "req.id": "x", "req.hdr.n": int64(2)}}},
+		// empty attrs go, groups without a key are inlined, valuers resolve
+		{"attr rules", func(l *slog.Logger) {
+			l.Info("m", slog.Attr{}, slog.Group("", "in", true), slog.Group("g", "k", "v"), slog.Group("empty"), "tok", token("secret"))
+		}, entry{legacylog.Info, "m", legacylog.Fields{"in": true, "g.k": "v", "tok": "***"}}},
+		{"debug", func(l *slog.Logger) { l.Log(context.Background(), slog.LevelDebug, "d") },
+			entry{legacylog.Debug, "d", legacylog.Fields{}}},
+		// levels between slog's round down
+		{"info+2", func(l *slog.Logger) { l.Log(context.Background(), slog.LevelInfo+2, "i") },
+			entry{legacylog.Info, "i", legacylog.Fields{}}},
+		{"error+4", func(l *slog.Logger) { l.Log(context.Background(), slog.LevelError+4, "e") },
+			entry{legacylog.Error, "e", legacylog.Fields{}}},
+	} {
+		var r recorder
+		c.log(slog.New(adapter.NewHandler(&r, slog.LevelDebug)))
+		if len(r.entries) != 1 {
+			t.Errorf("%s: logged %v, want one entry", c.name, r.entries)
+			continue
+		}
+		if got := r.entries[0]; got.level != c.want.level || got.msg != c.want.msg || !maps.Equal(got.fields, c.want.fields) {
+			t.Errorf("%s: logged %v, want %v", c.name, got, c.want)
+		}
+	}
+}
+
+// TestHandlerLevel checks the level filter and that handlers derived
+// with WithAttrs do not share their fields.
+func TestHandlerLevel(t *testing.T) {
+	var r recorder
+	h := adapter.NewHandler(&r, nil)
+	if h.Enabled(context.Background(), slog.LevelDebug) || !h.Enabled(context.Background(), slog.LevelInfo) {
+		t.Error("nil level is not slog.LevelInfo")
+	}
+	var level slog.LevelVar
+	level.Set(slog.LevelError)
+	l := slog.New(adapter.NewHandler(&r, &level))
+	l.Warn("dropped")
+	level.Set(slog.LevelWarn)
+	l.Warn("kept")
+	if len(r.entries) != 1 || r.entries[0].msg != "kept" {
+		t.Errorf("logged %v, want the level to be read on every record", r.entries)
+	}
+
+	base := l.With("a", 1)
+	base.With("b", 2).Warn("one")
+	base.With("c", 3).Warn("two")
+	if f := r.entries[2].fields; !maps.Equal(f, legacylog.Fields{"a": int64(1), "c": int64(3)}) {
+		t.Errorf("sibling handler fields %v", f)
+	}
+}
+
+// TestHandlerToLogger logs through slog into the legacy logger.
+func TestHandlerToLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(adapter.NewHandler(legacylog.New(&buf, false), slog.LevelInfo))
+	logger.WithGroup("db").Warn("slow query", "ms", 250, "table", "orders")
+	logger.Debug("hidden")
+	if want := "WARN  slow query db.ms=250 db.table=orders\n"; buf.String() != want {
+		t.Errorf("wrote %q, want %q", &buf, want)
+	}
+}
+
+// TestSink logs through legacylog into slog.
+func TestSink(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+		ReplaceAttr: func(_ []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+	sink := adapter.Sink(logger)
+	sink.Log(legacylog.Warn, "disk", legacylog.Fields{"used": 91, "mount": "/", "dev": "sda"})
+	sink.Log(legacylog.Debug, "d", nil)
+	sink.Log(legacylog.Error, "e", nil)
+	sink.Log(legacylog.Info, "i", nil)
+	sink.Log(legacylog.Level(9), "unknown", nil)
+	want := `level=WARN msg=disk dev=sda mount=/ used=91
+level=DEBUG msg=d
+level=ERROR msg=e
+level=INFO msg=i
+level=INFO msg=unknown
+`
+	if buf.String() != want {
+		t.Errorf("wrote:\n%s\nwant:\n%s", &buf, want)
+	}
+}
+
+// TestRoundTrip checks that legacy code logging through slog back into
+// legacylog loses nothing but the width of its ints, which slog keeps as
+// int64.
+func TestRoundTrip(t *testing.T) {
+	var r recorder
+	sink := adapter.Sink(slog.New(adapter.NewHandler(&r, slog.LevelDebug)))
+	for _, level := range []legacylog.Level{legacylog.Debug, legacylog.Info, legacylog.Warn, legacylog.Error} {
+		sink.Log(level, "m", legacylog.Fields{"user": "ann", "n": 3})
+		got := r.entries[len(r.entries)-1]
+		if got.level != level || got.msg != "m" || !maps.Equal(got.fields, legacylog.Fields{"user": "ann", "n": int64(3)}) {
+			t.Errorf("%s: came back as %v", level, got)
+		}
+	}
+}
+
+// collect returns the lines of Lines(r) and the error it ended with.
+func collect(r io.Reader) ([]string, error) {
+	var lines []string
+	for line, err := range adapter.Lines(r) {
+		if err != nil {
+			return lines, err
+		}
+		lines = append(lines, line)
+	}
+	return lines, nil
+}
+
+func TestLines(t *testing.T) {
+	for _, c := range []struct {
+		in   string
+		want []string
+	}{
+		{"", nil},
+		{"a", []string{"a"}},
+		{"a\n", []string{"a"}},
+		{"a\r\nb\n", []string{"a", "b"}},
+		{"a\n\nb", []string{"a", "", "b"}},
+		{"\n", []string{""}},
+	} {
+		got, err := collect(iotest.OneByteReader(strings.NewReader(c.in)))
+		if err != nil || !slices.Equal(got, c.want) {
+			t.Errorf("Lines(%q) = %q, %v; want %q", c.in, got, err, c.want)
+		}
+	}
+}
+
+func TestLinesErrors(t *testing.T) {
+	boom := errors.New("boom")
+	got, err := collect(io.MultiReader(strings.NewReader("a\nb\n"), iotest.ErrReader(boom)))
+	if !slices.Equal(got, []string{"a", "b"}) || !errors.Is(err, boom) {
+		t.Errorf("read error: %q, %v", got, err)
+	}
+	long := strings.Repeat("x", 70_000)
+	got, err = collect(strings.NewReader("a\n" + long + "\nb\n"))
+	if !slices.Equal(got, []string{"a"}) || err == nil {
+		t.Errorf("long line: %d lines, %v", len(got), err)
+	}
+}
+
+// counting counts the reads made of it.
+type counting struct {
+	r     io.Reader
+	reads int
+}
+
+func (c *counting) Read(p []byte) (int, error) {
+	c.reads++
+	return c.r.Read(p)
+}
+
+// TestLinesBreak checks that breaking out of the loop stops reading.
+func TestLinesBreak(t *testing.T) {
+	r := &counting{r: iotest.OneByteReader(strings.NewReader("a\nb\nc\n"))}
+	for line := range adapter.Lines(r) {
+		if line == "a" {
+			break
+		}
+	}
+	if r.reads != 2 {
+		t.Errorf("%d reads, want 2: one line's worth", r.reads)
+	}
+}
+
+func TestReader(t *testing.T) {
+	lines := []string{"first", "", "third line"}
+	if err := iotest.TestReader(adapter.Reader(slices.Values(lines)), []byte("first\n\nthird line\n")); err != nil {
+		t.Error(err)
+	}
+	back, err := collect(adapter.Reader(slices.Values(lines)))
+	if err != nil || !slices.Equal(back, lines) {
+		t.Errorf("Lines(Reader(%q)) = %q, %v", lines, back, err)
+	}
+}
+
+// TestReaderClose checks that Close ends the sequence early, running its
+// cleanup, and that reads after it report EOF.
+func TestReaderClose(t *testing.T) {
+	stopped := false
+	r := adapter.Reader(func(yield func(string) bool) {
+		defer func() { stopped = true }()
+		for i := 0; ; i++ {
+			if !yield(strings.Repeat("x", i)) {
+				return
+			}
+		}
+	})
+	p := make([]byte, 4)
+	if n, err := r.Read(p); n != 1 || err != nil || p[0] != '\n' {
+		t.Errorf("first Read = %d %q, %v", n, p[:n], err)
+	}
+	r.Close()
+	if !stopped {
+		t.Error("Close did not stop the sequence")
+	}
+	if n, err := r.Read(p); n != 0 || err != io.EOF {
+		t.Errorf("Read after Close = %d, %v", n, err)
+	}
+	if err := r.Close(); err != nil {
+		t.Errorf("second Close = %v", err)
+	}
+}
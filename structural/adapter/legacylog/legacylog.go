@@ -0,0 +1,73 @@
+// Package legacylog stands in for a third-party logging library with an
+// API of its own: levels as constants, fields as a map, and a Sink
+// interface its callers are written against. structural/adapter joins it
+// to log/slog in both directions.
+package legacylog
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Level int
+
+const (
+	Debug Level = iota
+	Info
+	Warn
+	Error
+)
+
+func (l Level) String() string {
+	switch l {
+	case Debug:
+		return "DEBUG"
+	case Info:
+		return "INFO"
+	case Warn:
+		return "WARN"
+	case Error:
+		return "ERROR"
+	}
+	return fmt.Sprintf("LEVEL(%d)", int(l))
+}
+
+type Fields map[string]any
+
+// Sink is what code using the library logs to.
+type Sink interface {
+	Log(level Level, msg string, fields Fields)
+}
+
+// Logger writes one line per entry: time, level, message, then the
+// fields sorted by name.
+type Logger struct {
+	mu       sync.Mutex
+	out      io.Writer
+	withTime bool
+}
+
+// New returns a logger writing to out; withTime false leaves the time
+// out, for reproducible output.
+func New(out io.Writer, withTime bool) *Logger { return &Logger{out: out, withTime: withTime} }
+
+func (l *Logger) Log(level Level, msg string, fields Fields) {
+	var b strings.Builder
+	if l.withTime {
+		b.WriteString(time.Now().Format(time.RFC3339))
+		b.WriteByte(' ')
+	}
+	fmt.Fprintf(&b, "%-5s %s", level, msg)
+	for _, k := range slices.Sorted(maps.Keys(fields)) {
+		fmt.Fprintf(&b, " %s=%v", k, fields[k])
+	}
+	b.WriteByte('\n')
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	io.WriteString(l.out, b.String())
+}
@@ -0,0 +1,62 @@
+package adapter
+
+import (
+	"bufio"
+	"io"
+	"iter"
+)
+
+// Lines adapts r to a sequence of its lines, without their line endings
+// ("\n" or "\r\n"). A read error, or a line longer than
+// bufio.MaxScanTokenSize, ends the sequence with one ("", err) pair.
+func Lines(r io.Reader) iter.Seq2[string, error] {
+	return func(yield func(string, error) bool) {
+		sc := bufio.NewScanner(r)
+		for sc.Scan() {
+			if !yield(sc.Text(), nil) {
+				return
+			}
+		}
+		if err := sc.Err(); err != nil {
+			yield("", err)
+		}
+	}
+}
+
+// Reader adapts a sequence of lines to an io.Reader producing each line
+// followed by "\n". Close stops the sequence early; it is not needed once
+// Read returned io.EOF.
+func Reader(lines iter.Seq[string]) io.ReadCloser {
+	next, stop := iter.Pull(lines)
+	return &lineReader{next: next, stop: stop}
+}
+
+type lineReader struct {
+	next func() (string, bool)
+	stop func()
+	buf  []byte // the rest of the current line
+	done bool
+}
+
+func (r *lineReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+		line, ok := r.next()
+		if !ok {
+			r.Close()
+			return 0, io.EOF
+		}
+		r.buf = append(append(r.buf[:0], line...), '\n')
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+func (r *lineReader) Close() error {
+	r.done = true
+	r.stop()
+	return nil
+}
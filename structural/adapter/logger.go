@@ -0,0 +1,144 @@
+// Package adapter makes a type usable where an interface it does not
+// implement is expected, by wrapping it in a small type that translates
+// the calls:
+//
+//   - Handler adapts legacylog, a stand-in for a third-party logger, to
+//     slog.Handler, so code logging with slog writes through it; Sink goes
+//     the other way, for code written against legacylog
+//   - Lines adapts an io.Reader to an iterator over its lines; Reader
+//     turns such an iterator back into an io.Reader
+//
+// For example:
+//
+//	logger := slog.New(adapter.NewHandler(legacylog.New(os.Stderr, true), slog.LevelInfo))
+//	for line, err := range adapter.Lines(file) { ... }
+//
+// An adapter owns the mismatch, such as levels, field groups or line
+// framing, in one place, and neither side changes.
+package adapter
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"slices"
+
+	"patterns/structural/adapter/legacylog"
+)
+
+// adapter
+// Level: Good
+// pros: the library and the code calling it stay as they are; the
+// translation is written, and tested, once.
+// cons: whatever one side cannot express is lost in translation: legacylog
+// has no groups, so they become dotted field names, and stamps its own
+// time instead of the record's.
+type Handler struct {
+	sink   legacylog.Sink
+	level  slog.Leveler
+	fields legacylog.Fields // from WithAttrs, already prefixed
+	prefix string           // the open groups, as "a.b."
+}
+
+// NewHandler returns a slog.Handler logging to sink the records at level
+// or above (nil means slog.LevelInfo).
+func NewHandler(sink legacylog.Sink, level slog.Leveler) *Handler {
+	if level == nil {
+		level = slog.LevelInfo
+	}
+	return &Handler{sink: sink, level: level}
+}
+
+func (h *Handler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+func (h *Handler) Handle(_ context.Context, r slog.Record) error {
+	fields := make(legacylog.Fields, len(h.fields)+r.NumAttrs())
+	maps.Copy(fields, h.fields)
+	r.Attrs(func(a slog.Attr) bool {
+		addField(fields, h.prefix, a)
+		return true
+	})
+	h.sink.Log(toLegacy(r.Level), r.Message, fields)
+	return nil
+}
+
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	c := *h
+	c.fields = maps.Clone(h.fields)
+	if c.fields == nil {
+		c.fields = legacylog.Fields{}
+	}
+	for _, a := range attrs {
+		addField(c.fields, h.prefix, a)
+	}
+	return &c
+}
+
+func (h *Handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	c := *h
+	c.prefix += name + "."
+	return &c
+}
+
+// addField flattens a into fields, following the slog.Handler rules:
+// empty attributes are dropped and groups without a key are inlined.
+func addField(fields legacylog.Fields, prefix string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+	if a.Value.Kind() != slog.KindGroup {
+		fields[prefix+a.Key] = a.Value.Any()
+		return
+	}
+	if a.Key != "" {
+		prefix += a.Key + "."
+	}
+	for _, g := range a.Value.Group() {
+		addField(fields, prefix, g)
+	}
+}
+
+func toLegacy(l slog.Level) legacylog.Level {
+	switch {
+	case l < slog.LevelInfo:
+		return legacylog.Debug
+	case l < slog.LevelWarn:
+		return legacylog.Info
+	case l < slog.LevelError:
+		return legacylog.Warn
+	}
+	return legacylog.Error
+}
+
+// Sink adapts l to legacylog.Sink, for code written against legacylog
+// that should log through slog. Fields become attributes in name order.
+func Sink(l *slog.Logger) legacylog.Sink { return sink{l} }
+
+type sink struct{ l *slog.Logger }
+
+func (s sink) Log(level legacylog.Level, msg string, fields legacylog.Fields) {
+	attrs := make([]slog.Attr, 0, len(fields))
+	for _, k := range slices.Sorted(maps.Keys(fields)) {
+		attrs = append(attrs, slog.Any(k, fields[k]))
+	}
+	s.l.LogAttrs(context.Background(), fromLegacy(level), msg, attrs...)
+}
+
+func fromLegacy(l legacylog.Level) slog.Level {
+	//exhaustive:ignore
+	switch l {
+	case legacylog.Debug:
+		return slog.LevelDebug
+	case legacylog.Warn:
+		return slog.LevelWarn
+	case legacylog.Error:
+		return slog.LevelError
+	}
+	return slog.LevelInfo
+}
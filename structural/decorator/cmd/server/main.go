@@ -0,0 +1,58 @@
+// Command server runs options/functional with a handler decorated by
+// logging, panic recovery and bearer-token authentication.
+//
+// usage:
+//
+//	go run patterns/structural/decorator/cmd/server [-port 8080]
+//
+//	curl -H 'Authorization: Bearer secret' localhost:8080/hello
+//	curl -H 'Authorization: Bearer secret' localhost:8080/panic
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+
+	"patterns/options/functional"
+	"patterns/structural/decorator"
+)
+
+func main() {
+	port := flag.Int("port", 8080, "port to listen on")
+	flag.Parse()
+
+	logger := slog.Default()
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /hello", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "hello, %s\n", decorator.UserKey.MustGet(r.Context()))
+	})
+	mux.HandleFunc("GET /panic", func(http.ResponseWriter, *http.Request) {
+		panic("handler bug")
+	})
+	tokens := map[string]string{"secret": "gopher"}
+
+	s, err := functional.NewServer("localhost",
+		functional.WithPort(*port),
+		functional.WithSlogLogger(logger),
+		functional.WithHandler(decorator.Chained(mux, logger, tokens)),
+	)
+	if err != nil {
+		logger.Error("create server", "err", err)
+		os.Exit(1)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	go func() {
+		<-s.Ready()
+		logger.Info("listening", "addr", s.Addr())
+	}()
+	if err := s.Run(ctx); err != nil {
+		logger.Error("run server", "err", err)
+	}
+}
@@ -0,0 +1,72 @@
+package flyweight
+
+import (
+	"runtime"
+	"testing"
+)
+
+var sinkLabels *Labels
+
+// BenchmarkBuild measures building SeriesCount series with each variant,
+// reporting the heap they keep per series.
+func BenchmarkBuild(b *testing.B) {
+	sets := LabelSets()
+	for _, v := range Variants {
+		b.Run(v.Name, func(b *testing.B) {
+			var kept uint64
+			for range b.N {
+				b.StopTimer()
+				before := LiveHeap()
+				newLabels := v.New()
+				b.StartTimer()
+				series := Build(newLabels, sets, SeriesCount)
+				b.StopTimer()
+				kept += LiveHeap() - before
+				runtime.KeepAlive(series)
+				b.StartTimer()
+			}
+			b.ReportMetric(float64(kept)/float64(b.N*SeriesCount), "heap-B/series")
+		})
+	}
+}
+
+// BenchmarkIntern measures a Table hit, serially and in parallel.
+func BenchmarkIntern(b *testing.B) {
+	get := LabelSets()[7]
+	b.Run("hit", func(b *testing.B) {
+		var t Table
+		t.Intern(get...)
+		b.ResetTimer()
+		for range b.N {
+			sinkLabels = t.Intern(get...)
+		}
+	})
+	b.Run("hit/parallel", func(b *testing.B) {
+		var t Table
+		t.Intern(get...)
+		b.ResetTimer()
+		b.RunParallel(func(pb *testing.PB) {
+			var l *Labels
+			for pb.Next() {
+				l = t.Intern(get...)
+			}
+			_ = l
+		})
+	})
+}
+
+// BenchmarkNewLabels measures one label set made with NewLabels.
+func BenchmarkNewLabels(b *testing.B) {
+	get := LabelSets()[7]
+	for range b.N {
+		sinkLabels = NewLabels(get...)
+	}
+}
+
+// BenchmarkInternStrings measures one label set made with InternStrings.
+func BenchmarkInternStrings(b *testing.B) {
+	get := LabelSets()[7]
+	for range b.N {
+		sinkLabels = InternStrings(get...)
+	}
+}
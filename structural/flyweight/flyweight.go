@@ -0,0 +1,127 @@
+// Package flyweight shares immutable values instead of copying them: the
+// label sets of metric series, where thousands of series repeat a few
+// dozen sets such as {method="GET",route="/users",status="200"}.
+//
+//   - NewLabels: every series builds its own Labels (Level: Poor for long-
+//     lived duplicates; fine for values that die young)
+//   - InternStrings: every string is interned with the standard unique
+//     package, so the bytes are shared but each series still has its own
+//     slice (Level: Average)
+//   - Table: whole label sets are interned in a Table, so series with the
+//     same labels hold the same *Labels (Level: Good)
+//
+// Sharing is only safe because Labels cannot change after it is made:
+// the flyweight's state is intrinsic, and what differs per series, its
+// value, stays in the series.
+//
+// findings (see bench_test.go; go test -bench . -benchmem
+// patterns/structural/flyweight), on a single-CPU machine, for 10,000
+// series over 60 label sets:
+//
+//   - a series keeps ~218 bytes with NewLabels, ~169 with InternStrings
+//     and ~17 with Table: its own 16 bytes and its share of 60 sets.
+//     InternStrings only saves the bytes of the strings; the 128-byte
+//     slice of pairs of each series stays.
+//   - a Table hit costs ~255ns and 0 allocations, against ~340ns and 2
+//     for NewLabels: the key is built on the stack and the map lookup
+//     does not copy it. InternStrings is the slowest, ~840ns, one
+//     unique.Make per name and value.
+//   - so building the 10,000 series, each from fresh strings, takes
+//     ~3.5-5ms with NewLabels, ~5.5-8ms with Table and ~7.5-13ms with
+//     InternStrings: Table pays for its key, but the heap it leaves
+//     behind is 12x smaller, and each collection scans that much less.
+//
+// The tests report the same from testing.AllocsPerRun and the live heap,
+// and check that a Table hit allocates nothing:
+//
+//	go test -v -run 'Allocates|LiveHeap' patterns/structural/flyweight
+package flyweight
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"unique"
+)
+
+// Label is one name and value.
+type Label struct {
+	Name, Value string
+}
+
+// Labels is an immutable set of labels sorted by name; compare two by
+// pointer only if both came from the same Table.
+type Labels struct {
+	pairs []Label
+}
+
+// NewLabels returns the labels of the name and value pairs in kv; a
+// name given twice keeps its last value, and an odd kv drops the last.
+func NewLabels(kv ...string) *Labels {
+	pairs := make([]Label, 0, len(kv)/2)
+	for i := 0; i+1 < len(kv); i += 2 {
+		pairs = append(pairs, Label{kv[i], kv[i+1]})
+	}
+	return &Labels{pairs: normalize(pairs)}
+}
+
+// normalize sorts pairs by name, keeping the last of each name.
+func normalize(pairs []Label) []Label {
+	slices.SortStableFunc(pairs, func(a, b Label) int { return strings.Compare(a.Name, b.Name) })
+	out := pairs[:0]
+	for i, p := range pairs {
+		if i+1 < len(pairs) && pairs[i+1].Name == p.Name {
+			continue
+		}
+		out = append(out, p)
+	}
+	return slices.Clip(out)
+}
+
+// Len returns the number of labels.
+func (l *Labels) Len() int { return len(l.pairs) }
+
+// Get returns the value of name, "" if absent.
+func (l *Labels) Get(name string) string {
+	i, ok := slices.BinarySearchFunc(l.pairs, name, func(p Label, name string) int { return strings.Compare(p.Name, name) })
+	if !ok {
+		return ""
+	}
+	return l.pairs[i].Value
+}
+
+// All returns a copy of the labels in name order.
+func (l *Labels) All() []Label { return slices.Clone(l.pairs) }
+
+// String returns the canonical form, {a="1",b="2"}, equal for equal sets.
+func (l *Labels) String() string { return string(appendKey(nil, l.pairs)) }
+
+// appendKey appends the canonical form of sorted pairs to b.
+func appendKey(b []byte, pairs []Label) []byte {
+	b = append(b, '{')
+	for i, p := range pairs {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		b = append(b, p.Name...)
+		b = append(b, '=')
+		b = strconv.AppendQuote(b, p.Value)
+	}
+	return append(b, '}')
+}
+
+// interned strings
+// Level: Average
+// pros: the standard library's intern table: safe for concurrent use, and
+// entries no longer referenced are collected.
+// cons: the strings are shared but the slice of pairs is not, and each
+// string is looked up on its own.
+//
+// InternStrings is NewLabels with every name and value interned.
+func InternStrings(kv ...string) *Labels {
+	l := NewLabels(kv...)
+	for i, p := range l.pairs {
+		l.pairs[i] = Label{unique.Make(p.Name).Value(), unique.Make(p.Value).Value()}
+	}
+	return l
+}
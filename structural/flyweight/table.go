@@ -0,0 +1,60 @@
+package flyweight
+
+import "sync"
+
+// intern table
+// Level: Good
+// pros: one *Labels per distinct set, shared by every series that has
+// it, so a series costs a pointer; a hit allocates nothing, and equal
+// sets from one Table compare equal by pointer.
+// cons: the table never forgets a set, so labels of unbounded
+// cardinality, a user ID or a request path, grow it without limit;
+// bound them before interning.
+//
+// Table interns label sets; it is safe for concurrent use. The zero value
+// is an empty table.
+type Table struct {
+	mu   sync.RWMutex
+	sets map[string]*Labels
+}
+
+// Intern returns the shared Labels of the name and value pairs in kv, as
+// NewLabels would build them, making it on the first request.
+func (t *Table) Intern(kv ...string) *Labels {
+	// the pairs and the key are built on the stack, so a hit allocates
+	// nothing: the map lookup converts the key without copying it
+	var pairBuf [8]Label
+	pairs := pairBuf[:0]
+	for i := 0; i+1 < len(kv); i += 2 {
+		pairs = append(pairs, Label{kv[i], kv[i+1]})
+	}
+	pairs = normalize(pairs)
+	var keyBuf [256]byte
+	key := appendKey(keyBuf[:0], pairs)
+
+	t.mu.RLock()
+	l, ok := t.sets[string(key)]
+	t.mu.RUnlock()
+	if ok {
+		return l
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if l, ok := t.sets[string(key)]; ok {
+		return l
+	}
+	if t.sets == nil {
+		t.sets = map[string]*Labels{}
+	}
+	l = &Labels{pairs: append([]Label(nil), pairs...)}
+	t.sets[string(key)] = l
+	return l
+}
+
+// Len returns the number of distinct sets interned.
+func (t *Table) Len() int {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return len(t.sets)
+}
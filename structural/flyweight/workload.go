@@ -0,0 +1,65 @@
+package flyweight
+
+import (
+	"runtime"
+	"strings"
+)
+
+// Series is one metric series: the shared labels and its own value.
+type Series struct {
+	Labels *Labels
+	Value  float64
+}
+
+// LabelSets returns the 60 label sets of the workload, as name and value
+// pairs: 3 methods, 5 routes, 4 statuses.
+func LabelSets() [][]string {
+	var sets [][]string
+	for _, m := range []string{"GET", "POST", "DELETE"} {
+		for _, r := range []string{"/users", "/users/{id}", "/orders", "/orders/{id}", "/health"} {
+			for _, s := range []string{"200", "404", "500", "503"} {
+				sets = append(sets, []string{"method", m, "route", r, "status", s, "service", "api"})
+			}
+		}
+	}
+	return sets
+}
+
+// Build makes n series over sets in turn, with labels from newLabels.
+// Each series gets fresh copies of its strings, as if decoded from a
+// request, so only a variant that interns keeps them shared.
+func Build(newLabels func(kv ...string) *Labels, sets [][]string, n int) []Series {
+	series := make([]Series, n)
+	kv := make([]string, 0, 16)
+	for i := range series {
+		kv = kv[:0]
+		for _, s := range sets[i%len(sets)] {
+			kv = append(kv, strings.Clone(s))
+		}
+		series[i] = Series{Labels: newLabels(kv...), Value: float64(i)}
+	}
+	return series
+}
+
+// Variants are the ways to make a series' labels, by name; each call of
+// New returns a fresh one, with its own Table.
+var Variants = []struct {
+	Name string
+	New  func() func(kv ...string) *Labels
+}{
+	{"copies", func() func(kv ...string) *Labels { return NewLabels }},
+	{"strings", func() func(kv ...string) *Labels { return InternStrings }},
+	{"table", func() func(kv ...string) *Labels { return new(Table).Intern }},
+}
+
+// SeriesCount is the number of series the build benchmarks make.
+const SeriesCount = 10_000
+
+// LiveHeap is the heap in use after two collections.
+func LiveHeap() uint64 {
+	runtime.GC()
+	runtime.GC()
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	return m.HeapAlloc
+}
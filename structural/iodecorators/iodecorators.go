@@ -0,0 +1,193 @@
+// Package iodecorators wraps io.Reader and io.Writer to add behaviour
+// without changing the interface, so decorators stack in any order over
+// any stream:
+//
+//	var counted iodecorators.Counter
+//	r := iodecorators.Count(
+//		iodecorators.Progress(
+//			iodecorators.RateLimit(file, bucket, 32<<10),
+//			size, 1<<20, report),
+//		&counted)
+//	io.Copy(dst, r)
+//
+// Each decorator forwards Read or Write to the wrapped value and only
+// observes or shapes the bytes; none of them buffer.
+package iodecorators
+
+import (
+	"io"
+	"sync/atomic"
+	"time"
+
+	"patterns/resilience/ratelimit"
+)
+
+// Counter accumulates bytes passed through Count. Safe for concurrent
+// use, so another goroutine can read a transfer in progress.
+type Counter struct {
+	n atomic.Int64
+}
+
+func (c *Counter) N() int64 { return c.n.Load() }
+
+type countReader struct {
+	r io.Reader
+	c *Counter
+}
+
+// Count returns a Reader adding every byte read from r to c.
+func Count(r io.Reader, c *Counter) io.Reader { return &countReader{r: r, c: c} }
+
+func (r *countReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.c.n.Add(int64(n))
+	return n, err
+}
+
+type countWriter struct {
+	w io.Writer
+	c *Counter
+}
+
+// CountWriter returns a Writer adding every byte written to w to c. Only
+// bytes w accepted are counted, so a short write is reported truthfully.
+func CountWriter(w io.Writer, c *Counter) io.Writer { return &countWriter{w: w, c: c} }
+
+func (w *countWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.c.n.Add(int64(n))
+	return n, err
+}
+
+type limitReader struct {
+	r     io.Reader
+	b     *ratelimit.TokenBucket
+	chunk int
+}
+
+// RateLimit returns a Reader that takes one token from b per byte,
+// reading at most chunk bytes per Read so the rate stays smooth instead
+// of arriving in bursts of len(p).
+func RateLimit(r io.Reader, b *ratelimit.TokenBucket, chunk int) io.Reader {
+	return &limitReader{r: r, b: b, chunk: max(chunk, 1)}
+}
+
+func (r *limitReader) Read(p []byte) (int, error) {
+	if len(p) > r.chunk {
+		p = p[:r.chunk]
+	}
+	n, err := r.r.Read(p)
+	if n > 0 {
+		time.Sleep(r.b.Reserve(n))
+	}
+	return n, err
+}
+
+type limitWriter struct {
+	w     io.Writer
+	b     *ratelimit.TokenBucket
+	chunk int
+}
+
+// RateLimitWriter returns a Writer that waits for one token per byte
+// before writing, in pieces of at most chunk bytes.
+func RateLimitWriter(w io.Writer, b *ratelimit.TokenBucket, chunk int) io.Writer {
+	return &limitWriter{w: w, b: b, chunk: max(chunk, 1)}
+}
+
+func (w *limitWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		piece := p[:min(len(p), w.chunk)]
+		time.Sleep(w.b.Reserve(len(piece)))
+		n, err := w.w.Write(piece)
+		written += n
+		if err != nil {
+			return written, err
+		}
+		p = p[n:]
+	}
+	return written, nil
+}
+
+// ProgressFunc receives the bytes done so far and the expected total
+// (-1 if unknown).
+type ProgressFunc func(done, total int64)
+
+type progress struct {
+	total, every     int64
+	done, next, last int64
+	report           ProgressFunc
+}
+
+func (p *progress) add(n int, final bool) {
+	p.done += int64(n)
+	if p.done >= p.next || (final && p.done != p.last) {
+		p.report(p.done, p.total)
+		p.last = p.done
+		for p.next <= p.done {
+			p.next += p.every
+		}
+	}
+}
+
+type progressReader struct {
+	r io.Reader
+	progress
+}
+
+// Progress returns a Reader calling report each time another every bytes
+// have been read, and once more at EOF.
+func Progress(r io.Reader, total, every int64, report ProgressFunc) io.Reader {
+	every = max(every, 1)
+	return &progressReader{r: r, progress: progress{total: total, every: every, next: every, last: -1, report: report}}
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.add(n, err == io.EOF)
+	return n, err
+}
+
+type progressWriter struct {
+	w io.Writer
+	progress
+}
+
+// ProgressWriter is Progress for writes; there is no EOF, so the last
+// report is the last multiple of every reached.
+func ProgressWriter(w io.Writer, total, every int64, report ProgressFunc) io.Writer {
+	every = max(every, 1)
+	return &progressWriter{w: w, progress: progress{total: total, every: every, next: every, last: -1, report: report}}
+}
+
+func (w *progressWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.add(n, false)
+	return n, err
+}
+
+// TeeReader is io.TeeReader for a side channel that must not break the
+// main stream: bytes read from R are copied to Side, but a Side error is
+// recorded in Err and stops the copying rather than failing the Read.
+type TeeReader struct {
+	R    io.Reader
+	Side io.Writer
+	Err  error
+}
+
+// Tee returns a TeeReader over r copying to side, e.g. a checksum or a
+// debug log that is allowed to fail.
+func Tee(r io.Reader, side io.Writer) *TeeReader { return &TeeReader{R: r, Side: side} }
+
+func (t *TeeReader) Read(p []byte) (int, error) {
+	n, err := t.R.Read(p)
+	if n > 0 && t.Err == nil {
+		if wn, werr := t.Side.Write(p[:n]); werr != nil {
+			t.Err = werr
+		} else if wn < n {
+			t.Err = io.ErrShortWrite
+		}
+	}
+	return n, err
+}
@@ -0,0 +1,318 @@
+package iodecorators_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"patterns/resilience/ratelimit"
+	"patterns/structural/iodecorators"
+)
+
+func payload(n int) []byte {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = byte('a' + i%26)
+	}
+	return b
+}
+
+// shortWriter accepts at most limit bytes in all, then fails.
+type shortWriter struct {
+	buf   bytes.Buffer
+	limit int
+}
+
+func (w *shortWriter) Write(p []byte) (int, error) {
+	n := min(len(p), w.limit-w.buf.Len())
+	w.buf.Write(p[:n])
+	if n < len(p) {
+		return n, errors.New("disk full")
+	}
+	return n, nil
+}
+
+// sizes records the length of each call and forwards it.
+type sizes struct {
+	r     io.Reader
+	w     io.Writer
+	calls []int
+}
+
+func (s *sizes) Read(p []byte) (int, error) {
+	s.calls = append(s.calls, len(p))
+	return s.r.Read(p)
+}
+
+func (s *sizes) Write(p []byte) (int, error) {
+	s.calls = append(s.calls, len(p))
+	return s.w.Write(p)
+}
+
+func TestCount(t *testing.T) {
+	data := payload(10000)
+	for _, c := range []struct {
+		name string
+		wrap func(io.Reader) io.Reader
+	}{
+		{"plain", func(r io.Reader) io.Reader { return r }},
+		{"one byte", iotest.OneByteReader},
+		{"half", iotest.HalfReader},
+		{"data with EOF", iotest.DataErrReader},
+	} {
+		var counted iodecorators.Counter
+		got, err := io.ReadAll(iodecorators.Count(c.wrap(bytes.NewReader(data)), &counted))
+		if err != nil || !bytes.Equal(got, data) || counted.N() != int64(len(data)) {
+			t.Errorf("%s: read %d bytes, %v; counted %d", c.name, len(got), err, counted.N())
+		}
+	}
+
+	// a failed read counts the bytes it did return
+	var counted iodecorators.Counter
+	r := iodecorators.Count(io.MultiReader(bytes.NewReader(data[:100]), iotest.ErrReader(io.ErrUnexpectedEOF)), &counted)
+	if _, err := io.ReadAll(r); err != io.ErrUnexpectedEOF || counted.N() != 100 {
+		t.Errorf("failing reader: %v, counted %d", err, counted.N())
+	}
+}
+
+// TestCountWriter checks that only the bytes the writer took are
+// counted.
+func TestCountWriter(t *testing.T) {
+	var counted iodecorators.Counter
+	sw := &shortWriter{limit: 150}
+	w := iodecorators.CountWriter(sw, &counted)
+	for range 2 {
+		w.Write(payload(100))
+	}
+	if counted.N() != 150 || sw.buf.Len() != 150 {
+		t.Errorf("counted %d, written %d; want 150", counted.N(), sw.buf.Len())
+	}
+}
+
+// TestCounterConcurrent reads a Counter while it is being added to from
+// several transfers; run with -race.
+func TestCounterConcurrent(t *testing.T) {
+	var counted iodecorators.Counter
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			io.Copy(io.Discard, iodecorators.Count(iotest.OneByteReader(bytes.NewReader(payload(1000))), &counted))
+		}()
+	}
+	last := int64(0)
+	for n := counted.N(); n < 4000; n = counted.N() {
+		if n < last {
+			t.Fatalf("counter went back from %d to %d", last, n)
+		}
+		last = n
+	}
+	wg.Wait()
+	if counted.N() != 4000 {
+		t.Errorf("counted %d, want 4000", counted.N())
+	}
+}
+
+const (
+	rate  = 20000 // bytes per second
+	burst = 2000
+	total = 10000
+	chunk = 500
+	// the first burst is free; the rest waits for the bucket
+	minTime = time.Duration(total-burst) * time.Second / rate
+)
+
+func bucket(t *testing.T) *ratelimit.TokenBucket {
+	t.Helper()
+	b, err := ratelimit.NewTokenBucket(rate, burst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func checkDuration(t *testing.T, name string, d time.Duration) {
+	t.Helper()
+	// a little slack for the bucket's float arithmetic, a lot for a slow
+	// machine
+	if d < minTime*9/10 || d > minTime+3*time.Second {
+		t.Errorf("%s: took %v, want about %v", name, d, minTime)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	data := payload(total)
+	src := &sizes{r: bytes.NewReader(data)}
+	start := time.Now()
+	got, err := io.ReadAll(iodecorators.RateLimit(src, bucket(t), chunk))
+	checkDuration(t, "RateLimit", time.Since(start))
+	if err != nil || !bytes.Equal(got, data) {
+		t.Fatalf("read %d bytes, %v", len(got), err)
+	}
+	if m := slices.Max(src.calls); m > chunk {
+		t.Errorf("a read asked for %d bytes, more than the chunk %d", m, chunk)
+	}
+}
+
+func TestRateLimitWriter(t *testing.T) {
+	data := payload(total)
+	var buf bytes.Buffer
+	dst := &sizes{w: &buf}
+	start := time.Now()
+	n, err := iodecorators.RateLimitWriter(dst, bucket(t), chunk).Write(data)
+	checkDuration(t, "RateLimitWriter", time.Since(start))
+	if n != total || err != nil || !bytes.Equal(buf.Bytes(), data) {
+		t.Fatalf("wrote %d bytes, %v", n, err)
+	}
+	if len(dst.calls) != total/chunk || slices.Max(dst.calls) != chunk {
+		t.Errorf("wrote in pieces of %v, want %d of %d", dst.calls, total/chunk, chunk)
+	}
+
+	// a failing write stops, reporting what got through
+	sw := &shortWriter{limit: 1200}
+	n, err = iodecorators.RateLimitWriter(sw, bucket(t), chunk).Write(payload(burst))
+	if n != 1200 || err == nil {
+		t.Errorf("failing writer: wrote %d, %v; want 1200 and the error", n, err)
+	}
+}
+
+type report struct{ done, total int64 }
+
+func TestProgress(t *testing.T) {
+	for _, c := range []struct {
+		name  string
+		size  int
+		read  int // bytes per Read
+		every int64
+		total int64
+		want  []report
+	}{
+		{"steps", 2500, 300, 1000, 2500, []report{{1200, 2500}, {2100, 2500}, {2500, 2500}}},
+		// one read past several marks reports once
+		{"one read", 2500, 4096, 1000, -1, []report{{2500, -1}}},
+		{"exact marks", 2000, 500, 1000, 2000, []report{{1000, 2000}, {2000, 2000}}},
+		{"empty", 0, 10, 1000, 0, []report{{0, 0}}},
+		{"every byte", 3, 1, 0, 3, []report{{1, 3}, {2, 3}, {3, 3}}},
+	} {
+		var got []report
+		r := iodecorators.Progress(readsOf(payload(c.size), c.read), c.total, c.every, func(done, total int64) {
+			got = append(got, report{done, total})
+		})
+		if n, err := io.Copy(io.Discard, r); err != nil || n != int64(c.size) {
+			t.Fatalf("%s: copied %d, %v", c.name, n, err)
+		}
+		if !slices.Equal(got, c.want) {
+			t.Errorf("%s: reports %v, want %v", c.name, got, c.want)
+		}
+	}
+}
+
+// readsOf returns a reader of data returning at most n bytes per Read,
+// and the last bytes together with io.EOF.
+func readsOf(data []byte, n int) io.Reader { return &limitedReads{r: bytes.NewReader(data), n: n} }
+
+type limitedReads struct {
+	r *bytes.Reader
+	n int
+}
+
+func (l *limitedReads) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p[:min(len(p), l.n)])
+	if err == nil && l.r.Len() == 0 {
+		err = io.EOF
+	}
+	return n, err
+}
+
+func TestProgressWriter(t *testing.T) {
+	var got []report
+	sw := &shortWriter{limit: 2700}
+	w := iodecorators.ProgressWriter(sw, 3000, 1000, func(done, total int64) {
+		got = append(got, report{done, total})
+	})
+	for range 10 {
+		w.Write(payload(300))
+	}
+	// the short write is counted for what was taken; there is no final
+	// report without an EOF
+	if want := []report{{1200, 3000}, {2100, 3000}}; !slices.Equal(got, want) {
+		t.Errorf("reports %v, want %v", got, want)
+	}
+}
+
+func TestTee(t *testing.T) {
+	data := payload(5000)
+	var side bytes.Buffer
+	tee := iodecorators.Tee(iotest.HalfReader(bytes.NewReader(data)), &side)
+	got, err := io.ReadAll(tee)
+	if err != nil || !bytes.Equal(got, data) || !bytes.Equal(side.Bytes(), data) || tee.Err != nil {
+		t.Errorf("read %d, side %d, %v, side error %v", len(got), side.Len(), err, tee.Err)
+	}
+}
+
+// TestTeeSideFails checks that a failing side channel stops being
+// written to, and the main stream goes on whole.
+func TestTeeSideFails(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		side io.Writer
+		want string
+	}{
+		{"error", &shortWriter{limit: 1000}, "disk full"},
+		{"short write", shortNoError{}, io.ErrShortWrite.Error()},
+	} {
+		data := payload(5000)
+		tee := iodecorators.Tee(readsOf(data, 700), c.side)
+		got, err := io.ReadAll(tee)
+		if err != nil || !bytes.Equal(got, data) {
+			t.Errorf("%s: main stream read %d, %v", c.name, len(got), err)
+		}
+		if tee.Err == nil || tee.Err.Error() != c.want {
+			t.Errorf("%s: side error = %v, want %q", c.name, tee.Err, c.want)
+		}
+	}
+	sw := &shortWriter{limit: 1000}
+	io.ReadAll(iodecorators.Tee(readsOf(payload(5000), 700), sw))
+	if sw.buf.Len() != 1000 {
+		t.Errorf("side got %d bytes, want the 1000 before it failed", sw.buf.Len())
+	}
+}
+
+// shortNoError breaks the io.Writer contract: it takes half and says
+// nothing.
+type shortNoError struct{}
+
+func (shortNoError) Write(p []byte) (int, error) { return len(p) / 2, nil }
+
+// TestStack composes the decorators as the package comment does, and
+// checks that each layer sees the same bytes.
+func TestStack(t *testing.T) {
+	data := payload(total)
+	var counted iodecorators.Counter
+	var sum bytes.Buffer
+	var last report
+	r := iodecorators.Count(
+		iodecorators.Progress(
+			iodecorators.Tee(iodecorators.RateLimit(bytes.NewReader(data), bucket(t), chunk), &sum),
+			total, 4096, func(done, total int64) { last = report{done, total} }),
+		&counted)
+	var out strings.Builder
+	start := time.Now()
+	if _, err := io.Copy(&out, r); err != nil {
+		t.Fatal(err)
+	}
+	checkDuration(t, "stack", time.Since(start))
+	if out.String() != string(data) || sum.String() != string(data) {
+		t.Errorf("copied %d bytes, teed %d; want %d", out.Len(), sum.Len(), total)
+	}
+	if counted.N() != total || last != (report{total, total}) {
+		t.Errorf("counted %d, last progress %v", counted.N(), last)
+	}
+}
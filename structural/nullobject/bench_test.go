@@ -0,0 +1,55 @@
+package nullobject
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+var benchRecords = func() []string {
+	rs := make([]string, 1000)
+	for i := range rs {
+		rs[i] = "key" + strconv.Itoa(i) + "=value"
+		if i%100 == 0 {
+			rs[i] = "malformed"
+		}
+	}
+	return rs
+}()
+
+// importBare is Import without logging or metrics: the baseline.
+func importBare(records []string) map[string]string {
+	out := make(map[string]string, len(records))
+	for _, r := range records {
+		k, v, ok := strings.Cut(r, "=")
+		if !ok || k == "" {
+			continue
+		}
+		out[k] = v
+	}
+	return out
+}
+
+var benchSink map[string]string
+
+// BenchmarkImport compares an importer with its logging and metrics off by
+// nil checks and by null objects.
+func BenchmarkImport(b *testing.B) {
+	b.Run("bare", func(b *testing.B) {
+		for range b.N {
+			benchSink = importBare(benchRecords)
+		}
+	})
+	b.Run("nilcheck", func(b *testing.B) {
+		im := &CheckedImporter{}
+		for range b.N {
+			benchSink = im.Import(benchRecords)
+		}
+	})
+	b.Run("nop", func(b *testing.B) {
+		im, _ := NewImporter()
+		for range b.N {
+			benchSink = im.Import(benchRecords)
+		}
+	})
+}
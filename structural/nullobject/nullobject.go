@@ -0,0 +1,137 @@
+// Package nullobject replaces "if x != nil" around optional
+// collaborators with implementations that do nothing: NopLogger and
+// NopMetrics stand in when an Importer is given no logger or metrics, so
+// its code calls them unconditionally.
+//
+// nilcheck.go has the same importer with nil checks instead, and
+// trap.go the way optional collaborators go wrong in Go: a nil pointer
+// stored in an interface is not a nil interface, so "if log != nil", and
+// WithLogger's own check, let it through, and the first call panics.
+//
+// findings (see bench_test.go; go test -bench . -benchmem
+// patterns/structural/nullobject), importing 1,000 records with logging
+// and metrics off:
+//
+//   - nil checks are within noise of an import with no logging at all,
+//     ~300µs: the branches are always predicted.
+//   - null objects make the same import ~3x slower. The calls are cheap;
+//     their arguments are not: Log's ...any boxes each line number and
+//     key, and the dynamic call makes them escape, 2.75 heap allocations
+//     per record for a logger that discards them.
+//   - so a null object is free where the collaborator is used rarely or
+//     with constant arguments, and on a hot path the answer is a nil
+//     check, or a logger asked whether it is enabled before the arguments
+//     are built.
+package nullobject
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"patterns/construct"
+	"patterns/funcopts"
+)
+
+// Logger records events.
+type Logger interface {
+	Log(msg string, kv ...any)
+}
+
+// Metrics records measurements.
+type Metrics interface {
+	Count(name string, n int64)
+}
+
+// null object
+// Level: Good
+// pros: code using the collaborator has no branches, and none to forget;
+// the zero configuration is valid.
+// cons: calls and their arguments are still paid for when nothing is
+// recorded; a null object hides a missing dependency that should have
+// been an error.
+//
+// NopLogger discards everything.
+type NopLogger struct{}
+
+func (NopLogger) Log(string, ...any) {}
+
+// NopMetrics discards everything.
+type NopMetrics struct{}
+
+func (NopMetrics) Count(string, int64) {}
+
+type options struct {
+	log     Logger
+	metrics Metrics
+}
+
+type Option = funcopts.Option[options]
+
+func WithLogger(l Logger) Option {
+	return func(options *options) error {
+		if l == nil {
+			return errors.New("logger cannot be nil")
+		}
+		options.log = l
+		return nil
+	}
+}
+
+func WithMetrics(m Metrics) Option {
+	return func(options *options) error {
+		if m == nil {
+			return errors.New("metrics cannot be nil")
+		}
+		options.metrics = m
+		return nil
+	}
+}
+
+func (o *options) SetDefaults() {
+	o.log = NopLogger{}
+	o.metrics = NopMetrics{}
+}
+
+// Importer parses "key=value" records; it logs and counts what it does.
+type Importer struct {
+	log     Logger
+	metrics Metrics
+}
+
+func NewImporter(opts ...Option) (*Importer, error) {
+	options, err := construct.New(opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &Importer{log: options.log, metrics: options.metrics}, nil
+}
+
+// Import returns the parsed records, skipping malformed ones.
+func (im *Importer) Import(records []string) map[string]string {
+	out := make(map[string]string, len(records))
+	for i, r := range records {
+		k, v, ok := strings.Cut(r, "=")
+		if !ok || k == "" {
+			im.log.Log("skipped", "line", i, "record", r)
+			im.metrics.Count("import.skipped", 1)
+			continue
+		}
+		out[k] = v
+		im.log.Log("imported", "line", i, "key", k)
+		im.metrics.Count("import.records", 1)
+	}
+	return out
+}
+
+// WriterLogger is a Logger printing to a strings.Builder, for readable
+// examples.
+type WriterLogger struct {
+	b *strings.Builder
+}
+
+func NewWriterLogger(b *strings.Builder) *WriterLogger { return &WriterLogger{b: b} }
+
+func (l *WriterLogger) Log(msg string, kv ...any) {
+	fmt.Fprintln(l.b, append([]any{msg}, kv...)...)
+}
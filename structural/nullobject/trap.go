@@ -0,0 +1,45 @@
+package nullobject
+
+import "strings"
+
+// typed nil in an interface
+// Level: Poor
+// pros: none; it is a trap.
+// cons: an interface holding a nil pointer is not nil: it has a type.
+// Checks against nil pass it, and the first method touching the pointer's
+// fields panics, far from where the nil came from.
+//
+// OpenLogger returns a logger writing to b, or none when b is nil, and
+// the nil it returns is a *WriterLogger: assigned to a Logger, the
+// interface is not nil, so CheckedImporter's checks call it, and
+// NewImporter's WithLogger accepts it.
+func OpenLogger(b *strings.Builder) *WriterLogger {
+	if b == nil {
+		return nil
+	}
+	return NewWriterLogger(b)
+}
+
+// Logging returns a logger writing to b, or NopLogger when b is nil: the
+// fix is to return the interface, and a null object rather than nil, from
+// any function whose result may be absent.
+func Logging(b *strings.Builder) Logger {
+	if b == nil {
+		return NopLogger{}
+	}
+	return NewWriterLogger(b)
+}
+
+// SafeLogger is the other fix, from the receiver's side: a method on a
+// pointer can be called on nil, so a nil *SafeLogger is itself a null
+// object, typed nil or not.
+type SafeLogger struct {
+	b *strings.Builder
+}
+
+func (l *SafeLogger) Log(msg string, kv ...any) {
+	if l == nil {
+		return
+	}
+	(&WriterLogger{b: l.b}).Log(msg, kv...)
+}
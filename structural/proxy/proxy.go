@@ -0,0 +1,292 @@
+// Package proxy puts stand-ins in front of an http.RoundTripper that
+// implement the same interface, so an http.Client uses them without
+// knowing:
+//
+//	bucket, _ := ratelimit.NewTokenBucket(10, 10)
+//	cached, _ := proxy.Cache(proxy.RateLimit(http.DefaultTransport, bucket))
+//	client := &http.Client{Transport: cached}
+//
+// Cache answers repeated GETs from memory and RateLimit spaces out what
+// reaches the server; in that order, cache hits spend no tokens. Each
+// controls access to the transport behind it, which is what makes it a
+// proxy rather than a decorator: a cache hit never reaches the real
+// transport at all.
+//
+// Cache is a private, client-side cache with a fixed lifetime; web/httpcache
+// is the shared, validating one for the server side.
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"patterns/caching/lru"
+	"patterns/clock"
+	"patterns/construct"
+	"patterns/funcopts"
+	"patterns/resilience/ratelimit"
+)
+
+type options struct {
+	ttl      time.Duration
+	capacity int
+	maxBody  int
+	clock    clock.Clock
+}
+
+type Option = funcopts.Option[options]
+
+// WithTTL sets how long a response is served from the cache when it has
+// no max-age of its own; the default is one minute.
+func WithTTL(d time.Duration) Option {
+	return func(options *options) error {
+		if d <= 0 {
+			return errors.New("ttl must be positive")
+		}
+		options.ttl = d
+		return nil
+	}
+}
+
+// WithCapacity sets how many responses the cache holds; the default is
+// 1000.
+func WithCapacity(n int) Option {
+	return func(options *options) error {
+		if n <= 0 {
+			return errors.New("capacity must be positive")
+		}
+		options.capacity = n
+		return nil
+	}
+}
+
+// WithMaxBody sets the largest body cached; bigger responses pass
+// through. The default is 1MiB.
+func WithMaxBody(n int) Option {
+	return func(options *options) error {
+		if n <= 0 {
+			return errors.New("max body must be positive")
+		}
+		options.maxBody = n
+		return nil
+	}
+}
+
+func WithClock(c clock.Clock) Option {
+	return func(options *options) error {
+		if c == nil {
+			return errors.New("clock cannot be nil")
+		}
+		options.clock = c
+		return nil
+	}
+}
+
+func (o *options) SetDefaults() {
+	o.ttl = time.Minute
+	o.capacity = 1000
+	o.maxBody = 1 << 20
+	o.clock = clock.Real
+}
+
+// caching proxy
+// Level: Good
+// pros: callers keep their http.Client and get fewer round trips; hits
+// cost a map lookup and a copy of the headers.
+// cons: a fixed lifetime serves stale data until it runs out; responses
+// are buffered in full, so large or streaming bodies pass through
+// uncached.
+type CachingTransport struct {
+	next    http.RoundTripper
+	options options
+	cache   *lru.Cache[string, *entry]
+
+	hits, misses atomic.Int64
+}
+
+type entry struct {
+	status  int
+	proto   string
+	major   int
+	minor   int
+	header  http.Header
+	body    []byte
+	expires time.Time
+}
+
+// Cache returns a transport answering GET requests with 200 responses
+// from next from memory until they expire. A response marked no-store is
+// not kept, and a request marked no-cache goes to next.
+func Cache(next http.RoundTripper, opts ...Option) (*CachingTransport, error) {
+	if next == nil {
+		return nil, errors.New("next cannot be nil")
+	}
+	options, err := construct.New(opts...)
+	if err != nil {
+		return nil, err
+	}
+	cache, err := lru.New[string, *entry](options.capacity)
+	if err != nil {
+		return nil, err
+	}
+	return &CachingTransport{next: next, options: *options, cache: cache}, nil
+}
+
+// Stats reports the GET requests answered from the cache and those that
+// went to next.
+func (t *CachingTransport) Stats() (hits, misses int64) { return t.hits.Load(), t.misses.Load() }
+
+func (t *CachingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet {
+		return t.next.RoundTrip(req)
+	}
+	key := req.URL.String()
+	now := t.options.clock.Now()
+	if !hasDirective(req.Header, "no-cache") {
+		if e, ok := t.cache.Get(key); ok && now.Before(e.expires) {
+			t.hits.Add(1)
+			return e.response(req), nil
+		}
+	}
+	t.misses.Add(1)
+	resp, err := t.next.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusOK || hasDirective(resp.Header, "no-store") {
+		return resp, err
+	}
+	ttl, ok := maxAge(resp.Header)
+	if !ok {
+		ttl = t.options.ttl
+	}
+	if ttl <= 0 {
+		return resp, nil
+	}
+
+	// read one byte past the limit to tell a body that fits from one that
+	// does not
+	body, err := io.ReadAll(io.LimitReader(resp.Body, int64(t.options.maxBody)+1))
+	if err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	if len(body) > t.options.maxBody {
+		resp.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), resp.Body), resp.Body}
+		return resp, nil
+	}
+	resp.Body.Close()
+	t.cache.Add(key, &entry{
+		status:  resp.StatusCode,
+		proto:   resp.Proto,
+		major:   resp.ProtoMajor,
+		minor:   resp.ProtoMinor,
+		header:  resp.Header.Clone(),
+		body:    body,
+		expires: now.Add(ttl),
+	})
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	return resp, nil
+}
+
+func (e *entry) response(req *http.Request) *http.Response {
+	h := e.header.Clone()
+	h.Set("X-Cache", "HIT")
+	return &http.Response{
+		Status:        strconv.Itoa(e.status) + " " + http.StatusText(e.status),
+		StatusCode:    e.status,
+		Proto:         e.proto,
+		ProtoMajor:    e.major,
+		ProtoMinor:    e.minor,
+		Header:        h,
+		Body:          io.NopCloser(bytes.NewReader(e.body)),
+		ContentLength: int64(len(e.body)),
+		Request:       req,
+	}
+}
+
+func hasDirective(h http.Header, name string) bool {
+	for _, v := range h.Values("Cache-Control") {
+		for _, d := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(d), name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func maxAge(h http.Header) (time.Duration, bool) {
+	for _, v := range h.Values("Cache-Control") {
+		for _, d := range strings.Split(v, ",") {
+			name, value, ok := strings.Cut(strings.TrimSpace(d), "=")
+			if !ok || !strings.EqualFold(name, "max-age") {
+				continue
+			}
+			if n, err := strconv.Atoi(value); err == nil {
+				return time.Duration(n) * time.Second, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// rate-limiting proxy
+// Level: Good
+// pros: the client keeps to the server's quota by itself, waiting instead
+// of collecting 429s; every caller sharing the transport shares the
+// budget.
+// cons: waiting holds the caller's goroutine; a wait longer than the
+// request's deadline fails at once, but its token stays spent.
+type RateLimitTransport struct {
+	next   http.RoundTripper
+	bucket *ratelimit.TokenBucket
+}
+
+// RateLimit returns a transport sending requests to next no faster than
+// bucket allows, each waiting for its token or its context.
+func RateLimit(next http.RoundTripper, bucket *ratelimit.TokenBucket) *RateLimitTransport {
+	return &RateLimitTransport{next: next, bucket: bucket}
+}
+
+var ErrRateLimited = errors.New("proxy: rate limit wait exceeds the request deadline")
+
+func (t *RateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := wait(req.Context(), t.bucket.Reserve(1)); err != nil {
+		closeBody(req)
+		return nil, err
+	}
+	return t.next.RoundTrip(req)
+}
+
+func wait(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < d {
+		return ErrRateLimited
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// closeBody honours the RoundTripper contract: the request body is
+// closed even when the request is never sent.
+func closeBody(req *http.Request) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+}
@@ -0,0 +1,313 @@
+package proxy_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"patterns/clock"
+	"patterns/resilience/ratelimit"
+	"patterns/structural/proxy"
+)
+
+// origin serves each path with the query's cc as Cache-Control and
+// status as the status, and counts the requests that reach it.
+type origin struct {
+	hits atomic.Int64
+}
+
+func (o *origin) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	n := o.hits.Add(1)
+	q := r.URL.Query()
+	if cc := q.Get("cc"); cc != "" {
+		w.Header().Set("Cache-Control", cc)
+	}
+	status := http.StatusOK
+	if s := q.Get("status"); s != "" {
+		fmt.Sscan(s, &status)
+	}
+	w.Header().Set("X-Origin", "yes")
+	w.WriteHeader(status)
+	if size := q.Get("size"); size != "" {
+		var n int
+		fmt.Sscan(size, &n)
+		io.WriteString(w, strings.Repeat("x", n))
+		return
+	}
+	body, _ := io.ReadAll(r.Body)
+	fmt.Fprintf(w, "%s %s #%d %s", r.Method, r.URL.Path, n, body)
+}
+
+type fixture struct {
+	origin *origin
+	clk    *clock.Fake
+	cache  *proxy.CachingTransport
+	client *http.Client
+	url    string
+}
+
+func newFixture(t *testing.T, opts ...proxy.Option) *fixture {
+	t.Helper()
+	f := &fixture{origin: &origin{}, clk: clock.NewFake(time.Unix(0, 0))}
+	srv := httptest.NewServer(f.origin)
+	t.Cleanup(srv.Close)
+	var err error
+	f.cache, err = proxy.Cache(http.DefaultTransport, append([]proxy.Option{proxy.WithClock(f.clk)}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.client = &http.Client{Transport: f.cache}
+	f.url = srv.URL
+	return f
+}
+
+// get returns the body and whether the cache answered.
+func (f *fixture) get(t *testing.T, path string, header ...string) (string, bool) {
+	t.Helper()
+	req, _ := http.NewRequest("GET", f.url+path, nil)
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	resp, err := f.client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Header.Get("X-Origin") != "yes" {
+		t.Errorf("%s: origin headers lost: %v", path, resp.Header)
+	}
+	return string(body), resp.Header.Get("X-Cache") == "HIT"
+}
+
+// TestCacheHit checks that a repeated GET is answered without reaching
+// the origin, with the origin's status, headers and body.
+func TestCacheHit(t *testing.T) {
+	f := newFixture(t)
+	first, hit1 := f.get(t, "/a")
+	second, hit2 := f.get(t, "/a")
+	if hit1 || !hit2 || first != second || first != "GET /a #1 " {
+		t.Errorf("got %q (hit %v), then %q (hit %v)", first, hit1, second, hit2)
+	}
+	if n := f.origin.hits.Load(); n != 1 {
+		t.Errorf("origin saw %d requests, want 1", n)
+	}
+	if hits, misses := f.cache.Stats(); hits != 1 || misses != 1 {
+		t.Errorf("Stats = %d hits, %d misses", hits, misses)
+	}
+
+	resp, err := f.client.Get(f.url + "/a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != 200 || resp.Status != "200 OK" || resp.ContentLength != int64(len(first)) || resp.Request == nil {
+		t.Errorf("cached response %q, length %d, request %v", resp.Status, resp.ContentLength, resp.Request)
+	}
+}
+
+// TestPassThrough checks what goes to the origin every time.
+func TestPassThrough(t *testing.T) {
+	for _, c := range []struct {
+		name   string
+		path   string
+		header []string
+	}{
+		{"not found", "/a?status=404", nil},
+		{"no content", "/a?status=204", nil},
+		{"no-store", "/a?cc=private,+no-store", nil},
+		{"max-age=0", "/a?cc=max-age=0", nil},
+		{"request no-cache", "/a", []string{"Cache-Control", "no-cache"}},
+		{"too large", "/a?size=2048", nil},
+	} {
+		f := newFixture(t, proxy.WithMaxBody(1024))
+		for i := range 2 {
+			if body, hit := f.get(t, c.path, c.header...); hit || c.name == "too large" && len(body) != 2048 {
+				t.Errorf("%s: request %d: hit %v, %d bytes", c.name, i, hit, len(body))
+			}
+		}
+		if n := f.origin.hits.Load(); n != 2 {
+			t.Errorf("%s: origin saw %d requests, want 2", c.name, n)
+		}
+	}
+
+	// other methods are never answered from the cache, nor cached
+	f := newFixture(t)
+	for i := range 2 {
+		resp, err := f.client.Post(f.url+"/a", "text/plain", strings.NewReader("payload"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if want := fmt.Sprintf("POST /a #%d payload", i+1); string(body) != want {
+			t.Errorf("POST %d: %q, want %q", i, body, want)
+		}
+	}
+	if _, hit := f.get(t, "/a"); hit {
+		t.Error("GET answered from a POST")
+	}
+}
+
+// TestNoCacheRefreshes checks that a no-cache request stores the fresh
+// response for the requests after it.
+func TestNoCacheRefreshes(t *testing.T) {
+	f := newFixture(t)
+	f.get(t, "/a")
+	fresh, _ := f.get(t, "/a", "Cache-Control", "no-cache")
+	if got, hit := f.get(t, "/a"); !hit || got != fresh || got != "GET /a #2 " {
+		t.Errorf("after no-cache: %q (hit %v), want %q from the cache", got, hit, fresh)
+	}
+}
+
+func TestExpiry(t *testing.T) {
+	f := newFixture(t, proxy.WithTTL(time.Minute))
+	f.get(t, "/ttl")
+	f.get(t, "/short?cc=public,+max-age=10")
+	f.get(t, "/long?cc=max-age=600")
+	f.clk.Advance(30 * time.Second)
+	if _, hit := f.get(t, "/ttl"); !hit {
+		t.Error("/ttl: miss at 30s, want the default ttl of a minute")
+	}
+	if _, hit := f.get(t, "/short?cc=public,+max-age=10"); hit {
+		t.Error("/short: hit at 30s, want max-age=10 to win over the ttl")
+	}
+	f.clk.Advance(time.Minute)
+	if _, hit := f.get(t, "/ttl"); hit {
+		t.Error("/ttl: hit at 90s")
+	}
+	if _, hit := f.get(t, "/long?cc=max-age=600"); !hit {
+		t.Error("/long: miss at 90s, want max-age=600 to win over the ttl")
+	}
+	f.clk.Advance(10 * time.Minute)
+	if _, hit := f.get(t, "/long?cc=max-age=600"); hit {
+		t.Error("/long: hit after 11m")
+	}
+}
+
+func TestCapacity(t *testing.T) {
+	f := newFixture(t, proxy.WithCapacity(2))
+	for _, p := range []string{"/a", "/b", "/a", "/c", "/a", "/b"} {
+		f.get(t, p)
+	}
+	// /a, /b, /a hit, /c evicts /b, /a hit, /b misses
+	if hits, misses := f.cache.Stats(); hits != 2 || misses != 4 {
+		t.Errorf("Stats = %d hits, %d misses; want 2 and 4", hits, misses)
+	}
+	if _, hit := f.get(t, "/a?x=1"); hit {
+		t.Error("the query is part of the key")
+	}
+}
+
+func TestTransportError(t *testing.T) {
+	f := newFixture(t)
+	f.url = "http://127.0.0.1:1"
+	if _, err := f.client.Get(f.url + "/a"); err == nil {
+		t.Fatal("no error from an unreachable origin")
+	}
+	if hits, misses := f.cache.Stats(); hits != 0 || misses != 1 {
+		t.Errorf("Stats = %d hits, %d misses", hits, misses)
+	}
+}
+
+// TestHitsSpendNoTokens puts the cache in front of the rate limit, as the
+// package doc does: only misses take a token.
+func TestHitsSpendNoTokens(t *testing.T) {
+	clk := clock.NewFake(time.Unix(0, 0))
+	bucket, _ := ratelimit.NewTokenBucket(1, 3, ratelimit.WithClock(clk))
+	o := &origin{}
+	srv := httptest.NewServer(o)
+	defer srv.Close()
+	cache, _ := proxy.Cache(proxy.RateLimit(http.DefaultTransport, bucket), proxy.WithClock(clk))
+	client := &http.Client{Transport: cache}
+	for range 5 {
+		resp, err := client.Get(srv.URL + "/a")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	// one token spent, two left
+	if !bucket.Allow() || !bucket.Allow() || bucket.Allow() {
+		t.Error("cache hits spent tokens")
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	clk := clock.NewFake(time.Unix(0, 0))
+	// the clock never moves, so each token after the first costs 20ms more
+	bucket, _ := ratelimit.NewTokenBucket(50, 1, ratelimit.WithClock(clk))
+	o := &origin{}
+	srv := httptest.NewServer(o)
+	defer srv.Close()
+	client := &http.Client{Transport: proxy.RateLimit(http.DefaultTransport, bucket)}
+
+	start := time.Now()
+	for range 3 {
+		resp, err := client.Get(srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	if elapsed := time.Since(start); elapsed < 60*time.Millisecond {
+		t.Errorf("3 requests took %v, want the waits of 20ms and 40ms", elapsed)
+	}
+
+	// the next token is 60ms off: a 10ms deadline fails at once
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	body := &closeRecorder{Reader: strings.NewReader("x")}
+	req, _ := http.NewRequestWithContext(ctx, "POST", srv.URL, body)
+	if _, err := client.Do(req); !errors.Is(err, proxy.ErrRateLimited) || !body.closed {
+		t.Errorf("Do = %v, body closed %v; want ErrRateLimited and the body closed", err, body.closed)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	req, _ = http.NewRequestWithContext(ctx, "GET", srv.URL, nil)
+	if _, err := client.Do(req); !errors.Is(err, context.Canceled) {
+		t.Errorf("Do canceled while waiting = %v", err)
+	}
+	if n := o.hits.Load(); n != 3 {
+		t.Errorf("origin saw %d requests, want 3", n)
+	}
+}
+
+type closeRecorder struct {
+	io.Reader
+	closed bool
+}
+
+func (c *closeRecorder) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestOptions(t *testing.T) {
+	for _, c := range []struct {
+		next http.RoundTripper
+		opts []proxy.Option
+		want string
+	}{
+		{nil, nil, "next cannot be nil"},
+		{http.DefaultTransport, []proxy.Option{proxy.WithTTL(0)}, "ttl must be positive"},
+		{http.DefaultTransport, []proxy.Option{proxy.WithCapacity(0)}, "capacity must be positive"},
+		{http.DefaultTransport, []proxy.Option{proxy.WithMaxBody(0)}, "max body must be positive"},
+		{http.DefaultTransport, []proxy.Option{proxy.WithClock(nil)}, "clock cannot be nil"},
+	} {
+		if ct, err := proxy.Cache(c.next, c.opts...); ct != nil || err == nil || err.Error() != c.want {
+			t.Errorf("Cache = %v, %v; want %q", ct, err, c.want)
+		}
+	}
+}
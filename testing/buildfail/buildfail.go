@@ -0,0 +1,67 @@
+// Package buildfail checks that code written to be rejected by the
+// compiler still is. Such code sits behind a build tag, so the package
+// builds without it, and its test builds the package with the tag and
+// states each error it expects:
+//
+//	func TestInvalid(t *testing.T) {
+//		buildfail.Check(t, "newtype_invalid", ".",
+//			"as UserID value in argument to CancelOrder",
+//		)
+//	}
+//
+// A want should be the part of the message that does not change between
+// Go releases: how the compiler describes the offending operand does,
+// so "cannot use order (variable of type OrderID)" in one release is
+// "(variable of int64 type OrderID)" in the next.
+//
+// Check fails the test if the build succeeds, if an expected error is
+// missing, or if the compiler reports an error no want matches, so a
+// typo that breaks the file for another reason does not pass for the
+// type error it was meant to show.
+package buildfail
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// Check builds pkg, relative to the test's package directory, with
+// tags and checks that the build fails with exactly the errors in
+// want, each matched as a substring of one error line.
+func Check(t testing.TB, tags, pkg string, want ...string) {
+	t.Helper()
+	gobin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go command not found:", err)
+	}
+	out, err := exec.Command(gobin, "build", "-tags", tags, pkg).CombinedOutput()
+	if err == nil {
+		t.Fatalf("go build -tags %s %s succeeded, want it to fail", tags, pkg)
+	}
+	if _, ok := err.(*exec.ExitError); !ok {
+		t.Fatalf("go build -tags %s %s: %v", tags, pkg, err)
+	}
+
+	matched := make([]bool, len(want))
+	for _, line := range strings.Split(string(out), "\n") {
+		// the compiler prefixes a package's errors with "# path"
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		found := false
+		for i, w := range want {
+			if strings.Contains(line, w) {
+				matched[i], found = true, true
+			}
+		}
+		if !found {
+			t.Errorf("unexpected error: %s", line)
+		}
+	}
+	for i, w := range want {
+		if !matched[i] {
+			t.Errorf("missing error %q in:\n%s", w, out)
+		}
+	}
+}
@@ -0,0 +1,255 @@
+// Package chaos injects faults into the dependencies of the code under
+// test, so its failure paths run on purpose rather than when production
+// finds them: slow calls, errors, short reads and writes, truncated
+// response bodies, clocks that disagree.
+//
+//	in, _ := chaos.New(chaos.WithSeed(7), chaos.WithErrors(0.3, errFlaky), chaos.WithLatency(0.1, 50*time.Millisecond))
+//	repo := chaos.Repository(repository.NewMemory[string, User](), in)
+//	client := &http.Client{Transport: chaos.Transport(http.DefaultTransport, in)}
+//
+// Faults come from a seeded randsource.Rand, and every call draws the
+// same numbers whichever faults are enabled, so a seed picks the same
+// calls to break on every run and turning on latency does not move the
+// errors. That makes a failure reproducible as long as the calls happen
+// in the same order: Check runs each Scenario twice and fails one whose
+// faults differ between runs, the sign of a scenario that depends on
+// goroutine scheduling and whose seed proves nothing.
+//
+// The tests run the scenarios of the resilience packages: retries that
+// ride out a flaky repository, a breaker that opens on a failing
+// transport, readers that cope with short reads.
+//
+//	go test -v -run TestScenarios patterns/testing/chaos
+package chaos
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"patterns/clock"
+	"patterns/construct"
+	"patterns/funcopts"
+	"patterns/randsource"
+)
+
+//go:generate go run patterns/cmd/enumgen -type=Kind -trimprefix=Kind
+
+// Kind is what a fault does to a call.
+type Kind int
+
+const (
+	KindLatency Kind = iota
+	KindError
+	KindPartial
+)
+
+// ErrInjected is the error of WithErrors(rate, nil).
+var ErrInjected = errors.New("chaos: injected fault")
+
+// Event is one fault injected into a call of Op.
+type Event struct {
+	Call int // the call's number, from 1, across every wrapper
+	Op   string
+	Kind Kind
+}
+
+// options hold a rate, the share of calls struck, for each kind.
+type options struct {
+	rand        randsource.Rand
+	clock       clock.Clock
+	latencyRate float64
+	latency     time.Duration
+	errorRate   float64
+	err         error
+	partialRate float64
+	skew        time.Duration
+}
+
+type Option = funcopts.Option[options]
+
+func rate(p float64) error {
+	if p < 0 || p > 1 {
+		return errors.New("rate must be between 0 and 1")
+	}
+	return nil
+}
+
+// WithSeed draws the faults from randsource.New(seed).
+func WithSeed(seed uint64) Option {
+	return func(options *options) error {
+		options.rand = randsource.New(seed)
+		return nil
+	}
+}
+
+// WithRand draws the faults from r, for scripted faults.
+func WithRand(r randsource.Rand) Option {
+	return func(options *options) error {
+		if r == nil {
+			return errors.New("rand cannot be nil")
+		}
+		options.rand = r
+		return nil
+	}
+}
+
+// WithClock sets the clock latency is spent on.
+func WithClock(c clock.Clock) Option {
+	return func(options *options) error {
+		if c == nil {
+			return errors.New("clock cannot be nil")
+		}
+		options.clock = c
+		return nil
+	}
+}
+
+// WithLatency delays a share p of calls by d, or until the call's
+// context ends.
+func WithLatency(p float64, d time.Duration) Option {
+	return func(options *options) error {
+		if err := rate(p); err != nil {
+			return err
+		}
+		if d <= 0 {
+			return errors.New("latency must be positive")
+		}
+		options.latencyRate, options.latency = p, d
+		return nil
+	}
+}
+
+// WithErrors fails a share p of calls with err, ErrInjected if nil.
+// Calls that fail do not reach the wrapped dependency.
+func WithErrors(p float64, err error) Option {
+	return func(options *options) error {
+		if err := rate(p); err != nil {
+			return err
+		}
+		if err == nil {
+			err = ErrInjected
+		}
+		options.errorRate, options.err = p, err
+		return nil
+	}
+}
+
+// WithPartial cuts a share p of reads and writes short, and truncates
+// response bodies; other calls are unaffected.
+func WithPartial(p float64) Option {
+	return func(options *options) error {
+		if err := rate(p); err != nil {
+			return err
+		}
+		options.partialRate = p
+		return nil
+	}
+}
+
+// WithSkew makes each Now read from a Clock wrapper off by up to max
+// either way, as if from another machine's clock, sometimes going
+// backwards.
+func WithSkew(max time.Duration) Option {
+	return func(options *options) error {
+		if max <= 0 {
+			return errors.New("skew must be positive")
+		}
+		options.skew = max
+		return nil
+	}
+}
+
+func (o *options) SetDefaults() {
+	o.rand = randsource.New(1)
+	o.clock = clock.Real
+}
+
+// fault injector
+// Level: Good
+// pros: failure paths run in every test run, not once in production; a
+// seed replays the same faults; wrappers need no change to the code under
+// test.
+// cons: faults are drawn per call in call order, so concurrent callers
+// get them in scheduling order and the run is no longer reproducible.
+//
+// Injector decides the faults of every call through the wrappers built
+// on it, and records them. It is safe for concurrent use.
+type Injector struct {
+	options
+	mu     sync.Mutex
+	calls  int
+	events []Event
+}
+
+func New(opts ...Option) (*Injector, error) {
+	options, err := construct.New(opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &Injector{options: *options}, nil
+}
+
+// Events returns the faults injected so far, in order. Skew is not one:
+// it applies to every Now.
+func (in *Injector) Events() []Event {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	return append([]Event(nil), in.events...)
+}
+
+// draw is the faults of one call of op. Every call draws three numbers,
+// in the same order, whichever are used.
+type draw struct {
+	delay   time.Duration
+	err     error
+	partial bool
+	cut     float64 // where a partial call stops, as a share of its length
+}
+
+func (in *Injector) call(op string) draw {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	in.calls++
+	latency, fail, partial := in.rand.Float64(), in.rand.Float64(), in.rand.Float64()
+	var d draw
+	if latency < in.latencyRate {
+		d.delay = in.latency
+		in.events = append(in.events, Event{in.calls, op, KindLatency})
+	}
+	if fail < in.errorRate {
+		d.err = in.err
+		in.events = append(in.events, Event{in.calls, op, KindError})
+	} else if partial < in.partialRate {
+		// the draw under the rate, scaled up, is uniform in [0, 1)
+		d.partial, d.cut = true, partial/in.partialRate
+		in.events = append(in.events, Event{in.calls, op, KindPartial})
+	}
+	return d
+}
+
+// before runs the latency and error faults of a call of op, and returns
+// its draw for the wrapper to apply a partial one.
+func (in *Injector) before(ctx context.Context, op string) (draw, error) {
+	d := in.call(op)
+	if d.delay > 0 {
+		t := in.clock.NewTimer(d.delay)
+		select {
+		case <-t.C():
+		case <-ctx.Done():
+			t.Stop()
+			return d, context.Cause(ctx)
+		}
+	}
+	return d, d.err
+}
+
+func (in *Injector) skewed() time.Duration {
+	if in.skew == 0 {
+		return 0
+	}
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	return time.Duration(in.rand.Int64N(int64(2*in.skew)+1)) - in.skew
+}
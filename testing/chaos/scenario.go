@@ -0,0 +1,64 @@
+package chaos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// Scenario is one failure path: Run wires the code under test to
+// dependencies wrapped with in, drives it, and returns an error if it
+// did not cope.
+type Scenario struct {
+	Name   string
+	Seed   uint64
+	Faults []Option
+	Run    func(ctx context.Context, in *Injector) error
+}
+
+// Result is the outcome of one play of a Scenario.
+type Result struct {
+	Err    error
+	Events []Event
+}
+
+// Play runs s once, on a fresh Injector seeded with s.Seed.
+func Play(ctx context.Context, s Scenario) Result {
+	in, err := New(append(slices.Clone(s.Faults), WithSeed(s.Seed))...)
+	if err != nil {
+		return Result{Err: err}
+	}
+	err = s.Run(ctx, in)
+	return Result{Err: err, Events: in.Events()}
+}
+
+// Verify plays s twice and returns the first play, with an error if s
+// failed or if the second play injected different faults, which makes
+// the seed useless for reproducing it.
+func Verify(ctx context.Context, s Scenario) (Result, error) {
+	first := Play(ctx, s)
+	if first.Err != nil {
+		return first, first.Err
+	}
+	second := Play(ctx, s)
+	if second.Err != nil {
+		return first, fmt.Errorf("failed on the second play with the same seed: %w", second.Err)
+	}
+	if !slices.Equal(first.Events, second.Events) {
+		return first, fmt.Errorf("not deterministic: seed %d injected different faults on the second play", s.Seed)
+	}
+	return first, nil
+}
+
+// Check verifies every scenario and returns the failures joined, each
+// naming its scenario; nil means every one coped, the same way twice.
+func Check(ctx context.Context, scenarios []Scenario) error {
+	var errs []error
+	for _, s := range scenarios {
+		if _, err := Verify(ctx, s); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", s.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,159 @@
+package chaos
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+
+	"patterns/clock"
+	"patterns/persistence/repository"
+)
+
+// Repository returns r with in's latency and error faults on every
+// method.
+func Repository[K comparable, V any](r repository.Repository[K, V], in *Injector) repository.Repository[K, V] {
+	return &repo[K, V]{r, in}
+}
+
+type repo[K comparable, V any] struct {
+	r  repository.Repository[K, V]
+	in *Injector
+}
+
+func (c *repo[K, V]) Get(ctx context.Context, key K) (V, error) {
+	if _, err := c.in.before(ctx, "Get"); err != nil {
+		var zero V
+		return zero, err
+	}
+	return c.r.Get(ctx, key)
+}
+
+func (c *repo[K, V]) Create(ctx context.Context, key K, value V) error {
+	if _, err := c.in.before(ctx, "Create"); err != nil {
+		return err
+	}
+	return c.r.Create(ctx, key, value)
+}
+
+func (c *repo[K, V]) Update(ctx context.Context, key K, value V) error {
+	if _, err := c.in.before(ctx, "Update"); err != nil {
+		return err
+	}
+	return c.r.Update(ctx, key, value)
+}
+
+func (c *repo[K, V]) Delete(ctx context.Context, key K) error {
+	if _, err := c.in.before(ctx, "Delete"); err != nil {
+		return err
+	}
+	return c.r.Delete(ctx, key)
+}
+
+func (c *repo[K, V]) List(ctx context.Context) ([]V, error) {
+	if _, err := c.in.before(ctx, "List"); err != nil {
+		return nil, err
+	}
+	return c.r.List(ctx)
+}
+
+// Reader returns r with in's faults on every Read; a partial one reads
+// into only a prefix of the buffer, the short read io.Reader allows and
+// callers that assume a full buffer get wrong.
+func Reader(r io.Reader, in *Injector) io.Reader { return &reader{r, in} }
+
+type reader struct {
+	r  io.Reader
+	in *Injector
+}
+
+func (r *reader) Read(p []byte) (int, error) {
+	d, err := r.in.before(context.Background(), "Read")
+	if err != nil {
+		return 0, err
+	}
+	if d.partial && len(p) > 1 {
+		p = p[:1+int(d.cut*float64(len(p)-1))]
+	}
+	return r.r.Read(p)
+}
+
+// Writer returns w with in's faults on every Write; a partial one writes
+// a prefix and fails with io.ErrShortWrite.
+func Writer(w io.Writer, in *Injector) io.Writer { return &writer{w, in} }
+
+type writer struct {
+	w  io.Writer
+	in *Injector
+}
+
+func (w *writer) Write(p []byte) (int, error) {
+	d, err := w.in.before(context.Background(), "Write")
+	if err != nil {
+		return 0, err
+	}
+	if !d.partial || len(p) == 0 {
+		return w.w.Write(p)
+	}
+	n, err := w.w.Write(p[:int(d.cut*float64(len(p)))])
+	if err != nil {
+		return n, err
+	}
+	return n, io.ErrShortWrite
+}
+
+// Transport returns rt with in's faults on every round trip: latency
+// honours the request's context, an error fails it before it is sent,
+// and a partial one truncates the response body, which then fails with
+// io.ErrUnexpectedEOF.
+func Transport(rt http.RoundTripper, in *Injector) http.RoundTripper {
+	return &transport{rt, in}
+}
+
+type transport struct {
+	rt http.RoundTripper
+	in *Injector
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	d, err := t.in.before(req.Context(), "RoundTrip")
+	if err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, err
+	}
+	resp, err := t.rt.RoundTrip(req)
+	if err != nil || !d.partial {
+		return resp, err
+	}
+	// an unknown length is cut at once
+	cut := int64(d.cut * float64(max(resp.ContentLength, 0)))
+	resp.Body = &truncated{resp.Body, io.LimitReader(resp.Body, cut)}
+	return resp, nil
+}
+
+type truncated struct {
+	io.Closer
+	r io.Reader
+}
+
+func (t *truncated) Read(p []byte) (int, error) {
+	n, err := t.r.Read(p)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return n, err
+}
+
+// Clock returns c with every Now, and Since, off by in's skew; timers
+// and sleeps are c's.
+func Clock(c clock.Clock, in *Injector) clock.Clock { return &skewed{c, in} }
+
+type skewed struct {
+	clock.Clock
+	in *Injector
+}
+
+func (s *skewed) Now() time.Time                  { return s.Clock.Now().Add(s.in.skewed()) }
+func (s *skewed) Since(t time.Time) time.Duration { return s.Now().Sub(t) }
@@ -0,0 +1,93 @@
+package contracts
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"patterns/persistence/repository"
+)
+
+// BlobStore stores opaque content by name; factory.Blobs is one.
+type BlobStore interface {
+	Put(ctx context.Context, name string, data []byte) error
+	// Get fails with repository.ErrNotFound for an unknown name.
+	Get(ctx context.Context, name string) ([]byte, error)
+}
+
+// BlobStoreFactory returns a new, empty store for one case.
+type BlobStoreFactory func(t testing.TB) BlobStore
+
+// BlobStores is the contract of BlobStore. Names are single path
+// elements: a store may keep them as files, so one that is not must be
+// refused rather than escape.
+func BlobStores(newStore BlobStoreFactory) []Case {
+	ctx := context.Background()
+	return []Case{
+		{"get missing", func(t testing.TB) {
+			_, err := newStore(t).Get(ctx, "a")
+			expectErr(t, "Get", err, repository.ErrNotFound)
+		}},
+		{"put then get", func(t testing.TB) {
+			s := newStore(t)
+			noErr(t, "Put", s.Put(ctx, "a", []byte("hello")))
+			noErr(t, "Put other", s.Put(ctx, "b", []byte("other")))
+			got, err := s.Get(ctx, "a")
+			noErr(t, "Get", err)
+			expect(t, "Get", string(got), "hello")
+		}},
+		{"overwrite", func(t testing.TB) {
+			s := newStore(t)
+			noErr(t, "Put", s.Put(ctx, "a", []byte("first, and longer")))
+			noErr(t, "second Put", s.Put(ctx, "a", []byte("second")))
+			got, _ := s.Get(ctx, "a")
+			expect(t, "Get after overwrite", string(got), "second")
+		}},
+		{"empty content", func(t testing.TB) {
+			s := newStore(t)
+			noErr(t, "Put", s.Put(ctx, "a", nil))
+			got, err := s.Get(ctx, "a")
+			noErr(t, "Get", err)
+			expect(t, "len(Get)", len(got), 0)
+		}},
+		{"content is copied", func(t testing.TB) {
+			s := newStore(t)
+			data := []byte("hello")
+			noErr(t, "Put", s.Put(ctx, "a", data))
+			data[0] = 'J'
+			got, _ := s.Get(ctx, "a")
+			expect(t, "Get after changing the Put slice", string(got), "hello")
+			got[0] = 'Y'
+			again, _ := s.Get(ctx, "a")
+			expect(t, "Get after changing an earlier Get", string(again), "hello")
+		}},
+		{"binary content", func(t testing.TB) {
+			s := newStore(t)
+			data := make([]byte, 1<<16)
+			for i := range data {
+				data[i] = byte(i * 7)
+			}
+			noErr(t, "Put", s.Put(ctx, "a", data))
+			got, _ := s.Get(ctx, "a")
+			expect(t, "Get returns the bytes put", bytes.Equal(got, data), true)
+		}},
+		{"invalid names", func(t testing.TB) {
+			s := newStore(t)
+			for _, name := range []string{"", ".", "..", "../a", "a/b", `a\b`, "/a"} {
+				if err := s.Put(ctx, name, []byte("x")); err == nil {
+					t.Fatalf("Put(%q) succeeded", name)
+				}
+				if _, err := s.Get(ctx, name); err == nil {
+					t.Fatalf("Get(%q) succeeded", name)
+				}
+			}
+		}},
+	}
+}
+
+// RunBlobStoreContract fails t unless stores from newStore keep the
+// BlobStore contract.
+func RunBlobStoreContract(t *testing.T, newStore BlobStoreFactory) {
+	t.Helper()
+	Run(t, BlobStores(newStore))
+}
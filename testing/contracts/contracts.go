@@ -0,0 +1,66 @@
+// Package contracts holds the behaviour every implementation of a port
+// must share, written once as test cases and run against each adapter:
+//
+//   - Repository, for persistence/repository.Repository
+//   - Users, for persistence/repository.UserStore
+//   - BlobStore, for stores of named content like factory.Blobs
+//   - Locker, for lease stores like election.Store
+//   - Limiter, for the rate limiters of resilience/ratelimit
+//
+// A contract turns "is the file repository a drop-in for the memory one?"
+// into a check: both must pass the same cases, so code tested against one
+// behaves the same against the other, and a new adapter is done when it
+// passes. Each adapter's package runs its contract in contract_test.go
+// with the contract's Run function, one subtest per case:
+//
+//	func TestSQLContract(t *testing.T) {
+//		contracts.RunRepositoryContract(t, func(t testing.TB) repository.Repository[string, string] {
+//			return openTestDB(t)
+//		})
+//	}
+//
+// A contract states what callers may rely on and nothing more: the
+// repository contract checks that List returns every value, not in
+// which order.
+package contracts
+
+import (
+	"errors"
+	"testing"
+)
+
+// Case is one behaviour of a contract, written like a go test function.
+// Each case gets a fresh implementation from the contract's factory.
+type Case struct {
+	Name string
+	Test func(t testing.TB)
+}
+
+// Run runs each case of cases as a subtest of t.
+func Run(t *testing.T, cases []Case) {
+	t.Helper()
+	for _, c := range cases {
+		t.Run(c.Name, func(t *testing.T) { c.Test(t) })
+	}
+}
+
+func expect[V comparable](t testing.TB, what string, got, want V) {
+	t.Helper()
+	if got != want {
+		t.Fatalf("%s = %v, want %v", what, got, want)
+	}
+}
+
+func expectErr(t testing.TB, what string, err, want error) {
+	t.Helper()
+	if !errors.Is(err, want) {
+		t.Fatalf("%s: err = %v, want %v", what, err, want)
+	}
+}
+
+func noErr(t testing.TB, what string, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("%s: %v", what, err)
+	}
+}
@@ -0,0 +1,107 @@
+package contracts
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"patterns/clock"
+	"patterns/resilience/ratelimit"
+)
+
+// LimiterFactory returns a new limiter for one case, admitting rate
+// events per second with bursts of at most burst, timed by c.
+type LimiterFactory func(t testing.TB, c clock.Clock, rate float64, burst int) ratelimit.Limiter
+
+// Limiter is the contract of ratelimit.Limiter, whatever the algorithm:
+// an idle limiter admits, a burst never gets more than burst through at
+// once, and over time the limiter holds the rate, neither above it nor
+// far below. How a burst is shaped within those bounds is the
+// algorithm's own; ratelimit's Benchmarks compare that.
+func Limiter(newLimiter LimiterFactory) []Case {
+	const (
+		rate  = 10
+		burst = 5
+	)
+	window := time.Duration(burst) * time.Second / rate
+	setup := func(t testing.TB) (ratelimit.Limiter, *clock.Fake) {
+		c := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+		l := newLimiter(t, c, rate, burst)
+		c.Advance(time.Minute)
+		return l, c
+	}
+	offer := func(l ratelimit.Limiter, n int) int {
+		admitted := 0
+		for range n {
+			if l.Allow() {
+				admitted++
+			}
+		}
+		return admitted
+	}
+	return []Case{
+		{"idle admits", func(t testing.TB) {
+			l, _ := setup(t)
+			expect(t, "Allow on an idle limiter", l.Allow(), true)
+		}},
+		{"burst", func(t testing.TB) {
+			l, _ := setup(t)
+			if n := offer(l, 100); n < 1 || n > burst {
+				t.Fatalf("100 events at once: %d admitted, want 1 to %d", n, burst)
+			}
+		}},
+		{"recovers", func(t testing.TB) {
+			l, c := setup(t)
+			offer(l, 100)
+			expect(t, "Allow right after a burst", l.Allow(), false)
+			c.Advance(2 * window)
+			expect(t, "Allow after idling", l.Allow(), true)
+		}},
+		{"holds the rate", func(t testing.TB) {
+			l, c := setup(t)
+			const seconds = 60
+			admitted := 0
+			var inWindow []time.Time
+			for range seconds * 100 {
+				if l.Allow() {
+					admitted++
+					inWindow = append(inWindow, c.Now())
+					for c.Now().Sub(inWindow[0]) >= window {
+						inWindow = inWindow[1:]
+					}
+					if len(inWindow) > 2*burst {
+						t.Fatalf("%d admitted within %v, want at most %d", len(inWindow), window, 2*burst)
+					}
+				}
+				c.Advance(10 * time.Millisecond)
+			}
+			if admitted > rate*seconds+burst || admitted < rate*seconds*9/10 {
+				t.Fatalf("offered 100/s for %ds at a rate of %d/s: %d admitted, want about %d", seconds, rate, admitted, rate*seconds)
+			}
+		}},
+		{"concurrent", func(t testing.TB) {
+			l, _ := setup(t)
+			var admitted atomic.Int64
+			var wg sync.WaitGroup
+			for range 16 {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					admitted.Add(int64(offer(l, 10)))
+				}()
+			}
+			wg.Wait()
+			if n := admitted.Load(); n < 1 || n > burst {
+				t.Fatalf("racing Allows: %d admitted, want 1 to %d", n, burst)
+			}
+		}},
+	}
+}
+
+// RunLimiterContract fails t unless limiters from newLimiter keep the
+// Limiter contract.
+func RunLimiterContract(t *testing.T, newLimiter LimiterFactory) {
+	t.Helper()
+	Run(t, Limiter(newLimiter))
+}
@@ -0,0 +1,108 @@
+package contracts
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"patterns/clock"
+	"patterns/distribution/election"
+)
+
+// LockerFactory returns a new, empty lease store for one case, timing
+// leases by c.
+type LockerFactory func(t testing.TB, c clock.Clock) election.Store
+
+// Locker is the contract of election.Store: at most one holder per lease
+// at a time, renewals that keep the term, and a new term for every change
+// of holder.
+func Locker(newStore LockerFactory) []Case {
+	ctx := context.Background()
+	const ttl = 10 * time.Second
+	setup := func(t testing.TB) (election.Store, *clock.Fake) {
+		c := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+		return newStore(t, c), c
+	}
+	return []Case{
+		{"acquire free", func(t testing.TB) {
+			s, c := setup(t)
+			l, err := s.Acquire(ctx, "job", "a", ttl)
+			noErr(t, "Acquire", err)
+			expect(t, "Holder", l.Holder, "a")
+			expect(t, "Term", l.Term, uint64(1))
+			expect(t, "Expires", l.Expires, c.Now().Add(ttl))
+		}},
+		{"renew", func(t testing.TB) {
+			s, c := setup(t)
+			s.Acquire(ctx, "job", "a", ttl)
+			c.Advance(ttl / 2)
+			l, err := s.Acquire(ctx, "job", "a", ttl)
+			noErr(t, "renewing Acquire", err)
+			expect(t, "Term after renewal", l.Term, uint64(1))
+			expect(t, "Expires after renewal", l.Expires, c.Now().Add(ttl))
+		}},
+		{"held", func(t testing.TB) {
+			s, c := setup(t)
+			s.Acquire(ctx, "job", "a", ttl)
+			c.Advance(ttl - time.Millisecond)
+			l, err := s.Acquire(ctx, "job", "b", ttl)
+			noErr(t, "Acquire of a held lease", err)
+			expect(t, "Holder of a held lease", l.Holder, "a")
+			other, _ := s.Acquire(ctx, "other", "b", ttl)
+			expect(t, "Holder of another lease", other.Holder, "b")
+		}},
+		{"expired", func(t testing.TB) {
+			s, c := setup(t)
+			s.Acquire(ctx, "job", "a", ttl)
+			c.Advance(ttl)
+			l, err := s.Acquire(ctx, "job", "b", ttl)
+			noErr(t, "Acquire of an expired lease", err)
+			expect(t, "Holder", l.Holder, "b")
+			expect(t, "Term", l.Term, uint64(2))
+			back, _ := s.Acquire(ctx, "job", "a", ttl)
+			expect(t, "Holder after the old holder retries", back.Holder, "b")
+		}},
+		{"release", func(t testing.TB) {
+			s, _ := setup(t)
+			noErr(t, "Release of an unknown lease", s.Release(ctx, "job", "a"))
+			s.Acquire(ctx, "job", "a", ttl)
+			noErr(t, "Release by a non-holder", s.Release(ctx, "job", "b"))
+			l, _ := s.Acquire(ctx, "job", "b", ttl)
+			expect(t, "Holder after a non-holder's Release", l.Holder, "a")
+			noErr(t, "Release", s.Release(ctx, "job", "a"))
+			l, _ = s.Acquire(ctx, "job", "b", ttl)
+			expect(t, "Holder after Release", l.Holder, "b")
+			expect(t, "Term after Release", l.Term, uint64(2))
+		}},
+		{"concurrent acquire", func(t testing.TB) {
+			s, _ := setup(t)
+			const n = 16
+			var wg sync.WaitGroup
+			holders := make([]string, n)
+			for i := range n {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					l, err := s.Acquire(ctx, "job", string(rune('a'+i)), ttl)
+					if err == nil {
+						holders[i] = l.Holder
+					}
+				}()
+			}
+			wg.Wait()
+			for _, h := range holders {
+				if h != holders[0] {
+					t.Fatalf("racing Acquires saw holders %q and %q", holders[0], h)
+				}
+			}
+		}},
+	}
+}
+
+// RunLockerContract fails t unless stores from newStore keep the Locker
+// contract.
+func RunLockerContract(t *testing.T, newStore LockerFactory) {
+	t.Helper()
+	Run(t, Locker(newStore))
+}
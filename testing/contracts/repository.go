@@ -0,0 +1,107 @@
+package contracts
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+
+	"patterns/persistence/repository"
+)
+
+// RepositoryFactory returns a new, empty repository for one case; it
+// registers any teardown with t.Cleanup.
+type RepositoryFactory func(t testing.TB) repository.Repository[string, string]
+
+// Repository is the contract of repository.Repository, over string keys
+// and values so that any storage can hold them.
+func Repository(newRepo RepositoryFactory) []Case {
+	ctx := context.Background()
+	return []Case{
+		{"get missing", func(t testing.TB) {
+			_, err := newRepo(t).Get(ctx, "a")
+			expectErr(t, "Get", err, repository.ErrNotFound)
+		}},
+		{"create then get", func(t testing.TB) {
+			r := newRepo(t)
+			noErr(t, "Create", r.Create(ctx, "a", "1"))
+			v, err := r.Get(ctx, "a")
+			noErr(t, "Get", err)
+			expect(t, "Get", v, "1")
+		}},
+		{"create existing", func(t testing.TB) {
+			r := newRepo(t)
+			noErr(t, "Create", r.Create(ctx, "a", "1"))
+			expectErr(t, "second Create", r.Create(ctx, "a", "2"), repository.ErrExists)
+			v, _ := r.Get(ctx, "a")
+			expect(t, "Get after failed Create", v, "1")
+		}},
+		{"update", func(t testing.TB) {
+			r := newRepo(t)
+			expectErr(t, "Update missing", r.Update(ctx, "a", "1"), repository.ErrNotFound)
+			if _, err := r.Get(ctx, "a"); !errors.Is(err, repository.ErrNotFound) {
+				t.Fatalf("Update of a missing key created it")
+			}
+			noErr(t, "Create", r.Create(ctx, "a", "1"))
+			noErr(t, "Update", r.Update(ctx, "a", "2"))
+			v, _ := r.Get(ctx, "a")
+			expect(t, "Get after Update", v, "2")
+		}},
+		{"delete", func(t testing.TB) {
+			r := newRepo(t)
+			expectErr(t, "Delete missing", r.Delete(ctx, "a"), repository.ErrNotFound)
+			noErr(t, "Create", r.Create(ctx, "a", "1"))
+			noErr(t, "Delete", r.Delete(ctx, "a"))
+			_, err := r.Get(ctx, "a")
+			expectErr(t, "Get after Delete", err, repository.ErrNotFound)
+			noErr(t, "Create after Delete", r.Create(ctx, "a", "2"))
+		}},
+		{"list", func(t testing.TB) {
+			r := newRepo(t)
+			vs, err := r.List(ctx)
+			noErr(t, "List empty", err)
+			expect(t, "len(List) of empty repository", len(vs), 0)
+			for i := range 5 {
+				noErr(t, "Create", r.Create(ctx, "k"+strconv.Itoa(i), strconv.Itoa(i)))
+			}
+			noErr(t, "Delete", r.Delete(ctx, "k2"))
+			vs, err = r.List(ctx)
+			noErr(t, "List", err)
+			slices.Sort(vs)
+			expect(t, "List", slices.Equal(vs, []string{"0", "1", "3", "4"}), true)
+		}},
+		{"concurrent create", func(t testing.TB) {
+			r := newRepo(t)
+			const n = 16
+			var wg sync.WaitGroup
+			errs := make([]error, n)
+			for i := range n {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					errs[i] = r.Create(ctx, "a", strconv.Itoa(i))
+				}()
+			}
+			wg.Wait()
+			won := 0
+			for _, err := range errs {
+				switch {
+				case err == nil:
+					won++
+				case !errors.Is(err, repository.ErrExists):
+					t.Fatalf("Create: %v", err)
+				}
+			}
+			expect(t, "successful concurrent Creates of one key", won, 1)
+		}},
+	}
+}
+
+// RunRepositoryContract fails t unless repositories from newRepo keep
+// the Repository contract.
+func RunRepositoryContract(t *testing.T, newRepo RepositoryFactory) {
+	t.Helper()
+	Run(t, Repository(newRepo))
+}
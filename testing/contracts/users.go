@@ -0,0 +1,129 @@
+package contracts
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"patterns/persistence/repository"
+)
+
+// UsersFactory returns a new, empty user store for one case.
+type UsersFactory func(t testing.TB) repository.UserStore
+
+// Users is the contract of repository.UserStore: unique ids and emails,
+// and transactions that apply all of their changes or none.
+func Users(newStore UsersFactory) []Case {
+	ctx := context.Background()
+	ann := repository.User{ID: "1", Email: "ann@example.com", Name: "Ann"}
+	bob := repository.User{ID: "2", Email: "bob@example.com", Name: "Bob"}
+	errStop := errors.New("stop")
+	return []Case{
+		{"get missing", func(t testing.TB) {
+			s := newStore(t)
+			_, err := s.Get(ctx, "1")
+			expectErr(t, "Get", err, repository.ErrNotFound)
+			_, err = s.ByEmail(ctx, ann.Email)
+			expectErr(t, "ByEmail", err, repository.ErrNotFound)
+		}},
+		{"create then get", func(t testing.TB) {
+			s := newStore(t)
+			noErr(t, "Create", s.Create(ctx, ann))
+			noErr(t, "Create other", s.Create(ctx, bob))
+			u, err := s.Get(ctx, "1")
+			noErr(t, "Get", err)
+			expect(t, "Get", u, ann)
+			u, err = s.ByEmail(ctx, bob.Email)
+			noErr(t, "ByEmail", err)
+			expect(t, "ByEmail", u, bob)
+		}},
+		{"unique id and email", func(t testing.TB) {
+			s := newStore(t)
+			noErr(t, "Create", s.Create(ctx, ann))
+			expectErr(t, "Create with a taken id", s.Create(ctx, repository.User{ID: "1", Email: "new@example.com"}), repository.ErrExists)
+			expectErr(t, "Create with a taken email", s.Create(ctx, repository.User{ID: "3", Email: ann.Email}), repository.ErrExists)
+			u, _ := s.Get(ctx, "1")
+			expect(t, "Get after failed Creates", u, ann)
+		}},
+		{"update", func(t testing.TB) {
+			s := newStore(t)
+			expectErr(t, "Update missing", s.Update(ctx, ann), repository.ErrNotFound)
+			noErr(t, "Create", s.Create(ctx, ann))
+			noErr(t, "Create other", s.Create(ctx, bob))
+			renamed := ann
+			renamed.Name = "Anne"
+			noErr(t, "Update keeping the email", s.Update(ctx, renamed))
+			taken := ann
+			taken.Email = bob.Email
+			expectErr(t, "Update to a taken email", s.Update(ctx, taken), repository.ErrExists)
+			u, _ := s.Get(ctx, "1")
+			expect(t, "Get after Updates", u, renamed)
+		}},
+		{"delete", func(t testing.TB) {
+			s := newStore(t)
+			expectErr(t, "Delete missing", s.Delete(ctx, "1"), repository.ErrNotFound)
+			noErr(t, "Create", s.Create(ctx, ann))
+			noErr(t, "Delete", s.Delete(ctx, "1"))
+			_, err := s.ByEmail(ctx, ann.Email)
+			expectErr(t, "ByEmail after Delete", err, repository.ErrNotFound)
+			noErr(t, "Create with the email of a deleted user", s.Create(ctx, repository.User{ID: "3", Email: ann.Email}))
+		}},
+		{"transaction commits", func(t testing.TB) {
+			s := newStore(t)
+			noErr(t, "Create", s.Create(ctx, ann))
+			noErr(t, "WithTx", s.WithTx(ctx, func(users repository.UserRepository) error {
+				if err := users.Delete(ctx, ann.ID); err != nil {
+					return err
+				}
+				moved := bob
+				moved.Email = ann.Email
+				if err := users.Create(ctx, moved); err != nil {
+					return err
+				}
+				u, err := users.ByEmail(ctx, ann.Email)
+				noErr(t, "ByEmail inside the transaction", err)
+				expect(t, "ByEmail sees the transaction's own writes", u.ID, bob.ID)
+				return nil
+			}))
+			u, err := s.ByEmail(ctx, ann.Email)
+			noErr(t, "ByEmail after commit", err)
+			expect(t, "owner of the email after commit", u.ID, bob.ID)
+		}},
+		{"transaction rolls back on error", func(t testing.TB) {
+			s := newStore(t)
+			noErr(t, "Create", s.Create(ctx, ann))
+			err := s.WithTx(ctx, func(users repository.UserRepository) error {
+				noErr(t, "Create inside", users.Create(ctx, bob))
+				noErr(t, "Delete inside", users.Delete(ctx, ann.ID))
+				return errStop
+			})
+			expect(t, "WithTx returns fn's error", err, errStop)
+			_, err = s.Get(ctx, bob.ID)
+			expectErr(t, "Get of a rolled back Create", err, repository.ErrNotFound)
+			_, err = s.Get(ctx, ann.ID)
+			noErr(t, "Get of a rolled back Delete", err)
+		}},
+		{"transaction rolls back on panic", func(t testing.TB) {
+			s := newStore(t)
+			func() {
+				defer func() {
+					expect(t, "panic passed on", recover(), any("boom"))
+				}()
+				s.WithTx(ctx, func(users repository.UserRepository) error {
+					users.Create(ctx, ann)
+					panic("boom")
+				})
+			}()
+			_, err := s.Get(ctx, ann.ID)
+			expectErr(t, "Get of a Create before the panic", err, repository.ErrNotFound)
+			noErr(t, "Create after the panic", s.Create(ctx, ann))
+		}},
+	}
+}
+
+// RunUsersContract fails t unless stores from newStore keep the Users
+// contract.
+func RunUsersContract(t *testing.T, newStore UsersFactory) {
+	t.Helper()
+	Run(t, Users(newStore))
+}
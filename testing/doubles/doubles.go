@@ -0,0 +1,80 @@
+// Package doubles stands in for one interface, Mailer, four ways, and
+// checks one service, Announcer, with each:
+//
+//   - FakeMailer is a working mailer in memory, with the rules of a real
+//     one: the test sends, then looks at the outbox (state verification)
+//   - StubMailer is a function field: each test says what Send returns,
+//     and nothing else happens
+//   - SpyMailer records every call and passes it on: the test asserts on
+//     the calls afterwards, as many or as few as it cares about
+//   - MockMailer is told beforehand which calls to expect, in order, and
+//     fails the test on any other (interaction verification)
+//
+// They differ in what a test pins down. A fake lets the test ask what
+// the user would see, and survives any refactoring that keeps it; a mock
+// pins the exact conversation, so reordering two harmless calls breaks
+// it. Stubs and spies sit between: a stub for steering the code under
+// test into a branch, a spy for the one interaction that is the point,
+// such as "sent once, not twice".
+//
+// All four are written by hand, ten to forty lines each, against a
+// one-method interface; none needs a framework or generated code.
+// The tests run the same cases of Announcer in each style.
+package doubles
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+var (
+	// ErrTemporary marks a failure worth one retry.
+	ErrTemporary = errors.New("doubles: temporary failure")
+	// ErrRejected is a permanent failure for one address.
+	ErrRejected = errors.New("doubles: address rejected")
+)
+
+// Message is one mail.
+type Message struct {
+	To, Subject, Body string
+}
+
+// Mailer sends mail; errors wrapping ErrTemporary may succeed if retried.
+type Mailer interface {
+	Send(ctx context.Context, m Message) error
+}
+
+// Announcer is the code under test: it mails an announcement to a list.
+type Announcer struct {
+	mailer Mailer
+}
+
+func NewAnnouncer(m Mailer) *Announcer {
+	return &Announcer{mailer: m}
+}
+
+// Announce mails subject and body to each distinct address of to, in
+// order, retrying a temporary failure once. It stops at the first failure
+// it cannot retry and returns how many were sent.
+func (a *Announcer) Announce(ctx context.Context, to []string, subject, body string) (int, error) {
+	var seen []string
+	sent := 0
+	for _, addr := range to {
+		if slices.Contains(seen, addr) {
+			continue
+		}
+		seen = append(seen, addr)
+		m := Message{To: addr, Subject: subject, Body: body}
+		err := a.mailer.Send(ctx, m)
+		if errors.Is(err, ErrTemporary) {
+			err = a.mailer.Send(ctx, m)
+		}
+		if err != nil {
+			return sent, fmt.Errorf("announce to %s: %w", addr, err)
+		}
+		sent++
+	}
+	return sent, nil
+}
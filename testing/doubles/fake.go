@@ -0,0 +1,64 @@
+package doubles
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// fake
+// Level: Good
+// pros: tests assert on the outcome, the outbox, not on how the code got
+// there, so they survive refactoring; one fake serves every test and
+// encodes the real rules, invalid addresses included, once.
+// cons: it is a second implementation, which can drift from the real
+// mailer unless a contract checks both; failures must be set up as state
+// (Bounce) rather than scripted per call.
+//
+// FakeMailer delivers to an in-memory outbox; safe for concurrent use.
+type FakeMailer struct {
+	mu     sync.Mutex
+	outbox map[string][]Message
+	bounce map[string]error
+}
+
+func NewFakeMailer() *FakeMailer {
+	return &FakeMailer{outbox: map[string][]Message{}, bounce: map[string]error{}}
+}
+
+// Bounce makes sends to addr fail with err until Bounce(addr, nil).
+func (f *FakeMailer) Bounce(addr string, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err == nil {
+		delete(f.bounce, addr)
+		return
+	}
+	f.bounce[addr] = err
+}
+
+// Send delivers m, rejecting addresses without an @ as a mail server
+// would.
+func (f *FakeMailer) Send(ctx context.Context, m Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if !strings.Contains(m.To, "@") {
+		return fmt.Errorf("%q: %w", m.To, ErrRejected)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.bounce[m.To]; err != nil {
+		return err
+	}
+	f.outbox[m.To] = append(f.outbox[m.To], m)
+	return nil
+}
+
+// Inbox returns what was delivered to addr, oldest first.
+func (f *FakeMailer) Inbox(addr string) []Message {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Message(nil), f.outbox[addr]...)
+}
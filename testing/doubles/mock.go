@@ -0,0 +1,70 @@
+package doubles
+
+import (
+	"context"
+	"sync"
+
+	"patterns/testing/testoptions"
+)
+
+// mock
+// Level: Poor
+// pros: states the expected conversation up front and fails at the first
+// wrong call, pointing at it; the tool for protocols where the order of
+// calls is the behaviour.
+// cons: pins every call, its order and count, so a harmless change, such
+// as sending in another order, fails tests while nothing a user sees
+// differs; the expectations restate the implementation.
+//
+// MockMailer fails its test on any Send it was not told to expect. Send
+// must be called from the test's goroutine, as Fatalf must.
+type MockMailer struct {
+	t testoptions.T
+
+	mu       sync.Mutex
+	expected []expectation
+	next     int
+}
+
+type expectation struct {
+	to  string
+	err error
+}
+
+func NewMockMailer(t testoptions.T) *MockMailer {
+	return &MockMailer{t: t}
+}
+
+// Expect adds a Send to addr, after those already expected, returning err.
+func (m *MockMailer) Expect(addr string, err error) *MockMailer {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.expected = append(m.expected, expectation{addr, err})
+	return m
+}
+
+func (m *MockMailer) Send(ctx context.Context, msg Message) error {
+	m.t.Helper()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.next == len(m.expected) {
+		m.t.Fatalf("unexpected Send to %s after %d expected calls", msg.To, len(m.expected))
+	}
+	e := m.expected[m.next]
+	if msg.To != e.to {
+		m.t.Fatalf("Send %d went to %s, expected %s", m.next+1, msg.To, e.to)
+	}
+	m.next++
+	return e.err
+}
+
+// Verify fails the test unless every expected Send happened.
+func (m *MockMailer) Verify() {
+	m.t.Helper()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.next < len(m.expected) {
+		m.t.Fatalf("%d of %d expected Sends did not happen; next was to %s",
+			len(m.expected)-m.next, len(m.expected), m.expected[m.next].to)
+	}
+}
@@ -0,0 +1,40 @@
+package doubles
+
+import (
+	"context"
+	"sync"
+)
+
+// spy
+// Level: Good
+// pros: records everything and asserts nothing, so the test checks only
+// the interactions that matter, after the fact; wraps any Mailer, a fake
+// or a stub, to add the recording.
+// cons: asserting on calls couples the test to the conversation, as a
+// mock does, one assertion at a time.
+//
+// SpyMailer records each Send, then passes it to Next; a nil Next
+// succeeds. Safe for concurrent use.
+type SpyMailer struct {
+	Next Mailer
+
+	mu    sync.Mutex
+	calls []Message
+}
+
+func (s *SpyMailer) Send(ctx context.Context, m Message) error {
+	s.mu.Lock()
+	s.calls = append(s.calls, m)
+	s.mu.Unlock()
+	if s.Next == nil {
+		return nil
+	}
+	return s.Next.Send(ctx, m)
+}
+
+// Calls returns the messages sent so far, in order, retries included.
+func (s *SpyMailer) Calls() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Message(nil), s.calls...)
+}
@@ -0,0 +1,22 @@
+package doubles
+
+import "context"
+
+// function-field stub
+// Level: Good
+// pros: each test writes exactly the behaviour it needs, inline, such as
+// "fail the first call only"; no shared state between tests.
+// cons: it knows no rules, so a test can stub an impossible answer; and
+// tests that script every call drift toward mocks.
+//
+// StubMailer sends by calling SendFunc; a nil SendFunc succeeds.
+type StubMailer struct {
+	SendFunc func(ctx context.Context, m Message) error
+}
+
+func (s StubMailer) Send(ctx context.Context, m Message) error {
+	if s.SendFunc == nil {
+		return nil
+	}
+	return s.SendFunc(ctx, m)
+}
@@ -0,0 +1,197 @@
+package doubles_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"patterns/testing/doubles"
+	"patterns/testing/testoptions"
+)
+
+// announce is the call under test; reordered stands in for a refactoring
+// that changes the order of the sends but not their outcome.
+type announce func(a *doubles.Announcer, to []string) (int, error)
+
+func asWritten(a *doubles.Announcer, to []string) (int, error) {
+	return a.Announce(context.Background(), to, "launch", "we are live")
+}
+
+func reordered(a *doubles.Announcer, to []string) (int, error) {
+	return asWritten(a, slices.Sorted(slices.Values(to)))
+}
+
+var list = []string{"cy@example.com", "ann@example.com", "cy@example.com", "bo@example.com"}
+
+func expect[V comparable](t testoptions.T, what string, got, want V) {
+	t.Helper()
+	if got != want {
+		t.Fatalf("%s = %v, want %v", what, got, want)
+	}
+}
+
+type style struct {
+	name string
+	// distinct: every address gets the mail once
+	distinct, retry, permanent func(t testoptions.T, call announce)
+}
+
+var styles = []style{
+	{
+		name: "fake",
+		distinct: func(t testoptions.T, call announce) {
+			f := doubles.NewFakeMailer()
+			n, err := call(doubles.NewAnnouncer(f), list)
+			expect(t, "error", err, nil)
+			expect(t, "sent", n, 3)
+			for _, addr := range []string{"ann@example.com", "bo@example.com", "cy@example.com"} {
+				expect(t, "mails in the inbox of "+addr, len(f.Inbox(addr)), 1)
+			}
+		},
+		retry: func(t testoptions.T, call announce) {
+			// a fake fails by state, not per call: a bounce that lasts
+			// cannot show a retry that succeeds, only that it stops
+			f := doubles.NewFakeMailer()
+			f.Bounce("ann@example.com", doubles.ErrTemporary)
+			n, err := call(doubles.NewAnnouncer(f), list)
+			expect(t, "error is temporary", errors.Is(err, doubles.ErrTemporary), true)
+			expect(t, "sent before ann", n, 1)
+		},
+		permanent: func(t testoptions.T, call announce) {
+			f := doubles.NewFakeMailer()
+			n, err := call(doubles.NewAnnouncer(f), []string{"ann@example.com", "not-an-address", "bo@example.com"})
+			expect(t, "error is a rejection", errors.Is(err, doubles.ErrRejected), true)
+			expect(t, "sent", n, 1)
+			expect(t, "mails to bo after the failure", len(f.Inbox("bo@example.com")), 0)
+		},
+	},
+	{
+		name: "stub",
+		distinct: func(t testoptions.T, call announce) {
+			// a stub only answers; counting needs state of its own
+			got := map[string]int{}
+			stub := doubles.StubMailer{SendFunc: func(_ context.Context, m doubles.Message) error {
+				got[m.To]++
+				return nil
+			}}
+			n, err := call(doubles.NewAnnouncer(stub), list)
+			expect(t, "error", err, nil)
+			expect(t, "sent", n, 3)
+			expect(t, "sends to cy", got["cy@example.com"], 1)
+		},
+		retry: func(t testoptions.T, call announce) {
+			calls := 0
+			stub := doubles.StubMailer{SendFunc: func(_ context.Context, m doubles.Message) error {
+				calls++
+				if calls == 1 {
+					return doubles.ErrTemporary
+				}
+				return nil
+			}}
+			n, err := call(doubles.NewAnnouncer(stub), []string{"ann@example.com"})
+			expect(t, "error", err, nil)
+			expect(t, "sent", n, 1)
+		},
+		permanent: func(t testoptions.T, call announce) {
+			stub := doubles.StubMailer{SendFunc: func(_ context.Context, m doubles.Message) error {
+				if m.To == "ann@example.com" {
+					return doubles.ErrRejected
+				}
+				return nil
+			}}
+			n, err := call(doubles.NewAnnouncer(stub), []string{"ann@example.com", "bo@example.com"})
+			expect(t, "error is a rejection", errors.Is(err, doubles.ErrRejected), true)
+			expect(t, "sent", n, 0)
+		},
+	},
+	{
+		name: "spy",
+		distinct: func(t testoptions.T, call announce) {
+			spy := &doubles.SpyMailer{}
+			call(doubles.NewAnnouncer(spy), list)
+			per := map[string]int{}
+			for _, m := range spy.Calls() {
+				per[m.To]++
+				expect(t, "subject", m.Subject, "launch")
+			}
+			expect(t, "addresses mailed", len(per), 3)
+			expect(t, "sends to cy", per["cy@example.com"], 1)
+		},
+		retry: func(t testoptions.T, call announce) {
+			first := true
+			spy := &doubles.SpyMailer{Next: doubles.StubMailer{SendFunc: func(context.Context, doubles.Message) error {
+				if first {
+					first = false
+					return doubles.ErrTemporary
+				}
+				return nil
+			}}}
+			_, err := call(doubles.NewAnnouncer(spy), []string{"ann@example.com"})
+			expect(t, "error", err, nil)
+			expect(t, "sends, the retry included", len(spy.Calls()), 2)
+		},
+		permanent: func(t testoptions.T, call announce) {
+			spy := &doubles.SpyMailer{Next: doubles.NewFakeMailer()}
+			call(doubles.NewAnnouncer(spy), []string{"not-an-address", "bo@example.com"})
+			expect(t, "sends, none retried", len(spy.Calls()), 1)
+		},
+	},
+	{
+		name: "mock",
+		distinct: func(t testoptions.T, call announce) {
+			m := doubles.NewMockMailer(t).
+				Expect("cy@example.com", nil).
+				Expect("ann@example.com", nil).
+				Expect("bo@example.com", nil)
+			n, err := call(doubles.NewAnnouncer(m), list)
+			expect(t, "error", err, nil)
+			expect(t, "sent", n, 3)
+			m.Verify()
+		},
+		retry: func(t testoptions.T, call announce) {
+			m := doubles.NewMockMailer(t).
+				Expect("ann@example.com", doubles.ErrTemporary).
+				Expect("ann@example.com", nil)
+			_, err := call(doubles.NewAnnouncer(m), []string{"ann@example.com"})
+			expect(t, "error", err, nil)
+			m.Verify()
+		},
+		permanent: func(t testoptions.T, call announce) {
+			m := doubles.NewMockMailer(t).Expect("ann@example.com", doubles.ErrRejected)
+			_, err := call(doubles.NewAnnouncer(m), []string{"ann@example.com", "bo@example.com"})
+			expect(t, "error is a rejection", errors.Is(err, doubles.ErrRejected), true)
+			m.Verify()
+		},
+	},
+}
+
+// brittle are the styles whose distinct case is expected to fail on the
+// reordered caller.
+var brittle = map[string]bool{"mock": true}
+
+func TestStyles(t *testing.T) {
+	for _, s := range styles {
+		t.Run(s.name, func(t *testing.T) {
+			t.Run("distinct", func(t *testing.T) { s.distinct(t, asWritten) })
+			t.Run("retry", func(t *testing.T) { s.retry(t, asWritten) })
+			t.Run("permanent", func(t *testing.T) { s.permanent(t, asWritten) })
+		})
+	}
+}
+
+// TestReordered runs the distinct case again against a caller that sends
+// in another order, to show which styles notice a change no user would.
+func TestReordered(t *testing.T) {
+	for _, s := range styles {
+		t.Run(s.name, func(t *testing.T) {
+			err := testoptions.Run(func(t testoptions.T) { s.distinct(t, reordered) })
+			if (err != nil) != brittle[s.name] {
+				t.Fatalf("got %v, want failure = %v", err, brittle[s.name])
+			}
+			if err != nil {
+				t.Logf("failed as expected: %v", err)
+			}
+		})
+	}
+}
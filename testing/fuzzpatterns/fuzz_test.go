@@ -0,0 +1,134 @@
+package fuzzpatterns_test
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"patterns/randsource"
+	"patterns/testing/fuzzpatterns"
+)
+
+// FuzzDecode checks CheckDecode on the strict codec, seeded with Seeds
+// and the corpus in testdata/fuzz/FuzzDecode:
+//
+//	go test -fuzz ^FuzzDecode$ patterns/testing/fuzzpatterns
+func FuzzDecode(f *testing.F) {
+	for _, s := range fuzzpatterns.Seeds {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		if err := fuzzpatterns.CheckDecode(fuzzpatterns.Strict, data); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+// FuzzRoundTrip checks CheckRoundTrip on the strict codec. The engine
+// mutates the four strings directly, which reaches escapes sooner than
+// mutating encoded bytes would.
+func FuzzRoundTrip(f *testing.F) {
+	f.Add("name", "ada", "note", "a;b=c")
+	f.Add("k=ey", "", `C:\tmp`, "x")
+	f.Fuzz(func(t *testing.T, k1, v1, k2, v2 string) {
+		if err := fuzzpatterns.CheckRoundTrip(fuzzpatterns.Strict, fuzzpatterns.Record{{k1, v1}, {k2, v2}}); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+// n is the inputs the tests without a fuzzing engine try per property.
+const n = 20000
+
+// corpus is the directory go test reads the seeds of FuzzDecode from.
+var corpus = filepath.Join("testdata", "fuzz", "FuzzDecode")
+
+func seeds(t *testing.T) [][]byte {
+	found, err := fuzzpatterns.ReadCorpus(corpus)
+	if err != nil {
+		t.Fatalf("reading the corpus: %v", err)
+	}
+	return append(slices.Clone(fuzzpatterns.Seeds), found...)
+}
+
+func decoder(c fuzzpatterns.Codec) func([]byte) error {
+	return func(data []byte) error { return fuzzpatterns.CheckDecode(c, data) }
+}
+
+func roundTripper(c fuzzpatterns.Codec) func(fuzzpatterns.Record) error {
+	return func(r fuzzpatterns.Record) error { return fuzzpatterns.CheckRoundTrip(c, r) }
+}
+
+// TestSearchStrict runs CheckDecode on mutations of the seeds with Search.
+// A failure is shrunk and written to the corpus, where go test replays it
+// from then on.
+func TestSearchStrict(t *testing.T) {
+	decode := decoder(fuzzpatterns.Strict)
+	crasher, tried, err := fuzzpatterns.Search(randsource.New(1), seeds(t), n, decode)
+	if err != nil {
+		small := fuzzpatterns.Minimize(crasher, decode)
+		path, werr := fuzzpatterns.WriteCorpus(corpus, small)
+		t.Fatalf("after %d mutations: %v; shrunk to %q, written to %s (%v)", tried, decode(small), small, path, werr)
+	}
+}
+
+// TestSearchCatchesNaive checks that Search finds the naive codec's bugs
+// and Minimize shrinks them to a few bytes: the invalid seeds catch it at
+// once, mutations of a valid one take a little longer.
+func TestSearchCatchesNaive(t *testing.T) {
+	decode := decoder(fuzzpatterns.Naive)
+	crasher, tried, err := fuzzpatterns.Search(randsource.New(1), seeds(t), n, decode)
+	if err == nil {
+		t.Fatalf("%d mutations found nothing", tried)
+	}
+	if small := fuzzpatterns.Minimize(crasher, decode); decode(small) == nil || len(small) > 4 {
+		t.Errorf("shrunk %q to %q", crasher, small)
+	}
+
+	crasher, tried, err = fuzzpatterns.Search(randsource.New(1), [][]byte{[]byte("a=1")}, n, decode)
+	if err == nil {
+		t.Fatalf("%d mutations of one valid seed found nothing", tried)
+	}
+	t.Logf("after %d mutations, on %q, shrunk to %q", tried, crasher, fuzzpatterns.Minimize(crasher, decode))
+}
+
+// TestRoundTripGenerated is the property-based half: CheckRoundTrip on
+// records from Generate, which knows the structure the mutator does not.
+func TestRoundTripGenerated(t *testing.T) {
+	for _, c := range []fuzzpatterns.Codec{fuzzpatterns.Strict, fuzzpatterns.Naive} {
+		t.Run(c.Name, func(t *testing.T) {
+			roundTrip := roundTripper(c)
+			rnd := randsource.New(1)
+			var bad fuzzpatterns.Record
+			i := 0
+			for ; i < n && bad == nil; i++ {
+				if r := fuzzpatterns.Generate(rnd); roundTrip(r) != nil {
+					bad = fuzzpatterns.MinimizeRecord(r, roundTrip)
+				}
+			}
+			if c.Name == fuzzpatterns.Strict.Name {
+				if bad != nil {
+					t.Fatalf("after %d records, shrunk to %v: %v", i, bad, roundTrip(bad))
+				}
+				return
+			}
+			err := roundTrip(bad)
+			if err == nil || len(bad) != 1 || len(bad[0].Key)+len(bad[0].Value) > 2 {
+				t.Fatalf("after %d records, shrunk to %v: %v", i, bad, err)
+			}
+			t.Logf("after %d records, shrunk to %v: %v", i, bad, err)
+		})
+	}
+}
+
+func TestCorpusRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	want := []byte("a=\\;\x00\xff\n")
+	if _, err := fuzzpatterns.WriteCorpus(dir, want); err != nil {
+		t.Fatal(err)
+	}
+	got, err := fuzzpatterns.ReadCorpus(dir)
+	if err != nil || len(got) != 1 || string(got[0]) != string(want) {
+		t.Fatalf("read back %q, %v; want %q", got, err, want)
+	}
+}
@@ -0,0 +1,31 @@
+package fuzzpatterns
+
+import "strings"
+
+// split-based parser
+// Level: Poor
+// pros: four lines, and right for every input its author thought of.
+// cons: no escapes, so a value with ';' or '=' comes back different; a
+// field without '=' indexes past the end and panics.
+//
+// NaiveDecode parses data by splitting on ';' and then '='.
+func NaiveDecode(data []byte) (Record, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+	var r Record
+	for _, f := range strings.Split(string(data), ";") {
+		kv := strings.SplitN(f, "=", 2)
+		r = append(r, Field{kv[0], kv[1]})
+	}
+	return r, nil
+}
+
+// NaiveEncode joins the fields without escaping them.
+func NaiveEncode(r Record) []byte {
+	fields := make([]string, len(r))
+	for i, f := range r {
+		fields[i] = f.Key + "=" + f.Value
+	}
+	return []byte(strings.Join(fields, ";"))
+}
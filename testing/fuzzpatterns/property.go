@@ -0,0 +1,113 @@
+package fuzzpatterns
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+
+	"patterns/randsource"
+)
+
+// Codec is an encoder and parser pair to check.
+type Codec struct {
+	Name   string
+	Encode func(Record) []byte
+	Decode func([]byte) (Record, error)
+}
+
+var (
+	Strict = Codec{"strict", Encode, Decode}
+	Naive  = Codec{"naive", NaiveEncode, NaiveDecode}
+)
+
+// Seeds is the seed corpus: one input per rule of the format, valid and
+// not, so mutations start next to every branch of Decode.
+var Seeds = [][]byte{
+	[]byte(""),
+	[]byte("a=1"),
+	[]byte("name=ada;note=a\\;b\\=c;path=C:\\\\tmp"),
+	[]byte("k\\=ey=;=v"),
+	[]byte("a=1;"),
+	[]byte("a"),
+	[]byte("a=b=c"),
+	[]byte("a=\\x"),
+	[]byte("a=\\"),
+}
+
+// panicError is a panic of Decode, which no input may cause.
+type panicError struct{ v any }
+
+func (e panicError) Error() string { return fmt.Sprintf("panic: %v", e.v) }
+
+// decode calls c.Decode, turning a panic into a panicError.
+func (c Codec) decode(data []byte) (r Record, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = panicError{p}
+		}
+	}()
+	return c.Decode(data)
+}
+
+// CheckDecode checks c on any input: decoding never panics, and a record
+// it accepts encodes back to exactly data and decodes again to itself.
+// Rejecting data is fine; that is what most random bytes are.
+func CheckDecode(c Codec, data []byte) error {
+	r, err := c.decode(data)
+	if _, ok := err.(panicError); ok {
+		return fmt.Errorf("%s: decoding %q: %v", c.Name, data, err)
+	}
+	if err != nil {
+		return nil
+	}
+	enc := c.Encode(r)
+	if !bytes.Equal(enc, data) {
+		return fmt.Errorf("%s: %q decodes to %v, which encodes to %q", c.Name, data, r, enc)
+	}
+	again, err := c.decode(enc)
+	if err != nil || !slices.Equal(again, r) {
+		return fmt.Errorf("%s: %q decodes to %v, then to %v, %v", c.Name, data, r, again, err)
+	}
+	return nil
+}
+
+// CheckRoundTrip checks c on any valid record: its encoding decodes back
+// to it. Invalid records are skipped, like a property's precondition.
+func CheckRoundTrip(c Codec, r Record) error {
+	if r.Validate() != nil {
+		return nil
+	}
+	enc := c.Encode(r)
+	got, err := c.decode(enc)
+	if err != nil {
+		return fmt.Errorf("%s: %v encodes to %q, which does not decode: %v", c.Name, r, enc, err)
+	}
+	if !slices.Equal(got, r) {
+		return fmt.Errorf("%s: %v encodes to %q, which decodes to %v", c.Name, r, enc, got)
+	}
+	return nil
+}
+
+// alphabet is what generated keys and values are made of: the special
+// bytes, weighted up, and a few ordinary ones, including a multi-byte
+// rune and a NUL.
+const alphabet = `ab1 é` + "\x00" + `\;=\;=`
+
+// Generate returns a random valid record of up to four fields, for
+// CheckRoundTrip: the property-based half, where the generator knows the
+// structure the mutator of Search does not.
+func Generate(r randsource.Rand) Record {
+	str := func(min int) string {
+		n := min + r.IntN(6)
+		b := make([]byte, 0, n)
+		for range n {
+			b = append(b, alphabet[r.IntN(len(alphabet))])
+		}
+		return string(b)
+	}
+	rec := make(Record, r.IntN(5))
+	for i := range rec {
+		rec[i] = Field{str(1), str(0)}
+	}
+	return rec
+}
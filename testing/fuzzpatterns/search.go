@@ -0,0 +1,183 @@
+package fuzzpatterns
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"patterns/randsource"
+)
+
+// dictionary holds the tokens of the format. A blind mutator inserts them
+// as a whole; a coverage-guided engine such as go test -fuzz finds them
+// itself, from the comparisons in Decode.
+var dictionary = [][]byte{[]byte(";"), []byte("="), []byte(`\`), []byte(`\;`), []byte(`\=`), []byte(`\\`), []byte("a=b")}
+
+// Mutate returns a copy of data with one to four random edits: a byte
+// flipped, inserted or dropped, a token of the format inserted, or a span
+// duplicated.
+func Mutate(r randsource.Rand, data []byte) []byte {
+	b := bytes.Clone(data)
+	for range 1 + r.IntN(4) {
+		at := 0
+		if len(b) > 0 {
+			at = r.IntN(len(b) + 1)
+		}
+		switch op := r.IntN(5); {
+		case op == 0 && at < len(b):
+			b[at] ^= 1 << r.IntN(8)
+		case op == 1:
+			b = slices.Insert(b, at, byte(r.IntN(256)))
+		case op == 2 && at < len(b):
+			b = append(b[:at], b[at+1:]...)
+		case op == 3:
+			b = slices.Insert(b, at, dictionary[r.IntN(len(dictionary))]...)
+		case len(b) > 0:
+			from := r.IntN(len(b))
+			n := 1 + r.IntN(min(8, len(b)-from))
+			b = slices.Insert(b, at, bytes.Clone(b[from:from+n])...)
+		}
+	}
+	if len(b) > MaxLen {
+		b = b[:MaxLen]
+	}
+	return b
+}
+
+// Search runs check on n mutations of the seeds and returns the first
+// input it fails, the number of inputs tried and the error; the input is
+// nil if none failed. Without coverage it explores far less than go test
+// -fuzz does; it is enough for shallow bugs, and needs no test binary.
+func Search(r randsource.Rand, seeds [][]byte, n int, check func([]byte) error) ([]byte, int, error) {
+	for _, s := range seeds {
+		if err := check(s); err != nil {
+			return s, 0, err
+		}
+	}
+	for i := range n {
+		data := Mutate(r, seeds[r.IntN(len(seeds))])
+		if err := check(data); err != nil {
+			return data, i + 1, err
+		}
+	}
+	return nil, n, nil
+}
+
+// Minimize shrinks an input check fails while it keeps failing: it drops
+// spans, halving their length down to single bytes, until no single
+// byte can go. A crasher of a few bytes shows the bug; one of a few
+// hundred hides it.
+func Minimize(data []byte, check func([]byte) error) []byte {
+	for span := max(1, len(data)/2); ; span /= 2 {
+		for at := 0; at+span <= len(data); {
+			smaller := append(bytes.Clone(data[:at]), data[at+span:]...)
+			if check(smaller) != nil {
+				data = smaller
+				continue
+			}
+			at++
+		}
+		if span == 1 {
+			return data
+		}
+	}
+}
+
+// MinimizeRecord shrinks a record check fails: fewer fields, then
+// shorter keys and values, one byte at a time.
+func MinimizeRecord(r Record, check func(Record) error) Record {
+	for changed := true; changed; {
+		changed = false
+		try := func(smaller Record) bool {
+			if check(smaller) == nil {
+				return false
+			}
+			r, changed = smaller, true
+			return true
+		}
+		for i := 0; i < len(r); i++ {
+			if try(slices.Delete(slices.Clone(r), i, i+1)) {
+				i--
+			}
+		}
+		for i := range r {
+			for j := 0; j < len(r[i].Key); j++ {
+				smaller := slices.Clone(r)
+				smaller[i].Key = r[i].Key[:j] + r[i].Key[j+1:]
+				if try(smaller) {
+					j--
+				}
+			}
+			for j := 0; j < len(r[i].Value); j++ {
+				smaller := slices.Clone(r)
+				smaller[i].Value = r[i].Value[:j] + r[i].Value[j+1:]
+				if try(smaller) {
+					j--
+				}
+			}
+		}
+	}
+	return r
+}
+
+const corpusHeader = "go test fuzz v1\n"
+
+// ReadCorpus reads the inputs of a go test corpus directory, such as
+// testdata/fuzz/FuzzDecode, whose files hold one []byte each.
+func ReadCorpus(dir string) ([][]byte, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var inputs [][]byte
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		raw, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		data, err := parseCorpusFile(string(raw))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", e.Name(), err)
+		}
+		inputs = append(inputs, data)
+	}
+	return inputs, nil
+}
+
+func parseCorpusFile(s string) ([]byte, error) {
+	body, ok := strings.CutPrefix(s, corpusHeader)
+	if !ok {
+		return nil, errors.New("not a go test fuzz v1 file")
+	}
+	lit, ok := strings.CutPrefix(strings.TrimSpace(body), "[]byte(")
+	if !ok || !strings.HasSuffix(lit, ")") {
+		return nil, errors.New("not a single []byte")
+	}
+	q, err := strconv.Unquote(strings.TrimSuffix(lit, ")"))
+	if err != nil {
+		return nil, err
+	}
+	return []byte(q), nil
+}
+
+// WriteCorpus adds data to a go test corpus directory, named, as go test
+// names them, by its hash, and returns the file's path; go test -run
+// replays it from then on.
+func WriteCorpus(dir string, data []byte) (string, error) {
+	content := corpusHeader + "[]byte(" + strconv.Quote(string(data)) + ")\n"
+	name := fmt.Sprintf("%x", sha256.Sum256([]byte(content)))[:16]
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, name)
+	return path, os.WriteFile(path, []byte(content), 0o644)
+}
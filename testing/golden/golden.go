@@ -0,0 +1,149 @@
+// Package golden compares output with a file checked in next to the
+// test, so a test of a renderer states what the output is, in full,
+// instead of asserting on pieces of it:
+//
+//	func TestDump(t *testing.T) {
+//		for _, c := range cases {
+//			t.Run(c.name, func(t *testing.T) {
+//				var b bytes.Buffer
+//				configdump.Dump(&b, c.config, c.format)
+//				golden.Assert(t, "dump/"+c.name, b.Bytes())
+//			})
+//		}
+//	}
+//
+// Golden files live under Dir, testdata by default, which go test runs
+// in the package directory and go build ignores. When the output changes
+// on purpose, run with -update to rewrite them, and review the change in
+// the diff of the commit:
+//
+//	go test . -run TestDump -update
+//
+// Comparison is exact but for line endings, so a checkout that turns \n
+// into \r\n does not fail every test. The tests of this package run
+// table-driven golden cases for configdump, with their files in
+// testdata/configdump, and check that a change, a missing file and
+// -update are each reported:
+//
+//	go test patterns/testing/golden [-update]
+package golden
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"patterns/testing/testoptions"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files with the output under test")
+
+// Dir is the directory golden files are read from and written to. go
+// test runs in the package directory, so testdata is that package's; a
+// harness run from elsewhere sets it.
+var Dir = "testdata"
+
+// maxDiff is the most lines of a diff Assert prints.
+const maxDiff = 40
+
+// golden file
+// Level: Good
+// pros: the whole output is reviewed, in a file, and a change to it
+// shows as a diff in the commit; -update rewrites every file at once.
+// cons: -update accepts whatever the code now prints, so a golden file
+// is only as good as the review of its diff; output with times or map
+// order must be made deterministic first.
+//
+// Assert fails t unless got is the content of the golden file name, a
+// slash-separated path under Dir without its .golden suffix. With
+// -update it writes got to the file instead, creating directories as
+// needed, and logs that it did.
+func Assert(t testoptions.T, name string, got []byte) {
+	t.Helper()
+	path, err := Path(name)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("golden: %v", err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatalf("golden: %v", err)
+		}
+		t.Logf("golden: updated %s", path)
+		return
+	}
+	want, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("golden: %s does not exist; run with -update to create it", path)
+	}
+	if err != nil {
+		t.Fatalf("golden: %v", err)
+	}
+	want = bytes.ReplaceAll(want, []byte("\r\n"), []byte("\n"))
+	if !bytes.Equal(got, want) {
+		t.Fatalf("golden: %s differs; run with -update if the change is intended\n%s", path, diff(string(want), string(got)))
+	}
+}
+
+// Path returns the file of the golden name; a name that is empty,
+// absolute or escapes Dir is an error.
+func Path(name string) (string, error) {
+	if name == "" || !filepath.IsLocal(filepath.FromSlash(name)) {
+		return "", fmt.Errorf("golden: name %q is not a relative path inside %s", name, Dir)
+	}
+	return filepath.Join(Dir, filepath.FromSlash(name)+".golden"), nil
+}
+
+// diff returns the lines that differ between want and got, past the
+// lines they begin and end with in common: "-" lines are want's, "+"
+// lines got's, under the line number they start at. It is no minimal
+// diff, but output that changed in one place reads as one hunk.
+func diff(want, got string) string {
+	w, g := lines(want), lines(got)
+	start := 0
+	for start < len(w) && start < len(g) && w[start] == g[start] {
+		start++
+	}
+	end := 0
+	for end < len(w)-start && end < len(g)-start && w[len(w)-1-end] == g[len(g)-1-end] {
+		end++
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "@@ line %d @@\n", start+1)
+	n := 0
+	for _, hunk := range []struct {
+		sign  string
+		lines []string
+	}{{"-", w[start : len(w)-end]}, {"+", g[start : len(g)-end]}} {
+		for _, l := range hunk.lines {
+			if n == maxDiff {
+				fmt.Fprintf(&b, "... %d more lines\n", len(w)+len(g)-2*(start+end)-n)
+				return b.String()
+			}
+			n++
+			b.WriteString(hunk.sign + " ")
+			if s, ok := strings.CutSuffix(l, "\n"); ok {
+				fmt.Fprintf(&b, "%s\n", s)
+			} else {
+				fmt.Fprintf(&b, "%s\n\\ no newline at end\n", s)
+			}
+		}
+	}
+	return b.String()
+}
+
+// lines splits s after each newline, with no empty line after the last.
+func lines(s string) []string {
+	l := strings.SplitAfter(s, "\n")
+	if l[len(l)-1] == "" {
+		l = l[:len(l)-1]
+	}
+	return l
+}
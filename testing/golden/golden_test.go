@@ -0,0 +1,158 @@
+package golden_test
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"patterns/configdump"
+	"patterns/testing/golden"
+	"patterns/testing/testoptions"
+)
+
+type backend struct {
+	URL    string  `json:"url"`
+	Weight float64 `json:"weight"`
+}
+
+type tlsConfig struct {
+	CertFile string `json:"cert_file"`
+	KeyFile  string `json:"key_file" secret:"true"`
+}
+
+type config struct {
+	Addr     string         `json:"addr"`
+	Timeout  time.Duration  `json:"timeout"`
+	Password string         `json:"password" secret:"true"`
+	Debug    bool           `json:"debug"`
+	Tags     []string       `json:"tags"`
+	Limits   map[string]int `json:"limits"`
+	TLS      *tlsConfig     `json:"tls"`
+	Backends []backend      `json:"backends"`
+}
+
+var full = config{
+	Addr:     "0.0.0.0:8443",
+	Timeout:  1500 * time.Millisecond,
+	Password: "hunter2",
+	Debug:    true,
+	Tags:     []string{"edge", "eu-west"},
+	Limits:   map[string]int{"rps": 200, "burst": 50},
+	TLS:      &tlsConfig{CertFile: "/etc/tls/cert.pem", KeyFile: "/etc/tls/key.pem"},
+	Backends: []backend{{"http://10.0.0.1:80", 0.75}, {"http://10.0.0.2:80", 0.25}},
+}
+
+// cases is the table: a name, used for the golden file, and the input.
+// A new case is a row here and a run with -update.
+var cases = []struct {
+	name   string
+	config any
+}{
+	{"zero", config{}},
+	{"full", full},
+	// strings YAML would read as something else are quoted
+	{"quoting", config{Addr: ":80", Tags: []string{"yes", "0755", "- dash", "", " padded", "a#b"}, Limits: map[string]int{"a b": 1}}},
+	// dumps the same as zero: nil and empty collections are not told apart
+	{"empty collections", config{Tags: []string{}, Limits: map[string]int{}, Backends: []backend{}}},
+	{"map", map[string]any{"b": []int{1, 2}, "a": nil, "c": map[string]bool{}}},
+}
+
+// TestDump dumps each configuration in every format and compares it with
+// testdata/configdump; -update rewrites the files instead.
+func TestDump(t *testing.T) {
+	for _, c := range cases {
+		for _, f := range []configdump.Format{configdump.JSON, configdump.YAML} {
+			name := strings.ReplaceAll(c.name, " ", "-") + "." + string(f)
+			t.Run(name, func(t *testing.T) {
+				var b bytes.Buffer
+				if err := configdump.Dump(&b, c.config, f); err != nil {
+					t.Fatalf("dump: %v", err)
+				}
+				if strings.Contains(b.String(), "hunter2") || strings.Contains(b.String(), "key.pem") {
+					t.Fatalf("a secret is in the dump:\n%s", &b)
+				}
+				golden.Assert(t, "configdump/"+name, b.Bytes())
+			})
+		}
+	}
+}
+
+// helper skips t under -update, where Assert writes instead of failing.
+func helper(t *testing.T) {
+	if flag.Lookup("update").Value.String() == "true" {
+		t.Skip("checks Assert failing, which -update turns off")
+	}
+}
+
+// tempDir points golden.Dir at a new directory for the rest of t.
+func tempDir(t *testing.T) {
+	dir := golden.Dir
+	golden.Dir = t.TempDir()
+	t.Cleanup(func() { golden.Dir = dir })
+}
+
+func TestChangeIsReportedAsADiff(t *testing.T) {
+	helper(t)
+	changed := full
+	changed.Addr = "0.0.0.0:9443"
+	var b bytes.Buffer
+	configdump.Dump(&b, changed, configdump.YAML)
+	err := testoptions.Run(func(t testoptions.T) { golden.Assert(t, "configdump/full.yaml", b.Bytes()) })
+	want := "@@ line 1 @@\n- addr: \"0.0.0.0:8443\"\n+ addr: \"0.0.0.0:9443\"\n"
+	if err == nil || !strings.HasSuffix(err.Error(), want) {
+		t.Fatalf("got %v, want a diff ending\n%s", err, want)
+	}
+}
+
+func TestMissingFileNamesUpdate(t *testing.T) {
+	helper(t)
+	err := testoptions.Run(func(t testoptions.T) { golden.Assert(t, "configdump/absent", nil) })
+	if err == nil || !strings.Contains(err.Error(), "does not exist; run with -update") {
+		t.Fatalf("got %v", err)
+	}
+}
+
+func TestNameOutsideTheDirectory(t *testing.T) {
+	for _, name := range []string{"", "../escape", "/abs"} {
+		err := testoptions.Run(func(t testoptions.T) { golden.Assert(t, name, nil) })
+		if err == nil || !strings.Contains(err.Error(), "is not a relative path") {
+			t.Errorf("%q: got %v", name, err)
+		}
+	}
+}
+
+func TestUpdateWritesAFileTheNextRunPasses(t *testing.T) {
+	helper(t)
+	tempDir(t)
+	got := []byte("one\ntwo\n")
+	flag.Set("update", "true")
+	err := testoptions.Run(func(t testoptions.T) { golden.Assert(t, "new/case", got) })
+	flag.Set("update", "false")
+	if err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if b, err := os.ReadFile(filepath.Join(golden.Dir, "new", "case.golden")); err != nil || !bytes.Equal(b, got) {
+		t.Fatalf("wrote %q, %v", b, err)
+	}
+	if err := testoptions.Run(func(t testoptions.T) { golden.Assert(t, "new/case", got) }); err != nil {
+		t.Fatalf("after update: %v", err)
+	}
+}
+
+// \r\n line endings match; a missing final newline does not.
+func TestLineEndings(t *testing.T) {
+	helper(t)
+	tempDir(t)
+	os.WriteFile(filepath.Join(golden.Dir, "crlf.golden"), []byte("one\r\ntwo\r\n"), 0o644)
+	if err := testoptions.Run(func(t testoptions.T) { golden.Assert(t, "crlf", []byte("one\ntwo\n")) }); err != nil {
+		t.Fatalf("%v", err)
+	}
+	err := testoptions.Run(func(t testoptions.T) { golden.Assert(t, "crlf", []byte("one\ntwo")) })
+	if want := "- two\n+ two\n\\ no newline at end\n"; err == nil || !strings.HasSuffix(err.Error(), want) {
+		t.Fatalf("got %v, want a diff ending\n%s", err, want)
+	}
+}
@@ -0,0 +1,85 @@
+package sched
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Setup adds the tasks of one run to s and returns the check of the
+// outcome, called once they have all finished.
+type Setup func(s *Scheduler) (check func() error)
+
+// Failure is a run that went wrong, with the schedule to replay it.
+type Failure struct {
+	Seed    uint64 // for Stress; zero for Exhaust
+	Choices []int
+	Trace   []string
+	Err     error
+}
+
+func (f *Failure) Error() string {
+	msg := fmt.Sprintf("%v\nschedule %v (Replay), steps %s", f.Err, f.Choices, strings.Join(f.Trace, " "))
+	if f.Seed != 0 {
+		msg = fmt.Sprintf("seed %d: %s", f.Seed, msg)
+	}
+	return msg
+}
+
+func (f *Failure) Unwrap() error { return f.Err }
+
+// Once runs setup under s and checks the outcome.
+func Once(s *Scheduler, setup Setup) error {
+	check := setup(s)
+	err := s.Run()
+	if err == nil {
+		err = check()
+	}
+	if err != nil {
+		return &Failure{Choices: s.Choices(), Trace: s.Trace(), Err: err}
+	}
+	return nil
+}
+
+// Stress runs setup under seeds 1 to n and returns the first *Failure.
+func Stress(n int, setup Setup) error {
+	for seed := uint64(1); seed <= uint64(n); seed++ {
+		if err := Once(New(seed), setup); err != nil {
+			f := err.(*Failure)
+			f.Seed = seed
+			return f
+		}
+	}
+	return nil
+}
+
+// Exhaust runs setup under every schedule in turn, depth first, up to
+// limit runs, and returns the first *Failure; nil means none failed. It
+// is for small tests: schedules multiply with every task and yield
+// point.
+func Exhaust(limit int, setup Setup) error {
+	var prefix []int
+	for range limit {
+		// Replay follows prefix and then takes the first task, so the
+		// choices it records are prefix extended by zeros
+		s := Replay(prefix)
+		var ns []int // how many tasks were runnable at each choice
+		choose := s.choose
+		s.choose = func(n int) int {
+			ns = append(ns, n)
+			return choose(n)
+		}
+		if err := Once(s, setup); err != nil {
+			return err
+		}
+		chosen := s.Choices()
+		i := len(chosen) - 1
+		for i >= 0 && chosen[i]+1 >= ns[i] {
+			i--
+		}
+		if i < 0 {
+			return nil
+		}
+		prefix = append(chosen[:i:i], chosen[i]+1)
+	}
+	return nil
+}
@@ -0,0 +1,268 @@
+// Package sched runs concurrent code one task at a time, switching only
+// at explicit yield points, so an interleaving is a value: it can be
+// chosen, logged, replayed, and varied until a bad one turns up.
+//
+// A task is a function given a context that carries it; the code under
+// test marks where another task could get in with Yield, and blocks only
+// through Wait:
+//
+//	func (c *Counter) Inc(ctx context.Context) {
+//		v := c.n
+//		sched.Yield(ctx) // another Inc may run here
+//		c.n = v + 1
+//	}
+//
+// Outside a scheduler, with a context that carries no task, Yield does
+// nothing and Wait polls, so the same code runs unchanged in production;
+// the points are free.
+//
+// Under one, each task runs alone from one yield point to the next, and
+// a seed decides which runnable task goes next:
+//
+//	err := sched.Stress(1000, func(s *sched.Scheduler) func() error {
+//		c := &Counter{}
+//		s.Go("a", c.Inc)
+//		s.Go("b", c.Inc)
+//		return func() error {
+//			if c.n != 2 {
+//				return fmt.Errorf("n = %d, want 2", c.n)
+//			}
+//			return nil
+//		}
+//	})
+//
+// Stress tries seeds; Exhaust walks every schedule of a small test in
+// turn. Either way a failure carries the schedule that produced it, and
+// Replay runs exactly that one again, under a debugger if need be.
+//
+// The scheduler sees only the yield points: it explores interleavings
+// at those, not the ones the Go memory model allows between them, so it
+// finds ordering bugs (check-then-act, lost wake-ups, orders that
+// deadlock), not data races, which are the race detector's. Code under
+// it must block only in Wait, not on a mutex or channel held by a paused
+// task; Run reports a task that does as stuck.
+package sched
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"time"
+
+	"patterns/randsource"
+)
+
+// ErrDeadlock is returned by Run when every task left is waiting.
+var ErrDeadlock = errors.New("sched: deadlock")
+
+// StuckAfter is how long Run waits for a task to reach its next yield
+// point before reporting it blocked outside the scheduler.
+var StuckAfter = 5 * time.Second
+
+type ctxKey struct{}
+
+type task struct {
+	s       *Scheduler
+	name    string
+	resume  chan struct{}
+	cond    func() bool // while waiting
+	done    bool
+	aborted bool
+}
+
+// event is a task handing control back: at a yield point, or finished.
+type event struct {
+	t     *task
+	done  bool
+	panic any
+}
+
+// deterministic scheduler
+// Level: Good
+// pros: an interleaving is reproducible from a seed or a schedule; a
+// stress run covers orders that the real scheduler almost never picks;
+// the yield points cost nothing in production.
+// cons: only interleavings at the marked points are explored; code under
+// test must block through Wait, not mutexes or channels.
+//
+// Scheduler runs tasks one at a time. Go and Run are called from the
+// test's goroutine, or Go from a running task.
+type Scheduler struct {
+	choose  func(n int) int
+	tasks   []*task
+	events  chan event
+	gone    chan struct{} // closed when Run returns, for tasks it gave up on
+	choices []int
+	trace   []string
+}
+
+func newScheduler(choose func(n int) int) *Scheduler {
+	return &Scheduler{choose: choose, events: make(chan event), gone: make(chan struct{})}
+}
+
+// New returns a scheduler that picks the next task at random from seed.
+func New(seed uint64) *Scheduler {
+	r := randsource.New(seed)
+	return newScheduler(r.IntN)
+}
+
+// Replay returns a scheduler that makes the given choices, as recorded
+// in Choices or a Failure, and the first runnable task after them.
+func Replay(choices []int) *Scheduler {
+	i := 0
+	return newScheduler(func(n int) int {
+		defer func() { i++ }()
+		if i < len(choices) && choices[i] < n {
+			return choices[i]
+		}
+		return 0
+	})
+}
+
+// Go adds a task; it first runs when Run picks it.
+func (s *Scheduler) Go(name string, fn func(ctx context.Context)) {
+	t := &task{s: s, name: name, resume: make(chan struct{})}
+	s.tasks = append(s.tasks, t)
+	go func() {
+		<-t.resume
+		defer func() {
+			p := recover()
+			if _, ok := p.(abort); ok {
+				p = nil
+			}
+			select {
+			case s.events <- event{t: t, done: true, panic: p}:
+			case <-s.gone:
+			}
+		}()
+		if t.aborted {
+			return
+		}
+		fn(context.WithValue(context.Background(), ctxKey{}, t))
+	}()
+}
+
+// abort unwinds a task that Run gave up on from its yield point.
+type abort struct{}
+
+// Run runs the tasks until all have returned, which is nil; a panic in a
+// task, every task waiting (ErrDeadlock), or a task blocked outside the
+// scheduler ends it early with an error naming the tasks, and the others
+// are unwound from their yield points.
+func (s *Scheduler) Run() error {
+	defer close(s.gone)
+	for {
+		var runnable, waiting []*task
+		for _, t := range s.tasks {
+			switch {
+			case t.done:
+			case t.cond == nil || t.cond():
+				runnable = append(runnable, t)
+			default:
+				waiting = append(waiting, t)
+			}
+		}
+		if len(runnable) == 0 {
+			if len(waiting) == 0 {
+				return nil
+			}
+			s.abort()
+			return fmt.Errorf("%w: %s waiting", ErrDeadlock, names(waiting))
+		}
+		i := 0
+		if len(runnable) > 1 {
+			i = s.choose(len(runnable))
+			s.choices = append(s.choices, i)
+		}
+		t := runnable[i]
+		t.cond = nil
+		s.trace = append(s.trace, t.name)
+		t.resume <- struct{}{}
+
+		timer := time.NewTimer(StuckAfter)
+		select {
+		case e := <-s.events:
+			timer.Stop()
+			if e.done {
+				e.t.done = true
+			}
+			if e.panic != nil {
+				s.abort()
+				return fmt.Errorf("sched: task %s panicked: %v", e.t.name, e.panic)
+			}
+		case <-timer.C:
+			t.done = true // leaked: it may never return
+			s.abort()
+			return fmt.Errorf("sched: task %s blocked outside the scheduler for %v", t.name, StuckAfter)
+		}
+	}
+}
+
+// abort unwinds every task not done, whether paused or never started.
+func (s *Scheduler) abort() {
+	for _, t := range s.tasks {
+		if t.done {
+			continue
+		}
+		t.aborted = true
+		t.resume <- struct{}{}
+		for e := range s.events {
+			if e.t == t && e.done {
+				break
+			}
+		}
+		t.done = true
+	}
+}
+
+// Choices returns the choice made at each point where more than one
+// task could run, for Replay.
+func (s *Scheduler) Choices() []int { return append([]int(nil), s.choices...) }
+
+// Trace returns the task run at each step, in order.
+func (s *Scheduler) Trace() []string { return append([]string(nil), s.trace...) }
+
+func names(ts []*task) string {
+	ns := make([]string, len(ts))
+	for i, t := range ts {
+		ns[i] = t.name
+	}
+	return strings.Join(ns, ", ")
+}
+
+// Yield is a point where another task may run: under a scheduler, the
+// calling task pauses until it is picked again. Without one it does
+// nothing.
+func Yield(ctx context.Context) {
+	t, ok := ctx.Value(ctxKey{}).(*task)
+	if !ok {
+		return
+	}
+	select {
+	case t.s.events <- event{t: t}:
+	case <-t.s.gone:
+		panic(abort{})
+	}
+	<-t.resume
+	if t.aborted {
+		panic(abort{})
+	}
+}
+
+// Wait returns once cond holds, letting other tasks run meanwhile; it is
+// how code under a scheduler blocks. The scheduler checks cond itself,
+// and resumes the task only when it holds. Without a scheduler, Wait
+// polls cond, yielding the processor between checks.
+func Wait(ctx context.Context, cond func() bool) {
+	t, ok := ctx.Value(ctxKey{}).(*task)
+	for !cond() {
+		if !ok {
+			runtime.Gosched()
+			continue
+		}
+		t.cond = cond
+		Yield(ctx)
+	}
+}
@@ -0,0 +1,315 @@
+package sched_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"patterns/testing/sched"
+)
+
+// counter loses an update when another Inc runs between its read and its
+// write; safe runs the two with no yield point between them.
+type counter struct {
+	n    int
+	safe bool
+}
+
+func (c *counter) Inc(ctx context.Context) {
+	if c.safe {
+		sched.Yield(ctx)
+		c.n++
+		return
+	}
+	v := c.n
+	sched.Yield(ctx)
+	c.n = v + 1
+}
+
+func incs(safe bool, runs *int) sched.Setup {
+	return func(s *sched.Scheduler) func() error {
+		*runs++
+		c := &counter{safe: safe}
+		s.Go("a", c.Inc)
+		s.Go("b", c.Inc)
+		return func() error {
+			if c.n != 2 {
+				return fmt.Errorf("n = %d, want 2", c.n)
+			}
+			return nil
+		}
+	}
+}
+
+// TestReplay runs two tasks of two steps under given choices: the first
+// runnable task, in the order they were added, is choice 0.
+func TestReplay(t *testing.T) {
+	for _, c := range []struct {
+		choices []int
+		log     string
+		trace   string
+		made    []int
+	}{
+		{nil, "a1 a2 b1 b2", "a a b b", []int{0, 0}},
+		{[]int{1}, "b1 a1 a2 b2", "b a a b", []int{1, 0, 0}},
+		{[]int{0, 1}, "a1 b1 a2 b2", "a b a b", []int{0, 1, 0}},
+		{[]int{1, 1}, "b1 b2 a1 a2", "b b a a", []int{1, 1}},
+		// choices past the runnable count, or past the end, take the first
+		{[]int{5, 7, 9, 9}, "a1 a2 b1 b2", "a a b b", []int{0, 0}},
+	} {
+		s := sched.Replay(c.choices)
+		var log []string
+		for _, name := range []string{"a", "b"} {
+			s.Go(name, func(ctx context.Context) {
+				log = append(log, name+"1")
+				sched.Yield(ctx)
+				log = append(log, name+"2")
+			})
+		}
+		if err := s.Run(); err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.Join(log, " "); got != c.log {
+			t.Errorf("%v: ran %s, want %s", c.choices, got, c.log)
+		}
+		if got := strings.Join(s.Trace(), " "); got != c.trace {
+			t.Errorf("%v: Trace = %s, want %s", c.choices, got, c.trace)
+		}
+		if got := s.Choices(); !slices.Equal(got, c.made) {
+			t.Errorf("%v: Choices = %v, want %v", c.choices, got, c.made)
+		}
+	}
+}
+
+// TestStress finds the lost update, and Replay of its schedule finds it
+// again, step for step.
+func TestStress(t *testing.T) {
+	runs := 0
+	err := sched.Stress(100, incs(false, &runs))
+	var f *sched.Failure
+	if !errors.As(err, &f) || f.Seed == 0 || f.Err.Error() != "n = 1, want 2" {
+		t.Fatalf("Stress = %v", err)
+	}
+	if runs != int(f.Seed) {
+		t.Errorf("%d runs to seed %d", runs, f.Seed)
+	}
+	if !strings.HasPrefix(err.Error(), fmt.Sprintf("seed %d: n = 1, want 2\nschedule %v (Replay), steps ", f.Seed, f.Choices)) {
+		t.Errorf("Error = %q", err)
+	}
+
+	again := sched.Once(sched.Replay(f.Choices), incs(false, &runs))
+	var g *sched.Failure
+	if !errors.As(again, &g) || g.Seed != 0 || !slices.Equal(g.Choices, f.Choices) || !slices.Equal(g.Trace, f.Trace) {
+		t.Errorf("Replay = %v, want the failure of seed %d", again, f.Seed)
+	}
+	if err := sched.Stress(100, incs(true, &runs)); err != nil {
+		t.Errorf("safe counter: %v", err)
+	}
+}
+
+// TestSeed runs the same seed twice: the same schedule both times.
+func TestSeed(t *testing.T) {
+	trace := func(seed uint64) []string {
+		s := sched.New(seed)
+		for _, name := range []string{"a", "b", "c"} {
+			s.Go(name, func(ctx context.Context) {
+				for range 5 {
+					sched.Yield(ctx)
+				}
+			})
+		}
+		if err := s.Run(); err != nil {
+			t.Fatal(err)
+		}
+		return s.Trace()
+	}
+	seen := map[string]bool{}
+	for seed := uint64(1); seed <= 10; seed++ {
+		first := trace(seed)
+		if again := trace(seed); !slices.Equal(first, again) {
+			t.Errorf("seed %d: %v, then %v", seed, first, again)
+		}
+		seen[strings.Join(first, "")] = true
+	}
+	if len(seen) < 2 {
+		t.Errorf("10 seeds gave %d schedules", len(seen))
+	}
+}
+
+// TestExhaust walks every schedule of the two Incs: of the six orders of
+// two tasks of two steps, it stops at the first that loses an update,
+// and passes all six of the safe counter.
+func TestExhaust(t *testing.T) {
+	runs := 0
+	err := sched.Exhaust(100, incs(false, &runs))
+	var f *sched.Failure
+	if !errors.As(err, &f) || f.Seed != 0 || strings.HasPrefix(err.Error(), "seed") {
+		t.Fatalf("Exhaust = %v", err)
+	}
+	// a a b b is fine; a b a b, the next, is not
+	if runs != 2 || strings.Join(f.Trace, " ") != "a b a b" {
+		t.Errorf("failed on run %d, at %v", runs, f.Trace)
+	}
+
+	var traces []string
+	err = sched.Exhaust(100, func(s *sched.Scheduler) func() error {
+		check := incs(true, &runs)(s)
+		return func() error {
+			traces = append(traces, strings.Join(s.Trace(), ""))
+			return check()
+		}
+	})
+	slices.Sort(traces)
+	if want := []string{"aabb", "abab", "abba", "baab", "baba", "bbaa"}; err != nil || !slices.Equal(traces, want) {
+		t.Errorf("Exhaust = %v after %v, want %v", err, traces, want)
+	}
+
+	// the limit stops the walk, without a failure
+	runs = 0
+	if err := sched.Exhaust(3, incs(true, &runs)); err != nil || runs != 3 {
+		t.Errorf("Exhaust(3) = %v after %d runs", err, runs)
+	}
+}
+
+// TestWait has a consumer wait for what a producer puts: the scheduler
+// resumes it only once there is something, in every schedule.
+func TestWait(t *testing.T) {
+	err := sched.Exhaust(1000, func(s *sched.Scheduler) func() error {
+		var queue, got []int
+		s.Go("consumer", func(ctx context.Context) {
+			for range 2 {
+				sched.Wait(ctx, func() bool { return len(queue) > 0 })
+				got = append(got, queue[0])
+				queue = queue[1:]
+			}
+		})
+		s.Go("producer", func(ctx context.Context) {
+			for i := range 2 {
+				sched.Yield(ctx)
+				queue = append(queue, i)
+			}
+		})
+		return func() error {
+			if !slices.Equal(got, []int{0, 1}) {
+				return fmt.Errorf("got %v", got)
+			}
+			return nil
+		}
+	})
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+// TestDeadlock has two tasks each wait for the other: Run reports both,
+// and unwinds them, running their deferred calls.
+func TestDeadlock(t *testing.T) {
+	s := sched.New(1)
+	var a, b bool
+	var unwound atomic.Int32
+	s.Go("a", func(ctx context.Context) {
+		defer unwound.Add(1)
+		sched.Wait(ctx, func() bool { return b })
+		a = true
+	})
+	s.Go("b", func(ctx context.Context) {
+		defer unwound.Add(1)
+		sched.Wait(ctx, func() bool { return a })
+		b = true
+	})
+	if err := s.Run(); !errors.Is(err, sched.ErrDeadlock) || err.Error() != "sched: deadlock: a, b waiting" {
+		t.Errorf("Run = %v", err)
+	}
+	if unwound.Load() != 2 {
+		t.Errorf("%d tasks unwound, want 2", unwound.Load())
+	}
+}
+
+// TestPanic has a task panic while another is paused: Run reports the
+// panic and unwinds the other from its yield point.
+func TestPanic(t *testing.T) {
+	// a runs to its yield point, then b
+	s := sched.Replay([]int{0, 1})
+	var unwound bool
+	s.Go("a", func(ctx context.Context) {
+		defer func() { unwound = true }()
+		sched.Yield(ctx)
+		t.Error("task a resumed after b panicked")
+	})
+	s.Go("b", func(ctx context.Context) { panic("boom") })
+	if err := s.Run(); err == nil || err.Error() != "sched: task b panicked: boom" || !unwound {
+		t.Errorf("Run = %v, a unwound %v", err, unwound)
+	}
+}
+
+// TestStuck blocks a task on a channel, outside the scheduler: Run gives
+// up on it after StuckAfter.
+func TestStuck(t *testing.T) {
+	defer func(d time.Duration) { sched.StuckAfter = d }(sched.StuckAfter)
+	sched.StuckAfter = 20 * time.Millisecond
+	s := sched.New(1)
+	release := make(chan struct{})
+	defer close(release)
+	s.Go("a", func(ctx context.Context) {
+		<-release
+		// too late: Run has returned, and the task unwinds from here
+		sched.Yield(ctx)
+		t.Error("task a resumed after Run returned")
+	})
+	if err := s.Run(); err == nil || err.Error() != "sched: task a blocked outside the scheduler for 20ms" {
+		t.Errorf("Run = %v", err)
+	}
+}
+
+// TestGo adds tasks from a running one: they run under the same
+// scheduler, and Run waits for them too.
+func TestGo(t *testing.T) {
+	err := sched.Exhaust(1000, func(s *sched.Scheduler) func() error {
+		var done atomic.Int32
+		s.Go("parent", func(ctx context.Context) {
+			for _, name := range []string{"x", "y"} {
+				s.Go(name, func(ctx context.Context) {
+					sched.Yield(ctx)
+					done.Add(1)
+				})
+				sched.Yield(ctx)
+			}
+			done.Add(1)
+		})
+		return func() error {
+			if n := done.Load(); n != 3 {
+				return fmt.Errorf("%d tasks finished, want 3", n)
+			}
+			return nil
+		}
+	})
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+// TestOutside runs the same code with no scheduler: Yield does nothing,
+// and Wait polls until the condition holds.
+func TestOutside(t *testing.T) {
+	c := &counter{}
+	c.Inc(context.Background())
+	c.Inc(context.Background())
+	if c.n != 2 {
+		t.Errorf("n = %d, want 2", c.n)
+	}
+
+	var ready atomic.Bool
+	go func() {
+		time.Sleep(time.Millisecond)
+		ready.Store(true)
+	}()
+	sched.Wait(context.Background(), ready.Load)
+	if !ready.Load() {
+		t.Error("Wait returned before its condition held")
+	}
+}
@@ -0,0 +1,101 @@
+// Package testoptions applies functional options to test helpers, so a
+// helper like newTestServer has one signature however many knobs tests
+// need:
+//
+//	func newTestServer(t testoptions.T, opts ...testoptions.Option[env]) *env {
+//		t.Helper()
+//		e := &env{clock: clock.NewFake(epoch)}
+//		testoptions.Apply(t, e, opts...)
+//		...
+//	}
+//
+//	s := newTestServer(t, withFakeClock(c), withSeededRepo(10))
+//
+// Unlike constructor options, test options take the test: they fail it
+// with t.Fatalf instead of returning errors, and register their own
+// teardown with t.Cleanup, so a test never has to remember to close what
+// an option opened. The tests of examples/urlshortener and examples/crud
+// build their environments this way.
+//
+// T is the subset of testing.TB the helpers use, and Run provides one
+// outside go test, for the check harness of the exercises.
+package testoptions
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// T is the part of testing.TB that helpers and options need.
+type T interface {
+	Helper()
+	Cleanup(func())
+	Fatalf(format string, args ...any)
+	Logf(format string, args ...any)
+}
+
+// Option configures the environment E a helper builds.
+type Option[E any] func(t T, env *E)
+
+// Apply applies opts to env in order.
+func Apply[E any](t T, env *E, opts ...Option[E]) {
+	t.Helper()
+	for _, opt := range opts {
+		opt(t, env)
+	}
+}
+
+// errFatal unwinds a Run after Fatalf, as runtime.Goexit does for go test.
+var errFatal = errors.New("fatal")
+
+type runner struct {
+	mu       sync.Mutex
+	cleanups []func()
+	failure  error
+	logs     []string
+}
+
+func (r *runner) Helper() {}
+
+func (r *runner) Cleanup(f func()) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cleanups = append(r.cleanups, f)
+}
+
+func (r *runner) Fatalf(format string, args ...any) {
+	r.mu.Lock()
+	r.failure = fmt.Errorf(format, args...)
+	r.mu.Unlock()
+	panic(errFatal)
+}
+
+func (r *runner) Logf(format string, args ...any) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.logs = append(r.logs, fmt.Sprintf(format, args...))
+}
+
+// Run calls f with a T that turns Fatalf into the returned error and runs
+// the registered cleanups, last first, when f returns or fails. Fatalf
+// must be called from f's goroutine, as with go test.
+func Run(f func(t T)) (err error) {
+	r := &runner{}
+	defer func() {
+		p := recover()
+		r.mu.Lock()
+		cleanups := r.cleanups
+		r.mu.Unlock()
+		for _, c := range slices.Backward(cleanups) {
+			c()
+		}
+		if p != nil && p != errFatal {
+			panic(p)
+		}
+		err = r.failure
+	}()
+	f(r)
+	return nil
+}
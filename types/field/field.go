@@ -0,0 +1,204 @@
+// Package field is a value that knows whether it was given: Field[T]
+// holds one of three states, where a plain T has one and a *T two.
+//
+//   - unset, the zero Field: nothing was said, so a default applies
+//   - null: cleared on purpose, JSON null in a patch
+//   - set: a value, which may be T's zero value
+//
+// The third state is what *int config fields are for, and what they do
+// badly: Port: 0 has to mean "pick a free port", not "not given", so the
+// field becomes a pointer, callers write &port from a local variable, and
+// every reader nil-checks. With Field, the config reads
+//
+//	configstruct.Config{Port: field.Of(0)}
+//
+// and Or supplies the default: cfg.Port.Or(8080).
+//
+// JSON keeps the three apart when decoding: a missing key leaves the
+// Field unset, null makes it null, anything else sets it. Encoding writes
+// null for both unset and null, because encoding/json cannot omit a
+// struct-typed field; omitzero, which could, needs Go 1.24.
+//
+// Patch and Merge apply a JSON merge patch (RFC 7396) to a struct of
+// Fields: a set field replaces, null clears back to unset, and unset
+// leaves the old value alone. Value and Scan make a Field a nullable
+// column, unset and null both being SQL NULL.
+package field
+
+import (
+	"bytes"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+type state uint8
+
+const (
+	unset state = iota
+	null
+	set
+)
+
+// tri-state field
+// Level: Good
+// pros: zero, absent and cleared are different values with names;
+// callers write values, not pointers to locals; PATCH semantics fall out.
+// cons: a wrapper to unwrap at every use; encoding/json before Go 1.24
+// writes unset fields as null.
+//
+// Field is a T that may be unset or null. The zero Field is unset.
+type Field[T any] struct {
+	v     T
+	state state
+}
+
+// Of returns a set Field holding v.
+func Of[T any](v T) Field[T] { return Field[T]{v: v, state: set} }
+
+// Null returns a Field explicitly cleared.
+func Null[T any]() Field[T] { return Field[T]{state: null} }
+
+// FromPtr returns a Field set to *p, or unset for a nil p, for code that
+// still speaks pointers.
+func FromPtr[T any](p *T) Field[T] {
+	if p == nil {
+		return Field[T]{}
+	}
+	return Of(*p)
+}
+
+// IsSet reports whether f holds a value.
+func (f Field[T]) IsSet() bool { return f.state == set }
+
+// IsNull reports whether f was explicitly cleared.
+func (f Field[T]) IsNull() bool { return f.state == null }
+
+// IsUnset reports whether nothing was said about f.
+func (f Field[T]) IsUnset() bool { return f.state == unset }
+
+// Get returns the value and whether f is set.
+func (f Field[T]) Get() (T, bool) { return f.v, f.state == set }
+
+// Or returns the value if f is set, else def.
+func (f Field[T]) Or(def T) T {
+	if f.state == set {
+		return f.v
+	}
+	return def
+}
+
+// Ptr returns a pointer to a copy of the value, or nil unless f is set.
+func (f Field[T]) Ptr() *T {
+	if f.state != set {
+		return nil
+	}
+	v := f.v
+	return &v
+}
+
+func (f Field[T]) String() string {
+	switch f.state {
+	case null:
+		return "null"
+	case set:
+		return fmt.Sprint(f.v)
+	}
+	return "unset"
+}
+
+// Patch returns f with p applied as a merge patch: set replaces, null
+// clears, unset keeps f.
+func (f Field[T]) Patch(p Field[T]) Field[T] {
+	switch p.state {
+	case set:
+		return p
+	case null:
+		return Field[T]{}
+	}
+	return f
+}
+
+func (f Field[T]) MarshalJSON() ([]byte, error) {
+	if f.state != set {
+		return []byte("null"), nil
+	}
+	return json.Marshal(f.v)
+}
+
+// UnmarshalJSON is only called for a key that is present: null makes f
+// null, anything else sets it.
+func (f *Field[T]) UnmarshalJSON(b []byte) error {
+	if bytes.Equal(bytes.TrimSpace(b), []byte("null")) {
+		*f = Null[T]()
+		return nil
+	}
+	var v T
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	*f = Of(v)
+	return nil
+}
+
+// Value is SQL NULL unless f is set. The value goes through the driver's
+// default conversion, so a Field[int] is an int64 argument; before Go
+// 1.24 sql.Null[T].Value returns an int as is, which database/sql refuses.
+func (f Field[T]) Value() (driver.Value, error) {
+	if f.state != set {
+		return nil, nil
+	}
+	return driver.DefaultParameterConverter.ConvertValue(f.v)
+}
+
+// Scan sets f from a column, making it null for SQL NULL.
+func (f *Field[T]) Scan(src any) error {
+	var n sql.Null[T]
+	if err := n.Scan(src); err != nil {
+		return err
+	}
+	if !n.Valid {
+		*f = Null[T]()
+		return nil
+	}
+	*f = Of(n.V)
+	return nil
+}
+
+// patcher is every Field, for Merge to find through reflection.
+type patcher interface {
+	patchAny(p any) any
+}
+
+func (f Field[T]) patchAny(p any) any { return f.Patch(p.(Field[T])) }
+
+// Merge returns base with patch applied field by field: Fields by Patch,
+// nested structs recursively, and every other field replaced when the
+// patch's is not its type's zero value. Decode the patch from JSON into
+// the same struct type, and its unset Fields are exactly the keys the
+// client left out.
+func Merge[S any](base, patch S) S {
+	out := base
+	merge(reflect.ValueOf(&out).Elem(), reflect.ValueOf(patch))
+	return out
+}
+
+func merge(dst, patch reflect.Value) {
+	if p, ok := dst.Interface().(patcher); ok {
+		dst.Set(reflect.ValueOf(p.patchAny(patch.Interface())))
+		return
+	}
+	if dst.Kind() == reflect.Struct {
+		for i := range dst.NumField() {
+			if dst.Type().Field(i).IsExported() {
+				merge(dst.Field(i), patch.Field(i))
+			}
+		}
+		return
+	}
+	if !patch.IsZero() {
+		dst.Set(patch)
+	}
+}
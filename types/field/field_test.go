@@ -0,0 +1,212 @@
+package field_test
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"patterns/types/field"
+)
+
+func TestStates(t *testing.T) {
+	for _, c := range []struct {
+		name             string
+		f                field.Field[int]
+		set, null, unset bool
+		or               int
+		str              string
+	}{
+		{"unset", field.Field[int]{}, false, false, true, 8080, "unset"},
+		{"null", field.Null[int](), false, true, false, 8080, "null"},
+		{"zero", field.Of(0), true, false, false, 0, "0"},
+		{"set", field.Of(443), true, false, false, 443, "443"},
+	} {
+		if c.f.IsSet() != c.set || c.f.IsNull() != c.null || c.f.IsUnset() != c.unset {
+			t.Errorf("%s: IsSet %v, IsNull %v, IsUnset %v", c.name, c.f.IsSet(), c.f.IsNull(), c.f.IsUnset())
+		}
+		if got := c.f.Or(8080); got != c.or {
+			t.Errorf("%s: Or(8080) = %d, want %d", c.name, got, c.or)
+		}
+		if got := c.f.String(); got != c.str {
+			t.Errorf("%s: String() = %q, want %q", c.name, got, c.str)
+		}
+		if v, ok := c.f.Get(); ok != c.set || ok && v != c.or {
+			t.Errorf("%s: Get() = %d, %v", c.name, v, ok)
+		}
+		if p := c.f.Ptr(); (p != nil) != c.set || p != nil && *p != c.or {
+			t.Errorf("%s: Ptr() = %v", c.name, p)
+		}
+	}
+}
+
+func TestPtr(t *testing.T) {
+	port := 0
+	f := field.FromPtr(&port)
+	if v, ok := f.Get(); !ok || v != 0 {
+		t.Errorf("FromPtr(&0) = %v, want set to 0", f)
+	}
+	if f := field.FromPtr[int](nil); !f.IsUnset() {
+		t.Errorf("FromPtr(nil) = %v, want unset", f)
+	}
+	*f.Ptr() = 1
+	if v, _ := f.Get(); v != 0 {
+		t.Errorf("writing through Ptr() changed the field to %d", v)
+	}
+}
+
+type patch struct {
+	Port  field.Field[int]    `json:"port"`
+	Name  field.Field[string] `json:"name"`
+	Debug field.Field[bool]   `json:"debug"`
+}
+
+func TestUnmarshalJSON(t *testing.T) {
+	for _, c := range []struct {
+		in   string
+		want patch
+	}{
+		{`{}`, patch{}},
+		{`{"port": null}`, patch{Port: field.Null[int]()}},
+		{`{"port": 0, "name": "", "debug": false}`, patch{Port: field.Of(0), Name: field.Of(""), Debug: field.Of(false)}},
+		{`{"port": 8080, "name": null}`, patch{Port: field.Of(8080), Name: field.Null[string]()}},
+	} {
+		var got patch
+		if err := json.Unmarshal([]byte(c.in), &got); err != nil {
+			t.Errorf("%s: %v", c.in, err)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("%s: decoded %+v, want %+v", c.in, got, c.want)
+		}
+	}
+	var p patch
+	if err := json.Unmarshal([]byte(`{"port": "http"}`), &p); err == nil {
+		t.Error(`decoding {"port": "http"} succeeded`)
+	}
+}
+
+func TestMarshalJSON(t *testing.T) {
+	b, err := json.Marshal(patch{Port: field.Of(0), Name: field.Null[string]()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"port":0,"name":null,"debug":null}`; string(b) != want {
+		t.Errorf("Marshal = %s, want %s", b, want)
+	}
+}
+
+func TestPatch(t *testing.T) {
+	base := field.Of(1)
+	for _, c := range []struct {
+		name  string
+		base  field.Field[int]
+		patch field.Field[int]
+		want  field.Field[int]
+	}{
+		{"set replaces", base, field.Of(2), field.Of(2)},
+		{"set to zero replaces", base, field.Of(0), field.Of(0)},
+		{"null clears", base, field.Null[int](), field.Field[int]{}},
+		{"unset keeps", base, field.Field[int]{}, base},
+		{"set fills unset", field.Field[int]{}, field.Of(3), field.Of(3)},
+		{"unset keeps null", field.Null[int](), field.Field[int]{}, field.Null[int]()},
+	} {
+		if got := c.base.Patch(c.patch); got != c.want {
+			t.Errorf("%s: %v.Patch(%v) = %v, want %v", c.name, c.base, c.patch, got, c.want)
+		}
+	}
+}
+
+type limits struct {
+	Rate  field.Field[float64]
+	Burst field.Field[int]
+}
+
+type config struct {
+	Name    field.Field[string]
+	Port    field.Field[int]
+	Tags    []string
+	Timeout time.Duration
+	Limits  limits
+	secret  string
+}
+
+// TestMergeJSON decodes a merge patch into the config type itself and
+// merges it, the way a PATCH handler would.
+func TestMergeJSON(t *testing.T) {
+	base := config{
+		Name:    field.Of("api"),
+		Port:    field.Of(8080),
+		Tags:    []string{"a"},
+		Timeout: time.Second,
+		Limits:  limits{Rate: field.Of(10.0), Burst: field.Of(5)},
+		secret:  "kept",
+	}
+	var p config
+	if err := json.Unmarshal([]byte(`{"Port": 0, "Name": null, "Limits": {"Burst": 20}}`), &p); err != nil {
+		t.Fatal(err)
+	}
+	p.secret = "ignored"
+
+	got := field.Merge(base, p)
+	want := base
+	want.Port = field.Of(0)
+	want.Name = field.Field[string]{}
+	want.Limits.Burst = field.Of(20)
+	if got.Name != want.Name || got.Port != want.Port || got.Limits != want.Limits ||
+		got.Timeout != want.Timeout || len(got.Tags) != 1 || got.secret != "kept" {
+		t.Errorf("Merge = %+v, want %+v", got, want)
+	}
+	if base.Port != field.Of(8080) {
+		t.Errorf("Merge changed base to %+v", base)
+	}
+
+	// plain fields replace only when the patch's is not zero
+	got = field.Merge(base, config{Tags: []string{"b", "c"}, Timeout: 0})
+	if len(got.Tags) != 2 || got.Timeout != time.Second {
+		t.Errorf("Merge of plain fields = %+v", got)
+	}
+}
+
+// TestValuer checks Value through the conversion database/sql applies to
+// every argument, which refuses anything that is not a driver.Value.
+func TestValuer(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		v    driver.Valuer
+		want driver.Value
+	}{
+		{"unset", field.Field[int]{}, nil},
+		{"null", field.Null[string](), nil},
+		{"int", field.Of(42), int64(42)},
+		{"int32 zero", field.Of(int32(0)), int64(0)},
+		{"string", field.Of("x"), "x"},
+		{"bool", field.Of(false), false},
+	} {
+		got, err := driver.DefaultParameterConverter.ConvertValue(c.v)
+		if err != nil || got != c.want {
+			t.Errorf("%s: converted to %#v, %v; want %#v", c.name, got, err, c.want)
+		}
+	}
+}
+
+func TestScan(t *testing.T) {
+	for _, c := range []struct {
+		src  any
+		want field.Field[int]
+	}{
+		{nil, field.Null[int]()},
+		{int64(7), field.Of(7)},
+		{int64(0), field.Of(0)},
+		{[]byte("12"), field.Of(12)},
+	} {
+		var f field.Field[int]
+		if err := f.Scan(c.src); err != nil || f != c.want {
+			t.Errorf("Scan(%#v) = %v, %v; want %v", c.src, f, err, c.want)
+		}
+	}
+	f := field.Of(1)
+	if err := f.Scan("not a number"); err == nil {
+		t.Errorf("Scan of a string into Field[int] = %v, want an error", f)
+	}
+}
@@ -0,0 +1,178 @@
+// Package validate checks constructor invariants and reports every
+// violation at once, each with the path of the field it concerns:
+//
+//	var v validate.Validator
+//	validate.Field(&v, "port", o.port, validate.Range(0, 65535))
+//	validate.Field(&v, "host", o.host, validate.NonEmpty[string]())
+//	tls := v.Nested("tls")
+//	tls.Check("cert", o.cert != "" || o.key == "", "required when key is set")
+//	return v.Err() // nil, or Errors{port: ..., tls.cert: ...}
+package validate
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// FieldError is one violated rule.
+type FieldError struct {
+	// Path is the dotted field path, e.g. "tls.cert" or "tags[2]".
+	Path    string
+	Message string
+}
+
+func (e *FieldError) Error() string {
+	if e.Path == "" {
+		// the value being validated as a whole
+		return e.Message
+	}
+	return e.Path + ": " + e.Message
+}
+
+// Errors is every FieldError found, in the order the checks ran.
+type Errors []*FieldError
+
+func (es Errors) Error() string {
+	msgs := make([]string, len(es))
+	for i, e := range es {
+		msgs[i] = e.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Unwrap lets errors.As find an individual *FieldError.
+func (es Errors) Unwrap() []error {
+	out := make([]error, len(es))
+	for i, e := range es {
+		out[i] = e
+	}
+	return out
+}
+
+// Rule checks one value and returns a message-only error if it fails; the
+// Validator adds the field path.
+type Rule[T any] func(v T) error
+
+// Range requires min <= v <= max.
+func Range[T cmp.Ordered](min, max T) Rule[T] {
+	return func(v T) error {
+		if v < min || v > max {
+			return fmt.Errorf("must be between %v and %v", min, max)
+		}
+		return nil
+	}
+}
+
+// Min requires v >= min.
+func Min[T cmp.Ordered](min T) Rule[T] {
+	return func(v T) error {
+		if v < min {
+			return fmt.Errorf("must be at least %v", min)
+		}
+		return nil
+	}
+}
+
+// NonEmpty requires a non-empty string, ignoring surrounding spaces.
+func NonEmpty[T ~string]() Rule[T] {
+	return func(v T) error {
+		if strings.TrimSpace(string(v)) == "" {
+			return errors.New("is required")
+		}
+		return nil
+	}
+}
+
+// OneOf requires v to be one of allowed.
+func OneOf[T comparable](allowed ...T) Rule[T] {
+	return func(v T) error {
+		if !slices.Contains(allowed, v) {
+			return fmt.Errorf("must be one of %v", allowed)
+		}
+		return nil
+	}
+}
+
+// Custom turns a predicate into a rule failing with msg.
+func Custom[T any](ok func(T) bool, msg string) Rule[T] {
+	return func(v T) error {
+		if !ok(v) {
+			return errors.New(msg)
+		}
+		return nil
+	}
+}
+
+// Validator collects field errors. The zero value is ready to use.
+type Validator struct {
+	prefix string
+	errs   *Errors
+}
+
+func (v *Validator) list() *Errors {
+	if v.errs == nil {
+		v.errs = new(Errors)
+	}
+	return v.errs
+}
+
+func (v *Validator) path(field string) string {
+	if v.prefix == "" {
+		return field
+	}
+	if field == "" {
+		// the value itself, e.g. an element from Index
+		return v.prefix
+	}
+	if strings.HasPrefix(field, "[") {
+		return v.prefix + field
+	}
+	return v.prefix + "." + field
+}
+
+// Field runs rules against value, recording the first failure only, since
+// later rules usually assume earlier ones passed.
+func Field[T any](v *Validator, field string, value T, rules ...Rule[T]) {
+	for _, rule := range rules {
+		if err := rule(value); err != nil {
+			v.Fail(field, err.Error())
+			return
+		}
+	}
+}
+
+// Check records msg against field unless ok; for rules spanning fields.
+func (v *Validator) Check(field string, ok bool, msg string) {
+	if !ok {
+		v.Fail(field, msg)
+	}
+}
+
+// Fail records msg against field.
+func (v *Validator) Fail(field, msg string) {
+	l := v.list()
+	*l = append(*l, &FieldError{Path: v.path(field), Message: msg})
+}
+
+// Nested returns a Validator whose paths are prefixed with field; errors
+// go to the same list.
+func (v *Validator) Nested(field string) *Validator {
+	return &Validator{prefix: v.path(field), errs: v.list()}
+}
+
+// Index returns a Validator for element i of the slice field.
+func (v *Validator) Index(field string, i int) *Validator {
+	return v.Nested(field + "[" + strconv.Itoa(i) + "]")
+}
+
+// Err returns nil if every check passed, or the Errors.
+func (v *Validator) Err() error {
+	if v.errs == nil || len(*v.errs) == 0 {
+		return nil
+	}
+	return *v.errs
+}
@@ -0,0 +1,145 @@
+package validate_test
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"patterns/validate"
+)
+
+// paths returns err's field paths and messages as "path: message".
+func paths(t *testing.T, err error) []string {
+	t.Helper()
+	if err == nil {
+		return nil
+	}
+	var es validate.Errors
+	if !errors.As(err, &es) {
+		t.Fatalf("error %T is not validate.Errors", err)
+	}
+	var out []string
+	for _, e := range es {
+		out = append(out, e.Error())
+	}
+	return out
+}
+
+func TestRules(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"range in", validate.Range(1, 10)(10), ""},
+		{"range below", validate.Range(1, 10)(0), "must be between 1 and 10"},
+		{"range above", validate.Range(0.5, 1.5)(2), "must be between 0.5 and 1.5"},
+		{"min", validate.Min("b")("a"), "must be at least b"},
+		{"min equal", validate.Min(3)(3), ""},
+		{"non-empty", validate.NonEmpty[string]()("x"), ""},
+		{"empty", validate.NonEmpty[string]()(""), "is required"},
+		{"blank", validate.NonEmpty[string]()(" \t"), "is required"},
+		{"one of", validate.OneOf("tcp", "udp")("udp"), ""},
+		{"not one of", validate.OneOf("tcp", "udp")("http"), "must be one of [tcp udp]"},
+		{"custom", validate.Custom(func(s []int) bool { return len(s) < 3 }, "too many")([]int{1, 2, 3}), "too many"},
+	} {
+		got := ""
+		if c.err != nil {
+			got = c.err.Error()
+		}
+		if got != c.want {
+			t.Errorf("%s: %q, want %q", c.name, got, c.want)
+		}
+	}
+}
+
+func TestZeroValidator(t *testing.T) {
+	var v validate.Validator
+	if err := v.Err(); err != nil {
+		t.Errorf("Err of a zero Validator = %v", err)
+	}
+	validate.Field(&v, "port", 80, validate.Range(0, 65535))
+	v.Check("host", true, "is required")
+	v.Nested("tls").Check("cert", true, "is required")
+	if err := v.Err(); err != nil {
+		t.Errorf("Err after passing checks = %v", err)
+	}
+}
+
+// TestAggregation checks that every failing field is reported, in the
+// order of the checks, and that a field stops at its first failing rule.
+func TestAggregation(t *testing.T) {
+	var v validate.Validator
+	validate.Field(&v, "port", -1, validate.Min(0), validate.Range(1, 10))
+	validate.Field(&v, "host", "", validate.NonEmpty[string](), validate.OneOf("a", "b"))
+	validate.Field(&v, "mode", "fast", validate.NonEmpty[string](), validate.OneOf("safe", "slow"))
+	v.Check("retries", false, "must be set with a timeout")
+	v.Fail("", "config is unusable")
+	want := []string{
+		"port: must be at least 0",
+		"host: is required",
+		"mode: must be one of [safe slow]",
+		"retries: must be set with a timeout",
+		"config is unusable",
+	}
+	err := v.Err()
+	if got := paths(t, err); !slices.Equal(got, want) {
+		t.Errorf("errors =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if err.Error() != strings.Join(want, "; ") {
+		t.Errorf("message = %q", err)
+	}
+}
+
+func TestPaths(t *testing.T) {
+	var v validate.Validator
+	tls := v.Nested("tls")
+	tls.Check("cert", false, "is required")
+	client := tls.Nested("client")
+	client.Check("ca", false, "is required")
+	for i, tag := range []string{"ok", "", "also ok", " "} {
+		validate.Field(v.Index("tags", i), "", tag, validate.NonEmpty[string]())
+	}
+	// a matrix, and a struct in a slice inside a nested struct
+	v.Index("grid", 1).Index("", 2).Fail("", "out of range")
+	v.Nested("routes").Index("", 0).Check("path", false, "must start with /")
+	v.Nested("listen").Index("addrs", 3).Check("port", false, "is taken")
+	v.Check("top", false, "still top level")
+
+	want := []string{
+		"tls.cert: is required",
+		"tls.client.ca: is required",
+		"tags[1]: is required",
+		"tags[3]: is required",
+		"grid[1][2]: out of range",
+		"routes[0].path: must start with /",
+		"listen.addrs[3].port: is taken",
+		"top: still top level",
+	}
+	if got := paths(t, v.Err()); !slices.Equal(got, want) {
+		t.Errorf("paths =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	// a nested Validator shares its parent's list
+	if err := tls.Err(); err == nil || len(paths(t, err)) != len(want) {
+		t.Errorf("nested Err = %v, want the shared list", err)
+	}
+}
+
+// TestErrorsAs checks that errors.As finds an individual field error
+// through Errors, and through wrapping.
+func TestErrorsAs(t *testing.T) {
+	var v validate.Validator
+	v.Nested("db").Check("dsn", false, "is required")
+	v.Check("name", false, "is required")
+	err := errors.Join(errors.New("new server"), v.Err())
+
+	var fe *validate.FieldError
+	if !errors.As(err, &fe) || fe.Path != "db.dsn" || fe.Message != "is required" {
+		t.Errorf("errors.As = %+v, want db.dsn", fe)
+	}
+	var es validate.Errors
+	if !errors.As(err, &es) || len(es) != 2 || es[1].Path != "name" {
+		t.Errorf("errors.As(Errors) = %v", es)
+	}
+}
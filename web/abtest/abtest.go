@@ -0,0 +1,122 @@
+// Package abtest assigns requests to experiment variants.
+//
+// A known user is bucketed by hashing the experiment name with their id,
+// so they see the same variant on every device and every deploy without
+// any stored state. An anonymous visitor is assigned at random on the
+// first request and pinned with a cookie. That random draw comes from a
+// randsource.Rand, so tests can seed it and check the split.
+package abtest
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"net/http"
+
+	"patterns/randsource"
+	"patterns/web/middleware"
+)
+
+// Variant is one arm of an experiment; traffic is split in proportion to
+// Weight.
+type Variant struct {
+	Name   string
+	Weight int
+}
+
+// Experiment is a named split between variants.
+type Experiment struct {
+	name     string
+	variants []Variant
+	total    int
+	rand     randsource.Rand
+}
+
+// New returns an experiment; weights must be non-negative with a positive
+// sum. r is the source for anonymous assignment, nil meaning
+// randsource.Global.
+func New(name string, r randsource.Rand, variants ...Variant) (*Experiment, error) {
+	if name == "" {
+		return nil, errors.New("abtest: experiment name is required")
+	}
+	e := &Experiment{name: name, variants: variants, rand: randsource.Or(r)}
+	for _, v := range variants {
+		if v.Weight < 0 {
+			return nil, errors.New("abtest: negative weight for " + v.Name)
+		}
+		e.total += v.Weight
+	}
+	if e.total == 0 {
+		return nil, errors.New("abtest: weights sum to zero")
+	}
+	return e, nil
+}
+
+func (e *Experiment) Name() string { return e.name }
+
+// Bucket returns the variant for id. The same experiment and id always
+// yield the same variant; different experiments are bucketed
+// independently because the name is part of the hash.
+func (e *Experiment) Bucket(id string) string {
+	h := fnv.New64a()
+	h.Write([]byte(e.name))
+	h.Write([]byte{0})
+	h.Write([]byte(id))
+	return e.pick(int(h.Sum64() % uint64(e.total)))
+}
+
+// Assign draws a variant at random, weighted.
+func (e *Experiment) Assign() string {
+	return e.pick(e.rand.IntN(e.total))
+}
+
+func (e *Experiment) pick(n int) string {
+	for _, v := range e.variants {
+		if n < v.Weight {
+			return v.Name
+		}
+		n -= v.Weight
+	}
+	panic("unreachable")
+}
+
+func (e *Experiment) valid(name string) bool {
+	for _, v := range e.variants {
+		if v.Name == name && v.Weight > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+type ctxKey struct{ experiment string }
+
+// FromContext returns the variant the Middleware for experiment chose for
+// this request, or "" if it did not run.
+func FromContext(ctx context.Context, experiment string) string {
+	v, _ := ctx.Value(ctxKey{experiment}).(string)
+	return v
+}
+
+// Middleware resolves the variant for every request: by Bucket when
+// userID returns a non-empty id, otherwise from the experiment's cookie,
+// otherwise by Assign, setting the cookie so the visitor stays put.
+// A cookie naming a variant that no longer exists or has weight zero is
+// replaced.
+func (e *Experiment) Middleware(userID func(*http.Request) string) middleware.Middleware {
+	cookie := "ab_" + e.name
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var variant string
+			if id := userID(r); id != "" {
+				variant = e.Bucket(id)
+			} else if c, err := r.Cookie(cookie); err == nil && e.valid(c.Value) {
+				variant = c.Value
+			} else {
+				variant = e.Assign()
+				http.SetCookie(w, &http.Cookie{Name: cookie, Value: variant, Path: "/", HttpOnly: true, SameSite: http.SameSiteLaxMode})
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), ctxKey{e.name}, variant)))
+		})
+	}
+}
@@ -0,0 +1,159 @@
+// Package draining ends long-lived requests (long polls, server-sent
+// events, hijacked streams) cleanly when a server shuts down.
+//
+// http.Server.Shutdown closes listeners and idle connections, then waits
+// for active handlers to return. Two kinds of handler defeat it:
+//
+//   - a stream that only ends when the client leaves keeps Shutdown
+//     waiting until its deadline, after which it is still open and can
+//     only be cut mid-message by Close;
+//   - a hijacked connection is forgotten by the server, so Shutdown
+//     returns while the stream is still being written.
+//
+// A Drainer fixes both. Attach installs a BaseContext through which every
+// handler can find Signal, closed when draining starts, so a stream can
+// send a final event and return. Track counts the handlers it wraps,
+// hijacked or not, and Shutdown waits for that count to reach zero as
+// well as for the server.
+//
+//	var d draining.Drainer
+//	d.Attach(srv)
+//	mux.Handle("/events", d.Track(draining.Events(time.Second)))
+//	...
+//	d.Shutdown(ctx, srv)
+package draining
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Drainer coordinates one server's shutdown. Zero value ready; safe for
+// concurrent use.
+type Drainer struct {
+	once     sync.Once
+	draining chan struct{}
+	drain    sync.Once
+
+	mu      sync.Mutex
+	streams int
+	conns   map[net.Conn]http.ConnState
+}
+
+func (d *Drainer) init() {
+	d.once.Do(func() {
+		d.draining = make(chan struct{})
+		d.conns = map[net.Conn]http.ConnState{}
+	})
+}
+
+type ctxKey struct{}
+
+// Attach installs the drainer on srv, keeping any BaseContext and
+// ConnState hooks already set. Call it before the server starts.
+func (d *Drainer) Attach(srv *http.Server) {
+	d.init()
+	base, state := srv.BaseContext, srv.ConnState
+	srv.BaseContext = func(l net.Listener) context.Context {
+		ctx := context.Background()
+		if base != nil {
+			ctx = base(l)
+		}
+		return context.WithValue(ctx, ctxKey{}, d)
+	}
+	srv.ConnState = func(c net.Conn, s http.ConnState) {
+		d.mu.Lock()
+		switch s {
+		case http.StateClosed, http.StateHijacked:
+			delete(d.conns, c)
+		default:
+			d.conns[c] = s
+		}
+		d.mu.Unlock()
+		if state != nil {
+			state(c, s)
+		}
+	}
+}
+
+// Signal returns a channel closed when the server handling ctx starts
+// draining. It returns nil, which never fires, outside an attached server.
+func Signal(ctx context.Context) <-chan struct{} {
+	d, _ := ctx.Value(ctxKey{}).(*Drainer)
+	if d == nil {
+		return nil
+	}
+	return d.draining
+}
+
+// Track counts next as a stream for Shutdown to wait on, including after
+// the handler hijacks its connection.
+func (d *Drainer) Track(next http.Handler) http.Handler {
+	d.init()
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		d.mu.Lock()
+		d.streams++
+		d.mu.Unlock()
+		defer func() {
+			d.mu.Lock()
+			d.streams--
+			d.mu.Unlock()
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Streams is the number of tracked handlers still running.
+func (d *Drainer) Streams() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.streams
+}
+
+// Conns counts the server's connections by state. Hijacked connections
+// are not counted; Streams covers them.
+func (d *Drainer) Conns() map[http.ConnState]int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	counts := map[http.ConnState]int{}
+	for _, s := range d.conns {
+		counts[s]++
+	}
+	return counts
+}
+
+// Drain closes Signal's channel. Idempotent.
+func (d *Drainer) Drain() {
+	d.init()
+	d.drain.Do(func() { close(d.draining) })
+}
+
+// Shutdown drains, shuts srv down and waits for tracked streams, all
+// within ctx. Streams are told to finish before Shutdown starts waiting,
+// so a well-behaved one ends in well under the deadline.
+func (d *Drainer) Shutdown(ctx context.Context, srv *http.Server) error {
+	d.Drain()
+	err := srv.Shutdown(ctx)
+	if werr := d.wait(ctx); err == nil {
+		err = werr
+	}
+	return err
+}
+
+// wait polls like http.Server.Shutdown does, since streams only ever
+// decrease once draining has started.
+func (d *Drainer) wait(ctx context.Context) error {
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	for d.Streams() > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
@@ -0,0 +1,281 @@
+package draining_test
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"patterns/options/functional"
+	"patterns/web/draining"
+	"patterns/web/streaming"
+)
+
+// serve starts srv on a loopback port and returns its URL.
+func serve(t *testing.T, srv *http.Server) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go srv.Serve(l)
+	t.Cleanup(func() { srv.Close() })
+	return "http://" + l.Addr().String()
+}
+
+// ended wraps next to count the handlers that have returned.
+func ended(next http.Handler, n *atomic.Int64) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer n.Add(1)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// subscribe opens an event stream and returns the names of its events,
+// sent as they arrive, after the first has been received.
+func subscribe(t *testing.T, url string) <-chan string {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make(chan string, 100)
+	dec := streaming.NewDecoder(resp.Body)
+	e, err := dec.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	names <- e.Name
+	go func() {
+		defer resp.Body.Close()
+		defer close(names)
+		for {
+			e, err := dec.Next()
+			if err != nil {
+				return
+			}
+			names <- e.Name
+		}
+	}()
+	return names
+}
+
+func last(names <-chan string) string {
+	var name string
+	for name = range names {
+	}
+	return name
+}
+
+// TestShutdownEndsStreams holds several event streams open and checks
+// that each has sent its shutdown event and returned by the time
+// Shutdown does, well within the deadline.
+func TestShutdownEndsStreams(t *testing.T) {
+	var d draining.Drainer
+	var done atomic.Int64
+	mux := http.NewServeMux()
+	mux.Handle("/events", d.Track(ended(draining.Events(5*time.Millisecond), &done)))
+	srv := &http.Server{Handler: mux}
+	d.Attach(srv)
+	url := serve(t, srv)
+
+	const clients = 5
+	var streams []<-chan string
+	for range clients {
+		streams = append(streams, subscribe(t, url+"/events"))
+	}
+	if n := d.Streams(); n != clients {
+		t.Errorf("Streams = %d, want %d", n, clients)
+	}
+	if n := d.Conns()[http.StateActive]; n != clients {
+		t.Errorf("%d active connections, want %d", n, clients)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	start := time.Now()
+	if err := d.Shutdown(ctx, srv); err != nil {
+		t.Fatalf("Shutdown = %v", err)
+	}
+	if n := done.Load(); n != clients {
+		t.Errorf("%d of %d handlers returned before Shutdown did", n, clients)
+	}
+	if d.Streams() != 0 {
+		t.Errorf("Streams = %d after Shutdown", d.Streams())
+	}
+	if took := time.Since(start); took > 2*time.Second {
+		t.Errorf("Shutdown took %v, want the streams to end on the signal", took)
+	}
+	for i, names := range streams {
+		if name := last(names); name != "shutdown" {
+			t.Errorf("stream %d ended with %q, want the shutdown event", i, name)
+		}
+	}
+}
+
+// hijacked writes lines on a hijacked connection until drained.
+func hijacked(w http.ResponseWriter, r *http.Request) {
+	conn, buf, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	buf.WriteString("HTTP/1.1 200 OK\r\nContent-Type: text/plain\r\n\r\n")
+	buf.Flush()
+	drain := draining.Signal(r.Context())
+	for {
+		select {
+		case <-drain:
+			buf.WriteString("bye\n")
+			buf.Flush()
+			return
+		case <-time.After(5 * time.Millisecond):
+			buf.WriteString("tick\n")
+			buf.Flush()
+		}
+	}
+}
+
+// TestShutdownWaitsForHijacked checks the case http.Server.Shutdown
+// misses: it forgets a hijacked connection, the Drainer does not.
+func TestShutdownWaitsForHijacked(t *testing.T) {
+	var d draining.Drainer
+	var done atomic.Int64
+	srv := &http.Server{Handler: d.Track(ended(http.HandlerFunc(hijacked), &done))}
+	d.Attach(srv)
+	url := serve(t, srv)
+
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	lines := bufio.NewScanner(resp.Body)
+	if !lines.Scan() || lines.Text() != "tick" {
+		t.Fatalf("first line %q, %v", lines.Text(), lines.Err())
+	}
+	if n := len(d.Conns()); n != 0 {
+		t.Errorf("Conns = %v, want the hijacked connection left out", d.Conns())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := d.Shutdown(ctx, srv); err != nil {
+		t.Fatalf("Shutdown = %v", err)
+	}
+	if done.Load() != 1 {
+		t.Error("Shutdown returned while the hijacked stream was still running")
+	}
+	var got string
+	for lines.Scan() {
+		got = lines.Text()
+	}
+	if got != "bye" {
+		t.Errorf("stream ended with %q, want bye", got)
+	}
+}
+
+// TestShutdownDeadline checks that a stream ignoring the signal makes
+// Shutdown give up at its deadline and say so.
+func TestShutdownDeadline(t *testing.T) {
+	var d draining.Drainer
+	release := make(chan struct{})
+	defer close(release)
+	srv := &http.Server{Handler: d.Track(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		http.NewResponseController(w).Flush()
+		<-release
+	}))}
+	d.Attach(srv)
+	url := serve(t, srv)
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := d.Shutdown(ctx, srv); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Shutdown = %v, want the deadline", err)
+	}
+	if d.Streams() != 1 {
+		t.Errorf("Streams = %d, want the stuck one", d.Streams())
+	}
+}
+
+// TestRun drives the same shutdown through the functional server's Run.
+func TestRun(t *testing.T) {
+	var d draining.Drainer
+	var done atomic.Int64
+	s, err := functional.NewServer("127.0.0.1", functional.WithPort(0), functional.WithDrainer(&d),
+		functional.WithHandler(d.Track(ended(draining.Events(5*time.Millisecond), &done))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan error)
+	go func() { stopped <- s.Run(ctx) }()
+	<-s.Ready()
+	names := subscribe(t, "http://"+s.Addr())
+
+	cancel()
+	select {
+	case err := <-stopped:
+		if err != nil {
+			t.Errorf("Run = %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("Run did not return")
+	}
+	if done.Load() != 1 {
+		t.Error("Run returned before the stream ended")
+	}
+	if name := last(names); name != "shutdown" {
+		t.Errorf("stream ended with %q, want the shutdown event", name)
+	}
+}
+
+// TestAttachKeepsHooks checks that hooks set before Attach still run.
+func TestAttachKeepsHooks(t *testing.T) {
+	type key struct{}
+	var d draining.Drainer
+	var states atomic.Int64
+	got := make(chan string, 1)
+	srv := &http.Server{
+		BaseContext: func(net.Listener) context.Context {
+			return context.WithValue(context.Background(), key{}, "base")
+		},
+		ConnState: func(net.Conn, http.ConnState) { states.Add(1) },
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got <- fmt.Sprintf("%v %v", r.Context().Value(key{}), draining.Signal(r.Context()) != nil)
+		}),
+	}
+	d.Attach(srv)
+	url := serve(t, srv)
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if g := <-got; g != "base true" {
+		t.Errorf("handler saw %q, want the base value and a drain signal", g)
+	}
+	if states.Load() == 0 {
+		t.Error("the earlier ConnState hook was not called")
+	}
+}
+
+func TestSignalUnattached(t *testing.T) {
+	if ch := draining.Signal(context.Background()); ch != nil {
+		t.Errorf("Signal outside a server = %v, want nil", ch)
+	}
+	var d draining.Drainer
+	d.Drain()
+	d.Drain()
+}
@@ -0,0 +1,38 @@
+package draining
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"patterns/web/streaming"
+)
+
+// Events is a server-sent events stream of ticks, the kind of handler a
+// Drainer exists for: it never ends on its own. On drain it sends a
+// "shutdown" event, so clients reconnect elsewhere instead of treating the
+// cut as an error, and returns.
+func Events(interval time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s, err := streaming.NewWriter(w)
+		if err != nil {
+			http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+			return
+		}
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		drain := Signal(r.Context())
+		for n := 1; ; n++ {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-drain:
+				s.Send(streaming.Event{Name: "shutdown", Data: "reconnect"})
+				return
+			case <-ticker.C:
+				s.Send(streaming.Event{Name: "tick", Data: strconv.Itoa(n)})
+			}
+		}
+	})
+}
@@ -0,0 +1,186 @@
+// Package handler adapts typed functions func(ctx, Req) (Resp, error) to
+// http.Handler: the adapter decodes the request, calls the function, and
+// maps the result or error to a response, so business code never touches
+// http.ResponseWriter.
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"time"
+
+	"patterns/web/negotiate"
+)
+
+// Func is a typed endpoint.
+type Func[Req, Resp any] func(ctx context.Context, req Req) (Resp, error)
+
+// ErrorMapper converts an error to a status code and response body.
+type ErrorMapper func(err error) (status int, body any)
+
+// StatusCoder lets a response choose its own success status.
+type StatusCoder interface {
+	StatusCode() int
+}
+
+// DecodeError is returned when the request cannot be decoded into Req.
+type DecodeError struct {
+	Err error
+}
+
+func (e *DecodeError) Error() string { return "decode request: " + e.Err.Error() }
+
+func (e *DecodeError) Unwrap() error { return e.Err }
+
+type config struct {
+	mapError ErrorMapper
+	write    func(w http.ResponseWriter, r *http.Request, status int, body any)
+}
+
+type Option func(*config)
+
+// WithErrorMapper replaces DefaultErrorMapper for errors returned by the
+// endpoint.
+func WithErrorMapper(m ErrorMapper) Option {
+	return func(c *config) {
+		c.mapError = m
+	}
+}
+
+// WithNegotiator encodes responses, errors included, in the format the
+// request's Accept header prefers instead of always as JSON.
+func WithNegotiator(n *negotiate.Negotiator) Option {
+	return func(c *config) {
+		c.write = func(w http.ResponseWriter, r *http.Request, status int, body any) {
+			_ = n.Write(w, r, status, body)
+		}
+	}
+}
+
+// DefaultErrorMapper answers 400 for decode errors and 500 otherwise.
+func DefaultErrorMapper(err error) (int, any) {
+	var de *DecodeError
+	if errors.As(err, &de) {
+		return http.StatusBadRequest, map[string]string{"error": de.Error()}
+	}
+	return http.StatusInternalServerError, map[string]string{"error": "internal error"}
+}
+
+// Adapt wraps f as an http.Handler.
+//
+// Req is decoded from the JSON body (if any), then fields tagged
+// `path:"name"` and `query:"name"` are filled from r.PathValue and the
+// query string. time.Duration fields parse as durations ("5s").
+func Adapt[Req, Resp any](f Func[Req, Resp], opts ...Option) http.Handler {
+	cfg := config{mapError: DefaultErrorMapper, write: func(w http.ResponseWriter, _ *http.Request, status int, body any) {
+		WriteJSON(w, status, body)
+	}}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req Req
+		if err := Decode(r, &req); err != nil {
+			// decode failures are the adapter's concern, not the endpoint's
+			status, body := DefaultErrorMapper(err)
+			cfg.write(w, r, status, body)
+			return
+		}
+
+		resp, err := f(r.Context(), req)
+		if err != nil {
+			status, body := cfg.mapError(err)
+			cfg.write(w, r, status, body)
+			return
+		}
+
+		status := http.StatusOK
+		if sc, ok := any(resp).(StatusCoder); ok {
+			status = sc.StatusCode()
+		}
+		cfg.write(w, r, status, resp)
+	})
+}
+
+// Decode fills dst from r's body, path values and query string.
+func Decode(r *http.Request, dst any) error {
+	if r.Body != nil && r.Body != http.NoBody {
+		if err := json.NewDecoder(r.Body).Decode(dst); err != nil && !errors.Is(err, io.EOF) {
+			return &DecodeError{Err: err}
+		}
+	}
+
+	v := reflect.ValueOf(dst).Elem()
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+	t := v.Type()
+	for i := range t.NumField() {
+		field := t.Field(i)
+		var raw string
+		if name, ok := field.Tag.Lookup("path"); ok {
+			raw = r.PathValue(name)
+		} else if name, ok := field.Tag.Lookup("query"); ok {
+			raw = r.URL.Query().Get(name)
+		} else {
+			continue
+		}
+		if raw == "" {
+			continue
+		}
+		if err := setString(v.Field(i), raw); err != nil {
+			return &DecodeError{Err: fmt.Errorf("field %s: %w", field.Name, err)}
+		}
+	}
+
+	return nil
+}
+
+var durationType = reflect.TypeFor[time.Duration]()
+
+func setString(f reflect.Value, raw string) error {
+	if f.Type() == durationType {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		f.SetInt(int64(d))
+		return nil
+	}
+	switch f.Kind() {
+	case reflect.String:
+		f.SetString(raw)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return err
+		}
+		f.SetInt(n)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		f.SetBool(b)
+	default:
+		return fmt.Errorf("unsupported kind %s", f.Kind())
+	}
+
+	return nil
+}
+
+// WriteJSON writes body as JSON with status.
+func WriteJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if body != nil {
+		_ = json.NewEncoder(w).Encode(body)
+	}
+}
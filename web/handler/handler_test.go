@@ -0,0 +1,179 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"patterns/errors/union"
+	"patterns/web/handler"
+	"patterns/web/negotiate"
+)
+
+type createBook struct {
+	Shelf   string        `path:"shelf"`
+	DryRun  bool          `query:"dry_run"`
+	Copies  int           `query:"copies"`
+	Timeout time.Duration `query:"timeout"`
+	Title   string        `json:"title"`
+}
+
+type created struct {
+	Shelf   string `json:"shelf"`
+	Title   string `json:"title"`
+	DryRun  bool   `json:"dry_run"`
+	Copies  int    `json:"copies"`
+	Timeout string `json:"timeout"`
+}
+
+func (created) StatusCode() int { return http.StatusCreated }
+
+type (
+	validation = *union.ValidationError
+	conflict   = *union.ConflictError
+	internal   = *union.InternalError
+)
+
+// create fails with every kind of union.ServiceError, chosen by title.
+func create(_ context.Context, req createBook) (created, error) {
+	switch req.Title {
+	case "":
+		return created{}, union.Of3A[validation, conflict, internal](&union.ValidationError{Field: "title", Reason: "empty"})
+	case "Dune":
+		return created{}, union.Of3B[validation, conflict, internal](&union.ConflictError{Resource: "book", ID: "Dune"})
+	case "crash":
+		return created{}, union.Of3C[validation, conflict, internal](&union.InternalError{Err: errors.New("disk full")})
+	case "unmapped":
+		return created{}, errors.New("secret detail")
+	}
+	return created{Shelf: req.Shelf, Title: req.Title, DryRun: req.DryRun, Copies: req.Copies, Timeout: req.Timeout.String()}, nil
+}
+
+func serve(t *testing.T, h http.Handler, target, body string) (int, map[string]any) {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.Handle("POST /shelves/{shelf}/books", h)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, target, strings.NewReader(body)))
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("%s: Content-Type = %q", target, ct)
+	}
+	var got map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("%s: body %q: %v", target, rec.Body, err)
+	}
+	return rec.Code, got
+}
+
+// TestUnionErrors sends a request failing with each kind of the union;
+// union.MapError handles them all, and hides what is not a ServiceError.
+func TestUnionErrors(t *testing.T) {
+	h := handler.Adapt(create, handler.WithErrorMapper(union.MapError))
+	for _, c := range []struct {
+		title  string
+		status int
+		body   map[string]any
+	}{
+		{"", http.StatusUnprocessableEntity, map[string]any{"error": "invalid title: empty", "field": "title"}},
+		{"Dune", http.StatusConflict, map[string]any{"error": `book "Dune" already exists`}},
+		{"crash", http.StatusInternalServerError, map[string]any{"error": "internal error"}},
+		{"unmapped", http.StatusInternalServerError, map[string]any{"error": "internal error"}},
+	} {
+		status, body := serve(t, h, "/shelves/sf/books", `{"title":"`+c.title+`"}`)
+		if status != c.status || !reflect.DeepEqual(body, c.body) {
+			t.Errorf("%q: %d %v, want %d %v", c.title, status, body, c.status, c.body)
+		}
+	}
+}
+
+func TestDecode(t *testing.T) {
+	h := handler.Adapt(create, handler.WithErrorMapper(union.MapError))
+	status, body := serve(t, h, "/shelves/sf/books?dry_run=true&copies=3&timeout=1m30s", `{"title":"Solaris"}`)
+	want := map[string]any{"shelf": "sf", "title": "Solaris", "dry_run": true, "copies": float64(3), "timeout": "1m30s"}
+	if status != http.StatusCreated || !reflect.DeepEqual(body, want) {
+		t.Errorf("%d %v, want 201 %v", status, body, want)
+	}
+}
+
+// TestDecodeErrors checks that requests the adapter cannot decode are a
+// 400 whatever the endpoint's error mapper.
+func TestDecodeErrors(t *testing.T) {
+	h := handler.Adapt(create, handler.WithErrorMapper(union.MapError))
+	for _, c := range []struct {
+		target, body, error string
+	}{
+		{"/shelves/sf/books", `{"title":`, "decode request: unexpected EOF"},
+		{"/shelves/sf/books", `{"title":7}`, "decode request: json: cannot unmarshal number"},
+		{"/shelves/sf/books?copies=many", `{"title":"x"}`, `decode request: field Copies: strconv.ParseInt: parsing "many"`},
+		{"/shelves/sf/books?timeout=soon", `{"title":"x"}`, `decode request: field Timeout: time: invalid duration "soon"`},
+		{"/shelves/sf/books?dry_run=maybe", `{"title":"x"}`, "decode request: field DryRun"},
+	} {
+		status, body := serve(t, h, c.target, c.body)
+		msg, _ := body["error"].(string)
+		if status != http.StatusBadRequest || !strings.HasPrefix(msg, c.error) {
+			t.Errorf("%s %s: %d %q, want 400 %q", c.target, c.body, status, msg, c.error)
+		}
+	}
+}
+
+func TestDefaultErrorMapper(t *testing.T) {
+	h := handler.Adapt(create)
+	for _, title := range []string{"", "Dune", "unmapped"} {
+		status, body := serve(t, h, "/shelves/sf/books", `{"title":"`+title+`"}`)
+		if want := map[string]any{"error": "internal error"}; status != http.StatusInternalServerError || !reflect.DeepEqual(body, want) {
+			t.Errorf("%q: %d %v, want an opaque 500", title, status, body)
+		}
+	}
+	status, _ := serve(t, h, "/shelves/sf/books", `{"title":"Solaris"}`)
+	if status != http.StatusCreated {
+		t.Errorf("status = %d, want 201", status)
+	}
+}
+
+func TestEmptyBody(t *testing.T) {
+	type ping struct {
+		Name string `query:"name"`
+	}
+	h := handler.Adapt(func(_ context.Context, p ping) (map[string]string, error) {
+		return map[string]string{"pong": p.Name}, nil
+	})
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ping?name=ann", nil))
+	if rec.Code != http.StatusOK || strings.TrimSpace(rec.Body.String()) != `{"pong":"ann"}` {
+		t.Errorf("GET /ping = %d %s", rec.Code, rec.Body)
+	}
+}
+
+// TestNegotiator checks that with a Negotiator the adapter answers in the
+// format asked for, errors included, keeping the status.
+func TestNegotiator(t *testing.T) {
+	h := handler.Adapt(create, handler.WithErrorMapper(union.MapError),
+		handler.WithNegotiator(negotiate.New(negotiate.JSON{}, negotiate.XML{}, negotiate.Text{})))
+	mux := http.NewServeMux()
+	mux.Handle("POST /shelves/{shelf}/books", h)
+	for _, c := range []struct {
+		title, accept string
+		status        int
+		ctype         string
+	}{
+		{"Solaris", "", http.StatusCreated, "application/json"},
+		{"Solaris", "application/xml", http.StatusCreated, "application/xml"},
+		{"Solaris", "text/plain", http.StatusCreated, "text/plain"},
+		{"Dune", "application/xml, application/json;q=0.5", http.StatusConflict, "application/json"},
+		{"Solaris", "image/png", http.StatusNotAcceptable, "text/plain"},
+	} {
+		r := httptest.NewRequest(http.MethodPost, "/shelves/sf/books", strings.NewReader(`{"title":"`+c.title+`"}`))
+		r.Header.Set("Accept", c.accept)
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, r)
+		if ct := rec.Header().Get("Content-Type"); rec.Code != c.status || !strings.HasPrefix(ct, c.ctype) {
+			t.Errorf("%s with Accept %q: %d %s, want %d %s", c.title, c.accept, rec.Code, ct, c.status, c.ctype)
+		}
+	}
+}
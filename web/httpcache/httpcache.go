@@ -0,0 +1,421 @@
+// Package httpcache is a caching decorator for handlers: a shared cache in
+// front of next, the way a CDN would sit in front of the service, backed
+// by caching/lru.
+//
+//	c, err := httpcache.New(httpcache.WithCapacity(1000))
+//	mux.Handle("/catalog/", c.Middleware()(catalog))
+//
+// What it does, per RFC 9111 but only the parts a handler layer needs:
+//
+//   - GET responses with status 200 are stored, unless marked no-store
+//     or private, setting a cookie, Vary: *, or over the size limit; HEAD
+//     requests are answered from them but never stored;
+//   - a request with Authorization is neither answered from nor stored
+//     in the cache unless the response says it may be shared, with
+//     public, s-maxage or must-revalidate (RFC 9111 section 3.5);
+//   - an entry is fresh for the response's s-maxage or max-age (no-cache
+//     means zero), or WithDefaultMaxAge when the handler says nothing,
+//     and is served without calling next;
+//   - a stale entry is revalidated: next gets If-None-Match with the
+//     stored ETag, and a 304 from it refreshes the entry instead of
+//     re-sending the body;
+//   - responses get an ETag (a body hash if the handler set none), and a
+//     client's matching If-None-Match is answered with 304;
+//   - Vary is honoured: each combination of the listed request headers is
+//     its own variant under the same key.
+//
+// The X-Cache response header says HIT, MISS or REVALIDATED. Cacheable
+// requests are buffered in full, so keep streaming endpoints out of it.
+package httpcache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"patterns/caching/lru"
+	"patterns/clock"
+	"patterns/construct"
+	"patterns/funcopts"
+	"patterns/web/middleware"
+)
+
+// KeyFunc maps a request to its cache key. Requests with equal keys share
+// an entry (subject to Vary), so a key must include everything the
+// response depends on that Vary does not cover.
+type KeyFunc func(r *http.Request) string
+
+// KeyURL keys by host and request URI, query included. The default.
+func KeyURL(r *http.Request) string { return r.Host + r.URL.RequestURI() }
+
+// KeyPath keys by host and path, ignoring the query; for handlers whose
+// query only carries tracking parameters.
+func KeyPath(r *http.Request) string { return r.Host + r.URL.Path }
+
+// KeyWithHeaders extends base with the values of request headers, for
+// responses that depend on headers the handler does not list in Vary
+// (a tenant header set by a gateway, say).
+func KeyWithHeaders(base KeyFunc, names ...string) KeyFunc {
+	return func(r *http.Request) string {
+		var b strings.Builder
+		b.WriteString(base(r))
+		for _, n := range names {
+			b.WriteString("\x00")
+			b.WriteString(r.Header.Get(n))
+		}
+		return b.String()
+	}
+}
+
+type options struct {
+	capacity      int
+	key           KeyFunc
+	defaultMaxAge time.Duration
+	maxBody       int
+	clock         clock.Clock
+}
+
+type Option = funcopts.Option[options]
+
+// WithCapacity sets how many keys the cache holds; variants of one key
+// count once.
+func WithCapacity(n int) Option {
+	return func(options *options) error {
+		if n <= 0 {
+			return errors.New("capacity must be positive")
+		}
+		options.capacity = n
+		return nil
+	}
+}
+
+func WithKey(key KeyFunc) Option {
+	return func(options *options) error {
+		if key == nil {
+			return errors.New("key func cannot be nil")
+		}
+		options.key = key
+		return nil
+	}
+}
+
+// WithDefaultMaxAge sets the freshness of responses without a max-age.
+// The default, zero, still stores them, but revalidates on every request.
+func WithDefaultMaxAge(d time.Duration) Option {
+	return func(options *options) error {
+		if d < 0 {
+			return errors.New("default max-age cannot be negative")
+		}
+		options.defaultMaxAge = d
+		return nil
+	}
+}
+
+// WithMaxBody sets the largest body stored, in bytes.
+func WithMaxBody(n int) Option {
+	return func(options *options) error {
+		if n <= 0 {
+			return errors.New("max body must be positive")
+		}
+		options.maxBody = n
+		return nil
+	}
+}
+
+func WithClock(c clock.Clock) Option {
+	return func(options *options) error {
+		if c == nil {
+			return errors.New("clock cannot be nil")
+		}
+		options.clock = c
+		return nil
+	}
+}
+
+func (o *options) SetDefaults() {
+	o.capacity = 1024
+	o.key = KeyURL
+	o.maxBody = 1 << 20
+	o.clock = clock.Real
+}
+
+// Cache is a response cache. Safe for concurrent use.
+type Cache struct {
+	options options
+	lru     *lru.Cache[string, *resource]
+}
+
+func New(opts ...Option) (*Cache, error) {
+	options, err := construct.New(opts...)
+	if err != nil {
+		return nil, err
+	}
+	l, err := lru.New[string, *resource](options.capacity)
+	if err != nil {
+		return nil, err
+	}
+	return &Cache{options: *options, lru: l}, nil
+}
+
+// resource is everything stored under one key. It is replaced, never
+// modified, so readers need no lock.
+type resource struct {
+	vary     []string // canonical header names
+	variants map[string]*entry
+}
+
+func (res *resource) variantKey(r *http.Request) string {
+	var b strings.Builder
+	for _, h := range res.vary {
+		b.WriteString(strings.Join(r.Header.Values(h), ","))
+		b.WriteString("\x00")
+	}
+	return b.String()
+}
+
+type entry struct {
+	header http.Header
+	body   []byte
+	etag   string
+	stored time.Time
+	maxAge time.Duration
+	// shared is whether the response may answer a request with
+	// Authorization.
+	shared bool
+}
+
+func (e *entry) fresh(now time.Time) bool { return now.Sub(e.stored) < e.maxAge }
+
+// Middleware returns the caching decorator.
+func (c *Cache) Middleware() middleware.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			c.serveHTTP(next, w, r)
+		})
+	}
+}
+
+func (c *Cache) serveHTTP(next http.Handler, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		next.ServeHTTP(w, r)
+		return
+	}
+	key := c.options.key(r)
+	now := c.options.clock.Now()
+	res, _ := c.lru.Get(key)
+	var cached *entry
+	if res != nil {
+		cached = res.variants[res.variantKey(r)]
+	}
+	if cached != nil && authorized(r) && !cached.shared {
+		// answered for someone else, without leave to share it
+		cached = nil
+	}
+	if cached != nil && cached.fresh(now) && !directives(r.Header).has("no-cache") {
+		c.serve(w, r, cached, now, "HIT")
+		return
+	}
+
+	inner := r
+	if cached != nil {
+		inner = r.Clone(r.Context())
+		inner.Header.Set("If-None-Match", cached.etag)
+	}
+	rec := &capture{header: http.Header{}}
+	next.ServeHTTP(rec, inner)
+
+	if cached != nil && rec.status == http.StatusNotModified {
+		refreshed := *cached
+		refreshed.stored = now
+		if maxAge, ok := c.freshness(rec.header); ok {
+			refreshed.maxAge = maxAge
+		}
+		c.store(key, r, res, cached.header.Values("Vary"), &refreshed)
+		c.serve(w, r, &refreshed, now, "REVALIDATED")
+		return
+	}
+	if e := c.entryFrom(r, rec, now); e != nil {
+		c.store(key, r, res, rec.header.Values("Vary"), e)
+		c.serve(w, r, e, now, "MISS")
+		return
+	}
+	rec.header.Set("X-Cache", "MISS")
+	rec.writeTo(w)
+}
+
+// entryFrom returns the entry to store for rec, or nil if it must not be
+// stored. Only GET responses are: a HEAD response has no body to serve
+// later GETs with. Nor is the response to a request with Authorization,
+// unless it says it may be shared.
+func (c *Cache) entryFrom(r *http.Request, rec *capture, now time.Time) *entry {
+	if r.Method != http.MethodGet || rec.status != http.StatusOK || len(rec.body) > c.options.maxBody {
+		return nil
+	}
+	shared := sharable(rec.header)
+	if authorized(r) && !shared {
+		return nil
+	}
+	if rec.header.Get("Set-Cookie") != "" || slices.Contains(varyNames(rec.header), "*") {
+		return nil
+	}
+	maxAge, ok := c.freshness(rec.header)
+	if !ok {
+		return nil
+	}
+	etag := rec.header.Get("ETag")
+	if etag == "" {
+		sum := sha256.Sum256(rec.body)
+		etag = `"` + hex.EncodeToString(sum[:8]) + `"`
+	}
+	header := rec.header.Clone()
+	for _, h := range []string{"Connection", "Keep-Alive", "Transfer-Encoding", "Date", "X-Cache"} {
+		header.Del(h)
+	}
+	header.Set("ETag", etag)
+	return &entry{header: header, body: rec.body, etag: etag, stored: now, maxAge: maxAge, shared: shared}
+}
+
+func authorized(r *http.Request) bool { return r.Header.Get("Authorization") != "" }
+
+// sharable reports whether a response to a request with Authorization
+// may be stored and used for others: RFC 9111 section 3.5.
+func sharable(h http.Header) bool {
+	d := directives(h)
+	return d.has("public") || d.has("s-maxage") || d.has("must-revalidate")
+}
+
+// freshness reads the max-age of a response, ok false if it must not be
+// stored at all.
+func (c *Cache) freshness(h http.Header) (maxAge time.Duration, ok bool) {
+	d := directives(h)
+	switch {
+	case d.has("no-store"), d.has("private"):
+		return 0, false
+	case d.has("no-cache"):
+		return 0, true
+	}
+	// s-maxage is for shared caches, such as this one, and wins
+	for _, name := range []string{"s-maxage", "max-age"} {
+		if v, found := d[name]; found {
+			secs, err := strconv.Atoi(v)
+			if err != nil || secs < 0 {
+				return 0, false
+			}
+			return time.Duration(secs) * time.Second, true
+		}
+	}
+	return c.options.defaultMaxAge, true
+}
+
+func (c *Cache) store(key string, r *http.Request, old *resource, vary []string, e *entry) {
+	names := varyNames(http.Header{"Vary": vary})
+	res := &resource{vary: names, variants: map[string]*entry{}}
+	if old != nil && slices.Equal(old.vary, names) {
+		// same variant dimensions: keep the other variants
+		for k, v := range old.variants {
+			res.variants[k] = v
+		}
+	}
+	res.variants[res.variantKey(r)] = e
+	c.lru.Add(key, res)
+}
+
+func (c *Cache) serve(w http.ResponseWriter, r *http.Request, e *entry, now time.Time, status string) {
+	h := w.Header()
+	for k, v := range e.header {
+		h[k] = slices.Clone(v)
+	}
+	h.Set("Age", strconv.Itoa(int(now.Sub(e.stored).Seconds())))
+	h.Set("X-Cache", status)
+	if etagMatch(r.Header.Get("If-None-Match"), e.etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	h.Set("Content-Length", strconv.Itoa(len(e.body)))
+	w.WriteHeader(http.StatusOK)
+	if r.Method != http.MethodHead {
+		w.Write(e.body)
+	}
+}
+
+// etagMatch implements the weak comparison If-None-Match uses.
+func etagMatch(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	for _, t := range strings.Split(ifNoneMatch, ",") {
+		t = strings.TrimSpace(t)
+		if t == "*" || strings.TrimPrefix(t, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+func varyNames(h http.Header) []string {
+	var names []string
+	for _, v := range h.Values("Vary") {
+		for _, n := range strings.Split(v, ",") {
+			if n = strings.TrimSpace(n); n != "" {
+				names = append(names, http.CanonicalHeaderKey(n))
+			}
+		}
+	}
+	slices.Sort(names)
+	return slices.Compact(names)
+}
+
+type cacheControl map[string]string
+
+func directives(h http.Header) cacheControl {
+	d := cacheControl{}
+	for _, v := range h.Values("Cache-Control") {
+		for _, part := range strings.Split(v, ",") {
+			name, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+			d[strings.ToLower(name)] = strings.Trim(value, `"`)
+		}
+	}
+	return d
+}
+
+func (d cacheControl) has(name string) bool {
+	_, ok := d[name]
+	return ok
+}
+
+// capture buffers a response from next.
+type capture struct {
+	header http.Header
+	status int
+	body   []byte
+}
+
+func (c *capture) Header() http.Header { return c.header }
+
+func (c *capture) WriteHeader(status int) {
+	if c.status == 0 {
+		c.status = status
+	}
+}
+
+func (c *capture) Write(b []byte) (int, error) {
+	c.WriteHeader(http.StatusOK)
+	c.body = append(c.body, b...)
+	return len(b), nil
+}
+
+func (c *capture) writeTo(w http.ResponseWriter) {
+	for k, v := range c.header {
+		w.Header()[k] = v
+	}
+	if c.status == 0 {
+		c.status = http.StatusOK
+	}
+	w.WriteHeader(c.status)
+	w.Write(c.body)
+}
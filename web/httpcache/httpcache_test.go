@@ -0,0 +1,213 @@
+package httpcache_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"patterns/clock"
+	"patterns/web/httpcache"
+)
+
+// origin is a handler that counts its calls and answers 304 to a
+// matching If-None-Match, as a handler that supports revalidation does.
+type origin struct {
+	calls, revalidations int
+	cacheControl         string
+	etag                 string
+	vary                 string
+}
+
+func (o *origin) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	o.calls++
+	if o.cacheControl != "" {
+		w.Header().Set("Cache-Control", o.cacheControl)
+	}
+	if o.vary != "" {
+		w.Header().Set("Vary", o.vary)
+	}
+	w.Header().Set("ETag", o.etag)
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		o.revalidations++
+		if inm == o.etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+	fmt.Fprintf(w, "body %d lang=%s user=%s", o.calls, r.Header.Get("Accept-Language"), r.Header.Get("Authorization"))
+}
+
+func newHandler(t *testing.T, o *origin) (http.Handler, *clock.Fake) {
+	c := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	cache, err := httpcache.New(httpcache.WithClock(c))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cache.Middleware()(o), c
+}
+
+type response struct {
+	status      int
+	cache, body string
+	etag        string
+}
+
+func get(h http.Handler, header ...string) response {
+	r := httptest.NewRequest(http.MethodGet, "/catalog", nil)
+	for i := 0; i < len(header); i += 2 {
+		r.Header.Set(header[i], header[i+1])
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return response{w.Code, w.Header().Get("X-Cache"), w.Body.String(), w.Header().Get("ETag")}
+}
+
+func TestFreshEntryIsServedWithoutCallingNext(t *testing.T) {
+	o := &origin{cacheControl: "max-age=60", etag: `"v1"`}
+	h, c := newHandler(t, o)
+	if got := get(h); got.cache != "MISS" || got.body != "body 1 lang= user=" {
+		t.Fatalf("first: %+v", got)
+	}
+	c.Advance(59 * time.Second)
+	if got := get(h); got.cache != "HIT" || got.body != "body 1 lang= user=" || o.calls != 1 {
+		t.Fatalf("within max-age: %+v after %d calls", got, o.calls)
+	}
+}
+
+func TestStaleEntryIsRevalidated(t *testing.T) {
+	o := &origin{cacheControl: "max-age=60", etag: `"v1"`}
+	h, c := newHandler(t, o)
+	get(h)
+	c.Advance(61 * time.Second)
+	got := get(h)
+	if got.cache != "REVALIDATED" || got.status != http.StatusOK || got.body != "body 1 lang= user=" {
+		t.Fatalf("stale: %+v", got)
+	}
+	if o.revalidations != 1 {
+		t.Fatalf("next saw %d conditional requests, want 1", o.revalidations)
+	}
+	// the 304 refreshed the entry
+	if got := get(h); got.cache != "HIT" || o.calls != 2 {
+		t.Fatalf("after revalidation: %+v after %d calls", got, o.calls)
+	}
+
+	// a changed resource answers the conditional request in full
+	c.Advance(61 * time.Second)
+	o.etag = `"v2"`
+	if got := get(h); got.cache != "MISS" || got.body != "body 3 lang= user=" || got.etag != `"v2"` {
+		t.Fatalf("changed: %+v", got)
+	}
+}
+
+func TestClientIfNoneMatchGets304(t *testing.T) {
+	o := &origin{cacheControl: "max-age=60", etag: `"v1"`}
+	h, _ := newHandler(t, o)
+	get(h)
+	if got := get(h, "If-None-Match", `W/"v1"`); got.status != http.StatusNotModified || got.body != "" || got.cache != "HIT" {
+		t.Fatalf("matching If-None-Match: %+v", got)
+	}
+	if got := get(h, "If-None-Match", `"v0", "v2"`); got.status != http.StatusOK {
+		t.Fatalf("other If-None-Match: %+v", got)
+	}
+}
+
+func TestBodyHashETag(t *testing.T) {
+	o := &origin{cacheControl: "max-age=60"}
+	h, _ := newHandler(t, o)
+	first := get(h)
+	if first.etag == "" {
+		t.Fatalf("no ETag on %+v", first)
+	}
+	if got := get(h, "If-None-Match", first.etag); got.status != http.StatusNotModified {
+		t.Fatalf("If-None-Match of the generated ETag: %+v", got)
+	}
+}
+
+func TestVaryKeepsAVariantPerHeaderValue(t *testing.T) {
+	o := &origin{cacheControl: "max-age=60", etag: `"v1"`, vary: "Accept-Language"}
+	h, _ := newHandler(t, o)
+	en, de := get(h, "Accept-Language", "en"), get(h, "Accept-Language", "de")
+	if en.cache != "MISS" || de.cache != "MISS" || en.body == de.body {
+		t.Fatalf("en %+v, de %+v", en, de)
+	}
+	for _, lang := range []string{"en", "de"} {
+		got := get(h, "Accept-Language", lang)
+		if want := map[string]string{"en": en.body, "de": de.body}[lang]; got.cache != "HIT" || got.body != want {
+			t.Fatalf("%s: %+v, want %q", lang, got, want)
+		}
+	}
+	if o.calls != 2 {
+		t.Fatalf("%d calls, want 2", o.calls)
+	}
+}
+
+func TestVaryStarIsNotStored(t *testing.T) {
+	o := &origin{cacheControl: "max-age=60", etag: `"v1"`, vary: "*"}
+	h, _ := newHandler(t, o)
+	get(h)
+	if got := get(h); got.cache != "MISS" || o.calls != 2 {
+		t.Fatalf("%+v after %d calls", got, o.calls)
+	}
+}
+
+func TestAuthorization(t *testing.T) {
+	for _, c := range []struct {
+		cacheControl string
+		shared       bool
+	}{
+		{"max-age=60", false},
+		{"public, max-age=60", true},
+		{"s-maxage=60", true},
+		{"max-age=60, must-revalidate", true},
+	} {
+		t.Run(c.cacheControl, func(t *testing.T) {
+			o := &origin{cacheControl: c.cacheControl, etag: `"v1"`}
+			h, _ := newHandler(t, o)
+			get(h, "Authorization", "Bearer ann")
+			bo := get(h, "Authorization", "Bearer bo")
+			anon := get(h)
+			if !c.shared {
+				if bo.cache != "MISS" || bo.body != "body 2 lang= user=Bearer bo" {
+					t.Fatalf("another user got %+v", bo)
+				}
+				if anon.cache != "MISS" || anon.body != "body 3 lang= user=" {
+					t.Fatalf("an anonymous request got %+v", anon)
+				}
+				return
+			}
+			if bo.cache != "HIT" || anon.cache != "HIT" || o.calls != 1 {
+				t.Fatalf("another user got %+v, an anonymous request %+v, after %d calls", bo, anon, o.calls)
+			}
+		})
+	}
+}
+
+// A response stored for an anonymous request is not replayed to one with
+// Authorization, which goes to next, and whose unshared answer does not
+// replace it.
+func TestAuthorizedRequestBypassesAnUnsharedEntry(t *testing.T) {
+	o := &origin{cacheControl: "max-age=60", etag: `"v1"`}
+	h, _ := newHandler(t, o)
+	get(h)
+	if got := get(h, "Authorization", "Bearer ann"); got.cache != "MISS" || got.body != "body 2 lang= user=Bearer ann" {
+		t.Fatalf("authorized: %+v", got)
+	}
+	if o.revalidations != 0 {
+		t.Fatalf("the authorized request was sent the anonymous ETag")
+	}
+	if got := get(h); got.cache != "HIT" || got.body != "body 1 lang= user=" {
+		t.Fatalf("anonymous after: %+v", got)
+	}
+}
+
+func TestSMaxAgeWinsOverMaxAge(t *testing.T) {
+	o := &origin{cacheControl: "max-age=10, s-maxage=60", etag: `"v1"`}
+	h, c := newHandler(t, o)
+	get(h)
+	c.Advance(30 * time.Second)
+	if got := get(h); got.cache != "HIT" {
+		t.Fatalf("within s-maxage: %+v", got)
+	}
+}
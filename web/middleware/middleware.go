@@ -0,0 +1,40 @@
+// Package middleware composes http.Handler decorators.
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"patterns/web/responserecorder"
+)
+
+// Middleware decorates a handler.
+type Middleware func(http.Handler) http.Handler
+
+// Chain applies mw so that the first one is the outermost.
+func Chain(h http.Handler, mw ...Middleware) http.Handler {
+	for i := len(mw) - 1; i >= 0; i-- {
+		h = mw[i](h)
+	}
+	return h
+}
+
+// Logging logs method, path, status, size and duration of every request.
+func Logging(logger *slog.Logger) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			ww, rec := responserecorder.Wrap(w)
+			next.ServeHTTP(ww, r)
+			logger.LogAttrs(r.Context(), slog.LevelInfo, "request",
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.Path),
+				slog.Int("status", rec.Status()),
+				slog.Int64("bytes", rec.Bytes()),
+				slog.Bool("hijacked", rec.Hijacked()),
+				slog.Duration("duration", time.Since(start)),
+			)
+		})
+	}
+}
@@ -0,0 +1,298 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Predicate decides whether a middleware applies to a request.
+type Predicate func(*http.Request) bool
+
+// PathPrefix matches requests whose path starts with prefix.
+func PathPrefix(prefix string) Predicate {
+	return func(r *http.Request) bool { return strings.HasPrefix(r.URL.Path, prefix) }
+}
+
+// Method matches requests with one of methods.
+func Method(methods ...string) Predicate {
+	return func(r *http.Request) bool {
+		for _, m := range methods {
+			if r.Method == m {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+type entry struct {
+	name   string
+	mw     Middleware
+	groups []string
+	before []string
+	after  []string
+	when   Predicate
+}
+
+// Constraint configures a named middleware in a Stack.
+type Constraint func(*entry)
+
+// Before orders the middleware outside (earlier than) the named ones.
+// A name may be a group, standing for each of its members.
+func Before(names ...string) Constraint {
+	return func(e *entry) { e.before = append(e.before, names...) }
+}
+
+// After orders the middleware inside (later than) the named ones.
+// A name may be a group, standing for each of its members.
+func After(names ...string) Constraint {
+	return func(e *entry) { e.after = append(e.after, names...) }
+}
+
+// InGroup adds the middleware to the named groups, so others can be
+// ordered against all of them at once, e.g. logging Before("auth")
+// whatever the auth group holds. A group needs no declaration, but its
+// name must not also be a middleware's.
+func InGroup(groups ...string) Constraint {
+	return func(e *entry) { e.groups = append(e.groups, groups...) }
+}
+
+// When applies the middleware only to requests matching p.
+func When(p Predicate) Constraint {
+	return func(e *entry) { e.when = p }
+}
+
+// Stack collects named middleware and resolves their order from
+// Before/After constraints, on middleware or on groups of them.
+// Unconstrained middleware keep registration order.
+type Stack struct {
+	entries []*entry
+}
+
+// Use registers mw under name.
+func (s *Stack) Use(name string, mw Middleware, cs ...Constraint) *Stack {
+	e := &entry{name: name, mw: mw}
+	for _, c := range cs {
+		c(e)
+	}
+	s.entries = append(s.entries, e)
+	return s
+}
+
+// OrderReason is why a stack cannot be ordered.
+type OrderReason int
+
+const (
+	// Duplicate: a name is registered twice, or is both a middleware
+	// and a group.
+	Duplicate OrderReason = iota + 1
+	// Unknown: a constraint names neither a middleware nor a group.
+	Unknown
+	// Cycle: the constraints contradict each other.
+	Cycle
+)
+
+func (r OrderReason) String() string {
+	switch r {
+	case Duplicate:
+		return "duplicate"
+	case Unknown:
+		return "unknown"
+	case Cycle:
+		return "cycle"
+	}
+	return fmt.Sprintf("OrderReason(%d)", int(r))
+}
+
+// OrderError reports an unsatisfiable stack. Names are the duplicate
+// name; the constrained middleware and the unknown name; or the cycle,
+// first name repeated last.
+type OrderError struct {
+	Reason OrderReason
+	Names  []string
+}
+
+func (e *OrderError) Error() string {
+	switch e.Reason {
+	case Duplicate:
+		return fmt.Sprintf("middleware: %q registered twice", e.Names[0])
+	case Unknown:
+		return fmt.Sprintf("middleware: %q has a constraint on unknown middleware or group %q", e.Names[0], e.Names[1])
+	default:
+		return "middleware: ordering cycle: " + strings.Join(e.Names, " -> ")
+	}
+}
+
+// Order returns the resolved names, outermost first.
+func (s *Stack) Order() ([]string, error) {
+	sorted, err := s.sort()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(sorted))
+	for i, e := range sorted {
+		names[i] = e.name
+	}
+	return names, nil
+}
+
+// Handler wraps h with the resolved stack.
+func (s *Stack) Handler(h http.Handler) (http.Handler, error) {
+	sorted, err := s.sort()
+	if err != nil {
+		return nil, err
+	}
+	for i := len(sorted) - 1; i >= 0; i-- {
+		e := sorted[i]
+		if e.when == nil {
+			h = e.mw(h)
+			continue
+		}
+		h = conditional(e.when, e.mw, h)
+	}
+	return h, nil
+}
+
+func conditional(p Predicate, mw Middleware, next http.Handler) http.Handler {
+	wrapped := mw(next)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if p(r) {
+			wrapped.ServeHTTP(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// sort is Kahn's algorithm, always picking the earliest registered entry
+// among those ready so the result is deterministic.
+func (s *Stack) sort() ([]*entry, error) {
+	index := map[string]int{}
+	for i, e := range s.entries {
+		if _, dup := index[e.name]; dup {
+			return nil, &OrderError{Reason: Duplicate, Names: []string{e.name}}
+		}
+		index[e.name] = i
+	}
+	members := map[string][]int{}
+	for i, e := range s.entries {
+		for _, g := range e.groups {
+			if _, clash := index[g]; clash {
+				return nil, &OrderError{Reason: Duplicate, Names: []string{g}}
+			}
+			members[g] = append(members[g], i)
+		}
+	}
+	// resolve returns the entries name stands for in the constraints of
+	// entry i; a member ordered against its own group is ordered against
+	// the other members
+	resolve := func(i int, name string) ([]int, error) {
+		if j, ok := index[name]; ok {
+			return []int{j}, nil
+		}
+		js, ok := members[name]
+		if !ok {
+			return nil, &OrderError{Reason: Unknown, Names: []string{s.entries[i].name, name}}
+		}
+		var others []int
+		for _, j := range js {
+			if j != i {
+				others = append(others, j)
+			}
+		}
+		return others, nil
+	}
+
+	// edges[a] contains b when a must be outside b
+	edges := make([][]int, len(s.entries))
+	indegree := make([]int, len(s.entries))
+	addEdge := func(from, to int) {
+		edges[from] = append(edges[from], to)
+		indegree[to]++
+	}
+	for i, e := range s.entries {
+		for _, name := range e.before {
+			js, err := resolve(i, name)
+			if err != nil {
+				return nil, err
+			}
+			for _, j := range js {
+				addEdge(i, j)
+			}
+		}
+		for _, name := range e.after {
+			js, err := resolve(i, name)
+			if err != nil {
+				return nil, err
+			}
+			for _, j := range js {
+				addEdge(j, i)
+			}
+		}
+	}
+
+	done := make([]bool, len(s.entries))
+	var out []*entry
+	for len(out) < len(s.entries) {
+		next := -1
+		for i := range s.entries {
+			if !done[i] && indegree[i] == 0 {
+				next = i
+				break
+			}
+		}
+		if next < 0 {
+			return nil, &OrderError{Reason: Cycle, Names: s.cycle(edges, done)}
+		}
+		done[next] = true
+		out = append(out, s.entries[next])
+		for _, j := range edges[next] {
+			indegree[j]--
+		}
+	}
+
+	return out, nil
+}
+
+// cycle finds one cycle among the unsorted entries for the error message.
+func (s *Stack) cycle(edges [][]int, done []bool) []string {
+	state := make([]int, len(s.entries)) // 0 new, 1 on path, 2 finished
+	var path []int
+	var found []int
+	var visit func(int) bool
+	visit = func(i int) bool {
+		state[i] = 1
+		path = append(path, i)
+		for _, j := range edges[i] {
+			if done[j] {
+				continue
+			}
+			if state[j] == 1 {
+				for k, p := range path {
+					if p == j {
+						found = append(append([]int(nil), path[k:]...), j)
+					}
+				}
+				return true
+			}
+			if state[j] == 0 && visit(j) {
+				return true
+			}
+		}
+		path = path[:len(path)-1]
+		state[i] = 2
+		return false
+	}
+	for i := range s.entries {
+		if !done[i] && state[i] == 0 && visit(i) {
+			break
+		}
+	}
+
+	names := make([]string, len(found))
+	for i, n := range found {
+		names[i] = s.entries[n].name
+	}
+	return names
+}
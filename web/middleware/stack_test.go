@@ -0,0 +1,191 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// tag appends name to the X-Trace header, so a response shows which
+// middleware ran, outermost first.
+func tag(name string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("X-Trace", name)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func (s *Stack) use(name string, cs ...Constraint) *Stack { return s.Use(name, tag(name), cs...) }
+
+func TestStackOrder(t *testing.T) {
+	for _, c := range []struct {
+		name  string
+		stack *Stack
+		want  []string
+	}{
+		{"registration order", new(Stack).use("a").use("b").use("c"), []string{"a", "b", "c"}},
+		// the earliest registered entry that is free to go goes first
+		{"before", new(Stack).use("a").use("b").use("c", Before("a")), []string{"b", "c", "a"}},
+		{"after", new(Stack).use("a", After("c")).use("b").use("c"), []string{"b", "c", "a"}},
+		{"both", new(Stack).use("auth").use("handler-log", After("auth")).use("recover", Before("auth", "handler-log")),
+			[]string{"recover", "auth", "handler-log"}},
+		{
+			"before a group",
+			new(Stack).
+				use("session", InGroup("auth")).
+				use("csrf", InGroup("auth")).
+				use("log", Before("auth")),
+			[]string{"log", "session", "csrf"},
+		},
+		{
+			"after a group",
+			new(Stack).
+				use("metrics", After("edge")).
+				use("cors", InGroup("edge")).
+				use("gzip", InGroup("edge")).
+				use("app"),
+			[]string{"cors", "gzip", "metrics", "app"},
+		},
+		{
+			"group ordered against a group",
+			new(Stack).
+				use("session", InGroup("auth"), After("edge")).
+				use("token", InGroup("auth"), After("edge")).
+				use("cors", InGroup("edge")),
+			[]string{"cors", "session", "token"},
+		},
+		{
+			"member against its own group",
+			new(Stack).
+				use("session", InGroup("auth")).
+				use("rate", InGroup("auth"), Before("auth")).
+				use("csrf", InGroup("auth")),
+			[]string{"rate", "session", "csrf"},
+		},
+		{"only member against its group", new(Stack).use("a", InGroup("g"), Before("g")), []string{"a"}},
+	} {
+		got, err := c.stack.Order()
+		if err != nil {
+			t.Errorf("%s: %v", c.name, err)
+			continue
+		}
+		if !slices.Equal(got, c.want) {
+			t.Errorf("%s: Order = %v, want %v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestStackConflicts(t *testing.T) {
+	for _, c := range []struct {
+		name   string
+		stack  *Stack
+		reason OrderReason
+		names  []string
+		msg    string
+	}{
+		{
+			"duplicate", new(Stack).use("a").use("b").use("a"),
+			Duplicate, []string{"a"}, `middleware: "a" registered twice`,
+		},
+		{
+			"group named like a middleware", new(Stack).use("auth").use("session", InGroup("auth")),
+			Duplicate, []string{"auth"}, `middleware: "auth" registered twice`,
+		},
+		{
+			"unknown before", new(Stack).use("a", Before("nope")),
+			Unknown, []string{"a", "nope"}, `middleware: "a" has a constraint on unknown middleware or group "nope"`,
+		},
+		{
+			"unknown after", new(Stack).use("a").use("b", After("a", "nope")),
+			Unknown, []string{"b", "nope"}, `middleware: "b" has a constraint on unknown middleware or group "nope"`,
+		},
+		{
+			"self", new(Stack).use("a", Before("a")),
+			Cycle, []string{"a", "a"}, "middleware: ordering cycle: a -> a",
+		},
+		{
+			"two way", new(Stack).use("a", Before("b")).use("b", Before("a")),
+			Cycle, []string{"a", "b", "a"}, "middleware: ordering cycle: a -> b -> a",
+		},
+		{
+			"before and after the same", new(Stack).use("a").use("b", Before("a"), After("a")),
+			Cycle, []string{"a", "b", "a"}, "middleware: ordering cycle: a -> b -> a",
+		},
+		{
+			"three way, after unrelated ones",
+			new(Stack).use("x").use("a", Before("b")).use("b", Before("c")).use("c", Before("a")),
+			Cycle, []string{"a", "b", "c", "a"}, "middleware: ordering cycle: a -> b -> c -> a",
+		},
+		{
+			"through a group",
+			new(Stack).use("log", Before("auth")).use("session", InGroup("auth")).use("csrf", InGroup("auth"), Before("log")),
+			Cycle, []string{"log", "csrf", "log"}, "middleware: ordering cycle: log -> csrf -> log",
+		},
+	} {
+		_, err := c.stack.Order()
+		var oe *OrderError
+		if !errors.As(err, &oe) {
+			t.Errorf("%s: err = %v, want an OrderError", c.name, err)
+			continue
+		}
+		if oe.Reason != c.reason || !slices.Equal(oe.Names, c.names) {
+			t.Errorf("%s: %v %q, want %v %q", c.name, oe.Reason, oe.Names, c.reason, c.names)
+		}
+		if err.Error() != c.msg {
+			t.Errorf("%s: Error() = %q, want %q", c.name, err, c.msg)
+		}
+		// Handler reports the same error
+		if _, herr := c.stack.Handler(http.NotFoundHandler()); herr == nil || herr.Error() != c.msg {
+			t.Errorf("%s: Handler err = %v", c.name, herr)
+		}
+	}
+}
+
+func TestOrderReasonString(t *testing.T) {
+	for r, want := range map[OrderReason]string{Duplicate: "duplicate", Unknown: "unknown", Cycle: "cycle", 0: "OrderReason(0)"} {
+		if got := r.String(); got != want {
+			t.Errorf("String() = %q, want %q", got, want)
+		}
+	}
+}
+
+func TestStackHandler(t *testing.T) {
+	h, err := new(Stack).
+		use("log").
+		use("auth", InGroup("security"), After("log"), When(PathPrefix("/admin"))).
+		use("csrf", InGroup("security"), After("log"), When(Method(http.MethodPost, http.MethodPut))).
+		use("recover", Before("log")).
+		Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("X-Trace", "handler")
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		method, path, want string
+	}{
+		{http.MethodGet, "/", "recover,log,handler"},
+		{http.MethodGet, "/admin/users", "recover,log,auth,handler"},
+		{http.MethodPost, "/", "recover,log,csrf,handler"},
+		{http.MethodPut, "/admin", "recover,log,auth,csrf,handler"},
+	} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(c.method, c.path, nil))
+		if got := strings.Join(rec.Header().Values("X-Trace"), ","); got != c.want {
+			t.Errorf("%s %s: trace %s, want %s", c.method, c.path, got, c.want)
+		}
+	}
+}
+
+func TestChain(t *testing.T) {
+	rec := httptest.NewRecorder()
+	Chain(http.NotFoundHandler(), tag("a"), tag("b")).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if got := strings.Join(rec.Header().Values("X-Trace"), ","); got != "a,b" {
+		t.Errorf("trace %s, want a,b", got)
+	}
+}
@@ -0,0 +1,41 @@
+package negotiate
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+)
+
+type JSON struct{}
+
+func (JSON) MediaType() string { return "application/json" }
+
+func (JSON) Encode(buf *bytes.Buffer, v any) error {
+	return json.NewEncoder(buf).Encode(v)
+}
+
+// XML cannot encode maps, so error bodies built from maps fall through to
+// the next acceptable encoder.
+type XML struct{}
+
+func (XML) MediaType() string { return "application/xml" }
+
+func (XML) Encode(buf *bytes.Buffer, v any) error {
+	buf.WriteString(xml.Header)
+	if err := xml.NewEncoder(buf).Encode(v); err != nil {
+		return err
+	}
+	buf.WriteByte('\n')
+	return nil
+}
+
+// Text writes fmt's %v of the value, or its String method.
+type Text struct{}
+
+func (Text) MediaType() string { return "text/plain" }
+
+func (Text) Encode(buf *bytes.Buffer, v any) error {
+	_, err := fmt.Fprintln(buf, v)
+	return err
+}
@@ -0,0 +1,177 @@
+// Package negotiate picks the response encoding from the Accept header.
+// Each encoding is an Encoder strategy; a Negotiator ranks the ones it
+// has against the client's preferences and lets the best acceptable one
+// write the response:
+//
+//	n := negotiate.New(negotiate.JSON{}, negotiate.XML{}, negotiate.Text{})
+//	n.Write(w, r, http.StatusOK, order)
+//
+// Adding a format is adding an Encoder; neither the Negotiator nor the
+// handlers change.
+package negotiate
+
+import (
+	"bytes"
+	"cmp"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Encoder is one response format.
+type Encoder interface {
+	// MediaType is the type/subtype the encoder produces, e.g.
+	// "application/json".
+	MediaType() string
+	// Encode writes v; it may refuse values its format cannot express.
+	Encode(buf *bytes.Buffer, v any) error
+}
+
+// MediaRange is one element of an Accept header.
+type MediaRange struct {
+	Type, Subtype string
+	Q             float64
+}
+
+// ParseAccept parses an Accept header. Malformed elements are skipped and
+// a missing or malformed q counts as 1, as browsers and RFC 9110 expect
+// of a lenient server. The result keeps header order.
+func ParseAccept(header string) []MediaRange {
+	var ranges []MediaRange
+	for _, part := range strings.Split(header, ",") {
+		params := strings.Split(part, ";")
+		typ, sub, ok := strings.Cut(strings.ToLower(strings.TrimSpace(params[0])), "/")
+		if !ok || typ == "" || sub == "" || (typ == "*" && sub != "*") {
+			continue
+		}
+		mr := MediaRange{Type: typ, Subtype: sub, Q: 1}
+		for _, p := range params[1:] {
+			k, v, _ := strings.Cut(strings.TrimSpace(p), "=")
+			if strings.EqualFold(k, "q") {
+				if q, err := strconv.ParseFloat(v, 64); err == nil && q >= 0 && q <= 1 {
+					mr.Q = q
+				}
+			}
+		}
+		ranges = append(ranges, mr)
+	}
+	return ranges
+}
+
+// quality is the q the most specific range matching mediaType gives it,
+// 0 if none matches.
+func quality(ranges []MediaRange, mediaType string) float64 {
+	typ, sub, _ := strings.Cut(mediaType, "/")
+	best, specificity := 0.0, -1
+	for _, r := range ranges {
+		s := -1
+		switch {
+		case r.Type == typ && r.Subtype == sub:
+			s = 2
+		case r.Type == typ && r.Subtype == "*":
+			s = 1
+		case r.Type == "*":
+			s = 0
+		}
+		if s > specificity {
+			best, specificity = r.Q, s
+		}
+	}
+	return best
+}
+
+// ErrNotAcceptable is returned when no encoder satisfies the Accept
+// header.
+var ErrNotAcceptable = errors.New("negotiate: no acceptable encoding")
+
+// Negotiator selects among encoders. The order given to New is the
+// server's preference, used for an absent Accept header and for ties.
+type Negotiator struct {
+	encoders []Encoder
+}
+
+func New(encoders ...Encoder) *Negotiator {
+	return &Negotiator{encoders: encoders}
+}
+
+// Rank returns the acceptable encoders for an Accept header, best first.
+func (n *Negotiator) Rank(accept string) []Encoder {
+	order := n.rank(accept)
+	out := make([]Encoder, len(order))
+	for i, idx := range order {
+		out[i] = n.encoders[idx]
+	}
+	return out
+}
+
+// rank returns indexes into n.encoders, so callers need not compare
+// Encoders, which may not be comparable.
+func (n *Negotiator) rank(accept string) []int {
+	var order []int
+	if strings.TrimSpace(accept) == "" {
+		for i := range n.encoders {
+			order = append(order, i)
+		}
+		return order
+	}
+	ranges := ParseAccept(accept)
+	q := make([]float64, len(n.encoders))
+	for i, e := range n.encoders {
+		if q[i] = quality(ranges, e.MediaType()); q[i] > 0 {
+			order = append(order, i)
+		}
+	}
+	// stable, so equal q keeps server preference
+	slices.SortStableFunc(order, func(a, b int) int { return cmp.Compare(q[b], q[a]) })
+	return order
+}
+
+// Select returns the best encoder for an Accept header.
+func (n *Negotiator) Select(accept string) (Encoder, error) {
+	ranked := n.Rank(accept)
+	if len(ranked) == 0 {
+		return nil, ErrNotAcceptable
+	}
+	return ranked[0], nil
+}
+
+// Write encodes v with the best acceptable encoder that can express it,
+// falling through to the next on an encoding error, and writes it with
+// status. If every acceptable encoder fails, the remaining ones are tried
+// in server order: RFC 9110 allows an unacceptable type, and an error body
+// keeps its status instead of turning into a 500. With none acceptable at
+// all it answers 406 and returns ErrNotAcceptable. The response varies by
+// Accept, and says so. Several Accept lines count as one list, as RFC
+// 9110 has it.
+func (n *Negotiator) Write(w http.ResponseWriter, r *http.Request, status int, v any) error {
+	w.Header().Add("Vary", "Accept")
+	order := n.rank(strings.Join(r.Header.Values("Accept"), ","))
+	if len(order) == 0 {
+		http.Error(w, http.StatusText(http.StatusNotAcceptable), http.StatusNotAcceptable)
+		return ErrNotAcceptable
+	}
+	for i := range n.encoders {
+		if !slices.Contains(order, i) {
+			order = append(order, i)
+		}
+	}
+
+	var buf bytes.Buffer
+	var errs []error
+	for _, idx := range order {
+		e := n.encoders[idx]
+		buf.Reset()
+		if err := e.Encode(&buf, v); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		w.Header().Set("Content-Type", e.MediaType()+"; charset=utf-8")
+		w.WriteHeader(status)
+		_, err := w.Write(buf.Bytes())
+		return err
+	}
+	http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	return errors.Join(errs...)
+}
@@ -0,0 +1,160 @@
+package negotiate_test
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"patterns/web/negotiate"
+)
+
+func TestParseAccept(t *testing.T) {
+	type mr = negotiate.MediaRange
+	for _, c := range []struct {
+		header string
+		want   []mr
+	}{
+		{"", nil},
+		{"application/json", []mr{{"application", "json", 1}}},
+		{"text/html;q=0.9, */*;q=0.1", []mr{{"text", "html", 0.9}, {"*", "*", 0.1}}},
+		// case folds, whitespace goes, foreign parameters are ignored
+		{" Text/Plain ; format=flowed ; Q=0.5 ", []mr{{"text", "plain", 0.5}}},
+		{"image/*;q=0", []mr{{"image", "*", 0}}},
+		// a malformed q counts as 1
+		{"a/b;q=2, c/d;q=-1, e/f;q=high, g/h;q", []mr{{"a", "b", 1}, {"c", "d", 1}, {"e", "f", 1}, {"g", "h", 1}}},
+		// malformed ranges are skipped
+		{"json, /json, application/, */json, , text/plain", []mr{{"text", "plain", 1}}},
+		// header order is kept, whatever the q
+		{"a/b;q=0.1, c/d;q=0.9", []mr{{"a", "b", 0.1}, {"c", "d", 0.9}}},
+	} {
+		if got := negotiate.ParseAccept(c.header); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("ParseAccept(%q) = %v, want %v", c.header, got, c.want)
+		}
+	}
+}
+
+var all = negotiate.New(negotiate.JSON{}, negotiate.XML{}, negotiate.Text{})
+
+func types(encoders []negotiate.Encoder) string {
+	var s []string
+	for _, e := range encoders {
+		s = append(s, e.MediaType())
+	}
+	return strings.Join(s, " ")
+}
+
+func TestRank(t *testing.T) {
+	for _, c := range []struct {
+		accept string
+		want   string
+	}{
+		// no preference: the server's order
+		{"", "application/json application/xml text/plain"},
+		{"  ", "application/json application/xml text/plain"},
+		{"*/*", "application/json application/xml text/plain"},
+		{"application/xml", "application/xml"},
+		{"text/plain, application/json;q=0.5", "text/plain application/json"},
+		// equal q keeps the server's order, not the header's
+		{"text/plain, application/xml", "application/xml text/plain"},
+		{"application/*", "application/json application/xml"},
+		{"application/*;q=0.5, application/xml", "application/xml application/json"},
+		// the most specific range decides, even to exclude
+		{"*/*, application/json;q=0", "application/xml text/plain"},
+		{"text/*;q=0, text/plain;q=0.2, */*;q=0.1", "text/plain application/json application/xml"},
+		{"application/*;q=0, */*", "text/plain"},
+		// specific beats wildcard whatever their order in the header
+		{"application/json;q=0.1, */*;q=0.9", "application/xml text/plain application/json"},
+		{"image/png", ""},
+		{"*/*;q=0", ""},
+		{"garbage", ""},
+	} {
+		if got := types(all.Rank(c.accept)); got != c.want {
+			t.Errorf("Rank(%q) = [%s], want [%s]", c.accept, got, c.want)
+		}
+	}
+}
+
+func TestSelect(t *testing.T) {
+	if e, err := all.Select("text/*"); err != nil || e.MediaType() != "text/plain" {
+		t.Errorf("Select(text/*) = %v, %v", e, err)
+	}
+	if e, err := all.Select("image/png"); e != nil || !errors.Is(err, negotiate.ErrNotAcceptable) {
+		t.Errorf("Select(image/png) = %v, %v", e, err)
+	}
+	if e, err := negotiate.New().Select(""); e != nil || !errors.Is(err, negotiate.ErrNotAcceptable) {
+		t.Errorf("Select with no encoders = %v, %v", e, err)
+	}
+}
+
+type order struct {
+	ID    int    `json:"id" xml:"id"`
+	Title string `json:"title" xml:"title"`
+}
+
+func (o order) String() string { return "order " + o.Title }
+
+// failing refuses everything, like a format that cannot express a value.
+type failing struct{}
+
+func (failing) MediaType() string               { return "application/cbor" }
+func (failing) Encode(*bytes.Buffer, any) error { return errors.New("cbor: unsupported") }
+
+func write(n *negotiate.Negotiator, accept []string, status int, v any) (*httptest.ResponseRecorder, error) {
+	r := httptest.NewRequest("GET", "/", nil)
+	for _, a := range accept {
+		r.Header.Add("Accept", a)
+	}
+	w := httptest.NewRecorder()
+	return w, n.Write(w, r, status, v)
+}
+
+func TestWrite(t *testing.T) {
+	for _, c := range []struct {
+		name   string
+		accept []string
+		v      any
+		status int
+		ctype  string
+		body   string
+	}{
+		{"default", nil, order{1, "Dune"}, 200, "application/json", `{"id":1,"title":"Dune"}` + "\n"},
+		{"xml", []string{"application/xml"}, order{1, "Dune"}, 200, "application/xml",
+			`<?xml version="1.0" encoding="UTF-8"?>` + "\n<order><id>1</id><title>Dune</title></order>\n"},
+		{"text", []string{"text/plain;q=1, application/json;q=0.2"}, order{1, "Dune"}, 200, "text/plain", "order Dune\n"},
+		// several Accept lines make one list
+		{"two lines", []string{"application/json;q=0.2", "text/plain"}, order{1, "Dune"}, 200, "text/plain", "order Dune\n"},
+		// XML cannot encode a map: the next acceptable encoder does
+		{"fall through", []string{"application/xml, text/plain;q=0.5"}, map[string]string{"error": "gone"}, 404, "text/plain", "map[error:gone]\n"},
+		// and when no acceptable one can, an unacceptable one keeps the status
+		{"outside accept", []string{"application/xml"}, map[string]string{"error": "gone"}, 404, "application/json", `{"error":"gone"}` + "\n"},
+		{"not acceptable", []string{"image/png"}, order{}, 406, "text/plain", "Not Acceptable\n"},
+	} {
+		w, err := write(all, c.accept, c.status, c.v)
+		if (err != nil) != (c.status == 406) {
+			t.Errorf("%s: Write = %v", c.name, err)
+		}
+		if w.Code != c.status || !strings.HasPrefix(w.Header().Get("Content-Type"), c.ctype+";") || w.Body.String() != c.body {
+			t.Errorf("%s: %d %s %q, want %d %s %q", c.name, w.Code, w.Header().Get("Content-Type"), w.Body, c.status, c.ctype, c.body)
+		}
+		if v := w.Header().Get("Vary"); v != "Accept" {
+			t.Errorf("%s: Vary = %q", c.name, v)
+		}
+	}
+}
+
+// TestWriteAllFail checks that when no encoder at all can express the
+// value the client gets a 500 and the caller every encoder's error.
+func TestWriteAllFail(t *testing.T) {
+	n := negotiate.New(failing{}, negotiate.XML{})
+	w, err := write(n, []string{"application/cbor"}, 200, map[string]int{"a": 1})
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("status %d, want 500", w.Code)
+	}
+	if err == nil || !strings.Contains(err.Error(), "cbor: unsupported") || !strings.Contains(err.Error(), "xml: unsupported type") {
+		t.Errorf("Write = %v, want both encoders' errors", err)
+	}
+}
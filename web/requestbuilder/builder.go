@@ -0,0 +1,219 @@
+// Package requestbuilder builds *http.Request values with a fluent
+// builder. Setter errors are accumulated and reported together by Build,
+// so a chain never has to be broken up to check errors.
+package requestbuilder
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// Option adjusts the request after it is built, e.g. to sign it.
+type Option func(*http.Request) error
+
+// Builder accumulates the parts of a request.
+type Builder struct {
+	method     string
+	base       string
+	path       string
+	pathParams map[string]string
+	query      url.Values
+	header     http.Header
+	body       []byte
+	ctx        context.Context
+	opts       []Option
+	errs       []error
+}
+
+// New starts a request to base (scheme and host, optionally a path prefix).
+func New(method, base string) *Builder {
+	return &Builder{
+		method:     method,
+		base:       base,
+		pathParams: map[string]string{},
+		query:      url.Values{},
+		header:     http.Header{},
+		ctx:        context.Background(),
+	}
+}
+
+func Get(base string) *Builder  { return New(http.MethodGet, base) }
+func Post(base string) *Builder { return New(http.MethodPost, base) }
+
+// Path sets the path template; {name} segments are filled by Param.
+func (b *Builder) Path(tmpl string) *Builder {
+	b.path = tmpl
+	return b
+}
+
+// Param fills the {name} segment of the path, escaped.
+func (b *Builder) Param(name, value string) *Builder {
+	if value == "" {
+		b.errs = append(b.errs, fmt.Errorf("path param %q is empty", name))
+	}
+	b.pathParams[name] = value
+	return b
+}
+
+func (b *Builder) Query(key, value string) *Builder {
+	b.query.Add(key, value)
+	return b
+}
+
+func (b *Builder) Header(key, value string) *Builder {
+	b.header.Add(key, value)
+	return b
+}
+
+// JSON encodes v as the body and sets Content-Type.
+func (b *Builder) JSON(v any) *Builder {
+	body, err := json.Marshal(v)
+	if err != nil {
+		b.errs = append(b.errs, fmt.Errorf("encode body: %w", err))
+		return b
+	}
+	b.body = body
+	b.header.Set("Content-Type", "application/json")
+	return b
+}
+
+func (b *Builder) Context(ctx context.Context) *Builder {
+	if ctx == nil {
+		b.errs = append(b.errs, errors.New("nil context"))
+		return b
+	}
+	b.ctx = ctx
+	return b
+}
+
+// With adds per-request options applied by Build.
+func (b *Builder) With(opts ...Option) *Builder {
+	b.opts = append(b.opts, opts...)
+	return b
+}
+
+// Build returns the request, or every error recorded along the chain.
+func (b *Builder) Build() (*http.Request, error) {
+	errs := append([]error(nil), b.errs...)
+
+	path, err := b.expandPath()
+	if err != nil {
+		errs = append(errs, err)
+	}
+	u, err := joinURL(b.base, path)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("parse url: %w", err))
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	q := u.Query()
+	for k, vs := range b.query {
+		for _, v := range vs {
+			q.Add(k, v)
+		}
+	}
+	u.RawQuery = q.Encode()
+
+	var body io.Reader
+	if b.body != nil {
+		body = bytes.NewReader(b.body)
+	}
+	req, err := http.NewRequestWithContext(b.ctx, b.method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	for k, vs := range b.header {
+		req.Header[k] = append([]string(nil), vs...)
+	}
+
+	for _, opt := range b.opts {
+		if err := opt(req); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// joinURL appends the escaped path to base's path, keeping base's query.
+func joinURL(base, path string) (*url.URL, error) {
+	u, err := url.Parse(base)
+	if err != nil {
+		return nil, err
+	}
+	p, err := url.Parse(strings.TrimSuffix(u.EscapedPath(), "/") + path)
+	if err != nil {
+		return nil, err
+	}
+	u.Path, u.RawPath = p.Path, p.RawPath
+	return u, nil
+}
+
+// expandPath fills the template, reporting every missing and unused
+// param.
+func (b *Builder) expandPath() (string, error) {
+	var out strings.Builder
+	var errs []error
+	rest := b.path
+	used := map[string]bool{}
+	for {
+		start := strings.IndexByte(rest, '{')
+		if start < 0 {
+			out.WriteString(rest)
+			break
+		}
+		end := strings.IndexByte(rest[start:], '}')
+		if end < 0 {
+			errs = append(errs, fmt.Errorf("unterminated path param in %q", b.path))
+			break
+		}
+		name := rest[start+1 : start+end]
+		value, ok := b.pathParams[name]
+		if !ok {
+			errs = append(errs, fmt.Errorf("missing path param %q", name))
+		}
+		used[name] = true
+		out.WriteString(rest[:start])
+		out.WriteString(url.PathEscape(value))
+		rest = rest[start+end+1:]
+	}
+	for _, name := range slices.Sorted(maps.Keys(b.pathParams)) {
+		if !used[name] {
+			errs = append(errs, fmt.Errorf("path param %q not in template %q", name, b.path))
+		}
+	}
+
+	return out.String(), errors.Join(errs...)
+}
+
+// WithBearer sets an Authorization header.
+func WithBearer(token string) Option {
+	return func(r *http.Request) error {
+		if token == "" {
+			return errors.New("empty bearer token")
+		}
+		r.Header.Set("Authorization", "Bearer "+token)
+		return nil
+	}
+}
+
+// WithIdempotencyKey sets the Idempotency-Key header.
+func WithIdempotencyKey(key string) Option {
+	return func(r *http.Request) error {
+		r.Header.Set("Idempotency-Key", key)
+		return nil
+	}
+}
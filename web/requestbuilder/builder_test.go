@@ -0,0 +1,185 @@
+package requestbuilder_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"patterns/web/requestbuilder"
+)
+
+// echo is what the test server saw of a request.
+type echo struct {
+	Method  string              `json:"method"`
+	RawPath string              `json:"raw_path"`
+	ID      string              `json:"id"`
+	Query   map[string][]string `json:"query"`
+	Header  map[string]string   `json:"header"`
+	Body    string              `json:"body"`
+}
+
+func newServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/users/{id}/orders", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		e := echo{
+			Method: r.Method, RawPath: r.URL.EscapedPath(), ID: r.PathValue("id"),
+			Query: r.URL.Query(), Header: map[string]string{}, Body: string(body),
+		}
+		for _, h := range []string{"Content-Type", "Authorization", "Idempotency-Key", "X-Trace"} {
+			e.Header[h] = strings.Join(r.Header.Values(h), ",")
+		}
+		_ = json.NewEncoder(w).Encode(e)
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func do(t *testing.T, req *http.Request) echo {
+	t.Helper()
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("%s %s: %s", req.Method, req.URL, resp.Status)
+	}
+	var e echo
+	if err := json.NewDecoder(resp.Body).Decode(&e); err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+func TestBuild(t *testing.T) {
+	srv := newServer(t)
+	req, err := requestbuilder.Post(srv.URL+"/api/?v=2").
+		Path("/users/{id}/orders").
+		Param("id", "ann/b ob").
+		Query("item", "book").
+		Query("item", "pen").
+		Header("X-Trace", "a").
+		Header("x-trace", "b").
+		JSON(map[string]int{"qty": 2}).
+		With(requestbuilder.WithBearer("t0k"), requestbuilder.WithIdempotencyKey("k-1")).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := do(t, req)
+	want := echo{
+		Method: http.MethodPost,
+		// the param is one segment, escaped, whatever it contains
+		RawPath: "/api/users/ann%2Fb%20ob/orders",
+		ID:      "ann/b ob",
+		Query:   map[string][]string{"v": {"2"}, "item": {"book", "pen"}},
+		Header: map[string]string{
+			"Content-Type": "application/json", "Authorization": "Bearer t0k",
+			"Idempotency-Key": "k-1", "X-Trace": "a,b",
+		},
+		Body: `{"qty":2}`,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("server saw %+v\nwant %+v", got, want)
+	}
+}
+
+// TestBuildTwice checks that Build makes independent requests, each with
+// the whole body.
+func TestBuildTwice(t *testing.T) {
+	srv := newServer(t)
+	b := requestbuilder.Post(srv.URL).Path("/api/users/{id}/orders").Param("id", "1").JSON([]int{1, 2})
+	for range 2 {
+		req, err := b.Build()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := do(t, req); got.Body != "[1,2]" {
+			t.Errorf("body = %q, want [1,2]", got.Body)
+		}
+	}
+}
+
+func TestContext(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	req, err := requestbuilder.Get(srv.URL).Context(ctx).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.Context() != ctx {
+		t.Error("request does not carry the context")
+	}
+	if _, err := http.DefaultClient.Do(req); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Do = %v, want the deadline", err)
+	}
+}
+
+// TestBuildErrors checks that Build reports every mistake along the
+// chain at once.
+func TestBuildErrors(t *testing.T) {
+	var nilCtx context.Context
+	_, err := requestbuilder.Get("http://example.com").
+		Path("/users/{id}/orders/{order}").
+		Param("id", "").
+		Param("shop", "x").
+		JSON(math.NaN()).
+		Context(nilCtx).
+		With(requestbuilder.WithBearer(""), func(*http.Request) error { return errors.New("sign: no key") }).
+		Build()
+	if err == nil {
+		t.Fatal("Build succeeded")
+	}
+	// the option errors come only once everything else is valid
+	for _, want := range []string{
+		`path param "id" is empty`,
+		"encode body: json: unsupported value: NaN",
+		"nil context",
+		`missing path param "order"`,
+		`path param "shop" not in template "/users/{id}/orders/{order}"`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Build error lacks %q:\n%v", want, err)
+		}
+	}
+	if n := strings.Count(err.Error(), "\n") + 1; n != 5 {
+		t.Errorf("Build reported %d errors, want 5:\n%v", n, err)
+	}
+
+	_, err = requestbuilder.Get("http://example.com").
+		With(requestbuilder.WithBearer(""), func(*http.Request) error { return errors.New("sign: no key") }).
+		Build()
+	if err == nil || err.Error() != "empty bearer token\nsign: no key" {
+		t.Errorf("Build error = %v, want both option errors", err)
+	}
+}
+
+func TestBuildURLErrors(t *testing.T) {
+	for _, c := range []struct {
+		base, path, want string
+	}{
+		{"http://example.com", "/users/{id", `unterminated path param in "/users/{id"`},
+		{"http://exa mple.com", "/", "parse url"},
+		{"://example.com", "/", "parse url"},
+	} {
+		_, err := requestbuilder.Get(c.base).Path(c.path).Build()
+		if err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Errorf("%s%s: Build error = %v, want %q", c.base, c.path, err, c.want)
+		}
+	}
+}
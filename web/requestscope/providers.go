@@ -0,0 +1,115 @@
+package requestscope
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"patterns/idioms/txdefer"
+)
+
+// RequestIDHeader carries the request id in and out.
+const RequestIDHeader = "X-Request-ID"
+
+var requestIDKey = NewKey[string]("request id")
+
+// RequestIDFrom returns the id set by RequestID, or "".
+func RequestIDFrom(ctx context.Context) string {
+	id, _ := requestIDKey.Get(ctx)
+	return id
+}
+
+// RequestID takes the id from the X-Request-ID header, or generates one,
+// and echoes it in the response.
+func RequestID() Provider {
+	return func(s *Scope, r *http.Request) (context.Context, error) {
+		id := r.Header.Get(RequestIDHeader)
+		if id == "" || len(id) > 64 {
+			var b [8]byte
+			rand.Read(b[:])
+			id = hex.EncodeToString(b[:])
+		}
+		s.Header().Set(RequestIDHeader, id)
+		return requestIDKey.With(r.Context(), id), nil
+	}
+}
+
+var loggerKey = NewKey[*slog.Logger]("logger")
+
+// LoggerFrom returns the request's logger, or slog.Default outside a
+// scope so code shared with non-HTTP callers can always log.
+func LoggerFrom(ctx context.Context) *slog.Logger {
+	if l, ok := loggerKey.Get(ctx); ok {
+		return l
+	}
+	return slog.Default()
+}
+
+// Logger derives a logger tagged with the method, path and, when
+// RequestID ran before it, the request id.
+func Logger(base *slog.Logger) Provider {
+	return func(s *Scope, r *http.Request) (context.Context, error) {
+		l := base.With("method", r.Method, "path", r.URL.Path)
+		if id := RequestIDFrom(r.Context()); id != "" {
+			l = l.With("request_id", id)
+		}
+		return loggerKey.With(r.Context(), l), nil
+	}
+}
+
+// User is an authenticated principal.
+type User struct {
+	ID    string
+	Roles []string
+}
+
+var userKey = NewKey[User]("principal")
+
+// PrincipalFrom returns the user authenticated by Principal.
+func PrincipalFrom(ctx context.Context) (User, bool) { return userKey.Get(ctx) }
+
+// ErrUnauthenticated is what an authenticate func returns for requests
+// without valid credentials; Principal answers them with 401.
+var ErrUnauthenticated = errors.New("unauthenticated")
+
+// Principal authenticates the request. ErrUnauthenticated becomes a 401;
+// any other error a 500.
+func Principal(authenticate func(r *http.Request) (User, error)) Provider {
+	return func(s *Scope, r *http.Request) (context.Context, error) {
+		u, err := authenticate(r)
+		if errors.Is(err, ErrUnauthenticated) {
+			return nil, &Error{Status: http.StatusUnauthorized, Err: err}
+		}
+		if err != nil {
+			return nil, err
+		}
+		return userKey.With(r.Context(), u), nil
+	}
+}
+
+// Tx begins a transaction per request and stores it under key. When the
+// request ends it commits, or rolls back if the request Failed.
+// Level: Average
+// pros: handlers never begin, commit or roll back, so none can forget to.
+// cons: the commit happens after the response is written, so a commit
+// failure cannot change the status the client already saw; it is only
+// logged. Handlers that must report it should own their transaction.
+func Tx[T txdefer.Transactional](key *Key[T], begin func(ctx context.Context) (T, error)) Provider {
+	return func(s *Scope, r *http.Request) (context.Context, error) {
+		raw, err := begin(r.Context())
+		if err != nil {
+			return nil, err
+		}
+		tx := txdefer.Wrap(raw)
+		s.OnEnd("tx", func(failed bool) error {
+			if failed {
+				return tx.Rollback()
+			}
+			return tx.Commit()
+		})
+		return key.With(r.Context(), raw), nil
+	}
+}
@@ -0,0 +1,174 @@
+// Package requestscope builds dependencies that live for one request (a
+// transaction, a logger tagged with the request id, the authenticated
+// principal) in middleware, and hands them to handlers through typed
+// context accessors:
+//
+//	mux.Handle("/orders", requestscope.Middleware(
+//		requestscope.RequestID(),
+//		requestscope.Logger(slog.Default()),
+//		requestscope.Principal(authenticate),
+//		requestscope.Tx(TxKey, db.BeginTx),
+//	)(orders))
+//
+//	func orders(w http.ResponseWriter, r *http.Request) {
+//		tx := TxKey.MustGet(r.Context())
+//		requestscope.LoggerFrom(r.Context()).Info("placing order")
+//		...
+//	}
+//
+// Every request gets a fresh Scope; what providers register with it is
+// torn down, last first, when the request ends. Values live in the
+// request's context, never in the handler or a global, so nothing one
+// request built is visible to another.
+package requestscope
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"patterns/lifecycle/multicloser"
+	"patterns/web/middleware"
+	"patterns/web/responserecorder"
+)
+
+// Key is a typed context key: the type parameter fixes what Get returns,
+// so accessors need no type assertions at call sites, and the pointer
+// identity keeps keys with equal names apart.
+type Key[T any] struct {
+	name string
+}
+
+func NewKey[T any](name string) *Key[T] { return &Key[T]{name: name} }
+
+func (k *Key[T]) With(ctx context.Context, v T) context.Context {
+	return context.WithValue(ctx, k, v)
+}
+
+func (k *Key[T]) Get(ctx context.Context) (T, bool) {
+	v, ok := ctx.Value(k).(T)
+	return v, ok
+}
+
+// MustGet is Get for handlers mounted behind the provider of k; a missing
+// value is a wiring bug, so it panics naming the key.
+func (k *Key[T]) MustGet(ctx context.Context) T {
+	v, ok := k.Get(ctx)
+	if !ok {
+		panic(fmt.Sprintf("requestscope: %s not in context; is its provider installed?", k.name))
+	}
+	return v
+}
+
+// Scope is the lifetime of one request.
+type Scope struct {
+	header http.Header
+
+	mu      sync.Mutex
+	failed  bool
+	ended   bool
+	closers multicloser.Stack
+}
+
+// Header is the response's header, for providers that answer with one.
+// Changes after the handler has written are not sent, as with any
+// http.ResponseWriter.
+func (s *Scope) Header() http.Header { return s.header }
+
+var scopeKey = NewKey[*Scope]("scope")
+
+// ScopeFrom returns the Scope of the request ctx belongs to.
+func ScopeFrom(ctx context.Context) (*Scope, bool) { return scopeKey.Get(ctx) }
+
+// OnEnd registers fn to run when the request ends, after the handler
+// returned; failed reports whether the request failed (see Failed).
+// Registering on an ended scope runs fn immediately with failed true, so
+// a goroutine that outlived its request cannot keep resources alive.
+func (s *Scope) OnEnd(name string, fn func(failed bool) error) {
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		fn(true)
+		return
+	}
+	s.closers.Defer(name, func() error { return fn(s.Failed()) })
+	s.mu.Unlock()
+}
+
+// Fail marks the request failed, e.g. to roll back a transaction behind
+// a 2xx response.
+func (s *Scope) Fail() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failed = true
+}
+
+// Failed reports whether the request failed: Fail was called, the
+// handler panicked, or the response status is 400 or above.
+func (s *Scope) Failed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.failed
+}
+
+func (s *Scope) end(failed bool) error {
+	s.mu.Lock()
+	s.failed = s.failed || failed
+	s.ended = true
+	s.mu.Unlock()
+	return s.closers.Close()
+}
+
+// Provider builds one scoped dependency. It receives the request with
+// the context built so far and returns the context extended with its
+// value. Returning an *Error answers the request with its status.
+type Provider func(s *Scope, r *http.Request) (context.Context, error)
+
+// Error is a provider failure with the status to answer with.
+type Error struct {
+	Status int
+	Err    error
+}
+
+func (e *Error) Error() string { return e.Err.Error() }
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// Middleware runs providers in order to assemble the request scope, then
+// the handler, then the scope's OnEnd funcs in reverse. Errors from OnEnd
+// funcs come after the response is written, so they are logged through
+// LoggerFrom.
+func Middleware(providers ...Provider) middleware.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ww, rec := responserecorder.Wrap(w)
+			s := &Scope{header: ww.Header()}
+			r = r.WithContext(scopeKey.With(r.Context(), s))
+			panicked := true
+			defer func() {
+				if err := s.end(panicked || rec.Status() >= 400); err != nil {
+					LoggerFrom(r.Context()).Error("request scope teardown", "err", err)
+				}
+			}()
+
+			for _, provide := range providers {
+				ctx, err := provide(s, r)
+				if err != nil {
+					status := http.StatusInternalServerError
+					var pe *Error
+					if errors.As(err, &pe) {
+						status = pe.Status
+					}
+					http.Error(ww, http.StatusText(status), status)
+					panicked = false
+					return
+				}
+				r = r.WithContext(ctx)
+			}
+			next.ServeHTTP(ww, r)
+			panicked = false
+		})
+	}
+}
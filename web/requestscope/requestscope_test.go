@@ -0,0 +1,342 @@
+package requestscope_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"patterns/web/requestscope"
+)
+
+// fakeTx records how it ended into a shared log.
+type fakeTx struct {
+	name      string
+	log       *txLog
+	commitErr error
+}
+
+type txLog struct {
+	mu    sync.Mutex
+	began []*fakeTx
+	ended []string
+}
+
+func (l *txLog) begin(name string) func(context.Context) (*fakeTx, error) {
+	return func(context.Context) (*fakeTx, error) {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		tx := &fakeTx{name: fmt.Sprint(name, len(l.began)), log: l}
+		l.began = append(l.began, tx)
+		return tx, nil
+	}
+}
+
+func (l *txLog) String() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return strings.Join(l.ended, " ")
+}
+
+func (tx *fakeTx) end(how string) {
+	tx.log.mu.Lock()
+	defer tx.log.mu.Unlock()
+	tx.log.ended = append(tx.log.ended, tx.name+" "+how)
+}
+
+func (tx *fakeTx) Commit() error   { tx.end("commit"); return tx.commitErr }
+func (tx *fakeTx) Rollback() error { tx.end("rollback"); return nil }
+
+var txKey = requestscope.NewKey[*fakeTx]("tx")
+
+// authenticate takes the user from the X-User header.
+func authenticate(r *http.Request) (requestscope.User, error) {
+	switch id := r.Header.Get("X-User"); id {
+	case "":
+		return requestscope.User{}, requestscope.ErrUnauthenticated
+	case "broken":
+		return requestscope.User{}, errors.New("directory unreachable")
+	default:
+		return requestscope.User{ID: id}, nil
+	}
+}
+
+func get(h http.Handler, user, id string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest("GET", "/orders", nil)
+	if user != "" {
+		r.Header.Set("X-User", user)
+	}
+	if id != "" {
+		r.Header.Set(requestscope.RequestIDHeader, id)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+// TestNoLeak keeps many requests in flight at once and checks that each
+// handler sees only its own request id, logger, principal, transaction
+// and scope, and that every transaction ends once.
+func TestNoLeak(t *testing.T) {
+	const n = 32
+	var logs bytes.Buffer
+	var logMu sync.Mutex
+	base := slog.New(slog.NewTextHandler(lockedWriter{&logs, &logMu}, nil))
+	txs := &txLog{}
+	var inFlight sync.WaitGroup
+	inFlight.Add(n)
+	var mu sync.Mutex
+	seen := map[*requestscope.Scope]string{}
+	seenTx := map[*fakeTx]string{}
+
+	h := requestscope.Middleware(
+		requestscope.RequestID(),
+		requestscope.Logger(base),
+		requestscope.Principal(authenticate),
+		requestscope.Tx(txKey, txs.begin("tx")),
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// wait until every request has its scope, so any sharing shows
+		inFlight.Done()
+		inFlight.Wait()
+		ctx := r.Context()
+		u, _ := requestscope.PrincipalFrom(ctx)
+		id := requestscope.RequestIDFrom(ctx)
+		if want := strings.Replace(u.ID, "user", "req", 1); id != want {
+			t.Errorf("user %s sees request id %s", u.ID, id)
+		}
+		s, _ := requestscope.ScopeFrom(ctx)
+		tx := txKey.MustGet(ctx)
+		mu.Lock()
+		if other, ok := seen[s]; ok {
+			t.Errorf("%s and %s share a scope", u.ID, other)
+		}
+		if other, ok := seenTx[tx]; ok {
+			t.Errorf("%s and %s share a transaction", u.ID, other)
+		}
+		seen[s], seenTx[tx] = u.ID, u.ID
+		mu.Unlock()
+		requestscope.LoggerFrom(ctx).Info("placing order", "user", u.ID)
+		if u.ID == "user3" {
+			w.WriteHeader(http.StatusConflict)
+		}
+	}))
+
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w := get(h, fmt.Sprint("user", i), fmt.Sprint("req", i))
+			if got := w.Header().Get(requestscope.RequestIDHeader); got != fmt.Sprint("req", i) {
+				t.Errorf("request %d echoed id %q", i, got)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(txs.began) != n || len(txs.ended) != n {
+		t.Errorf("%d transactions began, %d ended; want %d", len(txs.began), len(txs.ended), n)
+	}
+	if rollbacks := strings.Count(txs.String(), "rollback"); rollbacks != 1 {
+		t.Errorf("%d rollbacks, want the one for the 409", rollbacks)
+	}
+	// every log line carries the id of the request that wrote it
+	for _, line := range strings.Split(strings.TrimSpace(logs.String()), "\n") {
+		var user, id string
+		for _, f := range strings.Fields(line) {
+			if v, ok := strings.CutPrefix(f, "user="); ok {
+				user = v
+			}
+			if v, ok := strings.CutPrefix(f, "request_id="); ok {
+				id = v
+			}
+		}
+		if id != strings.Replace(user, "user", "req", 1) {
+			t.Errorf("log line of %s has request id %s: %s", user, id, line)
+		}
+	}
+}
+
+type lockedWriter struct {
+	buf *bytes.Buffer
+	mu  *sync.Mutex
+}
+
+func (w lockedWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.Write(p)
+}
+
+// TestOutlivedScope checks that a goroutine keeping a request's context
+// after it ended sees the scope as ended: what it registers is torn down
+// at once, as failed, instead of being kept alive.
+func TestOutlivedScope(t *testing.T) {
+	kept := make(chan context.Context, 1)
+	h := requestscope.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		kept <- r.Context()
+	}))
+	get(h, "", "")
+	s, ok := requestscope.ScopeFrom(<-kept)
+	if !ok {
+		t.Fatal("no scope in the request context")
+	}
+	var got []bool
+	s.OnEnd("late", func(failed bool) error {
+		got = append(got, failed)
+		return nil
+	})
+	if !slices.Equal(got, []bool{true}) {
+		t.Errorf("OnEnd on an ended scope ran %v, want at once with failed", got)
+	}
+}
+
+// TestOutsideScope checks the accessors' answers for a context no
+// middleware built, as a background job or a test would have.
+func TestOutsideScope(t *testing.T) {
+	ctx := context.Background()
+	if id := requestscope.RequestIDFrom(ctx); id != "" {
+		t.Errorf("RequestIDFrom = %q", id)
+	}
+	if l := requestscope.LoggerFrom(ctx); l != slog.Default() {
+		t.Errorf("LoggerFrom = %v, want slog.Default", l)
+	}
+	if u, ok := requestscope.PrincipalFrom(ctx); ok {
+		t.Errorf("PrincipalFrom = %v", u)
+	}
+	if s, ok := requestscope.ScopeFrom(ctx); ok {
+		t.Errorf("ScopeFrom = %v", s)
+	}
+	defer func() {
+		want := "requestscope: tx not in context; is its provider installed?"
+		if p := recover(); p != want {
+			t.Errorf("MustGet panicked with %v, want %q", p, want)
+		}
+	}()
+	txKey.MustGet(ctx)
+}
+
+// TestKeys checks that keys are told apart by identity, not name.
+func TestKeys(t *testing.T) {
+	a, b := requestscope.NewKey[string]("name"), requestscope.NewKey[string]("name")
+	ctx := a.With(context.Background(), "a")
+	if v, ok := b.Get(ctx); ok {
+		t.Errorf("a key of the same name read %q", v)
+	}
+	if v, _ := a.Get(b.With(ctx, "b")); v != "a" {
+		t.Errorf("a.Get = %q after setting b", v)
+	}
+}
+
+// TestTxOutcome checks when the request's transaction commits and when
+// it rolls back.
+func TestTxOutcome(t *testing.T) {
+	for _, c := range []struct {
+		name    string
+		handler func(w http.ResponseWriter, r *http.Request)
+		want    string
+	}{
+		{"nothing written", func(http.ResponseWriter, *http.Request) {}, "tx0 commit"},
+		{"ok", func(w http.ResponseWriter, r *http.Request) { fmt.Fprint(w, "ok") }, "tx0 commit"},
+		{"client error", func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusBadRequest) }, "tx0 rollback"},
+		{"server error", func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusInternalServerError) }, "tx0 rollback"},
+		{"failed behind a 200", func(w http.ResponseWriter, r *http.Request) {
+			s, _ := requestscope.ScopeFrom(r.Context())
+			s.Fail()
+		}, "tx0 rollback"},
+		{"panic", func(http.ResponseWriter, *http.Request) { panic("boom") }, "tx0 rollback"},
+	} {
+		txs := &txLog{}
+		h := requestscope.Middleware(requestscope.Tx(txKey, txs.begin("tx")))(http.HandlerFunc(c.handler))
+		func() {
+			defer func() { recover() }()
+			get(h, "", "")
+		}()
+		if got := txs.String(); got != c.want {
+			t.Errorf("%s: %s, want %s", c.name, got, c.want)
+		}
+	}
+}
+
+// TestTeardown checks that scoped dependencies end last first, that an
+// earlier one ends when a later provider fails, and that a failed commit
+// is logged with the request id.
+func TestTeardown(t *testing.T) {
+	txs := &txLog{}
+	second := requestscope.NewKey[*fakeTx]("second")
+	ok := http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})
+	h := requestscope.Middleware(requestscope.Tx(txKey, txs.begin("a")), requestscope.Tx(second, txs.begin("b")))(ok)
+	get(h, "", "")
+	if got := txs.String(); got != "b1 commit a0 commit" {
+		t.Errorf("teardown %s, want the last begun first", got)
+	}
+
+	for _, c := range []struct {
+		user   string
+		status int
+	}{
+		{"", http.StatusUnauthorized},
+		{"broken", http.StatusInternalServerError},
+	} {
+		txs := &txLog{}
+		h := requestscope.Middleware(requestscope.Tx(txKey, txs.begin("tx")), requestscope.Principal(authenticate))(ok)
+		if w := get(h, c.user, ""); w.Code != c.status {
+			t.Errorf("user %q: status %d, want %d", c.user, w.Code, c.status)
+		}
+		if got := txs.String(); got != "tx0 rollback" {
+			t.Errorf("user %q: %s, want the transaction begun before the failure rolled back", c.user, got)
+		}
+	}
+
+	var logs bytes.Buffer
+	failing := func(ctx context.Context) (*fakeTx, error) {
+		return &fakeTx{name: "tx", log: &txLog{}, commitErr: errors.New("serialization failure")}, nil
+	}
+	h = requestscope.Middleware(
+		requestscope.RequestID(),
+		requestscope.Logger(slog.New(slog.NewTextHandler(&logs, nil))),
+		requestscope.Tx(txKey, failing),
+	)(ok)
+	if w := get(h, "", "req-1"); w.Code != http.StatusOK {
+		t.Errorf("status %d; a commit failure comes too late to change it", w.Code)
+	}
+	for _, want := range []string{`msg="request scope teardown"`, "request_id=req-1", "serialization failure"} {
+		if !strings.Contains(logs.String(), want) {
+			t.Errorf("log lacks %s:\n%s", want, logs.String())
+		}
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	var got string
+	h := requestscope.Middleware(requestscope.RequestID())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = requestscope.RequestIDFrom(r.Context())
+	}))
+	for _, c := range []struct {
+		name, in  string
+		generated bool
+	}{
+		{"given", "abc", false},
+		{"missing", "", true},
+		{"too long", strings.Repeat("x", 65), true},
+	} {
+		w := get(h, "", c.in)
+		if echoed := w.Header().Get(requestscope.RequestIDHeader); echoed != got {
+			t.Errorf("%s: echoed %q, handler saw %q", c.name, echoed, got)
+		}
+		if c.generated && len(got) != 16 || !c.generated && got != c.in {
+			t.Errorf("%s: request id %q", c.name, got)
+		}
+	}
+	a, b := get(h, "", ""), get(h, "", "")
+	if a.Header().Get(requestscope.RequestIDHeader) == b.Header().Get(requestscope.RequestIDHeader) {
+		t.Error("two generated ids are equal")
+	}
+}
@@ -0,0 +1,111 @@
+// Package responserecorder wraps an http.ResponseWriter to observe the
+// status code and body size without hiding the writer's optional
+// interfaces.
+//
+// A naive wrapper struct embedding http.ResponseWriter only exposes the
+// ResponseWriter methods: w.(http.Flusher) starts failing as soon as a
+// middleware wraps the writer. Wrap re-assembles a type that implements
+// exactly the optional interfaces the original one did.
+package responserecorder
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+)
+
+// Recorder holds what was observed. Read it after the handler returns.
+type Recorder struct {
+	w           http.ResponseWriter
+	status      int
+	bytes       int64
+	wroteHeader bool
+	hijacked    bool
+}
+
+// Status is the written status, 200 if the handler wrote a body without
+// calling WriteHeader, or 0 if nothing was written.
+func (r *Recorder) Status() int { return r.status }
+
+// Bytes is the number of body bytes written.
+func (r *Recorder) Bytes() int64 { return r.bytes }
+
+// Hijacked reports whether the connection was taken over.
+func (r *Recorder) Hijacked() bool { return r.hijacked }
+
+func (r *Recorder) Header() http.Header { return r.w.Header() }
+
+func (r *Recorder) WriteHeader(code int) {
+	if !r.wroteHeader {
+		r.status = code
+		// 1xx informational headers may precede the real one
+		r.wroteHeader = code >= 200
+	}
+	r.w.WriteHeader(code)
+}
+
+func (r *Recorder) Write(b []byte) (int, error) {
+	if !r.wroteHeader {
+		r.status = http.StatusOK
+		r.wroteHeader = true
+	}
+	n, err := r.w.Write(b)
+	r.bytes += int64(n)
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (r *Recorder) Unwrap() http.ResponseWriter { return r.w }
+
+func (r *Recorder) flush() {
+	if !r.wroteHeader {
+		r.status = http.StatusOK
+		r.wroteHeader = true
+	}
+	r.w.(http.Flusher).Flush()
+}
+
+func (r *Recorder) hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := r.w.(http.Hijacker).Hijack()
+	if err == nil {
+		r.hijacked = true
+		if !r.wroteHeader {
+			r.status = http.StatusSwitchingProtocols
+		}
+	}
+	return conn, rw, err
+}
+
+type flusher struct{ *Recorder }
+
+func (f flusher) Flush() { f.flush() }
+
+type hijacker struct{ *Recorder }
+
+func (h hijacker) Hijack() (net.Conn, *bufio.ReadWriter, error) { return h.hijack() }
+
+type flushHijacker struct{ *Recorder }
+
+func (f flushHijacker) Flush() { f.flush() }
+
+func (f flushHijacker) Hijack() (net.Conn, *bufio.ReadWriter, error) { return f.hijack() }
+
+// Wrap returns a writer to hand to the next handler and the Recorder that
+// observes it. The returned writer implements http.Flusher and
+// http.Hijacker if and only if w does.
+func Wrap(w http.ResponseWriter) (http.ResponseWriter, *Recorder) {
+	r := &Recorder{w: w}
+	_, canFlush := w.(http.Flusher)
+	_, canHijack := w.(http.Hijacker)
+
+	switch {
+	case canFlush && canHijack:
+		return flushHijacker{r}, r
+	case canFlush:
+		return flusher{r}, r
+	case canHijack:
+		return hijacker{r}, r
+	default:
+		return r, r
+	}
+}
@@ -0,0 +1,231 @@
+package responserecorder_test
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"patterns/web/responserecorder"
+)
+
+// seen is what a Recorder observed of one request.
+type seen struct {
+	status   int
+	bytes    int64
+	hijacked bool
+}
+
+// recording serves h behind Wrap, as a logging middleware would, and
+// sends what the Recorder saw once h returns.
+func recording(t *testing.T, h http.HandlerFunc) (*httptest.Server, <-chan seen) {
+	t.Helper()
+	got := make(chan seen, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ww, rec := responserecorder.Wrap(w)
+		h(ww, r)
+		got <- seen{rec.Status(), rec.Bytes(), rec.Hijacked()}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, got
+}
+
+func TestStatusAndBytes(t *testing.T) {
+	for _, c := range []struct {
+		name    string
+		handler http.HandlerFunc
+		want    seen
+		// the status the client sees, when net/http fills one in
+		client int
+	}{
+		{"write only", func(w http.ResponseWriter, _ *http.Request) {
+			io.WriteString(w, "hello")
+		}, seen{status: 200, bytes: 5}, 200},
+		{"status and body", func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+			io.WriteString(w, "no such page")
+		}, seen{status: 404, bytes: 12}, 404},
+		{"second WriteHeader ignored", func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusCreated)
+			w.WriteHeader(http.StatusInternalServerError)
+		}, seen{status: 201}, 201},
+		{"early hints", func(w http.ResponseWriter, _ *http.Request) {
+			w.Header().Set("Link", "</style.css>; rel=preload")
+			w.WriteHeader(http.StatusEarlyHints)
+			w.WriteHeader(http.StatusAccepted)
+		}, seen{status: 202}, 202},
+		{"nothing", func(http.ResponseWriter, *http.Request) {}, seen{}, 200},
+	} {
+		srv, got := recording(t, c.handler)
+		resp, err := http.Get(srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if s := <-got; s != c.want || resp.StatusCode != c.client {
+			t.Errorf("%s: recorded %+v, client got %d; want %+v, %d", c.name, s, resp.StatusCode, c.want, c.client)
+		}
+	}
+}
+
+// TestFlush checks that a handler behind Wrap can still stream: the
+// client reads the first chunk while the handler waits for it to.
+func TestFlush(t *testing.T) {
+	read := make(chan struct{})
+	srv, got := recording(t, func(w http.ResponseWriter, _ *http.Request) {
+		f, ok := w.(http.Flusher)
+		if !ok {
+			t.Error("wrapped writer is not a Flusher")
+			return
+		}
+		io.WriteString(w, "first\n")
+		f.Flush()
+		select {
+		case <-read:
+		case <-time.After(5 * time.Second):
+			t.Error("client did not get the flushed chunk")
+		}
+		io.WriteString(w, "second\n")
+	})
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	br := bufio.NewReader(resp.Body)
+	if line, err := br.ReadString('\n'); err != nil || line != "first\n" {
+		t.Fatalf("first chunk = %q, %v", line, err)
+	}
+	close(read)
+	if rest, err := io.ReadAll(br); err != nil || string(rest) != "second\n" {
+		t.Errorf("rest = %q, %v", rest, err)
+	}
+	if s := <-got; s != (seen{status: 200, bytes: 13}) {
+		t.Errorf("recorded %+v, want 200, 13 bytes", s)
+	}
+}
+
+// TestFlushBeforeWrite checks that flushing commits the implicit 200.
+func TestFlushBeforeWrite(t *testing.T) {
+	srv, got := recording(t, func(w http.ResponseWriter, _ *http.Request) {
+		w.(http.Flusher).Flush()
+		w.WriteHeader(http.StatusTeapot)
+	})
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if s := <-got; s.status != 200 || resp.StatusCode != 200 {
+		t.Errorf("recorded %d, client got %d, want 200", s.status, resp.StatusCode)
+	}
+}
+
+// TestHijack upgrades the connection to a line echo protocol, as a
+// WebSocket handler would.
+func TestHijack(t *testing.T) {
+	srv, got := recording(t, func(w http.ResponseWriter, _ *http.Request) {
+		h, ok := w.(http.Hijacker)
+		if !ok {
+			t.Error("wrapped writer is not a Hijacker")
+			return
+		}
+		conn, rw, err := h.Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: echo\r\nConnection: Upgrade\r\n\r\n")
+		rw.Flush()
+		line, _ := rw.ReadString('\n')
+		rw.WriteString(strings.ToUpper(line))
+		rw.Flush()
+	})
+
+	conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	io.WriteString(conn, "GET / HTTP/1.1\r\nHost: test\r\nUpgrade: echo\r\nConnection: Upgrade\r\n\r\n")
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil || resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("upgrade response = %v, %v", resp, err)
+	}
+	io.WriteString(conn, "ping\n")
+	if line, err := br.ReadString('\n'); err != nil || line != "PING\n" {
+		t.Errorf("echo = %q, %v", line, err)
+	}
+	if s := <-got; s != (seen{status: 101, hijacked: true}) {
+		t.Errorf("recorded %+v, want 101 and hijacked", s)
+	}
+}
+
+// onlyWriter hides the optional interfaces of the writer it embeds.
+type onlyWriter struct{ http.ResponseWriter }
+
+// hijackOnly implements http.Hijacker and not http.Flusher.
+type hijackOnly struct{ onlyWriter }
+
+func (hijackOnly) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return nil, nil, errors.New("no connection")
+}
+
+// TestInterfaces checks that the wrapper implements exactly the optional
+// interfaces of the writer it wraps.
+func TestInterfaces(t *testing.T) {
+	for _, c := range []struct {
+		name              string
+		w                 http.ResponseWriter
+		flusher, hijacker bool
+	}{
+		{"neither", onlyWriter{httptest.NewRecorder()}, false, false},
+		{"flusher", httptest.NewRecorder(), true, false},
+		{"hijacker", hijackOnly{onlyWriter{httptest.NewRecorder()}}, false, true},
+	} {
+		ww, _ := responserecorder.Wrap(c.w)
+		_, f := ww.(http.Flusher)
+		_, h := ww.(http.Hijacker)
+		if f != c.flusher || h != c.hijacker {
+			t.Errorf("%s: Flusher %v, Hijacker %v; want %v, %v", c.name, f, h, c.flusher, c.hijacker)
+		}
+	}
+}
+
+// TestResponseController checks that http.ResponseController reaches the
+// connection's writer through the wrapper, and reports what it lacks.
+func TestResponseController(t *testing.T) {
+	srv, got := recording(t, func(w http.ResponseWriter, _ *http.Request) {
+		rc := http.NewResponseController(w)
+		if err := rc.SetWriteDeadline(time.Now().Add(time.Minute)); err != nil {
+			t.Errorf("SetWriteDeadline: %v", err)
+		}
+		io.WriteString(w, "ok")
+		if err := rc.Flush(); err != nil {
+			t.Errorf("Flush: %v", err)
+		}
+	})
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if s := <-got; s != (seen{status: 200, bytes: 2}) {
+		t.Errorf("recorded %+v", s)
+	}
+
+	ww, _ := responserecorder.Wrap(onlyWriter{httptest.NewRecorder()})
+	if err := http.NewResponseController(ww).Flush(); !errors.Is(err, http.ErrNotSupported) {
+		t.Errorf("Flush of a plain writer = %v, want ErrNotSupported", err)
+	}
+	if _, _, err := http.NewResponseController(ww).Hijack(); !errors.Is(err, http.ErrNotSupported) {
+		t.Errorf("Hijack of a plain writer = %v, want ErrNotSupported", err)
+	}
+}
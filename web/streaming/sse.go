@@ -0,0 +1,316 @@
+package streaming
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"iter"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"patterns/clock"
+	"patterns/construct"
+	"patterns/funcopts"
+)
+
+// Event is one server-sent event.
+type Event struct {
+	// ID is what the client sends back as Last-Event-ID when it
+	// reconnects, so the stream can resume after it. Clients keep the last
+	// ID they saw, so leaving it empty on an event does not reset it.
+	ID string
+	// Name is the event type; the browser's EventSource dispatches it to
+	// addEventListener(Name). Empty means "message".
+	Name string
+	// Data is the payload. New lines are allowed: each line is sent as its
+	// own data field and the client joins them again.
+	Data string
+	// Retry, if positive, tells the client how long to wait before
+	// reconnecting.
+	Retry time.Duration
+}
+
+var (
+	fieldCleaner = strings.NewReplacer("\r", "", "\n", "", "\x00", "")
+	newlines     = strings.NewReplacer("\r\n", "\n", "\r", "\n")
+)
+
+// WriteTo writes e in the text/event-stream format. A new line in ID or
+// Name would end the field early, so they are removed. An event with only
+// an ID or Retry sends no data field: the client updates its state but
+// dispatches nothing.
+func (e Event) WriteTo(w io.Writer) (int64, error) {
+	var b strings.Builder
+	if e.ID != "" {
+		b.WriteString("id: " + fieldCleaner.Replace(e.ID) + "\n")
+	}
+	if e.Name != "" {
+		b.WriteString("event: " + fieldCleaner.Replace(e.Name) + "\n")
+	}
+	if e.Retry > 0 {
+		b.WriteString("retry: " + strconv.FormatInt(e.Retry.Milliseconds(), 10) + "\n")
+	}
+	if e.Data != "" || e.Name != "" {
+		for _, line := range strings.Split(newlines.Replace(e.Data), "\n") {
+			b.WriteString("data: " + line + "\n")
+		}
+	}
+	b.WriteString("\n")
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+// LastEventID returns the ID of the last event a reconnecting client
+// received, or "" on a first connection.
+func LastEventID(r *http.Request) string {
+	return r.Header.Get("Last-Event-ID")
+}
+
+// Writer writes server-sent events to a response, flushing after each.
+// It is not safe for concurrent use: one goroutine owns the stream.
+type Writer struct {
+	w  http.ResponseWriter
+	rc *http.ResponseController
+}
+
+// NewWriter sets the event stream headers and sends them. It fails if the
+// response cannot be flushed, before anything is written, so the caller
+// can still answer with an error.
+func NewWriter(w http.ResponseWriter) (*Writer, error) {
+	h := w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	// nginx buffers proxied responses unless told otherwise
+	h.Set("X-Accel-Buffering", "no")
+	rc := http.NewResponseController(w)
+	if err := rc.Flush(); err != nil {
+		return nil, err
+	}
+	return &Writer{w: w, rc: rc}, nil
+}
+
+// Send writes e and flushes it.
+func (s *Writer) Send(e Event) error {
+	if _, err := e.WriteTo(s.w); err != nil {
+		return err
+	}
+	return s.rc.Flush()
+}
+
+// Comment writes a comment line, which clients ignore. Sent periodically
+// it keeps proxies and load balancers from closing an idle stream.
+func (s *Writer) Comment(text string) error {
+	if _, err := io.WriteString(s.w, ": "+fieldCleaner.Replace(text)+"\n\n"); err != nil {
+		return err
+	}
+	return s.rc.Flush()
+}
+
+type options struct {
+	heartbeat time.Duration
+	retry     time.Duration
+	clock     clock.Clock
+}
+
+type Option = funcopts.Option[options]
+
+// WithHeartbeat sends a comment after d without events; zero disables it.
+// The default is 15 seconds, below the idle timeout of common proxies.
+func WithHeartbeat(d time.Duration) Option {
+	return func(options *options) error {
+		if d < 0 {
+			return errors.New("heartbeat cannot be negative")
+		}
+		options.heartbeat = d
+		return nil
+	}
+}
+
+// WithRetry sends d as the reconnection delay when the stream opens.
+func WithRetry(d time.Duration) Option {
+	return func(options *options) error {
+		if d <= 0 {
+			return errors.New("retry must be positive")
+		}
+		options.retry = d
+		return nil
+	}
+}
+
+func WithClock(c clock.Clock) Option {
+	return func(options *options) error {
+		if c == nil {
+			return errors.New("clock cannot be nil")
+		}
+		options.clock = c
+		return nil
+	}
+}
+
+func (o *options) SetDefaults() {
+	o.heartbeat = 15 * time.Second
+	o.clock = clock.Real
+}
+
+// Serve streams events to the client until the channel is closed (nil is
+// returned) or the client goes away (the context error is returned).
+func Serve(w http.ResponseWriter, r *http.Request, events <-chan Event, opts ...Option) error {
+	options, err := construct.New(opts...)
+	if err != nil {
+		return err
+	}
+	return serve(w, r, events, *options)
+}
+
+// Source produces the events of one stream. lastID is the Last-Event-ID
+// the client reconnected with, empty on the first connection, so the
+// source can resume after it instead of replaying from the start. The
+// sequence should end when ctx is done.
+type Source func(ctx context.Context, lastID string) iter.Seq[Event]
+
+// Handler serves every request with its own stream from src.
+func Handler(src Source, opts ...Option) (http.Handler, error) {
+	options, err := construct.New(opts...)
+	if err != nil {
+		return nil, err
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+		serve(w, r, Pipe(ctx, src(ctx, LastEventID(r))), *options)
+	}), nil
+}
+
+func serve(w http.ResponseWriter, r *http.Request, events <-chan Event, options options) error {
+	s, err := NewWriter(w)
+	if err != nil {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return err
+	}
+	if options.retry > 0 {
+		if err := s.Send(Event{Retry: options.retry}); err != nil {
+			return err
+		}
+	}
+
+	var beat <-chan time.Time
+	var timer clock.Timer
+	if options.heartbeat > 0 {
+		timer = options.clock.NewTimer(options.heartbeat)
+		defer timer.Stop()
+		beat = timer.C()
+	}
+	for {
+		select {
+		case <-r.Context().Done():
+			return r.Context().Err()
+		case e, ok := <-events:
+			if !ok {
+				return nil
+			}
+			if err := s.Send(e); err != nil {
+				return err
+			}
+		case <-beat:
+			if err := s.Comment("heartbeat"); err != nil {
+				return err
+			}
+		}
+		if timer != nil {
+			// the heartbeat only fills silence, so every write restarts it
+			timer.Stop()
+			timer.Reset(options.heartbeat)
+		}
+	}
+}
+
+// Decoder reads server-sent events from a stream, the client side of
+// Writer, following the EventSource parsing rules: comments are skipped,
+// fields without a value are empty, and the last ID carries over to later
+// events.
+type Decoder struct {
+	r      *bufio.Reader
+	lastID string
+	retry  time.Duration
+	first  bool
+}
+
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: bufio.NewReader(r), first: true}
+}
+
+// LastEventID is the ID to send as Last-Event-ID when reconnecting.
+func (d *Decoder) LastEventID() string { return d.lastID }
+
+// Retry is the reconnection delay the server asked for, or zero.
+func (d *Decoder) Retry() time.Duration { return d.retry }
+
+// Next returns the next event. When the stream ends it returns io.EOF,
+// discarding an event that was not terminated by a blank line.
+func (d *Decoder) Next() (Event, error) {
+	var e Event
+	var data []string
+	hasData := false
+	for {
+		line, err := d.r.ReadString('\n')
+		if err != nil {
+			return Event{}, err
+		}
+		line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+		if d.first {
+			line = strings.TrimPrefix(line, "\ufeff")
+			d.first = false
+		}
+
+		if line == "" {
+			if !hasData {
+				e, data = Event{}, nil
+				continue
+			}
+			e.ID = d.lastID
+			e.Data = strings.Join(data, "\n")
+			return e, nil
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			e.Name = value
+		case "data":
+			data = append(data, value)
+			hasData = true
+		case "id":
+			if !strings.ContainsRune(value, 0) {
+				d.lastID = value
+			}
+		case "retry":
+			if ms, err := strconv.ParseUint(value, 10, 63); err == nil {
+				d.retry = time.Duration(ms) * time.Millisecond
+				e.Retry = d.retry
+			}
+		}
+	}
+}
+
+// Events ranges over the events of r until it ends or fails; a failure
+// other than io.EOF is yielded as the last pair.
+func Events(r io.Reader) iter.Seq2[Event, error] {
+	return func(yield func(Event, error) bool) {
+		d := NewDecoder(r)
+		for {
+			e, err := d.Next()
+			if err == io.EOF {
+				return
+			}
+			if !yield(e, err) || err != nil {
+				return
+			}
+		}
+	}
+}
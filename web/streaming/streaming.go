@@ -0,0 +1,93 @@
+// Package streaming writes responses that are sent while they are being
+// produced, instead of buffered and sent at the end: chunked bodies (one
+// JSON value per line, or any encoding) and server-sent events.
+//
+//	mux.HandleFunc("/orders/export", func(w http.ResponseWriter, r *http.Request) {
+//		streaming.NDJSON(w, r, store.All(r.Context()))
+//	})
+//
+//	events, err := streaming.Handler(func(ctx context.Context, lastID string) iter.Seq[streaming.Event] {
+//		return feed.Since(ctx, lastID)
+//	}, streaming.WithHeartbeat(15*time.Second))
+//
+// Producers are an iter.Seq or a channel; Chan and Pipe convert between
+// the two. Flushing goes through http.ResponseController, so it works
+// behind middleware whose writers implement Unwrap (responserecorder
+// does). Package streamtest reads such responses incrementally from a
+// real httptest server, so a test can assert on each event as it arrives.
+package streaming
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"iter"
+	"net/http"
+)
+
+// Chunked writes each value of seq with encode and flushes after each, so
+// the client receives it immediately. It returns when seq ends, a write
+// fails, or the client goes away (the request context is done). Headers
+// are sent with the first value, so set Content-Type before calling it.
+//
+// A seq blocked waiting for its next value cannot notice the client
+// leaving; produce from Chan, or watch r.Context() in the producer.
+func Chunked[T any](w http.ResponseWriter, r *http.Request, seq iter.Seq[T], encode func(w io.Writer, v T) error) error {
+	rc := http.NewResponseController(w)
+	for v := range seq {
+		if err := encode(w, v); err != nil {
+			return err
+		}
+		if err := rc.Flush(); err != nil {
+			return err
+		}
+		if err := r.Context().Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NDJSON streams seq as newline-delimited JSON, one value per line.
+func NDJSON[T any](w http.ResponseWriter, r *http.Request, seq iter.Seq[T]) error {
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	enc := json.NewEncoder(w)
+	return Chunked(w, r, seq, func(_ io.Writer, v T) error { return enc.Encode(v) })
+}
+
+// Chan yields the values received from ch until it is closed or ctx is
+// done, so a channel producer can feed Chunked.
+func Chan[T any](ctx context.Context, ch <-chan T) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case v, ok := <-ch:
+				if !ok || !yield(v) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Pipe runs seq in a goroutine and sends its values on the returned
+// channel, which is closed when seq ends or ctx is done. It is how an
+// iter.Seq producer feeds Serve, which needs to wait for the next value
+// and a heartbeat at the same time.
+func Pipe[T any](ctx context.Context, seq iter.Seq[T]) <-chan T {
+	ch := make(chan T)
+	go func() {
+		defer close(ch)
+		for v := range seq {
+			select {
+			case <-ctx.Done():
+				return
+			case ch <- v:
+			}
+		}
+	}()
+	return ch
+}
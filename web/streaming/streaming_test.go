@@ -0,0 +1,350 @@
+package streaming_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"iter"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"patterns/clock"
+	"patterns/web/streaming"
+	"patterns/web/streaming/streamtest"
+)
+
+func TestEventWriteTo(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		e    streaming.Event
+		want string
+	}{
+		{"data", streaming.Event{Data: "hello"}, "data: hello\n\n"},
+		{"all fields", streaming.Event{ID: "7", Name: "tick", Data: "1", Retry: 1500 * time.Millisecond},
+			"id: 7\nevent: tick\nretry: 1500\ndata: 1\n\n"},
+		{"lines", streaming.Event{Data: "a\nb\r\nc\rd"}, "data: a\ndata: b\ndata: c\ndata: d\n\n"},
+		{"empty line", streaming.Event{Data: "a\n\nb"}, "data: a\ndata: \ndata: b\n\n"},
+		// a name alone still dispatches, so it needs a data field
+		{"name only", streaming.Event{Name: "ping"}, "event: ping\ndata: \n\n"},
+		// an id or retry alone updates the client without dispatching
+		{"id only", streaming.Event{ID: "9"}, "id: 9\n\n"},
+		{"retry only", streaming.Event{Retry: time.Second}, "retry: 1000\n\n"},
+		{"cleaned fields", streaming.Event{ID: "1\n2\x00", Name: "a\r\nb", Data: "x"}, "id: 12\nevent: ab\ndata: x\n\n"},
+		{"empty", streaming.Event{}, "\n"},
+	} {
+		var b strings.Builder
+		n, err := c.e.WriteTo(&b)
+		if err != nil || b.String() != c.want || n != int64(len(c.want)) {
+			t.Errorf("%s: wrote %q (%d), %v; want %q", c.name, b.String(), n, err, c.want)
+		}
+	}
+}
+
+func TestDecoder(t *testing.T) {
+	for _, c := range []struct {
+		name   string
+		stream string
+		want   []streaming.Event
+		lastID string
+		retry  time.Duration
+	}{
+		{"plain", "data: a\n\ndata: b\n\n", []streaming.Event{{Data: "a"}, {Data: "b"}}, "", 0},
+		{"lines joined", "data: a\ndata: b\n\n", []streaming.Event{{Data: "a\nb"}}, "", 0},
+		{"crlf and bom", "\ufeffevent: x\r\ndata: 1\r\n\r\n", []streaming.Event{{Name: "x", Data: "1"}}, "", 0},
+		{"comments", ": heartbeat\n\n: hi\ndata: a\n\n", []streaming.Event{{Data: "a"}}, "", 0},
+		// the id carries over to later events, until changed
+		{"ids", "id: 1\ndata: a\n\ndata: b\n\nid: 2\ndata: c\n\n",
+			[]streaming.Event{{ID: "1", Data: "a"}, {ID: "1", Data: "b"}, {ID: "2", Data: "c"}}, "2", 0},
+		// an id without data is kept but nothing is dispatched; a NUL
+		// makes the id invalid
+		{"id alone", "id: 5\n\nid: 6\x00\n\ndata: a\n\n", []streaming.Event{{ID: "5", Data: "a"}}, "5", 0},
+		{"retry", "retry: 2500\n\nretry: soon\ndata: a\n\n", []streaming.Event{{Data: "a"}}, "", 2500 * time.Millisecond},
+		{"no space", "data:a\nevent:x\n\n", []streaming.Event{{Name: "x", Data: "a"}}, "", 0},
+		{"field alone", "data\ndata\n\n", []streaming.Event{{Data: "\n"}}, "", 0},
+		{"unknown field", "foo: bar\ndata: a\n\n", []streaming.Event{{Data: "a"}}, "", 0},
+		// an event cut off by the end of the stream is dropped
+		{"unterminated", "data: a\n\ndata: b\n", []streaming.Event{{Data: "a"}}, "", 0},
+	} {
+		d := streaming.NewDecoder(strings.NewReader(c.stream))
+		var got []streaming.Event
+		for {
+			e, err := d.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("%s: %v", c.name, err)
+			}
+			got = append(got, e)
+		}
+		if len(got) != len(c.want) {
+			t.Errorf("%s: events %+v, want %+v", c.name, got, c.want)
+			continue
+		}
+		for i := range got {
+			// Retry is checked through the decoder below
+			got[i].Retry = 0
+			if got[i] != c.want[i] {
+				t.Errorf("%s: event %d = %+v, want %+v", c.name, i, got[i], c.want[i])
+			}
+		}
+		if d.LastEventID() != c.lastID || d.Retry() != c.retry {
+			t.Errorf("%s: last id %q, retry %v; want %q, %v", c.name, d.LastEventID(), d.Retry(), c.lastID, c.retry)
+		}
+	}
+}
+
+// TestRoundTrip checks that what WriteTo writes, Events reads back.
+func TestRoundTrip(t *testing.T) {
+	sent := []streaming.Event{
+		{ID: "1", Name: "order", Data: `{"id":1}`},
+		{ID: "2", Data: "line one\nline two"},
+		{ID: "2", Name: "ping", Data: ""},
+		{ID: "3", Data: " leading space"},
+	}
+	var b strings.Builder
+	for _, e := range sent {
+		e.WriteTo(&b)
+	}
+	i := 0
+	for e, err := range streaming.Events(strings.NewReader(b.String())) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i >= len(sent) || e != sent[i] {
+			t.Errorf("event %d = %+v", i, e)
+		}
+		i++
+	}
+	if i != len(sent) {
+		t.Errorf("read %d events, want %d", i, len(sent))
+	}
+}
+
+// TestNDJSON sends one value at a time and waits to see each arrive
+// before sending the next, which only a stream flushing per value passes.
+func TestNDJSON(t *testing.T) {
+	type order struct {
+		ID int `json:"id"`
+	}
+	values := make(chan order)
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		streaming.NDJSON(w, r, streaming.Chan(r.Context(), values))
+	})
+	// headers go out with the first value, so it is sent while the
+	// request waits for them
+	go func() { values <- order{0} }()
+	s := streamtest.Lines(t, h, "/export", nil)
+	s.Expect(`{"id":0}`)
+	values <- order{1}
+	s.Expect(`{"id":1}`)
+	values <- order{2}
+	s.Expect(`{"id":2}`)
+	close(values)
+	s.ExpectEnd()
+	if ct := s.Response().Header.Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+}
+
+// TestServeHeartbeat drives heartbeats with a fake clock: one per
+// interval of silence, and none while events flow.
+func TestServeHeartbeat(t *testing.T) {
+	fake := clock.NewFake(time.Unix(0, 0))
+	events := make(chan streaming.Event)
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		streaming.Serve(w, r, events, streaming.WithHeartbeat(10*time.Second),
+			streaming.WithRetry(3*time.Second), streaming.WithClock(fake))
+	})
+	s := streamtest.Lines(t, h, "/events", nil)
+	s.Expect("retry: 3000", "")
+
+	fake.BlockUntil(1)
+	fake.Advance(10 * time.Second)
+	s.Expect(": heartbeat", "")
+	// a fired timer is no longer pending, so this waits for the reset
+	fake.BlockUntil(1)
+	fake.Advance(5 * time.Second)
+
+	// each send returns once Serve is waiting again, so the first has
+	// restarted the heartbeat by the time the second is taken
+	events <- streaming.Event{Data: "a"}
+	events <- streaming.Event{Data: "b"}
+	s.Expect("data: a", "", "data: b", "")
+	// 19s after the heartbeat, 9s after the events: an unreset timer
+	// would have fired at 20s
+	fake.Advance(9 * time.Second)
+	events <- streaming.Event{Data: "c"}
+	s.Expect("data: c", "")
+
+	close(events)
+	s.ExpectEnd()
+	if got := s.Response().Header; got.Get("Content-Type") != "text/event-stream" || got.Get("Cache-Control") != "no-cache" {
+		t.Errorf("headers %v", got)
+	}
+}
+
+// TestHandlerResume checks that a reconnecting client's Last-Event-ID
+// reaches the Source, and the stream picks up after it.
+func TestHandlerResume(t *testing.T) {
+	src := func(ctx context.Context, lastID string) iter.Seq[streaming.Event] {
+		return func(yield func(streaming.Event) bool) {
+			start := 0
+			if lastID != "" {
+				start = int(lastID[0] - '0')
+			}
+			for i := start + 1; i <= 4; i++ {
+				if !yield(streaming.Event{ID: string(rune('0' + i)), Data: "tick"}) {
+					return
+				}
+			}
+		}
+	}
+	h, err := streaming.Handler(src, streaming.WithHeartbeat(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := streamtest.Events(t, h, "/events", nil)
+	s.Expect(streaming.Event{ID: "1", Data: "tick"}, streaming.Event{ID: "2", Data: "tick"})
+
+	s = streamtest.Events(t, h, "/events", http.Header{"Last-Event-ID": {"2"}})
+	s.Expect(streaming.Event{ID: "3", Data: "tick"}, streaming.Event{ID: "4", Data: "tick"})
+	s.ExpectEnd()
+}
+
+// TestClientGone checks that when the client leaves, Serve returns the
+// context error and the Source's sequence is stopped.
+func TestClientGone(t *testing.T) {
+	stopped := make(chan struct{})
+	src := func(ctx context.Context, lastID string) iter.Seq[streaming.Event] {
+		return func(yield func(streaming.Event) bool) {
+			defer close(stopped)
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(time.Millisecond):
+				}
+				if !yield(streaming.Event{Data: "tick"}) {
+					return
+				}
+			}
+		}
+	}
+	h, _ := streaming.Handler(src)
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := streaming.NewDecoder(resp.Body).Next(); err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the source kept running after the client left")
+	}
+
+	served := make(chan error, 1)
+	srv2 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		served <- streaming.Serve(w, r, make(chan streaming.Event))
+	}))
+	defer srv2.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	req, _ := http.NewRequestWithContext(ctx, "GET", srv2.URL, nil)
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	resp.Body.Close()
+	select {
+	case err := <-served:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Serve = %v, want the context error", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Serve did not return after the client left")
+	}
+}
+
+// plainWriter cannot flush.
+type plainWriter struct {
+	header http.Header
+	status int
+	body   strings.Builder
+}
+
+func (w *plainWriter) Header() http.Header         { return w.header }
+func (w *plainWriter) WriteHeader(status int)      { w.status = status }
+func (w *plainWriter) Write(b []byte) (int, error) { return w.body.Write(b) }
+
+func TestNoFlusher(t *testing.T) {
+	if _, err := streaming.NewWriter(&plainWriter{header: http.Header{}}); !errors.Is(err, http.ErrNotSupported) {
+		t.Errorf("NewWriter = %v, want ErrNotSupported", err)
+	}
+	w := &plainWriter{header: http.Header{}}
+	err := streaming.Serve(w, httptest.NewRequest("GET", "/", nil), nil)
+	if err == nil || w.status != http.StatusInternalServerError {
+		t.Errorf("Serve = %v with status %d, want an error and a 500", err, w.status)
+	}
+}
+
+// TestChunkedErrors checks that Chunked stops at the first failing
+// encode, and Pipe stops its producer when the context ends.
+func TestChunkedErrors(t *testing.T) {
+	rec := httptest.NewRecorder()
+	n := 0
+	err := streaming.Chunked(rec, httptest.NewRequest("GET", "/", nil), func(yield func(int) bool) {
+		for i := 0; yield(i); i++ {
+		}
+	}, func(w io.Writer, v int) error {
+		if n++; v == 2 {
+			return errors.New("unencodable")
+		}
+		_, err := io.WriteString(w, "x")
+		return err
+	})
+	if err == nil || rec.Body.String() != "xx" || n != 3 {
+		t.Errorf("Chunked = %v after %d encodes, body %q", err, n, rec.Body)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	ch := streaming.Pipe(ctx, func(yield func(int) bool) {
+		defer close(done)
+		for i := 0; yield(i); i++ {
+		}
+	})
+	<-ch
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Pipe's producer kept running after cancel")
+	}
+	for range ch {
+	}
+}
+
+func TestOptions(t *testing.T) {
+	src := func(context.Context, string) iter.Seq[streaming.Event] { return func(func(streaming.Event) bool) {} }
+	for _, c := range []struct {
+		opt  streaming.Option
+		want string
+	}{
+		{streaming.WithHeartbeat(-1), "heartbeat cannot be negative"},
+		{streaming.WithRetry(0), "retry must be positive"},
+		{streaming.WithClock(nil), "clock cannot be nil"},
+	} {
+		if _, err := streaming.Handler(src, c.opt); err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Errorf("Handler = %v, want %q", err, c.want)
+		}
+	}
+}
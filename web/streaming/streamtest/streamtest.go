@@ -0,0 +1,166 @@
+// Package streamtest asserts on streaming responses as they arrive. The
+// handler runs behind a real httptest server, because
+// httptest.ResponseRecorder only shows the body once the handler has
+// returned, and a stream that never flushes would pass with it:
+//
+//	s := streamtest.Events(t, handler, "/events", nil)
+//	s.Expect(streaming.Event{Name: "tick", Data: "1"})
+//	s.Expect(streaming.Event{Name: "tick", Data: "2"})
+//
+// Every read has a deadline (Timeout), so a handler that buffers fails the
+// test instead of hanging it.
+package streamtest
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"patterns/web/streaming"
+)
+
+// T is the part of testing.TB the helpers use.
+type T interface {
+	Helper()
+	Cleanup(func())
+	Fatalf(format string, args ...any)
+}
+
+// Timeout is how long Next waits for the next value by default, and how
+// long the response headers may take.
+const Timeout = 2 * time.Second
+
+// Stream is a response being read in the background, one value at a time.
+type Stream[V comparable] struct {
+	t       T
+	resp    *http.Response
+	values  chan result[V]
+	done    chan struct{}
+	Timeout time.Duration
+}
+
+type result[V comparable] struct {
+	v   V
+	err error
+}
+
+// Events requests target from h with header and decodes the response as
+// server-sent events. The server and the request are closed at cleanup.
+func Events(t T, h http.Handler, target string, header http.Header) *Stream[streaming.Event] {
+	t.Helper()
+	return open(t, h, target, header, func(s *Stream[streaming.Event]) {
+		d := streaming.NewDecoder(s.resp.Body)
+		for {
+			e, err := d.Next()
+			if !s.send(result[streaming.Event]{e, err}) || err != nil {
+				return
+			}
+		}
+	})
+}
+
+// Lines requests target from h with header and reads the response line by
+// line, for chunked formats like NDJSON, or to see an event stream as sent
+// (heartbeat comments included).
+func Lines(t T, h http.Handler, target string, header http.Header) *Stream[string] {
+	t.Helper()
+	return open(t, h, target, header, func(s *Stream[string]) {
+		sc := bufio.NewScanner(s.resp.Body)
+		for sc.Scan() {
+			if !s.send(result[string]{v: sc.Text()}) {
+				return
+			}
+		}
+		err := sc.Err()
+		if err == nil {
+			err = io.EOF
+		}
+		s.send(result[string]{err: err})
+	})
+}
+
+func open[V comparable](t T, h http.Handler, target string, header http.Header, read func(*Stream[V])) *Stream[V] {
+	t.Helper()
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+	req, err := http.NewRequest(http.MethodGet, srv.URL+target, nil)
+	if err != nil {
+		t.Fatalf("streamtest: %v", err)
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	// a handler that never flushes sends no headers either
+	tr := srv.Client().Transport.(*http.Transport).Clone()
+	tr.ResponseHeaderTimeout = Timeout
+	t.Cleanup(tr.CloseIdleConnections)
+	resp, err := (&http.Client{Transport: tr}).Do(req)
+	if err != nil {
+		t.Fatalf("streamtest: GET %s: %v", target, err)
+	}
+	s := &Stream[V]{t: t, resp: resp, values: make(chan result[V]), done: make(chan struct{}), Timeout: Timeout}
+	t.Cleanup(func() {
+		close(s.done)
+		resp.Body.Close()
+	})
+	go read(s)
+	return s
+}
+
+// send hands r to Next, or gives up once the test is over.
+func (s *Stream[V]) send(r result[V]) bool {
+	select {
+	case s.values <- r:
+		return true
+	case <-s.done:
+		return false
+	}
+}
+
+// Response is the response, for its status and headers; its body belongs
+// to the stream.
+func (s *Stream[V]) Response() *http.Response { return s.resp }
+
+// Next returns the next value, failing the test if the stream ends or
+// nothing arrives within s.Timeout.
+func (s *Stream[V]) Next() V {
+	s.t.Helper()
+	select {
+	case r := <-s.values:
+		if r.err != nil {
+			s.t.Fatalf("streamtest: stream ended: %v", r.err)
+		}
+		return r.v
+	case <-time.After(s.Timeout):
+		var zero V
+		s.t.Fatalf("streamtest: nothing received within %v", s.Timeout)
+		return zero
+	}
+}
+
+// Expect reads one value per element of want and fails the test at the
+// first that differs.
+func (s *Stream[V]) Expect(want ...V) {
+	s.t.Helper()
+	for i, w := range want {
+		if got := s.Next(); got != w {
+			s.t.Fatalf("streamtest: value %d = %+v, want %+v", i, got, w)
+		}
+	}
+}
+
+// ExpectEnd fails the test unless the handler finishes the response
+// within s.Timeout without sending anything more.
+func (s *Stream[V]) ExpectEnd() {
+	s.t.Helper()
+	select {
+	case r := <-s.values:
+		if r.err == nil {
+			s.t.Fatalf("streamtest: want end of stream, got %+v", r.v)
+		}
+	case <-time.After(s.Timeout):
+		s.t.Fatalf("streamtest: stream still open after %v", s.Timeout)
+	}
+}
@@ -0,0 +1,128 @@
+package streamtest_test
+
+import (
+	"fmt"
+	"net/http"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"patterns/web/streaming"
+	"patterns/web/streaming/streamtest"
+)
+
+// fakeT records a failure and stops the goroutine, as testing.T does.
+type fakeT struct {
+	cleanups []func()
+	failure  string
+}
+
+func (*fakeT) Helper()            {}
+func (t *fakeT) Cleanup(f func()) { t.cleanups = append(t.cleanups, f) }
+func (t *fakeT) Fatalf(format string, args ...any) {
+	t.failure = fmt.Sprintf(format, args...)
+	runtime.Goexit()
+}
+
+// run calls f with a fake T on its own goroutine, closes release so
+// blocked handlers return, runs the cleanups and returns the failure, ""
+// if none.
+func run(f func(t *fakeT), release chan struct{}) string {
+	t := &fakeT{}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		f(t)
+	}()
+	<-done
+	close(release)
+	for _, c := range t.cleanups {
+		c()
+	}
+	return t.failure
+}
+
+func sse(events ...streaming.Event) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s, _ := streaming.NewWriter(w)
+		for _, e := range events {
+			s.Send(e)
+		}
+	})
+}
+
+// TestFailures checks that each way a stream can misbehave fails the
+// test with a message saying so, instead of hanging it.
+func TestFailures(t *testing.T) {
+	tick := streaming.Event{Name: "tick", Data: "1"}
+	var release chan struct{}
+	for _, c := range []struct {
+		name string
+		test func(t *fakeT)
+		want string
+	}{
+		{"passes", func(t *fakeT) {
+			s := streamtest.Events(t, sse(tick), "/", nil)
+			s.Expect(tick)
+			s.ExpectEnd()
+		}, ""},
+		{"wrong value", func(t *fakeT) {
+			streamtest.Events(t, sse(tick), "/", nil).Expect(streaming.Event{Name: "tock"})
+		}, "streamtest: value 0 = {ID: Name:tick Data:1 Retry:0s}, want {ID: Name:tock Data: Retry:0s}"},
+		{"ended early", func(t *fakeT) {
+			streamtest.Events(t, sse(tick), "/", nil).Expect(tick, tick)
+		}, "streamtest: stream ended: EOF"},
+		{"more than expected", func(t *fakeT) {
+			streamtest.Events(t, sse(tick, tick), "/", nil).ExpectEnd()
+		}, "streamtest: want end of stream, got {ID: Name:tick Data:1 Retry:0s}"},
+		// headers sent, the body written but never flushed
+		{"buffered body", func(t *fakeT) {
+			s := streamtest.Lines(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+				http.NewResponseController(w).Flush()
+				fmt.Fprintln(w, "held back")
+				<-release
+			}), "/", nil)
+			s.Timeout = 50 * time.Millisecond
+			s.Next()
+		}, "streamtest: nothing received within 50ms"},
+		{"still open", func(t *fakeT) {
+			s := streamtest.Lines(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprintln(w, "one")
+				http.NewResponseController(w).Flush()
+				<-release
+			}), "/", nil)
+			s.Timeout = 50 * time.Millisecond
+			s.Expect("one")
+			s.ExpectEnd()
+		}, "streamtest: stream still open after 50ms"},
+		// nothing flushed at all: not even the headers arrive
+		{"no headers", func(t *fakeT) {
+			streamtest.Lines(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprintln(w, "held back")
+				<-release
+			}), "/never", nil)
+		}, "timeout awaiting response headers"},
+	} {
+		release = make(chan struct{})
+		if got := run(c.test, release); c.want == "" && got != "" || !strings.Contains(got, c.want) {
+			t.Errorf("%s: failure %q, want %q", c.name, got, c.want)
+		}
+	}
+}
+
+func TestHeaderAndResponse(t *testing.T) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Seen", r.Header.Get("X-Token"))
+		w.WriteHeader(http.StatusAccepted)
+		fmt.Fprintln(w, "a")
+		fmt.Fprintln(w, "b")
+	})
+	s := streamtest.Lines(t, h, "/path?q=1", http.Header{"X-Token": {"secret"}})
+	s.Expect("a", "b")
+	s.ExpectEnd()
+	if resp := s.Response(); resp.StatusCode != http.StatusAccepted || resp.Header.Get("X-Seen") != "secret" {
+		t.Errorf("response %d %v", resp.StatusCode, resp.Header)
+	}
+}
@@ -0,0 +1,108 @@
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"patterns/validate"
+)
+
+// builtins compile a rule for a field of type t, refusing types the rule
+// does not apply to:
+//
+//   - min=N, max=N bound numbers, the length in characters of strings,
+//     and the number of items of slices and maps
+//   - oneof=a b c requires a string to be one of the space-separated
+//     values
+//   - email requires a bare address, like "ann@example.com"
+//
+// required is not here: it is the check of the zero value every field
+// gets.
+var builtins = map[string]func(t reflect.Type, arg string) (func(reflect.Value) error, error){
+	"min":   func(t reflect.Type, arg string) (func(reflect.Value) error, error) { return bound(t, arg, true) },
+	"max":   func(t reflect.Type, arg string) (func(reflect.Value) error, error) { return bound(t, arg, false) },
+	"oneof": oneOf,
+	"email": email,
+}
+
+func bound(t reflect.Type, arg string, min bool) (func(reflect.Value) error, error) {
+	n, err := strconv.ParseFloat(arg, 64)
+	if err != nil {
+		return nil, fmt.Errorf("bound %q is not a number", arg)
+	}
+	word := "most"
+	if min {
+		word = "least"
+	}
+	var measure func(reflect.Value) float64
+	msg := fmt.Sprintf("must be at %s %s", word, arg)
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		measure = func(v reflect.Value) float64 { return float64(v.Int()) }
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		measure = func(v reflect.Value) float64 { return float64(v.Uint()) }
+	case reflect.Float32, reflect.Float64:
+		measure = reflect.Value.Float
+	case reflect.String:
+		measure = func(v reflect.Value) float64 { return float64(utf8.RuneCountInString(v.String())) }
+		msg += plural(n, " character")
+	case reflect.Slice, reflect.Map, reflect.Array:
+		measure = func(v reflect.Value) float64 { return float64(v.Len()) }
+		msg = fmt.Sprintf("must have at %s %s", word, arg) + plural(n, " item")
+	default:
+		return nil, fmt.Errorf("cannot bound a %s", t)
+	}
+	return func(v reflect.Value) error {
+		if got := measure(v); min && got < n || !min && got > n {
+			return errors.New(msg)
+		}
+		return nil
+	}, nil
+}
+
+func plural(n float64, unit string) string {
+	if n == 1 {
+		return unit
+	}
+	return unit + "s"
+}
+
+func oneOf(t reflect.Type, arg string) (func(reflect.Value) error, error) {
+	if t.Kind() != reflect.String {
+		return nil, fmt.Errorf("cannot apply to a %s", t)
+	}
+	allowed := strings.Fields(arg)
+	if len(allowed) == 0 {
+		return nil, errors.New("no values")
+	}
+	rule := validate.OneOf(allowed...)
+	return func(v reflect.Value) error { return rule(v.String()) }, nil
+}
+
+func email(t reflect.Type, arg string) (func(reflect.Value) error, error) {
+	if t.Kind() != reflect.String {
+		return nil, fmt.Errorf("cannot apply to a %s", t)
+	}
+	return func(v reflect.Value) error {
+		// ParseAddress also accepts "Ann <ann@example.com>": require the
+		// bare address
+		if a, err := mail.ParseAddress(v.String()); err != nil || a.Address != v.String() {
+			return errors.New("must be an email address")
+		}
+		return nil
+	}, nil
+}
+
+// sanitizers are the built-in entries of the sanitize tag.
+var sanitizers = map[string]func(string) string{
+	"trim":  strings.TrimSpace,
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+	// collapse turns every run of whitespace into one space
+	"collapse": func(s string) string { return strings.Join(strings.Fields(s), " ") },
+}
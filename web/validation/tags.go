@@ -0,0 +1,199 @@
+package validation
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"patterns/validate"
+)
+
+// plan is a struct type's tags, compiled once.
+type plan struct {
+	fields []field
+	self   bool // *T implements Validatable
+}
+
+type field struct {
+	index    int
+	name     string
+	embedded bool
+	required bool
+	sanitize []func(string) string
+	rules    []func(reflect.Value) error
+	nested   *plan // struct, or pointer to one
+	elems    *plan // slice or array of structs, or of pointers to them
+}
+
+var validatable = reflect.TypeFor[Validatable]()
+
+// compile returns the plan for struct type t; other types have nothing to
+// check. Plans are built before they are filled in, so recursive types
+// terminate.
+func compile(t reflect.Type, c *config) (*plan, error) {
+	return compileIn(t, c, map[reflect.Type]*plan{})
+}
+
+func compileIn(t reflect.Type, c *config, seen map[reflect.Type]*plan) (*plan, error) {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, nil
+	}
+	if p, ok := seen[t]; ok {
+		return p, nil
+	}
+	p := &plan{self: reflect.PointerTo(t).Implements(validatable)}
+	seen[t] = p
+	for i := range t.NumField() {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		f, err := compileField(sf, c)
+		if err != nil {
+			return nil, fmt.Errorf("validation: %s.%s: %w", t.Name(), sf.Name, err)
+		}
+		f.index = i
+		ft := sf.Type
+		if ft.Kind() == reflect.Slice || ft.Kind() == reflect.Array {
+			if f.elems, err = compileIn(ft.Elem(), c, seen); err != nil {
+				return nil, err
+			}
+		} else if f.nested, err = compileIn(ft, c, seen); err != nil {
+			return nil, err
+		}
+		if f.required || len(f.sanitize) > 0 || len(f.rules) > 0 || f.nested != nil || f.elems != nil {
+			p.fields = append(p.fields, f)
+		}
+	}
+	return p, nil
+}
+
+func compileField(sf reflect.StructField, c *config) (field, error) {
+	f := field{name: fieldName(sf)}
+	f.embedded = sf.Anonymous && f.name == sf.Name
+	if tag := sf.Tag.Get("sanitize"); tag != "" {
+		if sf.Type.Kind() != reflect.String {
+			return f, fmt.Errorf("sanitize on a %s", sf.Type)
+		}
+		for _, name := range strings.Split(tag, ",") {
+			s, ok := c.sanitizer[name]
+			if !ok {
+				return f, fmt.Errorf("unknown sanitizer %q", name)
+			}
+			f.sanitize = append(f.sanitize, s)
+		}
+	}
+	tag := sf.Tag.Get("validate")
+	if tag == "" {
+		return f, nil
+	}
+	for _, spec := range strings.Split(tag, ",") {
+		name, arg, _ := strings.Cut(spec, "=")
+		if name == "required" {
+			f.required = true
+			continue
+		}
+		if custom, ok := c.rules[name]; ok {
+			f.rules = append(f.rules, func(v reflect.Value) error { return custom(v.Interface(), arg) })
+			continue
+		}
+		build, ok := builtins[name]
+		if !ok {
+			return f, fmt.Errorf("unknown rule %q", name)
+		}
+		r, err := build(sf.Type, arg)
+		if err != nil {
+			return f, fmt.Errorf("%s: %w", name, err)
+		}
+		f.rules = append(f.rules, r)
+	}
+	return f, nil
+}
+
+// fieldName is the name clients know the field by: its JSON name, or
+// else its path or query parameter.
+func fieldName(sf reflect.StructField) string {
+	if name, _, _ := strings.Cut(sf.Tag.Get("json"), ","); name != "" && name != "-" {
+		return name
+	}
+	for _, key := range []string{"path", "query"} {
+		if name := sf.Tag.Get(key); name != "" {
+			return name
+		}
+	}
+	return sf.Name
+}
+
+// check sanitizes and validates the addressable struct v; a nil plan,
+// for a type that is not a struct, passes.
+func (p *plan) check(v reflect.Value) error {
+	if p == nil {
+		return nil
+	}
+	var val validate.Validator
+	p.run(v, &val)
+	return val.Err()
+}
+
+func (p *plan) run(v reflect.Value, val *validate.Validator) {
+	for _, f := range p.fields {
+		fv := v.Field(f.index)
+		if len(f.sanitize) > 0 {
+			s := fv.String()
+			for _, sanitize := range f.sanitize {
+				s = sanitize(s)
+			}
+			fv.SetString(s)
+		}
+		if isZero(fv) {
+			// fields are optional unless required: a zero value passes
+			if f.required {
+				val.Fail(f.name, "is required")
+			}
+			continue
+		}
+		failed := false
+		for _, r := range f.rules {
+			if err := r(fv); err != nil {
+				val.Fail(f.name, err.Error())
+				failed = true
+				break
+			}
+		}
+		if failed {
+			continue
+		}
+		switch {
+		case f.nested != nil && f.embedded:
+			f.nested.run(reflect.Indirect(fv), val)
+		case f.nested != nil:
+			f.nested.run(reflect.Indirect(fv), val.Nested(f.name))
+		case f.elems != nil:
+			for i := range fv.Len() {
+				if e := reflect.Indirect(fv.Index(i)); e.IsValid() {
+					f.elems.run(e, val.Index(f.name, i))
+				}
+			}
+		}
+	}
+	if p.self {
+		v.Addr().Interface().(Validatable).Validate(val)
+	}
+}
+
+// isZero treats blank strings as missing, and empty slices as well as nil
+// ones.
+func isZero(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.String:
+		return strings.TrimSpace(v.String()) == ""
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	case reflect.Struct:
+		return false // checked field by field instead
+	}
+	return v.IsZero()
+}
@@ -0,0 +1,397 @@
+package validation_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"patterns/validate"
+	"patterns/web/handler"
+	"patterns/web/validation"
+)
+
+type Order struct {
+	Email    string    `json:"email" sanitize:"trim,lower" validate:"required,email"`
+	Customer Customer  `json:"customer"`
+	Billing  *Address  `json:"billing"`
+	Items    []Item    `json:"items" validate:"required,max=3"`
+	Extras   []*Item   `json:"extras"`
+	Tags     []string  `json:"tags" validate:"max=2"`
+	Period   *Period   `json:"period"`
+	Notes    [2]Note   `json:"notes"`
+	Priority string    `query:"priority" validate:"oneof=low high"`
+	ID       int       `path:"id" validate:"min=1"`
+	internal Customer  // unexported: never checked
+	Ignore   *struct{} `json:"-"`
+}
+
+type Customer struct {
+	Name    string  `json:"name" sanitize:"collapse" validate:"required,max=10"`
+	Address Address `json:"address"`
+}
+
+type Address struct {
+	Street string `json:"street" validate:"required"`
+	Zip    string `json:"zip" sanitize:"upper" validate:"min=4,max=8"`
+}
+
+type Item struct {
+	SKU      string  `json:"sku" sanitize:"trim,upper" validate:"required"`
+	Quantity int     `json:"quantity" validate:"required,min=1,max=99"`
+	Price    float64 `json:"price" validate:"max=1000.5"`
+}
+
+type Note struct {
+	Text string `json:"text" validate:"max=5"`
+}
+
+type Period struct {
+	Start int `json:"start" validate:"required"`
+	End   int `json:"end" validate:"required"`
+}
+
+// Validate runs after the tags, and only for a period that passed them.
+func (p *Period) Validate(v *validate.Validator) {
+	v.Check("end", p.End > p.Start, "must be after start")
+}
+
+func valid() Order {
+	return Order{
+		Email:    "ann@example.com",
+		Customer: Customer{Name: "Ann", Address: Address{Street: "Main St"}},
+		Items:    []Item{{SKU: "A1", Quantity: 1}},
+		ID:       7,
+	}
+}
+
+// paths returns "path: message" for each problem in err.
+func paths(t *testing.T, err error) []string {
+	t.Helper()
+	var errs validate.Errors
+	if err != nil && !errors.As(err, &errs) {
+		t.Fatalf("error %v is not validate.Errors", err)
+	}
+	var out []string
+	for _, e := range errs {
+		out = append(out, e.Error())
+	}
+	return out
+}
+
+// TestNested breaks rules at every depth: each problem is reported, in
+// field order, under its path in JSON names.
+func TestNested(t *testing.T) {
+	o := valid()
+	o.Email = " Not An Address "
+	o.Customer = Customer{Name: "Someone   Long Named", Address: Address{Zip: "ab"}}
+	o.Billing = &Address{Street: "Side St", Zip: "123456789"}
+	o.Items = []Item{{SKU: "a1", Quantity: 1}, {Quantity: 100}, {SKU: "c", Quantity: 1, Price: 1000.75}}
+	o.Extras = []*Item{nil, {SKU: "x"}}
+	o.Tags = []string{"a", "b", "c"}
+	o.Period = &Period{Start: 5, End: 3}
+	o.Notes = [2]Note{{Text: "short"}, {Text: "too long"}}
+	o.Priority = "urgent"
+	o.ID = -1
+	o.internal = Customer{}
+	want := []string{
+		"email: must be an email address",
+		"customer.name: must be at most 10 characters",
+		"customer.address.street: is required",
+		"customer.address.zip: must be at least 4 characters",
+		"billing.zip: must be at most 8 characters",
+		"items[1].sku: is required",
+		"items[1].quantity: must be at most 99",
+		"items[2].price: must be at most 1000.5",
+		"extras[1].quantity: is required",
+		"tags: must have at most 2 items",
+		"period.end: must be after start",
+		"notes[1].text: must be at most 5 characters",
+		"priority: must be one of [low high]",
+		"id: must be at least 1",
+	}
+	if got := paths(t, validation.Struct(&o)); !slices.Equal(got, want) {
+		t.Errorf("got:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	// sanitized before the rules ran, at every depth
+	if o.Email != "not an address" || o.Customer.Name != "Someone Long Named" || o.Customer.Address.Zip != "AB" ||
+		o.Items[0].SKU != "A1" || o.Extras[1].SKU != "X" {
+		t.Errorf("not sanitized: %+v", o)
+	}
+}
+
+func TestOptional(t *testing.T) {
+	o := valid()
+	if err := validation.Struct(&o); err != nil {
+		t.Errorf("valid order: %v", err)
+	}
+	// blank and empty are missing
+	var empty Order
+	empty.Email = "   "
+	empty.Items = []Item{}
+	want := []string{
+		"email: is required",
+		"customer.name: is required",
+		"customer.address.street: is required",
+		"items: is required",
+	}
+	if got := paths(t, validation.Struct(&empty)); !slices.Equal(got, want) {
+		t.Errorf("empty order:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	// a rule that fails stops the field's other rules, and its nested
+	// checks
+	o.Items = []Item{{SKU: "a", Quantity: 1}, {SKU: "b", Quantity: 1}, {SKU: "c", Quantity: 1}, {}}
+	if got := paths(t, validation.Struct(&o)); !slices.Equal(got, []string{"items: must have at most 3 items"}) {
+		t.Errorf("too many items: %v", got)
+	}
+}
+
+// Tree is recursive, with a rule spanning its fields at every level.
+type Tree struct {
+	Name     string  `json:"name" validate:"required"`
+	Children []*Tree `json:"children"`
+	Next     *Tree   `json:"next"`
+}
+
+func (t *Tree) Validate(v *validate.Validator) {
+	v.Check("", len(t.Children) <= 2, "too many children")
+}
+
+func TestRecursive(t *testing.T) {
+	tree := &Tree{Name: "root", Children: []*Tree{
+		{Name: "a", Children: []*Tree{{}, {Name: "b", Next: &Tree{}}}},
+		{Name: "c", Children: []*Tree{{Name: "d"}, {Name: "e"}, {Name: "f"}}},
+	}}
+	want := []string{
+		"children[0].children[0].name: is required",
+		"children[0].children[1].next.name: is required",
+		"children[1]: too many children",
+	}
+	if got := paths(t, validation.Struct(tree)); !slices.Equal(got, want) {
+		t.Errorf("got:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+type Base struct {
+	Tenant string `json:"tenant" validate:"required"`
+}
+
+type Audit struct {
+	By string `validate:"required"`
+}
+
+type Embeds struct {
+	Base
+	*Audit
+	Named Base   `json:"named"`
+	Title string `json:"title" validate:"required"`
+}
+
+// TestEmbedded checks that an embedded struct's fields are reported as
+// the outer struct's.
+func TestEmbedded(t *testing.T) {
+	e := Embeds{Audit: &Audit{}}
+	want := []string{"tenant: is required", "By: is required", "named.tenant: is required", "title: is required"}
+	if got := paths(t, validation.Struct(&e)); !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestOptions(t *testing.T) {
+	type Signup struct {
+		Handle string `json:"handle" sanitize:"slug" validate:"required,prefix=@,min=3"`
+		Email  string `json:"email" sanitize:"trim" validate:"email"`
+	}
+	opts := []validation.Option{
+		validation.WithSanitizer("slug", func(s string) string { return strings.ReplaceAll(s, " ", "-") }),
+		validation.WithRule("prefix", func(v any, arg string) error {
+			if !strings.HasPrefix(v.(string), arg) {
+				return errors.New("must start with " + arg)
+			}
+			return nil
+		}),
+		// a custom rule replaces the built-in one
+		validation.WithRule("email", func(v any, _ string) error {
+			if !strings.HasSuffix(strings.ToLower(v.(string)), "@example.com") {
+				return errors.New("must be at example.com")
+			}
+			return nil
+		}),
+		// and so does a sanitizer
+		validation.WithSanitizer("trim", strings.ToUpper),
+	}
+	s := Signup{Handle: "ann lee", Email: " ann@other.org "}
+	want := []string{"handle: must start with @", "email: must be at example.com"}
+	if got := paths(t, validation.Struct(&s, opts...)); !slices.Equal(got, want) || s.Handle != "ann-lee" || s.Email != " ANN@OTHER.ORG " {
+		t.Errorf("got %v, want %v; sanitized to %+v", got, want, s)
+	}
+	s = Signup{Handle: "@a b", Email: "bob@example.com"}
+	if err := validation.Struct(&s, opts...); err != nil || s.Handle != "@a-b" {
+		t.Errorf("got %v, sanitized to %q", err, s.Handle)
+	}
+}
+
+func TestBuiltins(t *testing.T) {
+	type T struct {
+		S  string         `json:"s" validate:"min=1"`
+		R  string         `json:"r" validate:"max=2"`
+		E  string         `json:"e" validate:"email"`
+		U  uint8          `json:"u" validate:"max=9"`
+		F  float32        `json:"f" validate:"min=0.5"`
+		L  []int          `json:"l" validate:"min=2"`
+		M  []int          `json:"m" validate:"min=1,max=1"`
+		Mp map[string]int `json:"mp" validate:"max=1"`
+	}
+	v := T{S: " ", R: "héé", E: "Ann <ann@example.com>", U: 10, F: 0.25, L: []int{1}, M: []int{1, 2}, Mp: map[string]int{"a": 1, "b": 2}}
+	want := []string{
+		"r: must be at most 2 characters",
+		"e: must be an email address",
+		"u: must be at most 9",
+		"f: must be at least 0.5",
+		"l: must have at least 2 items",
+		"m: must have at most 1 item",
+		"mp: must have at most 1 item",
+	}
+	if got := paths(t, validation.Struct(&v)); !slices.Equal(got, want) {
+		t.Errorf("got:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	// counted in characters, not bytes
+	v = T{R: "hé", E: "ann@example.com"}
+	if err := validation.Struct(&v); err != nil {
+		t.Errorf("valid: %v", err)
+	}
+}
+
+func TestBadTags(t *testing.T) {
+	for _, c := range []struct {
+		dst  any
+		want string
+	}{
+		{&struct {
+			A string `validate:"nope"`
+		}{}, `unknown rule "nope"`},
+		{&struct {
+			A int `validate:"min=x"`
+		}{}, `min: bound "x" is not a number`},
+		{&struct {
+			A bool `validate:"max=1"`
+		}{}, "max: cannot bound a bool"},
+		{&struct {
+			A int `validate:"oneof=1 2"`
+		}{}, "oneof: cannot apply to a int"},
+		{&struct {
+			A string `validate:"oneof="`
+		}{}, "oneof: no values"},
+		{&struct {
+			A int `validate:"email"`
+		}{}, "email: cannot apply to a int"},
+		{&struct {
+			A int `sanitize:"trim"`
+		}{}, "sanitize on a int"},
+		{&struct {
+			A string `sanitize:"shout"`
+		}{}, `unknown sanitizer "shout"`},
+		{&struct {
+			B struct {
+				C []struct {
+					A string `validate:"min"`
+				}
+			}
+		}{}, `min: bound "" is not a number`},
+	} {
+		err := validation.Struct(c.dst)
+		var errs validate.Errors
+		if err == nil || errors.As(err, &errs) || !strings.HasPrefix(err.Error(), "validation: ") || !strings.HasSuffix(err.Error(), c.want) {
+			t.Errorf("%T: %v, want %q", c.dst, err, c.want)
+		}
+	}
+	for _, dst := range []any{Order{}, (*Order)(nil), nil} {
+		if err := validation.Struct(dst); err == nil || err.Error() != "validation: Struct needs a non-nil pointer" {
+			t.Errorf("Struct(%T) = %v", dst, err)
+		}
+	}
+	// a type without structs has nothing to check
+	n := 3
+	if err := validation.Struct(&n); err != nil {
+		t.Errorf("Struct(*int) = %v", err)
+	}
+
+	defer func() {
+		if r := recover(); r == nil || !strings.Contains(r.(error).Error(), `unknown rule "nope"`) {
+			t.Errorf("Func recovered %v", r)
+		}
+	}()
+	validation.Func(func(context.Context, struct {
+		A string `validate:"nope"`
+	}) (any, error) {
+		return nil, nil
+	})
+	t.Error("Func accepted a bad tag")
+}
+
+type created struct {
+	ID    int    `json:"id"`
+	Email string `json:"email"`
+	SKUs  string `json:"skus"`
+}
+
+func (created) StatusCode() int { return http.StatusCreated }
+
+var errStore = errors.New("store down")
+
+func TestHTTP(t *testing.T) {
+	calls := 0
+	endpoint := func(_ context.Context, o Order) (created, error) {
+		calls++
+		switch o.Priority {
+		case "high":
+			// the endpoint's own checks are answered alike
+			var v validate.Validator
+			v.Fail("priority", "not today")
+			return created{}, v.Err()
+		case "low":
+			return created{}, errStore
+		}
+		var skus []string
+		for _, it := range o.Items {
+			skus = append(skus, it.SKU)
+		}
+		return created{ID: o.ID, Email: o.Email, SKUs: strings.Join(skus, ",")}, nil
+	}
+	mux := http.NewServeMux()
+	mux.Handle("POST /orders/{id}", handler.Adapt(validation.Func(endpoint),
+		handler.WithErrorMapper(validation.ErrorMapper(handler.DefaultErrorMapper))))
+
+	const good = `"email":" Ann@Example.com ","customer":{"name":"Ann","address":{"street":"Main"}}`
+	for _, c := range []struct {
+		name, target, body string
+		code               int
+		resp               string
+		called             bool
+	}{
+		{"valid", "/orders/7", `{` + good + `,"items":[{"sku":" a1 ","quantity":2},{"sku":"b2","quantity":1}]}`,
+			http.StatusCreated, `{"id":7,"email":"ann@example.com","skus":"A1,B2"}`, true},
+		{"invalid", "/orders/-1?priority=soon", `{` + good + `,"items":[{"sku":"a1"},{"quantity":1}]}`, http.StatusUnprocessableEntity,
+			`{"error":"invalid request","fields":[` +
+				`{"path":"items[0].quantity","message":"is required"},` +
+				`{"path":"items[1].sku","message":"is required"},` +
+				`{"path":"priority","message":"must be one of [low high]"},` +
+				`{"path":"id","message":"must be at least 1"}]}`, false},
+		{"endpoint check", "/orders/7?priority=high", `{` + good + `,"items":[{"sku":"a","quantity":1}]}`,
+			http.StatusUnprocessableEntity, `{"error":"invalid request","fields":[{"path":"priority","message":"not today"}]}`, true},
+		{"other error", "/orders/7?priority=low", `{` + good + `,"items":[{"sku":"a","quantity":1}]}`,
+			http.StatusInternalServerError, `{"error":"internal error"}`, true},
+		{"undecodable", "/orders/7", `{"items":`, http.StatusBadRequest, "", false},
+	} {
+		calls = 0
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest("POST", c.target, strings.NewReader(c.body)))
+		body := strings.TrimSpace(rec.Body.String())
+		if rec.Code != c.code || c.resp != "" && body != c.resp || (calls == 1) != c.called {
+			t.Errorf("%s: %d %s, endpoint called %v; want %d %s", c.name, rec.Code, body, calls == 1, c.code, c.resp)
+		}
+	}
+}